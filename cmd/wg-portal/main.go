@@ -9,11 +9,16 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	evbus "github.com/vardius/message-bus"
+	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 
 	"github.com/h44z/wg-portal/internal"
 	"github.com/h44z/wg-portal/internal/adapters"
 	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/acceptableuse"
+	"github.com/h44z/wg-portal/internal/app/accessrequests"
+	"github.com/h44z/wg-portal/internal/app/alerting"
+	"github.com/h44z/wg-portal/internal/app/announcements"
 	"github.com/h44z/wg-portal/internal/app/api/core"
 	backendV0 "github.com/h44z/wg-portal/internal/app/api/v0/backend"
 	handlersV0 "github.com/h44z/wg-portal/internal/app/api/v0/handlers"
@@ -21,9 +26,57 @@ import (
 	handlersV1 "github.com/h44z/wg-portal/internal/app/api/v1/handlers"
 	"github.com/h44z/wg-portal/internal/app/audit"
 	"github.com/h44z/wg-portal/internal/app/auth"
+	"github.com/h44z/wg-portal/internal/app/capacity"
+	"github.com/h44z/wg-portal/internal/app/cleanup"
+	"github.com/h44z/wg-portal/internal/app/compromise"
 	"github.com/h44z/wg-portal/internal/app/configfile"
+	"github.com/h44z/wg-portal/internal/app/configpull"
+	"github.com/h44z/wg-portal/internal/app/conflicts"
+	"github.com/h44z/wg-portal/internal/app/deviceauth"
+	"github.com/h44z/wg-portal/internal/app/diagnostics"
+	"github.com/h44z/wg-portal/internal/app/dnsrecords"
+	"github.com/h44z/wg-portal/internal/app/duplicates"
+	"github.com/h44z/wg-portal/internal/app/emergency"
+	"github.com/h44z/wg-portal/internal/app/ephemeral"
+	"github.com/h44z/wg-portal/internal/app/eventstream"
+	"github.com/h44z/wg-portal/internal/app/expiry"
+	"github.com/h44z/wg-portal/internal/app/export"
+	"github.com/h44z/wg-portal/internal/app/exportapproval"
+	"github.com/h44z/wg-portal/internal/app/failover"
+	"github.com/h44z/wg-portal/internal/app/flowexport"
+	"github.com/h44z/wg-portal/internal/app/health"
+	"github.com/h44z/wg-portal/internal/app/idempotency"
+	"github.com/h44z/wg-portal/internal/app/importer"
+	"github.com/h44z/wg-portal/internal/app/isolation"
+	"github.com/h44z/wg-portal/internal/app/keepalive"
+	"github.com/h44z/wg-portal/internal/app/keyreveal"
+	"github.com/h44z/wg-portal/internal/app/knock"
+	"github.com/h44z/wg-portal/internal/app/livestats"
 	"github.com/h44z/wg-portal/internal/app/mail"
+	"github.com/h44z/wg-portal/internal/app/organizations"
+	"github.com/h44z/wg-portal/internal/app/packetcapture"
+	"github.com/h44z/wg-portal/internal/app/peertransfer"
+	"github.com/h44z/wg-portal/internal/app/portforward"
+	"github.com/h44z/wg-portal/internal/app/reload"
+	"github.com/h44z/wg-portal/internal/app/reservations"
+	"github.com/h44z/wg-portal/internal/app/resolver"
+	"github.com/h44z/wg-portal/internal/app/restore"
+	"github.com/h44z/wg-portal/internal/app/retention"
+	"github.com/h44z/wg-portal/internal/app/roaming"
 	"github.com/h44z/wg-portal/internal/app/route"
+	"github.com/h44z/wg-portal/internal/app/routereview"
+	"github.com/h44z/wg-portal/internal/app/routesets"
+	"github.com/h44z/wg-portal/internal/app/schedule"
+	"github.com/h44z/wg-portal/internal/app/search"
+	"github.com/h44z/wg-portal/internal/app/secrets"
+	"github.com/h44z/wg-portal/internal/app/securityevents"
+	"github.com/h44z/wg-portal/internal/app/securitynotify"
+	"github.com/h44z/wg-portal/internal/app/setupreminder"
+	"github.com/h44z/wg-portal/internal/app/shaping"
+	"github.com/h44z/wg-portal/internal/app/snmp"
+	"github.com/h44z/wg-portal/internal/app/sshdeploy"
+	"github.com/h44z/wg-portal/internal/app/statuspage"
+	"github.com/h44z/wg-portal/internal/app/usergroups"
 	"github.com/h44z/wg-portal/internal/app/users"
 	"github.com/h44z/wg-portal/internal/app/webhooks"
 	"github.com/h44z/wg-portal/internal/app/wireguard"
@@ -32,7 +85,7 @@ import (
 
 // main entry point for WireGuard Portal
 func main() {
-	ctx := internal.SignalAwareContext(context.Background(), syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	ctx := internal.SignalAwareContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 
 	slog.Info("Starting WireGuard Portal V2...", "version", internal.Version)
 
@@ -40,24 +93,55 @@ func main() {
 	internal.AssertNoError(err)
 	internal.SetupLogging(cfg.Advanced.LogLevel, cfg.Advanced.LogPretty, cfg.Advanced.LogJson)
 
+	queueSize := 100
+	eventBus := evbus.New(queueSize)
+
+	secretManager, err := secrets.NewSecretManager(cfg, eventBus, adapters.NewSecretRepo(cfg.Secrets))
+	internal.AssertNoError(err)
+	internal.AssertNoError(secretManager.ResolveSecrets(ctx))
+	secretManager.StartBackgroundJobs(ctx)
+
+	reloadManager, err := reload.NewReloadManager(cfg, eventBus, secretManager, config.GetConfig)
+	internal.AssertNoError(err)
+	reloadManager.StartBackgroundJobs(ctx)
+
 	cfg.LogStartupValues()
 
 	dbEncryptedSerializer := app.NewGormEncryptedStringSerializer(cfg.Database.EncryptionPassphrase)
 	schema.RegisterSerializer("encstr", dbEncryptedSerializer)
-	rawDb, err := adapters.NewDatabase(cfg.Database)
-	internal.AssertNoError(err)
+	var rawDb *gorm.DB // only set for SQL databases
+	var database adapters.Repository
+	if cfg.Database.Type.IsKeyValueStore() {
+		database, err = adapters.NewKeyValueRepository(cfg.Database, dbEncryptedSerializer)
+		internal.AssertNoError(err)
+	} else {
+		rawDb, err = adapters.NewDatabase(cfg.Database)
+		internal.AssertNoError(err)
 
-	database, err := adapters.NewSqlRepository(rawDb)
-	internal.AssertNoError(err)
+		database, err = adapters.NewSqlRepository(rawDb)
+		internal.AssertNoError(err)
+	}
 
-	wireGuard := adapters.NewWireGuardRepository()
+	wireGuard := adapters.NewWireGuardRepository(cfg)
 
 	wgQuick := adapters.NewWgQuickRepo()
 
 	mailer := adapters.NewSmtpMailRepo(cfg.Mail)
 
+	dnsRepo, err := adapters.NewDnsRepository(cfg.DnsRecords)
+	internal.AssertNoError(err)
+
+	statsExporter, err := adapters.NewStatisticsExportRepository(cfg.StatisticsExport)
+	internal.AssertNoError(err)
+
+	eventStream, err := adapters.NewEventStreamRepository(cfg.EventStream)
+	internal.AssertNoError(err)
+
 	metricsServer := adapters.NewMetricsServer(cfg)
 
+	trafficAccounting, err := adapters.NewEbpfAccountingRepo(cfg)
+	internal.AssertNoError(err)
+
 	cfgFileSystem, err := adapters.NewFileSystemRepository(cfg.Advanced.ConfigStoragePath)
 	internal.AssertNoError(err)
 
@@ -72,9 +156,6 @@ func main() {
 		internal.AssertNoError(err)
 	}
 
-	queueSize := 100
-	eventBus := evbus.New(queueSize)
-
 	auditManager := audit.NewManager(database)
 
 	auditRecorder, err := audit.NewAuditRecorder(cfg, eventBus, database)
@@ -91,29 +172,220 @@ func main() {
 	webAuthn, err := auth.NewWebAuthnAuthenticator(cfg, eventBus, userManager)
 	internal.AssertNoError(err)
 
-	wireGuardManager, err := wireguard.NewWireGuardManager(cfg, eventBus, wireGuard, wgQuick, database)
+	acceptableUseManager, err := acceptableuse.NewAcceptableUseManager(cfg, eventBus, database)
+	internal.AssertNoError(err)
+
+	keyRevealManager, err := keyreveal.NewKeyRevealManager(cfg, eventBus, database)
+	internal.AssertNoError(err)
+
+	wireGuardManager, err := wireguard.NewWireGuardManager(cfg, eventBus, wireGuard, wgQuick, database,
+		acceptableUseManager, keyRevealManager)
 	internal.AssertNoError(err)
 	wireGuardManager.StartBackgroundJobs(ctx)
 
-	statisticsCollector, err := wireguard.NewStatisticsCollector(cfg, eventBus, database, wireGuard, metricsServer)
+	statisticsCollector, err := wireguard.NewStatisticsCollector(cfg, eventBus, database, wireGuard, metricsServer,
+		statsExporter, trafficAccounting)
 	internal.AssertNoError(err)
 	statisticsCollector.StartBackgroundJobs(ctx)
 
-	cfgFileManager, err := configfile.NewConfigFileManager(cfg, eventBus, database, database, cfgFileSystem)
+	cfgFileManager, err := configfile.NewConfigFileManager(cfg, eventBus, database, database, cfgFileSystem,
+		acceptableUseManager, keyRevealManager)
+	internal.AssertNoError(err)
+
+	messenger := adapters.NewTelegramRepo(cfg.Alerting.Telegram)
+	mailManager, err := mail.NewMailManager(cfg, eventBus, mailer, messenger, cfgFileManager, database, database, database,
+		database, database)
+	internal.AssertNoError(err)
+
+	cleanupManager, err := cleanup.NewCleanupManager(cfg, eventBus, database, wireGuardManager, mailManager)
+	internal.AssertNoError(err)
+	cleanupManager.StartBackgroundJobs(ctx)
+
+	expiryManager, err := expiry.NewExpiryManager(cfg, eventBus, database, mailManager)
+	internal.AssertNoError(err)
+	expiryManager.StartBackgroundJobs(ctx)
+
+	ephemeralPeerManager, err := ephemeral.NewEphemeralPeerManager(cfg, eventBus, database, wireGuardManager)
+	internal.AssertNoError(err)
+	ephemeralPeerManager.StartBackgroundJobs(ctx)
+
+	setupReminderManager, err := setupreminder.NewSetupReminderManager(cfg, eventBus, database, mailManager)
+	internal.AssertNoError(err)
+	setupReminderManager.StartBackgroundJobs(ctx)
+
+	capacityManager, err := capacity.NewCapacityManager(cfg, eventBus, database, metricsServer, mailManager)
+	internal.AssertNoError(err)
+	capacityManager.StartBackgroundJobs(ctx)
+
+	duplicateManager, err := duplicates.NewDuplicateManager(database, wireGuardManager)
+	internal.AssertNoError(err)
+
+	reservationManager, err := reservations.NewReservationManager(eventBus, database)
+	internal.AssertNoError(err)
+
+	conflictManager, err := conflicts.NewConflictManager(cfg, eventBus, database)
+	internal.AssertNoError(err)
+	conflictManager.StartBackgroundJobs(ctx)
+
+	scheduleManager, err := schedule.NewScheduleManager(cfg, eventBus, database, wireGuardManager)
+	internal.AssertNoError(err)
+	scheduleManager.StartBackgroundJobs(ctx)
+
+	_, err = securitynotify.NewSecurityNotificationManager(cfg, eventBus, database, mailManager)
+	internal.AssertNoError(err)
+
+	exportApprovalManager, err := exportapproval.NewExportApprovalManager(cfg, eventBus, database, mailManager)
+	internal.AssertNoError(err)
+
+	_, err = roaming.NewRoamingManager(cfg, eventBus, wireGuardManager, mailManager)
+	internal.AssertNoError(err)
+
+	emergencyManager, err := emergency.NewEmergencyManager(cfg, eventBus, database, wireGuardManager,
+		wireGuardManager)
+	internal.AssertNoError(err)
+
+	compromiseManager, err := compromise.NewCompromiseManager(cfg, eventBus, wireGuardManager, mailManager, mailer)
+	internal.AssertNoError(err)
+
+	securityEventManager, err := securityevents.NewSecurityEventManager(cfg, eventBus, database)
+	internal.AssertNoError(err)
+	securityEventManager.StartBackgroundJobs(ctx)
+
+	searchManager, err := search.NewSearchManager(database)
 	internal.AssertNoError(err)
 
-	mailManager, err := mail.NewMailManager(cfg, mailer, cfgFileManager, database, database)
+	diagnosticsManager, err := diagnostics.NewDiagnosticsManager(cfg, database, wireGuard, wireGuardManager)
+	internal.AssertNoError(err)
+
+	liveStatsManager, err := livestats.NewLiveStatsManager(cfg, database, wireGuard)
+	internal.AssertNoError(err)
+
+	keepaliveManager, err := keepalive.NewKeepaliveManager(cfg, database, wireGuardManager)
 	internal.AssertNoError(err)
 
 	routeManager, err := route.NewRouteManager(cfg, eventBus, database)
 	internal.AssertNoError(err)
 	routeManager.StartBackgroundJobs(ctx)
 
-	webhookManager, err := webhooks.NewManager(cfg, eventBus)
+	shapingManager, err := shaping.NewShapingManager(cfg, eventBus, database)
+	internal.AssertNoError(err)
+	shapingManager.StartBackgroundJobs(ctx)
+
+	dnsRecordManager, err := dnsrecords.NewDnsRecordManager(cfg, eventBus, database, dnsRepo)
+	internal.AssertNoError(err)
+	dnsRecordManager.StartBackgroundJobs(ctx)
+
+	dnsResolverManager, err := resolver.NewDnsResolverManager(cfg, eventBus, database)
+	internal.AssertNoError(err)
+	dnsResolverManager.StartBackgroundJobs(ctx)
+
+	knockManager, err := knock.NewKnockManager(cfg, eventBus, database, adapters.NewNftablesRepo())
+	internal.AssertNoError(err)
+	knockManager.StartBackgroundJobs(ctx)
+
+	isolationManager, err := isolation.NewIsolationManager(cfg, eventBus, database, adapters.NewNftablesRepo())
+	internal.AssertNoError(err)
+	isolationManager.StartBackgroundJobs(ctx)
+
+	portForwardManager, err := portforward.NewPortForwardManager(cfg, eventBus, database, adapters.NewNftablesRepo())
+	internal.AssertNoError(err)
+	portForwardManager.StartBackgroundJobs(ctx)
+
+	packetCaptureManager, err := packetcapture.NewPacketCaptureManager(cfg, eventBus, database,
+		adapters.NewPacketCaptureRepo(cfg.PacketCapture.TcpdumpPath))
+	internal.AssertNoError(err)
+
+	snmpManager, err := snmp.NewSnmpManager(cfg, database)
+	internal.AssertNoError(err)
+	snmpManager.StartBackgroundJobs(ctx)
+
+	flowExportManager, err := flowexport.NewFlowExportManager(cfg, database, adapters.NewConntrackRepo())
+	internal.AssertNoError(err)
+	flowExportManager.StartBackgroundJobs(ctx)
+
+	routeSetManager, err := routesets.NewRouteSetManager(cfg, database, mailManager)
+	internal.AssertNoError(err)
+
+	alertManager, err := alerting.NewAlertManager(cfg, eventBus, database, mailer)
+	internal.AssertNoError(err)
+	alertManager.StartBackgroundJobs(ctx)
+
+	deviceAuthManager, err := deviceauth.NewDeviceAuthManager(cfg, wireGuardManager, cfgFileManager)
+	internal.AssertNoError(err)
+	deviceAuthManager.StartBackgroundJobs(ctx)
+
+	idempotencyManager, err := idempotency.NewIdempotencyManager(database)
+	internal.AssertNoError(err)
+	idempotencyManager.StartBackgroundJobs(ctx)
+
+	configPullManager, err := configpull.NewConfigPullManager(cfg, eventBus, database, wireGuardManager,
+		cfgFileManager, mailManager)
+	internal.AssertNoError(err)
+
+	sshDeploymentManager, err := sshdeploy.NewSshDeploymentManager(cfg, eventBus, database, wireGuardManager,
+		cfgFileManager)
+	internal.AssertNoError(err)
+	sshDeploymentManager.StartBackgroundJobs(ctx)
+
+	peerTransferManager, err := peertransfer.NewPeerTransferManager(cfg, eventBus, database, wireGuardManager,
+		mailManager)
+	internal.AssertNoError(err)
+	peerTransferManager.StartBackgroundJobs(ctx)
+
+	accessRequestManager, err := accessrequests.NewAccessRequestManager(cfg, eventBus, database, wireGuardManager)
+	internal.AssertNoError(err)
+	accessRequestManager.StartBackgroundJobs(ctx)
+
+	routeReviewManager, err := routereview.NewRouteReviewManager(cfg, eventBus, database, wireGuardManager)
+	internal.AssertNoError(err)
+	routeReviewManager.StartBackgroundJobs(ctx)
+
+	statusPageManager, err := statuspage.NewStatusPageManager(cfg, database, wireGuard)
+	internal.AssertNoError(err)
+	statusPageManager.StartBackgroundJobs(ctx)
+
+	failoverManager, err := failover.NewFailoverManager(cfg, database, wireGuard, wireGuardManager,
+		adapters.NewFailoverRepo(cfg.Failover), dnsRepo)
+	internal.AssertNoError(err)
+	failoverManager.StartBackgroundJobs(ctx)
+
+	healthManager, err := health.NewHealthManager(cfg, eventBus, database, wireGuard, adapters.NewSystemdNotifier())
+	internal.AssertNoError(err)
+	healthManager.StartBackgroundJobs(ctx)
+
+	organizationManager, err := organizations.NewOrganizationManager(cfg, database)
+	internal.AssertNoError(err)
+
+	userGroupManager, err := usergroups.NewUserGroupManager(cfg, eventBus, database)
+	internal.AssertNoError(err)
+
+	announcementManager, err := announcements.NewAnnouncementManager(cfg, database, mailManager)
+	internal.AssertNoError(err)
+	announcementManager.StartBackgroundJobs(ctx)
+
+	retentionManager, err := retention.NewRetentionManager(cfg, eventBus, database, wireGuardManager)
+	internal.AssertNoError(err)
+	retentionManager.StartBackgroundJobs(ctx)
+
+	exportManager, err := export.NewExportManager(cfg, wireGuardManager, userManager)
+	internal.AssertNoError(err)
+
+	importManager, err := importer.NewImportManager(cfg, userManager, wireGuardManager, mailManager)
+	internal.AssertNoError(err)
+
+	webhookManager, err := webhooks.NewManager(cfg, eventBus, database)
 	internal.AssertNoError(err)
 	webhookManager.StartBackgroundJobs(ctx)
 
-	err = app.Initialize(cfg, wireGuardManager, userManager)
+	eventStreamManager, err := eventstream.NewEventStreamManager(cfg, eventBus, eventStream)
+	internal.AssertNoError(err)
+	eventStreamManager.StartBackgroundJobs(ctx)
+
+	restoreManager, err := restore.NewRestoreManager(cfg, database, wireGuardManager, routeManager, isolationManager,
+		knockManager, portForwardManager, dnsRecordManager, dnsResolverManager)
+	internal.AssertNoError(err)
+
+	err = app.Initialize(cfg, wireGuardManager, userManager, restoreManager)
 	internal.AssertNoError(err)
 
 	validatorManager := validator.New()
@@ -124,8 +396,9 @@ func main() {
 	apiV0Auth := handlersV0.NewAuthenticationHandler(authenticator, apiV0Session)
 
 	apiV0BackendUsers := backendV0.NewUserService(cfg, userManager, wireGuardManager)
-	apiV0BackendInterfaces := backendV0.NewInterfaceService(cfg, wireGuardManager, cfgFileManager)
-	apiV0BackendPeers := backendV0.NewPeerService(cfg, wireGuardManager, cfgFileManager, mailManager)
+	apiV0BackendInterfaces := backendV0.NewInterfaceService(cfg, wireGuardManager, cfgFileManager, mailManager)
+	apiV0BackendPeers := backendV0.NewPeerService(cfg, wireGuardManager, cfgFileManager, mailManager,
+		cleanupManager, shapingManager, keepaliveManager)
 
 	apiV0EndpointAuth := handlersV0.NewAuthEndpoint(cfg, apiV0Auth, apiV0Session, validatorManager, authenticator,
 		webAuthn)
@@ -133,7 +406,54 @@ func main() {
 	apiV0EndpointUsers := handlersV0.NewUserEndpoint(cfg, apiV0Auth, validatorManager, apiV0BackendUsers)
 	apiV0EndpointInterfaces := handlersV0.NewInterfaceEndpoint(cfg, apiV0Auth, validatorManager, apiV0BackendInterfaces)
 	apiV0EndpointPeers := handlersV0.NewPeerEndpoint(cfg, apiV0Auth, validatorManager, apiV0BackendPeers)
-	apiV0EndpointConfig := handlersV0.NewConfigEndpoint(cfg, apiV0Auth)
+	apiV0EndpointRouteSets := handlersV0.NewRouteSetEndpoint(cfg, apiV0Auth, validatorManager, routeSetManager)
+	apiV0EndpointAlerts := handlersV0.NewAlertEndpoint(cfg, apiV0Auth, validatorManager, alertManager)
+	apiV0EndpointDevice := handlersV0.NewDeviceEndpoint(cfg, apiV0Auth, validatorManager, deviceAuthManager)
+	apiV0EndpointConfigPull := handlersV0.NewConfigPullEndpoint(cfg, apiV0Auth, configPullManager)
+	apiV0EndpointSshDeployment := handlersV0.NewSshDeploymentEndpoint(cfg, apiV0Auth, validatorManager,
+		sshDeploymentManager)
+	apiV0EndpointPeerTransfers := handlersV0.NewPeerTransferEndpoint(cfg, apiV0Auth, validatorManager,
+		peerTransferManager)
+	apiV0EndpointAccessRequests := handlersV0.NewAccessRequestEndpoint(cfg, apiV0Auth, validatorManager,
+		accessRequestManager)
+	apiV0EndpointRouteReviews := handlersV0.NewRouteReviewEndpoint(cfg, apiV0Auth, validatorManager,
+		routeReviewManager)
+	apiV0EndpointPortForwards := handlersV0.NewPortForwardEndpoint(cfg, apiV0Auth, validatorManager,
+		portForwardManager)
+	apiV0EndpointEmergency := handlersV0.NewEmergencyEndpoint(cfg, apiV0Auth, validatorManager,
+		emergencyManager)
+	apiV0EndpointCompromise := handlersV0.NewCompromiseEndpoint(cfg, apiV0Auth, validatorManager,
+		compromiseManager)
+	apiV0EndpointSecurityEvents := handlersV0.NewSecurityEventEndpoint(cfg, apiV0Auth, validatorManager,
+		securityEventManager)
+	apiV0EndpointOrganizations := handlersV0.NewOrganizationEndpoint(cfg, apiV0Auth, validatorManager,
+		organizationManager)
+	apiV0EndpointUserGroups := handlersV0.NewUserGroupEndpoint(cfg, apiV0Auth, validatorManager,
+		userGroupManager)
+	apiV0EndpointAnnouncements := handlersV0.NewAnnouncementEndpoint(cfg, apiV0Auth, validatorManager,
+		announcementManager)
+	apiV0EndpointExportApprovals := handlersV0.NewExportApprovalEndpoint(cfg, apiV0Auth, exportApprovalManager)
+	apiV0EndpointMail := handlersV0.NewMailEndpoint(cfg, apiV0Auth, validatorManager, mailManager)
+	apiV0EndpointWebhooks := handlersV0.NewWebhookEndpoint(cfg, apiV0Auth, validatorManager, webhookManager)
+	apiV0EndpointDataRetention := handlersV0.NewDataRetentionEndpoint(cfg, apiV0Auth, retentionManager)
+	apiV0EndpointExport := handlersV0.NewExportEndpoint(cfg, apiV0Auth, exportManager)
+	apiV0EndpointImport := handlersV0.NewImportEndpoint(cfg, apiV0Auth, validatorManager, importManager)
+	apiV0EndpointConfig := handlersV0.NewConfigEndpoint(cfg, apiV0Auth, organizationManager, reloadManager)
+	apiV0EndpointStatus := handlersV0.NewStatusPageEndpoint(statusPageManager)
+	apiV0EndpointFailover := handlersV0.NewFailoverEndpoint(apiV0Auth, failoverManager)
+	apiV0EndpointHealth := handlersV0.NewHealthEndpoint(healthManager)
+	apiV0EndpointRestore := handlersV0.NewRestoreEndpoint(cfg, apiV0Auth, restoreManager)
+	apiV0EndpointCapacity := handlersV0.NewCapacityEndpoint(apiV0Auth, capacityManager)
+	apiV0EndpointDuplicates := handlersV0.NewDuplicateEndpoint(apiV0Auth, duplicateManager)
+	apiV0EndpointAllowedIPConflicts := handlersV0.NewAllowedIPConflictEndpoint(apiV0Auth, conflictManager)
+	apiV0EndpointAddressReservations := handlersV0.NewAddressReservationEndpoint(apiV0Auth, validatorManager,
+		reservationManager)
+	apiV0EndpointSearch := handlersV0.NewSearchEndpoint(apiV0Auth, searchManager)
+	apiV0EndpointDiagnostics := handlersV0.NewDiagnosticsEndpoint(apiV0Auth, diagnosticsManager)
+	apiV0EndpointPacketCapture := handlersV0.NewPacketCaptureEndpoint(apiV0Auth, packetCaptureManager)
+	apiV0EndpointLiveStats := handlersV0.NewLiveStatsEndpoint(cfg, apiV0Auth, liveStatsManager)
+	apiV0EndpointAcceptableUse := handlersV0.NewAcceptableUseEndpoint(cfg, apiV0Auth, validatorManager,
+		acceptableUseManager)
 	apiV0EndpointTest := handlersV0.NewTestEndpoint(apiV0Auth)
 
 	apiFrontend := handlersV0.NewRestApi(apiV0Session,
@@ -142,7 +462,41 @@ func main() {
 		apiV0EndpointUsers,
 		apiV0EndpointInterfaces,
 		apiV0EndpointPeers,
+		apiV0EndpointRouteSets,
+		apiV0EndpointAlerts,
+		apiV0EndpointDevice,
+		apiV0EndpointConfigPull,
+		apiV0EndpointSshDeployment,
+		apiV0EndpointPeerTransfers,
+		apiV0EndpointAccessRequests,
+		apiV0EndpointRouteReviews,
+		apiV0EndpointPortForwards,
+		apiV0EndpointEmergency,
+		apiV0EndpointCompromise,
+		apiV0EndpointSecurityEvents,
+		apiV0EndpointOrganizations,
+		apiV0EndpointUserGroups,
+		apiV0EndpointAnnouncements,
+		apiV0EndpointExportApprovals,
+		apiV0EndpointMail,
+		apiV0EndpointWebhooks,
+		apiV0EndpointDataRetention,
+		apiV0EndpointExport,
+		apiV0EndpointImport,
 		apiV0EndpointConfig,
+		apiV0EndpointStatus,
+		apiV0EndpointFailover,
+		apiV0EndpointHealth,
+		apiV0EndpointRestore,
+		apiV0EndpointCapacity,
+		apiV0EndpointDuplicates,
+		apiV0EndpointAllowedIPConflicts,
+		apiV0EndpointAddressReservations,
+		apiV0EndpointSearch,
+		apiV0EndpointDiagnostics,
+		apiV0EndpointPacketCapture,
+		apiV0EndpointLiveStats,
+		apiV0EndpointAcceptableUse,
 		apiV0EndpointTest,
 	)
 
@@ -151,18 +505,26 @@ func main() {
 	// region API v1 (User REST API)
 
 	apiV1Auth := handlersV1.NewAuthenticationHandler(userManager)
+	apiV1Idempotency := handlersV1.NewIdempotencyHandler(idempotencyManager)
 	apiV1BackendUsers := backendV1.NewUserService(cfg, userManager)
 	apiV1BackendPeers := backendV1.NewPeerService(cfg, wireGuardManager, userManager)
 	apiV1BackendInterfaces := backendV1.NewInterfaceService(cfg, wireGuardManager)
-	apiV1BackendProvisioning := backendV1.NewProvisioningService(cfg, userManager, wireGuardManager, cfgFileManager)
+	apiV1BackendProvisioning := backendV1.NewProvisioningService(cfg, userManager, wireGuardManager, cfgFileManager,
+		ephemeralPeerManager)
 	apiV1BackendMetrics := backendV1.NewMetricsService(cfg, database, userManager, wireGuardManager)
 
 	apiV1EndpointUsers := handlersV1.NewUserEndpoint(apiV1Auth, validatorManager, apiV1BackendUsers)
-	apiV1EndpointPeers := handlersV1.NewPeerEndpoint(apiV1Auth, validatorManager, apiV1BackendPeers)
-	apiV1EndpointInterfaces := handlersV1.NewInterfaceEndpoint(apiV1Auth, validatorManager, apiV1BackendInterfaces)
-	apiV1EndpointProvisioning := handlersV1.NewProvisioningEndpoint(apiV1Auth, validatorManager,
+	apiV1EndpointPeers := handlersV1.NewPeerEndpoint(apiV1Auth, validatorManager, apiV1Idempotency,
+		apiV1BackendPeers)
+	apiV1EndpointInterfaces := handlersV1.NewInterfaceEndpoint(apiV1Auth, validatorManager, apiV1Idempotency,
+		apiV1BackendInterfaces)
+	apiV1EndpointProvisioning := handlersV1.NewProvisioningEndpoint(apiV1Auth, validatorManager, apiV1Idempotency,
 		apiV1BackendProvisioning)
 	apiV1EndpointMetrics := handlersV1.NewMetricsEndpoint(apiV1Auth, validatorManager, apiV1BackendMetrics)
+	apiV1EndpointDevice := handlersV1.NewDeviceEndpoint(cfg, deviceAuthManager)
+	apiV1EndpointConfigPull := handlersV1.NewConfigPullEndpoint(cfg, apiV1Auth, configPullManager)
+	apiV1EndpointGraphQL := handlersV1.NewGraphQLEndpoint(cfg, apiV1Auth, apiV1BackendUsers, apiV1BackendPeers,
+		apiV1BackendInterfaces, apiV1BackendMetrics)
 
 	apiV1 := handlersV1.NewRestApi(
 		apiV1EndpointUsers,
@@ -170,6 +532,9 @@ func main() {
 		apiV1EndpointInterfaces,
 		apiV1EndpointProvisioning,
 		apiV1EndpointMetrics,
+		apiV1EndpointDevice,
+		apiV1EndpointConfigPull,
+		apiV1EndpointGraphQL,
 	)
 
 	// endregion API v1 (User REST API)
@@ -181,6 +546,7 @@ func main() {
 	go webSrv.Run(ctx, cfg.Web.ListeningAddress)
 
 	slog.Info("Application startup complete")
+	healthManager.NotifyReady()
 
 	// wait until context gets cancelled
 	<-ctx.Done()
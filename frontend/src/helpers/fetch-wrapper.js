@@ -85,6 +85,10 @@ function handleResponse(response) {
                 // auto logout if 401 Unauthorized or 403 Forbidden response returned from api
                 auth.Logout();
             }
+            if (response.status === 428 && auth.IsAuthenticated) {
+                // the request, for example to reveal a private key, requires a re-authentication
+                auth.RequireStepUp();
+            }
 
             const error = (data && data.Message) || response.statusText;
             return Promise.reject(error);
@@ -5,6 +5,7 @@ export function freshInterface() {
     DisplayName: "",
     Identifier: "",
     Mode: "server",
+    Organization: "",
 
     PublicKey: "",
     PrivateKey: "",
@@ -31,16 +32,28 @@ export function freshInterface() {
     PeerDefDns: [],
     PeerDefDnsSearch: [],
     PeerDefEndpoint: "",
+    PeerDefBackupEndpoints: [],
     PeerDefAllowedIPs: [],
+    PeerDefRouteSets: [],
     PeerDefMtu: 0,
     PeerDefPersistentKeepalive: 0,
     PeerDefFirewallMark: 0,
     PeerDefRoutingTable: "",
+    PeerDefUploadLimit: 0,
+    PeerDefDownloadLimit: 0,
     PeerDefPreUp: "",
     PeerDefPostUp: "",
     PeerDefPreDown: "",
     PeerDefPostDown: "",
 
+    PeerMailCc: [],
+    PeerMailBcc: [],
+
+    ClientIsolation: false,
+    ClientIsolationAllowed: [],
+
+    PeerRouteAllowlist: [],
+
     TotalPeers: 0,
     EnabledPeers: 0,
     Filename: ""
@@ -56,6 +69,14 @@ export function freshPeer() {
     Disabled: false,
     ExpiresAt: null,
     Notes: "",
+    MailRecipients: [],
+    AccessSchedule: "",
+    AccessTimezone: "",
+    SharedUsers: [],
+    EndpointPin: [],
+    OperatingSystem: "",
+    DeviceType: "",
+    KeysRotatedAt: null,
 
     Endpoint: {
       Value: "",
@@ -70,11 +91,23 @@ export function freshPeer() {
       Overridable: true,
     },
     ExtraAllowedIPs: [],
+    RouteSets: {
+      Value: [],
+      Overridable: true,
+    },
     PresharedKey: "",
     PersistentKeepalive: {
       Value: 0,
       Overridable: true,
     },
+    UploadLimit: {
+      Value: 0,
+      Overridable: true,
+    },
+    DownloadLimit: {
+      Value: 0,
+      Overridable: true,
+    },
 
     PrivateKey: "",
     PublicKey: "",
@@ -120,6 +153,7 @@ export function freshPeer() {
       Value: "",
       Overridable: true,
     },
+    KillSwitch: "",
 
     Filename: "",
 
@@ -135,7 +169,11 @@ export function freshUser() {
 
     Email: "",
     Source: "db",
+    Type: "human",
     IsAdmin: false,
+    IsHelpdesk: false,
+    AdminInterfaces: [],
+    Organization: "",
 
     Firstname: "",
     Lastname: "",
@@ -164,10 +202,68 @@ export function freshStats() {
     IsConnected: false,
     IsPingable: false,
     LastHandshake: null,
+    NeverConnected: false,
     LastPing: null,
     LastSessionStart: null,
     BytesTransmitted: 0,
     BytesReceived: 0,
     EndpointAddress: ""
   }
-}
\ No newline at end of file
+}
+
+export function freshShapingStats() {
+  return {
+    UploadLimit: 0,
+    DownloadLimit: 0,
+    UploadBytes: 0,
+    UploadPackets: 0,
+    UploadDropped: 0,
+    DownloadBytes: 0,
+    DownloadPackets: 0,
+    DownloadDropped: 0,
+    DownloadOverlimits: 0
+  }
+}
+export function freshOrganization() {
+  return {
+    Identifier: "",
+    DisplayName: "",
+    SiteTitle: "",
+    CompanyName: "",
+  }
+}
+
+export function freshUserGroup() {
+  return {
+    Identifier: "",
+    DisplayName: "",
+    Description: "",
+    Members: [],
+    AdminInterfaces: [],
+    PeerRouteSets: [],
+  }
+}
+
+export function freshAnnouncement() {
+  return {
+    Id: 0,
+    Kind: "info",
+    Title: "",
+    Message: "",
+    StartsAt: "",
+    EndsAt: "",
+    TargetGroups: [],
+    SendMail: false,
+  }
+}
+
+export function freshRouteSet() {
+  return {
+    Identifier: "",
+    DisplayName: "",
+    Description: "",
+    Networks: [],
+
+    PeerCount: 0
+  }
+}
@@ -18,3 +18,15 @@ export function humanFileSize(size) {
   const i = parseInt(Math.floor(Math.log(size) / Math.log(1024)))
   return Math.round(size / Math.pow(1024, i), 2) + sizes[i]
 }
+
+// deviceTypeIcon returns the font awesome icon of the given peer device type
+export function deviceTypeIcon(deviceType) {
+  switch (deviceType) {
+    case "laptop": return "fa-solid fa-laptop"
+    case "phone": return "fa-solid fa-mobile-screen"
+    case "server": return "fa-solid fa-server"
+    case "router": return "fa-solid fa-network-wired"
+    case "iot": return "fa-solid fa-microchip"
+    default: return ""
+  }
+}
@@ -23,4 +23,11 @@ export function validateDomain() {
     rule: tag => tag.text.length < 3,
     disableAdd: true,
   }]
-}
\ No newline at end of file
+}
+export function validateEmail() {
+  return [{
+    classes: 'invalid-email',
+    rule: ({ text }) => !/^[^\s@]+@[^\s@]+$/.test(text),
+    disableAdd: true,
+  }]
+}
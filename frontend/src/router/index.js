@@ -65,6 +65,78 @@ const router = createRouter({
       // which is lazy-loaded when the route is visited.
       component: () => import('../views/AuditView.vue')
     },
+    {
+      path: '/route-sets',
+      name: 'route-sets',
+      // route level code-splitting
+      // this generates a separate chunk (About.[hash].js) for this route
+      // which is lazy-loaded when the route is visited.
+      component: () => import('../views/RouteSetView.vue')
+    },
+    {
+      path: '/alerts',
+      name: 'alerts',
+      // route level code-splitting
+      // this generates a separate chunk (About.[hash].js) for this route
+      // which is lazy-loaded when the route is visited.
+      component: () => import('../views/AlertView.vue')
+    },
+    {
+      path: '/security-events',
+      name: 'security-events',
+      // route level code-splitting
+      // this generates a separate chunk (About.[hash].js) for this route
+      // which is lazy-loaded when the route is visited.
+      component: () => import('../views/SecurityEventView.vue')
+    },
+    {
+      path: '/webhooks',
+      name: 'webhooks',
+      // route level code-splitting
+      // this generates a separate chunk (About.[hash].js) for this route
+      // which is lazy-loaded when the route is visited.
+      component: () => import('../views/WebhookView.vue')
+    },
+    {
+      path: '/organizations',
+      name: 'organizations',
+      // route level code-splitting
+      // this generates a separate chunk (About.[hash].js) for this route
+      // which is lazy-loaded when the route is visited.
+      component: () => import('../views/OrganizationView.vue')
+    },
+    {
+      path: '/user-groups',
+      name: 'user-groups',
+      // route level code-splitting
+      // this generates a separate chunk (About.[hash].js) for this route
+      // which is lazy-loaded when the route is visited.
+      component: () => import('../views/UserGroupView.vue')
+    },
+    {
+      path: '/announcements',
+      name: 'announcements',
+      // route level code-splitting
+      // this generates a separate chunk (About.[hash].js) for this route
+      // which is lazy-loaded when the route is visited.
+      component: () => import('../views/AnnouncementView.vue')
+    },
+    {
+      path: '/export-approvals',
+      name: 'export-approvals',
+      // route level code-splitting
+      // this generates a separate chunk (About.[hash].js) for this route
+      // which is lazy-loaded when the route is visited.
+      component: () => import('../views/ExportApprovalView.vue')
+    },
+    {
+      path: '/device',
+      name: 'device',
+      // route level code-splitting
+      // this generates a separate chunk (About.[hash].js) for this route
+      // which is lazy-loaded when the route is visited.
+      component: () => import('../views/DeviceView.vue')
+    },
     {
       path: '/key-generator',
       name: 'key-generator',
@@ -72,6 +144,14 @@ const router = createRouter({
       // this generates a separate chunk (About.[hash].js) for this route
       // which is lazy-loaded when the route is visited.
       component: () => import('../views/KeyGeneraterView.vue')
+    },
+    {
+      path: '/status',
+      name: 'status',
+      // route level code-splitting
+      // this generates a separate chunk (About.[hash].js) for this route
+      // which is lazy-loaded when the route is visited.
+      component: () => import('../views/StatusView.vue')
     }
   ],
   linkActiveClass: "active",
@@ -122,7 +202,7 @@ router.beforeEach(async (to) => {
   }
 
   // redirect to login page if not logged in and trying to access a restricted page
-  const publicPages = ['/', '/login', '/key-generator']
+  const publicPages = ['/', '/login', '/key-generator', '/status']
   const authRequired = !publicPages.includes(to.path)
 
   if (authRequired && !auth.IsAuthenticated) {
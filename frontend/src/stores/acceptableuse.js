@@ -0,0 +1,41 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+
+const baseUrl = `/acceptable-use`
+
+export const acceptableUseStore = defineStore('acceptableuse', {
+  state: () => ({
+    status: null,
+    fetching: false,
+  }),
+  getters: {
+    Status: (state) => state.status,
+    NeedsAcceptance: (state) => state.status !== null && !state.status.Accepted,
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setStatus(status) {
+      this.status = status
+      this.fetching = false
+    },
+    async LoadStatus() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/my`)
+        .then(this.setStatus)
+        .catch(error => {
+          this.setStatus(null)
+          console.log("Failed to load acceptable use policy: ", error)
+        })
+    },
+    async Accept() {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/my/accept`, { Version: this.status.Version })
+        .then(() => this.LoadStatus())
+        .catch(error => {
+          this.fetching = false
+          console.log("Failed to accept acceptable use policy: ", error)
+          throw new Error(error)
+        })
+    },
+  }
+})
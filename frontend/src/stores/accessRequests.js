@@ -0,0 +1,84 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/access-request`
+
+export const accessRequestStore = defineStore('accessRequests', {
+  state: () => ({
+    requests: [],
+    routeSets: [],
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.requests.length,
+    All: (state) => state.requests,
+    Current: (state) => state.requests.filter((r) => r.State === 'pending' || r.State === 'active'),
+    FindCurrentForPeer: (state) => {
+      return (peerId) => state.requests.filter((r) => r.PeerId === peerId &&
+        (r.State === 'pending' || r.State === 'active'))
+    },
+    RouteSets: (state) => state.routeSets,
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setRequests(requests) {
+      this.requests = requests
+      this.fetching = false
+    },
+    setRouteSets(routeSets) {
+      this.routeSets = routeSets
+    },
+    updateRequest(request) {
+      let idx = this.requests.findIndex((r) => r.Identifier === request.Identifier)
+      if (idx === -1) {
+        this.requests.unshift(request)
+      } else {
+        this.requests[idx] = request
+      }
+      this.fetching = false
+    },
+    async LoadRequests() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/all`)
+        .then(this.setRequests)
+        .catch(error => {
+          this.setRequests([])
+          console.log("Failed to load access requests: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load access requests!",
+          })
+        })
+    },
+    async LoadRouteSets() {
+      return apiWrapper.get(`${baseUrl}/route-sets`)
+        .then(this.setRouteSets)
+        .catch(error => {
+          this.setRouteSets([])
+          console.log("Failed to load requestable route sets: ", error)
+        })
+    },
+    async RequestAccess(peerId, routeSetId, duration, reason) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/new`,
+        { PeerId: peerId, RouteSetId: routeSetId, Duration: duration, Reason: reason })
+        .then(this.updateRequest)
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async RequestAction(id, action) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/by-id/${encodeURIComponent(id)}/${action}`)
+        .then(this.updateRequest)
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
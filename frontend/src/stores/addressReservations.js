@@ -0,0 +1,67 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+import { base64_url_encode } from '@/helpers/encoding';
+
+const baseUrl = `/address-reservation`
+
+export const addressReservationStore = defineStore('addressReservations', {
+  state: () => ({
+    reservations: [],
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.reservations.length,
+    All: (state) => state.reservations,
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setReservations(reservations) {
+      this.reservations = reservations
+      this.fetching = false
+    },
+    async LoadReservations(interfaceId) {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/by-interface/${base64_url_encode(interfaceId)}`)
+        .then(this.setReservations)
+        .catch(error => {
+          this.setReservations([])
+          console.log("Failed to load address reservations: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load address reservations!",
+          })
+        })
+    },
+    async CreateReservation(interfaceId, reservation) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/by-interface/${base64_url_encode(interfaceId)}`, reservation)
+        .then(() => this.LoadReservations(interfaceId))
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async UpdateReservation(interfaceId, id, reservation) {
+      this.fetching = true
+      return apiWrapper.put(`${baseUrl}/by-id/${encodeURIComponent(id)}`, reservation)
+        .then(() => this.LoadReservations(interfaceId))
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async DeleteReservation(interfaceId, id) {
+      this.fetching = true
+      return apiWrapper.delete(`${baseUrl}/by-id/${encodeURIComponent(id)}`)
+        .then(() => this.LoadReservations(interfaceId))
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
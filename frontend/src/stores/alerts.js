@@ -0,0 +1,82 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+import { base64_url_encode } from '@/helpers/encoding';
+
+const baseUrl = `/alert`
+
+export const alertStore = defineStore('alerts', {
+  state: () => ({
+    rules: [],
+    alerts: [],
+    silences: [],
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.alerts.length,
+    All: (state) => state.alerts,
+    Rules: (state) => state.rules,
+    Silences: (state) => state.silences,
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setRules(rules) {
+      this.rules = rules
+    },
+    setAlerts(alerts) {
+      this.alerts = alerts
+      this.fetching = false
+    },
+    setSilences(silences) {
+      this.silences = silences
+    },
+    async LoadAll() {
+      this.fetching = true
+      return Promise.all([
+        apiWrapper.get(`${baseUrl}/rules`).then(this.setRules),
+        apiWrapper.get(`${baseUrl}/all`).then(this.setAlerts),
+        apiWrapper.get(`${baseUrl}/silences`).then(this.setSilences),
+      ]).catch(error => {
+        this.setAlerts([])
+        console.log("Failed to load alerts: ", error)
+        notify({
+          title: "Backend Connection Failure",
+          text: "Failed to load alerts!",
+        })
+      })
+    },
+    async Acknowledge(ruleName, peerId) {
+      return apiWrapper.post(`${baseUrl}/ack/${encodeURIComponent(ruleName)}/${base64_url_encode(peerId)}`)
+        .then(alert => {
+          let idx = this.alerts.findIndex((a) => a.RuleName === ruleName && a.PeerIdentifier === peerId)
+          if (idx >= 0) {
+            this.alerts[idx] = alert
+          }
+        })
+        .catch(error => {
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async CreateSilence(formData) {
+      return apiWrapper.post(`${baseUrl}/silence/new`, formData)
+        .then(silence => {
+          this.silences.push(silence)
+        })
+        .catch(error => {
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async DeleteSilence(id) {
+      return apiWrapper.delete(`${baseUrl}/silence/${id}`)
+        .then(() => {
+          this.silences = this.silences.filter(s => s.Id !== id)
+        })
+        .catch(error => {
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
@@ -0,0 +1,104 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/announcement`
+
+export const announcementStore = defineStore('announcements', {
+  state: () => ({
+    announcements: [], // all announcements, only loaded for administrators
+    active: [], // the active announcements of the current user
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.announcements.length,
+    All: (state) => state.announcements,
+    Find: (state) => {
+      return (id) => state.announcements.find((a) => a.Id === id)
+    },
+    Unread: (state) => state.active.filter((a) => !a.Read),
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setAnnouncements(announcements) {
+      this.announcements = announcements
+      this.fetching = false
+    },
+    async LoadAnnouncements() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/all`)
+        .then(this.setAnnouncements)
+        .catch(error => {
+          this.setAnnouncements([])
+          console.log("Failed to load announcements: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load announcements!",
+          })
+        })
+    },
+    async LoadActive() {
+      return apiWrapper.get(`${baseUrl}/active`)
+        .then(announcements => {
+          this.active = announcements
+        })
+        .catch(error => {
+          this.active = []
+          console.log("Failed to load active announcements: ", error)
+        })
+    },
+    async MarkRead(id) {
+      return apiWrapper.post(`${baseUrl}/by-id/${id}/read`)
+        .then(() => {
+          let idx = this.active.findIndex((a) => a.Id === id)
+          if (idx >= 0) {
+            this.active[idx].Read = true
+          }
+        })
+        .catch(error => {
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async DeleteAnnouncement(id) {
+      this.fetching = true
+      return apiWrapper.delete(`${baseUrl}/by-id/${id}`)
+        .then(() => {
+          this.announcements = this.announcements.filter(a => a.Id !== id)
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async UpdateAnnouncement(id, formData) {
+      this.fetching = true
+      return apiWrapper.put(`${baseUrl}/by-id/${id}`, formData)
+        .then(announcement => {
+          let idx = this.announcements.findIndex((a) => a.Id === id)
+          this.announcements[idx] = {...this.announcements[idx], ...announcement, ReadCount: this.announcements[idx].ReadCount}
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async CreateAnnouncement(formData) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/new`, formData)
+        .then(announcement => {
+          this.announcements.unshift(announcement)
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
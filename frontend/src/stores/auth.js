@@ -14,6 +14,8 @@ export const authStore = defineStore('auth',{
         returnUrl: localStorage.getItem('returnUrl'),
         webAuthnCredentials: [],
         fetching: false,
+        stepUpRequired: false, // set if the backend requires a re-authentication, for example to reveal private keys
+        reAuthenticatedAt: null,
     }),
     getters: {
         UserIdentifier: (state) => state.user?.Identifier || 'unknown',
@@ -21,6 +23,15 @@ export const authStore = defineStore('auth',{
         LoginProviders: (state) => state.providers,
         IsAuthenticated: (state) => state.user != null,
         IsAdmin: (state) => state.user?.IsAdmin || false,
+        // helpdesk users support other users without full admin rights, admins have all helpdesk rights
+        IsHelpdesk: (state) => state.user?.IsAdmin || state.user?.IsHelpdesk || false,
+        // admins of an organization only manage the resources of their own organization
+        IsGlobalAdmin: (state) => (state.user?.IsAdmin && !state.user?.Organization) || false,
+        // interface admins manage the peers of specific interfaces without global admin rights
+        HasAdminInterfaces: (state) => state.user?.IsAdmin || state.user?.AdminInterfaces?.length > 0 || false,
+        IsInterfaceAdmin: (state) => {
+            return (id) => state.user?.IsAdmin || state.user?.AdminInterfaces?.includes(id) || false
+        },
         ReturnUrl: (state) => state.returnUrl || '/',
         IsWebAuthnEnabled: (state) => {
             if (state.webAuthnCredentials) {
@@ -29,6 +40,8 @@ export const authStore = defineStore('auth',{
             return false
         },
         WebAuthnCredentials: (state) => state.webAuthnCredentials || [],
+        StepUpRequired: (state) => state.stepUpRequired,
+        ReAuthenticatedAt: (state) => state.reAuthenticatedAt,
         isFetching: (state) => state.fetching,
     },
     actions: {
@@ -103,6 +116,36 @@ export const authStore = defineStore('auth',{
                     return Promise.reject(new Error("login failed"))
                 })
         },
+        RequireStepUp() {
+            this.stepUpRequired = true
+        },
+        CancelStepUp() {
+            this.stepUpRequired = false
+        },
+        // StepUp returns promise that might have been rejected if the re-authentication was not successful.
+        async StepUp(password) {
+            return apiWrapper.post(`/auth/step-up`, { password })
+                .then(() => this.setReAuthenticated())
+                .catch(err => {
+                    console.log("Re-authentication failed:", err)
+                    return Promise.reject(new Error("re-authentication failed"))
+                })
+        },
+        // StepUpWebAuthn returns promise that might have been rejected if the re-authentication was not successful.
+        async StepUpWebAuthn() {
+            if (!browserSupportsWebAuthn()) {
+                return Promise.reject(new Error("WebAuthn not supported"));
+            }
+
+            return apiWrapper.post(`/auth/step-up/webauthn/start`, {})
+                .then(optionsJSON => startAuthentication({ optionsJSON: optionsJSON.publicKey }))
+                .then(asseResp => apiWrapper.post(`/auth/step-up/webauthn/finish`, asseResp))
+                .then(() => this.setReAuthenticated())
+                .catch(err => {
+                    console.error("Failed to re-authenticate with passkey:", err)
+                    return Promise.reject(new Error("re-authentication failed"))
+                })
+        },
         async Logout() {
             this.setUserInfo(null)
             this.ResetReturnUrl() // just to be sure^^
@@ -274,6 +317,10 @@ export const authStore = defineStore('auth',{
                 })
         },
         // -- internal setters
+        setReAuthenticated() {
+            this.stepUpRequired = false
+            this.reAuthenticatedAt = new Date()
+        },
         setUserInfo(userInfo) {
             // store user details and jwt in local storage to keep user logged in between page refreshes
             if (userInfo) {
@@ -283,7 +330,10 @@ export const authStore = defineStore('auth',{
                         Firstname: userInfo['UserFirstname'],
                         Lastname: userInfo['UserLastname'],
                         Email: userInfo['UserEmail'],
-                        IsAdmin: userInfo['IsAdmin']
+                        IsAdmin: userInfo['IsAdmin'],
+                        IsHelpdesk: userInfo['IsHelpdesk'] || false,
+                        AdminInterfaces: userInfo['AdminInterfaces'] || [],
+                        Organization: userInfo['Organization'] || ''
                     }
                 } else { // user object
                     this.user = {
@@ -291,7 +341,10 @@ export const authStore = defineStore('auth',{
                         Firstname: userInfo['Firstname'],
                         Lastname: userInfo['Lastname'],
                         Email: userInfo['Email'],
-                        IsAdmin: userInfo['IsAdmin']
+                        IsAdmin: userInfo['IsAdmin'],
+                        IsHelpdesk: userInfo['IsHelpdesk'] || false,
+                        AdminInterfaces: userInfo['AdminInterfaces'] || [],
+                        Organization: userInfo['Organization'] || ''
                     }
                 }
                 localStorage.setItem('user', JSON.stringify(this.user))
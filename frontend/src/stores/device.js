@@ -0,0 +1,49 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+
+const baseUrl = `/device`
+
+export const deviceStore = defineStore('device', {
+  state: () => ({
+    request: null,
+    fetching: false,
+  }),
+  getters: {
+    Request: (state) => state.request,
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setRequest(request) {
+      this.request = request
+      this.fetching = false
+    },
+    async LoadRequest(code) {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/${encodeURIComponent(code)}`)
+        .then(this.setRequest)
+        .catch(error => {
+          this.setRequest(null)
+          console.log("Failed to load device request: ", error)
+          throw new Error(error)
+        })
+    },
+    async Approve(code, formData) {
+      return apiWrapper.post(`${baseUrl}/${encodeURIComponent(code)}/approve`, formData)
+        .then(this.setRequest)
+        .catch(error => {
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async Deny(code) {
+      return apiWrapper.post(`${baseUrl}/${encodeURIComponent(code)}/deny`)
+        .then(() => {
+          this.request = null
+        })
+        .catch(error => {
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
@@ -0,0 +1,69 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/emergency`
+
+export const emergencyStore = defineStore('emergency', {
+  state: () => ({
+    lockdowns: [],
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.lockdowns.length,
+    All: (state) => state.lockdowns,
+    Active: (state) => {
+      return (target, targetId) => state.lockdowns.filter((l) => l.Active && l.Target === target &&
+        (!targetId || l.TargetId === targetId))
+    },
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setLockdowns(lockdowns) {
+      this.lockdowns = lockdowns
+      this.fetching = false
+    },
+    updateLockdown(lockdown) {
+      let idx = this.lockdowns.findIndex((l) => l.Identifier === lockdown.Identifier)
+      if (idx === -1) {
+        this.lockdowns.unshift(lockdown)
+      } else {
+        this.lockdowns[idx] = lockdown
+      }
+      this.fetching = false
+    },
+    async LoadLockdowns() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/all`)
+        .then(this.setLockdowns)
+        .catch(error => {
+          this.setLockdowns([])
+          console.log("Failed to load emergency lockdowns: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load emergency lockdowns!",
+          })
+        })
+    },
+    async Lockdown(target, targetId, reason) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/new`, { Target: target, TargetId: targetId, Reason: reason })
+        .then(this.updateLockdown)
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async Restore(id) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/by-id/${encodeURIComponent(id)}/restore`)
+        .then(this.updateLockdown)
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
@@ -0,0 +1,64 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/export-approval`
+
+export const exportApprovalStore = defineStore('exportApprovals', {
+  state: () => ({
+    approvals: [],
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.approvals.length,
+    All: (state) => state.approvals,
+    Pending: (state) => state.approvals.filter((a) => a.State === 'pending'),
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setApprovals(approvals) {
+      this.approvals = approvals
+      this.fetching = false
+    },
+    setApproval(approval) {
+      let idx = this.approvals.findIndex((a) => a.Id === approval.Id)
+      if (idx >= 0) {
+        this.approvals[idx] = approval
+      }
+      this.fetching = false
+    },
+    async LoadApprovals() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/all`)
+        .then(this.setApprovals)
+        .catch(error => {
+          this.setApprovals([])
+          console.log("Failed to load export approvals: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load export approvals!",
+          })
+        })
+    },
+    async Approve(id) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/by-id/${id}/approve`)
+        .then(this.setApproval)
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async Reject(id) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/by-id/${id}/reject`)
+        .then(this.setApproval)
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
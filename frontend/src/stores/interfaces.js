@@ -12,6 +12,9 @@ export const interfaceStore = defineStore('interfaces', {
     interfaces: [],
     prepared: freshInterface(),
     configuration: "",
+    capacity: [],
+    duplicates: [],
+    conflicts: null, // the allowed IP conflict report of the selected interface
     selected: "",
     fetching: false,
   }),
@@ -22,7 +25,14 @@ export const interfaceStore = defineStore('interfaces', {
     Find: (state) => {
         return (id) => state.interfaces.find((p) => p.Identifier === id)
     },
+    Capacity: (state) => state.capacity,
+    Duplicates: (state) => state.duplicates,
+    Conflicts: (state) => state.conflicts ? state.conflicts.Conflicts : [],
+    ConflictsCheckedAt: (state) => state.conflicts ? state.conflicts.CheckedAt : null,
     GetSelected: (state) => state.interfaces.find((i) => i.Identifier === state.selected) || state.interfaces[0],
+    PeerConfigsUrl: () => {
+      return (id) => apiWrapper.url(`${baseUrl}/${base64_url_encode(id)}/peer-configs`)
+    },
     isFetching: (state) => state.fetching,
   },
   actions: {
@@ -78,6 +88,90 @@ export const interfaceStore = defineStore('interfaces', {
           })
         })
     },
+    async LoadCapacity(id) {
+      // if no id is given, use the currently selected interface
+      if (!id) {
+        id = this.GetSelected ? this.GetSelected.Identifier : ""
+        if (!id) {
+          this.capacity = []
+          return // no interface, nothing to load
+        }
+      }
+
+      return apiWrapper.get(`/capacity/by-interface/${base64_url_encode(id)}`)
+        .then(pools => this.capacity = pools)
+        .catch(error => {
+          this.capacity = []
+          console.log("Failed to load address pool capacity: ", error)
+        })
+    },
+    async LoadDuplicates(id) {
+      // if no id is given, use the currently selected interface
+      if (!id) {
+        id = this.GetSelected ? this.GetSelected.Identifier : ""
+        if (!id) {
+          this.duplicates = []
+          return // no interface, nothing to load
+        }
+      }
+
+      return apiWrapper.get(`/duplicates/by-interface/${base64_url_encode(id)}`)
+        .then(groups => this.duplicates = groups)
+        .catch(error => {
+          this.duplicates = []
+          console.log("Failed to load duplicate peers: ", error)
+        })
+    },
+    async LoadConflicts(id) {
+      // if no id is given, use the currently selected interface
+      if (!id) {
+        id = this.GetSelected ? this.GetSelected.Identifier : ""
+        if (!id) {
+          this.conflicts = null
+          return // no interface, nothing to load
+        }
+      }
+
+      return apiWrapper.get(`/allowed-ip-conflicts/by-interface/${base64_url_encode(id)}`)
+        .then(report => this.conflicts = report)
+        .catch(error => {
+          this.conflicts = null
+          console.log("Failed to load allowed IP conflicts: ", error)
+        })
+    },
+    async CheckConflicts() {
+      const id = this.GetSelected ? this.GetSelected.Identifier : ""
+      if (!id) {
+        return // no interface, nothing to check
+      }
+
+      this.fetching = true
+      return apiWrapper.post(`/allowed-ip-conflicts/by-interface/${base64_url_encode(id)}/check`)
+        .then(report => {
+          this.conflicts = report
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async MergeDuplicates(keep, archive) {
+      this.fetching = true
+      return apiWrapper.post(`/duplicates/merge`, {
+        Keep: keep,
+        Archive: archive
+      })
+        .then(() => {
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
     async DeleteInterface(id) {
       this.fetching = true
       return apiWrapper.delete(`${baseUrl}/${base64_url_encode(id)}`)
@@ -135,6 +229,64 @@ export const interfaceStore = defineStore('interfaces', {
           throw new Error(error)
         })
     },
+    async MailPeerConfigs(id, linkOnly) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/${base64_url_encode(id)}/mail-peers`, {
+        LinkOnly: linkOnly
+      })
+        .then(() => {
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async SuggestMtu(id) {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/${base64_url_encode(id)}/mtu-suggestion`)
+        .then((suggestion) => {
+          this.fetching = false
+          return suggestion
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async PrepareRenumbering(id, from, to) {
+      this.fetching = true
+      const params = new URLSearchParams({from: from, to: to || ""})
+      return apiWrapper.get(`${baseUrl}/${base64_url_encode(id)}/renumbering?${params.toString()}`)
+        .then((renumbering) => {
+          this.fetching = false
+          return renumbering
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async RenumberInterface(id, from, to, sendMails) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/${base64_url_encode(id)}/renumbering`, {
+        From: from,
+        To: to,
+        SendMails: sendMails
+      })
+        .then((renumbering) => {
+          this.fetching = false
+          return renumbering
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
     async SaveConfiguration(id) {
       this.fetching = true
       return apiWrapper.post(`${baseUrl}/${base64_url_encode(id)}/save-config`)
@@ -0,0 +1,91 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/mail`
+
+export const mailStore = defineStore('mail', {
+  state: () => ({
+    previews: [],
+    templates: [],
+    lintResult: null,
+    campaignReport: null,
+    fetching: false,
+  }),
+  getters: {
+    Previews: (state) => state.previews,
+    Templates: (state) => state.templates,
+    LintResult: (state) => state.lintResult,
+    CampaignReport: (state) => state.campaignReport,
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setPreviews(previews) {
+      this.previews = previews
+      this.fetching = false
+    },
+    async LoadPreviews(organization) {
+      this.fetching = true
+      let query = organization ? `?organization=${encodeURIComponent(organization)}` : ''
+      return apiWrapper.get(`${baseUrl}/preview${query}`)
+        .then(this.setPreviews)
+        .catch(error => {
+          this.setPreviews([])
+          console.log("Failed to load mail previews: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to render mail templates!",
+            type: 'error',
+          })
+        })
+    },
+    async LoadTemplates() {
+      return apiWrapper.get(`${baseUrl}/templates`)
+        .then(templates => this.templates = templates)
+        .catch(error => {
+          this.templates = []
+          console.log("Failed to load mail templates: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load mail templates!",
+            type: 'error',
+          })
+        })
+    },
+    async LintTemplate(template, format, content) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/templates/lint`, { Template: template, Format: format, Content: content })
+        .then(result => {
+          this.lintResult = result
+          this.fetching = false
+        })
+        .catch(error => {
+          this.lintResult = null
+          this.fetching = false
+          console.log("Failed to validate mail template: ", error)
+          notify({
+            title: "Failed to validate mail template!",
+            text: error,
+            type: 'error',
+          })
+        })
+    },
+    async RunClientUpdateCampaign(minVersions, dryRun) {
+      this.fetching = true
+      return apiWrapper.post(`/config-pull/update-campaign`, { MinVersions: minVersions, DryRun: dryRun })
+        .then(report => {
+          this.campaignReport = report
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log("Failed to run client update campaign: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to run the client update campaign!",
+            type: 'error',
+          })
+        })
+    },
+  }
+})
@@ -0,0 +1,79 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/organization`
+
+export const organizationStore = defineStore('organizations', {
+  state: () => ({
+    organizations: [],
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.organizations.length,
+    All: (state) => state.organizations,
+    Find: (state) => {
+      return (id) => state.organizations.find((o) => o.Identifier === id)
+    },
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setOrganizations(organizations) {
+      this.organizations = organizations
+      this.fetching = false
+    },
+    async LoadOrganizations() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/all`)
+        .then(this.setOrganizations)
+        .catch(error => {
+          this.setOrganizations([])
+          console.log("Failed to load organizations: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load organizations!",
+          })
+        })
+    },
+    async DeleteOrganization(id) {
+      this.fetching = true
+      return apiWrapper.delete(`${baseUrl}/by-id/${encodeURIComponent(id)}`)
+        .then(() => {
+          this.organizations = this.organizations.filter(o => o.Identifier !== id)
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async UpdateOrganization(id, formData) {
+      this.fetching = true
+      return apiWrapper.put(`${baseUrl}/by-id/${encodeURIComponent(id)}`, formData)
+        .then(organization => {
+          let idx = this.organizations.findIndex((o) => o.Identifier === id)
+          this.organizations[idx] = organization
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async CreateOrganization(formData) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/new`, formData)
+        .then(organization => {
+          this.organizations.push(organization)
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
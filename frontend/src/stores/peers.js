@@ -2,7 +2,8 @@ import { defineStore } from 'pinia'
 import {apiWrapper} from "@/helpers/fetch-wrapper";
 import {notify} from "@kyvg/vue3-notification";
 import {interfaceStore} from "./interfaces";
-import {freshPeer, freshStats} from '@/helpers/models';
+import {authStore} from "./auth";
+import {freshPeer, freshShapingStats, freshStats} from '@/helpers/models';
 import { base64_url_encode } from '@/helpers/encoding';
 import { ipToBigInt } from '@/helpers/utils';
 
@@ -13,16 +14,24 @@ export const peerStore = defineStore('peers', {
     peers: [],
     stats: {},
     statsEnabled: false,
+    shapingStats: {},
+    shapingEnabled: false,
+    keepaliveRecommendations: {},
     peer: freshPeer(),
     prepared: freshPeer(),
     configuration: "",
+    configPullToken: {},
+    sshDeployment: {},
+    sshHosts: [],
     filter: "",
+    deviceTypeFilter: "",
     pageSize: 10,
     pageOffset: 0,
     pages: [],
     fetching: false,
     sortKey: 'IsConnected', // Default sort key
     sortOrder: -1, // 1 for ascending, -1 for descending
+    hiddenColumns: [],
   }),
   getters: {
     Find: (state) => {
@@ -34,10 +43,13 @@ export const peerStore = defineStore('peers', {
     FilteredCount: (state) => state.Filtered.length,
     All: (state) => state.peers,
     Filtered: (state) => {
-      if (!state.filter) {
+      if (!state.filter && !state.deviceTypeFilter) {
         return state.peers
       }
       return state.peers.filter((p) => {
+        if (state.deviceTypeFilter && p.DeviceType !== state.deviceTypeFilter) {
+          return false
+        }
         return p.DisplayName.includes(state.filter) || p.Identifier.includes(state.filter)
       })
     },
@@ -66,9 +78,27 @@ export const peerStore = defineStore('peers', {
     FilteredAndPaged: (state) => {
       return state.Sorted.slice(state.pageOffset, state.pageOffset + state.pageSize);
     },
+    isColumnVisible: (state) => {
+      return (column) => !state.hiddenColumns.includes(column)
+    },
+    CurrentView: (state) => {
+      return {
+        Filter: state.filter,
+        FilterOptions: state.deviceTypeFilter ? { deviceType: state.deviceTypeFilter } : {},
+        SortKey: state.sortKey,
+        SortDescending: state.sortOrder === -1,
+      }
+    },
     ConfigQrUrl: (state) => {
       return (id) => state.peers.find((p) => p.Identifier === id) ? apiWrapper.url(`${baseUrl}/config-qr/${base64_url_encode(id)}`) : ''
     },
+    ExportUrl: (state) => {
+      return (format, columns) => {
+        const interfaceId = interfaceStore().GetSelected.Identifier
+        const params = new URLSearchParams({ format: format, filter: state.filter, device_type: state.deviceTypeFilter, columns: columns.join(',') })
+        return apiWrapper.url(`/export/peers/${base64_url_encode(interfaceId)}?${params}`)
+      }
+    },
     isFetching: (state) => state.fetching,
     hasNextPage: (state) => state.pageOffset < (state.FilteredCount - state.pageSize),
     hasPrevPage: (state) => state.pageOffset > 0,
@@ -77,9 +107,31 @@ export const peerStore = defineStore('peers', {
       return (id) => state.statsEnabled && (id in state.stats) ? state.stats[id] : freshStats()
     },
     hasStatistics: (state) => state.statsEnabled,
+    ShapingStatistics: (state) => {
+      return (id) => state.shapingEnabled && (id in state.shapingStats) ? state.shapingStats[id] : freshShapingStats()
+    },
+    hasShapingStatistics: (state) => state.shapingEnabled,
+    KeepaliveRecommendation: (state) => {
+      return (id) => state.keepaliveRecommendations[id]
+    },
 
   },
   actions: {
+    ApplyView(view, hiddenColumns) {
+      this.filter = view.Filter || ""
+      this.deviceTypeFilter = view.FilterOptions?.deviceType || ""
+      this.sortKey = view.SortKey || 'IsConnected'
+      this.sortOrder = view.SortDescending ? -1 : 1
+      this.hiddenColumns = hiddenColumns
+      this.afterPageSizeChange()
+    },
+    ToggleColumn(column) {
+      if (this.hiddenColumns.includes(column)) {
+        this.hiddenColumns = this.hiddenColumns.filter(c => c !== column)
+      } else {
+        this.hiddenColumns.push(column)
+      }
+    },
     afterPageSizeChange() {
       // reset pageOffset to avoid problems with new page sizes
       this.pageOffset = 0
@@ -130,6 +182,22 @@ export const peerStore = defineStore('peers', {
       this.stats = statsResponse.Stats
       this.statsEnabled = statsResponse.Enabled
     },
+    setShapingStats(statsResponse) {
+      if (!statsResponse) {
+        this.shapingStats = {}
+        this.shapingEnabled = false
+        return
+      }
+      this.shapingStats = statsResponse.Stats
+      this.shapingEnabled = statsResponse.Enabled
+    },
+    setKeepaliveRecommendations(recommendationsResponse) {
+      if (!recommendationsResponse || !recommendationsResponse.Enabled) {
+        this.keepaliveRecommendations = {}
+        return
+      }
+      this.keepaliveRecommendations = recommendationsResponse.Recommendations
+    },
     async PreparePeer(interfaceId) {
       return apiWrapper.get(`${baseUrl}/iface/${base64_url_encode(interfaceId)}/prepare`)
         .then(this.setPreparedPeer)
@@ -158,18 +226,30 @@ export const peerStore = defineStore('peers', {
           throw new Error(error)
         })
     },
-    async LoadPeerConfig(id) {
-      return apiWrapper.get(`${baseUrl}/config/${base64_url_encode(id)}`)
+    async LoadPeerConfig(id, endpoint = 0) {
+      return apiWrapper.get(`${baseUrl}/config/${base64_url_encode(id)}?endpoint=${endpoint}`)
         .then(this.setPeerConfig)
         .catch(error => {
           this.configuration = ""
           console.log("Failed to load peer configuration: ", error)
+          if (authStore().StepUpRequired) {
+            return // the configuration is loaded again after the re-authentication
+          }
           notify({
             title: "Backend Connection Failure",
             text: "Failed to load peer configuration!",
           })
         })
     },
+    async LoadPeerPrivateKey(id) {
+      return apiWrapper.get(`${baseUrl}/${base64_url_encode(id)}/private-key`)
+    },
+    async LoadPeerEndpoints(id) {
+      return apiWrapper.get(`${baseUrl}/config-endpoints/${base64_url_encode(id)}`)
+    },
+    async LoadPeerUciConfig(id, style) {
+      return apiWrapper.get(`${baseUrl}/config-uci/${base64_url_encode(id)}?style=${style}`)
+    },
     async LoadPeer(id) {
       this.fetching = true
       return apiWrapper.get(`${baseUrl}/${base64_url_encode(id)}`)
@@ -204,6 +284,96 @@ export const peerStore = defineStore('peers', {
           })
         })
     },
+    async LoadShapingStats(interfaceId) {
+      // if no interfaceId is given, use the currently selected interface
+      if (!interfaceId) {
+        interfaceId = interfaceStore().GetSelected.Identifier
+        if (!interfaceId) {
+          return // no interface, nothing to load
+        }
+      }
+
+      return apiWrapper.get(`${baseUrl}/iface/${base64_url_encode(interfaceId)}/shaping-stats`)
+        .then(this.setShapingStats)
+        .catch(error => {
+          this.setShapingStats(undefined)
+          console.log("Failed to load peer shaping stats: ", error)
+        })
+    },
+    async LoadKeepaliveRecommendations(interfaceId) {
+      // if no interfaceId is given, use the currently selected interface
+      if (!interfaceId) {
+        interfaceId = interfaceStore().GetSelected.Identifier
+        if (!interfaceId) {
+          return // no interface, nothing to load
+        }
+      }
+
+      return apiWrapper.get(`${baseUrl}/iface/${base64_url_encode(interfaceId)}/keepalive-recommendations`)
+        .then(this.setKeepaliveRecommendations)
+        .catch(error => {
+          this.setKeepaliveRecommendations(undefined)
+          console.log("Failed to load keepalive recommendations: ", error)
+        })
+    },
+    async ApplyKeepaliveRecommendation(id) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/${base64_url_encode(id)}/apply-keepalive-recommendation`)
+        .then(peer => {
+          let idx = this.peers.findIndex((p) => p.Identifier === id)
+          this.peers[idx] = peer
+          delete this.keepaliveRecommendations[id]
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async LoadConfigPullToken(id) {
+      return apiWrapper.get(`/config-pull/${base64_url_encode(id)}`)
+        .then(token => this.configPullToken = token)
+        .catch(error => {
+          this.configPullToken = {}
+          console.log("Failed to load config pull token: ", error)
+        })
+    },
+    async CreateConfigPullToken(id) {
+      return apiWrapper.post(`/config-pull/${base64_url_encode(id)}`)
+        .then(token => this.configPullToken = token)
+    },
+    async DeleteConfigPullToken(id) {
+      return apiWrapper.delete(`/config-pull/${base64_url_encode(id)}`)
+        .then(() => this.configPullToken = { PullUrl: this.configPullToken.PullUrl })
+    },
+    async LoadSshDeployment(id) {
+      return Promise.all([
+        apiWrapper.get(`/ssh-deployment/hosts`),
+        apiWrapper.get(`/ssh-deployment/by-peer/${base64_url_encode(id)}`),
+      ]).then(([hosts, deployment]) => {
+        this.sshHosts = hosts
+        this.sshDeployment = deployment
+      }).catch(error => {
+        this.sshHosts = []
+        this.sshDeployment = {}
+        console.log("Failed to load SSH deployment: ", error)
+      })
+    },
+    async SaveSshDeployment(id, host, remoteInterface) {
+      return apiWrapper.put(`/ssh-deployment/by-peer/${base64_url_encode(id)}`, {
+        Host: host,
+        RemoteInterface: remoteInterface,
+      }).then(deployment => this.sshDeployment = deployment)
+    },
+    async DeploySsh(id) {
+      return apiWrapper.post(`/ssh-deployment/by-peer/${base64_url_encode(id)}/deploy`)
+        .then(deployment => this.sshDeployment = deployment)
+    },
+    async DeleteSshDeployment(id) {
+      return apiWrapper.delete(`/ssh-deployment/by-peer/${base64_url_encode(id)}`)
+        .then(() => this.sshDeployment = {})
+    },
     async DeletePeer(id) {
       this.fetching = true
       return apiWrapper.delete(`${baseUrl}/${base64_url_encode(id)}`)
@@ -217,6 +387,62 @@ export const peerStore = defineStore('peers', {
           throw new Error(error)
         })
     },
+    async SuggestMtu(id) {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/${base64_url_encode(id)}/mtu-suggestion`)
+        .then((suggestion) => {
+          this.fetching = false
+          return suggestion
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async MarkCompromised(id, reason) {
+      this.fetching = true
+      return apiWrapper.post(`/compromise/peer/${base64_url_encode(id)}`, { Reason: reason })
+        .then((incident) => {
+          this.fetching = false
+          return this.LoadPeers().then(() => incident)
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async RegeneratePeerKeys(id) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/${base64_url_encode(id)}/regenerate-keys`)
+        .then(peer => {
+          let idx = this.peers.findIndex((p) => p.Identifier === id)
+          if (idx !== -1) {
+            this.peers[idx] = peer
+          }
+          this.fetching = false
+          return peer
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async DiagnosePeer(id) {
+      this.fetching = true
+      return apiWrapper.get(`/diagnostics/peer/${base64_url_encode(id)}`)
+        .then((diagnosis) => {
+          this.fetching = false
+          return diagnosis
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
     async UpdatePeer(id, formData) {
       this.fetching = true
       return apiWrapper.put(`${baseUrl}/${base64_url_encode(id)}`, formData)
@@ -224,6 +450,7 @@ export const peerStore = defineStore('peers', {
           let idx = this.peers.findIndex((p) => p.Identifier === id)
           this.peers[idx] = peer
           this.fetching = false
+          return peer
         })
         .catch(error => {
           this.fetching = false
@@ -257,6 +484,27 @@ export const peerStore = defineStore('peers', {
             throw new Error(error)
           })
     },
+    async PreviewImport(interfaceId, request) {
+      return apiWrapper.post(`/import/peers/${base64_url_encode(interfaceId)}/preview`, request)
+        .catch(error => {
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async Import(interfaceId, request) {
+      this.fetching = true
+      return apiWrapper.post(`/import/peers/${base64_url_encode(interfaceId)}`, request)
+        .then(report => {
+          this.fetching = false
+          this.LoadPeers(interfaceId)
+          return report
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
     async LoadPeers(interfaceId) {
       // if no interfaceId is given, use the currently selected interface
       if (!interfaceId) {
@@ -0,0 +1,64 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+import { base64_url_encode } from '@/helpers/encoding';
+
+const baseUrl = `/port-forward`
+
+export const portForwardStore = defineStore('portforwards', {
+  state: () => ({
+    forwards: [],
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.forwards.length,
+    All: (state) => state.forwards,
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setForwards(forwards) {
+      this.forwards = forwards
+      this.fetching = false
+    },
+    addForward(forward) {
+      this.forwards.push(forward)
+      this.fetching = false
+    },
+    async LoadPeerForwards(peerId) {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/by-peer/${base64_url_encode(peerId)}`)
+        .then(this.setForwards)
+        .catch(error => {
+          this.setForwards([])
+          console.log("Failed to load port forwards: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load port forwards!",
+          })
+        })
+    },
+    async CreateForward(forward) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/new`, forward)
+        .then(this.addForward)
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async DeleteForward(id) {
+      this.fetching = true
+      return apiWrapper.delete(`${baseUrl}/by-id/${encodeURIComponent(id)}`)
+        .then(() => {
+          this.forwards = this.forwards.filter((f) => f.Identifier !== id)
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
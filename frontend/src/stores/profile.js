@@ -16,6 +16,7 @@ export const profileStore = defineStore('profile', {
     stats: {},
     statsEnabled: false,
     user: {},
+    notificationPreferences: {},
     filter: "",
     pageSize: 10,
     pageOffset: 0,
@@ -118,6 +119,10 @@ export const profileStore = defineStore('profile', {
       this.stats = statsResponse.Stats
       this.statsEnabled = statsResponse.Enabled
     },
+    setNotificationPreferences(prefs) {
+      this.notificationPreferences = prefs
+      this.fetching = false
+    },
     setInterfaces(interfaces) {
       this.interfaces = interfaces
       this.selectedInterfaceId = interfaces.length > 0 ? interfaces[0].Identifier : ""
@@ -207,5 +212,35 @@ export const profileStore = defineStore('profile', {
             })
           })
     },
+    async LoadNotificationPreferences() {
+      this.fetching = true
+      let currentUser = authStore().user.Identifier
+      return apiWrapper.get(`${baseUrl}/${base64_url_encode(currentUser)}/notification-preferences`)
+          .then(this.setNotificationPreferences)
+          .catch(error => {
+            this.setNotificationPreferences({})
+            console.log("Failed to load notification preferences for ", currentUser, ": ", error)
+            notify({
+              title: "Backend Connection Failure",
+              text: "Failed to load notification preferences!",
+            })
+          })
+    },
+    async UpdateNotificationPreferences(prefs) {
+      this.fetching = true
+      let currentUser = authStore().user.Identifier
+      return apiWrapper.put(`${baseUrl}/${base64_url_encode(currentUser)}/notification-preferences`, prefs)
+          .then(this.setNotificationPreferences)
+          .catch(error => {
+            this.fetching = false
+            console.log("Failed to update notification preferences for ", currentUser, ": ", error)
+            notify({
+              title: "Failed to update notification preferences!",
+              text: error,
+              type: 'error',
+            })
+            throw new Error(error)
+          })
+    },
   }
 })
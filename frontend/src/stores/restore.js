@@ -0,0 +1,31 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+
+const baseUrl = `/restore`
+
+export const restoreStore = defineStore('restore', {
+  state: () => ({
+    report: null,
+    fetching: false,
+  }),
+  getters: {
+    Report: (state) => state.report,
+    Failed: (state) => !!state.report && state.report.Failed,
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setReport(report) {
+      this.report = report
+      this.fetching = false
+    },
+    async LoadReport() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/report`)
+        .then(this.setReport)
+        .catch(error => {
+          this.setReport(null)
+          console.log("Failed to load restore report: ", error) // the report is optional, do not notify
+        })
+    },
+  }
+})
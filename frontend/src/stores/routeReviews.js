@@ -0,0 +1,56 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/route-review`
+
+export const routeReviewStore = defineStore('routeReviews', {
+  state: () => ({
+    reviews: [],
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.reviews.length,
+    All: (state) => state.reviews,
+    Pending: (state) => state.reviews.filter((r) => r.State === 'pending'),
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setReviews(reviews) {
+      this.reviews = reviews
+      this.fetching = false
+    },
+    updateReview(review) {
+      let idx = this.reviews.findIndex((r) => r.Identifier === review.Identifier)
+      if (idx === -1) {
+        this.reviews.unshift(review)
+      } else {
+        this.reviews[idx] = review
+      }
+      this.fetching = false
+    },
+    async LoadReviews() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/all`)
+        .then(this.setReviews)
+        .catch(error => {
+          this.setReviews([])
+          console.log("Failed to load route reviews: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load route reviews!",
+          })
+        })
+    },
+    async ReviewAction(id, action) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/by-id/${encodeURIComponent(id)}/${action}`)
+        .then(this.updateReview)
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
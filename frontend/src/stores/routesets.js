@@ -0,0 +1,80 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/route-set`
+
+export const routeSetStore = defineStore('routesets', {
+  state: () => ({
+    routeSets: [],
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.routeSets.length,
+    All: (state) => state.routeSets,
+    Find: (state) => {
+      return (id) => state.routeSets.find((r) => r.Identifier === id)
+    },
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setRouteSets(routeSets) {
+      this.routeSets = routeSets
+      this.fetching = false
+    },
+    async LoadRouteSets() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/all`)
+        .then(this.setRouteSets)
+        .catch(error => {
+          this.setRouteSets([])
+          console.log("Failed to load route sets: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load route sets!",
+          })
+        })
+    },
+    async DeleteRouteSet(id) {
+      this.fetching = true
+      return apiWrapper.delete(`${baseUrl}/by-id/${encodeURIComponent(id)}`)
+        .then(() => {
+          this.routeSets = this.routeSets.filter(r => r.Identifier !== id)
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async UpdateRouteSet(id, formData) {
+      this.fetching = true
+      return apiWrapper.put(`${baseUrl}/by-id/${encodeURIComponent(id)}`, formData)
+        .then(routeSet => {
+          let idx = this.routeSets.findIndex((r) => r.Identifier === id)
+          routeSet.PeerCount = this.routeSets[idx].PeerCount
+          this.routeSets[idx] = routeSet
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async CreateRouteSet(formData) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/new`, formData)
+        .then(routeSet => {
+          this.routeSets.push(routeSet)
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
@@ -0,0 +1,78 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+import {authStore} from "@/stores/auth";
+import { base64_url_encode } from '@/helpers/encoding';
+
+const baseUrl = `/user`
+
+export const savedViewStore = defineStore('savedviews', {
+  state: () => ({
+    views: {}, // the saved views per list
+    fetching: false,
+  }),
+  getters: {
+    Views: (state) => {
+      return (list) => state.views[list] || []
+    },
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setViews(list, views) {
+      this.views[list] = views
+      this.fetching = false
+    },
+    viewUrl() {
+      return `${baseUrl}/${base64_url_encode(authStore().user.Identifier)}/saved-views`
+    },
+    async LoadViews(list) {
+      this.fetching = true
+      return apiWrapper.get(`${this.viewUrl()}?list=${encodeURIComponent(list)}`)
+        .then(views => this.setViews(list, views))
+        .catch(error => {
+          this.setViews(list, [])
+          console.log("Failed to load saved views: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load saved views!",
+          })
+        })
+    },
+    async CreateView(view) {
+      this.fetching = true
+      return apiWrapper.post(this.viewUrl(), view)
+        .then(created => {
+          this.setViews(view.List, [...this.Views(view.List), created].sort((a, b) => a.Name.localeCompare(b.Name)))
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async UpdateView(view) {
+      this.fetching = true
+      return apiWrapper.put(`${this.viewUrl()}/${view.Id}`, view)
+        .then(updated => {
+          this.setViews(view.List, this.Views(view.List).map(v => v.Id === updated.Id ? updated : v))
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async DeleteView(view) {
+      this.fetching = true
+      return apiWrapper.delete(`${this.viewUrl()}/${view.Id}`)
+        .then(() => {
+          this.setViews(view.List, this.Views(view.List).filter(v => v.Id !== view.Id))
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
@@ -0,0 +1,52 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+
+const baseUrl = `/search`
+
+// minQueryLength must match the minimum query length of the backend
+const minQueryLength = 2
+
+export const searchStore = defineStore('search', {
+  state: () => ({
+    query: "",
+    results: [],
+    fetching: false,
+  }),
+  getters: {
+    Results: (state) => {
+      return (type) => state.results.filter((r) => r.Type === type)
+    },
+    HasResults: (state) => state.results.length !== 0,
+    CanSearch: (state) => state.query.trim().length >= minQueryLength,
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setResults(results) {
+      this.results = results
+      this.fetching = false
+    },
+    Clear() {
+      this.query = ""
+      this.setResults([])
+    },
+    async Search(query) {
+      this.query = query
+      if (!this.CanSearch) {
+        this.setResults([])
+        return
+      }
+
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/query?q=${encodeURIComponent(query.trim())}`)
+        .then(results => {
+          if (query === this.query) { // ignore responses of outdated type-ahead requests
+            this.setResults(results)
+          }
+        })
+        .catch(error => {
+          this.setResults([])
+          console.log("Failed to search: ", error)
+        })
+    },
+  }
+})
@@ -0,0 +1,47 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/security-event`
+
+export const securityEventStore = defineStore('securityevents', {
+  state: () => ({
+    events: [],
+    filter: "",
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.events.length,
+    Filtered: (state) => {
+      if (!state.filter) {
+        return state.events
+      }
+      const filter = state.filter.toLowerCase()
+      return state.events.filter((e) => {
+        return e.RuleName.toLowerCase().includes(filter) ||
+          e.Message.toLowerCase().includes(filter) ||
+          e.Severity.toLowerCase().includes(filter)
+      })
+    },
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setEvents(events) {
+      this.events = events
+      this.fetching = false
+    },
+    async LoadEvents() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/all`)
+        .then(this.setEvents)
+        .catch(error => {
+          this.setEvents([])
+          console.log("Failed to load security events: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load security events!",
+          })
+        })
+    },
+  }
+})
@@ -0,0 +1,45 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/status`
+
+export const statusStore = defineStore('status', {
+  state: () => ({
+    endpoints: [],
+    notices: [],
+    checkedAt: null,
+    fetching: false,
+  }),
+  getters: {
+    Endpoints: (state) => state.endpoints,
+    Notices: (state) => state.notices,
+    ActiveNotices: (state) => state.notices.filter(n => n.Active),
+    UpcomingNotices: (state) => state.notices.filter(n => !n.Active),
+    CheckedAt: (state) => state.checkedAt,
+    AllReachable: (state) => state.endpoints.every(e => e.Reachable),
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setStatus(status) {
+      this.endpoints = status.Endpoints || []
+      this.notices = status.Notices || []
+      this.checkedAt = status.CheckedAt
+      this.fetching = false
+    },
+    async LoadStatus() {
+      this.fetching = true
+      return apiWrapper.get(baseUrl)
+        .then(this.setStatus)
+        .catch(error => {
+          this.setStatus({})
+          console.log("Failed to load service status: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load the service status!",
+            type: 'error',
+          })
+        })
+    },
+  }
+})
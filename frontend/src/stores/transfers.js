@@ -0,0 +1,69 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/peer-transfer`
+
+export const transferStore = defineStore('transfers', {
+  state: () => ({
+    transfers: [],
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.transfers.length,
+    All: (state) => state.transfers,
+    Open: (state) => state.transfers.filter((t) => t.State === 'pending'),
+    FindOpenForPeer: (state) => {
+      return (peerId) => state.transfers.find((t) => t.PeerId === peerId && t.State === 'pending')
+    },
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setTransfers(transfers) {
+      this.transfers = transfers
+      this.fetching = false
+    },
+    updateTransfer(transfer) {
+      let idx = this.transfers.findIndex((t) => t.Identifier === transfer.Identifier)
+      if (idx === -1) {
+        this.transfers.unshift(transfer)
+      } else {
+        this.transfers[idx] = transfer
+      }
+      this.fetching = false
+    },
+    async LoadTransfers() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/all`)
+        .then(this.setTransfers)
+        .catch(error => {
+          this.setTransfers([])
+          console.log("Failed to load peer transfers: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load peer transfers!",
+          })
+        })
+    },
+    async RequestTransfer(peerId, toUser, message) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/new`, { PeerId: peerId, ToUser: toUser, Message: message })
+        .then(this.updateTransfer)
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async TransferAction(id, action) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/by-id/${encodeURIComponent(id)}/${action}`)
+        .then(this.updateTransfer)
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
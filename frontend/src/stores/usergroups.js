@@ -0,0 +1,79 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/user-group`
+
+export const userGroupStore = defineStore('usergroups', {
+  state: () => ({
+    groups: [],
+    fetching: false,
+  }),
+  getters: {
+    Count: (state) => state.groups.length,
+    All: (state) => state.groups,
+    Find: (state) => {
+      return (id) => state.groups.find((g) => g.Identifier === id)
+    },
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setGroups(groups) {
+      this.groups = groups
+      this.fetching = false
+    },
+    async LoadGroups() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/all`)
+        .then(this.setGroups)
+        .catch(error => {
+          this.setGroups([])
+          console.log("Failed to load user groups: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load user groups!",
+          })
+        })
+    },
+    async DeleteGroup(id) {
+      this.fetching = true
+      return apiWrapper.delete(`${baseUrl}/by-id/${encodeURIComponent(id)}`)
+        .then(() => {
+          this.groups = this.groups.filter(g => g.Identifier !== id)
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async UpdateGroup(id, formData) {
+      this.fetching = true
+      return apiWrapper.put(`${baseUrl}/by-id/${encodeURIComponent(id)}`, formData)
+        .then(group => {
+          let idx = this.groups.findIndex((g) => g.Identifier === id)
+          this.groups[idx] = group
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async CreateGroup(formData) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/new`, formData)
+        .then(group => {
+          this.groups.push(group)
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
@@ -2,14 +2,20 @@ import { defineStore } from 'pinia'
 import {apiWrapper} from "@/helpers/fetch-wrapper";
 import {notify} from "@kyvg/vue3-notification";
 import { base64_url_encode } from '@/helpers/encoding';
+import {freshStats} from "@/helpers/models";
 
 const baseUrl = `/user`
 
 export const userStore = defineStore('users', {
   state: () => ({
     userPeers: [],
+    userPeerStats: {},
+    userPeerStatsEnabled: false,
     users: [],
     filter: "",
+    sortKey: "",
+    sortOrder: 1, // 1 for ascending, -1 for descending
+    hiddenColumns: [],
     pageSize: 10,
     pageOffset: 0,
     pages: [],
@@ -23,6 +29,10 @@ export const userStore = defineStore('users', {
     FilteredCount: (state) => state.Filtered.length,
     All: (state) => state.users,
     Peers: (state) => state.userPeers,
+    hasPeerStatistics: (state) => state.userPeerStatsEnabled,
+    PeerStatistics: (state) => {
+      return (id) => state.userPeerStatsEnabled && (id in state.userPeerStats) ? state.userPeerStats[id] : freshStats()
+    },
     Filtered: (state) => {
       if (!state.filter) {
         return state.users
@@ -31,8 +41,42 @@ export const userStore = defineStore('users', {
         return u.Firstname.includes(state.filter) || u.Lastname.includes(state.filter) || u.Email.includes(state.filter) || u.Identifier.includes(state.filter)
       })
     },
+    Sorted: (state) => {
+      if (!state.sortKey) {
+        return state.Filtered
+      }
+      return state.Filtered.slice().sort((a, b) => {
+        let aValue = a[state.sortKey];
+        let bValue = b[state.sortKey];
+        if (typeof aValue === 'string') {
+          aValue = aValue.toLowerCase()
+          bValue = (bValue || '').toLowerCase()
+        }
+        let result = 0;
+        if (aValue > bValue) result = 1;
+        if (aValue < bValue) result = -1;
+        return state.sortOrder === 1 ? result : -result;
+      })
+    },
     FilteredAndPaged: (state) => {
-      return state.Filtered.slice(state.pageOffset, state.pageOffset + state.pageSize)
+      return state.Sorted.slice(state.pageOffset, state.pageOffset + state.pageSize)
+    },
+    isColumnVisible: (state) => {
+      return (column) => !state.hiddenColumns.includes(column)
+    },
+    CurrentView: (state) => {
+      return {
+        Filter: state.filter,
+        FilterOptions: {},
+        SortKey: state.sortKey,
+        SortDescending: state.sortOrder === -1,
+      }
+    },
+    ExportUrl: (state) => {
+      return (format, columns) => {
+        const params = new URLSearchParams({ format: format, filter: state.filter, columns: columns.join(',') })
+        return apiWrapper.url(`/export/users?${params}`)
+      }
     },
     isFetching: (state) => state.fetching,
     hasNextPage: (state) => state.pageOffset < (state.FilteredCount - state.pageSize),
@@ -40,6 +84,28 @@ export const userStore = defineStore('users', {
     currentPage: (state) => (state.pageOffset / state.pageSize)+1,
   },
   actions: {
+    ApplyView(view, hiddenColumns) {
+      this.filter = view.Filter || ""
+      this.sortKey = view.SortKey || ""
+      this.sortOrder = view.SortDescending ? -1 : 1
+      this.hiddenColumns = hiddenColumns
+      this.afterPageSizeChange()
+    },
+    ToggleColumn(column) {
+      if (this.hiddenColumns.includes(column)) {
+        this.hiddenColumns = this.hiddenColumns.filter(c => c !== column)
+      } else {
+        this.hiddenColumns.push(column)
+      }
+    },
+    sortBy(key) {
+      if (this.sortKey === key) {
+        this.sortOrder = this.sortOrder * -1 // Toggle sort order
+      } else {
+        this.sortKey = key
+        this.sortOrder = 1 // Default to ascending
+      }
+    },
     afterPageSizeChange() {
       // reset pageOffset to avoid problems with new page sizes
       this.pageOffset = 0
@@ -76,6 +142,15 @@ export const userStore = defineStore('users', {
       this.userPeers = peers
       this.fetching = false
     },
+    setUserPeerStats(statsResponse) {
+      if (!statsResponse) {
+        this.userPeerStats = {}
+        this.userPeerStatsEnabled = false
+        return
+      }
+      this.userPeerStats = statsResponse.Stats
+      this.userPeerStatsEnabled = statsResponse.Enabled
+    },
     async LoadUsers() {
       this.fetching = true
       return apiWrapper.get(`${baseUrl}/all`)
@@ -89,6 +164,27 @@ export const userStore = defineStore('users', {
           })
         })
     },
+    async PreviewImport(request) {
+      return apiWrapper.post(`/import/users/preview`, request)
+        .catch(error => {
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async Import(request) {
+      this.fetching = true
+      return apiWrapper.post(`/import/users`, request)
+        .then(report => {
+          this.fetching = false
+          this.LoadUsers()
+          return report
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
     async DeleteUser(id) {
       this.fetching = true
       return apiWrapper.delete(`${baseUrl}/${base64_url_encode(id)}`)
@@ -102,6 +198,20 @@ export const userStore = defineStore('users', {
           throw new Error(error)
         })
     },
+    async ForgetUser(id) {
+      this.fetching = true
+      return apiWrapper.post(`/data-retention/forget-user/${base64_url_encode(id)}`)
+        .then(erasure => {
+          this.users = this.users.filter(u => u.Identifier !== id)
+          this.fetching = false
+          return erasure
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
     async UpdateUser(id, formData) {
       this.fetching = true
       return apiWrapper.put(`${baseUrl}/${base64_url_encode(id)}`, formData)
@@ -129,6 +239,35 @@ export const userStore = defineStore('users', {
           throw new Error(error)
         })
     },
+    async EnableApi(id) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/${base64_url_encode(id)}/api/enable`)
+        .then(user => {
+          let idx = this.users.findIndex((u) => u.Identifier === id)
+          this.users[idx] = user
+          this.fetching = false
+          return user
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+    async DisableApi(id) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/${base64_url_encode(id)}/api/disable`)
+        .then(user => {
+          let idx = this.users.findIndex((u) => u.Identifier === id)
+          this.users[idx] = user
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
     async LoadUserPeers(id) {
       this.fetching = true
       return apiWrapper.get(`${baseUrl}/${base64_url_encode(id)}/peers`)
@@ -142,5 +281,27 @@ export const userStore = defineStore('users', {
           })
         })
     },
+    async LoadUserPeerStats(id) {
+      return apiWrapper.get(`${baseUrl}/${base64_url_encode(id)}/stats`)
+        .then(this.setUserPeerStats)
+        .catch(error => {
+          this.setUserPeerStats(undefined)
+          console.log("Failed to load user peer stats for ",id ,": ", error)
+        })
+    },
+    async ResetWebAuthn(id) {
+      this.fetching = true
+      return apiWrapper.post(`${baseUrl}/${base64_url_encode(id)}/webauthn/reset`)
+        .then(user => {
+          let idx = this.users.findIndex((u) => u.Identifier === id)
+          this.users[idx] = user
+          this.fetching = false
+        })
+        .catch(error => {
+          this.fetching = false
+          console.log(error)
+          throw new Error(error)
+        })
+    },
   }
 })
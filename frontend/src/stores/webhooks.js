@@ -0,0 +1,68 @@
+import { defineStore } from 'pinia'
+import {apiWrapper} from "@/helpers/fetch-wrapper";
+import {notify} from "@kyvg/vue3-notification";
+
+const baseUrl = `/webhook`
+
+export const webhookStore = defineStore('webhooks', {
+  state: () => ({
+    endpoints: [],
+    events: [],
+    fetching: false,
+  }),
+  getters: {
+    EndpointCount: (state) => state.endpoints.length,
+    EventCount: (state) => state.events.length,
+    LastEventId: (state) => state.events.length === 0 ? 0 : state.events[state.events.length - 1].Id,
+    isFetching: (state) => state.fetching,
+  },
+  actions: {
+    setEndpoints(endpoints) {
+      this.endpoints = endpoints
+      this.fetching = false
+    },
+    setEvents(events) {
+      this.events = events
+      this.fetching = false
+    },
+    async LoadEndpoints() {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/endpoints`)
+        .then(this.setEndpoints)
+        .catch(error => {
+          this.setEndpoints([])
+          console.log("Failed to load webhook endpoints: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load webhook endpoints!",
+          })
+        })
+    },
+    async LoadEvents(afterId = 0) {
+      this.fetching = true
+      return apiWrapper.get(`${baseUrl}/events?after=${encodeURIComponent(afterId)}`)
+        .then(this.setEvents)
+        .catch(error => {
+          this.setEvents([])
+          console.log("Failed to load webhook events: ", error)
+          notify({
+            title: "Backend Connection Failure",
+            text: "Failed to load webhook events!",
+          })
+        })
+    },
+    async SetCursor(endpoint, eventId) {
+      return apiWrapper.put(`${baseUrl}/endpoint/${encodeURIComponent(endpoint)}/cursor`, { EventId: eventId })
+        .then(endpoint => {
+          let idx = this.endpoints.findIndex((e) => e.Name === endpoint.Name)
+          if (idx >= 0) {
+            this.endpoints[idx] = endpoint
+          }
+        })
+        .catch(error => {
+          console.log(error)
+          throw new Error(error)
+        })
+    },
+  }
+})
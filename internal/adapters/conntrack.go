@@ -0,0 +1,66 @@
+//go:build linux
+
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// ConntrackRepo reads the connection tracking table of the kernel via netlink.
+type ConntrackRepo struct{}
+
+// NewConntrackRepo creates a new ConntrackRepo instance.
+func NewConntrackRepo() *ConntrackRepo {
+	return &ConntrackRepo{}
+}
+
+// GetConnectionFlows returns all IPv4 and IPv6 connections of the connection tracking table. The counters are only
+// filled if the sysctl net.netfilter.nf_conntrack_acct is enabled, the start times only if
+// net.netfilter.nf_conntrack_timestamp is enabled.
+func (r *ConntrackRepo) GetConnectionFlows(_ context.Context) ([]domain.ConnectionFlow, error) {
+	var flows []domain.ConnectionFlow
+	for _, family := range []netlink.InetFamily{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		entries, err := netlink.ConntrackTableList(netlink.ConntrackTable, family)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list connection tracking table: %w", err)
+		}
+
+		for _, entry := range entries {
+			flow := domain.ConnectionFlow{
+				Protocol:     entry.Forward.Protocol,
+				Source:       conntrackAddrPort(entry.Forward.SrcIP, entry.Forward.SrcPort),
+				Destination:  conntrackAddrPort(entry.Forward.DstIP, entry.Forward.DstPort),
+				Packets:      entry.Forward.Packets,
+				Bytes:        entry.Forward.Bytes,
+				ReplyPackets: entry.Reverse.Packets,
+				ReplyBytes:   entry.Reverse.Bytes,
+			}
+			if entry.TimeStart > 0 {
+				flow.StartedAt = time.Unix(0, int64(entry.TimeStart))
+			}
+			if !flow.Source.IsValid() || !flow.Destination.IsValid() ||
+				flow.Source.Addr().Is4() != flow.Destination.Addr().Is4() {
+				continue
+			}
+			flows = append(flows, flow)
+		}
+	}
+
+	return flows, nil
+}
+
+func conntrackAddrPort(ip net.IP, port uint16) netip.AddrPort {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.AddrPort{}
+	}
+	return netip.AddrPortFrom(addr.Unmap(), port)
+}
@@ -0,0 +1,21 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// ConntrackRepo is not supported on FreeBSD, the connection tracking table is only available on Linux.
+type ConntrackRepo struct{}
+
+// NewConntrackRepo creates a new ConntrackRepo instance.
+func NewConntrackRepo() *ConntrackRepo {
+	return &ConntrackRepo{}
+}
+
+// GetConnectionFlows always returns an error on FreeBSD.
+func (r *ConntrackRepo) GetConnectionFlows(_ context.Context) ([]domain.ConnectionFlow, error) {
+	return nil, errors.New("connection tracking is only supported on Linux")
+}
@@ -2,15 +2,18 @@ package adapters
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/glebarez/sqlite"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlserver"
@@ -100,6 +103,9 @@ func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (stri
 	if l.SourceField != "" {
 		attrs = append(attrs, l.SourceField, utils.FileWithLineNum())
 	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		attrs = append(attrs, "trace_id", spanContext.TraceID().String())
+	}
 
 	if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound) && l.IgnoreErrRecordNotFound) {
 		attrs = append(attrs, "error", err)
@@ -108,6 +114,7 @@ func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (stri
 	}
 
 	if l.SlowThreshold != 0 && elapsed > l.SlowThreshold {
+		attrs = append(attrs, "threshold", l.SlowThreshold)
 		slog.WarnContext(ctx, l.prefix+sql, attrs...)
 		return
 	}
@@ -132,9 +139,7 @@ func NewDatabase(cfg config.DatabaseConfig) (*gorm.DB, error) {
 		}
 
 		sqlDB, _ := gormDb.DB()
-		sqlDB.SetConnMaxLifetime(time.Minute * 5)
-		sqlDB.SetMaxIdleConns(2)
-		sqlDB.SetMaxOpenConns(10)
+		configureConnectionPool(sqlDB, cfg)
 		err = sqlDB.Ping() // This DOES open a connection if necessary. This makes sure the database is accessible
 		if err != nil {
 			return nil, fmt.Errorf("failed to ping MySQL database: %w", err)
@@ -146,6 +151,9 @@ func NewDatabase(cfg config.DatabaseConfig) (*gorm.DB, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to open sqlserver database: %w", err)
 		}
+
+		sqlDB, _ := gormDb.DB()
+		configureConnectionPool(sqlDB, cfg)
 	case config.DatabasePostgres:
 		gormDb, err = gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{
 			Logger: NewLogger(cfg.SlowQueryThreshold, cfg.Debug),
@@ -153,6 +161,9 @@ func NewDatabase(cfg config.DatabaseConfig) (*gorm.DB, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to open Postgres database: %w", err)
 		}
+
+		sqlDB, _ := gormDb.DB()
+		configureConnectionPool(sqlDB, cfg)
 	case config.DatabaseSQLite:
 		if _, err = os.Stat(filepath.Dir(cfg.DSN)); os.IsNotExist(err) {
 			if err = os.MkdirAll(filepath.Dir(cfg.DSN), 0700); err != nil {
@@ -167,12 +178,26 @@ func NewDatabase(cfg config.DatabaseConfig) (*gorm.DB, error) {
 			return nil, fmt.Errorf("failed to open sqlite database: %w", err)
 		}
 		sqlDB, _ := gormDb.DB()
-		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxOpenConns(1) // SQLite does not support concurrent writes, the pool settings are ignored
+	}
+
+	if gormDb != nil {
+		if err := gormDb.Use(newGormTracer(string(cfg.Type))); err != nil {
+			return nil, fmt.Errorf("failed to register database tracing: %w", err)
+		}
 	}
 
 	return gormDb, nil
 }
 
+// configureConnectionPool applies the connection pool settings to the given database connection.
+func configureConnectionPool(sqlDB *sql.DB, cfg config.DatabaseConfig) {
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConnections)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConnections)
+	sqlDB.SetConnMaxLifetime(cfg.ConnectionMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnectionMaxIdleTime)
+}
+
 // SqlRepo is a SQL database repository implementation.
 // Currently, it supports MySQL, SQLite, Microsoft SQL and Postgresql database systems.
 type SqlRepo struct {
@@ -226,7 +251,52 @@ func (r *SqlRepo) migrate() error {
 	slog.Debug("running migration: peer", "result", r.db.AutoMigrate(&domain.Peer{}))
 	slog.Debug("running migration: peer status", "result", r.db.AutoMigrate(&domain.PeerStatus{}))
 	slog.Debug("running migration: interface status", "result", r.db.AutoMigrate(&domain.InterfaceStatus{}))
+	slog.Debug("running migration: peer stats samples", "result", r.db.AutoMigrate(&domain.PeerStatsSample{}))
+	slog.Debug("running migration: peer connection events", "result",
+		r.db.AutoMigrate(&domain.PeerConnectionEvent{}))
 	slog.Debug("running migration: audit data", "result", r.db.AutoMigrate(&domain.AuditEntry{}))
+	slog.Debug("running migration: mail log", "result", r.db.AutoMigrate(&domain.MailLogEntry{}))
+	slog.Debug("running migration: peer cleanup notices", "result",
+		r.db.AutoMigrate(&domain.PeerCleanupNotice{}))
+	slog.Debug("running migration: peer expiry reminders", "result",
+		r.db.AutoMigrate(&domain.PeerExpiryReminder{}))
+	slog.Debug("running migration: peer setup reminders", "result",
+		r.db.AutoMigrate(&domain.PeerSetupReminder{}))
+	slog.Debug("running migration: peer config versions", "result",
+		r.db.AutoMigrate(&domain.PeerConfigVersion{}))
+	slog.Debug("running migration: peer transfers", "result", r.db.AutoMigrate(&domain.PeerTransfer{}))
+	slog.Debug("running migration: emergency lockdowns", "result",
+		r.db.AutoMigrate(&domain.EmergencyLockdown{}))
+	slog.Debug("running migration: user login devices", "result",
+		r.db.AutoMigrate(&domain.UserLoginDevice{}))
+	slog.Debug("running migration: user notification preferences", "result",
+		r.db.AutoMigrate(&domain.UserNotificationPreferences{}))
+	slog.Debug("running migration: saved views", "result", r.db.AutoMigrate(&domain.SavedView{}))
+	slog.Debug("running migration: idempotency records", "result",
+		r.db.AutoMigrate(&domain.IdempotencyRecord{}))
+	slog.Debug("running migration: webhook events", "result", r.db.AutoMigrate(&domain.WebhookEvent{}))
+	slog.Debug("running migration: webhook deliveries", "result", r.db.AutoMigrate(&domain.WebhookDelivery{}))
+	slog.Debug("running migration: webhook cursors", "result", r.db.AutoMigrate(&domain.WebhookCursor{}))
+	slog.Debug("running migration: acceptable use acceptances", "result",
+		r.db.AutoMigrate(&domain.AcceptableUseAcceptance{}))
+	slog.Debug("running migration: dns record sets", "result", r.db.AutoMigrate(&domain.DnsRecordSet{}))
+	slog.Debug("running migration: route sets", "result", r.db.AutoMigrate(&domain.RouteSet{}))
+	slog.Debug("running migration: alerts", "result", r.db.AutoMigrate(&domain.Alert{}))
+	slog.Debug("running migration: alert silences", "result", r.db.AutoMigrate(&domain.AlertSilence{}))
+	slog.Debug("running migration: security events", "result", r.db.AutoMigrate(&domain.SecurityEvent{}))
+	slog.Debug("running migration: announcements", "result", r.db.AutoMigrate(&domain.Announcement{}))
+	slog.Debug("running migration: announcement reads", "result", r.db.AutoMigrate(&domain.AnnouncementRead{}))
+	slog.Debug("running migration: export approvals", "result", r.db.AutoMigrate(&domain.ExportApproval{}))
+	slog.Debug("running migration: key exports", "result", r.db.AutoMigrate(&domain.KeyExport{}))
+	slog.Debug("running migration: config pull tokens", "result", r.db.AutoMigrate(&domain.ConfigPullToken{}))
+	slog.Debug("running migration: organizations", "result", r.db.AutoMigrate(&domain.Organization{}))
+	slog.Debug("running migration: user groups", "result", r.db.AutoMigrate(&domain.UserGroup{}))
+	slog.Debug("running migration: ssh deployments", "result", r.db.AutoMigrate(&domain.SshDeployment{}))
+	slog.Debug("running migration: port forwards", "result", r.db.AutoMigrate(&domain.PortForward{}))
+	slog.Debug("running migration: access requests", "result", r.db.AutoMigrate(&domain.AccessRequest{}))
+	slog.Debug("running migration: route reviews", "result", r.db.AutoMigrate(&domain.RouteReview{}))
+	slog.Debug("running migration: address reservations", "result",
+		r.db.AutoMigrate(&domain.AddressReservation{}))
 
 	existingSysStat := SysStat{}
 	r.db.Where("schema_version = ?", SchemaVersion).First(&existingSysStat)
@@ -546,6 +616,29 @@ func (r *SqlRepo) GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([
 	return peers, nil
 }
 
+// GetSharedUserPeers returns all peers that are shared with the given user id. Peers owned by the user are not
+// included.
+func (r *SqlRepo) GetSharedUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
+	var candidates []domain.Peer
+
+	err := r.db.WithContext(ctx).Preload("Addresses").
+		Where("shared_users_str LIKE ?", "%"+string(id)+"%").
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// the LIKE query also matches users whose identifier contains the given id
+	peers := make([]domain.Peer, 0, len(candidates))
+	for _, peer := range candidates {
+		if peer.UserIdentifier != id && peer.IsMember(id) {
+			peers = append(peers, peer)
+		}
+	}
+
+	return peers, nil
+}
+
 // FindUserPeers returns all peers associated with the given user id that match the given search string.
 // The search string is matched against the peer identifier, display name and IP address.
 func (r *SqlRepo) FindUserPeers(ctx context.Context, id domain.UserIdentifier, search string) ([]domain.Peer, error) {
@@ -662,6 +755,18 @@ func (r *SqlRepo) DeletePeer(ctx context.Context, id domain.PeerIdentifier) erro
 	return nil
 }
 
+// GetPeerCount returns the number of all peers.
+func (r *SqlRepo) GetPeerCount(ctx context.Context) (int, error) {
+	var count int64
+
+	err := r.db.WithContext(ctx).Model(&domain.Peer{}).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
 // GetPeerIps returns a map of peer identifiers to their respective IP addresses.
 func (r *SqlRepo) GetPeerIps(ctx context.Context) (map[domain.PeerIdentifier][]domain.Cidr, error) {
 	var ips []struct {
@@ -1058,6 +1163,118 @@ func (r *SqlRepo) DeletePeerStatus(ctx context.Context, id domain.PeerIdentifier
 	return nil
 }
 
+// SavePeerStatsSamples creates or updates the given peer statistics samples.
+func (r *SqlRepo) SavePeerStatsSamples(ctx context.Context, samples []domain.PeerStatsSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(samples, 100).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetPeerStatsSamples returns the peer statistics samples of the given resolution within [from, to).
+// If no peer ids are given, the samples of all peers are returned. The samples are ordered by timestamp.
+func (r *SqlRepo) GetPeerStatsSamples(
+	ctx context.Context,
+	resolution domain.PeerStatsResolution,
+	from, to time.Time,
+	ids ...domain.PeerIdentifier,
+) ([]domain.PeerStatsSample, error) {
+	var samples []domain.PeerStatsSample
+
+	query := r.db.WithContext(ctx).
+		Where("resolution = ? AND timestamp >= ? AND timestamp < ?", resolution, from.UTC(), to.UTC())
+	if len(ids) > 0 {
+		query = query.Where("identifier IN ?", ids)
+	}
+	err := query.Order("timestamp").Find(&samples).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+// DeletePeerStatsSamples deletes all peer statistics samples of the given resolution that are older than the
+// given time.
+func (r *SqlRepo) DeletePeerStatsSamples(
+	ctx context.Context,
+	resolution domain.PeerStatsResolution,
+	before time.Time,
+) error {
+	err := r.db.WithContext(ctx).
+		Where("resolution = ? AND timestamp < ?", resolution, before.UTC()).
+		Delete(&domain.PeerStatsSample{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SavePeerConnectionEvents stores the given peer connection events.
+func (r *SqlRepo) SavePeerConnectionEvents(ctx context.Context, events []domain.PeerConnectionEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(events, 100).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetPeerConnectionEvents returns the connection events of the given peer within [from, to).
+// The events are ordered by timestamp.
+func (r *SqlRepo) GetPeerConnectionEvents(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	from, to time.Time,
+) ([]domain.PeerConnectionEvent, error) {
+	var events []domain.PeerConnectionEvent
+
+	err := r.db.WithContext(ctx).
+		Where("identifier = ? AND timestamp >= ? AND timestamp < ?", id, from.UTC(), to.UTC()).
+		Order("timestamp").
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// AnonymizePeerConnectionEvents removes the endpoint addresses from all connection events of the given peer.
+func (r *SqlRepo) AnonymizePeerConnectionEvents(ctx context.Context, id domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Model(&domain.PeerConnectionEvent{}).
+		Where("identifier = ?", id).
+		Updates(map[string]any{"endpoint": "", "previous_endpoint": ""}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeletePeerConnectionEvents deletes all peer connection events that are older than the given time.
+func (r *SqlRepo) DeletePeerConnectionEvents(ctx context.Context, before time.Time) error {
+	err := r.db.WithContext(ctx).
+		Where("timestamp < ?", before.UTC()).
+		Delete(&domain.PeerConnectionEvent{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // endregion statistics
 
 // region audit
@@ -1084,4 +1301,1775 @@ func (r *SqlRepo) GetAllAuditEntries(ctx context.Context) ([]domain.AuditEntry,
 	return entries, nil
 }
 
+// DeleteAuditEntries deletes all audit entries that were created before the given time.
+func (r *SqlRepo) DeleteAuditEntries(ctx context.Context, before time.Time) error {
+	err := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&domain.AuditEntry{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AnonymizeAuditEntries replaces the given personal data values in the context user and the message of all audit
+// entries with domain.ErasedPlaceholder.
+func (r *SqlRepo) AnonymizeAuditEntries(ctx context.Context, values []string) error {
+	for _, value := range values {
+		pattern := "%" + value + "%"
+		err := r.db.WithContext(ctx).Model(&domain.AuditEntry{}).
+			Where("context_user LIKE ? OR message LIKE ?", pattern, pattern).
+			Updates(map[string]any{
+				"context_user": gorm.Expr("REPLACE(context_user, ?, ?)", value, domain.ErasedPlaceholder),
+				"message":      gorm.Expr("REPLACE(message, ?, ?)", value, domain.ErasedPlaceholder),
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // endregion audit
+
+// region mail-log
+
+// SaveMailLogEntry saves the given mail log entry.
+func (r *SqlRepo) SaveMailLogEntry(ctx context.Context, entry *domain.MailLogEntry) error {
+	err := r.db.WithContext(ctx).Save(entry).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindMailLogEntries returns all mail log entries that match the given filter.
+// The entries are ordered by timestamp, with the newest entries first.
+func (r *SqlRepo) FindMailLogEntries(ctx context.Context, filter domain.MailLogFilter) ([]domain.MailLogEntry, error) {
+	var entries []domain.MailLogEntry
+
+	tx := r.db.WithContext(ctx)
+	if filter.UserId != "" {
+		tx = tx.Where("user_identifier = ?", filter.UserId)
+	}
+	if filter.PeerId != "" {
+		tx = tx.Where("peer_identifier = ?", filter.PeerId)
+	}
+	if filter.Search != "" {
+		searchValue := "%" + strings.ToLower(filter.Search) + "%"
+		tx = tx.Where("LOWER(recipients) LIKE ? OR LOWER(subject) LIKE ? OR LOWER(template) LIKE ?",
+			searchValue, searchValue, searchValue)
+	}
+
+	err := tx.Order("sent_at desc").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetMailLogEntryByTrackingToken returns the mail log entry of the tracked mail with the given token.
+// If no entry is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetMailLogEntryByTrackingToken(ctx context.Context, token string) (*domain.MailLogEntry, error) {
+	if token == "" {
+		return nil, domain.ErrNotFound // untracked mails have no token
+	}
+
+	var entry domain.MailLogEntry
+
+	err := r.db.WithContext(ctx).Where("tracking_token = ?", token).First(&entry).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// DeleteMailLogEntries deletes all mail log entries that were created before the given time.
+func (r *SqlRepo) DeleteMailLogEntries(ctx context.Context, before time.Time) error {
+	err := r.db.WithContext(ctx).Where("sent_at < ?", before).Delete(&domain.MailLogEntry{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUserMailLogEntries deletes all mail log entries of mails that were sent to the given user.
+func (r *SqlRepo) DeleteUserMailLogEntries(ctx context.Context, id domain.UserIdentifier) error {
+	err := r.db.WithContext(ctx).Where("user_identifier = ?", id).Delete(&domain.MailLogEntry{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion mail-log
+
+// region peer-cleanup
+
+// GetPeerCleanupNotices returns all stored peer cleanup notices.
+func (r *SqlRepo) GetPeerCleanupNotices(ctx context.Context) ([]domain.PeerCleanupNotice, error) {
+	var notices []domain.PeerCleanupNotice
+
+	err := r.db.WithContext(ctx).Find(&notices).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return notices, nil
+}
+
+// SavePeerCleanupNotice creates or updates the given peer cleanup notice.
+func (r *SqlRepo) SavePeerCleanupNotice(ctx context.Context, notice *domain.PeerCleanupNotice) error {
+	err := r.db.WithContext(ctx).Save(notice).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeletePeerCleanupNotice deletes the peer cleanup notice for the given peer id.
+func (r *SqlRepo) DeletePeerCleanupNotice(ctx context.Context, id domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Delete(&domain.PeerCleanupNotice{}, id).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion peer-cleanup
+
+// region peer-expiry
+
+// GetPeerExpiryReminders returns all stored peer expiry reminders.
+func (r *SqlRepo) GetPeerExpiryReminders(ctx context.Context) ([]domain.PeerExpiryReminder, error) {
+	var reminders []domain.PeerExpiryReminder
+
+	err := r.db.WithContext(ctx).Find(&reminders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+// SavePeerExpiryReminder creates or updates the given peer expiry reminder.
+func (r *SqlRepo) SavePeerExpiryReminder(ctx context.Context, reminder *domain.PeerExpiryReminder) error {
+	err := r.db.WithContext(ctx).Save(reminder).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeletePeerExpiryReminder deletes the peer expiry reminder for the given peer id.
+func (r *SqlRepo) DeletePeerExpiryReminder(ctx context.Context, id domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Delete(&domain.PeerExpiryReminder{}, id).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion peer-expiry
+
+// region peer-setup-reminders
+
+// GetPeerSetupReminders returns all stored peer setup reminders.
+func (r *SqlRepo) GetPeerSetupReminders(ctx context.Context) ([]domain.PeerSetupReminder, error) {
+	var reminders []domain.PeerSetupReminder
+
+	err := r.db.WithContext(ctx).Find(&reminders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+// SavePeerSetupReminder creates or updates the given peer setup reminder.
+func (r *SqlRepo) SavePeerSetupReminder(ctx context.Context, reminder *domain.PeerSetupReminder) error {
+	err := r.db.WithContext(ctx).Save(reminder).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeletePeerSetupReminder deletes the peer setup reminder for the given peer id.
+func (r *SqlRepo) DeletePeerSetupReminder(ctx context.Context, id domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Delete(&domain.PeerSetupReminder{}, id).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion peer-setup-reminders
+
+// region peer-config-versions
+
+// GetPeerConfigVersions returns the recorded configuration versions of the given peer, ordered by version.
+func (r *SqlRepo) GetPeerConfigVersions(ctx context.Context, id domain.PeerIdentifier) (
+	[]domain.PeerConfigVersion,
+	error,
+) {
+	var versions []domain.PeerConfigVersion
+
+	err := r.db.WithContext(ctx).Where("identifier = ?", id).Order("version ASC").Find(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// SavePeerConfigVersion creates or updates the given peer configuration version.
+func (r *SqlRepo) SavePeerConfigVersion(ctx context.Context, version *domain.PeerConfigVersion) error {
+	err := r.db.WithContext(ctx).Save(version).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeletePeerConfigVersion deletes the given configuration version of the peer.
+func (r *SqlRepo) DeletePeerConfigVersion(ctx context.Context, id domain.PeerIdentifier, version int) error {
+	err := r.db.WithContext(ctx).
+		Delete(&domain.PeerConfigVersion{}, "identifier = ? AND version = ?", id, version).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion peer-config-versions
+
+// region peer-transfers
+
+// GetPeerTransfer returns the peer transfer with the given id.
+// If no transfer is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetPeerTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (
+	*domain.PeerTransfer,
+	error,
+) {
+	var transfer domain.PeerTransfer
+
+	err := r.db.WithContext(ctx).Where("identifier = ?", id).First(&transfer).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+// GetPeerTransfers returns all peer transfers.
+func (r *SqlRepo) GetPeerTransfers(ctx context.Context) ([]domain.PeerTransfer, error) {
+	var transfers []domain.PeerTransfer
+
+	err := r.db.WithContext(ctx).Order("requested_at desc").Find(&transfers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+// SavePeerTransfer creates or updates the given peer transfer.
+func (r *SqlRepo) SavePeerTransfer(ctx context.Context, transfer *domain.PeerTransfer) error {
+	err := r.db.WithContext(ctx).Save(transfer).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdatePeerTransferPeer moves all transfers of a peer to the new peer identifier.
+func (r *SqlRepo) UpdatePeerTransferPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Model(&domain.PeerTransfer{}).
+		Where("peer_identifier = ?", oldId).
+		Update("peer_identifier", newId).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion peer-transfers
+
+// region emergency-lockdowns
+
+// GetEmergencyLockdown returns the emergency lockdown with the given id.
+// If no lockdown is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetEmergencyLockdown(ctx context.Context, id domain.EmergencyLockdownIdentifier) (
+	*domain.EmergencyLockdown,
+	error,
+) {
+	var lockdown domain.EmergencyLockdown
+
+	err := r.db.WithContext(ctx).Where("identifier = ?", id).First(&lockdown).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &lockdown, nil
+}
+
+// GetEmergencyLockdowns returns all emergency lockdowns, the most recent lockdowns first.
+func (r *SqlRepo) GetEmergencyLockdowns(ctx context.Context) ([]domain.EmergencyLockdown, error) {
+	var lockdowns []domain.EmergencyLockdown
+
+	err := r.db.WithContext(ctx).Order("created_at desc").Find(&lockdowns).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return lockdowns, nil
+}
+
+// SaveEmergencyLockdown creates or updates the given emergency lockdown.
+func (r *SqlRepo) SaveEmergencyLockdown(ctx context.Context, lockdown *domain.EmergencyLockdown) error {
+	err := r.db.WithContext(ctx).Save(lockdown).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion emergency-lockdowns
+
+// region login-devices
+
+// GetUserLoginDevices returns all known login devices of the given user.
+func (r *SqlRepo) GetUserLoginDevices(ctx context.Context, id domain.UserIdentifier) (
+	[]domain.UserLoginDevice,
+	error,
+) {
+	var devices []domain.UserLoginDevice
+
+	err := r.db.WithContext(ctx).Where("user_identifier = ?", id).Find(&devices).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+// SaveUserLoginDevice creates or updates the given login device.
+func (r *SqlRepo) SaveUserLoginDevice(ctx context.Context, device *domain.UserLoginDevice) error {
+	err := r.db.WithContext(ctx).Save(device).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUserLoginDevice deletes the login device with the given identifier.
+func (r *SqlRepo) DeleteUserLoginDevice(ctx context.Context, id string) error {
+	err := r.db.WithContext(ctx).Delete(&domain.UserLoginDevice{}, "identifier = ?", id).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUserLoginDevices deletes all login devices of the given user.
+func (r *SqlRepo) DeleteUserLoginDevices(ctx context.Context, id domain.UserIdentifier) error {
+	err := r.db.WithContext(ctx).Where("user_identifier = ?", id).Delete(&domain.UserLoginDevice{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion login-devices
+
+// region notification-preferences
+
+// GetUserNotificationPreferences returns the notification preferences of the given user.
+func (r *SqlRepo) GetUserNotificationPreferences(ctx context.Context, id domain.UserIdentifier) (
+	*domain.UserNotificationPreferences,
+	error,
+) {
+	var prefs domain.UserNotificationPreferences
+
+	err := r.db.WithContext(ctx).Where("user_identifier = ?", id).First(&prefs).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &prefs, nil
+}
+
+// SaveUserNotificationPreferences creates or updates the given notification preferences.
+func (r *SqlRepo) SaveUserNotificationPreferences(ctx context.Context, prefs *domain.UserNotificationPreferences) error {
+	err := r.db.WithContext(ctx).Save(prefs).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUserNotificationPreferences deletes the notification preferences of the given user.
+func (r *SqlRepo) DeleteUserNotificationPreferences(ctx context.Context, id domain.UserIdentifier) error {
+	err := r.db.WithContext(ctx).Delete(&domain.UserNotificationPreferences{}, "user_identifier = ?", id).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion notification-preferences
+
+// region saved-views
+
+// GetSavedView returns the saved view with the given id.
+// If no view is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetSavedView(ctx context.Context, id uint64) (*domain.SavedView, error) {
+	var view domain.SavedView
+
+	err := r.db.WithContext(ctx).First(&view, id).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &view, nil
+}
+
+// GetSavedViews returns all saved views of the given list, ordered by name.
+func (r *SqlRepo) GetSavedViews(ctx context.Context, list domain.SavedViewList) ([]domain.SavedView, error) {
+	var views []domain.SavedView
+
+	err := r.db.WithContext(ctx).Where("list_name = ?", list).Order("name").Find(&views).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+// SaveSavedView creates or updates the given saved view.
+func (r *SqlRepo) SaveSavedView(ctx context.Context, view *domain.SavedView) error {
+	err := r.db.WithContext(ctx).Save(view).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteSavedView deletes the saved view with the given id.
+func (r *SqlRepo) DeleteSavedView(ctx context.Context, id uint64) error {
+	err := r.db.WithContext(ctx).Delete(&domain.SavedView{}, id).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUserSavedViews deletes all saved views of the given user.
+func (r *SqlRepo) DeleteUserSavedViews(ctx context.Context, id domain.UserIdentifier) error {
+	err := r.db.WithContext(ctx).Where("user_identifier = ?", id).Delete(&domain.SavedView{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion saved-views
+
+// region idempotency
+
+// GetIdempotencyRecord returns the stored response for the given idempotency key of the given user.
+// If no response is stored, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetIdempotencyRecord(ctx context.Context, userId domain.UserIdentifier, key string) (
+	*domain.IdempotencyRecord,
+	error,
+) {
+	var record domain.IdempotencyRecord
+
+	err := r.db.WithContext(ctx).Where("user_identifier = ? AND idempotency_key = ?", userId, key).
+		First(&record).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// SaveIdempotencyRecord creates or updates the given idempotency record.
+func (r *SqlRepo) SaveIdempotencyRecord(ctx context.Context, record *domain.IdempotencyRecord) error {
+	err := r.db.WithContext(ctx).Save(record).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteIdempotencyRecords deletes all idempotency records that were created before the given time.
+func (r *SqlRepo) DeleteIdempotencyRecords(ctx context.Context, before time.Time) error {
+	err := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&domain.IdempotencyRecord{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion idempotency
+
+// region webhook-events
+
+// SaveWebhookEvent stores the given webhook event. New events receive the next event id.
+func (r *SqlRepo) SaveWebhookEvent(ctx context.Context, event *domain.WebhookEvent) error {
+	err := r.db.WithContext(ctx).Save(event).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetWebhookEvent returns the webhook event with the given id.
+// If no event is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetWebhookEvent(ctx context.Context, id uint64) (*domain.WebhookEvent, error) {
+	var event domain.WebhookEvent
+
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&event).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// GetWebhookEvents returns the webhook events with an id greater than the given id, ordered by id.
+// If limit is greater than zero, at most limit events are returned.
+func (r *SqlRepo) GetWebhookEvents(ctx context.Context, afterId uint64, limit int) ([]domain.WebhookEvent, error) {
+	var events []domain.WebhookEvent
+
+	tx := r.db.WithContext(ctx).Where("id > ?", afterId).Order("id ASC")
+	if limit > 0 {
+		tx = tx.Limit(limit)
+	}
+
+	err := tx.Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetLastWebhookEventId returns the id of the most recent webhook event, 0 if no event was stored yet.
+func (r *SqlRepo) GetLastWebhookEventId(ctx context.Context) (uint64, error) {
+	var event domain.WebhookEvent
+
+	err := r.db.WithContext(ctx).Order("id DESC").First(&event).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return event.Id, nil
+}
+
+// DeleteWebhookEvents deletes all webhook events that were created before the given time, including their
+// delivery records.
+func (r *SqlRepo) DeleteWebhookEvents(ctx context.Context, before time.Time) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		expired := tx.Model(&domain.WebhookEvent{}).Select("id").Where("created_at < ?", before)
+		if err := tx.Where("event_id IN (?)", expired).Delete(&domain.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("created_at < ?", before).Delete(&domain.WebhookEvent{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns the delivery records of the given webhook event, ordered by endpoint.
+func (r *SqlRepo) GetWebhookDeliveries(ctx context.Context, eventId uint64) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+
+	err := r.db.WithContext(ctx).Where("event_id = ?", eventId).Order("endpoint ASC").Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// SaveWebhookDelivery creates or updates the given webhook delivery record.
+func (r *SqlRepo) SaveWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	err := r.db.WithContext(ctx).Save(delivery).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetWebhookCursors returns the cursors of all webhook endpoints.
+func (r *SqlRepo) GetWebhookCursors(ctx context.Context) ([]domain.WebhookCursor, error) {
+	var cursors []domain.WebhookCursor
+
+	err := r.db.WithContext(ctx).Find(&cursors).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return cursors, nil
+}
+
+// SaveWebhookCursor creates or updates the given webhook cursor.
+func (r *SqlRepo) SaveWebhookCursor(ctx context.Context, cursor *domain.WebhookCursor) error {
+	err := r.db.WithContext(ctx).Save(cursor).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion webhook-events
+
+// region acceptable-use
+
+// GetLastAcceptableUseAcceptance returns the most recent acceptance of the acceptable use policy by the given user.
+// If the user never accepted the policy, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetLastAcceptableUseAcceptance(ctx context.Context, id domain.UserIdentifier) (
+	*domain.AcceptableUseAcceptance,
+	error,
+) {
+	var acceptance domain.AcceptableUseAcceptance
+
+	err := r.db.WithContext(ctx).Where("user_identifier = ?", id).Order("accepted_at DESC").
+		First(&acceptance).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &acceptance, nil
+}
+
+// GetAcceptableUseAcceptances returns all acceptances of the acceptable use policy by the given user, the most
+// recent acceptances first.
+func (r *SqlRepo) GetAcceptableUseAcceptances(ctx context.Context, id domain.UserIdentifier) (
+	[]domain.AcceptableUseAcceptance,
+	error,
+) {
+	var acceptances []domain.AcceptableUseAcceptance
+
+	err := r.db.WithContext(ctx).Where("user_identifier = ?", id).Order("accepted_at DESC").
+		Find(&acceptances).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return acceptances, nil
+}
+
+// SaveAcceptableUseAcceptance creates or updates the given acceptance.
+func (r *SqlRepo) SaveAcceptableUseAcceptance(ctx context.Context, acceptance *domain.AcceptableUseAcceptance) error {
+	err := r.db.WithContext(ctx).Save(acceptance).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAcceptableUseAcceptances deletes all acceptances of the given user.
+func (r *SqlRepo) DeleteAcceptableUseAcceptances(ctx context.Context, id domain.UserIdentifier) error {
+	err := r.db.WithContext(ctx).Where("user_identifier = ?", id).Delete(&domain.AcceptableUseAcceptance{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion acceptable-use
+
+// region dns-records
+
+// GetDnsRecordSets returns all DNS record sets that are managed by WireGuard Portal.
+func (r *SqlRepo) GetDnsRecordSets(ctx context.Context) ([]domain.DnsRecordSet, error) {
+	var records []domain.DnsRecordSet
+
+	err := r.db.WithContext(ctx).Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// GetPeerDnsRecordSets returns all DNS record sets that are owned by the given peer.
+func (r *SqlRepo) GetPeerDnsRecordSets(ctx context.Context, id domain.PeerIdentifier) ([]domain.DnsRecordSet, error) {
+	var records []domain.DnsRecordSet
+
+	err := r.db.WithContext(ctx).Where("peer_identifier = ?", id).Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// SaveDnsRecordSet creates or updates the given DNS record set.
+func (r *SqlRepo) SaveDnsRecordSet(ctx context.Context, record *domain.DnsRecordSet) error {
+	err := r.db.WithContext(ctx).Save(record).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteDnsRecordSet deletes the DNS record set with the given name and type.
+func (r *SqlRepo) DeleteDnsRecordSet(ctx context.Context, name string, recordType domain.DnsRecordType) error {
+	err := r.db.WithContext(ctx).Where("name = ? AND type = ?", name, recordType).
+		Delete(&domain.DnsRecordSet{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion dns-records
+
+// region route-sets
+
+// GetRouteSet returns the route set with the given id.
+// If no route set is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetRouteSet(ctx context.Context, id domain.RouteSetIdentifier) (*domain.RouteSet, error) {
+	var routeSet domain.RouteSet
+
+	err := r.db.WithContext(ctx).First(&routeSet, id).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &routeSet, nil
+}
+
+// GetAllRouteSets returns all route sets.
+func (r *SqlRepo) GetAllRouteSets(ctx context.Context) ([]domain.RouteSet, error) {
+	var routeSets []domain.RouteSet
+
+	err := r.db.WithContext(ctx).Order("identifier").Find(&routeSets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return routeSets, nil
+}
+
+// SaveRouteSet updates the route set with the given id.
+// If no route set is found, a new route set is created.
+func (r *SqlRepo) SaveRouteSet(
+	ctx context.Context,
+	id domain.RouteSetIdentifier,
+	updateFunc func(rs *domain.RouteSet) (*domain.RouteSet, error),
+) error {
+	userInfo := domain.GetUserInfo(ctx)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		routeSet, err := r.getOrCreateRouteSet(userInfo, tx, id)
+		if err != nil {
+			return err // return any error will roll back
+		}
+
+		routeSet, err = updateFunc(routeSet)
+		if err != nil {
+			return err
+		}
+
+		routeSet.UpdatedBy = userInfo.UserId()
+		routeSet.UpdatedAt = time.Now()
+
+		// return nil will commit the whole transaction
+		return tx.Save(routeSet).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SqlRepo) getOrCreateRouteSet(ui *domain.ContextUserInfo, tx *gorm.DB, id domain.RouteSetIdentifier) (
+	*domain.RouteSet,
+	error,
+) {
+	var routeSet domain.RouteSet
+
+	// routeSetDefaults will be applied to newly created route set records
+	routeSetDefaults := domain.RouteSet{
+		BaseModel: domain.BaseModel{
+			CreatedBy: ui.UserId(),
+			UpdatedBy: ui.UserId(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		Identifier: id,
+	}
+
+	err := tx.Attrs(routeSetDefaults).FirstOrCreate(&routeSet, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &routeSet, nil
+}
+
+// DeleteRouteSet deletes the route set with the given id.
+func (r *SqlRepo) DeleteRouteSet(ctx context.Context, id domain.RouteSetIdentifier) error {
+	err := r.db.WithContext(ctx).Delete(&domain.RouteSet{Identifier: id}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion route-sets
+
+// region alerts
+
+// GetAlerts returns all firing alerts.
+func (r *SqlRepo) GetAlerts(ctx context.Context) ([]domain.Alert, error) {
+	var alerts []domain.Alert
+
+	err := r.db.WithContext(ctx).Order("fired_at").Find(&alerts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+// SaveAlert creates or updates the given alert.
+func (r *SqlRepo) SaveAlert(ctx context.Context, alert *domain.Alert) error {
+	err := r.db.WithContext(ctx).Save(alert).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAlert deletes the alert of the given rule and peer.
+func (r *SqlRepo) DeleteAlert(ctx context.Context, ruleName string, peerId domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).
+		Where("rule_name = ? AND peer_identifier = ?", ruleName, peerId).
+		Delete(&domain.Alert{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeletePeerAlerts deletes all alerts of the given peer.
+func (r *SqlRepo) DeletePeerAlerts(ctx context.Context, peerId domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Where("peer_identifier = ?", peerId).Delete(&domain.Alert{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAlertSilences returns all alert silences.
+func (r *SqlRepo) GetAlertSilences(ctx context.Context) ([]domain.AlertSilence, error) {
+	var silences []domain.AlertSilence
+
+	err := r.db.WithContext(ctx).Order("starts_at").Find(&silences).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return silences, nil
+}
+
+// SaveAlertSilence creates or updates the given alert silence.
+func (r *SqlRepo) SaveAlertSilence(ctx context.Context, silence *domain.AlertSilence) error {
+	err := r.db.WithContext(ctx).Save(silence).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAlertSilence deletes the alert silence with the given id.
+func (r *SqlRepo) DeleteAlertSilence(ctx context.Context, id uint64) error {
+	err := r.db.WithContext(ctx).Delete(&domain.AlertSilence{}, id).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion alerts
+
+// region security-events
+
+// GetSecurityEvents returns all security events, the most recent events first.
+func (r *SqlRepo) GetSecurityEvents(ctx context.Context) ([]domain.SecurityEvent, error) {
+	var events []domain.SecurityEvent
+
+	err := r.db.WithContext(ctx).Order("created_at desc").Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// SaveSecurityEvent creates or updates the given security event.
+func (r *SqlRepo) SaveSecurityEvent(ctx context.Context, event *domain.SecurityEvent) error {
+	err := r.db.WithContext(ctx).Save(event).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteSecurityEvents deletes all security events that were created before the given time.
+func (r *SqlRepo) DeleteSecurityEvents(ctx context.Context, before time.Time) error {
+	err := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&domain.SecurityEvent{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion security-events
+
+// region announcements
+
+// GetAnnouncement returns the announcement with the given id.
+// If no announcement is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetAnnouncement(ctx context.Context, id uint64) (*domain.Announcement, error) {
+	var announcement domain.Announcement
+
+	err := r.db.WithContext(ctx).First(&announcement, id).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &announcement, nil
+}
+
+// GetAnnouncements returns all announcements, the most recently started announcements first.
+func (r *SqlRepo) GetAnnouncements(ctx context.Context) ([]domain.Announcement, error) {
+	var announcements []domain.Announcement
+
+	err := r.db.WithContext(ctx).Order("starts_at desc").Find(&announcements).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return announcements, nil
+}
+
+// SaveAnnouncement creates or updates the given announcement.
+func (r *SqlRepo) SaveAnnouncement(ctx context.Context, announcement *domain.Announcement) error {
+	err := r.db.WithContext(ctx).Save(announcement).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAnnouncement deletes the announcement with the given id and all read markers of it.
+func (r *SqlRepo) DeleteAnnouncement(ctx context.Context, id uint64) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("announcement_id = ?", id).Delete(&domain.AnnouncementRead{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.Announcement{}, id).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAnnouncementReads returns all read markers of the given announcement.
+func (r *SqlRepo) GetAnnouncementReads(ctx context.Context, id uint64) ([]domain.AnnouncementRead, error) {
+	var reads []domain.AnnouncementRead
+
+	err := r.db.WithContext(ctx).Where("announcement_id = ?", id).Order("read_at").Find(&reads).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return reads, nil
+}
+
+// SaveAnnouncementRead creates or updates the given read marker.
+func (r *SqlRepo) SaveAnnouncementRead(ctx context.Context, read *domain.AnnouncementRead) error {
+	err := r.db.WithContext(ctx).Save(read).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion announcements
+
+// region export-approvals
+
+// GetExportApproval returns the export approval request with the given id.
+// If no request is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetExportApproval(ctx context.Context, id uint64) (*domain.ExportApproval, error) {
+	var approval domain.ExportApproval
+
+	err := r.db.WithContext(ctx).First(&approval, id).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &approval, nil
+}
+
+// GetExportApprovals returns all export approval requests, the most recent requests first.
+func (r *SqlRepo) GetExportApprovals(ctx context.Context) ([]domain.ExportApproval, error) {
+	var approvals []domain.ExportApproval
+
+	err := r.db.WithContext(ctx).Order("requested_at desc").Find(&approvals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return approvals, nil
+}
+
+// SaveExportApproval creates or updates the given export approval request.
+func (r *SqlRepo) SaveExportApproval(ctx context.Context, approval *domain.ExportApproval) error {
+	err := r.db.WithContext(ctx).Save(approval).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetKeyExports returns the private key exports of the given user since the given time.
+func (r *SqlRepo) GetKeyExports(
+	ctx context.Context,
+	id domain.UserIdentifier,
+	since time.Time,
+) ([]domain.KeyExport, error) {
+	var exports []domain.KeyExport
+
+	err := r.db.WithContext(ctx).Where("user_identifier = ? AND exported_at > ?", id, since).Find(&exports).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// SaveKeyExport creates or updates the given private key export.
+func (r *SqlRepo) SaveKeyExport(ctx context.Context, export *domain.KeyExport) error {
+	err := r.db.WithContext(ctx).Save(export).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion export-approvals
+
+// region config-pull
+
+// GetConfigPullToken returns the config pull token with the given hash.
+func (r *SqlRepo) GetConfigPullToken(ctx context.Context, tokenHash string) (*domain.ConfigPullToken, error) {
+	var token domain.ConfigPullToken
+
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetAllConfigPullTokens returns all config pull tokens.
+func (r *SqlRepo) GetAllConfigPullTokens(ctx context.Context) ([]domain.ConfigPullToken, error) {
+	var tokens []domain.ConfigPullToken
+
+	err := r.db.WithContext(ctx).Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// GetPeerConfigPullToken returns the config pull token of the given peer.
+func (r *SqlRepo) GetPeerConfigPullToken(ctx context.Context, peerId domain.PeerIdentifier) (
+	*domain.ConfigPullToken,
+	error,
+) {
+	var token domain.ConfigPullToken
+
+	err := r.db.WithContext(ctx).Where("peer_identifier = ?", peerId).First(&token).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// SaveConfigPullToken stores the given config pull token, replacing an existing token of the same peer.
+func (r *SqlRepo) SaveConfigPullToken(ctx context.Context, token *domain.ConfigPullToken) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("peer_identifier = ? AND token_hash <> ?", token.PeerId, token.TokenHash).
+			Delete(&domain.ConfigPullToken{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Save(token).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeletePeerConfigPullToken deletes the config pull token of the given peer.
+func (r *SqlRepo) DeletePeerConfigPullToken(ctx context.Context, peerId domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Where("peer_identifier = ?", peerId).Delete(&domain.ConfigPullToken{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateConfigPullTokenPeer moves the config pull token of a peer to the new peer identifier.
+func (r *SqlRepo) UpdateConfigPullTokenPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Model(&domain.ConfigPullToken{}).
+		Where("peer_identifier = ?", oldId).
+		Update("peer_identifier", newId).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion config-pull
+
+// region ssh-deployments
+
+// GetSshDeployment returns the SSH deployment of the given peer.
+func (r *SqlRepo) GetSshDeployment(ctx context.Context, peerId domain.PeerIdentifier) (*domain.SshDeployment, error) {
+	var deployment domain.SshDeployment
+
+	err := r.db.WithContext(ctx).Where("peer_identifier = ?", peerId).First(&deployment).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &deployment, nil
+}
+
+// GetAllSshDeployments returns all SSH deployments.
+func (r *SqlRepo) GetAllSshDeployments(ctx context.Context) ([]domain.SshDeployment, error) {
+	var deployments []domain.SshDeployment
+
+	err := r.db.WithContext(ctx).Find(&deployments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deployments, nil
+}
+
+// SaveSshDeployment creates or updates the given SSH deployment.
+func (r *SqlRepo) SaveSshDeployment(ctx context.Context, deployment *domain.SshDeployment) error {
+	err := r.db.WithContext(ctx).Save(deployment).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteSshDeployment deletes the SSH deployment of the given peer.
+func (r *SqlRepo) DeleteSshDeployment(ctx context.Context, peerId domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Where("peer_identifier = ?", peerId).Delete(&domain.SshDeployment{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateSshDeploymentPeer moves the SSH deployment of a peer to the new peer identifier.
+func (r *SqlRepo) UpdateSshDeploymentPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Model(&domain.SshDeployment{}).
+		Where("peer_identifier = ?", oldId).
+		Update("peer_identifier", newId).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion ssh-deployments
+
+// region organizations
+
+// GetOrganization returns the organization with the given id.
+// If no organization is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetOrganization(ctx context.Context, id domain.OrganizationIdentifier) (
+	*domain.Organization,
+	error,
+) {
+	var organization domain.Organization
+
+	err := r.db.WithContext(ctx).First(&organization, id).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &organization, nil
+}
+
+// GetAllOrganizations returns all organizations.
+func (r *SqlRepo) GetAllOrganizations(ctx context.Context) ([]domain.Organization, error) {
+	var organizations []domain.Organization
+
+	err := r.db.WithContext(ctx).Order("identifier").Find(&organizations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return organizations, nil
+}
+
+// SaveOrganization updates the organization with the given id.
+// If no organization is found, a new organization is created.
+func (r *SqlRepo) SaveOrganization(
+	ctx context.Context,
+	id domain.OrganizationIdentifier,
+	updateFunc func(o *domain.Organization) (*domain.Organization, error),
+) error {
+	userInfo := domain.GetUserInfo(ctx)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		organization, err := r.getOrCreateOrganization(userInfo, tx, id)
+		if err != nil {
+			return err // return any error will roll back
+		}
+
+		organization, err = updateFunc(organization)
+		if err != nil {
+			return err
+		}
+
+		organization.UpdatedBy = userInfo.UserId()
+		organization.UpdatedAt = time.Now()
+
+		// return nil will commit the whole transaction
+		return tx.Save(organization).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SqlRepo) getOrCreateOrganization(
+	ui *domain.ContextUserInfo,
+	tx *gorm.DB,
+	id domain.OrganizationIdentifier,
+) (*domain.Organization, error) {
+	var organization domain.Organization
+
+	// organizationDefaults will be applied to newly created organization records
+	organizationDefaults := domain.Organization{
+		BaseModel: domain.BaseModel{
+			CreatedBy: ui.UserId(),
+			UpdatedBy: ui.UserId(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		Identifier: id,
+	}
+
+	err := tx.Attrs(organizationDefaults).FirstOrCreate(&organization, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &organization, nil
+}
+
+// DeleteOrganization deletes the organization with the given id.
+func (r *SqlRepo) DeleteOrganization(ctx context.Context, id domain.OrganizationIdentifier) error {
+	err := r.db.WithContext(ctx).Delete(&domain.Organization{Identifier: id}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IsOrganizationInUse returns true if at least one interface or user belongs to the given organization.
+func (r *SqlRepo) IsOrganizationInUse(ctx context.Context, id domain.OrganizationIdentifier) (bool, error) {
+	var interfaceCount, userCount int64
+
+	err := r.db.WithContext(ctx).Model(&domain.Interface{}).
+		Where("organization_identifier = ?", id).Count(&interfaceCount).Error
+	if err != nil {
+		return false, err
+	}
+
+	err = r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("organization_identifier = ?", id).Count(&userCount).Error
+	if err != nil {
+		return false, err
+	}
+
+	return interfaceCount > 0 || userCount > 0, nil
+}
+
+// endregion organizations
+
+// region user-groups
+
+// GetUserGroup returns the user group with the given id.
+// If no user group is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetUserGroup(ctx context.Context, id domain.UserGroupIdentifier) (*domain.UserGroup, error) {
+	var group domain.UserGroup
+
+	err := r.db.WithContext(ctx).First(&group, id).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// GetAllUserGroups returns all user groups.
+func (r *SqlRepo) GetAllUserGroups(ctx context.Context) ([]domain.UserGroup, error) {
+	var groups []domain.UserGroup
+
+	err := r.db.WithContext(ctx).Order("identifier").Find(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// SaveUserGroup updates the user group with the given id.
+// If no user group is found, a new user group is created.
+func (r *SqlRepo) SaveUserGroup(
+	ctx context.Context,
+	id domain.UserGroupIdentifier,
+	updateFunc func(g *domain.UserGroup) (*domain.UserGroup, error),
+) error {
+	userInfo := domain.GetUserInfo(ctx)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		group, err := r.getOrCreateUserGroup(userInfo, tx, id)
+		if err != nil {
+			return err // return any error will roll back
+		}
+
+		group, err = updateFunc(group)
+		if err != nil {
+			return err
+		}
+
+		group.UpdatedBy = userInfo.UserId()
+		group.UpdatedAt = time.Now()
+
+		// return nil will commit the whole transaction
+		return tx.Save(group).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SqlRepo) getOrCreateUserGroup(
+	ui *domain.ContextUserInfo,
+	tx *gorm.DB,
+	id domain.UserGroupIdentifier,
+) (*domain.UserGroup, error) {
+	var group domain.UserGroup
+
+	// groupDefaults will be applied to newly created user group records
+	groupDefaults := domain.UserGroup{
+		BaseModel: domain.BaseModel{
+			CreatedBy: ui.UserId(),
+			UpdatedBy: ui.UserId(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		Identifier: id,
+	}
+
+	err := tx.Attrs(groupDefaults).FirstOrCreate(&group, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// DeleteUserGroup deletes the user group with the given id.
+func (r *SqlRepo) DeleteUserGroup(ctx context.Context, id domain.UserGroupIdentifier) error {
+	err := r.db.WithContext(ctx).Delete(&domain.UserGroup{Identifier: id}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion user-groups
+
+// region port-forwards
+
+// GetPortForward returns the port forward with the given id.
+// If no port forward is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetPortForward(ctx context.Context, id domain.PortForwardIdentifier) (*domain.PortForward, error) {
+	var forward domain.PortForward
+
+	err := r.db.WithContext(ctx).Where("identifier = ?", id).First(&forward).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &forward, nil
+}
+
+// GetPortForwards returns all port forwards, ordered by protocol and external port.
+func (r *SqlRepo) GetPortForwards(ctx context.Context) ([]domain.PortForward, error) {
+	var forwards []domain.PortForward
+
+	err := r.db.WithContext(ctx).Order("protocol, external_port").Find(&forwards).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return forwards, nil
+}
+
+// SavePortForward creates or updates the given port forward.
+func (r *SqlRepo) SavePortForward(ctx context.Context, forward *domain.PortForward) error {
+	err := r.db.WithContext(ctx).Save(forward).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeletePortForward deletes the port forward with the given id.
+func (r *SqlRepo) DeletePortForward(ctx context.Context, id domain.PortForwardIdentifier) error {
+	err := r.db.WithContext(ctx).Delete(&domain.PortForward{Identifier: id}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdatePortForwardPeer moves all port forwards of a peer to the new peer identifier.
+func (r *SqlRepo) UpdatePortForwardPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Model(&domain.PortForward{}).
+		Where("peer_identifier = ?", oldId).
+		Update("peer_identifier", newId).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion port-forwards
+
+// region access-requests
+
+// GetAccessRequest returns the access request with the given id.
+// If no request is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetAccessRequest(ctx context.Context, id domain.AccessRequestIdentifier) (
+	*domain.AccessRequest,
+	error,
+) {
+	var request domain.AccessRequest
+
+	err := r.db.WithContext(ctx).Where("identifier = ?", id).First(&request).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// GetAccessRequests returns all access requests.
+func (r *SqlRepo) GetAccessRequests(ctx context.Context) ([]domain.AccessRequest, error) {
+	var requests []domain.AccessRequest
+
+	err := r.db.WithContext(ctx).Order("requested_at desc").Find(&requests).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// SaveAccessRequest creates or updates the given access request.
+func (r *SqlRepo) SaveAccessRequest(ctx context.Context, request *domain.AccessRequest) error {
+	err := r.db.WithContext(ctx).Save(request).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateAccessRequestPeer moves all access requests of a peer to the new peer identifier.
+func (r *SqlRepo) UpdateAccessRequestPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Model(&domain.AccessRequest{}).
+		Where("peer_identifier = ?", oldId).
+		Update("peer_identifier", newId).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion access-requests
+
+// region route-reviews
+
+// GetRouteReview returns the route review with the given id.
+// If no review is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetRouteReview(ctx context.Context, id domain.RouteReviewIdentifier) (
+	*domain.RouteReview,
+	error,
+) {
+	var review domain.RouteReview
+
+	err := r.db.WithContext(ctx).Where("identifier = ?", id).First(&review).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+// GetRouteReviews returns all route reviews.
+func (r *SqlRepo) GetRouteReviews(ctx context.Context) ([]domain.RouteReview, error) {
+	var reviews []domain.RouteReview
+
+	err := r.db.WithContext(ctx).Order("reviewed_at desc").Find(&reviews).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+// SaveRouteReview creates or updates the given route review.
+func (r *SqlRepo) SaveRouteReview(ctx context.Context, review *domain.RouteReview) error {
+	err := r.db.WithContext(ctx).Save(review).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateRouteReviewPeer moves all route reviews of a peer to the new peer identifier.
+func (r *SqlRepo) UpdateRouteReviewPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Model(&domain.RouteReview{}).
+		Where("peer_identifier = ?", oldId).
+		Update("peer_identifier", newId).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion route-reviews
+
+// region address-reservations
+
+// GetAddressReservation returns the address reservation with the given id.
+// If no reservation is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetAddressReservation(ctx context.Context, id domain.AddressReservationIdentifier) (
+	*domain.AddressReservation,
+	error,
+) {
+	var reservation domain.AddressReservation
+
+	err := r.db.WithContext(ctx).Where("identifier = ?", id).First(&reservation).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// GetAddressReservations returns all address reservations of the given interface, ordered by address.
+func (r *SqlRepo) GetAddressReservations(ctx context.Context, id domain.InterfaceIdentifier) (
+	[]domain.AddressReservation,
+	error,
+) {
+	var reservations []domain.AddressReservation
+
+	err := r.db.WithContext(ctx).Where("interface_identifier = ?", id).Find(&reservations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// addresses are stored as text, sort them numerically
+	slices.SortFunc(reservations, func(a, b domain.AddressReservation) int {
+		return a.Addr().Compare(b.Addr())
+	})
+
+	return reservations, nil
+}
+
+// SaveAddressReservation creates or updates the given address reservation.
+func (r *SqlRepo) SaveAddressReservation(ctx context.Context, reservation *domain.AddressReservation) error {
+	err := r.db.WithContext(ctx).Save(reservation).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAddressReservation deletes the address reservation with the given id.
+func (r *SqlRepo) DeleteAddressReservation(ctx context.Context, id domain.AddressReservationIdentifier) error {
+	err := r.db.WithContext(ctx).Delete(&domain.AddressReservation{Identifier: id}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion address-reservations
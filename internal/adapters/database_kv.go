@@ -0,0 +1,2625 @@
+package adapters
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	// kvRequestTimeout is the timeout of a single request to the key-value store.
+	kvRequestTimeout = 10 * time.Second
+	// kvMaxUpdateAttempts limits the retries of read-modify-write operations on concurrently modified keys.
+	kvMaxUpdateAttempts = 10
+)
+
+// key prefixes of the stored entities
+const (
+	kvKindSysStat           = "sys-stat"
+	kvKindSequences         = "sequences"
+	kvKindInterfaces        = "interfaces"
+	kvKindPeers             = "peers"
+	kvKindUsers             = "users"
+	kvKindInterfaceStatus   = "interface-status"
+	kvKindPeerStatus        = "peer-status"
+	kvKindPeerStatsSamples  = "peer-stats-samples"
+	kvKindPeerConnections   = "peer-connection-events"
+	kvKindAudit             = "audit"
+	kvKindMailLog           = "mail-log"
+	kvKindPeerCleanup       = "peer-cleanup-notices"
+	kvKindPeerExpiry        = "peer-expiry-reminders"
+	kvKindPeerSetup         = "peer-setup-reminders"
+	kvKindPeerTransfers     = "peer-transfers"
+	kvKindEmergency         = "emergency-lockdowns"
+	kvKindLoginDevices      = "user-login-devices"
+	kvKindNotificationPrefs = "user-notification-preferences"
+	kvKindAcceptableUse     = "acceptable-use-acceptances"
+	kvKindDnsRecordSets     = "dns-record-sets"
+	kvKindRouteSets         = "route-sets"
+	kvKindAlerts            = "alerts"
+	kvKindAlertSilences     = "alert-silences"
+	kvKindConfigPullTokens  = "config-pull-tokens"
+	kvKindOrganizations     = "organizations"
+	kvKindUserGroups        = "user-groups"
+	kvKindSshDeployments    = "ssh-deployments"
+	kvKindPeerConfigVersion = "peer-config-versions"
+	kvKindPortForwards      = "port-forwards"
+	kvKindSecurityEvents    = "security-events"
+	kvKindAnnouncements     = "announcements"
+	kvKindAnnouncementReads = "announcement-reads"
+	kvKindExportApprovals   = "export-approvals"
+	kvKindKeyExports        = "key-exports"
+	kvKindSavedViews        = "saved-views"
+	kvKindIdempotency       = "idempotency-records"
+	kvKindWebhookEvents     = "webhook-events"
+	kvKindWebhookDeliveries = "webhook-deliveries"
+	kvKindWebhookCursors    = "webhook-cursors"
+	kvKindAccessRequests    = "access-requests"
+	kvKindRouteReviews      = "route-reviews"
+	kvKindReservations      = "address-reservations"
+	kvSequenceAudit         = "audit"
+	kvSequenceMailLog       = "mail-log"
+	kvSequenceAlertSilences = "alert-silences"
+	kvSequenceSecurity      = "security-events"
+	kvSequenceAnnouncements = "announcements"
+	kvSequenceApprovals     = "export-approvals"
+	kvSequenceSavedViews    = "saved-views"
+	kvSequenceWebhookEvents = "webhook-events"
+)
+
+// errKvConflict is returned by a key-value store if a conditional write failed because the key was modified.
+var errKvConflict = errors.New("key was modified concurrently")
+
+type kvPair struct {
+	Key      string // the key, relative to the key prefix of the store
+	Value    []byte
+	Revision uint64 // the revision of the last modification
+}
+
+// kvStore is a key-value store with support for conditional writes. All keys are relative to the key prefix of
+// the store.
+type kvStore interface {
+	// get returns the given key. If the key does not exist, domain.ErrNotFound is returned.
+	get(ctx context.Context, key string) (kvPair, error)
+	// list returns all keys with the given prefix, ordered by key.
+	list(ctx context.Context, prefix string) ([]kvPair, error)
+	// put stores the value of the given key.
+	put(ctx context.Context, key string, value []byte) error
+	// compareAndPut stores the value of the given key if the revision of the key did not change. A revision of 0
+	// requires that the key does not exist yet. If the revision changed, errKvConflict is returned.
+	compareAndPut(ctx context.Context, key string, value []byte, revision uint64) error
+	// delete removes the given key. Removing a missing key is not an error.
+	delete(ctx context.Context, key string) error
+}
+
+// ValueEncryptor encrypts sensitive values, like WireGuard private keys, before they are stored.
+type ValueEncryptor interface {
+	// EncryptString encrypts the given value.
+	EncryptString(value string) (string, error)
+	// DecryptString decrypts the given value.
+	DecryptString(value string) (string, error)
+}
+
+// KvRepo is a key-value store repository implementation. Currently, it supports etcd and Consul.
+// All entities are stored as JSON documents. Queries load all entities of a kind, so this repository is
+// intended for small deployments.
+type KvRepo struct {
+	store     kvStore
+	encryptor ValueEncryptor
+}
+
+// NewKeyValueRepository creates a new KvRepo instance for the configured key-value store.
+func NewKeyValueRepository(cfg config.DatabaseConfig, encryptor ValueEncryptor) (*KvRepo, error) {
+	dsn, err := url.Parse(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key-value store url: %w", err)
+	}
+	if dsn.Scheme != "http" && dsn.Scheme != "https" {
+		return nil, fmt.Errorf("invalid key-value store url %q: scheme must be http or https", dsn.Redacted())
+	}
+
+	var store kvStore
+	switch cfg.Type {
+	case config.DatabaseEtcd:
+		store = newEtcdStore(dsn)
+	case config.DatabaseConsul:
+		store = newConsulStore(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported key-value store: %s", cfg.Type)
+	}
+
+	return newKvRepository(newTracedKvStore(store, string(cfg.Type), cfg.SlowQueryThreshold), encryptor)
+}
+
+func newKvRepository(store kvStore, encryptor ValueEncryptor) (*KvRepo, error) {
+	repo := &KvRepo{
+		store:     store,
+		encryptor: encryptor,
+	}
+
+	if err := repo.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to initialize key-value store: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *KvRepo) migrate() error {
+	ctx, cancel := context.WithTimeout(context.Background(), kvRequestTimeout)
+	defer cancel()
+
+	key := kvKey(kvKindSysStat, fmt.Sprintf("%d", SchemaVersion))
+	_, err := r.store.get(ctx, key)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("failed to read sysstat entry: %w", err)
+	}
+
+	sysStat := SysStat{
+		MigratedAt:    time.Now(),
+		SchemaVersion: SchemaVersion,
+	}
+	if err := kvPut(ctx, r.store, key, sysStat); err != nil {
+		return fmt.Errorf("failed to write sysstat entry for schema version %d: %w", SchemaVersion, err)
+	}
+	slog.Debug("sys-stat entry written", "schema_version", SchemaVersion)
+
+	return nil
+}
+
+// region helpers
+
+// kvKey builds a key from the given entity kind and identifiers. The identifiers are escaped, so they can
+// contain slashes, for example, base64 encoded public keys.
+func kvKey(kind string, ids ...string) string {
+	parts := make([]string, 0, len(ids)+1)
+	parts = append(parts, kind)
+	for _, id := range ids {
+		parts = append(parts, url.PathEscape(id))
+	}
+	return strings.Join(parts, "/")
+}
+
+func kvGet[T any](ctx context.Context, store kvStore, key string) (*T, error) {
+	pair, err := store.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var v T
+	if err := json.Unmarshal(pair.Value, &v); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+
+	return &v, nil
+}
+
+func kvList[T any](ctx context.Context, store kvStore, kind string) ([]T, error) {
+	pairs, err := store.list(ctx, kind+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]T, 0, len(pairs))
+	for _, pair := range pairs {
+		var v T
+		if err := json.Unmarshal(pair.Value, &v); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", pair.Key, err)
+		}
+		result = append(result, v)
+	}
+
+	return result, nil
+}
+
+func kvPut(ctx context.Context, store kvStore, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+
+	return store.put(ctx, key, data)
+}
+
+// kvUpdate performs a read-modify-write of the given key. The update function receives nil if the key does not
+// exist yet. If the key is modified concurrently, the update function is called again with the new value.
+func kvUpdate[T any](ctx context.Context, store kvStore, key string, updateFunc func(current *T) (*T, error)) error {
+	for range kvMaxUpdateAttempts {
+		var current *T
+		var revision uint64
+
+		pair, err := store.get(ctx, key)
+		switch {
+		case err == nil:
+			current = new(T)
+			if err := json.Unmarshal(pair.Value, current); err != nil {
+				return fmt.Errorf("failed to decode %s: %w", key, err)
+			}
+			revision = pair.Revision
+		case errors.Is(err, domain.ErrNotFound):
+			// the update function creates a new entity
+		default:
+			return err
+		}
+
+		updated, err := updateFunc(current)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(updated)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+
+		err = store.compareAndPut(ctx, key, data, revision)
+		if errors.Is(err, errKvConflict) {
+			continue // try again with the latest value
+		}
+		return err
+	}
+
+	return fmt.Errorf("failed to update %s: %w", key, errKvConflict)
+}
+
+// nextSequence returns the next value of the given sequence, starting at 1.
+func (r *KvRepo) nextSequence(ctx context.Context, name string) (uint64, error) {
+	var next uint64
+	err := kvUpdate(ctx, r.store, kvKey(kvKindSequences, name), func(current *uint64) (*uint64, error) {
+		next = 1
+		if current != nil {
+			next = *current + 1
+		}
+		return &next, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to update sequence %s: %w", name, err)
+	}
+
+	return next, nil
+}
+
+func kvSequenceId(id uint64) string {
+	return fmt.Sprintf("%020d", id) // zero padded, so that the keys are ordered by id
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func newKvBaseModel(ui *domain.ContextUserInfo) domain.BaseModel {
+	return domain.BaseModel{
+		CreatedBy: ui.UserId(),
+		UpdatedBy: ui.UserId(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func (r *KvRepo) encrypt(value string) (string, error) {
+	if r.encryptor == nil {
+		return value, nil
+	}
+	return r.encryptor.EncryptString(value)
+}
+
+func (r *KvRepo) decrypt(value string) (string, error) {
+	if r.encryptor == nil {
+		return value, nil
+	}
+	return r.encryptor.DecryptString(value)
+}
+
+// endregion helpers
+
+// region interfaces
+
+// encryptInterface returns a copy of the given interface with encrypted sensitive values.
+func (r *KvRepo) encryptInterface(in *domain.Interface) (*domain.Interface, error) {
+	stored := *in
+
+	var err error
+	if stored.PrivateKey, err = r.encrypt(in.PrivateKey); err != nil {
+		return nil, fmt.Errorf("failed to encrypt private key of interface %s: %w", in.Identifier, err)
+	}
+
+	return &stored, nil
+}
+
+func (r *KvRepo) decryptInterface(in *domain.Interface) error {
+	var err error
+	if in.PrivateKey, err = r.decrypt(in.PrivateKey); err != nil {
+		return fmt.Errorf("failed to decrypt private key of interface %s: %w", in.Identifier, err)
+	}
+
+	return nil
+}
+
+// GetInterface returns the interface with the given id.
+// If no interface is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	in, err := kvGet[domain.Interface](ctx, r.store, kvKey(kvKindInterfaces, string(id)))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.decryptInterface(in); err != nil {
+		return nil, err
+	}
+
+	return in, nil
+}
+
+// GetInterfaceAndPeers returns the interface with the given id and all peers associated with it.
+// If no interface is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (
+	*domain.Interface,
+	[]domain.Peer,
+	error,
+) {
+	in, err := r.GetInterface(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load interface: %w", err)
+	}
+
+	peers, err := r.GetInterfacePeers(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load peers: %w", err)
+	}
+
+	return in, peers, nil
+}
+
+// GetPeersStats returns the stats for the given peer ids. The order of the returned stats is not guaranteed.
+func (r *KvRepo) GetPeersStats(ctx context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	stats := make([]domain.PeerStatus, 0, len(ids))
+	for _, id := range ids {
+		stat, err := kvGet[domain.PeerStatus](ctx, r.store, kvKey(kvKindPeerStatus, string(id)))
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, *stat)
+	}
+
+	return stats, nil
+}
+
+// GetAllInterfaces returns all interfaces.
+func (r *KvRepo) GetAllInterfaces(ctx context.Context) ([]domain.Interface, error) {
+	interfaces, err := kvList[domain.Interface](ctx, r.store, kvKindInterfaces)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range interfaces {
+		if err := r.decryptInterface(&interfaces[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return interfaces, nil
+}
+
+// GetInterfaceStats returns the stats for the given interface id.
+// If no stats are found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetInterfaceStats(ctx context.Context, id domain.InterfaceIdentifier) (
+	*domain.InterfaceStatus,
+	error,
+) {
+	if id == "" {
+		return nil, nil
+	}
+
+	return kvGet[domain.InterfaceStatus](ctx, r.store, kvKey(kvKindInterfaceStatus, string(id)))
+}
+
+// FindInterfaces returns all interfaces that match the given search string.
+// The search string is matched against the interface identifier and display name.
+func (r *KvRepo) FindInterfaces(ctx context.Context, search string) ([]domain.Interface, error) {
+	interfaces, err := r.GetAllInterfaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(interfaces, func(in domain.Interface) bool {
+		return !containsFold(string(in.Identifier), search) && !containsFold(in.DisplayName, search)
+	}), nil
+}
+
+// SaveInterface updates the interface with the given id.
+func (r *KvRepo) SaveInterface(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	updateFunc func(in *domain.Interface) (*domain.Interface, error),
+) error {
+	userInfo := domain.GetUserInfo(ctx)
+
+	return kvUpdate(ctx, r.store, kvKey(kvKindInterfaces, string(id)),
+		func(in *domain.Interface) (*domain.Interface, error) {
+			if in == nil {
+				in = &domain.Interface{
+					BaseModel:  newKvBaseModel(userInfo),
+					Identifier: id,
+				}
+			} else if err := r.decryptInterface(in); err != nil {
+				return nil, err
+			}
+
+			in, err := updateFunc(in)
+			if err != nil {
+				return nil, err
+			}
+
+			in.UpdatedBy = userInfo.UserId()
+			in.UpdatedAt = time.Now()
+
+			return r.encryptInterface(in)
+		})
+}
+
+// DeleteInterface deletes the interface with the given id.
+func (r *KvRepo) DeleteInterface(ctx context.Context, id domain.InterfaceIdentifier) error {
+	peers, err := r.GetInterfacePeers(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, peer := range peers {
+		if err := r.store.delete(ctx, kvKey(kvKindPeers, string(peer.Identifier))); err != nil {
+			return err
+		}
+	}
+
+	if err := r.store.delete(ctx, kvKey(kvKindInterfaceStatus, string(id))); err != nil {
+		return err
+	}
+
+	return r.store.delete(ctx, kvKey(kvKindInterfaces, string(id)))
+}
+
+// GetInterfaceIps returns a map of interface identifiers to their respective IP addresses.
+func (r *KvRepo) GetInterfaceIps(ctx context.Context) (map[domain.InterfaceIdentifier][]domain.Cidr, error) {
+	interfaces, err := kvList[domain.Interface](ctx, r.store, kvKindInterfaces)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[domain.InterfaceIdentifier][]domain.Cidr)
+	for _, in := range interfaces {
+		if len(in.Addresses) > 0 {
+			result[in.Identifier] = append(result[in.Identifier], in.Addresses...)
+		}
+	}
+	return result, nil
+}
+
+// endregion interfaces
+
+// region peers
+
+// encryptPeer returns a copy of the given peer with encrypted sensitive values.
+func (r *KvRepo) encryptPeer(peer *domain.Peer) (*domain.Peer, error) {
+	stored := *peer
+
+	var err error
+	if stored.Interface.PrivateKey, err = r.encrypt(peer.Interface.PrivateKey); err != nil {
+		return nil, fmt.Errorf("failed to encrypt private key of peer %s: %w", peer.Identifier, err)
+	}
+	presharedKey, err := r.encrypt(string(peer.PresharedKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt preshared key of peer %s: %w", peer.Identifier, err)
+	}
+	stored.PresharedKey = domain.PreSharedKey(presharedKey)
+
+	return &stored, nil
+}
+
+func (r *KvRepo) decryptPeer(peer *domain.Peer) error {
+	var err error
+	if peer.Interface.PrivateKey, err = r.decrypt(peer.Interface.PrivateKey); err != nil {
+		return fmt.Errorf("failed to decrypt private key of peer %s: %w", peer.Identifier, err)
+	}
+	presharedKey, err := r.decrypt(string(peer.PresharedKey))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt preshared key of peer %s: %w", peer.Identifier, err)
+	}
+	peer.PresharedKey = domain.PreSharedKey(presharedKey)
+
+	return nil
+}
+
+// getPeers returns all peers that match the given filter.
+func (r *KvRepo) getPeers(ctx context.Context, filter func(peer *domain.Peer) bool) ([]domain.Peer, error) {
+	peers, err := kvList[domain.Peer](ctx, r.store, kvKindPeers)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.Peer, 0, len(peers))
+	for _, peer := range peers {
+		if !filter(&peer) {
+			continue
+		}
+		if err := r.decryptPeer(&peer); err != nil {
+			return nil, err
+		}
+		result = append(result, peer)
+	}
+
+	return result, nil
+}
+
+func peerMatchesSearch(peer *domain.Peer, search string) bool {
+	return containsFold(string(peer.Identifier), search) ||
+		containsFold(peer.DisplayName, search) ||
+		containsFold(peer.Interface.AddressStr(), search)
+}
+
+// GetPeer returns the peer with the given id.
+// If no peer is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, err := kvGet[domain.Peer](ctx, r.store, kvKey(kvKindPeers, string(id)))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.decryptPeer(peer); err != nil {
+		return nil, err
+	}
+
+	return peer, nil
+}
+
+// GetInterfacePeers returns all peers associated with the given interface id.
+func (r *KvRepo) GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	return r.getPeers(ctx, func(peer *domain.Peer) bool {
+		return peer.InterfaceIdentifier == id
+	})
+}
+
+// FindInterfacePeers returns all peers associated with the given interface id that match the given search string.
+// The search string is matched against the peer identifier, display name and IP address.
+func (r *KvRepo) FindInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier, search string) (
+	[]domain.Peer,
+	error,
+) {
+	return r.getPeers(ctx, func(peer *domain.Peer) bool {
+		return peer.InterfaceIdentifier == id && peerMatchesSearch(peer, search)
+	})
+}
+
+// GetUserPeers returns all peers associated with the given user id.
+func (r *KvRepo) GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
+	return r.getPeers(ctx, func(peer *domain.Peer) bool {
+		return peer.UserIdentifier == id
+	})
+}
+
+// GetSharedUserPeers returns all peers that are shared with the given user id. Peers owned by the user are not
+// included.
+func (r *KvRepo) GetSharedUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
+	return r.getPeers(ctx, func(peer *domain.Peer) bool {
+		return peer.UserIdentifier != id && peer.IsMember(id)
+	})
+}
+
+// FindUserPeers returns all peers associated with the given user id that match the given search string.
+// The search string is matched against the peer identifier, display name and IP address.
+func (r *KvRepo) FindUserPeers(ctx context.Context, id domain.UserIdentifier, search string) ([]domain.Peer, error) {
+	return r.getPeers(ctx, func(peer *domain.Peer) bool {
+		return peer.UserIdentifier == id && peerMatchesSearch(peer, search)
+	})
+}
+
+// SavePeer updates the peer with the given id.
+// If no existing peer is found, a new peer is created.
+func (r *KvRepo) SavePeer(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	updateFunc func(in *domain.Peer) (*domain.Peer, error),
+) error {
+	userInfo := domain.GetUserInfo(ctx)
+
+	return kvUpdate(ctx, r.store, kvKey(kvKindPeers, string(id)), func(peer *domain.Peer) (*domain.Peer, error) {
+		if peer == nil {
+			peer = &domain.Peer{
+				BaseModel:  newKvBaseModel(userInfo),
+				Identifier: id,
+			}
+		} else if err := r.decryptPeer(peer); err != nil {
+			return nil, err
+		}
+
+		peer, err := updateFunc(peer)
+		if err != nil {
+			return nil, err
+		}
+
+		peer.UpdatedBy = userInfo.UserId()
+		peer.UpdatedAt = time.Now()
+
+		return r.encryptPeer(peer)
+	})
+}
+
+// DeletePeer deletes the peer with the given id.
+func (r *KvRepo) DeletePeer(ctx context.Context, id domain.PeerIdentifier) error {
+	if err := r.store.delete(ctx, kvKey(kvKindPeerStatus, string(id))); err != nil {
+		return err
+	}
+
+	return r.store.delete(ctx, kvKey(kvKindPeers, string(id)))
+}
+
+// GetPeerCount returns the number of all peers.
+func (r *KvRepo) GetPeerCount(ctx context.Context) (int, error) {
+	pairs, err := r.store.list(ctx, kvKindPeers+"/")
+	if err != nil {
+		return 0, err
+	}
+
+	return len(pairs), nil
+}
+
+// GetPeerIps returns a map of peer identifiers to their respective IP addresses.
+func (r *KvRepo) GetPeerIps(ctx context.Context) (map[domain.PeerIdentifier][]domain.Cidr, error) {
+	peers, err := kvList[domain.Peer](ctx, r.store, kvKindPeers)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[domain.PeerIdentifier][]domain.Cidr)
+	for _, peer := range peers {
+		if len(peer.Interface.Addresses) > 0 {
+			result[peer.Identifier] = append(result[peer.Identifier], peer.Interface.Addresses...)
+		}
+	}
+	return result, nil
+}
+
+// GetUsedIpsPerSubnet returns a map of subnets to their respective used IP addresses.
+func (r *KvRepo) GetUsedIpsPerSubnet(ctx context.Context, subnets []domain.Cidr) (
+	map[domain.Cidr][]domain.Cidr,
+	error,
+) {
+	interfaceIps, err := r.GetInterfaceIps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch interface IP's: %w", err)
+	}
+
+	peerIps, err := r.GetPeerIps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch peer IP's: %w", err)
+	}
+
+	usedIps := make([]domain.Cidr, 0, len(interfaceIps)+len(peerIps))
+	for _, ips := range interfaceIps {
+		usedIps = append(usedIps, ips...)
+	}
+	for _, ips := range peerIps {
+		usedIps = append(usedIps, ips...)
+	}
+
+	result := make(map[domain.Cidr][]domain.Cidr, len(subnets))
+	for _, ip := range usedIps {
+		var subnet domain.Cidr // default empty subnet (if no subnet matches, we will add the IP to the empty subnet group)
+		for _, s := range subnets {
+			if s.Contains(ip) {
+				subnet = s
+				break
+			}
+		}
+		result[subnet] = append(result[subnet], ip)
+	}
+	return result, nil
+}
+
+// endregion peers
+
+// region users
+
+// kvUser is the stored representation of a user. The JSON encoding of domain.User omits the password hash,
+// so it is stored in a separate field.
+type kvUser struct {
+	domain.User
+	PasswordHash string
+}
+
+func newKvUser(user *domain.User) *kvUser {
+	stored := &kvUser{
+		User:         *user,
+		PasswordHash: string(user.Password),
+	}
+	stored.LinkedPeerCount = 0 // calculated value, not persisted
+	stored.Groups = nil        // calculated value, not persisted
+
+	return stored
+}
+
+func (u *kvUser) toDomain() *domain.User {
+	user := u.User
+	user.Password = domain.PrivateString(u.PasswordHash)
+
+	return &user
+}
+
+func (r *KvRepo) getUsers(ctx context.Context, filter func(user *domain.User) bool) ([]domain.User, error) {
+	storedUsers, err := kvList[kvUser](ctx, r.store, kvKindUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]domain.User, 0, len(storedUsers))
+	for _, storedUser := range storedUsers {
+		user := storedUser.toDomain()
+		if filter(user) {
+			users = append(users, *user)
+		}
+	}
+
+	return users, nil
+}
+
+// GetUser returns the user with the given id.
+// If no user is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	user, err := kvGet[kvUser](ctx, r.store, kvKey(kvKindUsers, string(id)))
+	if err != nil {
+		return nil, err
+	}
+
+	return user.toDomain(), nil
+}
+
+// GetUserByEmail returns the user with the given email.
+// If no user is found, an error domain.ErrNotFound is returned.
+// If multiple users are found, an error domain.ErrNotUnique is returned.
+func (r *KvRepo) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	users, err := r.getUsers(ctx, func(user *domain.User) bool {
+		return user.Email == email
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	if len(users) > 1 {
+		return nil, fmt.Errorf("found multiple users with email %s: %w", email, domain.ErrNotUnique)
+	}
+
+	user := users[0]
+
+	return &user, nil
+}
+
+// GetUserByWebAuthnCredential returns the user with the given webauthn credential id.
+func (r *KvRepo) GetUserByWebAuthnCredential(ctx context.Context, credentialIdBase64 string) (*domain.User, error) {
+	users, err := r.getUsers(ctx, func(user *domain.User) bool {
+		return slices.ContainsFunc(user.WebAuthnCredentialList, func(c domain.UserWebauthnCredential) bool {
+			return c.CredentialIdentifier == credentialIdBase64
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	user := users[0]
+
+	return &user, nil
+}
+
+// GetAllUsers returns all users.
+func (r *KvRepo) GetAllUsers(ctx context.Context) ([]domain.User, error) {
+	return r.getUsers(ctx, func(_ *domain.User) bool {
+		return true
+	})
+}
+
+// FindUsers returns all users that match the given search string.
+// The search string is matched against the user identifier, firstname, lastname and email.
+func (r *KvRepo) FindUsers(ctx context.Context, search string) ([]domain.User, error) {
+	return r.getUsers(ctx, func(user *domain.User) bool {
+		return containsFold(string(user.Identifier), search) ||
+			containsFold(user.Firstname, search) ||
+			containsFold(user.Lastname, search) ||
+			containsFold(user.Email, search)
+	})
+}
+
+// SaveUser updates the user with the given id.
+// If no user is found, a new user is created.
+func (r *KvRepo) SaveUser(
+	ctx context.Context,
+	id domain.UserIdentifier,
+	updateFunc func(u *domain.User) (*domain.User, error),
+) error {
+	userInfo := domain.GetUserInfo(ctx)
+
+	return kvUpdate(ctx, r.store, kvKey(kvKindUsers, string(id)), func(stored *kvUser) (*kvUser, error) {
+		var user *domain.User
+		if stored == nil {
+			user = &domain.User{
+				BaseModel:  newKvBaseModel(userInfo),
+				Identifier: id,
+				Source:     domain.UserSourceDatabase,
+				IsAdmin:    false,
+			}
+		} else {
+			user = stored.toDomain()
+		}
+
+		user, err := updateFunc(user)
+		if err != nil {
+			return nil, err
+		}
+
+		user.UpdatedBy = userInfo.UserId()
+		user.UpdatedAt = time.Now()
+
+		return newKvUser(user), nil
+	})
+}
+
+// DeleteUser deletes the user with the given id.
+func (r *KvRepo) DeleteUser(ctx context.Context, id domain.UserIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindUsers, string(id)))
+}
+
+// endregion users
+
+// region statistics
+
+// UpdateInterfaceStatus updates the interface status with the given id.
+// If no interface status is found, a new one is created.
+func (r *KvRepo) UpdateInterfaceStatus(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	updateFunc func(in *domain.InterfaceStatus) (*domain.InterfaceStatus, error),
+) error {
+	return kvUpdate(ctx, r.store, kvKey(kvKindInterfaceStatus, string(id)),
+		func(in *domain.InterfaceStatus) (*domain.InterfaceStatus, error) {
+			if in == nil {
+				in = &domain.InterfaceStatus{
+					InterfaceId: id,
+					UpdatedAt:   time.Now(),
+				}
+			}
+
+			return updateFunc(in)
+		})
+}
+
+// UpdatePeerStatus updates the peer status with the given id.
+// If no peer status is found, a new one is created.
+func (r *KvRepo) UpdatePeerStatus(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	updateFunc func(in *domain.PeerStatus) (*domain.PeerStatus, error),
+) error {
+	return kvUpdate(ctx, r.store, kvKey(kvKindPeerStatus, string(id)),
+		func(in *domain.PeerStatus) (*domain.PeerStatus, error) {
+			if in == nil {
+				in = &domain.PeerStatus{
+					PeerId:    id,
+					UpdatedAt: time.Now(),
+				}
+			}
+
+			return updateFunc(in)
+		})
+}
+
+// DeletePeerStatus deletes the peer status with the given id.
+func (r *KvRepo) DeletePeerStatus(ctx context.Context, id domain.PeerIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindPeerStatus, string(id)))
+}
+
+func kvPeerStatsSampleKey(sample *domain.PeerStatsSample) string {
+	return kvKey(kvKindPeerStatsSamples, string(sample.Resolution), kvSequenceId(uint64(sample.Timestamp.UnixNano())),
+		string(sample.PeerId))
+}
+
+// SavePeerStatsSamples creates or updates the given peer statistics samples.
+func (r *KvRepo) SavePeerStatsSamples(ctx context.Context, samples []domain.PeerStatsSample) error {
+	for i := range samples {
+		if err := kvPut(ctx, r.store, kvPeerStatsSampleKey(&samples[i]), samples[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetPeerStatsSamples returns the peer statistics samples of the given resolution within [from, to).
+// If no peer ids are given, the samples of all peers are returned. The samples are ordered by timestamp.
+func (r *KvRepo) GetPeerStatsSamples(
+	ctx context.Context,
+	resolution domain.PeerStatsResolution,
+	from, to time.Time,
+	ids ...domain.PeerIdentifier,
+) ([]domain.PeerStatsSample, error) {
+	samples, err := kvList[domain.PeerStatsSample](ctx, r.store, kvKey(kvKindPeerStatsSamples, string(resolution)))
+	if err != nil {
+		return nil, err
+	}
+
+	// keys are ordered by timestamp
+	return slices.DeleteFunc(samples, func(sample domain.PeerStatsSample) bool {
+		if sample.Timestamp.Before(from) || !sample.Timestamp.Before(to) {
+			return true
+		}
+		return len(ids) > 0 && !slices.Contains(ids, sample.PeerId)
+	}), nil
+}
+
+// DeletePeerStatsSamples deletes all peer statistics samples of the given resolution that are older than the
+// given time.
+func (r *KvRepo) DeletePeerStatsSamples(
+	ctx context.Context,
+	resolution domain.PeerStatsResolution,
+	before time.Time,
+) error {
+	samples, err := r.GetPeerStatsSamples(ctx, resolution, time.Unix(0, 0), before)
+	if err != nil {
+		return err
+	}
+
+	for i := range samples {
+		if err := r.store.delete(ctx, kvPeerStatsSampleKey(&samples[i])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func kvPeerConnectionEventKey(event *domain.PeerConnectionEvent) string {
+	return kvKey(kvKindPeerConnections, string(event.PeerId), kvSequenceId(uint64(event.Timestamp.UnixNano())),
+		string(event.Kind))
+}
+
+// SavePeerConnectionEvents stores the given peer connection events.
+func (r *KvRepo) SavePeerConnectionEvents(ctx context.Context, events []domain.PeerConnectionEvent) error {
+	for i := range events {
+		if err := kvPut(ctx, r.store, kvPeerConnectionEventKey(&events[i]), events[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetPeerConnectionEvents returns the connection events of the given peer within [from, to).
+// The events are ordered by timestamp.
+func (r *KvRepo) GetPeerConnectionEvents(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	from, to time.Time,
+) ([]domain.PeerConnectionEvent, error) {
+	events, err := kvList[domain.PeerConnectionEvent](ctx, r.store, kvKey(kvKindPeerConnections, string(id)))
+	if err != nil {
+		return nil, err
+	}
+
+	// keys are ordered by timestamp
+	return slices.DeleteFunc(events, func(event domain.PeerConnectionEvent) bool {
+		return event.Timestamp.Before(from) || !event.Timestamp.Before(to)
+	}), nil
+}
+
+// AnonymizePeerConnectionEvents removes the endpoint addresses from all connection events of the given peer.
+func (r *KvRepo) AnonymizePeerConnectionEvents(ctx context.Context, id domain.PeerIdentifier) error {
+	events, err := kvList[domain.PeerConnectionEvent](ctx, r.store, kvKey(kvKindPeerConnections, string(id)))
+	if err != nil {
+		return err
+	}
+
+	for i := range events {
+		events[i].Endpoint = ""
+		events[i].PreviousEndpoint = ""
+		if err := kvPut(ctx, r.store, kvPeerConnectionEventKey(&events[i]), events[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeletePeerConnectionEvents deletes all peer connection events that are older than the given time.
+func (r *KvRepo) DeletePeerConnectionEvents(ctx context.Context, before time.Time) error {
+	events, err := kvList[domain.PeerConnectionEvent](ctx, r.store, kvKindPeerConnections)
+	if err != nil {
+		return err
+	}
+
+	for i := range events {
+		if !events[i].Timestamp.Before(before) {
+			continue
+		}
+		if err := r.store.delete(ctx, kvPeerConnectionEventKey(&events[i])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion statistics
+
+// region audit
+
+// SaveAuditEntry saves the given audit entry.
+func (r *KvRepo) SaveAuditEntry(ctx context.Context, entry *domain.AuditEntry) error {
+	if entry.UniqueId == 0 {
+		id, err := r.nextSequence(ctx, kvSequenceAudit)
+		if err != nil {
+			return err
+		}
+		entry.UniqueId = id
+	}
+
+	return kvPut(ctx, r.store, kvKey(kvKindAudit, kvSequenceId(entry.UniqueId)), entry)
+}
+
+// GetAllAuditEntries retrieves all audit entries from the database.
+// The entries are ordered by timestamp, with the newest entries first.
+func (r *KvRepo) GetAllAuditEntries(ctx context.Context) ([]domain.AuditEntry, error) {
+	entries, err := kvList[domain.AuditEntry](ctx, r.store, kvKindAudit)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(entries, func(a, b domain.AuditEntry) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return entries, nil
+}
+
+// DeleteAuditEntries deletes all audit entries that were created before the given time.
+func (r *KvRepo) DeleteAuditEntries(ctx context.Context, before time.Time) error {
+	entries, err := kvList[domain.AuditEntry](ctx, r.store, kvKindAudit)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.CreatedAt.Before(before) {
+			continue
+		}
+		if err := r.store.delete(ctx, kvKey(kvKindAudit, kvSequenceId(entry.UniqueId))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AnonymizeAuditEntries replaces the given personal data values in the context user and the message of all audit
+// entries with domain.ErasedPlaceholder.
+func (r *KvRepo) AnonymizeAuditEntries(ctx context.Context, values []string) error {
+	entries, err := kvList[domain.AuditEntry](ctx, r.store, kvKindAudit)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		contextUser := domain.AnonymizeText(entry.ContextUser, values)
+		message := domain.AnonymizeText(entry.Message, values)
+		if contextUser == entry.ContextUser && message == entry.Message {
+			continue
+		}
+
+		entry.ContextUser = contextUser
+		entry.Message = message
+		if err := kvPut(ctx, r.store, kvKey(kvKindAudit, kvSequenceId(entry.UniqueId)), entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion audit
+
+// region mail-log
+
+// SaveMailLogEntry saves the given mail log entry.
+func (r *KvRepo) SaveMailLogEntry(ctx context.Context, entry *domain.MailLogEntry) error {
+	if entry.UniqueId == 0 {
+		id, err := r.nextSequence(ctx, kvSequenceMailLog)
+		if err != nil {
+			return err
+		}
+		entry.UniqueId = id
+	}
+
+	return kvPut(ctx, r.store, kvKey(kvKindMailLog, kvSequenceId(entry.UniqueId)), entry)
+}
+
+// FindMailLogEntries returns all mail log entries that match the given filter.
+// The entries are ordered by timestamp, with the newest entries first.
+func (r *KvRepo) FindMailLogEntries(ctx context.Context, filter domain.MailLogFilter) ([]domain.MailLogEntry, error) {
+	entries, err := kvList[domain.MailLogEntry](ctx, r.store, kvKindMailLog)
+	if err != nil {
+		return nil, err
+	}
+
+	entries = slices.DeleteFunc(entries, func(entry domain.MailLogEntry) bool {
+		return !filter.Matches(entry)
+	})
+	slices.SortStableFunc(entries, func(a, b domain.MailLogEntry) int {
+		return b.SentAt.Compare(a.SentAt)
+	})
+
+	return entries, nil
+}
+
+// GetMailLogEntryByTrackingToken returns the mail log entry of the tracked mail with the given token.
+// If no entry is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetMailLogEntryByTrackingToken(ctx context.Context, token string) (*domain.MailLogEntry, error) {
+	if token == "" {
+		return nil, domain.ErrNotFound // untracked mails have no token
+	}
+
+	entries, err := kvList[domain.MailLogEntry](ctx, r.store, kvKindMailLog)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.TrackingToken == token {
+			return &entry, nil
+		}
+	}
+
+	return nil, domain.ErrNotFound
+}
+
+// DeleteMailLogEntries deletes all mail log entries that were created before the given time.
+func (r *KvRepo) DeleteMailLogEntries(ctx context.Context, before time.Time) error {
+	return r.deleteMailLogEntries(ctx, func(entry domain.MailLogEntry) bool {
+		return entry.SentAt.Before(before)
+	})
+}
+
+// DeleteUserMailLogEntries deletes all mail log entries of mails that were sent to the given user.
+func (r *KvRepo) DeleteUserMailLogEntries(ctx context.Context, id domain.UserIdentifier) error {
+	return r.deleteMailLogEntries(ctx, func(entry domain.MailLogEntry) bool {
+		return entry.UserIdentifier == id
+	})
+}
+
+func (r *KvRepo) deleteMailLogEntries(ctx context.Context, match func(entry domain.MailLogEntry) bool) error {
+	entries, err := kvList[domain.MailLogEntry](ctx, r.store, kvKindMailLog)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !match(entry) {
+			continue
+		}
+		if err := r.store.delete(ctx, kvKey(kvKindMailLog, kvSequenceId(entry.UniqueId))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion mail-log
+
+// region peer-cleanup
+
+// GetPeerCleanupNotices returns all stored peer cleanup notices.
+func (r *KvRepo) GetPeerCleanupNotices(ctx context.Context) ([]domain.PeerCleanupNotice, error) {
+	return kvList[domain.PeerCleanupNotice](ctx, r.store, kvKindPeerCleanup)
+}
+
+// SavePeerCleanupNotice creates or updates the given peer cleanup notice.
+func (r *KvRepo) SavePeerCleanupNotice(ctx context.Context, notice *domain.PeerCleanupNotice) error {
+	return kvPut(ctx, r.store, kvKey(kvKindPeerCleanup, string(notice.PeerId)), notice)
+}
+
+// DeletePeerCleanupNotice deletes the peer cleanup notice for the given peer id.
+func (r *KvRepo) DeletePeerCleanupNotice(ctx context.Context, id domain.PeerIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindPeerCleanup, string(id)))
+}
+
+// endregion peer-cleanup
+
+// region peer-expiry
+
+// GetPeerExpiryReminders returns all stored peer expiry reminders.
+func (r *KvRepo) GetPeerExpiryReminders(ctx context.Context) ([]domain.PeerExpiryReminder, error) {
+	return kvList[domain.PeerExpiryReminder](ctx, r.store, kvKindPeerExpiry)
+}
+
+// SavePeerExpiryReminder creates or updates the given peer expiry reminder.
+func (r *KvRepo) SavePeerExpiryReminder(ctx context.Context, reminder *domain.PeerExpiryReminder) error {
+	return kvPut(ctx, r.store, kvKey(kvKindPeerExpiry, string(reminder.PeerId)), reminder)
+}
+
+// DeletePeerExpiryReminder deletes the peer expiry reminder for the given peer id.
+func (r *KvRepo) DeletePeerExpiryReminder(ctx context.Context, id domain.PeerIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindPeerExpiry, string(id)))
+}
+
+// endregion peer-expiry
+
+// region peer-setup-reminders
+
+// GetPeerSetupReminders returns all stored peer setup reminders.
+func (r *KvRepo) GetPeerSetupReminders(ctx context.Context) ([]domain.PeerSetupReminder, error) {
+	return kvList[domain.PeerSetupReminder](ctx, r.store, kvKindPeerSetup)
+}
+
+// SavePeerSetupReminder creates or updates the given peer setup reminder.
+func (r *KvRepo) SavePeerSetupReminder(ctx context.Context, reminder *domain.PeerSetupReminder) error {
+	return kvPut(ctx, r.store, kvKey(kvKindPeerSetup, string(reminder.PeerId)), reminder)
+}
+
+// DeletePeerSetupReminder deletes the peer setup reminder for the given peer id.
+func (r *KvRepo) DeletePeerSetupReminder(ctx context.Context, id domain.PeerIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindPeerSetup, string(id)))
+}
+
+// endregion peer-setup-reminders
+
+// region peer-config-versions
+
+// GetPeerConfigVersions returns the recorded configuration versions of the given peer, ordered by version.
+func (r *KvRepo) GetPeerConfigVersions(ctx context.Context, id domain.PeerIdentifier) (
+	[]domain.PeerConfigVersion,
+	error,
+) {
+	// the version is zero padded in the key, so the list is ordered by version
+	return kvList[domain.PeerConfigVersion](ctx, r.store, kvKey(kvKindPeerConfigVersion, string(id)))
+}
+
+// SavePeerConfigVersion creates or updates the given peer configuration version.
+func (r *KvRepo) SavePeerConfigVersion(ctx context.Context, version *domain.PeerConfigVersion) error {
+	return kvPut(ctx, r.store, kvPeerConfigVersionKey(version.PeerId, version.Version), version)
+}
+
+// DeletePeerConfigVersion deletes the given configuration version of the peer.
+func (r *KvRepo) DeletePeerConfigVersion(ctx context.Context, id domain.PeerIdentifier, version int) error {
+	return r.store.delete(ctx, kvPeerConfigVersionKey(id, version))
+}
+
+func kvPeerConfigVersionKey(id domain.PeerIdentifier, version int) string {
+	return kvKey(kvKindPeerConfigVersion, string(id), kvSequenceId(uint64(version)))
+}
+
+// endregion peer-config-versions
+
+// region peer-transfers
+
+// GetPeerTransfer returns the peer transfer with the given id.
+// If no transfer is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetPeerTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (
+	*domain.PeerTransfer,
+	error,
+) {
+	return kvGet[domain.PeerTransfer](ctx, r.store, kvKey(kvKindPeerTransfers, string(id)))
+}
+
+// GetPeerTransfers returns all peer transfers.
+func (r *KvRepo) GetPeerTransfers(ctx context.Context) ([]domain.PeerTransfer, error) {
+	transfers, err := kvList[domain.PeerTransfer](ctx, r.store, kvKindPeerTransfers)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(transfers, func(a, b domain.PeerTransfer) int {
+		return b.RequestedAt.Compare(a.RequestedAt)
+	})
+
+	return transfers, nil
+}
+
+// SavePeerTransfer creates or updates the given peer transfer.
+func (r *KvRepo) SavePeerTransfer(ctx context.Context, transfer *domain.PeerTransfer) error {
+	return kvPut(ctx, r.store, kvKey(kvKindPeerTransfers, string(transfer.Identifier)), transfer)
+}
+
+// UpdatePeerTransferPeer moves all transfers of a peer to the new peer identifier.
+func (r *KvRepo) UpdatePeerTransferPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error {
+	transfers, err := kvList[domain.PeerTransfer](ctx, r.store, kvKindPeerTransfers)
+	if err != nil {
+		return err
+	}
+
+	for _, transfer := range transfers {
+		if transfer.PeerId != oldId {
+			continue
+		}
+
+		// only the peer identifier is modified, concurrent state changes are preserved
+		err := kvUpdate(ctx, r.store, kvKey(kvKindPeerTransfers, string(transfer.Identifier)),
+			func(current *domain.PeerTransfer) (*domain.PeerTransfer, error) {
+				if current == nil {
+					return nil, domain.ErrNotFound
+				}
+				current.PeerId = newId
+				return current, nil
+			})
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion peer-transfers
+
+// region emergency-lockdowns
+
+// GetEmergencyLockdown returns the emergency lockdown with the given id.
+// If no lockdown is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetEmergencyLockdown(ctx context.Context, id domain.EmergencyLockdownIdentifier) (
+	*domain.EmergencyLockdown,
+	error,
+) {
+	return kvGet[domain.EmergencyLockdown](ctx, r.store, kvKey(kvKindEmergency, string(id)))
+}
+
+// GetEmergencyLockdowns returns all emergency lockdowns, the most recent lockdowns first.
+func (r *KvRepo) GetEmergencyLockdowns(ctx context.Context) ([]domain.EmergencyLockdown, error) {
+	lockdowns, err := kvList[domain.EmergencyLockdown](ctx, r.store, kvKindEmergency)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(lockdowns, func(a, b domain.EmergencyLockdown) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return lockdowns, nil
+}
+
+// SaveEmergencyLockdown creates or updates the given emergency lockdown.
+func (r *KvRepo) SaveEmergencyLockdown(ctx context.Context, lockdown *domain.EmergencyLockdown) error {
+	return kvPut(ctx, r.store, kvKey(kvKindEmergency, string(lockdown.Identifier)), lockdown)
+}
+
+// endregion emergency-lockdowns
+
+// region login-devices
+
+// GetUserLoginDevices returns all known login devices of the given user.
+func (r *KvRepo) GetUserLoginDevices(ctx context.Context, id domain.UserIdentifier) (
+	[]domain.UserLoginDevice,
+	error,
+) {
+	devices, err := kvList[domain.UserLoginDevice](ctx, r.store, kvKindLoginDevices)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(devices, func(device domain.UserLoginDevice) bool {
+		return device.UserIdentifier != id
+	}), nil
+}
+
+// SaveUserLoginDevice creates or updates the given login device.
+func (r *KvRepo) SaveUserLoginDevice(ctx context.Context, device *domain.UserLoginDevice) error {
+	return kvPut(ctx, r.store, kvKey(kvKindLoginDevices, device.Identifier), device)
+}
+
+// DeleteUserLoginDevice deletes the login device with the given identifier.
+func (r *KvRepo) DeleteUserLoginDevice(ctx context.Context, id string) error {
+	return r.store.delete(ctx, kvKey(kvKindLoginDevices, id))
+}
+
+// DeleteUserLoginDevices deletes all login devices of the given user.
+func (r *KvRepo) DeleteUserLoginDevices(ctx context.Context, id domain.UserIdentifier) error {
+	devices, err := r.GetUserLoginDevices(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		if err := r.DeleteUserLoginDevice(ctx, device.Identifier); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion login-devices
+
+// region notification-preferences
+
+// GetUserNotificationPreferences returns the notification preferences of the given user.
+func (r *KvRepo) GetUserNotificationPreferences(ctx context.Context, id domain.UserIdentifier) (
+	*domain.UserNotificationPreferences,
+	error,
+) {
+	return kvGet[domain.UserNotificationPreferences](ctx, r.store, kvKey(kvKindNotificationPrefs, string(id)))
+}
+
+// SaveUserNotificationPreferences creates or updates the given notification preferences.
+func (r *KvRepo) SaveUserNotificationPreferences(ctx context.Context, prefs *domain.UserNotificationPreferences) error {
+	return kvPut(ctx, r.store, kvKey(kvKindNotificationPrefs, string(prefs.UserIdentifier)), prefs)
+}
+
+// DeleteUserNotificationPreferences deletes the notification preferences of the given user.
+func (r *KvRepo) DeleteUserNotificationPreferences(ctx context.Context, id domain.UserIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindNotificationPrefs, string(id)))
+}
+
+// endregion notification-preferences
+
+// region saved-views
+
+// GetSavedView returns the saved view with the given id.
+// If no view is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetSavedView(ctx context.Context, id uint64) (*domain.SavedView, error) {
+	return kvGet[domain.SavedView](ctx, r.store, kvKey(kvKindSavedViews, kvSequenceId(id)))
+}
+
+// GetSavedViews returns all saved views of the given list, ordered by name.
+func (r *KvRepo) GetSavedViews(ctx context.Context, list domain.SavedViewList) ([]domain.SavedView, error) {
+	views, err := kvList[domain.SavedView](ctx, r.store, kvKindSavedViews)
+	if err != nil {
+		return nil, err
+	}
+
+	views = slices.DeleteFunc(views, func(view domain.SavedView) bool {
+		return view.List != list
+	})
+	slices.SortStableFunc(views, func(a, b domain.SavedView) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return views, nil
+}
+
+// SaveSavedView creates or updates the given saved view.
+func (r *KvRepo) SaveSavedView(ctx context.Context, view *domain.SavedView) error {
+	if view.Id == 0 {
+		id, err := r.nextSequence(ctx, kvSequenceSavedViews)
+		if err != nil {
+			return err
+		}
+		view.Id = id
+	}
+
+	return kvPut(ctx, r.store, kvKey(kvKindSavedViews, kvSequenceId(view.Id)), view)
+}
+
+// DeleteSavedView deletes the saved view with the given id.
+func (r *KvRepo) DeleteSavedView(ctx context.Context, id uint64) error {
+	return r.store.delete(ctx, kvKey(kvKindSavedViews, kvSequenceId(id)))
+}
+
+// DeleteUserSavedViews deletes all saved views of the given user.
+func (r *KvRepo) DeleteUserSavedViews(ctx context.Context, id domain.UserIdentifier) error {
+	views, err := kvList[domain.SavedView](ctx, r.store, kvKindSavedViews)
+	if err != nil {
+		return err
+	}
+
+	for _, view := range views {
+		if view.UserIdentifier != id {
+			continue
+		}
+		if err := r.DeleteSavedView(ctx, view.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion saved-views
+
+// region idempotency
+
+// GetIdempotencyRecord returns the stored response for the given idempotency key of the given user.
+// If no response is stored, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetIdempotencyRecord(ctx context.Context, userId domain.UserIdentifier, key string) (
+	*domain.IdempotencyRecord,
+	error,
+) {
+	return kvGet[domain.IdempotencyRecord](ctx, r.store, kvKey(kvKindIdempotency, string(userId), key))
+}
+
+// SaveIdempotencyRecord creates or updates the given idempotency record.
+func (r *KvRepo) SaveIdempotencyRecord(ctx context.Context, record *domain.IdempotencyRecord) error {
+	return kvPut(ctx, r.store, kvKey(kvKindIdempotency, string(record.UserIdentifier), record.Key), record)
+}
+
+// DeleteIdempotencyRecords deletes all idempotency records that were created before the given time.
+func (r *KvRepo) DeleteIdempotencyRecords(ctx context.Context, before time.Time) error {
+	records, err := kvList[domain.IdempotencyRecord](ctx, r.store, kvKindIdempotency)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if !record.CreatedAt.Before(before) {
+			continue
+		}
+		key := kvKey(kvKindIdempotency, string(record.UserIdentifier), record.Key)
+		if err := r.store.delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion idempotency
+
+// region webhook-events
+
+// SaveWebhookEvent stores the given webhook event. New events receive the next event id.
+func (r *KvRepo) SaveWebhookEvent(ctx context.Context, event *domain.WebhookEvent) error {
+	if event.Id == 0 {
+		id, err := r.nextSequence(ctx, kvSequenceWebhookEvents)
+		if err != nil {
+			return err
+		}
+		event.Id = id
+	}
+
+	return kvPut(ctx, r.store, kvKey(kvKindWebhookEvents, kvSequenceId(event.Id)), event)
+}
+
+// GetWebhookEvent returns the webhook event with the given id.
+// If no event is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetWebhookEvent(ctx context.Context, id uint64) (*domain.WebhookEvent, error) {
+	return kvGet[domain.WebhookEvent](ctx, r.store, kvKey(kvKindWebhookEvents, kvSequenceId(id)))
+}
+
+// GetWebhookEvents returns the webhook events with an id greater than the given id, ordered by id.
+// If limit is greater than zero, at most limit events are returned.
+func (r *KvRepo) GetWebhookEvents(ctx context.Context, afterId uint64, limit int) ([]domain.WebhookEvent, error) {
+	events, err := kvList[domain.WebhookEvent](ctx, r.store, kvKindWebhookEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	events = slices.DeleteFunc(events, func(event domain.WebhookEvent) bool {
+		return event.Id <= afterId
+	})
+	slices.SortFunc(events, func(a, b domain.WebhookEvent) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+
+	return events, nil
+}
+
+// GetLastWebhookEventId returns the id of the most recent webhook event, 0 if no event was stored yet.
+func (r *KvRepo) GetLastWebhookEventId(ctx context.Context) (uint64, error) {
+	events, err := kvList[domain.WebhookEvent](ctx, r.store, kvKindWebhookEvents)
+	if err != nil {
+		return 0, err
+	}
+
+	var lastId uint64
+	for _, event := range events {
+		lastId = max(lastId, event.Id)
+	}
+
+	return lastId, nil
+}
+
+// DeleteWebhookEvents deletes all webhook events that were created before the given time, including their
+// delivery records.
+func (r *KvRepo) DeleteWebhookEvents(ctx context.Context, before time.Time) error {
+	events, err := kvList[domain.WebhookEvent](ctx, r.store, kvKindWebhookEvents)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if !event.CreatedAt.Before(before) {
+			continue
+		}
+		deliveries, err := r.GetWebhookDeliveries(ctx, event.Id)
+		if err != nil {
+			return err
+		}
+		for _, delivery := range deliveries {
+			key := kvKey(kvKindWebhookDeliveries, kvSequenceId(delivery.EventId), delivery.Endpoint)
+			if err := r.store.delete(ctx, key); err != nil {
+				return err
+			}
+		}
+		if err := r.store.delete(ctx, kvKey(kvKindWebhookEvents, kvSequenceId(event.Id))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns the delivery records of the given webhook event, ordered by endpoint.
+func (r *KvRepo) GetWebhookDeliveries(ctx context.Context, eventId uint64) ([]domain.WebhookDelivery, error) {
+	deliveries, err := kvList[domain.WebhookDelivery](ctx, r.store,
+		kvKey(kvKindWebhookDeliveries, kvSequenceId(eventId)))
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(deliveries, func(a, b domain.WebhookDelivery) int {
+		return strings.Compare(a.Endpoint, b.Endpoint)
+	})
+
+	return deliveries, nil
+}
+
+// SaveWebhookDelivery creates or updates the given webhook delivery record.
+func (r *KvRepo) SaveWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return kvPut(ctx, r.store, kvKey(kvKindWebhookDeliveries, kvSequenceId(delivery.EventId), delivery.Endpoint),
+		delivery)
+}
+
+// GetWebhookCursors returns the cursors of all webhook endpoints.
+func (r *KvRepo) GetWebhookCursors(ctx context.Context) ([]domain.WebhookCursor, error) {
+	return kvList[domain.WebhookCursor](ctx, r.store, kvKindWebhookCursors)
+}
+
+// SaveWebhookCursor creates or updates the given webhook cursor.
+func (r *KvRepo) SaveWebhookCursor(ctx context.Context, cursor *domain.WebhookCursor) error {
+	return kvPut(ctx, r.store, kvKey(kvKindWebhookCursors, cursor.Endpoint), cursor)
+}
+
+// endregion webhook-events
+
+// region acceptable-use
+
+// GetLastAcceptableUseAcceptance returns the most recent acceptance of the acceptable use policy by the given user.
+func (r *KvRepo) GetLastAcceptableUseAcceptance(ctx context.Context, id domain.UserIdentifier) (
+	*domain.AcceptableUseAcceptance,
+	error,
+) {
+	acceptances, err := r.GetAcceptableUseAcceptances(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(acceptances) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	return &acceptances[0], nil
+}
+
+// GetAcceptableUseAcceptances returns all acceptances of the acceptable use policy by the given user, the most
+// recent acceptances first.
+func (r *KvRepo) GetAcceptableUseAcceptances(ctx context.Context, id domain.UserIdentifier) (
+	[]domain.AcceptableUseAcceptance,
+	error,
+) {
+	acceptances, err := kvList[domain.AcceptableUseAcceptance](ctx, r.store, kvKey(kvKindAcceptableUse, string(id)))
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(acceptances, func(a, b domain.AcceptableUseAcceptance) int {
+		return b.AcceptedAt.Compare(a.AcceptedAt)
+	})
+
+	return acceptances, nil
+}
+
+// SaveAcceptableUseAcceptance creates or updates the given acceptance.
+func (r *KvRepo) SaveAcceptableUseAcceptance(ctx context.Context, acceptance *domain.AcceptableUseAcceptance) error {
+	key := kvKey(kvKindAcceptableUse, string(acceptance.UserIdentifier), acceptance.Version)
+	return kvPut(ctx, r.store, key, acceptance)
+}
+
+// DeleteAcceptableUseAcceptances deletes all acceptances of the given user.
+func (r *KvRepo) DeleteAcceptableUseAcceptances(ctx context.Context, id domain.UserIdentifier) error {
+	acceptances, err := r.GetAcceptableUseAcceptances(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, acceptance := range acceptances {
+		key := kvKey(kvKindAcceptableUse, string(id), acceptance.Version)
+		if err := r.store.delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion acceptable-use
+
+// region dns-records
+
+// GetDnsRecordSets returns all DNS record sets that are managed by WireGuard Portal.
+func (r *KvRepo) GetDnsRecordSets(ctx context.Context) ([]domain.DnsRecordSet, error) {
+	return kvList[domain.DnsRecordSet](ctx, r.store, kvKindDnsRecordSets)
+}
+
+// GetPeerDnsRecordSets returns all DNS record sets that are owned by the given peer.
+func (r *KvRepo) GetPeerDnsRecordSets(ctx context.Context, id domain.PeerIdentifier) ([]domain.DnsRecordSet, error) {
+	records, err := r.GetDnsRecordSets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(records, func(record domain.DnsRecordSet) bool {
+		return record.PeerId != id
+	}), nil
+}
+
+// SaveDnsRecordSet creates or updates the given DNS record set.
+func (r *KvRepo) SaveDnsRecordSet(ctx context.Context, record *domain.DnsRecordSet) error {
+	return kvPut(ctx, r.store, kvKey(kvKindDnsRecordSets, record.Name, string(record.Type)), record)
+}
+
+// DeleteDnsRecordSet deletes the DNS record set with the given name and type.
+func (r *KvRepo) DeleteDnsRecordSet(ctx context.Context, name string, recordType domain.DnsRecordType) error {
+	return r.store.delete(ctx, kvKey(kvKindDnsRecordSets, name, string(recordType)))
+}
+
+// endregion dns-records
+
+// region route-sets
+
+// GetRouteSet returns the route set with the given id.
+// If no route set is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetRouteSet(ctx context.Context, id domain.RouteSetIdentifier) (*domain.RouteSet, error) {
+	return kvGet[domain.RouteSet](ctx, r.store, kvKey(kvKindRouteSets, string(id)))
+}
+
+// GetAllRouteSets returns all route sets.
+func (r *KvRepo) GetAllRouteSets(ctx context.Context) ([]domain.RouteSet, error) {
+	routeSets, err := kvList[domain.RouteSet](ctx, r.store, kvKindRouteSets)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(routeSets, func(a, b domain.RouteSet) int {
+		return cmp.Compare(a.Identifier, b.Identifier)
+	})
+
+	return routeSets, nil
+}
+
+// SaveRouteSet updates the route set with the given id.
+// If no route set is found, a new route set is created.
+func (r *KvRepo) SaveRouteSet(
+	ctx context.Context,
+	id domain.RouteSetIdentifier,
+	updateFunc func(rs *domain.RouteSet) (*domain.RouteSet, error),
+) error {
+	userInfo := domain.GetUserInfo(ctx)
+
+	return kvUpdate(ctx, r.store, kvKey(kvKindRouteSets, string(id)),
+		func(routeSet *domain.RouteSet) (*domain.RouteSet, error) {
+			if routeSet == nil {
+				routeSet = &domain.RouteSet{
+					BaseModel:  newKvBaseModel(userInfo),
+					Identifier: id,
+				}
+			}
+
+			routeSet, err := updateFunc(routeSet)
+			if err != nil {
+				return nil, err
+			}
+
+			routeSet.UpdatedBy = userInfo.UserId()
+			routeSet.UpdatedAt = time.Now()
+
+			return routeSet, nil
+		})
+}
+
+// DeleteRouteSet deletes the route set with the given id.
+func (r *KvRepo) DeleteRouteSet(ctx context.Context, id domain.RouteSetIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindRouteSets, string(id)))
+}
+
+// endregion route-sets
+
+// region alerts
+
+// GetAlerts returns all firing alerts.
+func (r *KvRepo) GetAlerts(ctx context.Context) ([]domain.Alert, error) {
+	alerts, err := kvList[domain.Alert](ctx, r.store, kvKindAlerts)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(alerts, func(a, b domain.Alert) int {
+		return a.FiredAt.Compare(b.FiredAt)
+	})
+
+	return alerts, nil
+}
+
+// SaveAlert creates or updates the given alert.
+func (r *KvRepo) SaveAlert(ctx context.Context, alert *domain.Alert) error {
+	return kvPut(ctx, r.store, kvKey(kvKindAlerts, alert.RuleName, string(alert.PeerId)), alert)
+}
+
+// DeleteAlert deletes the alert of the given rule and peer.
+func (r *KvRepo) DeleteAlert(ctx context.Context, ruleName string, peerId domain.PeerIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindAlerts, ruleName, string(peerId)))
+}
+
+// DeletePeerAlerts deletes all alerts of the given peer.
+func (r *KvRepo) DeletePeerAlerts(ctx context.Context, peerId domain.PeerIdentifier) error {
+	alerts, err := kvList[domain.Alert](ctx, r.store, kvKindAlerts)
+	if err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		if alert.PeerId != peerId {
+			continue
+		}
+		if err := r.DeleteAlert(ctx, alert.RuleName, alert.PeerId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAlertSilences returns all alert silences.
+func (r *KvRepo) GetAlertSilences(ctx context.Context) ([]domain.AlertSilence, error) {
+	silences, err := kvList[domain.AlertSilence](ctx, r.store, kvKindAlertSilences)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(silences, func(a, b domain.AlertSilence) int {
+		return a.StartsAt.Compare(b.StartsAt)
+	})
+
+	return silences, nil
+}
+
+// SaveAlertSilence creates or updates the given alert silence.
+func (r *KvRepo) SaveAlertSilence(ctx context.Context, silence *domain.AlertSilence) error {
+	if silence.Id == 0 {
+		id, err := r.nextSequence(ctx, kvSequenceAlertSilences)
+		if err != nil {
+			return err
+		}
+		silence.Id = id
+	}
+
+	return kvPut(ctx, r.store, kvKey(kvKindAlertSilences, kvSequenceId(silence.Id)), silence)
+}
+
+// DeleteAlertSilence deletes the alert silence with the given id.
+func (r *KvRepo) DeleteAlertSilence(ctx context.Context, id uint64) error {
+	return r.store.delete(ctx, kvKey(kvKindAlertSilences, kvSequenceId(id)))
+}
+
+// endregion alerts
+
+// region security-events
+
+// GetSecurityEvents returns all security events, the most recent events first.
+func (r *KvRepo) GetSecurityEvents(ctx context.Context) ([]domain.SecurityEvent, error) {
+	events, err := kvList[domain.SecurityEvent](ctx, r.store, kvKindSecurityEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(events, func(a, b domain.SecurityEvent) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return events, nil
+}
+
+// SaveSecurityEvent creates or updates the given security event.
+func (r *KvRepo) SaveSecurityEvent(ctx context.Context, event *domain.SecurityEvent) error {
+	if event.Id == 0 {
+		id, err := r.nextSequence(ctx, kvSequenceSecurity)
+		if err != nil {
+			return err
+		}
+		event.Id = id
+	}
+
+	return kvPut(ctx, r.store, kvKey(kvKindSecurityEvents, kvSequenceId(event.Id)), event)
+}
+
+// DeleteSecurityEvents deletes all security events that were created before the given time.
+func (r *KvRepo) DeleteSecurityEvents(ctx context.Context, before time.Time) error {
+	events, err := kvList[domain.SecurityEvent](ctx, r.store, kvKindSecurityEvents)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if !event.CreatedAt.Before(before) {
+			continue
+		}
+		if err := r.store.delete(ctx, kvKey(kvKindSecurityEvents, kvSequenceId(event.Id))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion security-events
+
+// region announcements
+
+// GetAnnouncement returns the announcement with the given id.
+// If no announcement is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetAnnouncement(ctx context.Context, id uint64) (*domain.Announcement, error) {
+	return kvGet[domain.Announcement](ctx, r.store, kvKey(kvKindAnnouncements, kvSequenceId(id)))
+}
+
+// GetAnnouncements returns all announcements, the most recently started announcements first.
+func (r *KvRepo) GetAnnouncements(ctx context.Context) ([]domain.Announcement, error) {
+	announcements, err := kvList[domain.Announcement](ctx, r.store, kvKindAnnouncements)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(announcements, func(a, b domain.Announcement) int {
+		return b.StartsAt.Compare(a.StartsAt)
+	})
+
+	return announcements, nil
+}
+
+// SaveAnnouncement creates or updates the given announcement.
+func (r *KvRepo) SaveAnnouncement(ctx context.Context, announcement *domain.Announcement) error {
+	if announcement.Id == 0 {
+		id, err := r.nextSequence(ctx, kvSequenceAnnouncements)
+		if err != nil {
+			return err
+		}
+		announcement.Id = id
+	}
+
+	return kvPut(ctx, r.store, kvKey(kvKindAnnouncements, kvSequenceId(announcement.Id)), announcement)
+}
+
+// DeleteAnnouncement deletes the announcement with the given id and all read markers of it.
+func (r *KvRepo) DeleteAnnouncement(ctx context.Context, id uint64) error {
+	reads, err := r.GetAnnouncementReads(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, read := range reads {
+		key := kvKey(kvKindAnnouncementReads, kvSequenceId(id), string(read.UserIdentifier))
+		if err := r.store.delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return r.store.delete(ctx, kvKey(kvKindAnnouncements, kvSequenceId(id)))
+}
+
+// GetAnnouncementReads returns all read markers of the given announcement.
+func (r *KvRepo) GetAnnouncementReads(ctx context.Context, id uint64) ([]domain.AnnouncementRead, error) {
+	reads, err := kvList[domain.AnnouncementRead](ctx, r.store, kvKey(kvKindAnnouncementReads, kvSequenceId(id)))
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(reads, func(a, b domain.AnnouncementRead) int {
+		return a.ReadAt.Compare(b.ReadAt)
+	})
+
+	return reads, nil
+}
+
+// SaveAnnouncementRead creates or updates the given read marker.
+func (r *KvRepo) SaveAnnouncementRead(ctx context.Context, read *domain.AnnouncementRead) error {
+	key := kvKey(kvKindAnnouncementReads, kvSequenceId(read.AnnouncementId), string(read.UserIdentifier))
+	return kvPut(ctx, r.store, key, read)
+}
+
+// endregion announcements
+
+// region export-approvals
+
+// GetExportApproval returns the export approval request with the given id.
+// If no request is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetExportApproval(ctx context.Context, id uint64) (*domain.ExportApproval, error) {
+	return kvGet[domain.ExportApproval](ctx, r.store, kvKey(kvKindExportApprovals, kvSequenceId(id)))
+}
+
+// GetExportApprovals returns all export approval requests, the most recent requests first.
+func (r *KvRepo) GetExportApprovals(ctx context.Context) ([]domain.ExportApproval, error) {
+	approvals, err := kvList[domain.ExportApproval](ctx, r.store, kvKindExportApprovals)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(approvals, func(a, b domain.ExportApproval) int {
+		return b.RequestedAt.Compare(a.RequestedAt)
+	})
+
+	return approvals, nil
+}
+
+// SaveExportApproval creates or updates the given export approval request.
+func (r *KvRepo) SaveExportApproval(ctx context.Context, approval *domain.ExportApproval) error {
+	if approval.Id == 0 {
+		id, err := r.nextSequence(ctx, kvSequenceApprovals)
+		if err != nil {
+			return err
+		}
+		approval.Id = id
+	}
+
+	return kvPut(ctx, r.store, kvKey(kvKindExportApprovals, kvSequenceId(approval.Id)), approval)
+}
+
+// GetKeyExports returns the private key exports of the given user since the given time.
+func (r *KvRepo) GetKeyExports(
+	ctx context.Context,
+	id domain.UserIdentifier,
+	since time.Time,
+) ([]domain.KeyExport, error) {
+	exports, err := kvList[domain.KeyExport](ctx, r.store, kvKey(kvKindKeyExports, string(id)))
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(exports, func(export domain.KeyExport) bool {
+		return !export.ExportedAt.After(since)
+	}), nil
+}
+
+// SaveKeyExport creates or updates the given private key export.
+func (r *KvRepo) SaveKeyExport(ctx context.Context, export *domain.KeyExport) error {
+	key := kvKey(kvKindKeyExports, string(export.UserIdentifier), string(export.PeerIdentifier))
+	return kvPut(ctx, r.store, key, export)
+}
+
+// endregion export-approvals
+
+// region config-pull
+
+func (r *KvRepo) getPeerConfigPullTokens(
+	ctx context.Context,
+	peerId domain.PeerIdentifier,
+) ([]domain.ConfigPullToken, error) {
+	tokens, err := kvList[domain.ConfigPullToken](ctx, r.store, kvKindConfigPullTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(tokens, func(token domain.ConfigPullToken) bool {
+		return token.PeerId != peerId
+	}), nil
+}
+
+// GetConfigPullToken returns the config pull token with the given hash.
+func (r *KvRepo) GetConfigPullToken(ctx context.Context, tokenHash string) (*domain.ConfigPullToken, error) {
+	return kvGet[domain.ConfigPullToken](ctx, r.store, kvKey(kvKindConfigPullTokens, tokenHash))
+}
+
+// GetAllConfigPullTokens returns all config pull tokens.
+func (r *KvRepo) GetAllConfigPullTokens(ctx context.Context) ([]domain.ConfigPullToken, error) {
+	return kvList[domain.ConfigPullToken](ctx, r.store, kvKindConfigPullTokens)
+}
+
+// GetPeerConfigPullToken returns the config pull token of the given peer.
+func (r *KvRepo) GetPeerConfigPullToken(ctx context.Context, peerId domain.PeerIdentifier) (
+	*domain.ConfigPullToken,
+	error,
+) {
+	tokens, err := r.getPeerConfigPullTokens(ctx, peerId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	token := tokens[0]
+
+	return &token, nil
+}
+
+// SaveConfigPullToken stores the given config pull token, replacing an existing token of the same peer.
+func (r *KvRepo) SaveConfigPullToken(ctx context.Context, token *domain.ConfigPullToken) error {
+	tokens, err := r.getPeerConfigPullTokens(ctx, token.PeerId)
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range tokens {
+		if existing.TokenHash == token.TokenHash {
+			continue
+		}
+		if err := r.store.delete(ctx, kvKey(kvKindConfigPullTokens, existing.TokenHash)); err != nil {
+			return err
+		}
+	}
+
+	return kvPut(ctx, r.store, kvKey(kvKindConfigPullTokens, token.TokenHash), token)
+}
+
+// DeletePeerConfigPullToken deletes the config pull token of the given peer.
+func (r *KvRepo) DeletePeerConfigPullToken(ctx context.Context, peerId domain.PeerIdentifier) error {
+	tokens, err := r.getPeerConfigPullTokens(ctx, peerId)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if err := r.store.delete(ctx, kvKey(kvKindConfigPullTokens, token.TokenHash)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateConfigPullTokenPeer moves the config pull token of a peer to the new peer identifier.
+func (r *KvRepo) UpdateConfigPullTokenPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error {
+	tokens, err := r.getPeerConfigPullTokens(ctx, oldId)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		token.PeerId = newId
+		if err := kvPut(ctx, r.store, kvKey(kvKindConfigPullTokens, token.TokenHash), token); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion config-pull
+
+// region ssh-deployments
+
+// GetSshDeployment returns the SSH deployment of the given peer.
+func (r *KvRepo) GetSshDeployment(ctx context.Context, peerId domain.PeerIdentifier) (*domain.SshDeployment, error) {
+	return kvGet[domain.SshDeployment](ctx, r.store, kvKey(kvKindSshDeployments, string(peerId)))
+}
+
+// GetAllSshDeployments returns all SSH deployments.
+func (r *KvRepo) GetAllSshDeployments(ctx context.Context) ([]domain.SshDeployment, error) {
+	return kvList[domain.SshDeployment](ctx, r.store, kvKindSshDeployments)
+}
+
+// SaveSshDeployment creates or updates the given SSH deployment.
+func (r *KvRepo) SaveSshDeployment(ctx context.Context, deployment *domain.SshDeployment) error {
+	return kvPut(ctx, r.store, kvKey(kvKindSshDeployments, string(deployment.PeerId)), deployment)
+}
+
+// DeleteSshDeployment deletes the SSH deployment of the given peer.
+func (r *KvRepo) DeleteSshDeployment(ctx context.Context, peerId domain.PeerIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindSshDeployments, string(peerId)))
+}
+
+// UpdateSshDeploymentPeer moves the SSH deployment of a peer to the new peer identifier.
+func (r *KvRepo) UpdateSshDeploymentPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error {
+	deployment, err := r.GetSshDeployment(ctx, oldId)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	deployment.PeerId = newId
+	if err := r.SaveSshDeployment(ctx, deployment); err != nil {
+		return err
+	}
+
+	return r.DeleteSshDeployment(ctx, oldId)
+}
+
+// endregion ssh-deployments
+
+// region organizations
+
+// GetOrganization returns the organization with the given id.
+// If no organization is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetOrganization(ctx context.Context, id domain.OrganizationIdentifier) (
+	*domain.Organization,
+	error,
+) {
+	return kvGet[domain.Organization](ctx, r.store, kvKey(kvKindOrganizations, string(id)))
+}
+
+// GetAllOrganizations returns all organizations.
+func (r *KvRepo) GetAllOrganizations(ctx context.Context) ([]domain.Organization, error) {
+	organizations, err := kvList[domain.Organization](ctx, r.store, kvKindOrganizations)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(organizations, func(a, b domain.Organization) int {
+		return cmp.Compare(a.Identifier, b.Identifier)
+	})
+
+	return organizations, nil
+}
+
+// SaveOrganization updates the organization with the given id.
+// If no organization is found, a new organization is created.
+func (r *KvRepo) SaveOrganization(
+	ctx context.Context,
+	id domain.OrganizationIdentifier,
+	updateFunc func(o *domain.Organization) (*domain.Organization, error),
+) error {
+	userInfo := domain.GetUserInfo(ctx)
+
+	return kvUpdate(ctx, r.store, kvKey(kvKindOrganizations, string(id)),
+		func(organization *domain.Organization) (*domain.Organization, error) {
+			if organization == nil {
+				organization = &domain.Organization{
+					BaseModel:  newKvBaseModel(userInfo),
+					Identifier: id,
+				}
+			}
+
+			organization, err := updateFunc(organization)
+			if err != nil {
+				return nil, err
+			}
+
+			organization.UpdatedBy = userInfo.UserId()
+			organization.UpdatedAt = time.Now()
+
+			return organization, nil
+		})
+}
+
+// DeleteOrganization deletes the organization with the given id.
+func (r *KvRepo) DeleteOrganization(ctx context.Context, id domain.OrganizationIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindOrganizations, string(id)))
+}
+
+// IsOrganizationInUse returns true if at least one interface or user belongs to the given organization.
+func (r *KvRepo) IsOrganizationInUse(ctx context.Context, id domain.OrganizationIdentifier) (bool, error) {
+	interfaces, err := kvList[domain.Interface](ctx, r.store, kvKindInterfaces)
+	if err != nil {
+		return false, err
+	}
+	if slices.ContainsFunc(interfaces, func(in domain.Interface) bool { return in.OrganizationIdentifier == id }) {
+		return true, nil
+	}
+
+	users, err := kvList[kvUser](ctx, r.store, kvKindUsers)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.ContainsFunc(users, func(u kvUser) bool { return u.OrganizationIdentifier == id }), nil
+}
+
+// endregion organizations
+
+// region user-groups
+
+// GetUserGroup returns the user group with the given id.
+// If no user group is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetUserGroup(ctx context.Context, id domain.UserGroupIdentifier) (*domain.UserGroup, error) {
+	return kvGet[domain.UserGroup](ctx, r.store, kvKey(kvKindUserGroups, string(id)))
+}
+
+// GetAllUserGroups returns all user groups.
+func (r *KvRepo) GetAllUserGroups(ctx context.Context) ([]domain.UserGroup, error) {
+	groups, err := kvList[domain.UserGroup](ctx, r.store, kvKindUserGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(groups, func(a, b domain.UserGroup) int {
+		return cmp.Compare(a.Identifier, b.Identifier)
+	})
+
+	return groups, nil
+}
+
+// SaveUserGroup updates the user group with the given id.
+// If no user group is found, a new user group is created.
+func (r *KvRepo) SaveUserGroup(
+	ctx context.Context,
+	id domain.UserGroupIdentifier,
+	updateFunc func(g *domain.UserGroup) (*domain.UserGroup, error),
+) error {
+	userInfo := domain.GetUserInfo(ctx)
+
+	return kvUpdate(ctx, r.store, kvKey(kvKindUserGroups, string(id)),
+		func(group *domain.UserGroup) (*domain.UserGroup, error) {
+			if group == nil {
+				group = &domain.UserGroup{
+					BaseModel:  newKvBaseModel(userInfo),
+					Identifier: id,
+				}
+			}
+
+			group, err := updateFunc(group)
+			if err != nil {
+				return nil, err
+			}
+
+			group.UpdatedBy = userInfo.UserId()
+			group.UpdatedAt = time.Now()
+
+			return group, nil
+		})
+}
+
+// DeleteUserGroup deletes the user group with the given id.
+func (r *KvRepo) DeleteUserGroup(ctx context.Context, id domain.UserGroupIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindUserGroups, string(id)))
+}
+
+// endregion user-groups
+
+// region port-forwards
+
+// GetPortForward returns the port forward with the given id.
+// If no port forward is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetPortForward(ctx context.Context, id domain.PortForwardIdentifier) (*domain.PortForward, error) {
+	return kvGet[domain.PortForward](ctx, r.store, kvKey(kvKindPortForwards, string(id)))
+}
+
+// GetPortForwards returns all port forwards, ordered by protocol and external port.
+func (r *KvRepo) GetPortForwards(ctx context.Context) ([]domain.PortForward, error) {
+	forwards, err := kvList[domain.PortForward](ctx, r.store, kvKindPortForwards)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(forwards, func(a, b domain.PortForward) int {
+		if a.Protocol != b.Protocol {
+			return strings.Compare(string(a.Protocol), string(b.Protocol))
+		}
+		return a.ExternalPort - b.ExternalPort
+	})
+
+	return forwards, nil
+}
+
+// SavePortForward creates or updates the given port forward.
+func (r *KvRepo) SavePortForward(ctx context.Context, forward *domain.PortForward) error {
+	return kvPut(ctx, r.store, kvKey(kvKindPortForwards, string(forward.Identifier)), forward)
+}
+
+// DeletePortForward deletes the port forward with the given id.
+func (r *KvRepo) DeletePortForward(ctx context.Context, id domain.PortForwardIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindPortForwards, string(id)))
+}
+
+// UpdatePortForwardPeer moves all port forwards of a peer to the new peer identifier.
+func (r *KvRepo) UpdatePortForwardPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error {
+	forwards, err := kvList[domain.PortForward](ctx, r.store, kvKindPortForwards)
+	if err != nil {
+		return err
+	}
+
+	for _, forward := range forwards {
+		if forward.PeerId != oldId {
+			continue
+		}
+
+		err := kvUpdate(ctx, r.store, kvKey(kvKindPortForwards, string(forward.Identifier)),
+			func(current *domain.PortForward) (*domain.PortForward, error) {
+				if current == nil {
+					return nil, domain.ErrNotFound
+				}
+				current.PeerId = newId
+				return current, nil
+			})
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion port-forwards
+
+// region access-requests
+
+// GetAccessRequest returns the access request with the given id.
+// If no request is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetAccessRequest(ctx context.Context, id domain.AccessRequestIdentifier) (
+	*domain.AccessRequest,
+	error,
+) {
+	return kvGet[domain.AccessRequest](ctx, r.store, kvKey(kvKindAccessRequests, string(id)))
+}
+
+// GetAccessRequests returns all access requests.
+func (r *KvRepo) GetAccessRequests(ctx context.Context) ([]domain.AccessRequest, error) {
+	requests, err := kvList[domain.AccessRequest](ctx, r.store, kvKindAccessRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(requests, func(a, b domain.AccessRequest) int {
+		return b.RequestedAt.Compare(a.RequestedAt)
+	})
+
+	return requests, nil
+}
+
+// SaveAccessRequest creates or updates the given access request.
+func (r *KvRepo) SaveAccessRequest(ctx context.Context, request *domain.AccessRequest) error {
+	return kvPut(ctx, r.store, kvKey(kvKindAccessRequests, string(request.Identifier)), request)
+}
+
+// UpdateAccessRequestPeer moves all access requests of a peer to the new peer identifier.
+func (r *KvRepo) UpdateAccessRequestPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error {
+	requests, err := kvList[domain.AccessRequest](ctx, r.store, kvKindAccessRequests)
+	if err != nil {
+		return err
+	}
+
+	for _, request := range requests {
+		if request.PeerId != oldId {
+			continue
+		}
+
+		// only the peer identifier is modified, concurrent state changes are preserved
+		err := kvUpdate(ctx, r.store, kvKey(kvKindAccessRequests, string(request.Identifier)),
+			func(current *domain.AccessRequest) (*domain.AccessRequest, error) {
+				if current == nil {
+					return nil, domain.ErrNotFound
+				}
+				current.PeerId = newId
+				return current, nil
+			})
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion access-requests
+
+// region route-reviews
+
+// GetRouteReview returns the route review with the given id.
+// If no review is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetRouteReview(ctx context.Context, id domain.RouteReviewIdentifier) (
+	*domain.RouteReview,
+	error,
+) {
+	return kvGet[domain.RouteReview](ctx, r.store, kvKey(kvKindRouteReviews, string(id)))
+}
+
+// GetRouteReviews returns all route reviews.
+func (r *KvRepo) GetRouteReviews(ctx context.Context) ([]domain.RouteReview, error) {
+	reviews, err := kvList[domain.RouteReview](ctx, r.store, kvKindRouteReviews)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(reviews, func(a, b domain.RouteReview) int {
+		return b.RequestedAt.Compare(a.RequestedAt)
+	})
+
+	return reviews, nil
+}
+
+// SaveRouteReview creates or updates the given route review.
+func (r *KvRepo) SaveRouteReview(ctx context.Context, review *domain.RouteReview) error {
+	return kvPut(ctx, r.store, kvKey(kvKindRouteReviews, string(review.Identifier)), review)
+}
+
+// UpdateRouteReviewPeer moves all route reviews of a peer to the new peer identifier.
+func (r *KvRepo) UpdateRouteReviewPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error {
+	reviews, err := kvList[domain.RouteReview](ctx, r.store, kvKindRouteReviews)
+	if err != nil {
+		return err
+	}
+
+	for _, review := range reviews {
+		if review.PeerId != oldId {
+			continue
+		}
+
+		// only the peer identifier is modified, concurrent state changes are preserved
+		err := kvUpdate(ctx, r.store, kvKey(kvKindRouteReviews, string(review.Identifier)),
+			func(current *domain.RouteReview) (*domain.RouteReview, error) {
+				if current == nil {
+					return nil, domain.ErrNotFound
+				}
+				current.PeerId = newId
+				return current, nil
+			})
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endregion route-reviews
+
+// region address-reservations
+
+// GetAddressReservation returns the address reservation with the given id.
+// If no reservation is found, an error domain.ErrNotFound is returned.
+func (r *KvRepo) GetAddressReservation(ctx context.Context, id domain.AddressReservationIdentifier) (
+	*domain.AddressReservation,
+	error,
+) {
+	return kvGet[domain.AddressReservation](ctx, r.store, kvKey(kvKindReservations, string(id)))
+}
+
+// GetAddressReservations returns all address reservations of the given interface, ordered by address.
+func (r *KvRepo) GetAddressReservations(ctx context.Context, id domain.InterfaceIdentifier) (
+	[]domain.AddressReservation,
+	error,
+) {
+	reservations, err := kvList[domain.AddressReservation](ctx, r.store, kvKindReservations)
+	if err != nil {
+		return nil, err
+	}
+
+	reservations = slices.DeleteFunc(reservations, func(reservation domain.AddressReservation) bool {
+		return reservation.InterfaceIdentifier != id
+	})
+	slices.SortFunc(reservations, func(a, b domain.AddressReservation) int {
+		return a.Addr().Compare(b.Addr())
+	})
+
+	return reservations, nil
+}
+
+// SaveAddressReservation creates or updates the given address reservation.
+func (r *KvRepo) SaveAddressReservation(ctx context.Context, reservation *domain.AddressReservation) error {
+	return kvPut(ctx, r.store, kvKey(kvKindReservations, string(reservation.Identifier)), reservation)
+}
+
+// DeleteAddressReservation deletes the address reservation with the given id.
+func (r *KvRepo) DeleteAddressReservation(ctx context.Context, id domain.AddressReservationIdentifier) error {
+	return r.store.delete(ctx, kvKey(kvKindReservations, string(id)))
+}
+
+// endregion address-reservations
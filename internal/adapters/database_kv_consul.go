@@ -0,0 +1,195 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// consulStore is a kvStore that uses the HTTP API of the Consul key-value store.
+type consulStore struct {
+	baseUrl string // the Consul API url, for example: https://consul.local:8501
+	prefix  string // the key prefix, for example: wg-portal/
+	token   string // the optional ACL token
+	client  *http.Client
+}
+
+type consulKvPair struct {
+	Key         string
+	Value       []byte
+	ModifyIndex uint64
+}
+
+func newConsulStore(dsn *url.URL) *consulStore {
+	return &consulStore{
+		baseUrl: dsn.Scheme + "://" + dsn.Host,
+		prefix:  kvPrefix(dsn.Path),
+		token:   dsn.Query().Get("token"),
+		client:  &http.Client{Timeout: kvRequestTimeout},
+	}
+}
+
+// kvPrefix returns the key prefix for the given url path.
+func kvPrefix(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return ""
+	}
+	return path + "/"
+}
+
+func (s *consulStore) get(ctx context.Context, key string) (kvPair, error) {
+	pairs, err := s.read(ctx, key, false)
+	if err != nil {
+		return kvPair{}, err
+	}
+	if len(pairs) == 0 {
+		return kvPair{}, domain.ErrNotFound
+	}
+
+	return pairs[0], nil
+}
+
+func (s *consulStore) list(ctx context.Context, prefix string) ([]kvPair, error) {
+	pairs, err := s.read(ctx, prefix, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return pairs, nil
+}
+
+func (s *consulStore) put(ctx context.Context, key string, value []byte) error {
+	_, err := s.write(ctx, key, value, nil)
+	return err
+}
+
+func (s *consulStore) compareAndPut(ctx context.Context, key string, value []byte, revision uint64) error {
+	ok, err := s.write(ctx, key, value, url.Values{"cas": {strconv.FormatUint(revision, 10)}})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errKvConflict
+	}
+
+	return nil
+}
+
+func (s *consulStore) delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkConsulResponse(resp)
+}
+
+func (s *consulStore) read(ctx context.Context, key string, recurse bool) ([]kvPair, error) {
+	var query url.Values
+	if recurse {
+		query = url.Values{"recurse": {"true"}}
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, key, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err := checkConsulResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var consulPairs []consulKvPair
+	if err := json.NewDecoder(resp.Body).Decode(&consulPairs); err != nil {
+		return nil, fmt.Errorf("failed to decode Consul response: %w", err)
+	}
+
+	pairs := make([]kvPair, 0, len(consulPairs))
+	for _, p := range consulPairs {
+		if p.Value == nil {
+			continue // folder entry
+		}
+		pairs = append(pairs, kvPair{
+			Key:      strings.TrimPrefix(p.Key, s.prefix),
+			Value:    p.Value,
+			Revision: p.ModifyIndex,
+		})
+	}
+
+	return pairs, nil
+}
+
+func (s *consulStore) write(ctx context.Context, key string, value []byte, query url.Values) (bool, error) {
+	resp, err := s.do(ctx, http.MethodPut, key, query, value)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkConsulResponse(resp); err != nil {
+		return false, err
+	}
+
+	var ok bool
+	if err := json.NewDecoder(resp.Body).Decode(&ok); err != nil {
+		return false, fmt.Errorf("failed to decode Consul response: %w", err)
+	}
+
+	return ok, nil
+}
+
+func (s *consulStore) do(ctx context.Context, method, key string, query url.Values, body []byte) (
+	*http.Response,
+	error,
+) {
+	segments := strings.Split(s.prefix+key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	endpoint := s.baseUrl + "/v1/kv/" + strings.Join(segments, "/")
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("X-Consul-Token", s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send Consul request: %w", err)
+	}
+
+	return resp, nil
+}
+
+func checkConsulResponse(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("consul request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
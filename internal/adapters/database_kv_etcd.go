@@ -0,0 +1,288 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// etcdStore is a kvStore that uses the JSON gateway of the etcd v3 API.
+type etcdStore struct {
+	baseUrl  string // the etcd API url, for example: http://etcd.local:2379
+	prefix   string // the key prefix, for example: wg-portal/
+	username string
+	password string
+	client   *http.Client
+
+	mux   sync.Mutex
+	token string // the current authentication token
+}
+
+type etcdKeyValue struct {
+	Key         []byte `json:"key"`
+	Value       []byte `json:"value"`
+	ModRevision uint64 `json:"mod_revision,string"`
+}
+
+type etcdRangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+}
+
+type etcdRangeResponse struct {
+	Kvs []etcdKeyValue `json:"kvs"`
+}
+
+type etcdPutRequest struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type etcdCompare struct {
+	Key         []byte `json:"key"`
+	Result      string `json:"result"`
+	Target      string `json:"target"`
+	ModRevision uint64 `json:"mod_revision,string,omitempty"` // if omitted, etcd compares against revision 0
+}
+
+type etcdRequestOp struct {
+	RequestPut *etcdPutRequest `json:"request_put,omitempty"`
+}
+
+type etcdTxnRequest struct {
+	Compare []etcdCompare   `json:"compare"`
+	Success []etcdRequestOp `json:"success"`
+}
+
+type etcdTxnResponse struct {
+	Succeeded bool `json:"succeeded"` // omitted by the gateway if false
+}
+
+type etcdAuthRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type etcdAuthResponse struct {
+	Token string `json:"token"`
+}
+
+// errEtcdUnauthorized is returned if the authentication token of a request was rejected.
+var errEtcdUnauthorized = errors.New("etcd authentication failed")
+
+func newEtcdStore(dsn *url.URL) *etcdStore {
+	s := &etcdStore{
+		baseUrl: dsn.Scheme + "://" + dsn.Host,
+		prefix:  kvPrefix(dsn.Path),
+		client:  &http.Client{Timeout: kvRequestTimeout},
+	}
+	if dsn.User != nil {
+		s.username = dsn.User.Username()
+		s.password, _ = dsn.User.Password()
+	}
+
+	return s
+}
+
+func (s *etcdStore) get(ctx context.Context, key string) (kvPair, error) {
+	var resp etcdRangeResponse
+	if err := s.call(ctx, "/v3/kv/range", etcdRangeRequest{Key: []byte(s.prefix + key)}, &resp); err != nil {
+		return kvPair{}, err
+	}
+	if len(resp.Kvs) == 0 {
+		return kvPair{}, domain.ErrNotFound
+	}
+
+	return s.toPair(resp.Kvs[0]), nil
+}
+
+func (s *etcdStore) list(ctx context.Context, prefix string) ([]kvPair, error) {
+	key := []byte(s.prefix + prefix)
+
+	var resp etcdRangeResponse
+	err := s.call(ctx, "/v3/kv/range", etcdRangeRequest{Key: key, RangeEnd: etcdPrefixEnd(key)}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]kvPair, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		pairs = append(pairs, s.toPair(kv))
+	}
+
+	return pairs, nil
+}
+
+func (s *etcdStore) put(ctx context.Context, key string, value []byte) error {
+	return s.call(ctx, "/v3/kv/put", etcdPutRequest{Key: []byte(s.prefix + key), Value: value}, nil)
+}
+
+func (s *etcdStore) compareAndPut(ctx context.Context, key string, value []byte, revision uint64) error {
+	compare := etcdCompare{
+		Key:         []byte(s.prefix + key),
+		Result:      "EQUAL",
+		Target:      "MOD",
+		ModRevision: revision,
+	}
+	if revision == 0 {
+		compare.Target = "CREATE" // a create revision of 0 means that the key does not exist
+	}
+
+	req := etcdTxnRequest{
+		Compare: []etcdCompare{compare},
+		Success: []etcdRequestOp{{RequestPut: &etcdPutRequest{Key: []byte(s.prefix + key), Value: value}}},
+	}
+
+	var resp etcdTxnResponse
+	if err := s.call(ctx, "/v3/kv/txn", req, &resp); err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return errKvConflict
+	}
+
+	return nil
+}
+
+func (s *etcdStore) delete(ctx context.Context, key string) error {
+	return s.call(ctx, "/v3/kv/deleterange", etcdRangeRequest{Key: []byte(s.prefix + key)}, nil)
+}
+
+func (s *etcdStore) toPair(kv etcdKeyValue) kvPair {
+	return kvPair{
+		Key:      strings.TrimPrefix(string(kv.Key), s.prefix),
+		Value:    kv.Value,
+		Revision: kv.ModRevision,
+	}
+}
+
+// etcdPrefixEnd returns the range end that matches all keys with the given prefix.
+func etcdPrefixEnd(prefix []byte) []byte {
+	end := bytes.Clone(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+
+	return []byte{0} // all keys
+}
+
+// call sends the given request to the etcd gateway. If authentication is configured and the current token
+// expired, the request is retried once with a new token.
+func (s *etcdStore) call(ctx context.Context, path string, request, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode etcd request: %w", err)
+	}
+
+	err = s.post(ctx, path, body, response)
+	if errors.Is(err, errEtcdUnauthorized) && s.username != "" {
+		s.setToken("")
+		err = s.post(ctx, path, body, response)
+	}
+
+	return err
+}
+
+func (s *etcdStore) post(ctx context.Context, path string, body []byte, response any) error {
+	token, err := s.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseUrl+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send etcd request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errEtcdUnauthorized
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("etcd request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if response == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode etcd response: %w", err)
+	}
+
+	return nil
+}
+
+// authenticate returns the authentication token. If no credentials are configured, an empty token is returned.
+func (s *etcdStore) authenticate(ctx context.Context) (string, error) {
+	if s.username == "" {
+		return "", nil
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.token != "" {
+		return s.token, nil
+	}
+
+	body, err := json.Marshal(etcdAuthRequest{Name: s.username, Password: s.password})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode etcd auth request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseUrl+"/v3/auth/authenticate",
+		bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send etcd auth request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("etcd authentication failed with status %d: %s", resp.StatusCode,
+			strings.TrimSpace(string(msg)))
+	}
+
+	var authResp etcdAuthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
+		return "", fmt.Errorf("failed to decode etcd auth response: %w", err)
+	}
+	s.token = authResp.Token
+
+	return s.token, nil
+}
+
+func (s *etcdStore) setToken(token string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.token = token
+}
@@ -0,0 +1,571 @@
+package adapters
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type memoryKvStore struct {
+	mux      sync.Mutex
+	data     map[string]kvPair
+	revision uint64
+
+	conflicts int // number of conflicts that compareAndPut reports before succeeding
+}
+
+func newMemoryKvStore() *memoryKvStore {
+	return &memoryKvStore{data: make(map[string]kvPair)}
+}
+
+func (s *memoryKvStore) get(_ context.Context, key string) (kvPair, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	pair, ok := s.data[key]
+	if !ok {
+		return kvPair{}, domain.ErrNotFound
+	}
+	return pair, nil
+}
+
+func (s *memoryKvStore) list(_ context.Context, prefix string) ([]kvPair, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	var pairs []kvPair
+	for key, pair := range s.data {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, pair)
+		}
+	}
+	slices.SortFunc(pairs, func(a, b kvPair) int { return strings.Compare(a.Key, b.Key) })
+	return pairs, nil
+}
+
+func (s *memoryKvStore) put(_ context.Context, key string, value []byte) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.revision++
+	s.data[key] = kvPair{Key: key, Value: value, Revision: s.revision}
+	return nil
+}
+
+func (s *memoryKvStore) compareAndPut(ctx context.Context, key string, value []byte, revision uint64) error {
+	s.mux.Lock()
+	if s.conflicts > 0 {
+		s.conflicts--
+		s.mux.Unlock()
+		return errKvConflict
+	}
+	if s.data[key].Revision != revision {
+		s.mux.Unlock()
+		return errKvConflict
+	}
+	s.mux.Unlock()
+
+	return s.put(ctx, key, value)
+}
+
+func (s *memoryKvStore) delete(_ context.Context, key string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.data, key)
+	return nil
+}
+
+type reverseEncryptor struct{}
+
+func (reverseEncryptor) EncryptString(value string) (string, error) {
+	runes := []rune(value)
+	slices.Reverse(runes)
+	return "enc:" + string(runes), nil
+}
+
+func (reverseEncryptor) DecryptString(value string) (string, error) {
+	runes := []rune(strings.TrimPrefix(value, "enc:"))
+	slices.Reverse(runes)
+	return string(runes), nil
+}
+
+func newTestKvRepo(t *testing.T) (*KvRepo, *memoryKvStore) {
+	store := newMemoryKvStore()
+	repo, err := newKvRepository(store, reverseEncryptor{})
+	require.NoError(t, err)
+
+	return repo, store
+}
+
+func TestKvRepo_SaveInterface(t *testing.T) {
+	repo, store := newTestKvRepo(t)
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	store.conflicts = 2 // concurrent modifications are retried
+	err := repo.SaveInterface(ctx, "wg0", func(in *domain.Interface) (*domain.Interface, error) {
+		in.DisplayName = "Office"
+		in.KeyPair.PrivateKey = "private"
+		return in, nil
+	})
+	require.NoError(t, err)
+
+	stored, err := store.get(ctx, kvKey(kvKindInterfaces, "wg0"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(stored.Value), `"private"`, "private keys must be stored encrypted")
+
+	in, err := repo.GetInterface(ctx, "wg0")
+	require.NoError(t, err)
+	assert.Equal(t, "Office", in.DisplayName)
+	assert.Equal(t, "private", in.PrivateKey)
+	assert.Equal(t, domain.CtxSystemAdminId, in.CreatedBy)
+
+	found, err := repo.FindInterfaces(ctx, "offi")
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+
+	_, err = repo.GetInterface(ctx, "wg1")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestKvRepo_DeleteInterface(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	for _, id := range []domain.PeerIdentifier{"peer/a", "peer/b"} {
+		err := repo.SavePeer(ctx, id, func(p *domain.Peer) (*domain.Peer, error) {
+			p.InterfaceIdentifier = "wg0"
+			p.PresharedKey = "psk"
+			return p, nil
+		})
+		require.NoError(t, err)
+	}
+	err := repo.SavePeer(ctx, "peer-c", func(p *domain.Peer) (*domain.Peer, error) {
+		p.InterfaceIdentifier = "wg1"
+		return p, nil
+	})
+	require.NoError(t, err)
+
+	peer, err := repo.GetPeer(ctx, "peer/a")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PreSharedKey("psk"), peer.PresharedKey)
+
+	require.NoError(t, repo.DeleteInterface(ctx, "wg0"))
+
+	count, err := repo.GetPeerCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "only the peers of the deleted interface are removed")
+}
+
+func TestKvRepo_SaveUser(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	err := repo.SaveUser(ctx, "alice", func(u *domain.User) (*domain.User, error) {
+		u.Email = "alice@example.com"
+		u.Password = "hash"
+		u.LinkedPeerCount = 3
+		return u, nil
+	})
+	require.NoError(t, err)
+
+	user, err := repo.GetUserByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserIdentifier("alice"), user.Identifier)
+	assert.Equal(t, domain.PrivateString("hash"), user.Password, "the password hash must be persisted")
+	assert.Zero(t, user.LinkedPeerCount)
+	assert.Equal(t, domain.UserSourceDatabase, user.Source)
+
+	_, err = repo.GetUserByEmail(ctx, "bob@example.com")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestKvRepo_SaveAuditEntry(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+
+	for _, message := range []string{"first", "second"} {
+		require.NoError(t, repo.SaveAuditEntry(ctx, &domain.AuditEntry{Message: message}))
+	}
+
+	entries, err := repo.GetAllAuditEntries(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.ElementsMatch(t, []uint64{1, 2}, []uint64{entries[0].UniqueId, entries[1].UniqueId})
+}
+
+func TestKvRepo_PeerConnectionEvents(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	events := []domain.PeerConnectionEvent{
+		{PeerId: "peer/a", Timestamp: start.Add(2 * time.Hour), Kind: domain.PeerStateDisconnected},
+		{PeerId: "peer/a", Timestamp: start, Kind: domain.PeerStateConnected},
+		{PeerId: "peer/a", Timestamp: start.Add(time.Hour), Kind: domain.PeerStateEndpointChanged},
+		{PeerId: "peer/b", Timestamp: start, Kind: domain.PeerStateConnected},
+	}
+	require.NoError(t, repo.SavePeerConnectionEvents(ctx, events))
+
+	result, err := repo.GetPeerConnectionEvents(ctx, "peer/a", start, start.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, domain.PeerStateConnected, result[0].Kind)
+	assert.Equal(t, domain.PeerStateEndpointChanged, result[1].Kind)
+
+	require.NoError(t, repo.DeletePeerConnectionEvents(ctx, start.Add(time.Minute)))
+	result, err = repo.GetPeerConnectionEvents(ctx, "peer/a", start, start.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, result, 2)
+	result, err = repo.GetPeerConnectionEvents(ctx, "peer/b", start, start.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestKvRepo_PeerConfigVersions(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+
+	for _, version := range []int{10, 2, 1} {
+		require.NoError(t, repo.SavePeerConfigVersion(ctx, &domain.PeerConfigVersion{PeerId: "peer/a", Version: version}))
+	}
+	require.NoError(t, repo.SavePeerConfigVersion(ctx, &domain.PeerConfigVersion{PeerId: "peer/b", Version: 1}))
+
+	versions, err := repo.GetPeerConfigVersions(ctx, "peer/a")
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	assert.Equal(t, []int{1, 2, 10}, []int{versions[0].Version, versions[1].Version, versions[2].Version})
+
+	require.NoError(t, repo.DeletePeerConfigVersion(ctx, "peer/a", 1))
+	versions, err = repo.GetPeerConfigVersions(ctx, "peer/a")
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+}
+
+func TestKvRepo_MailLog(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	entries := []domain.MailLogEntry{
+		{SentAt: now.Add(-48 * time.Hour), Subject: "old", Recipients: "alice@example.com", UserIdentifier: "alice"},
+		{SentAt: now, Subject: "new", Recipients: "Alice@example.com, team@example.com", UserIdentifier: "alice",
+			PeerIdentifier: "peer-a", TrackingToken: "token-a"},
+		{SentAt: now.Add(-time.Hour), Subject: "other", Recipients: "bob@example.com", UserIdentifier: "bob"},
+	}
+	for i := range entries {
+		require.NoError(t, repo.SaveMailLogEntry(ctx, &entries[i]))
+	}
+
+	found, err := repo.FindMailLogEntries(ctx, domain.MailLogFilter{})
+	require.NoError(t, err)
+	require.Len(t, found, 3)
+	assert.Equal(t, "new", found[0].Subject, "newest entries first")
+
+	found, err = repo.FindMailLogEntries(ctx, domain.MailLogFilter{Search: "alice@"})
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	found, err = repo.FindMailLogEntries(ctx, domain.MailLogFilter{UserId: "alice", PeerId: "peer-a"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "new", found[0].Subject)
+
+	tracked, err := repo.GetMailLogEntryByTrackingToken(ctx, "token-a")
+	require.NoError(t, err)
+	assert.Equal(t, "new", tracked.Subject)
+	_, err = repo.GetMailLogEntryByTrackingToken(ctx, "")
+	assert.ErrorIs(t, err, domain.ErrNotFound, "untracked mails are never found")
+
+	require.NoError(t, repo.DeleteMailLogEntries(ctx, now.Add(-24*time.Hour)))
+	require.NoError(t, repo.DeleteUserMailLogEntries(ctx, "bob"))
+	found, err = repo.FindMailLogEntries(ctx, domain.MailLogFilter{})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "new", found[0].Subject)
+}
+
+func TestKvRepo_Announcements(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	older := domain.Announcement{Kind: domain.AnnouncementKindInfo, Title: "older", StartsAt: now.Add(-time.Hour)}
+	newer := domain.Announcement{Kind: domain.AnnouncementKindPolicy, Title: "newer", StartsAt: now}
+	require.NoError(t, repo.SaveAnnouncement(ctx, &older))
+	require.NoError(t, repo.SaveAnnouncement(ctx, &newer))
+	assert.NotEqual(t, older.Id, newer.Id)
+
+	announcements, err := repo.GetAnnouncements(ctx)
+	require.NoError(t, err)
+	require.Len(t, announcements, 2)
+	assert.Equal(t, "newer", announcements[0].Title, "most recently started announcements first")
+
+	require.NoError(t, repo.SaveAnnouncementRead(ctx, &domain.AnnouncementRead{AnnouncementId: older.Id,
+		UserIdentifier: "alice", ReadAt: now}))
+	require.NoError(t, repo.SaveAnnouncementRead(ctx, &domain.AnnouncementRead{AnnouncementId: newer.Id,
+		UserIdentifier: "bob", ReadAt: now}))
+
+	reads, err := repo.GetAnnouncementReads(ctx, older.Id)
+	require.NoError(t, err)
+	require.Len(t, reads, 1)
+	assert.Equal(t, domain.UserIdentifier("alice"), reads[0].UserIdentifier)
+
+	require.NoError(t, repo.DeleteAnnouncement(ctx, older.Id))
+	_, err = repo.GetAnnouncement(ctx, older.Id)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	reads, err = repo.GetAnnouncementReads(ctx, older.Id)
+	require.NoError(t, err)
+	assert.Empty(t, reads)
+	reads, err = repo.GetAnnouncementReads(ctx, newer.Id)
+	require.NoError(t, err)
+	assert.Len(t, reads, 1)
+}
+
+func TestKvRepo_ExportApprovals(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	older := domain.ExportApproval{RequestedBy: "alice", RequestedAt: now.Add(-time.Hour),
+		State: domain.ExportApprovalStateRejected}
+	newer := domain.ExportApproval{RequestedBy: "bob", RequestedAt: now, State: domain.ExportApprovalStatePending}
+	require.NoError(t, repo.SaveExportApproval(ctx, &older))
+	require.NoError(t, repo.SaveExportApproval(ctx, &newer))
+	assert.NotEqual(t, older.Id, newer.Id)
+
+	approvals, err := repo.GetExportApprovals(ctx)
+	require.NoError(t, err)
+	require.Len(t, approvals, 2)
+	assert.Equal(t, domain.UserIdentifier("bob"), approvals[0].RequestedBy, "most recent requests first")
+
+	newer.State = domain.ExportApprovalStateApproved
+	require.NoError(t, repo.SaveExportApproval(ctx, &newer))
+	approval, err := repo.GetExportApproval(ctx, newer.Id)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ExportApprovalStateApproved, approval.State)
+
+	_, err = repo.GetExportApproval(ctx, newer.Id+1)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestKvRepo_KeyExports(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	require.NoError(t, repo.SaveKeyExport(ctx, &domain.KeyExport{UserIdentifier: "alice", PeerIdentifier: "p1",
+		ExportedAt: now.Add(-2 * time.Hour)}))
+	require.NoError(t, repo.SaveKeyExport(ctx, &domain.KeyExport{UserIdentifier: "alice", PeerIdentifier: "p2",
+		ExportedAt: now}))
+	require.NoError(t, repo.SaveKeyExport(ctx, &domain.KeyExport{UserIdentifier: "alice2", PeerIdentifier: "p3",
+		ExportedAt: now}))
+
+	exports, err := repo.GetKeyExports(ctx, "alice", now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, exports, 1, "older exports and exports of other users are skipped")
+	assert.Equal(t, domain.PeerIdentifier("p2"), exports[0].PeerIdentifier)
+
+	require.NoError(t, repo.SaveKeyExport(ctx, &domain.KeyExport{UserIdentifier: "alice", PeerIdentifier: "p1",
+		ExportedAt: now}))
+	exports, err = repo.GetKeyExports(ctx, "alice", now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, exports, 2, "a new export of the same peer replaces the previous one")
+}
+
+func TestKvRepo_SavedViews(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+
+	offline := domain.SavedView{UserIdentifier: "alice", List: domain.SavedViewListPeers, Name: "offline",
+		FilterOptions: map[string]string{"deviceType": "laptop"}}
+	lab := domain.SavedView{UserIdentifier: "bob", List: domain.SavedViewListPeers, Name: "lab", Shared: true}
+	admins := domain.SavedView{UserIdentifier: "alice", List: domain.SavedViewListUsers, Name: "admins"}
+	for _, view := range []*domain.SavedView{&offline, &lab, &admins} {
+		require.NoError(t, repo.SaveSavedView(ctx, view))
+	}
+	assert.NotEqual(t, offline.Id, lab.Id)
+
+	views, err := repo.GetSavedViews(ctx, domain.SavedViewListPeers)
+	require.NoError(t, err)
+	require.Len(t, views, 2)
+	assert.Equal(t, "lab", views[0].Name, "views are ordered by name")
+	assert.Equal(t, "laptop", views[1].FilterOptions["deviceType"])
+
+	require.NoError(t, repo.DeleteUserSavedViews(ctx, "alice"))
+	_, err = repo.GetSavedView(ctx, offline.Id)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	views, err = repo.GetSavedViews(ctx, domain.SavedViewListUsers)
+	require.NoError(t, err)
+	assert.Empty(t, views)
+
+	require.NoError(t, repo.DeleteSavedView(ctx, lab.Id))
+	views, err = repo.GetSavedViews(ctx, domain.SavedViewListPeers)
+	require.NoError(t, err)
+	assert.Empty(t, views)
+}
+
+func TestKvRepo_IdempotencyRecords(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	old := domain.IdempotencyRecord{UserIdentifier: "alice", Key: "retry/1", StatusCode: 200,
+		Body: []byte(`{"a":1}`), CreatedAt: now.Add(-25 * time.Hour)}
+	recent := domain.IdempotencyRecord{UserIdentifier: "alice", Key: "retry/2", StatusCode: 200, CreatedAt: now}
+	for _, record := range []*domain.IdempotencyRecord{&old, &recent} {
+		require.NoError(t, repo.SaveIdempotencyRecord(ctx, record))
+	}
+
+	record, err := repo.GetIdempotencyRecord(ctx, "alice", "retry/1")
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(record.Body))
+	_, err = repo.GetIdempotencyRecord(ctx, "bob", "retry/1")
+	assert.ErrorIs(t, err, domain.ErrNotFound, "keys are unique per user")
+
+	require.NoError(t, repo.DeleteIdempotencyRecords(ctx, now.Add(-24*time.Hour)))
+	_, err = repo.GetIdempotencyRecord(ctx, "alice", "retry/1")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = repo.GetIdempotencyRecord(ctx, "alice", "retry/2")
+	assert.NoError(t, err)
+}
+
+func TestKvRepo_WebhookEvents(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	old := domain.WebhookEvent{CreatedAt: now.Add(-48 * time.Hour), Event: "create", Payload: []byte(`{"a":1}`)}
+	recent := domain.WebhookEvent{CreatedAt: now, Event: "update"}
+	for _, event := range []*domain.WebhookEvent{&old, &recent} {
+		require.NoError(t, repo.SaveWebhookEvent(ctx, event))
+	}
+	assert.Equal(t, uint64(1), old.Id)
+	assert.Equal(t, uint64(2), recent.Id)
+
+	lastId, err := repo.GetLastWebhookEventId(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), lastId)
+
+	events, err := repo.GetWebhookEvents(ctx, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, `{"a":1}`, string(events[0].Payload))
+	events, err = repo.GetWebhookEvents(ctx, 1, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(2), events[0].Id)
+
+	require.NoError(t, repo.SaveWebhookDelivery(ctx, &domain.WebhookDelivery{EventId: 1, Endpoint: "siem",
+		Status: domain.WebhookDeliveryStatusDelivered}))
+	require.NoError(t, repo.SaveWebhookDelivery(ctx, &domain.WebhookDelivery{EventId: 1, Endpoint: "default",
+		Status: domain.WebhookDeliveryStatusPending, Attempts: 2}))
+	deliveries, err := repo.GetWebhookDeliveries(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, "default", deliveries[0].Endpoint)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+
+	require.NoError(t, repo.SaveWebhookCursor(ctx, &domain.WebhookCursor{Endpoint: "default", EventId: 1}))
+	cursors, err := repo.GetWebhookCursors(ctx)
+	require.NoError(t, err)
+	require.Len(t, cursors, 1)
+	assert.Equal(t, uint64(1), cursors[0].EventId)
+
+	require.NoError(t, repo.DeleteWebhookEvents(ctx, now.Add(-24*time.Hour)))
+	_, err = repo.GetWebhookEvent(ctx, 1)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	deliveries, err = repo.GetWebhookDeliveries(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries, "deliveries of deleted events are removed")
+	_, err = repo.GetWebhookEvent(ctx, 2)
+	assert.NoError(t, err)
+}
+
+func TestKvRepo_AccessRequests(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	older := domain.AccessRequest{Identifier: "older", PeerId: "peer1", RequestedAt: now.Add(-time.Hour),
+		State: domain.AccessRequestEnded}
+	newer := domain.AccessRequest{Identifier: "newer", PeerId: "peer2", RequestedAt: now,
+		State: domain.AccessRequestPending}
+	require.NoError(t, repo.SaveAccessRequest(ctx, &older))
+	require.NoError(t, repo.SaveAccessRequest(ctx, &newer))
+
+	requests, err := repo.GetAccessRequests(ctx)
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Equal(t, domain.AccessRequestIdentifier("newer"), requests[0].Identifier, "most recent requests first")
+
+	require.NoError(t, repo.UpdateAccessRequestPeer(ctx, "peer2", "peer3"))
+	request, err := repo.GetAccessRequest(ctx, "newer")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerIdentifier("peer3"), request.PeerId)
+	assert.Equal(t, domain.AccessRequestPending, request.State)
+
+	_, err = repo.GetAccessRequest(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestKvRepo_RouteReviews(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	older := domain.RouteReview{Identifier: "older", PeerId: "peer1", RequestedAt: now.Add(-time.Hour),
+		State: domain.RouteReviewApproved}
+	newer := domain.RouteReview{Identifier: "newer", PeerId: "peer2", RequestedAt: now,
+		RoutesStr: "10.0.0.0/8", State: domain.RouteReviewPending}
+	require.NoError(t, repo.SaveRouteReview(ctx, &older))
+	require.NoError(t, repo.SaveRouteReview(ctx, &newer))
+
+	reviews, err := repo.GetRouteReviews(ctx)
+	require.NoError(t, err)
+	require.Len(t, reviews, 2)
+	assert.Equal(t, domain.RouteReviewIdentifier("newer"), reviews[0].Identifier, "most recent reviews first")
+
+	require.NoError(t, repo.UpdateRouteReviewPeer(ctx, "peer2", "peer3"))
+	review, err := repo.GetRouteReview(ctx, "newer")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerIdentifier("peer3"), review.PeerId)
+	assert.Equal(t, "10.0.0.0/8", review.RoutesStr)
+
+	_, err = repo.GetRouteReview(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestKvRepo_AddressReservations(t *testing.T) {
+	repo, _ := newTestKvRepo(t)
+	ctx := context.Background()
+
+	for _, reservation := range []domain.AddressReservation{
+		{Identifier: "r1", InterfaceIdentifier: "wg0", Address: "10.0.0.10", Kind: domain.AddressReservationExcluded},
+		{Identifier: "r2", InterfaceIdentifier: "wg0", Address: "10.0.0.9", Kind: domain.AddressReservationReserved},
+		{Identifier: "r3", InterfaceIdentifier: "wg1", Address: "10.0.1.2", Kind: domain.AddressReservationReserved},
+	} {
+		require.NoError(t, repo.SaveAddressReservation(ctx, &reservation))
+	}
+
+	reservations, err := repo.GetAddressReservations(ctx, "wg0")
+	require.NoError(t, err)
+	require.Len(t, reservations, 2)
+	assert.Equal(t, "10.0.0.9", reservations[0].Address, "addresses are sorted numerically")
+
+	require.NoError(t, repo.DeleteAddressReservation(ctx, "r2"))
+	_, err = repo.GetAddressReservation(ctx, "r2")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	reservation, err := repo.GetAddressReservation(ctx, "r3")
+	require.NoError(t, err)
+	assert.Equal(t, domain.InterfaceIdentifier("wg1"), reservation.InterfaceIdentifier)
+}
@@ -0,0 +1,192 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// databaseTracerName is the instrumentation name of all database spans.
+const databaseTracerName = "github.com/h44z/wg-portal/internal/adapters"
+
+// region gorm
+
+// gormTracer is a Gorm plugin that wraps every database statement in an OpenTelemetry span. The spans are recorded by
+// the global tracer provider, they are dropped if no provider is registered.
+type gormTracer struct {
+	system string
+	tracer trace.Tracer
+}
+
+func newGormTracer(system string) *gormTracer {
+	return &gormTracer{
+		system: system,
+		tracer: otel.Tracer(databaseTracerName),
+	}
+}
+
+func (t *gormTracer) Name() string {
+	return "wg-portal:tracing"
+}
+
+func (t *gormTracer) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", t.before("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", t.after),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", t.before("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", t.after),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", t.before("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", t.after),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", t.before("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", t.after),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", t.before("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", t.after),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", t.before("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", t.after),
+	)
+}
+
+// before starts the span of the statement. The span is stored in the statement context, so that the statement and
+// the query log are linked to it.
+func (t *gormTracer) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		db.Statement.Context, _ = t.tracer.Start(ctx, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", t.system),
+				attribute.String("db.operation", operation),
+			))
+	}
+}
+
+// after completes the span of the statement. The statement is recorded without its values, as they may contain
+// sensitive data.
+func (t *gormTracer) after(db *gorm.DB) {
+	span := trace.SpanFromContext(db.Statement.Context)
+	if !span.IsRecording() {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
+
+// endregion gorm
+
+// region key-value
+
+// tracedKvStore wraps every request to the key-value store in an OpenTelemetry span and logs slow requests.
+type tracedKvStore struct {
+	store         kvStore
+	system        string
+	slowThreshold time.Duration
+	tracer        trace.Tracer
+}
+
+func newTracedKvStore(store kvStore, system string, slowThreshold time.Duration) *tracedKvStore {
+	return &tracedKvStore{
+		store:         store,
+		system:        system,
+		slowThreshold: slowThreshold,
+		tracer:        otel.Tracer(databaseTracerName),
+	}
+}
+
+func (s *tracedKvStore) get(ctx context.Context, key string) (kvPair, error) {
+	ctx, done := s.start(ctx, "get", key)
+	pair, err := s.store.get(ctx, key)
+	done(err)
+	return pair, err
+}
+
+func (s *tracedKvStore) list(ctx context.Context, prefix string) ([]kvPair, error) {
+	ctx, done := s.start(ctx, "list", prefix)
+	pairs, err := s.store.list(ctx, prefix)
+	done(err)
+	return pairs, err
+}
+
+func (s *tracedKvStore) put(ctx context.Context, key string, value []byte) error {
+	ctx, done := s.start(ctx, "put", key)
+	err := s.store.put(ctx, key, value)
+	done(err)
+	return err
+}
+
+func (s *tracedKvStore) compareAndPut(ctx context.Context, key string, value []byte, revision uint64) error {
+	ctx, done := s.start(ctx, "compareAndPut", key)
+	err := s.store.compareAndPut(ctx, key, value, revision)
+	done(err)
+	return err
+}
+
+func (s *tracedKvStore) delete(ctx context.Context, key string) error {
+	ctx, done := s.start(ctx, "delete", key)
+	err := s.store.delete(ctx, key)
+	done(err)
+	return err
+}
+
+// start starts the span of a request. The returned function completes the span and logs the request if it took
+// longer than the slow query threshold. Keys contain identifiers like user names or mail addresses, so only the key
+// namespace (the record kind) is recorded.
+func (s *tracedKvStore) start(ctx context.Context, operation, key string) (context.Context, func(err error)) {
+	begin := time.Now()
+	namespace := kvKeyNamespace(key)
+	ctx, span := s.tracer.Start(ctx, "kv."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", s.system),
+			attribute.String("db.operation", operation),
+			attribute.String("db.kv.namespace", namespace),
+		))
+
+	return ctx, func(err error) {
+		defer span.End()
+
+		// missing keys and concurrent modifications are expected, they are handled by the repository
+		if err != nil && !errors.Is(err, domain.ErrNotFound) && !errors.Is(err, errKvConflict) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		elapsed := time.Since(begin)
+		if s.slowThreshold != 0 && elapsed > s.slowThreshold {
+			slog.WarnContext(ctx, "slow key-value store request", "operation", operation, "namespace", namespace,
+				"duration", elapsed, "threshold", s.slowThreshold)
+		}
+	}
+}
+
+// kvKeyNamespace returns the first segment of the given key, see kvKey.
+func kvKeyNamespace(key string) string {
+	namespace, _, _ := strings.Cut(key, "/")
+	return namespace
+}
+
+// endregion key-value
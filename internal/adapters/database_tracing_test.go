@@ -0,0 +1,118 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// recordedSpan records the attributes and the error of a span, all other methods are not implemented.
+type recordedSpan struct {
+	trace.Span
+
+	name  string
+	attrs map[attribute.Key]attribute.Value
+	err   error
+	ended bool
+}
+
+func (s *recordedSpan) IsRecording() bool { return true }
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, a := range kv {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error, _ ...trace.EventOption) { s.err = err }
+
+func (s *recordedSpan) End(_ ...trace.SpanEndOption) { s.ended = true }
+
+type recordingTracer struct {
+	trace.Tracer
+
+	spans []*recordedSpan
+}
+
+func newRecordingTracer() *recordingTracer {
+	return &recordingTracer{Tracer: noop.NewTracerProvider().Tracer("test")}
+}
+
+func (t *recordingTracer) Start(
+	ctx context.Context,
+	name string,
+	opts ...trace.SpanStartOption,
+) (context.Context, trace.Span) {
+	_, noopSpan := t.Tracer.Start(ctx, name)
+	span := &recordedSpan{Span: noopSpan, name: name, attrs: make(map[attribute.Key]attribute.Value)}
+	cfg := trace.NewSpanStartConfig(opts...)
+	span.SetAttributes(cfg.Attributes()...)
+	t.spans = append(t.spans, span)
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func TestGormTracer(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&SysStat{}))
+
+	tracer := newRecordingTracer()
+	require.NoError(t, db.Use(&gormTracer{system: "sqlite", tracer: tracer}))
+
+	require.NoError(t, db.Create(&SysStat{SchemaVersion: 1}).Error)
+	err = db.First(&SysStat{}, "schema_version = ?", 2).Error
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	require.Len(t, tracer.spans, 2)
+	create, query := tracer.spans[0], tracer.spans[1]
+
+	assert.Equal(t, "db.create", create.name)
+	assert.True(t, create.ended)
+	assert.Equal(t, "sqlite", create.attrs["db.system"].AsString())
+	assert.Equal(t, "sys_stats", create.attrs["db.sql.table"].AsString())
+	assert.Contains(t, create.attrs["db.statement"].AsString(), "INSERT INTO `sys_stats`")
+	assert.Equal(t, int64(1), create.attrs["db.rows_affected"].AsInt64())
+
+	assert.Equal(t, "db.query", query.name)
+	assert.True(t, query.ended)
+	assert.NotContains(t, query.attrs["db.statement"].AsString(), "2", "values are not recorded")
+	assert.NoError(t, query.err, "missing records are not an error")
+}
+
+func TestTracedKvStore(t *testing.T) {
+	tracer := newRecordingTracer()
+	store := &tracedKvStore{store: newMemoryKvStore(), system: "etcd", tracer: tracer}
+	ctx := context.Background()
+
+	require.NoError(t, store.put(ctx, "peers/a", []byte("a")))
+	_, err := store.get(ctx, "peers/b")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+	err = store.compareAndPut(ctx, "peers/a", []byte("a2"), 0)
+	require.ErrorIs(t, err, errKvConflict)
+
+	require.Len(t, tracer.spans, 3)
+	assert.Equal(t, []string{"kv.put", "kv.get", "kv.compareAndPut"},
+		[]string{tracer.spans[0].name, tracer.spans[1].name, tracer.spans[2].name})
+	for _, span := range tracer.spans {
+		assert.True(t, span.ended, span.name)
+		assert.NoError(t, span.err, "expected errors are not recorded")
+		assert.Equal(t, "etcd", span.attrs["db.system"].AsString())
+	}
+	assert.Equal(t, "peers", tracer.spans[1].attrs["db.kv.namespace"].AsString())
+	for _, span := range tracer.spans {
+		for key, value := range span.attrs {
+			assert.NotContains(t, value.Emit(), "peers/", "%s: keys are not recorded", key)
+		}
+	}
+}
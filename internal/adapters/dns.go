@@ -0,0 +1,63 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type dnsProvider interface {
+	replaceRecordSet(ctx context.Context, record domain.DnsRecordSet) error
+	deleteRecordSet(ctx context.Context, record domain.DnsRecordSet) error
+}
+
+// DnsRepo manages DNS record sets at the configured DNS provider.
+type DnsRepo struct {
+	provider dnsProvider
+}
+
+// NewDnsRepository creates a new DnsRepo instance for the configured DNS provider.
+func NewDnsRepository(cfg config.DnsRecordConfig) (*DnsRepo, error) {
+	if !cfg.Enabled {
+		return &DnsRepo{}, nil
+	}
+
+	var provider dnsProvider
+	var err error
+	switch cfg.Provider {
+	case config.DnsProviderRfc2136:
+		provider, err = newRfc2136Provider(cfg.Rfc2136)
+	case config.DnsProviderPowerDns:
+		provider, err = newPowerDnsProvider(cfg.PowerDns)
+	case config.DnsProviderRoute53:
+		provider, err = newRoute53Provider(cfg.Route53)
+	default:
+		err = fmt.Errorf("unsupported dns provider: %s", cfg.Provider)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize dns provider: %w", err)
+	}
+
+	return &DnsRepo{provider: provider}, nil
+}
+
+// ReplaceRecordSet creates the given record set or replaces all records of an existing record set.
+func (r *DnsRepo) ReplaceRecordSet(ctx context.Context, record domain.DnsRecordSet) error {
+	if r.provider == nil {
+		return errors.New("dns record management is disabled")
+	}
+
+	return r.provider.replaceRecordSet(ctx, record)
+}
+
+// DeleteRecordSet removes the given record set.
+func (r *DnsRepo) DeleteRecordSet(ctx context.Context, record domain.DnsRecordSet) error {
+	if r.provider == nil {
+		return errors.New("dns record management is disabled")
+	}
+
+	return r.provider.deleteRecordSet(ctx, record)
+}
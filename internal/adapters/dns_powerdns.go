@@ -0,0 +1,100 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type powerDnsProvider struct {
+	cfg    config.PowerDnsConfig
+	client *http.Client
+}
+
+type powerDnsRecord struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+type powerDnsRecordSet struct {
+	Name       string           `json:"name"`
+	Type       string           `json:"type"`
+	TTL        uint32           `json:"ttl,omitempty"`
+	ChangeType string           `json:"changetype"`
+	Records    []powerDnsRecord `json:"records"`
+}
+
+func newPowerDnsProvider(cfg config.PowerDnsConfig) (*powerDnsProvider, error) {
+	if cfg.Url == "" {
+		return nil, errors.New("missing PowerDNS API url")
+	}
+	if cfg.ServerId == "" {
+		cfg.ServerId = "localhost"
+	}
+
+	return &powerDnsProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (p *powerDnsProvider) replaceRecordSet(ctx context.Context, record domain.DnsRecordSet) error {
+	rrSet := powerDnsRecordSet{
+		Name:       record.Name,
+		Type:       string(record.Type),
+		TTL:        record.TTL,
+		ChangeType: "REPLACE",
+	}
+	for _, value := range record.Values() {
+		rrSet.Records = append(rrSet.Records, powerDnsRecord{Content: value})
+	}
+
+	return p.patchZone(ctx, record.Zone, rrSet)
+}
+
+func (p *powerDnsProvider) deleteRecordSet(ctx context.Context, record domain.DnsRecordSet) error {
+	return p.patchZone(ctx, record.Zone, powerDnsRecordSet{
+		Name:       record.Name,
+		Type:       string(record.Type),
+		ChangeType: "DELETE",
+		Records:    []powerDnsRecord{},
+	})
+}
+
+func (p *powerDnsProvider) patchZone(ctx context.Context, zone string, rrSet powerDnsRecordSet) error {
+	body, err := json.Marshal(map[string][]powerDnsRecordSet{"rrsets": {rrSet}})
+	if err != nil {
+		return fmt.Errorf("failed to encode rrset: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v1/servers/%s/zones/%s", strings.TrimSuffix(p.cfg.Url, "/"),
+		url.PathEscape(p.cfg.ServerId), url.PathEscape(domain.DnsFqdn("", zone)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", p.cfg.ApiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send PowerDNS request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("PowerDNS request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
@@ -0,0 +1,312 @@
+package adapters
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	dnsOpCodeUpdate = dnsmessage.OpCode(5)
+	dnsTypeTSIG     = 250
+	tsigFudge       = 300
+)
+
+type rfc2136Provider struct {
+	cfg        config.Rfc2136Config
+	server     string
+	tsigSecret []byte
+	tsigHash   func() hash.Hash
+}
+
+func newRfc2136Provider(cfg config.Rfc2136Config) (*rfc2136Provider, error) {
+	if cfg.Server == "" {
+		return nil, errors.New("missing RFC2136 server")
+	}
+
+	p := &rfc2136Provider{
+		cfg:    cfg,
+		server: cfg.Server,
+	}
+	if _, _, err := net.SplitHostPort(cfg.Server); err != nil {
+		p.server = net.JoinHostPort(cfg.Server, "53")
+	}
+
+	if cfg.TsigKeyName != "" {
+		secret, err := base64.StdEncoding.DecodeString(cfg.TsigSecret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TSIG secret: %w", err)
+		}
+		p.tsigSecret = secret
+
+		switch strings.ToLower(cfg.TsigAlgorithm) {
+		case "hmac-sha1":
+			p.tsigHash = sha1.New
+		case "hmac-sha256", "":
+			p.cfg.TsigAlgorithm = "hmac-sha256"
+			p.tsigHash = sha256.New
+		case "hmac-sha512":
+			p.tsigHash = sha512.New
+		default:
+			return nil, fmt.Errorf("unsupported TSIG algorithm: %s", cfg.TsigAlgorithm)
+		}
+	}
+
+	return p, nil
+}
+
+func (p *rfc2136Provider) replaceRecordSet(ctx context.Context, record domain.DnsRecordSet) error {
+	return p.update(ctx, record, true)
+}
+
+func (p *rfc2136Provider) deleteRecordSet(ctx context.Context, record domain.DnsRecordSet) error {
+	return p.update(ctx, record, false)
+}
+
+// update sends a dynamic update message that removes the record set and, if requested, re-adds all record values.
+func (p *rfc2136Provider) update(ctx context.Context, record domain.DnsRecordSet, add bool) error {
+	msg, id, err := p.buildUpdate(record, add)
+	if err != nil {
+		return fmt.Errorf("failed to build update message: %w", err)
+	}
+
+	if p.tsigHash != nil {
+		msg, err = p.sign(msg, id)
+		if err != nil {
+			return fmt.Errorf("failed to sign update message: %w", err)
+		}
+	}
+
+	return p.exchange(ctx, msg, id)
+}
+
+func (p *rfc2136Provider) buildUpdate(record domain.DnsRecordSet, add bool) ([]byte, uint16, error) {
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, 0, err
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	zoneName, err := dnsmessage.NewName(domain.DnsFqdn("", record.Zone))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid zone %s: %w", record.Zone, err)
+	}
+	recordName, err := dnsmessage.NewName(record.Name)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid record name %s: %w", record.Name, err)
+	}
+	recordType, err := dnsMessageType(record.Type)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, OpCode: dnsOpCodeUpdate})
+
+	// the zone section
+	if err := b.StartQuestions(); err != nil {
+		return nil, 0, err
+	}
+	if err := b.Question(dnsmessage.Question{
+		Name:  zoneName,
+		Type:  dnsmessage.TypeSOA,
+		Class: dnsmessage.ClassINET,
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	// the update section
+	if err := b.StartAuthorities(); err != nil {
+		return nil, 0, err
+	}
+	if err := b.UnknownResource(dnsmessage.ResourceHeader{
+		Name:  recordName,
+		Class: dnsmessage.ClassANY,
+	}, dnsmessage.UnknownResource{Type: recordType}); err != nil {
+		return nil, 0, err
+	}
+
+	if add {
+		for _, value := range record.Values() {
+			header := dnsmessage.ResourceHeader{Name: recordName, Class: dnsmessage.ClassINET, TTL: record.TTL}
+			if err := addDnsMessageResource(&b, header, record.Type, value); err != nil {
+				return nil, 0, fmt.Errorf("invalid record value %s: %w", value, err)
+			}
+		}
+	}
+
+	msg, err := b.Finish()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return msg, id, nil
+}
+
+// sign appends a TSIG record (RFC 8945) to the given message.
+func (p *rfc2136Provider) sign(msg []byte, id uint16) ([]byte, error) {
+	keyName, err := dnsWireName(p.cfg.TsigKeyName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TSIG key name: %w", err)
+	}
+	algorithmName, err := dnsWireName(p.cfg.TsigAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	now := uint64(time.Now().Unix())
+	var timeSigned [8]byte
+	binary.BigEndian.PutUint64(timeSigned[:], now)
+
+	// TSIG variables, see RFC 8945 section 4.3.3
+	variables := make([]byte, 0, 64)
+	variables = append(variables, keyName...)
+	variables = binary.BigEndian.AppendUint16(variables, uint16(dnsmessage.ClassANY))
+	variables = binary.BigEndian.AppendUint32(variables, 0) // TTL
+	variables = append(variables, algorithmName...)
+	variables = append(variables, timeSigned[2:]...)
+	variables = binary.BigEndian.AppendUint16(variables, tsigFudge)
+	variables = binary.BigEndian.AppendUint16(variables, 0) // error
+	variables = binary.BigEndian.AppendUint16(variables, 0) // other len
+
+	mac := hmac.New(p.tsigHash, p.tsigSecret)
+	mac.Write(msg)
+	mac.Write(variables)
+	signature := mac.Sum(nil)
+
+	rdata := make([]byte, 0, 64+len(signature))
+	rdata = append(rdata, algorithmName...)
+	rdata = append(rdata, timeSigned[2:]...)
+	rdata = binary.BigEndian.AppendUint16(rdata, tsigFudge)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(signature)))
+	rdata = append(rdata, signature...)
+	rdata = binary.BigEndian.AppendUint16(rdata, id)
+	rdata = binary.BigEndian.AppendUint16(rdata, 0) // error
+	rdata = binary.BigEndian.AppendUint16(rdata, 0) // other len
+
+	signed := make([]byte, 0, len(msg)+len(keyName)+10+len(rdata))
+	signed = append(signed, msg...)
+	signed = append(signed, keyName...)
+	signed = binary.BigEndian.AppendUint16(signed, dnsTypeTSIG)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(dnsmessage.ClassANY))
+	signed = binary.BigEndian.AppendUint32(signed, 0) // TTL
+	signed = binary.BigEndian.AppendUint16(signed, uint16(len(rdata)))
+	signed = append(signed, rdata...)
+
+	// increase the additional record count
+	binary.BigEndian.PutUint16(signed[10:12], binary.BigEndian.Uint16(signed[10:12])+1)
+
+	return signed, nil
+}
+
+func (p *rfc2136Provider) exchange(ctx context.Context, msg []byte, id uint16) error {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", p.server)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", p.server, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(msg); err != nil {
+		return fmt.Errorf("failed to send update message: %w", err)
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return fmt.Errorf("failed to read update response: %w", err)
+		}
+
+		var parser dnsmessage.Parser
+		header, err := parser.Start(buf[:n])
+		if err != nil || header.ID != id || !header.Response {
+			continue // ignore unrelated or malformed responses
+		}
+
+		if header.RCode != dnsmessage.RCodeSuccess {
+			return fmt.Errorf("update rejected by %s: %s", p.server, header.RCode)
+		}
+
+		return nil
+	}
+}
+
+func dnsMessageType(t domain.DnsRecordType) (dnsmessage.Type, error) {
+	switch t {
+	case domain.DnsRecordTypeA:
+		return dnsmessage.TypeA, nil
+	case domain.DnsRecordTypeAAAA:
+		return dnsmessage.TypeAAAA, nil
+	case domain.DnsRecordTypePTR:
+		return dnsmessage.TypePTR, nil
+	default:
+		return 0, fmt.Errorf("unsupported record type: %s", t)
+	}
+}
+
+func addDnsMessageResource(b *dnsmessage.Builder, h dnsmessage.ResourceHeader, t domain.DnsRecordType,
+	value string) error {
+	switch t {
+	case domain.DnsRecordTypeA:
+		addr, err := netip.ParseAddr(value)
+		if err != nil || !addr.Is4() {
+			return errors.New("not an IPv4 address")
+		}
+		return b.AResource(h, dnsmessage.AResource{A: addr.As4()})
+	case domain.DnsRecordTypeAAAA:
+		addr, err := netip.ParseAddr(value)
+		if err != nil || !addr.Is6() {
+			return errors.New("not an IPv6 address")
+		}
+		return b.AAAAResource(h, dnsmessage.AAAAResource{AAAA: addr.As16()})
+	case domain.DnsRecordTypePTR:
+		name, err := dnsmessage.NewName(value)
+		if err != nil {
+			return err
+		}
+		return b.PTRResource(h, dnsmessage.PTRResource{PTR: name})
+	default:
+		return fmt.Errorf("unsupported record type: %s", t)
+	}
+}
+
+// dnsWireName returns the uncompressed, lower case wire format of the given domain name.
+func dnsWireName(name string) ([]byte, error) {
+	name = strings.Trim(strings.ToLower(name), ".")
+	var wire []byte
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if len(label) == 0 || len(label) > 63 {
+				return nil, fmt.Errorf("invalid label in name %s", name)
+			}
+			wire = append(wire, byte(len(label)))
+			wire = append(wire, label...)
+		}
+	}
+
+	return append(wire, 0), nil
+}
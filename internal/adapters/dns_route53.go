@@ -0,0 +1,140 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	route53Endpoint = "https://route53.amazonaws.com"
+	route53Region   = "us-east-1"
+	route53Service  = "route53"
+)
+
+type route53Provider struct {
+	cfg      config.Route53Config
+	client   *http.Client
+	endpoint string
+}
+
+type route53ChangeRequest struct {
+	XMLName     xml.Name           `xml:"ChangeResourceRecordSetsRequest"`
+	Xmlns       string             `xml:"xmlns,attr"`
+	ChangeBatch route53ChangeBatch `xml:"ChangeBatch"`
+}
+
+type route53ChangeBatch struct {
+	Changes []route53Change `xml:"Changes>Change"`
+}
+
+type route53Change struct {
+	Action            string                   `xml:"Action"`
+	ResourceRecordSet route53ResourceRecordSet `xml:"ResourceRecordSet"`
+}
+
+type route53ResourceRecordSet struct {
+	Name            string   `xml:"Name"`
+	Type            string   `xml:"Type"`
+	TTL             uint32   `xml:"TTL"`
+	ResourceRecords []string `xml:"ResourceRecords>ResourceRecord>Value"`
+}
+
+func newRoute53Provider(cfg config.Route53Config) (*route53Provider, error) {
+	if cfg.AccessKeyId == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("missing Route 53 credentials")
+	}
+	if len(cfg.HostedZones) == 0 {
+		return nil, errors.New("missing Route 53 hosted zones")
+	}
+
+	return &route53Provider{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		endpoint: route53Endpoint,
+	}, nil
+}
+
+func (p *route53Provider) replaceRecordSet(ctx context.Context, record domain.DnsRecordSet) error {
+	return p.change(ctx, "UPSERT", record)
+}
+
+func (p *route53Provider) deleteRecordSet(ctx context.Context, record domain.DnsRecordSet) error {
+	// Route 53 requires the exact record values for deletions, they are taken from the stored record set
+	return p.change(ctx, "DELETE", record)
+}
+
+func (p *route53Provider) change(ctx context.Context, action string, record domain.DnsRecordSet) error {
+	zoneId, err := p.hostedZoneId(record.Zone)
+	if err != nil {
+		return err
+	}
+
+	body, err := xml.Marshal(route53ChangeRequest{
+		Xmlns: "https://route53.amazonaws.com/doc/2013-04-01/",
+		ChangeBatch: route53ChangeBatch{
+			Changes: []route53Change{{
+				Action: action,
+				ResourceRecordSet: route53ResourceRecordSet{
+					Name:            record.Name,
+					Type:            string(record.Type),
+					TTL:             record.TTL,
+					ResourceRecords: record.Values(),
+				},
+			}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode change request: %w", err)
+	}
+	body = append([]byte(xml.Header), body...)
+
+	endpoint := fmt.Sprintf("%s/2013-04-01/hostedzone/%s/rrset", p.endpoint, zoneId)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	p.signRequest(req, body, time.Now().UTC())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send Route 53 request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Route 53 request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+func (p *route53Provider) hostedZoneId(zone string) (string, error) {
+	zone = strings.Trim(strings.ToLower(zone), ".")
+	for name, id := range p.cfg.HostedZones {
+		if strings.Trim(strings.ToLower(name), ".") == zone {
+			return strings.TrimPrefix(id, "/hostedzone/"), nil
+		}
+	}
+
+	return "", fmt.Errorf("no Route 53 hosted zone configured for zone %s", zone)
+}
+
+// signRequest adds an AWS signature version 4 to the request.
+func (p *route53Provider) signRequest(req *http.Request, body []byte, now time.Time) {
+	signAwsRequest(req, body, now, route53Region, route53Service, awsCredentials{
+		AccessKeyId:     p.cfg.AccessKeyId,
+		SecretAccessKey: p.cfg.SecretAccessKey,
+	})
+}
@@ -0,0 +1,87 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type eventPublisher interface {
+	publish(ctx context.Context, event domain.StreamEvent, data []byte) error
+	close() error
+}
+
+// EventStreamRepo publishes events to the configured message broker.
+type EventStreamRepo struct {
+	publisher eventPublisher
+}
+
+// NewEventStreamRepository creates a new EventStreamRepo instance for the configured publisher.
+func NewEventStreamRepository(cfg config.EventStreamConfig) (*EventStreamRepo, error) {
+	if !cfg.Enabled {
+		return &EventStreamRepo{}, nil
+	}
+
+	var publisher eventPublisher
+	var err error
+	switch cfg.Publisher {
+	case config.EventStreamPublisherNats:
+		publisher, err = newNatsPublisher(cfg.Nats)
+	case config.EventStreamPublisherKafka:
+		publisher, err = newKafkaPublisher(cfg.Kafka)
+	default:
+		err = fmt.Errorf("unsupported event stream publisher: %s", cfg.Publisher)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize event stream publisher: %w", err)
+	}
+
+	return &EventStreamRepo{publisher: publisher}, nil
+}
+
+// PublishEvent publishes the given event as JSON document.
+func (r *EventStreamRepo) PublishEvent(ctx context.Context, event domain.StreamEvent) error {
+	if r.publisher == nil {
+		return errors.New("event stream is disabled")
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", event.Name, err)
+	}
+
+	return r.publisher.publish(ctx, event, data)
+}
+
+// Close closes the connections to the message broker.
+func (r *EventStreamRepo) Close() error {
+	if r.publisher == nil {
+		return nil
+	}
+
+	return r.publisher.close()
+}
+
+// eventStreamDeadline returns the deadline for a single publish operation.
+func eventStreamDeadline(ctx context.Context, timeout time.Duration) time.Time {
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	return deadline
+}
+
+// eventStreamHost returns the host part of the given address, it is used as TLS server name.
+func eventStreamHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,580 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// Kafka API keys and the versions that are used by the publisher. The versions are supported by all brokers since
+// Kafka 1.0, including Kafka 4.
+const (
+	kafkaApiProduce           int16 = 0
+	kafkaApiMetadata          int16 = 3
+	kafkaApiSaslHandshake     int16 = 17
+	kafkaApiSaslAuthenticate  int16 = 36
+	kafkaProduceVersion       int16 = 3
+	kafkaMetadataVersion      int16 = 4
+	kafkaSaslHandshakeVersion int16 = 1
+	kafkaSaslAuthVersion      int16 = 0
+)
+
+var kafkaCrcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// kafkaErrorNames contains the names of the Kafka error codes that are expected by the publisher.
+var kafkaErrorNames = map[int16]string{
+	3:  "UNKNOWN_TOPIC_OR_PARTITION",
+	5:  "LEADER_NOT_AVAILABLE",
+	6:  "NOT_LEADER_OR_FOLLOWER",
+	7:  "REQUEST_TIMED_OUT",
+	10: "MESSAGE_TOO_LARGE",
+	19: "NOT_ENOUGH_REPLICAS",
+	29: "TOPIC_AUTHORIZATION_FAILED",
+	33: "UNSUPPORTED_SASL_MECHANISM",
+	58: "SASL_AUTHENTICATION_FAILED",
+}
+
+// kafkaPublisher publishes events to a single Kafka topic. The entity identifier is used as record key, so all
+// events of an entity are written to the same partition, using the same partitioner as the Java client.
+type kafkaPublisher struct {
+	cfg config.KafkaConfig
+
+	mux           sync.Mutex
+	correlationId int32
+	brokers       map[int32]string // the broker addresses by node id
+	leaders       []int32          // the leader node id of each partition
+	conns         map[string]*kafkaConn
+}
+
+type kafkaPartition struct {
+	id     int32
+	leader int32
+}
+
+type kafkaConn struct {
+	conn net.Conn
+}
+
+func newKafkaPublisher(cfg config.KafkaConfig) (*kafkaPublisher, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("missing Kafka brokers")
+	}
+	if cfg.Topic == "" {
+		return nil, errors.New("missing Kafka topic")
+	}
+
+	return &kafkaPublisher{
+		cfg:   cfg,
+		conns: make(map[string]*kafkaConn),
+	}, nil
+}
+
+func (p *kafkaPublisher) publish(ctx context.Context, event domain.StreamEvent, data []byte) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	var err error
+	for range 2 { // the partition leader might have moved, so try again with fresh metadata
+		if err = p.produce(ctx, event, data); err == nil {
+			return nil
+		}
+		p.reset()
+	}
+
+	return fmt.Errorf("failed to publish Kafka record: %w", err)
+}
+
+func (p *kafkaPublisher) close() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.reset()
+	return nil
+}
+
+// reset closes all connections and forgets the cluster metadata.
+func (p *kafkaPublisher) reset() {
+	for _, c := range p.conns {
+		_ = c.conn.Close()
+	}
+	p.conns = make(map[string]*kafkaConn)
+	p.brokers = nil
+	p.leaders = nil
+}
+
+func (p *kafkaPublisher) produce(ctx context.Context, event domain.StreamEvent, data []byte) error {
+	deadline := eventStreamDeadline(ctx, p.cfg.Timeout)
+	if p.leaders == nil {
+		if err := p.loadMetadata(ctx, deadline); err != nil {
+			return err
+		}
+	}
+
+	key := []byte(event.Identifier)
+	partition := (kafkaMurmur2(key) & 0x7fffffff) % int32(len(p.leaders))
+	addr, ok := p.brokers[p.leaders[partition]]
+	if !ok {
+		return fmt.Errorf("unknown leader %d of partition %d", p.leaders[partition], partition)
+	}
+	conn, err := p.connection(ctx, addr, deadline)
+	if err != nil {
+		return err
+	}
+
+	records := kafkaRecordBatch(event.Time, key, data, map[string]string{"event": event.Name})
+
+	var req kafkaEncoder
+	req.int16(-1) // no transactional id
+	req.int16(-1) // acks from all in-sync replicas
+	req.int32(int32(time.Until(deadline).Milliseconds()))
+	req.int32(1)
+	req.string(p.cfg.Topic)
+	req.int32(1)
+	req.int32(partition)
+	req.bytes(records)
+
+	resp, err := p.roundTrip(conn, kafkaApiProduce, kafkaProduceVersion, req.buf.Bytes(), deadline)
+	if err != nil {
+		return err
+	}
+
+	topics := resp.int32()
+	for range topics {
+		_ = resp.string()
+		partitions := resp.int32()
+		for range partitions {
+			_ = resp.int32() // partition
+			if code := resp.int16(); code != 0 {
+				return fmt.Errorf("produce request failed: %s", kafkaErrorName(code))
+			}
+			_ = resp.int64() // base offset
+			_ = resp.int64() // log append time
+		}
+	}
+
+	return resp.err
+}
+
+// loadMetadata loads the brokers and the partition leaders of the topic from the first reachable bootstrap broker.
+func (p *kafkaPublisher) loadMetadata(ctx context.Context, deadline time.Time) error {
+	var req kafkaEncoder
+	req.int32(1)
+	req.string(p.cfg.Topic)
+	req.bool(true) // allow auto topic creation, if enabled on the broker
+
+	var err error
+	for _, bootstrap := range p.cfg.Brokers {
+		var conn *kafkaConn
+		if conn, err = p.connection(ctx, bootstrap, deadline); err != nil {
+			continue
+		}
+
+		var resp *kafkaDecoder
+		if resp, err = p.roundTrip(conn, kafkaApiMetadata, kafkaMetadataVersion, req.buf.Bytes(), deadline); err != nil {
+			continue
+		}
+		if err = p.parseMetadata(resp); err != nil {
+			return err
+		}
+
+		// the bootstrap connection is registered under the bootstrap address, use the advertised addresses
+		_ = conn.conn.Close()
+		delete(p.conns, bootstrap)
+		return nil
+	}
+
+	return fmt.Errorf("failed to load metadata: %w", err)
+}
+
+func (p *kafkaPublisher) parseMetadata(resp *kafkaDecoder) error {
+	_ = resp.int32() // throttle time
+	brokers := make(map[int32]string)
+	for range resp.int32() {
+		nodeId := resp.int32()
+		host := resp.string()
+		port := resp.int32()
+		_ = resp.nullableString() // rack
+		brokers[nodeId] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	_ = resp.nullableString() // cluster id
+	_ = resp.int32()          // controller id
+
+	var partitions []kafkaPartition
+	for range resp.int32() {
+		code := resp.int16()
+		name := resp.string()
+		_ = resp.bool() // is internal
+		if code != 0 && name == p.cfg.Topic {
+			return fmt.Errorf("metadata of topic %s: %s", name, kafkaErrorName(code))
+		}
+		for range resp.int32() {
+			_ = resp.int16() // partition error, the leader is checked instead
+			partition := kafkaPartition{id: resp.int32(), leader: resp.int32()}
+			_ = resp.int32Array() // replicas
+			_ = resp.int32Array() // in-sync replicas
+			if name == p.cfg.Topic {
+				partitions = append(partitions, partition)
+			}
+		}
+	}
+	if resp.err != nil {
+		return fmt.Errorf("invalid metadata response: %w", resp.err)
+	}
+	if len(partitions) == 0 {
+		return fmt.Errorf("topic %s has no partitions", p.cfg.Topic)
+	}
+
+	leaders := make([]int32, len(partitions))
+	for _, partition := range partitions {
+		if partition.id < 0 || int(partition.id) >= len(leaders) || partition.leader < 0 {
+			return fmt.Errorf("partition %d of topic %s has no leader", partition.id, p.cfg.Topic)
+		}
+		leaders[partition.id] = partition.leader
+	}
+
+	p.brokers = brokers
+	p.leaders = leaders
+	return nil
+}
+
+// connection returns an open connection to the given broker. New connections are authenticated if SASL is used.
+func (p *kafkaPublisher) connection(ctx context.Context, addr string, deadline time.Time) (*kafkaConn, error) {
+	if conn, ok := p.conns[addr]; ok {
+		return conn, nil
+	}
+
+	dialer := net.Dialer{Deadline: deadline}
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if p.cfg.Tls {
+		tlsConn := tls.Client(netConn, &tls.Config{ServerName: eventStreamHost(addr), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = netConn.Close()
+			return nil, fmt.Errorf("TLS handshake with %s failed: %w", addr, err)
+		}
+		netConn = tlsConn
+	}
+
+	conn := &kafkaConn{conn: netConn}
+	if p.cfg.Username != "" {
+		if err := p.authenticate(conn, deadline); err != nil {
+			_ = netConn.Close()
+			return nil, fmt.Errorf("SASL authentication with %s failed: %w", addr, err)
+		}
+	}
+
+	p.conns[addr] = conn
+	return conn, nil
+}
+
+// authenticate performs a SASL/PLAIN authentication.
+func (p *kafkaPublisher) authenticate(conn *kafkaConn, deadline time.Time) error {
+	var handshake kafkaEncoder
+	handshake.string("PLAIN")
+	resp, err := p.roundTrip(conn, kafkaApiSaslHandshake, kafkaSaslHandshakeVersion, handshake.buf.Bytes(), deadline)
+	if err != nil {
+		return err
+	}
+	if code := resp.int16(); code != 0 {
+		return errors.New(kafkaErrorName(code))
+	}
+
+	var auth kafkaEncoder
+	auth.bytes([]byte("\x00" + p.cfg.Username + "\x00" + p.cfg.Password))
+	resp, err = p.roundTrip(conn, kafkaApiSaslAuthenticate, kafkaSaslAuthVersion, auth.buf.Bytes(), deadline)
+	if err != nil {
+		return err
+	}
+	if code := resp.int16(); code != 0 {
+		msg := resp.nullableString()
+		return fmt.Errorf("%s: %s", kafkaErrorName(code), msg)
+	}
+
+	return resp.err
+}
+
+// roundTrip sends a request with the given body and returns the decoder for the response body.
+func (p *kafkaPublisher) roundTrip(
+	conn *kafkaConn,
+	apiKey, apiVersion int16,
+	body []byte,
+	deadline time.Time,
+) (*kafkaDecoder, error) {
+	p.correlationId++
+	correlationId := p.correlationId
+
+	var req kafkaEncoder
+	req.int32(0) // the size is set below
+	req.int16(apiKey)
+	req.int16(apiVersion)
+	req.int32(correlationId)
+	req.string(p.cfg.ClientId)
+	req.buf.Write(body)
+	msg := req.buf.Bytes()
+	binary.BigEndian.PutUint32(msg, uint32(len(msg)-4))
+
+	_ = conn.conn.SetDeadline(deadline)
+	if _, err := conn.conn.Write(msg); err != nil {
+		return nil, err
+	}
+
+	var size int32
+	if err := binary.Read(conn.conn, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size < 4 {
+		return nil, fmt.Errorf("invalid response size %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(conn.conn, data); err != nil {
+		return nil, err
+	}
+
+	resp := &kafkaDecoder{data: data}
+	if id := resp.int32(); id != correlationId {
+		return nil, fmt.Errorf("unexpected correlation id %d, expected %d", id, correlationId)
+	}
+
+	return resp, nil
+}
+
+// kafkaRecordBatch encodes a record batch (magic 2) with a single record.
+func kafkaRecordBatch(timestamp time.Time, key, value []byte, headers map[string]string) []byte {
+	var record kafkaEncoder
+	record.int8(0)   // attributes
+	record.varint(0) // timestamp delta
+	record.varint(0) // offset delta
+	record.varint(int64(len(key)))
+	record.buf.Write(key)
+	record.varint(int64(len(value)))
+	record.buf.Write(value)
+	record.varint(int64(len(headers)))
+	for name, value := range headers {
+		record.varint(int64(len(name)))
+		record.buf.WriteString(name)
+		record.varint(int64(len(value)))
+		record.buf.WriteString(value)
+	}
+
+	// the fields after the CRC, the CRC covers them
+	var batch kafkaEncoder
+	batch.int16(0) // attributes: no compression, create time
+	batch.int32(0) // last offset delta
+	batch.int64(timestamp.UnixMilli())
+	batch.int64(timestamp.UnixMilli())
+	batch.int64(-1) // producer id
+	batch.int16(-1) // producer epoch
+	batch.int32(-1) // base sequence
+	batch.int32(1)  // number of records
+	batch.varint(int64(record.buf.Len()))
+	batch.buf.Write(record.buf.Bytes())
+
+	var result kafkaEncoder
+	result.int64(0)                                  // base offset
+	result.int32(int32(batch.buf.Len() + 4 + 1 + 4)) // batch length: leader epoch, magic, crc and the rest
+	result.int32(-1)                                 // partition leader epoch
+	result.int8(2)                                   // magic
+	result.int32(int32(crc32.Checksum(batch.buf.Bytes(), kafkaCrcTable)))
+	result.buf.Write(batch.buf.Bytes())
+
+	return result.buf.Bytes()
+}
+
+// kafkaMurmur2 is the hash function of the default partitioner of the Kafka Java client.
+func kafkaMurmur2(data []byte) int32 {
+	const seed uint32 = 0x9747b28c
+	const m uint32 = 0x5bd1e995
+	const r = 24
+
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+
+	return int32(h)
+}
+
+func kafkaErrorName(code int16) string {
+	if name, ok := kafkaErrorNames[code]; ok {
+		return name
+	}
+	return "error code " + strconv.Itoa(int(code))
+}
+
+// kafkaEncoder writes the primitive types of the Kafka protocol.
+type kafkaEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *kafkaEncoder) int8(v int8) {
+	e.buf.WriteByte(byte(v))
+}
+
+func (e *kafkaEncoder) int16(v int16) {
+	e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(v)))
+}
+
+func (e *kafkaEncoder) int32(v int32) {
+	e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(v)))
+}
+
+func (e *kafkaEncoder) int64(v int64) {
+	e.buf.Write(binary.BigEndian.AppendUint64(nil, uint64(v)))
+}
+
+func (e *kafkaEncoder) bool(v bool) {
+	if v {
+		e.int8(1)
+	} else {
+		e.int8(0)
+	}
+}
+
+func (e *kafkaEncoder) string(v string) {
+	e.int16(int16(len(v)))
+	e.buf.WriteString(v)
+}
+
+func (e *kafkaEncoder) bytes(v []byte) {
+	e.int32(int32(len(v)))
+	e.buf.Write(v)
+}
+
+// varint writes a zig-zag encoded variable length integer.
+func (e *kafkaEncoder) varint(v int64) {
+	e.buf.Write(binary.AppendVarint(nil, v))
+}
+
+// kafkaDecoder reads the primitive types of the Kafka protocol. After the first error, all reads return zero values
+// and the error is kept.
+type kafkaDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *kafkaDecoder) read(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.data) < n {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	v := d.data[:n]
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *kafkaDecoder) int8() int8 {
+	if v := d.read(1); v != nil {
+		return int8(v[0])
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if v := d.read(2); v != nil {
+		return int16(binary.BigEndian.Uint16(v))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	if v := d.read(4); v != nil {
+		return int32(binary.BigEndian.Uint32(v))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if v := d.read(8); v != nil {
+		return int64(binary.BigEndian.Uint64(v))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) bool() bool {
+	return d.int8() != 0
+}
+
+func (d *kafkaDecoder) string() string {
+	return string(d.read(int(d.int16())))
+}
+
+func (d *kafkaDecoder) nullableString() string {
+	length := d.int16()
+	if length < 0 {
+		return ""
+	}
+	return string(d.read(int(length)))
+}
+
+func (d *kafkaDecoder) bytes() []byte {
+	length := d.int32()
+	if length < 0 {
+		return nil
+	}
+	return d.read(int(length))
+}
+
+func (d *kafkaDecoder) int32Array() []int32 {
+	length := d.int32()
+	if length < 0 || d.err != nil {
+		return nil
+	}
+	values := make([]int32, 0, min(int(length), len(d.data)/4))
+	for range length {
+		values = append(values, d.int32())
+	}
+	return values
+}
+
+func (d *kafkaDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
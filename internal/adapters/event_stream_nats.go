@@ -0,0 +1,201 @@
+package adapters
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// natsPublisher publishes events using the NATS client protocol. Each event is followed by a PING, so that the
+// event is only reported as published after the server processed it.
+type natsPublisher struct {
+	cfg  config.NatsConfig
+	addr string
+	tls  bool
+
+	mux    sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newNatsPublisher(cfg config.NatsConfig) (*natsPublisher, error) {
+	if cfg.Url == "" {
+		return nil, errors.New("missing NATS url")
+	}
+
+	serverUrl, err := url.Parse(cfg.Url)
+	if err != nil || serverUrl.Host == "" {
+		return nil, fmt.Errorf("invalid NATS url %s", cfg.Url)
+	}
+	addr := serverUrl.Host
+	if serverUrl.Port() == "" {
+		addr = net.JoinHostPort(serverUrl.Hostname(), "4222")
+	}
+	if serverUrl.User != nil && cfg.Username == "" && cfg.Token == "" {
+		cfg.Username = serverUrl.User.Username()
+		cfg.Password, _ = serverUrl.User.Password()
+	}
+
+	return &natsPublisher{
+		cfg:  cfg,
+		addr: addr,
+		tls:  cfg.Tls || serverUrl.Scheme == "tls",
+	}, nil
+}
+
+func (p *natsPublisher) publish(ctx context.Context, event domain.StreamEvent, data []byte) error {
+	subject := event.Name
+	if p.cfg.SubjectPrefix != "" {
+		subject = p.cfg.SubjectPrefix + "." + event.Name
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	var err error
+	for range 2 { // the server might have closed an idle connection, so try again with a new connection
+		deadline := eventStreamDeadline(ctx, p.cfg.Timeout)
+		if p.conn == nil {
+			if err = p.connect(ctx, deadline); err != nil {
+				continue
+			}
+		}
+
+		if err = p.send(subject, data, deadline); err == nil {
+			return nil
+		}
+		p.closeConnection()
+	}
+
+	return fmt.Errorf("failed to publish NATS message: %w", err)
+}
+
+func (p *natsPublisher) close() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.closeConnection()
+	return nil
+}
+
+func (p *natsPublisher) closeConnection() {
+	if p.conn != nil {
+		_ = p.conn.Close()
+	}
+	p.conn = nil
+	p.reader = nil
+}
+
+func (p *natsPublisher) connect(ctx context.Context, deadline time.Time) error {
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(deadline)
+	reader := bufio.NewReader(conn)
+
+	line, err := natsReadLine(reader)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		_ = conn.Close()
+		return fmt.Errorf("unexpected NATS greeting: %s", line)
+	}
+	var info struct {
+		TlsRequired bool `json:"tls_required"`
+	}
+	_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info)
+
+	if p.tls || info.TlsRequired {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: eventStreamHost(p.addr), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return fmt.Errorf("NATS TLS handshake failed: %w", err)
+		}
+		conn = tlsConn
+		reader = bufio.NewReader(conn)
+	}
+
+	connect, _ := json.Marshal(map[string]any{
+		"verbose":    false,
+		"pedantic":   false,
+		"name":       "wg-portal",
+		"lang":       "go",
+		"protocol":   1,
+		"user":       p.cfg.Username,
+		"pass":       p.cfg.Password,
+		"auth_token": p.cfg.Token,
+	})
+	p.conn = conn
+	p.reader = reader
+	if _, err := conn.Write([]byte("CONNECT " + string(connect) + "\r\nPING\r\n")); err != nil {
+		p.closeConnection()
+		return err
+	}
+	if err := p.waitForPong(); err != nil {
+		p.closeConnection()
+		return err
+	}
+
+	return nil
+}
+
+func (p *natsPublisher) send(subject string, data []byte, deadline time.Time) error {
+	_ = p.conn.SetDeadline(deadline)
+
+	msg := make([]byte, 0, len(subject)+len(data)+32)
+	msg = append(msg, "PUB "+subject+" "+strconv.Itoa(len(data))+"\r\n"...)
+	msg = append(msg, data...)
+	msg = append(msg, "\r\nPING\r\n"...)
+	if _, err := p.conn.Write(msg); err != nil {
+		return err
+	}
+
+	return p.waitForPong()
+}
+
+// waitForPong reads the server messages until the PONG of the last PING is received.
+func (p *natsPublisher) waitForPong() error {
+	for {
+		line, err := natsReadLine(p.reader)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		default:
+			// +OK and INFO updates are ignored
+		}
+	}
+}
+
+func natsReadLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,321 @@
+package adapters
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestEventStreamRepo_disabled(t *testing.T) {
+	repo, err := NewEventStreamRepository(config.EventStreamConfig{Enabled: false})
+	require.NoError(t, err)
+
+	assert.Error(t, repo.PublishEvent(context.Background(), domain.StreamEvent{Name: "peer.created"}))
+	assert.NoError(t, repo.Close())
+}
+
+func TestEventStreamRepo_nats(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	type natsMessage struct {
+		connect string
+		subject string
+		payload string
+	}
+	messages := make(chan natsMessage, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		_, _ = conn.Write([]byte(`INFO {"server_id":"test","max_payload":1048576}` + "\r\n"))
+
+		var msg natsMessage
+		for {
+			line, err := natsReadLine(reader)
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "CONNECT "):
+				msg.connect = strings.TrimPrefix(line, "CONNECT ")
+			case line == "PING":
+				_, _ = conn.Write([]byte("PONG\r\n"))
+				if msg.subject != "" {
+					messages <- msg
+					msg.subject = ""
+				}
+			case strings.HasPrefix(line, "PUB "):
+				fields := strings.Fields(line)
+				size, _ := strconv.Atoi(fields[2])
+				payload := make([]byte, size+2)
+				_, _ = io.ReadFull(reader, payload)
+				msg.subject = fields[1]
+				msg.payload = string(payload[:size])
+			}
+		}
+	}()
+
+	repo, err := NewEventStreamRepository(config.EventStreamConfig{
+		Enabled:   true,
+		Publisher: config.EventStreamPublisherNats,
+		Nats: config.NatsConfig{
+			Url:           "nats://" + listener.Addr().String(),
+			SubjectPrefix: "wg-portal",
+			Token:         "secret",
+			Timeout:       5 * time.Second,
+		},
+	})
+	require.NoError(t, err)
+	defer repo.Close()
+
+	err = repo.PublishEvent(context.Background(), domain.StreamEvent{
+		Name:       "peer.created",
+		Time:       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Entity:     "peer",
+		Identifier: "xTIBA5rbo=",
+		Payload:    map[string]string{"interface": "wg0"},
+	})
+	require.NoError(t, err)
+
+	select {
+	case msg := <-messages:
+		assert.Contains(t, msg.connect, `"auth_token":"secret"`)
+		assert.Equal(t, "wg-portal.peer.created", msg.subject)
+		assert.JSONEq(t, `{"event":"peer.created","time":"2024-01-02T03:04:05Z","entity":"peer",`+
+			`"identifier":"xTIBA5rbo=","payload":{"interface":"wg0"}}`, msg.payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+func TestEventStreamRepo_natsServerError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("INFO {}\r\n-ERR 'Authorization Violation'\r\n"))
+			_ = conn.Close()
+		}
+	}()
+
+	repo, err := NewEventStreamRepository(config.EventStreamConfig{
+		Enabled:   true,
+		Publisher: config.EventStreamPublisherNats,
+		Nats:      config.NatsConfig{Url: "nats://" + listener.Addr().String(), Timeout: 5 * time.Second},
+	})
+	require.NoError(t, err)
+	defer repo.Close()
+
+	err = repo.PublishEvent(context.Background(), domain.StreamEvent{Name: "peer.created"})
+	assert.ErrorContains(t, err, "Authorization Violation")
+}
+
+type kafkaTestRecord struct {
+	topic     string
+	partition int32
+	key       string
+	value     string
+	headers   map[string]string
+}
+
+// runKafkaTestBroker starts a minimal Kafka broker with a single topic that answers metadata and produce requests.
+func runKafkaTestBroker(t *testing.T, topic string, partitions int32) (string, chan kafkaTestRecord) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	addr := listener.Addr().(*net.TCPAddr)
+
+	records := make(chan kafkaTestRecord, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					var size int32
+					if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+						return
+					}
+					data := make([]byte, size)
+					if _, err := io.ReadFull(conn, data); err != nil {
+						return
+					}
+
+					req := &kafkaDecoder{data: data}
+					apiKey := req.int16()
+					_ = req.int16() // api version
+					correlationId := req.int32()
+					_ = req.nullableString() // client id
+
+					var resp kafkaEncoder
+					resp.int32(correlationId)
+					switch apiKey {
+					case kafkaApiMetadata:
+						resp.int32(0) // throttle time
+						resp.int32(1)
+						resp.int32(1) // node id
+						resp.string(addr.IP.String())
+						resp.int32(int32(addr.Port))
+						resp.int16(-1) // rack
+						resp.int16(-1) // cluster id
+						resp.int32(1)  // controller id
+						resp.int32(1)
+						resp.int16(0)
+						resp.string(topic)
+						resp.bool(false)
+						resp.int32(partitions)
+						for i := range partitions {
+							resp.int16(0)
+							resp.int32(i)
+							resp.int32(1) // leader
+							resp.int32(1) // replicas
+							resp.int32(1) // replica
+							resp.int32(1) // in-sync replicas
+							resp.int32(1) // in-sync replica
+						}
+					case kafkaApiProduce:
+						record := decodeKafkaTestProduce(t, req)
+						resp.int32(1)
+						resp.string(record.topic)
+						resp.int32(1)
+						resp.int32(record.partition)
+						resp.int16(0)
+						resp.int64(0)
+						resp.int64(-1)
+						resp.int32(0) // throttle time
+						records <- record
+					default:
+						return
+					}
+
+					msg := binary.BigEndian.AppendUint32(nil, uint32(resp.buf.Len()))
+					if _, err := conn.Write(append(msg, resp.buf.Bytes()...)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return addr.String(), records
+}
+
+func decodeKafkaTestProduce(t *testing.T, req *kafkaDecoder) kafkaTestRecord {
+	var record kafkaTestRecord
+	_ = req.nullableString() // transactional id
+	assert.Equal(t, int16(-1), req.int16())
+	_ = req.int32() // timeout
+	assert.Equal(t, int32(1), req.int32())
+	record.topic = req.string()
+	assert.Equal(t, int32(1), req.int32())
+	record.partition = req.int32()
+
+	batch := &kafkaDecoder{data: req.bytes()}
+	_ = batch.int64() // base offset
+	remaining := len(batch.data) - 4
+	assert.Equal(t, int32(remaining), batch.int32())
+	_ = batch.int32() // leader epoch
+	assert.Equal(t, int8(2), batch.int8())
+	crc := uint32(batch.int32())
+	assert.Equal(t, crc32.Checksum(batch.data, crc32.MakeTable(crc32.Castagnoli)), crc)
+	_ = batch.read(2 + 4 + 8 + 8 + 8 + 2 + 4) // attributes to base sequence
+	assert.Equal(t, int32(1), batch.int32())
+
+	recordData := &kafkaDecoder{data: batch.read(int(batch.varint()))}
+	_ = recordData.int8()   // attributes
+	_ = recordData.varint() // timestamp delta
+	_ = recordData.varint() // offset delta
+	record.key = string(recordData.read(int(recordData.varint())))
+	record.value = string(recordData.read(int(recordData.varint())))
+	record.headers = make(map[string]string)
+	for range recordData.varint() {
+		name := string(recordData.read(int(recordData.varint())))
+		record.headers[name] = string(recordData.read(int(recordData.varint())))
+	}
+	require.NoError(t, req.err)
+	require.NoError(t, batch.err)
+	require.NoError(t, recordData.err)
+
+	return record
+}
+
+func TestEventStreamRepo_kafka(t *testing.T) {
+	addr, records := runKafkaTestBroker(t, "wg-events", 4)
+
+	repo, err := NewEventStreamRepository(config.EventStreamConfig{
+		Enabled:   true,
+		Publisher: config.EventStreamPublisherKafka,
+		Kafka: config.KafkaConfig{
+			Brokers:  []string{addr},
+			Topic:    "wg-events",
+			ClientId: "wg-portal",
+			Timeout:  5 * time.Second,
+		},
+	})
+	require.NoError(t, err)
+	defer repo.Close()
+
+	for range 2 { // the second event reuses the connection and metadata
+		err = repo.PublishEvent(context.Background(), domain.StreamEvent{
+			Name:       "peer.deleted",
+			Time:       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Entity:     "peer",
+			Identifier: "foobar",
+		})
+		require.NoError(t, err)
+
+		select {
+		case record := <-records:
+			assert.Equal(t, "wg-events", record.topic)
+			assert.Equal(t, int32(2), record.partition) // murmur2("foobar") & 0x7fffffff % 4
+			assert.Equal(t, "foobar", record.key)
+			assert.Equal(t, map[string]string{"event": "peer.deleted"}, record.headers)
+			assert.JSONEq(t, `{"event":"peer.deleted","time":"2024-01-02T03:04:05Z","entity":"peer",`+
+				`"identifier":"foobar","payload":null}`, record.value)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no record received")
+		}
+	}
+}
+
+func TestKafkaMurmur2(t *testing.T) {
+	// test vectors of the Kafka Java client
+	tests := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"abc":                        479470107,
+	}
+
+	for input, expected := range tests {
+		assert.Equal(t, expected, kafkaMurmur2([]byte(input)), input)
+	}
+}
@@ -0,0 +1,80 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// FailoverRepo checks the health of the other gateway of a failover pair and executes the failover hook.
+type FailoverRepo struct {
+	client   *http.Client
+	shellCmd string
+}
+
+// NewFailoverRepo creates a new FailoverRepo instance.
+func NewFailoverRepo(cfg config.FailoverConfig) *FailoverRepo {
+	return &FailoverRepo{
+		client:   &http.Client{Timeout: cfg.CheckTimeout},
+		shellCmd: "bash",
+	}
+}
+
+// CheckHealth fetches the health report of the gateway with the given health url. An error is returned if the
+// gateway is unreachable or reports itself as unhealthy.
+func (r *FailoverRepo) CheckHealth(ctx context.Context, healthUrl string) (*domain.FailoverHealth, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create health request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("health request failed: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		_, _ = io.Copy(io.Discard, Body)
+		_ = Body.Close()
+	}(resp.Body)
+
+	var health domain.FailoverHealth
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&health); err != nil {
+		return nil, fmt.Errorf("invalid health response with status %s: %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK || !health.Healthy {
+		return &health, fmt.Errorf("gateway %s is unhealthy, status: %s", health.Node, resp.Status)
+	}
+
+	return &health, nil
+}
+
+// ExecuteHook executes the given shell command with the failover state in the environment.
+func (r *FailoverRepo) ExecuteHook(ctx context.Context, hook string, state domain.FailoverState, node string) error {
+	if hook == "" {
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, r.shellCmd, "-ce", hook)
+	cmd.Env = append(os.Environ(),
+		"WG_PORTAL_FAILOVER_STATE="+string(state),
+		"WG_PORTAL_FAILOVER_NODE="+node,
+	)
+
+	out, err := cmd.CombinedOutput() // execute and wait for output
+	if err != nil {
+		return fmt.Errorf("failed to execute failover hook: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	slog.Debug("executed failover hook", "state", state, "output", string(out))
+
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"sync"
 	"time"
 
 	mail "github.com/xhit/go-simple-mail/v2"
@@ -16,12 +18,41 @@ import (
 )
 
 type MailRepo struct {
-	cfg *config.MailConfig
+	state *mailState
+}
+
+// mailState holds the SMTP settings, they are replaced if the password is rotated or the config is reloaded.
+type mailState struct {
+	mux sync.RWMutex
+	cfg config.MailConfig
 }
 
 // NewSmtpMailRepo creates a new MailRepo instance.
 func NewSmtpMailRepo(cfg config.MailConfig) MailRepo {
-	return MailRepo{cfg: &cfg}
+	return MailRepo{state: &mailState{cfg: cfg}}
+}
+
+// SetPassword replaces the SMTP password that is used for new connections.
+func (r MailRepo) SetPassword(password string) {
+	r.state.mux.Lock()
+	defer r.state.mux.Unlock()
+
+	r.state.cfg.Password = password
+}
+
+// SetConfig replaces the SMTP settings that are used for new connections.
+func (r MailRepo) SetConfig(cfg config.MailConfig) {
+	r.state.mux.Lock()
+	defer r.state.mux.Unlock()
+
+	r.state.cfg = cfg
+}
+
+func (r MailRepo) config() config.MailConfig {
+	r.state.mux.RLock()
+	defer r.state.mux.RUnlock()
+
+	return r.state.cfg
 }
 
 // Send sends a mail using SMTP.
@@ -29,7 +60,8 @@ func (r MailRepo) Send(_ context.Context, subject, body string, to []string, opt
 	if options == nil {
 		options = &domain.MailOptions{}
 	}
-	r.setDefaultOptions(r.cfg.From, options)
+	cfg := r.config()
+	r.setDefaultOptions(cfg.From, options)
 
 	if len(to) == 0 {
 		return errors.New("missing email recipient")
@@ -37,7 +69,7 @@ func (r MailRepo) Send(_ context.Context, subject, body string, to []string, opt
 
 	uniqueTo := internal.UniqueStringSlice(to)
 	email := mail.NewMSG()
-	email.SetFrom(r.cfg.From).
+	email.SetFrom(cfg.From).
 		AddTo(uniqueTo...).
 		SetReplyTo(options.ReplyTo).
 		SetSubject(subject).
@@ -55,7 +87,7 @@ func (r MailRepo) Send(_ context.Context, subject, body string, to []string, opt
 		bcc := RemoveDuplicates(internal.UniqueStringSlice(options.Bcc), uniqueTo)
 		bcc = RemoveDuplicates(bcc, options.Cc)
 
-		email.AddCc(internal.UniqueStringSlice(options.Bcc)...)
+		email.AddBcc(bcc...)
 	}
 	if options.HtmlBody != "" {
 		email.AddAlternative(mail.TextHTML, options.HtmlBody)
@@ -75,7 +107,7 @@ func (r MailRepo) Send(_ context.Context, subject, body string, to []string, opt
 	}
 
 	// Call Send and pass the client
-	srv := r.getMailServer()
+	srv := r.getMailServer(cfg)
 	client, err := srv.Connect()
 	if err != nil {
 		return fmt.Errorf("failed to connect to SMTP server: %w", err)
@@ -95,17 +127,17 @@ func (r MailRepo) setDefaultOptions(sender string, options *domain.MailOptions)
 	}
 }
 
-func (r MailRepo) getMailServer() *mail.SMTPServer {
+func (r MailRepo) getMailServer(cfg config.MailConfig) *mail.SMTPServer {
 	srv := mail.NewSMTPClient()
 
 	srv.ConnectTimeout = 30 * time.Second
 	srv.SendTimeout = 30 * time.Second
-	srv.Host = r.cfg.Host
-	srv.Port = r.cfg.Port
-	srv.Username = r.cfg.Username
-	srv.Password = r.cfg.Password
+	srv.Host = cfg.Host
+	srv.Port = cfg.Port
+	srv.Username = cfg.Username
+	srv.Password = cfg.Password
 
-	switch r.cfg.Encryption {
+	switch cfg.Encryption {
 	case config.MailEncryptionTLS:
 		srv.Encryption = mail.EncryptionSSLTLS
 	case config.MailEncryptionStartTLS:
@@ -113,8 +145,8 @@ func (r MailRepo) getMailServer() *mail.SMTPServer {
 	default: // MailEncryptionNone
 		srv.Encryption = mail.EncryptionNone
 	}
-	srv.TLSConfig = &tls.Config{ServerName: srv.Host, InsecureSkipVerify: !r.cfg.CertValidation}
-	switch r.cfg.AuthType {
+	srv.TLSConfig = &tls.Config{ServerName: srv.Host, InsecureSkipVerify: !cfg.CertValidation}
+	switch cfg.AuthType {
 	case config.MailAuthPlain:
 		srv.Authentication = mail.AuthPlain
 	case config.MailAuthLogin:
@@ -130,11 +162,9 @@ func (r MailRepo) getMailServer() *mail.SMTPServer {
 func RemoveDuplicates(slice []string, remove []string) []string {
 	uniqueSlice := make([]string, 0, len(slice))
 
-	for _, i := range remove {
-		for _, j := range slice {
-			if i != j {
-				uniqueSlice = append(uniqueSlice, j)
-			}
+	for _, s := range slice {
+		if !slices.Contains(remove, s) {
+			uniqueSlice = append(uniqueSlice, s)
 		}
 	}
 	return uniqueSlice
@@ -25,12 +25,25 @@ type MetricsServer struct {
 	peerLastHandshakeSeconds *prometheus.GaugeVec
 	peerReceivedBytesTotal   *prometheus.GaugeVec
 	peerSendBytesTotal       *prometheus.GaugeVec
+	peerInfo                 *prometheus.GaugeVec
+
+	peerSubnetReceivedBytesTotal *prometheus.GaugeVec
+	peerSubnetSendBytesTotal     *prometheus.GaugeVec
+
+	poolSize                *prometheus.GaugeVec
+	poolUsed                *prometheus.GaugeVec
+	poolExhaustionTimestamp *prometheus.GaugeVec
 }
 
 // Wireguard metrics labels
 var (
 	ifaceLabels = []string{"interface"}
 	peerLabels  = []string{"interface", "addresses", "id", "name"}
+	// subnetLabels are the labels of the per-subnet traffic of a peer
+	subnetLabels = append(append([]string{}, peerLabels...), "subnet")
+	// peerInfoLabels are the labels of the peer info metric, which can be joined with the other peer metrics
+	peerInfoLabels = append(append([]string{}, peerLabels...), "device_type")
+	poolLabels     = []string{"interface", "network"}
 )
 
 // NewMetricsServer returns a new prometheus server
@@ -83,6 +96,44 @@ func NewMetricsServer(cfg *config.Config) *MetricsServer {
 				Help: "Bytes sent to the peer.",
 			}, peerLabels,
 		),
+		peerInfo: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "wireguard_peer_info",
+				Help: "Peer metadata, the value is always 1.",
+			}, peerInfoLabels,
+		),
+
+		peerSubnetReceivedBytesTotal: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "wireguard_peer_subnet_received_bytes_total",
+				Help: "Bytes received from the peer that were sent to the subnet.",
+			}, subnetLabels,
+		),
+		peerSubnetSendBytesTotal: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "wireguard_peer_subnet_sent_bytes_total",
+				Help: "Bytes sent to the peer that were received from the subnet.",
+			}, subnetLabels,
+		),
+
+		poolSize: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "wireguard_address_pool_size",
+				Help: "Number of assignable addresses in the peer network.",
+			}, poolLabels,
+		),
+		poolUsed: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "wireguard_address_pool_used",
+				Help: "Number of assigned addresses in the peer network.",
+			}, poolLabels,
+		),
+		poolExhaustionTimestamp: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "wireguard_address_pool_exhaustion_timestamp_seconds",
+				Help: "Projected exhaustion of the peer network as unix timestamp, 0 if the pool does not grow.",
+			}, poolLabels,
+		),
 	}
 }
 
@@ -134,4 +185,34 @@ func (m *MetricsServer) UpdatePeerMetrics(peer *domain.Peer, status domain.PeerS
 	m.peerReceivedBytesTotal.WithLabelValues(labels...).Set(float64(status.BytesReceived))
 	m.peerSendBytesTotal.WithLabelValues(labels...).Set(float64(status.BytesTransmitted))
 	m.peerIsConnected.WithLabelValues(labels...).Set(internal.BoolToFloat64(status.IsConnected()))
+	m.peerInfo.WithLabelValues(append(labels, string(peer.DeviceType))...).Set(1)
+}
+
+// UpdatePeerTrafficMetrics updates the per-subnet traffic metrics for the given peer
+func (m *MetricsServer) UpdatePeerTrafficMetrics(peer *domain.Peer, traffic domain.PeerTraffic) {
+	for _, subnet := range traffic.Subnets {
+		labels := []string{
+			string(peer.InterfaceIdentifier),
+			peer.Interface.AddressStr(),
+			string(peer.Identifier),
+			peer.DisplayName,
+			subnet.Subnet,
+		}
+
+		m.peerSubnetReceivedBytesTotal.WithLabelValues(labels...).Set(float64(subnet.BytesReceived))
+		m.peerSubnetSendBytesTotal.WithLabelValues(labels...).Set(float64(subnet.BytesTransmitted))
+	}
+}
+
+// UpdateAddressPoolMetrics updates the metrics for the given address pool
+func (m *MetricsServer) UpdateAddressPoolMetrics(pool domain.AddressPoolCapacity) {
+	labels := []string{string(pool.InterfaceId), pool.Network.String()}
+
+	exhaustionTimestamp := float64(0)
+	if pool.ExhaustedAt != nil {
+		exhaustionTimestamp = float64(pool.ExhaustedAt.Unix())
+	}
+	m.poolSize.WithLabelValues(labels...).Set(float64(pool.Size))
+	m.poolUsed.WithLabelValues(labels...).Set(float64(pool.Used))
+	m.poolExhaustionTimestamp.WithLabelValues(labels...).Set(exhaustionTimestamp)
 }
@@ -0,0 +1,36 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+)
+
+// NftablesRepo applies nftables rulesets using the nft command line tool.
+type NftablesRepo struct {
+	nftCmd string
+}
+
+// NewNftablesRepo creates a new NftablesRepo instance.
+func NewNftablesRepo() *NftablesRepo {
+	return &NftablesRepo{
+		nftCmd: "nft",
+	}
+}
+
+// ApplyRuleset applies the given nftables script. All commands of the script are applied in a single transaction.
+func (r *NftablesRepo) ApplyRuleset(ctx context.Context, ruleset string) error {
+	cmd := exec.CommandContext(ctx, r.nftCmd, "-f", "-")
+	cmd.Stdin = strings.NewReader(ruleset)
+
+	out, err := cmd.CombinedOutput() // execute and wait for output
+	if err != nil {
+		return fmt.Errorf("failed to apply nftables ruleset (is nft available?): %w: %s", err,
+			strings.TrimSpace(string(out)))
+	}
+	slog.Debug("applied nftables ruleset", "ruleset", ruleset)
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// PacketCaptureRepo captures packets on WireGuard interfaces using the tcpdump command line tool.
+type PacketCaptureRepo struct {
+	tcpdumpCmd string
+}
+
+// NewPacketCaptureRepo creates a new PacketCaptureRepo instance.
+func NewPacketCaptureRepo(tcpdumpCmd string) *PacketCaptureRepo {
+	return &PacketCaptureRepo{
+		tcpdumpCmd: tcpdumpCmd,
+	}
+}
+
+// Capture streams the packets of the given capture in pcap format to the writer. The capture runs until the context
+// is done or the maximum size of the capture is reached, in the latter case the last packet may be truncated.
+// It returns the number of bytes that were written.
+func (r *PacketCaptureRepo) Capture(ctx context.Context, capture *domain.PacketCapture, w io.Writer) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	args := []string{"-i", string(capture.InterfaceId), "-n", "-U", "-w", "-"}
+	if capture.SnapLength > 0 {
+		args = append(args, "-s", strconv.Itoa(capture.SnapLength))
+	}
+	args = append(args, capture.Filter())
+
+	cmd := exec.CommandContext(ctx, r.tcpdumpCmd, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt) // tcpdump flushes the capture file on interrupts
+	}
+	cmd.WaitDelay = 5 * time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open tcpdump output: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start packet capture (is tcpdump available?): %w", err)
+	}
+	slog.Debug("started packet capture", "interface", capture.InterfaceId, "args", args)
+
+	var reader io.Reader = stdout
+	if capture.MaxSize > 0 {
+		reader = io.LimitReader(stdout, capture.MaxSize)
+	}
+	written, copyErr := io.Copy(w, reader)
+
+	cancel()                           // stop tcpdump if the size limit was reached or the client is gone
+	_, _ = io.Copy(io.Discard, stdout) // drain the remaining output, so that tcpdump can exit
+	waitErr := cmd.Wait()
+
+	if waitErr != nil && !errors.Is(waitErr, context.Canceled) && !errors.Is(waitErr, context.DeadlineExceeded) {
+		return written, fmt.Errorf("packet capture failed: %w: %s", waitErr, strings.TrimSpace(stderr.String()))
+	}
+	if copyErr != nil {
+		return written, fmt.Errorf("failed to write packet capture: %w", copyErr)
+	}
+
+	return written, nil
+}
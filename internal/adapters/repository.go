@@ -0,0 +1,389 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// Repository is the storage backend of WireGuard Portal. It is implemented by the SQL repository (SqlRepo) and
+// the key-value repository (KvRepo). The application services only depend on the subsets they need.
+type Repository interface {
+	// region interfaces
+
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
+	GetPeersStats(ctx context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error)
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	GetInterfaceStats(ctx context.Context, id domain.InterfaceIdentifier) (*domain.InterfaceStatus, error)
+	FindInterfaces(ctx context.Context, search string) ([]domain.Interface, error)
+	SaveInterface(
+		ctx context.Context,
+		id domain.InterfaceIdentifier,
+		updateFunc func(in *domain.Interface) (*domain.Interface, error),
+	) error
+	DeleteInterface(ctx context.Context, id domain.InterfaceIdentifier) error
+	GetInterfaceIps(ctx context.Context) (map[domain.InterfaceIdentifier][]domain.Cidr, error)
+
+	// endregion interfaces
+
+	// region peers
+
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	FindInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier, search string) ([]domain.Peer, error)
+	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
+	GetSharedUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
+	FindUserPeers(ctx context.Context, id domain.UserIdentifier, search string) ([]domain.Peer, error)
+	SavePeer(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		updateFunc func(in *domain.Peer) (*domain.Peer, error),
+	) error
+	DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
+	GetPeerCount(ctx context.Context) (int, error)
+	GetPeerIps(ctx context.Context) (map[domain.PeerIdentifier][]domain.Cidr, error)
+	GetUsedIpsPerSubnet(ctx context.Context, subnets []domain.Cidr) (map[domain.Cidr][]domain.Cidr, error)
+
+	// endregion peers
+
+	// region users
+
+	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	GetUserByWebAuthnCredential(ctx context.Context, credentialIdBase64 string) (*domain.User, error)
+	GetAllUsers(ctx context.Context) ([]domain.User, error)
+	FindUsers(ctx context.Context, search string) ([]domain.User, error)
+	SaveUser(
+		ctx context.Context,
+		id domain.UserIdentifier,
+		updateFunc func(u *domain.User) (*domain.User, error),
+	) error
+	DeleteUser(ctx context.Context, id domain.UserIdentifier) error
+
+	// endregion users
+
+	// region statistics
+
+	UpdateInterfaceStatus(
+		ctx context.Context,
+		id domain.InterfaceIdentifier,
+		updateFunc func(in *domain.InterfaceStatus) (*domain.InterfaceStatus, error),
+	) error
+	UpdatePeerStatus(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		updateFunc func(in *domain.PeerStatus) (*domain.PeerStatus, error),
+	) error
+	DeletePeerStatus(ctx context.Context, id domain.PeerIdentifier) error
+	SavePeerStatsSamples(ctx context.Context, samples []domain.PeerStatsSample) error
+	GetPeerStatsSamples(
+		ctx context.Context,
+		resolution domain.PeerStatsResolution,
+		from, to time.Time,
+		ids ...domain.PeerIdentifier,
+	) ([]domain.PeerStatsSample, error)
+	DeletePeerStatsSamples(ctx context.Context, resolution domain.PeerStatsResolution, before time.Time) error
+	SavePeerConnectionEvents(ctx context.Context, events []domain.PeerConnectionEvent) error
+	GetPeerConnectionEvents(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		from, to time.Time,
+	) ([]domain.PeerConnectionEvent, error)
+	AnonymizePeerConnectionEvents(ctx context.Context, id domain.PeerIdentifier) error
+	DeletePeerConnectionEvents(ctx context.Context, before time.Time) error
+
+	// endregion statistics
+
+	// region audit
+
+	SaveAuditEntry(ctx context.Context, entry *domain.AuditEntry) error
+	GetAllAuditEntries(ctx context.Context) ([]domain.AuditEntry, error)
+	DeleteAuditEntries(ctx context.Context, before time.Time) error
+	AnonymizeAuditEntries(ctx context.Context, values []string) error
+
+	// endregion audit
+
+	// region mail-log
+
+	SaveMailLogEntry(ctx context.Context, entry *domain.MailLogEntry) error
+	FindMailLogEntries(ctx context.Context, filter domain.MailLogFilter) ([]domain.MailLogEntry, error)
+	GetMailLogEntryByTrackingToken(ctx context.Context, token string) (*domain.MailLogEntry, error)
+	DeleteMailLogEntries(ctx context.Context, before time.Time) error
+	DeleteUserMailLogEntries(ctx context.Context, id domain.UserIdentifier) error
+
+	// endregion mail-log
+
+	// region peer-cleanup
+
+	GetPeerCleanupNotices(ctx context.Context) ([]domain.PeerCleanupNotice, error)
+	SavePeerCleanupNotice(ctx context.Context, notice *domain.PeerCleanupNotice) error
+	DeletePeerCleanupNotice(ctx context.Context, id domain.PeerIdentifier) error
+
+	// endregion peer-cleanup
+
+	// region peer-expiry
+
+	GetPeerExpiryReminders(ctx context.Context) ([]domain.PeerExpiryReminder, error)
+	SavePeerExpiryReminder(ctx context.Context, reminder *domain.PeerExpiryReminder) error
+	DeletePeerExpiryReminder(ctx context.Context, id domain.PeerIdentifier) error
+
+	// endregion peer-expiry
+
+	// region peer-setup-reminders
+
+	GetPeerSetupReminders(ctx context.Context) ([]domain.PeerSetupReminder, error)
+	SavePeerSetupReminder(ctx context.Context, reminder *domain.PeerSetupReminder) error
+	DeletePeerSetupReminder(ctx context.Context, id domain.PeerIdentifier) error
+
+	// endregion peer-setup-reminders
+
+	// region peer-config-versions
+
+	GetPeerConfigVersions(ctx context.Context, id domain.PeerIdentifier) ([]domain.PeerConfigVersion, error)
+	SavePeerConfigVersion(ctx context.Context, version *domain.PeerConfigVersion) error
+	DeletePeerConfigVersion(ctx context.Context, id domain.PeerIdentifier, version int) error
+
+	// endregion peer-config-versions
+
+	// region peer-transfers
+
+	GetPeerTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (*domain.PeerTransfer, error)
+	GetPeerTransfers(ctx context.Context) ([]domain.PeerTransfer, error)
+	SavePeerTransfer(ctx context.Context, transfer *domain.PeerTransfer) error
+	UpdatePeerTransferPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error
+
+	// endregion peer-transfers
+
+	// region emergency-lockdowns
+
+	GetEmergencyLockdown(ctx context.Context, id domain.EmergencyLockdownIdentifier) (*domain.EmergencyLockdown, error)
+	GetEmergencyLockdowns(ctx context.Context) ([]domain.EmergencyLockdown, error)
+	SaveEmergencyLockdown(ctx context.Context, lockdown *domain.EmergencyLockdown) error
+
+	// endregion emergency-lockdowns
+
+	// region login-devices
+
+	GetUserLoginDevices(ctx context.Context, id domain.UserIdentifier) ([]domain.UserLoginDevice, error)
+	SaveUserLoginDevice(ctx context.Context, device *domain.UserLoginDevice) error
+	DeleteUserLoginDevice(ctx context.Context, id string) error
+	DeleteUserLoginDevices(ctx context.Context, id domain.UserIdentifier) error
+
+	// endregion login-devices
+
+	// region notification-preferences
+
+	GetUserNotificationPreferences(ctx context.Context, id domain.UserIdentifier) (
+		*domain.UserNotificationPreferences,
+		error,
+	)
+	SaveUserNotificationPreferences(ctx context.Context, prefs *domain.UserNotificationPreferences) error
+	DeleteUserNotificationPreferences(ctx context.Context, id domain.UserIdentifier) error
+
+	// endregion notification-preferences
+
+	// region saved-views
+
+	GetSavedView(ctx context.Context, id uint64) (*domain.SavedView, error)
+	GetSavedViews(ctx context.Context, list domain.SavedViewList) ([]domain.SavedView, error)
+	SaveSavedView(ctx context.Context, view *domain.SavedView) error
+	DeleteSavedView(ctx context.Context, id uint64) error
+	DeleteUserSavedViews(ctx context.Context, id domain.UserIdentifier) error
+
+	// endregion saved-views
+
+	// region idempotency
+
+	GetIdempotencyRecord(ctx context.Context, userId domain.UserIdentifier, key string) (
+		*domain.IdempotencyRecord,
+		error,
+	)
+	SaveIdempotencyRecord(ctx context.Context, record *domain.IdempotencyRecord) error
+	DeleteIdempotencyRecords(ctx context.Context, before time.Time) error
+
+	// endregion idempotency
+
+	// region webhook-events
+
+	SaveWebhookEvent(ctx context.Context, event *domain.WebhookEvent) error
+	GetWebhookEvent(ctx context.Context, id uint64) (*domain.WebhookEvent, error)
+	GetWebhookEvents(ctx context.Context, afterId uint64, limit int) ([]domain.WebhookEvent, error)
+	GetLastWebhookEventId(ctx context.Context) (uint64, error)
+	DeleteWebhookEvents(ctx context.Context, before time.Time) error
+	GetWebhookDeliveries(ctx context.Context, eventId uint64) ([]domain.WebhookDelivery, error)
+	SaveWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	GetWebhookCursors(ctx context.Context) ([]domain.WebhookCursor, error)
+	SaveWebhookCursor(ctx context.Context, cursor *domain.WebhookCursor) error
+
+	// endregion webhook-events
+
+	// region acceptable-use
+
+	GetLastAcceptableUseAcceptance(ctx context.Context, id domain.UserIdentifier) (
+		*domain.AcceptableUseAcceptance,
+		error,
+	)
+	GetAcceptableUseAcceptances(ctx context.Context, id domain.UserIdentifier) ([]domain.AcceptableUseAcceptance, error)
+	SaveAcceptableUseAcceptance(ctx context.Context, acceptance *domain.AcceptableUseAcceptance) error
+	DeleteAcceptableUseAcceptances(ctx context.Context, id domain.UserIdentifier) error
+
+	// endregion acceptable-use
+
+	// region dns-records
+
+	GetDnsRecordSets(ctx context.Context) ([]domain.DnsRecordSet, error)
+	GetPeerDnsRecordSets(ctx context.Context, id domain.PeerIdentifier) ([]domain.DnsRecordSet, error)
+	SaveDnsRecordSet(ctx context.Context, record *domain.DnsRecordSet) error
+	DeleteDnsRecordSet(ctx context.Context, name string, recordType domain.DnsRecordType) error
+
+	// endregion dns-records
+
+	// region route-sets
+
+	GetRouteSet(ctx context.Context, id domain.RouteSetIdentifier) (*domain.RouteSet, error)
+	GetAllRouteSets(ctx context.Context) ([]domain.RouteSet, error)
+	SaveRouteSet(
+		ctx context.Context,
+		id domain.RouteSetIdentifier,
+		updateFunc func(rs *domain.RouteSet) (*domain.RouteSet, error),
+	) error
+	DeleteRouteSet(ctx context.Context, id domain.RouteSetIdentifier) error
+
+	// endregion route-sets
+
+	// region alerts
+
+	GetAlerts(ctx context.Context) ([]domain.Alert, error)
+	SaveAlert(ctx context.Context, alert *domain.Alert) error
+	DeleteAlert(ctx context.Context, ruleName string, peerId domain.PeerIdentifier) error
+	DeletePeerAlerts(ctx context.Context, peerId domain.PeerIdentifier) error
+	GetAlertSilences(ctx context.Context) ([]domain.AlertSilence, error)
+	SaveAlertSilence(ctx context.Context, silence *domain.AlertSilence) error
+	DeleteAlertSilence(ctx context.Context, id uint64) error
+
+	// endregion alerts
+
+	// region security-events
+
+	GetSecurityEvents(ctx context.Context) ([]domain.SecurityEvent, error)
+	SaveSecurityEvent(ctx context.Context, event *domain.SecurityEvent) error
+	DeleteSecurityEvents(ctx context.Context, before time.Time) error
+
+	// endregion security-events
+
+	// region announcements
+
+	GetAnnouncement(ctx context.Context, id uint64) (*domain.Announcement, error)
+	GetAnnouncements(ctx context.Context) ([]domain.Announcement, error)
+	SaveAnnouncement(ctx context.Context, announcement *domain.Announcement) error
+	DeleteAnnouncement(ctx context.Context, id uint64) error
+	GetAnnouncementReads(ctx context.Context, id uint64) ([]domain.AnnouncementRead, error)
+	SaveAnnouncementRead(ctx context.Context, read *domain.AnnouncementRead) error
+
+	// endregion announcements
+
+	// region export-approvals
+
+	GetExportApproval(ctx context.Context, id uint64) (*domain.ExportApproval, error)
+	GetExportApprovals(ctx context.Context) ([]domain.ExportApproval, error)
+	SaveExportApproval(ctx context.Context, approval *domain.ExportApproval) error
+	GetKeyExports(ctx context.Context, id domain.UserIdentifier, since time.Time) ([]domain.KeyExport, error)
+	SaveKeyExport(ctx context.Context, export *domain.KeyExport) error
+
+	// endregion export-approvals
+
+	// region config-pull
+
+	GetConfigPullToken(ctx context.Context, tokenHash string) (*domain.ConfigPullToken, error)
+	GetAllConfigPullTokens(ctx context.Context) ([]domain.ConfigPullToken, error)
+	GetPeerConfigPullToken(ctx context.Context, peerId domain.PeerIdentifier) (*domain.ConfigPullToken, error)
+	SaveConfigPullToken(ctx context.Context, token *domain.ConfigPullToken) error
+	DeletePeerConfigPullToken(ctx context.Context, peerId domain.PeerIdentifier) error
+	UpdateConfigPullTokenPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error
+
+	// endregion config-pull
+
+	// region ssh-deployments
+
+	GetSshDeployment(ctx context.Context, peerId domain.PeerIdentifier) (*domain.SshDeployment, error)
+	GetAllSshDeployments(ctx context.Context) ([]domain.SshDeployment, error)
+	SaveSshDeployment(ctx context.Context, deployment *domain.SshDeployment) error
+	DeleteSshDeployment(ctx context.Context, peerId domain.PeerIdentifier) error
+	UpdateSshDeploymentPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error
+
+	// endregion ssh-deployments
+
+	// region organizations
+
+	GetOrganization(ctx context.Context, id domain.OrganizationIdentifier) (*domain.Organization, error)
+	GetAllOrganizations(ctx context.Context) ([]domain.Organization, error)
+	SaveOrganization(
+		ctx context.Context,
+		id domain.OrganizationIdentifier,
+		updateFunc func(o *domain.Organization) (*domain.Organization, error),
+	) error
+	DeleteOrganization(ctx context.Context, id domain.OrganizationIdentifier) error
+	IsOrganizationInUse(ctx context.Context, id domain.OrganizationIdentifier) (bool, error)
+
+	// endregion organizations
+
+	// region user-groups
+
+	GetUserGroup(ctx context.Context, id domain.UserGroupIdentifier) (*domain.UserGroup, error)
+	GetAllUserGroups(ctx context.Context) ([]domain.UserGroup, error)
+	SaveUserGroup(
+		ctx context.Context,
+		id domain.UserGroupIdentifier,
+		updateFunc func(g *domain.UserGroup) (*domain.UserGroup, error),
+	) error
+	DeleteUserGroup(ctx context.Context, id domain.UserGroupIdentifier) error
+
+	// endregion user-groups
+
+	// region port-forwards
+
+	GetPortForward(ctx context.Context, id domain.PortForwardIdentifier) (*domain.PortForward, error)
+	GetPortForwards(ctx context.Context) ([]domain.PortForward, error)
+	SavePortForward(ctx context.Context, forward *domain.PortForward) error
+	DeletePortForward(ctx context.Context, id domain.PortForwardIdentifier) error
+	UpdatePortForwardPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error
+
+	// endregion port-forwards
+
+	// region access-requests
+
+	GetAccessRequest(ctx context.Context, id domain.AccessRequestIdentifier) (*domain.AccessRequest, error)
+	GetAccessRequests(ctx context.Context) ([]domain.AccessRequest, error)
+	SaveAccessRequest(ctx context.Context, request *domain.AccessRequest) error
+	UpdateAccessRequestPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error
+
+	// endregion access-requests
+
+	// region route-reviews
+
+	GetRouteReview(ctx context.Context, id domain.RouteReviewIdentifier) (*domain.RouteReview, error)
+	GetRouteReviews(ctx context.Context) ([]domain.RouteReview, error)
+	SaveRouteReview(ctx context.Context, review *domain.RouteReview) error
+	UpdateRouteReviewPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error
+
+	// endregion route-reviews
+
+	// region address-reservations
+
+	GetAddressReservation(ctx context.Context, id domain.AddressReservationIdentifier) (
+		*domain.AddressReservation,
+		error,
+	)
+	GetAddressReservations(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.AddressReservation, error)
+	SaveAddressReservation(ctx context.Context, reservation *domain.AddressReservation) error
+	DeleteAddressReservation(ctx context.Context, id domain.AddressReservationIdentifier) error
+
+	// endregion address-reservations
+}
+
+var (
+	_ Repository = (*SqlRepo)(nil)
+	_ Repository = (*KvRepo)(nil)
+)
@@ -0,0 +1,289 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	secretsRequestTimeout = 10 * time.Second
+	awsSecretsService     = "secretsmanager"
+	gcpSecretsEndpoint    = "https://secretmanager.googleapis.com"
+	gcpMetadataTokenUrl   = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// SecretRepo reads secrets from the environment, from files, from HashiCorp Vault, from AWS Secrets Manager and from
+// Google Cloud Secret Manager.
+type SecretRepo struct {
+	cfg    config.SecretsConfig
+	client *http.Client
+
+	gcpTokenUrl string
+}
+
+// NewSecretRepo creates a new SecretRepo instance.
+func NewSecretRepo(cfg config.SecretsConfig) *SecretRepo {
+	return &SecretRepo{
+		cfg:         cfg,
+		client:      &http.Client{Timeout: secretsRequestTimeout},
+		gcpTokenUrl: gcpMetadataTokenUrl,
+	}
+}
+
+// GetSecret returns the current value of the referenced secret.
+func (r *SecretRepo) GetSecret(ctx context.Context, ref domain.SecretReference) (string, error) {
+	switch ref.Provider {
+	case domain.SecretProviderEnv:
+		value, ok := os.LookupEnv(ref.Path)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", ref.Path)
+		}
+		return value, nil
+	case domain.SecretProviderFile:
+		data, err := os.ReadFile(ref.Path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case domain.SecretProviderVault:
+		return r.getVaultSecret(ctx, ref)
+	case domain.SecretProviderAws:
+		return r.getAwsSecret(ctx, ref)
+	case domain.SecretProviderGcp:
+		return r.getGcpSecret(ctx, ref)
+	default:
+		return "", fmt.Errorf("unsupported secret provider %s", ref.Provider)
+	}
+}
+
+// getVaultSecret reads a secret of a KV version 1 or version 2 secrets engine.
+func (r *SecretRepo) getVaultSecret(ctx context.Context, ref domain.SecretReference) (string, error) {
+	if r.cfg.Vault.Address == "" {
+		return "", errors.New("missing Vault address")
+	}
+	token := r.cfg.Vault.Token
+	if token == "" && r.cfg.Vault.TokenFile != "" {
+		data, err := os.ReadFile(r.cfg.Vault.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read Vault token file: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		return "", errors.New("missing Vault token")
+	}
+
+	endpoint := strings.TrimSuffix(r.cfg.Vault.Address, "/") + "/v1/" + strings.TrimPrefix(ref.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if r.cfg.Vault.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.cfg.Vault.Namespace)
+	}
+
+	var response struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := r.doJson(req, "Vault", &response); err != nil {
+		return "", err
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(response.Data, &data); err != nil {
+		return "", fmt.Errorf("invalid Vault secret %s: %w", ref.Path, err)
+	}
+	if nested, ok := data["data"].(map[string]any); ok && data["metadata"] != nil {
+		data = nested // KV version 2 wraps the secret data
+	}
+
+	return secretMapValue(data, ref)
+}
+
+// getAwsSecret reads a secret of AWS Secrets Manager.
+func (r *SecretRepo) getAwsSecret(ctx context.Context, ref domain.SecretReference) (string, error) {
+	region := r.cfg.Aws.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return "", errors.New("missing AWS region")
+	}
+	creds := awsCredentials{
+		AccessKeyId:     r.cfg.Aws.AccessKeyId,
+		SecretAccessKey: r.cfg.Aws.SecretAccessKey,
+		SessionToken:    r.cfg.Aws.SessionToken,
+	}
+	if creds.AccessKeyId == "" {
+		creds = awsCredentials{
+			AccessKeyId:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+	if creds.AccessKeyId == "" || creds.SecretAccessKey == "" {
+		return "", errors.New("missing AWS credentials")
+	}
+
+	endpoint := r.cfg.Aws.Endpoint
+	if endpoint == "" {
+		endpoint = "https://" + awsSecretsService + "." + region + ".amazonaws.com"
+	}
+	body, err := json.Marshal(map[string]string{"SecretId": ref.Path})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/",
+		bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signAwsRequest(req, body, time.Now().UTC(), region, awsSecretsService, creds)
+
+	var response struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := r.doJson(req, "AWS Secrets Manager", &response); err != nil {
+		return "", err
+	}
+
+	return secretStringValue(response.SecretString, ref)
+}
+
+// getGcpSecret reads a secret version of Google Cloud Secret Manager.
+func (r *SecretRepo) getGcpSecret(ctx context.Context, ref domain.SecretReference) (string, error) {
+	token, err := r.getGcpAccessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := r.cfg.Gcp.Endpoint
+	if endpoint == "" {
+		endpoint = gcpSecretsEndpoint
+	}
+	name := strings.TrimPrefix(ref.Path, "/")
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimSuffix(endpoint, "/")+"/v1/"+name+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var response struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := r.doJson(req, "Google Cloud Secret Manager", &response); err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(response.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("invalid Google Cloud secret %s: %w", ref.Path, err)
+	}
+
+	return secretStringValue(string(data), ref)
+}
+
+func (r *SecretRepo) getGcpAccessToken(ctx context.Context) (string, error) {
+	if r.cfg.Gcp.AccessTokenFile != "" {
+		data, err := os.ReadFile(r.cfg.Gcp.AccessTokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read Google Cloud access token file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.gcpTokenUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	var response struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := r.doJson(req, "Google Cloud metadata server", &response); err != nil {
+		return "", err
+	}
+
+	return response.AccessToken, nil
+}
+
+// doJson sends the request and decodes the JSON response into the given target.
+func (r *SecretRepo) doJson(req *http.Request, service string, target any) error {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s request: %w", service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s request failed with status %d: %s", service, resp.StatusCode,
+			strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(target); err != nil {
+		return fmt.Errorf("invalid %s response: %w", service, err)
+	}
+
+	return nil
+}
+
+// secretStringValue returns the secret value. If the reference contains a key, the secret must be a JSON object.
+func secretStringValue(secret string, ref domain.SecretReference) (string, error) {
+	if ref.Key == "" {
+		return secret, nil
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal([]byte(secret), &data); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object: %w", ref.Path, err)
+	}
+
+	return secretMapValue(data, ref)
+}
+
+// secretMapValue returns the value of the referenced key. Without a key, the secret must contain a single value.
+func secretMapValue(data map[string]any, ref domain.SecretReference) (string, error) {
+	key := ref.Key
+	if key == "" {
+		if len(data) != 1 {
+			return "", fmt.Errorf("secret %s contains %d values, a key is required", ref.Path, len(data))
+		}
+		for k := range data {
+			key = k
+		}
+	}
+
+	switch value := data[key].(type) {
+	case string:
+		return value, nil
+	case nil:
+		return "", fmt.Errorf("secret %s does not contain the key %s", ref.Path, key)
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(encoded), nil
+	}
+}
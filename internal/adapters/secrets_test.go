@@ -0,0 +1,117 @@
+package adapters
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestSecretRepo_envAndFile(t *testing.T) {
+	t.Setenv("WG_PORTAL_TEST_SECRET", "from-env")
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("from-file\n"), 0600))
+
+	repo := NewSecretRepo(config.SecretsConfig{})
+
+	value, err := repo.GetSecret(context.Background(),
+		domain.SecretReference{Provider: domain.SecretProviderEnv, Path: "WG_PORTAL_TEST_SECRET"})
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", value)
+
+	value, err = repo.GetSecret(context.Background(),
+		domain.SecretReference{Provider: domain.SecretProviderFile, Path: secretFile})
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", value)
+
+	_, err = repo.GetSecret(context.Background(),
+		domain.SecretReference{Provider: domain.SecretProviderEnv, Path: "WG_PORTAL_TEST_MISSING"})
+	assert.Error(t, err)
+}
+
+func TestSecretRepo_vault(t *testing.T) {
+	var token, path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Vault-Token")
+		path = r.URL.Path
+		_, _ = io.WriteString(w, `{"data":{"data":{"smtp":"mail-secret","oidc":"client-secret"},"metadata":{}}}`)
+	}))
+	defer srv.Close()
+
+	repo := NewSecretRepo(config.SecretsConfig{Vault: config.VaultSecretsConfig{Address: srv.URL, Token: "s.123"}})
+
+	value, err := repo.GetSecret(context.Background(),
+		domain.SecretReference{Provider: domain.SecretProviderVault, Path: "secret/data/wg-portal", Key: "smtp"})
+	require.NoError(t, err)
+	assert.Equal(t, "mail-secret", value)
+	assert.Equal(t, "s.123", token)
+	assert.Equal(t, "/v1/secret/data/wg-portal", path)
+
+	_, err = repo.GetSecret(context.Background(),
+		domain.SecretReference{Provider: domain.SecretProviderVault, Path: "secret/data/wg-portal"})
+	assert.ErrorContains(t, err, "a key is required")
+}
+
+func TestSecretRepo_aws(t *testing.T) {
+	var target, auth, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = r.Header.Get("X-Amz-Target")
+		auth = r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		_, _ = io.WriteString(w, `{"SecretString":"{\"dsn\":\"postgres://wg\"}"}`)
+	}))
+	defer srv.Close()
+
+	repo := NewSecretRepo(config.SecretsConfig{Aws: config.AwsSecretsConfig{
+		Region:          "eu-central-1",
+		AccessKeyId:     "AKID",
+		SecretAccessKey: "secret",
+		Endpoint:        srv.URL,
+	}})
+
+	value, err := repo.GetSecret(context.Background(),
+		domain.SecretReference{Provider: domain.SecretProviderAws, Path: "wg-portal", Key: "dsn"})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://wg", value)
+	assert.Equal(t, "secretsmanager.GetSecretValue", target)
+	assert.Equal(t, `{"SecretId":"wg-portal"}`, body)
+	assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/"), auth)
+	assert.Contains(t, auth, "/eu-central-1/secretsmanager/aws4_request")
+}
+
+func TestSecretRepo_gcp(t *testing.T) {
+	var auth, path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			_, _ = io.WriteString(w, `{"access_token":"ya29.token"}`)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		path = r.URL.Path
+		_, _ = io.WriteString(w, `{"payload":{"data":"`+base64.StdEncoding.EncodeToString([]byte("gcp-secret"))+`"}}`)
+	}))
+	defer srv.Close()
+
+	repo := NewSecretRepo(config.SecretsConfig{Gcp: config.GcpSecretsConfig{Endpoint: srv.URL}})
+	repo.gcpTokenUrl = srv.URL + "/token"
+
+	value, err := repo.GetSecret(context.Background(),
+		domain.SecretReference{Provider: domain.SecretProviderGcp, Path: "projects/p/secrets/smtp"})
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-secret", value)
+	assert.Equal(t, "Bearer ya29.token", auth)
+	assert.Equal(t, "/v1/projects/p/secrets/smtp/versions/latest:access", path)
+}
@@ -0,0 +1,54 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type statsExporter interface {
+	writePeerStats(ctx context.Context, points []domain.PeerStatsPoint) error
+}
+
+// StatisticsExportRepo writes peer statistics to the configured time-series database.
+type StatisticsExportRepo struct {
+	exporter statsExporter
+}
+
+// NewStatisticsExportRepository creates a new StatisticsExportRepo instance for the configured exporter.
+func NewStatisticsExportRepository(cfg config.StatisticsExportConfig) (*StatisticsExportRepo, error) {
+	if !cfg.Enabled {
+		return &StatisticsExportRepo{}, nil
+	}
+
+	var exporter statsExporter
+	var err error
+	switch cfg.Exporter {
+	case config.StatisticsExporterInfluxDb:
+		exporter, err = newInfluxDbExporter(cfg.InfluxDb)
+	case config.StatisticsExporterTimescaleDb:
+		exporter, err = newTimescaleDbExporter(cfg.TimescaleDb)
+	default:
+		err = fmt.Errorf("unsupported statistics exporter: %s", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize statistics exporter: %w", err)
+	}
+
+	return &StatisticsExportRepo{exporter: exporter}, nil
+}
+
+// ExportPeerStats writes the given peer statistics points.
+func (r *StatisticsExportRepo) ExportPeerStats(ctx context.Context, points []domain.PeerStatsPoint) error {
+	if r.exporter == nil {
+		return errors.New("statistics export is disabled")
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	return r.exporter.writePeerStats(ctx, points)
+}
@@ -0,0 +1,108 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type influxDbExporter struct {
+	cfg    config.InfluxDbConfig
+	client *http.Client
+}
+
+var (
+	influxDbNameEscaper   = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxDbTagEscaper    = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	influxDbStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+func newInfluxDbExporter(cfg config.InfluxDbConfig) (*influxDbExporter, error) {
+	if cfg.Url == "" {
+		return nil, errors.New("missing InfluxDB url")
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New("missing InfluxDB bucket")
+	}
+	if cfg.Measurement == "" {
+		cfg.Measurement = "wireguard_peer"
+	}
+
+	return &influxDbExporter{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (e *influxDbExporter) writePeerStats(ctx context.Context, points []domain.PeerStatsPoint) error {
+	var body bytes.Buffer
+	for _, point := range points {
+		e.writeLine(&body, point)
+	}
+
+	query := url.Values{"bucket": {e.cfg.Bucket}, "precision": {"ns"}}
+	if e.cfg.Organization != "" {
+		query.Set("org", e.cfg.Organization)
+	}
+	endpoint := strings.TrimSuffix(e.cfg.Url, "/") + "/api/v2/write?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if e.cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+e.cfg.Token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send InfluxDB request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("InfluxDB request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// writeLine writes the given point in the InfluxDB line protocol format.
+func (e *influxDbExporter) writeLine(w *bytes.Buffer, point domain.PeerStatsPoint) {
+	w.WriteString(influxDbNameEscaper.Replace(e.cfg.Measurement))
+
+	tags := [][2]string{
+		{"interface", string(point.InterfaceId)},
+		{"name", point.PeerName},
+		{"peer", string(point.PeerId)},
+		{"user", string(point.UserId)},
+	}
+	for _, tag := range tags {
+		if tag[1] == "" {
+			continue // empty tag values are not allowed
+		}
+		w.WriteString("," + tag[0] + "=" + influxDbTagEscaper.Replace(tag[1]))
+	}
+
+	w.WriteString(" received=" + strconv.FormatUint(point.BytesReceived, 10) + "i")
+	w.WriteString(",transmitted=" + strconv.FormatUint(point.BytesTransmitted, 10) + "i")
+	w.WriteString(",connected=" + strconv.FormatBool(point.IsConnected))
+	if point.LastHandshake != nil {
+		w.WriteString(",last_handshake=" + strconv.FormatInt(point.LastHandshake.Unix(), 10) + "i")
+	}
+	if point.Endpoint != "" {
+		w.WriteString(`,endpoint="` + influxDbStringEscaper.Replace(point.Endpoint) + `"`)
+	}
+
+	w.WriteString(" " + strconv.FormatInt(point.Time.UnixNano(), 10) + "\n")
+}
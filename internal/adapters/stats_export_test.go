@@ -0,0 +1,71 @@
+package adapters
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestStatisticsExportRepo_influxDb(t *testing.T) {
+	var body, query, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		query = r.URL.RawQuery
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	repo, err := NewStatisticsExportRepository(config.StatisticsExportConfig{
+		Enabled:  true,
+		Exporter: config.StatisticsExporterInfluxDb,
+		InfluxDb: config.InfluxDbConfig{
+			Url:          srv.URL,
+			Token:        "secret",
+			Organization: "acme",
+			Bucket:       "wg",
+			Measurement:  "wireguard_peer",
+		},
+	})
+	require.NoError(t, err)
+
+	handshake := time.Unix(1700000000, 0)
+	err = repo.ExportPeerStats(context.Background(), []domain.PeerStatsPoint{
+		{
+			Time:             time.Unix(1700000060, 0),
+			PeerId:           "xTIBA5rbo=",
+			PeerName:         "Alice Laptop",
+			InterfaceId:      "wg0",
+			BytesReceived:    10,
+			BytesTransmitted: 20,
+			LastHandshake:    &handshake,
+			Endpoint:         "192.0.2.1:51820",
+			IsConnected:      true,
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Token secret", auth)
+	assert.Equal(t, "bucket=wg&org=acme&precision=ns", query)
+	assert.Equal(t, `wireguard_peer,interface=wg0,name=Alice\ Laptop,peer=xTIBA5rbo\= `+
+		`received=10i,transmitted=20i,connected=true,last_handshake=1700000000i,endpoint="192.0.2.1:51820" `+
+		"1700000060000000000\n", body)
+}
+
+func TestStatisticsExportRepo_disabled(t *testing.T) {
+	repo, err := NewStatisticsExportRepository(config.StatisticsExportConfig{Enabled: false})
+	require.NoError(t, err)
+
+	err = repo.ExportPeerStats(context.Background(), []domain.PeerStatsPoint{{PeerId: "peer"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,119 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+var timescaleDbTablePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,62}$`)
+
+type timescaleDbExporter struct {
+	db    *gorm.DB
+	table string
+}
+
+type timescaleDbRow struct {
+	Time          time.Time  `gorm:"column:time"`
+	PeerId        string     `gorm:"column:peer_id"`
+	PeerName      string     `gorm:"column:peer_name"`
+	InterfaceId   string     `gorm:"column:interface_id"`
+	UserId        string     `gorm:"column:user_id"`
+	Received      uint64     `gorm:"column:received"`
+	Transmitted   uint64     `gorm:"column:transmitted"`
+	LastHandshake *time.Time `gorm:"column:last_handshake"`
+	Endpoint      string     `gorm:"column:endpoint"`
+	Connected     bool       `gorm:"column:connected"`
+}
+
+func newTimescaleDbExporter(cfg config.TimescaleDbConfig) (*timescaleDbExporter, error) {
+	if cfg.DSN == "" {
+		return nil, errors.New("missing TimescaleDB dsn")
+	}
+	if !timescaleDbTablePattern.MatchString(cfg.Table) {
+		return nil, fmt.Errorf("invalid TimescaleDB table name %q", cfg.Table)
+	}
+
+	db, err := gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{
+		Logger: NewLogger(0, false),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open TimescaleDB database: %w", err)
+	}
+
+	e := &timescaleDbExporter{
+		db:    db,
+		table: cfg.Table,
+	}
+	if err := e.migrate(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// migrate creates the hypertable if it does not exist yet.
+func (e *timescaleDbExporter) migrate() error {
+	// the table name is validated, so it is safe to use it in the statements
+	err := e.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		time           TIMESTAMPTZ NOT NULL,
+		peer_id        TEXT        NOT NULL,
+		peer_name      TEXT,
+		interface_id   TEXT,
+		user_id        TEXT,
+		received       BIGINT,
+		transmitted    BIGINT,
+		last_handshake TIMESTAMPTZ,
+		endpoint       TEXT,
+		connected      BOOLEAN
+	)`, e.table)).Error
+	if err != nil {
+		return fmt.Errorf("failed to create TimescaleDB table %s: %w", e.table, err)
+	}
+
+	err = e.db.Exec("SELECT create_hypertable(?::regclass, 'time', if_not_exists => TRUE)", e.table).Error
+	if err != nil {
+		return fmt.Errorf("failed to create TimescaleDB hypertable %s: %w", e.table, err)
+	}
+
+	err = e.db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_peer_id_time_idx ON %s (peer_id, time DESC)",
+		e.table, e.table)).Error
+	if err != nil {
+		return fmt.Errorf("failed to create TimescaleDB index for %s: %w", e.table, err)
+	}
+
+	return nil
+}
+
+func (e *timescaleDbExporter) writePeerStats(ctx context.Context, points []domain.PeerStatsPoint) error {
+	rows := make([]timescaleDbRow, len(points))
+	for i, point := range points {
+		rows[i] = timescaleDbRow{
+			Time:          point.Time,
+			PeerId:        string(point.PeerId),
+			PeerName:      point.PeerName,
+			InterfaceId:   string(point.InterfaceId),
+			UserId:        string(point.UserId),
+			Received:      point.BytesReceived,
+			Transmitted:   point.BytesTransmitted,
+			LastHandshake: point.LastHandshake,
+			Endpoint:      point.Endpoint,
+			Connected:     point.IsConnected,
+		}
+	}
+
+	err := e.db.WithContext(ctx).Table(e.table).CreateInBatches(rows, 100).Error
+	if err != nil {
+		return fmt.Errorf("failed to write TimescaleDB rows: %w", err)
+	}
+
+	return nil
+}
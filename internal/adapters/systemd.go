@@ -0,0 +1,62 @@
+package adapters
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SystemdNotifier sends service state notifications to systemd, see sd_notify(3).
+// If WireGuard Portal is not started by systemd with Type=notify, all notifications are ignored.
+type SystemdNotifier struct {
+	socket   string        // the address of the notification socket, empty if notifications are disabled
+	watchdog time.Duration // the watchdog timeout, zero if the watchdog is disabled
+}
+
+// NewSystemdNotifier creates a new SystemdNotifier instance from the environment variables set by systemd.
+func NewSystemdNotifier() *SystemdNotifier {
+	n := &SystemdNotifier{
+		socket: os.Getenv("NOTIFY_SOCKET"),
+	}
+
+	// the watchdog is only meant for the main process if WATCHDOG_PID is set
+	pid := os.Getenv("WATCHDOG_PID")
+	if usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64); err == nil && usec > 0 &&
+		(pid == "" || pid == strconv.Itoa(os.Getpid())) {
+		n.watchdog = time.Duration(usec) * time.Microsecond
+	}
+
+	return n
+}
+
+// Notify sends the given newline separated state assignments, for example READY=1, to systemd.
+func (n *SystemdNotifier) Notify(state string) error {
+	if n.socket == "" {
+		return nil // not started by systemd
+	}
+
+	addr := &net.UnixAddr{Name: n.socket, Net: "unixgram"}
+	if strings.HasPrefix(addr.Name, "@") {
+		addr.Name = "\x00" + addr.Name[1:] // abstract socket
+	}
+
+	conn, err := net.DialUnix(addr.Net, nil, addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to systemd notification socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("failed to send systemd notification: %w", err)
+	}
+
+	return nil
+}
+
+// WatchdogTimeout returns the timeout of the systemd watchdog, zero if the watchdog is disabled.
+func (n *SystemdNotifier) WatchdogTimeout() time.Duration {
+	return n.watchdog
+}
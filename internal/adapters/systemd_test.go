@@ -0,0 +1,48 @@
+package adapters
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemdNotifier_Notify(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	notifier := NewSystemdNotifier()
+	require.NoError(t, notifier.Notify("READY=1\nSTATUS=ready"))
+
+	buf := make([]byte, 64)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "READY=1\nSTATUS=ready", string(buf[:n]))
+}
+
+func TestSystemdNotifier_Disabled(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	t.Setenv("WATCHDOG_USEC", "")
+
+	notifier := NewSystemdNotifier()
+	assert.NoError(t, notifier.Notify("READY=1"))
+	assert.Zero(t, notifier.WatchdogTimeout())
+}
+
+func TestSystemdNotifier_WatchdogTimeout(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	assert.Equal(t, 30*time.Second, NewSystemdNotifier().WatchdogTimeout())
+
+	t.Setenv("WATCHDOG_PID", "1") // the watchdog is meant for another process
+	assert.Zero(t, NewSystemdNotifier().WatchdogTimeout())
+}
@@ -0,0 +1,76 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/h44z/wg-portal/internal/config"
+)
+
+// telegramMaxMessageLength is the maximum length of a message accepted by the Telegram Bot API.
+const telegramMaxMessageLength = 4096
+
+type TelegramRepo struct {
+	cfg    *config.TelegramConfig
+	client *http.Client
+}
+
+// NewTelegramRepo creates a new TelegramRepo instance that sends messages using the given Telegram bot.
+func NewTelegramRepo(cfg config.TelegramConfig) TelegramRepo {
+	return TelegramRepo{
+		cfg:    &cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// SendMessage sends a plain text message to the given chat. Messages that exceed the size limit of Telegram
+// are truncated.
+func (r TelegramRepo) SendMessage(ctx context.Context, chatId, text string) error {
+	if r.cfg.BotToken == "" {
+		return errors.New("telegram bot token not configured")
+	}
+	if chatId == "" {
+		return errors.New("missing telegram chat id")
+	}
+
+	if runes := []rune(text); len(runes) > telegramMaxMessageLength {
+		text = string(runes[:telegramMaxMessageLength])
+	}
+
+	form := url.Values{}
+	form.Set("chat_id", chatId)
+	form.Set("text", text)
+	form.Set("disable_web_page_preview", "true")
+
+	endpoint := strings.TrimSuffix(r.cfg.ApiUrl, "/") + "/bot" + r.cfg.BotToken + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.New("failed to create telegram request") // do not leak the bot token
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// strip the url from the error, it contains the bot token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("telegram request failed: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		_, _ = io.Copy(io.Discard, Body)
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("telegram request failed with status: %s", resp.Status)
+	}
+
+	return nil
+}
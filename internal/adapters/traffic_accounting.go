@@ -0,0 +1,391 @@
+//go:build linux
+
+package adapters
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"sync"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+	"github.com/h44z/wg-portal/internal/lowlevel"
+)
+
+const (
+	accountingMaxPeerNetworks = 1 << 16 // the maximum number of allowed IPs per interface
+	accountingMaxCounters     = 1 << 18 // the maximum number of peer, subnet and direction combinations per interface
+
+	accountingFilterName     = "wg_portal_acct"
+	accountingFilterPriority = 1
+
+	accountingDirectionReceived    uint32 = 0 // tc ingress, packets that were received from a peer
+	accountingDirectionTransmitted uint32 = 1 // tc egress, packets that are sent to a peer
+
+	lpmKeySize      = 4 + 16 // prefix length and IPv6 address, IPv4 addresses are stored IPv4-mapped
+	counterKeySize  = 3 * 4  // peer index, subnet index, direction
+	counterValueLen = 2 * 8  // bytes, packets
+)
+
+// EbpfAccountingRepo counts the traffic of WireGuard peers with eBPF programs that are attached to the tc ingress and
+// egress hooks of the WireGuard interfaces. Packets are assigned to peers by the allowed IPs of the peers and to
+// accounting subnets by the address of the other end of the connection.
+type EbpfAccountingRepo struct {
+	nl lowlevel.NetlinkClient
+
+	subnets    []netip.Prefix   // the accounting subnets, the index + 1 is the subnet index of the eBPF program
+	subnetsMap *lowlevel.BpfMap // LPM trie: subnet -> subnet index, shared by all interfaces
+	mux        sync.Mutex       // protects interfaces
+	interfaces map[domain.InterfaceIdentifier]*accountedInterface
+}
+
+// accountedInterface contains the eBPF objects of a WireGuard interface.
+type accountedInterface struct {
+	linkIndex int
+
+	peersMap    *lowlevel.BpfMap // LPM trie: allowed IP -> peer index
+	countersMap *lowlevel.BpfMap // hash: peer index, subnet index, direction -> bytes, packets
+	programs    []*lowlevel.BpfProgram
+
+	peerIndices  map[domain.PeerIdentifier]uint32
+	nextIndex    uint32
+	peerNetworks map[string]struct{} // the keys of the peers map
+}
+
+type accountingCounter struct {
+	peer, subnet, direction uint32
+	bytes, packets          uint64
+}
+
+// NewEbpfAccountingRepo creates a new EbpfAccountingRepo instance. If the eBPF backend is not configured, the
+// repository is created without loading any eBPF objects.
+func NewEbpfAccountingRepo(cfg *config.Config) (*EbpfAccountingRepo, error) {
+	repo := &EbpfAccountingRepo{
+		nl:         &lowlevel.NetlinkManager{},
+		interfaces: make(map[domain.InterfaceIdentifier]*accountedInterface),
+	}
+
+	if cfg.Statistics.AccountingBackend != config.AccountingBackendEbpf {
+		return repo, nil
+	}
+	if cfg.Advanced.BandwidthShaping {
+		return nil, errors.New("ebpf traffic accounting cannot be combined with bandwidth shaping")
+	}
+
+	for _, subnet := range cfg.Statistics.AccountingSubnets {
+		prefix, err := netip.ParsePrefix(subnet)
+		if err != nil {
+			return nil, fmt.Errorf("invalid accounting subnet %s: %w", subnet, err)
+		}
+		repo.subnets = append(repo.subnets, prefix.Masked())
+	}
+
+	subnetsMap, err := lowlevel.NewBpfMap("wgp_subnets", unix.BPF_MAP_TYPE_LPM_TRIE, lpmKeySize, 4,
+		uint32(max(len(repo.subnets), 1)), unix.BPF_F_NO_PREALLOC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ebpf traffic accounting (missing CAP_BPF?): %w", err)
+	}
+	for i, subnet := range repo.subnets {
+		if err := subnetsMap.Update(lpmKey(subnet), binary.NativeEndian.AppendUint32(nil, uint32(i+1))); err != nil {
+			return nil, fmt.Errorf("failed to store accounting subnet %s: %w", subnet, err)
+		}
+	}
+	repo.subnetsMap = subnetsMap
+
+	return repo, nil
+}
+
+// GetPeerTraffic returns the traffic of the given peers of the interface since the eBPF programs were attached to the
+// interface. On the first call for an interface, or if the interface was recreated, the programs are attached.
+func (r *EbpfAccountingRepo) GetPeerTraffic(
+	_ context.Context,
+	id domain.InterfaceIdentifier,
+	peers []domain.PhysicalPeer,
+) (map[domain.PeerIdentifier]domain.PeerTraffic, error) {
+	if r.subnetsMap == nil {
+		return nil, errors.New("ebpf traffic accounting is disabled")
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	iface, err := r.attach(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := iface.syncPeers(peers); err != nil {
+		return nil, fmt.Errorf("failed to update accounted peers of %s: %w", id, err)
+	}
+
+	counters, err := iface.readCounters()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read traffic counters of %s: %w", id, err)
+	}
+
+	peerIds := make(map[uint32]domain.PeerIdentifier, len(iface.peerIndices))
+	for peerId, index := range iface.peerIndices {
+		peerIds[index] = peerId
+	}
+
+	breakdowns := make(map[domain.PeerIdentifier]map[uint32]*domain.PeerSubnetTraffic)
+	for _, counter := range counters {
+		peerId, ok := peerIds[counter.peer]
+		if !ok || counter.subnet > uint32(len(r.subnets)) {
+			continue
+		}
+		if breakdowns[peerId] == nil {
+			breakdowns[peerId] = make(map[uint32]*domain.PeerSubnetTraffic)
+		}
+		subnet, ok := breakdowns[peerId][counter.subnet]
+		if !ok {
+			subnet = &domain.PeerSubnetTraffic{Subnet: r.subnetName(counter.subnet)}
+			breakdowns[peerId][counter.subnet] = subnet
+		}
+		switch counter.direction {
+		case accountingDirectionReceived:
+			subnet.BytesReceived, subnet.PacketsReceived = counter.bytes, counter.packets
+		case accountingDirectionTransmitted:
+			subnet.BytesTransmitted, subnet.PacketsTransmitted = counter.bytes, counter.packets
+		}
+	}
+
+	traffic := make(map[domain.PeerIdentifier]domain.PeerTraffic, len(peers))
+	for _, peer := range peers {
+		var result domain.PeerTraffic
+		for index := uint32(0); index <= uint32(len(r.subnets)); index++ {
+			if subnet, ok := breakdowns[peer.Identifier][index]; ok {
+				result.Subnets = append(result.Subnets, *subnet)
+			}
+		}
+		traffic[peer.Identifier] = result
+	}
+
+	return traffic, nil
+}
+
+func (r *EbpfAccountingRepo) subnetName(index uint32) string {
+	if index == 0 {
+		return domain.AccountingSubnetOther
+	}
+	return r.subnets[index-1].String()
+}
+
+// attach loads and attaches the eBPF programs of the interface, unless they are already attached to the current link
+// of the interface.
+func (r *EbpfAccountingRepo) attach(id domain.InterfaceIdentifier) (*accountedInterface, error) {
+	link, err := r.nl.LinkByName(string(id))
+	if err != nil {
+		if existing, ok := r.interfaces[id]; ok {
+			existing.close()
+			delete(r.interfaces, id)
+		}
+		return nil, fmt.Errorf("failed to find interface %s: %w", id, err)
+	}
+	linkIndex := link.Attrs().Index
+
+	if existing, ok := r.interfaces[id]; ok {
+		if existing.linkIndex == linkIndex {
+			return existing, nil
+		}
+		existing.close() // the interface was recreated
+		delete(r.interfaces, id)
+	}
+
+	iface, err := r.newAccountedInterface(linkIndex, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load traffic accounting of %s: %w", id, err)
+	}
+
+	err = r.nl.QdiscReplace(&netlink.Clsact{QdiscAttrs: netlink.QdiscAttrs{
+		LinkIndex: linkIndex,
+		Handle:    netlink.MakeHandle(0xffff, 0),
+		Parent:    netlink.HANDLE_CLSACT,
+	}})
+	if err != nil {
+		iface.close()
+		return nil, fmt.Errorf("failed to add clsact qdisc to %s: %w", id, err)
+	}
+	for i, parent := range []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS} {
+		err = r.nl.FilterReplace(&netlink.BpfFilter{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: linkIndex,
+				Parent:    parent,
+				Handle:    netlink.MakeHandle(0, 1),
+				Protocol:  unix.ETH_P_ALL,
+				Priority:  accountingFilterPriority,
+			},
+			Fd:           iface.programs[i].Fd(),
+			Name:         accountingFilterName,
+			DirectAction: true,
+		})
+		if err != nil {
+			iface.close()
+			return nil, fmt.Errorf("failed to attach traffic accounting to %s: %w", id, err)
+		}
+	}
+
+	slog.Info("attached ebpf traffic accounting", "interface", id, "subnets", len(r.subnets))
+	r.interfaces[id] = iface
+
+	return iface, nil
+}
+
+// newAccountedInterface creates the maps and loads the programs of an interface. The l3Offset is the offset of the
+// IP header in the packets, WireGuard interfaces have no link layer header.
+func (r *EbpfAccountingRepo) newAccountedInterface(linkIndex, l3Offset int) (*accountedInterface, error) {
+	iface := &accountedInterface{
+		linkIndex:    linkIndex,
+		peerIndices:  make(map[domain.PeerIdentifier]uint32),
+		nextIndex:    1,
+		peerNetworks: make(map[string]struct{}),
+	}
+
+	var err error
+	iface.peersMap, err = lowlevel.NewBpfMap("wgp_peers", unix.BPF_MAP_TYPE_LPM_TRIE, lpmKeySize, 4,
+		accountingMaxPeerNetworks, unix.BPF_F_NO_PREALLOC)
+	if err != nil {
+		return nil, err
+	}
+	iface.countersMap, err = lowlevel.NewBpfMap("wgp_counters", unix.BPF_MAP_TYPE_HASH, counterKeySize,
+		counterValueLen, accountingMaxCounters, unix.BPF_F_NO_PREALLOC)
+	if err != nil {
+		iface.close()
+		return nil, err
+	}
+
+	for _, direction := range []uint32{accountingDirectionReceived, accountingDirectionTransmitted} {
+		instructions, err := accountingProgram(direction, l3Offset, iface.peersMap.Fd(), r.subnetsMap.Fd(),
+			iface.countersMap.Fd())
+		if err != nil {
+			iface.close()
+			return nil, err
+		}
+		program, err := lowlevel.LoadBpfProgram(accountingFilterName, unix.BPF_PROG_TYPE_SCHED_CLS, instructions)
+		if err != nil {
+			iface.close()
+			return nil, err
+		}
+		iface.programs = append(iface.programs, program)
+	}
+
+	return iface, nil
+}
+
+// syncPeers updates the peers map to the allowed IPs of the given peers and removes the counters of removed peers.
+func (i *accountedInterface) syncPeers(peers []domain.PhysicalPeer) error {
+	current := make(map[domain.PeerIdentifier]struct{}, len(peers))
+	networks := make(map[string]struct{})
+	for _, peer := range peers {
+		current[peer.Identifier] = struct{}{}
+		index, ok := i.peerIndices[peer.Identifier]
+		if !ok {
+			index = i.nextIndex
+			i.nextIndex++
+			i.peerIndices[peer.Identifier] = index
+		}
+
+		for _, allowedIP := range peer.AllowedIPs {
+			key := lpmKey(allowedIP.Prefix().Masked())
+			networks[string(key)] = struct{}{}
+			if err := i.peersMap.Update(key, binary.NativeEndian.AppendUint32(nil, index)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for key := range i.peerNetworks {
+		if _, ok := networks[key]; ok {
+			continue
+		}
+		if err := i.peersMap.Delete([]byte(key)); err != nil && !errors.Is(err, unix.ENOENT) {
+			return err
+		}
+	}
+	i.peerNetworks = networks
+
+	removed := make(map[uint32]struct{})
+	for peerId, index := range i.peerIndices {
+		if _, ok := current[peerId]; !ok {
+			removed[index] = struct{}{}
+			delete(i.peerIndices, peerId)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	keys, err := i.countersMap.Keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if _, ok := removed[binary.NativeEndian.Uint32(key)]; !ok {
+			continue
+		}
+		if err := i.countersMap.Delete(key); err != nil && !errors.Is(err, unix.ENOENT) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *accountedInterface) readCounters() ([]accountingCounter, error) {
+	keys, err := i.countersMap.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make([]accountingCounter, 0, len(keys))
+	for _, key := range keys {
+		value, err := i.countersMap.Lookup(key)
+		if errors.Is(err, unix.ENOENT) {
+			continue // removed in the meantime
+		}
+		if err != nil {
+			return nil, err
+		}
+		counters = append(counters, accountingCounter{
+			peer:      binary.NativeEndian.Uint32(key[0:]),
+			subnet:    binary.NativeEndian.Uint32(key[4:]),
+			direction: binary.NativeEndian.Uint32(key[8:]),
+			bytes:     binary.NativeEndian.Uint64(value[0:]),
+			packets:   binary.NativeEndian.Uint64(value[8:]),
+		})
+	}
+
+	return counters, nil
+}
+
+// close releases the eBPF objects of the interface. Attached programs stay active until the filters are replaced or
+// the interface is removed.
+func (i *accountedInterface) close() {
+	for _, program := range i.programs {
+		_ = program.Close()
+	}
+	if i.countersMap != nil {
+		_ = i.countersMap.Close()
+	}
+	if i.peersMap != nil {
+		_ = i.peersMap.Close()
+	}
+}
+
+// lpmKey returns the key of the prefix in a LPM trie map. IPv4 prefixes are stored as IPv4-mapped IPv6 prefixes.
+func lpmKey(prefix netip.Prefix) []byte {
+	bits := prefix.Bits()
+	if prefix.Addr().Is4() {
+		bits += 96
+	}
+	addr := prefix.Addr().As16()
+
+	key := binary.NativeEndian.AppendUint32(make([]byte, 0, lpmKeySize), uint32(bits))
+	return append(key, addr[:]...)
+}
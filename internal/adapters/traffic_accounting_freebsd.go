@@ -0,0 +1,31 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// EbpfAccountingRepo is not supported on FreeBSD, eBPF traffic accounting is only available on Linux.
+type EbpfAccountingRepo struct{}
+
+// NewEbpfAccountingRepo creates a new EbpfAccountingRepo instance. An error is returned if the eBPF backend is
+// configured.
+func NewEbpfAccountingRepo(cfg *config.Config) (*EbpfAccountingRepo, error) {
+	if cfg.Statistics.AccountingBackend == config.AccountingBackendEbpf {
+		return nil, errors.New("ebpf traffic accounting is only supported on Linux")
+	}
+
+	return &EbpfAccountingRepo{}, nil
+}
+
+// GetPeerTraffic always returns an error on FreeBSD.
+func (r *EbpfAccountingRepo) GetPeerTraffic(
+	_ context.Context,
+	_ domain.InterfaceIdentifier,
+	_ []domain.PhysicalPeer,
+) (map[domain.PeerIdentifier]domain.PeerTraffic, error) {
+	return nil, errors.New("ebpf traffic accounting is disabled")
+}
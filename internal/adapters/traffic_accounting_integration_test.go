@@ -0,0 +1,111 @@
+//go:build integration && linux
+
+package adapters
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// testRunProgram runs the program with the given ethernet frame, see the BPF_PROG_TEST_RUN command.
+func testRunProgram(t *testing.T, fd int, frame []byte) uint32 {
+	attr := struct {
+		progFd      uint32
+		retval      uint32
+		dataSizeIn  uint32
+		dataSizeOut uint32
+		dataIn      uint64
+		dataOut     uint64
+		repeat      uint32
+		duration    uint32
+	}{
+		progFd:     uint32(fd),
+		dataSizeIn: uint32(len(frame)),
+		dataIn:     uint64(uintptr(unsafe.Pointer(&frame[0]))),
+		repeat:     1,
+	}
+	_, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_PROG_TEST_RUN, uintptr(unsafe.Pointer(&attr)),
+		unsafe.Sizeof(attr))
+	require.Zero(t, errno)
+	return attr.retval
+}
+
+// testFrame returns an ethernet frame with an IP header of the given addresses and the given total length.
+func testFrame(source, destination string, length int) []byte {
+	src, dst := netip.MustParseAddr(source), netip.MustParseAddr(destination)
+	frame := make([]byte, 14+length)
+	if src.Is4() {
+		binary.BigEndian.PutUint16(frame[12:], unix.ETH_P_IP)
+		frame[14] = 0x45
+		copy(frame[14+12:], src.AsSlice())
+		copy(frame[14+16:], dst.AsSlice())
+	} else {
+		binary.BigEndian.PutUint16(frame[12:], unix.ETH_P_IPV6)
+		frame[14] = 0x60
+		copy(frame[14+8:], src.AsSlice())
+		copy(frame[14+24:], dst.AsSlice())
+	}
+	return frame
+}
+
+func TestEbpfAccountingRepo_Programs(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Statistics.AccountingBackend = config.AccountingBackendEbpf
+	cfg.Statistics.AccountingSubnets = []string{"192.168.0.0/16", "192.168.10.0/24", "2001:db8::/32"}
+	repo, err := NewEbpfAccountingRepo(cfg)
+	require.NoError(t, err)
+
+	iface, err := repo.newAccountedInterface(0, 14) // test runs include the ethernet header
+	require.NoError(t, err)
+	defer iface.close()
+
+	peers := []domain.PhysicalPeer{
+		{Identifier: "peer-a", AllowedIPs: []domain.Cidr{domain.CidrFromPrefix(netip.MustParsePrefix("10.0.0.2/32")),
+			domain.CidrFromPrefix(netip.MustParsePrefix("fd00::2/128"))}},
+		{Identifier: "peer-b", AllowedIPs: []domain.Cidr{domain.CidrFromPrefix(netip.MustParsePrefix("10.0.1.0/24"))}},
+	}
+	require.NoError(t, iface.syncPeers(peers))
+
+	received, transmitted := iface.programs[0].Fd(), iface.programs[1].Fd()
+	assert.Equal(t, uint32(tcActOk), testRunProgram(t, received, testFrame("10.0.0.2", "192.168.10.5", 100)))
+	testRunProgram(t, received, testFrame("10.0.0.2", "192.168.20.5", 200))
+	testRunProgram(t, received, testFrame("10.0.0.2", "8.8.8.8", 300))
+	testRunProgram(t, transmitted, testFrame("8.8.8.8", "10.0.0.2", 400))
+	testRunProgram(t, received, testFrame("fd00::2", "2001:db8::1", 500))
+	testRunProgram(t, received, testFrame("10.0.1.7", "8.8.8.8", 600))
+	testRunProgram(t, received, testFrame("10.9.9.9", "8.8.8.8", 700))                       // unknown peer
+	testRunProgram(t, received, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 0x45}) // truncated
+
+	counters, err := iface.readCounters()
+	require.NoError(t, err)
+	byKey := make(map[[3]uint32]accountingCounter)
+	for _, counter := range counters {
+		byKey[[3]uint32{counter.peer, counter.subnet, counter.direction}] = counter
+	}
+	assert.Len(t, byKey, 6)
+	assert.Equal(t, uint64(100), byKey[[3]uint32{1, 2, 0}].bytes, "most specific accounting subnet")
+	assert.Equal(t, uint64(200), byKey[[3]uint32{1, 1, 0}].bytes)
+	assert.Equal(t, uint64(300), byKey[[3]uint32{1, 0, 0}].bytes, "other destinations")
+	assert.Equal(t, uint64(400), byKey[[3]uint32{1, 0, 1}].bytes, "transmitted to the peer")
+	assert.Equal(t, uint64(500), byKey[[3]uint32{1, 3, 0}].bytes, "IPv6")
+	assert.Equal(t, uint64(600), byKey[[3]uint32{2, 0, 0}].bytes, "allowed IP network")
+	assert.Equal(t, uint64(1), byKey[[3]uint32{2, 0, 0}].packets)
+
+	require.NoError(t, iface.syncPeers(peers[:1]))
+	counters, err = iface.readCounters()
+	require.NoError(t, err)
+	assert.Len(t, counters, 5, "counters of removed peers are deleted")
+	testRunProgram(t, received, testFrame("10.0.1.7", "8.8.8.8", 600))
+	counters, err = iface.readCounters()
+	require.NoError(t, err)
+	assert.Len(t, counters, 5, "allowed IPs of removed peers are not counted")
+}
@@ -0,0 +1,259 @@
+//go:build linux
+
+package adapters
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/h44z/wg-portal/internal/lowlevel"
+)
+
+// eBPF registers, see the kernel documentation of the eBPF instruction set.
+const (
+	bpfR0 uint8 = iota // return value of calls and of the program
+	bpfR1              // arguments of calls, r1 is the context on program entry
+	bpfR2
+	bpfR3
+	bpfR4
+	bpfR5
+	bpfR6 // callee saved registers
+	bpfR7
+	bpfR8
+	bpfR9
+	bpfR10 // read-only frame pointer
+)
+
+const (
+	bpfFuncMapLookupElem = 1
+	bpfFuncMapUpdateElem = 2
+	bpfPseudoMapFd       = 1
+	tcActOk              = 0
+
+	// offsets of the fields of struct __sk_buff
+	skbLen     = 0
+	skbData    = 76
+	skbDataEnd = 80
+)
+
+// Stack layout of the accounting program, relative to the frame pointer.
+const (
+	stackPeerKey    = -24 // LPM key of the address of the peer
+	stackRemoteKey  = -48 // LPM key of the address of the other end
+	stackCounterKey = -64 // peer index, subnet index, direction
+	stackZeroValue  = -80 // initial counter value
+)
+
+// accountingProgram returns a tc classifier that adds the length of each IP packet to the counter of its peer,
+// accounting subnet and direction. The peer is looked up by the source address of received packets, or by the
+// destination address of transmitted packets. Packets of unknown peers are not counted. All packets pass.
+func accountingProgram(direction uint32, l3Offset, peersFd, subnetsFd, countersFd int) ([]lowlevel.BpfInstruction,
+	error) {
+	peerOffset4, remoteOffset4 := int16(12), int16(16) // IPv4 source and destination address
+	peerOffset6, remoteOffset6 := int16(8), int16(24)  // IPv6 source and destination address
+	if direction == accountingDirectionTransmitted {
+		peerOffset4, remoteOffset4 = remoteOffset4, peerOffset4
+		peerOffset6, remoteOffset6 = remoteOffset6, peerOffset6
+	}
+	l3 := int16(l3Offset)
+	mapped := int32(binary.NativeEndian.Uint32([]byte{0, 0, 0xff, 0xff})) // the IPv4-mapped address prefix
+
+	a := &bpfAssembler{labels: make(map[string]int)}
+	a.emit(bpfMovReg(bpfR6, bpfR1))
+	a.emit(bpfLoad(unix.BPF_W, bpfR7, bpfR6, skbLen))
+	a.emit(bpfLoad(unix.BPF_W, bpfR2, bpfR6, skbData))
+	a.emit(bpfLoad(unix.BPF_W, bpfR3, bpfR6, skbDataEnd))
+	if l3 > 0 {
+		a.emit(bpfAddImm(bpfR7, -int32(l3))) // count the length of the IP packet
+	}
+
+	// the packet must contain at least an IPv4 header
+	a.emit(bpfMovReg(bpfR4, bpfR2))
+	a.emit(bpfAddImm(bpfR4, int32(l3)+20))
+	a.jumpReg(unix.BPF_JGT, bpfR4, bpfR3, "out")
+	a.emit(bpfLoad(unix.BPF_B, bpfR5, bpfR2, l3))
+	a.emit(bpfRshImm(bpfR5, 4))
+	a.jump(unix.BPF_JEQ, bpfR5, 4, "ipv4")
+	a.jump(unix.BPF_JNE, bpfR5, 6, "out")
+
+	// IPv6, the packet must contain the full header
+	a.emit(bpfMovReg(bpfR4, bpfR2))
+	a.emit(bpfAddImm(bpfR4, int32(l3)+40))
+	a.jumpReg(unix.BPF_JGT, bpfR4, bpfR3, "out")
+	a.emit(bpfStoreImm(unix.BPF_W, bpfR10, stackPeerKey, 128))
+	a.emit(bpfStoreImm(unix.BPF_W, bpfR10, stackRemoteKey, 128))
+	for word := int16(0); word < 4; word++ {
+		a.emit(bpfLoad(unix.BPF_W, bpfR5, bpfR2, l3+peerOffset6+4*word))
+		a.emit(bpfStore(unix.BPF_W, bpfR10, bpfR5, stackPeerKey+4+4*word))
+		a.emit(bpfLoad(unix.BPF_W, bpfR5, bpfR2, l3+remoteOffset6+4*word))
+		a.emit(bpfStore(unix.BPF_W, bpfR10, bpfR5, stackRemoteKey+4+4*word))
+	}
+	a.jump(unix.BPF_JA, 0, 0, "lookup")
+
+	// IPv4, the addresses are stored IPv4-mapped
+	a.label("ipv4")
+	for _, key := range []int16{stackPeerKey, stackRemoteKey} {
+		a.emit(bpfStoreImm(unix.BPF_W, bpfR10, key, 128))
+		a.emit(bpfStoreImm(unix.BPF_W, bpfR10, key+4, 0))
+		a.emit(bpfStoreImm(unix.BPF_W, bpfR10, key+8, 0))
+		a.emit(bpfStoreImm(unix.BPF_W, bpfR10, key+12, mapped))
+	}
+	a.emit(bpfLoad(unix.BPF_W, bpfR5, bpfR2, l3+peerOffset4))
+	a.emit(bpfStore(unix.BPF_W, bpfR10, bpfR5, stackPeerKey+16))
+	a.emit(bpfLoad(unix.BPF_W, bpfR5, bpfR2, l3+remoteOffset4))
+	a.emit(bpfStore(unix.BPF_W, bpfR10, bpfR5, stackRemoteKey+16))
+
+	// r8 = peer index, packets of unknown peers are not counted
+	a.label("lookup")
+	a.lookup(peersFd, stackPeerKey)
+	a.jump(unix.BPF_JEQ, bpfR0, 0, "out")
+	a.emit(bpfLoad(unix.BPF_W, bpfR8, bpfR0, 0))
+
+	// r9 = subnet index, 0 for destinations outside of all accounting subnets
+	a.lookup(subnetsFd, stackRemoteKey)
+	a.emit(bpfMovImm(bpfR9, 0))
+	a.jump(unix.BPF_JEQ, bpfR0, 0, "count")
+	a.emit(bpfLoad(unix.BPF_W, bpfR9, bpfR0, 0))
+
+	a.label("count")
+	a.emit(bpfStore(unix.BPF_W, bpfR10, bpfR8, stackCounterKey))
+	a.emit(bpfStore(unix.BPF_W, bpfR10, bpfR9, stackCounterKey+4))
+	a.emit(bpfStoreImm(unix.BPF_W, bpfR10, stackCounterKey+8, int32(direction)))
+	a.lookup(countersFd, stackCounterKey)
+	a.jump(unix.BPF_JNE, bpfR0, 0, "add")
+
+	// create the counter, concurrent programs may have created it in the meantime
+	a.emit(bpfStoreImm(unix.BPF_DW, bpfR10, stackZeroValue, 0))
+	a.emit(bpfStoreImm(unix.BPF_DW, bpfR10, stackZeroValue+8, 0))
+	a.loadMap(bpfR1, countersFd)
+	a.emit(bpfMovReg(bpfR2, bpfR10))
+	a.emit(bpfAddImm(bpfR2, stackCounterKey))
+	a.emit(bpfMovReg(bpfR3, bpfR10))
+	a.emit(bpfAddImm(bpfR3, stackZeroValue))
+	a.emit(bpfMovImm(bpfR4, unix.BPF_NOEXIST))
+	a.emit(bpfCall(bpfFuncMapUpdateElem))
+	a.lookup(countersFd, stackCounterKey)
+	a.jump(unix.BPF_JEQ, bpfR0, 0, "out")
+
+	a.label("add")
+	a.emit(bpfAtomicAdd(bpfR0, bpfR7, 0))
+	a.emit(bpfMovImm(bpfR1, 1))
+	a.emit(bpfAtomicAdd(bpfR0, bpfR1, 8))
+
+	a.label("out")
+	a.emit(bpfMovImm(bpfR0, tcActOk))
+	a.emit(lowlevel.BpfInstruction{OpCode: unix.BPF_JMP | unix.BPF_EXIT})
+
+	return a.assemble()
+}
+
+// bpfAssembler builds eBPF programs with labeled jump targets.
+type bpfAssembler struct {
+	instructions []lowlevel.BpfInstruction
+	labels       map[string]int // the index of the instruction that follows the label
+	jumps        map[int]string // the target labels of the jump instructions, by instruction index
+}
+
+func (a *bpfAssembler) emit(instruction lowlevel.BpfInstruction) {
+	a.instructions = append(a.instructions, instruction)
+}
+
+func (a *bpfAssembler) label(name string) {
+	a.labels[name] = len(a.instructions)
+}
+
+// jump emits a conditional jump that compares the register with the immediate value.
+func (a *bpfAssembler) jump(op uint8, dst uint8, imm int32, label string) {
+	a.addJump(label)
+	a.emit(lowlevel.BpfInstruction{OpCode: unix.BPF_JMP | op | unix.BPF_K, Dst: dst, Imm: imm})
+}
+
+// jumpReg emits a conditional jump that compares two registers.
+func (a *bpfAssembler) jumpReg(op uint8, dst, src uint8, label string) {
+	a.addJump(label)
+	a.emit(lowlevel.BpfInstruction{OpCode: unix.BPF_JMP | op | unix.BPF_X, Dst: dst, Src: src})
+}
+
+func (a *bpfAssembler) addJump(label string) {
+	if a.jumps == nil {
+		a.jumps = make(map[int]string)
+	}
+	a.jumps[len(a.instructions)] = label
+}
+
+// loadMap emits the 64-bit load of the map with the given file descriptor, which spans two instructions.
+func (a *bpfAssembler) loadMap(dst uint8, fd int) {
+	a.emit(lowlevel.BpfInstruction{
+		OpCode: unix.BPF_LD | unix.BPF_DW | unix.BPF_IMM,
+		Dst:    dst,
+		Src:    bpfPseudoMapFd,
+		Imm:    int32(fd),
+	})
+	a.emit(lowlevel.BpfInstruction{})
+}
+
+// lookup emits a map lookup of the key on the stack, r0 is the pointer to the value or 0.
+func (a *bpfAssembler) lookup(fd int, stackKey int16) {
+	a.loadMap(bpfR1, fd)
+	a.emit(bpfMovReg(bpfR2, bpfR10))
+	a.emit(bpfAddImm(bpfR2, int32(stackKey)))
+	a.emit(bpfCall(bpfFuncMapLookupElem))
+}
+
+// assemble resolves the jump targets and returns the instructions.
+func (a *bpfAssembler) assemble() ([]lowlevel.BpfInstruction, error) {
+	for index, label := range a.jumps {
+		target, ok := a.labels[label]
+		if !ok {
+			return nil, fmt.Errorf("undefined bpf label %s", label)
+		}
+		a.instructions[index].Off = int16(target - index - 1)
+	}
+
+	return a.instructions, nil
+}
+
+func bpfMovReg(dst, src uint8) lowlevel.BpfInstruction {
+	return lowlevel.BpfInstruction{OpCode: unix.BPF_ALU64 | unix.BPF_MOV | unix.BPF_X, Dst: dst, Src: src}
+}
+
+func bpfMovImm(dst uint8, imm int32) lowlevel.BpfInstruction {
+	return lowlevel.BpfInstruction{OpCode: unix.BPF_ALU64 | unix.BPF_MOV | unix.BPF_K, Dst: dst, Imm: imm}
+}
+
+func bpfAddImm(dst uint8, imm int32) lowlevel.BpfInstruction {
+	return lowlevel.BpfInstruction{OpCode: unix.BPF_ALU64 | unix.BPF_ADD | unix.BPF_K, Dst: dst, Imm: imm}
+}
+
+func bpfRshImm(dst uint8, imm int32) lowlevel.BpfInstruction {
+	return lowlevel.BpfInstruction{OpCode: unix.BPF_ALU64 | unix.BPF_RSH | unix.BPF_K, Dst: dst, Imm: imm}
+}
+
+func bpfLoad(size uint8, dst, src uint8, off int16) lowlevel.BpfInstruction {
+	return lowlevel.BpfInstruction{OpCode: unix.BPF_LDX | unix.BPF_MEM | size, Dst: dst, Src: src, Off: off}
+}
+
+func bpfStore(size uint8, dst, src uint8, off int16) lowlevel.BpfInstruction {
+	return lowlevel.BpfInstruction{OpCode: unix.BPF_STX | unix.BPF_MEM | size, Dst: dst, Src: src, Off: off}
+}
+
+func bpfStoreImm(size uint8, dst uint8, off int16, imm int32) lowlevel.BpfInstruction {
+	return lowlevel.BpfInstruction{OpCode: unix.BPF_ST | unix.BPF_MEM | size, Dst: dst, Off: off, Imm: imm}
+}
+
+// bpfAtomicAdd adds the source register to the 64-bit value at dst + off.
+func bpfAtomicAdd(dst, src uint8, off int16) lowlevel.BpfInstruction {
+	return lowlevel.BpfInstruction{
+		OpCode: unix.BPF_STX | unix.BPF_XADD | unix.BPF_DW,
+		Dst:    dst,
+		Src:    src,
+		Off:    off,
+		Imm:    unix.BPF_ADD,
+	}
+}
+
+func bpfCall(fn int32) lowlevel.BpfInstruction {
+	return lowlevel.BpfInstruction{OpCode: unix.BPF_JMP | unix.BPF_CALL, Imm: fn}
+}
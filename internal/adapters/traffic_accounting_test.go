@@ -0,0 +1,63 @@
+//go:build linux
+
+package adapters
+
+import (
+	"context"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/h44z/wg-portal/internal/config"
+)
+
+func TestNewEbpfAccountingRepo_disabled(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Statistics.AccountingBackend = config.AccountingBackendWireGuard
+
+	repo, err := NewEbpfAccountingRepo(cfg)
+	require.NoError(t, err)
+
+	_, err = repo.GetPeerTraffic(context.Background(), "wg0", nil)
+	assert.Error(t, err)
+}
+
+func TestNewEbpfAccountingRepo_bandwidthShaping(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Statistics.AccountingBackend = config.AccountingBackendEbpf
+	cfg.Advanced.BandwidthShaping = true
+
+	_, err := NewEbpfAccountingRepo(cfg)
+	assert.Error(t, err)
+}
+
+func TestLpmKey(t *testing.T) {
+	key := lpmKey(netip.MustParsePrefix("10.1.0.0/16"))
+	require.Len(t, key, lpmKeySize)
+	assert.Equal(t, uint32(112), binary.NativeEndian.Uint32(key))
+	assert.Equal(t, netip.MustParseAddr("::ffff:10.1.0.0").As16(), [16]byte(key[4:]))
+
+	key = lpmKey(netip.MustParsePrefix("fd00::/8"))
+	assert.Equal(t, uint32(8), binary.NativeEndian.Uint32(key))
+	assert.Equal(t, netip.MustParseAddr("fd00::").As16(), [16]byte(key[4:]))
+}
+
+func TestBpfAssembler_jumps(t *testing.T) {
+	a := &bpfAssembler{labels: make(map[string]int)}
+	a.jump(unix.BPF_JEQ, bpfR1, 0, "out")
+	a.emit(bpfMovImm(bpfR0, 1))
+	a.label("out")
+	a.emit(bpfMovImm(bpfR0, 0))
+
+	instructions, err := a.assemble()
+	require.NoError(t, err)
+	assert.Equal(t, int16(1), instructions[0].Off)
+
+	a.jump(unix.BPF_JEQ, bpfR1, 0, "missing")
+	_, err = a.assemble()
+	assert.Error(t, err)
+}
@@ -1,28 +1,37 @@
+//go:build linux
+
 package adapters
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/netip"
 	"os"
 
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 	"golang.zx2c4.com/wireguard/wgctrl"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 
+	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
 	"github.com/h44z/wg-portal/internal/lowlevel"
 )
 
 // WgRepo implements all low-level WireGuard interactions.
 type WgRepo struct {
-	wg lowlevel.WireGuardClient
-	nl lowlevel.NetlinkClient
+	wgctrlRepo
+
+	nl        lowlevel.NetlinkClient
+	userspace *userspaceSupervisor
 }
 
 // NewWireGuardRepository creates a new WgRepo instance.
 // This repository is used to interact with the WireGuard kernel or userspace module.
-func NewWireGuardRepository() *WgRepo {
+// If the kernel module is not available, interfaces are created using the configured userspace implementation.
+func NewWireGuardRepository(cfg *config.Config) *WgRepo {
 	wg, err := wgctrl.New()
 	if err != nil {
 		panic("failed to init wgctrl: " + err.Error())
@@ -31,9 +40,11 @@ func NewWireGuardRepository() *WgRepo {
 	nl := &lowlevel.NetlinkManager{}
 
 	repo := &WgRepo{
-		wg: wg,
-		nl: nl,
+		wgctrlRepo: wgctrlRepo{wg: wg},
+		nl:         nl,
+		userspace:  newUserspaceSupervisor(cfg.Advanced.UserspaceImplementation),
 	}
+	repo.userspace.restore = repo.restoreUserspaceInterface
 
 	return repo
 }
@@ -63,34 +74,46 @@ func (r *WgRepo) GetInterface(_ context.Context, id domain.InterfaceIdentifier)
 	return r.getInterface(id)
 }
 
-// GetPeers returns all peers associated with the given interface id.
-// If the requested interface is found, an error os.ErrNotExist is returned.
-func (r *WgRepo) GetPeers(_ context.Context, deviceId domain.InterfaceIdentifier) ([]domain.PhysicalPeer, error) {
-	device, err := r.wg.Device(string(deviceId))
-	if err != nil {
-		return nil, fmt.Errorf("device error: %w", err)
-	}
-
-	peers := make([]domain.PhysicalPeer, 0, len(device.Peers))
-	for _, peer := range device.Peers {
-		peerModel, err := r.convertWireGuardPeer(&peer)
-		if err != nil {
-			return nil, fmt.Errorf("peer convert failed for %v: %w", peer.PublicKey, err)
+// SubscribeLinkUpdates subscribes to the netlink link events of the kernel. The returned channel receives the
+// identifier of a WireGuard interface whenever its link changes, for example, if the interface is created, removed,
+// or set up or down. The channel is closed if the context is done or the subscription fails.
+func (r *WgRepo) SubscribeLinkUpdates(ctx context.Context) (<-chan domain.InterfaceIdentifier, error) {
+	updates := make(chan netlink.LinkUpdate, 16)
+	done := make(chan struct{})
+	if err := r.nl.LinkSubscribe(updates, done); err != nil {
+		return nil, fmt.Errorf("link subscription error: %w", err)
+	}
+
+	ids := make(chan domain.InterfaceIdentifier, 16)
+	go func() {
+		defer close(ids)
+		defer close(done)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+				if update.Link == nil {
+					continue
+				}
+				name := domain.InterfaceIdentifier(update.Link.Attrs().Name)
+				if update.Link.Type() != "wireguard" && !r.userspace.IsManaged(name) {
+					continue
+				}
+				select {
+				case ids <- name:
+				case <-ctx.Done():
+					return
+				}
+			}
 		}
-		peers = append(peers, peerModel)
-	}
+	}()
 
-	return peers, nil
-}
-
-// GetPeer returns the peer with the given id.
-// If the requested interface or peer is found, an error os.ErrNotExist is returned.
-func (r *WgRepo) GetPeer(
-	_ context.Context,
-	deviceId domain.InterfaceIdentifier,
-	id domain.PeerIdentifier,
-) (*domain.PhysicalPeer, error) {
-	return r.getPeer(deviceId, id)
+	return ids, nil
 }
 
 func (r *WgRepo) convertWireGuardInterface(device *wgtypes.Device) (domain.PhysicalInterface, error) {
@@ -137,35 +160,6 @@ func (r *WgRepo) convertWireGuardInterface(device *wgtypes.Device) (domain.Physi
 	return iface, nil
 }
 
-func (r *WgRepo) convertWireGuardPeer(peer *wgtypes.Peer) (domain.PhysicalPeer, error) {
-	peerModel := domain.PhysicalPeer{
-		Identifier: domain.PeerIdentifier(peer.PublicKey.String()),
-		Endpoint:   "",
-		AllowedIPs: nil,
-		KeyPair: domain.KeyPair{
-			PublicKey: peer.PublicKey.String(),
-		},
-		PresharedKey:        "",
-		PersistentKeepalive: int(peer.PersistentKeepaliveInterval.Seconds()),
-		LastHandshake:       peer.LastHandshakeTime,
-		ProtocolVersion:     peer.ProtocolVersion,
-		BytesUpload:         uint64(peer.ReceiveBytes),
-		BytesDownload:       uint64(peer.TransmitBytes),
-	}
-
-	for _, addr := range peer.AllowedIPs {
-		peerModel.AllowedIPs = append(peerModel.AllowedIPs, domain.CidrFromIpNet(addr))
-	}
-	if peer.Endpoint != nil {
-		peerModel.Endpoint = peer.Endpoint.String()
-	}
-	if peer.PresharedKey != (wgtypes.Key{}) {
-		peerModel.PresharedKey = domain.PreSharedKey(peer.PresharedKey.String())
-	}
-
-	return peerModel, nil
-}
-
 // SaveInterface updates the interface with the given id.
 // If no existing interface is found, a new interface is created.
 // Updating the interface does not interrupt any existing connections.
@@ -192,6 +186,7 @@ func (r *WgRepo) SaveInterface(
 	if err := r.updateWireGuardInterface(physicalInterface); err != nil {
 		return err
 	}
+	r.userspace.RememberInterface(physicalInterface)
 
 	return nil
 }
@@ -232,6 +227,11 @@ func (r *WgRepo) createLowLevelInterface(id domain.InterfaceIdentifier) error {
 		LinkType: "wireguard",
 	}
 	err := r.nl.LinkAdd(link)
+	if errors.Is(err, unix.EOPNOTSUPP) && r.userspace.Enabled() {
+		slog.Info("WireGuard kernel module not available, using userspace implementation",
+			"interface", id, "implementation", r.userspace.command)
+		return r.userspace.Start(id)
+	}
 	if err != nil {
 		return fmt.Errorf("link add failed: %w", err)
 	}
@@ -296,33 +296,12 @@ func (r *WgRepo) updateLowLevelInterface(pi *domain.PhysicalInterface) error {
 	return nil
 }
 
-func (r *WgRepo) updateWireGuardInterface(pi *domain.PhysicalInterface) error {
-	pKey, err := wgtypes.NewKey(pi.KeyPair.GetPrivateKeyBytes())
-	if err != nil {
-		return err
-	}
-
-	var fwMark *int
-	if pi.FirewallMark != 0 {
-		intFwMark := int(pi.FirewallMark)
-		fwMark = &intFwMark
-	}
-	err = r.wg.ConfigureDevice(string(pi.Identifier), wgtypes.Config{
-		PrivateKey:   &pKey,
-		ListenPort:   &pi.ListenPort,
-		FirewallMark: fwMark,
-		ReplacePeers: false,
-	})
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 // DeleteInterface deletes the interface with the given id.
 // If the requested interface is found, no error is returned.
 func (r *WgRepo) DeleteInterface(_ context.Context, id domain.InterfaceIdentifier) error {
+	if err := r.userspace.Stop(id); err != nil {
+		return err
+	}
 	if err := r.deleteLowLevelInterface(id); err != nil {
 		return err
 	}
@@ -351,127 +330,103 @@ func (r *WgRepo) deleteLowLevelInterface(id domain.InterfaceIdentifier) error {
 // SavePeer updates the peer with the given id.
 // If no existing peer is found, a new peer is created.
 func (r *WgRepo) SavePeer(
-	_ context.Context,
+	ctx context.Context,
 	deviceId domain.InterfaceIdentifier,
 	id domain.PeerIdentifier,
 	updateFunc func(pp *domain.PhysicalPeer) (*domain.PhysicalPeer, error),
 ) error {
-	physicalPeer, err := r.getOrCreatePeer(deviceId, id)
+	var physicalPeer *domain.PhysicalPeer
+	err := r.wgctrlRepo.SavePeer(ctx, deviceId, id, func(pp *domain.PhysicalPeer) (*domain.PhysicalPeer, error) {
+		var err error
+		physicalPeer, err = updateFunc(pp)
+		return physicalPeer, err
+	})
 	if err != nil {
 		return err
 	}
+	r.userspace.RememberPeer(deviceId, physicalPeer)
 
-	physicalPeer, err = updateFunc(physicalPeer)
-	if err != nil {
-		return err
-	}
+	return nil
+}
 
-	if err := r.updatePeer(deviceId, physicalPeer); err != nil {
+// DeletePeer deletes the peer with the given id.
+// If the requested interface or peer is found, no error is returned.
+func (r *WgRepo) DeletePeer(ctx context.Context, deviceId domain.InterfaceIdentifier, id domain.PeerIdentifier) error {
+	if err := r.wgctrlRepo.DeletePeer(ctx, deviceId, id); err != nil {
 		return err
 	}
+	r.userspace.ForgetPeer(deviceId, id)
 
 	return nil
 }
 
-func (r *WgRepo) getOrCreatePeer(deviceId domain.InterfaceIdentifier, id domain.PeerIdentifier) (
-	*domain.PhysicalPeer,
-	error,
-) {
-	peer, err := r.getPeer(deviceId, id)
-	if err == nil {
-		return peer, nil // peer exists
+// ApplyPeers creates or updates the given peers and removes the peers with the given ids in a single device
+// configuration call.
+func (r *WgRepo) ApplyPeers(
+	ctx context.Context,
+	deviceId domain.InterfaceIdentifier,
+	peers []domain.PhysicalPeer,
+	removed []domain.PeerIdentifier,
+) error {
+	if err := r.wgctrlRepo.ApplyPeers(ctx, deviceId, peers, removed); err != nil {
+		return err
 	}
-	if !errors.Is(err, os.ErrNotExist) {
-		return nil, fmt.Errorf("peer error: %w", err) // unknown error
+	for _, id := range removed {
+		r.userspace.ForgetPeer(deviceId, id)
 	}
-
-	// create new peer
-	err = r.wg.ConfigureDevice(string(deviceId), wgtypes.Config{
-		Peers: []wgtypes.PeerConfig{
-			{
-				PublicKey: id.ToPublicKey(),
-			},
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("peer create error for %s: %w", id.ToPublicKey(), err)
+	for i := range peers {
+		r.userspace.RememberPeer(deviceId, &peers[i])
 	}
 
-	peer, err = r.getPeer(deviceId, id)
-	if err != nil {
-		return nil, fmt.Errorf("peer error after create: %w", err)
-	}
-	return peer, nil
+	return nil
 }
 
-func (r *WgRepo) getPeer(deviceId domain.InterfaceIdentifier, id domain.PeerIdentifier) (*domain.PhysicalPeer, error) {
-	if !id.IsPublicKey() {
-		return nil, errors.New("invalid public key")
+// restoreUserspaceInterface applies the interface and peer configuration to a restarted userspace interface.
+func (r *WgRepo) restoreUserspaceInterface(pi *domain.PhysicalInterface, peers []domain.PhysicalPeer) error {
+	if err := r.updateLowLevelInterface(pi); err != nil {
+		return err
 	}
-
-	device, err := r.wg.Device(string(deviceId))
-	if err != nil {
-		return nil, err
+	if err := r.updateWireGuardInterface(pi); err != nil {
+		return err
 	}
-
-	publicKey := id.ToPublicKey()
-	for _, peer := range device.Peers {
-		if peer.PublicKey != publicKey {
-			continue
-		}
-
-		peerModel, err := r.convertWireGuardPeer(&peer)
-		return &peerModel, err
+	if err := r.wgctrlRepo.ApplyPeers(context.Background(), pi.Identifier, peers, nil); err != nil {
+		return fmt.Errorf("failed to restore peers: %w", err)
 	}
 
-	return nil, os.ErrNotExist
+	return nil
 }
 
-func (r *WgRepo) updatePeer(deviceId domain.InterfaceIdentifier, pp *domain.PhysicalPeer) error {
-	cfg := wgtypes.PeerConfig{
-		PublicKey:                   pp.GetPublicKey(),
-		Remove:                      false,
-		UpdateOnly:                  true,
-		PresharedKey:                pp.GetPresharedKey(),
-		Endpoint:                    pp.GetEndpointAddress(),
-		PersistentKeepaliveInterval: pp.GetPersistentKeepaliveTime(),
-		ReplaceAllowedIPs:           true,
-		AllowedIPs:                  pp.GetAllowedIPs(),
+// ProbePathMtu returns the MTU of the path to the given destination, based on the MTU of the outgoing interface
+// and the path MTU that is known by the kernel. If the destination is invalid, the path of the default route
+// is probed.
+func (r *WgRepo) ProbePathMtu(_ context.Context, destination netip.Addr) (*domain.PathMtu, error) {
+	probeAddr := destination
+	if !probeAddr.IsValid() {
+		probeAddr = defaultRouteProbeAddr
 	}
 
-	err := r.wg.ConfigureDevice(string(deviceId), wgtypes.Config{ReplacePeers: false, Peers: []wgtypes.PeerConfig{cfg}})
+	routes, err := r.nl.RouteGet(probeAddr.Unmap().AsSlice())
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to look up route to %s: %w", probeAddr, err)
 	}
-
-	return nil
-}
-
-// DeletePeer deletes the peer with the given id.
-// If the requested interface or peer is found, no error is returned.
-func (r *WgRepo) DeletePeer(_ context.Context, deviceId domain.InterfaceIdentifier, id domain.PeerIdentifier) error {
-	if !id.IsPublicKey() {
-		return errors.New("invalid public key")
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no route to %s", probeAddr)
 	}
 
-	err := r.deletePeer(deviceId, id)
+	link, err := r.nl.LinkByIndex(routes[0].LinkIndex)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to find outgoing interface for %s: %w", probeAddr, err)
 	}
 
-	return nil
-}
-
-func (r *WgRepo) deletePeer(deviceId domain.InterfaceIdentifier, id domain.PeerIdentifier) error {
-	cfg := wgtypes.PeerConfig{
-		PublicKey: id.ToPublicKey(),
-		Remove:    true,
+	pathMtu := &domain.PathMtu{
+		Destination: destination,
+		LinkName:    link.Attrs().Name,
+		LinkMtu:     link.Attrs().MTU,
+		Mtu:         link.Attrs().MTU,
 	}
-
-	err := r.wg.ConfigureDevice(string(deviceId), wgtypes.Config{ReplacePeers: false, Peers: []wgtypes.PeerConfig{cfg}})
-	if err != nil {
-		return err
+	if routes[0].MTU > 0 && routes[0].MTU < pathMtu.Mtu {
+		pathMtu.Mtu = routes[0].MTU // route MTU or cached path MTU
 	}
 
-	return nil
+	return pathMtu, nil
 }
@@ -0,0 +1,397 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/h44z/wg-portal/internal/domain"
+	"github.com/h44z/wg-portal/internal/lowlevel"
+)
+
+// bsdWireGuardCloner is the interface cloner of the FreeBSD kernel WireGuard implementation.
+const bsdWireGuardCloner = "wg"
+
+// bsdClonedInterfaceName matches the interface names that can be created by the rc.conf cloned_interfaces variable.
+var bsdClonedInterfaceName = regexp.MustCompile(`^wg[0-9]+$`)
+
+// BsdWgRepo implements all low-level WireGuard interactions on FreeBSD, using the kernel WireGuard implementation
+// (if_wg). If a rc.conf client is set, the interfaces are also stored in rc.conf, so that they are created with
+// their addresses on boot. The keys and peers are not stored in rc.conf, they are restored by WireGuard Portal.
+type BsdWgRepo struct {
+	wgctrlRepo
+
+	nw lowlevel.BsdNetworkClient
+	rc lowlevel.RcConfClient // nil if the interfaces are not stored in rc.conf
+}
+
+// NewBsdWireGuardRepository creates a new BsdWgRepo instance. The rc.conf client is optional.
+func NewBsdWireGuardRepository(
+	wg lowlevel.WireGuardClient,
+	nw lowlevel.BsdNetworkClient,
+	rc lowlevel.RcConfClient,
+) *BsdWgRepo {
+	return &BsdWgRepo{
+		wgctrlRepo: wgctrlRepo{wg: wg},
+		nw:         nw,
+		rc:         rc,
+	}
+}
+
+// GetInterfaces returns all existing WireGuard interfaces.
+func (r *BsdWgRepo) GetInterfaces(_ context.Context) ([]domain.PhysicalInterface, error) {
+	devices, err := r.wg.Devices()
+	if err != nil {
+		return nil, fmt.Errorf("device list error: %w", err)
+	}
+
+	interfaces := make([]domain.PhysicalInterface, 0, len(devices))
+	for _, device := range devices {
+		interfaceModel, err := r.convertWireGuardInterface(device)
+		if err != nil {
+			return nil, fmt.Errorf("interface convert failed for %s: %w", device.Name, err)
+		}
+		interfaces = append(interfaces, interfaceModel)
+	}
+
+	return interfaces, nil
+}
+
+// GetInterface returns the interface with the given id.
+// If no interface is found, an error os.ErrNotExist is returned.
+func (r *BsdWgRepo) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.PhysicalInterface, error) {
+	return r.getInterface(id)
+}
+
+// SubscribeLinkUpdates is not supported on FreeBSD, interface changes are detected by polling.
+func (r *BsdWgRepo) SubscribeLinkUpdates(_ context.Context) (<-chan domain.InterfaceIdentifier, error) {
+	return nil, errors.New("link updates are not supported on FreeBSD")
+}
+
+func (r *BsdWgRepo) convertWireGuardInterface(device *wgtypes.Device) (domain.PhysicalInterface, error) {
+	// read data from wgctrl interface
+
+	iface := domain.PhysicalInterface{
+		Identifier: domain.InterfaceIdentifier(device.Name),
+		KeyPair: domain.KeyPair{
+			PrivateKey: device.PrivateKey.String(),
+			PublicKey:  device.PublicKey.String(),
+		},
+		ListenPort:   device.ListenPort,
+		FirewallMark: uint32(device.FirewallMark),
+		ImportSource: "wgctrl",
+		DeviceType:   device.Type.String(),
+	}
+
+	// read data from ifconfig
+
+	lowLevelInterface, err := r.nw.InterfaceGet(device.Name)
+	if err != nil {
+		return domain.PhysicalInterface{}, fmt.Errorf("ifconfig error for %s: %w", device.Name, err)
+	}
+
+	for _, addr := range lowLevelInterface.Addresses {
+		iface.Addresses = append(iface.Addresses, domain.CidrFromPrefix(addr))
+	}
+	iface.Mtu = lowLevelInterface.Mtu
+	iface.DeviceUp = lowLevelInterface.Up
+	iface.BytesUpload = lowLevelInterface.BytesTransmitted
+	iface.BytesDownload = lowLevelInterface.BytesReceived
+
+	return iface, nil
+}
+
+// SaveInterface updates the interface with the given id.
+// If no existing interface is found, a new interface is created.
+// Updating the interface does not interrupt any existing connections.
+func (r *BsdWgRepo) SaveInterface(
+	_ context.Context,
+	id domain.InterfaceIdentifier,
+	updateFunc func(pi *domain.PhysicalInterface) (*domain.PhysicalInterface, error),
+) error {
+	physicalInterface, err := r.getOrCreateInterface(id)
+	if err != nil {
+		return err
+	}
+
+	if updateFunc != nil {
+		physicalInterface, err = updateFunc(physicalInterface)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := r.updateLowLevelInterface(physicalInterface); err != nil {
+		return err
+	}
+	if err := r.updateWireGuardInterface(physicalInterface); err != nil {
+		return err
+	}
+	if err := r.persistInterface(physicalInterface); err != nil {
+		return fmt.Errorf("failed to store interface %s in rc.conf: %w", id, err)
+	}
+
+	return nil
+}
+
+func (r *BsdWgRepo) getOrCreateInterface(id domain.InterfaceIdentifier) (*domain.PhysicalInterface, error) {
+	device, err := r.getInterface(id)
+	if err == nil {
+		return device, nil // interface exists
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("device error: %w", err) // unknown error
+	}
+
+	// create new device
+	if err := r.nw.InterfaceCreate(bsdWireGuardCloner, string(id)); err != nil {
+		return nil, fmt.Errorf("interface create failed: %w", err)
+	}
+
+	device, err = r.getInterface(id)
+	return device, err
+}
+
+func (r *BsdWgRepo) getInterface(id domain.InterfaceIdentifier) (*domain.PhysicalInterface, error) {
+	device, err := r.wg.Device(string(id))
+	if err != nil {
+		return nil, err
+	}
+
+	pi, err := r.convertWireGuardInterface(device)
+	return &pi, err
+}
+
+func (r *BsdWgRepo) updateLowLevelInterface(pi *domain.PhysicalInterface) error {
+	name := string(pi.Identifier)
+	current, err := r.nw.InterfaceGet(name)
+	if err != nil {
+		return err
+	}
+	if pi.Mtu != 0 && pi.Mtu != current.Mtu {
+		if err := r.nw.InterfaceSetMtu(name, pi.Mtu); err != nil {
+			return fmt.Errorf("mtu error: %w", err)
+		}
+	}
+
+	wanted := make([]netip.Prefix, 0, len(pi.Addresses))
+	for _, addr := range pi.Addresses {
+		wanted = append(wanted, addr.Prefix())
+	}
+	for _, addr := range wanted {
+		if slices.Contains(current.Addresses, addr) {
+			continue
+		}
+		if err := r.nw.AddrAdd(name, addr); err != nil {
+			return fmt.Errorf("failed to set ip %s: %w", addr.String(), err)
+		}
+	}
+
+	// Remove unwanted IP addresses
+	for _, addr := range current.Addresses {
+		if slices.Contains(wanted, addr) {
+			continue
+		}
+		if err := r.nw.AddrDel(name, addr); err != nil {
+			return fmt.Errorf("failed to remove deprecated ip %s: %w", addr.String(), err)
+		}
+	}
+
+	// Update link state
+	if pi.DeviceUp {
+		if err := r.nw.InterfaceSetUp(name); err != nil {
+			return fmt.Errorf("failed to bring up device: %w", err)
+		}
+	} else {
+		if err := r.nw.InterfaceSetDown(name); err != nil {
+			return fmt.Errorf("failed to bring down device: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteInterface deletes the interface with the given id.
+// If the requested interface is found, no error is returned.
+func (r *BsdWgRepo) DeleteInterface(_ context.Context, id domain.InterfaceIdentifier) error {
+	err := r.nw.InterfaceDestroy(string(id))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete low level interface: %w", err)
+	}
+
+	if err := r.unpersistInterface(id); err != nil {
+		return fmt.Errorf("failed to remove interface %s from rc.conf: %w", id, err)
+	}
+
+	return nil
+}
+
+// ProbePathMtu returns the MTU of the path to the given destination, based on the MTU of the outgoing interface
+// and the MTU of the route. If the destination is invalid, the path of the default route is probed.
+func (r *BsdWgRepo) ProbePathMtu(_ context.Context, destination netip.Addr) (*domain.PathMtu, error) {
+	probeAddr := destination
+	if !probeAddr.IsValid() {
+		probeAddr = defaultRouteProbeAddr
+	}
+
+	route, err := r.nw.RouteGet(probeAddr.Unmap())
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up route to %s: %w", probeAddr, err)
+	}
+
+	link, err := r.nw.InterfaceGet(route.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find outgoing interface for %s: %w", probeAddr, err)
+	}
+
+	pathMtu := &domain.PathMtu{
+		Destination: destination,
+		LinkName:    link.Name,
+		LinkMtu:     link.Mtu,
+		Mtu:         link.Mtu,
+	}
+	if route.Mtu > 0 && route.Mtu < pathMtu.Mtu {
+		pathMtu.Mtu = route.Mtu
+	}
+
+	return pathMtu, nil
+}
+
+// persistInterface stores the interface with its addresses and MTU in rc.conf. The first IPv4 and IPv6 addresses
+// are stored in the ifconfig_<name> and ifconfig_<name>_ipv6 variables, all other addresses as aliases.
+func (r *BsdWgRepo) persistInterface(pi *domain.PhysicalInterface) error {
+	if r.rc == nil {
+		return nil
+	}
+	name := string(pi.Identifier)
+	if !bsdClonedInterfaceName.MatchString(name) {
+		slog.Warn("interface is not stored in rc.conf, only interfaces named wg<number> can be created on boot",
+			"interface", name)
+		return nil
+	}
+
+	if err := r.updateClonedInterfaces(name, true); err != nil {
+		return err
+	}
+
+	var primary, primaryV6 string
+	var aliases []string
+	for _, addr := range pi.Addresses {
+		switch {
+		case addr.IsV4() && primary == "":
+			primary = "inet " + addr.String()
+		case !addr.IsV4() && primaryV6 == "":
+			primaryV6 = "inet6 " + addr.String()
+		case addr.IsV4():
+			aliases = append(aliases, "inet "+addr.String())
+		default:
+			aliases = append(aliases, "inet6 "+addr.String())
+		}
+	}
+
+	var ifconfigArgs []string
+	if primary != "" {
+		ifconfigArgs = append(ifconfigArgs, primary)
+	}
+	if pi.Mtu != 0 {
+		ifconfigArgs = append(ifconfigArgs, "mtu", strconv.Itoa(pi.Mtu))
+	}
+	if len(ifconfigArgs) == 0 {
+		ifconfigArgs = append(ifconfigArgs, "up")
+	}
+
+	if err := r.rc.Set(rcIfconfigVariable(name, ""), strings.Join(ifconfigArgs, " ")); err != nil {
+		return err
+	}
+	if primaryV6 != "" {
+		if err := r.rc.Set(rcIfconfigVariable(name, "_ipv6"), primaryV6); err != nil {
+			return err
+		}
+	} else if err := r.rc.Unset(rcIfconfigVariable(name, "_ipv6")); err != nil {
+		return err
+	}
+	for i, alias := range aliases {
+		if err := r.rc.Set(rcIfconfigVariable(name, "_alias"+strconv.Itoa(i)), alias); err != nil {
+			return err
+		}
+	}
+
+	return r.unsetRcAliases(name, len(aliases))
+}
+
+// unpersistInterface removes the interface from rc.conf.
+func (r *BsdWgRepo) unpersistInterface(id domain.InterfaceIdentifier) error {
+	if r.rc == nil {
+		return nil
+	}
+	name := string(id)
+	if !bsdClonedInterfaceName.MatchString(name) {
+		return nil // never stored
+	}
+
+	if err := r.updateClonedInterfaces(name, false); err != nil {
+		return err
+	}
+	if err := r.rc.Unset(rcIfconfigVariable(name, "")); err != nil {
+		return err
+	}
+	if err := r.rc.Unset(rcIfconfigVariable(name, "_ipv6")); err != nil {
+		return err
+	}
+
+	return r.unsetRcAliases(name, 0)
+}
+
+// updateClonedInterfaces adds the interface to or removes the interface from the cloned_interfaces variable.
+func (r *BsdWgRepo) updateClonedInterfaces(name string, add bool) error {
+	value, err := r.rc.Get("cloned_interfaces")
+	if err != nil {
+		return err
+	}
+	interfaces := strings.Fields(value)
+	contained := slices.Contains(interfaces, name)
+
+	switch {
+	case add && !contained:
+		interfaces = append(interfaces, name)
+	case !add && contained:
+		interfaces = slices.DeleteFunc(interfaces, func(s string) bool { return s == name })
+	default:
+		return nil // nothing changed
+	}
+
+	if len(interfaces) == 0 {
+		return r.rc.Unset("cloned_interfaces")
+	}
+	return r.rc.Set("cloned_interfaces", strings.Join(interfaces, " "))
+}
+
+// unsetRcAliases removes the alias variables of the interface, starting with the given alias number.
+func (r *BsdWgRepo) unsetRcAliases(name string, from int) error {
+	for i := from; ; i++ {
+		variable := rcIfconfigVariable(name, "_alias"+strconv.Itoa(i))
+		value, err := r.rc.Get(variable)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			return nil // aliases must be numbered consecutively
+		}
+		if err := r.rc.Unset(variable); err != nil {
+			return err
+		}
+	}
+}
+
+func rcIfconfigVariable(name, suffix string) string {
+	return "ifconfig_" + name + suffix
+}
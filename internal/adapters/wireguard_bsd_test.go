@@ -0,0 +1,229 @@
+package adapters
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"github.com/h44z/wg-portal/internal/domain"
+	"github.com/h44z/wg-portal/internal/lowlevel"
+)
+
+type fakeBsdWireGuardClient struct {
+	devices map[string]*wgtypes.Device
+}
+
+func (f *fakeBsdWireGuardClient) Close() error { return nil }
+
+func (f *fakeBsdWireGuardClient) Devices() ([]*wgtypes.Device, error) {
+	var devices []*wgtypes.Device
+	for _, device := range f.devices {
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+func (f *fakeBsdWireGuardClient) Device(name string) (*wgtypes.Device, error) {
+	device, ok := f.devices[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return device, nil
+}
+
+func (f *fakeBsdWireGuardClient) ConfigureDevice(name string, cfg wgtypes.Config) error {
+	device, ok := f.devices[name]
+	if !ok {
+		return os.ErrNotExist
+	}
+	if cfg.ListenPort != nil {
+		device.ListenPort = *cfg.ListenPort
+	}
+	return nil
+}
+
+type fakeBsdNetworkClient struct {
+	lowlevel.BsdNetworkClient
+
+	wg         *fakeBsdWireGuardClient
+	interfaces map[string]*lowlevel.BsdInterface
+	routes     map[string][]lowlevel.BsdRoute
+}
+
+func (f *fakeBsdNetworkClient) InterfaceCreate(_, name string) error {
+	f.interfaces[name] = &lowlevel.BsdInterface{Name: name, Mtu: 1420}
+	f.wg.devices[name] = &wgtypes.Device{Name: name}
+	return nil
+}
+
+func (f *fakeBsdNetworkClient) InterfaceDestroy(name string) error {
+	if _, ok := f.interfaces[name]; !ok {
+		return os.ErrNotExist
+	}
+	delete(f.interfaces, name)
+	delete(f.wg.devices, name)
+	return nil
+}
+
+func (f *fakeBsdNetworkClient) InterfaceGet(name string) (*lowlevel.BsdInterface, error) {
+	iface, ok := f.interfaces[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	copied := *iface
+	return &copied, nil
+}
+
+func (f *fakeBsdNetworkClient) InterfaceSetMtu(name string, mtu int) error {
+	f.interfaces[name].Mtu = mtu
+	return nil
+}
+
+func (f *fakeBsdNetworkClient) InterfaceSetUp(name string) error {
+	f.interfaces[name].Up = true
+	return nil
+}
+
+func (f *fakeBsdNetworkClient) InterfaceSetDown(name string) error {
+	f.interfaces[name].Up = false
+	return nil
+}
+
+func (f *fakeBsdNetworkClient) AddrAdd(name string, addr netip.Prefix) error {
+	f.interfaces[name].Addresses = append(f.interfaces[name].Addresses, addr)
+	return nil
+}
+
+func (f *fakeBsdNetworkClient) AddrDel(name string, addr netip.Prefix) error {
+	iface := f.interfaces[name]
+	var addresses []netip.Prefix
+	for _, a := range iface.Addresses {
+		if a != addr {
+			addresses = append(addresses, a)
+		}
+	}
+	iface.Addresses = addresses
+	return nil
+}
+
+func (f *fakeBsdNetworkClient) RouteGet(_ netip.Addr) (*lowlevel.BsdRoute, error) {
+	return &f.routes["default"][0], nil
+}
+
+type fakeRcConfClient struct {
+	variables map[string]string
+}
+
+func (f *fakeRcConfClient) Get(name string) (string, error) { return f.variables[name], nil }
+
+func (f *fakeRcConfClient) Set(name, value string) error {
+	f.variables[name] = value
+	return nil
+}
+
+func (f *fakeRcConfClient) Unset(name string) error {
+	delete(f.variables, name)
+	return nil
+}
+
+func setupBsdWgRepo() (*BsdWgRepo, *fakeBsdNetworkClient, *fakeRcConfClient) {
+	wg := &fakeBsdWireGuardClient{devices: map[string]*wgtypes.Device{}}
+	nw := &fakeBsdNetworkClient{
+		wg:         wg,
+		interfaces: map[string]*lowlevel.BsdInterface{"em0": {Name: "em0", Up: true, Mtu: 1500}},
+		routes:     map[string][]lowlevel.BsdRoute{"default": {{Interface: "em0", Mtu: 1492}}},
+	}
+	rc := &fakeRcConfClient{variables: map[string]string{"cloned_interfaces": "lagg0"}}
+
+	return NewBsdWireGuardRepository(wg, nw, rc), nw, rc
+}
+
+func TestBsdWgRepo_SaveInterface(t *testing.T) {
+	repo, nw, rc := setupBsdWgRepo()
+	rc.variables["ifconfig_wg0_alias1"] = "inet 10.0.0.1/24" // left over from a previous configuration
+
+	addresses, _ := domain.CidrsFromString("10.11.12.1/24,10.11.13.1/24,fdfd:d3ad:c0de:1234::1/64")
+	err := repo.SaveInterface(context.Background(), "wg0",
+		func(pi *domain.PhysicalInterface) (*domain.PhysicalInterface, error) {
+			pi.Addresses = addresses
+			pi.Mtu = 1380
+			pi.DeviceUp = true
+			pi.ListenPort = 51820
+			pi.KeyPair = domain.KeyPair{PrivateKey: "aB3cD4eF5gH6iJ7kL8mN9oP0qR1sT2uV3wX4yZ5a6bI="}
+			return pi, nil
+		})
+	require.NoError(t, err)
+
+	iface, err := repo.GetInterface(context.Background(), "wg0")
+	require.NoError(t, err)
+	assert.Equal(t, addresses, iface.Addresses)
+	assert.Equal(t, 1380, iface.Mtu)
+	assert.True(t, iface.DeviceUp)
+	assert.Equal(t, 51820, iface.ListenPort)
+
+	assert.Equal(t, map[string]string{
+		"cloned_interfaces":   "lagg0 wg0",
+		"ifconfig_wg0":        "inet 10.11.12.1/24 mtu 1380",
+		"ifconfig_wg0_ipv6":   "inet6 fdfd:d3ad:c0de:1234::1/64",
+		"ifconfig_wg0_alias0": "inet 10.11.13.1/24",
+	}, rc.variables)
+
+	// removing addresses
+	addresses, _ = domain.CidrsFromString("10.11.12.1/24")
+	err = repo.SaveInterface(context.Background(), "wg0",
+		func(pi *domain.PhysicalInterface) (*domain.PhysicalInterface, error) {
+			pi.Addresses = addresses
+			return pi, nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.11.12.1/24")}, nw.interfaces["wg0"].Addresses)
+	assert.Equal(t, map[string]string{
+		"cloned_interfaces": "lagg0 wg0",
+		"ifconfig_wg0":      "inet 10.11.12.1/24 mtu 1380",
+	}, rc.variables)
+}
+
+func TestBsdWgRepo_SaveInterface_notClonable(t *testing.T) {
+	repo, nw, rc := setupBsdWgRepo()
+
+	err := repo.SaveInterface(context.Background(), "wg-office",
+		func(pi *domain.PhysicalInterface) (*domain.PhysicalInterface, error) {
+			pi.KeyPair = domain.KeyPair{PrivateKey: "aB3cD4eF5gH6iJ7kL8mN9oP0qR1sT2uV3wX4yZ5a6bI="}
+			return pi, nil
+		})
+	require.NoError(t, err)
+
+	assert.Contains(t, nw.interfaces, "wg-office")
+	assert.Equal(t, map[string]string{"cloned_interfaces": "lagg0"}, rc.variables)
+}
+
+func TestBsdWgRepo_DeleteInterface(t *testing.T) {
+	repo, nw, rc := setupBsdWgRepo()
+	require.NoError(t, nw.InterfaceCreate(bsdWireGuardCloner, "wg0"))
+	rc.variables["cloned_interfaces"] = "wg0"
+	rc.variables["ifconfig_wg0"] = "inet 10.11.12.1/24"
+	rc.variables["ifconfig_wg0_alias0"] = "inet 10.11.13.1/24"
+	rc.variables["ifconfig_em0"] = "DHCP"
+
+	require.NoError(t, repo.DeleteInterface(context.Background(), "wg0"))
+	assert.NotContains(t, nw.interfaces, "wg0")
+	assert.Equal(t, map[string]string{"ifconfig_em0": "DHCP"}, rc.variables)
+
+	// deleting a missing interface is not an error
+	require.NoError(t, repo.DeleteInterface(context.Background(), "wg0"))
+}
+
+func TestBsdWgRepo_ProbePathMtu(t *testing.T) {
+	repo, _, _ := setupBsdWgRepo()
+
+	pathMtu, err := repo.ProbePathMtu(context.Background(), netip.Addr{})
+	require.NoError(t, err)
+	assert.Equal(t, "em0", pathMtu.LinkName)
+	assert.Equal(t, 1500, pathMtu.LinkMtu)
+	assert.Equal(t, 1492, pathMtu.Mtu)
+}
@@ -0,0 +1,25 @@
+package adapters
+
+import (
+	"golang.zx2c4.com/wireguard/wgctrl"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/lowlevel"
+)
+
+// NewWireGuardRepository creates a new BsdWgRepo instance.
+// This repository is used to interact with the WireGuard kernel module (if_wg) of FreeBSD.
+// If a rc.conf path is configured, the interfaces are also stored in this file.
+func NewWireGuardRepository(cfg *config.Config) *BsdWgRepo {
+	wg, err := wgctrl.New()
+	if err != nil {
+		panic("failed to init wgctrl: " + err.Error())
+	}
+
+	var rc lowlevel.RcConfClient
+	if cfg.Advanced.RcConfPath != "" {
+		rc = lowlevel.RcConfManager{Path: cfg.Advanced.RcConfPath}
+	}
+
+	return NewBsdWireGuardRepository(wg, lowlevel.BsdNetworkManager{}, rc)
+}
@@ -1,4 +1,4 @@
-//go:build integration
+//go:build integration && linux
 
 package adapters
 
@@ -16,6 +16,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/h44z/wg-portal/internal"
+	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
@@ -25,7 +26,7 @@ func setup(t *testing.T) *WgRepo {
 		t.Fatalf("this tests need to be executed as root user")
 	}
 
-	repo := NewWireGuardRepository()
+	repo := NewWireGuardRepository(&config.Config{})
 
 	return repo
 }
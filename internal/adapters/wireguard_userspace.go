@@ -0,0 +1,247 @@
+//go:build linux
+
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	userspaceSocketDir     = "/var/run/wireguard" // the UAPI socket directory of wireguard-go and boringtun
+	userspaceStartTimeout  = 5 * time.Second
+	userspaceStopTimeout   = 5 * time.Second
+	userspaceMaxRestartGap = time.Minute
+)
+
+// userspaceInstance is the supervised process of a userspace WireGuard implementation for one interface.
+type userspaceInstance struct {
+	cmd      *exec.Cmd
+	exited   chan struct{} // closed once the process exited
+	stopping bool
+
+	iface *domain.PhysicalInterface // the last applied interface configuration
+	peers map[domain.PeerIdentifier]domain.PhysicalPeer
+}
+
+// userspaceSupervisor starts one process of a userspace WireGuard implementation, like wireguard-go or boringtun,
+// per interface. The interfaces are configured via the UAPI socket of the process, which is used by wgctrl
+// automatically. If a process exits unexpectedly, it is restarted and the last applied configuration is restored.
+type userspaceSupervisor struct {
+	command   string // keep empty to disable userspace interfaces
+	socketDir string
+	restore   func(pi *domain.PhysicalInterface, peers []domain.PhysicalPeer) error
+
+	mux       sync.Mutex
+	instances map[domain.InterfaceIdentifier]*userspaceInstance
+}
+
+func newUserspaceSupervisor(command string) *userspaceSupervisor {
+	return &userspaceSupervisor{
+		command:   command,
+		socketDir: userspaceSocketDir,
+		instances: make(map[domain.InterfaceIdentifier]*userspaceInstance),
+	}
+}
+
+// Enabled returns true if a userspace implementation is configured.
+func (s *userspaceSupervisor) Enabled() bool {
+	return s.command != ""
+}
+
+// IsManaged returns true if the interface is handled by a userspace process.
+func (s *userspaceSupervisor) IsManaged(id domain.InterfaceIdentifier) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	_, ok := s.instances[id]
+	return ok
+}
+
+// Start starts the userspace process of the interface and waits until its UAPI socket is available.
+func (s *userspaceSupervisor) Start(id domain.InterfaceIdentifier) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.instances[id]; ok {
+		return nil // already running
+	}
+
+	instance := &userspaceInstance{peers: make(map[domain.PeerIdentifier]domain.PhysicalPeer)}
+	if err := s.startProcess(id, instance); err != nil {
+		return err
+	}
+	s.instances[id] = instance
+
+	go s.supervise(id, instance)
+
+	return nil
+}
+
+// startProcess must be called with the lock held.
+func (s *userspaceSupervisor) startProcess(id domain.InterfaceIdentifier, instance *userspaceInstance) error {
+	cmd := exec.Command(s.command, "-f", string(id)) // wireguard-go and boringtun both stay in the foreground with -f
+	cmd.Env = append(os.Environ(), "WG_PROCESS_FOREGROUND=1")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGTERM} // do not outlive WireGuard Portal
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start userspace implementation %s: %w", s.command, err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	socket := filepath.Join(s.socketDir, string(id)+".sock")
+	deadline := time.After(userspaceStartTimeout)
+	for {
+		if _, err := os.Stat(socket); err == nil {
+			break
+		}
+		select {
+		case <-exited:
+			return fmt.Errorf("userspace implementation %s exited: %s", s.command, cmd.ProcessState)
+		case <-deadline:
+			_ = cmd.Process.Kill()
+			return fmt.Errorf("userspace implementation %s did not create socket %s", s.command, socket)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	instance.cmd = cmd
+	instance.exited = exited
+	slog.Info("started userspace WireGuard interface", "interface", id, "pid", cmd.Process.Pid)
+
+	return nil
+}
+
+// supervise restarts the process of the interface until it is stopped. The configuration of the interface is
+// restored after each restart.
+func (s *userspaceSupervisor) supervise(id domain.InterfaceIdentifier, instance *userspaceInstance) {
+	restartGap := time.Second
+	for {
+		s.mux.Lock()
+		exited := instance.exited
+		s.mux.Unlock()
+
+		<-exited
+
+		s.mux.Lock()
+		if instance.stopping {
+			s.mux.Unlock()
+			return
+		}
+		slog.Error("userspace WireGuard process exited unexpectedly, restarting", "interface", id,
+			"state", instance.cmd.ProcessState, "delay", restartGap)
+		s.mux.Unlock()
+
+		time.Sleep(restartGap)
+
+		s.mux.Lock()
+		if instance.stopping {
+			s.mux.Unlock()
+			return
+		}
+		err := s.startProcess(id, instance)
+		if err != nil {
+			instance.exited = closedChannel() // try again after the next delay
+		}
+		iface, peers := instance.iface, instance.peerList()
+		s.mux.Unlock()
+
+		if err != nil {
+			slog.Error("failed to restart userspace WireGuard process", "interface", id, "error", err)
+			restartGap = min(2*restartGap, userspaceMaxRestartGap)
+			continue
+		}
+		restartGap = time.Second
+
+		if iface != nil && s.restore != nil {
+			if err := s.restore(iface, peers); err != nil {
+				slog.Error("failed to restore userspace WireGuard interface", "interface", id, "error", err)
+			}
+		}
+	}
+}
+
+// Stop stops the userspace process of the interface. The interface is removed by the process.
+func (s *userspaceSupervisor) Stop(id domain.InterfaceIdentifier) error {
+	s.mux.Lock()
+	instance, ok := s.instances[id]
+	if !ok {
+		s.mux.Unlock()
+		return nil
+	}
+	instance.stopping = true
+	delete(s.instances, id)
+	cmd, exited := instance.cmd, instance.exited
+	s.mux.Unlock()
+
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to stop userspace WireGuard process: %w", err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(userspaceStopTimeout):
+		_ = cmd.Process.Kill()
+		<-exited
+	}
+	slog.Info("stopped userspace WireGuard interface", "interface", id)
+
+	return nil
+}
+
+// RememberInterface stores the applied interface configuration, so that it can be restored after a restart.
+func (s *userspaceSupervisor) RememberInterface(pi *domain.PhysicalInterface) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if instance, ok := s.instances[pi.Identifier]; ok {
+		copied := *pi
+		instance.iface = &copied
+	}
+}
+
+// RememberPeer stores the applied peer configuration, so that it can be restored after a restart.
+func (s *userspaceSupervisor) RememberPeer(id domain.InterfaceIdentifier, pp *domain.PhysicalPeer) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if instance, ok := s.instances[id]; ok {
+		instance.peers[pp.Identifier] = *pp
+	}
+}
+
+// ForgetPeer removes the stored peer configuration.
+func (s *userspaceSupervisor) ForgetPeer(id domain.InterfaceIdentifier, peerId domain.PeerIdentifier) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if instance, ok := s.instances[id]; ok {
+		delete(instance.peers, peerId)
+	}
+}
+
+func (i *userspaceInstance) peerList() []domain.PhysicalPeer {
+	peers := make([]domain.PhysicalPeer, 0, len(i.peers))
+	for _, peer := range i.peers {
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+func closedChannel() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
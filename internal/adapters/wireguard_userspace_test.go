@@ -0,0 +1,96 @@
+//go:build linux
+
+package adapters
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// setupUserspaceSupervisor creates a supervisor for a fake userspace implementation, which only creates its
+// UAPI socket file and waits until it is terminated.
+func setupUserspaceSupervisor(t *testing.T) *userspaceSupervisor {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "wireguard-go")
+	err := os.WriteFile(script, []byte(`#!/bin/sh
+trap 'rm -f "`+dir+`/$2.sock"; exit 0' TERM
+touch "`+dir+`/$2.sock"
+while true; do sleep 0.1; done
+`), 0o755)
+	require.NoError(t, err)
+
+	supervisor := newUserspaceSupervisor(script)
+	supervisor.socketDir = dir
+
+	return supervisor
+}
+
+func TestUserspaceSupervisor_Disabled(t *testing.T) {
+	supervisor := newUserspaceSupervisor("")
+
+	assert.False(t, supervisor.Enabled())
+	assert.False(t, supervisor.IsManaged("wg0"))
+	assert.NoError(t, supervisor.Stop("wg0"))
+}
+
+func TestUserspaceSupervisor_StartStop(t *testing.T) {
+	supervisor := setupUserspaceSupervisor(t)
+
+	require.NoError(t, supervisor.Start("wg0"))
+	assert.True(t, supervisor.IsManaged("wg0"))
+	assert.FileExists(t, filepath.Join(supervisor.socketDir, "wg0.sock"))
+
+	require.NoError(t, supervisor.Stop("wg0"))
+	assert.False(t, supervisor.IsManaged("wg0"))
+	assert.NoFileExists(t, filepath.Join(supervisor.socketDir, "wg0.sock"))
+}
+
+func TestUserspaceSupervisor_Start_noSocket(t *testing.T) {
+	supervisor := newUserspaceSupervisor("/bin/true")
+	supervisor.socketDir = t.TempDir()
+
+	assert.Error(t, supervisor.Start("wg0"))
+	assert.False(t, supervisor.IsManaged("wg0"))
+}
+
+func TestUserspaceSupervisor_Restart(t *testing.T) {
+	supervisor := setupUserspaceSupervisor(t)
+	restored := make(chan []domain.PhysicalPeer, 1)
+	supervisor.restore = func(pi *domain.PhysicalInterface, peers []domain.PhysicalPeer) error {
+		assert.Equal(t, domain.InterfaceIdentifier("wg0"), pi.Identifier)
+		restored <- peers
+		return nil
+	}
+
+	require.NoError(t, supervisor.Start("wg0"))
+	defer func() { _ = supervisor.Stop("wg0") }()
+
+	supervisor.RememberInterface(&domain.PhysicalInterface{Identifier: "wg0", ListenPort: 51820})
+	supervisor.RememberPeer("wg0", &domain.PhysicalPeer{Identifier: "peer1"})
+	supervisor.RememberPeer("wg0", &domain.PhysicalPeer{Identifier: "peer2"})
+	supervisor.ForgetPeer("wg0", "peer2")
+
+	supervisor.mux.Lock()
+	pid := supervisor.instances["wg0"].cmd.Process.Pid
+	supervisor.mux.Unlock()
+	require.NoError(t, syscall.Kill(pid, syscall.SIGKILL))
+
+	select {
+	case peers := <-restored:
+		assert.Equal(t, []domain.PhysicalPeer{{Identifier: "peer1"}}, peers)
+	case <-time.After(10 * time.Second):
+		t.Fatal("interface was not restored after restart")
+	}
+
+	supervisor.mux.Lock()
+	assert.NotEqual(t, pid, supervisor.instances["wg0"].cmd.Process.Pid)
+	supervisor.mux.Unlock()
+}
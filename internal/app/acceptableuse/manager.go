@@ -0,0 +1,184 @@
+package acceptableuse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetUser returns the user with the given identifier.
+	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	// GetLastAcceptableUseAcceptance returns the most recent acceptance of the acceptable use policy by the user.
+	GetLastAcceptableUseAcceptance(ctx context.Context, id domain.UserIdentifier) (
+		*domain.AcceptableUseAcceptance,
+		error,
+	)
+	// GetAcceptableUseAcceptances returns all acceptances of the acceptable use policy by the user.
+	GetAcceptableUseAcceptances(ctx context.Context, id domain.UserIdentifier) ([]domain.AcceptableUseAcceptance, error)
+	// SaveAcceptableUseAcceptance creates or updates the given acceptance.
+	SaveAcceptableUseAcceptance(ctx context.Context, acceptance *domain.AcceptableUseAcceptance) error
+	// DeleteAcceptableUseAcceptances deletes all acceptances of the user.
+	DeleteAcceptableUseAcceptances(ctx context.Context, id domain.UserIdentifier) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager gates the issuing of peers and peer configurations behind the acceptance of the acceptable use policy.
+// Users have to accept the policy again whenever the policy text changes. Administrators are not gated, as they
+// issue peers on behalf of the users.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+	db  DatabaseRepo
+
+	policy domain.AcceptableUsePolicy
+}
+
+// NewAcceptableUseManager creates a new acceptable use policy manager.
+func NewAcceptableUseManager(cfg *config.Config, bus EventBus, db DatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+		db:  db,
+
+		policy: domain.NewAcceptableUsePolicy(cfg.AcceptableUse.Title, cfg.AcceptableUse.Text),
+	}
+
+	if !cfg.AcceptableUse.Enabled {
+		return m, nil
+	}
+
+	if m.policy.Text == "" {
+		return nil, errors.New("missing acceptable use policy text")
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicUserDeleted, m.handleUserDeletedEvent)
+}
+
+func (m Manager) handleUserDeletedEvent(user domain.User) {
+	slog.Debug("handling user deleted event", "user", user.Identifier)
+
+	err := m.db.DeleteAcceptableUseAcceptances(context.Background(), user.Identifier)
+	if err != nil {
+		slog.Error("failed to delete acceptable use acceptances", "user", user.Identifier, "error", err)
+	}
+}
+
+// GetStatus returns the current policy and the latest acceptance of the given user.
+func (m Manager) GetStatus(ctx context.Context, id domain.UserIdentifier) (*domain.AcceptableUseStatus, error) {
+	user, err := m.db.GetUser(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user %s: %w", id, err)
+	}
+	if err := domain.ValidateUserAccessRights(ctx, id, user.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
+	if !m.cfg.AcceptableUse.Enabled {
+		return nil, fmt.Errorf("acceptable use policy is disabled: %w", domain.ErrNotFound)
+	}
+
+	acceptance, err := m.db.GetLastAcceptableUseAcceptance(ctx, id)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load acceptance of user %s: %w", id, err)
+	}
+
+	return &domain.AcceptableUseStatus{
+		Policy:         m.policy,
+		LastAcceptance: acceptance,
+	}, nil
+}
+
+// GetAcceptances returns all acceptances of the given user, the most recent acceptances first.
+func (m Manager) GetAcceptances(ctx context.Context, id domain.UserIdentifier) (
+	[]domain.AcceptableUseAcceptance,
+	error,
+) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := m.db.GetUser(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user %s: %w", id, err)
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, user.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetAcceptableUseAcceptances(ctx, id)
+}
+
+// Accept records the acceptance of the policy by the current user. The version must match the current policy
+// version, so that users cannot accept a text that they have not seen.
+func (m Manager) Accept(ctx context.Context, version string) (*domain.AcceptableUseAcceptance, error) {
+	if !m.cfg.AcceptableUse.Enabled {
+		return nil, fmt.Errorf("acceptable use policy is disabled: %w", domain.ErrNotFound)
+	}
+
+	currentUser := domain.GetUserInfo(ctx)
+	if currentUser.ImpersonatedBy != "" {
+		return nil, fmt.Errorf("the policy cannot be accepted on behalf of a user: %w", domain.ErrNoPermission)
+	}
+	if version != m.policy.Version {
+		return nil, fmt.Errorf("policy version %s is outdated, the current version is %s: %w",
+			version, m.policy.Version, domain.ErrInvalidData)
+	}
+
+	acceptance := &domain.AcceptableUseAcceptance{
+		UserIdentifier: currentUser.Id,
+		Version:        m.policy.Version,
+		AcceptedAt:     time.Now(),
+	}
+	if err := m.db.SaveAcceptableUseAcceptance(ctx, acceptance); err != nil {
+		return nil, fmt.Errorf("failed to save acceptance: %w", err)
+	}
+
+	slog.Info("acceptable use policy accepted", "user", acceptance.UserIdentifier, "version", acceptance.Version)
+
+	return acceptance, nil
+}
+
+// ValidateAcceptance checks that the current user accepted the current version of the policy. Administrators and
+// system tasks are not gated.
+func (m Manager) ValidateAcceptance(ctx context.Context) error {
+	if !m.cfg.AcceptableUse.Enabled {
+		return nil
+	}
+
+	currentUser := domain.GetUserInfo(ctx)
+	if currentUser.IsAdmin {
+		return nil
+	}
+
+	acceptance, err := m.db.GetLastAcceptableUseAcceptance(ctx, currentUser.Id)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("failed to load acceptance of user %s: %w", currentUser.Id, err)
+	}
+
+	status := domain.AcceptableUseStatus{Policy: m.policy, LastAcceptance: acceptance}
+	if !status.IsAccepted() {
+		return fmt.Errorf("the acceptable use policy has to be accepted first: %w", domain.ErrNoPermission)
+	}
+
+	return nil
+}
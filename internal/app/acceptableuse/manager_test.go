@@ -0,0 +1,155 @@
+package acceptableuse
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	acceptances []domain.AcceptableUseAcceptance // the most recent acceptance first
+}
+
+func (f *fakeDatabase) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	return &domain.User{Identifier: id, OrganizationIdentifier: "org-a"}, nil
+}
+
+func (f *fakeDatabase) GetLastAcceptableUseAcceptance(ctx context.Context, id domain.UserIdentifier) (
+	*domain.AcceptableUseAcceptance,
+	error,
+) {
+	acceptances, _ := f.GetAcceptableUseAcceptances(ctx, id)
+	if len(acceptances) == 0 {
+		return nil, domain.ErrNotFound
+	}
+	return &acceptances[0], nil
+}
+
+func (f *fakeDatabase) GetAcceptableUseAcceptances(_ context.Context, id domain.UserIdentifier) (
+	[]domain.AcceptableUseAcceptance,
+	error,
+) {
+	var acceptances []domain.AcceptableUseAcceptance
+	for _, acceptance := range f.acceptances {
+		if acceptance.UserIdentifier == id {
+			acceptances = append(acceptances, acceptance)
+		}
+	}
+	return acceptances, nil
+}
+
+func (f *fakeDatabase) SaveAcceptableUseAcceptance(_ context.Context, acceptance *domain.AcceptableUseAcceptance) error {
+	f.acceptances = slices.Insert(f.acceptances, 0, *acceptance)
+	return nil
+}
+
+func (f *fakeDatabase) DeleteAcceptableUseAcceptances(_ context.Context, id domain.UserIdentifier) error {
+	f.acceptances = slices.DeleteFunc(f.acceptances, func(a domain.AcceptableUseAcceptance) bool {
+		return a.UserIdentifier == id
+	})
+	return nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error {
+	return nil
+}
+
+func newTestManager(t *testing.T, text string) (*Manager, *fakeDatabase) {
+	cfg := &config.Config{}
+	cfg.AcceptableUse = config.AcceptableUseConfig{Enabled: true, Title: "AUP", Text: text}
+
+	db := &fakeDatabase{}
+	m, err := NewAcceptableUseManager(cfg, fakeBus{}, db)
+	require.NoError(t, err)
+
+	return m, db
+}
+
+func TestNewAcceptableUseManager_MissingText(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.AcceptableUse = config.AcceptableUseConfig{Enabled: true, Text: "  \n"}
+
+	_, err := NewAcceptableUseManager(cfg, fakeBus{}, &fakeDatabase{})
+	assert.Error(t, err)
+}
+
+func TestManager_AcceptAndValidate(t *testing.T) {
+	m, db := newTestManager(t, "Be nice.")
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	assert.ErrorIs(t, m.ValidateAcceptance(userCtx), domain.ErrNoPermission)
+	assert.NoError(t, m.ValidateAcceptance(adminCtx), "administrators are not gated")
+
+	status, err := m.GetStatus(userCtx, "alice")
+	require.NoError(t, err)
+	assert.False(t, status.IsAccepted())
+	assert.Equal(t, "Be nice.", status.Policy.Text)
+
+	_, err = m.Accept(userCtx, "outdated")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	acceptance, err := m.Accept(userCtx, status.Policy.Version)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserIdentifier("alice"), acceptance.UserIdentifier)
+	assert.NoError(t, m.ValidateAcceptance(userCtx))
+
+	_, err = m.GetStatus(userCtx, "bob")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	// a changed policy text requires a new acceptance
+	changed, _ := newTestManager(t, "Be nicer.")
+	changed.db = db
+	assert.ErrorIs(t, changed.ValidateAcceptance(userCtx), domain.ErrNoPermission)
+	status, err = changed.GetStatus(userCtx, "alice")
+	require.NoError(t, err)
+	assert.False(t, status.IsAccepted())
+	assert.Equal(t, acceptance.Version, status.LastAcceptance.Version)
+}
+
+func TestManager_GetAcceptances(t *testing.T) {
+	m, _ := newTestManager(t, "Be nice.")
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	_, err := m.Accept(userCtx, m.policy.Version)
+	require.NoError(t, err)
+
+	orgAdminCtx := domain.SetUserInfo(context.Background(),
+		&domain.ContextUserInfo{Id: "admin", IsAdmin: true, Organization: "org-a"})
+	acceptances, err := m.GetAcceptances(orgAdminCtx, "alice")
+	require.NoError(t, err)
+	assert.Len(t, acceptances, 1)
+
+	otherAdminCtx := domain.SetUserInfo(context.Background(),
+		&domain.ContextUserInfo{Id: "admin", IsAdmin: true, Organization: "org-b"})
+	_, err = m.GetAcceptances(otherAdminCtx, "alice")
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "admins only see users of their organization")
+
+	_, err = m.GetAcceptances(userCtx, "alice")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_Accept_Impersonated(t *testing.T) {
+	m, _ := newTestManager(t, "Be nice.")
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice", ImpersonatedBy: "admin"})
+
+	_, err := m.Accept(ctx, m.policy.Version)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_Disabled(t *testing.T) {
+	m, err := NewAcceptableUseManager(&config.Config{}, fakeBus{}, &fakeDatabase{})
+	require.NoError(t, err)
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+
+	assert.NoError(t, m.ValidateAcceptance(ctx))
+	_, err = m.GetStatus(ctx, "alice")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
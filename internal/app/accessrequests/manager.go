@@ -0,0 +1,508 @@
+package accessrequests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetRouteSet returns the route set with the given identifier.
+	GetRouteSet(ctx context.Context, id domain.RouteSetIdentifier) (*domain.RouteSet, error)
+	// GetAllRouteSets returns all route sets.
+	GetAllRouteSets(ctx context.Context) ([]domain.RouteSet, error)
+	// GetAccessRequest returns the access request with the given identifier.
+	GetAccessRequest(ctx context.Context, id domain.AccessRequestIdentifier) (*domain.AccessRequest, error)
+	// GetAccessRequests returns all access requests, the most recent requests first.
+	GetAccessRequests(ctx context.Context) ([]domain.AccessRequest, error)
+	// SaveAccessRequest creates or updates the given access request.
+	SaveAccessRequest(ctx context.Context, request *domain.AccessRequest) error
+	// UpdateAccessRequestPeer moves all access requests of a peer to the new peer identifier.
+	UpdateAccessRequestPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error
+}
+
+type PeerManager interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager handles just-in-time access requests. A user requests temporary access to a route set for one of their
+// peers, an administrator of the interface approves the request. On approval, the route set is attached to the peer,
+// so its networks are added to the AllowedIPs of the peer configuration. Once the access window ends, the route set
+// is detached again.
+// The end of the access window is stored with the request, so access windows that ended while WireGuard Portal was
+// not running are closed on startup.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db    DatabaseRepo
+	peers PeerManager
+}
+
+// NewAccessRequestManager creates a new access request manager.
+func NewAccessRequestManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	peers PeerManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:    db,
+		peers: peers,
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background job that expires open requests and closes ended access windows.
+// Access windows are also closed if new requests are disabled.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	go m.runExpiryCheck(ctx)
+
+	slog.Debug("started access request expiry checks")
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerIdentifierUpdated, m.handlePeerIdentifierUpdatedEvent)
+}
+
+func (m Manager) handlePeerIdentifierUpdatedEvent(oldId, newId domain.PeerIdentifier) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	if err := m.db.UpdateAccessRequestPeer(ctx, oldId, newId); err != nil {
+		slog.Error("failed to migrate access requests", "oldIdentifier", oldId, "newIdentifier", newId,
+			"error", err)
+	}
+}
+
+func (m Manager) runExpiryCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		if err := m.expireRequests(ctx, time.Now()); err != nil {
+			slog.Error("failed to expire access requests", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-time.After(m.cfg.Advanced.ExpiryCheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+	}
+}
+
+// expireRequests resolves all open requests that were not approved in time and closes all access windows that
+// ended. Access windows that cannot be closed are retried in the next run.
+func (m Manager) expireRequests(ctx context.Context, now time.Time) error {
+	requests, err := m.db.GetAccessRequests(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load access requests: %w", err)
+	}
+
+	for _, request := range requests {
+		switch {
+		case request.IsExpired(now):
+			request.Resolve(domain.AccessRequestExpired, domain.CtxSystemAdminId, now)
+			if err := m.db.SaveAccessRequest(ctx, &request); err != nil {
+				return fmt.Errorf("failed to expire access request %s: %w", request.Identifier, err)
+			}
+		case request.IsOver(now):
+			if err := m.end(ctx, &request); err != nil {
+				slog.Error("failed to close access window", "request", request.Identifier, "peer", request.PeerId,
+					"error", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m Manager) checkEnabled() error {
+	if !m.cfg.AccessRequests.Enabled {
+		return fmt.Errorf("access requests are disabled: %w", domain.ErrNoPermission)
+	}
+
+	return nil
+}
+
+// GetAccessRequests returns all requests of the current user. Admins receive all requests of the interfaces they
+// administrate.
+func (m Manager) GetAccessRequests(ctx context.Context) ([]domain.AccessRequest, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	requests, err := m.db.GetAccessRequests(domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load access requests: %w", err)
+	}
+
+	sessionUser := domain.GetUserInfo(ctx)
+	visible := make([]domain.AccessRequest, 0, len(requests))
+	for _, request := range requests {
+		if isRequester(sessionUser, &request) || m.isInterfaceAdmin(ctx, request.InterfaceIdentifier) {
+			visible = append(visible, request)
+		}
+	}
+
+	return visible, nil
+}
+
+// GetRouteSets returns all route sets that can be requested. The networks of the route sets are only visible to
+// admins.
+func (m Manager) GetRouteSets(ctx context.Context) ([]domain.RouteSet, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	routeSets, err := m.db.GetAllRouteSets(domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load route sets: %w", err)
+	}
+
+	if !domain.GetUserInfo(ctx).HasAdminInterfaces() {
+		for i := range routeSets {
+			routeSets[i].NetworksStr = ""
+		}
+	}
+
+	return routeSets, nil
+}
+
+// RequestAccess requests temporary access to the given route set for the given peer. Only the owner of the peer
+// and admins can request access. If no duration is given, the configured default duration is used. If the access
+// is requested by an admin of the interface, the request is approved immediately.
+func (m Manager) RequestAccess(
+	ctx context.Context,
+	peerId domain.PeerIdentifier,
+	routeSetId domain.RouteSetIdentifier,
+	duration time.Duration,
+	reason string,
+) (*domain.AccessRequest, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	peer, err := m.peers.GetPeer(ctx, peerId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer %s: %w", peerId, err)
+	}
+
+	iface, err := m.getInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier, iface.OrganizationIdentifier,
+		iface.Identifier); err != nil {
+		return nil, err
+	}
+
+	if duration == 0 {
+		duration = m.cfg.AccessRequests.DefaultDuration
+	}
+	if duration < 0 || duration > m.cfg.AccessRequests.MaxDuration {
+		return nil, errors.Join(fmt.Errorf("duration %s must be between 0 and %s", duration,
+			m.cfg.AccessRequests.MaxDuration), domain.ErrInvalidData)
+	}
+
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+	if _, err := m.db.GetRouteSet(systemCtx, routeSetId); err != nil {
+		return nil, fmt.Errorf("failed to load route set %s: %w", routeSetId, err)
+	}
+	if peer.HasRouteSet(routeSetId) {
+		return nil, fmt.Errorf("peer %s already has access to route set %s: %w", peer.Identifier, routeSetId,
+			domain.ErrInvalidData)
+	}
+
+	requests, err := m.db.GetAccessRequests(systemCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load access requests: %w", err)
+	}
+	for _, request := range requests {
+		if request.PeerId == peer.Identifier && request.RouteSetId == routeSetId &&
+			(request.IsOpen() || request.IsActive()) {
+			return nil, fmt.Errorf("peer %s already has an open request for route set %s: %w", peer.Identifier,
+				routeSetId, domain.ErrDuplicateEntry)
+		}
+	}
+
+	sessionUser := domain.GetUserInfo(ctx)
+	now := time.Now()
+	request := &domain.AccessRequest{
+		Identifier:          domain.AccessRequestIdentifier(uuid.New().String()),
+		PeerId:              peer.Identifier,
+		PeerName:            peer.DisplayName,
+		InterfaceIdentifier: peer.InterfaceIdentifier,
+		UserIdentifier:      peer.UserIdentifier,
+		RouteSetId:          routeSetId,
+		Reason:              reason,
+		Duration:            duration,
+		State:               domain.AccessRequestPending,
+		RequestedBy:         sessionUser.Id,
+		RequestedAt:         now,
+		ExpiresAt:           now.Add(m.cfg.AccessRequests.RequestTimeout),
+	}
+
+	if err := m.db.SaveAccessRequest(systemCtx, request); err != nil {
+		return nil, fmt.Errorf("failed to store access request: %w", err)
+	}
+
+	slog.Info("requested temporary access", "request", request.Identifier, "peer", peer.Identifier,
+		"routeSet", routeSetId, "duration", duration, "by", request.RequestedBy)
+
+	if sessionUser.IsInterfaceAdmin(iface) {
+		if err := m.activate(ctx, request); err != nil {
+			return nil, err
+		}
+	}
+
+	return request, nil
+}
+
+// ApproveRequest approves the given request and attaches the route set to the peer. Only admins of the interface
+// can approve requests.
+func (m Manager) ApproveRequest(ctx context.Context, id domain.AccessRequestIdentifier) (
+	*domain.AccessRequest,
+	error,
+) {
+	request, err := m.getRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.validateInterfaceAdmin(ctx, request.InterfaceIdentifier); err != nil {
+		return nil, err
+	}
+	if !request.IsOpen() {
+		return nil, fmt.Errorf("access request %s is %s: %w", id, request.State, domain.ErrInvalidData)
+	}
+
+	if err := m.activate(ctx, request); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// RejectRequest rejects the given request. Only admins of the interface can reject requests.
+func (m Manager) RejectRequest(ctx context.Context, id domain.AccessRequestIdentifier) (
+	*domain.AccessRequest,
+	error,
+) {
+	request, err := m.getRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.validateInterfaceAdmin(ctx, request.InterfaceIdentifier); err != nil {
+		return nil, err
+	}
+	if !request.IsOpen() {
+		return nil, fmt.Errorf("access request %s is %s: %w", id, request.State, domain.ErrInvalidData)
+	}
+
+	return m.resolve(ctx, request, domain.AccessRequestRejected)
+}
+
+// CancelRequest withdraws the given open request, or closes the access window of the given active request early.
+// Requests can be cancelled by the owner of the peer, the requesting user and by admins.
+func (m Manager) CancelRequest(ctx context.Context, id domain.AccessRequestIdentifier) (
+	*domain.AccessRequest,
+	error,
+) {
+	request, err := m.getRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case request.IsOpen():
+		return m.resolve(ctx, request, domain.AccessRequestCancelled)
+	case request.IsActive():
+		if err := m.end(ctx, request); err != nil {
+			return nil, err
+		}
+		return request, nil
+	default:
+		return nil, fmt.Errorf("access request %s is %s: %w", id, request.State, domain.ErrInvalidData)
+	}
+}
+
+// getRequest loads the given request and ensures that the current user is allowed to access it. Expired requests
+// are resolved on access, so they can no longer be approved before the background job handled them.
+func (m Manager) getRequest(ctx context.Context, id domain.AccessRequestIdentifier) (
+	*domain.AccessRequest,
+	error,
+) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+	request, err := m.db.GetAccessRequest(systemCtx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load access request %s: %w", id, err)
+	}
+
+	sessionUser := domain.GetUserInfo(ctx)
+	if !isRequester(sessionUser, request) && !m.isInterfaceAdmin(ctx, request.InterfaceIdentifier) {
+		return nil, domain.ErrNoPermission
+	}
+
+	if request.IsExpired(time.Now()) {
+		if _, err := m.resolve(systemCtx, request, domain.AccessRequestExpired); err != nil {
+			return nil, err
+		}
+	}
+
+	return request, nil
+}
+
+// resolve sets the final state of the request.
+func (m Manager) resolve(
+	ctx context.Context,
+	request *domain.AccessRequest,
+	state domain.AccessRequestState,
+) (*domain.AccessRequest, error) {
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	request.Resolve(state, domain.GetUserInfo(ctx).Id, time.Now())
+	if err := m.db.SaveAccessRequest(systemCtx, request); err != nil {
+		return nil, fmt.Errorf("failed to store access request: %w", err)
+	}
+
+	slog.Info("resolved access request", "request", request.Identifier, "peer", request.PeerId,
+		"state", request.State, "by", request.ResolvedBy)
+
+	return request, nil
+}
+
+// activate attaches the route set to the peer and opens the access window. If the route set was attached to the
+// peer in the meantime, the peer is not modified and the route set is kept once the access window ends.
+func (m Manager) activate(ctx context.Context, request *domain.AccessRequest) error {
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	peer, err := m.peers.GetPeer(systemCtx, request.PeerId)
+	if errors.Is(err, domain.ErrNotFound) {
+		_, _ = m.resolve(systemCtx, request, domain.AccessRequestCancelled)
+		return fmt.Errorf("peer %s of access request %s no longer exists: %w", request.PeerId, request.Identifier,
+			domain.ErrInvalidData)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load peer %s: %w", request.PeerId, err)
+	}
+
+	granted := peer.AddRouteSet(request.RouteSetId)
+	if granted {
+		if _, err := m.peers.UpdatePeer(systemCtx, peer); err != nil {
+			return fmt.Errorf("failed to attach route set %s to peer %s: %w", request.RouteSetId, peer.Identifier,
+				err)
+		}
+	}
+
+	request.Activate(domain.GetUserInfo(ctx).Id, time.Now(), granted)
+	if err := m.db.SaveAccessRequest(systemCtx, request); err != nil {
+		return fmt.Errorf("failed to store access request: %w", err)
+	}
+
+	slog.Info("granted temporary access", "request", request.Identifier, "peer", request.PeerId,
+		"routeSet", request.RouteSetId, "until", request.EndsAt, "approvedBy", request.ApprovedBy)
+
+	return nil
+}
+
+// end detaches the route set from the peer, if it was attached by the request, and closes the access window.
+func (m Manager) end(ctx context.Context, request *domain.AccessRequest) error {
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	if request.Granted {
+		peer, err := m.peers.GetPeer(systemCtx, request.PeerId)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			// the peer was deleted, there is nothing to revert
+		case err != nil:
+			return fmt.Errorf("failed to load peer %s: %w", request.PeerId, err)
+		case peer.RemoveRouteSet(request.RouteSetId):
+			if _, err := m.peers.UpdatePeer(systemCtx, peer); err != nil {
+				return fmt.Errorf("failed to detach route set %s from peer %s: %w", request.RouteSetId,
+					peer.Identifier, err)
+			}
+		}
+	}
+
+	request.Resolve(domain.AccessRequestEnded, domain.GetUserInfo(ctx).Id, time.Now())
+	if err := m.db.SaveAccessRequest(systemCtx, request); err != nil {
+		return fmt.Errorf("failed to store access request: %w", err)
+	}
+
+	slog.Info("revoked temporary access", "request", request.Identifier, "peer", request.PeerId,
+		"routeSet", request.RouteSetId, "by", request.ResolvedBy)
+
+	return nil
+}
+
+// getInterface loads the interface with the given identifier regardless of the access rights of the current user.
+func (m Manager) getInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	iface, err := m.db.GetInterface(domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo()), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load interface %s: %w", id, err)
+	}
+
+	return iface, nil
+}
+
+// isInterfaceAdmin returns true if the current user administrates the interface with the given identifier.
+func (m Manager) isInterfaceAdmin(ctx context.Context, id domain.InterfaceIdentifier) bool {
+	iface, err := m.getInterface(ctx, id)
+	if err != nil {
+		return false
+	}
+
+	return domain.GetUserInfo(ctx).IsInterfaceAdmin(iface)
+}
+
+// validateInterfaceAdmin checks if the current user administrates the interface with the given identifier. Admins
+// of an organization can only administrate the interfaces of their organization.
+func (m Manager) validateInterfaceAdmin(ctx context.Context, id domain.InterfaceIdentifier) error {
+	iface, err := m.getInterface(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return domain.ValidateInterfaceAdminAccessRights(ctx, iface)
+}
+
+// isRequester returns true if the given user requested the access or owns the peer of the request.
+func isRequester(user *domain.ContextUserInfo, request *domain.AccessRequest) bool {
+	return user.Id == request.RequestedBy || user.Id == request.UserIdentifier
+}
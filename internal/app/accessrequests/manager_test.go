@@ -0,0 +1,270 @@
+package accessrequests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	requests map[domain.AccessRequestIdentifier]domain.AccessRequest
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	return &domain.Interface{Identifier: id, OrganizationIdentifier: "org-a"}, nil
+}
+
+func (f *fakeDatabase) GetRouteSet(_ context.Context, id domain.RouteSetIdentifier) (*domain.RouteSet, error) {
+	if id != "corp-subnets" && id != "printers" {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.RouteSet{Identifier: id}, nil
+}
+
+func (f *fakeDatabase) GetAllRouteSets(_ context.Context) ([]domain.RouteSet, error) {
+	return []domain.RouteSet{
+		{Identifier: "corp-subnets", NetworksStr: "10.10.0.0/16"},
+		{Identifier: "printers", NetworksStr: "192.168.5.0/24"},
+	}, nil
+}
+
+func (f *fakeDatabase) GetAccessRequest(_ context.Context, id domain.AccessRequestIdentifier) (
+	*domain.AccessRequest,
+	error,
+) {
+	request, ok := f.requests[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &request, nil
+}
+
+func (f *fakeDatabase) GetAccessRequests(_ context.Context) ([]domain.AccessRequest, error) {
+	requests := make([]domain.AccessRequest, 0, len(f.requests))
+	for _, request := range f.requests {
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+func (f *fakeDatabase) SaveAccessRequest(_ context.Context, request *domain.AccessRequest) error {
+	f.requests[request.Identifier] = *request
+	return nil
+}
+
+func (f *fakeDatabase) UpdateAccessRequestPeer(_ context.Context, oldId, newId domain.PeerIdentifier) error {
+	for id, request := range f.requests {
+		if request.PeerId == oldId {
+			request.PeerId = newId
+			f.requests[id] = request
+		}
+	}
+	return nil
+}
+
+type fakePeerManager struct {
+	peers map[domain.PeerIdentifier]domain.Peer
+}
+
+func (f *fakePeerManager) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &peer, nil
+}
+
+func (f *fakePeerManager) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	f.peers[peer.Identifier] = *peer
+	return peer, nil
+}
+
+func (f *fakePeerManager) routeSets(id domain.PeerIdentifier) string {
+	peer := f.peers[id]
+	return peer.RouteSetsStr.GetValue()
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func newTestManager(t *testing.T) (*Manager, *fakePeerManager) {
+	cfg := &config.Config{}
+	cfg.AccessRequests.Enabled = true
+	cfg.AccessRequests.DefaultDuration = time.Hour
+	cfg.AccessRequests.MaxDuration = 8 * time.Hour
+	cfg.AccessRequests.RequestTimeout = 24 * time.Hour
+
+	db := &fakeDatabase{requests: map[domain.AccessRequestIdentifier]domain.AccessRequest{}}
+	peers := &fakePeerManager{peers: map[domain.PeerIdentifier]domain.Peer{
+		"peer1": {
+			Identifier:          "peer1",
+			InterfaceIdentifier: "wg0",
+			UserIdentifier:      "alice",
+			RouteSetsStr:        domain.NewConfigOption("printers", true),
+		},
+	}}
+
+	m, err := NewAccessRequestManager(cfg, fakeBus{}, db, peers)
+	require.NoError(t, err)
+
+	return m, peers
+}
+
+func userContext(id domain.UserIdentifier, admin bool) context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: id, IsAdmin: admin})
+}
+
+func TestManager_RequestAccess(t *testing.T) {
+	m, peers := newTestManager(t)
+
+	_, err := m.RequestAccess(userContext("bob", false), "peer1", "corp-subnets", 0, "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "only the owner can request access")
+
+	_, err = m.RequestAccess(userContext("alice", false), "peer1", "corp-subnets", 12*time.Hour, "")
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "the duration exceeds the maximum")
+
+	_, err = m.RequestAccess(userContext("alice", false), "peer1", "printers", 0, "")
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "the route set is already attached")
+
+	_, err = m.RequestAccess(userContext("alice", false), "peer1", "missing", 0, "")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	request, err := m.RequestAccess(userContext("alice", false), "peer1", "corp-subnets", 0, "incident 42")
+	require.NoError(t, err)
+	assert.Equal(t, domain.AccessRequestPending, request.State)
+	assert.Equal(t, time.Hour, request.Duration, "the default duration is used")
+	assert.Equal(t, "printers", peers.routeSets("peer1"))
+
+	_, err = m.RequestAccess(userContext("alice", false), "peer1", "corp-subnets", 0, "")
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+
+	_, err = m.ApproveRequest(userContext("alice", false), request.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "users cannot approve their own requests")
+
+	request, err = m.ApproveRequest(userContext("admin", true), request.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, domain.AccessRequestActive, request.State)
+	assert.True(t, request.Granted)
+	assert.Equal(t, "printers,corp-subnets", peers.routeSets("peer1"))
+
+	request, err = m.CancelRequest(userContext("alice", false), request.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, domain.AccessRequestEnded, request.State, "active requests can be ended early")
+	assert.Equal(t, "printers", peers.routeSets("peer1"))
+}
+
+func TestManager_RequestAccess_admin(t *testing.T) {
+	m, peers := newTestManager(t)
+
+	request, err := m.RequestAccess(userContext("admin", true), "peer1", "corp-subnets", 2*time.Hour, "")
+	require.NoError(t, err)
+	assert.Equal(t, domain.AccessRequestActive, request.State, "requests of admins are approved immediately")
+	assert.Equal(t, domain.UserIdentifier("admin"), request.ApprovedBy)
+	assert.Equal(t, "printers,corp-subnets", peers.routeSets("peer1"))
+}
+
+func TestManager_ApproveRequest_otherOrganization(t *testing.T) {
+	m, peers := newTestManager(t)
+
+	request, err := m.RequestAccess(userContext("alice", false), "peer1", "corp-subnets", 0, "")
+	require.NoError(t, err)
+
+	orgAdminCtx := func(org domain.OrganizationIdentifier) context.Context {
+		return domain.SetUserInfo(context.Background(),
+			&domain.ContextUserInfo{Id: "org-admin", IsAdmin: true, Organization: org})
+	}
+
+	_, err = m.ApproveRequest(orgAdminCtx("org-b"), request.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "admins of other organizations cannot approve requests")
+	_, err = m.RejectRequest(orgAdminCtx("org-b"), request.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	_, err = m.RequestAccess(orgAdminCtx("org-b"), "peer1", "corp-subnets", 0, "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	requests, err := m.GetAccessRequests(orgAdminCtx("org-b"))
+	require.NoError(t, err)
+	assert.Empty(t, requests)
+
+	request, err = m.ApproveRequest(orgAdminCtx("org-a"), request.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, domain.AccessRequestActive, request.State)
+	assert.Equal(t, "printers,corp-subnets", peers.routeSets("peer1"))
+}
+
+func TestManager_expireRequests(t *testing.T) {
+	m, peers := newTestManager(t)
+
+	pending, err := m.RequestAccess(userContext("alice", false), "peer1", "corp-subnets", 0, "")
+	require.NoError(t, err)
+
+	require.NoError(t, m.expireRequests(userContext("admin", true), time.Now().Add(48*time.Hour)))
+	_, err = m.ApproveRequest(userContext("admin", true), pending.Identifier)
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "expired requests cannot be approved")
+
+	active, err := m.RequestAccess(userContext("admin", true), "peer1", "corp-subnets", time.Hour, "")
+	require.NoError(t, err)
+
+	require.NoError(t, m.expireRequests(userContext("admin", true), time.Now().Add(30*time.Minute)))
+	assert.Equal(t, "printers,corp-subnets", peers.routeSets("peer1"),
+		"the access window is still open")
+
+	require.NoError(t, m.expireRequests(userContext("admin", true), time.Now().Add(2*time.Hour)))
+	assert.Equal(t, "printers", peers.routeSets("peer1"))
+
+	requests, err := m.GetAccessRequests(userContext("alice", false))
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	for _, request := range requests {
+		if request.Identifier == active.Identifier {
+			assert.Equal(t, domain.AccessRequestEnded, request.State)
+		} else {
+			assert.Equal(t, domain.AccessRequestExpired, request.State)
+		}
+	}
+
+	requests, err = m.GetAccessRequests(userContext("bob", false))
+	require.NoError(t, err)
+	assert.Empty(t, requests)
+}
+
+func TestManager_ApproveRequest_alreadyAttached(t *testing.T) {
+	m, peers := newTestManager(t)
+
+	request, err := m.RequestAccess(userContext("alice", false), "peer1", "corp-subnets", 0, "")
+	require.NoError(t, err)
+
+	// an admin attached the route set permanently while the request was pending
+	peer := peers.peers["peer1"]
+	peer.AddRouteSet("corp-subnets")
+	peers.peers["peer1"] = peer
+
+	request, err = m.ApproveRequest(userContext("admin", true), request.Identifier)
+	require.NoError(t, err)
+	assert.False(t, request.Granted)
+
+	request, err = m.CancelRequest(userContext("admin", true), request.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, domain.AccessRequestEnded, request.State)
+	assert.Equal(t, "printers,corp-subnets", peers.routeSets("peer1"),
+		"route sets that were not attached by the request are kept")
+}
+
+func TestManager_GetRouteSets(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	routeSets, err := m.GetRouteSets(userContext("alice", false))
+	require.NoError(t, err)
+	require.Len(t, routeSets, 2)
+	assert.Empty(t, routeSets[0].NetworksStr, "networks are hidden from users")
+
+	routeSets, err = m.GetRouteSets(userContext("admin", true))
+	require.NoError(t, err)
+	assert.Equal(t, "10.10.0.0/16", routeSets[0].NetworksStr)
+}
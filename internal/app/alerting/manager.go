@@ -0,0 +1,465 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers for the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	// GetPeersStats returns the stats for the given peer ids.
+	GetPeersStats(ctx context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error)
+	// GetAlerts returns all firing alerts.
+	GetAlerts(ctx context.Context) ([]domain.Alert, error)
+	// SaveAlert creates or updates the given alert.
+	SaveAlert(ctx context.Context, alert *domain.Alert) error
+	// DeleteAlert deletes the alert of the given rule and peer.
+	DeleteAlert(ctx context.Context, ruleName string, peerId domain.PeerIdentifier) error
+	// DeletePeerAlerts deletes all alerts of the given peer.
+	DeletePeerAlerts(ctx context.Context, peerId domain.PeerIdentifier) error
+	// GetAlertSilences returns all alert silences.
+	GetAlertSilences(ctx context.Context) ([]domain.AlertSilence, error)
+	// SaveAlertSilence creates or updates the given alert silence.
+	SaveAlertSilence(ctx context.Context, silence *domain.AlertSilence) error
+	// DeleteAlertSilence deletes the alert silence with the given id.
+	DeleteAlertSilence(ctx context.Context, id uint64) error
+	// GetUserGroup returns the user group with the given id.
+	GetUserGroup(ctx context.Context, id domain.UserGroupIdentifier) (*domain.UserGroup, error)
+	// GetUser returns the user with the given id.
+	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+}
+
+type Mailer interface {
+	// Send sends an email with the given subject and body to the given recipients.
+	Send(ctx context.Context, subject, body string, to []string, options *domain.MailOptions) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager periodically evaluates the configured alert rules for all peers and sends notifications for
+// firing and resolved alerts. Alerts can be acknowledged and notifications can be silenced for a time window.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db     DatabaseRepo
+	mailer Mailer
+
+	webhookClient  *http.Client
+	telegramClient *http.Client
+
+	mux *sync.Mutex // serializes rule evaluations and alert modifications
+}
+
+// NewAlertManager creates a new alert manager.
+func NewAlertManager(cfg *config.Config, bus EventBus, db DatabaseRepo, mailer Mailer) (*Manager, error) {
+	seenRules := make(map[string]struct{}, len(cfg.Alerting.Rules))
+	for _, rule := range cfg.Alerting.Rules {
+		if rule.Name == "" {
+			return nil, errors.New("alert rule without name")
+		}
+		if _, exists := seenRules[rule.Name]; exists {
+			return nil, fmt.Errorf("duplicate alert rule %s", rule.Name)
+		}
+		seenRules[rule.Name] = struct{}{}
+
+		switch rule.Condition {
+		case config.AlertConditionNoHandshake, config.AlertConditionHighLatency:
+		default:
+			return nil, fmt.Errorf("alert rule %s has unsupported condition %s", rule.Name, rule.Condition)
+		}
+	}
+
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:     db,
+		mailer: mailer,
+
+		webhookClient:  &http.Client{Timeout: cfg.Alerting.Webhook.Timeout},
+		telegramClient: &http.Client{Timeout: cfg.Alerting.Telegram.Timeout},
+
+		mux: &sync.Mutex{},
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background jobs for the alert manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.Alerting.Enabled {
+		return
+	}
+
+	go m.runAlertChecks(ctx)
+
+	slog.Debug("started alert rule checks", "rules", len(m.cfg.Alerting.Rules))
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerDeletedEvent)
+}
+
+func (m Manager) handlePeerDeletedEvent(peer domain.Peer) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if err := m.db.DeletePeerAlerts(ctx, peer.Identifier); err != nil {
+		slog.Error("failed to delete alerts of deleted peer", "peer", peer.Identifier, "error", err)
+	}
+}
+
+func (m Manager) runAlertChecks(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(m.cfg.Alerting.CheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		if err := m.evaluateRules(ctx, time.Now()); err != nil {
+			slog.Error("failed to evaluate alert rules", "error", err)
+		}
+	}
+}
+
+// evaluateRules checks all rules for all peers, updates the stored alerts and sends the notifications.
+func (m Manager) evaluateRules(ctx context.Context, now time.Time) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	storedAlerts, err := m.db.GetAlerts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load alerts: %w", err)
+	}
+	alerts := make(map[string]domain.Alert, len(storedAlerts))
+	for _, alert := range storedAlerts {
+		alerts[alertKey(alert.RuleName, alert.PeerId)] = alert
+	}
+
+	silences, err := m.db.GetAlertSilences(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load alert silences: %w", err)
+	}
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	evaluated := make(map[string]struct{})
+	for _, iface := range interfaces {
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			return fmt.Errorf("failed to load peers of interface %s: %w", iface.Identifier, err)
+		}
+
+		peerIds := make([]domain.PeerIdentifier, len(peers))
+		for i, peer := range peers {
+			peerIds[i] = peer.Identifier
+		}
+		stats, err := m.db.GetPeersStats(ctx, peerIds...)
+		if err != nil {
+			return fmt.Errorf("failed to load peer stats of interface %s: %w", iface.Identifier, err)
+		}
+		statsMap := make(map[domain.PeerIdentifier]domain.PeerStatus, len(stats))
+		for _, s := range stats {
+			statsMap[s.PeerId] = s
+		}
+
+		for _, peer := range peers {
+			var status *domain.PeerStatus
+			if s, ok := statsMap[peer.Identifier]; ok {
+				status = &s
+			}
+
+			for _, rule := range m.cfg.Alerting.Rules {
+				key := alertKey(rule.Name, peer.Identifier)
+				evaluated[key] = struct{}{}
+
+				violated, message := evaluateRule(rule, m.cfg.Statistics.UsePingChecks, peer, status, now)
+				existing, firing := alerts[key]
+				switch {
+				case violated && !firing:
+					m.fireAlert(ctx, rule, peer, message, silences, now)
+				case violated && firing:
+					m.repeatAlert(ctx, rule, existing, message, silences, now)
+				case !violated && firing:
+					m.resolveAlert(ctx, rule, existing, silences, now)
+				}
+			}
+		}
+	}
+
+	// remove alerts of rules that no longer exist or peers that are no longer available
+	for key, alert := range alerts {
+		if _, ok := evaluated[key]; ok {
+			continue
+		}
+		if err := m.db.DeleteAlert(ctx, alert.RuleName, alert.PeerId); err != nil {
+			slog.Warn("failed to delete stale alert", "rule", alert.RuleName, "peer", alert.PeerId, "error", err)
+		}
+	}
+
+	m.deleteExpiredSilences(ctx, silences, now)
+
+	return nil
+}
+
+func (m Manager) fireAlert(
+	ctx context.Context,
+	rule config.AlertRuleConfig,
+	peer domain.Peer,
+	message string,
+	silences []domain.AlertSilence,
+	now time.Time,
+) {
+	alert := domain.Alert{
+		RuleName:    rule.Name,
+		PeerId:      peer.Identifier,
+		InterfaceId: peer.InterfaceIdentifier,
+		PeerName:    peer.DisplayName,
+		Message:     message,
+		FiredAt:     now,
+	}
+
+	silence := findSilence(silences, rule.Name, peer.Identifier, now)
+	if silence == nil {
+		alert.LastNotifiedAt = &now
+	}
+
+	if err := m.db.SaveAlert(ctx, &alert); err != nil {
+		slog.Error("failed to store alert", "rule", rule.Name, "peer", peer.Identifier, "error", err)
+		return
+	}
+
+	slog.Info("alert fired", "rule", rule.Name, "peer", peer.Identifier, "message", message,
+		"silenced", silence != nil)
+
+	if silence == nil {
+		m.notify(ctx, rule, newAlertNotification(AlertStateFiring, rule, alert))
+	}
+}
+
+func (m Manager) repeatAlert(
+	ctx context.Context,
+	rule config.AlertRuleConfig,
+	alert domain.Alert,
+	message string,
+	silences []domain.AlertSilence,
+	now time.Time,
+) {
+	notify := notificationDue(rule, alert, now) && findSilence(silences, rule.Name, alert.PeerId, now) == nil
+	if !notify && alert.Message == message {
+		return // nothing changed
+	}
+
+	alert.Message = message
+	if notify {
+		alert.LastNotifiedAt = &now
+	}
+
+	if err := m.db.SaveAlert(ctx, &alert); err != nil {
+		slog.Error("failed to update alert", "rule", rule.Name, "peer", alert.PeerId, "error", err)
+		return
+	}
+
+	if notify {
+		m.notify(ctx, rule, newAlertNotification(AlertStateFiring, rule, alert))
+	}
+}
+
+func (m Manager) resolveAlert(
+	ctx context.Context,
+	rule config.AlertRuleConfig,
+	alert domain.Alert,
+	silences []domain.AlertSilence,
+	now time.Time,
+) {
+	if err := m.db.DeleteAlert(ctx, alert.RuleName, alert.PeerId); err != nil {
+		slog.Error("failed to delete resolved alert", "rule", rule.Name, "peer", alert.PeerId, "error", err)
+		return
+	}
+
+	slog.Info("alert resolved", "rule", rule.Name, "peer", alert.PeerId)
+
+	// only announce the resolution if the alert was announced before
+	if alert.LastNotifiedAt != nil && findSilence(silences, rule.Name, alert.PeerId, now) == nil {
+		m.notify(ctx, rule, newAlertNotification(AlertStateResolved, rule, alert))
+	}
+}
+
+func (m Manager) deleteExpiredSilences(ctx context.Context, silences []domain.AlertSilence, now time.Time) {
+	for _, silence := range silences {
+		if !silence.IsExpired(now) {
+			continue
+		}
+		if err := m.db.DeleteAlertSilence(ctx, silence.Id); err != nil {
+			slog.Warn("failed to delete expired alert silence", "silence", silence.Id, "error", err)
+		}
+	}
+}
+
+// GetAlertRules returns all configured alert rules.
+func (m Manager) GetAlertRules(ctx context.Context) ([]config.AlertRuleConfig, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.cfg.Alerting.Rules, nil
+}
+
+// GetAlerts returns all firing alerts.
+func (m Manager) GetAlerts(ctx context.Context) ([]domain.Alert, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetAlerts(ctx)
+}
+
+// AcknowledgeAlert acknowledges the firing alert of the given rule and peer.
+// No further notifications are sent for acknowledged alerts until they are resolved.
+func (m Manager) AcknowledgeAlert(ctx context.Context, ruleName string, peerId domain.PeerIdentifier) (
+	*domain.Alert,
+	error,
+) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	alerts, err := m.db.GetAlerts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alerts: %w", err)
+	}
+
+	for _, alert := range alerts {
+		if alert.RuleName != ruleName || alert.PeerId != peerId {
+			continue
+		}
+
+		if alert.IsAcknowledged() {
+			return &alert, nil
+		}
+
+		now := time.Now()
+		alert.AcknowledgedAt = &now
+		alert.AcknowledgedBy = domain.GetUserInfo(ctx).Id
+		if err := m.db.SaveAlert(ctx, &alert); err != nil {
+			return nil, fmt.Errorf("failed to acknowledge alert: %w", err)
+		}
+
+		return &alert, nil
+	}
+
+	return nil, errors.Join(fmt.Errorf("no firing alert for rule %s and peer %s", ruleName, peerId),
+		domain.ErrNotFound)
+}
+
+// GetSilences returns all alert silences that have not expired yet.
+func (m Manager) GetSilences(ctx context.Context) ([]domain.AlertSilence, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	silences, err := m.db.GetAlertSilences(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	activeSilences := make([]domain.AlertSilence, 0, len(silences))
+	for _, silence := range silences {
+		if !silence.IsExpired(now) {
+			activeSilences = append(activeSilences, silence)
+		}
+	}
+
+	return activeSilences, nil
+}
+
+// CreateSilence creates a new alert silence.
+func (m Manager) CreateSilence(ctx context.Context, silence *domain.AlertSilence) (*domain.AlertSilence, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if !silence.EndsAt.After(silence.StartsAt) {
+		return nil, errors.Join(errors.New("silence must end after it starts"), domain.ErrInvalidData)
+	}
+	if silence.IsExpired(time.Now()) {
+		return nil, errors.Join(errors.New("silence already ended"), domain.ErrInvalidData)
+	}
+	if silence.RuleName != "" && !m.ruleExists(silence.RuleName) {
+		return nil, errors.Join(fmt.Errorf("unknown alert rule %s", silence.RuleName), domain.ErrInvalidData)
+	}
+
+	silence.Id = 0
+	silence.CreatedBy = domain.GetUserInfo(ctx).Id
+	silence.CreatedAt = time.Now()
+
+	if err := m.db.SaveAlertSilence(ctx, silence); err != nil {
+		return nil, fmt.Errorf("failed to store silence: %w", err)
+	}
+
+	return silence, nil
+}
+
+// DeleteSilence deletes the alert silence with the given id.
+func (m Manager) DeleteSilence(ctx context.Context, id uint64) error {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	if err := m.db.DeleteAlertSilence(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete silence: %w", err)
+	}
+
+	return nil
+}
+
+func (m Manager) ruleExists(name string) bool {
+	for _, rule := range m.cfg.Alerting.Rules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func alertKey(ruleName string, peerId domain.PeerIdentifier) string {
+	return ruleName + "/" + string(peerId)
+}
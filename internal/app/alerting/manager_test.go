@@ -0,0 +1,244 @@
+package alerting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	peers    []domain.Peer
+	stats    []domain.PeerStatus
+	alerts   map[string]domain.Alert
+	silences []domain.AlertSilence
+	groups   map[domain.UserGroupIdentifier]domain.UserGroup
+	users    map[domain.UserIdentifier]domain.User
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return []domain.Interface{{Identifier: "wg0"}}, nil
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	return f.peers, nil
+}
+
+func (f *fakeDatabase) GetPeersStats(_ context.Context, _ ...domain.PeerIdentifier) ([]domain.PeerStatus, error) {
+	return f.stats, nil
+}
+
+func (f *fakeDatabase) GetAlerts(_ context.Context) ([]domain.Alert, error) {
+	var alerts []domain.Alert
+	for _, alert := range f.alerts {
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+func (f *fakeDatabase) SaveAlert(_ context.Context, alert *domain.Alert) error {
+	f.alerts[alertKey(alert.RuleName, alert.PeerId)] = *alert
+	return nil
+}
+
+func (f *fakeDatabase) DeleteAlert(_ context.Context, ruleName string, peerId domain.PeerIdentifier) error {
+	delete(f.alerts, alertKey(ruleName, peerId))
+	return nil
+}
+
+func (f *fakeDatabase) DeletePeerAlerts(_ context.Context, _ domain.PeerIdentifier) error {
+	return nil
+}
+
+func (f *fakeDatabase) GetAlertSilences(_ context.Context) ([]domain.AlertSilence, error) {
+	return f.silences, nil
+}
+
+func (f *fakeDatabase) SaveAlertSilence(_ context.Context, silence *domain.AlertSilence) error {
+	f.silences = append(f.silences, *silence)
+	return nil
+}
+
+func (f *fakeDatabase) DeleteAlertSilence(_ context.Context, _ uint64) error {
+	return nil
+}
+
+func (f *fakeDatabase) GetUserGroup(_ context.Context, id domain.UserGroupIdentifier) (*domain.UserGroup, error) {
+	group, ok := f.groups[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &group, nil
+}
+
+func (f *fakeDatabase) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &user, nil
+}
+
+type fakeMailer struct {
+	subjects []string
+}
+
+func (f *fakeMailer) Send(_ context.Context, subject, _ string, _ []string, _ *domain.MailOptions) error {
+	f.subjects = append(f.subjects, subject)
+	return nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func Test_evaluateRule(t *testing.T) {
+	now := time.Now()
+	rule := config.AlertRuleConfig{
+		Name:      "critical",
+		Condition: config.AlertConditionNoHandshake,
+		Threshold: 10 * time.Minute,
+		Tag:       "#critical",
+	}
+	peer := domain.Peer{
+		BaseModel:           domain.BaseModel{CreatedAt: now.Add(-time.Hour)},
+		Identifier:          "peer",
+		InterfaceIdentifier: "wg0",
+		Notes:               "branch office router #CRITICAL",
+	}
+
+	recent := now.Add(-5 * time.Minute)
+	violated, _ := evaluateRule(rule, false, peer, &domain.PeerStatus{LastHandshake: &recent}, now)
+	assert.False(t, violated)
+
+	old := now.Add(-15 * time.Minute)
+	violated, message := evaluateRule(rule, false, peer, &domain.PeerStatus{LastHandshake: &old}, now)
+	assert.True(t, violated)
+	assert.Contains(t, message, "no handshake since")
+
+	violated, _ = evaluateRule(rule, false, peer, nil, now)
+	assert.True(t, violated, "peers that never connected are measured from their creation time")
+
+	untagged := peer
+	untagged.Notes = ""
+	violated, _ = evaluateRule(rule, false, untagged, nil, now)
+	assert.False(t, violated)
+
+	latencyRule := config.AlertRuleConfig{Condition: config.AlertConditionHighLatency, Threshold: 100 * time.Millisecond}
+	violated, _ = evaluateRule(latencyRule, false, peer, &domain.PeerStatus{}, now)
+	assert.False(t, violated, "latency rules require ping checks")
+	violated, _ = evaluateRule(latencyRule, true, peer, &domain.PeerStatus{IsPingable: true, LastRtt: 20 * time.Millisecond}, now)
+	assert.False(t, violated)
+	violated, _ = evaluateRule(latencyRule, true, peer, &domain.PeerStatus{IsPingable: true, LastRtt: 200 * time.Millisecond}, now)
+	assert.True(t, violated)
+	violated, _ = evaluateRule(latencyRule, true, peer, &domain.PeerStatus{IsPingable: false}, now)
+	assert.True(t, violated)
+}
+
+func TestManager_evaluateRules(t *testing.T) {
+	now := time.Now()
+	cfg := &config.Config{}
+	cfg.Alerting.Rules = []config.AlertRuleConfig{{
+		Name:           "handshake",
+		Condition:      config.AlertConditionNoHandshake,
+		Threshold:      10 * time.Minute,
+		Channels:       []config.AlertChannel{config.AlertChannelMail},
+		RepeatInterval: time.Hour,
+	}}
+	cfg.Alerting.MailRecipients = []string{"ops@example.com"}
+
+	old := now.Add(-time.Hour)
+	db := &fakeDatabase{
+		peers: []domain.Peer{{
+			BaseModel:           domain.BaseModel{CreatedAt: now.Add(-2 * time.Hour)},
+			Identifier:          "peer",
+			DisplayName:         "Router",
+			InterfaceIdentifier: "wg0",
+		}},
+		stats:  []domain.PeerStatus{{PeerId: "peer", LastHandshake: &old}},
+		alerts: map[string]domain.Alert{},
+	}
+	mailer := &fakeMailer{}
+	m, err := NewAlertManager(cfg, fakeBus{}, db, mailer)
+	require.NoError(t, err)
+
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	// fire
+	require.NoError(t, m.evaluateRules(ctx, now))
+	assert.Len(t, db.alerts, 1)
+	assert.Equal(t, []string{"[FIRING] handshake: Router"}, mailer.subjects)
+
+	// no repeated notification within the repeat interval
+	require.NoError(t, m.evaluateRules(ctx, now.Add(time.Minute)))
+	assert.Len(t, mailer.subjects, 1)
+
+	// acknowledged alerts are not repeated
+	_, err = m.AcknowledgeAlert(ctx, "handshake", "peer")
+	require.NoError(t, err)
+	require.NoError(t, m.evaluateRules(ctx, now.Add(2*time.Hour)))
+	assert.Len(t, mailer.subjects, 1)
+
+	// silenced resolutions are not announced
+	db.silences = []domain.AlertSilence{{RuleName: "handshake", StartsAt: now, EndsAt: now.Add(24 * time.Hour)}}
+	recent := now.Add(2 * time.Hour)
+	db.stats[0].LastHandshake = &recent
+	require.NoError(t, m.evaluateRules(ctx, now.Add(2*time.Hour)))
+	assert.Empty(t, db.alerts)
+	assert.Len(t, mailer.subjects, 1)
+
+	// firing while silenced does not notify, the notification is sent once the silence ended
+	db.stats[0].LastHandshake = &old
+	require.NoError(t, m.evaluateRules(ctx, now.Add(3*time.Hour)))
+	assert.Len(t, db.alerts, 1)
+	assert.Len(t, mailer.subjects, 1)
+	require.NoError(t, m.evaluateRules(ctx, now.Add(25*time.Hour)))
+	assert.Len(t, mailer.subjects, 2)
+
+	// resolve
+	resolved := now.Add(26 * time.Hour)
+	db.stats[0].LastHandshake = &resolved
+	require.NoError(t, m.evaluateRules(ctx, now.Add(26*time.Hour)))
+	assert.Empty(t, db.alerts)
+	assert.Equal(t, "[RESOLVED] handshake: Router", mailer.subjects[2])
+}
+
+func TestManager_mailRecipients(t *testing.T) {
+	now := time.Now()
+	cfg := &config.Config{}
+	cfg.Alerting.MailRecipients = []string{"ops@example.com"}
+	cfg.Alerting.MailGroups = []string{"noc", "missing"}
+	db := &fakeDatabase{
+		groups: map[domain.UserGroupIdentifier]domain.UserGroup{
+			"noc": {Identifier: "noc", MembersStr: "alice,bob,ci,ops,unknown"},
+		},
+		users: map[domain.UserIdentifier]domain.User{
+			"alice": {Identifier: "alice", Email: "alice@example.com"},
+			"bob":   {Identifier: "bob", Email: "bob@example.com", Disabled: &now},
+			"ci":    {Identifier: "ci", Type: domain.UserTypeService},
+			"ops":   {Identifier: "ops", Email: "ops@example.com"},
+		},
+	}
+	m := Manager{cfg: cfg, db: db}
+
+	assert.Equal(t, []string{"ops@example.com", "alice@example.com"}, m.mailRecipients(context.Background()))
+}
+
+func TestNewAlertManager_invalidRules(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Alerting.Rules = []config.AlertRuleConfig{{Name: "a", Condition: "unknown"}}
+	_, err := NewAlertManager(cfg, fakeBus{}, &fakeDatabase{}, &fakeMailer{})
+	assert.Error(t, err)
+
+	cfg.Alerting.Rules = []config.AlertRuleConfig{
+		{Name: "a", Condition: config.AlertConditionNoHandshake},
+		{Name: "a", Condition: config.AlertConditionHighLatency},
+	}
+	_, err = NewAlertManager(cfg, fakeBus{}, &fakeDatabase{}, &fakeMailer{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,203 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AlertState = string
+
+const (
+	AlertStateFiring   AlertState = "firing"
+	AlertStateResolved AlertState = "resolved"
+)
+
+// AlertNotification is the payload that is sent to the webhook channel.
+type AlertNotification struct {
+	// State is the alert state (firing, resolved)
+	State AlertState `json:"state" example:"firing"`
+
+	Rule        string `json:"rule" example:"critical-handshake"`
+	Description string `json:"description"`
+
+	PeerIdentifier      string `json:"peer_identifier"`
+	PeerName            string `json:"peer_name"`
+	InterfaceIdentifier string `json:"interface_identifier"`
+
+	Message string    `json:"message"`
+	FiredAt time.Time `json:"fired_at"`
+}
+
+func newAlertNotification(state AlertState, rule config.AlertRuleConfig, alert domain.Alert) AlertNotification {
+	return AlertNotification{
+		State:               state,
+		Rule:                rule.Name,
+		Description:         rule.Description,
+		PeerIdentifier:      string(alert.PeerId),
+		PeerName:            alert.PeerName,
+		InterfaceIdentifier: string(alert.InterfaceId),
+		Message:             alert.Message,
+		FiredAt:             alert.FiredAt,
+	}
+}
+
+// Subject returns a short summary of the notification.
+func (n AlertNotification) Subject() string {
+	peerName := n.PeerName
+	if peerName == "" {
+		peerName = n.PeerIdentifier
+	}
+	return fmt.Sprintf("[%s] %s: %s", strings.ToUpper(n.State), n.Rule, peerName)
+}
+
+// Text returns the plain text body of the notification.
+func (n AlertNotification) Text() string {
+	var sb strings.Builder
+	sb.WriteString(n.Subject() + "\n\n")
+	if n.Description != "" {
+		sb.WriteString(n.Description + "\n\n")
+	}
+	sb.WriteString("Peer: " + n.PeerIdentifier + "\n")
+	if n.PeerName != "" {
+		sb.WriteString("Name: " + n.PeerName + "\n")
+	}
+	sb.WriteString("Interface: " + n.InterfaceIdentifier + "\n")
+	if n.State == AlertStateFiring {
+		sb.WriteString("Problem: " + n.Message + "\n")
+	}
+	sb.WriteString("Fired at: " + n.FiredAt.Format(time.RFC3339) + "\n")
+	return sb.String()
+}
+
+func (m Manager) notify(ctx context.Context, rule config.AlertRuleConfig, n AlertNotification) {
+	for _, channel := range rule.Channels {
+		var err error
+		switch channel {
+		case config.AlertChannelMail:
+			err = m.notifyMail(ctx, n)
+		case config.AlertChannelWebhook:
+			err = m.notifyWebhook(ctx, n)
+		case config.AlertChannelTelegram:
+			err = m.notifyTelegram(ctx, n)
+		default:
+			err = errors.New("unsupported channel")
+		}
+		if err != nil {
+			slog.Error("failed to send alert notification",
+				"rule", rule.Name, "peer", n.PeerIdentifier, "channel", channel, "error", err)
+		}
+	}
+}
+
+func (m Manager) notifyMail(ctx context.Context, n AlertNotification) error {
+	recipients := m.mailRecipients(ctx)
+	if len(recipients) == 0 {
+		return errors.New("no mail recipients configured")
+	}
+
+	return m.mailer.Send(ctx, n.Subject(), n.Text(), recipients, &domain.MailOptions{})
+}
+
+// mailRecipients returns the configured mail recipients and the mail addresses of all active members of the
+// configured user groups.
+func (m Manager) mailRecipients(ctx context.Context) []string {
+	recipients := slices.Clone(m.cfg.Alerting.MailRecipients)
+	for _, groupId := range m.cfg.Alerting.MailGroups {
+		group, err := m.db.GetUserGroup(ctx, domain.UserGroupIdentifier(groupId))
+		if err != nil {
+			slog.Warn("failed to load user group for alert notifications", "group", groupId, "error", err)
+			continue
+		}
+
+		for _, userId := range group.Members() {
+			user, err := m.db.GetUser(ctx, userId)
+			if err != nil || user.IsDisabled() || user.IsServiceAccount() || user.Email == "" {
+				continue
+			}
+			if !slices.Contains(recipients, user.Email) {
+				recipients = append(recipients, user.Email)
+			}
+		}
+	}
+
+	return recipients
+}
+
+func (m Manager) notifyWebhook(ctx context.Context, n AlertNotification) error {
+	if m.cfg.Alerting.Webhook.Url == "" {
+		return errors.New("no webhook url configured")
+	}
+
+	data, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to serialize notification: %w", err)
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	if m.cfg.Alerting.Webhook.Authentication != "" {
+		headers["Authorization"] = m.cfg.Alerting.Webhook.Authentication
+	}
+
+	return m.post(ctx, m.webhookClient, m.cfg.Alerting.Webhook.Url, headers, bytes.NewReader(data))
+}
+
+func (m Manager) notifyTelegram(ctx context.Context, n AlertNotification) error {
+	cfg := m.cfg.Alerting.Telegram
+	if cfg.BotToken == "" || cfg.ChatId == "" {
+		return errors.New("telegram bot token or chat id not configured")
+	}
+
+	form := url.Values{}
+	form.Set("chat_id", cfg.ChatId)
+	form.Set("text", n.Text())
+	form.Set("disable_web_page_preview", "true")
+
+	endpoint := strings.TrimSuffix(cfg.ApiUrl, "/") + "/bot" + cfg.BotToken + "/sendMessage"
+	headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+
+	return m.post(ctx, m.telegramClient, endpoint, headers, strings.NewReader(form.Encode()))
+}
+
+func (m Manager) post(ctx context.Context, client *http.Client, endpoint string, headers map[string]string,
+	body io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// strip the url from the error, the url of telegram requests contains the bot token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("%s request failed: %w", urlErr.Op, urlErr.Err)
+		}
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		_, _ = io.Copy(io.Discard, Body)
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("request failed with status: %s", resp.Status)
+	}
+
+	return nil
+}
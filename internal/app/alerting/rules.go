@@ -0,0 +1,101 @@
+package alerting
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// ruleApplies returns true if the given rule checks the given peer.
+func ruleApplies(rule config.AlertRuleConfig, peer domain.Peer) bool {
+	if peer.IsDisabled() {
+		return false
+	}
+	if rule.Tag != "" && !peer.HasTag(rule.Tag) {
+		return false
+	}
+	if len(rule.Interfaces) > 0 && !slices.Contains(rule.Interfaces, string(peer.InterfaceIdentifier)) {
+		return false
+	}
+
+	return true
+}
+
+// evaluateRule checks the rule condition for the given peer.
+// If the condition is violated, true and a short description of the violation are returned.
+func evaluateRule(
+	rule config.AlertRuleConfig,
+	pingChecks bool,
+	peer domain.Peer,
+	status *domain.PeerStatus,
+	now time.Time,
+) (bool, string) {
+	if !ruleApplies(rule, peer) {
+		return false, ""
+	}
+
+	switch rule.Condition {
+	case config.AlertConditionNoHandshake:
+		// peers that never connected are measured from their creation time
+		lastHandshake := peer.CreatedAt
+		if status != nil && status.LastHandshake != nil {
+			lastHandshake = *status.LastHandshake
+		}
+		if now.Sub(lastHandshake) <= rule.Threshold {
+			return false, ""
+		}
+		// messages must not change while the alert is firing, otherwise the alert is updated on each check
+		if status == nil || status.LastHandshake == nil {
+			return true, fmt.Sprintf("no handshake since the peer was created at %s",
+				lastHandshake.Format(time.RFC3339))
+		}
+		return true, fmt.Sprintf("no handshake since %s (threshold %s)",
+			lastHandshake.Format(time.RFC3339), rule.Threshold)
+	case config.AlertConditionHighLatency:
+		if !pingChecks || status == nil {
+			return false, "" // no latency data available
+		}
+		if !status.IsPingable {
+			return true, "peer is not reachable by ping"
+		}
+		if status.LastRtt > rule.Threshold {
+			return true, fmt.Sprintf("ping round-trip time exceeds %s", rule.Threshold)
+		}
+		return false, ""
+	default:
+		return false, ""
+	}
+}
+
+// findSilence returns the first active silence that matches the given rule and peer, or nil.
+func findSilence(
+	silences []domain.AlertSilence,
+	ruleName string,
+	peerId domain.PeerIdentifier,
+	now time.Time,
+) *domain.AlertSilence {
+	for i := range silences {
+		if silences[i].Matches(ruleName, peerId, now) {
+			return &silences[i]
+		}
+	}
+	return nil
+}
+
+// notificationDue returns true if a repeated notification should be sent for the given firing alert.
+func notificationDue(rule config.AlertRuleConfig, alert domain.Alert, now time.Time) bool {
+	if alert.IsAcknowledged() {
+		return false
+	}
+	if alert.LastNotifiedAt == nil {
+		return true // the alert was silenced when it fired
+	}
+	if rule.RepeatInterval <= 0 {
+		return false
+	}
+
+	return !now.Before(alert.LastNotifiedAt.Add(rule.RepeatInterval))
+}
@@ -0,0 +1,356 @@
+package announcements
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// mailInterval is the interval in which started announcements are mailed to the targeted users.
+const mailInterval = 1 * time.Minute
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAnnouncement returns the announcement with the given id.
+	GetAnnouncement(ctx context.Context, id uint64) (*domain.Announcement, error)
+	// GetAnnouncements returns all announcements, the most recently started announcements first.
+	GetAnnouncements(ctx context.Context) ([]domain.Announcement, error)
+	// SaveAnnouncement creates or updates the given announcement.
+	SaveAnnouncement(ctx context.Context, announcement *domain.Announcement) error
+	// DeleteAnnouncement deletes the announcement with the given id and all read markers of it.
+	DeleteAnnouncement(ctx context.Context, id uint64) error
+	// GetAnnouncementReads returns all read markers of the given announcement.
+	GetAnnouncementReads(ctx context.Context, id uint64) ([]domain.AnnouncementRead, error)
+	// SaveAnnouncementRead creates or updates the given read marker.
+	SaveAnnouncementRead(ctx context.Context, read *domain.AnnouncementRead) error
+	// GetAllUserGroups returns all user groups.
+	GetAllUserGroups(ctx context.Context) ([]domain.UserGroup, error)
+	// GetAllUsers returns all users.
+	GetAllUsers(ctx context.Context) ([]domain.User, error)
+}
+
+type MailManager interface {
+	// SendAnnouncement sends the given announcement to the given user.
+	SendAnnouncement(ctx context.Context, userId domain.UserIdentifier, announcement *domain.Announcement) error
+}
+
+// endregion dependencies
+
+// Manager manages the announcements of the administrators. Active announcements are shown as banner in the portal
+// to all targeted users until they mark them as read. Announcements can also be sent by mail once they start.
+// Only global administrators can manage announcements.
+type Manager struct {
+	cfg *config.Config
+
+	db   DatabaseRepo
+	mail MailManager
+}
+
+// NewAnnouncementManager creates a new announcement manager instance.
+func NewAnnouncementManager(cfg *config.Config, db DatabaseRepo, mail MailManager) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+
+		db:   db,
+		mail: mail,
+	}
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background jobs for the announcement manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	go m.runMailDelivery(ctx)
+
+	slog.Debug("started announcement mail delivery")
+}
+
+func (m Manager) runMailDelivery(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	ticker := time.NewTicker(mailInterval)
+	defer ticker.Stop()
+	for {
+		if err := m.sendMails(ctx, time.Now()); err != nil {
+			slog.Error("failed to send announcement mails", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return // program stopped
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendMails mails all active announcements that were not mailed yet to the targeted users. Disabled users are
+// skipped. Each announcement is mailed once, even if some of the mails could not be sent.
+func (m Manager) sendMails(ctx context.Context, now time.Time) error {
+	announcements, err := m.db.GetAnnouncements(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load announcements: %w", err)
+	}
+
+	var pending []domain.Announcement
+	for _, announcement := range announcements {
+		if announcement.SendMail && announcement.MailedAt == nil && announcement.IsActive(now) {
+			pending = append(pending, announcement)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	groups, err := m.db.GetAllUserGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load user groups: %w", err)
+	}
+	users, err := m.db.GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+
+	for _, announcement := range pending {
+		sent := 0
+		for _, user := range users {
+			if user.IsDisabled() || !announcement.IsTargeted(domain.GroupsOfUser(groups, user.Identifier)) {
+				continue
+			}
+
+			if err := m.mail.SendAnnouncement(ctx, user.Identifier, &announcement); err != nil {
+				slog.Warn("failed to send announcement mail",
+					"announcement", announcement.Id,
+					"user", user.Identifier,
+					"error", err)
+				continue
+			}
+			sent++
+		}
+
+		announcement.MailedAt = &now
+		if err := m.db.SaveAnnouncement(ctx, &announcement); err != nil {
+			return fmt.Errorf("failed to store mail state of announcement %d: %w", announcement.Id, err)
+		}
+		slog.Info("sent announcement mails", "announcement", announcement.Id, "recipients", sent)
+	}
+
+	return nil
+}
+
+// GetAnnouncements returns all announcements, including the number of users that read them.
+func (m Manager) GetAnnouncements(ctx context.Context) ([]domain.AnnouncementStatus, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	announcements, err := m.db.GetAnnouncements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load announcements: %w", err)
+	}
+
+	result := make([]domain.AnnouncementStatus, len(announcements))
+	for i, announcement := range announcements {
+		reads, err := m.db.GetAnnouncementReads(ctx, announcement.Id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load reads of announcement %d: %w", announcement.Id, err)
+		}
+		result[i] = domain.AnnouncementStatus{Announcement: announcement, ReadCount: len(reads)}
+	}
+
+	return result, nil
+}
+
+// GetActiveAnnouncements returns all active announcements that target the current user, including their read state.
+func (m Manager) GetActiveAnnouncements(ctx context.Context) ([]domain.AnnouncementStatus, error) {
+	userId := domain.GetUserInfo(ctx).Id
+
+	announcements, err := m.getActiveUserAnnouncements(ctx, userId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.AnnouncementStatus, len(announcements))
+	for i, announcement := range announcements {
+		read, err := m.isRead(ctx, announcement.Id, userId)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = domain.AnnouncementStatus{Announcement: announcement, Read: read}
+	}
+
+	return result, nil
+}
+
+// MarkAnnouncementRead marks the given announcement as read by the current user. Only active announcements that
+// target the user can be marked as read.
+func (m Manager) MarkAnnouncementRead(ctx context.Context, id uint64) error {
+	userId := domain.GetUserInfo(ctx).Id
+	now := time.Now()
+
+	announcements, err := m.getActiveUserAnnouncements(ctx, userId, now)
+	if err != nil {
+		return err
+	}
+
+	for _, announcement := range announcements {
+		if announcement.Id != id {
+			continue
+		}
+
+		read, err := m.isRead(ctx, id, userId)
+		if err != nil || read {
+			return err
+		}
+
+		err = m.db.SaveAnnouncementRead(ctx, &domain.AnnouncementRead{
+			AnnouncementId: id,
+			UserIdentifier: userId,
+			ReadAt:         now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mark announcement %d as read: %w", id, err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("announcement %d is not active: %w", id, domain.ErrNotFound)
+}
+
+// getActiveUserAnnouncements returns all announcements that are active at the given time and target the given user.
+func (m Manager) getActiveUserAnnouncements(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	now time.Time,
+) ([]domain.Announcement, error) {
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	announcements, err := m.db.GetAnnouncements(systemCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load announcements: %w", err)
+	}
+	groups, err := m.db.GetAllUserGroups(systemCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user groups: %w", err)
+	}
+	userGroups := domain.GroupsOfUser(groups, userId)
+
+	var active []domain.Announcement
+	for _, announcement := range announcements {
+		if announcement.IsActive(now) && announcement.IsTargeted(userGroups) {
+			active = append(active, announcement)
+		}
+	}
+
+	return active, nil
+}
+
+// isRead returns true if the given user marked the announcement as read.
+func (m Manager) isRead(ctx context.Context, id uint64, userId domain.UserIdentifier) (bool, error) {
+	reads, err := m.db.GetAnnouncementReads(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to load reads of announcement %d: %w", id, err)
+	}
+
+	for _, read := range reads {
+		if read.UserIdentifier == userId {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// CreateAnnouncement creates a new announcement.
+func (m Manager) CreateAnnouncement(
+	ctx context.Context,
+	announcement *domain.Announcement,
+) (*domain.Announcement, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := validateAnnouncement(announcement); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	announcement.Id = 0
+	announcement.MailedAt = nil
+	announcement.CreatedBy = domain.GetUserInfo(ctx).Id
+	announcement.CreatedAt = now
+	announcement.UpdatedAt = now
+
+	if err := m.db.SaveAnnouncement(ctx, announcement); err != nil {
+		return nil, fmt.Errorf("failed to create announcement: %w", err)
+	}
+
+	slog.Info("announcement created", "announcement", announcement.Id, "by", announcement.CreatedBy)
+
+	return announcement, nil
+}
+
+// UpdateAnnouncement updates the given announcement. Announcements that were already mailed are not mailed again.
+func (m Manager) UpdateAnnouncement(
+	ctx context.Context,
+	announcement *domain.Announcement,
+) (*domain.Announcement, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	existing, err := m.db.GetAnnouncement(ctx, announcement.Id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load announcement %d: %w", announcement.Id, err)
+	}
+
+	if err := validateAnnouncement(announcement); err != nil {
+		return nil, err
+	}
+
+	announcement.MailedAt = existing.MailedAt
+	announcement.CreatedBy = existing.CreatedBy
+	announcement.CreatedAt = existing.CreatedAt
+	announcement.UpdatedAt = time.Now()
+
+	if err := m.db.SaveAnnouncement(ctx, announcement); err != nil {
+		return nil, fmt.Errorf("failed to update announcement %d: %w", announcement.Id, err)
+	}
+
+	return announcement, nil
+}
+
+// DeleteAnnouncement deletes the announcement with the given id.
+func (m Manager) DeleteAnnouncement(ctx context.Context, id uint64) error {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	if _, err := m.db.GetAnnouncement(ctx, id); err != nil {
+		return fmt.Errorf("unable to load announcement %d: %w", id, err)
+	}
+
+	if err := m.db.DeleteAnnouncement(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete announcement %d: %w", id, err)
+	}
+
+	return nil
+}
+
+func validateAnnouncement(announcement *domain.Announcement) error {
+	announcement.Title = strings.TrimSpace(announcement.Title)
+	announcement.Message = strings.TrimSpace(announcement.Message)
+
+	if err := announcement.Validate(); err != nil {
+		return errors.Join(fmt.Errorf("invalid announcement: %w", err), domain.ErrInvalidData)
+	}
+
+	return nil
+}
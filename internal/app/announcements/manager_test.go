@@ -0,0 +1,187 @@
+package announcements
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	announcements map[uint64]domain.Announcement
+	reads         []domain.AnnouncementRead
+	groups        []domain.UserGroup
+	users         []domain.User
+}
+
+func (f *fakeDatabase) GetAnnouncement(_ context.Context, id uint64) (*domain.Announcement, error) {
+	announcement, ok := f.announcements[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &announcement, nil
+}
+
+func (f *fakeDatabase) GetAnnouncements(_ context.Context) ([]domain.Announcement, error) {
+	var announcements []domain.Announcement
+	for _, announcement := range f.announcements {
+		announcements = append(announcements, announcement)
+	}
+	return announcements, nil
+}
+
+func (f *fakeDatabase) SaveAnnouncement(_ context.Context, announcement *domain.Announcement) error {
+	if announcement.Id == 0 {
+		announcement.Id = uint64(len(f.announcements) + 1)
+	}
+	f.announcements[announcement.Id] = *announcement
+	return nil
+}
+
+func (f *fakeDatabase) DeleteAnnouncement(_ context.Context, id uint64) error {
+	delete(f.announcements, id)
+	return nil
+}
+
+func (f *fakeDatabase) GetAnnouncementReads(_ context.Context, id uint64) ([]domain.AnnouncementRead, error) {
+	var reads []domain.AnnouncementRead
+	for _, read := range f.reads {
+		if read.AnnouncementId == id {
+			reads = append(reads, read)
+		}
+	}
+	return reads, nil
+}
+
+func (f *fakeDatabase) SaveAnnouncementRead(_ context.Context, read *domain.AnnouncementRead) error {
+	f.reads = append(f.reads, *read)
+	return nil
+}
+
+func (f *fakeDatabase) GetAllUserGroups(_ context.Context) ([]domain.UserGroup, error) {
+	return f.groups, nil
+}
+
+func (f *fakeDatabase) GetAllUsers(_ context.Context) ([]domain.User, error) {
+	return f.users, nil
+}
+
+type fakeMailManager struct {
+	recipients []domain.UserIdentifier
+}
+
+func (f *fakeMailManager) SendAnnouncement(
+	_ context.Context,
+	userId domain.UserIdentifier,
+	_ *domain.Announcement,
+) error {
+	f.recipients = append(f.recipients, userId)
+	return nil
+}
+
+func newTestManager() (*Manager, *fakeDatabase, *fakeMailManager) {
+	past := time.Now().Add(-time.Hour)
+	db := &fakeDatabase{
+		announcements: map[uint64]domain.Announcement{},
+		groups:        []domain.UserGroup{{Identifier: "lab-staff", MembersStr: "alice"}},
+		users: []domain.User{
+			{Identifier: "alice"},
+			{Identifier: "bob"},
+			{Identifier: "carol", Disabled: &past},
+		},
+	}
+	mail := &fakeMailManager{}
+	m, _ := NewAnnouncementManager(&config.Config{}, db, mail)
+	return m, db, mail
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+}
+
+func userContext(id domain.UserIdentifier) context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: id})
+}
+
+func TestManager_CreateAnnouncement(t *testing.T) {
+	m, db, _ := newTestManager()
+
+	announcement, err := m.CreateAnnouncement(adminContext(), &domain.Announcement{
+		Kind:     domain.AnnouncementKindInfo,
+		Title:    " News ",
+		StartsAt: time.Now(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "News", announcement.Title)
+	assert.Equal(t, domain.UserIdentifier("admin"), announcement.CreatedBy)
+	assert.Len(t, db.announcements, 1)
+
+	_, err = m.CreateAnnouncement(adminContext(), &domain.Announcement{Kind: "urgent", Title: "News",
+		StartsAt: time.Now()})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, err = m.CreateAnnouncement(userContext("alice"), &domain.Announcement{Kind: domain.AnnouncementKindInfo,
+		Title: "News", StartsAt: time.Now()})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_GetActiveAnnouncements(t *testing.T) {
+	m, db, _ := newTestManager()
+	now := time.Now()
+	future := now.Add(time.Hour)
+	db.announcements[1] = domain.Announcement{Id: 1, Title: "all", StartsAt: now.Add(-time.Hour)}
+	db.announcements[2] = domain.Announcement{Id: 2, Title: "lab", StartsAt: now.Add(-time.Hour),
+		TargetGroupsStr: "lab-staff"}
+	db.announcements[3] = domain.Announcement{Id: 3, Title: "scheduled", StartsAt: future}
+
+	active, err := m.GetActiveAnnouncements(userContext("alice"))
+	require.NoError(t, err)
+	assert.Len(t, active, 2)
+
+	active, err = m.GetActiveAnnouncements(userContext("bob"))
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "all", active[0].Title)
+	assert.False(t, active[0].Read)
+
+	require.NoError(t, m.MarkAnnouncementRead(userContext("bob"), 1))
+	require.NoError(t, m.MarkAnnouncementRead(userContext("bob"), 1), "marking twice is allowed")
+	assert.Len(t, db.reads, 1)
+	active, err = m.GetActiveAnnouncements(userContext("bob"))
+	require.NoError(t, err)
+	assert.True(t, active[0].Read)
+
+	assert.ErrorIs(t, m.MarkAnnouncementRead(userContext("bob"), 2), domain.ErrNotFound, "not targeted")
+	assert.ErrorIs(t, m.MarkAnnouncementRead(userContext("bob"), 3), domain.ErrNotFound, "not started")
+
+	all, err := m.GetAnnouncements(adminContext())
+	require.NoError(t, err)
+	for _, announcement := range all {
+		if announcement.Id == 1 {
+			assert.Equal(t, 1, announcement.ReadCount)
+		}
+	}
+}
+
+func TestManager_sendMails(t *testing.T) {
+	m, db, mail := newTestManager()
+	now := time.Now()
+	db.announcements[1] = domain.Announcement{Id: 1, StartsAt: now.Add(-time.Minute), SendMail: true,
+		TargetGroupsStr: "lab-staff"}
+	db.announcements[2] = domain.Announcement{Id: 2, StartsAt: now.Add(time.Hour), SendMail: true}
+	db.announcements[3] = domain.Announcement{Id: 3, StartsAt: now.Add(-time.Minute)}
+
+	require.NoError(t, m.sendMails(adminContext(), now))
+	assert.Equal(t, []domain.UserIdentifier{"alice"}, mail.recipients)
+	assert.NotNil(t, db.announcements[1].MailedAt)
+	assert.Nil(t, db.announcements[2].MailedAt, "scheduled announcements are mailed once they start")
+
+	require.NoError(t, m.sendMails(adminContext(), now.Add(2*time.Hour)))
+	assert.Equal(t, []domain.UserIdentifier{"alice", "alice", "bob"}, mail.recipients,
+		"disabled users are skipped and announcements are mailed once")
+}
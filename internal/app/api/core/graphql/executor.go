@@ -0,0 +1,340 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// ResolveFunc resolves the value of a field. The source is the value of the parent object, nil for the fields of
+// the query type.
+type ResolveFunc func(ctx context.Context, source any, args Arguments) (any, error)
+
+// Object is an object type of a schema.
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Field is a field of an object type. Fields without a type are scalar fields, their resolved values are encoded
+// as JSON. Resolved values of object fields are either a single value or a slice of values, which are passed as
+// source to the resolvers of the selected fields.
+type Field struct {
+	Type      *Object
+	Arguments map[string]bool // the names of the supported arguments, true for required arguments
+	Resolve   ResolveFunc
+}
+
+// Schema is a read-only GraphQL schema.
+type Schema struct {
+	Query    *Object
+	MaxDepth int // the maximum nesting depth of the selected fields, 0 disables the limit
+}
+
+// Response is the result of a query.
+type Response struct {
+	Data   any     `json:"data"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// Error is an error of a query. Errors of single fields contain the path of the field.
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Arguments are the resolved arguments of a field.
+type Arguments map[string]any
+
+// String returns the value of the given string argument.
+func (a Arguments) String(name string) (string, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return "", nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("argument %s must be a string", name)
+	}
+	return str, nil
+}
+
+// Execute parses and executes the given query. If the document contains more than one operation, the operation
+// name selects the operation to execute. Errors of single fields do not abort the execution, the field is null
+// and the error is listed in the response.
+func (s *Schema) Execute(ctx context.Context, query, operationName string, variables map[string]any) Response {
+	doc, err := Parse(query)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	op, err := doc.operation(operationName)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	if err := s.validate(s.Query, op.Selections, 1); err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	if err := op.checkVariableUsage(op.Selections); err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	values, err := op.variableValues(variables)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	e := &execution{variables: values}
+	data := e.executeObject(ctx, s.Query, nil, op.Selections, nil)
+
+	return Response{Data: data, Errors: e.errors}
+}
+
+func (d *Document) operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(d.Operations) > 1 {
+			return nil, fmt.Errorf("operation name is required for documents with multiple operations")
+		}
+		return d.Operations[0], nil
+	}
+
+	for _, op := range d.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+func (op *Operation) variableValues(variables map[string]any) (map[string]any, error) {
+	values := make(map[string]any, len(op.Variables))
+	for _, definition := range op.Variables {
+		value, ok := variables[definition.Name]
+		switch {
+		case ok:
+			values[definition.Name] = value
+		case definition.HasDefault:
+			values[definition.Name] = definition.Default
+		case definition.Required:
+			return nil, fmt.Errorf("variable $%s is required", definition.Name)
+		}
+	}
+	return values, nil
+}
+
+// checkVariableUsage checks that all variables used by the given selections are declared by the operation.
+func (op *Operation) checkVariableUsage(selections []*Selection) error {
+	for _, selection := range selections {
+		for _, value := range selection.Arguments {
+			if err := op.checkValueVariables(value); err != nil {
+				return err
+			}
+		}
+		if err := op.checkVariableUsage(selection.Selections); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (op *Operation) checkValueVariables(value any) error {
+	switch v := value.(type) {
+	case Variable:
+		for _, definition := range op.Variables {
+			if definition.Name == string(v) {
+				return nil
+			}
+		}
+		return fmt.Errorf("variable $%s is not defined", v)
+	case []any:
+		for _, item := range v {
+			if err := op.checkValueVariables(item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for _, item := range v {
+			if err := op.checkValueVariables(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks the selected fields and arguments against the schema before the query is executed.
+func (s *Schema) validate(object *Object, selections []*Selection, depth int) error {
+	if s.MaxDepth > 0 && depth > s.MaxDepth {
+		return fmt.Errorf("query exceeds the maximum depth of %d", s.MaxDepth)
+	}
+
+	for _, selection := range selections {
+		if selection.Name == "__typename" {
+			if len(selection.Selections) != 0 {
+				return fmt.Errorf("field __typename must not have a selection")
+			}
+			continue
+		}
+
+		field, ok := object.Fields[selection.Name]
+		if !ok {
+			return fmt.Errorf("unknown field %s on type %s", selection.Name, object.Name)
+		}
+		for name := range selection.Arguments {
+			if _, ok := field.Arguments[name]; !ok {
+				return fmt.Errorf("unknown argument %s on field %s.%s", name, object.Name, selection.Name)
+			}
+		}
+		for name, required := range field.Arguments {
+			if _, ok := selection.Arguments[name]; required && !ok {
+				return fmt.Errorf("argument %s of field %s.%s is required", name, object.Name, selection.Name)
+			}
+		}
+
+		switch {
+		case field.Type == nil && len(selection.Selections) != 0:
+			return fmt.Errorf("field %s.%s must not have a selection", object.Name, selection.Name)
+		case field.Type != nil && len(selection.Selections) == 0:
+			return fmt.Errorf("field %s.%s of type %s must have a selection", object.Name, selection.Name,
+				field.Type.Name)
+		case field.Type != nil:
+			if err := s.validate(field.Type, selection.Selections, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+type execution struct {
+	variables map[string]any
+	errors    []Error
+}
+
+func (e *execution) executeObject(
+	ctx context.Context,
+	object *Object,
+	source any,
+	selections []*Selection,
+	path []any,
+) *orderedMap {
+	result := &orderedMap{}
+	for _, selection := range selections {
+		key := selection.ResponseKey()
+		if selection.Name == "__typename" {
+			result.set(key, object.Name)
+			continue
+		}
+
+		fieldPath := append(append([]any{}, path...), key)
+		field := object.Fields[selection.Name]
+		value, err := field.Resolve(ctx, source, e.arguments(selection.Arguments))
+		if err != nil {
+			e.errors = append(e.errors, Error{Message: err.Error(), Path: fieldPath})
+			result.set(key, nil)
+			continue
+		}
+
+		if field.Type == nil {
+			result.set(key, value)
+			continue
+		}
+		result.set(key, e.executeValue(ctx, field.Type, value, selection.Selections, fieldPath))
+	}
+
+	return result
+}
+
+// executeValue executes the selection for the resolved value of an object field, which can be a single value or
+// a slice of values.
+func (e *execution) executeValue(ctx context.Context, object *Object, value any, selections []*Selection,
+	path []any) any {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || ((v.Kind() == reflect.Pointer || v.Kind() == reflect.Slice) && v.IsNil()) {
+		return nil
+	}
+
+	if v.Kind() != reflect.Slice {
+		return e.executeObject(ctx, object, value, selections, path)
+	}
+
+	list := make([]any, v.Len())
+	for i := range list {
+		list[i] = e.executeValue(ctx, object, v.Index(i).Interface(), selections, append(path, i))
+	}
+	return list
+}
+
+// arguments replaces the variable references of the given arguments with the values of the variables.
+func (e *execution) arguments(arguments map[string]any) Arguments {
+	resolved := make(Arguments, len(arguments))
+	for name, value := range arguments {
+		resolved[name] = e.resolveValue(value)
+	}
+	return resolved
+}
+
+func (e *execution) resolveValue(value any) any {
+	switch v := value.(type) {
+	case Variable:
+		return e.variables[string(v)]
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = e.resolveValue(item)
+		}
+		return list
+	case map[string]any:
+		object := make(map[string]any, len(v))
+		for name, item := range v {
+			object[name] = e.resolveValue(item)
+		}
+		return object
+	default:
+		return value
+	}
+}
+
+// orderedMap is a JSON object that keeps the order of the selected fields.
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) set(key string, value any) {
+	if m.values == nil {
+		m.values = make(map[string]any)
+	}
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		encodedValue, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(encodedValue)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
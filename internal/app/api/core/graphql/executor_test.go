@@ -0,0 +1,131 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testUser struct {
+	Id    string
+	Peers []string
+}
+
+func newTestSchema() *Schema {
+	peerType := &Object{Name: "Peer", Fields: map[string]*Field{
+		"name": {Resolve: func(_ context.Context, source any, _ Arguments) (any, error) {
+			if source.(string) == "hidden" {
+				return nil, errors.New("no permission")
+			}
+			return source, nil
+		}},
+	}}
+	userType := &Object{Name: "User", Fields: map[string]*Field{
+		"id": {Resolve: func(_ context.Context, source any, _ Arguments) (any, error) {
+			return source.(*testUser).Id, nil
+		}},
+		"peers": {Type: peerType, Resolve: func(_ context.Context, source any, _ Arguments) (any, error) {
+			return source.(*testUser).Peers, nil
+		}},
+	}}
+	users := map[string]*testUser{
+		"alice": {Id: "alice", Peers: []string{"laptop", "hidden"}},
+	}
+
+	return &Schema{
+		MaxDepth: 3,
+		Query: &Object{Name: "Query", Fields: map[string]*Field{
+			"user": {Type: userType, Arguments: map[string]bool{"id": true},
+				Resolve: func(_ context.Context, _ any, args Arguments) (any, error) {
+					id, err := args.String("id")
+					if err != nil {
+						return nil, err
+					}
+					return users[id], nil
+				}},
+		}},
+	}
+}
+
+func execute(t *testing.T, query, operationName string, variables map[string]any) string {
+	t.Helper()
+	result, err := json.Marshal(newTestSchema().Execute(context.Background(), query, operationName, variables))
+	if err != nil {
+		t.Fatalf("failed to encode response: %v", err)
+	}
+	return string(result)
+}
+
+func TestSchema_Execute(t *testing.T) {
+	result := execute(t, `{ user(id: "alice") { __typename id first: peers { name } } }`, "", nil)
+	expected := `{"data":{"user":{"__typename":"User","id":"alice","first":[{"name":"laptop"},{"name":null}]}},` +
+		`"errors":[{"message":"no permission","path":["user","first",1,"name"]}]}`
+	if result != expected {
+		t.Errorf("unexpected result:\n%s\nexpected:\n%s", result, expected)
+	}
+
+	result = execute(t, `{ user(id: "bob") { id } }`, "", nil)
+	if result != `{"data":{"user":null}}` {
+		t.Errorf("unexpected result for unknown user: %s", result)
+	}
+}
+
+func TestSchema_Execute_variables(t *testing.T) {
+	query := `query A($id: String!) { user(id: $id) { id } } query B($id: String = "alice") { user(id: $id) { id } }`
+
+	result := execute(t, query, "A", map[string]any{"id": "alice"})
+	if result != `{"data":{"user":{"id":"alice"}}}` {
+		t.Errorf("unexpected result: %s", result)
+	}
+	result = execute(t, query, "B", nil)
+	if result != `{"data":{"user":{"id":"alice"}}}` {
+		t.Errorf("unexpected result for default value: %s", result)
+	}
+	result = execute(t, query, "A", nil)
+	if !strings.Contains(result, "variable $id is required") {
+		t.Errorf("expected missing variable error, got %s", result)
+	}
+	result = execute(t, query, "", nil)
+	if !strings.Contains(result, "operation name is required") {
+		t.Errorf("expected operation name error, got %s", result)
+	}
+	result = execute(t, query, "A", map[string]any{"id": 1})
+	if result != `{"data":{"user":null},"errors":[{"message":"argument id must be a string","path":["user"]}]}` {
+		t.Errorf("expected argument type error, got %s", result)
+	}
+}
+
+func TestSchema_Execute_validation(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{`{ users { id } }`, "unknown field users on type Query"},
+		{`{ user { id } }`, "argument id of field Query.user is required"},
+		{`{ user(id: "alice", limit: 1) { id } }`, "unknown argument limit on field Query.user"},
+		{`{ user(id: "alice") }`, "field Query.user of type User must have a selection"},
+		{`{ user(id: "alice") { id { name } } }`, "field User.id must not have a selection"},
+		{`{ user(id: $id) { id } }`, "variable $id is not defined"},
+		{`{ user(id: "alice") { peers { name __typename { a } } } }`, "field __typename must not have a selection"},
+	}
+
+	for _, tt := range tests {
+		result := execute(t, tt.query, "", nil)
+		if !strings.HasPrefix(result, `{"data":null,"errors":`) || !strings.Contains(result, tt.err) {
+			t.Errorf("expected error containing %q for %q, got %s", tt.err, tt.query, result)
+		}
+	}
+}
+
+func TestSchema_Execute_maxDepth(t *testing.T) {
+	schema := newTestSchema()
+	schema.MaxDepth = 2
+
+	response := schema.Execute(context.Background(), `{ user(id: "alice") { peers { name } } }`, "", nil)
+	if response.Data != nil || len(response.Errors) != 1 ||
+		response.Errors[0].Message != "query exceeds the maximum depth of 2" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+}
@@ -0,0 +1,563 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document is a parsed GraphQL request document.
+type Document struct {
+	Operations []*Operation
+}
+
+// Operation is a query operation of a document.
+type Operation struct {
+	Name       string
+	Variables  []VariableDefinition
+	Selections []*Selection
+}
+
+// VariableDefinition is a variable declared by an operation.
+type VariableDefinition struct {
+	Name       string
+	Required   bool // the type of the variable is non-null
+	Default    any
+	HasDefault bool
+}
+
+// Selection is a field selected by a query.
+type Selection struct {
+	Alias      string
+	Name       string
+	Arguments  map[string]any // literal values or Variable references
+	Selections []*Selection
+}
+
+// ResponseKey returns the key of the field in the response, the alias if one was given.
+func (s *Selection) ResponseKey() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Name
+}
+
+// Variable is a reference to a variable of the operation, used as argument value.
+type Variable string
+
+// Parse parses the given GraphQL document. Only query operations with fields, aliases, arguments and variables
+// are supported. Fragments, directives, mutations and subscriptions are rejected.
+func Parse(query string) (*Document, error) {
+	p := &parser{lexer: lexer{src: query}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	for p.tok.kind != tokenEOF {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		doc.Operations = append(doc.Operations, op)
+	}
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("document does not contain any operation")
+	}
+
+	return doc, nil
+}
+
+// region lexer
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("{}()[]:!$=@", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunctuator, value: string(c), pos: start}, nil
+	case c == '.':
+		if !strings.HasPrefix(l.src[l.pos:], "...") {
+			return token{}, fmt.Errorf("unexpected character '.' at position %d", start)
+		}
+		l.pos += 3
+		return token{kind: tokenPunctuator, value: "...", pos: start}, nil
+	case c == '"':
+		return l.readString()
+	case c == '-' || isDigit(c):
+		return l.readNumber()
+	case isNameStart(c):
+		for l.pos < len(l.src) && isNameContinue(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.src[start:l.pos], pos: start}, nil
+	default:
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+		return token{}, fmt.Errorf("unexpected character %q at position %d", r, start)
+	}
+}
+
+// skipIgnored skips white space, commas and comments.
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"): // byte order mark
+			l.pos += len("\uFEFF")
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) readString() (token, error) {
+	start := l.pos
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		return token{}, fmt.Errorf("block strings are not supported, position %d", start)
+	}
+
+	l.pos++ // opening quote
+	var sb strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokenString, value: sb.String(), pos: start}, nil
+		case c == '\n' || c == '\r':
+			return token{}, fmt.Errorf("unterminated string at position %d", start)
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, fmt.Errorf("unterminated string at position %d", start)
+			}
+			escaped := l.src[l.pos+1]
+			l.pos += 2
+			switch escaped {
+			case '"', '\\', '/':
+				sb.WriteByte(escaped)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, fmt.Errorf("invalid unicode escape at position %d", l.pos)
+				}
+				code, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, fmt.Errorf("invalid unicode escape at position %d", l.pos)
+				}
+				sb.WriteRune(rune(code))
+				l.pos += 4
+			default:
+				return token{}, fmt.Errorf("invalid escape sequence at position %d", l.pos-2)
+			}
+		default:
+			sb.WriteByte(c)
+			l.pos++
+		}
+	}
+
+	return token{}, fmt.Errorf("unterminated string at position %d", start)
+}
+
+func (l *lexer) readNumber() (token, error) {
+	start := l.pos
+	kind := tokenInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case isDigit(c):
+		case c == '.' || c == 'e' || c == 'E' || ((c == '+' || c == '-') && kind == tokenFloat):
+			kind = tokenFloat
+		default:
+			if isNameStart(c) {
+				return token{}, fmt.Errorf("invalid number at position %d", start)
+			}
+			return token{kind: kind, value: l.src[start:l.pos], pos: start}, nil
+		}
+		l.pos++
+	}
+
+	return token{kind: kind, value: l.src[start:l.pos], pos: start}, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+// endregion lexer
+
+// region parser
+
+type parser struct {
+	lexer lexer
+	tok   token
+}
+
+func (p *parser) next() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) peek(value string) bool {
+	return p.tok.kind == tokenPunctuator && p.tok.value == value
+}
+
+func (p *parser) expect(value string) error {
+	if !p.peek(value) {
+		return p.unexpected(fmt.Sprintf("'%s'", value))
+	}
+	return p.next()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.unexpected("name")
+	}
+	name := p.tok.value
+	return name, p.next()
+}
+
+func (p *parser) unexpected(expected string) error {
+	if p.tok.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of document, expected %s", expected)
+	}
+	return fmt.Errorf("unexpected '%s' at position %d, expected %s", p.tok.value, p.tok.pos, expected)
+}
+
+func (p *parser) parseOperation() (*Operation, error) {
+	op := &Operation{}
+	if p.peek("{") { // query shorthand
+		selections, err := p.parseSelectionSet()
+		if err != nil {
+			return nil, err
+		}
+		op.Selections = selections
+		return op, nil
+	}
+
+	if p.tok.kind != tokenName {
+		return nil, p.unexpected("operation")
+	}
+	switch p.tok.value {
+	case "query":
+	case "mutation", "subscription":
+		return nil, fmt.Errorf("%s operations are not supported", p.tok.value)
+	case "fragment":
+		return nil, fmt.Errorf("fragments are not supported")
+	default:
+		return nil, p.unexpected("operation")
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName {
+		op.Name = p.tok.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		variables, err := p.parseVariableDefinitions()
+		if err != nil {
+			return nil, err
+		}
+		op.Variables = variables
+	}
+	if p.peek("@") {
+		return nil, fmt.Errorf("directives are not supported")
+	}
+
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.Selections = selections
+
+	return op, nil
+}
+
+func (p *parser) parseVariableDefinitions() ([]VariableDefinition, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	var definitions []VariableDefinition
+	for !p.peek(")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		required, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+
+		definition := VariableDefinition{Name: name, Required: required}
+		if p.peek("=") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			value, err := p.parseValue(true)
+			if err != nil {
+				return nil, err
+			}
+			definition.Default = value
+			definition.HasDefault = true
+		}
+		definitions = append(definitions, definition)
+	}
+
+	return definitions, p.expect(")")
+}
+
+// parseType parses a variable type and reports whether the type is non-null. The type itself is not validated,
+// the resolvers check the types of their arguments.
+func (p *parser) parseType() (bool, error) {
+	if p.peek("[") {
+		if err := p.next(); err != nil {
+			return false, err
+		}
+		if _, err := p.parseType(); err != nil {
+			return false, err
+		}
+		if err := p.expect("]"); err != nil {
+			return false, err
+		}
+	} else if _, err := p.name(); err != nil {
+		return false, err
+	}
+
+	if p.peek("!") {
+		return true, p.next()
+	}
+	return false, nil
+}
+
+func (p *parser) parseSelectionSet() ([]*Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var selections []*Selection
+	for !p.peek("}") {
+		if p.peek("...") {
+			return nil, fmt.Errorf("fragments are not supported")
+		}
+		selection, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	if len(selections) == 0 {
+		return nil, p.unexpected("field")
+	}
+
+	return selections, p.expect("}")
+}
+
+func (p *parser) parseField() (*Selection, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	selection := &Selection{Name: name}
+	if p.peek(":") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		selection.Alias = name
+		if selection.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek("(") {
+		if selection.Arguments, err = p.parseArguments(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("@") {
+		return nil, fmt.Errorf("directives are not supported")
+	}
+	if p.peek("{") {
+		if selection.Selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+
+	return selection, nil
+}
+
+func (p *parser) parseArguments() (map[string]any, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	arguments := make(map[string]any)
+	for !p.peek(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := arguments[name]; exists {
+			return nil, fmt.Errorf("duplicate argument %s", name)
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue(false)
+		if err != nil {
+			return nil, err
+		}
+		arguments[name] = value
+	}
+
+	return arguments, p.expect(")")
+}
+
+// parseValue parses an argument value. Enum values are returned as strings.
+func (p *parser) parseValue(constant bool) (any, error) {
+	tok := p.tok
+	switch {
+	case p.peek("$"):
+		if constant {
+			return nil, fmt.Errorf("variables are not allowed in default values, position %d", tok.pos)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		return Variable(name), nil
+	case p.peek("["):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		list := make([]any, 0)
+		for !p.peek("]") {
+			value, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, p.next()
+	case p.peek("{"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		object := make(map[string]any)
+		for !p.peek("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if object[name], err = p.parseValue(constant); err != nil {
+				return nil, err
+			}
+		}
+		return object, p.next()
+	case tok.kind == tokenInt:
+		value, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %s at position %d", tok.value, tok.pos)
+		}
+		return value, p.next()
+	case tok.kind == tokenFloat:
+		value, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %s at position %d", tok.value, tok.pos)
+		}
+		return value, p.next()
+	case tok.kind == tokenString:
+		return tok.value, p.next()
+	case tok.kind == tokenName:
+		var value any
+		switch tok.value {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		default:
+			value = tok.value
+		}
+		return value, p.next()
+	default:
+		return nil, p.unexpected("value")
+	}
+}
+
+// endregion parser
@@ -0,0 +1,107 @@
+package graphql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(`
+		# peers of an interface
+		query InterfacePeers($id: String!, $limit: Int = 10) {
+			iface: interface(id: $id) {
+				identifier
+				peers(filter: {tags: ["a", "b"], enabled: true}, limit: -1.5e2, mode: FAST) { displayName }
+			}
+		}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(doc.Operations) != 1 {
+		t.Fatalf("expected one operation, got %d", len(doc.Operations))
+	}
+
+	op := doc.Operations[0]
+	if op.Name != "InterfacePeers" {
+		t.Errorf("expected operation name InterfacePeers, got %s", op.Name)
+	}
+	expectedVariables := []VariableDefinition{
+		{Name: "id", Required: true},
+		{Name: "limit", Default: int64(10), HasDefault: true},
+	}
+	if !reflect.DeepEqual(op.Variables, expectedVariables) {
+		t.Errorf("unexpected variables: %+v", op.Variables)
+	}
+
+	iface := op.Selections[0]
+	if iface.Name != "interface" || iface.ResponseKey() != "iface" {
+		t.Errorf("unexpected field %s with response key %s", iface.Name, iface.ResponseKey())
+	}
+	if iface.Arguments["id"] != Variable("id") {
+		t.Errorf("expected variable argument, got %v", iface.Arguments["id"])
+	}
+
+	peers := iface.Selections[1]
+	expectedArguments := map[string]any{
+		"filter": map[string]any{"tags": []any{"a", "b"}, "enabled": true},
+		"limit":  -150.0,
+		"mode":   "FAST",
+	}
+	if !reflect.DeepEqual(peers.Arguments, expectedArguments) {
+		t.Errorf("unexpected arguments: %+v", peers.Arguments)
+	}
+	if len(peers.Selections) != 1 || peers.Selections[0].ResponseKey() != "displayName" {
+		t.Errorf("unexpected selections: %+v", peers.Selections)
+	}
+}
+
+func TestParse_shorthand(t *testing.T) {
+	doc, err := Parse(`{ me { identifier email } }`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doc.Operations[0].Name != "" || len(doc.Operations[0].Selections[0].Selections) != 2 {
+		t.Errorf("unexpected operation: %+v", doc.Operations[0])
+	}
+}
+
+func TestParse_strings(t *testing.T) {
+	doc, err := Parse(`{ user(id: "a\"b\\cä\n") { identifier } }`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value := doc.Operations[0].Selections[0].Arguments["id"]; value != "a\"b\\cä\n" {
+		t.Errorf("unexpected string value %q", value)
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"", "does not contain any operation"},
+		{"mutation { deletePeer }", "mutation operations are not supported"},
+		{"{ ...PeerFields }", "fragments are not supported"},
+		{"fragment F on Peer { identifier }", "fragments are not supported"},
+		{"{ me @include(if: true) { identifier } }", "directives are not supported"},
+		{"{ me { identifier }", "unexpected end of document"},
+		{"{ }", "expected field"},
+		{`{ user(id: "abc) { identifier } }`, "unterminated string"},
+		{`{ user(id: 1, id: 2) { identifier } }`, "duplicate argument id"},
+		{"query ($id: String = $other) { me { identifier } }", "variables are not allowed in default values"},
+		{"{ me { identifier ; } }", "unexpected character"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		if err == nil {
+			t.Errorf("expected error for %q", tt.query)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("expected error containing %q for %q, got %v", tt.err, tt.query, err)
+		}
+	}
+}
@@ -38,6 +38,10 @@ func (m *Middleware) Handler(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r) // skip CSRF check for ignored methods
 			return
 		}
+		if m.o.ignoreFunc != nil && m.o.ignoreFunc(r) {
+			next.ServeHTTP(w, r) // skip CSRF check for ignored requests
+			return
+		}
 
 		// get the token from the request
 		token := m.o.tokenGetter(r)
@@ -249,3 +249,39 @@ func TestMiddleware_Handler_FormBodyAvailable(t *testing.T) {
 		t.Errorf("Handler() status = %d, want %d", status, http.StatusOK)
 	}
 }
+
+func TestMiddleware_Handler_IgnoreFunc(t *testing.T) {
+	sessionReader := func(r *http.Request) string {
+		return "stored-token"
+	}
+	sessionWriter := func(r *http.Request, token string) {}
+	m := New(sessionReader, sessionWriter, WithIgnoreFunc(func(r *http.Request) bool {
+		return r.URL.Path == "/external"
+	}))
+
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"IgnoredRequest", "/external", http.StatusOK},
+		{"CheckedRequest", "/internal", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatus {
+				t.Errorf("Handler() status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
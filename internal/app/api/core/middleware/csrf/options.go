@@ -10,6 +10,7 @@ type SessionWriter func(r *http.Request, token string)
 type options struct {
 	tokenLength   int
 	ignoreMethods []string
+	ignoreFunc    func(r *http.Request) bool
 
 	errCallbackOverride bool
 	errCallback         func(w http.ResponseWriter, r *http.Request)
@@ -53,6 +54,14 @@ func WithTokenGetter(fn func(r *http.Request) string) Option {
 	}
 }
 
+// WithIgnoreFunc is a method that sets a function that decides whether the CSRF check is skipped for a request.
+// This is required for endpoints that are called by external servers, which do not have a session.
+func WithIgnoreFunc(fn func(r *http.Request) bool) Option {
+	return func(o *options) {
+		o.ignoreFunc = fn
+	}
+}
+
 // withSessionReader is a method that sets the session reader function for the CSRF middleware.
 // The session reader function is called to get the CSRF token from the session.
 func withSessionReader(fn SessionReader) Option {
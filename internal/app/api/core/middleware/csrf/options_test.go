@@ -38,6 +38,16 @@ func TestWithTokenGetter(t *testing.T) {
 	}
 }
 
+func TestWithIgnoreFunc(t *testing.T) {
+	ignore := func(r *http.Request) bool {
+		return true
+	}
+	o := newOptions(WithIgnoreFunc(ignore))
+	if o.ignoreFunc == nil {
+		t.Errorf("WithIgnoreFunc() did not set ignoreFunc")
+	}
+}
+
 func TestWithSessionReader(t *testing.T) {
 	reader := func(r *http.Request) string {
 		return "session-token"
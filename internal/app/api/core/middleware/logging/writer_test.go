@@ -83,3 +83,15 @@ func TestNewWriterWrapper(t *testing.T) {
 		t.Errorf("expected ResponseWriter to be %v, got %v", rr, ww.ResponseWriter)
 	}
 }
+
+func TestWriterWrapper_Flush(t *testing.T) {
+	rr := httptest.NewRecorder()
+	ww := newWriterWrapper(rr)
+
+	if err := http.NewResponseController(ww).Flush(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !rr.Flushed {
+		t.Errorf("expected the wrapped ResponseWriter to be flushed")
+	}
+}
@@ -18,11 +18,28 @@ type InterfaceServiceInterfaceManager interface {
 	DeleteInterface(ctx context.Context, id domain.InterfaceIdentifier) error
 	PrepareInterface(ctx context.Context) (*domain.Interface, error)
 	ApplyPeerDefaults(ctx context.Context, in *domain.Interface) error
+	SuggestInterfaceMtu(ctx context.Context, id domain.InterfaceIdentifier) (*domain.PathMtu, error)
+	PrepareRenumbering(
+		ctx context.Context,
+		id domain.InterfaceIdentifier,
+		from, to domain.Cidr,
+	) (*domain.InterfaceRenumbering, error)
+	RenumberInterface(
+		ctx context.Context,
+		id domain.InterfaceIdentifier,
+		from, to domain.Cidr,
+		sendMails bool,
+	) (*domain.InterfaceRenumbering, error)
 }
 
 type InterfaceServiceConfigFileManager interface {
 	PersistInterfaceConfig(ctx context.Context, id domain.InterfaceIdentifier) error
 	GetInterfaceConfig(ctx context.Context, id domain.InterfaceIdentifier) (io.Reader, error)
+	ExportInterfacePeerConfigs(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Export, error)
+}
+
+type InterfaceServiceMailManager interface {
+	SendPeerEmail(ctx context.Context, linkOnly bool, peers ...domain.PeerIdentifier) error
 }
 
 // endregion dependencies
@@ -32,17 +49,20 @@ type InterfaceService struct {
 
 	interfaces InterfaceServiceInterfaceManager
 	configFile InterfaceServiceConfigFileManager
+	mailer     InterfaceServiceMailManager
 }
 
 func NewInterfaceService(
 	cfg *config.Config,
 	interfaces InterfaceServiceInterfaceManager,
 	configFile InterfaceServiceConfigFileManager,
+	mailer InterfaceServiceMailManager,
 ) *InterfaceService {
 	return &InterfaceService{
 		cfg:        cfg,
 		interfaces: interfaces,
 		configFile: configFile,
+		mailer:     mailer,
 	}
 }
 
@@ -51,7 +71,13 @@ func (i InterfaceService) GetInterfaceAndPeers(ctx context.Context, id domain.In
 	[]domain.Peer,
 	error,
 ) {
-	return i.interfaces.GetInterfaceAndPeers(ctx, id)
+	iface, peers, err := i.interfaces.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	hidePrivateKeys(i.cfg, peers)
+
+	return iface, peers, nil
 }
 
 func (i InterfaceService) PrepareInterface(ctx context.Context) (*domain.Interface, error) {
@@ -67,7 +93,13 @@ func (i InterfaceService) UpdateInterface(ctx context.Context, in *domain.Interf
 	[]domain.Peer,
 	error,
 ) {
-	return i.interfaces.UpdateInterface(ctx, in)
+	iface, peers, err := i.interfaces.UpdateInterface(ctx, in)
+	if err != nil {
+		return nil, nil, err
+	}
+	hidePrivateKeys(i.cfg, peers)
+
+	return iface, peers, nil
 }
 
 func (i InterfaceService) DeleteInterface(ctx context.Context, id domain.InterfaceIdentifier) error {
@@ -75,13 +107,28 @@ func (i InterfaceService) DeleteInterface(ctx context.Context, id domain.Interfa
 }
 
 func (i InterfaceService) GetAllInterfacesAndPeers(ctx context.Context) ([]domain.Interface, [][]domain.Peer, error) {
-	return i.interfaces.GetAllInterfacesAndPeers(ctx)
+	interfaces, allPeers, err := i.interfaces.GetAllInterfacesAndPeers(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, peers := range allPeers {
+		hidePrivateKeys(i.cfg, peers)
+	}
+
+	return interfaces, allPeers, nil
 }
 
 func (i InterfaceService) GetInterfaceConfig(ctx context.Context, id domain.InterfaceIdentifier) (io.Reader, error) {
 	return i.configFile.GetInterfaceConfig(ctx, id)
 }
 
+func (i InterfaceService) ExportInterfacePeerConfigs(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+) (*domain.Export, error) {
+	return i.configFile.ExportInterfacePeerConfigs(ctx, id)
+}
+
 func (i InterfaceService) PersistInterfaceConfig(ctx context.Context, id domain.InterfaceIdentifier) error {
 	return i.configFile.PersistInterfaceConfig(ctx, id)
 }
@@ -89,3 +136,48 @@ func (i InterfaceService) PersistInterfaceConfig(ctx context.Context, id domain.
 func (i InterfaceService) ApplyPeerDefaults(ctx context.Context, in *domain.Interface) error {
 	return i.interfaces.ApplyPeerDefaults(ctx, in)
 }
+
+func (i InterfaceService) SuggestInterfaceMtu(ctx context.Context, id domain.InterfaceIdentifier) (
+	*domain.PathMtu,
+	error,
+) {
+	return i.interfaces.SuggestInterfaceMtu(ctx, id)
+}
+
+func (i InterfaceService) PrepareRenumbering(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	from, to domain.Cidr,
+) (*domain.InterfaceRenumbering, error) {
+	return i.interfaces.PrepareRenumbering(ctx, id, from, to)
+}
+
+func (i InterfaceService) RenumberInterface(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	from, to domain.Cidr,
+	sendMails bool,
+) (*domain.InterfaceRenumbering, error) {
+	return i.interfaces.RenumberInterface(ctx, id, from, to, sendMails)
+}
+
+// SendPeerEmails sends the current configuration to the users of all peers of the given interface.
+func (i InterfaceService) SendPeerEmails(ctx context.Context, id domain.InterfaceIdentifier, linkOnly bool) error {
+	_, peers, err := i.interfaces.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	peerIds := make([]domain.PeerIdentifier, 0, len(peers))
+	for _, peer := range peers {
+		if peer.IsDisabled() || peer.UserIdentifier == "" {
+			continue
+		}
+		peerIds = append(peerIds, peer.Identifier)
+	}
+	if len(peerIds) == 0 {
+		return nil
+	}
+
+	return i.mailer.SendPeerEmail(ctx, linkOnly, peerIds...)
+}
@@ -3,6 +3,7 @@ package backend
 import (
 	"context"
 	"io"
+	"log/slog"
 
 	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
@@ -12,11 +13,13 @@ import (
 
 type PeerServicePeerManager interface {
 	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	GetPeerPrivateKey(ctx context.Context, id domain.PeerIdentifier) (string, error)
 	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
 	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
 	PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error)
 	CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
 	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+	RegeneratePeerKeys(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
 	DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
 	CreateMultiplePeers(
 		ctx context.Context,
@@ -24,17 +27,34 @@ type PeerServicePeerManager interface {
 		r *domain.PeerCreationRequest,
 	) ([]domain.Peer, error)
 	GetPeerStats(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.PeerStatus, error)
+	SuggestPeerMtu(ctx context.Context, id domain.PeerIdentifier) (*domain.PathMtu, error)
 }
 
 type PeerServiceConfigFileManager interface {
 	GetPeerConfig(ctx context.Context, id domain.PeerIdentifier) (io.Reader, error)
-	GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier) (io.Reader, error)
+	GetPeerConfigForEndpoint(ctx context.Context, id domain.PeerIdentifier, endpoint int) (io.Reader, error)
+	GetPeerEndpoints(ctx context.Context, id domain.PeerIdentifier) ([]string, error)
+	ExportPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier) (*domain.Export, error)
+	GetPeerUciConfig(ctx context.Context, id domain.PeerIdentifier, style domain.UciConfigStyle) (io.Reader, error)
 }
 
 type PeerServiceMailManager interface {
 	SendPeerEmail(ctx context.Context, linkOnly bool, peers ...domain.PeerIdentifier) error
 }
 
+type PeerServiceCleanupManager interface {
+	GetCleanupReport(ctx context.Context) ([]domain.PeerCleanupCandidate, error)
+}
+
+type PeerServiceShapingManager interface {
+	GetPeerShapingStatus(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.PeerShapingStatus, error)
+}
+
+type PeerServiceKeepaliveManager interface {
+	GetRecommendations(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.KeepaliveRecommendation, error)
+	ApplyRecommendation(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+}
+
 // endregion dependencies
 
 type PeerService struct {
@@ -43,6 +63,9 @@ type PeerService struct {
 	peers      PeerServicePeerManager
 	configFile PeerServiceConfigFileManager
 	mailer     PeerServiceMailManager
+	cleanup    PeerServiceCleanupManager
+	shaping    PeerServiceShapingManager
+	keepalive  PeerServiceKeepaliveManager
 }
 
 func NewPeerService(
@@ -50,12 +73,18 @@ func NewPeerService(
 	peers PeerServicePeerManager,
 	configFile PeerServiceConfigFileManager,
 	mailer PeerServiceMailManager,
+	cleanup PeerServiceCleanupManager,
+	shaping PeerServiceShapingManager,
+	keepalive PeerServiceKeepaliveManager,
 ) *PeerService {
 	return &PeerService{
 		cfg:        cfg,
 		peers:      peers,
 		configFile: configFile,
 		mailer:     mailer,
+		cleanup:    cleanup,
+		shaping:    shaping,
+		keepalive:  keepalive,
 	}
 }
 
@@ -64,7 +93,13 @@ func (p PeerService) GetInterfaceAndPeers(ctx context.Context, id domain.Interfa
 	[]domain.Peer,
 	error,
 ) {
-	return p.peers.GetInterfaceAndPeers(ctx, id)
+	iface, peers, err := p.peers.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	hidePrivateKeys(p.cfg, peers)
+
+	return iface, peers, nil
 }
 
 func (p PeerService) PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
@@ -72,7 +107,17 @@ func (p PeerService) PreparePeer(ctx context.Context, id domain.InterfaceIdentif
 }
 
 func (p PeerService) GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
-	return p.peers.GetPeer(ctx, id)
+	peer, err := p.peers.GetPeer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	hidePrivateKey(p.cfg, peer)
+
+	return peer, nil
+}
+
+func (p PeerService) GetPeerPrivateKey(ctx context.Context, id domain.PeerIdentifier) (string, error) {
+	return p.peers.GetPeerPrivateKey(ctx, id)
 }
 
 func (p PeerService) CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error) {
@@ -84,11 +129,43 @@ func (p PeerService) CreateMultiplePeers(
 	interfaceId domain.InterfaceIdentifier,
 	r *domain.PeerCreationRequest,
 ) ([]domain.Peer, error) {
-	return p.peers.CreateMultiplePeers(ctx, interfaceId, r)
+	peers, err := p.peers.CreateMultiplePeers(ctx, interfaceId, r)
+	if err != nil {
+		return nil, err
+	}
+	hidePrivateKeys(p.cfg, peers)
+
+	return peers, nil
 }
 
 func (p PeerService) UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error) {
-	return p.peers.UpdatePeer(ctx, peer)
+	updatedPeer, err := p.peers.UpdatePeer(ctx, peer)
+	if err != nil {
+		return nil, err
+	}
+	hidePrivateKey(p.cfg, updatedPeer)
+
+	return updatedPeer, nil
+}
+
+// RegeneratePeerKeys generates new keys for the given peer. If enabled, the updated configuration is mailed to the
+// owner of the peer. A failed mail does not revert the new keys, as the user can still download the configuration.
+func (p PeerService) RegeneratePeerKeys(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, err := p.peers.RegeneratePeerKeys(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.cfg.KeyRotation.MailConfig {
+		if err := p.mailer.SendPeerEmail(ctx, p.cfg.Mail.LinkOnly, peer.Identifier); err != nil {
+			slog.Warn("failed to mail configuration of peer with regenerated keys",
+				"peer", peer.Identifier,
+				"error", err)
+		}
+	}
+	hidePrivateKey(p.cfg, peer)
+
+	return peer, nil
 }
 
 func (p PeerService) DeletePeer(ctx context.Context, id domain.PeerIdentifier) error {
@@ -99,8 +176,28 @@ func (p PeerService) GetPeerConfig(ctx context.Context, id domain.PeerIdentifier
 	return p.configFile.GetPeerConfig(ctx, id)
 }
 
-func (p PeerService) GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier) (io.Reader, error) {
-	return p.configFile.GetPeerConfigQrCode(ctx, id)
+func (p PeerService) GetPeerConfigForEndpoint(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	endpoint int,
+) (io.Reader, error) {
+	return p.configFile.GetPeerConfigForEndpoint(ctx, id, endpoint)
+}
+
+func (p PeerService) GetPeerEndpoints(ctx context.Context, id domain.PeerIdentifier) ([]string, error) {
+	return p.configFile.GetPeerEndpoints(ctx, id)
+}
+
+func (p PeerService) ExportPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier) (*domain.Export, error) {
+	return p.configFile.ExportPeerConfigQrCode(ctx, id)
+}
+
+func (p PeerService) GetPeerUciConfig(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	style domain.UciConfigStyle,
+) (io.Reader, error) {
+	return p.configFile.GetPeerUciConfig(ctx, id, style)
 }
 
 func (p PeerService) SendPeerEmail(ctx context.Context, linkOnly bool, peers ...domain.PeerIdentifier) error {
@@ -110,3 +207,57 @@ func (p PeerService) SendPeerEmail(ctx context.Context, linkOnly bool, peers ...
 func (p PeerService) GetPeerStats(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.PeerStatus, error) {
 	return p.peers.GetPeerStats(ctx, id)
 }
+
+func (p PeerService) GetCleanupReport(ctx context.Context) ([]domain.PeerCleanupCandidate, error) {
+	return p.cleanup.GetCleanupReport(ctx)
+}
+
+func (p PeerService) GetPeerShapingStatus(ctx context.Context, id domain.InterfaceIdentifier) (
+	[]domain.PeerShapingStatus,
+	error,
+) {
+	return p.shaping.GetPeerShapingStatus(ctx, id)
+}
+
+func (p PeerService) SuggestPeerMtu(ctx context.Context, id domain.PeerIdentifier) (*domain.PathMtu, error) {
+	return p.peers.SuggestPeerMtu(ctx, id)
+}
+
+func (p PeerService) GetKeepaliveRecommendations(ctx context.Context, id domain.InterfaceIdentifier) (
+	[]domain.KeepaliveRecommendation,
+	error,
+) {
+	return p.keepalive.GetRecommendations(ctx, id)
+}
+
+func (p PeerService) ApplyKeepaliveRecommendation(ctx context.Context, id domain.PeerIdentifier) (
+	*domain.Peer,
+	error,
+) {
+	peer, err := p.keepalive.ApplyRecommendation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	hidePrivateKey(p.cfg, peer)
+
+	return peer, nil
+}
+
+// hidePrivateKeys removes the private keys from the given peers if they are only revealed after a re-authentication.
+func hidePrivateKeys(cfg *config.Config, peers []domain.Peer) {
+	if !cfg.KeyReveal.StepUpRequired {
+		return
+	}
+
+	for i := range peers {
+		peers[i].Interface.PrivateKey = ""
+	}
+}
+
+// hidePrivateKey removes the private key from the given peer if private keys are only revealed after a
+// re-authentication.
+func hidePrivateKey(cfg *config.Config, peer *domain.Peer) {
+	if cfg.KeyReveal.StepUpRequired {
+		peer.Interface.PrivateKey = ""
+	}
+}
@@ -17,6 +17,19 @@ type UserServiceUserManager interface {
 	DeleteUser(ctx context.Context, id domain.UserIdentifier) error
 	ActivateApi(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
 	DeactivateApi(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	ResetWebAuthnCredentials(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	GetNotificationPreferences(ctx context.Context, id domain.UserIdentifier) (
+		*domain.UserNotificationPreferences,
+		error,
+	)
+	UpdateNotificationPreferences(ctx context.Context, prefs *domain.UserNotificationPreferences) (
+		*domain.UserNotificationPreferences,
+		error,
+	)
+	GetSavedViews(ctx context.Context, id domain.UserIdentifier, list domain.SavedViewList) ([]domain.SavedView, error)
+	CreateSavedView(ctx context.Context, view *domain.SavedView) (*domain.SavedView, error)
+	UpdateSavedView(ctx context.Context, view *domain.SavedView) (*domain.SavedView, error)
+	DeleteSavedView(ctx context.Context, userId domain.UserIdentifier, id uint64) error
 }
 
 type UserServiceWireGuardManager interface {
@@ -70,8 +83,52 @@ func (u UserService) DeactivateApi(ctx context.Context, id domain.UserIdentifier
 	return u.users.DeactivateApi(ctx, id)
 }
 
+func (u UserService) ResetWebAuthnCredentials(ctx context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	return u.users.ResetWebAuthnCredentials(ctx, id)
+}
+
+func (u UserService) GetNotificationPreferences(ctx context.Context, id domain.UserIdentifier) (
+	*domain.UserNotificationPreferences,
+	error,
+) {
+	return u.users.GetNotificationPreferences(ctx, id)
+}
+
+func (u UserService) UpdateNotificationPreferences(
+	ctx context.Context,
+	prefs *domain.UserNotificationPreferences,
+) (*domain.UserNotificationPreferences, error) {
+	return u.users.UpdateNotificationPreferences(ctx, prefs)
+}
+
+func (u UserService) GetSavedViews(
+	ctx context.Context,
+	id domain.UserIdentifier,
+	list domain.SavedViewList,
+) ([]domain.SavedView, error) {
+	return u.users.GetSavedViews(ctx, id, list)
+}
+
+func (u UserService) CreateSavedView(ctx context.Context, view *domain.SavedView) (*domain.SavedView, error) {
+	return u.users.CreateSavedView(ctx, view)
+}
+
+func (u UserService) UpdateSavedView(ctx context.Context, view *domain.SavedView) (*domain.SavedView, error) {
+	return u.users.UpdateSavedView(ctx, view)
+}
+
+func (u UserService) DeleteSavedView(ctx context.Context, userId domain.UserIdentifier, id uint64) error {
+	return u.users.DeleteSavedView(ctx, userId, id)
+}
+
 func (u UserService) GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
-	return u.wg.GetUserPeers(ctx, id)
+	peers, err := u.wg.GetUserPeers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	hidePrivateKeys(u.cfg, peers)
+
+	return peers, nil
 }
 
 func (u UserService) GetUserPeerStats(ctx context.Context, id domain.UserIdentifier) ([]domain.PeerStatus, error) {
@@ -3,13 +3,16 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"strings"
 
 	"github.com/go-pkgz/routegroup"
 
 	"github.com/h44z/wg-portal/internal/app/api/core"
 	"github.com/h44z/wg-portal/internal/app/api/core/middleware/cors"
 	"github.com/h44z/wg-portal/internal/app/api/core/middleware/csrf"
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
 	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/domain"
 )
 
 type SessionMiddleware interface {
@@ -62,7 +65,10 @@ func NewRestApi(
 				currentSession := session.GetData(r.Context())
 				currentSession.CsrfToken = token
 				session.SetData(r.Context(), currentSession)
-			})
+			}, csrf.WithIgnoreFunc(func(r *http.Request) bool {
+				// back-channel logouts are sent by the OpenID Connect provider, not by the browser
+				return r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/backchannel-logout")
+			}))
 
 			group.Use(session.LoadAndSave)
 			group.Use(csrfMiddleware.Handler)
@@ -92,15 +98,27 @@ func handleCsrfGet() http.HandlerFunc {
 	}
 }
 
+// withClientInfo adds the IP address and user agent of the requesting client to the given context.
+// Services use this information for security notifications, for example about logins from new devices.
+func withClientInfo(ctx context.Context, r *http.Request) context.Context {
+	return domain.SetClientInfo(ctx, domain.ClientInfo{
+		IpAddress: request.ClientIp(r, request.CheckPrivateProxy),
+		UserAgent: request.Header(r, "User-Agent"),
+	})
+}
+
 // region handler-interfaces
 
 type Authenticator interface {
 	// LoggedIn checks if a user is logged in. If scopes are given, they are validated as well.
 	LoggedIn(scopes ...Scope) func(next http.Handler) http.Handler
 	// UserIdMatch checks if the user id in the session matches the user id in the request. If not, the request is aborted.
-	UserIdMatch(idParameter string) func(next http.Handler) http.Handler
+	// If scopes are given, users with these scopes can access the data of other users as well.
+	UserIdMatch(idParameter string, scopes ...Scope) func(next http.Handler) http.Handler
 	// InfoOnly only add user info to the request context. No login check is performed.
 	InfoOnly() func(next http.Handler) http.Handler
+	// NoImpersonation aborts the request if the session user is currently impersonated by an administrator.
+	NoImpersonation() func(next http.Handler) http.Handler
 }
 
 type Session interface {
@@ -110,6 +128,9 @@ type Session interface {
 	GetData(ctx context.Context) SessionData
 	// DestroyData destroys the session data for the given context.
 	DestroyData(ctx context.Context)
+	// DestroyMatching destroys all sessions for which the match function returns true. It returns the number of
+	// destroyed sessions.
+	DestroyMatching(ctx context.Context, match func(SessionData) bool) (int, error)
 }
 
 type Validator interface {
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AcceptableUseService interface {
+	// GetStatus returns the current policy and the latest acceptance of the given user.
+	GetStatus(ctx context.Context, id domain.UserIdentifier) (*domain.AcceptableUseStatus, error)
+	// GetAcceptances returns all acceptances of the given user.
+	GetAcceptances(ctx context.Context, id domain.UserIdentifier) ([]domain.AcceptableUseAcceptance, error)
+	// Accept records the acceptance of the given policy version by the current user.
+	Accept(ctx context.Context, version string) (*domain.AcceptableUseAcceptance, error)
+}
+
+type AcceptableUseEndpoint struct {
+	cfg                  *config.Config
+	acceptableUseService AcceptableUseService
+	authenticator        Authenticator
+	validator            Validator
+}
+
+func NewAcceptableUseEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	acceptableUseService AcceptableUseService,
+) AcceptableUseEndpoint {
+	return AcceptableUseEndpoint{
+		cfg:                  cfg,
+		acceptableUseService: acceptableUseService,
+		authenticator:        authenticator,
+		validator:            validator,
+	}
+}
+
+func (e AcceptableUseEndpoint) GetName() string {
+	return "AcceptableUseEndpoint"
+}
+
+func (e AcceptableUseEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/acceptable-use")
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.HandleFunc("GET /my", e.handleMyStatusGet())
+	apiGroup.HandleFunc("POST /my/accept", e.handleAcceptPost())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /by-user/{id}",
+		e.handleAcceptancesGet())
+}
+
+// handleMyStatusGet returns a gorm Handler function.
+//
+// @ID acceptableUse_handleMyStatusGet
+// @Tags Acceptable Use
+// @Summary Get the acceptable use policy and the acceptance state of the current user.
+// @Produce json
+// @Success 200 {object} model.AcceptableUseStatus
+// @Failure 401 {object} model.Error
+// @Failure 404 {object} model.Error "The acceptable use policy is disabled"
+// @Failure 500 {object} model.Error
+// @Router /acceptable-use/my [get]
+func (e AcceptableUseEndpoint) handleMyStatusGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := e.acceptableUseService.GetStatus(r.Context(), domain.GetUserInfo(r.Context()).Id)
+		if err != nil {
+			respondAcceptableUseError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAcceptableUseStatus(status))
+	}
+}
+
+// handleAcceptPost returns a gorm Handler function.
+//
+// @ID acceptableUse_handleAcceptPost
+// @Tags Acceptable Use
+// @Summary Accept the acceptable use policy.
+// @Description The version must match the current policy version, outdated versions are rejected.
+// @Produce json
+// @Param request body model.AcceptableUseAcceptRequest true "The accepted policy version"
+// @Success 200 {object} model.AcceptableUseAcceptance
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /acceptable-use/my/accept [post]
+func (e AcceptableUseEndpoint) handleAcceptPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req model.AcceptableUseAcceptRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		acceptance, err := e.acceptableUseService.Accept(r.Context(), req.Version)
+		if err != nil {
+			respondAcceptableUseError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAcceptableUseAcceptance(acceptance))
+	}
+}
+
+// handleAcceptancesGet returns a gorm Handler function.
+//
+// @ID acceptableUse_handleAcceptancesGet
+// @Tags Acceptable Use
+// @Summary Get all acceptances of the acceptable use policy by the given user, the most recent acceptances first.
+// @Param id path string true "The user identifier (base64 encoded)"
+// @Produce json
+// @Success 200 {object} []model.AcceptableUseAcceptance
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /acceptable-use/by-user/{id} [get]
+func (e AcceptableUseEndpoint) handleAcceptancesGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: "missing user id"})
+			return
+		}
+
+		acceptances, err := e.acceptableUseService.GetAcceptances(r.Context(), domain.UserIdentifier(id))
+		if err != nil {
+			respondAcceptableUseError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAcceptableUseAcceptances(acceptances))
+	}
+}
+
+func respondAcceptableUseError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
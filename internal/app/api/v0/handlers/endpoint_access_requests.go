@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AccessRequestService interface {
+	// GetAccessRequests returns all requests of the current user or of the interfaces the user administrates.
+	GetAccessRequests(ctx context.Context) ([]domain.AccessRequest, error)
+	// GetRouteSets returns all route sets that can be requested.
+	GetRouteSets(ctx context.Context) ([]domain.RouteSet, error)
+	// RequestAccess requests temporary access to the given route set for the given peer.
+	RequestAccess(
+		ctx context.Context,
+		peerId domain.PeerIdentifier,
+		routeSetId domain.RouteSetIdentifier,
+		duration time.Duration,
+		reason string,
+	) (*domain.AccessRequest, error)
+	// ApproveRequest approves the given request and grants the access.
+	ApproveRequest(ctx context.Context, id domain.AccessRequestIdentifier) (*domain.AccessRequest, error)
+	// RejectRequest rejects the given request.
+	RejectRequest(ctx context.Context, id domain.AccessRequestIdentifier) (*domain.AccessRequest, error)
+	// CancelRequest withdraws the given request or ends the granted access early.
+	CancelRequest(ctx context.Context, id domain.AccessRequestIdentifier) (*domain.AccessRequest, error)
+}
+
+type AccessRequestEndpoint struct {
+	cfg                  *config.Config
+	accessRequestService AccessRequestService
+	authenticator        Authenticator
+	validator            Validator
+}
+
+func NewAccessRequestEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	accessRequestService AccessRequestService,
+) AccessRequestEndpoint {
+	return AccessRequestEndpoint{
+		cfg:                  cfg,
+		accessRequestService: accessRequestService,
+		authenticator:        authenticator,
+		validator:            validator,
+	}
+}
+
+func (e AccessRequestEndpoint) GetName() string {
+	return "AccessRequestEndpoint"
+}
+
+func (e AccessRequestEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/access-request")
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.HandleFunc("GET /route-sets", e.handleRouteSetsGet())
+	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
+	apiGroup.HandleFunc("POST /by-id/{id}/approve", e.handleActionPost(e.accessRequestService.ApproveRequest))
+	apiGroup.HandleFunc("POST /by-id/{id}/reject", e.handleActionPost(e.accessRequestService.RejectRequest))
+	apiGroup.HandleFunc("POST /by-id/{id}/cancel", e.handleActionPost(e.accessRequestService.CancelRequest))
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID accessRequests_handleAllGet
+// @Tags Access Requests
+// @Summary Get all access requests of the current user. Admins receive the requests of their interfaces as well.
+// @Produce json
+// @Success 200 {object} []model.AccessRequest
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /access-request/all [get]
+func (e AccessRequestEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requests, err := e.accessRequestService.GetAccessRequests(r.Context())
+		if err != nil {
+			respondAccessRequestError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAccessRequests(requests))
+	}
+}
+
+// handleRouteSetsGet returns a gorm Handler function.
+//
+// @ID accessRequests_handleRouteSetsGet
+// @Tags Access Requests
+// @Summary Get all route sets that can be requested. The networks are only included for admins.
+// @Produce json
+// @Success 200 {object} []model.RouteSet
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /access-request/route-sets [get]
+func (e AccessRequestEndpoint) handleRouteSetsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		routeSets, err := e.accessRequestService.GetRouteSets(r.Context())
+		if err != nil {
+			respondAccessRequestError(w, err)
+			return
+		}
+
+		results := make([]model.RouteSet, len(routeSets))
+		for i := range routeSets {
+			results[i] = *model.NewRouteSet(&routeSets[i], 0)
+		}
+
+		respond.JSON(w, http.StatusOK, results)
+	}
+}
+
+// handleCreatePost returns a gorm Handler function.
+//
+// @ID accessRequests_handleCreatePost
+// @Tags Access Requests
+// @Summary Request temporary access to a route set for a peer.
+// @Description An admin of the interface has to approve the request. Requests of admins are approved immediately.
+// @Produce json
+// @Param request body model.AccessRequestRequest true "The access request"
+// @Success 200 {object} model.AccessRequest
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 409 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /access-request/new [post]
+func (e AccessRequestEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req model.AccessRequestRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		accessRequest, err := e.accessRequestService.RequestAccess(r.Context(), domain.PeerIdentifier(req.PeerId),
+			domain.RouteSetIdentifier(req.RouteSetId), time.Duration(req.Duration)*time.Second, req.Reason)
+		if err != nil {
+			respondAccessRequestError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAccessRequest(accessRequest))
+	}
+}
+
+// handleActionPost returns a gorm Handler function.
+//
+// @ID accessRequests_handleActionPost
+// @Tags Access Requests
+// @Summary Approve, reject or cancel an access request.
+// @Description Once a request is approved, the route set is attached to the peer until the access window ends.
+// @Description Cancelling an approved request ends the access window early.
+// @Param id path string true "The request identifier"
+// @Param action path string true "The action" Enums(approve, reject, cancel)
+// @Produce json
+// @Success 200 {object} model.AccessRequest
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /access-request/by-id/{id}/{action} [post]
+func (e AccessRequestEndpoint) handleActionPost(
+	action func(context.Context, domain.AccessRequestIdentifier) (*domain.AccessRequest, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing request id"})
+			return
+		}
+
+		accessRequest, err := action(r.Context(), domain.AccessRequestIdentifier(id))
+		if err != nil {
+			respondAccessRequestError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAccessRequest(accessRequest))
+	}
+}
+
+func respondAccessRequestError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrDuplicateEntry):
+		code = http.StatusConflict
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AddressReservationService interface {
+	// GetReservations returns all address reservations of the given interface.
+	GetReservations(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.AddressReservation, error)
+	// CreateReservation creates a new address reservation.
+	CreateReservation(
+		ctx context.Context,
+		reservation *domain.AddressReservation,
+	) (*domain.AddressReservation, error)
+	// UpdateReservation updates the given address reservation.
+	UpdateReservation(
+		ctx context.Context,
+		reservation *domain.AddressReservation,
+	) (*domain.AddressReservation, error)
+	// DeleteReservation deletes the address reservation with the given id.
+	DeleteReservation(ctx context.Context, id domain.AddressReservationIdentifier) error
+}
+
+type AddressReservationEndpoint struct {
+	reservationService AddressReservationService
+	authenticator      Authenticator
+	validator          Validator
+}
+
+func NewAddressReservationEndpoint(
+	authenticator Authenticator,
+	validator Validator,
+	reservationService AddressReservationService,
+) AddressReservationEndpoint {
+	return AddressReservationEndpoint{
+		reservationService: reservationService,
+		authenticator:      authenticator,
+		validator:          validator,
+	}
+}
+
+func (e AddressReservationEndpoint) GetName() string {
+	return "AddressReservationEndpoint"
+}
+
+func (e AddressReservationEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/address-reservation")
+	// interface admins can manage the reservations of the interfaces they administrate, the service validates the
+	// interface
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeInterfaceAdmin))
+
+	apiGroup.HandleFunc("GET /by-interface/{id}", e.handleInterfaceGet())
+	apiGroup.HandleFunc("POST /by-interface/{id}", e.handleCreatePost())
+	apiGroup.HandleFunc("PUT /by-id/{id}", e.handleUpdatePut())
+	apiGroup.HandleFunc("DELETE /by-id/{id}", e.handleDelete())
+}
+
+// handleInterfaceGet returns a gorm Handler function.
+//
+// @ID addressReservations_handleInterfaceGet
+// @Tags Address Reservations
+// @Summary Get all address reservations of the given interface.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Success 200 {object} []model.AddressReservation
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /address-reservation/by-interface/{id} [get]
+func (e AddressReservationEndpoint) handleInterfaceGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		reservations, err := e.reservationService.GetReservations(r.Context(), domain.InterfaceIdentifier(id))
+		if err != nil {
+			respondAddressReservationError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAddressReservations(reservations))
+	}
+}
+
+// handleCreatePost returns a gorm Handler function.
+//
+// @ID addressReservations_handleCreatePost
+// @Tags Address Reservations
+// @Summary Reserve or exclude an address of the peer network of the given interface.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Param request body model.AddressReservationRequest true "The reservation data"
+// @Success 200 {object} model.AddressReservation
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 409 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /address-reservation/by-interface/{id} [post]
+func (e AddressReservationEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		var req model.AddressReservationRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		reservation := model.NewDomainAddressReservation(&req)
+		reservation.InterfaceIdentifier = domain.InterfaceIdentifier(id)
+
+		newReservation, err := e.reservationService.CreateReservation(r.Context(), reservation)
+		if err != nil {
+			respondAddressReservationError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAddressReservation(newReservation))
+	}
+}
+
+// handleUpdatePut returns a gorm Handler function.
+//
+// @ID addressReservations_handleUpdatePut
+// @Tags Address Reservations
+// @Summary Update the address reservation with the given id.
+// @Produce json
+// @Param id path string true "The reservation identifier"
+// @Param request body model.AddressReservationRequest true "The reservation data"
+// @Success 200 {object} model.AddressReservation
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 409 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /address-reservation/by-id/{id} [put]
+func (e AddressReservationEndpoint) handleUpdatePut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing reservation id"})
+			return
+		}
+
+		var req model.AddressReservationRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		reservation := model.NewDomainAddressReservation(&req)
+		reservation.Identifier = domain.AddressReservationIdentifier(id)
+
+		updatedReservation, err := e.reservationService.UpdateReservation(r.Context(), reservation)
+		if err != nil {
+			respondAddressReservationError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAddressReservation(updatedReservation))
+	}
+}
+
+// handleDelete returns a gorm Handler function.
+//
+// @ID addressReservations_handleDelete
+// @Tags Address Reservations
+// @Summary Delete the address reservation with the given id. The address can be assigned to new peers again.
+// @Produce json
+// @Param id path string true "The reservation identifier"
+// @Success 204 "No content if deletion was successful"
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /address-reservation/by-id/{id} [delete]
+func (e AddressReservationEndpoint) handleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing reservation id"})
+			return
+		}
+
+		err := e.reservationService.DeleteReservation(r.Context(), domain.AddressReservationIdentifier(id))
+		if err != nil {
+			respondAddressReservationError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+func respondAddressReservationError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrDuplicateEntry):
+		code = http.StatusConflict
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AlertService interface {
+	// GetAlertRules returns all configured alert rules.
+	GetAlertRules(ctx context.Context) ([]config.AlertRuleConfig, error)
+	// GetAlerts returns all firing alerts.
+	GetAlerts(ctx context.Context) ([]domain.Alert, error)
+	// AcknowledgeAlert acknowledges the firing alert of the given rule and peer.
+	AcknowledgeAlert(ctx context.Context, ruleName string, peerId domain.PeerIdentifier) (*domain.Alert, error)
+	// GetSilences returns all alert silences that have not expired yet.
+	GetSilences(ctx context.Context) ([]domain.AlertSilence, error)
+	// CreateSilence creates a new alert silence.
+	CreateSilence(ctx context.Context, silence *domain.AlertSilence) (*domain.AlertSilence, error)
+	// DeleteSilence deletes the alert silence with the given id.
+	DeleteSilence(ctx context.Context, id uint64) error
+}
+
+type AlertEndpoint struct {
+	cfg           *config.Config
+	alertService  AlertService
+	authenticator Authenticator
+	validator     Validator
+}
+
+func NewAlertEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	alertService AlertService,
+) AlertEndpoint {
+	return AlertEndpoint{
+		cfg:           cfg,
+		alertService:  alertService,
+		authenticator: authenticator,
+		validator:     validator,
+	}
+}
+
+func (e AlertEndpoint) GetName() string {
+	return "AlertEndpoint"
+}
+
+func (e AlertEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/alert")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /rules", e.handleRulesGet())
+	apiGroup.HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.HandleFunc("POST /ack/{rule}/{peer}", e.handleAcknowledgePost())
+	apiGroup.HandleFunc("GET /silences", e.handleSilencesGet())
+	apiGroup.HandleFunc("POST /silence/new", e.handleSilenceCreatePost())
+	apiGroup.HandleFunc("DELETE /silence/{id}", e.handleSilenceDelete())
+}
+
+// handleRulesGet returns a gorm Handler function.
+//
+// @ID alerts_handleRulesGet
+// @Tags Alerts
+// @Summary Get all configured alert rules.
+// @Produce json
+// @Success 200 {object} []model.AlertRule
+// @Failure 500 {object} model.Error
+// @Router /alert/rules [get]
+func (e AlertEndpoint) handleRulesGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rules, err := e.alertService.GetAlertRules(r.Context())
+		if err != nil {
+			respondAlertError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAlertRules(rules))
+	}
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID alerts_handleAllGet
+// @Tags Alerts
+// @Summary Get all firing alerts.
+// @Produce json
+// @Success 200 {object} []model.Alert
+// @Failure 500 {object} model.Error
+// @Router /alert/all [get]
+func (e AlertEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		alerts, err := e.alertService.GetAlerts(r.Context())
+		if err != nil {
+			respondAlertError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAlerts(alerts))
+	}
+}
+
+// handleAcknowledgePost returns a gorm Handler function.
+//
+// @ID alerts_handleAcknowledgePost
+// @Tags Alerts
+// @Summary Acknowledge a firing alert. No further notifications are sent until the alert is resolved.
+// @Param rule path string true "The alert rule name"
+// @Param peer path string true "The peer identifier (base64 encoded)"
+// @Produce json
+// @Success 200 {object} model.Alert
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /alert/ack/{rule}/{peer} [post]
+func (e AlertEndpoint) handleAcknowledgePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ruleName := request.Path(r, "rule")
+		peerId := Base64UrlDecode(request.Path(r, "peer"))
+		if ruleName == "" || peerId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing rule or peer id"})
+			return
+		}
+
+		alert, err := e.alertService.AcknowledgeAlert(r.Context(), ruleName, domain.PeerIdentifier(peerId))
+		if err != nil {
+			respondAlertError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAlert(*alert))
+	}
+}
+
+// handleSilencesGet returns a gorm Handler function.
+//
+// @ID alerts_handleSilencesGet
+// @Tags Alerts
+// @Summary Get all alert silences that have not expired yet.
+// @Produce json
+// @Success 200 {object} []model.AlertSilence
+// @Failure 500 {object} model.Error
+// @Router /alert/silences [get]
+func (e AlertEndpoint) handleSilencesGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		silences, err := e.alertService.GetSilences(r.Context())
+		if err != nil {
+			respondAlertError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAlertSilences(silences))
+	}
+}
+
+// handleSilenceCreatePost returns a gorm Handler function.
+//
+// @ID alerts_handleSilenceCreatePost
+// @Tags Alerts
+// @Summary Create a new alert silence. Matching alerts do not send notifications within the silence window.
+// @Produce json
+// @Param request body model.AlertSilence true "The silence data"
+// @Success 200 {object} model.AlertSilence
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /alert/silence/new [post]
+func (e AlertEndpoint) handleSilenceCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var silence model.AlertSilence
+		if err := request.BodyJson(r, &silence); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(silence); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		newSilence, err := e.alertService.CreateSilence(r.Context(), model.NewDomainAlertSilence(&silence))
+		if err != nil {
+			respondAlertError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAlertSilence(*newSilence))
+	}
+}
+
+// handleSilenceDelete returns a gorm Handler function.
+//
+// @ID alerts_handleSilenceDelete
+// @Tags Alerts
+// @Summary Delete the alert silence with the given id.
+// @Param id path string true "The silence identifier"
+// @Produce json
+// @Success 204 "No content if deletion was successful"
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /alert/silence/{id} [delete]
+func (e AlertEndpoint) handleSilenceDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(request.Path(r, "id"), 10, 64)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "invalid silence id"})
+			return
+		}
+
+		if err := e.alertService.DeleteSilence(r.Context(), id); err != nil {
+			respondAlertError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+func respondAlertError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AllowedIPConflictService interface {
+	// GetConflictReport returns the result of the last conflict check of the given interface.
+	GetConflictReport(ctx context.Context, id domain.InterfaceIdentifier) (*domain.AllowedIPConflictReport, error)
+	// CheckInterface checks the allowed IPs of the given interface for conflicts immediately.
+	CheckInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.AllowedIPConflictReport, error)
+}
+
+type AllowedIPConflictEndpoint struct {
+	conflictService AllowedIPConflictService
+	authenticator   Authenticator
+}
+
+func NewAllowedIPConflictEndpoint(
+	authenticator Authenticator,
+	conflictService AllowedIPConflictService,
+) AllowedIPConflictEndpoint {
+	return AllowedIPConflictEndpoint{
+		conflictService: conflictService,
+		authenticator:   authenticator,
+	}
+}
+
+func (e AllowedIPConflictEndpoint) GetName() string {
+	return "AllowedIPConflictEndpoint"
+}
+
+func (e AllowedIPConflictEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/allowed-ip-conflicts")
+	// interface admins can view the conflicts of the interfaces they administrate, the service validates the interface
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeInterfaceAdmin))
+
+	apiGroup.HandleFunc("GET /by-interface/{id}", e.handleReportGet())
+	apiGroup.HandleFunc("POST /by-interface/{id}/check", e.handleCheckPost())
+}
+
+// handleReportGet returns a gorm Handler function.
+//
+// @ID allowedIPConflicts_handleReportGet
+// @Tags Allowed IP Conflicts
+// @Summary Get the overlapping allowed IPs of the peers of the given interface.
+// @Description The report of the last background check is returned. If the interface was not checked yet, it is
+// @Description checked immediately.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Success 200 {object} model.AllowedIPConflictReport
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /allowed-ip-conflicts/by-interface/{id} [get]
+func (e AllowedIPConflictEndpoint) handleReportGet() http.HandlerFunc {
+	return e.handleReport(e.conflictService.GetConflictReport)
+}
+
+// handleCheckPost returns a gorm Handler function.
+//
+// @ID allowedIPConflicts_handleCheckPost
+// @Tags Allowed IP Conflicts
+// @Summary Check the allowed IPs of the peers of the given interface for overlaps immediately.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Success 200 {object} model.AllowedIPConflictReport
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /allowed-ip-conflicts/by-interface/{id}/check [post]
+func (e AllowedIPConflictEndpoint) handleCheckPost() http.HandlerFunc {
+	return e.handleReport(e.conflictService.CheckInterface)
+}
+
+func (e AllowedIPConflictEndpoint) handleReport(
+	load func(context.Context, domain.InterfaceIdentifier) (*domain.AllowedIPConflictReport, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		report, err := load(r.Context(), domain.InterfaceIdentifier(id))
+		if err != nil {
+			respondAllowedIPConflictError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAllowedIPConflictReport(report))
+	}
+}
+
+func respondAllowedIPConflictError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AnnouncementService interface {
+	// GetAnnouncements returns all announcements, including the number of users that read them.
+	GetAnnouncements(ctx context.Context) ([]domain.AnnouncementStatus, error)
+	// GetActiveAnnouncements returns all active announcements that target the current user.
+	GetActiveAnnouncements(ctx context.Context) ([]domain.AnnouncementStatus, error)
+	// MarkAnnouncementRead marks the given announcement as read by the current user.
+	MarkAnnouncementRead(ctx context.Context, id uint64) error
+	// CreateAnnouncement creates a new announcement.
+	CreateAnnouncement(ctx context.Context, announcement *domain.Announcement) (*domain.Announcement, error)
+	// UpdateAnnouncement updates the announcement.
+	UpdateAnnouncement(ctx context.Context, announcement *domain.Announcement) (*domain.Announcement, error)
+	// DeleteAnnouncement deletes the announcement with the given id.
+	DeleteAnnouncement(ctx context.Context, id uint64) error
+}
+
+type AnnouncementEndpoint struct {
+	cfg                 *config.Config
+	announcementService AnnouncementService
+	authenticator       Authenticator
+	validator           Validator
+}
+
+func NewAnnouncementEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	announcementService AnnouncementService,
+) AnnouncementEndpoint {
+	return AnnouncementEndpoint{
+		cfg:                 cfg,
+		announcementService: announcementService,
+		authenticator:       authenticator,
+		validator:           validator,
+	}
+}
+
+func (e AnnouncementEndpoint) GetName() string {
+	return "AnnouncementEndpoint"
+}
+
+func (e AnnouncementEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/announcement")
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.HandleFunc("GET /active", e.handleActiveGet())
+	apiGroup.HandleFunc("POST /by-id/{id}/read", e.handleReadPost())
+
+	adminGroup := apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin))
+	adminGroup.HandleFunc("GET /all", e.handleAllGet())
+	adminGroup.HandleFunc("POST /new", e.handleCreatePost())
+	adminGroup.HandleFunc("PUT /by-id/{id}", e.handleUpdatePut())
+	adminGroup.HandleFunc("DELETE /by-id/{id}", e.handleDelete())
+}
+
+// handleActiveGet returns a gorm Handler function.
+//
+// @ID announcements_handleActiveGet
+// @Tags Announcements
+// @Summary Get all active announcements that target the current user, including their read state.
+// @Produce json
+// @Success 200 {object} []model.Announcement
+// @Failure 401 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /announcement/active [get]
+func (e AnnouncementEndpoint) handleActiveGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		announcements, err := e.announcementService.GetActiveAnnouncements(r.Context())
+		if err != nil {
+			respondAnnouncementError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAnnouncements(announcements))
+	}
+}
+
+// handleReadPost returns a gorm Handler function.
+//
+// @ID announcements_handleReadPost
+// @Tags Announcements
+// @Summary Mark the announcement with the given id as read by the current user.
+// @Produce json
+// @Param id path string true "The announcement identifier"
+// @Success 204 "No content if the announcement was marked as read"
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error "The announcement is not active or does not target the user"
+// @Failure 500 {object} model.Error
+// @Router /announcement/by-id/{id}/read [post]
+func (e AnnouncementEndpoint) handleReadPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(request.Path(r, "id"), 10, 64)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "invalid announcement id"})
+			return
+		}
+
+		if err := e.announcementService.MarkAnnouncementRead(r.Context(), id); err != nil {
+			respondAnnouncementError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID announcements_handleAllGet
+// @Tags Announcements
+// @Summary Get all announcements. Only available for global administrators.
+// @Produce json
+// @Success 200 {object} []model.Announcement
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /announcement/all [get]
+func (e AnnouncementEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		announcements, err := e.announcementService.GetAnnouncements(r.Context())
+		if err != nil {
+			respondAnnouncementError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAnnouncements(announcements))
+	}
+}
+
+// handleCreatePost returns a gorm Handler function.
+//
+// @ID announcements_handleCreatePost
+// @Tags Announcements
+// @Summary Create a new announcement. Only available for global administrators.
+// @Produce json
+// @Param request body model.Announcement true "The announcement data"
+// @Success 200 {object} model.Announcement
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /announcement/new [post]
+func (e AnnouncementEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var announcement model.Announcement
+		if err := request.BodyJson(r, &announcement); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(announcement); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		newAnnouncement, err := e.announcementService.CreateAnnouncement(r.Context(),
+			model.NewDomainAnnouncement(&announcement))
+		if err != nil {
+			respondAnnouncementError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK,
+			model.NewAnnouncement(&domain.AnnouncementStatus{Announcement: *newAnnouncement}))
+	}
+}
+
+// handleUpdatePut returns a gorm Handler function.
+//
+// @ID announcements_handleUpdatePut
+// @Tags Announcements
+// @Summary Update the announcement. Only available for global administrators.
+// @Description Announcements that were already mailed are not mailed again.
+// @Produce json
+// @Param id path string true "The announcement identifier"
+// @Param request body model.Announcement true "The announcement data"
+// @Success 200 {object} model.Announcement
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /announcement/by-id/{id} [put]
+func (e AnnouncementEndpoint) handleUpdatePut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(request.Path(r, "id"), 10, 64)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "invalid announcement id"})
+			return
+		}
+
+		var announcement model.Announcement
+		if err := request.BodyJson(r, &announcement); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(announcement); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		if id != announcement.Id {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "announcement id mismatch"})
+			return
+		}
+
+		updatedAnnouncement, err := e.announcementService.UpdateAnnouncement(r.Context(),
+			model.NewDomainAnnouncement(&announcement))
+		if err != nil {
+			respondAnnouncementError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK,
+			model.NewAnnouncement(&domain.AnnouncementStatus{Announcement: *updatedAnnouncement}))
+	}
+}
+
+// handleDelete returns a gorm Handler function.
+//
+// @ID announcements_handleDelete
+// @Tags Announcements
+// @Summary Delete the announcement with the given id. Only available for global administrators.
+// @Produce json
+// @Param id path string true "The announcement identifier"
+// @Success 204 "No content if deletion was successful"
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /announcement/by-id/{id} [delete]
+func (e AnnouncementEndpoint) handleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(request.Path(r, "id"), 10, 64)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "invalid announcement id"})
+			return
+		}
+
+		if err := e.announcementService.DeleteAnnouncement(r.Context(), id); err != nil {
+			respondAnnouncementError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+func respondAnnouncementError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
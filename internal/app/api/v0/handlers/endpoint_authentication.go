@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -25,7 +27,23 @@ type AuthenticationService interface {
 	// OauthLoginStep1 initiates the OAuth login flow.
 	OauthLoginStep1(_ context.Context, providerId string) (authCodeUrl, state, nonce string, err error)
 	// OauthLoginStep2 completes the OAuth login flow and logins the user in.
-	OauthLoginStep2(ctx context.Context, providerId, nonce, code string) (*domain.User, error)
+	OauthLoginStep2(ctx context.Context, providerId, nonce, code string) (
+		*domain.User,
+		*domain.OidcSession,
+		error,
+	)
+	// ProxyLogin logs in the user that was authenticated by a trusted authentication proxy.
+	ProxyLogin(ctx context.Context, remoteAddr string, header http.Header) (*domain.User, error)
+	// BackchannelLogout validates an OpenID Connect logout token and returns the provider session that has ended.
+	BackchannelLogout(ctx context.Context, providerId, logoutToken string) (*domain.OidcSession, error)
+	// StartImpersonation validates that the current user is allowed to impersonate the given user.
+	StartImpersonation(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	// StopImpersonation ends an impersonation and returns the impersonating administrator.
+	StopImpersonation(
+		ctx context.Context,
+		impersonatorId, id domain.UserIdentifier,
+		reason string,
+	) (*domain.User, error)
 }
 
 type WebAuthnService interface {
@@ -108,22 +126,33 @@ func (e AuthEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 
 	apiGroup.HandleFunc("GET /login/{provider}/init", e.handleOauthInitiateGet())
 	apiGroup.HandleFunc("GET /login/{provider}/callback", e.handleOauthCallbackGet())
+	apiGroup.HandleFunc("POST /login/{provider}/backchannel-logout", e.handleBackchannelLogoutPost())
 
 	apiGroup.HandleFunc("POST /webauthn/login/start", e.handleWebAuthnLoginStart())
 	apiGroup.HandleFunc("POST /webauthn/login/finish", e.handleWebAuthnLoginFinish())
 	apiGroup.With(e.authenticator.LoggedIn()).HandleFunc("GET /webauthn/credentials",
 		e.handleWebAuthnCredentialsGet())
-	apiGroup.With(e.authenticator.LoggedIn()).HandleFunc("POST /webauthn/register/start",
-		e.handleWebAuthnRegisterStart())
-	apiGroup.With(e.authenticator.LoggedIn()).HandleFunc("POST /webauthn/register/finish",
-		e.handleWebAuthnRegisterFinish())
-	apiGroup.With(e.authenticator.LoggedIn()).HandleFunc("DELETE /webauthn/credential/{id}",
-		e.handleWebAuthnCredentialsDelete())
-	apiGroup.With(e.authenticator.LoggedIn()).HandleFunc("PUT /webauthn/credential/{id}",
-		e.handleWebAuthnCredentialsPut())
+	apiGroup.With(e.authenticator.LoggedIn(), e.authenticator.NoImpersonation()).HandleFunc(
+		"POST /webauthn/register/start", e.handleWebAuthnRegisterStart())
+	apiGroup.With(e.authenticator.LoggedIn(), e.authenticator.NoImpersonation()).HandleFunc(
+		"POST /webauthn/register/finish", e.handleWebAuthnRegisterFinish())
+	apiGroup.With(e.authenticator.LoggedIn(), e.authenticator.NoImpersonation()).HandleFunc(
+		"DELETE /webauthn/credential/{id}", e.handleWebAuthnCredentialsDelete())
+	apiGroup.With(e.authenticator.LoggedIn(), e.authenticator.NoImpersonation()).HandleFunc(
+		"PUT /webauthn/credential/{id}", e.handleWebAuthnCredentialsPut())
 
 	apiGroup.HandleFunc("POST /login", e.handleLoginPost())
+	apiGroup.With(e.authenticator.LoggedIn(), e.authenticator.NoImpersonation()).HandleFunc(
+		"POST /step-up", e.handleStepUpPost())
+	apiGroup.With(e.authenticator.LoggedIn(), e.authenticator.NoImpersonation()).HandleFunc(
+		"POST /step-up/webauthn/start", e.handleWebAuthnLoginStart())
+	apiGroup.With(e.authenticator.LoggedIn(), e.authenticator.NoImpersonation()).HandleFunc(
+		"POST /step-up/webauthn/finish", e.handleStepUpWebAuthnFinish())
 	apiGroup.With(e.authenticator.LoggedIn()).HandleFunc("POST /logout", e.handleLogoutPost())
+
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /impersonate/{id}",
+		e.handleImpersonateStartPost())
+	apiGroup.With(e.authenticator.LoggedIn()).HandleFunc("DELETE /impersonate", e.handleImpersonateStopDelete())
 }
 
 // handleExternalLoginProvidersGet returns a gorm Handler function.
@@ -153,32 +182,84 @@ func (e AuthEndpoint) handleExternalLoginProvidersGet() http.HandlerFunc {
 // @Router /auth/session [get]
 func (e AuthEndpoint) handleSessionInfoGet() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if e.cfg.Auth.ProxyAuth.Enabled {
+			e.syncProxySession(r)
+		}
+
 		currentSession := e.session.GetData(r.Context())
 
-		var loggedInUid *string
-		var firstname *string
-		var lastname *string
-		var email *string
+		respond.JSON(w, http.StatusOK, newSessionInfo(currentSession))
+	}
+}
 
-		if currentSession.LoggedIn {
-			uid := currentSession.UserIdentifier
-			f := currentSession.Firstname
-			l := currentSession.Lastname
-			e := currentSession.Email
-			loggedInUid = &uid
-			firstname = &f
-			lastname = &l
-			email = &e
-		}
-
-		respond.JSON(w, http.StatusOK, model.SessionInfo{
-			LoggedIn:       currentSession.LoggedIn,
-			IsAdmin:        currentSession.IsAdmin,
-			UserIdentifier: loggedInUid,
-			UserFirstname:  firstname,
-			UserLastname:   lastname,
-			UserEmail:      email,
-		})
+// syncProxySession logs in the user passed by the authentication proxy. Sessions started by the proxy are ended
+// once the proxy passes a different user. Sessions started by other login methods are not modified.
+func (e AuthEndpoint) syncProxySession(r *http.Request) {
+	currentSession := e.session.GetData(r.Context())
+	proxyUser := request.Header(r, e.cfg.Auth.ProxyAuth.UserHeader)
+
+	if currentSession.LoggedIn {
+		if currentSession.ProxyUser == "" || currentSession.ProxyUser == proxyUser {
+			return
+		}
+		e.session.DestroyData(r.Context()) // the user has changed at the proxy
+	}
+	if proxyUser == "" {
+		return
+	}
+
+	user, err := e.authService.ProxyLogin(withClientInfo(context.Background(), r), r.RemoteAddr, r.Header)
+	if err != nil {
+		slog.Debug("proxy login failed", "user", proxyUser, "error", err)
+		return
+	}
+
+	e.setAuthenticatedUser(r, user, nil)
+
+	currentSession = e.session.GetData(r.Context())
+	currentSession.ProxyUser = proxyUser
+	e.session.SetData(r.Context(), currentSession)
+}
+
+// newSessionInfo converts the given session data to the session info model.
+func newSessionInfo(currentSession SessionData) model.SessionInfo {
+	var loggedInUid *string
+	var firstname *string
+	var lastname *string
+	var email *string
+	var impersonator *string
+	var impersonationExpiry *time.Time
+
+	if currentSession.LoggedIn {
+		uid := currentSession.UserIdentifier
+		f := currentSession.Firstname
+		l := currentSession.Lastname
+		e := currentSession.Email
+		loggedInUid = &uid
+		firstname = &f
+		lastname = &l
+		email = &e
+	}
+
+	if currentSession.LoggedIn && currentSession.IsImpersonated() {
+		i := currentSession.ImpersonatorIdentifier
+		x := currentSession.ImpersonationExpiresAt
+		impersonator = &i
+		impersonationExpiry = &x
+	}
+
+	return model.SessionInfo{
+		LoggedIn:               currentSession.LoggedIn,
+		IsAdmin:                currentSession.IsAdmin,
+		IsHelpdesk:             currentSession.IsHelpdesk,
+		AdminInterfaces:        currentSession.AdminInterfaces,
+		Organization:           currentSession.Organization,
+		UserIdentifier:         loggedInUid,
+		UserFirstname:          firstname,
+		UserLastname:           lastname,
+		UserEmail:              email,
+		ImpersonatorIdentifier: impersonator,
+		ImpersonationExpiresAt: impersonationExpiry,
 	}
 }
 
@@ -324,8 +405,8 @@ func (e AuthEndpoint) handleOauthCallbackGet() http.HandlerFunc {
 			return
 		}
 
-		loginCtx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
-		user, err := e.authService.OauthLoginStep2(loginCtx, provider, currentSession.OauthNonce,
+		loginCtx, cancel := context.WithTimeout(withClientInfo(context.Background(), r), 1000*time.Second)
+		user, oidcSession, err := e.authService.OauthLoginStep2(loginCtx, provider, currentSession.OauthNonce,
 			oauthCode)
 		cancel()
 		if err != nil {
@@ -338,7 +419,7 @@ func (e AuthEndpoint) handleOauthCallbackGet() http.HandlerFunc {
 			return
 		}
 
-		e.setAuthenticatedUser(r, user)
+		e.setAuthenticatedUser(r, user, oidcSession)
 
 		if returnUrl != nil && e.isValidReturnUrl(returnUrl.String()) {
 			queryParams := returnUrl.Query()
@@ -351,27 +432,81 @@ func (e AuthEndpoint) handleOauthCallbackGet() http.HandlerFunc {
 	}
 }
 
-func (e AuthEndpoint) setAuthenticatedUser(r *http.Request, user *domain.User) {
+func (e AuthEndpoint) setAuthenticatedUser(
+	r *http.Request,
+	user *domain.User,
+	oidcSession *domain.OidcSession,
+) {
 	// start a fresh session
 	e.session.DestroyData(r.Context())
 
 	currentSession := e.session.GetData(r.Context())
 
 	currentSession.LoggedIn = true
-	currentSession.IsAdmin = user.IsAdmin
-	currentSession.UserIdentifier = string(user.Identifier)
-	currentSession.Firstname = user.Firstname
-	currentSession.Lastname = user.Lastname
-	currentSession.Email = user.Email
+	currentSession.setUser(user)
+	currentSession.AuthenticatedAt = time.Now()
 
 	currentSession.OauthState = ""
 	currentSession.OauthNonce = ""
 	currentSession.OauthProvider = ""
 	currentSession.OauthReturnTo = ""
 
+	if oidcSession != nil {
+		currentSession.OidcProvider = oidcSession.Provider
+		currentSession.OidcSubject = oidcSession.Subject
+		currentSession.OidcSessionId = oidcSession.SessionId
+	}
+
 	e.session.SetData(r.Context(), currentSession)
 }
 
+// handleBackchannelLogoutPost returns a gorm Handler function.
+//
+// @ID auth_handleBackchannelLogoutPost
+// @Tags Authentication
+// @Summary Receive an OpenID Connect back-channel logout and end the matching portal sessions.
+// @Accept x-www-form-urlencoded
+// @Param provider path string true "Provider identifier"
+// @Param logout_token formData string true "The logout token issued by the provider"
+// @Success 200
+// @Failure 400 {object} model.Error
+// @Router /auth/login/{provider}/backchannel-logout [post]
+func (e AuthEndpoint) handleBackchannelLogoutPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		provider := request.Path(r, "provider")
+		logoutToken := r.PostFormValue("logout_token")
+		if logoutToken == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing logout token"})
+			return
+		}
+
+		logout, err := e.authService.BackchannelLogout(r.Context(), provider, logoutToken)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		ended, err := e.session.DestroyMatching(r.Context(), func(s SessionData) bool {
+			return s.OidcSession().IsEndedBy(*logout)
+		})
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		slog.Info("ended sessions after back-channel logout",
+			"provider", provider,
+			"subject", logout.Subject,
+			"sessions", ended)
+
+		respond.Status(w, http.StatusOK)
+	}
+}
+
 // handleLoginPost returns a gorm Handler function.
 //
 // @ID auth_handleLoginPost
@@ -402,7 +537,7 @@ func (e AuthEndpoint) handleLoginPost() http.HandlerFunc {
 			return
 		}
 
-		user, err := e.authService.PlainLogin(context.Background(), loginData.Username,
+		user, err := e.authService.PlainLogin(withClientInfo(context.Background(), r), loginData.Username,
 			loginData.Password)
 		if err != nil {
 			respond.JSON(w, http.StatusUnauthorized,
@@ -410,12 +545,99 @@ func (e AuthEndpoint) handleLoginPost() http.HandlerFunc {
 			return
 		}
 
-		e.setAuthenticatedUser(r, user)
+		e.setAuthenticatedUser(r, user, nil)
 
 		respond.JSON(w, http.StatusOK, user)
 	}
 }
 
+// handleStepUpPost returns a gorm Handler function.
+//
+// @ID auth_handleStepUpPost
+// @Tags Authentication
+// @Summary Re-authenticate the current user with the password.
+// @Description A recent re-authentication is required to reveal private keys if step-up authentication is enabled.
+// @Produce json
+// @Success 200 {object} model.SessionInfo
+// @Failure 400 {object} model.Error
+// @Router /auth/step-up [post]
+func (e AuthEndpoint) handleStepUpPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var stepUpData struct {
+			Password string `json:"password" binding:"required,min=4"`
+		}
+
+		if err := request.BodyJson(r, &stepUpData); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validate.Struct(stepUpData); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		currentSession := e.session.GetData(r.Context())
+
+		// a failed re-authentication must not end the session, so 401 is not used here
+		user, err := e.authService.PlainLogin(withClientInfo(context.Background(), r),
+			currentSession.UserIdentifier, stepUpData.Password)
+		if err != nil || string(user.Identifier) != currentSession.UserIdentifier {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "re-authentication failed"})
+			return
+		}
+
+		e.setReAuthenticated(r)
+
+		respond.JSON(w, http.StatusOK, newSessionInfo(e.session.GetData(r.Context())))
+	}
+}
+
+// handleStepUpWebAuthnFinish returns a gorm Handler function.
+//
+// @ID auth_handleStepUpWebAuthnFinish
+// @Tags Authentication
+// @Summary Finish the re-authentication of the current user with a passkey.
+// @Description The re-authentication is started with /auth/step-up/webauthn/start.
+// @Produce json
+// @Success 200 {object} model.SessionInfo
+// @Failure 400 {object} model.Error
+// @Router /auth/step-up/webauthn/finish [post]
+func (e AuthEndpoint) handleStepUpWebAuthnFinish() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !e.webAuthn.Enabled() {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "WebAuthn is not enabled"})
+			return
+		}
+
+		currentSession := e.session.GetData(r.Context())
+
+		webAuthnSessionData := []byte(currentSession.WebAuthnData)
+		currentSession.WebAuthnData = "" // clear the session data
+		e.session.SetData(r.Context(), currentSession)
+
+		user, err := e.webAuthn.FinishWebAuthnLogin(withClientInfo(r.Context(), r), webAuthnSessionData, r)
+		if err != nil || string(user.Identifier) != currentSession.UserIdentifier {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "re-authentication failed"})
+			return
+		}
+
+		e.setReAuthenticated(r)
+
+		respond.JSON(w, http.StatusOK, newSessionInfo(e.session.GetData(r.Context())))
+	}
+}
+
+// setReAuthenticated updates the authentication time of the current session. In contrast to
+// setAuthenticatedUser, the session is kept.
+func (e AuthEndpoint) setReAuthenticated(r *http.Request) {
+	currentSession := e.session.GetData(r.Context())
+	currentSession.AuthenticatedAt = time.Now()
+	e.session.SetData(r.Context(), currentSession)
+}
+
 // handleLogoutPost returns a gorm Handler function.
 //
 // @ID auth_handleLogoutPost
@@ -433,11 +655,102 @@ func (e AuthEndpoint) handleLogoutPost() http.HandlerFunc {
 			return
 		}
 
+		if currentSession.IsImpersonated() {
+			_, _ = e.authService.StopImpersonation(r.Context(),
+				domain.UserIdentifier(currentSession.ImpersonatorIdentifier),
+				domain.UserIdentifier(currentSession.UserIdentifier), "stop")
+		}
+
 		e.session.DestroyData(r.Context())
 		respond.JSON(w, http.StatusOK, model.Error{Code: http.StatusOK, Message: "logout ok"})
 	}
 }
 
+// handleImpersonateStartPost returns a gorm Handler function.
+//
+// @ID auth_handleImpersonateStartPost
+// @Tags Authentication
+// @Summary Start impersonating the given user.
+// @Description The administrator session is switched to the given user until the impersonation is stopped or expires.
+// @Param id path string true "The user identifier"
+// @Produce json
+// @Success 200 {object} model.SessionInfo
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /auth/impersonate/{id} [post]
+func (e AuthEndpoint) handleImpersonateStartPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing user id"})
+			return
+		}
+
+		user, err := e.authService.StartImpersonation(r.Context(), domain.UserIdentifier(id))
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, domain.ErrNoPermission):
+				status = http.StatusForbidden
+			case errors.Is(err, domain.ErrInvalidData), errors.Is(err, domain.ErrNotFound):
+				status = http.StatusBadRequest
+			}
+			respond.JSON(w, status, model.Error{Code: status, Message: err.Error()})
+			return
+		}
+
+		currentSession := e.session.GetData(r.Context())
+
+		currentSession.ImpersonatorIdentifier = currentSession.UserIdentifier
+		currentSession.ImpersonationExpiresAt = time.Now().Add(e.cfg.Auth.ImpersonationTimeout)
+
+		currentSession.setUser(user)
+
+		e.session.SetData(r.Context(), currentSession)
+
+		respond.JSON(w, http.StatusOK, newSessionInfo(currentSession))
+	}
+}
+
+// handleImpersonateStopDelete returns a gorm Handler function.
+//
+// @ID auth_handleImpersonateStopDelete
+// @Tags Authentication
+// @Summary Stop the current impersonation and restore the administrator session.
+// @Produce json
+// @Success 200 {object} model.SessionInfo
+// @Failure 400 {object} model.Error
+// @Failure 401 {object} model.Error
+// @Router /auth/impersonate [delete]
+func (e AuthEndpoint) handleImpersonateStopDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentSession := e.session.GetData(r.Context())
+
+		if !currentSession.IsImpersonated() {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "no active impersonation"})
+			return
+		}
+
+		impersonator, err := e.authService.StopImpersonation(r.Context(),
+			domain.UserIdentifier(currentSession.ImpersonatorIdentifier),
+			domain.UserIdentifier(currentSession.UserIdentifier), "stop")
+		if err != nil {
+			e.session.DestroyData(r.Context())
+			respond.JSON(w, http.StatusUnauthorized,
+				model.Error{Code: http.StatusUnauthorized, Message: "session no longer available"})
+			return
+		}
+
+		currentSession = restoreImpersonatorSession(currentSession, impersonator)
+		e.session.SetData(r.Context(), currentSession)
+
+		respond.JSON(w, http.StatusOK, newSessionInfo(currentSession))
+	}
+}
+
 // isValidReturnUrl checks if the given return URL matches the configured external URL of the application.
 func (e AuthEndpoint) isValidReturnUrl(returnUrl string) bool {
 	if !strings.HasPrefix(returnUrl, e.cfg.Web.ExternalUrl) {
@@ -666,7 +979,7 @@ func (e AuthEndpoint) handleWebAuthnLoginFinish() http.HandlerFunc {
 		e.session.SetData(r.Context(), currentSession)
 
 		user, err := e.webAuthn.FinishWebAuthnLogin(
-			r.Context(),
+			withClientInfo(r.Context(), r),
 			webAuthnSessionData,
 			r)
 		if err != nil {
@@ -675,7 +988,7 @@ func (e AuthEndpoint) handleWebAuthnLoginFinish() http.HandlerFunc {
 			return
 		}
 
-		e.setAuthenticatedUser(r, user)
+		e.setAuthenticatedUser(r, user, nil)
 
 		respond.JSON(w, http.StatusOK, model.NewUser(user, false))
 	}
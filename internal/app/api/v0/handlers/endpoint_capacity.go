@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type CapacityService interface {
+	// GetCapacity returns the utilization of all address pools of the given interface.
+	GetCapacity(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.AddressPoolCapacity, error)
+}
+
+type CapacityEndpoint struct {
+	capacityService CapacityService
+	authenticator   Authenticator
+}
+
+func NewCapacityEndpoint(authenticator Authenticator, capacityService CapacityService) CapacityEndpoint {
+	return CapacityEndpoint{
+		capacityService: capacityService,
+		authenticator:   authenticator,
+	}
+}
+
+func (e CapacityEndpoint) GetName() string {
+	return "CapacityEndpoint"
+}
+
+func (e CapacityEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/capacity")
+	// interface admins can view the capacity of the interfaces they administrate, the service validates the interface
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeInterfaceAdmin))
+
+	apiGroup.HandleFunc("GET /by-interface/{id}", e.handleInterfaceCapacityGet())
+}
+
+// handleInterfaceCapacityGet returns a gorm Handler function.
+//
+// @ID capacity_handleInterfaceCapacityGet
+// @Tags Capacity
+// @Summary Get the address pool utilization of the given interface.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Success 200 {object} []model.AddressPoolCapacity
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /capacity/by-interface/{id} [get]
+func (e CapacityEndpoint) handleInterfaceCapacityGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		pools, err := e.capacityService.GetCapacity(r.Context(), domain.InterfaceIdentifier(id))
+		if err != nil {
+			respondCapacityError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewAddressPoolCapacities(pools))
+	}
+}
+
+func respondCapacityError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type CompromiseService interface {
+	// MarkCompromised disables the given peer and records the incident.
+	MarkCompromised(ctx context.Context, id domain.PeerIdentifier, reason string) (*domain.PeerCompromise, error)
+}
+
+type CompromiseEndpoint struct {
+	cfg               *config.Config
+	compromiseService CompromiseService
+	authenticator     Authenticator
+	validator         Validator
+}
+
+func NewCompromiseEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	compromiseService CompromiseService,
+) CompromiseEndpoint {
+	return CompromiseEndpoint{
+		cfg:               cfg,
+		compromiseService: compromiseService,
+		authenticator:     authenticator,
+		validator:         validator,
+	}
+}
+
+func (e CompromiseEndpoint) GetName() string {
+	return "CompromiseEndpoint"
+}
+
+func (e CompromiseEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/compromise")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("POST /peer/{id}", e.handlePeerPost())
+}
+
+// handlePeerPost returns a gorm Handler function.
+//
+// @ID compromise_handlePeerPost
+// @Tags Compromise
+// @Summary Mark a peer as compromised.
+// @Description The peer is disabled immediately. If configured, the preshared keys of the other peers of the owner
+// @Description on the same interface are rotated. The owner and the security contacts are notified and the incident
+// @Description is recorded in the audit log. If some preshared keys could not be rotated, the error is returned.
+// @Param id path string true "The peer identifier"
+// @Param request body model.PeerCompromiseRequest true "The reason of the compromise"
+// @Produce json
+// @Success 200 {object} model.PeerCompromise
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /compromise/peer/{id} [post]
+func (e CompromiseEndpoint) handlePeerPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing id parameter"})
+			return
+		}
+
+		var req model.PeerCompromiseRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		incident, err := e.compromiseService.MarkCompromised(r.Context(), domain.PeerIdentifier(id), req.Reason)
+		if err != nil {
+			respondCompromiseError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeerCompromise(incident))
+	}
+}
+
+func respondCompromiseError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
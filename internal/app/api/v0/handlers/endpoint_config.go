@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"html/template"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
@@ -21,17 +24,36 @@ import (
 //go:embed frontend_config.js.gotpl
 var frontendJs embed.FS
 
+type ConfigEndpointOrganizationService interface {
+	// GetOrganization returns the organization with the given id.
+	GetOrganization(ctx context.Context, id domain.OrganizationIdentifier) (*domain.Organization, error)
+}
+
+type ConfigEndpointReloadService interface {
+	// Reload loads the config file again and applies the reloadable config sections.
+	Reload(ctx context.Context) (*domain.ConfigReload, error)
+}
+
 type ConfigEndpoint struct {
 	cfg           *config.Config
 	authenticator Authenticator
+	organizations ConfigEndpointOrganizationService
+	reload        ConfigEndpointReloadService
 
 	tpl *respond.TemplateRenderer
 }
 
-func NewConfigEndpoint(cfg *config.Config, authenticator Authenticator) ConfigEndpoint {
+func NewConfigEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	organizations ConfigEndpointOrganizationService,
+	reload ConfigEndpointReloadService,
+) ConfigEndpoint {
 	ep := ConfigEndpoint{
 		cfg:           cfg,
 		authenticator: authenticator,
+		organizations: organizations,
+		reload:        reload,
 		tpl: respond.NewTemplateRenderer(template.Must(template.ParseFS(frontendJs,
 			"frontend_config.js.gotpl"))),
 	}
@@ -48,6 +70,7 @@ func (e ConfigEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 
 	apiGroup.HandleFunc("GET /frontend.js", e.handleConfigJsGet())
 	apiGroup.With(e.authenticator.InfoOnly()).HandleFunc("GET /settings", e.handleSettingsGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /reload", e.handleReloadPost())
 }
 
 // handleConfigJsGet returns a gorm Handler function.
@@ -99,17 +122,90 @@ func (e ConfigEndpoint) handleSettingsGet() http.HandlerFunc {
 		// For anonymous users, we return the settings object with minimal information
 		if sessionUser.Id == domain.CtxUnknownUserId || sessionUser.Id == "" {
 			respond.JSON(w, http.StatusOK, model.Settings{
-				WebAuthnEnabled: e.cfg.Auth.WebAuthn.Enabled,
+				WebAuthnEnabled:   e.cfg.Auth.WebAuthn.Enabled,
+				StatusPageEnabled: e.cfg.StatusPage.Enabled,
 			})
 		} else {
-			respond.JSON(w, http.StatusOK, model.Settings{
+			settings := model.Settings{
 				MailLinkOnly:              e.cfg.Mail.LinkOnly,
 				PersistentConfigSupported: e.cfg.Advanced.ConfigStoragePath != "",
 				SelfProvisioning:          e.cfg.Core.SelfProvisioningAllowed,
 				ApiAdminOnly:              e.cfg.Advanced.ApiAdminOnly,
 				WebAuthnEnabled:           e.cfg.Auth.WebAuthn.Enabled,
 				MinPasswordLength:         e.cfg.Auth.MinPasswordLength,
-			})
+				ConfigPullEnabled:         e.cfg.ConfigPull.Enabled,
+				StatusPageEnabled:         e.cfg.StatusPage.Enabled,
+				PeerTransferEnabled:       e.cfg.PeerTransfer.Enabled,
+				SshDeploymentEnabled:      e.cfg.SshDeployment.Enabled,
+				TelegramNotifications:     e.cfg.Alerting.Telegram.BotToken != "",
+				AcceptableUseEnabled:      e.cfg.AcceptableUse.Enabled,
+				KeyRevealStepUp:           e.cfg.KeyReveal.StepUpRequired,
+				KeyRotationSelfService:    e.cfg.KeyRotation.SelfService,
+				KeyRotationCooldown:       int(e.cfg.KeyRotation.Cooldown.Seconds()),
+				KeyRotationMailConfig:     e.cfg.KeyRotation.MailConfig,
+				ExportApprovalEnabled:     e.cfg.ExportApproval.Threshold > 0,
+				PortForwardingEnabled:     e.cfg.PortForwarding.Enabled,
+				PortForwardingSelfService: e.cfg.PortForwarding.SelfService,
+				PacketCaptureEnabled:      e.cfg.PacketCapture.Enabled,
+				PacketCaptureMaxDuration:  int(e.cfg.PacketCapture.MaxDuration.Seconds()),
+				LiveStatsMaxDuration:      int(e.cfg.Statistics.LiveSamplingMaxDuration.Seconds()),
+				AccessRequestsEnabled:     e.cfg.AccessRequests.Enabled,
+				AccessRequestMaxDuration:  int(e.cfg.AccessRequests.MaxDuration.Seconds()),
+				RouteReviewEnabled:        e.cfg.RouteReview.Enabled,
+			}
+			e.applyOrganizationBranding(r.Context(), sessionUser.Organization, &settings)
+			respond.JSON(w, http.StatusOK, settings)
 		}
 	}
 }
+
+// handleReloadPost returns a gorm Handler function.
+//
+// @ID config_handleReloadPost
+// @Tags Configuration
+// @Summary Reload the configuration file.
+// @Description Changed mail, authentication and schedule settings are applied without a restart. The WireGuard
+// @Description interfaces are not touched. Other changed sections are reported and applied on the next restart.
+// @Produce json
+// @Success 200 {object} model.ConfigReload
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /config/reload [post]
+func (e ConfigEndpoint) handleReloadPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := e.reload.Reload(r.Context())
+		if err != nil {
+			code := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, domain.ErrInvalidData):
+				code = http.StatusBadRequest
+			case errors.Is(err, domain.ErrNoPermission):
+				code = http.StatusForbidden
+			}
+			respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewConfigReload(result))
+	}
+}
+
+func (e ConfigEndpoint) applyOrganizationBranding(
+	ctx context.Context,
+	id domain.OrganizationIdentifier,
+	settings *model.Settings,
+) {
+	if id == "" {
+		return
+	}
+
+	org, err := e.organizations.GetOrganization(ctx, id)
+	if err != nil {
+		slog.Warn("failed to load organization branding", "organization", id, "error", err)
+		return
+	}
+
+	settings.SiteTitle = org.SiteTitle
+	settings.SiteCompanyName = org.CompanyName
+}
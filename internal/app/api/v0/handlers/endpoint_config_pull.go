@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type ConfigPullService interface {
+	// GetToken returns the metadata of the config pull token of the given peer.
+	GetToken(ctx context.Context, peerId domain.PeerIdentifier) (*domain.ConfigPullToken, error)
+	// CreateToken creates a new config pull token for the given peer. An existing token of the peer is replaced.
+	CreateToken(ctx context.Context, peerId domain.PeerIdentifier) (string, *domain.ConfigPullToken, error)
+	// GetProvisioningQrCode returns a QR code that contains the pull URL and the given plain text pull token.
+	GetProvisioningQrCode(ctx context.Context, plainToken, pullUrl string) (io.Reader, error)
+	// DeleteToken revokes the config pull token of the given peer.
+	DeleteToken(ctx context.Context, peerId domain.PeerIdentifier) error
+	// RunUpdateCampaign notifies the owners of all clients with an outdated app version by mail.
+	RunUpdateCampaign(ctx context.Context, campaign domain.ClientUpdateCampaign) (
+		*domain.ClientUpdateCampaignReport,
+		error,
+	)
+}
+
+type ConfigPullEndpoint struct {
+	cfg               *config.Config
+	configPullService ConfigPullService
+	authenticator     Authenticator
+}
+
+func NewConfigPullEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	configPullService ConfigPullService,
+) ConfigPullEndpoint {
+	return ConfigPullEndpoint{
+		cfg:               cfg,
+		configPullService: configPullService,
+		authenticator:     authenticator,
+	}
+}
+
+func (e ConfigPullEndpoint) GetName() string {
+	return "ConfigPullEndpoint"
+}
+
+func (e ConfigPullEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/config-pull")
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.HandleFunc("GET /{id}", e.handleTokenGet())
+	apiGroup.HandleFunc("POST /{id}", e.handleTokenPost())
+	apiGroup.HandleFunc("DELETE /{id}", e.handleTokenDelete())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /update-campaign",
+		e.handleUpdateCampaignPost())
+}
+
+func (e ConfigPullEndpoint) pullUrl() string {
+	return e.cfg.Web.ExternalUrl + "/api/v1/provisioning/config-pull"
+}
+
+// handleTokenGet returns a gorm Handler function.
+//
+// @ID configPull_handleTokenGet
+// @Tags Config Pull
+// @Summary Get the config pull token status of the given peer.
+// @Param id path string true "The peer identifier (base64 encoded)"
+// @Produce json
+// @Success 200 {object} model.ConfigPullToken
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /config-pull/{id} [get]
+func (e ConfigPullEndpoint) handleTokenGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerId := Base64UrlDecode(request.Path(r, "id"))
+		if peerId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		token, err := e.configPullService.GetToken(r.Context(), domain.PeerIdentifier(peerId))
+		if errors.Is(err, domain.ErrNotFound) {
+			token, err = nil, nil // the token status is inactive
+		}
+		if err != nil {
+			respondConfigPullError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewConfigPullToken(token, "", e.pullUrl()))
+	}
+}
+
+// handleTokenPost returns a gorm Handler function.
+//
+// @ID configPull_handleTokenPost
+// @Tags Config Pull
+// @Summary Create a new config pull token for the given peer. An existing token of the peer is revoked.
+// @Description The plain text token is only returned once. The response also contains a provisioning QR code with
+// @Description the pull URL and the token, which can be scanned by devices that fetch their config via the pull API.
+// @Param id path string true "The peer identifier (base64 encoded)"
+// @Produce json
+// @Success 200 {object} model.ConfigPullToken
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /config-pull/{id} [post]
+func (e ConfigPullEndpoint) handleTokenPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerId := Base64UrlDecode(request.Path(r, "id"))
+		if peerId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		plainToken, token, err := e.configPullService.CreateToken(r.Context(), domain.PeerIdentifier(peerId))
+		if err != nil {
+			respondConfigPullError(w, err)
+			return
+		}
+
+		result := model.NewConfigPullToken(token, plainToken, e.pullUrl())
+		if qrCode, err := e.provisioningQrCode(r.Context(), plainToken); err != nil {
+			slog.Warn("failed to create provisioning QR code", "peer", peerId, "error", err)
+		} else {
+			result.ProvisioningQrCode = qrCode
+		}
+
+		respond.JSON(w, http.StatusOK, result)
+	}
+}
+
+// provisioningQrCode returns the provisioning QR code of the given pull token as PNG data URL.
+func (e ConfigPullEndpoint) provisioningQrCode(ctx context.Context, plainToken string) (string, error) {
+	qrCode, err := e.configPullService.GetProvisioningQrCode(ctx, plainToken, e.pullUrl())
+	if err != nil {
+		return "", err
+	}
+	pngData, err := io.ReadAll(qrCode)
+	if err != nil {
+		return "", err
+	}
+
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData), nil
+}
+
+// handleTokenDelete returns a gorm Handler function.
+//
+// @ID configPull_handleTokenDelete
+// @Tags Config Pull
+// @Summary Revoke the config pull token of the given peer.
+// @Param id path string true "The peer identifier (base64 encoded)"
+// @Produce json
+// @Success 204 "No content if the token was revoked"
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /config-pull/{id} [delete]
+func (e ConfigPullEndpoint) handleTokenDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerId := Base64UrlDecode(request.Path(r, "id"))
+		if peerId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		if err := e.configPullService.DeleteToken(r.Context(), domain.PeerIdentifier(peerId)); err != nil {
+			respondConfigPullError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+// handleUpdateCampaignPost returns a gorm Handler function.
+//
+// @ID configPull_handleUpdateCampaignPost
+// @Tags Config Pull
+// @Summary Notify the owners of peers whose clients reported an outdated app version.
+// @Description The app version is reported by the clients during config pulls. Each user receives a single mail
+// @Description with update instructions for all outdated devices. With DryRun, no mails are sent.
+// @Param request body model.ClientUpdateCampaign true "The minimum app versions per operating system"
+// @Produce json
+// @Success 200 {object} model.ClientUpdateCampaignReport
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /config-pull/update-campaign [post]
+func (e ConfigPullEndpoint) handleUpdateCampaignPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var campaign model.ClientUpdateCampaign
+		if err := request.BodyJson(r, &campaign); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if len(campaign.MinVersions) == 0 {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing minimum versions"})
+			return
+		}
+
+		report, err := e.configPullService.RunUpdateCampaign(r.Context(),
+			model.NewDomainClientUpdateCampaign(&campaign))
+		if err != nil {
+			respondConfigPullError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewClientUpdateCampaignReport(report, campaign.DryRun))
+	}
+}
+
+func respondConfigPullError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type DataRetentionService interface {
+	// ForgetUser erases the personal data of the given user.
+	ForgetUser(ctx context.Context, id domain.UserIdentifier) (*domain.UserErasure, error)
+}
+
+type DataRetentionEndpoint struct {
+	cfg                  *config.Config
+	dataRetentionService DataRetentionService
+	authenticator        Authenticator
+}
+
+func NewDataRetentionEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	dataRetentionService DataRetentionService,
+) DataRetentionEndpoint {
+	return DataRetentionEndpoint{
+		cfg:                  cfg,
+		dataRetentionService: dataRetentionService,
+		authenticator:        authenticator,
+	}
+}
+
+func (e DataRetentionEndpoint) GetName() string {
+	return "DataRetentionEndpoint"
+}
+
+func (e DataRetentionEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/data-retention")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin), e.authenticator.NoImpersonation())
+
+	apiGroup.HandleFunc("POST /forget-user/{id}", e.handleForgetUserPost())
+}
+
+// handleForgetUserPost returns a gorm Handler function.
+//
+// @ID dataRetention_handleForgetUserPost
+// @Tags Data Retention
+// @Summary Erase the personal data of the given user.
+// @Description The user is deleted, the peers of the user are deleted or disabled and unlinked. Endpoint addresses
+// @Description are removed and audit entries are anonymized. Traffic statistics are kept.
+// @Param id path string true "The user identifier (base64 encoded)"
+// @Produce json
+// @Success 200 {object} model.UserErasure
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /data-retention/forget-user/{id} [post]
+func (e DataRetentionEndpoint) handleForgetUserPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: "missing user id"})
+			return
+		}
+
+		erasure, err := e.dataRetentionService.ForgetUser(r.Context(), domain.UserIdentifier(id))
+		if err != nil {
+			code := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, domain.ErrInvalidData):
+				code = http.StatusBadRequest
+			case errors.Is(err, domain.ErrNoPermission):
+				code = http.StatusForbidden
+			}
+			respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewUserErasure(erasure))
+	}
+}
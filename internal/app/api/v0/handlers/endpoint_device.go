@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type DeviceAuthService interface {
+	// GetPendingAuthorization returns the pending device authorization request for the given user code.
+	GetPendingAuthorization(ctx context.Context, userCode string) (*domain.DeviceAuthorization, error)
+	// ApproveAuthorization approves the device authorization request for the given user code.
+	ApproveAuthorization(
+		ctx context.Context,
+		userCode string,
+		interfaceId domain.InterfaceIdentifier,
+		peerId domain.PeerIdentifier,
+	) (*domain.DeviceAuthorization, error)
+	// DenyAuthorization denies the device authorization request for the given user code.
+	DenyAuthorization(ctx context.Context, userCode string) error
+}
+
+type DeviceEndpoint struct {
+	cfg               *config.Config
+	deviceAuthService DeviceAuthService
+	authenticator     Authenticator
+	validator         Validator
+}
+
+func NewDeviceEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	deviceAuthService DeviceAuthService,
+) DeviceEndpoint {
+	return DeviceEndpoint{
+		cfg:               cfg,
+		deviceAuthService: deviceAuthService,
+		authenticator:     authenticator,
+		validator:         validator,
+	}
+}
+
+func (e DeviceEndpoint) GetName() string {
+	return "DeviceEndpoint"
+}
+
+func (e DeviceEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/device")
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.HandleFunc("GET /{code}", e.handleSingleGet())
+	apiGroup.HandleFunc("POST /{code}/approve", e.handleApprovePost())
+	apiGroup.HandleFunc("POST /{code}/deny", e.handleDenyPost())
+}
+
+// handleSingleGet returns a gorm Handler function.
+//
+// @ID device_handleSingleGet
+// @Tags Device Authorization
+// @Summary Get the pending device authorization request for the given user code.
+// @Param code path string true "The user code shown by the device"
+// @Produce json
+// @Success 200 {object} model.DeviceAuthorization
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /device/{code} [get]
+func (e DeviceEndpoint) handleSingleGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := e.deviceAuthService.GetPendingAuthorization(r.Context(), request.Path(r, "code"))
+		if err != nil {
+			respondDeviceError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewDeviceAuthorization(req))
+	}
+}
+
+// handleApprovePost returns a gorm Handler function.
+//
+// @ID device_handleApprovePost
+// @Tags Device Authorization
+// @Summary Approve the device authorization request. The device receives the configuration of the given or a new peer.
+// @Param code path string true "The user code shown by the device"
+// @Param request body model.DeviceApproval true "The peer selection"
+// @Produce json
+// @Success 200 {object} model.DeviceAuthorization
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /device/{code}/approve [post]
+func (e DeviceEndpoint) handleApprovePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var approval model.DeviceApproval
+		if err := request.BodyJson(r, &approval); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		req, err := e.deviceAuthService.ApproveAuthorization(r.Context(), request.Path(r, "code"),
+			domain.InterfaceIdentifier(approval.InterfaceIdentifier), domain.PeerIdentifier(approval.PeerIdentifier))
+		if err != nil {
+			respondDeviceError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewDeviceAuthorization(req))
+	}
+}
+
+// handleDenyPost returns a gorm Handler function.
+//
+// @ID device_handleDenyPost
+// @Tags Device Authorization
+// @Summary Deny the device authorization request.
+// @Param code path string true "The user code shown by the device"
+// @Produce json
+// @Success 204 "No content if the request was denied"
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /device/{code}/deny [post]
+func (e DeviceEndpoint) handleDenyPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := e.deviceAuthService.DenyAuthorization(r.Context(), request.Path(r, "code")); err != nil {
+			respondDeviceError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+func respondDeviceError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	case errors.Is(err, domain.ErrDuplicateEntry), errors.Is(err, domain.ErrQuotaExceeded):
+		code = http.StatusConflict
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type DiagnosticsService interface {
+	// DiagnosePeer runs all connection checks for the given peer.
+	DiagnosePeer(ctx context.Context, id domain.PeerIdentifier) (*domain.PeerDiagnosis, error)
+}
+
+type DiagnosticsEndpoint struct {
+	diagnosticsService DiagnosticsService
+	authenticator      Authenticator
+}
+
+func NewDiagnosticsEndpoint(authenticator Authenticator, diagnosticsService DiagnosticsService) DiagnosticsEndpoint {
+	return DiagnosticsEndpoint{
+		diagnosticsService: diagnosticsService,
+		authenticator:      authenticator,
+	}
+}
+
+func (e DiagnosticsEndpoint) GetName() string {
+	return "DiagnosticsEndpoint"
+}
+
+func (e DiagnosticsEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/diagnostics")
+	// interface admins can diagnose the peers of the interfaces they administrate, the service validates the
+	// interface
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeInterfaceAdmin))
+
+	apiGroup.HandleFunc("GET /peer/{id}", e.handlePeerDiagnosisGet())
+}
+
+// handlePeerDiagnosisGet returns a gorm Handler function.
+//
+// @ID diagnostics_handlePeerDiagnosisGet
+// @Tags Diagnostics
+// @Summary Diagnose the connection of the given peer.
+// @Description Returns the results of all connection checks, the most likely cause of connection problems first.
+// @Produce json
+// @Param id path string true "The peer identifier"
+// @Success 200 {object} model.PeerDiagnosis
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /diagnostics/peer/{id} [get]
+func (e DiagnosticsEndpoint) handlePeerDiagnosisGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		diagnosis, err := e.diagnosticsService.DiagnosePeer(r.Context(), domain.PeerIdentifier(id))
+		if err != nil {
+			respondDiagnosticsError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeerDiagnosis(diagnosis))
+	}
+}
+
+func respondDiagnosticsError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
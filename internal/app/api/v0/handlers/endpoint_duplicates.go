@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type DuplicateService interface {
+	// GetDuplicates returns all groups of likely duplicate peers of the given interface.
+	GetDuplicates(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.DuplicatePeerGroup, error)
+	// MergePeers keeps the given peer and archives the duplicates.
+	MergePeers(ctx context.Context, keepId domain.PeerIdentifier, duplicateIds []domain.PeerIdentifier) (
+		[]domain.Peer,
+		error,
+	)
+}
+
+type DuplicateEndpoint struct {
+	duplicateService DuplicateService
+	authenticator    Authenticator
+}
+
+func NewDuplicateEndpoint(authenticator Authenticator, duplicateService DuplicateService) DuplicateEndpoint {
+	return DuplicateEndpoint{
+		duplicateService: duplicateService,
+		authenticator:    authenticator,
+	}
+}
+
+func (e DuplicateEndpoint) GetName() string {
+	return "DuplicateEndpoint"
+}
+
+func (e DuplicateEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/duplicates")
+	// interface admins can manage the duplicates of the interfaces they administrate, the service validates the
+	// interface
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeInterfaceAdmin))
+
+	apiGroup.HandleFunc("GET /by-interface/{id}", e.handleInterfaceDuplicatesGet())
+	apiGroup.HandleFunc("POST /merge", e.handleMergePost())
+}
+
+// handleInterfaceDuplicatesGet returns a gorm Handler function.
+//
+// @ID duplicates_handleInterfaceDuplicatesGet
+// @Tags Duplicates
+// @Summary Get the groups of likely duplicate peers of the given interface.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Success 200 {object} []model.DuplicatePeerGroup
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /duplicates/by-interface/{id} [get]
+func (e DuplicateEndpoint) handleInterfaceDuplicatesGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		groups, err := e.duplicateService.GetDuplicates(r.Context(), domain.InterfaceIdentifier(id))
+		if err != nil {
+			respondDuplicateError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewDuplicatePeerGroups(groups))
+	}
+}
+
+// handleMergePost returns a gorm Handler function.
+//
+// @ID duplicates_handleMergePost
+// @Tags Duplicates
+// @Summary Keep one peer and archive its duplicates.
+// @Produce json
+// @Param request body model.DuplicateMergeRequest true "The merge request"
+// @Success 204 "No content if the peers were merged successfully"
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /duplicates/merge [post]
+func (e DuplicateEndpoint) handleMergePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req model.DuplicateMergeRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		duplicateIds := make([]domain.PeerIdentifier, len(req.Archive))
+		for i, id := range req.Archive {
+			duplicateIds[i] = domain.PeerIdentifier(id)
+		}
+
+		_, err := e.duplicateService.MergePeers(r.Context(), domain.PeerIdentifier(req.Keep), duplicateIds)
+		if err != nil {
+			respondDuplicateError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+func respondDuplicateError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
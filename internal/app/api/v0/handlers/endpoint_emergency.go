@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type EmergencyService interface {
+	// GetLockdowns returns all emergency lockdowns, the most recent lockdowns first.
+	GetLockdowns(ctx context.Context) ([]domain.EmergencyLockdown, error)
+	// Lockdown disables all peers of the given user or the given interface.
+	Lockdown(
+		ctx context.Context,
+		target domain.EmergencyTarget,
+		targetId string,
+		reason string,
+	) (*domain.EmergencyLockdown, error)
+	// Restore reverts the given lockdown.
+	Restore(ctx context.Context, id domain.EmergencyLockdownIdentifier) (*domain.EmergencyLockdown, error)
+}
+
+type EmergencyEndpoint struct {
+	cfg              *config.Config
+	emergencyService EmergencyService
+	authenticator    Authenticator
+	validator        Validator
+}
+
+func NewEmergencyEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	emergencyService EmergencyService,
+) EmergencyEndpoint {
+	return EmergencyEndpoint{
+		cfg:              cfg,
+		emergencyService: emergencyService,
+		authenticator:    authenticator,
+		validator:        validator,
+	}
+}
+
+func (e EmergencyEndpoint) GetName() string {
+	return "EmergencyEndpoint"
+}
+
+func (e EmergencyEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/emergency")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.HandleFunc("POST /new", e.handleLockdownPost())
+	apiGroup.HandleFunc("POST /by-id/{id}/restore", e.handleRestorePost())
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID emergency_handleAllGet
+// @Tags Emergency
+// @Summary Get all emergency lockdowns, the most recent lockdowns first.
+// @Produce json
+// @Success 200 {object} []model.EmergencyLockdown
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /emergency/all [get]
+func (e EmergencyEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lockdowns, err := e.emergencyService.GetLockdowns(r.Context())
+		if err != nil {
+			respondEmergencyError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewEmergencyLockdowns(lockdowns))
+	}
+}
+
+// handleLockdownPost returns a gorm Handler function.
+//
+// @ID emergency_handleLockdownPost
+// @Tags Emergency
+// @Summary Immediately disable all peers of a user or an entire interface.
+// @Description The state before the lockdown is stored as snapshot, so the lockdown can be restored later.
+// @Description If some peers could not be disabled, the error is returned and the lockdown is stored anyway.
+// @Produce json
+// @Param request body model.EmergencyLockdownRequest true "The lockdown target"
+// @Success 200 {object} model.EmergencyLockdown
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /emergency/new [post]
+func (e EmergencyEndpoint) handleLockdownPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req model.EmergencyLockdownRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		lockdown, err := e.emergencyService.Lockdown(r.Context(), domain.EmergencyTarget(req.Target), req.TargetId,
+			req.Reason)
+		if err != nil {
+			respondEmergencyError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewEmergencyLockdown(lockdown))
+	}
+}
+
+// handleRestorePost returns a gorm Handler function.
+//
+// @ID emergency_handleRestorePost
+// @Tags Emergency
+// @Summary Restore the state before an emergency lockdown.
+// @Description Peers and interfaces that were enabled, disabled for another reason or deleted since the lockdown
+// @Description are not modified.
+// @Param id path string true "The lockdown identifier"
+// @Produce json
+// @Success 200 {object} model.EmergencyLockdown
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /emergency/by-id/{id}/restore [post]
+func (e EmergencyEndpoint) handleRestorePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing lockdown id"})
+			return
+		}
+
+		lockdown, err := e.emergencyService.Restore(r.Context(), domain.EmergencyLockdownIdentifier(id))
+		if err != nil {
+			respondEmergencyError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewEmergencyLockdown(lockdown))
+	}
+}
+
+func respondEmergencyError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
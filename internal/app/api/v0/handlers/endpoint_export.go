@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type ExportService interface {
+	// ExportPeers prepares the export of the peers of the given interface.
+	ExportPeers(ctx context.Context, id domain.InterfaceIdentifier, req domain.ExportRequest) (*domain.Export, error)
+	// ExportUsers prepares the export of all users.
+	ExportUsers(ctx context.Context, req domain.ExportRequest) (*domain.Export, error)
+}
+
+type ExportEndpoint struct {
+	cfg           *config.Config
+	exportService ExportService
+	authenticator Authenticator
+}
+
+func NewExportEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	exportService ExportService,
+) ExportEndpoint {
+	return ExportEndpoint{
+		cfg:           cfg,
+		exportService: exportService,
+		authenticator: authenticator,
+	}
+}
+
+func (e ExportEndpoint) GetName() string {
+	return "ExportEndpoint"
+}
+
+func (e ExportEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/export")
+
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc("GET /peers/{iface}",
+		e.handlePeersGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /users", e.handleUsersGet())
+}
+
+// handlePeersGet returns a gorm Handler function.
+//
+// @ID export_handlePeersGet
+// @Tags Export
+// @Summary Export the peers of the given interface as CSV or XLSX file.
+// @Description The file is streamed, only peers matching the filter are exported.
+// @Param iface path string true "The interface identifier (base64 encoded)"
+// @Param format query string false "The file format, csv (default) or xlsx"
+// @Param filter query string false "Only export peers whose display name or identifier contains the filter"
+// @Param device_type query string false "Only export peers of the device type: laptop, phone, server, router or iot"
+// @Param columns query string false "The comma separated column keys, all columns are exported by default"
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Success 200 {file} binary
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /export/peers/{iface} [get]
+func (e ExportEndpoint) handlePeersGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		interfaceId := Base64UrlDecode(request.Path(r, "iface"))
+		if interfaceId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing iface parameter"})
+			return
+		}
+
+		req, err := parseExportRequest(r)
+		if err != nil {
+			respondExportError(w, err)
+			return
+		}
+
+		export, err := e.exportService.ExportPeers(r.Context(), domain.InterfaceIdentifier(interfaceId), req)
+		if err != nil {
+			respondExportError(w, err)
+			return
+		}
+
+		writeExport(w, export)
+	}
+}
+
+// handleUsersGet returns a gorm Handler function.
+//
+// @ID export_handleUsersGet
+// @Tags Export
+// @Summary Export all users as CSV or XLSX file.
+// @Description The file is streamed, only users matching the filter are exported.
+// @Param format query string false "The file format, csv (default) or xlsx"
+// @Param filter query string false "Only export users whose name, email or identifier contains the filter"
+// @Param columns query string false "The comma separated column keys, all columns are exported by default"
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Success 200 {file} binary
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /export/users [get]
+func (e ExportEndpoint) handleUsersGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := parseExportRequest(r)
+		if err != nil {
+			respondExportError(w, err)
+			return
+		}
+
+		export, err := e.exportService.ExportUsers(r.Context(), req)
+		if err != nil {
+			respondExportError(w, err)
+			return
+		}
+
+		writeExport(w, export)
+	}
+}
+
+func parseExportRequest(r *http.Request) (domain.ExportRequest, error) {
+	format, err := domain.ParseExportFormat(request.Query(r, "format"))
+	if err != nil {
+		return domain.ExportRequest{}, err
+	}
+
+	var columns []string
+	if columnList := request.Query(r, "columns"); columnList != "" {
+		columns = strings.Split(columnList, ",")
+	}
+
+	deviceType := domain.PeerDeviceType(request.Query(r, "device_type"))
+	if !deviceType.IsValid() {
+		return domain.ExportRequest{}, fmt.Errorf("invalid device type %q: %w", deviceType, domain.ErrInvalidData)
+	}
+
+	return domain.ExportRequest{
+		Format:     format,
+		Filter:     request.QueryRaw(r, "filter"),
+		Columns:    columns,
+		DeviceType: deviceType,
+	}, nil
+}
+
+// writeExport streams the export to the client. Errors that occur after the first row was sent can only be logged.
+func writeExport(w http.ResponseWriter, export *domain.Export) {
+	w.Header().Set("Content-Disposition", "attachment; filename="+export.Filename)
+	w.Header().Set("Content-Type", export.ContentType)
+	w.WriteHeader(http.StatusOK)
+
+	if err := export.Write(w); err != nil {
+		slog.Error("failed to write export", "file", export.Filename, "error", err)
+	}
+}
+
+func respondExportError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type ExportApprovalService interface {
+	// GetExportApprovals returns all export approval requests, the most recent requests first.
+	GetExportApprovals(ctx context.Context) ([]domain.ExportApproval, error)
+	// ApproveExportApproval approves the pending request with the given id.
+	ApproveExportApproval(ctx context.Context, id uint64) (*domain.ExportApproval, error)
+	// RejectExportApproval rejects the pending request with the given id.
+	RejectExportApproval(ctx context.Context, id uint64) (*domain.ExportApproval, error)
+}
+
+type ExportApprovalEndpoint struct {
+	cfg                   *config.Config
+	exportApprovalService ExportApprovalService
+	authenticator         Authenticator
+}
+
+func NewExportApprovalEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	exportApprovalService ExportApprovalService,
+) ExportApprovalEndpoint {
+	return ExportApprovalEndpoint{
+		cfg:                   cfg,
+		exportApprovalService: exportApprovalService,
+		authenticator:         authenticator,
+	}
+}
+
+func (e ExportApprovalEndpoint) GetName() string {
+	return "ExportApprovalEndpoint"
+}
+
+func (e ExportApprovalEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/export-approval")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.HandleFunc("POST /by-id/{id}/approve", e.handleApprovePost())
+	apiGroup.HandleFunc("POST /by-id/{id}/reject", e.handleRejectPost())
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID exportApprovals_handleAllGet
+// @Tags Export Approvals
+// @Summary Get all export approval requests. Only available for global administrators.
+// @Produce json
+// @Success 200 {object} []model.ExportApproval
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /export-approval/all [get]
+func (e ExportApprovalEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		approvals, err := e.exportApprovalService.GetExportApprovals(r.Context())
+		if err != nil {
+			respondExportApprovalError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewExportApprovals(approvals))
+	}
+}
+
+// handleApprovePost returns a gorm Handler function.
+//
+// @ID exportApprovals_handleApprovePost
+// @Tags Export Approvals
+// @Summary Approve the pending export request with the given id.
+// @Description The request must be approved by a global administrator other than the requesting user.
+// @Produce json
+// @Param id path string true "The export approval identifier"
+// @Success 200 {object} model.ExportApproval
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /export-approval/by-id/{id}/approve [post]
+func (e ExportApprovalEndpoint) handleApprovePost() http.HandlerFunc {
+	return e.handleDecision(e.exportApprovalService.ApproveExportApproval)
+}
+
+// handleRejectPost returns a gorm Handler function.
+//
+// @ID exportApprovals_handleRejectPost
+// @Tags Export Approvals
+// @Summary Reject the pending export request with the given id.
+// @Produce json
+// @Param id path string true "The export approval identifier"
+// @Success 200 {object} model.ExportApproval
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /export-approval/by-id/{id}/reject [post]
+func (e ExportApprovalEndpoint) handleRejectPost() http.HandlerFunc {
+	return e.handleDecision(e.exportApprovalService.RejectExportApproval)
+}
+
+func (e ExportApprovalEndpoint) handleDecision(
+	decide func(ctx context.Context, id uint64) (*domain.ExportApproval, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(request.Path(r, "id"), 10, 64)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "invalid export approval id"})
+			return
+		}
+
+		approval, err := decide(r.Context(), id)
+		if err != nil {
+			respondExportApprovalError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewExportApproval(approval))
+	}
+}
+
+func respondExportApprovalError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type FailoverService interface {
+	// GetHealth returns the health report of this gateway.
+	GetHealth(ctx context.Context) (*domain.FailoverHealth, error)
+	// GetStatus returns the current state of the failover controller.
+	GetStatus(ctx context.Context) (*domain.FailoverStatus, error)
+}
+
+type FailoverEndpoint struct {
+	failoverService FailoverService
+	authenticator   Authenticator
+}
+
+func NewFailoverEndpoint(authenticator Authenticator, failoverService FailoverService) FailoverEndpoint {
+	return FailoverEndpoint{
+		failoverService: failoverService,
+		authenticator:   authenticator,
+	}
+}
+
+func (e FailoverEndpoint) GetName() string {
+	return "FailoverEndpoint"
+}
+
+func (e FailoverEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/failover")
+
+	// the health report is polled by the other gateway of the pair, it contains no peer or user data
+	apiGroup.HandleFunc("GET /health", e.handleHealthGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /status", e.handleStatusGet())
+}
+
+// handleHealthGet returns a gorm Handler function.
+//
+// @ID failover_handleHealthGet
+// @Tags Failover
+// @Summary Get the failover health report of this gateway.
+// @Description No authentication is required. Unhealthy gateways respond with status 503.
+// @Produce json
+// @Success 200 {object} model.FailoverHealth
+// @Failure 404 {object} model.Error
+// @Failure 503 {object} model.FailoverHealth
+// @Router /failover/health [get]
+func (e FailoverEndpoint) handleHealthGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health, err := e.failoverService.GetHealth(r.Context())
+		if err != nil {
+			respondFailoverError(w, err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-cache")
+		code := http.StatusOK
+		if !health.Healthy {
+			code = http.StatusServiceUnavailable
+		}
+		respond.JSON(w, code, model.NewFailoverHealth(health))
+	}
+}
+
+// handleStatusGet returns a gorm Handler function.
+//
+// @ID failover_handleStatusGet
+// @Tags Failover
+// @Summary Get the state of the failover controller of this gateway.
+// @Produce json
+// @Success 200 {object} model.FailoverStatus
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /failover/status [get]
+func (e FailoverEndpoint) handleStatusGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := e.failoverService.GetStatus(r.Context())
+		if err != nil {
+			respondFailoverError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewFailoverStatus(status))
+	}
+}
+
+func respondFailoverError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
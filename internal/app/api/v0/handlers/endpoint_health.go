@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type HealthService interface {
+	// GetHealth returns the result of the last health check run.
+	GetHealth(ctx context.Context) (*domain.HealthStatus, error)
+}
+
+type HealthEndpoint struct {
+	healthService HealthService
+}
+
+func NewHealthEndpoint(healthService HealthService) HealthEndpoint {
+	return HealthEndpoint{
+		healthService: healthService,
+	}
+}
+
+func (e HealthEndpoint) GetName() string {
+	return "HealthEndpoint"
+}
+
+func (e HealthEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	// the health status is polled by container runtimes, it contains no peer or user data
+	g.HandleFunc("GET /health", e.handleHealthGet())
+}
+
+// handleHealthGet returns a gorm Handler function.
+//
+// @ID health_handleHealthGet
+// @Tags Health
+// @Summary Get the health status of WireGuard Portal.
+// @Description No authentication is required. If a check failed, the response has status 503.
+// @Produce json
+// @Success 200 {object} model.HealthStatus
+// @Failure 500 {object} model.Error
+// @Failure 503 {object} model.HealthStatus
+// @Router /health [get]
+func (e HealthEndpoint) handleHealthGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health, err := e.healthService.GetHealth(r.Context())
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-cache")
+		code := http.StatusOK
+		if !health.Healthy {
+			code = http.StatusServiceUnavailable
+		}
+		respond.JSON(w, code, model.NewHealthStatus(health))
+	}
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type ImportService interface {
+	// PreviewImport validates the given import without creating any user or peer.
+	PreviewImport(ctx context.Context, req domain.ImportRequest) (*domain.ImportReport, error)
+	// Import creates the users or peers of all valid rows of the given import.
+	Import(ctx context.Context, req domain.ImportRequest) (*domain.ImportReport, error)
+}
+
+type ImportEndpoint struct {
+	cfg           *config.Config
+	importService ImportService
+	authenticator Authenticator
+	validator     Validator
+}
+
+func NewImportEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	importService ImportService,
+) ImportEndpoint {
+	return ImportEndpoint{
+		cfg:           cfg,
+		importService: importService,
+		authenticator: authenticator,
+		validator:     validator,
+	}
+}
+
+func (e ImportEndpoint) GetName() string {
+	return "ImportEndpoint"
+}
+
+func (e ImportEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/import")
+
+	userGroup := apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin))
+	userGroup.HandleFunc("POST /users/preview", e.handleUsersPreviewPost())
+	userGroup.HandleFunc("POST /users", e.handleUsersPost())
+
+	peerGroup := apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin))
+	peerGroup.HandleFunc("POST /peers/{iface}/preview", e.handlePeersPreviewPost())
+	peerGroup.HandleFunc("POST /peers/{iface}", e.handlePeersPost())
+}
+
+// handleUsersPreviewPost returns a gorm Handler function.
+//
+// @ID import_handleUsersPreviewPost
+// @Tags Import
+// @Summary Validate a CSV user import without creating any user.
+// @Param request body model.ImportRequest true "The CSV data and the column mapping"
+// @Produce json
+// @Success 200 {object} model.ImportReport
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /import/users/preview [post]
+func (e ImportEndpoint) handleUsersPreviewPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.handleImport(w, r, domain.ImportKindUsers, "", false)
+	}
+}
+
+// handleUsersPost returns a gorm Handler function.
+//
+// @ID import_handleUsersPost
+// @Tags Import
+// @Summary Create users from CSV data. Rows with errors are skipped and reported.
+// @Param request body model.ImportRequest true "The CSV data and the column mapping"
+// @Produce json
+// @Success 200 {object} model.ImportReport
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /import/users [post]
+func (e ImportEndpoint) handleUsersPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.handleImport(w, r, domain.ImportKindUsers, "", true)
+	}
+}
+
+// handlePeersPreviewPost returns a gorm Handler function.
+//
+// @ID import_handlePeersPreviewPost
+// @Tags Import
+// @Summary Validate a CSV peer import without creating any peer.
+// @Param iface path string true "The interface identifier (base64 encoded)"
+// @Param request body model.ImportRequest true "The CSV data and the column mapping"
+// @Produce json
+// @Success 200 {object} model.ImportReport
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /import/peers/{iface}/preview [post]
+func (e ImportEndpoint) handlePeersPreviewPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.handlePeerImport(w, r, false)
+	}
+}
+
+// handlePeersPost returns a gorm Handler function.
+//
+// @ID import_handlePeersPost
+// @Tags Import
+// @Summary Create peers from CSV data and optionally send their configuration mails.
+// @Description Rows with errors are skipped and reported. Peers without addresses get the next free addresses.
+// @Param iface path string true "The interface identifier (base64 encoded)"
+// @Param request body model.ImportRequest true "The CSV data and the column mapping"
+// @Produce json
+// @Success 200 {object} model.ImportReport
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /import/peers/{iface} [post]
+func (e ImportEndpoint) handlePeersPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.handlePeerImport(w, r, true)
+	}
+}
+
+func (e ImportEndpoint) handlePeerImport(w http.ResponseWriter, r *http.Request, execute bool) {
+	interfaceId := Base64UrlDecode(request.Path(r, "iface"))
+	if interfaceId == "" {
+		respond.JSON(w, http.StatusBadRequest,
+			model.Error{Code: http.StatusBadRequest, Message: "missing iface parameter"})
+		return
+	}
+
+	e.handleImport(w, r, domain.ImportKindPeers, domain.InterfaceIdentifier(interfaceId), execute)
+}
+
+func (e ImportEndpoint) handleImport(
+	w http.ResponseWriter,
+	r *http.Request,
+	kind domain.ImportKind,
+	interfaceId domain.InterfaceIdentifier,
+	execute bool,
+) {
+	var req model.ImportRequest
+	if err := request.BodyJson(r, &req); err != nil {
+		respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if err := e.validator.Struct(req); err != nil {
+		respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+
+	importReq := model.NewDomainImportRequest(kind, interfaceId, &req)
+
+	var report *domain.ImportReport
+	var err error
+	if execute {
+		report, err = e.importService.Import(r.Context(), importReq)
+	} else {
+		report, err = e.importService.PreviewImport(r.Context(), importReq)
+	}
+	if err != nil {
+		respondImportError(w, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, model.NewImportReport(report))
+}
+
+func respondImportError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
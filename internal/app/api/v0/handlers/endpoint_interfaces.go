@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -29,10 +31,29 @@ type InterfaceService interface {
 	GetAllInterfacesAndPeers(ctx context.Context) ([]domain.Interface, [][]domain.Peer, error)
 	// GetInterfaceConfig returns the interface configuration as string.
 	GetInterfaceConfig(ctx context.Context, id domain.InterfaceIdentifier) (io.Reader, error)
+	// ExportInterfacePeerConfigs prepares the export of all peer configurations of the interface as zip archive.
+	ExportInterfacePeerConfigs(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Export, error)
 	// PersistInterfaceConfig persists the interface configuration to a file.
 	PersistInterfaceConfig(ctx context.Context, id domain.InterfaceIdentifier) error
 	// ApplyPeerDefaults applies the peer defaults to all peers of the given interface.
 	ApplyPeerDefaults(ctx context.Context, in *domain.Interface) error
+	// SuggestInterfaceMtu probes the paths to the peers of the given interface and returns the smallest path MTU.
+	SuggestInterfaceMtu(ctx context.Context, id domain.InterfaceIdentifier) (*domain.PathMtu, error)
+	// SendPeerEmails sends the current configuration to the users of all peers of the given interface.
+	SendPeerEmails(ctx context.Context, id domain.InterfaceIdentifier, linkOnly bool) error
+	// PrepareRenumbering calculates the renumbering of a peer network to a larger network without applying it.
+	PrepareRenumbering(
+		ctx context.Context,
+		id domain.InterfaceIdentifier,
+		from, to domain.Cidr,
+	) (*domain.InterfaceRenumbering, error)
+	// RenumberInterface moves a peer network, including all interface and peer addresses, to a larger network.
+	RenumberInterface(
+		ctx context.Context,
+		id domain.InterfaceIdentifier,
+		from, to domain.Cidr,
+		sendMails bool,
+	) (*domain.InterfaceRenumbering, error)
 }
 
 type InterfaceEndpoint struct {
@@ -62,19 +83,27 @@ func (e InterfaceEndpoint) GetName() string {
 
 func (e InterfaceEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup := g.Mount("/interface")
-	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
-
-	apiGroup.HandleFunc("GET /prepare", e.handlePrepareGet())
-	apiGroup.HandleFunc("GET /all", e.handleAllGet())
-	apiGroup.HandleFunc("GET /get/{id}", e.handleSingleGet())
-	apiGroup.HandleFunc("PUT /{id}", e.handleUpdatePut())
-	apiGroup.HandleFunc("DELETE /{id}", e.handleDelete())
-	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
-	apiGroup.HandleFunc("GET /config/{id}", e.handleConfigGet())
-	apiGroup.HandleFunc("POST /{id}/save-config", e.handleSaveConfigPost())
-	apiGroup.HandleFunc("POST /{id}/apply-peer-defaults", e.handleApplyPeerDefaultsPost())
-
-	apiGroup.HandleFunc("GET /peers/{id}", e.handlePeersGet())
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	// interface admins can view the interfaces they administrate, the services validate the interface
+	interfaceAdminGroup := apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin))
+	interfaceAdminGroup.HandleFunc("GET /all", e.handleAllGet())
+	interfaceAdminGroup.HandleFunc("GET /get/{id}", e.handleSingleGet())
+	interfaceAdminGroup.HandleFunc("GET /peers/{id}", e.handlePeersGet())
+
+	adminGroup := apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin))
+	adminGroup.HandleFunc("GET /prepare", e.handlePrepareGet())
+	adminGroup.HandleFunc("PUT /{id}", e.handleUpdatePut())
+	adminGroup.HandleFunc("DELETE /{id}", e.handleDelete())
+	adminGroup.HandleFunc("POST /new", e.handleCreatePost())
+	adminGroup.HandleFunc("GET /config/{id}", e.handleConfigGet())
+	adminGroup.HandleFunc("GET /{id}/peer-configs", e.handlePeerConfigsGet())
+	adminGroup.HandleFunc("POST /{id}/save-config", e.handleSaveConfigPost())
+	adminGroup.HandleFunc("POST /{id}/apply-peer-defaults", e.handleApplyPeerDefaultsPost())
+	adminGroup.HandleFunc("GET /{id}/mtu-suggestion", e.handleMtuSuggestionGet())
+	adminGroup.HandleFunc("POST /{id}/mail-peers", e.handleMailPeersPost())
+	adminGroup.HandleFunc("GET /{id}/renumbering", e.handleRenumberingGet())
+	adminGroup.HandleFunc("POST /{id}/renumbering", e.handleRenumberingPost())
 }
 
 // handlePrepareGet returns a gorm Handler function.
@@ -195,6 +224,51 @@ func (e InterfaceEndpoint) handleConfigGet() http.HandlerFunc {
 	}
 }
 
+// handlePeerConfigsGet returns a gorm Handler function.
+//
+// @ID interfaces_handlePeerConfigsGet
+// @Tags Interface
+// @Summary Download the configuration files of all peers of the interface as zip archive.
+// @Description The archive is streamed, the configuration files are rendered while it is sent.
+// @Produce application/zip
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Success 200 {file} binary
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error "The export requires the approval of another administrator"
+// @Failure 404 {object} model.Error
+// @Failure 428 {object} model.Error "A re-authentication is required"
+// @Failure 500 {object} model.Error
+// @Router /interface/{id}/peer-configs [get]
+func (e InterfaceEndpoint) handlePeerConfigsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		export, err := e.interfaceService.ExportInterfacePeerConfigs(withClientInfo(r.Context(), r),
+			domain.InterfaceIdentifier(id))
+		if err != nil {
+			code := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, domain.ErrStepUpRequired):
+				code = http.StatusPreconditionRequired
+			case errors.Is(err, domain.ErrApprovalRequired), errors.Is(err, domain.ErrNoPermission):
+				code = http.StatusForbidden
+			case errors.Is(err, domain.ErrNotFound):
+				code = http.StatusNotFound
+			}
+			respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+			return
+		}
+
+		writeExport(w, export)
+	}
+}
+
 // handleUpdatePut returns a gorm Handler function.
 //
 // @ID interfaces_handleUpdatePut
@@ -234,9 +308,7 @@ func (e InterfaceEndpoint) handleUpdatePut() http.HandlerFunc {
 
 		updatedInterface, peers, err := e.interfaceService.UpdateInterface(r.Context(), model.NewDomainInterface(&in))
 		if err != nil {
-			respond.JSON(w, http.StatusInternalServerError, model.Error{
-				Code: http.StatusInternalServerError, Message: err.Error(),
-			})
+			respondInterfaceSaveError(w, err)
 			return
 		}
 
@@ -269,9 +341,7 @@ func (e InterfaceEndpoint) handleCreatePost() http.HandlerFunc {
 
 		newInterface, err := e.interfaceService.CreateInterface(r.Context(), model.NewDomainInterface(&in))
 		if err != nil {
-			respond.JSON(w, http.StatusInternalServerError, model.Error{
-				Code: http.StatusInternalServerError, Message: err.Error(),
-			})
+			respondInterfaceSaveError(w, err)
 			return
 		}
 
@@ -421,3 +491,183 @@ func (e InterfaceEndpoint) handleApplyPeerDefaultsPost() http.HandlerFunc {
 		respond.Status(w, http.StatusNoContent)
 	}
 }
+
+// handleMailPeersPost returns a gorm Handler function.
+//
+// @ID interfaces_handleMailPeersPost
+// @Tags Interface
+// @Summary Send the current configuration to the users of all peers of the interface.
+// @Description This is used to distribute changed peer configurations, for example after a listen port change.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Param request body model.InterfaceMailRequest true "The mail request data"
+// @Success 204 "No content if mail sending was successful"
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /interface/{id}/mail-peers [post]
+func (e InterfaceEndpoint) handleMailPeersPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		var req model.InterfaceMailRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		err := e.interfaceService.SendPeerEmails(r.Context(), domain.InterfaceIdentifier(id), req.LinkOnly)
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+// handleMtuSuggestionGet returns a gorm Handler function.
+//
+// @ID interfaces_handleMtuSuggestionGet
+// @Tags Interface
+// @Summary Suggest an interface MTU based on the paths to the peer endpoints.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Success 200 {object} model.MtuSuggestion
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /interface/{id}/mtu-suggestion [get]
+func (e InterfaceEndpoint) handleMtuSuggestionGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest, model.Error{
+				Code: http.StatusInternalServerError, Message: "missing id parameter",
+			})
+			return
+		}
+
+		pathMtu, err := e.interfaceService.SuggestInterfaceMtu(r.Context(), domain.InterfaceIdentifier(id))
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError, model.Error{
+				Code: http.StatusInternalServerError, Message: err.Error(),
+			})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewMtuSuggestion(pathMtu))
+	}
+}
+
+// handleRenumberingGet returns a gorm Handler function.
+//
+// @ID interfaces_handleRenumberingGet
+// @Tags Interface
+// @Summary Preview the renumbering of a peer network to a larger network.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Param from query string true "The peer network that should be renumbered"
+// @Param to query string false "The new network, a larger network is suggested if empty"
+// @Success 200 {object} model.InterfaceRenumbering
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /interface/{id}/renumbering [get]
+func (e InterfaceEndpoint) handleRenumberingGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		from, to, err := parseRenumberingNetworks(request.Query(r, "from"), request.Query(r, "to"))
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		renumbering, err := e.interfaceService.PrepareRenumbering(r.Context(), domain.InterfaceIdentifier(id), from, to)
+		if err != nil {
+			respondInterfaceSaveError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewInterfaceRenumbering(renumbering))
+	}
+}
+
+// handleRenumberingPost returns a gorm Handler function.
+//
+// @ID interfaces_handleRenumberingPost
+// @Tags Interface
+// @Summary Renumber a peer network, including all interface and peer addresses, to a larger network.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Param request body model.InterfaceRenumberingRequest true "The renumbering request"
+// @Success 200 {object} model.InterfaceRenumbering
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /interface/{id}/renumbering [post]
+func (e InterfaceEndpoint) handleRenumberingPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		var req model.InterfaceRenumberingRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		from, to, err := parseRenumberingNetworks(req.From, req.To)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		renumbering, err := e.interfaceService.RenumberInterface(r.Context(), domain.InterfaceIdentifier(id), from, to,
+			req.SendMails)
+		if err != nil {
+			respondInterfaceSaveError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewInterfaceRenumbering(renumbering))
+	}
+}
+
+// parseRenumberingNetworks parses the old and the new network of a renumbering, the new network is optional.
+func parseRenumberingNetworks(fromStr, toStr string) (from, to domain.Cidr, err error) {
+	from, err = domain.CidrFromString(fromStr)
+	if err != nil {
+		return from, to, fmt.Errorf("invalid network %q: %w", fromStr, err)
+	}
+	if toStr == "" {
+		return from, to, nil
+	}
+	to, err = domain.CidrFromString(toStr)
+	if err != nil {
+		return from, to, fmt.Errorf("invalid network %q: %w", toStr, err)
+	}
+
+	return from, to, nil
+}
+
+func respondInterfaceSaveError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	if errors.Is(err, domain.ErrInvalidData) {
+		code = http.StatusBadRequest
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
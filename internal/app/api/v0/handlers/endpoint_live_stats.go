@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-pkgz/routegroup"
+	"golang.org/x/net/websocket"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type LiveStatsService interface {
+	// SamplePeer starts the live sampling of the given peer, the samples are sent to the returned channel.
+	SamplePeer(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		duration time.Duration,
+	) (<-chan domain.PeerLiveSample, error)
+}
+
+type LiveStatsEndpoint struct {
+	cfg              *config.Config
+	liveStatsService LiveStatsService
+	authenticator    Authenticator
+}
+
+func NewLiveStatsEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	liveStatsService LiveStatsService,
+) LiveStatsEndpoint {
+	return LiveStatsEndpoint{
+		cfg:              cfg,
+		liveStatsService: liveStatsService,
+		authenticator:    authenticator,
+	}
+}
+
+func (e LiveStatsEndpoint) GetName() string {
+	return "LiveStatsEndpoint"
+}
+
+func (e LiveStatsEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/live-stats")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeInterfaceAdmin))
+
+	apiGroup.HandleFunc("GET /peer/{id}", e.handlePeerLiveStatsGet())
+}
+
+// handlePeerLiveStatsGet returns a gorm Handler function.
+//
+// @ID liveStats_handlePeerLiveStatsGet
+// @Tags Live Statistics
+// @Summary Stream per-second throughput samples of the given peer over a WebSocket connection.
+// @Description Each sample is sent as model.PeerLiveSample JSON message. The connection is closed once the duration
+// @Description has passed or the peer can no longer be read from the WireGuard interface.
+// @Param id path string true "The peer identifier"
+// @Param duration query int false "The sampling duration in seconds, defaults to the configured maximum"
+// @Success 101 {object} model.PeerLiveSample
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /live-stats/peer/{id} [get]
+func (e LiveStatsEndpoint) handlePeerLiveStatsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		var duration time.Duration
+		if durationStr := request.Query(r, "duration"); durationStr != "" {
+			seconds, err := strconv.Atoi(durationStr)
+			if err != nil || seconds <= 0 {
+				respond.JSON(w, http.StatusBadRequest,
+					model.Error{Code: http.StatusBadRequest, Message: "invalid duration"})
+				return
+			}
+			duration = time.Duration(seconds) * time.Second
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		samples, err := e.liveStatsService.SamplePeer(ctx, domain.PeerIdentifier(id), duration)
+		if err != nil {
+			respondLiveStatsError(w, err)
+			return
+		}
+
+		server := websocket.Server{
+			Handshake: e.checkOrigin,
+			Handler: func(ws *websocket.Conn) {
+				go func() {
+					_, _ = io.Copy(io.Discard, ws) // the client does not send data, a read error means it is gone
+					cancel()
+				}()
+
+				for sample := range samples {
+					if err := websocket.JSON.Send(ws, model.NewPeerLiveSample(sample)); err != nil {
+						slog.Debug("failed to send live sample", "peer", id, "error", err)
+						cancel()
+					}
+				}
+			},
+		}
+		server.ServeHTTP(hijackableResponseWriter{w}, r)
+		cancel()
+		for range samples {
+			// wait until the sampling stopped, for example, if the handshake failed
+		}
+	}
+}
+
+// checkOrigin only accepts WebSocket connections from the portal itself, browsers send the session cookie with
+// cross-site WebSocket requests. Clients that send no origin, like command line tools, are accepted.
+func (e LiveStatsEndpoint) checkOrigin(cfg *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(cfg, r)
+	if err != nil {
+		return err
+	}
+	if origin == nil || origin.Host == r.Host {
+		return nil
+	}
+	if externalUrl, err := url.Parse(e.cfg.Web.ExternalUrl); err == nil && origin.Host == externalUrl.Host {
+		return nil
+	}
+
+	return fmt.Errorf("origin %s is not allowed", origin)
+}
+
+// hijackableResponseWriter makes the hijacker of wrapped response writers available to the WebSocket server.
+type hijackableResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (h hijackableResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(h.ResponseWriter).Hijack()
+}
+
+func respondLiveStatsError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type MailService interface {
+	// GetMailPreviews renders the peer configuration mail templates with sample data.
+	GetMailPreviews(ctx context.Context, orgId domain.OrganizationIdentifier) ([]domain.MailPreview, error)
+	// GetMailTemplates returns the built-in mail templates and their variables.
+	GetMailTemplates(ctx context.Context) ([]domain.MailTemplate, error)
+	// LintMailTemplate validates the content of a custom mail template.
+	LintMailTemplate(
+		ctx context.Context,
+		name string,
+		format domain.MailTemplateFormat,
+		content string,
+	) ([]domain.MailTemplateIssue, error)
+	// GetMailLog returns the mail log entries that match the given filter, newest first.
+	GetMailLog(ctx context.Context, filter domain.MailLogFilter) ([]domain.MailLogEntry, error)
+	// TrackMailOpened records that the tracking pixel of the mail with the given token was loaded.
+	TrackMailOpened(ctx context.Context, token string) error
+	// TrackMailClicked records that the tracked link of the mail was used and returns the redirect URL.
+	TrackMailClicked(ctx context.Context, token string) (string, error)
+}
+
+// trackingPixel is a transparent 1x1 GIF image.
+var trackingPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+type MailEndpoint struct {
+	cfg           *config.Config
+	authenticator Authenticator
+	validator     Validator
+	mailService   MailService
+}
+
+func NewMailEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	mailService MailService,
+) MailEndpoint {
+	return MailEndpoint{
+		cfg:           cfg,
+		authenticator: authenticator,
+		validator:     validator,
+		mailService:   mailService,
+	}
+}
+
+func (e MailEndpoint) GetName() string {
+	return "MailEndpoint"
+}
+
+func (e MailEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	// the tracking endpoints are loaded by mail clients, the token identifies the mail
+	g.HandleFunc("GET /mail-tracking/{token}/open", e.handleTrackingOpenGet())
+	g.HandleFunc("GET /mail-tracking/{token}/click", e.handleTrackingClickGet())
+
+	apiGroup := g.Mount("/mail")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /preview", e.handlePreviewGet())
+	apiGroup.HandleFunc("GET /templates", e.handleTemplatesGet())
+	apiGroup.HandleFunc("POST /templates/lint", e.handleTemplateLintPost())
+	apiGroup.HandleFunc("GET /log", e.handleLogGet())
+	apiGroup.HandleFunc("GET /log/by-user/{id}", e.handleUserLogGet())
+	apiGroup.HandleFunc("GET /log/by-peer/{id}", e.handlePeerLogGet())
+}
+
+// handlePreviewGet returns a gorm Handler function.
+//
+// @ID mail_handlePreviewGet
+// @Tags Mail
+// @Summary Render the peer configuration mail templates with sample data.
+// @Description Returns the text and html variant of the link and the attachment mail. Embedded images like the
+// @Description QR code are not part of the preview.
+// @Param organization query string false "The organization whose custom templates should be used."
+// @Produce json
+// @Success 200 {object} []model.MailPreview
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /mail/preview [get]
+func (e MailEndpoint) handlePreviewGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		previews, err := e.mailService.GetMailPreviews(r.Context(),
+			domain.OrganizationIdentifier(request.Query(r, "organization")))
+		if err != nil {
+			respondMailError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewMailPreviews(previews))
+	}
+}
+
+// handleTemplatesGet returns a gorm Handler function.
+//
+// @ID mail_handleTemplatesGet
+// @Tags Mail
+// @Summary Get the built-in mail templates and the variables that are available in them.
+// @Produce json
+// @Success 200 {object} []model.MailTemplate
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /mail/templates [get]
+func (e MailEndpoint) handleTemplatesGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		templates, err := e.mailService.GetMailTemplates(r.Context())
+		if err != nil {
+			respondMailError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewMailTemplates(templates))
+	}
+}
+
+// handleTemplateLintPost returns a gorm Handler function.
+//
+// @ID mail_handleTemplateLintPost
+// @Tags Mail
+// @Summary Validate a custom mail template.
+// @Description Checks the syntax of the template and whether all used variables and fields exist. Problems are
+// @Description returned as issues, an invalid template does not result in an error response.
+// @Param request body model.MailTemplateLintRequest true "The template to validate"
+// @Produce json
+// @Success 200 {object} model.MailTemplateLintResult
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /mail/templates/lint [post]
+func (e MailEndpoint) handleTemplateLintPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req model.MailTemplateLintRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		issues, err := e.mailService.LintMailTemplate(r.Context(), req.Template,
+			domain.MailTemplateFormat(req.Format), req.Content)
+		if err != nil {
+			respondMailError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewMailTemplateLintResult(issues))
+	}
+}
+
+// handleLogGet returns a gorm Handler function.
+//
+// @ID mail_handleLogGet
+// @Tags Mail
+// @Summary Get all mails that were sent by WireGuard Portal, the most recent mails first.
+// @Description The full mail log is only available to global administrators.
+// @Param search query string false "Only return mails whose recipients, subject or template contain this value."
+// @Produce json
+// @Success 200 {object} []model.MailLogEntry
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /mail/log [get]
+func (e MailEndpoint) handleLogGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.respondMailLog(w, r, domain.MailLogFilter{Search: request.Query(r, "search")})
+	}
+}
+
+// handleUserLogGet returns a gorm Handler function.
+//
+// @ID mail_handleUserLogGet
+// @Tags Mail
+// @Summary Get all mails that were sent to the given user, the most recent mails first.
+// @Param id path string true "The user identifier (base64 encoded)"
+// @Param search query string false "Only return mails whose recipients, subject or template contain this value."
+// @Produce json
+// @Success 200 {object} []model.MailLogEntry
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /mail/log/by-user/{id} [get]
+func (e MailEndpoint) handleUserLogGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: "missing user id"})
+			return
+		}
+
+		e.respondMailLog(w, r, domain.MailLogFilter{
+			UserId: domain.UserIdentifier(id),
+			Search: request.Query(r, "search"),
+		})
+	}
+}
+
+// handlePeerLogGet returns a gorm Handler function.
+//
+// @ID mail_handlePeerLogGet
+// @Tags Mail
+// @Summary Get all mails that were sent about the given peer, the most recent mails first.
+// @Param id path string true "The peer identifier (base64 encoded)"
+// @Param search query string false "Only return mails whose recipients, subject or template contain this value."
+// @Produce json
+// @Success 200 {object} []model.MailLogEntry
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /mail/log/by-peer/{id} [get]
+func (e MailEndpoint) handlePeerLogGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		e.respondMailLog(w, r, domain.MailLogFilter{
+			PeerId: domain.PeerIdentifier(id),
+			Search: request.Query(r, "search"),
+		})
+	}
+}
+
+func (e MailEndpoint) respondMailLog(w http.ResponseWriter, r *http.Request, filter domain.MailLogFilter) {
+	entries, err := e.mailService.GetMailLog(r.Context(), filter)
+	if err != nil {
+		respondMailError(w, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, model.NewMailLogEntries(entries))
+}
+
+// handleTrackingOpenGet returns a gorm Handler function.
+//
+// @ID mail_handleTrackingOpenGet
+// @Tags Mail
+// @Summary Record that a tracked configuration mail was opened.
+// @Description No authentication is required. A transparent 1x1 GIF image is returned, even if the token is unknown.
+// @Param token path string true "The tracking token of the mail"
+// @Produce image/gif
+// @Success 200 {file} binary
+// @Router /mail-tracking/{token}/open [get]
+func (e MailEndpoint) handleTrackingOpenGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := e.mailService.TrackMailOpened(r.Context(), request.Path(r, "token")); err != nil {
+			slog.Debug("failed to track mail open", "error", err)
+		}
+
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		respond.Data(w, http.StatusOK, "image/gif", trackingPixel)
+	}
+}
+
+// handleTrackingClickGet returns a gorm Handler function.
+//
+// @ID mail_handleTrackingClickGet
+// @Tags Mail
+// @Summary Record that the link of a tracked configuration mail was used.
+// @Description No authentication is required. The client is redirected to WireGuard Portal, even if the token is
+// @Description unknown.
+// @Param token path string true "The tracking token of the mail"
+// @Success 302
+// @Router /mail-tracking/{token}/click [get]
+func (e MailEndpoint) handleTrackingClickGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		redirectUrl, err := e.mailService.TrackMailClicked(r.Context(), request.Path(r, "token"))
+		if err != nil {
+			slog.Debug("failed to track mail click", "error", err)
+		}
+
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		respond.Redirect(w, r, http.StatusFound, redirectUrl)
+	}
+}
+
+func respondMailError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
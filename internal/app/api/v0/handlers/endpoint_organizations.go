@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type OrganizationService interface {
+	// GetAllOrganizations returns all organizations.
+	GetAllOrganizations(ctx context.Context) ([]domain.Organization, error)
+	// GetOrganization returns the organization with the given id.
+	GetOrganization(ctx context.Context, id domain.OrganizationIdentifier) (*domain.Organization, error)
+	// CreateOrganization creates a new organization.
+	CreateOrganization(ctx context.Context, organization *domain.Organization) (*domain.Organization, error)
+	// UpdateOrganization updates the organization.
+	UpdateOrganization(ctx context.Context, organization *domain.Organization) (*domain.Organization, error)
+	// DeleteOrganization deletes the organization with the given id.
+	DeleteOrganization(ctx context.Context, id domain.OrganizationIdentifier) error
+}
+
+type OrganizationEndpoint struct {
+	cfg                 *config.Config
+	organizationService OrganizationService
+	authenticator       Authenticator
+	validator           Validator
+}
+
+func NewOrganizationEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	organizationService OrganizationService,
+) OrganizationEndpoint {
+	return OrganizationEndpoint{
+		cfg:                 cfg,
+		organizationService: organizationService,
+		authenticator:       authenticator,
+		validator:           validator,
+	}
+}
+
+func (e OrganizationEndpoint) GetName() string {
+	return "OrganizationEndpoint"
+}
+
+func (e OrganizationEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/organization")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.HandleFunc("GET /by-id/{id}", e.handleSingleGet())
+	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
+	apiGroup.HandleFunc("PUT /by-id/{id}", e.handleUpdatePut())
+	apiGroup.HandleFunc("DELETE /by-id/{id}", e.handleDelete())
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID organizations_handleAllGet
+// @Tags Organizations
+// @Summary Get all organizations. Only available for global administrators.
+// @Produce json
+// @Success 200 {object} []model.Organization
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /organization/all [get]
+func (e OrganizationEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		organizations, err := e.organizationService.GetAllOrganizations(r.Context())
+		if err != nil {
+			respondOrganizationError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewOrganizations(organizations))
+	}
+}
+
+// handleSingleGet returns a gorm Handler function.
+//
+// @ID organizations_handleSingleGet
+// @Tags Organizations
+// @Summary Get a single organization.
+// @Param id path string true "The organization identifier"
+// @Produce json
+// @Success 200 {object} model.Organization
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /organization/by-id/{id} [get]
+func (e OrganizationEndpoint) handleSingleGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing organization id"})
+			return
+		}
+
+		organization, err := e.organizationService.GetOrganization(r.Context(), domain.OrganizationIdentifier(id))
+		if err != nil {
+			respondOrganizationError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewOrganization(organization))
+	}
+}
+
+// handleCreatePost returns a gorm Handler function.
+//
+// @ID organizations_handleCreatePost
+// @Tags Organizations
+// @Summary Create a new organization. Only available for global administrators.
+// @Produce json
+// @Param request body model.Organization true "The organization data"
+// @Success 200 {object} model.Organization
+// @Failure 400 {object} model.Error
+// @Failure 409 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /organization/new [post]
+func (e OrganizationEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var organization model.Organization
+		if err := request.BodyJson(r, &organization); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(organization); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		newOrganization, err := e.organizationService.CreateOrganization(r.Context(),
+			model.NewDomainOrganization(&organization))
+		if err != nil {
+			respondOrganizationError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewOrganization(newOrganization))
+	}
+}
+
+// handleUpdatePut returns a gorm Handler function.
+//
+// @ID organizations_handleUpdatePut
+// @Tags Organizations
+// @Summary Update the organization. Only available for global administrators.
+// @Produce json
+// @Param id path string true "The organization identifier"
+// @Param request body model.Organization true "The organization data"
+// @Success 200 {object} model.Organization
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /organization/by-id/{id} [put]
+func (e OrganizationEndpoint) handleUpdatePut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing organization id"})
+			return
+		}
+
+		var organization model.Organization
+		if err := request.BodyJson(r, &organization); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(organization); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		if id != organization.Identifier {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "organization id mismatch"})
+			return
+		}
+
+		updatedOrganization, err := e.organizationService.UpdateOrganization(r.Context(),
+			model.NewDomainOrganization(&organization))
+		if err != nil {
+			respondOrganizationError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewOrganization(updatedOrganization))
+	}
+}
+
+// handleDelete returns a gorm Handler function.
+//
+// @ID organizations_handleDelete
+// @Tags Organizations
+// @Summary Delete the organization with the given id. Only available for global administrators.
+// @Description Organizations that still own interfaces or users cannot be deleted.
+// @Produce json
+// @Param id path string true "The organization identifier"
+// @Success 204 "No content if deletion was successful"
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /organization/by-id/{id} [delete]
+func (e OrganizationEndpoint) handleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing organization id"})
+			return
+		}
+
+		err := e.organizationService.DeleteOrganization(r.Context(), domain.OrganizationIdentifier(id))
+		if err != nil {
+			respondOrganizationError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+func respondOrganizationError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrDuplicateEntry):
+		code = http.StatusConflict
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type PacketCaptureService interface {
+	// CapturePeer captures the traffic of the given peer and streams it in pcap format to the writer.
+	CapturePeer(ctx context.Context, id domain.PeerIdentifier, duration time.Duration, w io.Writer) error
+}
+
+type PacketCaptureEndpoint struct {
+	packetCaptureService PacketCaptureService
+	authenticator        Authenticator
+}
+
+func NewPacketCaptureEndpoint(
+	authenticator Authenticator,
+	packetCaptureService PacketCaptureService,
+) PacketCaptureEndpoint {
+	return PacketCaptureEndpoint{
+		packetCaptureService: packetCaptureService,
+		authenticator:        authenticator,
+	}
+}
+
+func (e PacketCaptureEndpoint) GetName() string {
+	return "PacketCaptureEndpoint"
+}
+
+func (e PacketCaptureEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/packet-capture")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /peer/{id}", e.handlePeerCaptureGet())
+}
+
+// handlePeerCaptureGet returns a gorm Handler function.
+//
+// @ID packetCapture_handlePeerCaptureGet
+// @Tags Packet Capture
+// @Summary Capture the traffic of the given peer as pcap file.
+// @Description The capture is streamed until the duration has passed, the size limit is reached or the client
+// @Description disconnects. Each capture is recorded in the audit log.
+// @Param id path string true "The peer identifier"
+// @Param duration query int false "The capture duration in seconds, defaults to the configured maximum"
+// @Produce application/vnd.tcpdump.pcap
+// @Success 200 {file} binary
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 409 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /packet-capture/peer/{id} [get]
+func (e PacketCaptureEndpoint) handlePeerCaptureGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		var duration time.Duration
+		if durationStr := request.Query(r, "duration"); durationStr != "" {
+			seconds, err := strconv.Atoi(durationStr)
+			if err != nil || seconds <= 0 {
+				respond.JSON(w, http.StatusBadRequest,
+					model.Error{Code: http.StatusBadRequest, Message: "invalid duration"})
+				return
+			}
+			duration = time.Duration(seconds) * time.Second
+		}
+
+		out := &pcapResponseWriter{
+			w:        w,
+			filename: domain.PacketCaptureFileName(domain.PeerIdentifier(id), time.Now()),
+		}
+		err := e.packetCaptureService.CapturePeer(r.Context(), domain.PeerIdentifier(id), duration, out)
+		switch {
+		case err != nil && !out.started:
+			respondPacketCaptureError(w, err)
+		case err != nil:
+			slog.Error("failed to stream packet capture", "peer", id, "error", err)
+		case !out.started:
+			respond.Status(w, http.StatusNoContent)
+		}
+	}
+}
+
+// pcapResponseWriter sends the response headers with the first write, so that errors that occur before the capture
+// started can still be reported as JSON. Each write is flushed to the client immediately.
+type pcapResponseWriter struct {
+	w        http.ResponseWriter
+	filename string
+	started  bool
+}
+
+func (p *pcapResponseWriter) Write(data []byte) (int, error) {
+	if !p.started {
+		p.started = true
+		p.w.Header().Set("Content-Disposition", "attachment; filename="+p.filename)
+		p.w.Header().Set("Content-Type", domain.PacketCaptureContentType)
+		p.w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := p.w.Write(data)
+	_ = http.NewResponseController(p.w).Flush() // the middlewares wrap the response writer
+
+	return n, err
+}
+
+func respondPacketCaptureError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	case errors.Is(err, domain.ErrDuplicateEntry):
+		code = http.StatusConflict
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type PeerTransferService interface {
+	// GetTransfers returns all transfers the current user is part of or administrates.
+	GetTransfers(ctx context.Context) ([]domain.PeerTransfer, error)
+	// RequestTransfer requests the transfer of the given peer to the target user.
+	RequestTransfer(
+		ctx context.Context,
+		peerId domain.PeerIdentifier,
+		toUser domain.UserIdentifier,
+		message string,
+	) (*domain.PeerTransfer, error)
+	// AcceptTransfer accepts the given transfer as target user.
+	AcceptTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (*domain.PeerTransfer, error)
+	// ApproveTransfer grants the admin approval for the given transfer.
+	ApproveTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (*domain.PeerTransfer, error)
+	// RejectTransfer rejects the given transfer.
+	RejectTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (*domain.PeerTransfer, error)
+	// CancelTransfer withdraws the given transfer.
+	CancelTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (*domain.PeerTransfer, error)
+}
+
+type PeerTransferEndpoint struct {
+	cfg                 *config.Config
+	peerTransferService PeerTransferService
+	authenticator       Authenticator
+	validator           Validator
+}
+
+func NewPeerTransferEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	peerTransferService PeerTransferService,
+) PeerTransferEndpoint {
+	return PeerTransferEndpoint{
+		cfg:                 cfg,
+		peerTransferService: peerTransferService,
+		authenticator:       authenticator,
+		validator:           validator,
+	}
+}
+
+func (e PeerTransferEndpoint) GetName() string {
+	return "PeerTransferEndpoint"
+}
+
+func (e PeerTransferEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/peer-transfer")
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
+	apiGroup.HandleFunc("POST /by-id/{id}/accept", e.handleActionPost(e.peerTransferService.AcceptTransfer))
+	apiGroup.HandleFunc("POST /by-id/{id}/approve", e.handleActionPost(e.peerTransferService.ApproveTransfer))
+	apiGroup.HandleFunc("POST /by-id/{id}/reject", e.handleActionPost(e.peerTransferService.RejectTransfer))
+	apiGroup.HandleFunc("POST /by-id/{id}/cancel", e.handleActionPost(e.peerTransferService.CancelTransfer))
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID peerTransfers_handleAllGet
+// @Tags Peer Transfers
+// @Summary Get all peer transfers of the current user. Admins receive the transfers of their interfaces as well.
+// @Produce json
+// @Success 200 {object} []model.PeerTransfer
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer-transfer/all [get]
+func (e PeerTransferEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		transfers, err := e.peerTransferService.GetTransfers(r.Context())
+		if err != nil {
+			respondPeerTransferError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeerTransfers(transfers))
+	}
+}
+
+// handleCreatePost returns a gorm Handler function.
+//
+// @ID peerTransfers_handleCreatePost
+// @Tags Peer Transfers
+// @Summary Request the transfer of a peer to another user.
+// @Description The target user is notified by mail and has to accept the transfer.
+// @Produce json
+// @Param request body model.PeerTransferRequest true "The transfer request"
+// @Success 200 {object} model.PeerTransfer
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 409 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer-transfer/new [post]
+func (e PeerTransferEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req model.PeerTransferRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		transfer, err := e.peerTransferService.RequestTransfer(r.Context(), domain.PeerIdentifier(req.PeerId),
+			domain.UserIdentifier(req.ToUser), req.Message)
+		if err != nil {
+			respondPeerTransferError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeerTransfer(transfer))
+	}
+}
+
+// handleActionPost returns a gorm Handler function.
+//
+// @ID peerTransfers_handleActionPost
+// @Tags Peer Transfers
+// @Summary Accept, approve, reject or cancel a peer transfer.
+// @Description Transfers are accepted by the target user and, if required, approved by an admin. Once the
+// @Description transfer is accepted, the keys of the peer are rotated and the peer is moved to the target user.
+// @Param id path string true "The transfer identifier"
+// @Param action path string true "The action" Enums(accept, approve, reject, cancel)
+// @Produce json
+// @Success 200 {object} model.PeerTransfer
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer-transfer/by-id/{id}/{action} [post]
+func (e PeerTransferEndpoint) handleActionPost(
+	action func(context.Context, domain.PeerTransferIdentifier) (*domain.PeerTransfer, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing transfer id"})
+			return
+		}
+
+		transfer, err := action(r.Context(), domain.PeerTransferIdentifier(id))
+		if err != nil {
+			respondPeerTransferError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeerTransfer(transfer))
+	}
+}
+
+func respondPeerTransferError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrDuplicateEntry):
+		code = http.StatusConflict
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/go-pkgz/routegroup"
 
@@ -31,16 +34,40 @@ type PeerService interface {
 	) ([]domain.Peer, error)
 	// UpdatePeer updates the peer with the given id.
 	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+	// RegeneratePeerKeys generates new keys for the peer with the given id and mails the updated configuration.
+	RegeneratePeerKeys(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
 	// DeletePeer deletes the peer with the given id.
 	DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
 	// GetPeerConfig returns the peer configuration for the given id.
 	GetPeerConfig(ctx context.Context, id domain.PeerIdentifier) (io.Reader, error)
-	// GetPeerConfigQrCode returns the peer configuration as qr code for the given id.
-	GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier) (io.Reader, error)
+	// GetPeerConfigForEndpoint returns the peer configuration variant that connects to the endpoint with the given
+	// index, see GetPeerEndpoints.
+	GetPeerConfigForEndpoint(ctx context.Context, id domain.PeerIdentifier, endpoint int) (io.Reader, error)
+	// GetPeerEndpoints returns the primary endpoint of the peer followed by all backup endpoints.
+	GetPeerEndpoints(ctx context.Context, id domain.PeerIdentifier) ([]string, error)
+	// ExportPeerConfigQrCode prepares the export of the peer configuration as qr code for the given id.
+	ExportPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier) (*domain.Export, error)
+	// GetPeerUciConfig returns the OpenWrt configuration of the peer as uci commands or /etc/config/network snippet.
+	GetPeerUciConfig(ctx context.Context, id domain.PeerIdentifier, style domain.UciConfigStyle) (io.Reader, error)
+	// GetPeerPrivateKey returns the private key of the peer with the given id.
+	GetPeerPrivateKey(ctx context.Context, id domain.PeerIdentifier) (string, error)
 	// SendPeerEmail sends the peer configuration via email.
 	SendPeerEmail(ctx context.Context, linkOnly bool, peers ...domain.PeerIdentifier) error
 	// GetPeerStats returns the peer stats for the given interface.
 	GetPeerStats(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.PeerStatus, error)
+	// GetCleanupReport returns all peers that are affected by the inactivity cleanup policy.
+	GetCleanupReport(ctx context.Context) ([]domain.PeerCleanupCandidate, error)
+	// GetPeerShapingStatus returns the bandwidth shaping counters for all limited peers of the given interface.
+	GetPeerShapingStatus(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.PeerShapingStatus, error)
+	// GetKeepaliveRecommendations returns the keepalive recommendations for all flapping peers of the given interface.
+	GetKeepaliveRecommendations(
+		ctx context.Context,
+		id domain.InterfaceIdentifier,
+	) ([]domain.KeepaliveRecommendation, error)
+	// ApplyKeepaliveRecommendation sets the recommended keepalive interval for the given peer.
+	ApplyKeepaliveRecommendation(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// SuggestPeerMtu probes the path to the endpoint of the given peer.
+	SuggestPeerMtu(ctx context.Context, id domain.PeerIdentifier) (*domain.PathMtu, error)
 }
 
 type PeerEndpoint struct {
@@ -72,15 +99,30 @@ func (e PeerEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup := g.Mount("/peer")
 	apiGroup.Use(e.authenticator.LoggedIn())
 
-	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /iface/{iface}/all", e.handleAllGet())
-	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /iface/{iface}/stats", e.handleStatsGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc("GET /iface/{iface}/all",
+		e.handleAllGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc("GET /iface/{iface}/stats",
+		e.handleStatsGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc("GET /iface/{iface}/shaping-stats",
+		e.handleShapingStatsGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc(
+		"GET /iface/{iface}/keepalive-recommendations", e.handleKeepaliveRecommendationsGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /cleanup-report", e.handleCleanupReportGet())
 	apiGroup.HandleFunc("GET /iface/{iface}/prepare", e.handlePrepareGet())
 	apiGroup.HandleFunc("POST /iface/{iface}/new", e.handleCreatePost())
-	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /iface/{iface}/multiplenew",
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc("POST /iface/{iface}/multiplenew",
 		e.handleCreateMultiplePost())
 	apiGroup.HandleFunc("GET /config-qr/{id}", e.handleQrCodeGet())
 	apiGroup.HandleFunc("POST /config-mail", e.handleEmailPost())
 	apiGroup.HandleFunc("GET /config/{id}", e.handleConfigGet())
+	apiGroup.HandleFunc("GET /config-endpoints/{id}", e.handleConfigEndpointsGet())
+	apiGroup.HandleFunc("GET /config-uci/{id}", e.handleUciConfigGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc("GET /{id}/mtu-suggestion",
+		e.handleMtuSuggestionGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc(
+		"POST /{id}/apply-keepalive-recommendation", e.handleApplyKeepaliveRecommendationPost())
+	apiGroup.HandleFunc("GET /{id}/private-key", e.handlePrivateKeyGet())
+	apiGroup.HandleFunc("POST /{id}/regenerate-keys", e.handleRegenerateKeysPost())
 	apiGroup.HandleFunc("GET /{id}", e.handleSingleGet())
 	apiGroup.HandleFunc("PUT /{id}", e.handleUpdatePut())
 	apiGroup.HandleFunc("DELETE /{id}", e.handleDelete())
@@ -148,6 +190,53 @@ func (e PeerEndpoint) handleSingleGet() http.HandlerFunc {
 	}
 }
 
+// handlePrivateKeyGet returns a gorm Handler function.
+//
+// @ID peers_handlePrivateKeyGet
+// @Tags Peer
+// @Summary Get the private key of the peer for the given identifier.
+// @Description If step-up authentication is enabled, the user must have re-authenticated recently.
+// @Produce json
+// @Param id path string true "The peer identifier"
+// @Success 200 {object} string
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error "The export requires the approval of another administrator"
+// @Failure 428 {object} model.Error "A re-authentication is required"
+// @Failure 500 {object} model.Error
+// @Router /peer/{id}/private-key [get]
+func (e PeerEndpoint) handlePrivateKeyGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerId := Base64UrlDecode(request.Path(r, "id"))
+		if peerId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing id parameter"})
+			return
+		}
+
+		privateKey, err := e.peerService.GetPeerPrivateKey(withClientInfo(r.Context(), r),
+			domain.PeerIdentifier(peerId))
+		if errors.Is(err, domain.ErrStepUpRequired) {
+			respond.JSON(w, http.StatusPreconditionRequired, model.Error{
+				Code: http.StatusPreconditionRequired, Message: err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrApprovalRequired) {
+			respond.JSON(w, http.StatusForbidden, model.Error{
+				Code: http.StatusForbidden, Message: err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, privateKey)
+	}
+}
+
 // handlePrepareGet returns a gorm Handler function.
 //
 // @ID peers_handlePrepareGet
@@ -189,6 +278,7 @@ func (e PeerEndpoint) handlePrepareGet() http.HandlerFunc {
 // @Param request body model.Peer true "The peer data"
 // @Success 200 {object} model.Peer
 // @Failure 400 {object} model.Error
+// @Failure 409 {object} model.Error "The peer quota is exceeded"
 // @Failure 500 {object} model.Error
 // @Router /peer/iface/{iface}/new [post]
 func (e PeerEndpoint) handleCreatePost() http.HandlerFunc {
@@ -218,8 +308,7 @@ func (e PeerEndpoint) handleCreatePost() http.HandlerFunc {
 
 		newPeer, err := e.peerService.CreatePeer(r.Context(), model.NewDomainPeer(&p))
 		if err != nil {
-			respond.JSON(w, http.StatusInternalServerError,
-				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			respondPeerCreationError(w, err)
 			return
 		}
 
@@ -237,6 +326,7 @@ func (e PeerEndpoint) handleCreatePost() http.HandlerFunc {
 // @Param request body model.MultiPeerRequest true "The peer creation request data"
 // @Success 200 {object} []model.Peer
 // @Failure 400 {object} model.Error
+// @Failure 409 {object} model.Error "The peer quota is exceeded"
 // @Failure 500 {object} model.Error
 // @Router /peer/iface/{iface}/multiplenew [post]
 func (e PeerEndpoint) handleCreateMultiplePost() http.HandlerFunc {
@@ -261,8 +351,7 @@ func (e PeerEndpoint) handleCreateMultiplePost() http.HandlerFunc {
 		newPeers, err := e.peerService.CreateMultiplePeers(r.Context(), domain.InterfaceIdentifier(interfaceId),
 			model.NewDomainPeerCreationRequest(&req))
 		if err != nil {
-			respond.JSON(w, http.StatusInternalServerError,
-				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			respondPeerCreationError(w, err)
 			return
 		}
 
@@ -309,6 +398,9 @@ func (e PeerEndpoint) handleUpdatePut() http.HandlerFunc {
 
 		updatedPeer, err := e.peerService.UpdatePeer(r.Context(), model.NewDomainPeer(&p))
 		if err != nil {
+			if respondPeerApplyError(w, http.StatusInternalServerError, err) {
+				return
+			}
 			respond.JSON(w, http.StatusInternalServerError,
 				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
 			return
@@ -318,6 +410,52 @@ func (e PeerEndpoint) handleUpdatePut() http.HandlerFunc {
 	}
 }
 
+// handleRegenerateKeysPost returns a gorm Handler function.
+//
+// @ID peers_handleRegenerateKeysPost
+// @Tags Peer
+// @Summary Generate new keys for the given peer.
+// @Description The peer is re-identified by its new public key. Users can only regenerate the keys of their own peers
+// @Description if the self-service key rotation is enabled, and only once per configured cooldown.
+// @Produce json
+// @Param id path string true "The peer identifier"
+// @Success 200 {object} model.Peer
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 409 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer/{id}/regenerate-keys [post]
+func (e PeerEndpoint) handleRegenerateKeysPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing id parameter"})
+			return
+		}
+
+		updatedPeer, err := e.peerService.RegeneratePeerKeys(r.Context(), domain.PeerIdentifier(id))
+		if err != nil {
+			code := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, domain.ErrInvalidData):
+				code = http.StatusBadRequest
+			case errors.Is(err, domain.ErrNoPermission):
+				code = http.StatusForbidden
+			case errors.Is(err, domain.ErrQuotaExceeded):
+				code = http.StatusConflict
+			}
+			if respondPeerApplyError(w, code, err) {
+				return
+			}
+			respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeer(updatedPeer))
+	}
+}
+
 // handleDelete returns a gorm Handler function.
 //
 // @ID peers_handleDelete
@@ -353,10 +491,15 @@ func (e PeerEndpoint) handleDelete() http.HandlerFunc {
 // @ID peers_handleConfigGet
 // @Tags Peer
 // @Summary Get peer configuration as string.
+// @Description The optional endpoint index selects the configuration variant for a backup endpoint, see
+// @Description /peer/config-endpoints/{id}. The default is the primary endpoint.
 // @Produce json
 // @Param id path string true "The peer identifier"
+// @Param endpoint query int false "The index of the endpoint"
 // @Success 200 {object} string
 // @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error "The export requires the approval of another administrator"
+// @Failure 428 {object} model.Error "A re-authentication is required"
 // @Failure 500 {object} model.Error
 // @Router /peer/config/{id} [get]
 func (e PeerEndpoint) handleConfigGet() http.HandlerFunc {
@@ -368,8 +511,134 @@ func (e PeerEndpoint) handleConfigGet() http.HandlerFunc {
 			})
 			return
 		}
+		endpoint, err := strconv.Atoi(request.QueryDefault(r, "endpoint", "0"))
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{
+				Code: http.StatusBadRequest, Message: "invalid endpoint parameter",
+			})
+			return
+		}
 
-		configTxt, err := e.peerService.GetPeerConfig(r.Context(), domain.PeerIdentifier(id))
+		configTxt, err := e.peerService.GetPeerConfigForEndpoint(withClientInfo(r.Context(), r),
+			domain.PeerIdentifier(id), endpoint)
+		if errors.Is(err, domain.ErrStepUpRequired) {
+			respond.JSON(w, http.StatusPreconditionRequired, model.Error{
+				Code: http.StatusPreconditionRequired, Message: err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrApprovalRequired) {
+			respond.JSON(w, http.StatusForbidden, model.Error{
+				Code: http.StatusForbidden, Message: err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidData) {
+			respond.JSON(w, http.StatusBadRequest, model.Error{
+				Code: http.StatusBadRequest, Message: err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError, model.Error{
+				Code: http.StatusInternalServerError, Message: err.Error(),
+			})
+			return
+		}
+
+		configTxtString, err := io.ReadAll(configTxt)
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError, model.Error{
+				Code: http.StatusInternalServerError, Message: err.Error(),
+			})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, string(configTxtString))
+	}
+}
+
+// handleConfigEndpointsGet returns a gorm Handler function.
+//
+// @ID peers_handleConfigEndpointsGet
+// @Tags Peer
+// @Summary Get the endpoints for which peer configuration variants are available.
+// @Description The first endpoint is the primary endpoint, all further endpoints are backup endpoints.
+// @Produce json
+// @Param id path string true "The peer identifier"
+// @Success 200 {object} []string
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer/config-endpoints/{id} [get]
+func (e PeerEndpoint) handleConfigEndpointsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest, model.Error{
+				Code: http.StatusInternalServerError, Message: "missing id parameter",
+			})
+			return
+		}
+
+		endpoints, err := e.peerService.GetPeerEndpoints(r.Context(), domain.PeerIdentifier(id))
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError, model.Error{
+				Code: http.StatusInternalServerError, Message: err.Error(),
+			})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, endpoints)
+	}
+}
+
+// handleUciConfigGet returns a gorm Handler function.
+//
+// @ID peers_handleUciConfigGet
+// @Tags Peer
+// @Summary Get peer configuration for OpenWrt routers as string.
+// @Description With the style "commands" (default), the configuration consists of uci commands. With the style "file",
+// @Description a snippet for the /etc/config/network file is returned.
+// @Produce json
+// @Param id path string true "The peer identifier"
+// @Param style query string false "The output style: commands or file"
+// @Success 200 {object} string
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error "The export requires the approval of another administrator"
+// @Failure 428 {object} model.Error "A re-authentication is required"
+// @Failure 500 {object} model.Error
+// @Router /peer/config-uci/{id} [get]
+func (e PeerEndpoint) handleUciConfigGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest, model.Error{
+				Code: http.StatusInternalServerError, Message: "missing id parameter",
+			})
+			return
+		}
+		style := domain.UciConfigStyle(request.QueryDefault(r, "style", string(domain.UciConfigStyleCommands)))
+
+		configTxt, err := e.peerService.GetPeerUciConfig(withClientInfo(r.Context(), r), domain.PeerIdentifier(id),
+			style)
+		if errors.Is(err, domain.ErrStepUpRequired) {
+			respond.JSON(w, http.StatusPreconditionRequired, model.Error{
+				Code: http.StatusPreconditionRequired, Message: err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrApprovalRequired) {
+			respond.JSON(w, http.StatusForbidden, model.Error{
+				Code: http.StatusForbidden, Message: err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidData) {
+			respond.JSON(w, http.StatusBadRequest, model.Error{
+				Code: http.StatusBadRequest, Message: err.Error(),
+			})
+			return
+		}
 		if err != nil {
 			respond.JSON(w, http.StatusInternalServerError, model.Error{
 				Code: http.StatusInternalServerError, Message: err.Error(),
@@ -399,6 +668,9 @@ func (e PeerEndpoint) handleConfigGet() http.HandlerFunc {
 // @Param id path string true "The peer identifier"
 // @Success 200 {file} binary
 // @Failure 400 {object} model.Error
+// @Failure 422 {object} model.Error "The configuration is too large for a QR code"
+// @Failure 403 {object} model.Error "The export requires the approval of another administrator"
+// @Failure 428 {object} model.Error "A re-authentication is required"
 // @Failure 500 {object} model.Error
 // @Router /peer/config-qr/{id} [get]
 func (e PeerEndpoint) handleQrCodeGet() http.HandlerFunc {
@@ -411,15 +683,26 @@ func (e PeerEndpoint) handleQrCodeGet() http.HandlerFunc {
 			return
 		}
 
-		configQr, err := e.peerService.GetPeerConfigQrCode(r.Context(), domain.PeerIdentifier(id))
-		if err != nil {
-			respond.JSON(w, http.StatusInternalServerError, model.Error{
-				Code: http.StatusInternalServerError, Message: err.Error(),
+		configQr, err := e.peerService.ExportPeerConfigQrCode(withClientInfo(r.Context(), r),
+			domain.PeerIdentifier(id))
+		if errors.Is(err, domain.ErrStepUpRequired) {
+			respond.JSON(w, http.StatusPreconditionRequired, model.Error{
+				Code: http.StatusPreconditionRequired, Message: err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrApprovalRequired) {
+			respond.JSON(w, http.StatusForbidden, model.Error{
+				Code: http.StatusForbidden, Message: err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrConfigTooLarge) {
+			respond.JSON(w, http.StatusUnprocessableEntity, model.Error{
+				Code: http.StatusUnprocessableEntity, Message: err.Error(),
 			})
 			return
 		}
-
-		configQrData, err := io.ReadAll(configQr)
 		if err != nil {
 			respond.JSON(w, http.StatusInternalServerError, model.Error{
 				Code: http.StatusInternalServerError, Message: err.Error(),
@@ -427,7 +710,12 @@ func (e PeerEndpoint) handleQrCodeGet() http.HandlerFunc {
 			return
 		}
 
-		respond.Data(w, http.StatusOK, "image/png", configQrData)
+		// the image is encoded directly into the response
+		w.Header().Set("Content-Type", configQr.ContentType)
+		w.WriteHeader(http.StatusOK)
+		if err := configQr.Write(w); err != nil {
+			slog.Error("failed to write qr code", "peer", id, "error", err)
+		}
 	}
 }
 
@@ -504,3 +792,180 @@ func (e PeerEndpoint) handleStatsGet() http.HandlerFunc {
 		respond.JSON(w, http.StatusOK, model.NewPeerStats(e.cfg.Statistics.CollectPeerData, stats))
 	}
 }
+
+// handleShapingStatsGet returns a gorm Handler function.
+//
+// @ID peers_handleShapingStatsGet
+// @Tags Peer
+// @Summary Get the bandwidth shaping counters of all limited peers for the given interface.
+// @Produce json
+// @Param iface path string true "The interface identifier"
+// @Success 200 {object} model.PeerShapingStats
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer/iface/{iface}/shaping-stats [get]
+func (e PeerEndpoint) handleShapingStatsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		interfaceId := Base64UrlDecode(request.Path(r, "iface"))
+		if interfaceId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing iface parameter"})
+			return
+		}
+
+		stats, err := e.peerService.GetPeerShapingStatus(r.Context(), domain.InterfaceIdentifier(interfaceId))
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeerShapingStats(e.cfg.Advanced.BandwidthShaping, stats))
+	}
+}
+
+// handleKeepaliveRecommendationsGet returns a gorm Handler function.
+//
+// @ID peers_handleKeepaliveRecommendationsGet
+// @Tags Peer
+// @Summary Get the keepalive recommendations of all flapping peers for the given interface.
+// @Description Peers are flapping if their endpoint changes frequently, usually because of NAT timeouts.
+// @Produce json
+// @Param iface path string true "The interface identifier"
+// @Success 200 {object} model.PeerKeepaliveRecommendations
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer/iface/{iface}/keepalive-recommendations [get]
+func (e PeerEndpoint) handleKeepaliveRecommendationsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		interfaceId := Base64UrlDecode(request.Path(r, "iface"))
+		if interfaceId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing iface parameter"})
+			return
+		}
+
+		recommendations, err := e.peerService.GetKeepaliveRecommendations(r.Context(),
+			domain.InterfaceIdentifier(interfaceId))
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		enabled := e.cfg.Statistics.CollectPeerData && e.cfg.Statistics.KeepaliveChurnThreshold > 0
+		respond.JSON(w, http.StatusOK, model.NewPeerKeepaliveRecommendations(enabled, recommendations))
+	}
+}
+
+// handleApplyKeepaliveRecommendationPost returns a gorm Handler function.
+//
+// @ID peers_handleApplyKeepaliveRecommendationPost
+// @Tags Peer
+// @Summary Apply the recommended keepalive interval to the given peer.
+// @Produce json
+// @Param id path string true "The peer identifier"
+// @Success 200 {object} model.Peer
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer/{id}/apply-keepalive-recommendation [post]
+func (e PeerEndpoint) handleApplyKeepaliveRecommendationPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing id parameter"})
+			return
+		}
+
+		updatedPeer, err := e.peerService.ApplyKeepaliveRecommendation(r.Context(), domain.PeerIdentifier(id))
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeer(updatedPeer))
+	}
+}
+
+// handleCleanupReportGet returns a gorm Handler function.
+//
+// @ID peers_handleCleanupReportGet
+// @Tags Peer
+// @Summary Get all peers that are affected by the inactivity cleanup policy.
+// @Description The report does not modify any peers, it can be used as a dry-run of the cleanup policy.
+// @Produce json
+// @Success 200 {object} model.PeerCleanupReport
+// @Failure 500 {object} model.Error
+// @Router /peer/cleanup-report [get]
+func (e PeerEndpoint) handleCleanupReportGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		candidates, err := e.peerService.GetCleanupReport(r.Context())
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeerCleanupReport(e.cfg.PeerCleanup, candidates))
+	}
+}
+
+func respondPeerCreationError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	if errors.Is(err, domain.ErrQuotaExceeded) {
+		code = http.StatusConflict
+	}
+
+	if respondPeerApplyError(w, code, err) {
+		return
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
+
+// respondPeerApplyError responds with the failed peer and the rollback outcome if err is a domain.PeerApplyError.
+// It returns false if err is another error.
+func respondPeerApplyError(w http.ResponseWriter, code int, err error) bool {
+	var applyErr *domain.PeerApplyError
+	if !errors.As(err, &applyErr) {
+		return false
+	}
+
+	respond.JSON(w, code, model.NewPeerApplyError(code, applyErr))
+	return true
+}
+
+// handleMtuSuggestionGet returns a gorm Handler function.
+//
+// @ID peers_handleMtuSuggestionGet
+// @Tags Peer
+// @Summary Suggest a peer MTU based on the path to the peer endpoint.
+// @Produce json
+// @Param id path string true "The peer identifier"
+// @Success 200 {object} model.MtuSuggestion
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer/{id}/mtu-suggestion [get]
+func (e PeerEndpoint) handleMtuSuggestionGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest, model.Error{
+				Code: http.StatusInternalServerError, Message: "missing id parameter",
+			})
+			return
+		}
+
+		pathMtu, err := e.peerService.SuggestPeerMtu(r.Context(), domain.PeerIdentifier(id))
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError, model.Error{
+				Code: http.StatusInternalServerError, Message: err.Error(),
+			})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewMtuSuggestion(pathMtu))
+	}
+}
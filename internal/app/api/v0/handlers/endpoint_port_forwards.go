@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type PortForwardService interface {
+	// GetAllPortForwards returns all port forwards.
+	GetAllPortForwards(ctx context.Context) ([]domain.PortForward, error)
+	// GetPeerPortForwards returns the port forwards of the given peer.
+	GetPeerPortForwards(ctx context.Context, id domain.PeerIdentifier) ([]domain.PortForward, error)
+	// CreatePortForward creates a new port forward.
+	CreatePortForward(ctx context.Context, forward *domain.PortForward) (*domain.PortForward, error)
+	// DeletePortForward deletes the port forward with the given identifier.
+	DeletePortForward(ctx context.Context, id domain.PortForwardIdentifier) error
+}
+
+type PortForwardEndpoint struct {
+	cfg                *config.Config
+	portForwardService PortForwardService
+	authenticator      Authenticator
+	validator          Validator
+}
+
+func NewPortForwardEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	portForwardService PortForwardService,
+) PortForwardEndpoint {
+	return PortForwardEndpoint{
+		cfg:                cfg,
+		portForwardService: portForwardService,
+		authenticator:      authenticator,
+		validator:          validator,
+	}
+}
+
+func (e PortForwardEndpoint) GetName() string {
+	return "PortForwardEndpoint"
+}
+
+func (e PortForwardEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/port-forward")
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.HandleFunc("GET /by-peer/{id}", e.handlePeerGet())
+	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
+	apiGroup.HandleFunc("DELETE /by-id/{id}", e.handleDelete())
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID portForwards_handleAllGet
+// @Tags Port Forwards
+// @Summary Get all port forwards.
+// @Produce json
+// @Success 200 {object} []model.PortForward
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /port-forward/all [get]
+func (e PortForwardEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		forwards, err := e.portForwardService.GetAllPortForwards(r.Context())
+		if err != nil {
+			respondPortForwardError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPortForwards(forwards))
+	}
+}
+
+// handlePeerGet returns a gorm Handler function.
+//
+// @ID portForwards_handlePeerGet
+// @Tags Port Forwards
+// @Summary Get the port forwards of the given peer.
+// @Param id path string true "The peer identifier (base64 encoded)"
+// @Produce json
+// @Success 200 {object} []model.PortForward
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /port-forward/by-peer/{id} [get]
+func (e PortForwardEndpoint) handlePeerGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerId := Base64UrlDecode(request.Path(r, "id"))
+		if peerId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		forwards, err := e.portForwardService.GetPeerPortForwards(r.Context(), domain.PeerIdentifier(peerId))
+		if err != nil {
+			respondPortForwardError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPortForwards(forwards))
+	}
+}
+
+// handleCreatePost returns a gorm Handler function.
+//
+// @ID portForwards_handleCreatePost
+// @Tags Port Forwards
+// @Summary Forward an external port of the server to the tunnel address of a peer.
+// @Description The external port must be within the configured port range and must not be used by another port
+// @Description forward, a WireGuard listen port or the web interface.
+// @Produce json
+// @Param request body model.PortForwardRequest true "The port forward"
+// @Success 200 {object} model.PortForward
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 409 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /port-forward/new [post]
+func (e PortForwardEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req model.PortForwardRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		forward, err := e.portForwardService.CreatePortForward(r.Context(), model.NewDomainPortForward(&req))
+		if err != nil {
+			respondPortForwardError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPortForward(forward))
+	}
+}
+
+// handleDelete returns a gorm Handler function.
+//
+// @ID portForwards_handleDelete
+// @Tags Port Forwards
+// @Summary Delete the port forward with the given identifier.
+// @Param id path string true "The port forward identifier"
+// @Produce json
+// @Success 204 "No content if the port forward was deleted"
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /port-forward/by-id/{id} [delete]
+func (e PortForwardEndpoint) handleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing port forward id"})
+			return
+		}
+
+		if err := e.portForwardService.DeletePortForward(r.Context(), domain.PortForwardIdentifier(id)); err != nil {
+			respondPortForwardError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+func respondPortForwardError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrDuplicateEntry):
+		code = http.StatusConflict
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type RestoreService interface {
+	// GetReport returns the report of the startup restoration.
+	GetReport(ctx context.Context) (*domain.RestoreReport, error)
+}
+
+type RestoreEndpoint struct {
+	cfg            *config.Config
+	restoreService RestoreService
+	authenticator  Authenticator
+}
+
+func NewRestoreEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	restoreService RestoreService,
+) RestoreEndpoint {
+	return RestoreEndpoint{
+		cfg:            cfg,
+		restoreService: restoreService,
+		authenticator:  authenticator,
+	}
+}
+
+func (e RestoreEndpoint) GetName() string {
+	return "RestoreEndpoint"
+}
+
+func (e RestoreEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/restore")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /report", e.handleReportGet())
+}
+
+// handleReportGet returns a gorm Handler function.
+//
+// @ID restore_handleReportGet
+// @Tags Restore
+// @Summary Get the report of the startup restoration.
+// @Description The report contains no steps if the ordered restoration is disabled.
+// @Produce json
+// @Success 200 {object} model.RestoreReport
+// @Failure 500 {object} model.Error
+// @Router /restore/report [get]
+func (e RestoreEndpoint) handleReportGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := e.restoreService.GetReport(r.Context())
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewRestoreReport(e.cfg.Restore.Enabled, report))
+	}
+}
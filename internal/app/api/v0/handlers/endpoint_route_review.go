@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type RouteReviewService interface {
+	// GetRouteReviews returns all reviews of the current user or of the interfaces the user administrates.
+	GetRouteReviews(ctx context.Context) ([]domain.RouteReview, error)
+	// ApproveReview approves the given review and applies the requested routes.
+	ApproveReview(ctx context.Context, id domain.RouteReviewIdentifier) (*domain.RouteReview, error)
+	// RejectReview rejects the given review.
+	RejectReview(ctx context.Context, id domain.RouteReviewIdentifier) (*domain.RouteReview, error)
+	// CancelReview withdraws the given review.
+	CancelReview(ctx context.Context, id domain.RouteReviewIdentifier) (*domain.RouteReview, error)
+}
+
+type RouteReviewEndpoint struct {
+	cfg                *config.Config
+	routeReviewService RouteReviewService
+	authenticator      Authenticator
+	validator          Validator
+}
+
+func NewRouteReviewEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	routeReviewService RouteReviewService,
+) RouteReviewEndpoint {
+	return RouteReviewEndpoint{
+		cfg:                cfg,
+		routeReviewService: routeReviewService,
+		authenticator:      authenticator,
+		validator:          validator,
+	}
+}
+
+func (e RouteReviewEndpoint) GetName() string {
+	return "RouteReviewEndpoint"
+}
+
+func (e RouteReviewEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/route-review")
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.HandleFunc("POST /by-id/{id}/approve", e.handleActionPost(e.routeReviewService.ApproveReview))
+	apiGroup.HandleFunc("POST /by-id/{id}/reject", e.handleActionPost(e.routeReviewService.RejectReview))
+	apiGroup.HandleFunc("POST /by-id/{id}/cancel", e.handleActionPost(e.routeReviewService.CancelReview))
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID routeReviews_handleAllGet
+// @Tags Route Reviews
+// @Summary Get all route reviews of the current user. Admins receive the reviews of their interfaces as well.
+// @Produce json
+// @Success 200 {object} []model.RouteReview
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /route-review/all [get]
+func (e RouteReviewEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviews, err := e.routeReviewService.GetRouteReviews(r.Context())
+		if err != nil {
+			respondRouteReviewError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewRouteReviews(reviews))
+	}
+}
+
+// handleActionPost returns a gorm Handler function.
+//
+// @ID routeReviews_handleActionPost
+// @Tags Route Reviews
+// @Summary Approve, reject or cancel a route review.
+// @Description Once a review is approved, the requested routes are applied to the peer.
+// @Param id path string true "The review identifier"
+// @Param action path string true "The action" Enums(approve, reject, cancel)
+// @Produce json
+// @Success 200 {object} model.RouteReview
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /route-review/by-id/{id}/{action} [post]
+func (e RouteReviewEndpoint) handleActionPost(
+	action func(context.Context, domain.RouteReviewIdentifier) (*domain.RouteReview, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing review id"})
+			return
+		}
+
+		review, err := action(r.Context(), domain.RouteReviewIdentifier(id))
+		if err != nil {
+			respondRouteReviewError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewRouteReview(review))
+	}
+}
+
+func respondRouteReviewError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type RouteSetService interface {
+	// GetAllRouteSets returns all route sets.
+	GetAllRouteSets(ctx context.Context) ([]domain.RouteSet, error)
+	// GetRouteSet returns the route set with the given id.
+	GetRouteSet(ctx context.Context, id domain.RouteSetIdentifier) (*domain.RouteSet, error)
+	// CreateRouteSet creates a new route set.
+	CreateRouteSet(ctx context.Context, routeSet *domain.RouteSet) (*domain.RouteSet, error)
+	// UpdateRouteSet updates the route set and notifies the users of all affected peers.
+	UpdateRouteSet(ctx context.Context, routeSet *domain.RouteSet) (*domain.RouteSet, error)
+	// DeleteRouteSet deletes the route set with the given id.
+	DeleteRouteSet(ctx context.Context, id domain.RouteSetIdentifier) error
+	// GetRouteSetPeers returns all peers that use the route set with the given id.
+	GetRouteSetPeers(ctx context.Context, id domain.RouteSetIdentifier) ([]domain.Peer, error)
+}
+
+type RouteSetEndpoint struct {
+	cfg             *config.Config
+	routeSetService RouteSetService
+	authenticator   Authenticator
+	validator       Validator
+}
+
+func NewRouteSetEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	routeSetService RouteSetService,
+) RouteSetEndpoint {
+	return RouteSetEndpoint{
+		cfg:             cfg,
+		routeSetService: routeSetService,
+		authenticator:   authenticator,
+		validator:       validator,
+	}
+}
+
+func (e RouteSetEndpoint) GetName() string {
+	return "RouteSetEndpoint"
+}
+
+func (e RouteSetEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/route-set")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.HandleFunc("GET /by-id/{id}", e.handleSingleGet())
+	apiGroup.HandleFunc("GET /by-id/{id}/peers", e.handlePeersGet())
+	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
+	apiGroup.HandleFunc("PUT /by-id/{id}", e.handleUpdatePut())
+	apiGroup.HandleFunc("DELETE /by-id/{id}", e.handleDelete())
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID routeSets_handleAllGet
+// @Tags Route Sets
+// @Summary Get all route sets.
+// @Produce json
+// @Success 200 {object} []model.RouteSet
+// @Failure 500 {object} model.Error
+// @Router /route-set/all [get]
+func (e RouteSetEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		routeSets, err := e.routeSetService.GetAllRouteSets(r.Context())
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		result := make([]model.RouteSet, len(routeSets))
+		for i := range routeSets {
+			peers, err := e.routeSetService.GetRouteSetPeers(r.Context(), routeSets[i].Identifier)
+			if err != nil {
+				respond.JSON(w, http.StatusInternalServerError,
+					model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+				return
+			}
+			result[i] = *model.NewRouteSet(&routeSets[i], len(peers))
+		}
+
+		respond.JSON(w, http.StatusOK, result)
+	}
+}
+
+// handleSingleGet returns a gorm Handler function.
+//
+// @ID routeSets_handleSingleGet
+// @Tags Route Sets
+// @Summary Get a single route set.
+// @Param id path string true "The route set identifier"
+// @Produce json
+// @Success 200 {object} model.RouteSet
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /route-set/by-id/{id} [get]
+func (e RouteSetEndpoint) handleSingleGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing route set id"})
+			return
+		}
+
+		routeSet, err := e.routeSetService.GetRouteSet(r.Context(), domain.RouteSetIdentifier(id))
+		if err != nil {
+			respondRouteSetError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewRouteSet(routeSet, 0))
+	}
+}
+
+// handlePeersGet returns a gorm Handler function.
+//
+// @ID routeSets_handlePeersGet
+// @Tags Route Sets
+// @Summary Get all peers that use the given route set.
+// @Param id path string true "The route set identifier"
+// @Produce json
+// @Success 200 {object} []model.Peer
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /route-set/by-id/{id}/peers [get]
+func (e RouteSetEndpoint) handlePeersGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing route set id"})
+			return
+		}
+
+		peers, err := e.routeSetService.GetRouteSetPeers(r.Context(), domain.RouteSetIdentifier(id))
+		if err != nil {
+			respondRouteSetError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeers(peers))
+	}
+}
+
+// handleCreatePost returns a gorm Handler function.
+//
+// @ID routeSets_handleCreatePost
+// @Tags Route Sets
+// @Summary Create a new route set.
+// @Produce json
+// @Param request body model.RouteSet true "The route set data"
+// @Success 200 {object} model.RouteSet
+// @Failure 400 {object} model.Error
+// @Failure 409 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /route-set/new [post]
+func (e RouteSetEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var routeSet model.RouteSet
+		if err := request.BodyJson(r, &routeSet); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(routeSet); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		newRouteSet, err := e.routeSetService.CreateRouteSet(r.Context(), model.NewDomainRouteSet(&routeSet))
+		if err != nil {
+			respondRouteSetError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewRouteSet(newRouteSet, 0))
+	}
+}
+
+// handleUpdatePut returns a gorm Handler function.
+//
+// @ID routeSets_handleUpdatePut
+// @Tags Route Sets
+// @Summary Update the route set. The users of all affected peers receive the updated configuration.
+// @Produce json
+// @Param id path string true "The route set identifier"
+// @Param request body model.RouteSet true "The route set data"
+// @Success 200 {object} model.RouteSet
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /route-set/by-id/{id} [put]
+func (e RouteSetEndpoint) handleUpdatePut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing route set id"})
+			return
+		}
+
+		var routeSet model.RouteSet
+		if err := request.BodyJson(r, &routeSet); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(routeSet); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		if id != routeSet.Identifier {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "route set id mismatch"})
+			return
+		}
+
+		updatedRouteSet, err := e.routeSetService.UpdateRouteSet(r.Context(), model.NewDomainRouteSet(&routeSet))
+		if err != nil {
+			respondRouteSetError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewRouteSet(updatedRouteSet, 0))
+	}
+}
+
+// handleDelete returns a gorm Handler function.
+//
+// @ID routeSets_handleDelete
+// @Tags Route Sets
+// @Summary Delete the route set with the given id.
+// @Produce json
+// @Param id path string true "The route set identifier"
+// @Success 204 "No content if deletion was successful"
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /route-set/by-id/{id} [delete]
+func (e RouteSetEndpoint) handleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing route set id"})
+			return
+		}
+
+		err := e.routeSetService.DeleteRouteSet(r.Context(), domain.RouteSetIdentifier(id))
+		if err != nil {
+			respondRouteSetError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+func respondRouteSetError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrDuplicateEntry):
+		code = http.StatusConflict
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
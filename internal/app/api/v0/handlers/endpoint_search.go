@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type SearchService interface {
+	// Search returns the entities that match the given query and that the current user is allowed to view, at most
+	// limit results per result type.
+	Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error)
+}
+
+type SearchEndpoint struct {
+	searchService SearchService
+	authenticator Authenticator
+}
+
+func NewSearchEndpoint(authenticator Authenticator, searchService SearchService) SearchEndpoint {
+	return SearchEndpoint{
+		searchService: searchService,
+		authenticator: authenticator,
+	}
+}
+
+func (e SearchEndpoint) GetName() string {
+	return "SearchEndpoint"
+}
+
+func (e SearchEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/search")
+	// all users can search, the results only contain the entities the user is allowed to view
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.HandleFunc("GET /query", e.handleSearchGet())
+}
+
+// handleSearchGet returns a gorm Handler function.
+//
+// @ID search_handleSearchGet
+// @Tags Search
+// @Summary Search users, peers, interfaces and audit entries.
+// @Description Queries shorter than two characters return no results. Only entities the current user is allowed
+// @Description to view are returned.
+// @Produce json
+// @Param q query string true "The search query"
+// @Param limit query int false "The maximum number of results per result type, defaults to 5, at most 25"
+// @Success 200 {object} []model.SearchResult
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /search/query [get]
+func (e SearchEndpoint) handleSearchGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := 0
+		if limitStr := request.Query(r, "limit"); limitStr != "" {
+			var err error
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit < 0 {
+				respond.JSON(w, http.StatusBadRequest,
+					model.Error{Code: http.StatusBadRequest, Message: "invalid limit parameter"})
+				return
+			}
+		}
+
+		results, err := e.searchService.Search(r.Context(), request.Query(r, "q"), limit)
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewSearchResults(results))
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type SecurityEventService interface {
+	// GetEvents returns all security events, the most recent events first.
+	GetEvents(ctx context.Context) ([]domain.SecurityEvent, error)
+}
+
+type SecurityEventEndpoint struct {
+	cfg                  *config.Config
+	securityEventService SecurityEventService
+	authenticator        Authenticator
+	validator            Validator
+}
+
+func NewSecurityEventEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	securityEventService SecurityEventService,
+) SecurityEventEndpoint {
+	return SecurityEventEndpoint{
+		cfg:                  cfg,
+		securityEventService: securityEventService,
+		authenticator:        authenticator,
+		validator:            validator,
+	}
+}
+
+func (e SecurityEventEndpoint) GetName() string {
+	return "SecurityEventEndpoint"
+}
+
+func (e SecurityEventEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/security-event")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /all", e.handleAllGet())
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID securityEvents_handleAllGet
+// @Tags Security Events
+// @Summary Get the security event feed, the most recent events first.
+// @Produce json
+// @Success 200 {object} []model.SecurityEvent
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /security-event/all [get]
+func (e SecurityEventEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		events, err := e.securityEventService.GetEvents(r.Context())
+		if err != nil {
+			respondSecurityEventError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewSecurityEvents(events))
+	}
+}
+
+func respondSecurityEventError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	if errors.Is(err, domain.ErrNoPermission) {
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
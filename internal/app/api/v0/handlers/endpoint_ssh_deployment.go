@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type SshDeploymentService interface {
+	// GetHosts returns the names of all configured SSH hosts.
+	GetHosts(ctx context.Context) ([]string, error)
+	// GetDeployment returns the SSH deployment of the given peer.
+	GetDeployment(ctx context.Context, peerId domain.PeerIdentifier) (*domain.SshDeployment, error)
+	// SaveDeployment assigns the given peer to a remote host and deploys the configuration immediately.
+	SaveDeployment(ctx context.Context, deployment *domain.SshDeployment) (*domain.SshDeployment, error)
+	// DeleteDeployment removes the SSH deployment of the given peer.
+	DeleteDeployment(ctx context.Context, peerId domain.PeerIdentifier) error
+	// Deploy pushes the current configuration of the given peer to its remote host.
+	Deploy(ctx context.Context, peerId domain.PeerIdentifier) (*domain.SshDeployment, error)
+}
+
+type SshDeploymentEndpoint struct {
+	cfg                  *config.Config
+	sshDeploymentService SshDeploymentService
+	authenticator        Authenticator
+	validator            Validator
+}
+
+func NewSshDeploymentEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	sshDeploymentService SshDeploymentService,
+) SshDeploymentEndpoint {
+	return SshDeploymentEndpoint{
+		cfg:                  cfg,
+		sshDeploymentService: sshDeploymentService,
+		authenticator:        authenticator,
+		validator:            validator,
+	}
+}
+
+func (e SshDeploymentEndpoint) GetName() string {
+	return "SshDeploymentEndpoint"
+}
+
+func (e SshDeploymentEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/ssh-deployment")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /hosts", e.handleHostsGet())
+	apiGroup.HandleFunc("GET /by-peer/{id}", e.handleDeploymentGet())
+	apiGroup.HandleFunc("PUT /by-peer/{id}", e.handleDeploymentPut())
+	apiGroup.HandleFunc("DELETE /by-peer/{id}", e.handleDeploymentDelete())
+	apiGroup.HandleFunc("POST /by-peer/{id}/deploy", e.handleDeployPost())
+}
+
+// handleHostsGet returns a gorm Handler function.
+//
+// @ID sshDeployment_handleHostsGet
+// @Tags SSH Deployment
+// @Summary Get the names of all configured SSH hosts.
+// @Produce json
+// @Success 200 {object} []string
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /ssh-deployment/hosts [get]
+func (e SshDeploymentEndpoint) handleHostsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hosts, err := e.sshDeploymentService.GetHosts(r.Context())
+		if err != nil {
+			respondSshDeploymentError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, hosts)
+	}
+}
+
+// handleDeploymentGet returns a gorm Handler function.
+//
+// @ID sshDeployment_handleDeploymentGet
+// @Tags SSH Deployment
+// @Summary Get the SSH deployment status of the given peer.
+// @Param id path string true "The peer identifier (base64 encoded)"
+// @Produce json
+// @Success 200 {object} model.SshDeployment
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /ssh-deployment/by-peer/{id} [get]
+func (e SshDeploymentEndpoint) handleDeploymentGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerId := Base64UrlDecode(request.Path(r, "id"))
+		if peerId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		deployment, err := e.sshDeploymentService.GetDeployment(r.Context(), domain.PeerIdentifier(peerId))
+		if errors.Is(err, domain.ErrNotFound) {
+			deployment, err = nil, nil // the peer is not deployed via SSH
+		}
+		if err != nil {
+			respondSshDeploymentError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewSshDeployment(deployment))
+	}
+}
+
+// handleDeploymentPut returns a gorm Handler function.
+//
+// @ID sshDeployment_handleDeploymentPut
+// @Tags SSH Deployment
+// @Summary Deploy the given peer to a remote host via SSH.
+// @Description The configuration is pushed immediately and whenever it changes afterwards. The result of the
+// @Description first deployment attempt is part of the response.
+// @Param id path string true "The peer identifier (base64 encoded)"
+// @Param request body model.SshDeploymentRequest true "The remote host and interface"
+// @Produce json
+// @Success 200 {object} model.SshDeployment
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /ssh-deployment/by-peer/{id} [put]
+func (e SshDeploymentEndpoint) handleDeploymentPut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerId := Base64UrlDecode(request.Path(r, "id"))
+		if peerId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		var req model.SshDeploymentRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		deployment, err := e.sshDeploymentService.SaveDeployment(r.Context(),
+			model.NewDomainSshDeployment(domain.PeerIdentifier(peerId), &req))
+		if err != nil {
+			respondSshDeploymentError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewSshDeployment(deployment))
+	}
+}
+
+// handleDeploymentDelete returns a gorm Handler function.
+//
+// @ID sshDeployment_handleDeploymentDelete
+// @Tags SSH Deployment
+// @Summary Stop deploying the given peer via SSH. The configuration on the remote host is not removed.
+// @Param id path string true "The peer identifier (base64 encoded)"
+// @Produce json
+// @Success 204 "No content if the deployment was removed"
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /ssh-deployment/by-peer/{id} [delete]
+func (e SshDeploymentEndpoint) handleDeploymentDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerId := Base64UrlDecode(request.Path(r, "id"))
+		if peerId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		if err := e.sshDeploymentService.DeleteDeployment(r.Context(), domain.PeerIdentifier(peerId)); err != nil {
+			respondSshDeploymentError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+// handleDeployPost returns a gorm Handler function.
+//
+// @ID sshDeployment_handleDeployPost
+// @Tags SSH Deployment
+// @Summary Push the current configuration of the given peer to its remote host, even if it did not change.
+// @Param id path string true "The peer identifier (base64 encoded)"
+// @Produce json
+// @Success 200 {object} model.SshDeployment
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /ssh-deployment/by-peer/{id}/deploy [post]
+func (e SshDeploymentEndpoint) handleDeployPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerId := Base64UrlDecode(request.Path(r, "id"))
+		if peerId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		deployment, err := e.sshDeploymentService.Deploy(r.Context(), domain.PeerIdentifier(peerId))
+		if err != nil {
+			respondSshDeploymentError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewSshDeployment(deployment))
+	}
+}
+
+func respondSshDeploymentError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type StatusPageService interface {
+	// GetStatus returns the last checked service status.
+	GetStatus(ctx context.Context) (*domain.ServiceStatus, error)
+}
+
+type StatusPageEndpoint struct {
+	statusPage StatusPageService
+}
+
+func NewStatusPageEndpoint(statusPage StatusPageService) StatusPageEndpoint {
+	return StatusPageEndpoint{
+		statusPage: statusPage,
+	}
+}
+
+func (e StatusPageEndpoint) GetName() string {
+	return "StatusPageEndpoint"
+}
+
+func (e StatusPageEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	// the status page is public, it contains no peer or user data
+	g.HandleFunc("GET /status", e.handleStatusGet())
+}
+
+// handleStatusGet returns a gorm Handler function.
+//
+// @ID status_handleStatusGet
+// @Tags Status
+// @Summary Get the public status of the VPN endpoints and the current maintenance notices.
+// @Description No authentication is required. The status is refreshed periodically in the background.
+// @Produce json
+// @Success 200 {object} model.ServiceStatus
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /status [get]
+func (e StatusPageEndpoint) handleStatusGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := e.statusPage.GetStatus(r.Context())
+		if errors.Is(err, domain.ErrNotFound) {
+			respond.JSON(w, http.StatusNotFound, model.Error{Code: http.StatusNotFound, Message: err.Error()})
+			return
+		}
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-cache")
+		respond.JSON(w, http.StatusOK, model.NewServiceStatus(status, time.Now()))
+	}
+}
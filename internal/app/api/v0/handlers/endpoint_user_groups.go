@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type UserGroupService interface {
+	// GetAllUserGroups returns all user groups.
+	GetAllUserGroups(ctx context.Context) ([]domain.UserGroup, error)
+	// GetUserGroup returns the user group with the given id.
+	GetUserGroup(ctx context.Context, id domain.UserGroupIdentifier) (*domain.UserGroup, error)
+	// CreateUserGroup creates a new user group.
+	CreateUserGroup(ctx context.Context, group *domain.UserGroup) (*domain.UserGroup, error)
+	// UpdateUserGroup updates the user group.
+	UpdateUserGroup(ctx context.Context, group *domain.UserGroup) (*domain.UserGroup, error)
+	// DeleteUserGroup deletes the user group with the given id.
+	DeleteUserGroup(ctx context.Context, id domain.UserGroupIdentifier) error
+}
+
+type UserGroupEndpoint struct {
+	cfg              *config.Config
+	userGroupService UserGroupService
+	authenticator    Authenticator
+	validator        Validator
+}
+
+func NewUserGroupEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	userGroupService UserGroupService,
+) UserGroupEndpoint {
+	return UserGroupEndpoint{
+		cfg:              cfg,
+		userGroupService: userGroupService,
+		authenticator:    authenticator,
+		validator:        validator,
+	}
+}
+
+func (e UserGroupEndpoint) GetName() string {
+	return "UserGroupEndpoint"
+}
+
+func (e UserGroupEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/user-group")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.HandleFunc("GET /by-id/{id}", e.handleSingleGet())
+	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
+	apiGroup.HandleFunc("PUT /by-id/{id}", e.handleUpdatePut())
+	apiGroup.HandleFunc("DELETE /by-id/{id}", e.handleDelete())
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID userGroups_handleAllGet
+// @Tags User Groups
+// @Summary Get all user groups. Only available for global administrators.
+// @Produce json
+// @Success 200 {object} []model.UserGroup
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /user-group/all [get]
+func (e UserGroupEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groups, err := e.userGroupService.GetAllUserGroups(r.Context())
+		if err != nil {
+			respondUserGroupError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewUserGroups(groups))
+	}
+}
+
+// handleSingleGet returns a gorm Handler function.
+//
+// @ID userGroups_handleSingleGet
+// @Tags User Groups
+// @Summary Get a single user group.
+// @Param id path string true "The user group identifier"
+// @Produce json
+// @Success 200 {object} model.UserGroup
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /user-group/by-id/{id} [get]
+func (e UserGroupEndpoint) handleSingleGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing user group id"})
+			return
+		}
+
+		group, err := e.userGroupService.GetUserGroup(r.Context(), domain.UserGroupIdentifier(id))
+		if err != nil {
+			respondUserGroupError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewUserGroup(group))
+	}
+}
+
+// handleCreatePost returns a gorm Handler function.
+//
+// @ID userGroups_handleCreatePost
+// @Tags User Groups
+// @Summary Create a new user group. Only available for global administrators.
+// @Produce json
+// @Param request body model.UserGroup true "The user group data"
+// @Success 200 {object} model.UserGroup
+// @Failure 400 {object} model.Error
+// @Failure 409 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /user-group/new [post]
+func (e UserGroupEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var group model.UserGroup
+		if err := request.BodyJson(r, &group); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(group); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		newGroup, err := e.userGroupService.CreateUserGroup(r.Context(),
+			model.NewDomainUserGroup(&group))
+		if err != nil {
+			respondUserGroupError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewUserGroup(newGroup))
+	}
+}
+
+// handleUpdatePut returns a gorm Handler function.
+//
+// @ID userGroups_handleUpdatePut
+// @Tags User Groups
+// @Summary Update the user group. Only available for global administrators.
+// @Produce json
+// @Param id path string true "The user group identifier"
+// @Param request body model.UserGroup true "The user group data"
+// @Success 200 {object} model.UserGroup
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /user-group/by-id/{id} [put]
+func (e UserGroupEndpoint) handleUpdatePut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing user group id"})
+			return
+		}
+
+		var group model.UserGroup
+		if err := request.BodyJson(r, &group); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(group); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		if id != group.Identifier {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "user group id mismatch"})
+			return
+		}
+
+		updatedGroup, err := e.userGroupService.UpdateUserGroup(r.Context(),
+			model.NewDomainUserGroup(&group))
+		if err != nil {
+			respondUserGroupError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewUserGroup(updatedGroup))
+	}
+}
+
+// handleDelete returns a gorm Handler function.
+//
+// @ID userGroups_handleDelete
+// @Tags User Groups
+// @Summary Delete the user group with the given id. Only available for global administrators.
+// @Description The members keep their peers, the group only no longer grants rights or route sets.
+// @Produce json
+// @Param id path string true "The user group identifier"
+// @Success 204 "No content if deletion was successful"
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /user-group/by-id/{id} [delete]
+func (e UserGroupEndpoint) handleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing user group id"})
+			return
+		}
+
+		err := e.userGroupService.DeleteUserGroup(r.Context(), domain.UserGroupIdentifier(id))
+		if err != nil {
+			respondUserGroupError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+func respondUserGroupError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrDuplicateEntry):
+		code = http.StatusConflict
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/go-pkgz/routegroup"
 
@@ -28,12 +30,32 @@ type UserService interface {
 	ActivateApi(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
 	// DeactivateApi disables the API for the user with the given id.
 	DeactivateApi(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	// ResetWebAuthnCredentials removes all passkeys of the user with the given id.
+	ResetWebAuthnCredentials(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
 	// GetUserPeers returns all peers for the given user.
 	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
 	// GetUserPeerStats returns all peer stats for the given user.
 	GetUserPeerStats(ctx context.Context, id domain.UserIdentifier) ([]domain.PeerStatus, error)
 	// GetUserInterfaces returns all interfaces for the given user.
 	GetUserInterfaces(ctx context.Context, id domain.UserIdentifier) ([]domain.Interface, error)
+	// GetNotificationPreferences returns the notification preferences of the given user.
+	GetNotificationPreferences(ctx context.Context, id domain.UserIdentifier) (
+		*domain.UserNotificationPreferences,
+		error,
+	)
+	// UpdateNotificationPreferences stores the notification preferences of a user.
+	UpdateNotificationPreferences(ctx context.Context, prefs *domain.UserNotificationPreferences) (
+		*domain.UserNotificationPreferences,
+		error,
+	)
+	// GetSavedViews returns the saved views of the given list that the given user can use.
+	GetSavedViews(ctx context.Context, id domain.UserIdentifier, list domain.SavedViewList) ([]domain.SavedView, error)
+	// CreateSavedView stores a new saved view.
+	CreateSavedView(ctx context.Context, view *domain.SavedView) (*domain.SavedView, error)
+	// UpdateSavedView updates the given saved view.
+	UpdateSavedView(ctx context.Context, view *domain.SavedView) (*domain.SavedView, error)
+	// DeleteSavedView deletes the saved view with the given id.
+	DeleteSavedView(ctx context.Context, userId domain.UserIdentifier, id uint64) error
 }
 
 type UserEndpoint struct {
@@ -65,16 +87,33 @@ func (e UserEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup := g.Mount("/user")
 	apiGroup.Use(e.authenticator.LoggedIn())
 
-	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /all", e.handleAllGet())
-	apiGroup.With(e.authenticator.UserIdMatch("id")).HandleFunc("GET /{id}", e.handleSingleGet())
-	apiGroup.With(e.authenticator.UserIdMatch("id")).HandleFunc("PUT /{id}", e.handleUpdatePut())
-	apiGroup.With(e.authenticator.UserIdMatch("id")).HandleFunc("DELETE /{id}", e.handleDelete())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeHelpdesk)).HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.With(e.authenticator.UserIdMatch("id", ScopeHelpdesk)).HandleFunc("GET /{id}", e.handleSingleGet())
+	apiGroup.With(e.authenticator.UserIdMatch("id"), e.authenticator.NoImpersonation()).HandleFunc("PUT /{id}",
+		e.handleUpdatePut())
+	apiGroup.With(e.authenticator.UserIdMatch("id"), e.authenticator.NoImpersonation()).HandleFunc("DELETE /{id}",
+		e.handleDelete())
 	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /new", e.handleCreatePost())
-	apiGroup.With(e.authenticator.UserIdMatch("id")).HandleFunc("GET /{id}/peers", e.handlePeersGet())
-	apiGroup.With(e.authenticator.UserIdMatch("id")).HandleFunc("GET /{id}/stats", e.handleStatsGet())
+	apiGroup.With(e.authenticator.UserIdMatch("id", ScopeHelpdesk)).HandleFunc("GET /{id}/peers", e.handlePeersGet())
+	apiGroup.With(e.authenticator.UserIdMatch("id", ScopeHelpdesk)).HandleFunc("GET /{id}/stats", e.handleStatsGet())
 	apiGroup.With(e.authenticator.UserIdMatch("id")).HandleFunc("GET /{id}/interfaces", e.handleInterfacesGet())
-	apiGroup.With(e.authenticator.UserIdMatch("id")).HandleFunc("POST /{id}/api/enable", e.handleApiEnablePost())
-	apiGroup.With(e.authenticator.UserIdMatch("id")).HandleFunc("POST /{id}/api/disable", e.handleApiDisablePost())
+	apiGroup.With(e.authenticator.UserIdMatch("id"), e.authenticator.NoImpersonation()).HandleFunc(
+		"POST /{id}/api/enable", e.handleApiEnablePost())
+	apiGroup.With(e.authenticator.UserIdMatch("id"), e.authenticator.NoImpersonation()).HandleFunc(
+		"POST /{id}/api/disable", e.handleApiDisablePost())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeHelpdesk), e.authenticator.NoImpersonation()).HandleFunc(
+		"POST /{id}/webauthn/reset", e.handleWebAuthnResetPost())
+	apiGroup.With(e.authenticator.UserIdMatch("id")).HandleFunc("GET /{id}/notification-preferences",
+		e.handleNotificationPreferencesGet())
+	apiGroup.With(e.authenticator.UserIdMatch("id"), e.authenticator.NoImpersonation()).HandleFunc(
+		"PUT /{id}/notification-preferences", e.handleNotificationPreferencesPut())
+	apiGroup.With(e.authenticator.UserIdMatch("id")).HandleFunc("GET /{id}/saved-views", e.handleSavedViewsGet())
+	apiGroup.With(e.authenticator.UserIdMatch("id"), e.authenticator.NoImpersonation()).HandleFunc(
+		"POST /{id}/saved-views", e.handleSavedViewPost())
+	apiGroup.With(e.authenticator.UserIdMatch("id"), e.authenticator.NoImpersonation()).HandleFunc(
+		"PUT /{id}/saved-views/{viewId}", e.handleSavedViewPut())
+	apiGroup.With(e.authenticator.UserIdMatch("id"), e.authenticator.NoImpersonation()).HandleFunc(
+		"DELETE /{id}/saved-views/{viewId}", e.handleSavedViewDelete())
 }
 
 // handleAllGet returns a gorm Handler function.
@@ -391,3 +430,294 @@ func (e UserEndpoint) handleApiDisablePost() http.HandlerFunc {
 		respond.JSON(w, http.StatusOK, model.NewUser(user, false))
 	}
 }
+
+// handleWebAuthnResetPost returns a gorm Handler function.
+//
+// @ID users_handleWebAuthnResetPost
+// @Tags Users
+// @Summary Remove all passkeys of the given user, for example after the user lost the security key.
+// @Description Only admins and helpdesk users can reset passkeys. Helpdesk users cannot reset administrators.
+// @Produce json
+// @Param id path string true "The user identifier"
+// @Success 200 {object} model.User
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /user/{id}/webauthn/reset [post]
+func (e UserEndpoint) handleWebAuthnResetPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := Base64UrlDecode(request.Path(r, "id"))
+		if userId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing id parameter"})
+			return
+		}
+
+		user, err := e.userService.ResetWebAuthnCredentials(r.Context(), domain.UserIdentifier(userId))
+		if err != nil {
+			respondUserError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewUser(user, false))
+	}
+}
+
+// handleNotificationPreferencesGet returns a gorm Handler function.
+//
+// @ID users_handleNotificationPreferencesGet
+// @Tags Users
+// @Summary Get the notification preferences of the given user.
+// @Produce json
+// @Param id path string true "The user identifier"
+// @Success 200 {object} model.NotificationPreferences
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /user/{id}/notification-preferences [get]
+func (e UserEndpoint) handleNotificationPreferencesGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := Base64UrlDecode(request.Path(r, "id"))
+		if userId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing id parameter"})
+			return
+		}
+
+		prefs, err := e.userService.GetNotificationPreferences(r.Context(), domain.UserIdentifier(userId))
+		if err != nil {
+			respondUserError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewNotificationPreferences(prefs))
+	}
+}
+
+// handleNotificationPreferencesPut returns a gorm Handler function.
+//
+// @ID users_handleNotificationPreferencesPut
+// @Tags Users
+// @Summary Update the notification preferences of the given user.
+// @Produce json
+// @Param id path string true "The user identifier"
+// @Param request body model.NotificationPreferences true "The notification preferences"
+// @Success 200 {object} model.NotificationPreferences
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /user/{id}/notification-preferences [put]
+func (e UserEndpoint) handleNotificationPreferencesPut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := Base64UrlDecode(request.Path(r, "id"))
+		if userId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing id parameter"})
+			return
+		}
+
+		var prefs model.NotificationPreferences
+		if err := request.BodyJson(r, &prefs); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(prefs); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		updated, err := e.userService.UpdateNotificationPreferences(r.Context(),
+			model.NewDomainNotificationPreferences(domain.UserIdentifier(userId), &prefs))
+		if err != nil {
+			respondUserError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewNotificationPreferences(updated))
+	}
+}
+
+// handleSavedViewsGet returns a gorm Handler function.
+//
+// @ID users_handleSavedViewsGet
+// @Tags Users
+// @Summary Get the saved views of the given user for a list, including the views shared by other administrators.
+// @Produce json
+// @Param id path string true "The user identifier"
+// @Param list query string true "The list of the views, either peers or users"
+// @Success 200 {object} []model.SavedView
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /user/{id}/saved-views [get]
+func (e UserEndpoint) handleSavedViewsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := Base64UrlDecode(request.Path(r, "id"))
+		if userId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing id parameter"})
+			return
+		}
+
+		views, err := e.userService.GetSavedViews(r.Context(), domain.UserIdentifier(userId),
+			domain.SavedViewList(request.Query(r, "list")))
+		if err != nil {
+			respondUserError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewSavedViews(views))
+	}
+}
+
+// handleSavedViewPost returns a gorm Handler function.
+//
+// @ID users_handleSavedViewPost
+// @Tags Users
+// @Summary Create a saved view for the given user. Only administrators can share views.
+// @Produce json
+// @Param id path string true "The user identifier"
+// @Param request body model.SavedView true "The saved view"
+// @Success 200 {object} model.SavedView
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /user/{id}/saved-views [post]
+func (e UserEndpoint) handleSavedViewPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := Base64UrlDecode(request.Path(r, "id"))
+		if userId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing id parameter"})
+			return
+		}
+
+		var view model.SavedView
+		if err := request.BodyJson(r, &view); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(view); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		created, err := e.userService.CreateSavedView(r.Context(),
+			model.NewDomainSavedView(domain.UserIdentifier(userId), &view))
+		if err != nil {
+			respondUserError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewSavedView(created))
+	}
+}
+
+// handleSavedViewPut returns a gorm Handler function.
+//
+// @ID users_handleSavedViewPut
+// @Tags Users
+// @Summary Update a saved view of the given user.
+// @Produce json
+// @Param id path string true "The user identifier"
+// @Param viewId path int true "The saved view identifier"
+// @Param request body model.SavedView true "The saved view"
+// @Success 200 {object} model.SavedView
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /user/{id}/saved-views/{viewId} [put]
+func (e UserEndpoint) handleSavedViewPut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := Base64UrlDecode(request.Path(r, "id"))
+		if userId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing id parameter"})
+			return
+		}
+		viewId, err := strconv.ParseUint(request.Path(r, "viewId"), 10, 64)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "invalid saved view id"})
+			return
+		}
+
+		var view model.SavedView
+		if err := request.BodyJson(r, &view); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(view); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if view.Id != viewId {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "saved view id mismatch"})
+			return
+		}
+
+		updated, err := e.userService.UpdateSavedView(r.Context(),
+			model.NewDomainSavedView(domain.UserIdentifier(userId), &view))
+		if err != nil {
+			respondUserError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewSavedView(updated))
+	}
+}
+
+// handleSavedViewDelete returns a gorm Handler function.
+//
+// @ID users_handleSavedViewDelete
+// @Tags Users
+// @Summary Delete a saved view of the given user.
+// @Produce json
+// @Param id path string true "The user identifier"
+// @Param viewId path int true "The saved view identifier"
+// @Success 204 "No content if deletion was successful"
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /user/{id}/saved-views/{viewId} [delete]
+func (e UserEndpoint) handleSavedViewDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := Base64UrlDecode(request.Path(r, "id"))
+		if userId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing id parameter"})
+			return
+		}
+		viewId, err := strconv.ParseUint(request.Path(r, "viewId"), 10, 64)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "invalid saved view id"})
+			return
+		}
+
+		if err := e.userService.DeleteSavedView(r.Context(), domain.UserIdentifier(userId), viewId); err != nil {
+			respondUserError(w, err)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
+func respondUserError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
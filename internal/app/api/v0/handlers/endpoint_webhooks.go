@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// webhookEventPageSize is the default number of events that are returned by the event log endpoint.
+const webhookEventPageSize = 50
+
+type WebhookService interface {
+	// GetEndpoints returns the delivery state of all configured webhook endpoints.
+	GetEndpoints(ctx context.Context) ([]domain.WebhookEndpointState, error)
+	// GetEvents returns the events of the event log with an id greater than the given id, ordered by id.
+	GetEvents(ctx context.Context, afterId uint64, limit int) ([]domain.WebhookEventLogEntry, error)
+	// GetEvent returns the event with the given id, together with its delivery records.
+	GetEvent(ctx context.Context, id uint64) (*domain.WebhookEventLogEntry, error)
+	// SetCursor moves the cursor of the given endpoint, all events after the given event are delivered again.
+	SetCursor(ctx context.Context, endpoint string, eventId uint64) (*domain.WebhookCursor, error)
+}
+
+type WebhookEndpoint struct {
+	cfg            *config.Config
+	webhookService WebhookService
+	authenticator  Authenticator
+	validator      Validator
+}
+
+func NewWebhookEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	webhookService WebhookService,
+) WebhookEndpoint {
+	return WebhookEndpoint{
+		cfg:            cfg,
+		webhookService: webhookService,
+		authenticator:  authenticator,
+		validator:      validator,
+	}
+}
+
+func (e WebhookEndpoint) GetName() string {
+	return "WebhookEndpoint"
+}
+
+func (e WebhookEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/webhook")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /endpoints", e.handleEndpointsGet())
+	apiGroup.HandleFunc("PUT /endpoint/{name}/cursor", e.handleCursorPut())
+	apiGroup.HandleFunc("GET /events", e.handleEventsGet())
+	apiGroup.HandleFunc("GET /event/{id}", e.handleEventGet())
+}
+
+// handleEndpointsGet returns a gorm Handler function.
+//
+// @ID webhooks_handleEndpointsGet
+// @Tags Webhooks
+// @Summary Get the delivery state of all configured webhook endpoints.
+// @Produce json
+// @Success 200 {object} []model.WebhookEndpoint
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /webhook/endpoints [get]
+func (e WebhookEndpoint) handleEndpointsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoints, err := e.webhookService.GetEndpoints(r.Context())
+		if err != nil {
+			respondWebhookError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewWebhookEndpoints(endpoints))
+	}
+}
+
+// handleCursorPut returns a gorm Handler function.
+//
+// @ID webhooks_handleCursorPut
+// @Tags Webhooks
+// @Summary Move the cursor of a webhook endpoint. All events after the cursor are delivered to the endpoint again.
+// @Param name path string true "The endpoint name"
+// @Param request body model.WebhookCursor true "The new cursor position"
+// @Produce json
+// @Success 200 {object} model.WebhookEndpoint
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /webhook/endpoint/{name}/cursor [put]
+func (e WebhookEndpoint) handleCursorPut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := request.Path(r, "name")
+		if name == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing endpoint name"})
+			return
+		}
+
+		var cursor model.WebhookCursor
+		if err := request.BodyJson(r, &cursor); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(cursor); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		if _, err := e.webhookService.SetCursor(r.Context(), name, cursor.EventId); err != nil {
+			respondWebhookError(w, err)
+			return
+		}
+
+		endpoints, err := e.webhookService.GetEndpoints(r.Context())
+		if err != nil {
+			respondWebhookError(w, err)
+			return
+		}
+		for _, endpoint := range endpoints {
+			if endpoint.Name == name {
+				respond.JSON(w, http.StatusOK, model.NewWebhookEndpoint(endpoint))
+				return
+			}
+		}
+
+		respondWebhookError(w, domain.ErrNotFound)
+	}
+}
+
+// handleEventsGet returns a gorm Handler function.
+//
+// @ID webhooks_handleEventsGet
+// @Tags Webhooks
+// @Summary Get the webhook event log, ordered by event id, together with the delivery attempts of each event.
+// @Param after query int false "Only return events with a greater id"
+// @Param limit query int false "The maximum number of events, defaults to 50"
+// @Produce json
+// @Success 200 {object} []model.WebhookEvent
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /webhook/events [get]
+func (e WebhookEndpoint) handleEventsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		afterId, err := strconv.ParseUint(request.QueryDefault(r, "after", "0"), 10, 64)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "invalid event id"})
+			return
+		}
+		limit, err := strconv.Atoi(request.QueryDefault(r, "limit", strconv.Itoa(webhookEventPageSize)))
+		if err != nil || limit <= 0 {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "invalid limit"})
+			return
+		}
+
+		events, err := e.webhookService.GetEvents(r.Context(), afterId, limit)
+		if err != nil {
+			respondWebhookError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewWebhookEvents(events))
+	}
+}
+
+// handleEventGet returns a gorm Handler function.
+//
+// @ID webhooks_handleEventGet
+// @Tags Webhooks
+// @Summary Get a single webhook event together with its delivery attempts.
+// @Param id path int true "The event id"
+// @Produce json
+// @Success 200 {object} model.WebhookEvent
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /webhook/event/{id} [get]
+func (e WebhookEndpoint) handleEventGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(request.Path(r, "id"), 10, 64)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "invalid event id"})
+			return
+		}
+
+		event, err := e.webhookService.GetEvent(r.Context(), id)
+		if err != nil {
+			respondWebhookError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewWebhookEvent(*event))
+	}
+}
+
+func respondWebhookError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	}
+
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
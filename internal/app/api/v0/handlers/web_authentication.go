@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/h44z/wg-portal/internal/app/api/core/request"
 	"github.com/h44z/wg-portal/internal/app/api/core/respond"
@@ -13,11 +14,18 @@ import (
 type Scope string
 
 const (
-	ScopeAdmin Scope = "ADMIN" // Admin scope contains all other scopes
+	ScopeAdmin          Scope = "ADMIN"           // Admin scope contains all other scopes
+	ScopeInterfaceAdmin Scope = "INTERFACE_ADMIN" // Interface admin scope, the interface is validated by the services
+	ScopeHelpdesk       Scope = "HELPDESK"        // Helpdesk scope, the permitted actions are validated by the services
 )
 
 type UserAuthenticator interface {
 	IsUserValid(ctx context.Context, id domain.UserIdentifier) bool
+	StopImpersonation(
+		ctx context.Context,
+		impersonatorId, id domain.UserIdentifier,
+		reason string,
+	) (*domain.User, error)
 }
 
 type AuthenticationHandler struct {
@@ -60,10 +68,25 @@ func (h AuthenticationHandler) LoggedIn(scopes ...Scope) func(next http.Handler)
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), domain.CtxUserInfo, &domain.ContextUserInfo{
-				Id:      domain.UserIdentifier(session.UserIdentifier),
-				IsAdmin: session.IsAdmin,
-			})
+			// End the impersonation if the timeout is exceeded, the administrator session is restored
+			if session.ImpersonationExpired() {
+				impersonator, err := h.authenticator.StopImpersonation(r.Context(),
+					domain.UserIdentifier(session.ImpersonatorIdentifier),
+					domain.UserIdentifier(session.UserIdentifier), "expired")
+				if err != nil {
+					h.session.DestroyData(r.Context())
+					respond.JSON(w, http.StatusUnauthorized,
+						model.Error{Code: http.StatusUnauthorized, Message: "session no longer available"})
+					return
+				}
+
+				h.session.SetData(r.Context(), restoreImpersonatorSession(session, impersonator))
+				respond.JSON(w, http.StatusUnauthorized,
+					model.Error{Code: http.StatusUnauthorized, Message: "impersonation expired"})
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), domain.CtxUserInfo, session.UserInfo())
 			r = r.WithContext(ctx)
 
 			// Continue down the chain to Handler etc
@@ -84,10 +107,7 @@ func (h AuthenticationHandler) InfoOnly() func(next http.Handler) http.Handler {
 			if !session.LoggedIn {
 				newContext = domain.SetUserInfo(r.Context(), domain.DefaultContextUserInfo())
 			} else {
-				newContext = domain.SetUserInfo(r.Context(), &domain.ContextUserInfo{
-					Id:      domain.UserIdentifier(session.UserIdentifier),
-					IsAdmin: session.IsAdmin,
-				})
+				newContext = domain.SetUserInfo(r.Context(), session.UserInfo())
 			}
 
 			r = r.WithContext(newContext)
@@ -99,7 +119,8 @@ func (h AuthenticationHandler) InfoOnly() func(next http.Handler) http.Handler {
 }
 
 // UserIdMatch checks if the user id in the session matches the user id in the request. If not, the request is aborted.
-func (h AuthenticationHandler) UserIdMatch(idParameter string) func(next http.Handler) http.Handler {
+// If scopes are given, users with these scopes can access the data of other users as well.
+func (h AuthenticationHandler) UserIdMatch(idParameter string, scopes ...Scope) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			session := h.session.GetData(r.Context())
@@ -109,6 +130,11 @@ func (h AuthenticationHandler) UserIdMatch(idParameter string) func(next http.Ha
 				return
 			}
 
+			if len(scopes) > 0 && UserHasScopes(session, scopes...) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			sessionUserId := domain.UserIdentifier(session.UserIdentifier)
 			requestUserId := domain.UserIdentifier(Base64UrlDecode(request.Path(r, idParameter)))
 
@@ -125,6 +151,38 @@ func (h AuthenticationHandler) UserIdMatch(idParameter string) func(next http.Ha
 	}
 }
 
+// NoImpersonation aborts the request if the session user is currently impersonated by an administrator.
+// This protects credentials and security settings of the impersonated user.
+func (h AuthenticationHandler) NoImpersonation() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session := h.session.GetData(r.Context())
+
+			if session.IsImpersonated() {
+				// Abort the request with the appropriate error code
+				respond.JSON(w, http.StatusForbidden,
+					model.Error{Code: http.StatusForbidden, Message: "not allowed while impersonating"})
+				return
+			}
+
+			// Continue down the chain to Handler etc
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// restoreImpersonatorSession returns session data that switches the impersonated session back to the
+// impersonating administrator. The CSRF token is preserved.
+func restoreImpersonatorSession(session SessionData, impersonator *domain.User) SessionData {
+	session.LoggedIn = true
+	session.setUser(impersonator)
+
+	session.ImpersonatorIdentifier = ""
+	session.ImpersonationExpiresAt = time.Time{}
+
+	return session
+}
+
 func UserHasScopes(session SessionData, scopes ...Scope) bool {
 	// No scopes give, so the check should succeed
 	if len(scopes) == 0 {
@@ -141,6 +199,12 @@ func UserHasScopes(session SessionData, scopes ...Scope) bool {
 		if scope == ScopeAdmin {
 			return false
 		}
+		if scope == ScopeInterfaceAdmin && len(session.AdminInterfaces) == 0 {
+			return false
+		}
+		if scope == ScopeHelpdesk && !session.IsHelpdesk {
+			return false
+		}
 	}
 
 	// For all other scopes, a logged-in user is sufficient (for now)
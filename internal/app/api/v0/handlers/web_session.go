@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/gob"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/alexedwards/scs/v2"
 
 	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
 )
 
 func init() {
@@ -17,8 +19,15 @@ func init() {
 }
 
 type SessionData struct {
-	LoggedIn bool
-	IsAdmin  bool
+	LoggedIn   bool
+	IsAdmin    bool
+	IsHelpdesk bool
+
+	// AdminInterfaces contains the interfaces the user administrates without global admin rights.
+	AdminInterfaces []string
+
+	// Organization restricts the admin rights of the user to the resources of this organization.
+	Organization string
 
 	UserIdentifier string
 
@@ -31,9 +40,77 @@ type SessionData struct {
 	OauthProvider string
 	OauthReturnTo string
 
+	// OidcProvider, OidcSubject and OidcSessionId identify the session at the OpenID Connect provider that was used
+	// for the login. They are used to end the session on a back-channel logout.
+	OidcProvider  string
+	OidcSubject   string
+	OidcSessionId string
+
+	// ProxyUser is the user identifier passed by the authentication proxy if the session was started by the proxy.
+	ProxyUser string
+
 	WebAuthnData string
 
 	CsrfToken string
+
+	// AuthenticatedAt is the time of the login or the last re-authentication of the session user.
+	AuthenticatedAt time.Time
+
+	// ImpersonatorIdentifier is set if an administrator currently impersonates the session user.
+	ImpersonatorIdentifier string
+	ImpersonationExpiresAt time.Time
+}
+
+// IsImpersonated returns true if the session user is currently impersonated by an administrator.
+func (s SessionData) IsImpersonated() bool {
+	return s.ImpersonatorIdentifier != ""
+}
+
+// UserInfo returns the context user info of the session user.
+func (s SessionData) UserInfo() *domain.ContextUserInfo {
+	adminInterfaces := make([]domain.InterfaceIdentifier, len(s.AdminInterfaces))
+	for i, id := range s.AdminInterfaces {
+		adminInterfaces[i] = domain.InterfaceIdentifier(id)
+	}
+
+	return &domain.ContextUserInfo{
+		Id:              domain.UserIdentifier(s.UserIdentifier),
+		IsAdmin:         s.IsAdmin,
+		IsHelpdesk:      s.IsHelpdesk,
+		AdminInterfaces: adminInterfaces,
+		Organization:    domain.OrganizationIdentifier(s.Organization),
+		ImpersonatedBy:  domain.UserIdentifier(s.ImpersonatorIdentifier),
+		AuthenticatedAt: s.AuthenticatedAt,
+	}
+}
+
+// OidcSession returns the OpenID Connect provider session of the login. The provider is empty for other logins.
+func (s SessionData) OidcSession() domain.OidcSession {
+	return domain.OidcSession{
+		Provider:  s.OidcProvider,
+		Subject:   s.OidcSubject,
+		SessionId: s.OidcSessionId,
+	}
+}
+
+// setUser sets the user related session fields.
+func (s *SessionData) setUser(user *domain.User) {
+	s.IsAdmin = user.IsAdmin
+	s.IsHelpdesk = user.IsHelpdesk
+	s.AdminInterfaces = make([]string, 0, len(user.AdminInterfaces()))
+	for _, id := range user.AdminInterfaces() {
+		s.AdminInterfaces = append(s.AdminInterfaces, string(id))
+	}
+	s.Organization = string(user.OrganizationIdentifier)
+	s.UserIdentifier = string(user.Identifier)
+	s.Firstname = user.Firstname
+	s.Lastname = user.Lastname
+	s.Email = user.Email
+}
+
+// ImpersonationExpired returns true if the session is impersonated and the impersonation timeout is exceeded.
+func (s SessionData) ImpersonationExpired() bool {
+	return s.IsImpersonated() && time.Now().After(s.ImpersonationExpiresAt)
 }
 
 const sessionApiV0Key = "session_api_v0"
@@ -44,7 +121,7 @@ type SessionWrapper struct {
 
 func NewSessionWrapper(cfg *config.Config) *SessionWrapper {
 	sessionManager := scs.New()
-	sessionManager.Lifetime = 24 * time.Hour
+	sessionManager.Lifetime = cfg.DataRetention.SessionLifetime
 	sessionManager.Cookie.Name = cfg.Web.SessionIdentifier
 	sessionManager.Cookie.Secure = strings.HasPrefix(cfg.Web.ExternalUrl, "https")
 	sessionManager.Cookie.HttpOnly = true
@@ -73,6 +150,30 @@ func (s *SessionWrapper) DestroyData(ctx context.Context) {
 	_ = s.SessionManager.Destroy(ctx)
 }
 
+// DestroyMatching destroys all sessions for which the match function returns true. It returns the number of
+// destroyed sessions.
+func (s *SessionWrapper) DestroyMatching(ctx context.Context, match func(SessionData) bool) (int, error) {
+	destroyed := 0
+	err := s.SessionManager.Iterate(ctx, func(ctx context.Context) error {
+		sessionData, ok := s.SessionManager.Get(ctx, sessionApiV0Key).(SessionData)
+		if !ok || !match(sessionData) {
+			return nil
+		}
+
+		if err := s.SessionManager.Destroy(ctx); err != nil {
+			return err
+		}
+		destroyed++
+
+		return nil
+	})
+	if err != nil {
+		return destroyed, fmt.Errorf("failed to destroy sessions: %w", err)
+	}
+
+	return destroyed, nil
+}
+
 func (s *SessionWrapper) defaultSessionData() SessionData {
 	return SessionData{
 		LoggedIn:       false,
@@ -12,4 +12,27 @@ type Settings struct {
 	ApiAdminOnly              bool `json:"ApiAdminOnly"`
 	WebAuthnEnabled           bool `json:"WebAuthnEnabled"`
 	MinPasswordLength         int  `json:"MinPasswordLength"`
+	ConfigPullEnabled         bool `json:"ConfigPullEnabled"`
+	StatusPageEnabled         bool `json:"StatusPageEnabled"`
+	PeerTransferEnabled       bool `json:"PeerTransferEnabled"`
+	SshDeploymentEnabled      bool `json:"SshDeploymentEnabled"`
+	TelegramNotifications     bool `json:"TelegramNotifications"`
+	AcceptableUseEnabled      bool `json:"AcceptableUseEnabled"`
+	KeyRevealStepUp           bool `json:"KeyRevealStepUp"`
+	KeyRotationSelfService    bool `json:"KeyRotationSelfService"`
+	KeyRotationCooldown       int  `json:"KeyRotationCooldown"` // in seconds
+	KeyRotationMailConfig     bool `json:"KeyRotationMailConfig"`
+	ExportApprovalEnabled     bool `json:"ExportApprovalEnabled"`
+	PortForwardingEnabled     bool `json:"PortForwardingEnabled"`
+	PortForwardingSelfService bool `json:"PortForwardingSelfService"`
+	PacketCaptureEnabled      bool `json:"PacketCaptureEnabled"`
+	PacketCaptureMaxDuration  int  `json:"PacketCaptureMaxDuration"` // in seconds
+	LiveStatsMaxDuration      int  `json:"LiveStatsMaxDuration"`     // in seconds, 0 if live sampling is disabled
+	AccessRequestsEnabled     bool `json:"AccessRequestsEnabled"`
+	AccessRequestMaxDuration  int  `json:"AccessRequestMaxDuration"` // in seconds
+	RouteReviewEnabled        bool `json:"RouteReviewEnabled"`
+
+	// Organization branding, only set for users of an organization with custom branding
+	SiteTitle       string `json:"SiteTitle,omitempty"`
+	SiteCompanyName string `json:"SiteCompanyName,omitempty"`
 }
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AcceptableUseStatus struct {
+	Version string `json:"Version"`
+	Title   string `json:"Title"`
+	Text    string `json:"Text"`
+
+	Accepted        bool       `json:"Accepted"`        // true if the current version was accepted
+	AcceptedVersion string     `json:"AcceptedVersion"` // the most recently accepted version, empty if never accepted
+	AcceptedAt      *time.Time `json:"AcceptedAt"`
+}
+
+func NewAcceptableUseStatus(src *domain.AcceptableUseStatus) *AcceptableUseStatus {
+	status := &AcceptableUseStatus{
+		Version:  src.Policy.Version,
+		Title:    src.Policy.Title,
+		Text:     src.Policy.Text,
+		Accepted: src.IsAccepted(),
+	}
+	if src.LastAcceptance != nil {
+		status.AcceptedVersion = src.LastAcceptance.Version
+		status.AcceptedAt = &src.LastAcceptance.AcceptedAt
+	}
+
+	return status
+}
+
+type AcceptableUseAcceptance struct {
+	UserIdentifier string    `json:"UserIdentifier"`
+	Version        string    `json:"Version"`
+	AcceptedAt     time.Time `json:"AcceptedAt"`
+}
+
+func NewAcceptableUseAcceptance(src *domain.AcceptableUseAcceptance) *AcceptableUseAcceptance {
+	return &AcceptableUseAcceptance{
+		UserIdentifier: string(src.UserIdentifier),
+		Version:        src.Version,
+		AcceptedAt:     src.AcceptedAt,
+	}
+}
+
+func NewAcceptableUseAcceptances(src []domain.AcceptableUseAcceptance) []AcceptableUseAcceptance {
+	results := make([]AcceptableUseAcceptance, len(src))
+	for i := range src {
+		results[i] = *NewAcceptableUseAcceptance(&src[i])
+	}
+
+	return results
+}
+
+type AcceptableUseAcceptRequest struct {
+	Version string `json:"Version" binding:"required"` // the version of the policy that was shown to the user
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AccessRequest struct {
+	Identifier  string `json:"Identifier"`
+	PeerId      string `json:"PeerId"`
+	PeerName    string `json:"PeerName"`
+	InterfaceId string `json:"InterfaceId"`
+	UserId      string `json:"UserId"` // the owner of the peer
+	RouteSetId  string `json:"RouteSetId"`
+	Reason      string `json:"Reason"`
+	Duration    int    `json:"Duration"` // the length of the access window in seconds
+
+	State       string    `json:"State"` // pending, active, ended, rejected, cancelled or expired
+	RequestedBy string    `json:"RequestedBy"`
+	RequestedAt time.Time `json:"RequestedAt"`
+	ExpiresAt   time.Time `json:"ExpiresAt"`
+
+	ApprovedBy string     `json:"ApprovedBy"`
+	ApprovedAt *time.Time `json:"ApprovedAt"`
+	EndsAt     *time.Time `json:"EndsAt"`
+	ResolvedBy string     `json:"ResolvedBy"`
+	ResolvedAt *time.Time `json:"ResolvedAt"`
+}
+
+func NewAccessRequest(src *domain.AccessRequest) *AccessRequest {
+	return &AccessRequest{
+		Identifier:  string(src.Identifier),
+		PeerId:      string(src.PeerId),
+		PeerName:    src.PeerName,
+		InterfaceId: string(src.InterfaceIdentifier),
+		UserId:      string(src.UserIdentifier),
+		RouteSetId:  string(src.RouteSetId),
+		Reason:      src.Reason,
+		Duration:    int(src.Duration.Seconds()),
+		State:       string(src.State),
+		RequestedBy: string(src.RequestedBy),
+		RequestedAt: src.RequestedAt,
+		ExpiresAt:   src.ExpiresAt,
+		ApprovedBy:  string(src.ApprovedBy),
+		ApprovedAt:  src.ApprovedAt,
+		EndsAt:      src.EndsAt,
+		ResolvedBy:  string(src.ResolvedBy),
+		ResolvedAt:  src.ResolvedAt,
+	}
+}
+
+func NewAccessRequests(src []domain.AccessRequest) []AccessRequest {
+	results := make([]AccessRequest, len(src))
+	for i := range src {
+		results[i] = *NewAccessRequest(&src[i])
+	}
+
+	return results
+}
+
+type AccessRequestRequest struct {
+	PeerId     string `json:"PeerId" binding:"required"`
+	RouteSetId string `json:"RouteSetId" binding:"required"`
+	Duration   int    `json:"Duration"` // in seconds, the default duration is used if not set
+	Reason     string `json:"Reason"`
+}
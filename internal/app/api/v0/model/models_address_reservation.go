@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AddressReservation struct {
+	Identifier     string    `json:"Identifier"`
+	InterfaceId    string    `json:"InterfaceId"`
+	Address        string    `json:"Address" example:"10.11.12.50"`
+	Kind           string    `json:"Kind" example:"reserved"` // reserved or excluded
+	UserIdentifier string    `json:"UserIdentifier"`          // the user a reserved address is assigned to, optional
+	Description    string    `json:"Description"`
+	CreatedBy      string    `json:"CreatedBy"`
+	CreatedAt      time.Time `json:"CreatedAt"`
+}
+
+func NewAddressReservation(src *domain.AddressReservation) *AddressReservation {
+	return &AddressReservation{
+		Identifier:     string(src.Identifier),
+		InterfaceId:    string(src.InterfaceIdentifier),
+		Address:        src.Address,
+		Kind:           string(src.Kind),
+		UserIdentifier: string(src.UserIdentifier),
+		Description:    src.Description,
+		CreatedBy:      string(src.CreatedBy),
+		CreatedAt:      src.CreatedAt,
+	}
+}
+
+func NewAddressReservations(src []domain.AddressReservation) []AddressReservation {
+	results := make([]AddressReservation, len(src))
+	for i := range src {
+		results[i] = *NewAddressReservation(&src[i])
+	}
+
+	return results
+}
+
+type AddressReservationRequest struct {
+	Address        string `json:"Address" binding:"required"`
+	Kind           string `json:"Kind" binding:"required,oneof=reserved excluded"`
+	UserIdentifier string `json:"UserIdentifier"`
+	Description    string `json:"Description"`
+}
+
+func NewDomainAddressReservation(src *AddressReservationRequest) *domain.AddressReservation {
+	return &domain.AddressReservation{
+		Address:        src.Address,
+		Kind:           domain.AddressReservationKind(src.Kind),
+		UserIdentifier: domain.UserIdentifier(src.UserIdentifier),
+		Description:    src.Description,
+	}
+}
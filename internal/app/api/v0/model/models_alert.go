@@ -0,0 +1,124 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AlertRule struct {
+	Name           string   `json:"Name" example:"critical-handshake"`
+	Description    string   `json:"Description"`
+	Condition      string   `json:"Condition" example:"no_handshake"` // no_handshake or high_latency
+	Threshold      string   `json:"Threshold" example:"10m0s"`
+	Tag            string   `json:"Tag" example:"#critical"`
+	Interfaces     []string `json:"Interfaces"`
+	Channels       []string `json:"Channels" example:"mail"`
+	RepeatInterval string   `json:"RepeatInterval" example:"1h0m0s"`
+}
+
+func NewAlertRule(src config.AlertRuleConfig) AlertRule {
+	channels := make([]string, len(src.Channels))
+	for i, channel := range src.Channels {
+		channels[i] = string(channel)
+	}
+
+	return AlertRule{
+		Name:           src.Name,
+		Description:    src.Description,
+		Condition:      string(src.Condition),
+		Threshold:      src.Threshold.String(),
+		Tag:            src.Tag,
+		Interfaces:     src.Interfaces,
+		Channels:       channels,
+		RepeatInterval: src.RepeatInterval.String(),
+	}
+}
+
+func NewAlertRules(src []config.AlertRuleConfig) []AlertRule {
+	results := make([]AlertRule, len(src))
+	for i := range src {
+		results[i] = NewAlertRule(src[i])
+	}
+
+	return results
+}
+
+type Alert struct {
+	RuleName            string     `json:"RuleName" example:"critical-handshake"`
+	PeerIdentifier      string     `json:"PeerIdentifier"`
+	PeerName            string     `json:"PeerName"`
+	InterfaceIdentifier string     `json:"InterfaceIdentifier" example:"wg0"`
+	Message             string     `json:"Message"`
+	FiredAt             time.Time  `json:"FiredAt"`
+	LastNotifiedAt      *time.Time `json:"LastNotifiedAt"`
+	Acknowledged        bool       `json:"Acknowledged"`
+	AcknowledgedAt      *time.Time `json:"AcknowledgedAt"`
+	AcknowledgedBy      string     `json:"AcknowledgedBy"`
+}
+
+func NewAlert(src domain.Alert) Alert {
+	return Alert{
+		RuleName:            src.RuleName,
+		PeerIdentifier:      string(src.PeerId),
+		PeerName:            src.PeerName,
+		InterfaceIdentifier: string(src.InterfaceId),
+		Message:             src.Message,
+		FiredAt:             src.FiredAt,
+		LastNotifiedAt:      src.LastNotifiedAt,
+		Acknowledged:        src.IsAcknowledged(),
+		AcknowledgedAt:      src.AcknowledgedAt,
+		AcknowledgedBy:      string(src.AcknowledgedBy),
+	}
+}
+
+func NewAlerts(src []domain.Alert) []Alert {
+	results := make([]Alert, len(src))
+	for i := range src {
+		results[i] = NewAlert(src[i])
+	}
+
+	return results
+}
+
+type AlertSilence struct {
+	Id             uint64    `json:"Id"`
+	RuleName       string    `json:"RuleName"`       // the silenced rule, empty for all rules
+	PeerIdentifier string    `json:"PeerIdentifier"` // the silenced peer, empty for all peers
+	StartsAt       time.Time `json:"StartsAt" binding:"required"`
+	EndsAt         time.Time `json:"EndsAt" binding:"required"`
+	Comment        string    `json:"Comment"`
+	CreatedBy      string    `json:"CreatedBy"`
+}
+
+func NewAlertSilence(src domain.AlertSilence) AlertSilence {
+	return AlertSilence{
+		Id:             src.Id,
+		RuleName:       src.RuleName,
+		PeerIdentifier: string(src.PeerId),
+		StartsAt:       src.StartsAt,
+		EndsAt:         src.EndsAt,
+		Comment:        src.Comment,
+		CreatedBy:      string(src.CreatedBy),
+	}
+}
+
+func NewAlertSilences(src []domain.AlertSilence) []AlertSilence {
+	results := make([]AlertSilence, len(src))
+	for i := range src {
+		results[i] = NewAlertSilence(src[i])
+	}
+
+	return results
+}
+
+func NewDomainAlertSilence(src *AlertSilence) *domain.AlertSilence {
+	return &domain.AlertSilence{
+		RuleName: src.RuleName,
+		PeerId:   domain.PeerIdentifier(src.PeerIdentifier),
+		StartsAt: src.StartsAt,
+		EndsAt:   src.EndsAt,
+		Comment:  src.Comment,
+	}
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AllowedIPConflict struct {
+	PeerId        string `json:"PeerId"`
+	PeerName      string `json:"PeerName"`
+	Network       string `json:"Network"` // the allowed IP of the peer
+	OtherPeerId   string `json:"OtherPeerId"`
+	OtherPeerName string `json:"OtherPeerName"`
+	OtherNetwork  string `json:"OtherNetwork"` // the overlapping allowed IP of the other peer
+}
+
+type AllowedIPConflictReport struct {
+	InterfaceIdentifier string              `json:"InterfaceIdentifier"`
+	CheckedAt           time.Time           `json:"CheckedAt"`
+	Conflicts           []AllowedIPConflict `json:"Conflicts"`
+}
+
+func NewAllowedIPConflictReport(src *domain.AllowedIPConflictReport) *AllowedIPConflictReport {
+	conflicts := make([]AllowedIPConflict, len(src.Conflicts))
+	for i, conflict := range src.Conflicts {
+		conflicts[i] = AllowedIPConflict{
+			PeerId:        string(conflict.PeerId),
+			PeerName:      conflict.PeerName,
+			Network:       conflict.Network.String(),
+			OtherPeerId:   string(conflict.OtherPeerId),
+			OtherPeerName: conflict.OtherPeerName,
+			OtherNetwork:  conflict.OtherNetwork.String(),
+		}
+	}
+
+	return &AllowedIPConflictReport{
+		InterfaceIdentifier: string(src.InterfaceIdentifier),
+		CheckedAt:           src.CheckedAt,
+		Conflicts:           conflicts,
+	}
+}
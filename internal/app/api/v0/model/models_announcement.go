@@ -0,0 +1,68 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type Announcement struct {
+	Id           uint64     `json:"Id"`
+	Kind         string     `json:"Kind" binding:"required,oneof=info maintenance policy" example:"maintenance"`
+	Title        string     `json:"Title" binding:"required"`
+	Message      string     `json:"Message"`
+	StartsAt     time.Time  `json:"StartsAt" binding:"required"` // the announcement is shown from this time on
+	EndsAt       *time.Time `json:"EndsAt"`                      // the announcement is hidden after this time, empty to show it until it is deleted
+	TargetGroups []string   `json:"TargetGroups"`                // the targeted user groups, empty for all users
+	SendMail     bool       `json:"SendMail"`                    // send the announcement by mail to the targeted users once it starts
+	MailedAt     *time.Time `json:"MailedAt"`
+	CreatedBy    string     `json:"CreatedBy"`
+	CreatedAt    time.Time  `json:"CreatedAt"`
+
+	Read      bool `json:"Read"`      // true if the current user marked the announcement as read
+	ReadCount int  `json:"ReadCount"` // the number of users that read the announcement, only set for administrators
+}
+
+// NewAnnouncement creates a REST API Announcement from a domain AnnouncementStatus.
+func NewAnnouncement(src *domain.AnnouncementStatus) *Announcement {
+	return &Announcement{
+		Id:           src.Id,
+		Kind:         string(src.Kind),
+		Title:        src.Title,
+		Message:      src.Message,
+		StartsAt:     src.StartsAt,
+		EndsAt:       src.EndsAt,
+		TargetGroups: internal.SliceString(src.TargetGroupsStr),
+		SendMail:     src.SendMail,
+		MailedAt:     src.MailedAt,
+		CreatedBy:    string(src.CreatedBy),
+		CreatedAt:    src.CreatedAt,
+		Read:         src.Read,
+		ReadCount:    src.ReadCount,
+	}
+}
+
+// NewAnnouncements creates a slice of REST API Announcements from a slice of domain AnnouncementStatus.
+func NewAnnouncements(src []domain.AnnouncementStatus) []Announcement {
+	results := make([]Announcement, len(src))
+	for i := range src {
+		results[i] = *NewAnnouncement(&src[i])
+	}
+
+	return results
+}
+
+// NewDomainAnnouncement creates a domain Announcement from a REST API Announcement.
+func NewDomainAnnouncement(src *Announcement) *domain.Announcement {
+	return &domain.Announcement{
+		Id:              src.Id,
+		Kind:            domain.AnnouncementKind(src.Kind),
+		Title:           src.Title,
+		Message:         src.Message,
+		StartsAt:        src.StartsAt,
+		EndsAt:          src.EndsAt,
+		TargetGroupsStr: internal.SliceToString(src.TargetGroups),
+		SendMail:        src.SendMail,
+	}
+}
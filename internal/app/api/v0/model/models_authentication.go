@@ -3,6 +3,7 @@ package model
 import (
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/h44z/wg-portal/internal/domain"
 )
@@ -32,12 +33,20 @@ func NewLoginProviderInfos(src []domain.LoginProviderInfo) []LoginProviderInfo {
 }
 
 type SessionInfo struct {
-	LoggedIn       bool    `json:"LoggedIn"`
-	IsAdmin        bool    `json:"IsAdmin,omitempty"`
+	LoggedIn   bool `json:"LoggedIn"`
+	IsAdmin    bool `json:"IsAdmin,omitempty"`
+	IsHelpdesk bool `json:"IsHelpdesk,omitempty"`
+	// AdminInterfaces contains the interfaces the user administrates without global admin rights.
+	AdminInterfaces []string `json:"AdminInterfaces,omitempty"`
+	// Organization restricts the admin rights of the user to the resources of this organization.
+	Organization   string  `json:"Organization,omitempty"`
 	UserIdentifier *string `json:"UserIdentifier,omitempty"`
 	UserFirstname  *string `json:"UserFirstname,omitempty"`
 	UserLastname   *string `json:"UserLastname,omitempty"`
 	UserEmail      *string `json:"UserEmail,omitempty"`
+
+	ImpersonatorIdentifier *string    `json:"ImpersonatorIdentifier,omitempty"`
+	ImpersonationExpiresAt *time.Time `json:"ImpersonationExpiresAt,omitempty"`
 }
 
 type OauthInitiationResponse struct {
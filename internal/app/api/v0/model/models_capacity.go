@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AddressPoolCapacity struct {
+	InterfaceIdentifier string     `json:"InterfaceIdentifier"`
+	Network             string     `json:"Network"`
+	Size                uint64     `json:"Size"` // large IPv6 networks are capped at the maximum uint64 value
+	Used                uint64     `json:"Used"`
+	Free                uint64     `json:"Free"`
+	Utilization         float64    `json:"Utilization"`  // in percent
+	GrowthPerDay        float64    `json:"GrowthPerDay"` // assigned addresses per day within the growth window
+	ExhaustedAt         *time.Time `json:"ExhaustedAt"`  // null if the pool does not grow
+}
+
+func NewAddressPoolCapacity(src domain.AddressPoolCapacity) AddressPoolCapacity {
+	return AddressPoolCapacity{
+		InterfaceIdentifier: string(src.InterfaceId),
+		Network:             src.Network.String(),
+		Size:                src.Size,
+		Used:                src.Used,
+		Free:                src.Free(),
+		Utilization:         src.Utilization(),
+		GrowthPerDay:        src.GrowthPerDay,
+		ExhaustedAt:         src.ExhaustedAt,
+	}
+}
+
+func NewAddressPoolCapacities(src []domain.AddressPoolCapacity) []AddressPoolCapacity {
+	results := make([]AddressPoolCapacity, len(src))
+	for i := range src {
+		results[i] = NewAddressPoolCapacity(src[i])
+	}
+
+	return results
+}
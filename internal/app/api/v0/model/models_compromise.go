@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type PeerCompromise struct {
+	PeerIdentifier      string `json:"PeerIdentifier"`
+	PeerName            string `json:"PeerName"`
+	InterfaceIdentifier string `json:"InterfaceIdentifier"`
+	UserIdentifier      string `json:"UserIdentifier"`
+	Reason              string `json:"Reason"`
+
+	ReportedBy string    `json:"ReportedBy"`
+	ReportedAt time.Time `json:"ReportedAt"`
+
+	RotatedPeers []string `json:"RotatedPeers"` // the peers of the owner that got a new preshared key
+}
+
+func NewPeerCompromise(src *domain.PeerCompromise) *PeerCompromise {
+	rotated := make([]string, len(src.RotatedPeers))
+	for i, peerId := range src.RotatedPeers {
+		rotated[i] = string(peerId)
+	}
+
+	return &PeerCompromise{
+		PeerIdentifier:      string(src.PeerIdentifier),
+		PeerName:            src.PeerName,
+		InterfaceIdentifier: string(src.InterfaceIdentifier),
+		UserIdentifier:      string(src.UserIdentifier),
+		Reason:              src.Reason,
+		ReportedBy:          string(src.ReportedBy),
+		ReportedAt:          src.ReportedAt,
+		RotatedPeers:        rotated,
+	}
+}
+
+type PeerCompromiseRequest struct {
+	Reason string `json:"Reason"`
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type ConfigPullToken struct {
+	Active         bool       `json:"Active"`  // false if no token exists for the peer
+	Token          string     `json:"Token"`   // the plain text token, only set once after creation
+	PullUrl        string     `json:"PullUrl"` // the URL of the config pull endpoint
+	CreatedAt      *time.Time `json:"CreatedAt"`
+	CreatedBy      string     `json:"CreatedBy"`
+	LastPulledAt   *time.Time `json:"LastPulledAt"`
+	LastPulledFrom string     `json:"LastPulledFrom"`
+	ClientOs       string     `json:"ClientOs"`      // the operating system reported by the client
+	ClientVersion  string     `json:"ClientVersion"` // the app version reported by the client
+
+	// ProvisioningQrCode is a PNG data URL of a QR code that contains the pull URL and the plain text token,
+	// only set once after creation
+	ProvisioningQrCode string `json:"ProvisioningQrCode,omitempty"`
+}
+
+func NewConfigPullToken(src *domain.ConfigPullToken, plainToken, pullUrl string) *ConfigPullToken {
+	if src == nil {
+		return &ConfigPullToken{PullUrl: pullUrl}
+	}
+
+	return &ConfigPullToken{
+		Active:         true,
+		Token:          plainToken,
+		PullUrl:        pullUrl,
+		CreatedAt:      &src.CreatedAt,
+		CreatedBy:      src.CreatedBy,
+		LastPulledAt:   src.LastPulledAt,
+		LastPulledFrom: src.LastPulledFrom,
+		ClientOs:       src.ClientOs,
+		ClientVersion:  src.ClientVersion,
+	}
+}
+
+type ClientUpdateCampaign struct {
+	MinVersions map[string]string `json:"MinVersions"` // the minimum app version per operating system
+	DryRun      bool              `json:"DryRun"`      // if true, the outdated clients are listed but not notified
+}
+
+func NewDomainClientUpdateCampaign(src *ClientUpdateCampaign) domain.ClientUpdateCampaign {
+	return domain.ClientUpdateCampaign{
+		MinVersions: src.MinVersions,
+		DryRun:      src.DryRun,
+	}
+}
+
+type OutdatedClient struct {
+	PeerId           string    `json:"PeerId"`
+	PeerName         string    `json:"PeerName"`
+	UserIdentifier   string    `json:"UserIdentifier"`
+	InterfaceId      string    `json:"InterfaceId"`
+	Os               string    `json:"Os"`
+	Version          string    `json:"Version"`
+	MinVersion       string    `json:"MinVersion"`
+	LastPulledAt     time.Time `json:"LastPulledAt"`
+	NotificationSent bool      `json:"NotificationSent"`
+}
+
+type ClientUpdateCampaignReport struct {
+	DryRun        bool             `json:"DryRun"`
+	Clients       []OutdatedClient `json:"Clients"`
+	NotifiedUsers int              `json:"NotifiedUsers"`
+}
+
+func NewClientUpdateCampaignReport(src *domain.ClientUpdateCampaignReport, dryRun bool) *ClientUpdateCampaignReport {
+	report := &ClientUpdateCampaignReport{
+		DryRun:        dryRun,
+		Clients:       make([]OutdatedClient, len(src.Clients)),
+		NotifiedUsers: len(src.NotifiedUsers),
+	}
+	for i, client := range src.Clients {
+		report.Clients[i] = OutdatedClient{
+			PeerId:           string(client.Peer.Identifier),
+			PeerName:         client.Peer.DisplayName,
+			UserIdentifier:   string(client.Peer.UserIdentifier),
+			InterfaceId:      string(client.Peer.InterfaceIdentifier),
+			Os:               client.Os,
+			Version:          client.Version,
+			MinVersion:       client.MinVersion,
+			LastPulledAt:     client.LastPulledAt,
+			NotificationSent: slices.Contains(src.NotifiedUsers, client.Peer.UserIdentifier),
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type ConfigReload struct {
+	ReloadedAt      time.Time `json:"ReloadedAt"`
+	Applied         []string  `json:"Applied"`         // the changed config sections that were applied
+	RestartRequired []string  `json:"RestartRequired"` // the changed config sections that require a restart
+}
+
+func NewConfigReload(src *domain.ConfigReload) *ConfigReload {
+	res := &ConfigReload{
+		ReloadedAt:      src.ReloadedAt,
+		Applied:         src.Applied,
+		RestartRequired: src.RestartRequired,
+	}
+	if res.Applied == nil {
+		res.Applied = []string{}
+	}
+	if res.RestartRequired == nil {
+		res.RestartRequired = []string{}
+	}
+
+	return res
+}
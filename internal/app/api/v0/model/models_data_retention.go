@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type UserErasure struct {
+	UserIdentifier  string    `json:"UserIdentifier"`
+	ErasedAt        time.Time `json:"ErasedAt"`
+	DeletedPeers    []string  `json:"DeletedPeers"`    // peers that were deleted
+	AnonymizedPeers []string  `json:"AnonymizedPeers"` // peers that were disabled and unlinked from the user
+}
+
+func NewUserErasure(src *domain.UserErasure) *UserErasure {
+	e := &UserErasure{
+		UserIdentifier:  string(src.UserId),
+		ErasedAt:        src.ErasedAt,
+		DeletedPeers:    make([]string, len(src.DeletedPeers)),
+		AnonymizedPeers: make([]string, len(src.AnonymizedPeers)),
+	}
+	for i, id := range src.DeletedPeers {
+		e.DeletedPeers[i] = string(id)
+	}
+	for i, id := range src.AnonymizedPeers {
+		e.AnonymizedPeers[i] = string(id)
+	}
+
+	return e
+}
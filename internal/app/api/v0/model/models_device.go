@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type DeviceAuthorization struct {
+	UserCode            string    `json:"UserCode" example:"BCDF-GHJK"`
+	ClientName          string    `json:"ClientName" example:"raspberry"`
+	InterfaceIdentifier string    `json:"InterfaceIdentifier" example:"wg0"` // the interface requested by the device
+	HasPublicKey        bool      `json:"HasPublicKey"`                      // true if the device generated its own key pair
+	CreatedAt           time.Time `json:"CreatedAt"`
+	ExpiresAt           time.Time `json:"ExpiresAt"`
+	State               string    `json:"State" example:"pending"`
+	PeerIdentifier      string    `json:"PeerIdentifier"`
+}
+
+func NewDeviceAuthorization(src *domain.DeviceAuthorization) *DeviceAuthorization {
+	return &DeviceAuthorization{
+		UserCode:            src.FormattedUserCode(),
+		ClientName:          src.ClientName,
+		InterfaceIdentifier: string(src.InterfaceIdentifier),
+		HasPublicKey:        src.PublicKey != "",
+		CreatedAt:           src.CreatedAt,
+		ExpiresAt:           src.ExpiresAt,
+		State:               string(src.State),
+		PeerIdentifier:      string(src.PeerIdentifier),
+	}
+}
+
+type DeviceApproval struct {
+	// InterfaceIdentifier is the interface for a new peer. If empty, the interface requested by the device is used.
+	InterfaceIdentifier string `json:"InterfaceIdentifier" example:"wg0"`
+	// PeerIdentifier is an existing peer of the user. If empty, a new peer is created.
+	PeerIdentifier string `json:"PeerIdentifier"`
+}
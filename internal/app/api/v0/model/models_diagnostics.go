@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type PeerDiagnosis struct {
+	PeerIdentifier      string             `json:"PeerIdentifier"`
+	InterfaceIdentifier string             `json:"InterfaceIdentifier"`
+	CheckedAt           time.Time          `json:"CheckedAt"`
+	Findings            []DiagnosisFinding `json:"Findings"` // the most likely cause first
+}
+
+type DiagnosisFinding struct {
+	Check      string `json:"Check" example:"handshake"` // state, keys, allowed-ips, handshake, endpoint, mtu or clock
+	Status     string `json:"Status" example:"problem"`  // ok, warning, problem or skipped
+	Likelihood int    `json:"Likelihood" example:"70"`   // 0 to 100
+	Message    string `json:"Message"`
+	Hint       string `json:"Hint"`
+}
+
+func NewPeerDiagnosis(src *domain.PeerDiagnosis) *PeerDiagnosis {
+	res := &PeerDiagnosis{
+		PeerIdentifier:      string(src.PeerId),
+		InterfaceIdentifier: string(src.InterfaceId),
+		CheckedAt:           src.CheckedAt,
+		Findings:            make([]DiagnosisFinding, len(src.Findings)),
+	}
+	for i, finding := range src.Findings {
+		res.Findings[i] = DiagnosisFinding{
+			Check:      string(finding.Check),
+			Status:     string(finding.Status),
+			Likelihood: finding.Likelihood,
+			Message:    finding.Message,
+			Hint:       finding.Hint,
+		}
+	}
+
+	return res
+}
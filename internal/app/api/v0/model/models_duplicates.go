@@ -0,0 +1,64 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type DuplicatePeerGroup struct {
+	InterfaceIdentifier string          `json:"InterfaceIdentifier"`
+	UserIdentifier      string          `json:"UserIdentifier"`
+	Device              string          `json:"Device" example:"alice-laptop"` // derived from the peer display names
+	Os                  string          `json:"Os" example:"windows"`          // empty if no client reported it
+	SuggestedPeer       string          `json:"SuggestedPeer"`                 // the most recently used peer
+	Peers               []DuplicatePeer `json:"Peers"`
+}
+
+type DuplicatePeer struct {
+	Identifier   string    `json:"Identifier"`
+	DisplayName  string    `json:"DisplayName"`
+	Addresses    []string  `json:"Addresses"`
+	Os           string    `json:"Os"`
+	Disabled     bool      `json:"Disabled"`
+	LastActivity time.Time `json:"LastActivity"`
+	CreatedAt    time.Time `json:"CreatedAt"`
+}
+
+func NewDuplicatePeerGroup(src domain.DuplicatePeerGroup) DuplicatePeerGroup {
+	res := DuplicatePeerGroup{
+		InterfaceIdentifier: string(src.InterfaceId),
+		UserIdentifier:      string(src.UserIdentifier),
+		Device:              src.Device,
+		Os:                  src.Os,
+		SuggestedPeer:       string(src.SuggestedPeer()),
+		Peers:               make([]DuplicatePeer, len(src.Peers)),
+	}
+	for i, peer := range src.Peers {
+		res.Peers[i] = DuplicatePeer{
+			Identifier:   string(peer.Peer.Identifier),
+			DisplayName:  peer.Peer.DisplayName,
+			Addresses:    domain.CidrsToStringSlice(peer.Peer.Interface.Addresses),
+			Os:           peer.Os,
+			Disabled:     peer.Peer.IsDisabled(),
+			LastActivity: peer.LastActivity,
+			CreatedAt:    peer.Peer.CreatedAt,
+		}
+	}
+
+	return res
+}
+
+func NewDuplicatePeerGroups(src []domain.DuplicatePeerGroup) []DuplicatePeerGroup {
+	results := make([]DuplicatePeerGroup, len(src))
+	for i := range src {
+		results[i] = NewDuplicatePeerGroup(src[i])
+	}
+
+	return results
+}
+
+type DuplicateMergeRequest struct {
+	Keep    string   `json:"Keep"`    // the identifier of the peer that is kept
+	Archive []string `json:"Archive"` // the identifiers of the duplicates that are archived
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type EmergencyLockdown struct {
+	Identifier string `json:"Identifier"`
+	Target     string `json:"Target"`   // user or interface
+	TargetId   string `json:"TargetId"` // the user or interface identifier
+	Reason     string `json:"Reason"`
+
+	CreatedBy  string     `json:"CreatedBy"`
+	CreatedAt  time.Time  `json:"CreatedAt"`
+	RestoredBy string     `json:"RestoredBy"`
+	RestoredAt *time.Time `json:"RestoredAt"`
+	Active     bool       `json:"Active"` // true if the lockdown has not been restored yet
+
+	Peers     []string `json:"Peers"`     // the peers that were disabled by the lockdown
+	Interface string   `json:"Interface"` // the interface that was disabled by the lockdown
+}
+
+func NewEmergencyLockdown(src *domain.EmergencyLockdown) *EmergencyLockdown {
+	peers := make([]string, len(src.Snapshot.Peers))
+	for i, peerId := range src.Snapshot.Peers {
+		peers[i] = string(peerId)
+	}
+
+	return &EmergencyLockdown{
+		Identifier: string(src.Identifier),
+		Target:     string(src.Target),
+		TargetId:   src.TargetId,
+		Reason:     src.Reason,
+		CreatedBy:  string(src.CreatedBy),
+		CreatedAt:  src.CreatedAt,
+		RestoredBy: string(src.RestoredBy),
+		RestoredAt: src.RestoredAt,
+		Active:     src.IsActive(),
+		Peers:      peers,
+		Interface:  string(src.Snapshot.Interface),
+	}
+}
+
+func NewEmergencyLockdowns(src []domain.EmergencyLockdown) []EmergencyLockdown {
+	results := make([]EmergencyLockdown, len(src))
+	for i := range src {
+		results[i] = *NewEmergencyLockdown(&src[i])
+	}
+
+	return results
+}
+
+type EmergencyLockdownRequest struct {
+	Target   string `json:"Target" binding:"required,oneof=user interface"`
+	TargetId string `json:"TargetId" binding:"required"`
+	Reason   string `json:"Reason"`
+}
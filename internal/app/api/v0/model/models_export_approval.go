@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type ExportApproval struct {
+	Id          uint64     `json:"Id"`
+	RequestedBy string     `json:"RequestedBy"`
+	RequestedAt time.Time  `json:"RequestedAt"`
+	ExportCount int        `json:"ExportCount"` // the number of exported peers when the request was created
+	State       string     `json:"State" example:"pending"`
+	DecidedBy   string     `json:"DecidedBy"`
+	DecidedAt   *time.Time `json:"DecidedAt"`
+	ValidUntil  *time.Time `json:"ValidUntil"` // approved requests allow further exports until this time
+}
+
+// NewExportApproval creates a REST API ExportApproval from a domain ExportApproval.
+func NewExportApproval(src *domain.ExportApproval) *ExportApproval {
+	return &ExportApproval{
+		Id:          src.Id,
+		RequestedBy: string(src.RequestedBy),
+		RequestedAt: src.RequestedAt,
+		ExportCount: src.ExportCount,
+		State:       string(src.State),
+		DecidedBy:   string(src.DecidedBy),
+		DecidedAt:   src.DecidedAt,
+		ValidUntil:  src.ValidUntil,
+	}
+}
+
+// NewExportApprovals creates a slice of REST API ExportApprovals from a slice of domain ExportApprovals.
+func NewExportApprovals(src []domain.ExportApproval) []ExportApproval {
+	results := make([]ExportApproval, len(src))
+	for i := range src {
+		results[i] = *NewExportApproval(&src[i])
+	}
+
+	return results
+}
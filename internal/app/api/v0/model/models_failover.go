@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type FailoverHealth struct {
+	Node    string `json:"Node"`
+	Role    string `json:"Role"`  // primary or standby
+	State   string `json:"State"` // active or standby
+	Healthy bool   `json:"Healthy"`
+}
+
+func NewFailoverHealth(src *domain.FailoverHealth) *FailoverHealth {
+	return &FailoverHealth{
+		Node:    src.Node,
+		Role:    src.Role,
+		State:   string(src.State),
+		Healthy: src.Healthy,
+	}
+}
+
+type FailoverStatus struct {
+	FailoverHealth
+
+	PeerState            string     `json:"PeerState"` // empty if the other gateway is unreachable
+	ConsecutiveFailures  int        `json:"ConsecutiveFailures"`
+	ConsecutiveSuccesses int        `json:"ConsecutiveSuccesses"`
+	LastCheck            *time.Time `json:"LastCheck"`
+	LastTransition       *time.Time `json:"LastTransition"`
+	LastSync             *time.Time `json:"LastSync"` // null if the state was not replicated yet
+	LastError            string     `json:"LastError"`
+}
+
+func NewFailoverStatus(src *domain.FailoverStatus) *FailoverStatus {
+	optionalTime := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+
+	return &FailoverStatus{
+		FailoverHealth:       *NewFailoverHealth(&src.FailoverHealth),
+		PeerState:            string(src.PeerState),
+		ConsecutiveFailures:  src.ConsecutiveFailures,
+		ConsecutiveSuccesses: src.ConsecutiveSuccesses,
+		LastCheck:            optionalTime(src.LastCheck),
+		LastTransition:       optionalTime(src.LastTransition),
+		LastSync:             optionalTime(src.LastSync),
+		LastError:            src.LastError,
+	}
+}
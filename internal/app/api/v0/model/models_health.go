@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type HealthCheck struct {
+	Name    string `json:"Name"`
+	Healthy bool   `json:"Healthy"`
+}
+
+type HealthStatus struct {
+	Healthy   bool          `json:"Healthy"`
+	Checks    []HealthCheck `json:"Checks"`
+	CheckedAt *time.Time    `json:"CheckedAt"` // null if no check finished yet
+}
+
+// NewHealthStatus converts the health status. The error messages of failed checks are not exposed, as the
+// health endpoint requires no authentication. They are written to the log instead.
+func NewHealthStatus(src *domain.HealthStatus) *HealthStatus {
+	status := &HealthStatus{
+		Healthy: src.Healthy,
+		Checks:  make([]HealthCheck, len(src.Checks)),
+	}
+	if !src.CheckedAt.IsZero() {
+		status.CheckedAt = &src.CheckedAt
+	}
+	for i, check := range src.Checks {
+		status.Checks[i] = HealthCheck{Name: check.Name, Healthy: check.Healthy}
+	}
+
+	return status
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type ImportRequest struct {
+	Data          string            `json:"Data" binding:"required"` // the CSV data, the first row contains the column names
+	Mapping       map[string]string `json:"Mapping"`                 // maps field keys to column names
+	SendMails     bool              `json:"SendMails"`               // only used for peer imports
+	LinkOnlyMails bool              `json:"LinkOnlyMails"`
+}
+
+func NewDomainImportRequest(
+	kind domain.ImportKind,
+	interfaceId domain.InterfaceIdentifier,
+	src *ImportRequest,
+) domain.ImportRequest {
+	return domain.ImportRequest{
+		Kind:          kind,
+		InterfaceId:   interfaceId,
+		Data:          src.Data,
+		Mapping:       src.Mapping,
+		SendMails:     src.SendMails,
+		LinkOnlyMails: src.LinkOnlyMails,
+	}
+}
+
+type ImportField struct {
+	Key      string `json:"Key"`
+	Title    string `json:"Title"`
+	Required bool   `json:"Required"`
+}
+
+type ImportRow struct {
+	Line       int               `json:"Line"`
+	Values     map[string]string `json:"Values"`
+	Identifier string            `json:"Identifier"` // the identifier of the created user or peer
+	Created    bool              `json:"Created"`
+	MailSent   bool              `json:"MailSent"`
+	Errors     []string          `json:"Errors"`
+	Warnings   []string          `json:"Warnings"`
+}
+
+type ImportReport struct {
+	Columns []string          `json:"Columns"`
+	Fields  []ImportField     `json:"Fields"`
+	Mapping map[string]string `json:"Mapping"`
+	Rows    []ImportRow       `json:"Rows"`
+	Created int               `json:"Created"`
+	Failed  int               `json:"Failed"`
+}
+
+func NewImportReport(src *domain.ImportReport) *ImportReport {
+	r := &ImportReport{
+		Columns: src.Columns,
+		Fields:  make([]ImportField, len(src.Fields)),
+		Mapping: src.Mapping,
+		Rows:    make([]ImportRow, len(src.Rows)),
+		Created: src.Created,
+		Failed:  src.Failed,
+	}
+	for i, field := range src.Fields {
+		r.Fields[i] = ImportField{Key: field.Key, Title: field.Title, Required: field.Required}
+	}
+	for i, row := range src.Rows {
+		r.Rows[i] = ImportRow{
+			Line:       row.Line,
+			Values:     row.Values,
+			Identifier: row.Identifier,
+			Created:    row.Created,
+			MailSent:   row.MailSent,
+			Errors:     row.Errors,
+			Warnings:   row.Warnings,
+		}
+	}
+
+	return r
+}
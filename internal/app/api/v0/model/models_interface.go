@@ -16,6 +16,7 @@ type Interface struct {
 	Disabled       bool   `json:"Disabled"`                      // flag that specifies if the interface is enabled (up) or not (down)
 	DisabledReason string `json:"DisabledReason"`                // the reason why the interface has been disabled
 	SaveConfig     bool   `json:"SaveConfig"`                    // automatically persist config changes to the wgX.conf file
+	Organization   string `json:"Organization"`                  // the organization that owns the interface and its peers
 
 	ListenPort   int      `json:"ListenPort"`   // the listening port, for example: 51820
 	Addresses    []string `json:"Addresses"`    // the interface ip addresses
@@ -34,17 +35,29 @@ type Interface struct {
 	PeerDefDns                 []string `json:"PeerDefDns"`                 // the default dns server for the peer
 	PeerDefDnsSearch           []string `json:"PeerDefDnsSearch"`           // the default dns search options for the peer
 	PeerDefEndpoint            string   `json:"PeerDefEndpoint"`            // the default endpoint for the peer
+	PeerDefBackupEndpoints     []string `json:"PeerDefBackupEndpoints"`     // the backup endpoints for the peer
 	PeerDefAllowedIPs          []string `json:"PeerDefAllowedIPs"`          // the default allowed IP string for the peer
+	PeerDefRouteSets           []string `json:"PeerDefRouteSets"`           // the default route sets for the peer
 	PeerDefMtu                 int      `json:"PeerDefMtu"`                 // the default device MTU
 	PeerDefPersistentKeepalive int      `json:"PeerDefPersistentKeepalive"` // the default persistent keep-alive Value
 	PeerDefFirewallMark        uint32   `json:"PeerDefFirewallMark"`        // default firewall mark
 	PeerDefRoutingTable        string   `json:"PeerDefRoutingTable"`        // the default routing table
+	PeerDefUploadLimit         int      `json:"PeerDefUploadLimit"`         // the default upload limit in kbit/s
+	PeerDefDownloadLimit       int      `json:"PeerDefDownloadLimit"`       // the default download limit in kbit/s
 
 	PeerDefPreUp    string `json:"PeerDefPreUp"`    // default action that is executed before the device is up
 	PeerDefPostUp   string `json:"PeerDefPostUp"`   // default action that is executed after the device is up
 	PeerDefPreDown  string `json:"PeerDefPreDown"`  // default action that is executed before the device is down
 	PeerDefPostDown string `json:"PeerDefPostDown"` // default action that is executed after the device is down
 
+	PeerMailCc  []string `json:"PeerMailCc"`  // recipients that receive a copy of all peer mails
+	PeerMailBcc []string `json:"PeerMailBcc"` // recipients that receive a blind copy of all peer mails
+
+	ClientIsolation        bool     `json:"ClientIsolation"`        // drop the traffic between the peers of the interface
+	ClientIsolationAllowed []string `json:"ClientIsolationAllowed"` // networks behind peers that stay reachable for all peers
+
+	PeerRouteAllowlist []string `json:"PeerRouteAllowlist"` // networks that users may route to their own peers without review
+
 	// Calculated values
 
 	EnabledPeers int    `json:"EnabledPeers"`
@@ -62,6 +75,7 @@ func NewInterface(src *domain.Interface, peers []domain.Peer) *Interface {
 		Disabled:                   src.IsDisabled(),
 		DisabledReason:             src.DisabledReason,
 		SaveConfig:                 src.SaveConfig,
+		Organization:               string(src.OrganizationIdentifier),
 		ListenPort:                 src.ListenPort,
 		Addresses:                  domain.CidrsToStringSlice(src.Addresses),
 		Dns:                        internal.SliceString(src.DnsStr),
@@ -77,15 +91,24 @@ func NewInterface(src *domain.Interface, peers []domain.Peer) *Interface {
 		PeerDefDns:                 internal.SliceString(src.PeerDefDnsStr),
 		PeerDefDnsSearch:           internal.SliceString(src.PeerDefDnsSearchStr),
 		PeerDefEndpoint:            src.PeerDefEndpoint,
+		PeerDefBackupEndpoints:     internal.SliceString(src.PeerDefBackupEndpointsStr),
 		PeerDefAllowedIPs:          internal.SliceString(src.PeerDefAllowedIPsStr),
+		PeerDefRouteSets:           internal.SliceString(src.PeerDefRouteSetsStr),
 		PeerDefMtu:                 src.PeerDefMtu,
 		PeerDefPersistentKeepalive: src.PeerDefPersistentKeepalive,
 		PeerDefFirewallMark:        src.PeerDefFirewallMark,
 		PeerDefRoutingTable:        src.PeerDefRoutingTable,
+		PeerDefUploadLimit:         src.PeerDefUploadLimit,
+		PeerDefDownloadLimit:       src.PeerDefDownloadLimit,
 		PeerDefPreUp:               src.PeerDefPreUp,
 		PeerDefPostUp:              src.PeerDefPostUp,
 		PeerDefPreDown:             src.PeerDefPreDown,
 		PeerDefPostDown:            src.PeerDefPostDown,
+		PeerMailCc:                 internal.SliceString(src.PeerMailCcStr),
+		PeerMailBcc:                internal.SliceString(src.PeerMailBccStr),
+		ClientIsolation:            src.ClientIsolation,
+		ClientIsolationAllowed:     internal.SliceString(src.ClientIsolationAllowedStr),
+		PeerRouteAllowlist:         internal.SliceString(src.PeerRouteAllowlistStr),
 
 		EnabledPeers: 0,
 		TotalPeers:   0,
@@ -149,19 +172,29 @@ func NewDomainInterface(src *Interface) *domain.Interface {
 		DriverType:                 "",  // currently unused
 		Disabled:                   nil, // set below
 		DisabledReason:             src.DisabledReason,
+		OrganizationIdentifier:     domain.OrganizationIdentifier(src.Organization),
 		PeerDefNetworkStr:          internal.SliceToString(src.PeerDefNetwork),
 		PeerDefDnsStr:              internal.SliceToString(src.PeerDefDns),
 		PeerDefDnsSearchStr:        internal.SliceToString(src.PeerDefDnsSearch),
 		PeerDefEndpoint:            src.PeerDefEndpoint,
+		PeerDefBackupEndpointsStr:  internal.SliceToString(src.PeerDefBackupEndpoints),
 		PeerDefAllowedIPsStr:       internal.SliceToString(src.PeerDefAllowedIPs),
+		PeerDefRouteSetsStr:        internal.SliceToString(src.PeerDefRouteSets),
 		PeerDefMtu:                 src.PeerDefMtu,
 		PeerDefPersistentKeepalive: src.PeerDefPersistentKeepalive,
 		PeerDefFirewallMark:        src.PeerDefFirewallMark,
 		PeerDefRoutingTable:        src.PeerDefRoutingTable,
+		PeerDefUploadLimit:         src.PeerDefUploadLimit,
+		PeerDefDownloadLimit:       src.PeerDefDownloadLimit,
 		PeerDefPreUp:               src.PeerDefPreUp,
 		PeerDefPostUp:              src.PeerDefPostUp,
 		PeerDefPreDown:             src.PeerDefPreDown,
 		PeerDefPostDown:            src.PeerDefPostDown,
+		PeerMailCcStr:              internal.SliceToString(src.PeerMailCc),
+		PeerMailBccStr:             internal.SliceToString(src.PeerMailBcc),
+		ClientIsolation:            src.ClientIsolation,
+		ClientIsolationAllowedStr:  internal.SliceToString(src.ClientIsolationAllowed),
+		PeerRouteAllowlistStr:      internal.SliceToString(src.PeerRouteAllowlist),
 	}
 
 	if src.Disabled {
@@ -170,3 +203,29 @@ func NewDomainInterface(src *Interface) *domain.Interface {
 
 	return res
 }
+
+type MtuSuggestion struct {
+	Mtu         int    `json:"Mtu" example:"1420"`            // the suggested tunnel MTU
+	Destination string `json:"Destination" example:"1.2.3.4"` // the probed address, empty if the default route was probed
+	LinkName    string `json:"LinkName" example:"eth0"`       // the underlying interface
+	LinkMtu     int    `json:"LinkMtu" example:"1500"`        // the MTU of the underlying interface
+	PathMtu     int    `json:"PathMtu" example:"1500"`        // the MTU of the path to the destination
+}
+
+func NewMtuSuggestion(src *domain.PathMtu) *MtuSuggestion {
+	res := &MtuSuggestion{
+		Mtu:      src.TunnelMtu(),
+		LinkName: src.LinkName,
+		LinkMtu:  src.LinkMtu,
+		PathMtu:  src.Mtu,
+	}
+	if src.Destination.IsValid() {
+		res.Destination = src.Destination.String()
+	}
+
+	return res
+}
+
+type InterfaceMailRequest struct {
+	LinkOnly bool `json:"LinkOnly"` // if true, only a download link is sent instead of the configuration
+}
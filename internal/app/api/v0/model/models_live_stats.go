@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type PeerLiveSample struct {
+	PeerIdentifier   string    `json:"PeerIdentifier"`
+	Timestamp        time.Time `json:"Timestamp"`
+	BytesReceived    uint64    `json:"BytesReceived"`    // bytes received from the peer since the previous sample
+	BytesTransmitted uint64    `json:"BytesTransmitted"` // bytes sent to the peer since the previous sample
+	ReceiveRate      float64   `json:"ReceiveRate"`      // bytes per second
+	TransmitRate     float64   `json:"TransmitRate"`     // bytes per second
+	LastHandshake    time.Time `json:"LastHandshake"`
+}
+
+func NewPeerLiveSample(src domain.PeerLiveSample) PeerLiveSample {
+	return PeerLiveSample{
+		PeerIdentifier:   string(src.PeerId),
+		Timestamp:        src.Timestamp,
+		BytesReceived:    src.BytesReceived,
+		BytesTransmitted: src.BytesTransmitted,
+		ReceiveRate:      src.ReceiveRate,
+		TransmitRate:     src.TransmitRate,
+		LastHandshake:    src.LastHandshake,
+	}
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type MailPreview struct {
+	Template string `json:"Template" example:"mail_with_link"` // the name of the rendered template
+	Subject  string `json:"Subject" example:"WireGuard VPN Configuration"`
+	TextBody string `json:"TextBody"` // the plain text variant of the mail
+	HtmlBody string `json:"HtmlBody"` // the html variant of the mail
+}
+
+// NewMailPreviews creates a slice of REST API MailPreviews from a slice of domain MailPreviews.
+func NewMailPreviews(src []domain.MailPreview) []MailPreview {
+	results := make([]MailPreview, len(src))
+	for i := range src {
+		results[i] = MailPreview{
+			Template: src[i].Template,
+			Subject:  src[i].Subject,
+			TextBody: src[i].TextBody,
+			HtmlBody: src[i].HtmlBody,
+		}
+	}
+
+	return results
+}
+
+type MailTemplate struct {
+	Name      string                 `json:"Name" example:"mail_with_link"`
+	Variables []MailTemplateVariable `json:"Variables"`
+}
+
+type MailTemplateVariable struct {
+	Name        string   `json:"Name" example:"User"` // accessed as {{$.User}} in the template
+	Type        string   `json:"Type" example:"*domain.User"`
+	Description string   `json:"Description"`
+	Fields      []string `json:"Fields"` // the fields and methods of struct variables
+}
+
+// NewMailTemplates creates a slice of REST API MailTemplates from a slice of domain MailTemplates.
+func NewMailTemplates(src []domain.MailTemplate) []MailTemplate {
+	results := make([]MailTemplate, len(src))
+	for i := range src {
+		variables := make([]MailTemplateVariable, len(src[i].Variables))
+		for j, variable := range src[i].Variables {
+			variables[j] = MailTemplateVariable{
+				Name:        variable.Name,
+				Type:        variable.Type,
+				Description: variable.Description,
+				Fields:      variable.Fields,
+			}
+		}
+		results[i] = MailTemplate{
+			Name:      src[i].Name,
+			Variables: variables,
+		}
+	}
+
+	return results
+}
+
+type MailTemplateLintRequest struct {
+	Template string `json:"Template" binding:"required" example:"mail_with_link"`
+	Format   string `json:"Format" binding:"required,oneof=text html" example:"html"`
+	Content  string `json:"Content"` // the content of the custom template file
+}
+
+type MailTemplateLintResult struct {
+	Valid  bool                `json:"Valid"`
+	Issues []MailTemplateIssue `json:"Issues"`
+}
+
+type MailTemplateIssue struct {
+	Line    int    `json:"Line" example:"3"` // 0 if the line is unknown
+	Message string `json:"Message"`
+}
+
+func NewMailTemplateLintResult(src []domain.MailTemplateIssue) MailTemplateLintResult {
+	issues := make([]MailTemplateIssue, len(src))
+	for i := range src {
+		issues[i] = MailTemplateIssue{
+			Line:    src[i].Line,
+			Message: src[i].Message,
+		}
+	}
+
+	return MailTemplateLintResult{
+		Valid:  len(issues) == 0,
+		Issues: issues,
+	}
+}
+
+type MailLogEntry struct {
+	Id        uint64 `json:"Id"`
+	Timestamp string `json:"Timestamp"`
+
+	Template   string `json:"Template" example:"mail_with_link"`
+	Subject    string `json:"Subject" example:"WireGuard VPN Configuration"`
+	Recipients string `json:"Recipients" example:"alice@example.com, team@example.com"` // including CC and BCC
+
+	UserIdentifier string `json:"UserIdentifier"`
+	PeerIdentifier string `json:"PeerIdentifier"` // empty if the mail is not about a single peer
+
+	Success bool   `json:"Success"`
+	Error   string `json:"Error"` // the reason if the mail could not be sent
+
+	Tracked bool   `json:"Tracked"` // true if the mail contains a tracking pixel and a tracked link
+	Opened  string `json:"Opened"`  // the time the mail was first opened, empty if it was not opened (yet)
+	Clicked string `json:"Clicked"` // the time the link in the mail was first used, empty if it was not used (yet)
+}
+
+// NewMailLogEntries creates a slice of REST API MailLogEntry from a slice of domain MailLogEntry.
+func NewMailLogEntries(src []domain.MailLogEntry) []MailLogEntry {
+	results := make([]MailLogEntry, len(src))
+	for i := range src {
+		results[i] = MailLogEntry{
+			Id:             src[i].UniqueId,
+			Timestamp:      src[i].SentAt.Format("2006-01-02 15:04:05"),
+			Template:       src[i].Template,
+			Subject:        src[i].Subject,
+			Recipients:     src[i].Recipients,
+			UserIdentifier: string(src[i].UserIdentifier),
+			PeerIdentifier: string(src[i].PeerIdentifier),
+			Success:        !src[i].Failed(),
+			Error:          src[i].Error,
+			Tracked:        src[i].IsTracked(),
+		}
+		if src[i].OpenedAt != nil {
+			results[i].Opened = src[i].OpenedAt.Format("2006-01-02 15:04:05")
+		}
+		if src[i].ClickedAt != nil {
+			results[i].Clicked = src[i].ClickedAt.Format("2006-01-02 15:04:05")
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type Organization struct {
+	Identifier  string `json:"Identifier" example:"acme"` // organization unique identifier
+	DisplayName string `json:"DisplayName" example:"ACME Corporation"`
+
+	SiteTitle   string `json:"SiteTitle"`   // the title that is shown in the web frontend, empty for the default title
+	CompanyName string `json:"CompanyName"` // the company name for the web frontend and mails, empty for the default
+}
+
+// NewOrganization creates a REST API Organization from a domain Organization.
+func NewOrganization(src *domain.Organization) *Organization {
+	return &Organization{
+		Identifier:  string(src.Identifier),
+		DisplayName: src.DisplayName,
+		SiteTitle:   src.SiteTitle,
+		CompanyName: src.CompanyName,
+	}
+}
+
+// NewOrganizations creates a slice of REST API Organizations from a slice of domain Organizations.
+func NewOrganizations(src []domain.Organization) []Organization {
+	results := make([]Organization, len(src))
+	for i := range src {
+		results[i] = *NewOrganization(&src[i])
+	}
+
+	return results
+}
+
+// NewDomainOrganization creates a domain Organization from a REST API Organization.
+func NewDomainOrganization(src *Organization) *domain.Organization {
+	return &domain.Organization{
+		Identifier:  domain.OrganizationIdentifier(src.Identifier),
+		DisplayName: src.DisplayName,
+		SiteTitle:   src.SiteTitle,
+		CompanyName: src.CompanyName,
+	}
+}
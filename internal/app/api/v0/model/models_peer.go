@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/h44z/wg-portal/internal"
+	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
@@ -47,14 +48,26 @@ type Peer struct {
 	Disabled            bool       `json:"Disabled"`                             // flag that specifies if the peer is enabled (up) or not (down)
 	DisabledReason      string     `json:"DisabledReason"`                       // the reason why the peer has been disabled
 	ExpiresAt           ExpiryDate `json:"ExpiresAt,omitempty"`                  // expiry dates for peers
+	Ephemeral           bool       `json:"Ephemeral"`                            // ephemeral peers are deleted once they expire, read only
 	Notes               string     `json:"Notes"`                                // a note field for peers
+	MailRecipients      []string   `json:"MailRecipients"`                       // additional recipients of peer mails
+	SharedUsers         []string   `json:"SharedUsers"`                          // additional users of a shared device
+	AccessSchedule      string     `json:"AccessSchedule"`                       // weekly time windows in which the peer is enabled
+	AccessTimezone      string     `json:"AccessTimezone"`                       // time zone of the access schedule
+	EndpointPin         []string   `json:"EndpointPin"`                          // networks or countries the endpoint is pinned to
+	OperatingSystem     string     `json:"OperatingSystem"`                      // selects the setup guide in config mails
+	DeviceType          string     `json:"DeviceType"`                           // the kind of device, for example laptop or server
+	KeysRotatedAt       *time.Time `json:"KeysRotatedAt"`                        // the last time the keys were regenerated
 
 	Endpoint            ConfigOption[string]   `json:"Endpoint"`            // the endpoint address
 	EndpointPublicKey   ConfigOption[string]   `json:"EndpointPublicKey"`   // the endpoint public key
 	AllowedIPs          ConfigOption[[]string] `json:"AllowedIPs"`          // all allowed ip subnets, comma seperated
 	ExtraAllowedIPs     []string               `json:"ExtraAllowedIPs"`     // all allowed ip subnets on the server side, comma seperated
+	RouteSets           ConfigOption[[]string] `json:"RouteSets"`           // the identifiers of the route sets that are added to the allowed ip subnets
 	PresharedKey        string                 `json:"PresharedKey"`        // the pre-shared Key of the peer
 	PersistentKeepalive ConfigOption[int]      `json:"PersistentKeepalive"` // the persistent keep-alive interval
+	UploadLimit         ConfigOption[int]      `json:"UploadLimit"`         // the upload limit in kbit/s, 0 means unlimited
+	DownloadLimit       ConfigOption[int]      `json:"DownloadLimit"`       // the download limit in kbit/s, 0 means unlimited
 
 	PrivateKey string `json:"PrivateKey" example:"abcdef=="` // private Key of the server peer
 	PublicKey  string `json:"PublicKey" example:"abcdef=="`  // public Key of the server peer
@@ -74,6 +87,8 @@ type Peer struct {
 	PreDown  ConfigOption[string] `json:"PreDown"`  // action that is executed before the device is down
 	PostDown ConfigOption[string] `json:"PostDown"` // action that is executed after the device is down
 
+	KillSwitch string `json:"KillSwitch" example:"iptables"` // the kill-switch variant (iptables, nftables, windows), empty means disabled
+
 	// Calculated values
 
 	Filename string `json:"Filename"` // the filename of the config file, for example: wg_peer_x.conf
@@ -88,13 +103,25 @@ func NewPeer(src *domain.Peer) *Peer {
 		Disabled:            src.IsDisabled(),
 		DisabledReason:      src.DisabledReason,
 		ExpiresAt:           ExpiryDate{src.ExpiresAt},
+		Ephemeral:           src.Ephemeral,
 		Notes:               src.Notes,
+		MailRecipients:      internal.SliceString(src.MailRecipientsStr),
+		SharedUsers:         internal.SliceString(src.SharedUsersStr),
+		AccessSchedule:      src.AccessScheduleStr,
+		AccessTimezone:      src.AccessTimezone,
+		EndpointPin:         internal.SliceString(src.EndpointPinStr),
+		OperatingSystem:     string(src.OperatingSystem),
+		DeviceType:          string(src.DeviceType),
+		KeysRotatedAt:       src.KeysRotatedAt,
 		Endpoint:            ConfigOptionFromDomain(src.Endpoint),
 		EndpointPublicKey:   ConfigOptionFromDomain(src.EndpointPublicKey),
 		AllowedIPs:          StringSliceConfigOptionFromDomain(src.AllowedIPsStr),
 		ExtraAllowedIPs:     internal.SliceString(src.ExtraAllowedIPsStr),
+		RouteSets:           StringSliceConfigOptionFromDomain(src.RouteSetsStr),
 		PresharedKey:        string(src.PresharedKey),
 		PersistentKeepalive: ConfigOptionFromDomain(src.PersistentKeepalive),
+		UploadLimit:         ConfigOptionFromDomain(src.UploadLimit),
+		DownloadLimit:       ConfigOptionFromDomain(src.DownloadLimit),
 		PrivateKey:          src.Interface.PrivateKey,
 		PublicKey:           src.Interface.PublicKey,
 		Mode:                string(src.Interface.Type),
@@ -109,6 +136,7 @@ func NewPeer(src *domain.Peer) *Peer {
 		PostUp:              ConfigOptionFromDomain(src.Interface.PostUp),
 		PreDown:             ConfigOptionFromDomain(src.Interface.PreDown),
 		PostDown:            ConfigOptionFromDomain(src.Interface.PostDown),
+		KillSwitch:          string(src.Interface.KillSwitch),
 		Filename:            src.GetConfigFileName(),
 	}
 }
@@ -133,8 +161,11 @@ func NewDomainPeer(src *Peer) *domain.Peer {
 		EndpointPublicKey:   ConfigOptionToDomain(src.EndpointPublicKey),
 		AllowedIPsStr:       StringSliceConfigOptionToDomain(src.AllowedIPs),
 		ExtraAllowedIPsStr:  internal.SliceToString(src.ExtraAllowedIPs),
+		RouteSetsStr:        StringSliceConfigOptionToDomain(src.RouteSets),
 		PresharedKey:        domain.PreSharedKey(src.PresharedKey),
 		PersistentKeepalive: ConfigOptionToDomain(src.PersistentKeepalive),
+		UploadLimit:         ConfigOptionToDomain(src.UploadLimit),
+		DownloadLimit:       ConfigOptionToDomain(src.DownloadLimit),
 		DisplayName:         src.DisplayName,
 		Identifier:          domain.PeerIdentifier(src.Identifier),
 		UserIdentifier:      domain.UserIdentifier(src.UserIdentifier),
@@ -143,6 +174,13 @@ func NewDomainPeer(src *Peer) *domain.Peer {
 		DisabledReason:      src.DisabledReason,
 		ExpiresAt:           src.ExpiresAt.Time,
 		Notes:               src.Notes,
+		MailRecipientsStr:   internal.SliceToString(src.MailRecipients),
+		SharedUsersStr:      internal.SliceToString(src.SharedUsers),
+		AccessScheduleStr:   src.AccessSchedule,
+		AccessTimezone:      src.AccessTimezone,
+		EndpointPinStr:      internal.SliceToString(src.EndpointPin),
+		OperatingSystem:     domain.PeerOperatingSystem(src.OperatingSystem),
+		DeviceType:          domain.PeerDeviceType(src.DeviceType),
 		Interface: domain.PeerInterfaceConfig{
 			KeyPair: domain.KeyPair{
 				PrivateKey: src.PrivateKey,
@@ -160,6 +198,7 @@ func NewDomainPeer(src *Peer) *domain.Peer {
 			PostUp:            ConfigOptionToDomain(src.PostUp),
 			PreDown:           ConfigOptionToDomain(src.PreDown),
 			PostDown:          ConfigOptionToDomain(src.PostDown),
+			KillSwitch:        domain.KillSwitchMode(src.KillSwitch),
 		},
 	}
 
@@ -204,6 +243,7 @@ func NewPeerStats(enabled bool, src []domain.PeerStatus) *PeerStats {
 			BytesReceived:    srcStat.BytesReceived,
 			BytesTransmitted: srcStat.BytesTransmitted,
 			LastHandshake:    srcStat.LastHandshake,
+			NeverConnected:   srcStat.LastHandshake == nil,
 			EndpointAddress:  srcStat.Endpoint,
 			LastSessionStart: srcStat.LastSessionStart,
 		}
@@ -225,6 +265,128 @@ type PeerStatData struct {
 	BytesTransmitted uint64 `json:"BytesTransmitted"`
 
 	LastHandshake    *time.Time `json:"LastHandshake"`
+	NeverConnected   bool       `json:"NeverConnected"` // true if the peer never completed a handshake
 	EndpointAddress  string     `json:"EndpointAddress"`
 	LastSessionStart *time.Time `json:"LastSessionStart"`
 }
+
+type PeerCleanupReport struct {
+	Enabled bool   `json:"Enabled" example:"true"`   // peer cleanup policy enabled
+	DryRun  bool   `json:"DryRun" example:"false"`   // no action is taken if true
+	Action  string `json:"Action" example:"disable"` // the cleanup action: disable or delete
+
+	Candidates []PeerCleanupCandidate `json:"Candidates"`
+}
+
+type PeerCleanupCandidate struct {
+	PeerIdentifier      string     `json:"PeerIdentifier" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	DisplayName         string     `json:"DisplayName" example:"My Peer"`
+	InterfaceIdentifier string     `json:"InterfaceIdentifier" example:"wg0"`
+	UserIdentifier      string     `json:"UserIdentifier" example:"uid-1234567"`
+	LastActivity        time.Time  `json:"LastActivity"`
+	WarnedAt            *time.Time `json:"WarnedAt"`
+	ActionAt            time.Time  `json:"ActionAt"`
+	Stage               string     `json:"Stage" example:"warn"` // warn, pending or action
+}
+
+func NewPeerCleanupReport(cfg config.PeerCleanupConfig, src []domain.PeerCleanupCandidate) *PeerCleanupReport {
+	candidates := make([]PeerCleanupCandidate, len(src))
+	for i, candidate := range src {
+		candidates[i] = PeerCleanupCandidate{
+			PeerIdentifier:      string(candidate.Peer.Identifier),
+			DisplayName:         candidate.Peer.DisplayName,
+			InterfaceIdentifier: string(candidate.Peer.InterfaceIdentifier),
+			UserIdentifier:      string(candidate.Peer.UserIdentifier),
+			LastActivity:        candidate.LastActivity,
+			WarnedAt:            candidate.WarnedAt,
+			ActionAt:            candidate.ActionAt,
+			Stage:               string(candidate.Stage),
+		}
+	}
+
+	return &PeerCleanupReport{
+		Enabled:    cfg.Enabled,
+		DryRun:     cfg.DryRun,
+		Action:     string(cfg.Action),
+		Candidates: candidates,
+	}
+}
+
+type PeerKeepaliveRecommendations struct {
+	Enabled bool `json:"Enabled" example:"true"` // keepalive recommendations enabled
+
+	Recommendations map[string]PeerKeepaliveRecommendation `json:"Recommendations"` // map key = Peer identifier
+}
+
+type PeerKeepaliveRecommendation struct {
+	EndpointChanges      int       `json:"EndpointChanges" example:"5"`       // within the observation window
+	NatRebinds           int       `json:"NatRebinds" example:"4"`            // endpoint changes of the port only
+	ObservedSince        time.Time `json:"ObservedSince"`                     // the start of the observation window
+	CurrentKeepalive     int       `json:"CurrentKeepalive" example:"0"`      // in seconds, 0 means disabled
+	RecommendedKeepalive int       `json:"RecommendedKeepalive" example:"25"` // in seconds, 0 if it would not help
+}
+
+func NewPeerKeepaliveRecommendations(
+	enabled bool,
+	src []domain.KeepaliveRecommendation,
+) *PeerKeepaliveRecommendations {
+	recommendations := make(map[string]PeerKeepaliveRecommendation, len(src))
+
+	for _, srcRecommendation := range src {
+		recommendations[string(srcRecommendation.Peer.Identifier)] = PeerKeepaliveRecommendation{
+			EndpointChanges:      srcRecommendation.EndpointChanges,
+			NatRebinds:           srcRecommendation.NatRebinds,
+			ObservedSince:        srcRecommendation.ObservedSince,
+			CurrentKeepalive:     srcRecommendation.CurrentKeepalive,
+			RecommendedKeepalive: srcRecommendation.RecommendedKeepalive,
+		}
+	}
+
+	return &PeerKeepaliveRecommendations{
+		Enabled:         enabled,
+		Recommendations: recommendations,
+	}
+}
+
+type PeerShapingStats struct {
+	Enabled bool `json:"Enabled" example:"true"` // bandwidth shaping enabled
+
+	Stats map[string]PeerShapingStatData `json:"Stats"` // stats, map key = Peer identifier
+}
+
+func NewPeerShapingStats(enabled bool, src []domain.PeerShapingStatus) *PeerShapingStats {
+	stats := make(map[string]PeerShapingStatData, len(src))
+
+	for _, srcStat := range src {
+		stats[string(srcStat.PeerId)] = PeerShapingStatData{
+			UploadLimit:        srcStat.UploadLimit,
+			DownloadLimit:      srcStat.DownloadLimit,
+			UploadBytes:        srcStat.UploadBytes,
+			UploadPackets:      srcStat.UploadPackets,
+			UploadDropped:      srcStat.UploadDropped,
+			DownloadBytes:      srcStat.DownloadBytes,
+			DownloadPackets:    srcStat.DownloadPackets,
+			DownloadDropped:    srcStat.DownloadDropped,
+			DownloadOverlimits: srcStat.DownloadOverlimits,
+		}
+	}
+
+	return &PeerShapingStats{
+		Enabled: enabled,
+		Stats:   stats,
+	}
+}
+
+type PeerShapingStatData struct {
+	UploadLimit   int `json:"UploadLimit" example:"10000"`   // in kbit/s, 0 means unlimited
+	DownloadLimit int `json:"DownloadLimit" example:"50000"` // in kbit/s, 0 means unlimited
+
+	UploadBytes   uint64 `json:"UploadBytes"`
+	UploadPackets uint32 `json:"UploadPackets"`
+	UploadDropped uint32 `json:"UploadDropped"`
+
+	DownloadBytes      uint64 `json:"DownloadBytes"`
+	DownloadPackets    uint32 `json:"DownloadPackets"`
+	DownloadDropped    uint32 `json:"DownloadDropped"`
+	DownloadOverlimits uint32 `json:"DownloadOverlimits"`
+}
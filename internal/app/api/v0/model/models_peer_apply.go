@@ -0,0 +1,42 @@
+package model
+
+import (
+	"sort"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// PeerApplyError is returned instead of Error if a set of peer changes failed and was rolled back.
+type PeerApplyError struct {
+	Code    int    `json:"Code"`
+	Message string `json:"Message"`
+
+	FailedPeer       string            `json:"FailedPeer"`
+	Interface        string            `json:"Interface"`
+	Reason           string            `json:"Reason"`
+	RolledBack       []string          `json:"RolledBack"`       // peers that were restored to their previous state
+	RollbackComplete bool              `json:"RollbackComplete"` // false if at least one peer could not be restored
+	RollbackFailures map[string]string `json:"RollbackFailures"` // peer identifier to the reason of the failure
+}
+
+func NewPeerApplyError(code int, src *domain.PeerApplyError) *PeerApplyError {
+	res := &PeerApplyError{
+		Code:             code,
+		Message:          src.Error(),
+		FailedPeer:       string(src.Peer),
+		Interface:        string(src.Interface),
+		Reason:           src.Err.Error(),
+		RolledBack:       make([]string, len(src.RolledBack)),
+		RollbackComplete: src.RollbackComplete(),
+		RollbackFailures: make(map[string]string, len(src.RollbackFailures)),
+	}
+	for i, id := range src.RolledBack {
+		res.RolledBack[i] = string(id)
+	}
+	sort.Strings(res.RolledBack)
+	for id, reason := range src.RollbackFailures {
+		res.RollbackFailures[string(id)] = reason
+	}
+
+	return res
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type PeerTransfer struct {
+	Identifier  string `json:"Identifier"`
+	PeerId      string `json:"PeerId"`
+	PeerName    string `json:"PeerName"`
+	InterfaceId string `json:"InterfaceId"`
+	FromUser    string `json:"FromUser"`
+	ToUser      string `json:"ToUser"`
+	Message     string `json:"Message"`
+
+	State       string    `json:"State"` // pending, completed, rejected, cancelled or expired
+	RequestedBy string    `json:"RequestedBy"`
+	RequestedAt time.Time `json:"RequestedAt"`
+	ExpiresAt   time.Time `json:"ExpiresAt"`
+
+	AcceptedAt       *time.Time `json:"AcceptedAt"`
+	RequiresApproval bool       `json:"RequiresApproval"` // if true, an admin has to approve the transfer
+	ApprovedBy       string     `json:"ApprovedBy"`
+	ApprovedAt       *time.Time `json:"ApprovedAt"`
+	ResolvedBy       string     `json:"ResolvedBy"`
+	ResolvedAt       *time.Time `json:"ResolvedAt"`
+}
+
+func NewPeerTransfer(src *domain.PeerTransfer) *PeerTransfer {
+	return &PeerTransfer{
+		Identifier:       string(src.Identifier),
+		PeerId:           string(src.PeerId),
+		PeerName:         src.PeerName,
+		InterfaceId:      string(src.InterfaceIdentifier),
+		FromUser:         string(src.FromUser),
+		ToUser:           string(src.ToUser),
+		Message:          src.Message,
+		State:            string(src.State),
+		RequestedBy:      string(src.RequestedBy),
+		RequestedAt:      src.RequestedAt,
+		ExpiresAt:        src.ExpiresAt,
+		AcceptedAt:       src.AcceptedAt,
+		RequiresApproval: src.RequiresApproval,
+		ApprovedBy:       string(src.ApprovedBy),
+		ApprovedAt:       src.ApprovedAt,
+		ResolvedBy:       string(src.ResolvedBy),
+		ResolvedAt:       src.ResolvedAt,
+	}
+}
+
+func NewPeerTransfers(src []domain.PeerTransfer) []PeerTransfer {
+	results := make([]PeerTransfer, len(src))
+	for i := range src {
+		results[i] = *NewPeerTransfer(&src[i])
+	}
+
+	return results
+}
+
+type PeerTransferRequest struct {
+	PeerId  string `json:"PeerId" binding:"required"`
+	ToUser  string `json:"ToUser" binding:"required"`
+	Message string `json:"Message"`
+}
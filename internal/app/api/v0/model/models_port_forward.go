@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type PortForward struct {
+	Identifier   string    `json:"Identifier"`
+	PeerId       string    `json:"PeerId"`
+	InterfaceId  string    `json:"InterfaceId"`
+	Protocol     string    `json:"Protocol" example:"tcp"` // tcp or udp
+	ExternalPort int       `json:"ExternalPort" example:"8080"`
+	TargetPort   int       `json:"TargetPort" example:"80"`
+	Description  string    `json:"Description"`
+	CreatedBy    string    `json:"CreatedBy"`
+	CreatedAt    time.Time `json:"CreatedAt"`
+}
+
+func NewPortForward(src *domain.PortForward) *PortForward {
+	return &PortForward{
+		Identifier:   string(src.Identifier),
+		PeerId:       string(src.PeerId),
+		InterfaceId:  string(src.InterfaceIdentifier),
+		Protocol:     string(src.Protocol),
+		ExternalPort: src.ExternalPort,
+		TargetPort:   src.TargetPort,
+		Description:  src.Description,
+		CreatedBy:    string(src.CreatedBy),
+		CreatedAt:    src.CreatedAt,
+	}
+}
+
+func NewPortForwards(src []domain.PortForward) []PortForward {
+	results := make([]PortForward, len(src))
+	for i := range src {
+		results[i] = *NewPortForward(&src[i])
+	}
+
+	return results
+}
+
+type PortForwardRequest struct {
+	PeerId       string `json:"PeerId" binding:"required"`
+	Protocol     string `json:"Protocol" binding:"required,oneof=tcp udp"`
+	ExternalPort int    `json:"ExternalPort" binding:"required,min=1,max=65535"`
+	TargetPort   int    `json:"TargetPort" binding:"required,min=1,max=65535"`
+	Description  string `json:"Description"`
+}
+
+func NewDomainPortForward(src *PortForwardRequest) *domain.PortForward {
+	return &domain.PortForward{
+		PeerId:       domain.PeerIdentifier(src.PeerId),
+		Protocol:     domain.PortForwardProtocol(src.Protocol),
+		ExternalPort: src.ExternalPort,
+		TargetPort:   src.TargetPort,
+		Description:  src.Description,
+	}
+}
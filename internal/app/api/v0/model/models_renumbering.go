@@ -0,0 +1,67 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type InterfaceRenumberingRequest struct {
+	From      string `json:"From" example:"10.11.12.0/24"` // the peer network that should be renumbered
+	To        string `json:"To" example:"10.11.12.0/23"`   // the new, larger network; a network is suggested if empty
+	SendMails bool   `json:"SendMails"`                    // if true, the updated configurations are mailed to the users
+}
+
+type InterfaceRenumbering struct {
+	InterfaceIdentifier string            `json:"InterfaceIdentifier"`
+	From                string            `json:"From" example:"10.11.12.0/24"`
+	To                  string            `json:"To" example:"10.11.12.0/23"`
+	InterfaceAddresses  []AddressChange   `json:"InterfaceAddresses"`
+	Peers               []PeerRenumbering `json:"Peers"`
+	SendMails           bool              `json:"SendMails"`
+}
+
+type AddressChange struct {
+	Old string `json:"Old" example:"10.11.12.1/24"`
+	New string `json:"New" example:"10.11.12.1/23"`
+}
+
+type PeerRenumbering struct {
+	Identifier     string          `json:"Identifier"`
+	DisplayName    string          `json:"DisplayName"`
+	UserIdentifier string          `json:"UserIdentifier"`
+	Disabled       bool            `json:"Disabled"`
+	Addresses      []AddressChange `json:"Addresses"`
+}
+
+func NewInterfaceRenumbering(src *domain.InterfaceRenumbering) *InterfaceRenumbering {
+	res := &InterfaceRenumbering{
+		InterfaceIdentifier: string(src.InterfaceId),
+		From:                src.From.String(),
+		To:                  src.To.String(),
+		InterfaceAddresses:  NewAddressChanges(src.InterfaceAddresses),
+		Peers:               make([]PeerRenumbering, len(src.Peers)),
+		SendMails:           src.SendMails,
+	}
+	for i, peer := range src.Peers {
+		res.Peers[i] = PeerRenumbering{
+			Identifier:     string(peer.PeerId),
+			DisplayName:    peer.DisplayName,
+			UserIdentifier: string(peer.UserIdentifier),
+			Disabled:       peer.Disabled,
+			Addresses:      NewAddressChanges(peer.Addresses),
+		}
+	}
+
+	return res
+}
+
+func NewAddressChanges(src []domain.AddressChange) []AddressChange {
+	results := make([]AddressChange, len(src))
+	for i := range src {
+		results[i] = AddressChange{
+			Old: src[i].Old.String(),
+			New: src[i].New.String(),
+		}
+	}
+
+	return results
+}
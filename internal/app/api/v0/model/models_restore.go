@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type RestoreStep struct {
+	Name       string `json:"Name"`
+	Status     string `json:"Status"` // succeeded, failed or skipped
+	Error      string `json:"Error"`
+	DurationMs int64  `json:"DurationMs"`
+}
+
+type RestoreReport struct {
+	Enabled    bool          `json:"Enabled"` // false if the ordered restoration is disabled
+	Failed     bool          `json:"Failed"`  // true if at least one step failed
+	StartedAt  *time.Time    `json:"StartedAt"`
+	FinishedAt *time.Time    `json:"FinishedAt"`
+	Steps      []RestoreStep `json:"Steps"`
+}
+
+func NewRestoreReport(enabled bool, src *domain.RestoreReport) *RestoreReport {
+	report := &RestoreReport{
+		Enabled: enabled,
+		Failed:  src.Failed(),
+		Steps:   make([]RestoreStep, len(src.Steps)),
+	}
+	if !src.StartedAt.IsZero() {
+		report.StartedAt = &src.StartedAt
+		report.FinishedAt = &src.FinishedAt
+	}
+	for i, step := range src.Steps {
+		report.Steps[i] = RestoreStep{
+			Name:       step.Name,
+			Status:     string(step.Status),
+			Error:      step.Error,
+			DurationMs: step.Duration.Milliseconds(),
+		}
+	}
+
+	return report
+}
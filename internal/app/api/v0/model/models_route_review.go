@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type RouteReview struct {
+	Identifier     string   `json:"Identifier"`
+	PeerId         string   `json:"PeerId"`
+	PeerName       string   `json:"PeerName"`
+	InterfaceId    string   `json:"InterfaceId"`
+	UserId         string   `json:"UserId"`         // the owner of the peer
+	Routes         []string `json:"Routes"`         // the requested routes of the peer
+	PreviousRoutes []string `json:"PreviousRoutes"` // the routes of the peer at request time
+	Unreviewed     []string `json:"Unreviewed"`     // the requested routes outside the allowlist of the interface
+
+	State       string    `json:"State"` // pending, approved, rejected, cancelled, superseded or expired
+	RequestedBy string    `json:"RequestedBy"`
+	RequestedAt time.Time `json:"RequestedAt"`
+	ExpiresAt   time.Time `json:"ExpiresAt"`
+
+	ResolvedBy string     `json:"ResolvedBy"`
+	ResolvedAt *time.Time `json:"ResolvedAt"`
+}
+
+func NewRouteReview(src *domain.RouteReview) *RouteReview {
+	return &RouteReview{
+		Identifier:     string(src.Identifier),
+		PeerId:         string(src.PeerId),
+		PeerName:       src.PeerName,
+		InterfaceId:    string(src.InterfaceIdentifier),
+		UserId:         string(src.UserIdentifier),
+		Routes:         internal.SliceString(src.RoutesStr),
+		PreviousRoutes: internal.SliceString(src.PreviousRoutesStr),
+		Unreviewed:     internal.SliceString(src.UnreviewedStr),
+		State:          string(src.State),
+		RequestedBy:    string(src.RequestedBy),
+		RequestedAt:    src.RequestedAt,
+		ExpiresAt:      src.ExpiresAt,
+		ResolvedBy:     string(src.ResolvedBy),
+		ResolvedAt:     src.ResolvedAt,
+	}
+}
+
+func NewRouteReviews(src []domain.RouteReview) []RouteReview {
+	results := make([]RouteReview, len(src))
+	for i := range src {
+		results[i] = *NewRouteReview(&src[i])
+	}
+
+	return results
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type RouteSet struct {
+	Identifier  string   `json:"Identifier" example:"corp-subnets"` // route set unique identifier
+	DisplayName string   `json:"DisplayName" example:"Corporate subnets"`
+	Description string   `json:"Description"`
+	Networks    []string `json:"Networks" example:"10.10.0.0/16"` // the networks that are added to the AllowedIPs
+
+	PeerCount int `json:"PeerCount"` // the number of peers that use the route set, only set when listing all route sets
+}
+
+// NewRouteSet creates a REST API RouteSet from a domain RouteSet.
+func NewRouteSet(src *domain.RouteSet, peerCount int) *RouteSet {
+	return &RouteSet{
+		Identifier:  string(src.Identifier),
+		DisplayName: src.DisplayName,
+		Description: src.Description,
+		Networks:    internal.SliceString(src.NetworksStr),
+		PeerCount:   peerCount,
+	}
+}
+
+// NewDomainRouteSet creates a domain RouteSet from a REST API RouteSet.
+func NewDomainRouteSet(src *RouteSet) *domain.RouteSet {
+	return &domain.RouteSet{
+		Identifier:  domain.RouteSetIdentifier(src.Identifier),
+		DisplayName: src.DisplayName,
+		Description: src.Description,
+		NetworksStr: internal.SliceToString(src.Networks),
+	}
+}
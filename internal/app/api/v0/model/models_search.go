@@ -0,0 +1,31 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type SearchResult struct {
+	Type                string `json:"Type" example:"peer"`                         // the type of the entity: user, peer, interface or audit
+	Identifier          string `json:"Identifier"`                                  // the identifier of the entity, the unique id for audit entries
+	Title               string `json:"Title" example:"Alice Laptop"`                // a human-readable name of the entity
+	Detail              string `json:"Detail" example:"10.0.0.2"`                   // the value that matched the search query
+	InterfaceIdentifier string `json:"InterfaceIdentifier,omitempty" example:"wg0"` // the interface of peers
+	UserIdentifier      string `json:"UserIdentifier,omitempty" example:"alice"`    // the owner of peers
+}
+
+// NewSearchResults creates a slice of REST API SearchResults from a slice of domain SearchResults.
+func NewSearchResults(src []domain.SearchResult) []SearchResult {
+	results := make([]SearchResult, len(src))
+	for i, result := range src {
+		results[i] = SearchResult{
+			Type:                string(result.Type),
+			Identifier:          result.Identifier,
+			Title:               result.Title,
+			Detail:              result.Detail,
+			InterfaceIdentifier: string(result.InterfaceIdentifier),
+			UserIdentifier:      string(result.UserIdentifier),
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type SecurityEvent struct {
+	Id                  uint64    `json:"Id"`
+	CreatedAt           time.Time `json:"CreatedAt"`
+	RuleName            string    `json:"RuleName" example:"blocked-countries"`
+	Condition           string    `json:"Condition" example:"handshake_from_country"`
+	Severity            string    `json:"Severity" example:"high"` // low, medium or high
+	Message             string    `json:"Message"`
+	PeerIdentifier      string    `json:"PeerIdentifier"`
+	InterfaceIdentifier string    `json:"InterfaceIdentifier" example:"wg0"`
+	UserIdentifier      string    `json:"UserIdentifier"`
+	Actor               string    `json:"Actor"`
+	Endpoint            string    `json:"Endpoint"`
+	Country             string    `json:"Country" example:"AT"`
+}
+
+func NewSecurityEvent(src domain.SecurityEvent) SecurityEvent {
+	return SecurityEvent{
+		Id:                  src.Id,
+		CreatedAt:           src.CreatedAt,
+		RuleName:            src.RuleName,
+		Condition:           src.Condition,
+		Severity:            string(src.Severity),
+		Message:             src.Message,
+		PeerIdentifier:      string(src.PeerId),
+		InterfaceIdentifier: string(src.InterfaceId),
+		UserIdentifier:      string(src.UserIdentifier),
+		Actor:               string(src.Actor),
+		Endpoint:            src.Endpoint,
+		Country:             src.Country,
+	}
+}
+
+func NewSecurityEvents(src []domain.SecurityEvent) []SecurityEvent {
+	results := make([]SecurityEvent, len(src))
+	for i := range src {
+		results[i] = NewSecurityEvent(src[i])
+	}
+
+	return results
+}
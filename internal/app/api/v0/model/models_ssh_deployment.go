@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type SshDeployment struct {
+	Active          bool       `json:"Active"` // false if the peer is not deployed via SSH
+	Host            string     `json:"Host"`
+	RemoteInterface string     `json:"RemoteInterface"`
+	CreatedAt       *time.Time `json:"CreatedAt"`
+	CreatedBy       string     `json:"CreatedBy"`
+	LastDeployedAt  *time.Time `json:"LastDeployedAt"`
+	LastError       string     `json:"LastError"` // the error of the last deployment attempt, empty on success
+}
+
+func NewSshDeployment(src *domain.SshDeployment) *SshDeployment {
+	if src == nil {
+		return &SshDeployment{}
+	}
+
+	return &SshDeployment{
+		Active:          true,
+		Host:            src.Host,
+		RemoteInterface: src.RemoteInterface,
+		CreatedAt:       &src.CreatedAt,
+		CreatedBy:       src.CreatedBy,
+		LastDeployedAt:  src.LastDeployedAt,
+		LastError:       src.LastError,
+	}
+}
+
+type SshDeploymentRequest struct {
+	Host            string `json:"Host" binding:"required"`
+	RemoteInterface string `json:"RemoteInterface" binding:"required"`
+}
+
+func NewDomainSshDeployment(peerId domain.PeerIdentifier, src *SshDeploymentRequest) *domain.SshDeployment {
+	return &domain.SshDeployment{
+		PeerId:          peerId,
+		Host:            src.Host,
+		RemoteInterface: src.RemoteInterface,
+	}
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type EndpointStatus struct {
+	Identifier  string     `json:"Identifier"`
+	DisplayName string     `json:"DisplayName"`
+	Endpoint    string     `json:"Endpoint"`
+	Reachable   bool       `json:"Reachable"`
+	DeviceUp    bool       `json:"DeviceUp"`
+	Resolvable  bool       `json:"Resolvable"`
+	UpSince     *time.Time `json:"UpSince"` // null if the uptime is unknown
+}
+
+type MaintenanceNotice struct {
+	Title      string     `json:"Title"`
+	Message    string     `json:"Message"`
+	Start      *time.Time `json:"Start"`
+	End        *time.Time `json:"End"`
+	Active     bool       `json:"Active"`     // false for upcoming maintenance
+	Interfaces []string   `json:"Interfaces"` // empty if all interfaces are affected
+}
+
+type ServiceStatus struct {
+	Endpoints []EndpointStatus    `json:"Endpoints"`
+	Notices   []MaintenanceNotice `json:"Notices"`
+	CheckedAt *time.Time          `json:"CheckedAt"` // null if no check finished yet
+}
+
+func NewServiceStatus(src *domain.ServiceStatus, now time.Time) *ServiceStatus {
+	status := &ServiceStatus{
+		Endpoints: make([]EndpointStatus, len(src.Endpoints)),
+		Notices:   make([]MaintenanceNotice, len(src.Notices)),
+	}
+	if !src.CheckedAt.IsZero() {
+		status.CheckedAt = &src.CheckedAt
+	}
+
+	for i, endpoint := range src.Endpoints {
+		status.Endpoints[i] = EndpointStatus{
+			Identifier:  string(endpoint.InterfaceId),
+			DisplayName: endpoint.DisplayName,
+			Endpoint:    endpoint.Endpoint,
+			Reachable:   endpoint.IsReachable(),
+			DeviceUp:    endpoint.DeviceUp,
+			Resolvable:  endpoint.Resolvable,
+			UpSince:     endpoint.UpSince,
+		}
+	}
+
+	for i, notice := range src.Notices {
+		interfaces := make([]string, len(notice.Interfaces))
+		for j, id := range notice.Interfaces {
+			interfaces[j] = string(id)
+		}
+		status.Notices[i] = MaintenanceNotice{
+			Title:      notice.Title,
+			Message:    notice.Message,
+			Start:      notice.Start,
+			End:        notice.End,
+			Active:     notice.IsActive(now),
+			Interfaces: interfaces,
+		}
+	}
+
+	return status
+}
@@ -1,8 +1,10 @@
 package model
 
 import (
+	"strings"
 	"time"
 
+	"github.com/h44z/wg-portal/internal"
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
@@ -12,6 +14,11 @@ type User struct {
 	Source       string `json:"Source"`
 	ProviderName string `json:"ProviderName"`
 	IsAdmin      bool   `json:"IsAdmin"`
+	IsHelpdesk   bool   `json:"IsHelpdesk"` // helpdesk users support other users without full admin rights
+	Type         string `json:"Type"`       // the type of the user, either human or service
+
+	AdminInterfaces []string `json:"AdminInterfaces"` // the interfaces the user administrates without global admin rights
+	Organization    string   `json:"Organization"`    // the organization of the user, empty for users without organization
 
 	Firstname  string `json:"Firstname"`
 	Lastname   string `json:"Lastname"`
@@ -31,7 +38,8 @@ type User struct {
 
 	// Calculated
 
-	PeerCount int `json:"PeerCount"`
+	PeerCount int      `json:"PeerCount"`
+	Groups    []string `json:"Groups"` // the user groups of the user, managed in the user group settings
 }
 
 func NewUser(src *domain.User, exposeCreds bool) *User {
@@ -41,6 +49,10 @@ func NewUser(src *domain.User, exposeCreds bool) *User {
 		Source:          string(src.Source),
 		ProviderName:    src.ProviderName,
 		IsAdmin:         src.IsAdmin,
+		IsHelpdesk:      src.IsHelpdesk,
+		Type:            string(domain.UserTypeHuman),
+		AdminInterfaces: internal.SliceString(src.AdminInterfacesStr),
+		Organization:    string(src.OrganizationIdentifier),
 		Firstname:       src.Firstname,
 		Lastname:        src.Lastname,
 		Phone:           src.Phone,
@@ -56,6 +68,14 @@ func NewUser(src *domain.User, exposeCreds bool) *User {
 		ApiEnabled:      src.IsApiEnabled(),
 
 		PeerCount: src.LinkedPeerCount,
+		Groups:    make([]string, 0, len(src.Groups)),
+	}
+	for _, id := range src.GroupIdentifiers() {
+		u.Groups = append(u.Groups, string(id))
+	}
+
+	if src.IsServiceAccount() {
+		u.Type = string(domain.UserTypeService)
 	}
 
 	if exposeCreds {
@@ -77,24 +97,29 @@ func NewUsers(src []domain.User) []User {
 func NewDomainUser(src *User) *domain.User {
 	now := time.Now()
 	res := &domain.User{
-		Identifier:      domain.UserIdentifier(src.Identifier),
-		Email:           src.Email,
-		Source:          domain.UserSource(src.Source),
-		ProviderName:    src.ProviderName,
-		IsAdmin:         src.IsAdmin,
-		Firstname:       src.Firstname,
-		Lastname:        src.Lastname,
-		Phone:           src.Phone,
-		Department:      src.Department,
-		Notes:           src.Notes,
-		Password:        domain.PrivateString(src.Password),
-		Disabled:        nil, // set below
-		DisabledReason:  src.DisabledReason,
-		Locked:          nil, // set below
-		LockedReason:    src.LockedReason,
-		LinkedPeerCount: src.PeerCount,
+		Identifier:         domain.UserIdentifier(src.Identifier),
+		Email:              src.Email,
+		Source:             domain.UserSource(src.Source),
+		ProviderName:       src.ProviderName,
+		IsAdmin:            src.IsAdmin,
+		IsHelpdesk:         src.IsHelpdesk,
+		Type:               domain.UserType(src.Type),
+		AdminInterfacesStr: internal.SliceToString(src.AdminInterfaces),
+		Firstname:          src.Firstname,
+		Lastname:           src.Lastname,
+		Phone:              src.Phone,
+		Department:         src.Department,
+		Notes:              src.Notes,
+		Password:           domain.PrivateString(src.Password),
+		Disabled:           nil, // set below
+		DisabledReason:     src.DisabledReason,
+		Locked:             nil, // set below
+		LockedReason:       src.LockedReason,
+		LinkedPeerCount:    src.PeerCount,
 	}
 
+	res.OrganizationIdentifier = domain.OrganizationIdentifier(src.Organization)
+
 	if src.Disabled {
 		res.Disabled = &now
 		if src.DisabledReason == "" {
@@ -111,3 +136,88 @@ func NewDomainUser(src *User) *domain.User {
 
 	return res
 }
+
+type NotificationPreferences struct {
+	ConfigChannel   string    `json:"ConfigChannel" binding:"omitempty,oneof=mail none" example:"mail"`
+	ExpiryChannel   string    `json:"ExpiryChannel" binding:"omitempty,oneof=mail telegram none" example:"mail"`
+	SecurityChannel string    `json:"SecurityChannel" binding:"omitempty,oneof=mail telegram none" example:"telegram"`
+	TelegramChatId  string    `json:"TelegramChatId" example:"123456789"`
+	UpdatedAt       time.Time `json:"UpdatedAt"`
+}
+
+func NewNotificationPreferences(src *domain.UserNotificationPreferences) *NotificationPreferences {
+	return &NotificationPreferences{
+		ConfigChannel:   string(src.Channel(domain.NotificationCategoryConfig)),
+		ExpiryChannel:   string(src.Channel(domain.NotificationCategoryExpiry)),
+		SecurityChannel: string(src.Channel(domain.NotificationCategorySecurity)),
+		TelegramChatId:  src.TelegramChatId,
+		UpdatedAt:       src.UpdatedAt,
+	}
+}
+
+func NewDomainNotificationPreferences(
+	userId domain.UserIdentifier,
+	src *NotificationPreferences,
+) *domain.UserNotificationPreferences {
+	return &domain.UserNotificationPreferences{
+		UserIdentifier:  userId,
+		ConfigChannel:   domain.NotificationChannel(src.ConfigChannel),
+		ExpiryChannel:   domain.NotificationChannel(src.ExpiryChannel),
+		SecurityChannel: domain.NotificationChannel(src.SecurityChannel),
+		TelegramChatId:  strings.TrimSpace(src.TelegramChatId),
+	}
+}
+
+type SavedView struct {
+	Id             uint64            `json:"Id"`
+	Owner          string            `json:"Owner"` // the user that created the view, only the owner can change it
+	List           string            `json:"List" binding:"required,oneof=peers users" example:"peers"`
+	Name           string            `json:"Name" binding:"required,max=64" example:"Offline laptops"`
+	Filter         string            `json:"Filter"`                        // the search text
+	FilterOptions  map[string]string `json:"FilterOptions"`                 // list specific filters, for example, the device type of peers
+	Columns        []string          `json:"Columns"`                       // the visible columns, empty for all columns
+	SortKey        string            `json:"SortKey" example:"DisplayName"` // empty for the default order of the list
+	SortDescending bool              `json:"SortDescending"`
+	Shared         bool              `json:"Shared"` // shared views are visible to all administrators
+	UpdatedAt      time.Time         `json:"UpdatedAt"`
+}
+
+func NewSavedView(src *domain.SavedView) *SavedView {
+	return &SavedView{
+		Id:             src.Id,
+		Owner:          string(src.UserIdentifier),
+		List:           string(src.List),
+		Name:           src.Name,
+		Filter:         src.Filter,
+		FilterOptions:  src.FilterOptions,
+		Columns:        src.Columns(),
+		SortKey:        src.SortKey,
+		SortDescending: src.SortDescending,
+		Shared:         src.Shared,
+		UpdatedAt:      src.UpdatedAt,
+	}
+}
+
+func NewSavedViews(src []domain.SavedView) []SavedView {
+	results := make([]SavedView, len(src))
+	for i := range src {
+		results[i] = *NewSavedView(&src[i])
+	}
+
+	return results
+}
+
+func NewDomainSavedView(userId domain.UserIdentifier, src *SavedView) *domain.SavedView {
+	return &domain.SavedView{
+		Id:             src.Id,
+		UserIdentifier: userId,
+		List:           domain.SavedViewList(src.List),
+		Name:           src.Name,
+		Filter:         src.Filter,
+		FilterOptions:  src.FilterOptions,
+		ColumnsStr:     strings.Join(src.Columns, ","),
+		SortKey:        src.SortKey,
+		SortDescending: src.SortDescending,
+		Shared:         src.Shared,
+	}
+}
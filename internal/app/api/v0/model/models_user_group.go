@@ -0,0 +1,50 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type UserGroup struct {
+	Identifier  string `json:"Identifier" example:"lab-staff"` // user group unique identifier
+	DisplayName string `json:"DisplayName" example:"Lab Staff"`
+	Description string `json:"Description"`
+
+	Members         []string `json:"Members"`         // the user identifiers of the members
+	AdminInterfaces []string `json:"AdminInterfaces"` // the interfaces the members administrate
+	PeerRouteSets   []string `json:"PeerRouteSets"`   // the route sets that are attached to new peers of the members
+}
+
+// NewUserGroup creates a REST API UserGroup from a domain UserGroup.
+func NewUserGroup(src *domain.UserGroup) *UserGroup {
+	return &UserGroup{
+		Identifier:      string(src.Identifier),
+		DisplayName:     src.DisplayName,
+		Description:     src.Description,
+		Members:         internal.SliceString(src.MembersStr),
+		AdminInterfaces: internal.SliceString(src.AdminInterfacesStr),
+		PeerRouteSets:   internal.SliceString(src.PeerRouteSetsStr),
+	}
+}
+
+// NewUserGroups creates a slice of REST API UserGroups from a slice of domain UserGroups.
+func NewUserGroups(src []domain.UserGroup) []UserGroup {
+	results := make([]UserGroup, len(src))
+	for i := range src {
+		results[i] = *NewUserGroup(&src[i])
+	}
+
+	return results
+}
+
+// NewDomainUserGroup creates a domain UserGroup from a REST API UserGroup.
+func NewDomainUserGroup(src *UserGroup) *domain.UserGroup {
+	return &domain.UserGroup{
+		Identifier:         domain.UserGroupIdentifier(src.Identifier),
+		DisplayName:        src.DisplayName,
+		Description:        src.Description,
+		MembersStr:         internal.SliceToString(src.Members),
+		AdminInterfacesStr: internal.SliceToString(src.AdminInterfaces),
+		PeerRouteSetsStr:   internal.SliceToString(src.PeerRouteSets),
+	}
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type WebhookEndpoint struct {
+	Name          string    `json:"Name" example:"default"`
+	Url           string    `json:"Url"`
+	Cursor        uint64    `json:"Cursor"`      // the last event that was processed by the endpoint
+	ReplayUntil   uint64    `json:"ReplayUntil"` // events up to this id are delivered again
+	LastEventId   uint64    `json:"LastEventId"` // the most recent event in the event log
+	PendingEvents uint64    `json:"PendingEvents"`
+	UpdatedAt     time.Time `json:"UpdatedAt"`
+}
+
+func NewWebhookEndpoint(src domain.WebhookEndpointState) WebhookEndpoint {
+	return WebhookEndpoint{
+		Name:          src.Name,
+		Url:           src.Url,
+		Cursor:        src.Cursor.EventId,
+		ReplayUntil:   src.Cursor.ReplayUntil,
+		LastEventId:   src.LastEventId,
+		PendingEvents: src.PendingEvents(),
+		UpdatedAt:     src.Cursor.UpdatedAt,
+	}
+}
+
+func NewWebhookEndpoints(src []domain.WebhookEndpointState) []WebhookEndpoint {
+	results := make([]WebhookEndpoint, len(src))
+	for i := range src {
+		results[i] = NewWebhookEndpoint(src[i])
+	}
+
+	return results
+}
+
+type WebhookCursor struct {
+	EventId uint64 `json:"EventId"` // events after this id are delivered, 0 replays all stored events
+}
+
+type WebhookEvent struct {
+	Id         uint64            `json:"Id"`
+	CreatedAt  time.Time         `json:"CreatedAt"`
+	Event      string            `json:"Event" example:"create"`
+	Entity     string            `json:"Entity" example:"peer"`
+	Identifier string            `json:"Identifier"`
+	Payload    json.RawMessage   `json:"Payload"` // the request body that is sent to the endpoints
+	Deliveries []WebhookDelivery `json:"Deliveries"`
+}
+
+type WebhookDelivery struct {
+	Endpoint      string     `json:"Endpoint" example:"default"`
+	Status        string     `json:"Status" example:"delivered"` // pending, delivered or failed
+	Attempts      int        `json:"Attempts"`
+	Replays       int        `json:"Replays"`
+	LastAttemptAt *time.Time `json:"LastAttemptAt"`
+	LastError     string     `json:"LastError"`
+	DeliveredAt   *time.Time `json:"DeliveredAt"`
+}
+
+func NewWebhookEvent(src domain.WebhookEventLogEntry) WebhookEvent {
+	deliveries := make([]WebhookDelivery, len(src.Deliveries))
+	for i, delivery := range src.Deliveries {
+		deliveries[i] = WebhookDelivery{
+			Endpoint:      delivery.Endpoint,
+			Status:        string(delivery.Status),
+			Attempts:      delivery.Attempts,
+			Replays:       delivery.Replays,
+			LastAttemptAt: delivery.LastAttemptAt,
+			LastError:     delivery.LastError,
+			DeliveredAt:   delivery.DeliveredAt,
+		}
+	}
+
+	return WebhookEvent{
+		Id:         src.Event.Id,
+		CreatedAt:  src.Event.CreatedAt,
+		Event:      src.Event.Event,
+		Entity:     src.Event.Entity,
+		Identifier: src.Event.Identifier,
+		Payload:    src.Event.Payload,
+		Deliveries: deliveries,
+	}
+}
+
+func NewWebhookEvents(src []domain.WebhookEventLogEntry) []WebhookEvent {
+	results := make([]WebhookEvent, len(src))
+	for i := range src {
+		results[i] = NewWebhookEvent(src[i])
+	}
+
+	return results
+}
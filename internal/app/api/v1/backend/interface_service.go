@@ -40,6 +40,9 @@ func (s InterfaceService) GetAll(ctx context.Context) ([]domain.Interface, [][]d
 	if err != nil {
 		return nil, nil, err
 	}
+	for _, peers := range interfacePeers {
+		hidePrivateKeys(s.cfg, peers)
+	}
 
 	return interfaces, interfacePeers, nil
 }
@@ -57,6 +60,7 @@ func (s InterfaceService) GetById(ctx context.Context, id domain.InterfaceIdenti
 	if err != nil {
 		return nil, nil, err
 	}
+	hidePrivateKeys(s.cfg, interfacePeers)
 
 	return interfaceData, interfacePeers, nil
 }
@@ -105,6 +109,7 @@ func (s InterfaceService) Update(ctx context.Context, id domain.InterfaceIdentif
 	if err != nil {
 		return nil, nil, err
 	}
+	hidePrivateKeys(s.cfg, updatedPeers)
 
 	return updatedInterface, updatedPeers, nil
 }
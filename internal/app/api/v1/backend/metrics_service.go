@@ -3,6 +3,7 @@ package backend
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
@@ -15,6 +16,18 @@ type MetricsServiceDatabaseRepo interface {
 		error,
 	)
 	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	GetPeerStatsSamples(
+		ctx context.Context,
+		resolution domain.PeerStatsResolution,
+		from, to time.Time,
+		ids ...domain.PeerIdentifier,
+	) ([]domain.PeerStatsSample, error)
+	GetPeerConnectionEvents(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		from, to time.Time,
+	) ([]domain.PeerConnectionEvent, error)
 }
 
 type MetricsServiceUserManagerRepo interface {
@@ -55,8 +68,16 @@ func (m MetricsService) GetForInterface(ctx context.Context, id domain.Interface
 		return nil, fmt.Errorf("interface statistics collection is disabled")
 	}
 
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch interface %s: %w", id, err)
+	}
+
 	// validate admin rights
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
 		return nil, err
 	}
 
@@ -77,14 +98,13 @@ func (m MetricsService) GetForUser(ctx context.Context, id domain.UserIdentifier
 		return nil, nil, fmt.Errorf("statistics collection is disabled")
 	}
 
-	if err := domain.ValidateUserAccessRights(ctx, id); err != nil {
-		return nil, nil, err
-	}
-
 	user, err := m.users.GetUser(ctx, id)
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := domain.ValidateUserAccessRights(ctx, id, user.OrganizationIdentifier); err != nil {
+		return nil, nil, err
+	}
 
 	peers, err := m.db.GetUserPeers(ctx, user.Identifier)
 	if err != nil {
@@ -114,7 +134,7 @@ func (m MetricsService) GetForPeer(ctx context.Context, id domain.PeerIdentifier
 		return nil, err
 	}
 
-	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+	if err := m.validatePeerAccessRights(ctx, peer); err != nil {
 		return nil, err
 	}
 
@@ -129,3 +149,77 @@ func (m MetricsService) GetForPeer(ctx context.Context, id domain.PeerIdentifier
 
 	return &peerStats[0], nil
 }
+
+func (m MetricsService) GetHistoryForPeer(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	resolution domain.PeerStatsResolution,
+	since time.Time,
+) ([]domain.PeerStatsSample, error) {
+	if !m.cfg.Statistics.CollectPeerData || m.cfg.Statistics.SampleRetention == 0 {
+		return nil, fmt.Errorf("peer statistics samples are disabled")
+	}
+
+	switch resolution {
+	case domain.PeerStatsResolutionRaw, domain.PeerStatsResolutionHourly, domain.PeerStatsResolutionDaily:
+	default:
+		return nil, fmt.Errorf("invalid resolution %q: %w", resolution, domain.ErrInvalidData)
+	}
+
+	peer, err := m.peers.GetPeer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.validatePeerAccessRights(ctx, peer); err != nil {
+		return nil, err
+	}
+
+	samples, err := m.db.GetPeerStatsSamples(ctx, resolution, since, time.Now(), peer.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stats samples for peer %s: %w", peer.Identifier, err)
+	}
+
+	return samples, nil
+}
+
+func (m MetricsService) GetConnectionHistoryForPeer(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	from, to time.Time,
+) ([]domain.PeerConnectionEvent, error) {
+	if !m.cfg.Statistics.CollectPeerData || m.cfg.Statistics.ConnectionHistoryRetention == 0 {
+		return nil, fmt.Errorf("peer connection history is disabled")
+	}
+
+	if !from.Before(to) {
+		return nil, fmt.Errorf("the start of the time range must be before its end: %w", domain.ErrInvalidData)
+	}
+
+	peer, err := m.peers.GetPeer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.validatePeerAccessRights(ctx, peer); err != nil {
+		return nil, err
+	}
+
+	events, err := m.db.GetPeerConnectionEvents(ctx, peer.Identifier, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch connection events for peer %s: %w", peer.Identifier, err)
+	}
+
+	return events, nil
+}
+
+// validatePeerAccessRights checks if the current user is the owner of the given peer or an administrator of its
+// interface.
+func (m MetricsService) validatePeerAccessRights(ctx context.Context, peer *domain.Peer) error {
+	iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to fetch interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+
+	return domain.ValidateUserAccessRights(ctx, peer.UserIdentifier, iface.OrganizationIdentifier, iface.Identifier)
+}
@@ -43,23 +43,19 @@ func NewPeerService(
 }
 
 func (s PeerService) GetForInterface(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error) {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+	iface, interfacePeers, err := s.peers.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
 		return nil, err
 	}
-
-	_, interfacePeers, err := s.peers.GetInterfaceAndPeers(ctx, id)
-	if err != nil {
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
 		return nil, err
 	}
+	hidePrivateKeys(s.cfg, interfacePeers)
 
 	return interfacePeers, nil
 }
 
 func (s PeerService) GetForUser(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
-	if err := domain.ValidateUserAccessRights(ctx, id); err != nil {
-		return nil, err
-	}
-
 	if s.cfg.Advanced.ApiAdminOnly && !domain.GetUserInfo(ctx).IsAdmin {
 		return nil, errors.Join(errors.New("only admins can access this endpoint"), domain.ErrNoPermission)
 	}
@@ -68,11 +64,15 @@ func (s PeerService) GetForUser(ctx context.Context, id domain.UserIdentifier) (
 	if err != nil {
 		return nil, err
 	}
+	if err := domain.ValidateUserAccessRights(ctx, id, user.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
 
 	userPeers, err := s.peers.GetUserPeers(ctx, user.Identifier)
 	if err != nil {
 		return nil, err
 	}
+	hidePrivateKeys(s.cfg, userPeers)
 
 	return userPeers, nil
 }
@@ -82,22 +82,19 @@ func (s PeerService) GetById(ctx context.Context, id domain.PeerIdentifier) (*do
 		return nil, errors.Join(errors.New("only admins can access this endpoint"), domain.ErrNoPermission)
 	}
 
+	// The peer manager checks if the user has access rights to the requested peer.
+	// If the peer is not linked to any user, access is granted only for admins of the interface.
 	peer, err := s.peers.GetPeer(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-
-	// Check if the user has access rights to the requested peer.
-	// If the peer is not linked to any user, access is granted only for admins.
-	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
-		return nil, err
-	}
+	hidePrivateKey(s.cfg, peer)
 
 	return peer, nil
 }
 
 func (s PeerService) Prepare(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+	if err := s.validateInterfaceAdminAccessRights(ctx, id); err != nil {
 		return nil, err
 	}
 
@@ -110,7 +107,7 @@ func (s PeerService) Prepare(ctx context.Context, id domain.InterfaceIdentifier)
 }
 
 func (s PeerService) Create(ctx context.Context, peer *domain.Peer) (*domain.Peer, error) {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+	if err := s.validateInterfaceAdminAccessRights(ctx, peer.InterfaceIdentifier); err != nil {
 		return nil, err
 	}
 
@@ -131,7 +128,7 @@ func (s PeerService) Update(ctx context.Context, _ domain.PeerIdentifier, peer *
 	*domain.Peer,
 	error,
 ) {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+	if err := s.validatePeerAdminAccessRights(ctx, peer.Identifier); err != nil {
 		return nil, err
 	}
 
@@ -139,12 +136,13 @@ func (s PeerService) Update(ctx context.Context, _ domain.PeerIdentifier, peer *
 	if err != nil {
 		return nil, err
 	}
+	hidePrivateKey(s.cfg, updatedPeer)
 
 	return updatedPeer, nil
 }
 
 func (s PeerService) Delete(ctx context.Context, id domain.PeerIdentifier) error {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+	if err := s.validatePeerAdminAccessRights(ctx, id); err != nil {
 		return err
 	}
 
@@ -155,3 +153,51 @@ func (s PeerService) Delete(ctx context.Context, id domain.PeerIdentifier) error
 
 	return nil
 }
+
+// validatePeerAdminAccessRights checks if the current user is an administrator of the interface of the given peer.
+func (s PeerService) validatePeerAdminAccessRights(ctx context.Context, id domain.PeerIdentifier) error {
+	if domain.GetUserInfo(ctx).IsGlobalAdmin() {
+		return nil
+	}
+
+	peer, err := s.peers.GetPeer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.validateInterfaceAdminAccessRights(ctx, peer.InterfaceIdentifier)
+}
+
+// validateInterfaceAdminAccessRights checks if the current user is an administrator of the given interface.
+func (s PeerService) validateInterfaceAdminAccessRights(ctx context.Context, id domain.InterfaceIdentifier) error {
+	if domain.GetUserInfo(ctx).IsGlobalAdmin() {
+		return nil
+	}
+
+	iface, _, err := s.peers.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return domain.ValidateInterfaceAdminAccessRights(ctx, iface)
+}
+
+// hidePrivateKeys removes the private keys from the given peers if they are only revealed after a re-authentication.
+// The keys are still part of the provisioning data.
+func hidePrivateKeys(cfg *config.Config, peers []domain.Peer) {
+	if !cfg.KeyReveal.StepUpRequired {
+		return
+	}
+
+	for i := range peers {
+		peers[i].Interface.PrivateKey = ""
+	}
+}
+
+// hidePrivateKey removes the private key from the given peer if private keys are only revealed after a
+// re-authentication.
+func hidePrivateKey(cfg *config.Config, peer *domain.Peer) {
+	if cfg.KeyReveal.StepUpRequired {
+		peer.Interface.PrivateKey = ""
+	}
+}
@@ -2,8 +2,10 @@ package backend
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/h44z/wg-portal/internal/app/api/v1/models"
 	"github.com/h44z/wg-portal/internal/config"
@@ -25,6 +27,11 @@ type ProvisioningServicePeerManagerRepo interface {
 type ProvisioningServiceConfigFileManagerRepo interface {
 	GetPeerConfig(ctx context.Context, id domain.PeerIdentifier) (io.Reader, error)
 	GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier) (io.Reader, error)
+	GetSigningPublicKey(ctx context.Context) (string, error)
+}
+
+type ProvisioningServiceEphemeralPeerManagerRepo interface {
+	CreatePeer(ctx context.Context, peer *domain.Peer, lifetime time.Duration) (*domain.Peer, error)
 }
 
 type ProvisioningService struct {
@@ -33,6 +40,7 @@ type ProvisioningService struct {
 	users       ProvisioningServiceUserManagerRepo
 	peers       ProvisioningServicePeerManagerRepo
 	configFiles ProvisioningServiceConfigFileManagerRepo
+	ephemeral   ProvisioningServiceEphemeralPeerManagerRepo
 }
 
 func NewProvisioningService(
@@ -40,6 +48,7 @@ func NewProvisioningService(
 	users ProvisioningServiceUserManagerRepo,
 	peers ProvisioningServicePeerManagerRepo,
 	configFiles ProvisioningServiceConfigFileManagerRepo,
+	ephemeral ProvisioningServiceEphemeralPeerManagerRepo,
 ) *ProvisioningService {
 	return &ProvisioningService{
 		cfg: cfg,
@@ -47,6 +56,7 @@ func NewProvisioningService(
 		users:       users,
 		peers:       peers,
 		configFiles: configFiles,
+		ephemeral:   ephemeral,
 	}
 }
 
@@ -74,7 +84,7 @@ func (p ProvisioningService) GetUserAndPeers(
 		return nil, nil, fmt.Errorf("either UserId or Email must be set: %w", domain.ErrInvalidData)
 	}
 
-	if err := domain.ValidateUserAccessRights(ctx, user.Identifier); err != nil {
+	if err := domain.ValidateUserAccessRights(ctx, user.Identifier, user.OrganizationIdentifier); err != nil {
 		return nil, nil, err
 	}
 
@@ -92,7 +102,7 @@ func (p ProvisioningService) GetPeerConfig(ctx context.Context, peerId domain.Pe
 		return nil, err
 	}
 
-	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+	if err := validatePeerOwnerAccessRights(ctx, peer); err != nil {
 		return nil, err
 	}
 
@@ -109,13 +119,23 @@ func (p ProvisioningService) GetPeerConfig(ctx context.Context, peerId domain.Pe
 	return peerCfgData, nil
 }
 
+// GetConfigSigningKey returns the PEM encoded public key that verifies the signatures of the configuration files.
+func (p ProvisioningService) GetConfigSigningKey(ctx context.Context) ([]byte, error) {
+	key, err := p.configFiles.GetSigningPublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(key), nil
+}
+
 func (p ProvisioningService) GetPeerQrPng(ctx context.Context, peerId domain.PeerIdentifier) ([]byte, error) {
 	peer, err := p.peers.GetPeer(ctx, peerId)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+	if err := validatePeerOwnerAccessRights(ctx, peer); err != nil {
 		return nil, err
 	}
 
@@ -138,7 +158,11 @@ func (p ProvisioningService) NewPeer(ctx context.Context, req models.Provisionin
 	}
 
 	// check permissions
-	if err := domain.ValidateUserAccessRights(ctx, domain.UserIdentifier(req.UserIdentifier)); err != nil {
+	user, err := p.users.GetUser(ctx, domain.UserIdentifier(req.UserIdentifier))
+	if err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateUserAccessRights(ctx, user.Identifier, user.OrganizationIdentifier); err != nil {
 		return nil, err
 	}
 	if !p.cfg.Core.SelfProvisioningAllowed {
@@ -172,3 +196,59 @@ func (p ProvisioningService) NewPeer(ctx context.Context, req models.Provisionin
 
 	return peer, nil
 }
+
+// NewEphemeralPeer issues a new ephemeral peer that is deleted after the requested lifetime. It returns the new peer
+// and its configuration file. Only interface administrators can issue ephemeral peers.
+func (p ProvisioningService) NewEphemeralPeer(ctx context.Context, req models.EphemeralPeerRequest) (
+	*domain.Peer,
+	[]byte,
+	error,
+) {
+	var lifetime time.Duration
+	if req.Lifetime != "" {
+		var err error
+		if lifetime, err = time.ParseDuration(req.Lifetime); err != nil {
+			return nil, nil, errors.Join(fmt.Errorf("invalid lifetime %s", req.Lifetime), domain.ErrInvalidData)
+		}
+	}
+
+	// the ephemeral peer manager validates the admin rights for the interface
+	interfaceId := domain.InterfaceIdentifier(req.InterfaceIdentifier)
+	if !domain.GetUserInfo(ctx).HasAdminInterfaces() {
+		return nil, nil, domain.ErrNoPermission
+	}
+
+	peer, err := p.peers.PreparePeer(ctx, interfaceId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to prepare new peer: %w", err)
+	}
+	peer.UserIdentifier = domain.UserIdentifier(req.UserIdentifier)
+	if req.PublicKey != "" {
+		peer.Identifier = domain.PeerIdentifier(req.PublicKey)
+		peer.Interface.PublicKey = req.PublicKey
+		peer.Interface.PrivateKey = "" // WireGuard Portal does not know the private key in that case
+	}
+	peer.GenerateDisplayName("Ephemeral")
+
+	peer, err = p.ephemeral.CreatePeer(ctx, peer, lifetime)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create ephemeral peer: %w", err)
+	}
+
+	peerCfgReader, err := p.configFiles.GetPeerConfig(ctx, peer.Identifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	peerCfgData, err := io.ReadAll(peerCfgReader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return peer, peerCfgData, nil
+}
+
+// validatePeerOwnerAccessRights checks if the current user is the owner of the given peer or an admin. The peer
+// manager only returns peers of interfaces that belong to the organization of an admin.
+func validatePeerOwnerAccessRights(ctx context.Context, peer *domain.Peer) error {
+	return domain.ValidateUserAccessRights(ctx, peer.UserIdentifier, domain.GetUserInfo(ctx).Organization)
+}
@@ -44,10 +44,6 @@ func (s UserService) GetAll(ctx context.Context) ([]domain.User, error) {
 }
 
 func (s UserService) GetById(ctx context.Context, id domain.UserIdentifier) (*domain.User, error) {
-	if err := domain.ValidateUserAccessRights(ctx, id); err != nil {
-		return nil, err
-	}
-
 	if s.cfg.Advanced.ApiAdminOnly && !domain.GetUserInfo(ctx).IsAdmin {
 		return nil, errors.Join(errors.New("only admins can access this endpoint"), domain.ErrNoPermission)
 	}
@@ -56,6 +52,9 @@ func (s UserService) GetById(ctx context.Context, id domain.UserIdentifier) (*do
 	if err != nil {
 		return nil, err
 	}
+	if err := domain.ValidateUserAccessRights(ctx, id, user.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
 
 	return user, nil
 }
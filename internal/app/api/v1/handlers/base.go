@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/go-pkgz/routegroup"
 
 	"github.com/h44z/wg-portal/internal/app/api/core"
 	"github.com/h44z/wg-portal/internal/app/api/core/middleware/cors"
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
 	"github.com/h44z/wg-portal/internal/app/api/v1/models"
 	"github.com/h44z/wg-portal/internal/domain"
 )
@@ -65,20 +68,44 @@ func ParseServiceError(err error) (int, models.Error) {
 	switch {
 	case errors.Is(err, domain.ErrNotFound):
 		code = http.StatusNotFound
-	case errors.Is(err, domain.ErrNoPermission):
+	case errors.Is(err, domain.ErrNoPermission), errors.Is(err, domain.ErrApprovalRequired):
 		code = http.StatusForbidden
-	case errors.Is(err, domain.ErrDuplicateEntry):
+	case errors.Is(err, domain.ErrDuplicateEntry), errors.Is(err, domain.ErrQuotaExceeded):
 		code = http.StatusConflict
 	case errors.Is(err, domain.ErrInvalidData):
 		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrConfigTooLarge), errors.Is(err, domain.ErrIdempotencyKeyReused):
+		code = http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrStepUpRequired):
+		code = http.StatusPreconditionRequired
+	}
+
+	var details string
+	var applyErr *domain.PeerApplyError
+	if errors.As(err, &applyErr) {
+		details = fmt.Sprintf("peer %s failed: %v; %d applied peers rolled back", applyErr.Peer, applyErr.Err,
+			len(applyErr.RolledBack))
+		if !applyErr.RollbackComplete() {
+			details += fmt.Sprintf(", %d peers could not be restored", len(applyErr.RollbackFailures))
+		}
 	}
 
 	return code, models.Error{
 		Code:    code,
 		Message: err.Error(),
+		Details: details,
 	}
 }
 
+// withClientInfo adds the IP address and user agent of the requesting client to the given context.
+// Services use this information for auditing, for example when private keys are revealed.
+func withClientInfo(ctx context.Context, r *http.Request) context.Context {
+	return domain.SetClientInfo(ctx, domain.ClientInfo{
+		IpAddress: request.ClientIp(r, request.CheckPrivateProxy),
+		UserAgent: request.Header(r, "User-Agent"),
+	})
+}
+
 // region handler-interfaces
 
 type Authenticator interface {
@@ -86,6 +113,11 @@ type Authenticator interface {
 	LoggedIn(scopes ...Scope) func(next http.Handler) http.Handler
 }
 
+type Idempotency interface {
+	// Idempotent replays the stored response of requests with an Idempotency-Key header that were already executed.
+	Idempotent() func(next http.Handler) http.Handler
+}
+
 type Validator interface {
 	// Struct validates the given struct.
 	Struct(s interface{}) error
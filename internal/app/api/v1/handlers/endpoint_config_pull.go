@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type ConfigPullEndpointConfigPullService interface {
+	CreateToken(ctx context.Context, peerId domain.PeerIdentifier) (string, *domain.ConfigPullToken, error)
+	PullConfig(ctx context.Context, plainToken string, client domain.ConfigPullClient) (
+		domain.PeerIdentifier,
+		[]byte,
+		error,
+	)
+}
+
+type ConfigPullEndpoint struct {
+	cfg           *config.Config
+	configPull    ConfigPullEndpointConfigPullService
+	authenticator Authenticator
+}
+
+func NewConfigPullEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	configPull ConfigPullEndpointConfigPullService,
+) *ConfigPullEndpoint {
+	return &ConfigPullEndpoint{
+		cfg:           cfg,
+		configPull:    configPull,
+		authenticator: authenticator,
+	}
+}
+
+func (e ConfigPullEndpoint) GetName() string {
+	return "ConfigPullEndpoint"
+}
+
+func (e ConfigPullEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/provisioning")
+
+	// the pull endpoint is authenticated with the pull token instead of the user credentials
+	apiGroup.HandleFunc("GET /config-pull", e.handlePullGet())
+	apiGroup.With(e.authenticator.LoggedIn()).HandleFunc("POST /config-pull/token", e.handleTokenPost())
+}
+
+// handlePullGet returns a gorm Handler function.
+//
+// @ID provisioning_handleConfigPullGet
+// @Tags Provisioning
+// @Summary Fetch the latest peer configuration in wg-quick format using a config pull token.
+// @Description The pull token must be sent as bearer token. The response contains an ETag header. If the
+// @Description If-None-Match header of the request matches the current ETag, 304 Not Modified is returned.
+// @Description The X-Poll-Interval header contains the recommended poll interval in seconds.
+// @Description Clients should report their operating system and app version, either with the X-Client-Os and
+// @Description X-Client-Version headers or with a User-Agent in the form "<app>/<version> (<os>)".
+// @Param Authorization header string true "Bearer <pull token>"
+// @Param X-Client-Os header string false "The operating system of the client, for example: windows"
+// @Param X-Client-Version header string false "The app version of the client, for example: 0.5.3"
+// @Param If-None-Match header string false "The ETag of the configuration the client currently uses."
+// @Produce plain
+// @Produce json
+// @Success 200 {string} string "The WireGuard configuration file"
+// @Success 304 "The configuration did not change"
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 410 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /provisioning/config-pull [get]
+func (e ConfigPullEndpoint) handlePullGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(request.Header(r, "Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="config-pull"`)
+			respond.JSON(w, http.StatusUnauthorized,
+				models.Error{Code: http.StatusUnauthorized, Message: "missing pull token"})
+			return
+		}
+
+		client := domain.ParseConfigPullClient(request.ClientIp(r, request.CheckPrivateProxy),
+			request.Header(r, "User-Agent"), request.Header(r, "X-Client-Os"), request.Header(r, "X-Client-Version"))
+		_, peerConfig, err := e.configPull.PullConfig(r.Context(), strings.TrimSpace(token), client)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			respond.JSON(w, http.StatusGone, models.Error{Code: http.StatusGone, Message: err.Error()})
+			return
+		case err != nil:
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		sum := sha256.Sum256(peerConfig)
+		etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Poll-Interval", strconv.Itoa(int(e.cfg.ConfigPull.PollInterval.Seconds())))
+
+		if request.Header(r, "If-None-Match") == etag {
+			respond.Status(w, http.StatusNotModified)
+			return
+		}
+
+		respond.Data(w, http.StatusOK, "text/plain", peerConfig)
+	}
+}
+
+// handleTokenPost returns a gorm Handler function.
+//
+// @ID provisioning_handleConfigPullTokenPost
+// @Tags Provisioning
+// @Summary Create a new config pull token for the given peer. An existing token of the peer is revoked.
+// @Description Normal users can only create tokens for their own peers. Admins can create tokens for all peers.
+// @Param PeerId query string true "The peer identifier (public key)."
+// @Produce json
+// @Success 200 {object} models.ConfigPullToken
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /provisioning/config-pull/token [post]
+// @Security BasicAuth
+func (e ConfigPullEndpoint) handleTokenPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(request.Query(r, "PeerId"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		plainToken, token, err := e.configPull.CreateToken(r.Context(), domain.PeerIdentifier(id))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		pullUrl := e.cfg.Web.ExternalUrl + "/api/v1/provisioning/config-pull"
+		respond.JSON(w, http.StatusOK, models.NewConfigPullToken(plainToken, token, pullUrl))
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// deviceCodeGrantType is the grant type of the device token request, as defined in RFC 8628, section 3.4.
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+type DeviceEndpointDeviceAuthService interface {
+	RequestAuthorization(
+		ctx context.Context,
+		clientName string,
+		interfaceId domain.InterfaceIdentifier,
+		publicKey string,
+	) (*domain.DeviceAuthorization, error)
+	PollAuthorization(ctx context.Context, deviceCode string) (domain.PeerIdentifier, []byte, error)
+}
+
+type DeviceEndpoint struct {
+	cfg        *config.Config
+	deviceAuth DeviceEndpointDeviceAuthService
+}
+
+func NewDeviceEndpoint(cfg *config.Config, deviceAuth DeviceEndpointDeviceAuthService) *DeviceEndpoint {
+	return &DeviceEndpoint{
+		cfg:        cfg,
+		deviceAuth: deviceAuth,
+	}
+}
+
+func (e DeviceEndpoint) GetName() string {
+	return "DeviceEndpoint"
+}
+
+func (e DeviceEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	// the device endpoints do not require authentication, the user confirms the request in the web UI
+	apiGroup := g.Mount("/provisioning/device")
+
+	apiGroup.HandleFunc("POST /code", e.handleCodePost())
+	apiGroup.HandleFunc("POST /token", e.handleTokenPost())
+}
+
+// handleCodePost returns a gorm Handler function.
+//
+// @ID provisioning_handleDeviceCodePost
+// @Tags Provisioning
+// @Summary Start the OAuth2 device authorization flow for a headless device.
+// @Description The device shows the returned user code and verification URL to the user and polls the token
+// @Description endpoint until the user confirmed the code in the web UI. No authentication is required.
+// @Accept x-www-form-urlencoded
+// @Param client_id formData string false "A name for the device, used as the display name of a new peer."
+// @Param interface_id formData string false "The interface identifier for a new peer."
+// @Param public_key formData string false "The public key of the device. If not set, a new key pair is generated."
+// @Produce json
+// @Success 200 {object} models.DeviceAuthorizationResponse
+// @Failure 400 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /provisioning/device/code [post]
+func (e DeviceEndpoint) handleCodePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := e.deviceAuth.RequestAuthorization(r.Context(),
+			request.Form(r, "client_id"),
+			domain.InterfaceIdentifier(request.Form(r, "interface_id")),
+			request.Form(r, "public_key"))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		verificationUri := e.cfg.Web.ExternalUrl + "/app/#/device"
+
+		w.Header().Set("Cache-Control", "no-store")
+		respond.JSON(w, http.StatusOK, models.NewDeviceAuthorizationResponse(req, verificationUri))
+	}
+}
+
+// handleTokenPost returns a gorm Handler function.
+//
+// @ID provisioning_handleDeviceTokenPost
+// @Tags Provisioning
+// @Summary Poll the result of the OAuth2 device authorization flow.
+// @Description Once the user confirmed the code, the peer configuration is returned. The device code can only be
+// @Description used once. While the code is not confirmed, the error authorization_pending is returned.
+// @Accept x-www-form-urlencoded
+// @Param grant_type formData string true "Must be urn:ietf:params:oauth:grant-type:device_code"
+// @Param device_code formData string true "The device code."
+// @Produce json
+// @Success 200 {object} models.DeviceTokenResponse
+// @Failure 400 {object} models.DeviceTokenError
+// @Failure 500 {object} models.DeviceTokenError
+// @Router /provisioning/device/token [post]
+func (e DeviceEndpoint) handleTokenPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		if request.Form(r, "grant_type") != deviceCodeGrantType {
+			respond.JSON(w, http.StatusBadRequest, models.DeviceTokenError{Error: "unsupported_grant_type"})
+			return
+		}
+		deviceCode := request.Form(r, "device_code")
+		if deviceCode == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.DeviceTokenError{Error: "invalid_request", ErrorDescription: "missing device_code"})
+			return
+		}
+
+		peerId, peerConfig, err := e.deviceAuth.PollAuthorization(r.Context(), deviceCode)
+		if err != nil {
+			respondDeviceTokenError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.DeviceTokenResponse{
+			PeerIdentifier: string(peerId),
+			PeerConfig:     string(peerConfig),
+		})
+	}
+}
+
+func respondDeviceTokenError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrDeviceAuthorizationPending),
+		errors.Is(err, domain.ErrDeviceSlowDown),
+		errors.Is(err, domain.ErrDeviceAccessDenied),
+		errors.Is(err, domain.ErrDeviceExpiredToken):
+		respond.JSON(w, http.StatusBadRequest, models.DeviceTokenError{Error: err.Error()})
+	case errors.Is(err, domain.ErrNoPermission):
+		respond.JSON(w, http.StatusBadRequest,
+			models.DeviceTokenError{Error: "unauthorized_client", ErrorDescription: err.Error()})
+	default:
+		respond.JSON(w, http.StatusInternalServerError,
+			models.DeviceTokenError{Error: "server_error", ErrorDescription: err.Error()})
+	}
+}
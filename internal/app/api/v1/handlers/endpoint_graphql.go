@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/graphql"
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type GraphQLEndpoint struct {
+	cfg           *config.Config
+	authenticator Authenticator
+
+	users      UserService
+	peers      PeerService
+	interfaces InterfaceEndpointInterfaceService
+	metrics    MetricsEndpointStatisticsService
+
+	schema           *graphql.Schema
+	persistedQueries map[string]string // persisted queries by identifier and by SHA-256 hash
+}
+
+func NewGraphQLEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	users UserService,
+	peers PeerService,
+	interfaces InterfaceEndpointInterfaceService,
+	metrics MetricsEndpointStatisticsService,
+) *GraphQLEndpoint {
+	e := &GraphQLEndpoint{
+		cfg:              cfg,
+		authenticator:    authenticator,
+		users:            users,
+		peers:            peers,
+		interfaces:       interfaces,
+		metrics:          metrics,
+		persistedQueries: make(map[string]string, 2*len(cfg.GraphQL.PersistedQueries)),
+	}
+	e.schema = e.buildSchema()
+
+	for id, query := range cfg.GraphQL.PersistedQueries {
+		hash := sha256.Sum256([]byte(query))
+		e.persistedQueries[id] = query
+		e.persistedQueries[hex.EncodeToString(hash[:])] = query
+	}
+
+	return e
+}
+
+func (e GraphQLEndpoint) GetName() string {
+	return "GraphQLEndpoint"
+}
+
+func (e GraphQLEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/graphql")
+	// the resolvers use the same services as the REST endpoints, so the permissions are validated by the services
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.HandleFunc("GET /query", e.handleQueryGet())
+	apiGroup.HandleFunc("POST /query", e.handleQueryPost())
+}
+
+// handleQueryGet returns a gorm Handler function.
+//
+// @ID graphql_handleQueryGet
+// @Tags GraphQL
+// @Summary Execute a GraphQL query, mainly used for persisted queries.
+// @Description The GraphQL endpoint must be enabled in the configuration. Each field is resolved with the permissions
+// @Description of the REST API: fields that the user is not allowed to access are null and listed in the errors.
+// @Param query query string false "The GraphQL query document, required if no persisted query id is given"
+// @Param id query string false "The identifier or SHA-256 hash of a persisted query"
+// @Param operationName query string false "The operation to execute"
+// @Param variables query string false "The JSON encoded variables of the operation"
+// @Produce json
+// @Success 200 {object} object
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Router /graphql/query [get]
+// @Security BasicAuth
+func (e GraphQLEndpoint) handleQueryGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := models.GraphQLRequest{
+			Query:         request.Query(r, "query"),
+			OperationName: request.Query(r, "operationName"),
+			Id:            request.Query(r, "id"),
+		}
+		if variables := request.Query(r, "variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				respond.JSON(w, http.StatusBadRequest,
+					models.Error{Code: http.StatusBadRequest, Message: "invalid variables: " + err.Error()})
+				return
+			}
+		}
+
+		e.execute(w, r, req)
+	}
+}
+
+// handleQueryPost returns a gorm Handler function.
+//
+// @ID graphql_handleQueryPost
+// @Tags GraphQL
+// @Summary Execute a GraphQL query.
+// @Description The GraphQL endpoint must be enabled in the configuration. Each field is resolved with the permissions
+// @Description of the REST API: fields that the user is not allowed to access are null and listed in the errors.
+// @Param request body models.GraphQLRequest true "The GraphQL request"
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Router /graphql/query [post]
+// @Security BasicAuth
+func (e GraphQLEndpoint) handleQueryPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.GraphQLRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		e.execute(w, r, req)
+	}
+}
+
+func (e GraphQLEndpoint) execute(w http.ResponseWriter, r *http.Request, req models.GraphQLRequest) {
+	if !e.cfg.GraphQL.Enabled {
+		respond.JSON(w, http.StatusNotFound,
+			models.Error{Code: http.StatusNotFound, Message: "the GraphQL endpoint is disabled"})
+		return
+	}
+
+	query := req.Query
+	if id := req.PersistedQueryId(); id != "" {
+		persistedQuery, ok := e.persistedQueries[strings.ToLower(id)]
+		if !ok {
+			persistedQuery, ok = e.persistedQueries[id]
+		}
+		if !ok {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "unknown persisted query " + id})
+			return
+		}
+		query = persistedQuery
+	} else if e.cfg.GraphQL.PersistedQueriesOnly {
+		respond.JSON(w, http.StatusBadRequest,
+			models.Error{Code: http.StatusBadRequest, Message: "only persisted queries are allowed"})
+		return
+	}
+	if query == "" {
+		respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: "missing query"})
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, e.schema.Execute(r.Context(), query, req.OperationName, req.Variables))
+}
+
+// region schema
+
+// buildSchema creates the GraphQL schema. The object types contain the fields of the REST API models, so the
+// field names and values match the REST API. Additional fields link the related users, peers, interfaces and
+// metrics.
+func (e GraphQLEndpoint) buildSchema() *graphql.Schema {
+	userType := modelObject[models.User]("User")
+	peerType := modelObject[models.Peer]("Peer")
+	interfaceType := modelObject[models.Interface]("Interface")
+	peerMetricsType := modelObject[models.PeerMetrics]("PeerMetrics")
+	interfaceMetricsType := modelObject[models.InterfaceMetrics]("InterfaceMetrics")
+
+	userType.Fields["Peers"] = &graphql.Field{Type: peerType,
+		Resolve: func(ctx context.Context, source any, _ graphql.Arguments) (any, error) {
+			return e.userPeers(ctx, domain.UserIdentifier(modelSource[models.User](source).Identifier))
+		}}
+	peerType.Fields["User"] = &graphql.Field{Type: userType,
+		Resolve: func(ctx context.Context, source any, _ graphql.Arguments) (any, error) {
+			userId := modelSource[models.Peer](source).UserIdentifier
+			if userId == "" {
+				return nil, nil
+			}
+			return e.user(ctx, domain.UserIdentifier(userId))
+		}}
+	peerType.Fields["Interface"] = &graphql.Field{Type: interfaceType,
+		Resolve: func(ctx context.Context, source any, _ graphql.Arguments) (any, error) {
+			return e.iface(ctx, domain.InterfaceIdentifier(modelSource[models.Peer](source).InterfaceIdentifier))
+		}}
+	peerType.Fields["Metrics"] = &graphql.Field{Type: peerMetricsType,
+		Resolve: func(ctx context.Context, source any, _ graphql.Arguments) (any, error) {
+			peerMetrics, err := e.metrics.GetForPeer(ctx,
+				domain.PeerIdentifier(modelSource[models.Peer](source).Identifier))
+			if errors.Is(err, domain.ErrNotFound) {
+				return nil, nil // no statistics collected yet
+			}
+			if err != nil {
+				return nil, err
+			}
+			return models.NewPeerMetrics(peerMetrics), nil
+		}}
+	interfaceType.Fields["Peers"] = &graphql.Field{Type: peerType,
+		Resolve: func(ctx context.Context, source any, _ graphql.Arguments) (any, error) {
+			return e.interfacePeers(ctx, domain.InterfaceIdentifier(modelSource[models.Interface](source).Identifier))
+		}}
+	interfaceType.Fields["Metrics"] = &graphql.Field{Type: interfaceMetricsType,
+		Resolve: func(ctx context.Context, source any, _ graphql.Arguments) (any, error) {
+			interfaceMetrics, err := e.metrics.GetForInterface(ctx,
+				domain.InterfaceIdentifier(modelSource[models.Interface](source).Identifier))
+			if errors.Is(err, domain.ErrNotFound) {
+				return nil, nil // no statistics collected yet
+			}
+			if err != nil {
+				return nil, err
+			}
+			return models.NewInterfaceMetrics(interfaceMetrics), nil
+		}}
+
+	queryType := &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"Me": {Type: userType,
+			Resolve: func(ctx context.Context, _ any, _ graphql.Arguments) (any, error) {
+				return e.user(ctx, domain.GetUserInfo(ctx).Id)
+			}},
+		"User": {Type: userType, Arguments: map[string]bool{"Identifier": true},
+			Resolve: func(ctx context.Context, _ any, args graphql.Arguments) (any, error) {
+				id, err := args.String("Identifier")
+				if err != nil {
+					return nil, err
+				}
+				return e.user(ctx, domain.UserIdentifier(id))
+			}},
+		"Users": {Type: userType,
+			Resolve: func(ctx context.Context, _ any, _ graphql.Arguments) (any, error) {
+				users, err := e.users.GetAll(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return models.NewUsers(users), nil
+			}},
+		"Peer": {Type: peerType, Arguments: map[string]bool{"Identifier": true},
+			Resolve: func(ctx context.Context, _ any, args graphql.Arguments) (any, error) {
+				id, err := args.String("Identifier")
+				if err != nil {
+					return nil, err
+				}
+				peer, err := e.peers.GetById(ctx, domain.PeerIdentifier(id))
+				if err != nil {
+					return nil, err
+				}
+				return models.NewPeer(peer), nil
+			}},
+		"Peers": {Type: peerType, Arguments: map[string]bool{"InterfaceIdentifier": false, "UserIdentifier": false},
+			Resolve: func(ctx context.Context, _ any, args graphql.Arguments) (any, error) {
+				interfaceId, err := args.String("InterfaceIdentifier")
+				if err != nil {
+					return nil, err
+				}
+				userId, err := args.String("UserIdentifier")
+				if err != nil {
+					return nil, err
+				}
+				switch {
+				case interfaceId != "" && userId == "":
+					return e.interfacePeers(ctx, domain.InterfaceIdentifier(interfaceId))
+				case userId != "" && interfaceId == "":
+					return e.userPeers(ctx, domain.UserIdentifier(userId))
+				default:
+					return nil, errors.New("either InterfaceIdentifier or UserIdentifier is required")
+				}
+			}},
+		"Interface": {Type: interfaceType, Arguments: map[string]bool{"Identifier": true},
+			Resolve: func(ctx context.Context, _ any, args graphql.Arguments) (any, error) {
+				id, err := args.String("Identifier")
+				if err != nil {
+					return nil, err
+				}
+				return e.iface(ctx, domain.InterfaceIdentifier(id))
+			}},
+		"Interfaces": {Type: interfaceType,
+			Resolve: func(ctx context.Context, _ any, _ graphql.Arguments) (any, error) {
+				interfaces, interfacePeers, err := e.interfaces.GetAll(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return models.NewInterfaces(interfaces, interfacePeers), nil
+			}},
+	}}
+
+	return &graphql.Schema{Query: queryType, MaxDepth: e.cfg.GraphQL.MaxDepth}
+}
+
+func (e GraphQLEndpoint) user(ctx context.Context, id domain.UserIdentifier) (*models.User, error) {
+	user, err := e.users.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewUser(user, false), nil
+}
+
+func (e GraphQLEndpoint) userPeers(ctx context.Context, id domain.UserIdentifier) ([]models.Peer, error) {
+	peers, err := e.peers.GetForUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewPeers(peers), nil
+}
+
+func (e GraphQLEndpoint) iface(ctx context.Context, id domain.InterfaceIdentifier) (*models.Interface, error) {
+	iface, peers, err := e.interfaces.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewInterface(iface, peers), nil
+}
+
+func (e GraphQLEndpoint) interfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]models.Peer, error) {
+	peers, err := e.peers.GetForInterface(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewPeers(peers), nil
+}
+
+// modelObject creates an object type with a scalar field for each JSON field of the given REST API model.
+func modelObject[T any](name string) *graphql.Object {
+	object := &graphql.Object{Name: name, Fields: make(map[string]*graphql.Field)}
+
+	modelType := reflect.TypeFor[T]()
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+
+		index := field.Index
+		object.Fields[jsonName] = &graphql.Field{
+			Resolve: func(_ context.Context, source any, _ graphql.Arguments) (any, error) {
+				return reflect.ValueOf(modelSource[T](source)).Elem().FieldByIndex(index).Interface(), nil
+			},
+		}
+	}
+
+	return object
+}
+
+// modelSource returns the model of a resolved object, which is either a model value or a pointer to a model.
+func modelSource[T any](source any) *T {
+	if model, ok := source.(*T); ok {
+		return model
+	}
+	model := source.(T)
+	return &model
+}
+
+// endregion schema
@@ -25,16 +25,19 @@ type InterfaceEndpoint struct {
 	interfaces    InterfaceEndpointInterfaceService
 	authenticator Authenticator
 	validator     Validator
+	idempotency   Idempotency
 }
 
 func NewInterfaceEndpoint(
 	authenticator Authenticator,
 	validator Validator,
+	idempotency Idempotency,
 	interfaceService InterfaceEndpointInterfaceService,
 ) *InterfaceEndpoint {
 	return &InterfaceEndpoint{
 		authenticator: authenticator,
 		validator:     validator,
+		idempotency:   idempotency,
 		interfaces:    interfaceService,
 	}
 }
@@ -51,7 +54,7 @@ func (e InterfaceEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup.HandleFunc("GET /by-id/{id}", e.handleByIdGet())
 
 	apiGroup.HandleFunc("GET /prepare", e.handlePrepareGet())
-	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
+	apiGroup.With(e.idempotency.Idempotent()).HandleFunc("POST /new", e.handleCreatePost())
 	apiGroup.HandleFunc("PUT /by-id/{id}", e.handleUpdatePut())
 	apiGroup.HandleFunc("DELETE /by-id/{id}", e.handleDelete())
 }
@@ -61,8 +64,15 @@ func (e InterfaceEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 // @ID interface_handleAllGet
 // @Tags Interfaces
 // @Summary Get all interface records.
+// @Param limit query int false "The maximum number of records per page (1-1000). All records are returned if omitted."
+// @Param cursor query string false "The cursor of the page to return, taken from the X-Next-Cursor header of the previous page."
+// @Param filter query []string false "Filter expressions of the form Field:operator:value. Operators: eq, ne, co, sw, gt, ge, lt, le, in." collectionFormat(multi)
+// @Param fields query string false "A comma separated list of fields to return."
 // @Produce json
 // @Success 200 {object} []models.Interface
+// @Header 200 {integer} X-Total-Count "The number of records matching the filters."
+// @Header 200 {string} X-Next-Cursor "The cursor of the next page, missing on the last page."
+// @Failure 400 {object} models.Error
 // @Failure 401 {object} models.Error
 // @Failure 500 {object} models.Error
 // @Router /interface/all [get]
@@ -76,7 +86,8 @@ func (e InterfaceEndpoint) handleAllGet() http.HandlerFunc {
 			return
 		}
 
-		respond.JSON(w, http.StatusOK, models.NewInterfaces(allInterfaces, allPeersPerInterface))
+		respondList(w, r, models.NewInterfaces(allInterfaces, allPeersPerInterface),
+			func(i models.Interface) string { return i.Identifier })
 	}
 }
 
@@ -147,12 +158,14 @@ func (e InterfaceEndpoint) handlePrepareGet() http.HandlerFunc {
 // @Summary Create a new interface record.
 // @Description This endpoint creates a new interface with the provided data. All required fields must be filled (e.g. name, private key, public key, ...).
 // @Param request body models.Interface true "The interface data."
+// @Param Idempotency-Key header string false "A unique key of the request. Retries with the same key within 24 hours receive the original response."
 // @Produce json
 // @Success 200 {object} models.Interface
 // @Failure 400 {object} models.Error
 // @Failure 401 {object} models.Error
 // @Failure 403 {object} models.Error
 // @Failure 409 {object} models.Error
+// @Failure 422 {object} models.Error
 // @Failure 500 {object} models.Error
 // @Router /interface/new [post]
 // @Security BasicAuth
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/go-pkgz/routegroup"
 
@@ -16,6 +17,17 @@ type MetricsEndpointStatisticsService interface {
 	GetForInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.InterfaceStatus, error)
 	GetForUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, []domain.PeerStatus, error)
 	GetForPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.PeerStatus, error)
+	GetHistoryForPeer(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		resolution domain.PeerStatsResolution,
+		since time.Time,
+	) ([]domain.PeerStatsSample, error)
+	GetConnectionHistoryForPeer(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		from, to time.Time,
+	) ([]domain.PeerConnectionEvent, error)
 }
 
 type MetricsEndpoint struct {
@@ -44,10 +56,12 @@ func (e MetricsEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup := g.Mount("/metrics")
 	apiGroup.Use(e.authenticator.LoggedIn())
 
-	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /by-interface/{id}",
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc("GET /by-interface/{id}",
 		e.handleMetricsForInterfaceGet())
 	apiGroup.HandleFunc("GET /by-user/{id}", e.handleMetricsForUserGet())
 	apiGroup.HandleFunc("GET /by-peer/{id}", e.handleMetricsForPeerGet())
+	apiGroup.HandleFunc("GET /by-peer/{id}/history", e.handleMetricsHistoryForPeerGet())
+	apiGroup.HandleFunc("GET /by-peer/{id}/connections", e.handleConnectionHistoryForPeerGet())
 }
 
 // handleMetricsForInterfaceGet returns a gorm Handler function.
@@ -151,3 +165,110 @@ func (e MetricsEndpoint) handleMetricsForPeerGet() http.HandlerFunc {
 		respond.JSON(w, http.StatusOK, models.NewPeerMetrics(peerMetrics))
 	}
 }
+
+// handleMetricsHistoryForPeerGet returns a gorm Handler function.
+//
+// @ID metrics_handleMetricsHistoryForPeerGet
+// @Tags Metrics
+// @Summary Get the traffic history of a WireGuard Portal peer.
+// @Param id path string true "The peer identifier (public key)."
+// @Param Resolution query string false "The sample resolution: raw, hourly or daily. Defaults to hourly."
+// @Param Since query string false "Only return samples since this time (RFC 3339). Defaults to the last 24 hours."
+// @Produce json
+// @Success 200 {object} models.PeerMetricsHistory
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /metrics/by-peer/{id}/history [get]
+// @Security BasicAuth
+func (e MetricsEndpoint) handleMetricsHistoryForPeerGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		resolution := domain.PeerStatsResolution(request.QueryDefault(r, "Resolution",
+			string(domain.PeerStatsResolutionHourly)))
+		since := time.Now().Add(-24 * time.Hour)
+		if sinceStr := request.Query(r, "Since"); sinceStr != "" {
+			var err error
+			since, err = time.Parse(time.RFC3339, sinceStr)
+			if err != nil {
+				respond.JSON(w, http.StatusBadRequest,
+					models.Error{Code: http.StatusBadRequest, Message: "invalid since time: " + err.Error()})
+				return
+			}
+		}
+
+		samples, err := e.metrics.GetHistoryForPeer(r.Context(), domain.PeerIdentifier(id), resolution, since)
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewPeerMetricsHistory(domain.PeerIdentifier(id), resolution, samples))
+	}
+}
+
+// handleConnectionHistoryForPeerGet returns a gorm Handler function.
+//
+// @ID metrics_handleConnectionHistoryForPeerGet
+// @Tags Metrics
+// @Summary Get the connection history of a WireGuard Portal peer.
+// @Description Returns the connects, disconnects and endpoint changes of the peer within the given time range.
+// @Param id path string true "The peer identifier (public key)."
+// @Param From query string false "The start of the time range (RFC 3339). Defaults to seven days before the end."
+// @Param To query string false "The end of the time range (RFC 3339). Defaults to the current time."
+// @Produce json
+// @Success 200 {object} models.PeerConnectionHistory
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /metrics/by-peer/{id}/connections [get]
+// @Security BasicAuth
+func (e MetricsEndpoint) handleConnectionHistoryForPeerGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		to := time.Now()
+		if toStr := request.Query(r, "To"); toStr != "" {
+			var err error
+			to, err = time.Parse(time.RFC3339, toStr)
+			if err != nil {
+				respond.JSON(w, http.StatusBadRequest,
+					models.Error{Code: http.StatusBadRequest, Message: "invalid to time: " + err.Error()})
+				return
+			}
+		}
+		from := to.Add(-7 * 24 * time.Hour)
+		if fromStr := request.Query(r, "From"); fromStr != "" {
+			var err error
+			from, err = time.Parse(time.RFC3339, fromStr)
+			if err != nil {
+				respond.JSON(w, http.StatusBadRequest,
+					models.Error{Code: http.StatusBadRequest, Message: "invalid from time: " + err.Error()})
+				return
+			}
+		}
+
+		events, err := e.metrics.GetConnectionHistoryForPeer(r.Context(), domain.PeerIdentifier(id), from, to)
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewPeerConnectionHistory(domain.PeerIdentifier(id), from, to, events))
+	}
+}
@@ -26,15 +26,19 @@ type PeerEndpoint struct {
 	peers         PeerService
 	authenticator Authenticator
 	validator     Validator
+	idempotency   Idempotency
 }
 
 func NewPeerEndpoint(
 	authenticator Authenticator,
-	validator Validator, peerService PeerService,
+	validator Validator,
+	idempotency Idempotency,
+	peerService PeerService,
 ) *PeerEndpoint {
 	return &PeerEndpoint{
 		authenticator: authenticator,
 		validator:     validator,
+		idempotency:   idempotency,
 		peers:         peerService,
 	}
 }
@@ -47,15 +51,16 @@ func (e PeerEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup := g.Mount("/peer")
 	apiGroup.Use(e.authenticator.LoggedIn())
 
-	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /by-interface/{id}",
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc("GET /by-interface/{id}",
 		e.handleAllForInterfaceGet())
 	apiGroup.HandleFunc("GET /by-user/{id}", e.handleAllForUserGet())
 	apiGroup.HandleFunc("GET /by-id/{id}", e.handleByIdGet())
 
-	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /prepare/{id}", e.handlePrepareGet())
-	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /new", e.handleCreatePost())
-	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("PUT /by-id/{id}", e.handleUpdatePut())
-	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("DELETE /by-id/{id}", e.handleDelete())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc("GET /prepare/{id}", e.handlePrepareGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin), e.idempotency.Idempotent()).HandleFunc("POST /new",
+		e.handleCreatePost())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc("PUT /by-id/{id}", e.handleUpdatePut())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeInterfaceAdmin)).HandleFunc("DELETE /by-id/{id}", e.handleDelete())
 }
 
 // handleAllForInterfaceGet returns a gorm Handler function.
@@ -64,8 +69,15 @@ func (e PeerEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 // @Tags Peers
 // @Summary Get all peer records for a given WireGuard interface.
 // @Param id path string true "The WireGuard interface identifier."
+// @Param limit query int false "The maximum number of records per page (1-1000). All records are returned if omitted."
+// @Param cursor query string false "The cursor of the page to return, taken from the X-Next-Cursor header of the previous page."
+// @Param filter query []string false "Filter expressions of the form Field:operator:value. Operators: eq, ne, co, sw, gt, ge, lt, le, in." collectionFormat(multi)
+// @Param fields query string false "A comma separated list of fields to return."
 // @Produce json
 // @Success 200 {object} []models.Peer
+// @Header 200 {integer} X-Total-Count "The number of records matching the filters."
+// @Header 200 {string} X-Next-Cursor "The cursor of the next page, missing on the last page."
+// @Failure 400 {object} models.Error
 // @Failure 401 {object} models.Error
 // @Failure 500 {object} models.Error
 // @Router /peer/by-interface/{id} [get]
@@ -86,7 +98,7 @@ func (e PeerEndpoint) handleAllForInterfaceGet() http.HandlerFunc {
 			return
 		}
 
-		respond.JSON(w, http.StatusOK, models.NewPeers(interfacePeers))
+		respondList(w, r, models.NewPeers(interfacePeers), func(p models.Peer) string { return p.Identifier })
 	}
 }
 
@@ -97,8 +109,15 @@ func (e PeerEndpoint) handleAllForInterfaceGet() http.HandlerFunc {
 // @Summary Get all peer records for a given user.
 // @Description Normal users can only access their own records. Admins can access all records.
 // @Param id path string true "The user identifier."
+// @Param limit query int false "The maximum number of records per page (1-1000). All records are returned if omitted."
+// @Param cursor query string false "The cursor of the page to return, taken from the X-Next-Cursor header of the previous page."
+// @Param filter query []string false "Filter expressions of the form Field:operator:value. Operators: eq, ne, co, sw, gt, ge, lt, le, in." collectionFormat(multi)
+// @Param fields query string false "A comma separated list of fields to return."
 // @Produce json
 // @Success 200 {object} []models.Peer
+// @Header 200 {integer} X-Total-Count "The number of records matching the filters."
+// @Header 200 {string} X-Next-Cursor "The cursor of the next page, missing on the last page."
+// @Failure 400 {object} models.Error
 // @Failure 401 {object} models.Error
 // @Failure 500 {object} models.Error
 // @Router /peer/by-user/{id} [get]
@@ -119,7 +138,7 @@ func (e PeerEndpoint) handleAllForUserGet() http.HandlerFunc {
 			return
 		}
 
-		respond.JSON(w, http.StatusOK, models.NewPeers(interfacePeers))
+		respondList(w, r, models.NewPeers(interfacePeers), func(p models.Peer) string { return p.Identifier })
 	}
 }
 
@@ -201,12 +220,14 @@ func (e PeerEndpoint) handlePrepareGet() http.HandlerFunc {
 // @Summary Create a new peer record.
 // @Description Only admins can create new records. The peer record must contain all required fields (e.g., public key, allowed IPs).
 // @Param request body models.Peer true "The peer data."
+// @Param Idempotency-Key header string false "A unique key of the request. Retries with the same key within 24 hours receive the original response."
 // @Produce json
 // @Success 200 {object} models.Peer
 // @Failure 400 {object} models.Error
 // @Failure 401 {object} models.Error
 // @Failure 403 {object} models.Error
 // @Failure 409 {object} models.Error
+// @Failure 422 {object} models.Error
 // @Failure 500 {object} models.Error
 // @Router /peer/new [post]
 // @Security BasicAuth
@@ -21,23 +21,28 @@ type ProvisioningEndpointProvisioningService interface {
 	)
 	GetPeerConfig(ctx context.Context, peerId domain.PeerIdentifier) ([]byte, error)
 	GetPeerQrPng(ctx context.Context, peerId domain.PeerIdentifier) ([]byte, error)
+	GetConfigSigningKey(ctx context.Context) ([]byte, error)
 	NewPeer(ctx context.Context, req models.ProvisioningRequest) (*domain.Peer, error)
+	NewEphemeralPeer(ctx context.Context, req models.EphemeralPeerRequest) (*domain.Peer, []byte, error)
 }
 
 type ProvisioningEndpoint struct {
 	provisioning  ProvisioningEndpointProvisioningService
 	authenticator Authenticator
 	validator     Validator
+	idempotency   Idempotency
 }
 
 func NewProvisioningEndpoint(
 	authenticator Authenticator,
 	validator Validator,
+	idempotency Idempotency,
 	provisioning ProvisioningEndpointProvisioningService,
 ) *ProvisioningEndpoint {
 	return &ProvisioningEndpoint{
 		authenticator: authenticator,
 		validator:     validator,
+		idempotency:   idempotency,
 		provisioning:  provisioning,
 	}
 }
@@ -53,8 +58,11 @@ func (e ProvisioningEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup.HandleFunc("GET /data/user-info", e.handleUserInfoGet())
 	apiGroup.HandleFunc("GET /data/peer-config", e.handlePeerConfigGet())
 	apiGroup.HandleFunc("GET /data/peer-qr", e.handlePeerQrGet())
+	apiGroup.HandleFunc("GET /data/config-signing-key", e.handleConfigSigningKeyGet())
 
-	apiGroup.HandleFunc("POST /new-peer", e.handleNewPeerPost())
+	apiGroup.With(e.idempotency.Idempotent()).HandleFunc("POST /new-peer", e.handleNewPeerPost())
+	apiGroup.With(e.idempotency.Idempotent()).HandleFunc("POST /new-ephemeral-peer",
+		e.handleNewEphemeralPeerPost())
 }
 
 // handleUserInfoGet returns a gorm Handler function.
@@ -120,7 +128,7 @@ func (e ProvisioningEndpoint) handlePeerConfigGet() http.HandlerFunc {
 			return
 		}
 
-		peerConfig, err := e.provisioning.GetPeerConfig(r.Context(), domain.PeerIdentifier(id))
+		peerConfig, err := e.provisioning.GetPeerConfig(withClientInfo(r.Context(), r), domain.PeerIdentifier(id))
 		if err != nil {
 			status, model := ParseServiceError(err)
 			respond.JSON(w, status, model)
@@ -131,6 +139,33 @@ func (e ProvisioningEndpoint) handlePeerConfigGet() http.HandlerFunc {
 	}
 }
 
+// handleConfigSigningKeyGet returns a gorm Handler function.
+//
+// @ID provisioning_handleConfigSigningKeyGet
+// @Tags Provisioning
+// @Summary Get the public key that verifies the signatures of the generated configuration files.
+// @Description The key is PEM encoded. If config signing is disabled, 404 is returned.
+// @Produce plain
+// @Produce json
+// @Success 200 {string} string "The PEM encoded Ed25519 public key"
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /provisioning/data/config-signing-key [get]
+// @Security BasicAuth
+func (e ProvisioningEndpoint) handleConfigSigningKeyGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := e.provisioning.GetConfigSigningKey(r.Context())
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.Data(w, http.StatusOK, "text/plain", key)
+	}
+}
+
 // handlePeerQrGet returns a gorm Handler function.
 //
 // @ID provisioning_handlePeerQrGet
@@ -145,6 +180,7 @@ func (e ProvisioningEndpoint) handlePeerConfigGet() http.HandlerFunc {
 // @Failure 401 {object} models.Error
 // @Failure 403 {object} models.Error
 // @Failure 404 {object} models.Error
+// @Failure 422 {object} models.Error "The configuration is too large for a QR code"
 // @Failure 500 {object} models.Error
 // @Router /provisioning/data/peer-qr [get]
 // @Security BasicAuth
@@ -157,7 +193,7 @@ func (e ProvisioningEndpoint) handlePeerQrGet() http.HandlerFunc {
 			return
 		}
 
-		peerConfigQrCode, err := e.provisioning.GetPeerQrPng(r.Context(), domain.PeerIdentifier(id))
+		peerConfigQrCode, err := e.provisioning.GetPeerQrPng(withClientInfo(r.Context(), r), domain.PeerIdentifier(id))
 		if err != nil {
 			status, model := ParseServiceError(err)
 			respond.JSON(w, status, model)
@@ -175,12 +211,15 @@ func (e ProvisioningEndpoint) handlePeerQrGet() http.HandlerFunc {
 // @Summary Create a new peer for the given interface and user.
 // @Description Normal users can only create new peers if self provisioning is allowed. Admins can always add new peers.
 // @Param request body models.ProvisioningRequest true "Provisioning request model."
+// @Param Idempotency-Key header string false "A unique key of the request. Retries with the same key within 24 hours receive the original response."
 // @Produce json
 // @Success 200 {object} models.Peer
 // @Failure 400 {object} models.Error
 // @Failure 401 {object} models.Error
 // @Failure 403 {object} models.Error
 // @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error "The peer quota is exceeded"
+// @Failure 422 {object} models.Error
 // @Failure 500 {object} models.Error
 // @Router /provisioning/new-peer [post]
 // @Security BasicAuth
@@ -206,3 +245,45 @@ func (e ProvisioningEndpoint) handleNewPeerPost() http.HandlerFunc {
 		respond.JSON(w, http.StatusOK, models.NewPeer(peer))
 	}
 }
+
+// handleNewEphemeralPeerPost returns a gorm Handler function.
+//
+// @ID provisioning_handleNewEphemeralPeerPost
+// @Tags Provisioning
+// @Summary Issue a short-lived peer for the given interface.
+// @Description Only interface admins can issue ephemeral peers. The peer is deleted automatically after the requested lifetime,
+// @Description which removes its keys and its firewall access. The response contains the peer configuration in wg-quick format.
+// @Param request body models.EphemeralPeerRequest true "Ephemeral peer request model."
+// @Param Idempotency-Key header string false "A unique key of the request. Retries with the same key within 24 hours receive the original response."
+// @Produce json
+// @Success 200 {object} models.EphemeralPeer
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error "The peer quota is exceeded"
+// @Failure 500 {object} models.Error
+// @Router /provisioning/new-ephemeral-peer [post]
+// @Security BasicAuth
+func (e ProvisioningEndpoint) handleNewEphemeralPeerPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.EphemeralPeerRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		peer, peerConfig, err := e.provisioning.NewEphemeralPeer(r.Context(), req)
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewEphemeralPeer(peer, peerConfig))
+	}
+}
@@ -46,7 +46,7 @@ func (e UserEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup := g.Mount("/user")
 	apiGroup.Use(e.authenticator.LoggedIn())
 
-	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeHelpdesk)).HandleFunc("GET /all", e.handleAllGet())
 	apiGroup.HandleFunc("GET /by-id/{id}", e.handleByIdGet())
 	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /new", e.handleCreatePost())
 	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("PUT /by-id/{id}", e.handleUpdatePut())
@@ -58,8 +58,16 @@ func (e UserEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 // @ID users_handleAllGet
 // @Tags Users
 // @Summary Get all user records.
+// @Description Only admins and helpdesk users can access all records.
+// @Param limit query int false "The maximum number of records per page (1-1000). All records are returned if omitted."
+// @Param cursor query string false "The cursor of the page to return, taken from the X-Next-Cursor header of the previous page."
+// @Param filter query []string false "Filter expressions of the form Field:operator:value. Operators: eq, ne, co, sw, gt, ge, lt, le, in." collectionFormat(multi)
+// @Param fields query string false "A comma separated list of fields to return."
 // @Produce json
 // @Success 200 {object} []models.User
+// @Header 200 {integer} X-Total-Count "The number of records matching the filters."
+// @Header 200 {string} X-Next-Cursor "The cursor of the next page, missing on the last page."
+// @Failure 400 {object} models.Error
 // @Failure 401 {object} models.Error
 // @Failure 500 {object} models.Error
 // @Router /user/all [get]
@@ -73,7 +81,7 @@ func (e UserEndpoint) handleAllGet() http.HandlerFunc {
 			return
 		}
 
-		respond.JSON(w, http.StatusOK, models.NewUsers(users))
+		respondList(w, r, models.NewUsers(users), func(u models.User) string { return u.Identifier })
 	}
 }
 
@@ -82,7 +90,7 @@ func (e UserEndpoint) handleAllGet() http.HandlerFunc {
 // @ID users_handleByIdGet
 // @Tags Users
 // @Summary Get a specific user record by its internal identifier.
-// @Description Normal users can only access their own record. Admins can access all records.
+// @Description Normal users can only access their own record. Admins and helpdesk users can access all records.
 // @Param id path string true "The user identifier."
 // @Produce json
 // @Success 200 {object} models.User
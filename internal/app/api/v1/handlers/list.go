@@ -0,0 +1,444 @@
+package handlers
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	// maxListLimit is the maximum number of records that are returned in a single page.
+	maxListLimit = 1000
+
+	headerTotalCount = "X-Total-Count"
+	headerNextCursor = "X-Next-Cursor"
+)
+
+// listFilter is a single filter expression of the form Field:operator:value.
+type listFilter struct {
+	Path     []string
+	Operator string
+	Value    string
+}
+
+// listOptions contains the pagination, filter and field selection parameters of a list request.
+type listOptions struct {
+	Limit   int // zero means that all records are returned
+	Cursor  string
+	Filters []listFilter
+	Fields  []string
+}
+
+// listOperators are all supported filter operators.
+var listOperators = []string{"eq", "ne", "co", "sw", "gt", "ge", "lt", "le", "in"}
+
+// parseListOptions parses the limit, cursor, filter and fields query parameters of a list request.
+// Field names are validated against the JSON field names of the model type T.
+func parseListOptions[T any](r *http.Request) (listOptions, error) {
+	var opts listOptions
+	modelType := reflect.TypeFor[T]()
+
+	if limit := request.Query(r, "limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxListLimit {
+			return opts, fmt.Errorf("limit must be a number between 1 and %d: %w", maxListLimit,
+				domain.ErrInvalidData)
+		}
+		opts.Limit = value
+	}
+
+	if cursor := request.Query(r, "cursor"); cursor != "" {
+		value, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || len(value) == 0 {
+			return opts, fmt.Errorf("invalid cursor: %w", domain.ErrInvalidData)
+		}
+		opts.Cursor = string(value)
+	}
+
+	for _, expression := range request.QuerySlice(r, "filter") {
+		parts := strings.SplitN(expression, ":", 3)
+		if len(parts) != 3 {
+			return opts, fmt.Errorf("invalid filter %q, expected Field:operator:value: %w", expression,
+				domain.ErrInvalidData)
+		}
+		path := strings.Split(parts[0], ".")
+		if _, ok := lookupModelField(modelType, path[0]); !ok {
+			return opts, fmt.Errorf("unknown filter field %q: %w", parts[0], domain.ErrInvalidData)
+		}
+		if !slices.Contains(listOperators, parts[1]) {
+			return opts, fmt.Errorf("unknown filter operator %q: %w", parts[1], domain.ErrInvalidData)
+		}
+		opts.Filters = append(opts.Filters, listFilter{Path: path, Operator: parts[1], Value: parts[2]})
+	}
+
+	for _, fields := range request.QuerySlice(r, "fields") {
+		for _, field := range strings.Split(fields, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			if _, ok := lookupModelField(modelType, field); !ok {
+				return opts, fmt.Errorf("unknown field %q: %w", field, domain.ErrInvalidData)
+			}
+			if !slices.Contains(opts.Fields, field) {
+				opts.Fields = append(opts.Fields, field)
+			}
+		}
+	}
+
+	return opts, nil
+}
+
+// listPage is the result of applying list options to a list of records.
+type listPage[T any] struct {
+	Items      []T
+	Total      int    // the number of records matching the filters
+	NextCursor string // empty if there are no more records
+}
+
+// applyListOptions filters the given records, orders them by their key and returns the requested page.
+// The key must be unique, it is used as stable sort order and to resume the listing with a cursor.
+func applyListOptions[T any](items []T, key func(T) string, opts listOptions) listPage[T] {
+	var page listPage[T]
+
+	matching := make([]T, 0, len(items))
+	for _, item := range items {
+		if matchesFilters(item, opts.Filters) {
+			matching = append(matching, item)
+		}
+	}
+
+	slices.SortStableFunc(matching, func(a, b T) int {
+		return strings.Compare(key(a), key(b))
+	})
+	page.Total = len(matching)
+
+	if opts.Cursor != "" {
+		start, _ := slices.BinarySearchFunc(matching, opts.Cursor, func(item T, cursor string) int {
+			return strings.Compare(key(item), cursor)
+		})
+		if start < len(matching) && key(matching[start]) == opts.Cursor {
+			start++ // the cursor points to the last record of the previous page
+		}
+		matching = matching[start:]
+	}
+
+	if opts.Limit > 0 && len(matching) > opts.Limit {
+		matching = matching[:opts.Limit]
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(key(matching[len(matching)-1])))
+	}
+	page.Items = matching
+
+	return page
+}
+
+// respondList writes a page of the given records as JSON array. The total number of matching records and the cursor
+// of the next page are returned in the X-Total-Count, X-Next-Cursor and Link headers.
+func respondList[T any](w http.ResponseWriter, r *http.Request, items []T, key func(T) string) {
+	opts, err := parseListOptions[T](r)
+	if err != nil {
+		status, model := ParseServiceError(err)
+		respond.JSON(w, status, model)
+		return
+	}
+
+	page := applyListOptions(items, key, opts)
+
+	w.Header().Set(headerTotalCount, strconv.Itoa(page.Total))
+	if page.NextCursor != "" {
+		w.Header().Set(headerNextCursor, page.NextCursor)
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", nextPageUrl(r, page.NextCursor)))
+	}
+
+	if len(opts.Fields) == 0 {
+		respond.JSON(w, http.StatusOK, page.Items)
+		return
+	}
+	respond.JSON(w, http.StatusOK, selectFields(page.Items, opts.Fields))
+}
+
+// nextPageUrl returns the request URL with the cursor query parameter replaced by the given cursor.
+func nextPageUrl(r *http.Request, cursor string) string {
+	query := r.URL.Query()
+	query.Set("cursor", cursor)
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return next.String()
+}
+
+// matchesFilters reports whether the record matches all filters.
+func matchesFilters[T any](item T, filters []listFilter) bool {
+	record := reflect.ValueOf(item)
+	for _, filter := range filters {
+		if !filter.matches(fieldValue(record, filter.Path)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matches reports whether the given field value fulfills the filter. Slices match if any of their elements matches,
+// except for the ne operator, which requires that no element equals the filter value.
+func (f listFilter) matches(value reflect.Value) bool {
+	value = indirect(value)
+	if isListValue(value) {
+		element := f
+		if f.Operator == "ne" {
+			element.Operator = "eq"
+		}
+		for i := 0; i < value.Len(); i++ {
+			if element.matches(value.Index(i)) {
+				return f.Operator != "ne"
+			}
+		}
+		return f.Operator == "ne"
+	}
+
+	str, number, isNumber := fieldScalar(value)
+	switch f.Operator {
+	case "eq":
+		return str == f.Value
+	case "ne":
+		return str != f.Value
+	case "co":
+		return strings.Contains(strings.ToLower(str), strings.ToLower(f.Value))
+	case "sw":
+		return strings.HasPrefix(strings.ToLower(str), strings.ToLower(f.Value))
+	case "in":
+		return slices.Contains(strings.Split(f.Value, "|"), str)
+	}
+
+	// numeric fields are compared by value, all other fields lexically
+	cmp := strings.Compare(str, f.Value)
+	if isNumber {
+		filterNumber, err := strconv.ParseFloat(f.Value, 64)
+		if err != nil {
+			return false
+		}
+		cmp = compareFloat(number, filterNumber)
+	}
+
+	switch f.Operator {
+	case "gt":
+		return cmp > 0
+	case "ge":
+		return cmp >= 0
+	case "lt":
+		return cmp < 0
+	case "le":
+		return cmp <= 0
+	}
+
+	return false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+var textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+
+// isListValue reports whether the value is encoded as JSON array.
+func isListValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice:
+		return value.Type().Elem().Kind() != reflect.Uint8 // byte slices are encoded as string
+	case reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
+
+// fieldScalar returns the value as it is represented in JSON, without quotes. For numbers, the numeric value is
+// returned as well.
+func fieldScalar(value reflect.Value) (str string, number float64, isNumber bool) {
+	if !value.IsValid() {
+		return "", 0, false
+	}
+	if value.Type().Implements(textMarshalerType) && value.CanInterface() {
+		text, _ := value.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), 0, false
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), 0, false
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), 0, false
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64), value.Float(), true
+	case reflect.Slice:
+		return base64.StdEncoding.EncodeToString(value.Bytes()), 0, false
+	default:
+		encoded, _ := json.Marshal(value.Interface())
+		return string(encoded), 0, false
+	}
+}
+
+// fieldValue returns the field of the record at the given (dotted) path. The returned value is invalid if the path
+// does not exist or if the field is omitted from the JSON representation of the record.
+func fieldValue(record reflect.Value, path []string) reflect.Value {
+	value := record
+	for _, name := range path {
+		value = indirect(value)
+		switch value.Kind() {
+		case reflect.Struct:
+			field, ok := lookupModelField(value.Type(), name)
+			if !ok {
+				return reflect.Value{}
+			}
+			var err error
+			value, err = value.FieldByIndexErr(field.Index)
+			if err != nil || (field.OmitEmpty && isEmptyValue(value)) {
+				return reflect.Value{} // nil embedded struct or omitted field
+			}
+		case reflect.Map:
+			if value.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}
+			}
+			value = value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key()))
+		default:
+			return reflect.Value{}
+		}
+	}
+
+	return value
+}
+
+// indirect dereferences pointers and interfaces. The returned value is invalid for nil values.
+func indirect(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// isEmptyValue reports whether the value is omitted by the omitempty option of encoding/json.
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return value.IsZero()
+	default:
+		return false
+	}
+}
+
+// selectFields reduces the given records to the given top-level fields.
+func selectFields[T any](items []T, fields []string) []map[string]any {
+	selected := make([]map[string]any, len(items))
+	for i := range items {
+		record := reflect.ValueOf(items[i])
+
+		selected[i] = make(map[string]any, len(fields))
+		for _, field := range fields {
+			if value := fieldValue(record, []string{field}); value.IsValid() {
+				selected[i][field] = value.Interface()
+			}
+		}
+	}
+
+	return selected
+}
+
+// modelField is a field of a model type, identified by its JSON name.
+type modelField struct {
+	Name      string
+	Index     []int // the index sequence for reflect.Value.FieldByIndex
+	OmitEmpty bool
+}
+
+// modelFieldCache caches the fields of each model type, see modelFields.
+var modelFieldCache sync.Map // reflect.Type -> map[string]modelField
+
+// lookupModelField returns the top-level field with the given JSON name of the given struct type.
+func lookupModelField(t reflect.Type, name string) (modelField, bool) {
+	field, ok := modelFields(t)[name]
+	return field, ok
+}
+
+// modelFields returns the top-level fields of the given struct type, keyed by their JSON name. Fields of embedded
+// structs without JSON name are promoted, like encoding/json does.
+func modelFields(t reflect.Type) map[string]modelField {
+	t = indirectType(t)
+	if cached, ok := modelFieldCache.Load(t); ok {
+		return cached.(map[string]modelField)
+	}
+
+	fields := make(map[string]modelField)
+	if t.Kind() == reflect.Struct {
+		collectModelFields(fields, t, nil)
+	}
+	modelFieldCache.Store(t, fields)
+
+	return fields
+}
+
+func collectModelFields(fields map[string]modelField, t reflect.Type, index []int) {
+	t = indirectType(t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !(field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct) {
+			continue // the exported fields of embedded structs are promoted, even if the struct is unexported
+		}
+		fieldIndex := append(slices.Clone(index), i)
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case name == "-":
+			continue
+		case name == "" && field.Anonymous:
+			collectModelFields(fields, field.Type, fieldIndex)
+			continue
+		case name == "":
+			name = field.Name
+		}
+		if _, ok := fields[name]; ok && len(index) > 0 {
+			continue // fields of the outer struct take precedence over promoted fields
+		}
+		fields[name] = modelField{
+			Name:      name,
+			Index:     fieldIndex,
+			OmitEmpty: slices.Contains(strings.Split(options, ","), "omitempty"),
+		}
+	}
+}
+
+// indirectType returns the element type of (nested) pointer types.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type listTestRecord struct {
+	Identifier string   `json:"Identifier"`
+	Name       string   `json:"Name"`
+	Port       int      `json:"Port"`
+	Enabled    bool     `json:"Enabled"`
+	Tags       []string `json:"Tags"`
+	Hidden     string   `json:"-"`
+}
+
+func listTestRecords() []listTestRecord {
+	return []listTestRecord{
+		{Identifier: "c", Name: "Charlie", Port: 51822, Enabled: true, Tags: []string{"office"}},
+		{Identifier: "a", Name: "Alpha", Port: 51820, Enabled: true, Tags: []string{"home", "office"}},
+		{Identifier: "d", Name: "Delta", Port: 9000, Enabled: false},
+		{Identifier: "b", Name: "Bravo", Port: 51821, Enabled: false, Tags: []string{"home"}},
+	}
+}
+
+func listTestKey(r listTestRecord) string { return r.Identifier }
+
+func listTestOptions(t *testing.T, query string) (listOptions, error) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/test/all?"+query, nil)
+	return parseListOptions[listTestRecord](r)
+}
+
+func listTestIds(items []listTestRecord) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.Identifier
+	}
+	return ids
+}
+
+func TestParseListOptions_invalid(t *testing.T) {
+	for _, query := range []string{
+		"limit=0",
+		"limit=1001",
+		"limit=abc",
+		"cursor=%21%21",
+		"filter=Name",
+		"filter=Name:eq",
+		"filter=Unknown:eq:x",
+		"filter=Hidden:eq:x",
+		"filter=Name:like:x",
+		"fields=Name,Unknown",
+	} {
+		_, err := listTestOptions(t, query)
+		assert.ErrorIs(t, err, domain.ErrInvalidData, query)
+	}
+}
+
+func TestApplyListOptions_pagination(t *testing.T) {
+	opts, err := listTestOptions(t, "limit=3")
+	require.NoError(t, err)
+
+	page := applyListOptions(listTestRecords(), listTestKey, opts)
+	assert.Equal(t, []string{"a", "b", "c"}, listTestIds(page.Items))
+	assert.Equal(t, 4, page.Total)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString([]byte("c")), page.NextCursor)
+
+	opts, err = listTestOptions(t, "limit=3&cursor="+page.NextCursor)
+	require.NoError(t, err)
+
+	page = applyListOptions(listTestRecords(), listTestKey, opts)
+	assert.Equal(t, []string{"d"}, listTestIds(page.Items))
+	assert.Empty(t, page.NextCursor, "the last page has no cursor")
+}
+
+func TestApplyListOptions_cursorOfRemovedRecord(t *testing.T) {
+	opts := listOptions{Limit: 2, Cursor: "bb"}
+
+	page := applyListOptions(listTestRecords(), listTestKey, opts)
+	assert.Equal(t, []string{"c", "d"}, listTestIds(page.Items))
+}
+
+func TestApplyListOptions_filters(t *testing.T) {
+	tests := map[string][]string{
+		"filter=Name:eq:Bravo":                          {"b"},
+		"filter=Name:ne:Bravo":                          {"a", "c", "d"},
+		"filter=Name:co:LT":                             {"d"},
+		"filter=Name:sw:c":                              {"c"},
+		"filter=Port:gt:51820":                          {"b", "c"},
+		"filter=Port:le:51820":                          {"a", "d"},
+		"filter=Port:lt:abc":                            {},
+		"filter=Enabled:eq:true":                        {"a", "c"},
+		"filter=Name:in:Alpha|Delta":                    {"a", "d"},
+		"filter=Tags:eq:office":                         {"a", "c"},
+		"filter=Tags:ne:office":                         {"b", "d"},
+		"filter=Tags:eq:home&filter=Enabled:eq:false":   {"b"},
+		"filter=Name:ge:Bravo&filter=Name:lt:Delta":     {"b", "c"},
+		"filter=Identifier:eq:a&filter=Identifier:eq:b": {},
+	}
+
+	for query, expected := range tests {
+		opts, err := listTestOptions(t, query)
+		require.NoError(t, err, query)
+
+		page := applyListOptions(listTestRecords(), listTestKey, opts)
+		assert.Equal(t, expected, listTestIds(page.Items), query)
+		assert.Equal(t, len(expected), page.Total, query)
+	}
+}
+
+func TestRespondList(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/test/all?limit=1&filter=Enabled:eq:true&fields=Name,Port", nil)
+	w := httptest.NewRecorder()
+
+	respondList(w, r, listTestRecords(), listTestKey)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(headerTotalCount))
+	cursor := base64.RawURLEncoding.EncodeToString([]byte("a"))
+	assert.Equal(t, cursor, w.Header().Get(headerNextCursor))
+	assert.Equal(t, "</test/all?cursor="+cursor+"&fields=Name%2CPort&filter=Enabled%3Aeq%3Atrue&limit=1>; rel=\"next\"",
+		w.Header().Get("Link"))
+
+	var body []map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, []map[string]any{{"Name": "Alpha", "Port": float64(51820)}}, body)
+}
+
+func TestRespondList_invalidOptions(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/test/all?filter=Unknown:eq:x", nil)
+	w := httptest.NewRecorder()
+
+	respondList(w, r, listTestRecords(), listTestKey)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+type listTestOption struct {
+	Value       int  `json:"Value"`
+	Overridable bool `json:"Overridable,omitempty"`
+}
+
+type listTestBase struct {
+	Identifier string `json:"Identifier"`
+}
+
+type listTestNestedRecord struct {
+	listTestBase
+	Mtu      listTestOption    `json:"Mtu"`
+	Owner    *string           `json:"Owner"`
+	Labels   map[string]string `json:"Labels,omitempty"`
+	Disabled bool              `json:"Disabled,omitempty"`
+}
+
+func TestApplyListOptions_nestedFields(t *testing.T) {
+	owner := "alice"
+	records := []listTestNestedRecord{
+		{listTestBase: listTestBase{Identifier: "a"}, Mtu: listTestOption{Value: 1420, Overridable: true},
+			Owner: &owner, Labels: map[string]string{"site": "berlin"}},
+		{listTestBase: listTestBase{Identifier: "b"}, Mtu: listTestOption{Value: 1280}, Disabled: true},
+	}
+	key := func(r listTestNestedRecord) string { return r.Identifier }
+
+	tests := map[string][]string{
+		"filter=Identifier:eq:b":          {"b"},
+		"filter=Mtu.Value:gt:1300":        {"a"},
+		"filter=Mtu.Overridable:eq:true":  {"a"},
+		"filter=Mtu.Overridable:eq:false": {}, // omitted fields have no value
+		"filter=Mtu.Unknown:eq:x":         {},
+		"filter=Owner:eq:alice":           {"a"},
+		"filter=Owner:eq:":                {"b"},
+		"filter=Labels.site:sw:ber":       {"a"},
+		"filter=Disabled:ne:true":         {"a"},
+	}
+
+	for query, expected := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/test/all?"+query, nil)
+		opts, err := parseListOptions[listTestNestedRecord](r)
+		require.NoError(t, err, query)
+
+		page := applyListOptions(records, key, opts)
+		assert.Equal(t, expected, listTestNestedIds(page.Items), query)
+	}
+
+	selected := selectFields(records, []string{"Identifier", "Owner", "Labels"})
+	encoded, err := json.Marshal(selected)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"Identifier":"a","Owner":"alice","Labels":{"site":"berlin"}},
+		{"Identifier":"b","Owner":null}]`, string(encoded))
+}
+
+func listTestNestedIds(items []listTestNestedRecord) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.Identifier
+	}
+	return ids
+}
+
+func BenchmarkApplyListOptions(b *testing.B) {
+	records := make([]listTestRecord, 10000)
+	for i := range records {
+		records[i] = listTestRecord{Identifier: strconv.Itoa(i), Name: "peer", Port: 51820 + i%100,
+			Enabled: i%2 == 0, Tags: []string{"office"}}
+	}
+	r := httptest.NewRequest(http.MethodGet, "/test/all?limit=100&filter=Port:gt:51850&filter=Tags:eq:office", nil)
+	opts, err := parseListOptions[listTestRecord](r)
+	require.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		applyListOptions(records, listTestKey, opts)
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/h44z/wg-portal/internal/app/api/core/respond"
 	"github.com/h44z/wg-portal/internal/app/api/v0/model"
@@ -12,7 +13,9 @@ import (
 type Scope string
 
 const (
-	ScopeAdmin Scope = "ADMIN" // Admin scope contains all other scopes
+	ScopeAdmin          Scope = "ADMIN"           // Admin scope contains all other scopes
+	ScopeInterfaceAdmin Scope = "INTERFACE_ADMIN" // Interface admin scope, the interface is validated by the services
+	ScopeHelpdesk       Scope = "HELPDESK"        // Helpdesk scope, the permitted actions are validated by the services
 )
 
 type UserAuthenticator interface {
@@ -68,8 +71,12 @@ func (h AuthenticationHandler) LoggedIn(scopes ...Scope) func(next http.Handler)
 			}
 
 			ctx = context.WithValue(r.Context(), domain.CtxUserInfo, &domain.ContextUserInfo{
-				Id:      user.Identifier,
-				IsAdmin: user.IsAdmin,
+				Id:              user.Identifier,
+				IsAdmin:         user.IsAdmin,
+				IsHelpdesk:      user.IsHelpdesk,
+				AdminInterfaces: user.AdminInterfaces(),
+				Organization:    user.OrganizationIdentifier,
+				AuthenticatedAt: time.Now(), // each API request is authenticated by the API token
 			})
 			r = r.WithContext(ctx)
 
@@ -95,6 +102,12 @@ func UserHasScopes(user *domain.User, scopes ...Scope) bool {
 		if scope == ScopeAdmin {
 			return false
 		}
+		if scope == ScopeInterfaceAdmin && len(user.AdminInterfaces()) == 0 {
+			return false
+		}
+		if scope == ScopeHelpdesk && !user.IsHelpdesk {
+			return false
+		}
 	}
 
 	return true
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// IdempotentReplayedHeader is set on responses that were replayed for a known idempotency key.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+type IdempotencyService interface {
+	// Begin starts a request with the given idempotency key for the current user. If the response of an earlier
+	// request with the same key is stored, it is returned and the request must not be executed again.
+	Begin(ctx context.Context, key, requestHash string) (*domain.IdempotencyRecord, error)
+	// Finish stores the response of a request that was started with Begin and releases the key.
+	Finish(ctx context.Context, key, requestHash string, statusCode int, contentType string, body []byte) error
+}
+
+type IdempotencyHandler struct {
+	idempotency IdempotencyService
+}
+
+func NewIdempotencyHandler(idempotency IdempotencyService) IdempotencyHandler {
+	return IdempotencyHandler{
+		idempotency: idempotency,
+	}
+}
+
+// Idempotent replays the stored response of requests with an Idempotency-Key header if the same request was
+// already executed. Requests without the header are not changed. The user must be logged in.
+func (h IdempotencyHandler) Idempotent() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := request.HeaderRaw(r, domain.IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				respond.JSON(w, http.StatusBadRequest,
+					models.Error{Code: http.StatusBadRequest, Message: "failed to read request body"})
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			hash := idempotencyRequestHash(r, body)
+
+			record, err := h.idempotency.Begin(r.Context(), key, hash)
+			if err != nil {
+				status, model := ParseServiceError(err)
+				respond.JSON(w, status, model)
+				return
+			}
+			if record != nil {
+				w.Header().Set("Content-Type", record.ContentType)
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(record.StatusCode)
+				_, _ = w.Write(record.Body)
+				return
+			}
+
+			recorder := &idempotencyRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			defer func() {
+				// Finish is also called if the handler panics, so that the key is released
+				err := h.idempotency.Finish(r.Context(), key, hash, recorder.statusCode,
+					recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+				if err != nil {
+					slog.Error("failed to store idempotent response", "key", key, "error", err)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
+
+// idempotencyRequestHash returns the SHA-256 hash of the method, path and body of the request.
+func idempotencyRequestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// idempotencyRecorder wraps a http.ResponseWriter and keeps a copy of the response status and body.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (w *idempotencyRecorder) WriteHeader(code int) {
+	w.statusCode = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *idempotencyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the wrapped ResponseWriter, so that http.ResponseController can access
+// optional interfaces like http.Flusher.
+func (w *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// ConfigPullToken represents a newly created config pull token.
+type ConfigPullToken struct {
+	// Token is the plain text pull token. It is only returned once and cannot be retrieved later.
+	Token string `json:"Token" example:"wgp_PSJ1mhxWfs2tDLbBffYpAwvOU5Ejlfqq0FUZWE6xnsA"`
+	// PeerIdentifier is the identifier of the peer whose configuration can be fetched with the token.
+	PeerIdentifier string `json:"PeerIdentifier" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	// PullUrl is the URL from which the configuration can be fetched.
+	PullUrl string `json:"PullUrl" example:"https://wg.example.com/api/v1/provisioning/config-pull"`
+	// CreatedAt is the creation time of the token.
+	CreatedAt time.Time `json:"CreatedAt"`
+}
+
+func NewConfigPullToken(plainToken string, src *domain.ConfigPullToken, pullUrl string) *ConfigPullToken {
+	return &ConfigPullToken{
+		Token:          plainToken,
+		PeerIdentifier: string(src.PeerId),
+		PullUrl:        pullUrl,
+		CreatedAt:      src.CreatedAt,
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// The device authorization models use the field names defined in RFC 8628, so that existing OAuth2 client
+// libraries can be used by devices.
+
+// DeviceAuthorizationResponse is the response to a device authorization request (RFC 8628, section 3.2).
+type DeviceAuthorizationResponse struct {
+	// DeviceCode is the secret code the device uses to poll for the authorization result.
+	DeviceCode string `json:"device_code"`
+	// UserCode is the code the user confirms in the browser.
+	UserCode string `json:"user_code" example:"BCDF-GHJK"`
+	// VerificationUri is the URL of the web page where the user confirms the code.
+	VerificationUri string `json:"verification_uri" example:"https://wg.example.com/app/#/device"`
+	// VerificationUriComplete is the verification URL including the user code, e.g. for QR codes.
+	VerificationUriComplete string `json:"verification_uri_complete" example:"https://wg.example.com/app/#/device?code=BCDF-GHJK"`
+	// ExpiresIn is the lifetime of the codes in seconds.
+	ExpiresIn int `json:"expires_in" example:"600"`
+	// Interval is the minimum number of seconds the device must wait between polling requests.
+	Interval int `json:"interval" example:"5"`
+}
+
+func NewDeviceAuthorizationResponse(src *domain.DeviceAuthorization, verificationUri string) *DeviceAuthorizationResponse {
+	return &DeviceAuthorizationResponse{
+		DeviceCode:              src.DeviceCode,
+		UserCode:                src.FormattedUserCode(),
+		VerificationUri:         verificationUri,
+		VerificationUriComplete: verificationUri + "?code=" + src.FormattedUserCode(),
+		ExpiresIn:               int(time.Until(src.ExpiresAt).Round(time.Second).Seconds()),
+		Interval:                int(src.Interval.Seconds()),
+	}
+}
+
+// DeviceTokenResponse is the response to a successful device token request.
+// Instead of an access token, the device receives its WireGuard configuration.
+type DeviceTokenResponse struct {
+	// PeerIdentifier is the identifier of the peer whose configuration is returned.
+	PeerIdentifier string `json:"peer_identifier" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	// PeerConfig is the peer configuration in wg-quick format.
+	PeerConfig string `json:"peer_config"`
+}
+
+// DeviceTokenError is the error response of the device token endpoint (RFC 6749, section 5.2).
+type DeviceTokenError struct {
+	// Error is the error code, e.g. authorization_pending, slow_down, access_denied or expired_token.
+	Error string `json:"error" example:"authorization_pending"`
+	// ErrorDescription is an optional human-readable description of the error.
+	ErrorDescription string `json:"error_description,omitempty"`
+}
@@ -0,0 +1,37 @@
+package models
+
+// GraphQLRequest represents a GraphQL query request.
+type GraphQLRequest struct {
+	// The GraphQL query document. Only queries are supported, fragments and directives are not available.
+	Query string `json:"query" example:"{ Me { Identifier Peers { DisplayName Metrics { IsPingable } } } }"`
+	// The operation to execute, only required if the query document contains more than one operation.
+	OperationName string `json:"operationName"`
+	// The values of the variables of the operation.
+	Variables map[string]any `json:"variables"`
+	// The identifier or the SHA-256 hash of a persisted query, it is used instead of the query document.
+	Id string `json:"id" example:"peer-overview"`
+	// The extensions of the request. The persisted query hash of Apollo clients is supported as well.
+	Extensions *GraphQLRequestExtensions `json:"extensions,omitempty"`
+}
+
+// GraphQLRequestExtensions represents the extensions of a GraphQL request.
+type GraphQLRequestExtensions struct {
+	PersistedQuery *GraphQLPersistedQuery `json:"persistedQuery,omitempty"`
+}
+
+// GraphQLPersistedQuery references a persisted query by the hash of the query document.
+type GraphQLPersistedQuery struct {
+	// The hex encoded SHA-256 hash of the query document.
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+// PersistedQueryId returns the identifier or hash of the persisted query, empty if no persisted query was requested.
+func (r GraphQLRequest) PersistedQueryId() string {
+	if r.Id != "" {
+		return r.Id
+	}
+	if r.Extensions != nil && r.Extensions.PersistedQuery != nil {
+		return r.Extensions.PersistedQuery.Sha256Hash
+	}
+	return ""
+}
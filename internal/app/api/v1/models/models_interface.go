@@ -25,6 +25,8 @@ type Interface struct {
 	DisabledReason string `json:"DisabledReason" binding:"required_if=Disabled true" example:"This is a reason why the interface has been disabled."`
 	// SaveConfig is a flag that specifies if the configuration should be saved to the configuration file (wgX.conf in wg-quick format).
 	SaveConfig bool `json:"SaveConfig" example:"false"`
+	// Organization is the organization that owns the interface and its peers.
+	Organization string `json:"Organization" example:"acme"`
 
 	// ListenPort is the listening port, for example: 51820. The listening port is only required for server interfaces.
 	ListenPort int `json:"ListenPort" binding:"omitempty,min=1,max=65535" example:"51820"`
@@ -58,8 +60,13 @@ type Interface struct {
 	PeerDefDnsSearch []string `json:"PeerDefDnsSearch" example:"wg.local"`
 	// PeerDefEndpoint specifies the default endpoint for a new peer.
 	PeerDefEndpoint string `json:"PeerDefEndpoint" example:"wg.example.com:51820"`
+	// PeerDefBackupEndpoints specifies the backup endpoints that are listed in the peer configurations.
+	// Entries with the "srv:" prefix are resolved using DNS SRV records.
+	PeerDefBackupEndpoints []string `json:"PeerDefBackupEndpoints" example:"wg2.example.com:51820"`
 	// PeerDefAllowedIPs specifies the default allowed IP addresses for a new peer.
 	PeerDefAllowedIPs []string `json:"PeerDefAllowedIPs" example:"10.11.12.0/24"`
+	// PeerDefRouteSets specifies the default route sets for a new peer.
+	PeerDefRouteSets []string `json:"PeerDefRouteSets" example:"corp-subnets"`
 	// PeerDefMtu specifies the default device MTU for a new peer.
 	PeerDefMtu int `json:"PeerDefMtu" example:"1420"`
 	// PeerDefPersistentKeepalive specifies the default persistent keep-alive value in seconds for a new peer.
@@ -68,6 +75,10 @@ type Interface struct {
 	PeerDefFirewallMark uint32 `json:"PeerDefFirewallMark"`
 	// PeerDefRoutingTable specifies the default routing table for a new peer.
 	PeerDefRoutingTable string `json:"PeerDefRoutingTable"`
+	// PeerDefUploadLimit specifies the default upload bandwidth limit in kbit/s for a new peer.
+	PeerDefUploadLimit int `json:"PeerDefUploadLimit" example:"0"`
+	// PeerDefDownloadLimit specifies the default download bandwidth limit in kbit/s for a new peer.
+	PeerDefDownloadLimit int `json:"PeerDefDownloadLimit" example:"0"`
 
 	// PeerDefPreUp specifies the default action that is executed before the device is up for a new peer.
 	PeerDefPreUp string `json:"PeerDefPreUp"`
@@ -78,6 +89,21 @@ type Interface struct {
 	// PeerDefPostDown specifies the default action that is executed after the device is down for a new peer.
 	PeerDefPostDown string `json:"PeerDefPostDown"`
 
+	// PeerMailCc is a list of recipients that receive a copy of all peer mails, for example an asset management.
+	PeerMailCc []string `json:"PeerMailCc" binding:"omitempty,dive,email" example:"assets@example.com"`
+	// PeerMailBcc is a list of recipients that receive a blind copy of all peer mails.
+	PeerMailBcc []string `json:"PeerMailBcc" binding:"omitempty,dive,email"`
+
+	// ClientIsolation drops the traffic between the peers of the interface. Only applies to server interfaces.
+	ClientIsolation bool `json:"ClientIsolation" example:"false"`
+	// ClientIsolationAllowed is a list of networks behind peers that stay reachable for all peers if client isolation
+	// is enabled, for example the site network behind a gateway peer.
+	ClientIsolationAllowed []string `json:"ClientIsolationAllowed" binding:"omitempty,dive,cidr" example:"192.168.50.0/24"`
+
+	// PeerRouteAllowlist is a list of networks that users may route to their own peers without the review of an
+	// administrator. Only applies if route reviews are enabled.
+	PeerRouteAllowlist []string `json:"PeerRouteAllowlist" binding:"omitempty,dive,cidr" example:"192.168.0.0/16"`
+
 	// Calculated values
 
 	// EnabledPeers is the number of enabled peers for this interface. Only enabled peers are able to connect.
@@ -99,6 +125,7 @@ func NewInterface(src *domain.Interface, peers []domain.Peer) *Interface {
 		Disabled:                   src.IsDisabled(),
 		DisabledReason:             src.DisabledReason,
 		SaveConfig:                 src.SaveConfig,
+		Organization:               string(src.OrganizationIdentifier),
 		ListenPort:                 src.ListenPort,
 		Addresses:                  domain.CidrsToStringSlice(src.Addresses),
 		Dns:                        internal.SliceString(src.DnsStr),
@@ -114,15 +141,24 @@ func NewInterface(src *domain.Interface, peers []domain.Peer) *Interface {
 		PeerDefDns:                 internal.SliceString(src.PeerDefDnsStr),
 		PeerDefDnsSearch:           internal.SliceString(src.PeerDefDnsSearchStr),
 		PeerDefEndpoint:            src.PeerDefEndpoint,
+		PeerDefBackupEndpoints:     internal.SliceString(src.PeerDefBackupEndpointsStr),
 		PeerDefAllowedIPs:          internal.SliceString(src.PeerDefAllowedIPsStr),
+		PeerDefRouteSets:           internal.SliceString(src.PeerDefRouteSetsStr),
 		PeerDefMtu:                 src.PeerDefMtu,
 		PeerDefPersistentKeepalive: src.PeerDefPersistentKeepalive,
 		PeerDefFirewallMark:        src.PeerDefFirewallMark,
 		PeerDefRoutingTable:        src.PeerDefRoutingTable,
+		PeerDefUploadLimit:         src.PeerDefUploadLimit,
+		PeerDefDownloadLimit:       src.PeerDefDownloadLimit,
 		PeerDefPreUp:               src.PeerDefPreUp,
 		PeerDefPostUp:              src.PeerDefPostUp,
 		PeerDefPreDown:             src.PeerDefPreDown,
 		PeerDefPostDown:            src.PeerDefPostDown,
+		PeerMailCc:                 internal.SliceString(src.PeerMailCcStr),
+		PeerMailBcc:                internal.SliceString(src.PeerMailBccStr),
+		ClientIsolation:            src.ClientIsolation,
+		ClientIsolationAllowed:     internal.SliceString(src.ClientIsolationAllowedStr),
+		PeerRouteAllowlist:         internal.SliceString(src.PeerRouteAllowlistStr),
 
 		EnabledPeers: 0,
 		TotalPeers:   0,
@@ -182,19 +218,29 @@ func NewDomainInterface(src *Interface) *domain.Interface {
 		DriverType:                 "",  // currently unused
 		Disabled:                   nil, // set below
 		DisabledReason:             src.DisabledReason,
+		OrganizationIdentifier:     domain.OrganizationIdentifier(src.Organization),
 		PeerDefNetworkStr:          internal.SliceToString(src.PeerDefNetwork),
 		PeerDefDnsStr:              internal.SliceToString(src.PeerDefDns),
 		PeerDefDnsSearchStr:        internal.SliceToString(src.PeerDefDnsSearch),
 		PeerDefEndpoint:            src.PeerDefEndpoint,
+		PeerDefBackupEndpointsStr:  internal.SliceToString(src.PeerDefBackupEndpoints),
 		PeerDefAllowedIPsStr:       internal.SliceToString(src.PeerDefAllowedIPs),
+		PeerDefRouteSetsStr:        internal.SliceToString(src.PeerDefRouteSets),
 		PeerDefMtu:                 src.PeerDefMtu,
 		PeerDefPersistentKeepalive: src.PeerDefPersistentKeepalive,
 		PeerDefFirewallMark:        src.PeerDefFirewallMark,
 		PeerDefRoutingTable:        src.PeerDefRoutingTable,
+		PeerDefUploadLimit:         src.PeerDefUploadLimit,
+		PeerDefDownloadLimit:       src.PeerDefDownloadLimit,
 		PeerDefPreUp:               src.PeerDefPreUp,
 		PeerDefPostUp:              src.PeerDefPostUp,
 		PeerDefPreDown:             src.PeerDefPreDown,
 		PeerDefPostDown:            src.PeerDefPostDown,
+		PeerMailCcStr:              internal.SliceToString(src.PeerMailCc),
+		PeerMailBccStr:             internal.SliceToString(src.PeerMailBcc),
+		ClientIsolation:            src.ClientIsolation,
+		ClientIsolationAllowedStr:  internal.SliceToString(src.ClientIsolationAllowed),
+		PeerRouteAllowlistStr:      internal.SliceToString(src.PeerRouteAllowlist),
 	}
 
 	if src.Disabled {
@@ -42,6 +42,108 @@ func NewPeerMetrics(src *domain.PeerStatus) *PeerMetrics {
 	}
 }
 
+// PeerStatsSample represents the traffic of a WireGuard peer within a time period.
+type PeerStatsSample struct {
+	// The start of the time period.
+	Timestamp time.Time `json:"Timestamp" example:"2021-01-01T12:00:00Z"`
+
+	// The number of bytes received by the peer within the time period.
+	BytesReceived uint64 `json:"BytesReceived" example:"123456789"`
+	// The number of bytes transmitted by the peer within the time period.
+	BytesTransmitted uint64 `json:"BytesTransmitted" example:"123456789"`
+
+	// The latest handshake of the peer within the time period.
+	LastHandshake *time.Time `json:"LastHandshake" example:"2021-01-01T12:00:00Z"`
+}
+
+// PeerMetricsHistory represents the traffic history of a WireGuard peer.
+type PeerMetricsHistory struct {
+	// The unique identifier of the peer.
+	PeerIdentifier string `json:"PeerIdentifier" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	// The resolution of the samples.
+	Resolution string `json:"Resolution" example:"hourly"`
+
+	// The samples, ordered by timestamp.
+	Samples []PeerStatsSample `json:"Samples"`
+}
+
+func NewPeerMetricsHistory(
+	id domain.PeerIdentifier,
+	resolution domain.PeerStatsResolution,
+	src []domain.PeerStatsSample,
+) *PeerMetricsHistory {
+	samples := make([]PeerStatsSample, len(src))
+	for i, sample := range src {
+		samples[i] = PeerStatsSample{
+			Timestamp:        sample.Timestamp,
+			BytesReceived:    sample.BytesReceived,
+			BytesTransmitted: sample.BytesTransmitted,
+			LastHandshake:    sample.LastHandshake,
+		}
+	}
+
+	return &PeerMetricsHistory{
+		PeerIdentifier: string(id),
+		Resolution:     string(resolution),
+		Samples:        samples,
+	}
+}
+
+// PeerConnectionEvent represents a connect, disconnect or endpoint change of a WireGuard peer.
+type PeerConnectionEvent struct {
+	// The time of the state change.
+	Timestamp time.Time `json:"Timestamp" example:"2021-01-01T12:00:00Z"`
+	// The kind of the state change: connected, disconnected or endpoint-changed.
+	Kind string `json:"Kind" example:"connected"`
+	// The interface of the peer.
+	InterfaceIdentifier string `json:"InterfaceIdentifier" example:"wg0"`
+
+	// The endpoint address of the peer after the state change.
+	Endpoint string `json:"Endpoint" example:"12.34.56.78:51820"`
+	// The endpoint address of the peer before the state change, only set for endpoint changes.
+	PreviousEndpoint string `json:"PreviousEndpoint,omitempty" example:"12.34.56.78:51820"`
+	// The last handshake of the peer at the time of the state change.
+	LastHandshake *time.Time `json:"LastHandshake" example:"2021-01-01T12:00:00Z"`
+}
+
+// PeerConnectionHistory represents the connection history of a WireGuard peer within a time range.
+type PeerConnectionHistory struct {
+	// The unique identifier of the peer.
+	PeerIdentifier string `json:"PeerIdentifier" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	// The start of the time range.
+	From time.Time `json:"From" example:"2021-01-01T00:00:00Z"`
+	// The end of the time range.
+	To time.Time `json:"To" example:"2021-01-08T00:00:00Z"`
+
+	// The events, ordered by timestamp.
+	Events []PeerConnectionEvent `json:"Events"`
+}
+
+func NewPeerConnectionHistory(
+	id domain.PeerIdentifier,
+	from, to time.Time,
+	src []domain.PeerConnectionEvent,
+) *PeerConnectionHistory {
+	events := make([]PeerConnectionEvent, len(src))
+	for i, event := range src {
+		events[i] = PeerConnectionEvent{
+			Timestamp:           event.Timestamp,
+			Kind:                string(event.Kind),
+			InterfaceIdentifier: string(event.InterfaceId),
+			Endpoint:            event.Endpoint,
+			PreviousEndpoint:    event.PreviousEndpoint,
+			LastHandshake:       event.LastHandshake,
+		}
+	}
+
+	return &PeerConnectionHistory{
+		PeerIdentifier: string(id),
+		From:           from,
+		To:             to,
+		Events:         events,
+	}
+}
+
 // InterfaceMetrics represents the metrics of a WireGuard interface.
 type InterfaceMetrics struct {
 	// The unique identifier of the interface.
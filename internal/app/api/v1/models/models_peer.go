@@ -25,8 +25,30 @@ type Peer struct {
 	DisabledReason string `json:"DisabledReason" binding:"required_if=Disabled true" example:"This is a reason why the peer has been disabled."`
 	// ExpiresAt is the expiry date of the peer  in YYYY-MM-DD format. An expired peer is not able to connect.
 	ExpiresAt string `json:"ExpiresAt,omitempty" binding:"omitempty,datetime=2006-01-02"`
+	// Ephemeral is set for peers that are deleted once they expire. The expiry date of ephemeral peers cannot be changed.
+	// This value is read only and is not settable by the user.
+	Ephemeral bool `json:"Ephemeral" example:"false" readonly:"true"`
 	// Notes is a note field for peers.
 	Notes string `json:"Notes" example:"This is a note for the peer."`
+	// MailRecipients is a list of additional recipients of peer mails, for example a team mailbox.
+	MailRecipients []string `json:"MailRecipients" binding:"omitempty,dive,email" example:"team@example.com"`
+	// SharedUsers is a list of additional users that are authorized to use the peer, for example for a lab machine.
+	// Only administrators can change the shared users.
+	SharedUsers []string `json:"SharedUsers" example:"alice,bob"`
+	// AccessSchedule contains the weekly time windows in which the peer is enabled, separated by semicolons.
+	// Outside these windows the peer is disabled automatically. An empty schedule allows access at any time.
+	AccessSchedule string `json:"AccessSchedule" example:"Mon-Fri 08:00-18:00"`
+	// AccessTimezone is the IANA time zone of the access schedule. If empty, the configured default time zone is used.
+	AccessTimezone string `json:"AccessTimezone" example:"Europe/Vienna"`
+	// EndpointPin contains the networks and two-letter country codes the source endpoint of the peer is pinned to.
+	// Endpoints outside the pin violate the roaming policy. Only administrators can change the pin.
+	EndpointPin []string `json:"EndpointPin" example:"203.0.113.0/24,AT"`
+	// OperatingSystem is the operating system of the device that uses the peer: windows, macos, ios, android or linux.
+	// It selects the setup guide that is attached to configuration mails. If empty, no setup guide is attached.
+	OperatingSystem string `json:"OperatingSystem" binding:"omitempty,oneof=windows macos ios android linux" example:"windows"`
+	// DeviceType is the kind of device that uses the peer: laptop, phone, server, router or iot.
+	// Peers of the device types listed in device_types.never_expire never expire.
+	DeviceType string `json:"DeviceType" binding:"omitempty,oneof=laptop phone server router iot" example:"laptop"`
 
 	// Endpoint is the endpoint address of the peer.
 	Endpoint ConfigOption[string] `json:"Endpoint"`
@@ -36,10 +58,16 @@ type Peer struct {
 	AllowedIPs ConfigOption[[]string] `json:"AllowedIPs"`
 	// ExtraAllowedIPs is a list of additional allowed IP subnets for the peer. These allowed IP subnets are added on the server side.
 	ExtraAllowedIPs []string `json:"ExtraAllowedIPs"`
+	// RouteSets is a list of route set identifiers. The networks of these route sets are added to the allowed IP subnets of the peer configuration.
+	RouteSets ConfigOption[[]string] `json:"RouteSets"`
 	// PresharedKey is the optional pre-shared Key of the peer.
 	PresharedKey string `json:"PresharedKey" example:"yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=" binding:"omitempty,len=44"`
 	// PersistentKeepalive is the optional persistent keep-alive interval in seconds.
 	PersistentKeepalive ConfigOption[int] `json:"PersistentKeepalive"`
+	// UploadLimit is the optional upload bandwidth limit in kbit/s. A value of 0 means unlimited.
+	UploadLimit ConfigOption[int] `json:"UploadLimit"`
+	// DownloadLimit is the optional download bandwidth limit in kbit/s. A value of 0 means unlimited.
+	DownloadLimit ConfigOption[int] `json:"DownloadLimit"`
 
 	// PrivateKey is the private Key of the peer.
 	PrivateKey string `json:"PrivateKey" example:"yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=" binding:"required,len=44"`
@@ -72,6 +100,9 @@ type Peer struct {
 	PreDown ConfigOption[string] `json:"PreDown"`
 	// PostDown is an optional action that is executed after the device is down.
 	PostDown ConfigOption[string] `json:"PostDown"`
+	// KillSwitch specifies the kill-switch firewall rules that are added to full-tunnel peer configurations.
+	// Supported values are iptables, nftables and windows. An empty value disables the kill-switch.
+	KillSwitch string `json:"KillSwitch" binding:"omitempty,oneof=iptables nftables windows" example:"iptables"`
 
 	// Filename is the name of the config file for this peer.
 	// This value is read only and is not settable by the user.
@@ -92,13 +123,24 @@ func NewPeer(src *domain.Peer) *Peer {
 		Disabled:            src.IsDisabled(),
 		DisabledReason:      src.DisabledReason,
 		ExpiresAt:           expiresAt,
+		Ephemeral:           src.Ephemeral,
 		Notes:               src.Notes,
+		MailRecipients:      internal.SliceString(src.MailRecipientsStr),
+		SharedUsers:         internal.SliceString(src.SharedUsersStr),
+		AccessSchedule:      src.AccessScheduleStr,
+		AccessTimezone:      src.AccessTimezone,
+		EndpointPin:         internal.SliceString(src.EndpointPinStr),
+		OperatingSystem:     string(src.OperatingSystem),
+		DeviceType:          string(src.DeviceType),
 		Endpoint:            ConfigOptionFromDomain(src.Endpoint),
 		EndpointPublicKey:   ConfigOptionFromDomain(src.EndpointPublicKey),
 		AllowedIPs:          StringSliceConfigOptionFromDomain(src.AllowedIPsStr),
 		ExtraAllowedIPs:     internal.SliceString(src.ExtraAllowedIPsStr),
+		RouteSets:           StringSliceConfigOptionFromDomain(src.RouteSetsStr),
 		PresharedKey:        string(src.PresharedKey),
 		PersistentKeepalive: ConfigOptionFromDomain(src.PersistentKeepalive),
+		UploadLimit:         ConfigOptionFromDomain(src.UploadLimit),
+		DownloadLimit:       ConfigOptionFromDomain(src.DownloadLimit),
 		PrivateKey:          src.Interface.PrivateKey,
 		PublicKey:           src.Interface.PublicKey,
 		Mode:                string(src.Interface.Type),
@@ -113,6 +155,7 @@ func NewPeer(src *domain.Peer) *Peer {
 		PostUp:              ConfigOptionFromDomain(src.Interface.PostUp),
 		PreDown:             ConfigOptionFromDomain(src.Interface.PreDown),
 		PostDown:            ConfigOptionFromDomain(src.Interface.PostDown),
+		KillSwitch:          string(src.Interface.KillSwitch),
 		Filename:            src.GetConfigFileName(),
 	}
 }
@@ -143,8 +186,11 @@ func NewDomainPeer(src *Peer) *domain.Peer {
 		EndpointPublicKey:   ConfigOptionToDomain(src.EndpointPublicKey),
 		AllowedIPsStr:       StringSliceConfigOptionToDomain(src.AllowedIPs),
 		ExtraAllowedIPsStr:  internal.SliceToString(src.ExtraAllowedIPs),
+		RouteSetsStr:        StringSliceConfigOptionToDomain(src.RouteSets),
 		PresharedKey:        domain.PreSharedKey(src.PresharedKey),
 		PersistentKeepalive: ConfigOptionToDomain(src.PersistentKeepalive),
+		UploadLimit:         ConfigOptionToDomain(src.UploadLimit),
+		DownloadLimit:       ConfigOptionToDomain(src.DownloadLimit),
 		DisplayName:         src.DisplayName,
 		Identifier:          domain.PeerIdentifier(src.Identifier),
 		UserIdentifier:      domain.UserIdentifier(src.UserIdentifier),
@@ -153,6 +199,13 @@ func NewDomainPeer(src *Peer) *domain.Peer {
 		DisabledReason:      src.DisabledReason,
 		ExpiresAt:           expiresAt,
 		Notes:               src.Notes,
+		MailRecipientsStr:   internal.SliceToString(src.MailRecipients),
+		SharedUsersStr:      internal.SliceToString(src.SharedUsers),
+		AccessScheduleStr:   src.AccessSchedule,
+		AccessTimezone:      src.AccessTimezone,
+		EndpointPinStr:      internal.SliceToString(src.EndpointPin),
+		OperatingSystem:     domain.PeerOperatingSystem(src.OperatingSystem),
+		DeviceType:          domain.PeerDeviceType(src.DeviceType),
 		Interface: domain.PeerInterfaceConfig{
 			KeyPair: domain.KeyPair{
 				PrivateKey: src.PrivateKey,
@@ -170,6 +223,7 @@ func NewDomainPeer(src *Peer) *domain.Peer {
 			PostUp:            ConfigOptionToDomain(src.PostUp),
 			PreDown:           ConfigOptionToDomain(src.PreDown),
 			PostDown:          ConfigOptionToDomain(src.PostDown),
+			KillSwitch:        domain.KillSwitchMode(src.KillSwitch),
 		},
 	}
 
@@ -1,6 +1,10 @@
 package models
 
-import "github.com/h44z/wg-portal/internal/domain"
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
 
 // UserInformation represents the information about a user and its linked peers.
 type UserInformation struct {
@@ -73,3 +77,40 @@ type ProvisioningRequest struct {
 	// PresharedKey is the optional pre-shared key of the peer. If no pre-shared key is set, a new key is generated.
 	PresharedKey string `json:"PresharedKey" example:"yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=" binding:"omitempty,len=44"`
 }
+
+// EphemeralPeerRequest represents a request to issue a new ephemeral peer.
+type EphemeralPeerRequest struct {
+	// InterfaceIdentifier is the identifier of the WireGuard interface the peer should be linked to.
+	InterfaceIdentifier string `json:"InterfaceIdentifier" example:"wg0" binding:"required"`
+	// UserIdentifier is the identifier of the user the peer should be linked to. If no user identifier is set,
+	// the peer is not linked to a user.
+	UserIdentifier string `json:"UserIdentifier" example:"uid-1234567"`
+	// Lifetime is the duration after which the peer is deleted, for example 30m or 4h. If no lifetime is set,
+	// the configured default lifetime is used.
+	Lifetime string `json:"Lifetime" example:"4h"`
+
+	// PublicKey is the optional public key of the peer. If no public key is set, a new key pair is generated.
+	PublicKey string `json:"PublicKey" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=" binding:"omitempty,len=44"`
+}
+
+// EphemeralPeer represents an issued ephemeral peer.
+type EphemeralPeer struct {
+	// Peer is the new peer. It contains the generated private key, unless a public key was given in the request.
+	Peer *Peer `json:"Peer"`
+	// ExpiresAt is the exact time at which the peer and its firewall access are removed.
+	ExpiresAt time.Time `json:"ExpiresAt" example:"2024-05-01T14:00:00Z"`
+	// Config is the WireGuard configuration file of the peer in wg-quick format.
+	Config string `json:"Config"`
+}
+
+func NewEphemeralPeer(peer *domain.Peer, config []byte) *EphemeralPeer {
+	ep := &EphemeralPeer{
+		Peer:   NewPeer(peer),
+		Config: string(config),
+	}
+	if peer.ExpiresAt != nil {
+		ep.ExpiresAt = *peer.ExpiresAt
+	}
+
+	return ep
+}
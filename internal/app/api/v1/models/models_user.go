@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/h44z/wg-portal/internal"
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
@@ -18,6 +19,18 @@ type User struct {
 	ProviderName string `json:"ProviderName,omitempty" readonly:"true" example:""`
 	// If this field is set, the user is an admin.
 	IsAdmin bool `json:"IsAdmin" example:"false"`
+	// If this field is set, the user is a helpdesk user. Helpdesk users can view the peers of other users, resend
+	// configuration mails and reset passkeys, but they cannot manage peers or view private keys.
+	IsHelpdesk bool `json:"IsHelpdesk" example:"false"`
+	// The type of the user. Service accounts cannot log in and have neither an email address nor a password,
+	// they can only use the RESTful API. The type cannot be changed after creation.
+	Type string `json:"Type" binding:"omitempty,oneof=human service" example:"human"`
+	// The interfaces the user administrates without global admin rights. Interface admins can manage the peers
+	// of these interfaces.
+	AdminInterfaces []string `json:"AdminInterfaces" example:"wg0"`
+	// The organization of the user. Admins of an organization can only manage the resources of their organization.
+	// Users without organization are not restricted to an organization.
+	Organization string `json:"Organization" example:"acme"`
 
 	// The first name of the user. This field is optional.
 	Firstname string `json:"Firstname" example:"Max"`
@@ -52,24 +65,32 @@ type User struct {
 
 func NewUser(src *domain.User, exposeCredentials bool) *User {
 	u := &User{
-		Identifier:     string(src.Identifier),
-		Email:          src.Email,
-		Source:         string(src.Source),
-		ProviderName:   src.ProviderName,
-		IsAdmin:        src.IsAdmin,
-		Firstname:      src.Firstname,
-		Lastname:       src.Lastname,
-		Phone:          src.Phone,
-		Department:     src.Department,
-		Notes:          src.Notes,
-		Password:       "", // never fill password
-		Disabled:       src.IsDisabled(),
-		DisabledReason: src.DisabledReason,
-		Locked:         src.IsLocked(),
-		LockedReason:   src.LockedReason,
-		ApiToken:       "", // by default, do not expose API token
-		ApiEnabled:     src.IsApiEnabled(),
-		PeerCount:      src.LinkedPeerCount,
+		Identifier:      string(src.Identifier),
+		Email:           src.Email,
+		Source:          string(src.Source),
+		ProviderName:    src.ProviderName,
+		IsAdmin:         src.IsAdmin,
+		IsHelpdesk:      src.IsHelpdesk,
+		Type:            string(domain.UserTypeHuman),
+		AdminInterfaces: internal.SliceString(src.AdminInterfacesStr),
+		Organization:    string(src.OrganizationIdentifier),
+		Firstname:       src.Firstname,
+		Lastname:        src.Lastname,
+		Phone:           src.Phone,
+		Department:      src.Department,
+		Notes:           src.Notes,
+		Password:        "", // never fill password
+		Disabled:        src.IsDisabled(),
+		DisabledReason:  src.DisabledReason,
+		Locked:          src.IsLocked(),
+		LockedReason:    src.LockedReason,
+		ApiToken:        "", // by default, do not expose API token
+		ApiEnabled:      src.IsApiEnabled(),
+		PeerCount:       src.LinkedPeerCount,
+	}
+
+	if src.IsServiceAccount() {
+		u.Type = string(domain.UserTypeService)
 	}
 
 	if exposeCredentials {
@@ -91,21 +112,24 @@ func NewUsers(src []domain.User) []User {
 func NewDomainUser(src *User) *domain.User {
 	now := time.Now()
 	res := &domain.User{
-		Identifier:     domain.UserIdentifier(src.Identifier),
-		Email:          src.Email,
-		Source:         domain.UserSource(src.Source),
-		ProviderName:   src.ProviderName,
-		IsAdmin:        src.IsAdmin,
-		Firstname:      src.Firstname,
-		Lastname:       src.Lastname,
-		Phone:          src.Phone,
-		Department:     src.Department,
-		Notes:          src.Notes,
-		Password:       domain.PrivateString(src.Password),
-		Disabled:       nil, // set below
-		DisabledReason: src.DisabledReason,
-		Locked:         nil, // set below
-		LockedReason:   src.LockedReason,
+		Identifier:         domain.UserIdentifier(src.Identifier),
+		Email:              src.Email,
+		Source:             domain.UserSource(src.Source),
+		ProviderName:       src.ProviderName,
+		IsAdmin:            src.IsAdmin,
+		IsHelpdesk:         src.IsHelpdesk,
+		Type:               domain.UserType(src.Type),
+		AdminInterfacesStr: internal.SliceToString(src.AdminInterfaces),
+		Firstname:          src.Firstname,
+		Lastname:           src.Lastname,
+		Phone:              src.Phone,
+		Department:         src.Department,
+		Notes:              src.Notes,
+		Password:           domain.PrivateString(src.Password),
+		Disabled:           nil, // set below
+		DisabledReason:     src.DisabledReason,
+		Locked:             nil, // set below
+		LockedReason:       src.LockedReason,
 	}
 
 	if src.ApiToken != "" {
@@ -113,6 +137,8 @@ func NewDomainUser(src *User) *domain.User {
 		res.ApiTokenCreated = &now
 	}
 
+	res.OrganizationIdentifier = domain.OrganizationIdentifier(src.Organization)
+
 	if src.Disabled {
 		res.Disabled = &now
 		if src.DisabledReason == "" {
@@ -23,14 +23,19 @@ type UserManager interface {
 	CreateUser(ctx context.Context, user *domain.User) (*domain.User, error)
 }
 
+type StateRestorer interface {
+	Run(ctx context.Context) error
+}
+
 // endregion dependencies
 
 // App is the main application struct.
 type App struct {
 	cfg *config.Config
 
-	wg    WireGuardManager
-	users UserManager
+	wg       WireGuardManager
+	users    UserManager
+	restorer StateRestorer
 }
 
 // Initialize creates a new App instance and initializes it.
@@ -38,12 +43,14 @@ func Initialize(
 	cfg *config.Config,
 	wg WireGuardManager,
 	users UserManager,
+	restorer StateRestorer,
 ) error {
 	a := &App{
 		cfg: cfg,
 
-		wg:    wg,
-		users: users,
+		wg:       wg,
+		users:    users,
+		restorer: restorer,
 	}
 
 	startupContext, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -90,6 +97,12 @@ func (a *App) restoreInterfaceState(ctx context.Context) error {
 		return nil // feature disabled
 	}
 
+	if a.cfg.Restore.Enabled {
+		// the ordered restoration uses its own step timeouts, it is not limited by the startup timeout
+		restoreContext := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+		return a.restorer.Run(restoreContext)
+	}
+
 	err := a.wg.RestoreInterfaceState(ctx, true)
 	if err != nil {
 		return err
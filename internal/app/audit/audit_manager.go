@@ -24,7 +24,7 @@ func NewManager(db ManagerDatabaseRepo) *Manager {
 func (m *Manager) GetAll(ctx context.Context) ([]domain.AuditEntry, error) {
 	currentUser := domain.GetUserInfo(ctx)
 
-	if !currentUser.IsAdmin {
+	if !currentUser.IsGlobalAdmin() {
 		return nil, domain.ErrNoPermission
 	}
 
@@ -7,6 +7,17 @@ type AuthEvent struct {
 	Error    string
 }
 
+type ImpersonationEvent struct {
+	Impersonator domain.UserIdentifier
+	User         domain.UserIdentifier
+	Action       string
+}
+
+type CredentialsResetEvent struct {
+	User        domain.UserIdentifier
+	Credentials int // the number of removed passkeys
+}
+
 type InterfaceEvent struct {
 	Interface domain.Interface
 	Action    string
@@ -16,3 +27,30 @@ type PeerEvent struct {
 	Peer   domain.Peer
 	Action string
 }
+
+type EmergencyEvent struct {
+	Lockdown domain.EmergencyLockdown
+	Action   string
+}
+
+type RoamingEvent struct {
+	Violation domain.RoamingViolation
+	Action    string // the action taken by the roaming policy, warn or disable
+}
+
+type CompromiseEvent struct {
+	Incident domain.PeerCompromise
+}
+
+type PacketCaptureEvent struct {
+	Capture domain.PacketCapture
+	Action  string // start or finish
+	Bytes   int64  // the size of the capture file, only set for finished captures
+	Client  domain.ClientInfo
+}
+
+type KeyRevealEvent struct {
+	Peer   domain.PeerIdentifier
+	Format string // one of the domain.PeerConfigFormat constants or domain.PeerPrivateKeyFormat
+	Client domain.ClientInfo
+}
@@ -84,12 +84,33 @@ func (r *Recorder) connectToMessageBus() error {
 	if err := r.bus.Subscribe(app.TopicAuditLoginFailed, r.handleAuthEvent); err != nil {
 		return fmt.Errorf("failed to subscribe to %s: %w", app.TopicAuditLoginFailed, err)
 	}
+	if err := r.bus.Subscribe(app.TopicAuditImpersonation, r.handleImpersonationEvent); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", app.TopicAuditImpersonation, err)
+	}
+	if err := r.bus.Subscribe(app.TopicAuditEmergency, r.handleEmergencyEvent); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", app.TopicAuditEmergency, err)
+	}
+	if err := r.bus.Subscribe(app.TopicAuditCredentialsReset, r.handleCredentialsResetEvent); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", app.TopicAuditCredentialsReset, err)
+	}
 	if err := r.bus.Subscribe(app.TopicAuditInterfaceChanged, r.handleInterfaceEvent); err != nil {
 		return fmt.Errorf("failed to subscribe to %s: %w", app.TopicAuditInterfaceChanged, err)
 	}
 	if err := r.bus.Subscribe(app.TopicAuditPeerChanged, r.handlePeerEvent); err != nil {
 		return fmt.Errorf("failed to subscribe to %s: %w", app.TopicAuditPeerChanged, err)
 	}
+	if err := r.bus.Subscribe(app.TopicAuditKeyRevealed, r.handleKeyRevealEvent); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", app.TopicAuditKeyRevealed, err)
+	}
+	if err := r.bus.Subscribe(app.TopicAuditRoamingViolation, r.handleRoamingEvent); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", app.TopicAuditRoamingViolation, err)
+	}
+	if err := r.bus.Subscribe(app.TopicAuditPeerCompromised, r.handleCompromiseEvent); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", app.TopicAuditPeerCompromised, err)
+	}
+	if err := r.bus.Subscribe(app.TopicAuditPacketCapture, r.handlePacketCaptureEvent); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", app.TopicAuditPacketCapture, err)
+	}
 
 	return nil
 }
@@ -102,6 +123,30 @@ func (r *Recorder) handleAuthEvent(event domain.AuditEventWrapper[AuthEvent]) {
 	}
 }
 
+func (r *Recorder) handleImpersonationEvent(event domain.AuditEventWrapper[ImpersonationEvent]) {
+	err := r.db.SaveAuditEntry(context.Background(), r.impersonationEventToAuditEntry(event))
+	if err != nil {
+		slog.Error("failed to create audit entry for impersonation event", "error", err)
+		return
+	}
+}
+
+func (r *Recorder) handleEmergencyEvent(event domain.AuditEventWrapper[EmergencyEvent]) {
+	err := r.db.SaveAuditEntry(context.Background(), r.emergencyEventToAuditEntry(event))
+	if err != nil {
+		slog.Error("failed to create audit entry for emergency event", "error", err)
+		return
+	}
+}
+
+func (r *Recorder) handleCredentialsResetEvent(event domain.AuditEventWrapper[CredentialsResetEvent]) {
+	err := r.db.SaveAuditEntry(context.Background(), r.credentialsResetEventToAuditEntry(event))
+	if err != nil {
+		slog.Error("failed to create audit entry for credentials reset event", "error", err)
+		return
+	}
+}
+
 func (r *Recorder) handleInterfaceEvent(event domain.AuditEventWrapper[InterfaceEvent]) {
 	err := r.db.SaveAuditEntry(context.Background(), r.interfaceEventToAuditEntry(event))
 	if err != nil {
@@ -118,12 +163,44 @@ func (r *Recorder) handlePeerEvent(event domain.AuditEventWrapper[PeerEvent]) {
 	}
 }
 
+func (r *Recorder) handleKeyRevealEvent(event domain.AuditEventWrapper[KeyRevealEvent]) {
+	err := r.db.SaveAuditEntry(context.Background(), r.keyRevealEventToAuditEntry(event))
+	if err != nil {
+		slog.Error("failed to create audit entry for key reveal event", "error", err)
+		return
+	}
+}
+
+func (r *Recorder) handleRoamingEvent(event domain.AuditEventWrapper[RoamingEvent]) {
+	err := r.db.SaveAuditEntry(context.Background(), r.roamingEventToAuditEntry(event))
+	if err != nil {
+		slog.Error("failed to create audit entry for roaming event", "error", err)
+		return
+	}
+}
+
+func (r *Recorder) handleCompromiseEvent(event domain.AuditEventWrapper[CompromiseEvent]) {
+	err := r.db.SaveAuditEntry(context.Background(), r.compromiseEventToAuditEntry(event))
+	if err != nil {
+		slog.Error("failed to create audit entry for compromise event", "error", err)
+		return
+	}
+}
+
+func (r *Recorder) handlePacketCaptureEvent(event domain.AuditEventWrapper[PacketCaptureEvent]) {
+	err := r.db.SaveAuditEntry(context.Background(), r.packetCaptureEventToAuditEntry(event))
+	if err != nil {
+		slog.Error("failed to create audit entry for packet capture event", "error", err)
+		return
+	}
+}
+
 func (r *Recorder) authEventToAuditEntry(event domain.AuditEventWrapper[AuthEvent]) *domain.AuditEntry {
 	contextUser := domain.GetUserInfo(event.Ctx)
 	e := domain.AuditEntry{
 		CreatedAt:   time.Now(),
 		Severity:    domain.AuditSeverityLevelLow,
-		ContextUser: contextUser.UserId(),
+		ContextUser: contextUser.AuditUserId(),
 		Origin:      fmt.Sprintf("auth: %s", event.Source),
 		Message:     fmt.Sprintf("%s logged in", event.Event.Username),
 	}
@@ -136,12 +213,76 @@ func (r *Recorder) authEventToAuditEntry(event domain.AuditEventWrapper[AuthEven
 	return &e
 }
 
+func (r *Recorder) impersonationEventToAuditEntry(
+	event domain.AuditEventWrapper[ImpersonationEvent],
+) *domain.AuditEntry {
+	e := domain.AuditEntry{
+		CreatedAt:   time.Now(),
+		Severity:    domain.AuditSeverityLevelHigh,
+		ContextUser: string(event.Event.Impersonator),
+		Origin:      fmt.Sprintf("impersonation: %s", event.Event.Action),
+	}
+
+	switch event.Event.Action {
+	case "start":
+		e.Message = fmt.Sprintf("%s started impersonating %s", event.Event.Impersonator, event.Event.User)
+	case "stop":
+		e.Severity = domain.AuditSeverityLevelLow
+		e.Message = fmt.Sprintf("%s stopped impersonating %s", event.Event.Impersonator, event.Event.User)
+	case "expired":
+		e.Severity = domain.AuditSeverityLevelLow
+		e.Message = fmt.Sprintf("impersonation of %s by %s expired", event.Event.User, event.Event.Impersonator)
+	default:
+		e.Message = fmt.Sprintf("%s: unknown action", event.Event.User)
+	}
+
+	return &e
+}
+
+func (r *Recorder) emergencyEventToAuditEntry(event domain.AuditEventWrapper[EmergencyEvent]) *domain.AuditEntry {
+	contextUser := domain.GetUserInfo(event.Ctx)
+	lockdown := event.Event.Lockdown
+	e := domain.AuditEntry{
+		CreatedAt:   time.Now(),
+		Severity:    domain.AuditSeverityLevelHigh,
+		ContextUser: contextUser.AuditUserId(),
+		Origin:      fmt.Sprintf("emergency: %s", event.Event.Action),
+	}
+
+	switch event.Event.Action {
+	case "lockdown":
+		e.Message = fmt.Sprintf("emergency lockdown of %s %s (%d peers): %s", lockdown.Target, lockdown.TargetId,
+			len(lockdown.Snapshot.Peers), lockdown.Reason)
+	case "restore":
+		e.Message = fmt.Sprintf("emergency lockdown of %s %s restored", lockdown.Target, lockdown.TargetId)
+	default:
+		e.Message = fmt.Sprintf("%s %s: unknown action", lockdown.Target, lockdown.TargetId)
+	}
+
+	return &e
+}
+
+func (r *Recorder) credentialsResetEventToAuditEntry(
+	event domain.AuditEventWrapper[CredentialsResetEvent],
+) *domain.AuditEntry {
+	contextUser := domain.GetUserInfo(event.Ctx)
+	e := domain.AuditEntry{
+		CreatedAt:   time.Now(),
+		Severity:    domain.AuditSeverityLevelHigh,
+		ContextUser: contextUser.AuditUserId(),
+		Origin:      fmt.Sprintf("credentials: %s", event.Source),
+		Message:     fmt.Sprintf("%d passkeys of %s removed", event.Event.Credentials, event.Event.User),
+	}
+
+	return &e
+}
+
 func (r *Recorder) interfaceEventToAuditEntry(event domain.AuditEventWrapper[InterfaceEvent]) *domain.AuditEntry {
 	contextUser := domain.GetUserInfo(event.Ctx)
 	e := domain.AuditEntry{
 		CreatedAt:   time.Now(),
 		Severity:    domain.AuditSeverityLevelLow,
-		ContextUser: contextUser.UserId(),
+		ContextUser: contextUser.AuditUserId(),
 		Origin:      fmt.Sprintf("interface: %s", event.Event.Action),
 	}
 
@@ -160,7 +301,7 @@ func (r *Recorder) peerEventToAuditEntry(event domain.AuditEventWrapper[PeerEven
 	e := domain.AuditEntry{
 		CreatedAt:   time.Now(),
 		Severity:    domain.AuditSeverityLevelLow,
-		ContextUser: contextUser.UserId(),
+		ContextUser: contextUser.AuditUserId(),
 		Origin:      fmt.Sprintf("peer: %s", event.Event.Action),
 	}
 
@@ -173,3 +314,74 @@ func (r *Recorder) peerEventToAuditEntry(event domain.AuditEventWrapper[PeerEven
 
 	return &e
 }
+
+func (r *Recorder) keyRevealEventToAuditEntry(event domain.AuditEventWrapper[KeyRevealEvent]) *domain.AuditEntry {
+	contextUser := domain.GetUserInfo(event.Ctx)
+	e := domain.AuditEntry{
+		CreatedAt:   time.Now(),
+		Severity:    domain.AuditSeverityLevelHigh,
+		ContextUser: contextUser.AuditUserId(),
+		Origin:      fmt.Sprintf("key reveal: %s", event.Event.Format),
+		Message: fmt.Sprintf("private key of %s revealed to %s (%s)", event.Event.Peer,
+			event.Event.Client.IpAddress, event.Event.Client.UserAgent),
+	}
+
+	return &e
+}
+
+func (r *Recorder) roamingEventToAuditEntry(event domain.AuditEventWrapper[RoamingEvent]) *domain.AuditEntry {
+	contextUser := domain.GetUserInfo(event.Ctx)
+	e := domain.AuditEntry{
+		CreatedAt:   time.Now(),
+		Severity:    domain.AuditSeverityLevelHigh,
+		ContextUser: contextUser.AuditUserId(),
+		Origin:      fmt.Sprintf("roaming policy: %s", event.Event.Action),
+		Message: fmt.Sprintf("peer %s of %s violated the roaming policy: %s", event.Event.Violation.PeerId,
+			event.Event.Violation.UserIdentifier, event.Event.Violation.Message),
+	}
+
+	return &e
+}
+
+func (r *Recorder) compromiseEventToAuditEntry(event domain.AuditEventWrapper[CompromiseEvent]) *domain.AuditEntry {
+	contextUser := domain.GetUserInfo(event.Ctx)
+	incident := event.Event.Incident
+	e := domain.AuditEntry{
+		CreatedAt:   time.Now(),
+		Severity:    domain.AuditSeverityLevelHigh,
+		ContextUser: contextUser.AuditUserId(),
+		Origin:      "incident: key compromise",
+		Message: fmt.Sprintf("peer %s of %s on %s marked as compromised, %d preshared keys rotated: %s",
+			incident.PeerIdentifier, incident.UserIdentifier, incident.InterfaceIdentifier,
+			len(incident.RotatedPeers), incident.Reason),
+	}
+
+	return &e
+}
+
+func (r *Recorder) packetCaptureEventToAuditEntry(
+	event domain.AuditEventWrapper[PacketCaptureEvent],
+) *domain.AuditEntry {
+	contextUser := domain.GetUserInfo(event.Ctx)
+	capture := event.Event.Capture
+	e := domain.AuditEntry{
+		CreatedAt:   time.Now(),
+		Severity:    domain.AuditSeverityLevelHigh,
+		ContextUser: contextUser.AuditUserId(),
+		Origin:      fmt.Sprintf("packet capture: %s", event.Event.Action),
+	}
+
+	switch event.Event.Action {
+	case "start":
+		e.Message = fmt.Sprintf("capture of peer %s on %s (%s) started for %s by %s", capture.PeerId,
+			capture.InterfaceId, capture.Filter(), capture.Duration, event.Event.Client.IpAddress)
+	case "finish":
+		e.Severity = domain.AuditSeverityLevelLow
+		e.Message = fmt.Sprintf("capture of peer %s on %s finished after %s, %d bytes captured", capture.PeerId,
+			capture.InterfaceId, time.Since(capture.StartedAt).Truncate(time.Second), event.Event.Bytes)
+	default:
+		e.Message = fmt.Sprintf("%s: unknown action", capture.PeerId)
+	}
+
+	return &e
+}
@@ -8,9 +8,12 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
 	"net/url"
 	"path"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
@@ -36,6 +39,8 @@ type UserManager interface {
 type EventBus interface {
 	// Publish sends a message to the message bus.
 	Publish(topic string, args ...any)
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
 }
 
 // endregion dependencies
@@ -65,6 +70,14 @@ type AuthenticatorOauth interface {
 	RegistrationEnabled() bool
 	// GetAllowedDomains returns the list of whitelisted domains
 	GetAllowedDomains() []string
+	// SetClientSecret replaces the client secret, for example, if the secret was rotated.
+	SetClientSecret(secret string)
+}
+
+// AuthenticatorOidcLogout is implemented by OAuth authenticators that support OpenID Connect back-channel logout.
+type AuthenticatorOidcLogout interface {
+	// VerifyLogoutToken validates the logout token and returns the provider session that has ended.
+	VerifyLogoutToken(ctx context.Context, rawToken string) (*domain.OidcSession, error)
 }
 
 // AuthenticatorLdap is the interface for all LDAP authenticators.
@@ -84,11 +97,11 @@ type AuthenticatorLdap interface {
 // Authenticator is the main entry point for all authentication related tasks.
 // This includes password authentication and external authentication providers (OIDC, OAuth, LDAP).
 type Authenticator struct {
-	cfg *config.Auth
 	bus EventBus
 
-	oauthAuthenticators map[string]AuthenticatorOauth
-	ldapAuthenticators  map[string]AuthenticatorLdap
+	mux       sync.RWMutex // protects cfg and providers, both are replaced if the config is reloaded
+	cfg       *config.Auth
+	providers *authProviders
 
 	// URL prefix for the callback endpoints, this is a combination of the external URL and the API prefix
 	callbackUrlPrefix string
@@ -96,6 +109,13 @@ type Authenticator struct {
 	users UserManager
 }
 
+// authProviders are the external authentication providers that were set up from the auth config.
+type authProviders struct {
+	oauth map[string]AuthenticatorOauth
+	ldap  map[string]AuthenticatorLdap
+	proxy *ProxyAuthenticator // only set if the proxy authentication is enabled
+}
+
 // NewAuthenticator creates a new Authenticator instance.
 func NewAuthenticator(cfg *config.Auth, extUrl string, bus EventBus, users UserManager) (
 	*Authenticator,
@@ -111,29 +131,106 @@ func NewAuthenticator(cfg *config.Auth, extUrl string, bus EventBus, users UserM
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	err := a.setupExternalAuthProviders(ctx)
+	providers, err := a.setupExternalAuthProviders(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
+	a.providers = providers
+
+	a.connectToMessageBus()
 
 	return a, nil
 }
 
-func (a *Authenticator) setupExternalAuthProviders(ctx context.Context) error {
+func (a *Authenticator) connectToMessageBus() {
+	_ = a.bus.Subscribe(app.TopicSecretRotated, a.handleSecretRotatedEvent)
+	_ = a.bus.Subscribe(app.TopicConfigReloaded, a.handleConfigReloadedEvent)
+}
+
+// current returns the current auth config and the authentication providers that were set up from the config.
+func (a *Authenticator) current() (*config.Auth, *authProviders) {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+
+	return a.cfg, a.providers
+}
+
+// handleConfigReloadedEvent sets up the authentication providers of the reloaded config. If the setup fails, the
+// current providers are kept.
+func (a *Authenticator) handleConfigReloadedEvent(cfg *config.Config) {
+	current, _ := a.current()
+	if reflect.DeepEqual(*current, cfg.Auth) {
+		return
+	}
+
+	slog.Debug("handling config reloaded event", "section", "auth")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	authCfg := cfg.Auth // the reloaded config must not be modified
+	providers, err := a.setupExternalAuthProviders(ctx, &authCfg)
+	if err != nil {
+		slog.Error("failed to reload authentication providers, keeping the current providers", "error", err)
+		return
+	}
+
+	a.mux.Lock()
+	a.cfg = &authCfg
+	a.providers = providers
+	a.mux.Unlock()
+
+	slog.Info("reloaded authentication providers",
+		"oidcProviders", len(authCfg.OpenIDConnect),
+		"oauthProviders", len(authCfg.OAuth),
+		"ldapProviders", len(authCfg.Ldap),
+		"proxyAuth", authCfg.ProxyAuth.Enabled)
+}
+
+// handleSecretRotatedEvent replaces the client secret of the OAuth or OIDC provider whose secret was rotated.
+func (a *Authenticator) handleSecretRotatedEvent(rotation domain.SecretRotation) {
+	cfg, _ := a.current()
+	for _, providerCfg := range cfg.OpenIDConnect {
+		if rotation.Field == config.OidcClientSecretField(providerCfg.ProviderName) {
+			a.setClientSecret(providerCfg.ProviderName, string(rotation.Value))
+		}
+	}
+	for _, providerCfg := range cfg.OAuth {
+		if rotation.Field == config.OauthClientSecretField(providerCfg.ProviderName) {
+			a.setClientSecret(providerCfg.ProviderName, string(rotation.Value))
+		}
+	}
+}
+
+func (a *Authenticator) setClientSecret(providerName, secret string) {
+	_, providers := a.current()
+	provider, ok := providers.oauth[strings.ToLower(providerName)]
+	if !ok {
+		return
+	}
+
+	slog.Debug("replacing rotated client secret", "provider", providerName)
+	provider.SetClientSecret(secret)
+}
+
+// setupExternalAuthProviders sets up the external authentication providers of the given config.
+func (a *Authenticator) setupExternalAuthProviders(ctx context.Context, cfg *config.Auth) (*authProviders, error) {
 	extUrl, err := url.Parse(a.callbackUrlPrefix)
 	if err != nil {
-		return fmt.Errorf("failed to parse external url: %w", err)
+		return nil, fmt.Errorf("failed to parse external url: %w", err)
 	}
 
-	a.oauthAuthenticators = make(map[string]AuthenticatorOauth, len(a.cfg.OpenIDConnect)+len(a.cfg.OAuth))
-	a.ldapAuthenticators = make(map[string]AuthenticatorLdap, len(a.cfg.Ldap))
+	providers := &authProviders{
+		oauth: make(map[string]AuthenticatorOauth, len(cfg.OpenIDConnect)+len(cfg.OAuth)),
+		ldap:  make(map[string]AuthenticatorLdap, len(cfg.Ldap)),
+	}
 
-	for i := range a.cfg.OpenIDConnect { // OIDC
-		providerCfg := &a.cfg.OpenIDConnect[i]
+	for i := range cfg.OpenIDConnect { // OIDC
+		providerCfg := &cfg.OpenIDConnect[i]
 		providerId := strings.ToLower(providerCfg.ProviderName)
 
-		if _, exists := a.oauthAuthenticators[providerId]; exists {
-			return fmt.Errorf("auth provider with name %s is already registerd", providerId)
+		if _, exists := providers.oauth[providerId]; exists {
+			return nil, fmt.Errorf("auth provider with name %s is already registerd", providerId)
 		}
 
 		redirectUrl := *extUrl
@@ -141,16 +238,16 @@ func (a *Authenticator) setupExternalAuthProviders(ctx context.Context) error {
 
 		provider, err := newOidcAuthenticator(ctx, redirectUrl.String(), providerCfg)
 		if err != nil {
-			return fmt.Errorf("failed to setup oidc authentication provider %s: %w", providerCfg.ProviderName, err)
+			return nil, fmt.Errorf("failed to setup oidc authentication provider %s: %w", providerCfg.ProviderName, err)
 		}
-		a.oauthAuthenticators[providerId] = provider
+		providers.oauth[providerId] = provider
 	}
-	for i := range a.cfg.OAuth { // PLAIN OAUTH
-		providerCfg := &a.cfg.OAuth[i]
+	for i := range cfg.OAuth { // PLAIN OAUTH
+		providerCfg := &cfg.OAuth[i]
 		providerId := strings.ToLower(providerCfg.ProviderName)
 
-		if _, exists := a.oauthAuthenticators[providerId]; exists {
-			return fmt.Errorf("auth provider with name %s is already registerd", providerId)
+		if _, exists := providers.oauth[providerId]; exists {
+			return nil, fmt.Errorf("auth provider with name %s is already registerd", providerId)
 		}
 
 		redirectUrl := *extUrl
@@ -158,33 +255,41 @@ func (a *Authenticator) setupExternalAuthProviders(ctx context.Context) error {
 
 		provider, err := newPlainOauthAuthenticator(ctx, redirectUrl.String(), providerCfg)
 		if err != nil {
-			return fmt.Errorf("failed to setup oauth authentication provider %s: %w", providerId, err)
+			return nil, fmt.Errorf("failed to setup oauth authentication provider %s: %w", providerId, err)
 		}
-		a.oauthAuthenticators[providerId] = provider
+		providers.oauth[providerId] = provider
 	}
-	for i := range a.cfg.Ldap { // LDAP
-		providerCfg := &a.cfg.Ldap[i]
+	for i := range cfg.Ldap { // LDAP
+		providerCfg := &cfg.Ldap[i]
 		providerId := strings.ToLower(providerCfg.URL)
 
-		if _, exists := a.ldapAuthenticators[providerId]; exists {
-			return fmt.Errorf("auth provider with name %s is already registerd", providerId)
+		if _, exists := providers.ldap[providerId]; exists {
+			return nil, fmt.Errorf("auth provider with name %s is already registerd", providerId)
 		}
 
 		provider, err := newLdapAuthenticator(ctx, providerCfg)
 		if err != nil {
-			return fmt.Errorf("failed to setup ldap authentication provider %s: %w", providerId, err)
+			return nil, fmt.Errorf("failed to setup ldap authentication provider %s: %w", providerId, err)
+		}
+		providers.ldap[providerId] = provider
+	}
+	if cfg.ProxyAuth.Enabled { // TRUSTED PROXY HEADERS
+		provider, err := newProxyAuthenticator(&cfg.ProxyAuth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup proxy authentication: %w", err)
 		}
-		a.ldapAuthenticators[providerId] = provider
+		providers.proxy = provider
 	}
 
-	return nil
+	return providers, nil
 }
 
 // GetExternalLoginProviders returns a list of all available external login providers.
 func (a *Authenticator) GetExternalLoginProviders(_ context.Context) []domain.LoginProviderInfo {
-	authProviders := make([]domain.LoginProviderInfo, 0, len(a.cfg.OAuth)+len(a.cfg.OpenIDConnect))
+	cfg, _ := a.current()
+	authProviders := make([]domain.LoginProviderInfo, 0, len(cfg.OAuth)+len(cfg.OpenIDConnect))
 
-	for _, provider := range a.cfg.OpenIDConnect {
+	for _, provider := range cfg.OpenIDConnect {
 		providerId := strings.ToLower(provider.ProviderName)
 		providerName := provider.DisplayName
 		if providerName == "" {
@@ -198,7 +303,7 @@ func (a *Authenticator) GetExternalLoginProviders(_ context.Context) []domain.Lo
 		})
 	}
 
-	for _, provider := range a.cfg.OAuth {
+	for _, provider := range cfg.OAuth {
 		providerId := strings.ToLower(provider.ProviderName)
 		providerName := provider.DisplayName
 		if providerName == "" {
@@ -234,6 +339,93 @@ func (a *Authenticator) IsUserValid(ctx context.Context, id domain.UserIdentifie
 	return true
 }
 
+// region impersonation
+
+// StartImpersonation validates that the current context user is allowed to impersonate the given user.
+// Only administrators can impersonate other users, and administrators themselves cannot be impersonated.
+// Organization admins can only impersonate users of their own organization.
+// On success, the user that will be impersonated is returned and an audit event is recorded.
+func (a *Authenticator) StartImpersonation(ctx context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	if cfg, _ := a.current(); cfg.ImpersonationTimeout <= 0 {
+		return nil, fmt.Errorf("impersonation is disabled: %w", domain.ErrNoPermission)
+	}
+
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	impersonator := domain.GetUserInfo(ctx)
+	if impersonator.ImpersonatedBy != "" {
+		return nil, fmt.Errorf("nested impersonation is not allowed: %w", domain.ErrNoPermission)
+	}
+	if impersonator.Id == id {
+		return nil, fmt.Errorf("cannot impersonate yourself: %w", domain.ErrInvalidData)
+	}
+
+	user, err := a.users.GetUser(domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo()), id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load user %s: %w", id, err)
+	}
+
+	if user.IsAdmin {
+		return nil, fmt.Errorf("administrators cannot be impersonated: %w", domain.ErrNoPermission)
+	}
+	if user.IsServiceAccount() {
+		return nil, fmt.Errorf("service accounts cannot be impersonated: %w", domain.ErrNoPermission)
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, user.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
+	if user.IsDisabled() || user.IsLocked() {
+		return nil, fmt.Errorf("user %s is disabled or locked: %w", id, domain.ErrInvalidData)
+	}
+
+	a.bus.Publish(app.TopicAuditImpersonation, domain.AuditEventWrapper[audit.ImpersonationEvent]{
+		Ctx:    ctx,
+		Source: "impersonation",
+		Event: audit.ImpersonationEvent{
+			Impersonator: impersonator.Id,
+			User:         user.Identifier,
+			Action:       "start",
+		},
+	})
+
+	return user, nil
+}
+
+// StopImpersonation ends the impersonation of the given user and returns the impersonating administrator.
+// The reason is recorded in the audit log, it should either be "stop" or "expired".
+// If the administrator is no longer valid or lost the admin privileges, an error is returned.
+func (a *Authenticator) StopImpersonation(
+	ctx context.Context,
+	impersonatorId, id domain.UserIdentifier,
+	reason string,
+) (*domain.User, error) {
+	a.bus.Publish(app.TopicAuditImpersonation, domain.AuditEventWrapper[audit.ImpersonationEvent]{
+		Ctx:    ctx,
+		Source: "impersonation",
+		Event: audit.ImpersonationEvent{
+			Impersonator: impersonatorId,
+			User:         id,
+			Action:       reason,
+		},
+	})
+
+	impersonator, err := a.users.GetUser(domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo()),
+		impersonatorId)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load impersonator %s: %w", impersonatorId, err)
+	}
+
+	if !impersonator.IsAdmin || impersonator.IsDisabled() || impersonator.IsLocked() {
+		return nil, fmt.Errorf("impersonator %s is no longer valid: %w", impersonatorId, domain.ErrNoPermission)
+	}
+
+	return impersonator, nil
+}
+
+// endregion impersonation
+
 // region password authentication
 
 // PlainLogin performs a password authentication for a user. The username and password are trimmed before usage.
@@ -291,10 +483,14 @@ func (a *Authenticator) passwordAuthentication(
 	if userInDatabase && (existingUser.IsLocked() || existingUser.IsDisabled()) {
 		return nil, errors.New("user is locked")
 	}
+	if userInDatabase && existingUser.IsServiceAccount() {
+		return nil, errors.New("login not allowed for service accounts")
+	}
 
 	if !userInDatabase || userSource == domain.UserSourceLdap {
 		// search user in ldap if registration is enabled
-		for _, ldapAuth := range a.ldapAuthenticators {
+		_, providers := a.current()
+		for _, ldapAuth := range providers.ldap {
 			if !userInDatabase && !ldapAuth.RegistrationEnabled() {
 				continue
 			}
@@ -353,6 +549,68 @@ func (a *Authenticator) passwordAuthentication(
 
 // endregion password authentication
 
+// region proxy authentication
+
+// ProxyLogin logs in the user that was authenticated by a trusted authentication proxy. The user is identified by
+// the request headers set by the proxy, the headers are only accepted if the request originates from a trusted proxy.
+func (a *Authenticator) ProxyLogin(ctx context.Context, remoteAddr string, header http.Header) (*domain.User, error) {
+	_, providers := a.current()
+	proxyProvider := providers.proxy
+	if proxyProvider == nil {
+		return nil, errors.New("proxy authentication is disabled")
+	}
+	if !proxyProvider.IsTrustedProxy(remoteAddr) {
+		slog.Warn("ignoring authentication headers from untrusted source", "source", remoteAddr)
+		return nil, fmt.Errorf("request from untrusted source %s: %w", remoteAddr, domain.ErrNoPermission)
+	}
+
+	userInfo, err := proxyProvider.ParseUserInfo(header)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse user information: %w", err)
+	}
+
+	ctx = domain.SetUserInfo(ctx,
+		domain.SystemAdminContextUserInfo()) // switch to admin user context to check if user exists
+	user, err := a.processUserInfo(ctx, userInfo, domain.UserSourceProxy, proxyProvider.GetName(),
+		proxyProvider.RegistrationEnabled())
+	if err != nil {
+		a.bus.Publish(app.TopicAuditLoginFailed, domain.AuditEventWrapper[audit.AuthEvent]{
+			Ctx:    ctx,
+			Source: "proxy",
+			Event: audit.AuthEvent{
+				Username: string(userInfo.Identifier),
+				Error:    err.Error(),
+			},
+		})
+		return nil, fmt.Errorf("unable to process user information: %w", err)
+	}
+
+	if user.IsLocked() || user.IsDisabled() {
+		a.bus.Publish(app.TopicAuditLoginFailed, domain.AuditEventWrapper[audit.AuthEvent]{
+			Ctx:    ctx,
+			Source: "proxy",
+			Event: audit.AuthEvent{
+				Username: string(user.Identifier),
+				Error:    "user is locked",
+			},
+		})
+		return nil, errors.New("user is locked")
+	}
+
+	a.bus.Publish(app.TopicAuthLogin, user.Identifier)
+	a.bus.Publish(app.TopicAuditLoginSuccess, domain.AuditEventWrapper[audit.AuthEvent]{
+		Ctx:    ctx,
+		Source: "proxy",
+		Event: audit.AuthEvent{
+			Username: string(user.Identifier),
+		},
+	})
+
+	return user, nil
+}
+
+// endregion proxy authentication
+
 // region oauth authentication
 
 // OauthLoginStep1 starts the oauth authentication flow by returning the authentication URL, state and nonce.
@@ -360,7 +618,8 @@ func (a *Authenticator) OauthLoginStep1(_ context.Context, providerId string) (
 	authCodeUrl, state, nonce string,
 	err error,
 ) {
-	oauthProvider, ok := a.oauthAuthenticators[providerId]
+	_, providers := a.current()
+	oauthProvider, ok := providers.oauth[providerId]
 	if !ok {
 		return "", "", "", fmt.Errorf("missing oauth provider %s", providerId)
 	}
@@ -412,26 +671,32 @@ func isDomainAllowed(email string, allowedDomains []string) bool {
 }
 
 // OauthLoginStep2 finishes the oauth authentication flow by exchanging the code for an access token and
-// fetching the user information.
-func (a *Authenticator) OauthLoginStep2(ctx context.Context, providerId, nonce, code string) (*domain.User, error) {
-	oauthProvider, ok := a.oauthAuthenticators[providerId]
+// fetching the user information. For OpenID Connect providers, the provider session is returned as well, so the
+// portal session can be ended by a back-channel logout.
+func (a *Authenticator) OauthLoginStep2(ctx context.Context, providerId, nonce, code string) (
+	*domain.User,
+	*domain.OidcSession,
+	error,
+) {
+	_, providers := a.current()
+	oauthProvider, ok := providers.oauth[providerId]
 	if !ok {
-		return nil, fmt.Errorf("missing oauth provider %s", providerId)
+		return nil, nil, fmt.Errorf("missing oauth provider %s", providerId)
 	}
 
 	oauth2Token, err := oauthProvider.Exchange(ctx, code)
 	if err != nil {
-		return nil, fmt.Errorf("unable to exchange code: %w", err)
+		return nil, nil, fmt.Errorf("unable to exchange code: %w", err)
 	}
 
 	rawUserInfo, err := oauthProvider.GetUserInfo(ctx, oauth2Token, nonce)
 	if err != nil {
-		return nil, fmt.Errorf("unable to fetch user information: %w", err)
+		return nil, nil, fmt.Errorf("unable to fetch user information: %w", err)
 	}
 
 	userInfo, err := oauthProvider.ParseUserInfo(rawUserInfo)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse user information: %w", err)
+		return nil, nil, fmt.Errorf("unable to parse user information: %w", err)
 	}
 
 	ctx = domain.SetUserInfo(ctx,
@@ -447,11 +712,11 @@ func (a *Authenticator) OauthLoginStep2(ctx context.Context, providerId, nonce,
 				Error:    err.Error(),
 			},
 		})
-		return nil, fmt.Errorf("unable to process user information: %w", err)
+		return nil, nil, fmt.Errorf("unable to process user information: %w", err)
 	}
 
 	if !isDomainAllowed(userInfo.Email, oauthProvider.GetAllowedDomains()) {
-		return nil, fmt.Errorf("user is not in allowed domains: %w", err)
+		return nil, nil, fmt.Errorf("user is not in allowed domains: %w", err)
 	}
 
 	if user.IsLocked() || user.IsDisabled() {
@@ -463,7 +728,7 @@ func (a *Authenticator) OauthLoginStep2(ctx context.Context, providerId, nonce,
 				Error:    "user is locked",
 			},
 		})
-		return nil, errors.New("user is locked")
+		return nil, nil, errors.New("user is locked")
 	}
 
 	a.bus.Publish(app.TopicAuthLogin, user.Identifier)
@@ -475,7 +740,45 @@ func (a *Authenticator) OauthLoginStep2(ctx context.Context, providerId, nonce,
 		},
 	})
 
-	return user, nil
+	var oidcSession *domain.OidcSession
+	if oauthProvider.GetType() == AuthenticatorTypeOidc {
+		oidcSession = &domain.OidcSession{Provider: providerId}
+		oidcSession.Subject, _ = rawUserInfo["sub"].(string)
+		oidcSession.SessionId, _ = rawUserInfo["sid"].(string)
+	}
+
+	return user, oidcSession, nil
+}
+
+// BackchannelLogout validates the logout token that was sent by the given OpenID Connect provider and returns the
+// provider session that has ended.
+func (a *Authenticator) BackchannelLogout(ctx context.Context, providerId, logoutToken string) (
+	*domain.OidcSession,
+	error,
+) {
+	_, providers := a.current()
+	oauthProvider, ok := providers.oauth[providerId]
+	if !ok {
+		return nil, fmt.Errorf("missing oauth provider %s: %w", providerId, domain.ErrNotFound)
+	}
+	logoutProvider, ok := oauthProvider.(AuthenticatorOidcLogout)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support back-channel logout: %w", providerId,
+			domain.ErrInvalidData)
+	}
+
+	session, err := logoutProvider.VerifyLogoutToken(ctx, logoutToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidData, err)
+	}
+	session.Provider = providerId
+
+	slog.Debug("received back-channel logout",
+		"provider", providerId,
+		"subject", session.Subject,
+		"session", session.SessionId)
+
+	return session, nil
 }
 
 func (a *Authenticator) processUserInfo(
@@ -495,6 +798,8 @@ func (a *Authenticator) processUserInfo(
 		}
 	case err != nil:
 		return nil, fmt.Errorf("registration disabled, cannot create missing user: %w", err)
+	case user.IsServiceAccount():
+		return nil, fmt.Errorf("login not allowed for service account %s", user.Identifier)
 	default:
 		err = a.updateExternalUser(ctx, user, userInfo, source, provider)
 		if err != nil {
@@ -538,15 +843,16 @@ func (a *Authenticator) registerNewUser(
 }
 
 func (a *Authenticator) getAuthenticatorConfig(id string) (any, error) {
-	for i := range a.cfg.OpenIDConnect {
-		if a.cfg.OpenIDConnect[i].ProviderName == id {
-			return a.cfg.OpenIDConnect[i], nil
+	cfg, _ := a.current()
+	for i := range cfg.OpenIDConnect {
+		if cfg.OpenIDConnect[i].ProviderName == id {
+			return cfg.OpenIDConnect[i], nil
 		}
 	}
 
-	for i := range a.cfg.OAuth {
-		if a.cfg.OAuth[i].ProviderName == id {
-			return a.cfg.OAuth[i], nil
+	for i := range cfg.OAuth {
+		if cfg.OAuth[i].ProviderName == id {
+			return cfg.OAuth[i], nil
 		}
 	}
 
@@ -21,6 +21,7 @@ import (
 type PlainOauthAuthenticator struct {
 	name                string
 	cfg                 *oauth2.Config
+	clientSecret        *oauthClientSecret
 	userInfoEndpoint    string
 	client              *http.Client
 	userInfoMapping     config.OauthFields
@@ -53,6 +54,7 @@ func newPlainOauthAuthenticator(
 		Scopes:      cfg.Scopes,
 	}
 	provider.userInfoEndpoint = cfg.UserInfoURL
+	provider.clientSecret = newOauthClientSecret(cfg.ClientSecret)
 	provider.userInfoMapping = getOauthFieldMapping(cfg.FieldMap)
 	provider.userAdminMapping = &cfg.AdminMapping
 	provider.registrationEnabled = cfg.RegistrationEnabled
@@ -92,7 +94,12 @@ func (p PlainOauthAuthenticator) Exchange(
 	code string,
 	opts ...oauth2.AuthCodeOption,
 ) (*oauth2.Token, error) {
-	return p.cfg.Exchange(ctx, code, opts...)
+	return p.clientSecret.config(p.cfg).Exchange(ctx, code, opts...)
+}
+
+// SetClientSecret replaces the client secret, for example, if the secret was rotated.
+func (p PlainOauthAuthenticator) SetClientSecret(secret string) {
+	p.clientSecret.set(secret)
 }
 
 // GetUserInfo retrieves the user information from the user info endpoint.
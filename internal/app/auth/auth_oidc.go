@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"golang.org/x/oauth2"
@@ -14,12 +15,17 @@ import (
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
+// backchannelLogoutEvent is the event type of logout tokens, see OpenID Connect Back-Channel Logout 1.0.
+const backchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
 // OidcAuthenticator is an authenticator for OpenID Connect providers.
 type OidcAuthenticator struct {
 	name                string
 	provider            *oidc.Provider
 	verifier            *oidc.IDTokenVerifier
+	logoutVerifier      *oidc.IDTokenVerifier
 	cfg                 *oauth2.Config
+	clientSecret        *oauthClientSecret
 	userInfoMapping     config.OauthFields
 	userAdminMapping    *config.OauthAdminMapping
 	registrationEnabled bool
@@ -44,6 +50,10 @@ func newOidcAuthenticator(
 	provider.verifier = provider.provider.Verifier(&oidc.Config{
 		ClientID: cfg.ClientID,
 	})
+	provider.logoutVerifier = provider.provider.Verifier(&oidc.Config{
+		ClientID:        cfg.ClientID,
+		SkipExpiryCheck: true, // the exp claim is optional for logout tokens, it is checked in VerifyLogoutToken
+	})
 
 	scopes := []string{oidc.ScopeOpenID}
 	scopes = append(scopes, cfg.ExtraScopes...)
@@ -54,6 +64,7 @@ func newOidcAuthenticator(
 		RedirectURL:  callbackUrl,
 		Scopes:       scopes,
 	}
+	provider.clientSecret = newOauthClientSecret(cfg.ClientSecret)
 	provider.userInfoMapping = getOauthFieldMapping(cfg.FieldMap)
 	provider.userAdminMapping = &cfg.AdminMapping
 	provider.registrationEnabled = cfg.RegistrationEnabled
@@ -92,7 +103,12 @@ func (o OidcAuthenticator) Exchange(ctx context.Context, code string, opts ...oa
 	*oauth2.Token,
 	error,
 ) {
-	return o.cfg.Exchange(ctx, code, opts...)
+	return o.clientSecret.config(o.cfg).Exchange(ctx, code, opts...)
+}
+
+// SetClientSecret replaces the client secret, for example, if the secret was rotated.
+func (o OidcAuthenticator) SetClientSecret(secret string) {
+	o.clientSecret.set(secret)
 }
 
 // GetUserInfo retrieves the user info from the token.
@@ -131,3 +147,43 @@ func (o OidcAuthenticator) GetUserInfo(ctx context.Context, token *oauth2.Token,
 func (o OidcAuthenticator) ParseUserInfo(raw map[string]any) (*domain.AuthenticatorUserInfo, error) {
 	return parseOauthUserInfo(o.userInfoMapping, o.userAdminMapping, raw)
 }
+
+// VerifyLogoutToken validates the given back-channel logout token and returns the provider session that has ended.
+// The provider field of the returned session is not set.
+func (o OidcAuthenticator) VerifyLogoutToken(ctx context.Context, rawToken string) (*domain.OidcSession, error) {
+	token, err := o.logoutVerifier.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate logout token: %w", err)
+	}
+
+	return parseLogoutToken(token.Subject, token.Nonce, token.Expiry, token.Claims)
+}
+
+// parseLogoutToken checks the claims of a logout token with a valid signature.
+func parseLogoutToken(subject, nonce string, expiry time.Time, claims func(v any) error) (*domain.OidcSession, error) {
+	var logoutClaims struct {
+		SessionId string                     `json:"sid"`
+		Events    map[string]json.RawMessage `json:"events"`
+	}
+	if err := claims(&logoutClaims); err != nil {
+		return nil, fmt.Errorf("failed to parse logout token claims: %w", err)
+	}
+
+	if _, ok := logoutClaims.Events[backchannelLogoutEvent]; !ok {
+		return nil, errors.New("logout token does not contain the back-channel logout event")
+	}
+	if nonce != "" {
+		return nil, errors.New("logout token must not contain a nonce")
+	}
+	if !expiry.IsZero() && time.Now().After(expiry) {
+		return nil, errors.New("logout token is expired")
+	}
+	if subject == "" && logoutClaims.SessionId == "" {
+		return nil, errors.New("logout token does neither contain a subject nor a session id")
+	}
+
+	return &domain.OidcSession{
+		Subject:   subject,
+		SessionId: logoutClaims.SessionId,
+	}, nil
+}
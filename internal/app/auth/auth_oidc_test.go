@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logoutClaims(raw string) func(v any) error {
+	return func(v any) error {
+		return json.Unmarshal([]byte(raw), v)
+	}
+}
+
+func Test_parseLogoutToken(t *testing.T) {
+	validClaims := `{"sid": "sid-1", "events": {"http://schemas.openid.net/event/backchannel-logout": {}}}`
+
+	session, err := parseLogoutToken("sub-1", "", time.Time{}, logoutClaims(validClaims))
+	require.NoError(t, err)
+	assert.Equal(t, "sub-1", session.Subject)
+	assert.Equal(t, "sid-1", session.SessionId)
+
+	session, err = parseLogoutToken("", "", time.Now().Add(time.Minute), logoutClaims(validClaims))
+	require.NoError(t, err)
+	assert.Empty(t, session.Subject)
+	assert.Equal(t, "sid-1", session.SessionId)
+}
+
+func Test_parseLogoutToken_invalid(t *testing.T) {
+	validClaims := `{"sid": "sid-1", "events": {"http://schemas.openid.net/event/backchannel-logout": {}}}`
+
+	tests := []struct {
+		name    string
+		subject string
+		nonce   string
+		expiry  time.Time
+		claims  string
+	}{
+		{"MissingEvent", "sub-1", "", time.Time{}, `{"sid": "sid-1", "events": {}}`},
+		{"NoEvents", "sub-1", "", time.Time{}, `{"sid": "sid-1"}`},
+		{"Nonce", "sub-1", "nonce", time.Time{}, validClaims},
+		{"Expired", "sub-1", "", time.Now().Add(-time.Minute), validClaims},
+		{"NoSubjectOrSession", "", "", time.Time{},
+			`{"events": {"http://schemas.openid.net/event/backchannel-logout": {}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseLogoutToken(tt.subject, tt.nonce, tt.expiry, logoutClaims(tt.claims))
+			assert.Error(t, err)
+		})
+	}
+}
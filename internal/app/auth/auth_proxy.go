@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// ProxyAuthenticator authenticates users based on the request headers of a trusted authentication proxy.
+type ProxyAuthenticator struct {
+	cfg             *config.ProxyAuthConfig
+	trustedProxies  []netip.Prefix
+	adminGroupRegex *regexp.Regexp
+	groupSeparator  string
+}
+
+func newProxyAuthenticator(cfg *config.ProxyAuthConfig) (*ProxyAuthenticator, error) {
+	provider := &ProxyAuthenticator{
+		cfg:            cfg,
+		groupSeparator: cfg.GroupSeparator,
+	}
+	if provider.groupSeparator == "" {
+		provider.groupSeparator = ","
+	}
+
+	if len(cfg.TrustedProxies) == 0 {
+		return nil, errors.New("at least one trusted proxy is required")
+	}
+	for _, trusted := range cfg.TrustedProxies {
+		prefix, err := parseTrustedProxy(trusted)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %s: %w", trusted, err)
+		}
+		provider.trustedProxies = append(provider.trustedProxies, prefix)
+	}
+
+	if cfg.AdminGroupRegex != "" {
+		adminGroupRegex, err := regexp.Compile(cfg.AdminGroupRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admin_group_regex: %w", err)
+		}
+		provider.adminGroupRegex = adminGroupRegex
+	}
+
+	return provider, nil
+}
+
+// parseTrustedProxy parses a single IP address or a CIDR range.
+func parseTrustedProxy(trusted string) (netip.Prefix, error) {
+	trusted = strings.TrimSpace(trusted)
+	if strings.Contains(trusted, "/") {
+		prefix, err := netip.ParsePrefix(trusted)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(trusted)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// GetName returns the name of the authenticator.
+func (p ProxyAuthenticator) GetName() string {
+	return p.cfg.ProviderName
+}
+
+// RegistrationEnabled returns whether registration is enabled for this authenticator.
+func (p ProxyAuthenticator) RegistrationEnabled() bool {
+	return p.cfg.RegistrationEnabled
+}
+
+// IsTrustedProxy returns true if the given remote address of a request belongs to a trusted proxy.
+// Forwarding headers like X-Forwarded-For are not considered, the proxy must connect to wg-portal directly.
+func (p ProxyAuthenticator) IsTrustedProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(remoteAddr))
+	if err != nil {
+		host = strings.TrimSpace(remoteAddr) // address without port
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, trusted := range p.trustedProxies {
+		if trusted.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseUserInfo maps the authentication headers of the proxy to the internal user info struct.
+func (p ProxyAuthenticator) ParseUserInfo(header http.Header) (*domain.AuthenticatorUserInfo, error) {
+	userId := strings.TrimSpace(header.Get(p.cfg.UserHeader))
+	if userId == "" {
+		return nil, fmt.Errorf("missing user header %s", p.cfg.UserHeader)
+	}
+
+	firstname, lastname, _ := strings.Cut(strings.TrimSpace(header.Get(p.cfg.NameHeader)), " ")
+
+	isAdmin := false
+	if p.adminGroupRegex != nil && p.cfg.GroupsHeader != "" {
+		for _, group := range strings.Split(header.Get(p.cfg.GroupsHeader), p.groupSeparator) {
+			if p.adminGroupRegex.MatchString(strings.TrimSpace(group)) {
+				isAdmin = true
+				break
+			}
+		}
+	}
+
+	userInfo := &domain.AuthenticatorUserInfo{
+		Identifier: domain.UserIdentifier(userId),
+		Email:      strings.TrimSpace(header.Get(p.cfg.EmailHeader)),
+		Firstname:  firstname,
+		Lastname:   strings.TrimSpace(lastname),
+		IsAdmin:    isAdmin,
+	}
+
+	return userInfo, nil
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+)
+
+func newTestProxyAuthenticator(t *testing.T) *ProxyAuthenticator {
+	provider, err := newProxyAuthenticator(&config.ProxyAuthConfig{
+		ProviderName:    "proxy",
+		TrustedProxies:  []string{"10.0.0.1", "172.16.0.0/12", "fd00::/8"},
+		UserHeader:      "Remote-User",
+		EmailHeader:     "Remote-Email",
+		NameHeader:      "Remote-Name",
+		GroupsHeader:    "Remote-Groups",
+		AdminGroupRegex: "^wg-admins$",
+	})
+	require.NoError(t, err)
+
+	return provider
+}
+
+func Test_newProxyAuthenticator_invalid(t *testing.T) {
+	_, err := newProxyAuthenticator(&config.ProxyAuthConfig{})
+	assert.Error(t, err, "trusted proxies are required")
+
+	_, err = newProxyAuthenticator(&config.ProxyAuthConfig{TrustedProxies: []string{"proxy.local"}})
+	assert.Error(t, err)
+
+	_, err = newProxyAuthenticator(&config.ProxyAuthConfig{
+		TrustedProxies:  []string{"10.0.0.1"},
+		AdminGroupRegex: "(",
+	})
+	assert.Error(t, err)
+}
+
+func TestProxyAuthenticator_IsTrustedProxy(t *testing.T) {
+	provider := newTestProxyAuthenticator(t)
+
+	tests := []struct {
+		remoteAddr string
+		want       bool
+	}{
+		{"10.0.0.1:54321", true},
+		{"10.0.0.1", true},
+		{"[::ffff:10.0.0.1]:54321", true},
+		{"10.0.0.2:54321", false},
+		{"172.20.1.5:443", true},
+		{"172.32.0.1:443", false},
+		{"[fd12::1]:8080", true},
+		{"[2001:db8::1]:8080", false},
+		{"", false},
+		{"invalid", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remoteAddr, func(t *testing.T) {
+			assert.Equal(t, tt.want, provider.IsTrustedProxy(tt.remoteAddr))
+		})
+	}
+}
+
+func TestProxyAuthenticator_ParseUserInfo(t *testing.T) {
+	provider := newTestProxyAuthenticator(t)
+
+	header := http.Header{}
+	header.Set("Remote-User", " jdoe ")
+	header.Set("Remote-Email", "jdoe@example.com")
+	header.Set("Remote-Name", "John van Doe")
+	header.Set("Remote-Groups", "users, wg-admins")
+
+	info, err := provider.ParseUserInfo(header)
+	require.NoError(t, err)
+	assert.Equal(t, "jdoe", string(info.Identifier))
+	assert.Equal(t, "jdoe@example.com", info.Email)
+	assert.Equal(t, "John", info.Firstname)
+	assert.Equal(t, "van Doe", info.Lastname)
+	assert.True(t, info.IsAdmin)
+
+	header.Set("Remote-Groups", "users,wg-admins-old")
+	info, err = provider.ParseUserInfo(header)
+	require.NoError(t, err)
+	assert.False(t, info.IsAdmin)
+
+	_, err = provider.ParseUserInfo(http.Header{})
+	assert.Error(t, err, "the user header is required")
+}
@@ -2,12 +2,44 @@ package auth
 
 import (
 	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
 
 	"github.com/h44z/wg-portal/internal"
 	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
+// oauthClientSecret holds the client secret of an OAuth or OIDC authenticator, it is replaced if the secret is
+// rotated.
+type oauthClientSecret struct {
+	mux   sync.RWMutex
+	value string
+}
+
+func newOauthClientSecret(value string) *oauthClientSecret {
+	return &oauthClientSecret{value: value}
+}
+
+// set replaces the client secret.
+func (s *oauthClientSecret) set(value string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.value = value
+}
+
+// config returns a copy of the given OAuth config that uses the current client secret.
+func (s *oauthClientSecret) config(base *oauth2.Config) *oauth2.Config {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	cfg := *base
+	cfg.ClientSecret = s.value
+	return &cfg
+}
+
 // parseOauthUserInfo parses the raw user info from the oauth provider and maps it to the internal user info struct
 func parseOauthUserInfo(
 	mapping config.OauthFields,
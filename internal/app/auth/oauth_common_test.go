@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
 
 	"github.com/h44z/wg-portal/internal/config"
 )
@@ -181,3 +182,15 @@ func Test_parseOauthUserInfo_admin_value_custom(t *testing.T) {
 	assert.Equal(t, info.Lastname, "")
 	assert.Equal(t, info.Email, "test@mydomain.net")
 }
+
+func Test_oauthClientSecret(t *testing.T) {
+	base := &oauth2.Config{ClientID: "wg-portal", ClientSecret: "initial"}
+	secret := newOauthClientSecret("initial")
+
+	secret.set("rotated")
+	cfg := secret.config(base)
+
+	assert.Equal(t, "rotated", cfg.ClientSecret)
+	assert.Equal(t, "wg-portal", cfg.ClientID)
+	assert.Equal(t, "initial", base.ClientSecret, "the base config must not be modified")
+}
@@ -86,6 +86,9 @@ func (a *WebAuthnAuthenticator) StartWebAuthnRegistration(ctx context.Context, u
 	if user.IsLocked() || user.IsDisabled() {
 		return nil, nil, errors.New("user is locked") // adding passkey to locked user is not allowed
 	}
+	if user.IsServiceAccount() {
+		return nil, nil, errors.New("service accounts cannot use passkeys")
+	}
 
 	if user.WebAuthnId == "" {
 		user.GenerateWebAuthnId()
@@ -272,6 +275,9 @@ func (a *WebAuthnAuthenticator) FinishWebAuthnLogin(
 		})
 		return nil, errors.New("user is locked") // login with passkey is not allowed
 	}
+	if user.IsServiceAccount() {
+		return nil, errors.New("login not allowed for service accounts")
+	}
 
 	a.bus.Publish(app.TopicAuthLogin, user.Identifier)
 	a.bus.Publish(app.TopicAuditLoginSuccess, domain.AuditEventWrapper[audit.AuthEvent]{
@@ -0,0 +1,317 @@
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetInterfacePeers returns all peers for the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	// GetUsedIpsPerSubnet returns the used interface and peer addresses grouped by the given subnets.
+	GetUsedIpsPerSubnet(ctx context.Context, subnets []domain.Cidr) (map[domain.Cidr][]domain.Cidr, error)
+	// GetAddressReservations returns all address reservations of the given interface.
+	GetAddressReservations(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.AddressReservation, error)
+	// GetAllUsers returns all users.
+	GetAllUsers(ctx context.Context) ([]domain.User, error)
+}
+
+type MetricsServer interface {
+	// UpdateAddressPoolMetrics updates the metrics for the given address pool.
+	UpdateAddressPoolMetrics(pool domain.AddressPoolCapacity)
+}
+
+type MailManager interface {
+	// SendAddressPoolWarning sends an email to the given administrator that lists almost exhausted address pools.
+	SendAddressPoolWarning(
+		ctx context.Context,
+		userId domain.UserIdentifier,
+		iface *domain.Interface,
+		pools []domain.AddressPoolCapacity,
+	) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager calculates the utilization of the peer networks (address pools) of all interfaces. It periodically
+// updates the address pool metrics and warns the responsible administrators once a pool crosses the warning
+// threshold. A pool that drops below the threshold again is warned about the next time it crosses it.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db      DatabaseRepo
+	metrics MetricsServer
+	mail    MailManager
+
+	mux    *sync.Mutex
+	warned map[poolKey]struct{} // the address pools that are above the warning threshold
+}
+
+// NewCapacityManager creates a new address pool capacity manager.
+func NewCapacityManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	metrics MetricsServer,
+	mail MailManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:      db,
+		metrics: metrics,
+		mail:    mail,
+
+		mux:    &sync.Mutex{},
+		warned: make(map[poolKey]struct{}),
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background jobs for the capacity manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if m.cfg.Capacity.CheckInterval <= 0 {
+		return
+	}
+
+	go m.runCapacityCheck(ctx)
+
+	slog.Debug("started address pool capacity checks",
+		"interval", m.cfg.Capacity.CheckInterval,
+		"threshold", m.cfg.Capacity.WarningThreshold)
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceDeletedEvent)
+}
+
+func (m Manager) handleInterfaceDeletedEvent(iface domain.Interface) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for key := range m.warned {
+		if key.iface == iface.Identifier {
+			delete(m.warned, key)
+		}
+	}
+}
+
+func (m Manager) runCapacityCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		if err := m.checkCapacity(ctx, time.Now()); err != nil {
+			slog.Error("failed to check address pool capacity", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-time.After(m.cfg.Capacity.CheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+	}
+}
+
+// GetCapacity returns the utilization of all address pools of the given interface.
+func (m Manager) GetCapacity(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.AddressPoolCapacity, error) {
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load interface %s: %w", id, err)
+	}
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
+
+	return m.calculateCapacity(ctx, iface, time.Now())
+}
+
+// checkCapacity updates the metrics of all address pools and warns the administrators about pools that crossed
+// the warning threshold since the last check.
+func (m Manager) checkCapacity(ctx context.Context, now time.Time) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	var users []domain.User
+	for _, iface := range interfaces {
+		pools, err := m.calculateCapacity(ctx, &iface, now)
+		if err != nil {
+			return err
+		}
+
+		var crossed []domain.AddressPoolCapacity
+		for _, pool := range pools {
+			m.metrics.UpdateAddressPoolMetrics(pool)
+
+			key := poolKey{iface: iface.Identifier, network: pool.Network.String()}
+			if !m.exceedsThreshold(pool) {
+				delete(m.warned, key)
+				continue
+			}
+			if _, ok := m.warned[key]; ok {
+				continue // already warned
+			}
+			m.warned[key] = struct{}{}
+			crossed = append(crossed, pool)
+		}
+		if len(crossed) == 0 {
+			continue
+		}
+
+		if users == nil {
+			if users, err = m.db.GetAllUsers(ctx); err != nil {
+				return fmt.Errorf("failed to load users: %w", err)
+			}
+		}
+		m.sendWarnings(ctx, &iface, crossed, users)
+	}
+
+	return nil
+}
+
+func (m Manager) exceedsThreshold(pool domain.AddressPoolCapacity) bool {
+	threshold := m.cfg.Capacity.WarningThreshold
+	return threshold > 0 && pool.Utilization() >= float64(threshold)
+}
+
+// sendWarnings warns all administrators that are responsible for the given interface.
+func (m Manager) sendWarnings(
+	ctx context.Context,
+	iface *domain.Interface,
+	pools []domain.AddressPoolCapacity,
+	users []domain.User,
+) {
+	for _, user := range users {
+		if !isResponsibleAdmin(&user, iface) {
+			continue
+		}
+
+		if err := m.mail.SendAddressPoolWarning(ctx, user.Identifier, iface, pools); err != nil {
+			slog.Error("failed to send address pool warning",
+				"interface", iface.Identifier,
+				"user", user.Identifier,
+				"error", err)
+		}
+	}
+}
+
+// calculateCapacity returns the utilization of all peer networks of the given interface. The growth is based on
+// the peers that were created within the configured growth window.
+func (m Manager) calculateCapacity(
+	ctx context.Context,
+	iface *domain.Interface,
+	now time.Time,
+) ([]domain.AddressPoolCapacity, error) {
+	if iface.PeerDefNetworkStr == "" {
+		return nil, nil // interfaces without peer network have no address pool
+	}
+
+	networks, err := domain.CidrsFromString(iface.PeerDefNetworkStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse peer networks of interface %s: %w", iface.Identifier, err)
+	}
+
+	usedIps, err := m.db.GetUsedIpsPerSubnet(ctx, networks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load used addresses of interface %s: %w", iface.Identifier, err)
+	}
+
+	// reserved and excluded addresses are never allocated to new peers, so they are counted as used
+	reservations, err := m.db.GetAddressReservations(ctx, iface.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load address reservations of interface %s: %w", iface.Identifier, err)
+	}
+	for _, network := range networks {
+		for _, reservation := range reservations {
+			addr := reservation.Addr()
+			if !network.Prefix().Contains(addr) {
+				continue
+			}
+			inUse := slices.ContainsFunc(usedIps[network], func(ip domain.Cidr) bool { return ip.Prefix().Addr() == addr })
+			if !inUse {
+				usedIps[network] = append(usedIps[network], domain.CidrFromPrefix(netip.PrefixFrom(addr, addr.BitLen())))
+			}
+		}
+	}
+
+	peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peers of interface %s: %w", iface.Identifier, err)
+	}
+
+	window := m.cfg.Capacity.GrowthWindow
+	pools := make([]domain.AddressPoolCapacity, 0, len(networks))
+	for _, network := range networks {
+		allocated := 0
+		for _, peer := range peers {
+			if now.Sub(peer.CreatedAt) > window {
+				continue
+			}
+			for _, addr := range peer.Interface.Addresses {
+				if network.Contains(addr) {
+					allocated++
+				}
+			}
+		}
+
+		pools = append(pools,
+			domain.NewAddressPoolCapacity(iface.Identifier, network, usedIps[network], allocated, window, now))
+	}
+
+	return pools, nil
+}
+
+// isResponsibleAdmin returns true if the user is an administrator of the given interface. Admins that are
+// restricted to another organization are not responsible.
+func isResponsibleAdmin(user *domain.User, iface *domain.Interface) bool {
+	if user.IsDisabled() || user.IsLocked() {
+		return false
+	}
+
+	if slices.Contains(user.AdminInterfaces(), iface.Identifier) {
+		return true
+	}
+
+	return user.IsAdmin &&
+		(user.OrganizationIdentifier == "" || user.OrganizationIdentifier == iface.OrganizationIdentifier)
+}
+
+type poolKey struct {
+	iface   domain.InterfaceIdentifier
+	network string
+}
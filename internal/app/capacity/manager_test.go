@@ -0,0 +1,200 @@
+package capacity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	interfaces   []domain.Interface
+	peers        []domain.Peer
+	usedIps      []domain.Cidr
+	reservations []domain.AddressReservation
+	users        []domain.User
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	for _, iface := range f.interfaces {
+		if iface.Identifier == id {
+			return &iface, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	return f.peers, nil
+}
+
+func (f *fakeDatabase) GetUsedIpsPerSubnet(_ context.Context, subnets []domain.Cidr) (
+	map[domain.Cidr][]domain.Cidr,
+	error,
+) {
+	result := make(map[domain.Cidr][]domain.Cidr)
+	for _, ip := range f.usedIps {
+		for _, subnet := range subnets {
+			if subnet.Contains(ip) {
+				result[subnet] = append(result[subnet], ip)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeDatabase) GetAddressReservations(_ context.Context, _ domain.InterfaceIdentifier) (
+	[]domain.AddressReservation,
+	error,
+) {
+	return f.reservations, nil
+}
+
+func (f *fakeDatabase) GetAllUsers(_ context.Context) ([]domain.User, error) {
+	return f.users, nil
+}
+
+type fakeMetrics struct {
+	pools map[string]domain.AddressPoolCapacity
+}
+
+func (f *fakeMetrics) UpdateAddressPoolMetrics(pool domain.AddressPoolCapacity) {
+	f.pools[pool.Network.String()] = pool
+}
+
+type fakeMailManager struct {
+	sent []domain.UserIdentifier
+}
+
+func (f *fakeMailManager) SendAddressPoolWarning(
+	_ context.Context,
+	userId domain.UserIdentifier,
+	_ *domain.Interface,
+	_ []domain.AddressPoolCapacity,
+) error {
+	f.sent = append(f.sent, userId)
+	return nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func mustCidrs(t *testing.T, strs ...string) []domain.Cidr {
+	cidrs, err := domain.CidrsFromArray(strs)
+	require.NoError(t, err)
+	return cidrs
+}
+
+func newTestManager(t *testing.T, db *fakeDatabase) (*Manager, *fakeMetrics, *fakeMailManager) {
+	cfg := &config.Config{}
+	cfg.Capacity.WarningThreshold = 80
+	cfg.Capacity.GrowthWindow = 10 * 24 * time.Hour
+
+	metrics := &fakeMetrics{pools: map[string]domain.AddressPoolCapacity{}}
+	mail := &fakeMailManager{}
+	m, err := NewCapacityManager(cfg, fakeBus{}, db, metrics, mail)
+	require.NoError(t, err)
+
+	return m, metrics, mail
+}
+
+func TestManager_checkCapacity(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	db := &fakeDatabase{
+		interfaces: []domain.Interface{
+			{Identifier: "wg0", PeerDefNetworkStr: "10.0.0.0/29,fd00::/64", OrganizationIdentifier: "acme"},
+		},
+		usedIps: mustCidrs(t, "10.0.0.1/32", "10.0.0.2/32", "10.0.0.3/32", "10.0.0.4/32"),
+		users: []domain.User{
+			{Identifier: "global-admin", IsAdmin: true},
+			{Identifier: "acme-admin", IsAdmin: true, OrganizationIdentifier: "acme"},
+			{Identifier: "other-admin", IsAdmin: true, OrganizationIdentifier: "other"},
+			{Identifier: "iface-admin", AdminInterfacesStr: "wg0"},
+			{Identifier: "user"},
+		},
+	}
+	m, metrics, mail := newTestManager(t, db)
+
+	require.NoError(t, m.checkCapacity(context.Background(), now))
+	assert.Equal(t, uint64(4), metrics.pools["10.0.0.0/29"].Used)
+	assert.Contains(t, metrics.pools, "fd00::/64")
+	assert.Empty(t, mail.sent, "the pool is below the warning threshold")
+
+	db.usedIps = append(db.usedIps, mustCidrs(t, "10.0.0.5/32")...)
+	require.NoError(t, m.checkCapacity(context.Background(), now))
+	assert.Equal(t, []domain.UserIdentifier{"global-admin", "acme-admin", "iface-admin"}, mail.sent)
+
+	// admins are warned once per threshold crossing
+	mail.sent = nil
+	require.NoError(t, m.checkCapacity(context.Background(), now))
+	assert.Empty(t, mail.sent)
+
+	db.usedIps = db.usedIps[:4]
+	require.NoError(t, m.checkCapacity(context.Background(), now))
+	db.usedIps = append(db.usedIps, mustCidrs(t, "10.0.0.6/32")...)
+	require.NoError(t, m.checkCapacity(context.Background(), now))
+	assert.Len(t, mail.sent, 3)
+}
+
+func TestManager_GetCapacity(t *testing.T) {
+	now := time.Now()
+	db := &fakeDatabase{
+		interfaces: []domain.Interface{
+			{Identifier: "wg0", PeerDefNetworkStr: "10.0.0.0/24", OrganizationIdentifier: "acme"},
+			{Identifier: "wg1"},
+		},
+		usedIps: mustCidrs(t, "10.0.0.1/32", "10.0.0.2/32"),
+		peers: []domain.Peer{
+			{Identifier: "new", Interface: domain.PeerInterfaceConfig{Addresses: mustCidrs(t, "10.0.0.2/32")}},
+			{Identifier: "old", Interface: domain.PeerInterfaceConfig{Addresses: mustCidrs(t, "10.0.0.3/32")}},
+		},
+	}
+	db.peers[0].CreatedAt = now.Add(-24 * time.Hour)
+	db.peers[1].CreatedAt = now.Add(-30 * 24 * time.Hour)
+	m, _, _ := newTestManager(t, db)
+
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	pools, err := m.GetCapacity(adminCtx, "wg0")
+	require.NoError(t, err)
+	require.Len(t, pools, 1)
+	assert.Equal(t, uint64(2), pools[0].Used)
+	assert.InDelta(t, 0.1, pools[0].GrowthPerDay, 0.001, "only peers of the growth window are counted")
+	assert.NotNil(t, pools[0].ExhaustedAt)
+
+	db.reservations = []domain.AddressReservation{
+		{InterfaceIdentifier: "wg0", Address: "10.0.0.2", Kind: domain.AddressReservationReserved},
+		{InterfaceIdentifier: "wg0", Address: "10.0.0.10", Kind: domain.AddressReservationExcluded},
+	}
+	pools, err = m.GetCapacity(adminCtx, "wg0")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), pools[0].Used, "unused reserved addresses are counted as used")
+
+	pools, err = m.GetCapacity(adminCtx, "wg1")
+	require.NoError(t, err)
+	assert.Empty(t, pools, "interfaces without peer network have no address pool")
+
+	ifaceAdminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{
+		Id:              "iface-admin",
+		AdminInterfaces: []domain.InterfaceIdentifier{"wg1"},
+	})
+	_, err = m.GetCapacity(ifaceAdminCtx, "wg0")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	otherOrgCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{
+		Id:           "other-admin",
+		IsAdmin:      true,
+		Organization: "other",
+	})
+	_, err = m.GetCapacity(otherOrgCtx, "wg0")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
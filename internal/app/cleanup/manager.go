@@ -0,0 +1,353 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers for the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	// GetPeersStats returns the stats for the given peer ids.
+	GetPeersStats(ctx context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error)
+	// GetPeerCleanupNotices returns all stored peer cleanup notices.
+	GetPeerCleanupNotices(ctx context.Context) ([]domain.PeerCleanupNotice, error)
+	// SavePeerCleanupNotice creates or updates the given peer cleanup notice.
+	SavePeerCleanupNotice(ctx context.Context, notice *domain.PeerCleanupNotice) error
+	// DeletePeerCleanupNotice deletes the peer cleanup notice for the given peer id.
+	DeletePeerCleanupNotice(ctx context.Context, id domain.PeerIdentifier) error
+}
+
+type PeerManager interface {
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+	// DeletePeer deletes the peer with the given identifier.
+	DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
+}
+
+type MailManager interface {
+	// SendPeerCleanupWarning sends an email to the given user that lists all peers which will be cleaned up.
+	SendPeerCleanupWarning(
+		ctx context.Context,
+		userId domain.UserIdentifier,
+		candidates []domain.PeerCleanupCandidate,
+	) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager periodically checks for peers without a recent handshake and disables or deletes them.
+// Peer owners are warned by mail before any action is taken.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db    DatabaseRepo
+	peers PeerManager
+	mail  MailManager
+}
+
+// NewCleanupManager creates a new peer cleanup manager.
+func NewCleanupManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	peers PeerManager,
+	mail MailManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:    db,
+		peers: peers,
+		mail:  mail,
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background jobs for the cleanup manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.PeerCleanup.Enabled {
+		return
+	}
+
+	go m.runCleanupCheck(ctx)
+
+	slog.Debug("started peer cleanup checks", "dryRun", m.cfg.PeerCleanup.DryRun)
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerDeletedEvent)
+}
+
+func (m Manager) handlePeerDeletedEvent(peer domain.Peer) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	err := m.db.DeletePeerCleanupNotice(ctx, peer.Identifier)
+	if err != nil {
+		slog.Error("failed to delete cleanup notice of deleted peer", "peer", peer.Identifier, "error", err)
+	}
+}
+
+// GetCleanupReport returns all peers that are currently affected by the cleanup policy.
+// The report does not modify any data, it can be used as a dry-run.
+func (m Manager) GetCleanupReport(ctx context.Context) ([]domain.PeerCleanupCandidate, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	candidates, _, err := m.collectCandidates(ctx, time.Now())
+	return candidates, err
+}
+
+func (m Manager) runCleanupCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(m.cfg.PeerCleanup.CheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		candidates, reactivated, err := m.collectCandidates(ctx, time.Now())
+		if err != nil {
+			slog.Error("failed to collect peer cleanup candidates", "error", err)
+			continue
+		}
+
+		if m.cfg.PeerCleanup.DryRun {
+			m.logReport(candidates)
+			continue
+		}
+
+		m.resetNotices(ctx, reactivated)
+		m.processCandidates(ctx, candidates)
+	}
+}
+
+// collectCandidates returns all peers that are affected by the cleanup policy. Additionally, all peers that were
+// warned before but are no longer affected (for example, because they were used again) are returned.
+func (m Manager) collectCandidates(ctx context.Context, now time.Time) (
+	[]domain.PeerCleanupCandidate,
+	[]domain.PeerIdentifier,
+	error,
+) {
+	notices, err := m.db.GetPeerCleanupNotices(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load cleanup notices: %w", err)
+	}
+	warnings := make(map[domain.PeerIdentifier]time.Time, len(notices))
+	for _, notice := range notices {
+		warnings[notice.PeerId] = notice.WarnedAt
+	}
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	var candidates []domain.PeerCleanupCandidate
+	var reactivated []domain.PeerIdentifier
+	for _, iface := range interfaces {
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load peers of interface %s: %w", iface.Identifier, err)
+		}
+
+		peerIds := make([]domain.PeerIdentifier, len(peers))
+		for i, peer := range peers {
+			peerIds[i] = peer.Identifier
+		}
+		stats, err := m.db.GetPeersStats(ctx, peerIds...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load peer stats of interface %s: %w", iface.Identifier, err)
+		}
+		statsMap := make(map[domain.PeerIdentifier]domain.PeerStatus, len(stats))
+		for _, s := range stats {
+			statsMap[s.PeerId] = s
+		}
+
+		for _, peer := range peers {
+			var status *domain.PeerStatus
+			if s, ok := statsMap[peer.Identifier]; ok {
+				status = &s
+			}
+
+			var warnedAt *time.Time
+			if w, ok := warnings[peer.Identifier]; ok {
+				warnedAt = &w
+			}
+
+			candidate, affected := evaluatePeer(m.cfg.PeerCleanup, peer, status, warnedAt, now)
+			if !affected {
+				if warnedAt != nil {
+					reactivated = append(reactivated, peer.Identifier)
+				}
+				continue
+			}
+
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ActionAt.Before(candidates[j].ActionAt)
+	})
+
+	return candidates, reactivated, nil
+}
+
+// evaluatePeer determines the cleanup stage of the given peer.
+// The second return value is false if the peer is not affected by the cleanup policy.
+func evaluatePeer(
+	cfg config.PeerCleanupConfig,
+	peer domain.Peer,
+	status *domain.PeerStatus,
+	warnedAt *time.Time,
+	now time.Time,
+) (domain.PeerCleanupCandidate, bool) {
+	if peer.IsDisabled() || peer.IsExcludedFromCleanup(cfg.ExclusionTag) {
+		return domain.PeerCleanupCandidate{}, false
+	}
+
+	lastActivity := domain.PeerLastActivity(peer, status)
+	actionAt := lastActivity.Add(cfg.InactivityPeriod())
+	warnAt := actionAt.Add(-cfg.WarningPeriod())
+
+	if now.Before(warnAt) {
+		return domain.PeerCleanupCandidate{}, false
+	}
+
+	candidate := domain.PeerCleanupCandidate{
+		Peer:         peer,
+		LastActivity: lastActivity,
+		WarnedAt:     warnedAt,
+		ActionAt:     actionAt,
+	}
+
+	if warnedAt == nil {
+		// owners always get the full warning period, even if the peer is inactive for a longer time
+		warnedActionAt := now.Add(cfg.WarningPeriod())
+		if warnedActionAt.After(candidate.ActionAt) {
+			candidate.ActionAt = warnedActionAt
+		}
+		candidate.Stage = domain.PeerCleanupStageWarn
+		return candidate, true
+	}
+
+	warnedActionAt := warnedAt.Add(cfg.WarningPeriod())
+	if warnedActionAt.After(candidate.ActionAt) {
+		candidate.ActionAt = warnedActionAt
+	}
+
+	if now.Before(candidate.ActionAt) {
+		candidate.Stage = domain.PeerCleanupStagePending
+	} else {
+		candidate.Stage = domain.PeerCleanupStageAction
+	}
+
+	return candidate, true
+}
+
+func (m Manager) logReport(candidates []domain.PeerCleanupCandidate) {
+	for _, candidate := range candidates {
+		slog.Info("[DRY-RUN] peer cleanup candidate",
+			"peer", candidate.Peer.Identifier,
+			"interface", candidate.Peer.InterfaceIdentifier,
+			"user", candidate.Peer.UserIdentifier,
+			"lastActivity", candidate.LastActivity,
+			"stage", candidate.Stage,
+			"actionAt", candidate.ActionAt)
+	}
+}
+
+func (m Manager) resetNotices(ctx context.Context, peers []domain.PeerIdentifier) {
+	for _, peerId := range peers {
+		if err := m.db.DeletePeerCleanupNotice(ctx, peerId); err != nil {
+			slog.Warn("failed to reset peer cleanup notice", "peer", peerId, "error", err)
+		}
+	}
+}
+
+func (m Manager) processCandidates(ctx context.Context, candidates []domain.PeerCleanupCandidate) {
+	now := time.Now()
+	warnings := make(map[domain.UserIdentifier][]domain.PeerCleanupCandidate)
+
+	for _, candidate := range candidates {
+		switch candidate.Stage {
+		case domain.PeerCleanupStageWarn:
+			err := m.db.SavePeerCleanupNotice(ctx, &domain.PeerCleanupNotice{
+				PeerId:   candidate.Peer.Identifier,
+				WarnedAt: now,
+			})
+			if err != nil {
+				slog.Error("failed to store peer cleanup notice", "peer", candidate.Peer.Identifier, "error", err)
+				continue
+			}
+			if candidate.Peer.UserIdentifier != "" {
+				warnings[candidate.Peer.UserIdentifier] = append(warnings[candidate.Peer.UserIdentifier], candidate)
+			}
+		case domain.PeerCleanupStageAction:
+			m.cleanupPeer(ctx, candidate.Peer)
+		}
+	}
+
+	for userId, userCandidates := range warnings {
+		err := m.mail.SendPeerCleanupWarning(ctx, userId, userCandidates)
+		if err != nil {
+			slog.Error("failed to send peer cleanup warning", "user", userId, "error", err)
+		}
+	}
+}
+
+func (m Manager) cleanupPeer(ctx context.Context, peer domain.Peer) {
+	switch m.cfg.PeerCleanup.Action {
+	case config.PeerCleanupActionDelete:
+		slog.Info("peer is inactive, deleting", "peer", peer.Identifier)
+
+		if err := m.peers.DeletePeer(ctx, peer.Identifier); err != nil {
+			slog.Error("failed to delete inactive peer", "peer", peer.Identifier, "error", err)
+			return
+		}
+	default:
+		slog.Info("peer is inactive, disabling", "peer", peer.Identifier)
+
+		now := time.Now()
+		peer.Disabled = &now
+		peer.DisabledReason = domain.DisabledReasonInactive
+
+		if _, err := m.peers.UpdatePeer(ctx, &peer); err != nil {
+			slog.Error("failed to disable inactive peer", "peer", peer.Identifier, "error", err)
+			return
+		}
+	}
+
+	if err := m.db.DeletePeerCleanupNotice(ctx, peer.Identifier); err != nil {
+		slog.Warn("failed to delete peer cleanup notice", "peer", peer.Identifier, "error", err)
+	}
+}
@@ -0,0 +1,78 @@
+package cleanup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func Test_evaluatePeer(t *testing.T) {
+	cfg := config.PeerCleanupConfig{
+		InactiveDays: 30,
+		WarningDays:  7,
+		Action:       config.PeerCleanupActionDisable,
+		ExclusionTag: "#keep",
+	}
+	now := time.Now()
+	daysAgo := func(days int) time.Time {
+		return now.Add(-time.Duration(days) * 24 * time.Hour)
+	}
+	newPeer := func(updatedDaysAgo int, notes string) domain.Peer {
+		return domain.Peer{
+			BaseModel:  domain.BaseModel{CreatedAt: daysAgo(100), UpdatedAt: daysAgo(updatedDaysAgo)},
+			Identifier: "peer",
+			Notes:      notes,
+		}
+	}
+
+	t.Run("recently active", func(t *testing.T) {
+		handshake := daysAgo(1)
+		_, affected := evaluatePeer(cfg, newPeer(50, ""), &domain.PeerStatus{LastHandshake: &handshake}, nil, now)
+		assert.False(t, affected)
+	})
+
+	t.Run("excluded by tag", func(t *testing.T) {
+		_, affected := evaluatePeer(cfg, newPeer(50, "office printer #KEEP"), nil, nil, now)
+		assert.False(t, affected)
+	})
+
+	t.Run("disabled peer", func(t *testing.T) {
+		peer := newPeer(50, "")
+		disabled := daysAgo(40)
+		peer.Disabled = &disabled
+		_, affected := evaluatePeer(cfg, peer, nil, nil, now)
+		assert.False(t, affected)
+	})
+
+	t.Run("within warning period", func(t *testing.T) {
+		candidate, affected := evaluatePeer(cfg, newPeer(25, ""), nil, nil, now)
+		assert.True(t, affected)
+		assert.Equal(t, domain.PeerCleanupStageWarn, candidate.Stage)
+		assert.WithinDuration(t, now.Add(cfg.WarningPeriod()), candidate.ActionAt, time.Second)
+	})
+
+	t.Run("long inactive but not warned", func(t *testing.T) {
+		candidate, affected := evaluatePeer(cfg, newPeer(50, ""), nil, nil, now)
+		assert.True(t, affected)
+		assert.Equal(t, domain.PeerCleanupStageWarn, candidate.Stage)
+		assert.WithinDuration(t, now.Add(cfg.WarningPeriod()), candidate.ActionAt, time.Second)
+	})
+
+	t.Run("warned recently", func(t *testing.T) {
+		warnedAt := daysAgo(2)
+		candidate, affected := evaluatePeer(cfg, newPeer(50, ""), nil, &warnedAt, now)
+		assert.True(t, affected)
+		assert.Equal(t, domain.PeerCleanupStagePending, candidate.Stage)
+	})
+
+	t.Run("warning period elapsed", func(t *testing.T) {
+		warnedAt := daysAgo(8)
+		candidate, affected := evaluatePeer(cfg, newPeer(50, ""), nil, &warnedAt, now)
+		assert.True(t, affected)
+		assert.Equal(t, domain.PeerCleanupStageAction, candidate.Stage)
+	})
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"errors"
 	"flag"
 
 	"gorm.io/gorm"
@@ -15,6 +16,10 @@ func HandleProgramArgs(db *gorm.DB) (exit bool, err error) {
 		"old database type, either mysql, mssql, postgres or sqlite")
 	flag.Parse()
 
+	if *migrationSource != "" && db == nil {
+		return true, errors.New("migration is only supported for SQL databases")
+	}
+
 	if *migrationSource != "" {
 		err = migrateFromV1(db, *migrationSource, *migrationDbType)
 		exit = true
@@ -0,0 +1,218 @@
+package compromise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type PeerManager interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// GetUserPeers returns all peers of the given user.
+	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+}
+
+type MailService interface {
+	// SendPeerCompromiseNotification informs the owner of a peer that the peer was marked as compromised.
+	SendPeerCompromiseNotification(ctx context.Context, incident *domain.PeerCompromise) error
+}
+
+type Mailer interface {
+	// Send sends an email with the given subject and body to the given recipients.
+	Send(ctx context.Context, subject, body string, to []string, options *domain.MailOptions) error
+}
+
+type EventBus interface {
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
+}
+
+// endregion dependencies
+
+// Manager handles the response to compromised peers. A compromised peer is disabled immediately, the preshared keys
+// of the other peers of the owner are rotated if configured, the owner and the security contacts are notified and
+// the incident is recorded in the audit log.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	peers  PeerManager
+	mails  MailService
+	mailer Mailer
+}
+
+// NewCompromiseManager creates a new compromise response manager.
+func NewCompromiseManager(
+	cfg *config.Config,
+	bus EventBus,
+	peers PeerManager,
+	mails MailService,
+	mailer Mailer,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		peers:  peers,
+		mails:  mails,
+		mailer: mailer,
+	}
+
+	return m, nil
+}
+
+// MarkCompromised disables the given peer and records the incident. The incident is returned even if some preshared
+// keys could not be rotated, the returned error contains all rotation failures in that case.
+func (m Manager) MarkCompromised(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	reason string,
+) (*domain.PeerCompromise, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	peer, err := m.peers.GetPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer %s: %w", id, err)
+	}
+	if peer.IsDisabled() && peer.DisabledReason == domain.DisabledReasonCompromised {
+		return nil, fmt.Errorf("peer %s is already marked as compromised: %w", id, domain.ErrInvalidData)
+	}
+
+	now := time.Now()
+	incident := &domain.PeerCompromise{
+		PeerIdentifier:      peer.Identifier,
+		PeerName:            peer.DisplayName,
+		InterfaceIdentifier: peer.InterfaceIdentifier,
+		UserIdentifier:      peer.UserIdentifier,
+		Reason:              strings.TrimSpace(reason),
+		ReportedBy:          domain.GetUserInfo(ctx).Id,
+		ReportedAt:          now,
+	}
+
+	peer.Disabled = &now
+	peer.DisabledReason = domain.DisabledReasonCompromised
+	if _, err := m.peers.UpdatePeer(ctx, peer); err != nil {
+		return nil, fmt.Errorf("failed to disable peer %s: %w", id, err)
+	}
+
+	var rotationErr error
+	if m.cfg.Compromise.RotatePresharedKeys && peer.UserIdentifier != "" {
+		rotationErr = m.rotatePresharedKeys(ctx, incident)
+	}
+
+	slog.Warn("peer marked as compromised", "peer", id, "user", incident.UserIdentifier,
+		"rotated", len(incident.RotatedPeers), "by", incident.ReportedBy)
+	m.bus.Publish(app.TopicAuditPeerCompromised, domain.AuditEventWrapper[audit.CompromiseEvent]{
+		Ctx:   ctx,
+		Event: audit.CompromiseEvent{Incident: *incident},
+	})
+
+	m.notifyOwner(ctx, incident)
+	m.notifySecurityContacts(ctx, incident)
+
+	return incident, rotationErr
+}
+
+// rotatePresharedKeys renews the preshared keys of all other enabled peers of the owner on the interface of the
+// compromised peer.
+func (m Manager) rotatePresharedKeys(ctx context.Context, incident *domain.PeerCompromise) error {
+	peers, err := m.peers.GetUserPeers(ctx, incident.UserIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to load peers of user %s: %w", incident.UserIdentifier, err)
+	}
+
+	var errs []error
+	for _, peer := range peers {
+		if peer.Identifier == incident.PeerIdentifier || peer.InterfaceIdentifier != incident.InterfaceIdentifier {
+			continue
+		}
+		if peer.IsDisabled() {
+			continue // disabled peers are not on the interface
+		}
+
+		psk, err := domain.NewPreSharedKey()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to generate preshared key for peer %s: %w", peer.Identifier, err))
+			continue
+		}
+		peer.PresharedKey = psk
+		if _, err := m.peers.UpdatePeer(ctx, &peer); err != nil {
+			errs = append(errs, fmt.Errorf("failed to rotate preshared key of peer %s: %w", peer.Identifier, err))
+			continue
+		}
+		incident.RotatedPeers = append(incident.RotatedPeers, peer.Identifier)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m Manager) notifyOwner(ctx context.Context, incident *domain.PeerCompromise) {
+	if incident.UserIdentifier == "" {
+		return // nobody to notify
+	}
+
+	if err := m.mails.SendPeerCompromiseNotification(ctx, incident); err != nil {
+		slog.Error("failed to notify owner of compromised peer", "peer", incident.PeerIdentifier, "error", err)
+	}
+}
+
+func (m Manager) notifySecurityContacts(ctx context.Context, incident *domain.PeerCompromise) {
+	if len(m.cfg.Compromise.SecurityContacts) == 0 {
+		return
+	}
+
+	err := m.mailer.Send(ctx, incidentSubject(incident), incidentText(incident), m.cfg.Compromise.SecurityContacts,
+		&domain.MailOptions{})
+	if err != nil {
+		slog.Error("failed to notify security contacts about compromised peer",
+			"peer", incident.PeerIdentifier,
+			"error", err)
+	}
+}
+
+// incidentSubject returns a short summary of the incident for the security contacts.
+func incidentSubject(incident *domain.PeerCompromise) string {
+	peerName := incident.PeerName
+	if peerName == "" {
+		peerName = string(incident.PeerIdentifier)
+	}
+	return fmt.Sprintf("[INCIDENT] peer %s marked as compromised", peerName)
+}
+
+// incidentText returns the plain text description of the incident for the security contacts.
+func incidentText(incident *domain.PeerCompromise) string {
+	var sb strings.Builder
+	sb.WriteString(incidentSubject(incident) + "\n\n")
+	sb.WriteString("Peer: " + string(incident.PeerIdentifier) + "\n")
+	if incident.PeerName != "" {
+		sb.WriteString("Name: " + incident.PeerName + "\n")
+	}
+	sb.WriteString("Interface: " + string(incident.InterfaceIdentifier) + "\n")
+	if incident.UserIdentifier != "" {
+		sb.WriteString("Owner: " + string(incident.UserIdentifier) + "\n")
+	}
+	if incident.Reason != "" {
+		sb.WriteString("Reason: " + incident.Reason + "\n")
+	}
+	sb.WriteString("Reported by: " + string(incident.ReportedBy) + "\n")
+	sb.WriteString("Reported at: " + incident.ReportedAt.Format(time.RFC3339) + "\n")
+	if len(incident.RotatedPeers) > 0 {
+		sb.WriteString(fmt.Sprintf("Rotated preshared keys: %d peers\n", len(incident.RotatedPeers)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,163 @@
+package compromise
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakePeerManager struct {
+	peers map[domain.PeerIdentifier]domain.Peer
+	fail  domain.PeerIdentifier
+}
+
+func (f *fakePeerManager) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &peer, nil
+}
+
+func (f *fakePeerManager) GetUserPeers(_ context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
+	var peers []domain.Peer
+	for _, peer := range f.peers {
+		if peer.UserIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+func (f *fakePeerManager) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	if peer.Identifier == f.fail {
+		return nil, errors.New("device busy")
+	}
+	f.peers[peer.Identifier] = *peer
+	return peer, nil
+}
+
+type fakeMailService struct {
+	incidents []domain.PeerCompromise
+}
+
+func (f *fakeMailService) SendPeerCompromiseNotification(_ context.Context, incident *domain.PeerCompromise) error {
+	f.incidents = append(f.incidents, *incident)
+	return nil
+}
+
+type fakeMailer struct {
+	recipients [][]string
+	bodies     []string
+}
+
+func (f *fakeMailer) Send(_ context.Context, _, body string, to []string, _ *domain.MailOptions) error {
+	f.recipients = append(f.recipients, to)
+	f.bodies = append(f.bodies, body)
+	return nil
+}
+
+type fakeBus struct {
+	published []string
+}
+
+func (f *fakeBus) Publish(topic string, _ ...any) {
+	f.published = append(f.published, topic)
+}
+
+type testManager struct {
+	*Manager
+	peers  *fakePeerManager
+	mails  *fakeMailService
+	mailer *fakeMailer
+	bus    *fakeBus
+}
+
+func newTestManager(cfg config.CompromiseConfig) testManager {
+	past := time.Now().Add(-time.Hour)
+	peers := &fakePeerManager{peers: map[domain.PeerIdentifier]domain.Peer{
+		"laptop": {Identifier: "laptop", DisplayName: "Laptop", InterfaceIdentifier: "wg0", UserIdentifier: "alice"},
+		"phone": {Identifier: "phone", InterfaceIdentifier: "wg0", UserIdentifier: "alice",
+			PresharedKey: "old"},
+		"tablet":   {Identifier: "tablet", InterfaceIdentifier: "wg1", UserIdentifier: "alice", PresharedKey: "old"},
+		"disabled": {Identifier: "disabled", InterfaceIdentifier: "wg0", UserIdentifier: "alice", Disabled: &past},
+		"other":    {Identifier: "other", InterfaceIdentifier: "wg0", UserIdentifier: "bob", PresharedKey: "old"},
+	}}
+	tm := testManager{peers: peers, mails: &fakeMailService{}, mailer: &fakeMailer{}, bus: &fakeBus{}}
+	tm.Manager, _ = NewCompromiseManager(&config.Config{Compromise: cfg}, tm.bus, peers, tm.mails, tm.mailer)
+	return tm
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+}
+
+func TestManager_MarkCompromised(t *testing.T) {
+	m := newTestManager(config.CompromiseConfig{
+		RotatePresharedKeys: true,
+		SecurityContacts:    []string{"security@example.com"},
+	})
+
+	incident, err := m.MarkCompromised(adminContext(), "laptop", " device stolen ")
+	require.NoError(t, err)
+
+	assert.Equal(t, "device stolen", incident.Reason)
+	assert.Equal(t, domain.UserIdentifier("admin"), incident.ReportedBy)
+	assert.Equal(t, domain.DisabledReasonCompromised, m.peers.peers["laptop"].DisabledReason)
+	assert.Equal(t, []domain.PeerIdentifier{"phone"}, incident.RotatedPeers,
+		"only enabled peers of the owner on the same interface are rotated")
+	assert.NotEqual(t, domain.PreSharedKey("old"), m.peers.peers["phone"].PresharedKey)
+	assert.Equal(t, domain.PreSharedKey("old"), m.peers.peers["tablet"].PresharedKey)
+	assert.Equal(t, domain.PreSharedKey("old"), m.peers.peers["other"].PresharedKey)
+
+	assert.Len(t, m.bus.published, 1)
+	require.Len(t, m.mails.incidents, 1)
+	assert.Equal(t, domain.UserIdentifier("alice"), m.mails.incidents[0].UserIdentifier)
+	require.Len(t, m.mailer.recipients, 1)
+	assert.Equal(t, []string{"security@example.com"}, m.mailer.recipients[0])
+	assert.Contains(t, m.mailer.bodies[0], "Reason: device stolen")
+
+	_, err = m.MarkCompromised(adminContext(), "laptop", "again")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
+
+func TestManager_MarkCompromised_withoutRotation(t *testing.T) {
+	m := newTestManager(config.CompromiseConfig{})
+
+	incident, err := m.MarkCompromised(adminContext(), "laptop", "")
+	require.NoError(t, err)
+	assert.Empty(t, incident.RotatedPeers)
+	assert.Equal(t, domain.PreSharedKey("old"), m.peers.peers["phone"].PresharedKey)
+	assert.Empty(t, m.mailer.recipients, "no security contacts configured")
+}
+
+func TestManager_MarkCompromised_partialFailure(t *testing.T) {
+	m := newTestManager(config.CompromiseConfig{RotatePresharedKeys: true})
+	m.peers.fail = "phone"
+
+	incident, err := m.MarkCompromised(adminContext(), "laptop", "")
+	assert.Error(t, err)
+	require.NotNil(t, incident, "the incident is recorded even if a rotation failed")
+	assert.Empty(t, incident.RotatedPeers)
+	assert.Equal(t, domain.DisabledReasonCompromised, m.peers.peers["laptop"].DisabledReason)
+	assert.Len(t, m.bus.published, 1)
+}
+
+func TestManager_MarkCompromised_validation(t *testing.T) {
+	m := newTestManager(config.CompromiseConfig{})
+
+	_, err := m.MarkCompromised(adminContext(), "unknown", "")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	_, err = m.MarkCompromised(userCtx, "laptop", "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	assert.Nil(t, m.peers.peers["laptop"].Disabled)
+}
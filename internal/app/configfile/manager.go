@@ -1,14 +1,20 @@
 package configfile
 
 import (
+	"archive/zip"
 	"bufio"
 	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/yeqown/go-qrcode/v2"
 	"github.com/yeqown/go-qrcode/writer/compressed"
@@ -18,6 +24,15 @@ import (
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
+// maxQrCodeBytes is the capacity of the largest QR code (version 40) in byte mode with low error correction.
+const maxQrCodeBytes = 2953
+
+// lookupSrv resolves the given DNS SRV name. It is a variable, so that tests do not depend on DNS.
+var lookupSrv = func(ctx context.Context, name string) ([]*net.SRV, error) {
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+	return records, err
+}
+
 // region dependencies
 
 type UserDatabaseRepo interface {
@@ -32,6 +47,8 @@ type WireguardDatabaseRepo interface {
 	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
 	// GetInterface returns the interface with the given identifier.
 	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetAllRouteSets returns all route sets.
+	GetAllRouteSets(ctx context.Context) ([]domain.RouteSet, error)
 }
 
 type FileSystemRepo interface {
@@ -43,15 +60,41 @@ type FileSystemRepo interface {
 }
 
 type TemplateRenderer interface {
-	// GetInterfaceConfig returns the configuration file for the given interface.
-	GetInterfaceConfig(iface *domain.Interface, peers []domain.Peer) (io.Reader, error)
-	// GetPeerConfig returns the configuration file for the given peer.
-	GetPeerConfig(peer *domain.Peer) (io.Reader, error)
+	// WriteInterfaceConfig writes the configuration file for the given interface to the given writer.
+	WriteInterfaceConfig(w io.Writer, iface *domain.Interface, peers []domain.Peer) error
+	// WritePeerConfig writes the configuration file for the given peer to the given writer, the knock credentials and
+	// the backup endpoints are optional.
+	WritePeerConfig(
+		w io.Writer,
+		peer *domain.Peer,
+		knock *domain.KnockCredentials,
+		backupEndpoints []string,
+	) error
+	// GetPeerUciConfig returns the OpenWrt configuration for the given peer.
+	GetPeerUciConfig(peer *domain.Peer, style domain.UciConfigStyle) (io.Reader, error)
+}
+
+type AcceptableUseValidator interface {
+	// ValidateAcceptance checks that the current user accepted the acceptable use policy.
+	ValidateAcceptance(ctx context.Context) error
+}
+
+type KeyRevealValidator interface {
+	// ValidateReveal checks that the current user is allowed to see the private key of the peer and records the
+	// reveal.
+	ValidateReveal(ctx context.Context, peer *domain.Peer, format string) error
+	// CheckReveals checks that the current user is allowed to see the private keys of all given peers, without
+	// recording any reveal.
+	CheckReveals(ctx context.Context, peers []domain.Peer) error
+	// RecordReveal records the reveal of the private key of a peer that was checked with CheckReveals.
+	RecordReveal(ctx context.Context, peer *domain.Peer, format string)
 }
 
 type EventBus interface {
 	// Subscribe subscribes to the given topic.
 	Subscribe(topic string, fn any) error
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
 }
 
 // endregion dependencies
@@ -62,9 +105,12 @@ type Manager struct {
 	bus EventBus
 
 	tplHandler TemplateRenderer
+	signer     *configSigner // nil if config signing is disabled
 	fsRepo     FileSystemRepo
 	users      UserDatabaseRepo
 	wg         WireguardDatabaseRepo
+	aup        AcceptableUseValidator
+	reveal     KeyRevealValidator
 }
 
 // NewConfigFileManager creates a new Manager instance.
@@ -74,20 +120,33 @@ func NewConfigFileManager(
 	users UserDatabaseRepo,
 	wg WireguardDatabaseRepo,
 	fsRepo FileSystemRepo,
+	aup AcceptableUseValidator,
+	reveal KeyRevealValidator,
 ) (*Manager, error) {
 	tplHandler, err := newTemplateHandler()
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize template handler: %w", err)
 	}
 
+	var signer *configSigner
+	if cfg.ConfigSigning.Enabled {
+		signer, err = newConfigSigner(cfg.ConfigSigning.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize config signer: %w", err)
+		}
+	}
+
 	m := &Manager{
 		cfg:        cfg,
 		bus:        bus,
 		tplHandler: tplHandler,
+		signer:     signer,
 
 		fsRepo: fsRepo,
 		users:  users,
 		wg:     wg,
+		aup:    aup,
+		reveal: reveal,
 	}
 
 	if m.cfg.Advanced.ConfigStoragePath != "" {
@@ -180,38 +239,301 @@ func (m Manager) GetInterfaceConfig(ctx context.Context, id domain.InterfaceIden
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch interface %s: %w", id, err)
 	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
 
-	return m.tplHandler.GetInterfaceConfig(iface, peers)
+	buf := bytes.NewBuffer(nil)
+	err = m.writeSigned(buf, func(w io.Writer) error {
+		return m.tplHandler.WriteInterfaceConfig(w, iface, peers)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// ExportInterfacePeerConfigs prepares the export of the configuration files of all peers of the given interface as
+// zip archive. The files are rendered one after another while the archive is written, so the archive is never held
+// in memory.
+func (m Manager) ExportInterfacePeerConfigs(ctx context.Context, id domain.InterfaceIdentifier) (
+	*domain.Export,
+	error,
+) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	iface, peers, err := m.wg.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch interface %s: %w", id, err)
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
+	if err := m.aup.ValidateAcceptance(ctx); err != nil {
+		return nil, err
+	}
+	// a denied reveal must fail the request, not result in a partial archive. The reveals are only recorded once
+	// the configuration files are written.
+	if err := m.reveal.CheckReveals(ctx, peers); err != nil {
+		return nil, err
+	}
+
+	return &domain.Export{
+		Filename:    fmt.Sprintf("%s-peers.zip", id),
+		ContentType: "application/zip",
+		Write: func(w io.Writer) error {
+			return m.writePeerConfigArchive(ctx, w, iface, peers)
+		},
+	}, nil
+}
+
+// writePeerConfigArchive writes the configuration files of the given peers as zip archive to the given writer.
+// The archive is flushed after each file, the reveal of a peer is recorded once its file was passed to the writer.
+func (m Manager) writePeerConfigArchive(
+	ctx context.Context,
+	w io.Writer,
+	iface *domain.Interface,
+	peers []domain.Peer,
+) error {
+	backupEndpoints := m.resolveBackupEndpoints(ctx, iface) // resolved once, not once per peer
+	fileNames := make(map[string]int, len(peers))
+
+	archive := zip.NewWriter(w)
+	for i := range peers {
+		peer := &peers[i]
+
+		endpoints := []string{peer.Endpoint.GetValue()}
+		if peer.Interface.Type != domain.InterfaceTypeServer {
+			endpoints = withBackupEndpoints(peer.Endpoint.GetValue(), backupEndpoints)
+		}
+		m.applyRouteSets(ctx, peer)
+		m.applyDnsResolver(ctx, peer)
+
+		file, err := archive.Create(uniqueFileName(fileNames, peer.GetConfigFileName()))
+		if err != nil {
+			return fmt.Errorf("failed to add config of peer %s to archive: %w", peer.Identifier, err)
+		}
+		err = m.writeSigned(file, func(w io.Writer) error {
+			return m.tplHandler.WritePeerConfig(w, peer, m.knockCredentials(peer), endpoints[1:])
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write config of peer %s to archive: %w", peer.Identifier, err)
+		}
+		if err := archive.Flush(); err != nil {
+			return fmt.Errorf("failed to write config of peer %s to archive: %w", peer.Identifier, err)
+		}
+
+		m.reveal.RecordReveal(ctx, peer, domain.PeerConfigFormatFile)
+		m.publishDownload(ctx, peer, domain.PeerConfigFormatFile)
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	return nil
+}
+
+// uniqueFileName returns the given file name, or, if the name was already used, the name with a numeric suffix.
+func uniqueFileName(used map[string]int, name string) string {
+	used[name]++
+	if used[name] == 1 {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), used[name], ext)
 }
 
 // GetPeerConfig returns the configuration file for the given peer.
 // The file is structured in wg-quick format.
 func (m Manager) GetPeerConfig(ctx context.Context, id domain.PeerIdentifier) (io.Reader, error) {
+	return m.GetPeerConfigForEndpoint(ctx, id, 0)
+}
+
+// GetPeerEndpoints returns the primary endpoint of the given peer, followed by the backup endpoints of its interface.
+// The index of an endpoint selects the configuration variant in GetPeerConfigForEndpoint.
+func (m Manager) GetPeerEndpoints(ctx context.Context, id domain.PeerIdentifier) ([]string, error) {
+	peer, err := m.wg.GetPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch peer %s: %w", id, err)
+	}
+
+	if err := m.validatePeerAccess(ctx, peer); err != nil {
+		return nil, err
+	}
+
+	return m.peerEndpoints(ctx, peer), nil
+}
+
+// GetPeerConfigForEndpoint returns the configuration file for the given peer that connects to the endpoint with the
+// given index, see GetPeerEndpoints. All other endpoints are listed as backup endpoints in the file.
+func (m Manager) GetPeerConfigForEndpoint(ctx context.Context, id domain.PeerIdentifier, endpoint int) (
+	io.Reader,
+	error,
+) {
+	peer, err := m.wg.GetPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch peer %s: %w", id, err)
+	}
+
+	if err := m.validatePeerAccess(ctx, peer); err != nil {
+		return nil, err
+	}
+	if err := m.aup.ValidateAcceptance(ctx); err != nil {
+		return nil, err
+	}
+
+	endpoints := m.peerEndpoints(ctx, peer)
+	if endpoint < 0 || endpoint >= len(endpoints) {
+		return nil, fmt.Errorf("peer %s has no endpoint %d: %w", id, endpoint, domain.ErrInvalidData)
+	}
+	if err := m.reveal.ValidateReveal(ctx, peer, domain.PeerConfigFormatFile); err != nil {
+		return nil, err
+	}
+	peer.Endpoint.Value = endpoints[endpoint]
+	backupEndpoints := slices.Delete(endpoints, endpoint, endpoint+1)
+
+	m.applyRouteSets(ctx, peer)
+	m.applyDnsResolver(ctx, peer)
+
+	buf := bytes.NewBuffer(nil)
+	err = m.writeSigned(buf, func(w io.Writer) error {
+		return m.tplHandler.WritePeerConfig(w, peer, m.knockCredentials(peer), backupEndpoints)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.publishDownload(ctx, peer, domain.PeerConfigFormatFile)
+
+	return buf, nil
+}
+
+// GetPeerUciConfig returns the OpenWrt configuration for the given peer, either as uci commands or as snippet
+// for the /etc/config/network file. Interface hooks and kill-switch rules are not supported by OpenWrt.
+func (m Manager) GetPeerUciConfig(ctx context.Context, id domain.PeerIdentifier, style domain.UciConfigStyle) (
+	io.Reader,
+	error,
+) {
+	if !style.IsValid() {
+		return nil, fmt.Errorf("unsupported uci config style %q: %w", style, domain.ErrInvalidData)
+	}
+
 	peer, err := m.wg.GetPeer(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch peer %s: %w", id, err)
 	}
 
-	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+	if err := m.validatePeerAccess(ctx, peer); err != nil {
+		return nil, err
+	}
+	if err := m.aup.ValidateAcceptance(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.reveal.ValidateReveal(ctx, peer, domain.PeerConfigFormatUci); err != nil {
+		return nil, err
+	}
+
+	m.applyRouteSets(ctx, peer)
+	m.applyDnsResolver(ctx, peer)
+
+	cfg, err := m.tplHandler.GetPeerUciConfig(peer, style)
+	if err != nil {
 		return nil, err
 	}
 
-	return m.tplHandler.GetPeerConfig(peer)
+	m.publishDownload(ctx, peer, domain.PeerConfigFormatUci)
+
+	return cfg, nil
+}
+
+// publishDownload informs about a download of the peer configuration. Only requests of the web interface carry
+// the client information, internal usages like configuration mails are not reported.
+func (m Manager) publishDownload(ctx context.Context, peer *domain.Peer, format string) {
+	client := domain.GetClientInfo(ctx)
+	if client == nil {
+		return
+	}
+
+	m.bus.Publish(app.TopicPeerConfigDownloaded, domain.PeerConfigDownload{
+		Peer:         *peer,
+		DownloadedBy: domain.GetUserInfo(ctx).Id,
+		Format:       format,
+		Client:       *client,
+		DownloadedAt: time.Now(),
+	})
+}
+
+// GetSigningPublicKey returns the PEM encoded public key that verifies the signatures of the configuration files.
+func (m Manager) GetSigningPublicKey(_ context.Context) (string, error) {
+	if m.signer == nil {
+		return "", fmt.Errorf("config signing is disabled: %w", domain.ErrNotFound)
+	}
+
+	return m.signer.PublicKeyPem(), nil
+}
+
+// writeSigned writes the configuration file that is rendered by the given function to the given writer. If config
+// signing is enabled, the file is rendered to memory first, as the signature covers the whole file.
+func (m Manager) writeSigned(w io.Writer, render func(w io.Writer) error) error {
+	if m.signer == nil {
+		return render(w)
+	}
+
+	var buf bytes.Buffer
+	if err := render(&buf); err != nil {
+		return err
+	}
+	if _, err := w.Write(m.signer.Sign(buf.Bytes())); err != nil {
+		return fmt.Errorf("failed to write signed config: %w", err)
+	}
+
+	return nil
 }
 
 // GetPeerConfigQrCode returns a QR code image containing the configuration for the given peer.
 func (m Manager) GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier) (io.Reader, error) {
+	export, err := m.ExportPeerConfigQrCode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := export.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// ExportPeerConfigQrCode prepares the export of a QR code image containing the configuration for the given peer.
+// All checks are done while preparing the export, the image is encoded directly into the writer.
+func (m Manager) ExportPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier) (*domain.Export, error) {
 	peer, err := m.wg.GetPeer(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch peer %s: %w", id, err)
 	}
 
-	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+	if err := m.validatePeerAccess(ctx, peer); err != nil {
+		return nil, err
+	}
+	if err := m.aup.ValidateAcceptance(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.reveal.ValidateReveal(ctx, peer, domain.PeerConfigFormatQrCode); err != nil {
 		return nil, err
 	}
 
-	cfgData, err := m.tplHandler.GetPeerConfig(peer)
-	if err != nil {
+	m.applyRouteSets(ctx, peer)
+	m.applyDnsResolver(ctx, peer)
+
+	cfgData := bytes.NewBuffer(nil)
+	// comments are removed from QR codes anyway
+	if err := m.tplHandler.WritePeerConfig(cfgData, peer, nil, nil); err != nil {
 		return nil, fmt.Errorf("failed to get peer config for %s: %w", id, err)
 	}
 
@@ -229,25 +551,200 @@ func (m Manager) GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifi
 		return nil, fmt.Errorf("failed to read peer config for %s: %w", id, err)
 	}
 
-	code, err := qrcode.NewWith(sb.String(),
+	if sb.Len() > maxQrCodeBytes {
+		return nil, fmt.Errorf("config of peer %s has %d bytes, at most %d bytes fit into a QR code: %w",
+			id, sb.Len(), maxQrCodeBytes, domain.ErrConfigTooLarge)
+	}
+
+	return &domain.Export{
+		Filename:    peer.GetConfigFileName() + ".png",
+		ContentType: "image/png",
+		Write: func(w io.Writer) error {
+			if err := encodeQrCode(w, sb.String()); err != nil {
+				return fmt.Errorf("failed to create qr code for %s: %w", id, err)
+			}
+
+			m.publishDownload(ctx, peer, domain.PeerConfigFormatQrCode)
+
+			return nil
+		},
+	}, nil
+}
+
+// GetProvisioningQrCode returns a QR code image containing the provisioning URI for the config pull API. If config
+// signing is enabled, the public signing key is inlined, so the device can verify the pulled configurations.
+func (m Manager) GetProvisioningQrCode(_ context.Context, provisioning domain.ConfigPullProvisioning) (
+	io.Reader,
+	error,
+) {
+	if m.signer != nil {
+		provisioning.SigningKey = m.signer.PublicKeyBase64()
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := encodeQrCode(buf, provisioning.Uri()); err != nil {
+		return nil, fmt.Errorf("failed to create provisioning qr code: %w", err)
+	}
+
+	return buf, nil
+}
+
+// encodeQrCode writes the PNG image of a QR code that contains the given data to the given writer.
+func encodeQrCode(w io.Writer, data string) error {
+	code, err := qrcode.NewWith(data,
 		qrcode.WithErrorCorrectionLevel(qrcode.ErrorCorrectionLow), qrcode.WithEncodingMode(qrcode.EncModeByte))
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize qr code for %s: %w", id, err)
+		return fmt.Errorf("failed to initialize qr code: %w", err)
 	}
 
-	buf := bytes.NewBuffer(nil)
-	wr := nopCloser{Writer: buf}
+	wr := nopCloser{Writer: w}
 	option := compressed.Option{
 		Padding:   8, // padding pixels around the qr code.
 		BlockSize: 4, // block pixels which represents a bit data.
 	}
 	qrWriter := compressed.NewWithWriter(wr, &option)
-	err = code.Save(qrWriter)
+	if err := code.Save(qrWriter); err != nil {
+		return fmt.Errorf("failed to write qr code: %w", err)
+	}
+
+	return nil
+}
+
+// validatePeerAccess checks if the current user is allowed to access the configuration of the given peer.
+// Users can always access their own peers, admins only the peers of interfaces of their organization.
+func (m Manager) validatePeerAccess(ctx context.Context, peer *domain.Peer) error {
+	iface, err := m.wg.GetInterface(ctx, peer.InterfaceIdentifier)
 	if err != nil {
-		return nil, fmt.Errorf("failed to write code for %s: %w", id, err)
+		return fmt.Errorf("failed to fetch interface %s: %w", peer.InterfaceIdentifier, err)
 	}
 
-	return buf, nil
+	if err := domain.ValidatePeerAccessRights(ctx, peer, iface); err != nil {
+		return err
+	}
+	if domain.GetUserInfo(ctx).Id == peer.UserIdentifier {
+		return nil
+	}
+
+	return domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier)
+}
+
+// applyRouteSets adds the networks of all route sets that are attached to the given peer to its AllowedIPs.
+func (m Manager) applyRouteSets(ctx context.Context, peer *domain.Peer) {
+	if peer.Interface.Type == domain.InterfaceTypeServer || len(peer.RouteSetIds()) == 0 {
+		return
+	}
+
+	routeSets, err := m.wg.GetAllRouteSets(ctx)
+	if err != nil {
+		slog.Warn("failed to load route sets for peer config", "peer", peer.Identifier, "error", err)
+		return
+	}
+
+	peer.AllowedIPsStr.Value = peer.ExpandAllowedIPs(routeSets)
+}
+
+// peerEndpoints returns the endpoint of the given peer, followed by the backup endpoints of its interface.
+func (m Manager) peerEndpoints(ctx context.Context, peer *domain.Peer) []string {
+	if peer.Interface.Type == domain.InterfaceTypeServer {
+		return []string{peer.Endpoint.GetValue()}
+	}
+
+	iface, err := m.wg.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		slog.Warn("failed to load interface for backup endpoints",
+			"interface", peer.InterfaceIdentifier, "peer", peer.Identifier, "error", err)
+		return []string{peer.Endpoint.GetValue()}
+	}
+
+	return withBackupEndpoints(peer.Endpoint.GetValue(), m.resolveBackupEndpoints(ctx, iface))
+}
+
+// resolveBackupEndpoints returns the backup endpoints of the given interface. DNS SRV entries are resolved to their
+// targets, in the order of their priority and weight.
+func (m Manager) resolveBackupEndpoints(ctx context.Context, iface *domain.Interface) []string {
+	var endpoints []string
+	for _, endpoint := range iface.PeerDefBackupEndpoints() {
+		srvName, isSrv := strings.CutPrefix(endpoint, domain.EndpointSrvPrefix)
+		if !isSrv {
+			endpoints = append(endpoints, endpoint)
+			continue
+		}
+
+		records, err := lookupSrv(ctx, srvName)
+		if err != nil {
+			slog.Warn("failed to resolve backup endpoint", "interface", iface.Identifier, "srv", srvName,
+				"error", err)
+			continue
+		}
+		for _, record := range records {
+			endpoints = append(endpoints,
+				net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port))))
+		}
+	}
+
+	return endpoints
+}
+
+// withBackupEndpoints returns the given endpoint, followed by the backup endpoints. Duplicates are removed.
+func withBackupEndpoints(endpoint string, backupEndpoints []string) []string {
+	endpoints := []string{endpoint}
+	for _, backupEndpoint := range backupEndpoints {
+		if !slices.Contains(endpoints, backupEndpoint) {
+			endpoints = append(endpoints, backupEndpoint)
+		}
+	}
+
+	return endpoints
+}
+
+// knockCredentials returns the port knocking credentials of the given peer, or nil if port knocking is disabled.
+// The knock packets are sent to the endpoint host of the peer.
+func (m Manager) knockCredentials(peer *domain.Peer) *domain.KnockCredentials {
+	if !m.cfg.PortKnocking.Enabled {
+		return nil
+	}
+
+	credentials := domain.DeriveKnockCredentials(m.cfg.PortKnocking.Secret, peer.Interface.PublicKey)
+	host, _ := domain.SplitEndpoint(peer.Endpoint.GetValue())
+	if _, port, err := net.SplitHostPort(m.cfg.PortKnocking.ListeningAddress); err == nil && host != "" {
+		credentials.Address = net.JoinHostPort(host, port)
+	}
+
+	return &credentials
+}
+
+// applyDnsResolver advertises the built-in DNS resolver in the configuration of the given peer.
+// The resolver listens on the addresses of the peer's interface. Non-overridable DNS settings of the peer are kept.
+func (m Manager) applyDnsResolver(ctx context.Context, peer *domain.Peer) {
+	if !m.cfg.DnsResolver.Enabled || !m.cfg.DnsResolver.AdvertiseDns || peer.Interface.Type == domain.InterfaceTypeServer {
+		return
+	}
+
+	iface, err := m.wg.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		slog.Warn("failed to load interface for DNS resolver settings",
+			"interface", peer.InterfaceIdentifier, "peer", peer.Identifier, "error", err)
+		return
+	}
+	if iface.Type == domain.InterfaceTypeClient || len(iface.Addresses) == 0 {
+		return
+	}
+
+	resolverAddresses := make([]string, 0, len(iface.Addresses))
+	for _, cidr := range iface.Addresses {
+		resolverAddresses = append(resolverAddresses, cidr.Addr)
+	}
+	peer.Interface.DnsStr.TrySetValue(strings.Join(resolverAddresses, ","))
+
+	zone := strings.Trim(m.cfg.DnsResolver.Zone, ".")
+	searchDomains := []string{zone}
+	for _, searchDomain := range strings.Split(peer.Interface.DnsSearchStr.GetValue(), ",") {
+		searchDomain = strings.TrimSpace(searchDomain)
+		if searchDomain != "" && searchDomain != zone {
+			searchDomains = append(searchDomains, searchDomain)
+		}
+	}
+	peer.Interface.DnsSearchStr.TrySetValue(strings.Join(searchDomains, ","))
 }
 
 // PersistInterfaceConfig writes the configuration file for the given interface to the file system.
@@ -257,12 +754,17 @@ func (m Manager) PersistInterfaceConfig(ctx context.Context, id domain.Interface
 		return fmt.Errorf("failed to fetch interface %s: %w", id, err)
 	}
 
-	cfg, err := m.tplHandler.GetInterfaceConfig(iface, peers)
-	if err != nil {
-		return fmt.Errorf("failed to get interface config: %w", err)
-	}
+	// the config is rendered directly into the file
+	cfg, cfgWriter := io.Pipe()
+	go func() {
+		_ = cfgWriter.CloseWithError(m.writeSigned(cfgWriter, func(w io.Writer) error {
+			return m.tplHandler.WriteInterfaceConfig(w, iface, peers)
+		}))
+	}()
 
-	if err := m.fsRepo.WriteFile(iface.GetConfigFileName(), cfg); err != nil {
+	err = m.fsRepo.WriteFile(iface.GetConfigFileName(), cfg)
+	_ = cfg.CloseWithError(err) // stops the renderer if the file could not be written
+	if err != nil {
 		return fmt.Errorf("failed to write interface config: %w", err)
 	}
 
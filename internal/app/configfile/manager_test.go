@@ -0,0 +1,255 @@
+package configfile
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// endpointDatabase implements the lookups required for the endpoint variants, all other methods are not implemented.
+type endpointDatabase struct {
+	WireguardDatabaseRepo
+
+	iface domain.Interface
+	peer  domain.Peer
+	peers []domain.Peer
+}
+
+func (f endpointDatabase) GetInterfaceAndPeers(_ context.Context, _ domain.InterfaceIdentifier) (
+	*domain.Interface,
+	[]domain.Peer,
+	error,
+) {
+	iface := f.iface
+	return &iface, slices.Clone(f.peers), nil
+}
+
+func (f endpointDatabase) GetInterface(_ context.Context, _ domain.InterfaceIdentifier) (*domain.Interface, error) {
+	iface := f.iface
+	return &iface, nil
+}
+
+func (f endpointDatabase) GetPeer(_ context.Context, _ domain.PeerIdentifier) (*domain.Peer, error) {
+	peer := f.peer
+	return &peer, nil
+}
+
+type acceptedPolicy struct{}
+
+func (acceptedPolicy) ValidateAcceptance(_ context.Context) error {
+	return nil
+}
+
+type revealGate struct {
+	err error
+}
+
+func (g revealGate) ValidateReveal(_ context.Context, _ *domain.Peer, _ string) error {
+	return g.err
+}
+
+func (g revealGate) CheckReveals(_ context.Context, _ []domain.Peer) error {
+	return g.err
+}
+
+func (g revealGate) RecordReveal(_ context.Context, _ *domain.Peer, _ string) {}
+
+// revealRecorder denies the reveal of the denied peer and records all other reveals.
+type revealRecorder struct {
+	denied   domain.PeerIdentifier
+	recorded []domain.PeerIdentifier
+}
+
+func (r *revealRecorder) ValidateReveal(_ context.Context, _ *domain.Peer, _ string) error {
+	return nil
+}
+
+func (r *revealRecorder) CheckReveals(_ context.Context, peers []domain.Peer) error {
+	for _, peer := range peers {
+		if peer.Identifier == r.denied {
+			return domain.ErrApprovalRequired
+		}
+	}
+	return nil
+}
+
+func (r *revealRecorder) RecordReveal(_ context.Context, peer *domain.Peer, _ string) {
+	r.recorded = append(r.recorded, peer.Identifier)
+}
+
+// limitedWriter fails once more than the given number of bytes were written.
+type limitedWriter struct {
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		return 0, io.ErrShortWrite
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestManager_GetPeerConfigForEndpoint(t *testing.T) {
+	originalLookup := lookupSrv
+	t.Cleanup(func() { lookupSrv = originalLookup })
+	lookupSrv = func(_ context.Context, name string) ([]*net.SRV, error) {
+		assert.Equal(t, "_wireguard._udp.example.com", name)
+		return []*net.SRV{
+			{Target: "vpn3.example.com.", Port: 51821},
+			{Target: "vpn1.example.com.", Port: 51820}, // duplicate of the primary endpoint
+		}, nil
+	}
+
+	tplHandler, err := newTemplateHandler()
+	require.NoError(t, err)
+	m := Manager{
+		cfg:        &config.Config{},
+		tplHandler: tplHandler,
+		aup:        acceptedPolicy{},
+		reveal:     revealGate{},
+		wg: endpointDatabase{
+			iface: domain.Interface{
+				Identifier:                "wg0",
+				PeerDefBackupEndpointsStr: "vpn2.example.com:51820,srv:_wireguard._udp.example.com",
+			},
+			peer: domain.Peer{
+				Identifier:          "peer-a",
+				InterfaceIdentifier: "wg0",
+				Endpoint:            domain.NewConfigOption("vpn1.example.com:51820", true),
+				Interface:           domain.PeerInterfaceConfig{Type: domain.InterfaceTypeClient},
+			},
+		},
+	}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	endpoints, err := m.GetPeerEndpoints(ctx, "peer-a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vpn1.example.com:51820", "vpn2.example.com:51820", "vpn3.example.com:51821"}, endpoints)
+
+	cfg, err := m.GetPeerConfigForEndpoint(ctx, "peer-a", 2)
+	require.NoError(t, err)
+	data, err := io.ReadAll(cfg)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Endpoint = vpn3.example.com:51821\n"+
+		"# -WGP- Backup endpoint: vpn1.example.com:51820\n"+
+		"# -WGP- Backup endpoint: vpn2.example.com:51820\n")
+
+	_, err = m.GetPeerConfigForEndpoint(ctx, "peer-a", 3)
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	m.reveal = revealGate{err: domain.ErrStepUpRequired}
+	_, err = m.GetPeerConfigForEndpoint(ctx, "peer-a", 0)
+	assert.ErrorIs(t, err, domain.ErrStepUpRequired)
+}
+
+func TestManager_ExportInterfacePeerConfigs(t *testing.T) {
+	originalLookup := lookupSrv
+	t.Cleanup(func() { lookupSrv = originalLookup })
+	lookups := 0
+	lookupSrv = func(_ context.Context, _ string) ([]*net.SRV, error) {
+		lookups++
+		return []*net.SRV{{Target: "vpn3.example.com.", Port: 51821}}, nil
+	}
+
+	tplHandler, err := newTemplateHandler()
+	require.NoError(t, err)
+	m := Manager{
+		cfg:        &config.Config{},
+		tplHandler: tplHandler,
+		aup:        acceptedPolicy{},
+		reveal:     revealGate{},
+		wg: endpointDatabase{
+			iface: domain.Interface{
+				Identifier:                "wg0",
+				PeerDefBackupEndpointsStr: "srv:_wireguard._udp.example.com",
+			},
+			peers: []domain.Peer{
+				{Identifier: "peer-a", DisplayName: "Laptop", InterfaceIdentifier: "wg0",
+					Endpoint:  domain.NewConfigOption("vpn1.example.com:51820", true),
+					Interface: domain.PeerInterfaceConfig{Type: domain.InterfaceTypeClient}},
+				{Identifier: "peer-b", DisplayName: "Laptop", InterfaceIdentifier: "wg0",
+					Endpoint:  domain.NewConfigOption("vpn1.example.com:51820", true),
+					Interface: domain.PeerInterfaceConfig{Type: domain.InterfaceTypeClient}},
+			},
+		},
+	}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	export, err := m.ExportInterfacePeerConfigs(ctx, "wg0")
+	require.NoError(t, err)
+	assert.Equal(t, "application/zip", export.ContentType)
+
+	var buf bytes.Buffer
+	require.NoError(t, export.Write(&buf))
+	assert.Equal(t, 1, lookups, "backup endpoints are resolved once per archive")
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, archive.File, 2)
+	assert.Equal(t, "Laptop.conf", archive.File[0].Name)
+	assert.Equal(t, "Laptop_2.conf", archive.File[1].Name)
+
+	file, err := archive.File[1].Open()
+	require.NoError(t, err)
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Endpoint = vpn1.example.com:51820\n"+
+		"# -WGP- Backup endpoint: vpn3.example.com:51821\n")
+
+	m.reveal = revealGate{err: domain.ErrStepUpRequired}
+	_, err = m.ExportInterfacePeerConfigs(ctx, "wg0")
+	assert.ErrorIs(t, err, domain.ErrStepUpRequired, "a denied reveal fails before the archive is written")
+}
+
+func TestManager_ExportInterfacePeerConfigs_partialReveal(t *testing.T) {
+	tplHandler, err := newTemplateHandler()
+	require.NoError(t, err)
+	reveal := &revealRecorder{denied: "peer-b"}
+	m := Manager{
+		cfg:        &config.Config{},
+		tplHandler: tplHandler,
+		aup:        acceptedPolicy{},
+		reveal:     reveal,
+		wg: endpointDatabase{
+			iface: domain.Interface{Identifier: "wg0"},
+			peers: []domain.Peer{
+				{Identifier: "peer-a", DisplayName: "Laptop", InterfaceIdentifier: "wg0"},
+				{Identifier: "peer-b", DisplayName: "Phone", InterfaceIdentifier: "wg0"},
+				{Identifier: "peer-c", DisplayName: "Tablet", InterfaceIdentifier: "wg0"},
+			},
+		},
+	}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	_, err = m.ExportInterfacePeerConfigs(ctx, "wg0")
+	assert.ErrorIs(t, err, domain.ErrApprovalRequired)
+	assert.Empty(t, reveal.recorded, "no reveal is recorded if the approval fails for a later peer")
+
+	reveal.denied = ""
+	export, err := m.ExportInterfacePeerConfigs(ctx, "wg0")
+	require.NoError(t, err)
+	assert.Empty(t, reveal.recorded, "reveals are recorded while the archive is written")
+
+	var buf bytes.Buffer
+	require.NoError(t, export.Write(&buf))
+	assert.Equal(t, []domain.PeerIdentifier{"peer-a", "peer-b", "peer-c"}, reveal.recorded)
+
+	// the stream breaks after the first file
+	reveal.recorded = nil
+	firstFile := bytes.Index(buf.Bytes()[4:], []byte("PK\x03\x04")) + 4
+	export, err = m.ExportInterfacePeerConfigs(ctx, "wg0")
+	require.NoError(t, err)
+	assert.ErrorIs(t, export.Write(&limitedWriter{limit: firstFile}), io.ErrShortWrite)
+	assert.Equal(t, []domain.PeerIdentifier{"peer-a"}, reveal.recorded, "only written configs are recorded")
+}
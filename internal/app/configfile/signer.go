@@ -0,0 +1,101 @@
+package configfile
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// configSigner signs the generated configuration files with the Ed25519 key of the portal.
+type configSigner struct {
+	key ed25519.PrivateKey
+}
+
+// newConfigSigner loads the signing key from the given file. If the file does not exist, a new key is generated
+// and stored in the file.
+func newConfigSigner(keyFile string) (*configSigner, error) {
+	if keyFile == "" {
+		return nil, errors.New("missing signing key file")
+	}
+
+	data, err := os.ReadFile(keyFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return generateConfigSigner(keyFile)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read signing key %s: %w", keyFile, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("signing key %s is not a PEM encoded private key", keyFile)
+	}
+	parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", keyFile, err)
+	}
+	key, ok := parsedKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s is not an Ed25519 key", keyFile)
+	}
+
+	return &configSigner{key: key}, nil
+}
+
+func generateConfigSigner(keyFile string) (*configSigner, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signing key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(keyFile), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory for signing key %s: %w", keyFile, err)
+	}
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(keyFile, keyPem, 0600); err != nil {
+		return nil, fmt.Errorf("failed to store signing key %s: %w", keyFile, err)
+	}
+
+	signer := &configSigner{key: key}
+	slog.Info("generated new config signing key", "file", keyFile, "fingerprint", signer.Fingerprint())
+
+	return signer, nil
+}
+
+// Sign appends the signature comment to the given configuration file.
+func (s *configSigner) Sign(data []byte) []byte {
+	return domain.SignConfig(s.key, data)
+}
+
+// PublicKeyPem returns the PEM encoded public key that verifies the signatures.
+func (s *configSigner) PublicKeyPem() string {
+	der, _ := x509.MarshalPKIXPublicKey(s.key.Public()) // cannot fail for Ed25519 keys
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// PublicKeyBase64 returns the base64 encoded raw public key, as inlined in provisioning QR codes.
+func (s *configSigner) PublicKeyBase64() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// Fingerprint returns the hex encoded SHA-256 hash of the public key.
+func (s *configSigner) Fingerprint() string {
+	sum := sha256.Sum256(s.key.Public().(ed25519.PublicKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,41 @@
+package configfile
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestNewConfigSigner(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "keys", "signing.pem")
+
+	// a new key is generated on first use
+	signer, err := newConfigSigner(keyFile)
+	require.NoError(t, err)
+	assert.FileExists(t, keyFile)
+
+	// the stored key is loaded afterward
+	loaded, err := newConfigSigner(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, signer.Fingerprint(), loaded.Fingerprint())
+
+	block, _ := pem.Decode([]byte(loaded.PublicKeyPem()))
+	require.NotNil(t, block)
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	require.NoError(t, err)
+
+	rawKey, err := base64.StdEncoding.DecodeString(loaded.PublicKeyBase64())
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, ed25519.PublicKey(rawKey))
+
+	signed := signer.Sign([]byte("[Interface]\nPrivateKey = abc\n"))
+	assert.NoError(t, domain.VerifyConfig(publicKey.(ed25519.PublicKey), signed))
+}
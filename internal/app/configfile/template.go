@@ -7,6 +7,7 @@ import (
 	"io"
 	"text/template"
 
+	"github.com/h44z/wg-portal/internal"
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
@@ -21,7 +22,10 @@ type TemplateHandler struct {
 
 func newTemplateHandler() (*TemplateHandler, error) {
 	tplFuncs := template.FuncMap{
-		"CidrsToString": domain.CidrsToString,
+		"CidrsToString":  domain.CidrsToString,
+		"AnnotationTime": domain.FormatAnnotationTime,
+		"SliceString":    internal.SliceString,
+		"UciQuote":       domain.UciQuote,
 	}
 
 	templateCache, err := template.New("WireGuard").Funcs(tplFuncs).ParseFS(TemplateFiles, "tpl_files/*.tpl")
@@ -39,8 +43,16 @@ func newTemplateHandler() (*TemplateHandler, error) {
 // GetInterfaceConfig returns the rendered configuration file for a WireGuard interface.
 func (c TemplateHandler) GetInterfaceConfig(cfg *domain.Interface, peers []domain.Peer) (io.Reader, error) {
 	var tplBuff bytes.Buffer
+	if err := c.WriteInterfaceConfig(&tplBuff, cfg, peers); err != nil {
+		return nil, err
+	}
+
+	return &tplBuff, nil
+}
 
-	err := c.templates.ExecuteTemplate(&tplBuff, "wg_interface.tpl", map[string]any{
+// WriteInterfaceConfig renders the configuration file for a WireGuard interface to the given writer.
+func (c TemplateHandler) WriteInterfaceConfig(w io.Writer, cfg *domain.Interface, peers []domain.Peer) error {
+	err := c.templates.ExecuteTemplate(w, "wg_interface.tpl", map[string]any{
 		"Interface": cfg,
 		"Peers":     peers,
 		"Portal": map[string]any{
@@ -48,24 +60,72 @@ func (c TemplateHandler) GetInterfaceConfig(cfg *domain.Interface, peers []domai
 		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute interface template for %s: %w", cfg.Identifier, err)
+		return fmt.Errorf("failed to execute interface template for %s: %w", cfg.Identifier, err)
 	}
 
-	return &tplBuff, nil
+	return nil
 }
 
 // GetPeerConfig returns the rendered configuration file for a WireGuard peer.
-func (c TemplateHandler) GetPeerConfig(peer *domain.Peer) (io.Reader, error) {
+// The knock credentials are optional, they are only rendered if port knocking is enabled.
+// The backup endpoints are rendered as comments, WireGuard only supports a single endpoint.
+func (c TemplateHandler) GetPeerConfig(
+	peer *domain.Peer,
+	knock *domain.KnockCredentials,
+	backupEndpoints []string,
+) (io.Reader, error) {
+	var tplBuff bytes.Buffer
+	if err := c.WritePeerConfig(&tplBuff, peer, knock, backupEndpoints); err != nil {
+		return nil, err
+	}
+
+	return &tplBuff, nil
+}
+
+// WritePeerConfig renders the configuration file for a WireGuard peer to the given writer, see GetPeerConfig.
+func (c TemplateHandler) WritePeerConfig(
+	w io.Writer,
+	peer *domain.Peer,
+	knock *domain.KnockCredentials,
+	backupEndpoints []string,
+) error {
+	err := c.templates.ExecuteTemplate(w, "wg_peer.tpl", map[string]any{
+		"Peer":            peer,
+		"Knock":           knock,
+		"BackupEndpoints": backupEndpoints,
+		"Portal": map[string]any{
+			"Version": "unknown",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute peer template for %s: %w", peer.Identifier, err)
+	}
+
+	return nil
+}
+
+// GetPeerUciConfig returns the rendered OpenWrt configuration for a WireGuard peer. Depending on the style,
+// the configuration consists of uci commands or of a snippet for the /etc/config/network file.
+func (c TemplateHandler) GetPeerUciConfig(peer *domain.Peer, style domain.UciConfigStyle) (io.Reader, error) {
 	var tplBuff bytes.Buffer
 
-	err := c.templates.ExecuteTemplate(&tplBuff, "wg_peer.tpl", map[string]any{
-		"Peer": peer,
+	tplName := "wg_peer_uci_commands.tpl"
+	if style == domain.UciConfigStyleFile {
+		tplName = "wg_peer_uci_file.tpl"
+	}
+
+	endpointHost, endpointPort := domain.SplitEndpoint(peer.Endpoint.GetValue())
+	err := c.templates.ExecuteTemplate(&tplBuff, tplName, map[string]any{
+		"Peer":         peer,
+		"Name":         domain.UciSectionName(string(peer.InterfaceIdentifier)),
+		"EndpointHost": endpointHost,
+		"EndpointPort": endpointPort,
 		"Portal": map[string]any{
 			"Version": "unknown",
 		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute peer template for %s: %w", peer.Identifier, err)
+		return nil, fmt.Errorf("failed to execute uci template for %s: %w", peer.Identifier, err)
 	}
 
 	return &tplBuff, nil
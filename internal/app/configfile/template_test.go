@@ -0,0 +1,145 @@
+package configfile
+
+import (
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestTemplateHandler_GetInterfaceConfig_annotations(t *testing.T) {
+	handler, err := newTemplateHandler()
+	require.NoError(t, err)
+
+	expiry := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	iface := &domain.Interface{Identifier: "wg0", Type: domain.InterfaceTypeServer}
+	peers := []domain.Peer{
+		{
+			Identifier:     "peer-a",
+			DisplayName:    "Laptop",
+			UserIdentifier: "alice",
+			ExpiresAt:      &expiry,
+			Interface: domain.PeerInterfaceConfig{
+				KeyPair: domain.KeyPair{PublicKey: "key-a"},
+			},
+		},
+		{
+			Identifier:  "peer-b",
+			DisplayName: "Router",
+			Interface: domain.PeerInterfaceConfig{
+				KeyPair: domain.KeyPair{PublicKey: "key-b"},
+			},
+		},
+	}
+
+	cfg, err := handler.GetInterfaceConfig(iface, peers)
+	require.NoError(t, err)
+
+	// the exported metadata is restored by the importer
+	annotations, err := domain.ParsePeerAnnotations(cfg)
+	require.NoError(t, err)
+	require.Len(t, annotations, 2)
+	assert.Equal(t, domain.PeerIdentifier("peer-a"), annotations["key-a"].PeerId)
+	assert.Equal(t, "Laptop", annotations["key-a"].DisplayName)
+	assert.Equal(t, domain.UserIdentifier("alice"), annotations["key-a"].Owner)
+	require.NotNil(t, annotations["key-a"].ExpiresAt)
+	assert.True(t, expiry.Equal(*annotations["key-a"].ExpiresAt))
+	assert.Empty(t, annotations["key-b"].Owner)
+	assert.Nil(t, annotations["key-b"].ExpiresAt)
+}
+
+func TestTemplateHandler_GetPeerUciConfig(t *testing.T) {
+	handler, err := newTemplateHandler()
+	require.NoError(t, err)
+
+	peer := &domain.Peer{
+		Identifier:          "peer-a",
+		DisplayName:         "Branch 'Office'",
+		InterfaceIdentifier: "wg-0",
+		Endpoint:            domain.NewConfigOption("vpn.example.com:51820", true),
+		EndpointPublicKey:   domain.NewConfigOption("server-key", true),
+		AllowedIPsStr:       domain.NewConfigOption("10.0.0.0/24, fd00::/64", true),
+		PersistentKeepalive: domain.NewConfigOption(25, true),
+		Interface: domain.PeerInterfaceConfig{
+			KeyPair:   domain.KeyPair{PrivateKey: "private-key", PublicKey: "key-a"},
+			Addresses: []domain.Cidr{domain.CidrFromPrefix(netip.MustParsePrefix("10.0.0.2/32"))},
+			Type:      domain.InterfaceTypeClient,
+			DnsStr:    domain.NewConfigOption("10.0.0.1", true),
+			PostUp:    domain.NewConfigOption("echo up", true),
+		},
+	}
+
+	cfg, err := handler.GetPeerUciConfig(peer, domain.UciConfigStyleCommands)
+	require.NoError(t, err)
+	data, err := io.ReadAll(cfg)
+	require.NoError(t, err)
+	commands := string(data)
+	assert.Contains(t, commands, "uci set network.wg_0.proto='wireguard'")
+	assert.Contains(t, commands, "uci set network.wg_0.private_key='private-key'")
+	assert.Contains(t, commands, "uci add_list network.wg_0.addresses='10.0.0.2/32'")
+	assert.Contains(t, commands, "uci add_list network.wg_0.dns='10.0.0.1'")
+	assert.Contains(t, commands, "uci set network.wg_0_peer=wireguard_wg_0")
+	assert.Contains(t, commands, `uci set network.wg_0_peer.description='Branch '\''Office'\'''`)
+	assert.Contains(t, commands, "uci set network.wg_0_peer.endpoint_host='vpn.example.com'")
+	assert.Contains(t, commands, "uci set network.wg_0_peer.endpoint_port='51820'")
+	assert.Contains(t, commands, "uci add_list network.wg_0_peer.allowed_ips='fd00::/64'")
+	assert.Contains(t, commands, "uci set network.wg_0_peer.persistent_keepalive='25'")
+	assert.Contains(t, commands, "hooks and kill-switch rules are not supported")
+	assert.NotContains(t, commands, "echo up")
+	assert.NotContains(t, commands, "preshared_key", "empty settings are omitted")
+
+	cfg, err = handler.GetPeerUciConfig(peer, domain.UciConfigStyleFile)
+	require.NoError(t, err)
+	data, err = io.ReadAll(cfg)
+	require.NoError(t, err)
+	file := string(data)
+	assert.Contains(t, file, "config interface 'wg_0'\n\toption proto 'wireguard'")
+	assert.Contains(t, file, "\tlist addresses '10.0.0.2/32'")
+	assert.Contains(t, file, "config wireguard_wg_0 'wg_0_peer'")
+	assert.Contains(t, file, "\tlist allowed_ips '10.0.0.0/24'")
+	assert.Contains(t, file, "\toption route_allowed_ips '1'")
+}
+
+func TestTemplateHandler_GetPeerConfig_knock(t *testing.T) {
+	handler, err := newTemplateHandler()
+	require.NoError(t, err)
+
+	peer := &domain.Peer{Identifier: "peer-a", Interface: domain.PeerInterfaceConfig{Type: domain.InterfaceTypeClient}}
+
+	cfg, err := handler.GetPeerConfig(peer, nil, nil)
+	require.NoError(t, err)
+	data, err := io.ReadAll(cfg)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "Knock")
+
+	knock := &domain.KnockCredentials{Id: "0011223344556677", Secret: "abcdef", Address: "vpn.example.com:62201"}
+	cfg, err = handler.GetPeerConfig(peer, knock, nil)
+	require.NoError(t, err)
+	data, err = io.ReadAll(cfg)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# -WGP- Knock: vpn.example.com:62201 0011223344556677 abcdef\n")
+}
+
+func TestTemplateHandler_GetPeerConfig_backupEndpoints(t *testing.T) {
+	handler, err := newTemplateHandler()
+	require.NoError(t, err)
+
+	peer := &domain.Peer{
+		Identifier: "peer-a",
+		Endpoint:   domain.NewConfigOption("vpn1.example.com:51820", true),
+		Interface:  domain.PeerInterfaceConfig{Type: domain.InterfaceTypeClient},
+	}
+
+	cfg, err := handler.GetPeerConfig(peer, nil, []string{"vpn2.example.com:51820", "192.0.2.1:51820"})
+	require.NoError(t, err)
+	data, err := io.ReadAll(cfg)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Endpoint = vpn1.example.com:51820\n"+
+		"# -WGP- Backup endpoint: vpn2.example.com:51820\n"+
+		"# -WGP- Backup endpoint: 192.0.2.1:51820\n")
+}
@@ -66,6 +66,12 @@ PostDown = {{ .Interface.PostDown }}
 # -WGP- Created: {{.CreatedAt}}
 # -WGP- Updated: {{.UpdatedAt}}
 # -WGP- Display name: {{ .DisplayName }}
+{{- if .UserIdentifier}}
+# -WGP- Owner: {{ .UserIdentifier }}
+{{- end}}
+{{- if .ExpiresAt}}
+# -WGP- Expires: {{ AnnotationTime .ExpiresAt }}
+{{- end}}
 {{- if .Interface.KeyPair.PrivateKey}}
 # -WGP- PrivateKey: {{.Interface.KeyPair.PrivateKey}}
 {{- end}}
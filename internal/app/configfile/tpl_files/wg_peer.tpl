@@ -13,6 +13,15 @@
 # -WGP- Updated: {{.Peer.UpdatedAt}}
 # -WGP- Display name: {{ .Peer.DisplayName }}
 # -WGP- PublicKey: {{ .Peer.Interface.KeyPair.PublicKey }}
+{{- if .Peer.UserIdentifier}}
+# -WGP- Owner: {{ .Peer.UserIdentifier }}
+{{- end}}
+{{- if .Peer.ExpiresAt}}
+# -WGP- Expires: {{ AnnotationTime .Peer.ExpiresAt }}
+{{- end}}
+{{- with .Knock}}
+# -WGP- Knock: {{ .Address }} {{ .Id }} {{ .Secret }}
+{{- end}}
 {{- if eq .Peer.Interface.Type "server"}}
 # -WGP- Peer type: server
 {{else}}
@@ -50,10 +59,23 @@ PreDown = {{ .Peer.Interface.PreDown.GetValue }}
 {{- if .Peer.Interface.PostDown.GetValue}}
 PostDown = {{ .Peer.Interface.PostDown.GetValue }}
 {{- end}}
+{{- with .Peer.KillSwitchRules}}
+
+# Kill-switch, blocks all traffic that does not pass through the tunnel
+{{- range .PostUp}}
+PostUp = {{ . }}
+{{- end}}
+{{- range .PreDown}}
+PreDown = {{ . }}
+{{- end}}
+{{- end}}
 
 [Peer]
 PublicKey = {{ .Peer.EndpointPublicKey.GetValue }}
 Endpoint = {{ .Peer.Endpoint.GetValue }}
+{{- range .BackupEndpoints}}
+# -WGP- Backup endpoint: {{ . }}
+{{- end}}
 {{- if .Peer.AllowedIPsStr.GetValue}}
 AllowedIPs = {{ .Peer.AllowedIPsStr.GetValue }}
 {{- end}}
@@ -0,0 +1,59 @@
+# AUTOGENERATED FILE - DO NOT EDIT
+# This file contains uci commands for OpenWrt.
+# Run the commands in a shell on the router to configure the WireGuard interface {{ .Name }}.
+# Existing settings of the interface are replaced.
+
+# -WGP- WIREGUARD PORTAL OPENWRT CONFIGURATION
+# -WGP- version {{ .Portal.Version }}
+# -WGP- Peer: {{ .Peer.Identifier }}
+# -WGP- Display name: {{ .Peer.DisplayName }}
+{{- if or .Peer.Interface.PreUp.GetValue .Peer.Interface.PostUp.GetValue .Peer.Interface.PreDown.GetValue .Peer.Interface.PostDown.GetValue .Peer.KillSwitchRules}}
+# Interface hooks and kill-switch rules are not supported by OpenWrt and were omitted.
+{{- end}}
+
+uci -q delete network.{{ .Name }}
+uci -q delete network.{{ .Name }}_peer
+
+# Interface settings
+uci set network.{{ .Name }}=interface
+uci set network.{{ .Name }}.proto='wireguard'
+uci set network.{{ .Name }}.private_key={{ UciQuote .Peer.Interface.KeyPair.PrivateKey }}
+{{- range .Peer.Interface.Addresses}}
+uci add_list network.{{ $.Name }}.addresses={{ UciQuote .String }}
+{{- end}}
+{{- range SliceString .Peer.Interface.DnsStr.GetValue}}
+uci add_list network.{{ $.Name }}.dns={{ UciQuote . }}
+{{- end}}
+{{- range SliceString .Peer.Interface.DnsSearchStr.GetValue}}
+uci add_list network.{{ $.Name }}.dns_search={{ UciQuote . }}
+{{- end}}
+{{- if ne .Peer.Interface.Mtu.GetValue 0}}
+uci set network.{{ .Name }}.mtu='{{ .Peer.Interface.Mtu.GetValue }}'
+{{- end}}
+{{- if ne .Peer.Interface.FirewallMark.GetValue 0}}
+uci set network.{{ .Name }}.fwmark='{{ .Peer.Interface.FirewallMark.GetValue }}'
+{{- end}}
+
+# Peer settings
+uci set network.{{ .Name }}_peer=wireguard_{{ .Name }}
+uci set network.{{ .Name }}_peer.description={{ UciQuote .Peer.DisplayName }}
+uci set network.{{ .Name }}_peer.public_key={{ UciQuote .Peer.EndpointPublicKey.GetValue }}
+{{- if .Peer.PresharedKey}}
+uci set network.{{ .Name }}_peer.preshared_key={{ UciQuote (print .Peer.PresharedKey) }}
+{{- end}}
+{{- if .EndpointHost}}
+uci set network.{{ .Name }}_peer.endpoint_host={{ UciQuote .EndpointHost }}
+{{- end}}
+{{- if .EndpointPort}}
+uci set network.{{ .Name }}_peer.endpoint_port={{ UciQuote .EndpointPort }}
+{{- end}}
+{{- range SliceString .Peer.AllowedIPsStr.GetValue}}
+uci add_list network.{{ $.Name }}_peer.allowed_ips={{ UciQuote . }}
+{{- end}}
+uci set network.{{ .Name }}_peer.route_allowed_ips='1'
+{{- if and (ne .Peer.PersistentKeepalive.GetValue 0) (eq .Peer.Interface.Type "client")}}
+uci set network.{{ .Name }}_peer.persistent_keepalive='{{ .Peer.PersistentKeepalive.GetValue }}'
+{{- end}}
+
+uci commit network
+ifup {{ .Name }}
@@ -0,0 +1,50 @@
+# AUTOGENERATED FILE - DO NOT EDIT
+# This file contains a snippet for the OpenWrt network configuration.
+# Add the sections to /etc/config/network on the router and reload the network afterward.
+
+# -WGP- WIREGUARD PORTAL OPENWRT CONFIGURATION
+# -WGP- version {{ .Portal.Version }}
+# -WGP- Peer: {{ .Peer.Identifier }}
+# -WGP- Display name: {{ .Peer.DisplayName }}
+{{- if or .Peer.Interface.PreUp.GetValue .Peer.Interface.PostUp.GetValue .Peer.Interface.PreDown.GetValue .Peer.Interface.PostDown.GetValue .Peer.KillSwitchRules}}
+# Interface hooks and kill-switch rules are not supported by OpenWrt and were omitted.
+{{- end}}
+
+config interface '{{ .Name }}'
+	option proto 'wireguard'
+	option private_key {{ UciQuote .Peer.Interface.KeyPair.PrivateKey }}
+{{- range .Peer.Interface.Addresses}}
+	list addresses {{ UciQuote .String }}
+{{- end}}
+{{- range SliceString .Peer.Interface.DnsStr.GetValue}}
+	list dns {{ UciQuote . }}
+{{- end}}
+{{- range SliceString .Peer.Interface.DnsSearchStr.GetValue}}
+	list dns_search {{ UciQuote . }}
+{{- end}}
+{{- if ne .Peer.Interface.Mtu.GetValue 0}}
+	option mtu '{{ .Peer.Interface.Mtu.GetValue }}'
+{{- end}}
+{{- if ne .Peer.Interface.FirewallMark.GetValue 0}}
+	option fwmark '{{ .Peer.Interface.FirewallMark.GetValue }}'
+{{- end}}
+
+config wireguard_{{ .Name }} '{{ .Name }}_peer'
+	option description {{ UciQuote .Peer.DisplayName }}
+	option public_key {{ UciQuote .Peer.EndpointPublicKey.GetValue }}
+{{- if .Peer.PresharedKey}}
+	option preshared_key {{ UciQuote (print .Peer.PresharedKey) }}
+{{- end}}
+{{- if .EndpointHost}}
+	option endpoint_host {{ UciQuote .EndpointHost }}
+{{- end}}
+{{- if .EndpointPort}}
+	option endpoint_port {{ UciQuote .EndpointPort }}
+{{- end}}
+{{- range SliceString .Peer.AllowedIPsStr.GetValue}}
+	list allowed_ips {{ UciQuote . }}
+{{- end}}
+	option route_allowed_ips '1'
+{{- if and (ne .Peer.PersistentKeepalive.GetValue 0) (eq .Peer.Interface.Type "client")}}
+	option persistent_keepalive '{{ .Peer.PersistentKeepalive.GetValue }}'
+{{- end}}
@@ -0,0 +1,384 @@
+package configpull
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// tokenPrefix makes pull tokens recognizable, for example for secret scanners.
+const tokenPrefix = "wgp_"
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetConfigPullToken returns the config pull token with the given hash.
+	GetConfigPullToken(ctx context.Context, tokenHash string) (*domain.ConfigPullToken, error)
+	// GetPeerConfigPullToken returns the config pull token of the given peer.
+	GetPeerConfigPullToken(ctx context.Context, peerId domain.PeerIdentifier) (*domain.ConfigPullToken, error)
+	// GetAllConfigPullTokens returns all config pull tokens.
+	GetAllConfigPullTokens(ctx context.Context) ([]domain.ConfigPullToken, error)
+	// SaveConfigPullToken stores the given config pull token, replacing an existing token of the same peer.
+	SaveConfigPullToken(ctx context.Context, token *domain.ConfigPullToken) error
+	// DeletePeerConfigPullToken deletes the config pull token of the given peer.
+	DeletePeerConfigPullToken(ctx context.Context, peerId domain.PeerIdentifier) error
+	// UpdateConfigPullTokenPeer moves the config pull token of a peer to the new peer identifier.
+	UpdateConfigPullTokenPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error
+}
+
+type PeerManager interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+}
+
+type ConfigFileManager interface {
+	// GetPeerConfig returns the peer configuration for the given peer identifier.
+	GetPeerConfig(ctx context.Context, id domain.PeerIdentifier) (io.Reader, error)
+	// GetProvisioningQrCode returns a QR code PNG image that contains the given provisioning data.
+	GetProvisioningQrCode(ctx context.Context, provisioning domain.ConfigPullProvisioning) (io.Reader, error)
+}
+
+type MailManager interface {
+	// SendClientUpdateNotification sends an email to the given user that lists all devices with an outdated app.
+	SendClientUpdateNotification(
+		ctx context.Context,
+		userId domain.UserIdentifier,
+		clients []domain.OutdatedClient,
+	) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager manages config pull tokens. With a pull token, a client can periodically fetch the latest
+// configuration of its peer, for example after key rotations or route changes.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db          DatabaseRepo
+	peers       PeerManager
+	configFiles ConfigFileManager
+	mail        MailManager
+}
+
+// NewConfigPullManager creates a new config pull manager.
+func NewConfigPullManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	peers PeerManager,
+	configFiles ConfigFileManager,
+	mail MailManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:          db,
+		peers:       peers,
+		configFiles: configFiles,
+		mail:        mail,
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	// Tokens of deleted peers are not removed on the peer deleted event, as the event is also published if the
+	// identifier of a peer changes. Stale tokens are removed on the next pull attempt instead.
+	_ = m.bus.Subscribe(app.TopicPeerIdentifierUpdated, m.handlePeerIdentifierUpdatedEvent)
+}
+
+func (m Manager) handlePeerIdentifierUpdatedEvent(oldId, newId domain.PeerIdentifier) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	if err := m.db.UpdateConfigPullTokenPeer(ctx, oldId, newId); err != nil {
+		slog.Error("failed to migrate config pull token", "oldIdentifier", oldId, "newIdentifier", newId,
+			"error", err)
+	}
+}
+
+func (m Manager) checkEnabled() error {
+	if !m.cfg.ConfigPull.Enabled {
+		return fmt.Errorf("config pull is disabled: %w", domain.ErrNoPermission)
+	}
+
+	return nil
+}
+
+// GetToken returns the metadata of the config pull token of the given peer.
+func (m Manager) GetToken(ctx context.Context, peerId domain.PeerIdentifier) (*domain.ConfigPullToken, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	// fetching the peer validates the access rights of the current user
+	if _, err := m.peers.GetPeer(ctx, peerId); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetPeerConfigPullToken(ctx, peerId)
+}
+
+// CreateToken creates a new config pull token for the given peer. An existing token of the peer is replaced.
+// The returned plain text token is not stored and cannot be retrieved later.
+func (m Manager) CreateToken(ctx context.Context, peerId domain.PeerIdentifier) (
+	string,
+	*domain.ConfigPullToken,
+	error,
+) {
+	if err := m.checkEnabled(); err != nil {
+		return "", nil, err
+	}
+
+	peer, err := m.peers.GetPeer(ctx, peerId)
+	if err != nil {
+		return "", nil, err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("failed to generate pull token: %w", err)
+	}
+	plainToken := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	token := &domain.ConfigPullToken{
+		TokenHash:      domain.HashConfigPullToken(plainToken),
+		PeerId:         peer.Identifier,
+		UserIdentifier: peer.UserIdentifier,
+		CreatedAt:      time.Now(),
+		CreatedBy:      string(domain.GetUserInfo(ctx).Id),
+	}
+	if err := m.db.SaveConfigPullToken(ctx, token); err != nil {
+		return "", nil, fmt.Errorf("failed to save pull token: %w", err)
+	}
+
+	return plainToken, token, nil
+}
+
+// GetProvisioningQrCode returns a QR code that contains the pull URL and the given plain text pull token instead of
+// the full configuration. Devices with small displays, like routers, can scan it and fetch their configuration
+// via the pull endpoint afterward.
+func (m Manager) GetProvisioningQrCode(ctx context.Context, plainToken, pullUrl string) (io.Reader, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	token, err := m.db.GetConfigPullToken(ctx, domain.HashConfigPullToken(plainToken))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("invalid pull token: %w", domain.ErrNoPermission)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pull token: %w", err)
+	}
+
+	// fetching the peer validates the access rights of the current user
+	if _, err := m.peers.GetPeer(ctx, token.PeerId); err != nil {
+		return nil, err
+	}
+
+	return m.configFiles.GetProvisioningQrCode(ctx, domain.ConfigPullProvisioning{
+		PullUrl: pullUrl,
+		Token:   plainToken,
+	})
+}
+
+// DeleteToken revokes the config pull token of the given peer.
+func (m Manager) DeleteToken(ctx context.Context, peerId domain.PeerIdentifier) error {
+	if err := m.checkEnabled(); err != nil {
+		return err
+	}
+
+	if _, err := m.peers.GetPeer(ctx, peerId); err != nil {
+		return err
+	}
+
+	return m.db.DeletePeerConfigPullToken(ctx, peerId)
+}
+
+// PullConfig returns the peer identifier and the current configuration of the peer that belongs to the given
+// pull token. No further authentication is required, the pull token serves as credential.
+// The reported client information is stored with the token, see GetOutdatedClients.
+func (m Manager) PullConfig(ctx context.Context, plainToken string, client domain.ConfigPullClient) (
+	domain.PeerIdentifier,
+	[]byte,
+	error,
+) {
+	if err := m.checkEnabled(); err != nil {
+		return "", nil, err
+	}
+
+	// the pull token proves the authorization of the client, so all data is loaded with system privileges
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	token, err := m.db.GetConfigPullToken(ctx, domain.HashConfigPullToken(plainToken))
+	if errors.Is(err, domain.ErrNotFound) {
+		return "", nil, fmt.Errorf("invalid pull token: %w", domain.ErrNoPermission)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load pull token: %w", err)
+	}
+
+	peer, err := m.peers.GetPeer(ctx, token.PeerId)
+	if errors.Is(err, domain.ErrNotFound) {
+		m.removeStaleToken(ctx, token)
+		return "", nil, fmt.Errorf("peer %s no longer exists: %w", token.PeerId, domain.ErrNotFound)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if peer.UserIdentifier != token.UserIdentifier {
+		// the peer was deleted and re-created for another user, the token must not grant access to it
+		m.removeStaleToken(ctx, token)
+		return "", nil, fmt.Errorf("invalid pull token: %w", domain.ErrNoPermission)
+	}
+	if peer.IsDisabled() {
+		return "", nil, fmt.Errorf("peer %s is disabled: %w", peer.Identifier, domain.ErrNoPermission)
+	}
+
+	cfgReader, err := m.configFiles.GetPeerConfig(ctx, peer.Identifier)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load peer config: %w", err)
+	}
+	cfgData, err := io.ReadAll(cfgReader)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read peer config: %w", err)
+	}
+
+	now := time.Now()
+	token.LastPulledAt = &now
+	token.LastPulledFrom = client.Ip
+	if client.Os != "" || client.Version != "" {
+		token.ClientOs = client.Os
+		token.ClientVersion = client.Version
+	}
+	if err := m.db.SaveConfigPullToken(ctx, token); err != nil {
+		slog.Warn("failed to update pull token", "peer", peer.Identifier, "error", err)
+	}
+
+	return peer.Identifier, cfgData, nil
+}
+
+func (m Manager) removeStaleToken(ctx context.Context, token *domain.ConfigPullToken) {
+	if err := m.db.DeletePeerConfigPullToken(ctx, token.PeerId); err != nil {
+		slog.Warn("failed to delete stale pull token", "peer", token.PeerId, "error", err)
+	}
+}
+
+// GetOutdatedClients returns all peers whose client reported an app version below the minimum version of its
+// operating system. Clients that did not report their version or operating system are ignored.
+func (m Manager) GetOutdatedClients(ctx context.Context, minVersions map[string]string) (
+	[]domain.OutdatedClient,
+	error,
+) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	normalizedMinVersions := make(map[string]string, len(minVersions))
+	for os, version := range minVersions {
+		normalizedMinVersions[domain.NormalizeClientOs(os)] = version
+	}
+
+	tokens, err := m.db.GetAllConfigPullTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pull tokens: %w", err)
+	}
+
+	var clients []domain.OutdatedClient
+	for _, token := range tokens {
+		minVersion, ok := normalizedMinVersions[token.ClientOs]
+		if !ok || minVersion == "" || token.ClientVersion == "" || token.LastPulledAt == nil {
+			continue
+		}
+		if domain.CompareClientVersions(token.ClientVersion, minVersion) >= 0 {
+			continue
+		}
+
+		peer, err := m.peers.GetPeer(ctx, token.PeerId)
+		if errors.Is(err, domain.ErrNotFound) {
+			continue // stale tokens are removed on the next pull attempt
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		clients = append(clients, domain.OutdatedClient{
+			Peer:         *peer,
+			Os:           token.ClientOs,
+			Version:      token.ClientVersion,
+			MinVersion:   minVersion,
+			LastPulledAt: *token.LastPulledAt,
+		})
+	}
+
+	slices.SortFunc(clients, func(a, b domain.OutdatedClient) int {
+		return strings.Compare(string(a.Peer.Identifier), string(b.Peer.Identifier))
+	})
+
+	return clients, nil
+}
+
+// RunUpdateCampaign notifies the owners of all outdated clients by mail. Each user receives a single mail that
+// lists all of their outdated devices. Peers without a linked user are reported, but nobody is notified.
+func (m Manager) RunUpdateCampaign(ctx context.Context, campaign domain.ClientUpdateCampaign) (
+	*domain.ClientUpdateCampaignReport,
+	error,
+) {
+	clients, err := m.GetOutdatedClients(ctx, campaign.MinVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &domain.ClientUpdateCampaignReport{Clients: clients}
+	if campaign.DryRun {
+		return report, nil
+	}
+
+	userClients := make(map[domain.UserIdentifier][]domain.OutdatedClient)
+	var users []domain.UserIdentifier
+	for _, client := range clients {
+		userId := client.Peer.UserIdentifier
+		if userId == "" {
+			continue
+		}
+		if _, ok := userClients[userId]; !ok {
+			users = append(users, userId)
+		}
+		userClients[userId] = append(userClients[userId], client)
+	}
+
+	for _, userId := range users {
+		if err := m.mail.SendClientUpdateNotification(ctx, userId, userClients[userId]); err != nil {
+			slog.Warn("failed to send client update notification", "user", userId, "error", err)
+			continue
+		}
+		report.NotifiedUsers = append(report.NotifiedUsers, userId)
+	}
+
+	slog.Info("client update campaign finished",
+		"outdatedClients", len(clients), "notifiedUsers", len(report.NotifiedUsers))
+
+	return report, nil
+}
@@ -0,0 +1,275 @@
+package configpull
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	tokens map[string]domain.ConfigPullToken
+}
+
+func (f *fakeDatabase) GetConfigPullToken(_ context.Context, tokenHash string) (*domain.ConfigPullToken, error) {
+	token, ok := f.tokens[tokenHash]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &token, nil
+}
+
+func (f *fakeDatabase) GetPeerConfigPullToken(_ context.Context, peerId domain.PeerIdentifier) (
+	*domain.ConfigPullToken,
+	error,
+) {
+	for _, token := range f.tokens {
+		if token.PeerId == peerId {
+			return &token, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeDatabase) GetAllConfigPullTokens(_ context.Context) ([]domain.ConfigPullToken, error) {
+	tokens := make([]domain.ConfigPullToken, 0, len(f.tokens))
+	for _, token := range f.tokens {
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+func (f *fakeDatabase) SaveConfigPullToken(_ context.Context, token *domain.ConfigPullToken) error {
+	for hash, existing := range f.tokens {
+		if existing.PeerId == token.PeerId && hash != token.TokenHash {
+			delete(f.tokens, hash)
+		}
+	}
+	f.tokens[token.TokenHash] = *token
+	return nil
+}
+
+func (f *fakeDatabase) DeletePeerConfigPullToken(_ context.Context, peerId domain.PeerIdentifier) error {
+	for hash, existing := range f.tokens {
+		if existing.PeerId == peerId {
+			delete(f.tokens, hash)
+		}
+	}
+	return nil
+}
+
+func (f *fakeDatabase) UpdateConfigPullTokenPeer(_ context.Context, oldId, newId domain.PeerIdentifier) error {
+	for hash, existing := range f.tokens {
+		if existing.PeerId == oldId {
+			existing.PeerId = newId
+			f.tokens[hash] = existing
+		}
+	}
+	return nil
+}
+
+type fakePeerManager struct {
+	peers map[domain.PeerIdentifier]domain.Peer
+}
+
+func (f *fakePeerManager) GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier, ""); err != nil {
+		return nil, err
+	}
+	return &peer, nil
+}
+
+type fakeConfigFiles struct{}
+
+func (fakeConfigFiles) GetPeerConfig(_ context.Context, id domain.PeerIdentifier) (io.Reader, error) {
+	return bytes.NewBufferString("config of " + string(id)), nil
+}
+
+func (fakeConfigFiles) GetProvisioningQrCode(
+	_ context.Context,
+	provisioning domain.ConfigPullProvisioning,
+) (io.Reader, error) {
+	return bytes.NewBufferString(provisioning.Uri()), nil
+}
+
+type fakeMailManager struct {
+	sent map[domain.UserIdentifier][]domain.OutdatedClient
+}
+
+func (f *fakeMailManager) SendClientUpdateNotification(
+	_ context.Context,
+	userId domain.UserIdentifier,
+	clients []domain.OutdatedClient,
+) error {
+	f.sent[userId] = clients
+	return nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func newTestManager(t *testing.T) (*Manager, *fakeDatabase, *fakePeerManager) {
+	cfg := &config.Config{}
+	cfg.ConfigPull.Enabled = true
+
+	db := &fakeDatabase{tokens: map[string]domain.ConfigPullToken{}}
+	peers := &fakePeerManager{peers: map[domain.PeerIdentifier]domain.Peer{
+		"peer-a": {Identifier: "peer-a", UserIdentifier: "alice"},
+	}}
+	m, err := NewConfigPullManager(cfg, fakeBus{}, db, peers, fakeConfigFiles{}, &fakeMailManager{})
+	require.NoError(t, err)
+
+	return m, db, peers
+}
+
+func userContext(id domain.UserIdentifier) context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: id})
+}
+
+func TestManager_CreateToken(t *testing.T) {
+	m, db, _ := newTestManager(t)
+
+	_, _, err := m.CreateToken(userContext("bob"), "peer-a")
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "only the owner can create tokens")
+
+	plain, token, err := m.CreateToken(userContext("alice"), "peer-a")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, tokenPrefix))
+	assert.NotContains(t, token.TokenHash, plain, "the plain token must not be stored")
+
+	// a new token replaces the old one
+	plain2, _, err := m.CreateToken(userContext("alice"), "peer-a")
+	require.NoError(t, err)
+	assert.Len(t, db.tokens, 1)
+
+	_, _, err = m.PullConfig(context.Background(), plain, domain.ConfigPullClient{Ip: "192.0.2.1"})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	peerId, cfgData, err := m.PullConfig(context.Background(), plain2, domain.ConfigPullClient{Ip: "192.0.2.1"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerIdentifier("peer-a"), peerId)
+	assert.Equal(t, "config of peer-a", string(cfgData))
+
+	info, err := m.GetToken(userContext("alice"), "peer-a")
+	require.NoError(t, err)
+	assert.Equal(t, "192.0.2.1", info.LastPulledFrom)
+	assert.NotNil(t, info.LastPulledAt)
+}
+
+func TestManager_PullConfig(t *testing.T) {
+	m, db, peers := newTestManager(t)
+
+	plain, _, err := m.CreateToken(userContext("alice"), "peer-a")
+	require.NoError(t, err)
+
+	// the token follows identifier changes
+	peers.peers["peer-b"] = domain.Peer{Identifier: "peer-b", UserIdentifier: "alice"}
+	delete(peers.peers, "peer-a")
+	m.handlePeerIdentifierUpdatedEvent("peer-a", "peer-b")
+	peerId, _, err := m.PullConfig(context.Background(), plain, domain.ConfigPullClient{})
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerIdentifier("peer-b"), peerId)
+
+	// disabled peers do not receive their config
+	disabled := time.Now()
+	peer := peers.peers["peer-b"]
+	peer.Disabled = &disabled
+	peers.peers["peer-b"] = peer
+	_, _, err = m.PullConfig(context.Background(), plain, domain.ConfigPullClient{})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	// peers that were re-created for another user are not accessible
+	peers.peers["peer-b"] = domain.Peer{Identifier: "peer-b", UserIdentifier: "bob"}
+	_, _, err = m.PullConfig(context.Background(), plain, domain.ConfigPullClient{})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	assert.Empty(t, db.tokens)
+
+	// tokens of deleted peers are removed
+	plain, _, err = m.CreateToken(userContext("bob"), "peer-b")
+	require.NoError(t, err)
+	delete(peers.peers, "peer-b")
+	_, _, err = m.PullConfig(context.Background(), plain, domain.ConfigPullClient{})
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Empty(t, db.tokens)
+}
+
+func TestManager_GetProvisioningQrCode(t *testing.T) {
+	m, _, _ := newTestManager(t)
+	pullUrl := "https://vpn.example.com/api/v1/provisioning/config-pull"
+
+	_, err := m.GetProvisioningQrCode(userContext("alice"), "wgp_unknown", pullUrl)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	plain, _, err := m.CreateToken(userContext("alice"), "peer-a")
+	require.NoError(t, err)
+
+	_, err = m.GetProvisioningQrCode(userContext("bob"), plain, pullUrl)
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "only the owner can get the QR code")
+
+	qrCode, err := m.GetProvisioningQrCode(userContext("alice"), plain, pullUrl)
+	require.NoError(t, err)
+	data, err := io.ReadAll(qrCode)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ConfigPullProvisioning{PullUrl: pullUrl, Token: plain}.Uri(), string(data))
+}
+
+func TestManager_disabled(t *testing.T) {
+	m, _, _ := newTestManager(t)
+	m.cfg.ConfigPull.Enabled = false
+
+	_, _, err := m.PullConfig(context.Background(), "wgp_token", domain.ConfigPullClient{})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_RunUpdateCampaign(t *testing.T) {
+	m, _, peers := newTestManager(t)
+	mail := &fakeMailManager{sent: map[domain.UserIdentifier][]domain.OutdatedClient{}}
+	m.mail = mail
+
+	peers.peers["peer-b"] = domain.Peer{Identifier: "peer-b", UserIdentifier: "alice"}
+	peers.peers["peer-c"] = domain.Peer{Identifier: "peer-c", UserIdentifier: "bob"}
+	clients := map[domain.PeerIdentifier]domain.ConfigPullClient{
+		"peer-a": {Os: domain.ClientOsWindows, Version: "0.5.1"},
+		"peer-b": {Os: domain.ClientOsAndroid, Version: "1.0.1"},
+		"peer-c": {Os: domain.ClientOsWindows, Version: "0.5.3"},
+	}
+	for peerId, client := range clients {
+		owner := peers.peers[peerId].UserIdentifier
+		plain, _, err := m.CreateToken(userContext(owner), peerId)
+		require.NoError(t, err)
+		_, _, err = m.PullConfig(context.Background(), plain, client)
+		require.NoError(t, err)
+	}
+
+	campaign := domain.ClientUpdateCampaign{MinVersions: map[string]string{"Windows": "0.5.3", "android": "1.0.2"}}
+
+	_, err := m.RunUpdateCampaign(userContext("alice"), campaign)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	campaign.DryRun = true
+	report, err := m.RunUpdateCampaign(adminCtx, campaign)
+	require.NoError(t, err)
+	require.Len(t, report.Clients, 2)
+	assert.Equal(t, domain.PeerIdentifier("peer-a"), report.Clients[0].Peer.Identifier)
+	assert.Empty(t, mail.sent, "dry runs must not send mails")
+
+	campaign.DryRun = false
+	report, err = m.RunUpdateCampaign(adminCtx, campaign)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserIdentifier{"alice"}, report.NotifiedUsers)
+	assert.Len(t, mail.sent["alice"], 2, "all outdated devices of a user are sent in a single mail")
+}
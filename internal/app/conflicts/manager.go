@@ -0,0 +1,198 @@
+package conflicts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetInterfacePeers returns all peers for the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager periodically checks the allowed IPs of the peers of all interfaces for overlaps. WireGuard routes every
+// address to exactly one peer, so overlapping allowed IPs silently blackhole the traffic of one of the peers. The
+// result of the last check is kept per interface and can be viewed by the administrators of the interface. Newly
+// detected conflicts are logged.
+// The WireGuard manager rejects new conflicts when a peer is saved, see config.AllowedIPConflictConfig. The
+// background check finds conflicts that were created before or by imports and synchronizations.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db DatabaseRepo
+
+	mux     *sync.Mutex
+	reports map[domain.InterfaceIdentifier]domain.AllowedIPConflictReport
+}
+
+// NewConflictManager creates a new allowed IP conflict manager.
+func NewConflictManager(cfg *config.Config, bus EventBus, db DatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db: db,
+
+		mux:     &sync.Mutex{},
+		reports: make(map[domain.InterfaceIdentifier]domain.AllowedIPConflictReport),
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the periodic conflict check of all interfaces.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if m.cfg.AllowedIPConflicts.CheckInterval <= 0 {
+		return
+	}
+
+	go m.runConflictCheck(ctx)
+
+	slog.Debug("started allowed IP conflict checks", "interval", m.cfg.AllowedIPConflicts.CheckInterval)
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceDeletedEvent)
+}
+
+func (m Manager) handleInterfaceDeletedEvent(iface domain.Interface) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.reports, iface.Identifier)
+}
+
+func (m Manager) runConflictCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		if err := m.checkAllInterfaces(ctx, time.Now()); err != nil {
+			slog.Error("failed to check allowed IP conflicts", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-time.After(m.cfg.AllowedIPConflicts.CheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+	}
+}
+
+// checkAllInterfaces checks the allowed IPs of all interfaces for conflicts.
+func (m Manager) checkAllInterfaces(ctx context.Context, now time.Time) error {
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	for _, iface := range interfaces {
+		if _, err := m.checkInterface(ctx, iface.Identifier, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkInterface checks the allowed IPs of the given interface for conflicts and stores the report. Conflicts that
+// were not part of the previous report are logged.
+func (m Manager) checkInterface(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	now time.Time,
+) (*domain.AllowedIPConflictReport, error) {
+	peers, err := m.db.GetInterfacePeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peers of interface %s: %w", id, err)
+	}
+
+	report := domain.AllowedIPConflictReport{
+		InterfaceIdentifier: id,
+		CheckedAt:           now,
+		Conflicts:           domain.FindAllowedIPConflicts(peers),
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	previous := m.reports[id]
+	for _, conflict := range report.Conflicts {
+		if !slices.Contains(previous.Conflicts, conflict) {
+			slog.Warn("detected overlapping allowed IPs", "interface", id, "conflict", conflict.String())
+		}
+	}
+	m.reports[id] = report
+
+	return &report, nil
+}
+
+// GetConflictReport returns the result of the last conflict check of the given interface. If the interface was not
+// checked yet, it is checked immediately.
+func (m Manager) GetConflictReport(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+) (*domain.AllowedIPConflictReport, error) {
+	if err := m.validateInterfaceAccess(ctx, id); err != nil {
+		return nil, err
+	}
+
+	m.mux.Lock()
+	report, ok := m.reports[id]
+	m.mux.Unlock()
+	if ok {
+		return &report, nil
+	}
+
+	return m.checkInterface(ctx, id, time.Now())
+}
+
+// CheckInterface checks the allowed IPs of the given interface for conflicts immediately and returns the new report.
+func (m Manager) CheckInterface(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+) (*domain.AllowedIPConflictReport, error) {
+	if err := m.validateInterfaceAccess(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return m.checkInterface(ctx, id, time.Now())
+}
+
+func (m Manager) validateInterfaceAccess(ctx context.Context, id domain.InterfaceIdentifier) error {
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to load interface %s: %w", id, err)
+	}
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return err
+	}
+
+	return domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier)
+}
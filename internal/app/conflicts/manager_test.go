@@ -0,0 +1,118 @@
+package conflicts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	interfaces []domain.Interface
+	peers      []domain.Peer
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	for _, iface := range f.interfaces {
+		if iface.Identifier == id {
+			return &iface, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	var peers []domain.Peer
+	for _, peer := range f.peers {
+		if peer.InterfaceIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+type fakeBus struct {
+	handlers map[string]interface{}
+}
+
+func (f *fakeBus) Subscribe(topic string, fn interface{}) error {
+	f.handlers[topic] = fn
+	return nil
+}
+
+func testPeer(id domain.PeerIdentifier, iface domain.InterfaceIdentifier, address, extra string) domain.Peer {
+	cidr, _ := domain.CidrFromString(address)
+	return domain.Peer{Identifier: id, InterfaceIdentifier: iface, ExtraAllowedIPsStr: extra,
+		Interface: domain.PeerInterfaceConfig{Addresses: []domain.Cidr{cidr}}}
+}
+
+func newTestManager(t *testing.T) (*Manager, *fakeDatabase, *fakeBus) {
+	db := &fakeDatabase{
+		interfaces: []domain.Interface{{Identifier: "wg0"}, {Identifier: "wg1"}},
+		peers: []domain.Peer{
+			testPeer("alice", "wg0", "10.0.0.2/32", ""),
+			testPeer("gateway", "wg0", "10.0.0.3/32", "10.0.0.0/30"),
+			testPeer("bob", "wg1", "10.0.0.2/32", ""),
+		},
+	}
+	bus := &fakeBus{handlers: make(map[string]interface{})}
+
+	m, err := NewConflictManager(&config.Config{}, bus, db)
+	require.NoError(t, err)
+
+	return m, db, bus
+}
+
+func TestManager_GetConflictReport(t *testing.T) {
+	m, db, _ := newTestManager(t)
+	adminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+	checkedAt := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	require.NoError(t, m.checkAllInterfaces(adminCtx, checkedAt))
+
+	report, err := m.GetConflictReport(adminCtx, "wg0")
+	require.NoError(t, err)
+	assert.Equal(t, checkedAt, report.CheckedAt, "the report of the background check is returned")
+	require.Len(t, report.Conflicts, 1)
+	assert.Equal(t, domain.PeerIdentifier("alice"), report.Conflicts[0].PeerId)
+	assert.Equal(t, domain.PeerIdentifier("gateway"), report.Conflicts[0].OtherPeerId)
+
+	report, err = m.GetConflictReport(adminCtx, "wg1")
+	require.NoError(t, err)
+	assert.Empty(t, report.Conflicts, "peers of other interfaces do not conflict")
+
+	db.peers = db.peers[:1]
+	report, err = m.CheckInterface(adminCtx, "wg0")
+	require.NoError(t, err)
+	assert.Empty(t, report.Conflicts)
+	assert.True(t, report.CheckedAt.After(checkedAt))
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	_, err = m.GetConflictReport(userCtx, "wg0")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	_, err = m.CheckInterface(adminCtx, "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestManager_interfaceDeleted(t *testing.T) {
+	m, _, bus := newTestManager(t)
+	adminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+
+	_, err := m.CheckInterface(adminCtx, "wg0")
+	require.NoError(t, err)
+	assert.Len(t, m.reports, 1)
+
+	bus.handlers[app.TopicInterfaceDeleted].(func(domain.Interface))(domain.Interface{Identifier: "wg0"})
+	assert.Empty(t, m.reports)
+}
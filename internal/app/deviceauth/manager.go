@@ -0,0 +1,419 @@
+package deviceauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// maxPendingRequests limits the number of unconfirmed device authorization requests kept in memory.
+const maxPendingRequests = 1000
+
+// region dependencies
+
+type PeerManager interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// PreparePeer prepares a new peer for the given interface with fresh keys and ip addresses.
+	PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error)
+	// CreatePeer creates a new peer.
+	CreatePeer(ctx context.Context, p *domain.Peer) (*domain.Peer, error)
+}
+
+type ConfigFileManager interface {
+	// GetPeerConfig returns the peer configuration for the given peer identifier.
+	GetPeerConfig(ctx context.Context, id domain.PeerIdentifier) (io.Reader, error)
+}
+
+// endregion dependencies
+
+// Manager implements the OAuth2 device authorization grant (RFC 8628) for headless peer enrollment.
+// Devices request a user code, a logged-in user approves the code in the web UI, and the device
+// receives the configuration of the selected or a newly created peer.
+// Requests are short-lived and only kept in memory.
+type Manager struct {
+	cfg *config.Config
+
+	peers       PeerManager
+	configFiles ConfigFileManager
+
+	mux        *sync.Mutex
+	requests   map[string]*domain.DeviceAuthorization // indexed by device code
+	userCodes  map[string]string                      // user code to device code
+	randReader io.Reader
+}
+
+// NewDeviceAuthManager creates a new device authorization manager.
+func NewDeviceAuthManager(
+	cfg *config.Config,
+	peers PeerManager,
+	configFiles ConfigFileManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+
+		peers:       peers,
+		configFiles: configFiles,
+
+		mux:        &sync.Mutex{},
+		requests:   make(map[string]*domain.DeviceAuthorization),
+		userCodes:  make(map[string]string),
+		randReader: rand.Reader,
+	}
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background jobs for the device authorization manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.Auth.DeviceAuthorization.Enabled {
+		return
+	}
+
+	go m.runExpiryCleanup(ctx)
+}
+
+func (m Manager) runExpiryCleanup(ctx context.Context) {
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(time.Minute):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		m.mux.Lock()
+		m.pruneExpired(time.Now())
+		m.mux.Unlock()
+	}
+}
+
+// pruneExpired deletes all expired requests. The caller must hold the lock.
+func (m Manager) pruneExpired(now time.Time) {
+	for deviceCode, req := range m.requests {
+		if req.IsExpired(now) {
+			m.remove(deviceCode)
+		}
+	}
+}
+
+// remove deletes the request with the given device code. The caller must hold the lock.
+func (m Manager) remove(deviceCode string) {
+	if req, ok := m.requests[deviceCode]; ok {
+		delete(m.userCodes, req.UserCode)
+	}
+	delete(m.requests, deviceCode)
+}
+
+func (m Manager) checkEnabled() error {
+	if !m.cfg.Auth.DeviceAuthorization.Enabled {
+		return fmt.Errorf("device authorization is disabled: %w", domain.ErrNoPermission)
+	}
+
+	return nil
+}
+
+// RequestAuthorization starts a new device authorization request. No authentication is required.
+// The client name, interface and public key are optional hints from the device that are used when the
+// user approves the request.
+func (m Manager) RequestAuthorization(
+	_ context.Context,
+	clientName string,
+	interfaceId domain.InterfaceIdentifier,
+	publicKey string,
+) (*domain.DeviceAuthorization, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if publicKey != "" && !domain.PeerIdentifier(publicKey).IsPublicKey() {
+		return nil, fmt.Errorf("invalid public key: %w", domain.ErrInvalidData)
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if len(m.requests) >= maxPendingRequests {
+		m.pruneExpired(time.Now())
+		if len(m.requests) >= maxPendingRequests {
+			return nil, errors.New("too many pending device authorization requests")
+		}
+	}
+
+	deviceCode, err := m.newDeviceCode()
+	if err != nil {
+		return nil, err
+	}
+	userCode, err := m.newUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	req := &domain.DeviceAuthorization{
+		DeviceCode:          deviceCode,
+		UserCode:            userCode,
+		ClientName:          strings.TrimSpace(clientName),
+		InterfaceIdentifier: interfaceId,
+		PublicKey:           publicKey,
+		CreatedAt:           now,
+		ExpiresAt:           now.Add(m.cfg.Auth.DeviceAuthorization.CodeLifetime),
+		Interval:            m.cfg.Auth.DeviceAuthorization.PollInterval,
+		State:               domain.DeviceAuthorizationPending,
+	}
+	m.requests[deviceCode] = req
+	m.userCodes[userCode] = deviceCode
+
+	slog.Debug("device authorization requested", "client", req.ClientName, "expires", req.ExpiresAt)
+
+	result := *req
+	return &result, nil
+}
+
+func (m Manager) newDeviceCode() (string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(m.randReader, b); err != nil {
+		return "", fmt.Errorf("failed to generate device code: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// newUserCode generates a new unique user code. The caller must hold the lock.
+func (m Manager) newUserCode() (string, error) {
+	for {
+		b := make([]byte, 4*domain.UserCodeLength)
+		if _, err := io.ReadFull(m.randReader, b); err != nil {
+			return "", fmt.Errorf("failed to generate user code: %w", err)
+		}
+
+		code := domain.NewUserCode(b)
+		if len(code) != domain.UserCodeLength {
+			continue // not enough unbiased random bytes, try again
+		}
+		if _, exists := m.userCodes[code]; exists {
+			continue
+		}
+
+		return code, nil
+	}
+}
+
+// getPendingRequest returns the pending request for the given user code. The caller must hold the lock.
+func (m Manager) getPendingRequest(userCode string) (*domain.DeviceAuthorization, error) {
+	deviceCode, ok := m.userCodes[domain.NormalizeUserCode(userCode)]
+	if !ok {
+		return nil, fmt.Errorf("unknown device code: %w", domain.ErrNotFound)
+	}
+	req := m.requests[deviceCode]
+	if req.IsExpired(time.Now()) {
+		return nil, fmt.Errorf("device code expired: %w", domain.ErrNotFound)
+	}
+	if req.State != domain.DeviceAuthorizationPending {
+		return nil, fmt.Errorf("device code already used: %w", domain.ErrNotFound)
+	}
+
+	return req, nil
+}
+
+// GetPendingAuthorization returns the pending device authorization request for the given user code.
+func (m Manager) GetPendingAuthorization(ctx context.Context, userCode string) (*domain.DeviceAuthorization, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+	currentUser := domain.GetUserInfo(ctx)
+	if err := domain.ValidateUserAccessRights(ctx, currentUser.Id, currentUser.Organization); err != nil {
+		return nil, err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	req, err := m.getPendingRequest(userCode)
+	if err != nil {
+		return nil, err
+	}
+
+	result := *req
+	return &result, nil
+}
+
+// ApproveAuthorization approves the device authorization request for the given user code.
+// If a peer identifier is given, the configuration of that peer is handed to the device. Otherwise, a new peer
+// is created for the current user on the given interface, or on the interface requested by the device.
+func (m Manager) ApproveAuthorization(
+	ctx context.Context,
+	userCode string,
+	interfaceId domain.InterfaceIdentifier,
+	peerId domain.PeerIdentifier,
+) (*domain.DeviceAuthorization, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	currentUser := domain.GetUserInfo(ctx)
+	if err := domain.ValidateUserAccessRights(ctx, currentUser.Id, currentUser.Organization); err != nil {
+		return nil, err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	req, err := m.getPendingRequest(userCode)
+	if err != nil {
+		return nil, err
+	}
+
+	var peer *domain.Peer
+	if peerId != "" {
+		peer, err = m.peers.GetPeer(ctx, peerId)
+		if err != nil {
+			return nil, err
+		}
+		if peer.UserIdentifier != currentUser.Id {
+			return nil, fmt.Errorf("peer %s is not linked to the current user: %w", peerId, domain.ErrNoPermission)
+		}
+	} else {
+		peer, err = m.createPeer(ctx, req, interfaceId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req.State = domain.DeviceAuthorizationApproved
+	req.UserIdentifier = currentUser.Id
+	req.PeerIdentifier = peer.Identifier
+
+	slog.Debug("device authorization approved",
+		"client", req.ClientName, "user", req.UserIdentifier, "peer", req.PeerIdentifier)
+
+	result := *req
+	return &result, nil
+}
+
+func (m Manager) createPeer(
+	ctx context.Context,
+	req *domain.DeviceAuthorization,
+	interfaceId domain.InterfaceIdentifier,
+) (*domain.Peer, error) {
+	if interfaceId == "" {
+		interfaceId = req.InterfaceIdentifier
+	}
+	if interfaceId == "" {
+		return nil, fmt.Errorf("missing interface for new peer: %w", domain.ErrInvalidData)
+	}
+
+	peer, err := m.peers.PreparePeer(ctx, interfaceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare new peer: %w", err)
+	}
+	if req.PublicKey != "" {
+		peer.Identifier = domain.PeerIdentifier(req.PublicKey)
+		peer.Interface.PublicKey = req.PublicKey
+		peer.Interface.PrivateKey = "" // the private key stays on the device
+	}
+	if req.ClientName != "" {
+		peer.DisplayName = req.ClientName
+	} else {
+		peer.GenerateDisplayName("Device")
+	}
+
+	peer, err = m.peers.CreatePeer(ctx, peer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new peer: %w", err)
+	}
+
+	return peer, nil
+}
+
+// DenyAuthorization denies the device authorization request for the given user code.
+func (m Manager) DenyAuthorization(ctx context.Context, userCode string) error {
+	if err := m.checkEnabled(); err != nil {
+		return err
+	}
+
+	currentUser := domain.GetUserInfo(ctx)
+	if err := domain.ValidateUserAccessRights(ctx, currentUser.Id, currentUser.Organization); err != nil {
+		return err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	req, err := m.getPendingRequest(userCode)
+	if err != nil {
+		return err
+	}
+
+	req.State = domain.DeviceAuthorizationDenied
+	req.UserIdentifier = currentUser.Id
+
+	slog.Debug("device authorization denied", "client", req.ClientName, "user", req.UserIdentifier)
+
+	return nil
+}
+
+// PollAuthorization is called by the device to fetch the authorization result. No authentication is required,
+// the device code serves as credential. Once the request was approved, the peer identifier and the peer
+// configuration are returned and the device code becomes invalid.
+func (m Manager) PollAuthorization(ctx context.Context, deviceCode string) (domain.PeerIdentifier, []byte, error) {
+	if err := m.checkEnabled(); err != nil {
+		return "", nil, err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	req, ok := m.requests[deviceCode]
+	if !ok {
+		return "", nil, domain.ErrDeviceExpiredToken // unknown device codes are not distinguished from expired ones
+	}
+
+	now := time.Now()
+	if req.IsExpired(now) {
+		m.remove(deviceCode)
+		return "", nil, domain.ErrDeviceExpiredToken
+	}
+
+	switch req.State {
+	case domain.DeviceAuthorizationDenied:
+		m.remove(deviceCode)
+		return "", nil, domain.ErrDeviceAccessDenied
+	case domain.DeviceAuthorizationPending:
+		if !req.LastPollAt.IsZero() && now.Sub(req.LastPollAt) < req.Interval {
+			req.LastPollAt = now
+			req.Interval += 5 * time.Second // as required by RFC 8628, section 3.5
+			return "", nil, domain.ErrDeviceSlowDown
+		}
+		req.LastPollAt = now
+		return "", nil, domain.ErrDeviceAuthorizationPending
+	}
+
+	// the device code proves the approval of the user, so the config is loaded with system privileges
+	sysCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+	cfgReader, err := m.configFiles.GetPeerConfig(sysCtx, req.PeerIdentifier)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load peer config: %w", err)
+	}
+	cfgData, err := io.ReadAll(cfgReader)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read peer config: %w", err)
+	}
+
+	peerId := req.PeerIdentifier
+	m.remove(deviceCode)
+
+	return peerId, cfgData, nil
+}
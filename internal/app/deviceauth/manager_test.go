@@ -0,0 +1,160 @@
+package deviceauth
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakePeerManager struct {
+	peers map[domain.PeerIdentifier]domain.Peer
+}
+
+func (f *fakePeerManager) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &peer, nil
+}
+
+func (f *fakePeerManager) PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
+	return &domain.Peer{
+		Identifier:          "prepared",
+		InterfaceIdentifier: id,
+		UserIdentifier:      domain.GetUserInfo(ctx).Id,
+	}, nil
+}
+
+func (f *fakePeerManager) CreatePeer(_ context.Context, p *domain.Peer) (*domain.Peer, error) {
+	f.peers[p.Identifier] = *p
+	return p, nil
+}
+
+type fakeConfigFiles struct{}
+
+func (fakeConfigFiles) GetPeerConfig(_ context.Context, id domain.PeerIdentifier) (io.Reader, error) {
+	return bytes.NewBufferString("config of " + string(id)), nil
+}
+
+func newTestManager(t *testing.T) (*Manager, *fakePeerManager) {
+	cfg := &config.Config{}
+	cfg.Auth.DeviceAuthorization = config.DeviceAuthorizationConfig{
+		Enabled:      true,
+		CodeLifetime: time.Minute,
+		PollInterval: time.Hour, // ensure the second poll triggers slow_down
+	}
+	peers := &fakePeerManager{peers: map[domain.PeerIdentifier]domain.Peer{}}
+	m, err := NewDeviceAuthManager(cfg, peers, fakeConfigFiles{})
+	require.NoError(t, err)
+
+	return m, peers
+}
+
+func userContext(id domain.UserIdentifier) context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: id})
+}
+
+func TestManager_approveFlow(t *testing.T) {
+	m, peers := newTestManager(t)
+	publicKey := "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="
+
+	req, err := m.RequestAuthorization(context.Background(), "raspberry", "wg0", publicKey)
+	require.NoError(t, err)
+	assert.Len(t, req.UserCode, domain.UserCodeLength)
+	assert.NotEmpty(t, req.DeviceCode)
+
+	_, _, err = m.PollAuthorization(context.Background(), req.DeviceCode)
+	assert.ErrorIs(t, err, domain.ErrDeviceAuthorizationPending)
+	_, _, err = m.PollAuthorization(context.Background(), req.DeviceCode)
+	assert.ErrorIs(t, err, domain.ErrDeviceSlowDown)
+
+	ctx := userContext("alice")
+	pending, err := m.GetPendingAuthorization(ctx, req.FormattedUserCode())
+	require.NoError(t, err)
+	assert.Equal(t, "raspberry", pending.ClientName)
+
+	approved, err := m.ApproveAuthorization(ctx, req.FormattedUserCode(), "", "")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerIdentifier(publicKey), approved.PeerIdentifier)
+
+	peer := peers.peers[domain.PeerIdentifier(publicKey)]
+	assert.Equal(t, "raspberry", peer.DisplayName)
+	assert.Equal(t, domain.UserIdentifier("alice"), peer.UserIdentifier)
+	assert.Equal(t, domain.InterfaceIdentifier("wg0"), peer.InterfaceIdentifier)
+	assert.Empty(t, peer.Interface.PrivateKey)
+
+	// approved codes cannot be approved again
+	_, err = m.ApproveAuthorization(ctx, req.UserCode, "", "")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	peerId, cfgData, err := m.PollAuthorization(context.Background(), req.DeviceCode)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerIdentifier(publicKey), peerId)
+	assert.Equal(t, "config of "+publicKey, string(cfgData))
+
+	// the device code is single use
+	_, _, err = m.PollAuthorization(context.Background(), req.DeviceCode)
+	assert.ErrorIs(t, err, domain.ErrDeviceExpiredToken)
+}
+
+func TestManager_approveExistingPeer(t *testing.T) {
+	m, peers := newTestManager(t)
+	peers.peers["bob-peer"] = domain.Peer{Identifier: "bob-peer", UserIdentifier: "bob"}
+
+	req, err := m.RequestAuthorization(context.Background(), "", "", "")
+	require.NoError(t, err)
+
+	_, err = m.ApproveAuthorization(userContext("alice"), req.UserCode, "", "bob-peer")
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "peers of other users must not be handed out")
+
+	_, err = m.ApproveAuthorization(userContext("alice"), req.UserCode, "", "")
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "new peers require an interface")
+
+	approved, err := m.ApproveAuthorization(userContext("bob"), req.UserCode, "", "bob-peer")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerIdentifier("bob-peer"), approved.PeerIdentifier)
+}
+
+func TestManager_denyAndExpire(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	req, err := m.RequestAuthorization(context.Background(), "", "", "")
+	require.NoError(t, err)
+	require.NoError(t, m.DenyAuthorization(userContext("alice"), req.UserCode))
+	_, _, err = m.PollAuthorization(context.Background(), req.DeviceCode)
+	assert.ErrorIs(t, err, domain.ErrDeviceAccessDenied)
+
+	req, err = m.RequestAuthorization(context.Background(), "", "", "")
+	require.NoError(t, err)
+	m.mux.Lock()
+	m.pruneExpired(time.Now().Add(2 * time.Minute))
+	m.mux.Unlock()
+	_, err = m.GetPendingAuthorization(userContext("alice"), req.UserCode)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, _, err = m.PollAuthorization(context.Background(), req.DeviceCode)
+	assert.ErrorIs(t, err, domain.ErrDeviceExpiredToken)
+}
+
+func TestManager_disabled(t *testing.T) {
+	m, err := NewDeviceAuthManager(&config.Config{}, &fakePeerManager{}, fakeConfigFiles{})
+	require.NoError(t, err)
+
+	_, err = m.RequestAuthorization(context.Background(), "", "", "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_invalidPublicKey(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	_, err := m.RequestAuthorization(context.Background(), "", "", "not-a-key")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
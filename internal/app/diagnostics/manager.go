@@ -0,0 +1,181 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// GetInterfaceAndPeers returns the interface with the given id and all peers associated with it.
+	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
+	// GetPeersStats returns the stats for the given peer ids.
+	GetPeersStats(ctx context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error)
+}
+
+type PeerManager interface {
+	// SuggestPeerMtu probes the path to the endpoint of the given peer.
+	SuggestPeerMtu(ctx context.Context, id domain.PeerIdentifier) (*domain.PathMtu, error)
+}
+
+type InterfaceController interface {
+	// GetPeers returns the peers that are configured on the given WireGuard interface.
+	GetPeers(_ context.Context, deviceId domain.InterfaceIdentifier) ([]domain.PhysicalPeer, error)
+}
+
+// endregion dependencies
+
+// Manager diagnoses the connection of peers. It combines the stored configuration, the state of the WireGuard
+// interface and the peer statistics to a ranked list of likely causes for connection problems.
+type Manager struct {
+	cfg *config.Config
+
+	db    DatabaseRepo
+	wg    InterfaceController
+	peers PeerManager
+
+	resolve func(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+// NewDiagnosticsManager creates a new connection diagnostics manager.
+func NewDiagnosticsManager(
+	cfg *config.Config,
+	db DatabaseRepo,
+	wg InterfaceController,
+	peers PeerManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+
+		db:    db,
+		wg:    wg,
+		peers: peers,
+
+		resolve: func(ctx context.Context, host string) ([]netip.Addr, error) {
+			return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		},
+	}
+
+	return m, nil
+}
+
+// DiagnosePeer runs all connection checks for the given peer. The findings are ranked, the most likely cause of
+// connection problems comes first.
+func (m Manager) DiagnosePeer(ctx context.Context, id domain.PeerIdentifier) (*domain.PeerDiagnosis, error) {
+	peer, err := m.db.GetPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find peer %s: %w", id, err)
+	}
+	iface, peers, err := m.db.GetInterfaceAndPeers(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
+
+	var status *domain.PeerStatus
+	if stats, err := m.db.GetPeersStats(ctx, id); err == nil && len(stats) > 0 {
+		status = &stats[0]
+	}
+
+	now := time.Now()
+	diagnosis := &domain.PeerDiagnosis{
+		PeerId:      peer.Identifier,
+		InterfaceId: iface.Identifier,
+		CheckedAt:   now,
+	}
+
+	physical, physicalKnown := m.getPhysicalPeer(ctx, iface, peer)
+
+	diagnosis.Add(
+		domain.DiagnosePeerState(peer, iface),
+		domain.DiagnosePeerKeys(peer, iface, physical, physicalKnown),
+		domain.DiagnosePeerAllowedIPs(peer, peers),
+		domain.DiagnosePeerHandshake(status, now, m.cfg.Statistics.UsePingChecks),
+		m.diagnoseEndpoint(ctx, iface, peer),
+		m.diagnoseMtu(ctx, iface, peer),
+		domain.DiagnosePeerClock(status, now),
+	)
+	diagnosis.Rank()
+
+	return diagnosis, nil
+}
+
+// getPhysicalPeer returns the state of the peer on the WireGuard interface. The second return value is false if the
+// state of the interface could not be loaded.
+func (m Manager) getPhysicalPeer(
+	ctx context.Context,
+	iface *domain.Interface,
+	peer *domain.Peer,
+) (*domain.PhysicalPeer, bool) {
+	if iface.IsDisabled() {
+		return nil, false
+	}
+
+	physicalPeers, err := m.wg.GetPeers(ctx, iface.Identifier)
+	if err != nil {
+		slog.Debug("failed to load peers of WireGuard interface", "interface", iface.Identifier, "error", err)
+		return nil, false
+	}
+	for _, physicalPeer := range physicalPeers {
+		if physicalPeer.Identifier == peer.Identifier {
+			return &physicalPeer, true
+		}
+	}
+
+	return nil, true
+}
+
+// diagnoseEndpoint resolves the endpoint of the peer configuration and checks it.
+func (m Manager) diagnoseEndpoint(
+	ctx context.Context,
+	iface *domain.Interface,
+	peer *domain.Peer,
+) domain.DiagnosisFinding {
+	endpoint := peer.Endpoint.GetValue()
+	listenPort := 0
+	if iface.Type == domain.InterfaceTypeServer {
+		listenPort = iface.ListenPort
+	}
+
+	var addrs []netip.Addr
+	var resolveErr error
+	if host, _, err := net.SplitHostPort(endpoint); err == nil {
+		if addr, err := netip.ParseAddr(host); err == nil {
+			addrs = []netip.Addr{addr.Unmap()}
+		} else {
+			addrs, resolveErr = m.resolve(ctx, host)
+		}
+	}
+
+	return domain.DiagnoseEndpoint(endpoint, addrs, resolveErr, listenPort)
+}
+
+// diagnoseMtu probes the path to the peer and checks the tunnel MTU.
+func (m Manager) diagnoseMtu(
+	ctx context.Context,
+	iface *domain.Interface,
+	peer *domain.Peer,
+) domain.DiagnosisFinding {
+	pathMtu, err := m.peers.SuggestPeerMtu(ctx, peer.Identifier)
+	if err != nil {
+		slog.Debug("failed to probe path mtu for diagnosis", "peer", peer.Identifier, "error", err)
+		pathMtu = nil
+	}
+
+	return domain.DiagnosePeerMtu(peer, iface, pathMtu)
+}
@@ -0,0 +1,136 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	iface domain.Interface
+	peers []domain.Peer
+	stats []domain.PeerStatus
+}
+
+func (f *fakeDatabase) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	for _, peer := range f.peers {
+		if peer.Identifier == id {
+			return &peer, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeDatabase) GetInterfaceAndPeers(_ context.Context, _ domain.InterfaceIdentifier) (
+	*domain.Interface,
+	[]domain.Peer,
+	error,
+) {
+	return &f.iface, f.peers, nil
+}
+
+func (f *fakeDatabase) GetPeersStats(_ context.Context, _ ...domain.PeerIdentifier) ([]domain.PeerStatus, error) {
+	return f.stats, nil
+}
+
+type fakeController struct {
+	peers []domain.PhysicalPeer
+	err   error
+}
+
+func (f *fakeController) GetPeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.PhysicalPeer, error) {
+	return f.peers, f.err
+}
+
+type fakePeerManager struct{}
+
+func (f *fakePeerManager) SuggestPeerMtu(_ context.Context, _ domain.PeerIdentifier) (*domain.PathMtu, error) {
+	return &domain.PathMtu{LinkName: "eth0", LinkMtu: 1500, Mtu: 1500}, nil
+}
+
+func newTestManager(t *testing.T, db *fakeDatabase, wg *fakeController) *Manager {
+	m, err := NewDiagnosticsManager(&config.Config{}, db, wg, &fakePeerManager{})
+	require.NoError(t, err)
+	m.resolve = func(_ context.Context, host string) ([]netip.Addr, error) {
+		if host == "vpn.example.com" {
+			return []netip.Addr{netip.MustParseAddr("203.0.113.1")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	return m
+}
+
+func testSetup(t *testing.T) *fakeDatabase {
+	serverKeys, err := domain.NewFreshKeypair()
+	require.NoError(t, err)
+	peerKeys, err := domain.NewFreshKeypair()
+	require.NoError(t, err)
+
+	address, _ := domain.CidrFromString("10.11.12.2/24")
+	handshake := time.Now().Add(-time.Minute)
+	return &fakeDatabase{
+		iface: domain.Interface{Identifier: "wg0", Type: domain.InterfaceTypeServer, KeyPair: serverKeys,
+			ListenPort: 51820, Mtu: 1420},
+		peers: []domain.Peer{{
+			Identifier:          domain.PeerIdentifier(peerKeys.PublicKey),
+			InterfaceIdentifier: "wg0",
+			Endpoint:            domain.NewConfigOption("vpn.example.com:51820", true),
+			EndpointPublicKey:   domain.NewConfigOption(serverKeys.PublicKey, true),
+			Interface: domain.PeerInterfaceConfig{
+				KeyPair:   peerKeys,
+				Type:      domain.InterfaceTypeClient,
+				Addresses: []domain.Cidr{address},
+			},
+		}},
+		stats: []domain.PeerStatus{{PeerId: domain.PeerIdentifier(peerKeys.PublicKey), LastHandshake: &handshake}},
+	}
+}
+
+func TestManager_DiagnosePeer(t *testing.T) {
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	db := testSetup(t)
+	peerId := db.peers[0].Identifier
+
+	m := newTestManager(t, db, &fakeController{peers: []domain.PhysicalPeer{{Identifier: peerId}}})
+	diagnosis, err := m.DiagnosePeer(adminCtx, peerId)
+	require.NoError(t, err)
+	assert.Len(t, diagnosis.Findings, 7)
+	assert.Empty(t, diagnosis.LikelyCauses(), "a healthy peer has no likely causes")
+
+	// the peer is missing on the WireGuard interface and the endpoint cannot be resolved
+	db.peers[0].Endpoint = domain.NewConfigOption("unknown.example.com:51820", true)
+	m = newTestManager(t, db, &fakeController{})
+	diagnosis, err = m.DiagnosePeer(adminCtx, peerId)
+	require.NoError(t, err)
+	causes := diagnosis.LikelyCauses()
+	require.Len(t, causes, 2)
+	assert.Equal(t, domain.DiagnosisCheckKeys, causes[0].Check)
+	assert.Equal(t, domain.DiagnosisCheckEndpoint, causes[1].Check)
+
+	// the state of the interface is unknown
+	m = newTestManager(t, db, &fakeController{err: errors.New("no such device")})
+	diagnosis, err = m.DiagnosePeer(adminCtx, peerId)
+	require.NoError(t, err)
+	assert.Len(t, diagnosis.LikelyCauses(), 1)
+}
+
+func TestManager_DiagnosePeer_AccessRights(t *testing.T) {
+	db := testSetup(t)
+	m := newTestManager(t, db, &fakeController{})
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user"})
+	_, err := m.DiagnosePeer(userCtx, db.peers[0].Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	_, err = m.DiagnosePeer(adminCtx, "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
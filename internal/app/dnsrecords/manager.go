@@ -0,0 +1,334 @@
+package dnsrecords
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers for a given interface
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	// GetDnsRecordSets returns all DNS record sets that are managed by WireGuard Portal
+	GetDnsRecordSets(ctx context.Context) ([]domain.DnsRecordSet, error)
+	// GetPeerDnsRecordSets returns all DNS record sets that belong to the given peer
+	GetPeerDnsRecordSets(ctx context.Context, id domain.PeerIdentifier) ([]domain.DnsRecordSet, error)
+	// SaveDnsRecordSet creates or updates the given DNS record set
+	SaveDnsRecordSet(ctx context.Context, record *domain.DnsRecordSet) error
+	// DeleteDnsRecordSet deletes the DNS record set with the given name and type
+	DeleteDnsRecordSet(ctx context.Context, name string, recordType domain.DnsRecordType) error
+}
+
+type DnsProvider interface {
+	// ReplaceRecordSet creates the given record set or replaces all records of an existing record set
+	ReplaceRecordSet(ctx context.Context, record domain.DnsRecordSet) error
+	// DeleteRecordSet removes the given record set
+	DeleteRecordSet(ctx context.Context, record domain.DnsRecordSet) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager keeps the A, AAAA and PTR records of peer tunnel addresses in sync with the configured DNS provider.
+type Manager struct {
+	cfg *config.Config
+
+	bus      EventBus
+	db       DatabaseRepo
+	provider DnsProvider
+
+	mux *sync.Mutex // serializes record updates, events are delivered concurrently
+}
+
+// NewDnsRecordManager creates a new DNS record manager instance.
+func NewDnsRecordManager(cfg *config.Config, bus EventBus, db DatabaseRepo, provider DnsProvider) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:       db,
+		provider: provider,
+
+		mux: &sync.Mutex{},
+	}
+
+	if cfg.DnsRecords.Enabled {
+		m.connectToMessageBus()
+	}
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerCreated, m.handlePeerChangedEvent)
+	_ = m.bus.Subscribe(app.TopicPeerUpdated, m.handlePeerChangedEvent)
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerDeletedEvent)
+	_ = m.bus.Subscribe(app.TopicPeerIdentifierUpdated, m.handlePeerIdentifierUpdatedEvent)
+}
+
+// StartBackgroundJobs starts background jobs for the DNS record manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.DnsRecords.Enabled {
+		return
+	}
+
+	go func() {
+		if err := m.synchronizeAll(ctx); err != nil {
+			slog.Error("failed to synchronize DNS records", "error", err)
+		}
+	}()
+}
+
+func (m Manager) handlePeerChangedEvent(peer domain.Peer) {
+	slog.Debug("handling peer changed event", "peer", peer.Identifier)
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if err := m.synchronizePeer(context.Background(), &peer, nil); err != nil {
+		slog.Error("failed to synchronize DNS records", "peer", peer.Identifier, "error", err)
+	}
+}
+
+func (m Manager) handlePeerDeletedEvent(peer domain.Peer) {
+	slog.Debug("handling peer deleted event", "peer", peer.Identifier)
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if err := m.removePeerRecords(context.Background(), peer.Identifier); err != nil {
+		slog.Error("failed to remove DNS records", "peer", peer.Identifier, "error", err)
+	}
+}
+
+func (m Manager) handlePeerIdentifierUpdatedEvent(oldId, newId domain.PeerIdentifier) {
+	slog.Debug("handling peer identifier updated event", "oldPeer", oldId, "newPeer", newId)
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	ctx := context.Background()
+	records, err := m.db.GetPeerDnsRecordSets(ctx, oldId)
+	if err != nil {
+		slog.Error("failed to load DNS records", "peer", oldId, "error", err)
+		return
+	}
+
+	for i := range records {
+		records[i].PeerId = newId
+		if err := m.db.SaveDnsRecordSet(ctx, &records[i]); err != nil {
+			slog.Error("failed to update DNS record owner", "record", records[i].Name, "peer", newId, "error", err)
+		}
+	}
+}
+
+// ApplyState synchronizes the records of all peers and removes records of peers that no longer exist.
+func (m Manager) ApplyState(ctx context.Context) error {
+	if !m.cfg.DnsRecords.Enabled {
+		return nil
+	}
+
+	return m.synchronizeAll(ctx)
+}
+
+// synchronizeAll synchronizes the records of all peers and removes records of peers that no longer exist.
+func (m Manager) synchronizeAll(ctx context.Context) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	stored, err := m.db.GetDnsRecordSets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load DNS records: %w", err)
+	}
+
+	existingPeers := make(map[domain.PeerIdentifier]struct{})
+	for _, iface := range interfaces {
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			return fmt.Errorf("failed to load peers for %s: %w", iface.Identifier, err)
+		}
+
+		for i := range peers {
+			existingPeers[peers[i].Identifier] = struct{}{}
+			if err := m.synchronizePeer(ctx, &peers[i], nil); err != nil {
+				slog.Error("failed to synchronize DNS records", "peer", peers[i].Identifier, "error", err)
+			}
+		}
+	}
+
+	for _, record := range stored {
+		if _, ok := existingPeers[record.PeerId]; ok {
+			continue
+		}
+		if err := m.removeRecordSet(ctx, record); err != nil {
+			slog.Error("failed to remove orphaned DNS record", "record", record.String(), "error", err)
+		}
+	}
+
+	return nil
+}
+
+// synchronizePeer creates, updates or removes the record sets of the given peer.
+// If stored is nil, the currently stored record sets are loaded from the database.
+func (m Manager) synchronizePeer(ctx context.Context, peer *domain.Peer, stored []domain.DnsRecordSet) error {
+	if stored == nil {
+		var err error
+		stored, err = m.db.GetDnsRecordSets(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load DNS records: %w", err)
+		}
+	}
+
+	storedByKey := make(map[string]domain.DnsRecordSet, len(stored))
+	for _, record := range stored {
+		storedByKey[recordKey(record)] = record
+	}
+
+	desired := m.desiredRecords(peer)
+	desiredKeys := make(map[string]struct{}, len(desired))
+	for _, record := range desired {
+		key := recordKey(record)
+		desiredKeys[key] = struct{}{}
+
+		existing, exists := storedByKey[key]
+		if exists && existing.PeerId != peer.Identifier {
+			slog.Warn("DNS record is already owned by another peer, skipping",
+				"record", record.Name, "type", record.Type, "peer", peer.Identifier, "owner", existing.PeerId)
+			continue
+		}
+		if exists && existing.Equals(record) {
+			continue
+		}
+
+		if err := m.provider.ReplaceRecordSet(ctx, record); err != nil {
+			return fmt.Errorf("failed to update record %s: %w", record.String(), err)
+		}
+		if err := m.db.SaveDnsRecordSet(ctx, &record); err != nil {
+			return fmt.Errorf("failed to save record %s: %w", record.String(), err)
+		}
+		slog.Debug("updated DNS record", "record", record.String(), "peer", peer.Identifier)
+	}
+
+	for _, record := range stored {
+		if record.PeerId != peer.Identifier {
+			continue
+		}
+		if _, ok := desiredKeys[recordKey(record)]; ok {
+			continue
+		}
+		if err := m.removeRecordSet(ctx, record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m Manager) removePeerRecords(ctx context.Context, id domain.PeerIdentifier) error {
+	records, err := m.db.GetPeerDnsRecordSets(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to load DNS records: %w", err)
+	}
+
+	for _, record := range records {
+		if err := m.removeRecordSet(ctx, record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m Manager) removeRecordSet(ctx context.Context, record domain.DnsRecordSet) error {
+	if err := m.provider.DeleteRecordSet(ctx, record); err != nil {
+		return fmt.Errorf("failed to delete record %s: %w", record.String(), err)
+	}
+	if err := m.db.DeleteDnsRecordSet(ctx, record.Name, record.Type); err != nil {
+		return fmt.Errorf("failed to remove record %s: %w", record.String(), err)
+	}
+	slog.Debug("removed DNS record", "record", record.String(), "peer", record.PeerId)
+
+	return nil
+}
+
+// desiredRecords returns the record sets that should exist for the given peer.
+// Disabled peers and peers that represent a remote server endpoint do not get any records.
+func (m Manager) desiredRecords(peer *domain.Peer) []domain.DnsRecordSet {
+	if m.cfg.DnsRecords.Zone == "" || peer.IsDisabled() || peer.Interface.Type == domain.InterfaceTypeServer {
+		return nil
+	}
+
+	ttl := uint32(m.cfg.DnsRecords.TTL / time.Second)
+	fqdn := domain.DnsFqdn(peer.DnsHostname(), m.cfg.DnsRecords.Zone)
+
+	var v4, v6 []string
+	var ptrs []domain.DnsRecordSet
+	for _, cidr := range peer.Interface.Addresses {
+		addr := cidr.Prefix().Addr()
+		if !addr.IsValid() {
+			continue
+		}
+		if addr.Is4() {
+			v4 = append(v4, addr.String())
+		} else {
+			v6 = append(v6, addr.String())
+		}
+
+		ptrName := domain.ReverseDnsName(addr)
+		for _, zone := range m.cfg.DnsRecords.ReverseZones {
+			if !domain.DnsNameInZone(ptrName, zone) {
+				continue
+			}
+			ptrs = append(ptrs, domain.DnsRecordSet{
+				Name:      ptrName,
+				Type:      domain.DnsRecordTypePTR,
+				Zone:      domain.DnsFqdn("", zone),
+				PeerId:    peer.Identifier,
+				TTL:       ttl,
+				ValuesStr: fqdn,
+			})
+			break
+		}
+	}
+
+	var records []domain.DnsRecordSet
+	zone := domain.DnsFqdn("", m.cfg.DnsRecords.Zone)
+	if len(v4) > 0 {
+		sort.Strings(v4)
+		records = append(records, domain.DnsRecordSet{Name: fqdn, Type: domain.DnsRecordTypeA, Zone: zone,
+			PeerId: peer.Identifier, TTL: ttl, ValuesStr: strings.Join(v4, ",")})
+	}
+	if len(v6) > 0 {
+		sort.Strings(v6)
+		records = append(records, domain.DnsRecordSet{Name: fqdn, Type: domain.DnsRecordTypeAAAA, Zone: zone,
+			PeerId: peer.Identifier, TTL: ttl, ValuesStr: strings.Join(v6, ",")})
+	}
+
+	return append(records, ptrs...)
+}
+
+func recordKey(record domain.DnsRecordSet) string {
+	return record.Name + "/" + string(record.Type)
+}
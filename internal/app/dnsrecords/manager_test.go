@@ -0,0 +1,79 @@
+package dnsrecords
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func testManager() Manager {
+	cfg := &config.Config{}
+	cfg.DnsRecords.Enabled = true
+	cfg.DnsRecords.Zone = "vpn.example.com"
+	cfg.DnsRecords.ReverseZones = []string{"12.11.10.in-addr.arpa"}
+	cfg.DnsRecords.TTL = 5 * time.Minute
+
+	return Manager{cfg: cfg}
+}
+
+func testPeer(addresses ...string) domain.Peer {
+	peer := domain.Peer{
+		Identifier:  "peer-a",
+		DisplayName: "Laptop",
+		Interface:   domain.PeerInterfaceConfig{Type: domain.InterfaceTypeClient},
+	}
+	for _, address := range addresses {
+		cidr, _ := domain.CidrFromString(address)
+		peer.Interface.Addresses = append(peer.Interface.Addresses, cidr)
+	}
+
+	return peer
+}
+
+func TestManager_desiredRecords(t *testing.T) {
+	m := testManager()
+	peer := testPeer("10.11.12.2/32", "fdfd:d3ad::2/128", "10.99.0.2/32")
+
+	records := m.desiredRecords(&peer)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, domain.DnsRecordSet{
+			Name:      "laptop.vpn.example.com.",
+			Type:      domain.DnsRecordTypeA,
+			Zone:      "vpn.example.com.",
+			PeerId:    "peer-a",
+			TTL:       300,
+			ValuesStr: "10.11.12.2,10.99.0.2",
+		}, records[0])
+		assert.Equal(t, domain.DnsRecordTypeAAAA, records[1].Type)
+		assert.Equal(t, "fdfd:d3ad::2", records[1].ValuesStr)
+		assert.Equal(t, domain.DnsRecordSet{
+			Name:      "2.12.11.10.in-addr.arpa.",
+			Type:      domain.DnsRecordTypePTR,
+			Zone:      "12.11.10.in-addr.arpa.",
+			PeerId:    "peer-a",
+			TTL:       300,
+			ValuesStr: "laptop.vpn.example.com.",
+		}, records[2])
+	}
+}
+
+func TestManager_desiredRecords_Skipped(t *testing.T) {
+	m := testManager()
+
+	now := time.Now()
+	disabled := testPeer("10.11.12.2/32")
+	disabled.Disabled = &now
+	assert.Empty(t, m.desiredRecords(&disabled))
+
+	server := testPeer("10.11.12.2/32")
+	server.Interface.Type = domain.InterfaceTypeServer
+	assert.Empty(t, m.desiredRecords(&server))
+
+	noZone := testPeer("10.11.12.2/32")
+	m.cfg.DnsRecords.Zone = ""
+	assert.Empty(t, m.desiredRecords(&noZone))
+}
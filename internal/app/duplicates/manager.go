@@ -0,0 +1,151 @@
+package duplicates
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetInterfacePeers returns all peers for the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// GetPeersStats returns the stats for the given peer ids.
+	GetPeersStats(ctx context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error)
+	// GetAllConfigPullTokens returns all config pull tokens.
+	GetAllConfigPullTokens(ctx context.Context) ([]domain.ConfigPullToken, error)
+}
+
+type PeerManager interface {
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+}
+
+// endregion dependencies
+
+// Manager detects peers that likely belong to the same device and merges them. Peers are considered duplicates if
+// they belong to the same user and interface and share the device name, see domain.FindDuplicatePeers. Merging
+// keeps one peer and archives the others, archived peers are disabled and can be restored by an admin.
+type Manager struct {
+	db    DatabaseRepo
+	peers PeerManager
+}
+
+// NewDuplicateManager creates a new duplicate peer manager.
+func NewDuplicateManager(db DatabaseRepo, peers PeerManager) (*Manager, error) {
+	m := &Manager{
+		db:    db,
+		peers: peers,
+	}
+
+	return m, nil
+}
+
+// GetDuplicates returns all groups of likely duplicate peers of the given interface.
+func (m Manager) GetDuplicates(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.DuplicatePeerGroup, error) {
+	if err := m.validateInterfaceAccess(ctx, id); err != nil {
+		return nil, err
+	}
+
+	peers, err := m.db.GetInterfacePeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peers of interface %s: %w", id, err)
+	}
+
+	peerIds := make([]domain.PeerIdentifier, len(peers))
+	for i, peer := range peers {
+		peerIds[i] = peer.Identifier
+	}
+	stats, err := m.db.GetPeersStats(ctx, peerIds...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer stats of interface %s: %w", id, err)
+	}
+	statsMap := make(map[domain.PeerIdentifier]domain.PeerStatus, len(stats))
+	for _, s := range stats {
+		statsMap[s.PeerId] = s
+	}
+
+	tokens, err := m.db.GetAllConfigPullTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config pull tokens: %w", err)
+	}
+	clientOs := make(map[domain.PeerIdentifier]string, len(tokens))
+	for _, token := range tokens {
+		if token.ClientOs != "" {
+			clientOs[token.PeerId] = token.ClientOs
+		}
+	}
+
+	return domain.FindDuplicatePeers(peers, statsMap, clientOs), nil
+}
+
+// MergePeers keeps the given peer and archives the duplicates. All peers must belong to the same user and
+// interface. The archived peers are updated one after another, the updated duplicates are returned even if
+// archiving one of them fails.
+func (m Manager) MergePeers(
+	ctx context.Context,
+	keepId domain.PeerIdentifier,
+	duplicateIds []domain.PeerIdentifier,
+) ([]domain.Peer, error) {
+	keep, err := m.db.GetPeer(ctx, keepId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer %s: %w", keepId, err)
+	}
+	if err := m.validateInterfaceAccess(ctx, keep.InterfaceIdentifier); err != nil {
+		return nil, err
+	}
+	if len(duplicateIds) == 0 || slices.Contains(duplicateIds, keepId) {
+		return nil, fmt.Errorf("the duplicates must not contain the kept peer: %w", domain.ErrInvalidData)
+	}
+
+	duplicates := make([]*domain.Peer, len(duplicateIds))
+	for i, id := range duplicateIds {
+		duplicate, err := m.db.GetPeer(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load peer %s: %w", id, err)
+		}
+		if duplicate.InterfaceIdentifier != keep.InterfaceIdentifier ||
+			duplicate.UserIdentifier != keep.UserIdentifier {
+			return nil, fmt.Errorf("peer %s does not belong to the same user and interface as %s: %w", id, keepId,
+				domain.ErrInvalidData)
+		}
+		duplicates[i] = duplicate
+	}
+
+	now := time.Now()
+	archived := make([]domain.Peer, 0, len(duplicates))
+	for _, duplicate := range duplicates {
+		duplicate.ArchiveAsDuplicate(keep, now)
+
+		updated, err := m.peers.UpdatePeer(ctx, duplicate)
+		if err != nil {
+			return archived, fmt.Errorf("failed to archive peer %s: %w", duplicate.Identifier, err)
+		}
+		archived = append(archived, *updated)
+	}
+
+	slog.Info("merged duplicate peers", "peer", keepId, "archived", duplicateIds)
+
+	return archived, nil
+}
+
+func (m Manager) validateInterfaceAccess(ctx context.Context, id domain.InterfaceIdentifier) error {
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to load interface %s: %w", id, err)
+	}
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return err
+	}
+
+	return domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier)
+}
@@ -0,0 +1,127 @@
+package duplicates
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	interfaces []domain.Interface
+	peers      []domain.Peer
+	tokens     []domain.ConfigPullToken
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	for _, iface := range f.interfaces {
+		if iface.Identifier == id {
+			return &iface, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	var peers []domain.Peer
+	for _, peer := range f.peers {
+		if peer.InterfaceIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+func (f *fakeDatabase) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	for _, peer := range f.peers {
+		if peer.Identifier == id {
+			return &peer, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeDatabase) GetPeersStats(_ context.Context, _ ...domain.PeerIdentifier) ([]domain.PeerStatus, error) {
+	return nil, nil
+}
+
+func (f *fakeDatabase) GetAllConfigPullTokens(_ context.Context) ([]domain.ConfigPullToken, error) {
+	return f.tokens, nil
+}
+
+type fakePeerManager struct {
+	updated []domain.Peer
+}
+
+func (f *fakePeerManager) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	f.updated = append(f.updated, *peer)
+	return peer, nil
+}
+
+func newTestDatabase() *fakeDatabase {
+	return &fakeDatabase{
+		interfaces: []domain.Interface{{Identifier: "wg0", OrganizationIdentifier: "acme"}},
+		peers: []domain.Peer{
+			{Identifier: "a1", InterfaceIdentifier: "wg0", UserIdentifier: "alice", DisplayName: "Laptop"},
+			{Identifier: "a2", InterfaceIdentifier: "wg0", UserIdentifier: "alice", DisplayName: "Laptop 2"},
+			{Identifier: "a3", InterfaceIdentifier: "wg0", UserIdentifier: "alice", DisplayName: "Laptop 3"},
+			{Identifier: "b1", InterfaceIdentifier: "wg0", UserIdentifier: "bob", DisplayName: "Laptop"},
+		},
+		tokens: []domain.ConfigPullToken{
+			{PeerId: "a1", ClientOs: domain.ClientOsWindows},
+			{PeerId: "a3", ClientOs: domain.ClientOsMacOs},
+		},
+	}
+}
+
+func TestManager_GetDuplicates(t *testing.T) {
+	m, err := NewDuplicateManager(newTestDatabase(), &fakePeerManager{})
+	require.NoError(t, err)
+
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	groups, err := m.GetDuplicates(adminCtx, "wg0")
+	require.NoError(t, err)
+	assert.Empty(t, groups, "the reported operating systems differ, a2 cannot be assigned to one of the devices")
+
+	m.db.(*fakeDatabase).tokens = m.db.(*fakeDatabase).tokens[:1]
+	groups, err = m.GetDuplicates(adminCtx, "wg0")
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, domain.ClientOsWindows, groups[0].Os)
+	assert.Len(t, groups[0].Peers, 3)
+
+	otherOrgCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{
+		Id:           "other-admin",
+		IsAdmin:      true,
+		Organization: "other",
+	})
+	_, err = m.GetDuplicates(otherOrgCtx, "wg0")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_MergePeers(t *testing.T) {
+	peers := &fakePeerManager{}
+	m, err := NewDuplicateManager(newTestDatabase(), peers)
+	require.NoError(t, err)
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	archived, err := m.MergePeers(adminCtx, "a1", []domain.PeerIdentifier{"a2", "a3"})
+	require.NoError(t, err)
+	require.Len(t, archived, 2)
+	assert.Equal(t, domain.DisabledReasonArchived, archived[0].DisabledReason)
+	assert.Contains(t, archived[1].Notes, "Archived as duplicate of Laptop (a1)")
+	assert.Len(t, peers.updated, 2)
+
+	_, err = m.MergePeers(adminCtx, "a1", []domain.PeerIdentifier{"b1"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "peers of other users cannot be merged")
+	_, err = m.MergePeers(adminCtx, "a1", []domain.PeerIdentifier{"a1"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+	assert.Len(t, peers.updated, 2)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	_, err = m.MergePeers(userCtx, "a1", []domain.PeerIdentifier{"a2"})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
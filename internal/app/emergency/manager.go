@@ -0,0 +1,343 @@
+package emergency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetUser returns the user with the given identifier.
+	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	// GetUserPeers returns all peers of the given user.
+	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetEmergencyLockdown returns the emergency lockdown with the given identifier.
+	GetEmergencyLockdown(ctx context.Context, id domain.EmergencyLockdownIdentifier) (*domain.EmergencyLockdown, error)
+	// GetEmergencyLockdowns returns all emergency lockdowns, the most recent lockdowns first.
+	GetEmergencyLockdowns(ctx context.Context) ([]domain.EmergencyLockdown, error)
+	// SaveEmergencyLockdown creates or updates the given emergency lockdown.
+	SaveEmergencyLockdown(ctx context.Context, lockdown *domain.EmergencyLockdown) error
+}
+
+type PeerManager interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+}
+
+type InterfaceManager interface {
+	// UpdateInterface updates the given interface.
+	UpdateInterface(ctx context.Context, in *domain.Interface) (*domain.Interface, []domain.Peer, error)
+}
+
+type EventBus interface {
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
+}
+
+// endregion dependencies
+
+// Manager handles emergency lockdowns. A lockdown immediately disables all peers of a user, which removes them from
+// the WireGuard interface, or disables an entire interface. The state before the lockdown is stored as snapshot, so
+// a lockdown can be reverted with a single restore. All actions are recorded in the audit log.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db         DatabaseRepo
+	peers      PeerManager
+	interfaces InterfaceManager
+}
+
+// NewEmergencyManager creates a new emergency lockdown manager.
+func NewEmergencyManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	peers PeerManager,
+	interfaces InterfaceManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:         db,
+		peers:      peers,
+		interfaces: interfaces,
+	}
+
+	return m, nil
+}
+
+// GetLockdowns returns all emergency lockdowns, the most recent lockdowns first.
+func (m Manager) GetLockdowns(ctx context.Context) ([]domain.EmergencyLockdown, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	lockdowns, err := m.db.GetEmergencyLockdowns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if domain.GetUserInfo(ctx).IsGlobalAdmin() {
+		return lockdowns, nil
+	}
+
+	filtered := make([]domain.EmergencyLockdown, 0, len(lockdowns))
+	for _, lockdown := range lockdowns {
+		org, err := m.getTargetOrganization(ctx, &lockdown)
+		if err != nil {
+			continue // targets that no longer exist are only visible to global admins
+		}
+		if domain.GetUserInfo(ctx).CanAccessOrganization(org) {
+			filtered = append(filtered, lockdown)
+		}
+	}
+
+	return filtered, nil
+}
+
+// getTargetOrganization returns the organization that owns the target of the given lockdown.
+func (m Manager) getTargetOrganization(
+	ctx context.Context,
+	lockdown *domain.EmergencyLockdown,
+) (domain.OrganizationIdentifier, error) {
+	switch lockdown.Target {
+	case domain.EmergencyTargetUser:
+		user, err := m.db.GetUser(ctx, domain.UserIdentifier(lockdown.TargetId))
+		if err != nil {
+			return "", fmt.Errorf("failed to load user %s: %w", lockdown.TargetId, err)
+		}
+		return user.OrganizationIdentifier, nil
+	case domain.EmergencyTargetInterface:
+		iface, err := m.db.GetInterface(ctx, domain.InterfaceIdentifier(lockdown.TargetId))
+		if err != nil {
+			return "", fmt.Errorf("failed to load interface %s: %w", lockdown.TargetId, err)
+		}
+		return iface.OrganizationIdentifier, nil
+	default:
+		return "", fmt.Errorf("unsupported lockdown target %q: %w", lockdown.Target, domain.ErrInvalidData)
+	}
+}
+
+// Lockdown disables all peers of the given user or the given interface. The lockdown is stored even if some peers
+// could not be disabled, the returned error contains all failures in that case.
+func (m Manager) Lockdown(
+	ctx context.Context,
+	target domain.EmergencyTarget,
+	targetId string,
+	reason string,
+) (*domain.EmergencyLockdown, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	lockdown := &domain.EmergencyLockdown{
+		Identifier: domain.EmergencyLockdownIdentifier(uuid.NewString()),
+		Target:     target,
+		TargetId:   targetId,
+		Reason:     strings.TrimSpace(reason),
+		CreatedBy:  domain.GetUserInfo(ctx).Id,
+		CreatedAt:  now,
+	}
+
+	var lockdownErr error
+	switch target {
+	case domain.EmergencyTargetUser:
+		lockdownErr = m.lockdownUser(ctx, lockdown, now)
+	case domain.EmergencyTargetInterface:
+		lockdownErr = m.lockdownInterface(ctx, lockdown, now)
+	default:
+		return nil, fmt.Errorf("unsupported lockdown target %q: %w", target, domain.ErrInvalidData)
+	}
+	if lockdownErr != nil && len(lockdown.Snapshot.Peers) == 0 && lockdown.Snapshot.Interface == "" {
+		return nil, lockdownErr // nothing was disabled, no snapshot required
+	}
+
+	if err := m.db.SaveEmergencyLockdown(ctx, lockdown); err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to store lockdown snapshot: %w", err), lockdownErr)
+	}
+
+	slog.Warn("emergency lockdown", "target", target, "id", targetId, "peers", len(lockdown.Snapshot.Peers),
+		"by", lockdown.CreatedBy)
+	m.publishAuditEvent(ctx, lockdown, "lockdown")
+
+	return lockdown, lockdownErr
+}
+
+func (m Manager) lockdownUser(ctx context.Context, lockdown *domain.EmergencyLockdown, now time.Time) error {
+	userId := domain.UserIdentifier(lockdown.TargetId)
+	user, err := m.db.GetUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to load user %s: %w", userId, err)
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, user.OrganizationIdentifier); err != nil {
+		return err
+	}
+
+	peers, err := m.db.GetUserPeers(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to load peers of user %s: %w", userId, err)
+	}
+
+	var errs []error
+	for _, peer := range peers {
+		if peer.IsDisabled() {
+			continue // already disabled peers stay disabled on restore
+		}
+
+		peer.Disabled = &now
+		peer.DisabledReason = domain.DisabledReasonEmergency
+		if _, err := m.peers.UpdatePeer(ctx, &peer); err != nil {
+			errs = append(errs, fmt.Errorf("failed to disable peer %s: %w", peer.Identifier, err))
+			continue
+		}
+		lockdown.Snapshot.Peers = append(lockdown.Snapshot.Peers, peer.Identifier)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m Manager) lockdownInterface(ctx context.Context, lockdown *domain.EmergencyLockdown, now time.Time) error {
+	interfaceId := domain.InterfaceIdentifier(lockdown.TargetId)
+	iface, err := m.db.GetInterface(ctx, interfaceId)
+	if err != nil {
+		return fmt.Errorf("failed to load interface %s: %w", interfaceId, err)
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+		return err
+	}
+	if iface.IsDisabled() {
+		return fmt.Errorf("interface %s is already disabled: %w", interfaceId, domain.ErrInvalidData)
+	}
+
+	iface.Disabled = &now
+	iface.DisabledReason = domain.DisabledReasonEmergency
+	if _, _, err := m.interfaces.UpdateInterface(ctx, iface); err != nil {
+		return fmt.Errorf("failed to disable interface %s: %w", interfaceId, err)
+	}
+	lockdown.Snapshot.Interface = interfaceId
+
+	return nil
+}
+
+// Restore reverts the given lockdown. Peers and interfaces that were modified since the lockdown, for example
+// enabled manually or deleted, are skipped.
+func (m Manager) Restore(ctx context.Context, id domain.EmergencyLockdownIdentifier) (*domain.EmergencyLockdown, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	lockdown, err := m.db.GetEmergencyLockdown(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lockdown %s: %w", id, err)
+	}
+	if !lockdown.IsActive() {
+		return nil, fmt.Errorf("lockdown %s has already been restored: %w", id, domain.ErrInvalidData)
+	}
+	if !domain.GetUserInfo(ctx).IsGlobalAdmin() {
+		org, err := m.getTargetOrganization(ctx, lockdown)
+		if err != nil {
+			return nil, err
+		}
+		if err := domain.ValidateOrganizationAccessRights(ctx, org); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, peerId := range lockdown.Snapshot.Peers {
+		if err := m.restorePeer(ctx, peerId); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if lockdown.Snapshot.Interface != "" {
+		if err := m.restoreInterface(ctx, lockdown.Snapshot.Interface); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...) // the lockdown stays active, so the restore can be retried
+	}
+
+	now := time.Now()
+	lockdown.RestoredAt = &now
+	lockdown.RestoredBy = domain.GetUserInfo(ctx).Id
+	if err := m.db.SaveEmergencyLockdown(ctx, lockdown); err != nil {
+		return nil, fmt.Errorf("failed to store restored lockdown: %w", err)
+	}
+
+	slog.Info("emergency lockdown restored", "target", lockdown.Target, "id", lockdown.TargetId,
+		"by", lockdown.RestoredBy)
+	m.publishAuditEvent(ctx, lockdown, "restore")
+
+	return lockdown, nil
+}
+
+func (m Manager) restorePeer(ctx context.Context, id domain.PeerIdentifier) error {
+	peer, err := m.peers.GetPeer(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil // the peer has been deleted or re-keyed in the meantime
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load peer %s: %w", id, err)
+	}
+	if !peer.IsDisabled() || peer.DisabledReason != domain.DisabledReasonEmergency {
+		return nil // modified since the lockdown
+	}
+
+	peer.Disabled = nil
+	peer.DisabledReason = ""
+	if _, err := m.peers.UpdatePeer(ctx, peer); err != nil {
+		return fmt.Errorf("failed to enable peer %s: %w", id, err)
+	}
+
+	return nil
+}
+
+func (m Manager) restoreInterface(ctx context.Context, id domain.InterfaceIdentifier) error {
+	iface, err := m.db.GetInterface(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil // the interface has been deleted in the meantime
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load interface %s: %w", id, err)
+	}
+	if !iface.IsDisabled() || iface.DisabledReason != domain.DisabledReasonEmergency {
+		return nil // modified since the lockdown
+	}
+
+	iface.Disabled = nil
+	iface.DisabledReason = ""
+	if _, _, err := m.interfaces.UpdateInterface(ctx, iface); err != nil {
+		return fmt.Errorf("failed to enable interface %s: %w", id, err)
+	}
+
+	return nil
+}
+
+func (m Manager) publishAuditEvent(ctx context.Context, lockdown *domain.EmergencyLockdown, action string) {
+	m.bus.Publish(app.TopicAuditEmergency, domain.AuditEventWrapper[audit.EmergencyEvent]{
+		Ctx: ctx,
+		Event: audit.EmergencyEvent{
+			Lockdown: *lockdown,
+			Action:   action,
+		},
+	})
+}
@@ -0,0 +1,252 @@
+package emergency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	interfaces map[domain.InterfaceIdentifier]domain.Interface
+	peers      map[domain.PeerIdentifier]domain.Peer
+	lockdowns  map[domain.EmergencyLockdownIdentifier]domain.EmergencyLockdown
+}
+
+func (f *fakeDatabase) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	if id != "alice" {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.User{Identifier: id, OrganizationIdentifier: "org-a"}, nil
+}
+
+func (f *fakeDatabase) GetUserPeers(_ context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
+	var peers []domain.Peer
+	for _, peer := range f.peers {
+		if peer.UserIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	iface, ok := f.interfaces[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &iface, nil
+}
+
+func (f *fakeDatabase) GetEmergencyLockdown(_ context.Context, id domain.EmergencyLockdownIdentifier) (
+	*domain.EmergencyLockdown,
+	error,
+) {
+	lockdown, ok := f.lockdowns[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &lockdown, nil
+}
+
+func (f *fakeDatabase) GetEmergencyLockdowns(_ context.Context) ([]domain.EmergencyLockdown, error) {
+	var lockdowns []domain.EmergencyLockdown
+	for _, lockdown := range f.lockdowns {
+		lockdowns = append(lockdowns, lockdown)
+	}
+	return lockdowns, nil
+}
+
+func (f *fakeDatabase) SaveEmergencyLockdown(_ context.Context, lockdown *domain.EmergencyLockdown) error {
+	f.lockdowns[lockdown.Identifier] = *lockdown
+	return nil
+}
+
+type fakePeerManager struct {
+	db   *fakeDatabase
+	fail domain.PeerIdentifier
+}
+
+func (f *fakePeerManager) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f.db.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &peer, nil
+}
+
+func (f *fakePeerManager) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	if peer.Identifier == f.fail {
+		return nil, errors.New("device busy")
+	}
+	f.db.peers[peer.Identifier] = *peer
+	return peer, nil
+}
+
+type fakeInterfaceManager struct {
+	db *fakeDatabase
+}
+
+func (f *fakeInterfaceManager) UpdateInterface(_ context.Context, in *domain.Interface) (
+	*domain.Interface,
+	[]domain.Peer,
+	error,
+) {
+	f.db.interfaces[in.Identifier] = *in
+	return in, nil, nil
+}
+
+type fakeBus struct {
+	published []string
+}
+
+func (f *fakeBus) Publish(topic string, _ ...any) {
+	f.published = append(f.published, topic)
+}
+
+func newTestManager() (*Manager, *fakeDatabase, *fakePeerManager, *fakeBus) {
+	past := time.Now().Add(-time.Hour)
+	db := &fakeDatabase{
+		interfaces: map[domain.InterfaceIdentifier]domain.Interface{"wg0": {Identifier: "wg0", OrganizationIdentifier: "org-a"},
+			"wg1": {Identifier: "wg1", OrganizationIdentifier: "org-b"},
+		},
+		peers: map[domain.PeerIdentifier]domain.Peer{
+			"laptop":   {Identifier: "laptop", UserIdentifier: "alice"},
+			"phone":    {Identifier: "phone", UserIdentifier: "alice"},
+			"disabled": {Identifier: "disabled", UserIdentifier: "alice", Disabled: &past},
+			"other":    {Identifier: "other", UserIdentifier: "bob"},
+		},
+		lockdowns: make(map[domain.EmergencyLockdownIdentifier]domain.EmergencyLockdown),
+	}
+	peers := &fakePeerManager{db: db}
+	bus := &fakeBus{}
+
+	m, _ := NewEmergencyManager(&config.Config{}, bus, db, peers, &fakeInterfaceManager{db: db})
+	return m, db, peers, bus
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+}
+
+func TestManager_Lockdown_user(t *testing.T) {
+	m, db, _, bus := newTestManager()
+
+	lockdown, err := m.Lockdown(adminContext(), domain.EmergencyTargetUser, "alice", " stolen laptop ")
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []domain.PeerIdentifier{"laptop", "phone"}, lockdown.Snapshot.Peers,
+		"already disabled peers are not part of the snapshot")
+	assert.Equal(t, "stolen laptop", lockdown.Reason)
+	assert.Equal(t, domain.UserIdentifier("admin"), lockdown.CreatedBy)
+	assert.True(t, lockdown.IsActive())
+	assert.Equal(t, domain.DisabledReasonEmergency, db.peers["laptop"].DisabledReason)
+	assert.Nil(t, db.peers["other"].Disabled)
+	assert.Contains(t, db.lockdowns, lockdown.Identifier)
+	assert.Len(t, bus.published, 1)
+
+	// the phone is enabled manually in the meantime, the laptop is deleted
+	db.peers["phone"] = domain.Peer{Identifier: "phone", UserIdentifier: "alice"}
+	delete(db.peers, "laptop")
+
+	restored, err := m.Restore(adminContext(), lockdown.Identifier)
+	require.NoError(t, err)
+	assert.False(t, restored.IsActive())
+	assert.Equal(t, domain.UserIdentifier("admin"), restored.RestoredBy)
+	assert.NotNil(t, db.peers["disabled"].Disabled, "peers that were disabled before stay disabled")
+	assert.Len(t, bus.published, 2)
+
+	_, err = m.Restore(adminContext(), lockdown.Identifier)
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "a lockdown can only be restored once")
+}
+
+func TestManager_Lockdown_partialFailure(t *testing.T) {
+	m, db, peers, _ := newTestManager()
+	peers.fail = "phone"
+
+	lockdown, err := m.Lockdown(adminContext(), domain.EmergencyTargetUser, "alice", "")
+	assert.Error(t, err)
+	require.NotNil(t, lockdown, "the lockdown is stored for the peers that were disabled")
+	assert.Equal(t, []domain.PeerIdentifier{"laptop"}, lockdown.Snapshot.Peers)
+
+	restored, err := m.Restore(adminContext(), lockdown.Identifier)
+	require.NoError(t, err)
+	assert.Nil(t, db.peers["laptop"].Disabled)
+	assert.False(t, restored.IsActive())
+}
+
+func TestManager_Lockdown_interface(t *testing.T) {
+	m, db, _, _ := newTestManager()
+
+	lockdown, err := m.Lockdown(adminContext(), domain.EmergencyTargetInterface, "wg0", "attack")
+	require.NoError(t, err)
+	assert.Equal(t, domain.InterfaceIdentifier("wg0"), lockdown.Snapshot.Interface)
+	assert.Equal(t, domain.DisabledReasonEmergency, db.interfaces["wg0"].DisabledReason)
+
+	_, err = m.Lockdown(adminContext(), domain.EmergencyTargetInterface, "wg0", "again")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+	assert.Len(t, db.lockdowns, 1, "failed lockdowns without changes are not stored")
+
+	_, err = m.Restore(adminContext(), lockdown.Identifier)
+	require.NoError(t, err)
+	iface := db.interfaces["wg0"]
+	assert.False(t, iface.IsDisabled())
+}
+
+func TestManager_Lockdown_validation(t *testing.T) {
+	m, _, _, _ := newTestManager()
+
+	_, err := m.Lockdown(adminContext(), domain.EmergencyTargetUser, "carol", "")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	_, err = m.Lockdown(adminContext(), "organization", "acme", "")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	_, err = m.Lockdown(userCtx, domain.EmergencyTargetUser, "alice", "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	_, err = m.GetLockdowns(userCtx)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_Lockdown_organization(t *testing.T) {
+	m, db, _, _ := newTestManager()
+	orgAdminCtx := func(org domain.OrganizationIdentifier) context.Context {
+		return domain.SetUserInfo(context.Background(),
+			&domain.ContextUserInfo{Id: "org-admin", IsAdmin: true, Organization: org})
+	}
+
+	_, err := m.Lockdown(orgAdminCtx("org-b"), domain.EmergencyTargetUser, "alice", "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	assert.Nil(t, db.peers["laptop"].Disabled, "peers of other organizations are not disabled")
+	_, err = m.Lockdown(orgAdminCtx("org-b"), domain.EmergencyTargetInterface, "wg0", "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	assert.Nil(t, db.interfaces["wg0"].Disabled, "interfaces of other organizations are not disabled")
+	assert.Empty(t, db.lockdowns)
+
+	userLockdown, err := m.Lockdown(orgAdminCtx("org-a"), domain.EmergencyTargetUser, "alice", "")
+	require.NoError(t, err)
+	_, err = m.Lockdown(adminContext(), domain.EmergencyTargetInterface, "wg1", "")
+	require.NoError(t, err)
+
+	lockdowns, err := m.GetLockdowns(orgAdminCtx("org-a"))
+	require.NoError(t, err)
+	require.Len(t, lockdowns, 1)
+	assert.Equal(t, userLockdown.Identifier, lockdowns[0].Identifier)
+	lockdowns, err = m.GetLockdowns(adminContext())
+	require.NoError(t, err)
+	assert.Len(t, lockdowns, 2, "global admins see the lockdowns of all organizations")
+
+	_, err = m.Restore(orgAdminCtx("org-b"), userLockdown.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	assert.NotNil(t, db.peers["laptop"].Disabled)
+	_, err = m.Restore(orgAdminCtx("org-a"), userLockdown.Identifier)
+	require.NoError(t, err)
+	assert.Nil(t, db.peers["laptop"].Disabled)
+}
@@ -0,0 +1,198 @@
+package ephemeral
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetInterfacePeers returns all peers for the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+}
+
+type PeerManager interface {
+	// CreatePeer creates a new peer.
+	CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+	// DeletePeer deletes the peer with the given identifier.
+	DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager issues ephemeral peers and deletes them once they expire. Deleting a peer removes it from the WireGuard
+// interface and removes all firewall rules of the peer, like port forwardings and port knocking rules.
+// The expiry is stored with the peer, so expired peers are also removed after a restart of WireGuard Portal. Expired
+// peers are never applied to the WireGuard interface, even before they are deleted.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db    DatabaseRepo
+	peers PeerManager
+
+	trigger chan struct{} // wakes up the cleanup worker if a new ephemeral peer was issued
+}
+
+// NewEphemeralPeerManager creates a new ephemeral peer manager.
+func NewEphemeralPeerManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	peers PeerManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:    db,
+		peers: peers,
+
+		trigger: make(chan struct{}, 1),
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the cleanup of expired ephemeral peers. The first cleanup runs immediately, so peers
+// that expired while WireGuard Portal was not running are removed on startup.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	go m.runCleanup(ctx)
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerCreated, m.handlePeerCreatedEvent)
+	_ = m.bus.Subscribe(app.TopicPeerUpdated, m.handlePeerCreatedEvent)
+}
+
+func (m Manager) handlePeerCreatedEvent(peer domain.Peer) {
+	if peer.Ephemeral {
+		m.wakeUp() // the expiry of the peer might be earlier than the next scheduled cleanup
+	}
+}
+
+func (m Manager) wakeUp() {
+	select {
+	case m.trigger <- struct{}{}:
+	default: // a cleanup is already pending
+	}
+}
+
+// CreatePeer creates the given peer as ephemeral peer, which is deleted after the given lifetime. If no lifetime is
+// given, the configured default lifetime is used. Only interface administrators can issue ephemeral peers.
+func (m Manager) CreatePeer(ctx context.Context, peer *domain.Peer, lifetime time.Duration) (*domain.Peer, error) {
+	if !m.cfg.EphemeralPeers.Enabled {
+		return nil, fmt.Errorf("ephemeral peers are disabled: %w", domain.ErrNoPermission)
+	}
+	iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return nil, err
+	}
+
+	if lifetime == 0 {
+		lifetime = m.cfg.EphemeralPeers.DefaultLifetime
+	}
+	if lifetime < 0 || lifetime > m.cfg.EphemeralPeers.MaxLifetime {
+		return nil, errors.Join(fmt.Errorf("lifetime %s must be between 0 and %s", lifetime,
+			m.cfg.EphemeralPeers.MaxLifetime), domain.ErrInvalidData)
+	}
+
+	expiresAt := time.Now().Add(lifetime)
+	peer.Ephemeral = true
+	peer.ExpiresAt = &expiresAt
+
+	createdPeer, err := m.peers.CreatePeer(ctx, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("issued ephemeral peer", "peer", createdPeer.Identifier, "interface", createdPeer.InterfaceIdentifier,
+		"user", createdPeer.UserIdentifier, "expiresAt", expiresAt, "issuer", domain.GetUserInfo(ctx).Id)
+
+	return createdPeer, nil
+}
+
+func (m Manager) runCleanup(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		wait := m.cfg.EphemeralPeers.CleanupInterval
+		next, err := m.deleteExpiredPeers(ctx, time.Now())
+		if err != nil {
+			slog.Error("failed to delete expired ephemeral peers", "error", err)
+		} else if !next.IsZero() && time.Until(next) < wait {
+			wait = max(time.Until(next), time.Second)
+		}
+
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-m.trigger:
+		case <-time.After(wait):
+			// select blocks until one of the cases evaluate to true
+		}
+	}
+}
+
+// deleteExpiredPeers deletes all ephemeral peers that expired before the given time. It returns the expiry time of
+// the next ephemeral peer, or a zero time if there are no other ephemeral peers. Peers that cannot be deleted are
+// retried in the next run.
+func (m Manager) deleteExpiredPeers(ctx context.Context, now time.Time) (time.Time, error) {
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	var next time.Time
+	for _, iface := range interfaces {
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to load peers of interface %s: %w", iface.Identifier, err)
+		}
+
+		for _, peer := range peers {
+			if !peer.Ephemeral || peer.ExpiresAt == nil {
+				continue
+			}
+			if peer.ExpiresAt.After(now) {
+				if next.IsZero() || peer.ExpiresAt.Before(next) {
+					next = *peer.ExpiresAt
+				}
+				continue
+			}
+
+			if err := m.peers.DeletePeer(ctx, peer.Identifier); err != nil {
+				slog.Error("failed to delete expired ephemeral peer", "peer", peer.Identifier, "error", err)
+				continue
+			}
+			slog.Info("deleted expired ephemeral peer", "peer", peer.Identifier, "interface", peer.InterfaceIdentifier,
+				"expiresAt", peer.ExpiresAt)
+		}
+	}
+
+	return next, nil
+}
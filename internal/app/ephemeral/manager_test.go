@@ -0,0 +1,121 @@
+package ephemeral
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	peers []domain.Peer
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return []domain.Interface{{Identifier: "wg0"}}, nil
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	return &domain.Interface{Identifier: id}, nil
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	return f.peers, nil
+}
+
+type fakePeerManager struct {
+	created []domain.Peer
+	deleted []domain.PeerIdentifier
+	failing domain.PeerIdentifier
+}
+
+func (f *fakePeerManager) CreatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	f.created = append(f.created, *peer)
+	return peer, nil
+}
+
+func (f *fakePeerManager) DeletePeer(_ context.Context, id domain.PeerIdentifier) error {
+	if id == f.failing {
+		return errors.New("wireguard failure")
+	}
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func newTestConfig() *config.Config {
+	return &config.Config{EphemeralPeers: config.EphemeralPeersConfig{
+		Enabled:         true,
+		DefaultLifetime: time.Hour,
+		MaxLifetime:     24 * time.Hour,
+		CleanupInterval: time.Minute,
+	}}
+}
+
+func TestManager_CreatePeer(t *testing.T) {
+	peers := &fakePeerManager{}
+	m, err := NewEphemeralPeerManager(newTestConfig(), fakeBus{}, &fakeDatabase{}, peers)
+	require.NoError(t, err)
+
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	before := time.Now()
+	peer, err := m.CreatePeer(adminCtx, &domain.Peer{Identifier: "peer1", InterfaceIdentifier: "wg0"}, 0)
+	require.NoError(t, err)
+	assert.True(t, peer.Ephemeral)
+	require.NotNil(t, peer.ExpiresAt)
+	assert.WithinDuration(t, before.Add(time.Hour), *peer.ExpiresAt, time.Second, "the default lifetime is used")
+
+	_, err = m.CreatePeer(adminCtx, &domain.Peer{Identifier: "peer2", InterfaceIdentifier: "wg0"}, 48*time.Hour)
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	_, err = m.CreatePeer(userCtx, &domain.Peer{Identifier: "peer3", InterfaceIdentifier: "wg0"}, time.Hour)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	assert.Len(t, peers.created, 1)
+}
+
+func TestManager_CreatePeer_disabled(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.EphemeralPeers.Enabled = false
+	m, err := NewEphemeralPeerManager(cfg, fakeBus{}, &fakeDatabase{}, &fakePeerManager{})
+	require.NoError(t, err)
+
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	_, err = m.CreatePeer(adminCtx, &domain.Peer{Identifier: "peer1", InterfaceIdentifier: "wg0"}, time.Hour)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_deleteExpiredPeers(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	soon := now.Add(10 * time.Minute)
+	later := now.Add(2 * time.Hour)
+
+	db := &fakeDatabase{peers: []domain.Peer{
+		{Identifier: "expired", Ephemeral: true, ExpiresAt: &past},
+		{Identifier: "failing", Ephemeral: true, ExpiresAt: &past},
+		{Identifier: "soon", Ephemeral: true, ExpiresAt: &soon},
+		{Identifier: "later", Ephemeral: true, ExpiresAt: &later},
+		{Identifier: "regular", ExpiresAt: &past},
+	}}
+	peers := &fakePeerManager{failing: "failing"}
+
+	m, err := NewEphemeralPeerManager(newTestConfig(), fakeBus{}, db, peers)
+	require.NoError(t, err)
+
+	next, err := m.deleteExpiredPeers(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.PeerIdentifier{"expired"}, peers.deleted, "regular expired peers are only disabled")
+	assert.Equal(t, soon, next)
+}
@@ -5,6 +5,11 @@ package app
 const TopicAuthLogin = "auth:login"
 const TopicRouteUpdate = "route:update"
 const TopicRouteRemove = "route:remove"
+const TopicSecretRotated = "secret:rotated"
+const TopicConfigReloaded = "config:reloaded"
+const TopicHealthHeartbeat = "health:heartbeat"
+const TopicSecurityEvent = "security:event"
+const TopicExportApprovalRequested = "export:approval:requested"
 
 // endregion misc-events
 
@@ -26,6 +31,7 @@ const TopicUserEnabled = "user:enabled"
 const TopicInterfaceCreated = "interface:created"
 const TopicInterfaceUpdated = "interface:updated"
 const TopicInterfaceDeleted = "interface:deleted"
+const TopicInterfaceRenumbered = "interface:renumbered"
 
 // endregion interface-events
 
@@ -34,8 +40,12 @@ const TopicInterfaceDeleted = "interface:deleted"
 const TopicPeerCreated = "peer:created"
 const TopicPeerDeleted = "peer:deleted"
 const TopicPeerUpdated = "peer:updated"
+const TopicPeerEnabled = "peer:enabled"
 const TopicPeerInterfaceUpdated = "peer:interface:updated"
 const TopicPeerIdentifierUpdated = "peer:identifier:updated"
+const TopicPeerStateChanged = "peer:state:changed"
+const TopicPeerConfigDownloaded = "peer:config:downloaded"
+const TopicPeerRouteReviewRequested = "peer:route:review:requested"
 
 // endregion peer-events
 
@@ -43,8 +53,15 @@ const TopicPeerIdentifierUpdated = "peer:identifier:updated"
 
 const TopicAuditLoginSuccess = "audit:login:success"
 const TopicAuditLoginFailed = "audit:login:failed"
+const TopicAuditImpersonation = "audit:impersonation"
+const TopicAuditEmergency = "audit:emergency"
+const TopicAuditCredentialsReset = "audit:credentials:reset"
 
 const TopicAuditInterfaceChanged = "audit:interface:changed"
 const TopicAuditPeerChanged = "audit:peer:changed"
+const TopicAuditKeyRevealed = "audit:key:revealed"
+const TopicAuditRoamingViolation = "audit:roaming:violation"
+const TopicAuditPeerCompromised = "audit:peer:compromised"
+const TopicAuditPacketCapture = "audit:packet:capture"
 
 // endregion audit-events
@@ -0,0 +1,150 @@
+package eventstream
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+type Publisher interface {
+	// PublishEvent publishes the given event to the message broker.
+	PublishEvent(ctx context.Context, event domain.StreamEvent) error
+	// Close closes the connections to the message broker.
+	Close() error
+}
+
+// endregion dependencies
+
+// Manager publishes the lifecycle events of users, interfaces and peers, the connection events of peers and the
+// security events to the configured message broker. Events are queued, so a slow or unavailable broker does not
+// block the event bus. If the queue is full, new events are dropped.
+type Manager struct {
+	cfg       *config.Config
+	bus       EventBus
+	publisher Publisher
+
+	queue chan domain.StreamEvent
+}
+
+// NewEventStreamManager creates a new event stream manager instance.
+func NewEventStreamManager(cfg *config.Config, bus EventBus, publisher Publisher) (*Manager, error) {
+	m := &Manager{
+		cfg:       cfg,
+		bus:       bus,
+		publisher: publisher,
+
+		queue: make(chan domain.StreamEvent, max(cfg.EventStream.QueueSize, 1)),
+	}
+
+	if cfg.EventStream.Enabled {
+		m.connectToMessageBus()
+	}
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicUserCreated, m.handleUserEvent("user.created"))
+	_ = m.bus.Subscribe(app.TopicUserUpdated, m.handleUserEvent("user.updated"))
+	_ = m.bus.Subscribe(app.TopicUserDeleted, m.handleUserEvent("user.deleted"))
+
+	_ = m.bus.Subscribe(app.TopicInterfaceCreated, m.handleInterfaceEvent("interface.created"))
+	_ = m.bus.Subscribe(app.TopicInterfaceUpdated, m.handleInterfaceEvent("interface.updated"))
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceEvent("interface.deleted"))
+
+	_ = m.bus.Subscribe(app.TopicPeerCreated, m.handlePeerEvent("peer.created"))
+	_ = m.bus.Subscribe(app.TopicPeerUpdated, m.handlePeerEvent("peer.updated"))
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerEvent("peer.deleted"))
+	_ = m.bus.Subscribe(app.TopicPeerStateChanged, m.handlePeerStateChangedEvent)
+
+	_ = m.bus.Subscribe(app.TopicSecurityEvent, m.handleSecurityEvent)
+}
+
+// StartBackgroundJobs starts the worker that publishes the queued events. The connections to the message broker
+// are closed once the context is cancelled.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.EventStream.Enabled {
+		return
+	}
+
+	go m.runPublisher(ctx)
+}
+
+func (m Manager) runPublisher(ctx context.Context) {
+	defer func() {
+		if err := m.publisher.Close(); err != nil {
+			slog.Warn("failed to close event stream publisher", "error", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-m.queue:
+			if err := m.publisher.PublishEvent(ctx, event); err != nil {
+				slog.Error("failed to publish event to event stream",
+					"event", event.Name, "identifier", event.Identifier, "error", err)
+			}
+		}
+	}
+}
+
+func (m Manager) handleUserEvent(name string) func(user domain.User) {
+	return func(user domain.User) {
+		m.enqueue(name, EntityUser, string(user.Identifier), NewUser(user))
+	}
+}
+
+func (m Manager) handleInterfaceEvent(name string) func(iface domain.Interface) {
+	return func(iface domain.Interface) {
+		m.enqueue(name, EntityInterface, string(iface.Identifier), NewInterface(iface))
+	}
+}
+
+func (m Manager) handlePeerEvent(name string) func(peer domain.Peer) {
+	return func(peer domain.Peer) {
+		m.enqueue(name, EntityPeer, string(peer.Identifier), NewPeer(peer))
+	}
+}
+
+func (m Manager) handlePeerStateChangedEvent(change domain.PeerStateChange) {
+	m.enqueue("peer."+string(change.Kind), EntityPeer, string(change.PeerId), NewConnection(change))
+}
+
+func (m Manager) handleSecurityEvent(event domain.SecurityEvent) {
+	m.enqueue("security.event", EntitySecurity, strconv.FormatUint(event.Id, 10), NewSecurityEvent(event))
+}
+
+// enqueue adds the event to the publishing queue, if the event is enabled. The event is dropped if the queue is full.
+func (m Manager) enqueue(name, entity, identifier string, payload any) {
+	if !m.cfg.EventStream.IsEventEnabled(name) {
+		return
+	}
+
+	event := domain.StreamEvent{
+		Name:       name,
+		Time:       time.Now(),
+		Entity:     entity,
+		Identifier: identifier,
+		Payload:    payload,
+	}
+
+	select {
+	case m.queue <- event:
+	default:
+		slog.Warn("event stream queue is full, dropping event", "event", name, "identifier", identifier)
+	}
+}
@@ -0,0 +1,160 @@
+package eventstream
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeBus struct {
+	handlers map[string]interface{}
+}
+
+func (f *fakeBus) Subscribe(topic string, fn interface{}) error {
+	f.handlers[topic] = fn
+	return nil
+}
+
+type fakePublisher struct {
+	mux    sync.Mutex
+	events []domain.StreamEvent
+	closed bool
+}
+
+func (f *fakePublisher) PublishEvent(_ context.Context, event domain.StreamEvent) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *fakePublisher) Close() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakePublisher) published() []domain.StreamEvent {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return append([]domain.StreamEvent(nil), f.events...)
+}
+
+func newTestManager(t *testing.T, cfg config.EventStreamConfig) (*Manager, *fakeBus, *fakePublisher) {
+	bus := &fakeBus{handlers: make(map[string]interface{})}
+	publisher := &fakePublisher{}
+
+	m, err := NewEventStreamManager(&config.Config{EventStream: cfg}, bus, publisher)
+	require.NoError(t, err)
+
+	return m, bus, publisher
+}
+
+func TestManager_disabled(t *testing.T) {
+	_, bus, _ := newTestManager(t, config.EventStreamConfig{Enabled: false})
+
+	assert.Empty(t, bus.handlers)
+}
+
+func TestManager_peerEvents(t *testing.T) {
+	m, bus, _ := newTestManager(t, config.EventStreamConfig{Enabled: true, QueueSize: 10})
+
+	address, err := domain.CidrFromString("10.0.0.2/32")
+	require.NoError(t, err)
+
+	peer := domain.Peer{
+		Identifier:          "peer1",
+		DisplayName:         "Alice Laptop",
+		InterfaceIdentifier: "wg0",
+		UserIdentifier:      "alice",
+		PresharedKey:        "preshared",
+		Interface: domain.PeerInterfaceConfig{
+			KeyPair:   domain.KeyPair{PrivateKey: "private", PublicKey: "public"},
+			Addresses: []domain.Cidr{address},
+		},
+	}
+	bus.handlers[app.TopicPeerCreated].(func(domain.Peer))(peer)
+
+	require.Len(t, m.queue, 1)
+	event := <-m.queue
+	assert.Equal(t, "peer.created", event.Name)
+	assert.Equal(t, EntityPeer, event.Entity)
+	assert.Equal(t, "peer1", event.Identifier)
+
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"public_key":"public"`)
+	assert.Contains(t, string(data), `"addresses":["10.0.0.2/32"]`)
+	assert.NotContains(t, string(data), "private")
+	assert.NotContains(t, string(data), "preshared")
+}
+
+func TestManager_connectionEvents(t *testing.T) {
+	m, bus, _ := newTestManager(t, config.EventStreamConfig{Enabled: true, QueueSize: 10})
+
+	bus.handlers[app.TopicPeerStateChanged].(func(domain.PeerStateChange))(domain.PeerStateChange{
+		PeerId:           "peer1",
+		InterfaceId:      "wg0",
+		Kind:             domain.PeerStateEndpointChanged,
+		Endpoint:         "198.51.100.1:51820",
+		PreviousEndpoint: "203.0.113.1:51820",
+	})
+
+	require.Len(t, m.queue, 1)
+	event := <-m.queue
+	assert.Equal(t, "peer.endpoint-changed", event.Name)
+	assert.Equal(t, Connection{
+		Peer:             "peer1",
+		Interface:        "wg0",
+		Endpoint:         "198.51.100.1:51820",
+		PreviousEndpoint: "203.0.113.1:51820",
+	}, event.Payload)
+}
+
+func TestManager_eventFilterAndFullQueue(t *testing.T) {
+	m, bus, _ := newTestManager(t, config.EventStreamConfig{
+		Enabled:   true,
+		QueueSize: 1,
+		Events:    []string{"user.created"},
+	})
+
+	bus.handlers[app.TopicUserUpdated].(func(domain.User))(domain.User{Identifier: "alice"})
+	assert.Len(t, m.queue, 0)
+
+	bus.handlers[app.TopicUserCreated].(func(domain.User))(domain.User{Identifier: "alice"})
+	bus.handlers[app.TopicUserCreated].(func(domain.User))(domain.User{Identifier: "bob"})
+	require.Len(t, m.queue, 1)
+	assert.Equal(t, "alice", (<-m.queue).Identifier)
+}
+
+func TestManager_publisher(t *testing.T) {
+	m, bus, publisher := newTestManager(t, config.EventStreamConfig{Enabled: true, QueueSize: 10})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.StartBackgroundJobs(ctx)
+
+	bus.handlers[app.TopicSecurityEvent].(func(domain.SecurityEvent))(domain.SecurityEvent{Id: 7, RuleName: "test"})
+
+	assert.Eventually(t, func() bool { return len(publisher.published()) == 1 }, time.Second, 10*time.Millisecond)
+	event := publisher.published()[0]
+	assert.Equal(t, "security.event", event.Name)
+	assert.Equal(t, EntitySecurity, event.Entity)
+	assert.Equal(t, "7", event.Identifier)
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		publisher.mux.Lock()
+		defer publisher.mux.Unlock()
+		return publisher.closed
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,135 @@
+package eventstream
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	EntityUser      = "user"
+	EntityPeer      = "peer"
+	EntityInterface = "interface"
+	EntitySecurity  = "security_event"
+)
+
+// User is the payload of user events.
+type User struct {
+	Identifier string     `json:"identifier"`
+	Email      string     `json:"email"`
+	Firstname  string     `json:"firstname"`
+	Lastname   string     `json:"lastname"`
+	Department string     `json:"department"`
+	IsAdmin    bool       `json:"is_admin"`
+	Disabled   *time.Time `json:"disabled,omitempty"`
+}
+
+func NewUser(src domain.User) User {
+	return User{
+		Identifier: string(src.Identifier),
+		Email:      src.Email,
+		Firstname:  src.Firstname,
+		Lastname:   src.Lastname,
+		Department: src.Department,
+		IsAdmin:    src.IsAdmin,
+		Disabled:   src.Disabled,
+	}
+}
+
+// Interface is the payload of interface events. The private key is not included.
+type Interface struct {
+	Identifier  string     `json:"identifier"`
+	DisplayName string     `json:"display_name"`
+	Type        string     `json:"type"`
+	PublicKey   string     `json:"public_key"`
+	Addresses   []string   `json:"addresses"`
+	Disabled    *time.Time `json:"disabled,omitempty"`
+}
+
+func NewInterface(src domain.Interface) Interface {
+	return Interface{
+		Identifier:  string(src.Identifier),
+		DisplayName: src.DisplayName,
+		Type:        string(src.Type),
+		PublicKey:   src.PublicKey,
+		Addresses:   domain.CidrsToStringSlice(src.Addresses),
+		Disabled:    src.Disabled,
+	}
+}
+
+// Peer is the payload of peer events. The private and the pre-shared key are not included.
+type Peer struct {
+	Identifier     string     `json:"identifier"`
+	DisplayName    string     `json:"display_name"`
+	Interface      string     `json:"interface"`
+	User           string     `json:"user"`
+	PublicKey      string     `json:"public_key"`
+	Addresses      []string   `json:"addresses"`
+	Disabled       *time.Time `json:"disabled,omitempty"`
+	DisabledReason string     `json:"disabled_reason,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+func NewPeer(src domain.Peer) Peer {
+	return Peer{
+		Identifier:     string(src.Identifier),
+		DisplayName:    src.DisplayName,
+		Interface:      string(src.InterfaceIdentifier),
+		User:           string(src.UserIdentifier),
+		PublicKey:      src.Interface.PublicKey,
+		Addresses:      domain.CidrsToStringSlice(src.Interface.Addresses),
+		Disabled:       src.Disabled,
+		DisabledReason: src.DisabledReason,
+		ExpiresAt:      src.ExpiresAt,
+	}
+}
+
+// Connection is the payload of the connection events of a peer.
+type Connection struct {
+	Peer             string     `json:"peer"`
+	Interface        string     `json:"interface"`
+	Endpoint         string     `json:"endpoint"`
+	PreviousEndpoint string     `json:"previous_endpoint,omitempty"`
+	LastHandshake    *time.Time `json:"last_handshake,omitempty"`
+}
+
+func NewConnection(src domain.PeerStateChange) Connection {
+	return Connection{
+		Peer:             string(src.PeerId),
+		Interface:        string(src.InterfaceId),
+		Endpoint:         src.Endpoint,
+		PreviousEndpoint: src.PreviousEndpoint,
+		LastHandshake:    src.LastHandshake,
+	}
+}
+
+// SecurityEvent is the payload of security events.
+type SecurityEvent struct {
+	Id        uint64 `json:"id"`
+	RuleName  string `json:"rule_name"`
+	Condition string `json:"condition"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+	Peer      string `json:"peer,omitempty"`
+	Interface string `json:"interface,omitempty"`
+	User      string `json:"user,omitempty"`
+	Actor     string `json:"actor,omitempty"`
+	Endpoint  string `json:"endpoint,omitempty"`
+	Country   string `json:"country,omitempty"`
+}
+
+func NewSecurityEvent(src domain.SecurityEvent) SecurityEvent {
+	return SecurityEvent{
+		Id:        src.Id,
+		RuleName:  src.RuleName,
+		Condition: src.Condition,
+		Severity:  string(src.Severity),
+		Message:   src.Message,
+		Peer:      string(src.PeerId),
+		Interface: string(src.InterfaceId),
+		User:      string(src.UserIdentifier),
+		Actor:     string(src.Actor),
+		Endpoint:  src.Endpoint,
+		Country:   src.Country,
+	}
+}
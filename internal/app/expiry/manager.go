@@ -0,0 +1,171 @@
+package expiry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers for the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	// GetPeerExpiryReminders returns all stored peer expiry reminders.
+	GetPeerExpiryReminders(ctx context.Context) ([]domain.PeerExpiryReminder, error)
+	// SavePeerExpiryReminder creates or updates the given peer expiry reminder.
+	SavePeerExpiryReminder(ctx context.Context, reminder *domain.PeerExpiryReminder) error
+	// DeletePeerExpiryReminder deletes the peer expiry reminder for the given peer id.
+	DeletePeerExpiryReminder(ctx context.Context, id domain.PeerIdentifier) error
+}
+
+type MailManager interface {
+	// SendPeerExpiryReminder sends an email with a calendar invitation to the owner of the given peer.
+	SendPeerExpiryReminder(ctx context.Context, peer *domain.Peer) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager periodically checks for peers that expire soon and reminds their owners by mail.
+// Each owner is reminded once per expiry date.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db   DatabaseRepo
+	mail MailManager
+}
+
+// NewExpiryManager creates a new peer expiry reminder manager.
+func NewExpiryManager(cfg *config.Config, bus EventBus, db DatabaseRepo, mail MailManager) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:   db,
+		mail: mail,
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background jobs for the expiry manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if m.cfg.Mail.ExpiryReminderDays <= 0 {
+		return
+	}
+
+	go m.runReminderCheck(ctx)
+
+	slog.Debug("started peer expiry reminder checks", "days", m.cfg.Mail.ExpiryReminderDays)
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerDeletedEvent)
+}
+
+func (m Manager) handlePeerDeletedEvent(peer domain.Peer) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	err := m.db.DeletePeerExpiryReminder(ctx, peer.Identifier)
+	if err != nil {
+		slog.Error("failed to delete expiry reminder of deleted peer", "peer", peer.Identifier, "error", err)
+	}
+}
+
+func (m Manager) runReminderCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		if err := m.sendReminders(ctx, time.Now()); err != nil {
+			slog.Error("failed to send peer expiry reminders", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-time.After(m.cfg.Advanced.ExpiryCheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+	}
+}
+
+// sendReminders reminds the owners of all peers that expire within the reminder period. Peers that were already
+// reminded about their current expiry date are skipped.
+func (m Manager) sendReminders(ctx context.Context, now time.Time) error {
+	reminders, err := m.db.GetPeerExpiryReminders(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load expiry reminders: %w", err)
+	}
+	reminded := make(map[domain.PeerIdentifier]time.Time, len(reminders))
+	for _, reminder := range reminders {
+		reminded[reminder.PeerId] = reminder.ExpiresAt
+	}
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	reminderPeriod := time.Duration(m.cfg.Mail.ExpiryReminderDays) * 24 * time.Hour
+	for _, iface := range interfaces {
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			return fmt.Errorf("failed to load peers of interface %s: %w", iface.Identifier, err)
+		}
+
+		for _, peer := range peers {
+			if !needsReminder(peer, now, reminderPeriod) {
+				continue
+			}
+			if expiresAt, ok := reminded[peer.Identifier]; ok && expiresAt.Equal(*peer.ExpiresAt) {
+				continue // already reminded
+			}
+
+			if err := m.mail.SendPeerExpiryReminder(ctx, &peer); err != nil {
+				slog.Warn("failed to send peer expiry reminder", "peer", peer.Identifier, "error", err)
+				continue
+			}
+
+			err := m.db.SavePeerExpiryReminder(ctx, &domain.PeerExpiryReminder{
+				PeerId:     peer.Identifier,
+				ExpiresAt:  *peer.ExpiresAt,
+				RemindedAt: now,
+			})
+			if err != nil {
+				slog.Warn("failed to store peer expiry reminder", "peer", peer.Identifier, "error", err)
+			}
+			slog.Info("sent peer expiry reminder", "peer", peer.Identifier, "expiresAt", peer.ExpiresAt)
+		}
+	}
+
+	return nil
+}
+
+// needsReminder returns true if the given peer expires within the reminder period and has an owner.
+func needsReminder(peer domain.Peer, now time.Time, reminderPeriod time.Duration) bool {
+	if peer.ExpiresAt == nil || peer.UserIdentifier == "" || peer.IsDisabled() {
+		return false
+	}
+	if !now.Before(*peer.ExpiresAt) {
+		return false // already expired
+	}
+
+	return peer.ExpiresAt.Sub(now) <= reminderPeriod
+}
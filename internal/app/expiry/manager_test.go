@@ -0,0 +1,94 @@
+package expiry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	peers     []domain.Peer
+	reminders map[domain.PeerIdentifier]domain.PeerExpiryReminder
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return []domain.Interface{{Identifier: "wg0"}}, nil
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	return f.peers, nil
+}
+
+func (f *fakeDatabase) GetPeerExpiryReminders(_ context.Context) ([]domain.PeerExpiryReminder, error) {
+	reminders := make([]domain.PeerExpiryReminder, 0, len(f.reminders))
+	for _, reminder := range f.reminders {
+		reminders = append(reminders, reminder)
+	}
+	return reminders, nil
+}
+
+func (f *fakeDatabase) SavePeerExpiryReminder(_ context.Context, reminder *domain.PeerExpiryReminder) error {
+	f.reminders[reminder.PeerId] = *reminder
+	return nil
+}
+
+func (f *fakeDatabase) DeletePeerExpiryReminder(_ context.Context, id domain.PeerIdentifier) error {
+	delete(f.reminders, id)
+	return nil
+}
+
+type fakeMailManager struct {
+	sent []domain.PeerIdentifier
+}
+
+func (f *fakeMailManager) SendPeerExpiryReminder(_ context.Context, peer *domain.Peer) error {
+	f.sent = append(f.sent, peer.Identifier)
+	return nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func TestManager_sendReminders(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	soon := now.Add(48 * time.Hour)
+	later := now.Add(30 * 24 * time.Hour)
+	past := now.Add(-time.Hour)
+
+	cfg := &config.Config{}
+	cfg.Mail.ExpiryReminderDays = 7
+
+	db := &fakeDatabase{
+		peers: []domain.Peer{
+			{Identifier: "soon", UserIdentifier: "alice", ExpiresAt: &soon},
+			{Identifier: "later", UserIdentifier: "alice", ExpiresAt: &later},
+			{Identifier: "expired", UserIdentifier: "alice", ExpiresAt: &past},
+			{Identifier: "no-owner", ExpiresAt: &soon},
+			{Identifier: "no-expiry", UserIdentifier: "alice"},
+		},
+		reminders: map[domain.PeerIdentifier]domain.PeerExpiryReminder{},
+	}
+	mail := &fakeMailManager{}
+
+	m, err := NewExpiryManager(cfg, fakeBus{}, db, mail)
+	require.NoError(t, err)
+
+	require.NoError(t, m.sendReminders(context.Background(), now))
+	assert.Equal(t, []domain.PeerIdentifier{"soon"}, mail.sent)
+
+	// owners are reminded once per expiry date
+	require.NoError(t, m.sendReminders(context.Background(), now.Add(time.Hour)))
+	assert.Len(t, mail.sent, 1)
+
+	extended := soon.Add(24 * time.Hour)
+	db.peers[0].ExpiresAt = &extended
+	require.NoError(t, m.sendReminders(context.Background(), now.Add(time.Hour)))
+	assert.Len(t, mail.sent, 2, "a changed expiry date is reminded again")
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// column describes a column of a list export. The key is used to select the column in export requests.
+type column[T any] struct {
+	key        string
+	title      string
+	numeric    bool // numeric values are exported as numbers in XLSX files
+	statistics bool // the column is only exported by default if peer statistics are collected
+	value      func(row T) string
+}
+
+// selectColumns returns the columns with the given keys in the requested order. If no keys are given, all default
+// columns are returned.
+func selectColumns[T any](all []column[T], keys []string, withStatistics bool) ([]column[T], error) {
+	if len(keys) == 0 {
+		selected := make([]column[T], 0, len(all))
+		for _, c := range all {
+			if !c.statistics || withStatistics {
+				selected = append(selected, c)
+			}
+		}
+		return selected, nil
+	}
+
+	selected := make([]column[T], 0, len(keys))
+	for _, key := range keys {
+		idx := -1
+		for i, c := range all {
+			if c.key == strings.TrimSpace(key) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("unknown export column %q: %w", key, domain.ErrInvalidData)
+		}
+		selected = append(selected, all[idx])
+	}
+	return selected, nil
+}
+
+type peerRow struct {
+	peer   domain.Peer
+	status *domain.PeerStatus // nil if no statistics are available for the peer
+}
+
+var peerColumns = []column[peerRow]{
+	{key: "identifier", title: "Identifier", value: func(r peerRow) string { return string(r.peer.Identifier) }},
+	{key: "display_name", title: "Display Name", value: func(r peerRow) string { return r.peer.DisplayName }},
+	{key: "user", title: "User", value: func(r peerRow) string { return string(r.peer.UserIdentifier) }},
+	{key: "device_type", title: "Device Type", value: func(r peerRow) string { return string(r.peer.DeviceType) }},
+	{key: "interface", title: "Interface", value: func(r peerRow) string {
+		return string(r.peer.InterfaceIdentifier)
+	}},
+	{key: "addresses", title: "Addresses", value: func(r peerRow) string {
+		return domain.CidrsToString(r.peer.Interface.Addresses)
+	}},
+	{key: "allowed_ips", title: "Allowed IPs", value: func(r peerRow) string {
+		return r.peer.AllowedIPsStr.GetValue()
+	}},
+	{key: "extra_allowed_ips", title: "Extra Allowed IPs", value: func(r peerRow) string {
+		return r.peer.ExtraAllowedIPsStr
+	}},
+	{key: "endpoint", title: "Endpoint", value: func(r peerRow) string { return r.peer.Endpoint.GetValue() }},
+	{key: "disabled", title: "Disabled", value: func(r peerRow) string { return formatTime(r.peer.Disabled) }},
+	{key: "disabled_reason", title: "Disabled Reason", value: func(r peerRow) string {
+		return r.peer.DisabledReason
+	}},
+	{key: "expires_at", title: "Expires At", value: func(r peerRow) string { return formatTime(r.peer.ExpiresAt) }},
+	{key: "notes", title: "Notes", value: func(r peerRow) string { return r.peer.Notes }},
+	{key: "created_at", title: "Created At", value: func(r peerRow) string { return formatTime(&r.peer.CreatedAt) }},
+	{key: "updated_at", title: "Updated At", value: func(r peerRow) string { return formatTime(&r.peer.UpdatedAt) }},
+	{key: "connected", title: "Connected", statistics: true, value: func(r peerRow) string {
+		return strconv.FormatBool(r.status != nil && r.status.IsConnected())
+	}},
+	{key: "last_handshake", title: "Last Handshake", statistics: true, value: func(r peerRow) string {
+		if r.status == nil {
+			return ""
+		}
+		return formatTime(r.status.LastHandshake)
+	}},
+	{key: "remote_endpoint", title: "Remote Endpoint", statistics: true, value: func(r peerRow) string {
+		if r.status == nil {
+			return ""
+		}
+		return r.status.Endpoint
+	}},
+	{key: "bytes_received", title: "Bytes Received", numeric: true, statistics: true, value: func(r peerRow) string {
+		if r.status == nil {
+			return ""
+		}
+		return strconv.FormatUint(r.status.BytesReceived, 10)
+	}},
+	{key: "bytes_transmitted", title: "Bytes Transmitted", numeric: true, statistics: true,
+		value: func(r peerRow) string {
+			if r.status == nil {
+				return ""
+			}
+			return strconv.FormatUint(r.status.BytesTransmitted, 10)
+		}},
+}
+
+var userColumns = []column[domain.User]{
+	{key: "identifier", title: "Identifier", value: func(u domain.User) string { return string(u.Identifier) }},
+	{key: "email", title: "Email", value: func(u domain.User) string { return u.Email }},
+	{key: "firstname", title: "Firstname", value: func(u domain.User) string { return u.Firstname }},
+	{key: "lastname", title: "Lastname", value: func(u domain.User) string { return u.Lastname }},
+	{key: "phone", title: "Phone", value: func(u domain.User) string { return u.Phone }},
+	{key: "department", title: "Department", value: func(u domain.User) string { return u.Department }},
+	{key: "organization", title: "Organization", value: func(u domain.User) string {
+		return string(u.OrganizationIdentifier)
+	}},
+	{key: "source", title: "Source", value: func(u domain.User) string { return string(u.Source) }},
+	{key: "provider", title: "Provider", value: func(u domain.User) string { return u.ProviderName }},
+	{key: "type", title: "Type", value: func(u domain.User) string { return string(u.Type) }},
+	{key: "is_admin", title: "Admin", value: func(u domain.User) string { return strconv.FormatBool(u.IsAdmin) }},
+	{key: "disabled", title: "Disabled", value: func(u domain.User) string { return formatTime(u.Disabled) }},
+	{key: "disabled_reason", title: "Disabled Reason", value: func(u domain.User) string { return u.DisabledReason }},
+	{key: "locked", title: "Locked", value: func(u domain.User) string { return formatTime(u.Locked) }},
+	{key: "locked_reason", title: "Locked Reason", value: func(u domain.User) string { return u.LockedReason }},
+	{key: "peers", title: "Peers", numeric: true, value: func(u domain.User) string {
+		return strconv.Itoa(u.LinkedPeerCount)
+	}},
+	{key: "notes", title: "Notes", value: func(u domain.User) string { return u.Notes }},
+	{key: "created_at", title: "Created At", value: func(u domain.User) string { return formatTime(&u.CreatedAt) }},
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
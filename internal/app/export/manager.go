@@ -0,0 +1,196 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type PeerService interface {
+	// GetInterfaceAndPeers returns the interface with the given id and all peers associated with it.
+	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
+	// GetPeerStats returns the peer stats for the given interface.
+	GetPeerStats(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.PeerStatus, error)
+}
+
+type UserService interface {
+	// GetAllUsers returns all users.
+	GetAllUsers(ctx context.Context) ([]domain.User, error)
+}
+
+// endregion dependencies
+
+// Manager prepares the list exports of peers and users. The access rights are checked by the peer and user
+// services.
+type Manager struct {
+	cfg *config.Config
+
+	peers PeerService
+	users UserService
+}
+
+// NewExportManager creates a new export manager.
+func NewExportManager(cfg *config.Config, peers PeerService, users UserService) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+
+		peers: peers,
+		users: users,
+	}
+
+	return m, nil
+}
+
+// ExportPeers prepares the export of the peers of the given interface.
+func (m Manager) ExportPeers(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	req domain.ExportRequest,
+) (*domain.Export, error) {
+	columns, err := selectColumns(peerColumns, req.Columns, m.cfg.Statistics.CollectPeerData)
+	if err != nil {
+		return nil, err
+	}
+
+	_, peers, err := m.peers.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peers of interface %s: %w", id, err)
+	}
+
+	status := make(map[domain.PeerIdentifier]*domain.PeerStatus)
+	if m.cfg.Statistics.CollectPeerData && hasStatisticsColumn(columns) {
+		stats, err := m.peers.GetPeerStats(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load peer statistics of interface %s: %w", id, err)
+		}
+		for i := range stats {
+			status[stats[i].PeerId] = &stats[i]
+		}
+	}
+
+	rows := make([]peerRow, 0, len(peers))
+	for _, peer := range peers {
+		if req.DeviceType != domain.PeerDeviceTypeUnknown && peer.DeviceType != req.DeviceType {
+			continue
+		}
+		if matchesFilter(req.Filter, peer.DisplayName, string(peer.Identifier)) {
+			rows = append(rows, peerRow{peer: peer, status: status[peer.Identifier]})
+		}
+	}
+
+	return newExport("peers-"+string(id), "Peers", req.Format, columns, rows), nil
+}
+
+// ExportUsers prepares the export of all users.
+func (m Manager) ExportUsers(ctx context.Context, req domain.ExportRequest) (*domain.Export, error) {
+	columns, err := selectColumns(userColumns, req.Columns, false)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := m.users.GetAllUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+
+	rows := make([]domain.User, 0, len(users))
+	for _, user := range users {
+		if matchesFilter(req.Filter, user.Firstname, user.Lastname, user.Email, string(user.Identifier)) {
+			rows = append(rows, user)
+		}
+	}
+
+	return newExport("users", "Users", req.Format, columns, rows), nil
+}
+
+// matchesFilter reports whether one of the values contains the filter, like the search of the frontend lists.
+func matchesFilter(filter string, values ...string) bool {
+	if filter == "" {
+		return true
+	}
+	for _, value := range values {
+		if strings.Contains(value, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasStatisticsColumn[T any](columns []column[T]) bool {
+	for _, c := range columns {
+		if c.statistics {
+			return true
+		}
+	}
+	return false
+}
+
+func newExport[T any](
+	name, sheetName string,
+	format domain.ExportFormat,
+	columns []column[T],
+	rows []T,
+) *domain.Export {
+	contentType := "text/csv; charset=utf-8"
+	if format == domain.ExportFormatXlsx {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	} else {
+		format = domain.ExportFormatCsv
+	}
+
+	return &domain.Export{
+		Filename:    fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102"), format),
+		ContentType: contentType,
+		Write: func(w io.Writer) error {
+			return writeTable(w, format, sheetName, columns, rows)
+		},
+	}
+}
+
+func writeTable[T any](
+	w io.Writer,
+	format domain.ExportFormat,
+	sheetName string,
+	columns []column[T],
+	rows []T,
+) error {
+	titles := make([]string, len(columns))
+	numeric := make([]bool, len(columns))
+	for i, c := range columns {
+		titles[i] = c.title
+		numeric[i] = c.numeric
+	}
+
+	var table tableWriter
+	if format == domain.ExportFormatXlsx {
+		xlsx, err := newXlsxWriter(w, sheetName, numeric)
+		if err != nil {
+			return fmt.Errorf("failed to start workbook: %w", err)
+		}
+		table = xlsx
+	} else {
+		table = newCsvWriter(w, numeric)
+	}
+
+	if err := table.WriteHeader(titles); err != nil {
+		return err
+	}
+	values := make([]string, len(columns))
+	for _, row := range rows {
+		for i, c := range columns {
+			values[i] = c.value(row)
+		}
+		if err := table.WriteRow(values); err != nil {
+			return err
+		}
+	}
+
+	return table.Close()
+}
@@ -0,0 +1,128 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakePeerService struct {
+	peers      []domain.Peer
+	stats      []domain.PeerStatus
+	statsCalls int
+}
+
+func (f *fakePeerService) GetInterfaceAndPeers(_ context.Context, id domain.InterfaceIdentifier) (
+	*domain.Interface,
+	[]domain.Peer,
+	error,
+) {
+	if id != "wg0" {
+		return nil, nil, domain.ErrNotFound
+	}
+	return &domain.Interface{Identifier: id}, f.peers, nil
+}
+
+func (f *fakePeerService) GetPeerStats(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.PeerStatus, error) {
+	f.statsCalls++
+	return f.stats, nil
+}
+
+type fakeUserService struct {
+	users []domain.User
+}
+
+func (f fakeUserService) GetAllUsers(_ context.Context) ([]domain.User, error) {
+	return f.users, nil
+}
+
+func newTestManager() (*Manager, *fakePeerService) {
+	peers := &fakePeerService{
+		peers: []domain.Peer{
+			{Identifier: "key-a", DisplayName: "Alice laptop", UserIdentifier: "alice"},
+			{Identifier: "key-b", DisplayName: "Bob phone", UserIdentifier: "bob", DeviceType: domain.PeerDeviceTypePhone},
+		},
+		stats: []domain.PeerStatus{{PeerId: "key-b", BytesReceived: 2048}},
+	}
+	users := fakeUserService{users: []domain.User{
+		{Identifier: "alice", Email: "alice@example.com", Firstname: "Alice"},
+		{Identifier: "bob", Email: "bob@example.com", Firstname: "Bob", LinkedPeerCount: 1},
+	}}
+
+	return &Manager{cfg: &config.Config{}, peers: peers, users: users}, peers
+}
+
+func render(t *testing.T, export *domain.Export) string {
+	var buf bytes.Buffer
+	require.NoError(t, export.Write(&buf))
+	return buf.String()
+}
+
+func TestManager_ExportPeers(t *testing.T) {
+	m, peers := newTestManager()
+
+	export, err := m.ExportPeers(context.Background(), "wg0", domain.ExportRequest{
+		Format:  domain.ExportFormatCsv,
+		Filter:  "Bob",
+		Columns: []string{"display_name", "identifier", "bytes_received"},
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, `^peers-wg0-\d{8}\.csv$`, export.Filename)
+	assert.Equal(t, "Display Name,Identifier,Bytes Received\nBob phone,key-b,\n", render(t, export))
+	assert.Zero(t, peers.statsCalls, "statistics are not collected")
+
+	m.cfg.Statistics.CollectPeerData = true
+	export, err = m.ExportPeers(context.Background(), "wg0", domain.ExportRequest{
+		Columns: []string{"identifier", "bytes_received"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Identifier,Bytes Received\nkey-a,\nkey-b,2048\n", render(t, export))
+	assert.Equal(t, 1, peers.statsCalls)
+
+	export, err = m.ExportPeers(context.Background(), "wg0", domain.ExportRequest{
+		Columns:    []string{"identifier", "device_type"},
+		DeviceType: domain.PeerDeviceTypePhone,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Identifier,Device Type\nkey-b,phone\n", render(t, export))
+
+	_, err = m.ExportPeers(context.Background(), "wg0", domain.ExportRequest{Columns: []string{"private_key"}})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, err = m.ExportPeers(context.Background(), "wg1", domain.ExportRequest{})
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestManager_ExportPeers_defaultColumns(t *testing.T) {
+	m, peers := newTestManager()
+
+	export, err := m.ExportPeers(context.Background(), "wg0", domain.ExportRequest{})
+	require.NoError(t, err)
+	assert.NotContains(t, render(t, export), "Bytes Received", "statistic columns need collected statistics")
+	assert.Zero(t, peers.statsCalls)
+
+	m.cfg.Statistics.CollectPeerData = true
+	export, err = m.ExportPeers(context.Background(), "wg0", domain.ExportRequest{})
+	require.NoError(t, err)
+	assert.Contains(t, render(t, export), "Bytes Received")
+}
+
+func TestManager_ExportUsers(t *testing.T) {
+	m, _ := newTestManager()
+
+	export, err := m.ExportUsers(context.Background(), domain.ExportRequest{
+		Format:  domain.ExportFormatXlsx,
+		Filter:  "bob@",
+		Columns: []string{"email", "peers"},
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, `^users-\d{8}\.xlsx$`, export.Filename)
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", export.ContentType)
+	assert.Contains(t, render(t, export), "PK", "the workbook is a zip archive")
+}
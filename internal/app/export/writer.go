@@ -0,0 +1,184 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// tableWriter writes a table row by row. Close must be called after the last row, it completes the file.
+type tableWriter interface {
+	WriteHeader(titles []string) error
+	WriteRow(values []string) error
+	Close() error
+}
+
+// region csv
+
+type csvWriter struct {
+	w       *csv.Writer
+	numeric []bool
+}
+
+func newCsvWriter(w io.Writer, numeric []bool) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), numeric: numeric}
+}
+
+func (c *csvWriter) WriteHeader(titles []string) error {
+	return c.w.Write(titles)
+}
+
+func (c *csvWriter) WriteRow(values []string) error {
+	for i, value := range values {
+		if !c.numeric[i] {
+			values[i] = escapeCsvFormula(value)
+		}
+	}
+	return c.w.Write(values)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// escapeCsvFormula prefixes values that spreadsheet applications would evaluate as formula, for example a peer
+// display name like "=HYPERLINK(...)".
+func escapeCsvFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// endregion csv
+
+// region xlsx
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ` +
+		`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ` +
+		`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" ` +
+		`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" ` +
+		`Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" ` +
+		`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" ` +
+		`Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<sheetViews><sheetView workbookViewId="0">` +
+		`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>` +
+		`</sheetView></sheetViews><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxWriter writes a workbook with a single worksheet. The static parts of the workbook are written first, the
+// worksheet is streamed into the zip archive, so the rows are never buffered.
+// All cells are written as inline strings, numeric columns are written as numbers.
+type xlsxWriter struct {
+	zip     *zip.Writer
+	sheet   *bufio.Writer
+	numeric []bool
+}
+
+func newXlsxWriter(w io.Writer, sheetName string, numeric []bool) (*xlsxWriter, error) {
+	x := &xlsxWriter{zip: zip.NewWriter(w), numeric: numeric}
+
+	var name strings.Builder
+	if err := xml.EscapeText(&name, []byte(sheetName)); err != nil {
+		return nil, err
+	}
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRelationships},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, name.String())},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRelationships},
+	}
+	for _, part := range parts {
+		if err := x.writePart(part.name, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := x.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create worksheet: %w", err)
+	}
+	x.sheet = bufio.NewWriter(sheet)
+	if _, err := x.sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+
+	return x, nil
+}
+
+func (x *xlsxWriter) writePart(name, content string) error {
+	part, err := x.zip.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	_, err = io.WriteString(part, content)
+	return err
+}
+
+func (x *xlsxWriter) WriteHeader(titles []string) error {
+	return x.writeRow(titles, false)
+}
+
+func (x *xlsxWriter) WriteRow(values []string) error {
+	return x.writeRow(values, true)
+}
+
+func (x *xlsxWriter) writeRow(values []string, typed bool) error {
+	_, _ = x.sheet.WriteString("<row>")
+	for i, value := range values {
+		switch {
+		case value == "":
+			_, _ = x.sheet.WriteString("<c/>") // cells have no explicit reference, empty cells keep the column order
+		case typed && x.numeric[i]:
+			_, _ = x.sheet.WriteString("<c><v>")
+			_, _ = x.sheet.WriteString(value)
+			_, _ = x.sheet.WriteString("</v></c>")
+		default:
+			_, _ = x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(x.sheet, []byte(value)); err != nil {
+				return err
+			}
+			_, _ = x.sheet.WriteString("</t></is></c>")
+		}
+	}
+	_, err := x.sheet.WriteString("</row>")
+	return err // the buffered writer keeps the first error, it is returned by all later writes
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+// endregion xlsx
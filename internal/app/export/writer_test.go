@@ -0,0 +1,69 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCsvWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newCsvWriter(&buf, []bool{false, true})
+
+	require.NoError(t, w.WriteHeader([]string{"Name", "Bytes"}))
+	require.NoError(t, w.WriteRow([]string{"=HYPERLINK(\"x\")", "-1"}))
+	require.NoError(t, w.WriteRow([]string{"a, \"quoted\" name", "42"}))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "Name,Bytes\n\"'=HYPERLINK(\"\"x\"\")\",-1\n\"a, \"\"quoted\"\" name\",42\n", buf.String())
+}
+
+func TestXlsxWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := newXlsxWriter(&buf, "Peers", []bool{false, true})
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteHeader([]string{"Name", "Bytes"}))
+	require.NoError(t, w.WriteRow([]string{"<peer> & co", "42"}))
+	require.NoError(t, w.WriteRow([]string{"", ""}))
+	require.NoError(t, w.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(r)
+		require.NoError(t, err)
+		_ = r.Close()
+	}
+	require.Contains(t, files, "[Content_Types].xml")
+	require.Contains(t, files, "xl/workbook.xml")
+	assert.Contains(t, string(files["xl/workbook.xml"]), `<sheet name="Peers"`)
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	require.NoError(t, xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet))
+	require.Len(t, sheet.Rows, 3)
+
+	assert.Equal(t, "inlineStr", sheet.Rows[0].Cells[1].Type, "header cells are always text")
+	assert.Equal(t, "Bytes", sheet.Rows[0].Cells[1].Inline)
+	assert.Equal(t, "<peer> & co", sheet.Rows[1].Cells[0].Inline)
+	assert.Empty(t, sheet.Rows[1].Cells[1].Type)
+	assert.Equal(t, "42", sheet.Rows[1].Cells[1].Value)
+	assert.Len(t, sheet.Rows[2].Cells, 2, "empty cells keep the column order")
+}
@@ -0,0 +1,186 @@
+package exportapproval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetExportApproval returns the export approval request with the given id.
+	GetExportApproval(ctx context.Context, id uint64) (*domain.ExportApproval, error)
+	// GetExportApprovals returns all export approval requests, the most recent requests first.
+	GetExportApprovals(ctx context.Context) ([]domain.ExportApproval, error)
+	// SaveExportApproval creates or updates the given export approval request.
+	SaveExportApproval(ctx context.Context, approval *domain.ExportApproval) error
+	// GetAllUsers returns all users.
+	GetAllUsers(ctx context.Context) ([]domain.User, error)
+}
+
+type MailManager interface {
+	// SendExportApprovalRequest asks the given administrator to decide on the given export approval request.
+	SendExportApprovalRequest(ctx context.Context, userId domain.UserIdentifier, approval *domain.ExportApproval) error
+	// SendExportApprovalDecision informs the requesting user about the decision on the given request.
+	SendExportApprovalDecision(ctx context.Context, approval *domain.ExportApproval) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager manages the approval queue of key material exports. Requests are created by the key reveal manager once
+// a user exported more peers of other users than the configured threshold allows. A global administrator other
+// than the requesting user must approve or reject the request (four-eyes principle).
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db   DatabaseRepo
+	mail MailManager
+}
+
+// NewExportApprovalManager creates a new export approval manager instance.
+func NewExportApprovalManager(cfg *config.Config, bus EventBus, db DatabaseRepo, mail MailManager) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:   db,
+		mail: mail,
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	if m.cfg.ExportApproval.Threshold <= 0 {
+		return
+	}
+
+	_ = m.bus.Subscribe(app.TopicExportApprovalRequested, m.handleApprovalRequestedEvent)
+}
+
+func (m Manager) handleApprovalRequestedEvent(approval domain.ExportApproval) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	if err := m.notifyApprovers(ctx, &approval); err != nil {
+		slog.Error("failed to notify approvers of export request", "request", approval.Id, "error", err)
+	}
+}
+
+// notifyApprovers sends the given request to all active global administrators, except the requesting user.
+func (m Manager) notifyApprovers(ctx context.Context, approval *domain.ExportApproval) error {
+	users, err := m.db.GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+
+	for _, user := range users {
+		if !isApprover(&user, approval) {
+			continue
+		}
+
+		if err := m.mail.SendExportApprovalRequest(ctx, user.Identifier, approval); err != nil {
+			slog.Warn("failed to send export approval request",
+				"request", approval.Id,
+				"user", user.Identifier,
+				"error", err)
+		}
+	}
+
+	return nil
+}
+
+// isApprover returns true if the given user may decide on the given request.
+func isApprover(user *domain.User, approval *domain.ExportApproval) bool {
+	if user.IsDisabled() || user.IsLocked() || user.Identifier == approval.RequestedBy {
+		return false
+	}
+
+	return user.IsAdmin && user.OrganizationIdentifier == ""
+}
+
+// GetExportApprovals returns all export approval requests, the most recent requests first.
+func (m Manager) GetExportApprovals(ctx context.Context) ([]domain.ExportApproval, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	approvals, err := m.db.GetExportApprovals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load export approvals: %w", err)
+	}
+
+	return approvals, nil
+}
+
+// ApproveExportApproval approves the given pending request. The requesting user can export further peers within
+// the configured validity.
+func (m Manager) ApproveExportApproval(ctx context.Context, id uint64) (*domain.ExportApproval, error) {
+	return m.decide(ctx, id, domain.ExportApprovalStateApproved)
+}
+
+// RejectExportApproval rejects the given pending request.
+func (m Manager) RejectExportApproval(ctx context.Context, id uint64) (*domain.ExportApproval, error) {
+	return m.decide(ctx, id, domain.ExportApprovalStateRejected)
+}
+
+func (m Manager) decide(
+	ctx context.Context,
+	id uint64,
+	state domain.ExportApprovalState,
+) (*domain.ExportApproval, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	approval, err := m.db.GetExportApproval(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load export approval %d: %w", id, err)
+	}
+
+	currentUser := domain.GetUserInfo(ctx)
+	if approval.RequestedBy == currentUser.Id {
+		return nil, fmt.Errorf("export requests must be decided by another administrator: %w",
+			domain.ErrNoPermission)
+	}
+	if !approval.IsPending() {
+		return nil, errors.Join(fmt.Errorf("export approval %d was already %s", id, approval.State),
+			domain.ErrInvalidData)
+	}
+
+	now := time.Now()
+	approval.State = state
+	approval.DecidedBy = currentUser.Id
+	approval.DecidedAt = &now
+	if state == domain.ExportApprovalStateApproved {
+		validUntil := now.Add(m.cfg.ExportApproval.Validity)
+		approval.ValidUntil = &validUntil
+	}
+
+	if err := m.db.SaveExportApproval(ctx, approval); err != nil {
+		return nil, fmt.Errorf("failed to store decision of export approval %d: %w", id, err)
+	}
+
+	slog.Info("export approval decided", "request", id, "user", approval.RequestedBy, "state", state,
+		"by", currentUser.Id)
+
+	if err := m.mail.SendExportApprovalDecision(ctx, approval); err != nil {
+		slog.Warn("failed to send export approval decision", "request", id, "error", err)
+	}
+
+	return approval, nil
+}
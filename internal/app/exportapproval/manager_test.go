@@ -0,0 +1,120 @@
+package exportapproval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	approvals map[uint64]domain.ExportApproval
+	users     []domain.User
+}
+
+func (f *fakeDatabase) GetExportApproval(_ context.Context, id uint64) (*domain.ExportApproval, error) {
+	approval, ok := f.approvals[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &approval, nil
+}
+
+func (f *fakeDatabase) GetExportApprovals(_ context.Context) ([]domain.ExportApproval, error) {
+	var approvals []domain.ExportApproval
+	for _, approval := range f.approvals {
+		approvals = append(approvals, approval)
+	}
+	return approvals, nil
+}
+
+func (f *fakeDatabase) SaveExportApproval(_ context.Context, approval *domain.ExportApproval) error {
+	f.approvals[approval.Id] = *approval
+	return nil
+}
+
+func (f *fakeDatabase) GetAllUsers(_ context.Context) ([]domain.User, error) {
+	return f.users, nil
+}
+
+type fakeMailManager struct {
+	requests  []domain.UserIdentifier
+	decisions []domain.ExportApprovalState
+}
+
+func (f *fakeMailManager) SendExportApprovalRequest(
+	_ context.Context,
+	userId domain.UserIdentifier,
+	_ *domain.ExportApproval,
+) error {
+	f.requests = append(f.requests, userId)
+	return nil
+}
+
+func (f *fakeMailManager) SendExportApprovalDecision(_ context.Context, approval *domain.ExportApproval) error {
+	f.decisions = append(f.decisions, approval.State)
+	return nil
+}
+
+func newTestManager() (*Manager, *fakeDatabase, *fakeMailManager) {
+	past := time.Now().Add(-time.Hour)
+	db := &fakeDatabase{
+		approvals: map[uint64]domain.ExportApproval{
+			1: {Id: 1, RequestedBy: "alice", State: domain.ExportApprovalStatePending},
+		},
+		users: []domain.User{
+			{Identifier: "alice", IsAdmin: true},
+			{Identifier: "bob", IsAdmin: true},
+			{Identifier: "carol", IsAdmin: true, Disabled: &past},
+			{Identifier: "dave", IsAdmin: true, OrganizationIdentifier: "acme"},
+			{Identifier: "eve"},
+		},
+	}
+	mail := &fakeMailManager{}
+	cfg := &config.Config{}
+	cfg.ExportApproval = config.ExportApprovalConfig{Threshold: 10, Window: time.Hour, Validity: time.Hour}
+	m := &Manager{cfg: cfg, db: db, mail: mail}
+	return m, db, mail
+}
+
+func adminContext(id domain.UserIdentifier) context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: id, IsAdmin: true})
+}
+
+func TestManager_notifyApprovers(t *testing.T) {
+	m, db, mail := newTestManager()
+	approval := db.approvals[1]
+
+	require.NoError(t, m.notifyApprovers(adminContext("system"), &approval))
+	assert.Equal(t, []domain.UserIdentifier{"bob"}, mail.requests,
+		"the requesting, disabled, organization and non-admin users are skipped")
+}
+
+func TestManager_decide(t *testing.T) {
+	m, db, mail := newTestManager()
+
+	_, err := m.ApproveExportApproval(adminContext("alice"), 1)
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "requests cannot be approved by the requesting user")
+
+	_, err = m.ApproveExportApproval(domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "eve"}), 1)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	approval, err := m.ApproveExportApproval(adminContext("bob"), 1)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ExportApprovalStateApproved, approval.State)
+	assert.Equal(t, domain.UserIdentifier("bob"), approval.DecidedBy)
+	assert.True(t, approval.AllowsExports(time.Now()))
+	assert.Equal(t, domain.ExportApprovalStateApproved, db.approvals[1].State)
+	assert.Equal(t, []domain.ExportApprovalState{domain.ExportApprovalStateApproved}, mail.decisions)
+
+	_, err = m.RejectExportApproval(adminContext("bob"), 1)
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "decided requests cannot be changed")
+
+	_, err = m.RejectExportApproval(adminContext("bob"), 2)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
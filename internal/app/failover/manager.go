@@ -0,0 +1,412 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+}
+
+type InterfaceController interface {
+	// GetInterface returns the physical interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.PhysicalInterface, error)
+}
+
+type InterfaceStateRestorer interface {
+	// RestoreInterfaceState applies the stored state of the given interfaces and their peers to the physical
+	// interfaces. If no interface is given, all interfaces are restored.
+	RestoreInterfaceState(ctx context.Context, updateDbOnError bool, filter ...domain.InterfaceIdentifier) error
+}
+
+type GatewayRepo interface {
+	// CheckHealth fetches the health report of the gateway with the given health url.
+	CheckHealth(ctx context.Context, healthUrl string) (*domain.FailoverHealth, error)
+	// ExecuteHook executes the given shell command with the failover state in the environment.
+	ExecuteHook(ctx context.Context, hook string, state domain.FailoverState, node string) error
+}
+
+type DnsProvider interface {
+	// ReplaceRecordSet creates the given record set or replaces all records of an existing record set
+	ReplaceRecordSet(ctx context.Context, record domain.DnsRecordSet) error
+}
+
+// endregion dependencies
+
+// Manager is the failover controller of a gateway in an active/standby pair. Both gateways share the database, the
+// standby replicates the stored peer state to its local interfaces and monitors the primary. If the primary fails,
+// the standby becomes active and steers the advertised endpoint to itself, using a DNS update or the failover hook.
+// Once the primary is healthy again, it takes back the endpoint and the standby returns to the standby state.
+type Manager struct {
+	cfg *config.Config
+
+	db       DatabaseRepo
+	wg       InterfaceController
+	restorer InterfaceStateRestorer
+	gateway  GatewayRepo
+	dns      DnsProvider
+
+	nodeName string
+	state    *failoverState
+}
+
+type failoverState struct {
+	mux    sync.Mutex
+	status domain.FailoverStatus
+}
+
+// NewFailoverManager creates a new failover controller.
+func NewFailoverManager(
+	cfg *config.Config,
+	db DatabaseRepo,
+	wg InterfaceController,
+	restorer InterfaceStateRestorer,
+	gateway GatewayRepo,
+	dns DnsProvider,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+
+		db:       db,
+		wg:       wg,
+		restorer: restorer,
+		gateway:  gateway,
+		dns:      dns,
+
+		nodeName: cfg.Failover.NodeName,
+		state:    &failoverState{},
+	}
+	if m.nodeName == "" {
+		m.nodeName, _ = os.Hostname()
+	}
+
+	if !cfg.Failover.Enabled {
+		return m, nil
+	}
+
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	m.state.status = domain.FailoverStatus{
+		FailoverHealth: domain.FailoverHealth{
+			Node:  m.nodeName,
+			Role:  string(cfg.Failover.Role),
+			State: domain.FailoverStateStandby,
+		},
+	}
+
+	return m, nil
+}
+
+func validateConfig(cfg *config.Config) error {
+	switch cfg.Failover.Role {
+	case config.FailoverRolePrimary, config.FailoverRoleStandby:
+	default:
+		return fmt.Errorf("invalid failover role: %s", cfg.Failover.Role)
+	}
+	if cfg.Failover.PeerHealthUrl == "" {
+		return errors.New("missing failover peer health url")
+	}
+	if cfg.Failover.CheckInterval <= 0 || cfg.Failover.FailureThreshold < 1 {
+		return errors.New("failover check interval and failure threshold must be positive")
+	}
+
+	if cfg.Failover.DnsName == "" {
+		return nil
+	}
+	if !cfg.DnsRecords.Enabled {
+		return errors.New("failover dns updates require the dns_records provider")
+	}
+	if cfg.Failover.DnsZone == "" {
+		return errors.New("missing failover dns zone")
+	}
+	if len(cfg.Failover.AdvertisedAddresses) == 0 {
+		return errors.New("missing failover advertised addresses")
+	}
+	for _, address := range cfg.Failover.AdvertisedAddresses {
+		if _, err := netip.ParseAddr(address); err != nil {
+			return fmt.Errorf("invalid failover advertised address %s: %w", address, err)
+		}
+	}
+
+	return nil
+}
+
+// StartBackgroundJobs starts the health checks and, on the standby, the replication of the peer state.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.Failover.Enabled {
+		return
+	}
+
+	go m.run(ctx)
+}
+
+func (m Manager) run(ctx context.Context) {
+	// the primary claims the endpoint on startup, the standby waits for failed health checks
+	if m.cfg.Failover.Role == config.FailoverRolePrimary {
+		m.transition(ctx, domain.FailoverStateActive)
+	} else {
+		m.transition(ctx, domain.FailoverStateStandby)
+		m.syncState(ctx)
+	}
+
+	lastSync := time.Now()
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(m.cfg.Failover.CheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		m.checkPeer(ctx)
+
+		syncInterval := m.cfg.Failover.SyncInterval
+		if m.cfg.Failover.Role == config.FailoverRoleStandby && syncInterval > 0 &&
+			time.Since(lastSync) >= syncInterval {
+			m.syncState(ctx)
+			lastSync = time.Now()
+		}
+	}
+}
+
+// GetHealth returns the health report of this gateway, it is served to the other gateway of the pair.
+func (m Manager) GetHealth(ctx context.Context) (*domain.FailoverHealth, error) {
+	if !m.cfg.Failover.Enabled {
+		return nil, fmt.Errorf("failover is disabled: %w", domain.ErrNotFound)
+	}
+
+	m.state.mux.Lock()
+	health := m.state.status.FailoverHealth
+	m.state.mux.Unlock()
+
+	health.Healthy = m.isHealthy(ctx) == nil
+
+	return &health, nil
+}
+
+// GetStatus returns the current state of the failover controller.
+func (m Manager) GetStatus(ctx context.Context) (*domain.FailoverStatus, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+	if !m.cfg.Failover.Enabled {
+		return nil, fmt.Errorf("failover is disabled: %w", domain.ErrNotFound)
+	}
+
+	m.state.mux.Lock()
+	status := m.state.status
+	m.state.mux.Unlock()
+
+	status.Healthy = m.isHealthy(ctx) == nil
+
+	return &status, nil
+}
+
+// isHealthy checks that all served interfaces are available on this gateway.
+func (m Manager) isHealthy(ctx context.Context) error {
+	ids, err := m.servedInterfaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if _, err := m.wg.GetInterface(ctx, id); err != nil {
+			return fmt.Errorf("interface %s is unavailable: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// servedInterfaces returns the configured interfaces, or all enabled interfaces if none are configured.
+func (m Manager) servedInterfaces(ctx context.Context) ([]domain.InterfaceIdentifier, error) {
+	if len(m.cfg.Failover.Interfaces) != 0 {
+		ids := make([]domain.InterfaceIdentifier, len(m.cfg.Failover.Interfaces))
+		for i, id := range m.cfg.Failover.Interfaces {
+			ids[i] = domain.InterfaceIdentifier(id)
+		}
+		return ids, nil
+	}
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load interfaces: %w", err)
+	}
+	ids := make([]domain.InterfaceIdentifier, 0, len(interfaces))
+	for _, iface := range interfaces {
+		if !iface.IsDisabled() {
+			ids = append(ids, iface.Identifier)
+		}
+	}
+
+	return ids, nil
+}
+
+// checkPeer runs a health check of the other gateway and changes the state of this gateway if required.
+func (m Manager) checkPeer(ctx context.Context) {
+	health, err := m.gateway.CheckHealth(ctx, m.cfg.Failover.PeerHealthUrl)
+
+	m.state.mux.Lock()
+	status := &m.state.status
+	status.LastCheck = time.Now()
+	status.PeerState = ""
+	if health != nil {
+		status.PeerState = health.State
+	}
+	if err != nil {
+		status.ConsecutiveFailures++
+		status.ConsecutiveSuccesses = 0
+		status.LastError = err.Error()
+	} else {
+		status.ConsecutiveSuccesses++
+		status.ConsecutiveFailures = 0
+	}
+	next, reclaim := nextState(m.cfg.Failover.Role, *status, m.cfg.Failover.FailureThreshold)
+	current := status.State
+	m.state.mux.Unlock()
+
+	if err != nil {
+		slog.Debug("failover health check failed", "peer", m.cfg.Failover.PeerHealthUrl, "error", err)
+	}
+
+	switch {
+	case next == domain.FailoverStateActive && current != domain.FailoverStateActive:
+		if err := m.isHealthy(ctx); err != nil {
+			slog.Error("failover takeover skipped, this gateway is unhealthy", "error", err)
+			return
+		}
+		m.transition(ctx, next)
+	case next != current:
+		m.transition(ctx, next)
+	case reclaim:
+		slog.Warn("failover peer is active, reclaiming the advertised endpoint", "peer", health.Node)
+		m.transition(ctx, next)
+	}
+}
+
+// nextState returns the desired state of the gateway with the given role. Reclaim is true if the primary has to
+// steer the endpoint back to itself, because the standby took over.
+func nextState(role config.FailoverRole, status domain.FailoverStatus, threshold int) (
+	next domain.FailoverState,
+	reclaim bool,
+) {
+	if role == config.FailoverRolePrimary {
+		peerActive := status.ConsecutiveFailures == 0 && status.PeerState == domain.FailoverStateActive
+		return domain.FailoverStateActive, peerActive
+	}
+
+	switch {
+	case status.State != domain.FailoverStateActive && status.ConsecutiveFailures >= threshold:
+		return domain.FailoverStateActive, false
+	case status.State == domain.FailoverStateActive && status.ConsecutiveSuccesses >= threshold:
+		return domain.FailoverStateStandby, false
+	default:
+		return status.State, false
+	}
+}
+
+// transition moves this gateway to the given state. An active gateway steers the advertised endpoint to itself.
+func (m Manager) transition(ctx context.Context, state domain.FailoverState) {
+	slog.Warn("failover state change", "node", m.nodeName, "role", m.cfg.Failover.Role, "state", state)
+
+	var errs []error
+	if state == domain.FailoverStateActive {
+		for _, record := range m.endpointRecords() {
+			if err := m.dns.ReplaceRecordSet(ctx, record); err != nil {
+				errs = append(errs, fmt.Errorf("failed to update %s record %s: %w", record.Type, record.Name, err))
+			}
+		}
+	}
+	if err := m.gateway.ExecuteHook(ctx, m.cfg.Failover.Hook, state, m.nodeName); err != nil {
+		errs = append(errs, err)
+	}
+
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	m.state.status.State = state
+	m.state.status.LastTransition = time.Now()
+	if err := errors.Join(errs...); err != nil {
+		slog.Error("failover state change incomplete", "state", state, "error", err)
+		m.state.status.LastError = err.Error()
+	}
+}
+
+// endpointRecords returns the DNS records that point the advertised endpoint to this gateway.
+func (m Manager) endpointRecords() []domain.DnsRecordSet {
+	if m.cfg.Failover.DnsName == "" {
+		return nil
+	}
+
+	var v4, v6 []string
+	for _, address := range m.cfg.Failover.AdvertisedAddresses {
+		addr, err := netip.ParseAddr(address)
+		switch {
+		case err != nil:
+			continue
+		case addr.Is4():
+			v4 = append(v4, addr.String())
+		default:
+			v6 = append(v6, addr.String())
+		}
+	}
+
+	name := strings.TrimSuffix(m.cfg.Failover.DnsName, ".") + "."
+	zone := strings.TrimSuffix(m.cfg.Failover.DnsZone, ".") + "."
+	ttl := uint32(m.cfg.Failover.DnsTTL.Seconds())
+
+	var records []domain.DnsRecordSet
+	if len(v4) != 0 {
+		records = append(records, domain.DnsRecordSet{
+			Name: name, Type: domain.DnsRecordTypeA, Zone: zone, TTL: ttl, ValuesStr: strings.Join(v4, ","),
+		})
+	}
+	if len(v6) != 0 {
+		records = append(records, domain.DnsRecordSet{
+			Name: name, Type: domain.DnsRecordTypeAAAA, Zone: zone, TTL: ttl, ValuesStr: strings.Join(v6, ","),
+		})
+	}
+
+	return records
+}
+
+// syncState applies the stored state of the served interfaces and their peers to the local interfaces, so the
+// standby is ready to take over at any time.
+func (m Manager) syncState(ctx context.Context) {
+	filter := make([]domain.InterfaceIdentifier, len(m.cfg.Failover.Interfaces))
+	for i, id := range m.cfg.Failover.Interfaces {
+		filter[i] = domain.InterfaceIdentifier(id)
+	}
+
+	sysCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+	err := m.restorer.RestoreInterfaceState(sysCtx, false, filter...)
+
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	if err != nil {
+		slog.Error("failed to replicate interface state", "error", err)
+		m.state.status.LastError = err.Error()
+		return
+	}
+	m.state.status.LastSync = time.Now()
+}
@@ -0,0 +1,210 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	interfaces []domain.Interface
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+type fakeController struct {
+	available map[domain.InterfaceIdentifier]bool
+}
+
+func (f *fakeController) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (
+	*domain.PhysicalInterface,
+	error,
+) {
+	if !f.available[id] {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.PhysicalInterface{Identifier: id}, nil
+}
+
+type fakeRestorer struct {
+	filters [][]domain.InterfaceIdentifier
+}
+
+func (f *fakeRestorer) RestoreInterfaceState(
+	_ context.Context,
+	_ bool,
+	filter ...domain.InterfaceIdentifier,
+) error {
+	f.filters = append(f.filters, filter)
+	return nil
+}
+
+type fakeGateway struct {
+	health *domain.FailoverHealth
+	err    error
+	hooks  []domain.FailoverState
+}
+
+func (f *fakeGateway) CheckHealth(_ context.Context, _ string) (*domain.FailoverHealth, error) {
+	return f.health, f.err
+}
+
+func (f *fakeGateway) ExecuteHook(_ context.Context, _ string, state domain.FailoverState, _ string) error {
+	f.hooks = append(f.hooks, state)
+	return nil
+}
+
+type fakeDns struct {
+	records []domain.DnsRecordSet
+}
+
+func (f *fakeDns) ReplaceRecordSet(_ context.Context, record domain.DnsRecordSet) error {
+	f.records = append(f.records, record)
+	return nil
+}
+
+func testConfig(role config.FailoverRole) *config.Config {
+	cfg := &config.Config{}
+	cfg.DnsRecords.Enabled = true
+	cfg.Failover = config.FailoverConfig{
+		Enabled:             true,
+		NodeName:            "gw1",
+		Role:                role,
+		PeerHealthUrl:       "https://gw2.example.com/api/v0/failover/health",
+		Interfaces:          []string{"wg0"},
+		CheckInterval:       time.Second,
+		FailureThreshold:    2,
+		DnsName:             "vpn.example.com",
+		DnsZone:             "example.com.",
+		DnsTTL:              30 * time.Second,
+		AdvertisedAddresses: []string{"192.0.2.1", "2001:db8::1"},
+	}
+	return cfg
+}
+
+func newTestManager(t *testing.T, cfg *config.Config) (*Manager, *fakeGateway, *fakeDns) {
+	gateway := &fakeGateway{}
+	dns := &fakeDns{}
+	m, err := NewFailoverManager(cfg, &fakeDatabase{},
+		&fakeController{available: map[domain.InterfaceIdentifier]bool{"wg0": true}}, &fakeRestorer{}, gateway, dns)
+	require.NoError(t, err)
+
+	return m, gateway, dns
+}
+
+func TestNewFailoverManager_Validation(t *testing.T) {
+	cfg := testConfig(config.FailoverRolePrimary)
+	cfg.Failover.Role = "leader"
+	_, err := NewFailoverManager(cfg, nil, nil, nil, nil, nil)
+	assert.Error(t, err)
+
+	cfg = testConfig(config.FailoverRoleStandby)
+	cfg.Failover.PeerHealthUrl = ""
+	_, err = NewFailoverManager(cfg, nil, nil, nil, nil, nil)
+	assert.Error(t, err)
+
+	cfg = testConfig(config.FailoverRoleStandby)
+	cfg.Failover.AdvertisedAddresses = []string{"vpn.example.com"}
+	_, err = NewFailoverManager(cfg, nil, nil, nil, nil, nil)
+	assert.Error(t, err)
+
+	cfg = testConfig(config.FailoverRoleStandby)
+	cfg.DnsRecords.Enabled = false
+	_, err = NewFailoverManager(cfg, nil, nil, nil, nil, nil)
+	assert.Error(t, err)
+
+	cfg = testConfig(config.FailoverRoleStandby)
+	cfg.Failover.Enabled = false
+	cfg.Failover.Role = "leader"
+	_, err = NewFailoverManager(cfg, nil, nil, nil, nil, nil)
+	assert.NoError(t, err, "a disabled controller is not validated")
+}
+
+func TestManager_checkPeer_StandbyTakeoverAndHandback(t *testing.T) {
+	m, gateway, dns := newTestManager(t, testConfig(config.FailoverRoleStandby))
+	ctx := context.Background()
+
+	gateway.err = errors.New("connection refused")
+	m.checkPeer(ctx)
+	assert.Equal(t, domain.FailoverStateStandby, m.state.status.State, "below the failure threshold")
+	assert.Empty(t, dns.records)
+
+	m.checkPeer(ctx)
+	assert.Equal(t, domain.FailoverStateActive, m.state.status.State)
+	assert.Equal(t, []domain.FailoverState{domain.FailoverStateActive}, gateway.hooks)
+	require.Len(t, dns.records, 2)
+	assert.Equal(t, "vpn.example.com.", dns.records[0].Name)
+	assert.Equal(t, "example.com.", dns.records[0].Zone)
+	assert.Equal(t, domain.DnsRecordTypeA, dns.records[0].Type)
+	assert.Equal(t, "192.0.2.1", dns.records[0].ValuesStr)
+	assert.Equal(t, uint32(30), dns.records[0].TTL)
+	assert.Equal(t, domain.DnsRecordTypeAAAA, dns.records[1].Type)
+	assert.Equal(t, "2001:db8::1", dns.records[1].ValuesStr)
+
+	gateway.err = nil
+	gateway.health = &domain.FailoverHealth{Node: "gw2", State: domain.FailoverStateActive, Healthy: true}
+	m.checkPeer(ctx)
+	assert.Equal(t, domain.FailoverStateActive, m.state.status.State, "below the success threshold")
+
+	m.checkPeer(ctx)
+	assert.Equal(t, domain.FailoverStateStandby, m.state.status.State)
+	assert.Equal(t, []domain.FailoverState{domain.FailoverStateActive, domain.FailoverStateStandby}, gateway.hooks)
+	assert.Len(t, dns.records, 2, "the primary steers the endpoint back")
+}
+
+func TestManager_checkPeer_UnhealthyStandbyDoesNotTakeOver(t *testing.T) {
+	cfg := testConfig(config.FailoverRoleStandby)
+	cfg.Failover.Interfaces = []string{"wg0", "wg1"}
+	m, gateway, dns := newTestManager(t, cfg)
+
+	gateway.err = errors.New("connection refused")
+	m.checkPeer(context.Background())
+	m.checkPeer(context.Background())
+
+	assert.Equal(t, domain.FailoverStateStandby, m.state.status.State)
+	assert.Empty(t, dns.records)
+	assert.Empty(t, gateway.hooks)
+}
+
+func TestManager_checkPeer_PrimaryReclaimsEndpoint(t *testing.T) {
+	m, gateway, dns := newTestManager(t, testConfig(config.FailoverRolePrimary))
+	m.state.status.State = domain.FailoverStateActive
+
+	gateway.health = &domain.FailoverHealth{Node: "gw2", State: domain.FailoverStateStandby, Healthy: true}
+	m.checkPeer(context.Background())
+	assert.Empty(t, dns.records)
+
+	gateway.health = &domain.FailoverHealth{Node: "gw2", State: domain.FailoverStateActive, Healthy: true}
+	m.checkPeer(context.Background())
+	assert.Equal(t, domain.FailoverStateActive, m.state.status.State)
+	assert.Len(t, dns.records, 2)
+}
+
+func TestManager_GetHealth(t *testing.T) {
+	cfg := testConfig(config.FailoverRoleStandby)
+	m, _, _ := newTestManager(t, cfg)
+
+	health, err := m.GetHealth(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "gw1", health.Node)
+	assert.Equal(t, "standby", health.Role)
+	assert.True(t, health.Healthy)
+
+	cfg.Failover.Interfaces = []string{"wg0", "wg1"}
+	health, err = m.GetHealth(context.Background())
+	require.NoError(t, err)
+	assert.False(t, health.Healthy)
+
+	cfg.Failover.Enabled = false
+	_, err = m.GetHealth(context.Background())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
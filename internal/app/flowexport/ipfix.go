@@ -0,0 +1,224 @@
+package flowexport
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// IPFIX constants, see RFC 7011.
+const (
+	ipfixVersion             = 10
+	ipfixTemplateSetId       = 2
+	ipfixVariableLength      = 65535
+	ipfixMessageHeaderLength = 16
+	ipfixSetHeaderLength     = 4
+
+	templateIdV4 uint16 = 256
+	templateIdV6 uint16 = 257
+)
+
+// informationElement is an IANA IPFIX information element with its encoded length.
+type informationElement struct {
+	id     uint16
+	length uint16
+}
+
+// The information elements of the exported records, see https://www.iana.org/assignments/ipfix.
+var (
+	ieSourceIPv4Address        = informationElement{id: 8, length: 4}
+	ieDestinationIPv4Address   = informationElement{id: 12, length: 4}
+	ieSourceIPv6Address        = informationElement{id: 27, length: 16}
+	ieDestinationIPv6Address   = informationElement{id: 28, length: 16}
+	ieSourceTransportPort      = informationElement{id: 7, length: 2}
+	ieDestinationTransportPort = informationElement{id: 11, length: 2}
+	ieProtocolIdentifier       = informationElement{id: 4, length: 1}
+	ieOctetDeltaCount          = informationElement{id: 1, length: 8}
+	iePacketDeltaCount         = informationElement{id: 2, length: 8}
+	ieFlowStartMilliseconds    = informationElement{id: 152, length: 8}
+	ieFlowEndMilliseconds      = informationElement{id: 153, length: 8}
+	ieInterfaceName            = informationElement{id: 82, length: ipfixVariableLength}
+	ieUserName                 = informationElement{id: 371, length: ipfixVariableLength}
+)
+
+// templateFields returns the fields of the template of IPv4 or IPv6 flow records.
+func templateFields(v4 bool) []informationElement {
+	source, destination := ieSourceIPv6Address, ieDestinationIPv6Address
+	if v4 {
+		source, destination = ieSourceIPv4Address, ieDestinationIPv4Address
+	}
+
+	return []informationElement{
+		source,
+		destination,
+		ieSourceTransportPort,
+		ieDestinationTransportPort,
+		ieProtocolIdentifier,
+		ieOctetDeltaCount,
+		iePacketDeltaCount,
+		ieFlowStartMilliseconds,
+		ieFlowEndMilliseconds,
+		ieInterfaceName,
+		ieUserName,
+	}
+}
+
+// ipfixEncoder encodes flow records as IPFIX messages of one observation domain.
+type ipfixEncoder struct {
+	domainId       uint32
+	maxMessageSize int
+	sequence       uint32 // the number of data records that were exported before, modulo 2^32
+}
+
+// encode returns the IPFIX messages that contain the given records. Each message starts with the templates, so
+// that collectors can decode the records after a restart or a lost message.
+func (e *ipfixEncoder) encode(records []domain.FlowRecord, now time.Time) [][]byte {
+	templates := encodeTemplateSet()
+
+	var messages [][]byte
+	var v4Records, v6Records [][]byte
+	var v4Size, v6Size int
+	size := func() int {
+		total := ipfixMessageHeaderLength + len(templates)
+		if len(v4Records) > 0 {
+			total += ipfixSetHeaderLength + v4Size
+		}
+		if len(v6Records) > 0 {
+			total += ipfixSetHeaderLength + v6Size
+		}
+		return total
+	}
+	flush := func() {
+		if len(v4Records) == 0 && len(v6Records) == 0 {
+			return
+		}
+		messages = append(messages, e.encodeMessage(templates, v4Records, v6Records, now))
+		v4Records, v6Records = nil, nil
+		v4Size, v6Size = 0, 0
+	}
+
+	for _, record := range records {
+		encoded := encodeDataRecord(record)
+		v4 := record.Source.Addr().Is4()
+
+		additional := len(encoded)
+		if v4 && len(v4Records) == 0 || !v4 && len(v6Records) == 0 {
+			additional += ipfixSetHeaderLength
+		}
+		if size()+additional > e.maxMessageSize {
+			flush()
+		}
+
+		if v4 {
+			v4Records = append(v4Records, encoded)
+			v4Size += len(encoded)
+		} else {
+			v6Records = append(v6Records, encoded)
+			v6Size += len(encoded)
+		}
+	}
+	flush()
+
+	return messages
+}
+
+func (e *ipfixEncoder) encodeMessage(templates []byte, v4Records, v6Records [][]byte, now time.Time) []byte {
+	body := append([]byte{}, templates...)
+	if len(v4Records) > 0 {
+		body = append(body, encodeSet(templateIdV4, v4Records...)...)
+	}
+	if len(v6Records) > 0 {
+		body = append(body, encodeSet(templateIdV6, v6Records...)...)
+	}
+
+	header := make([]byte, ipfixMessageHeaderLength)
+	binary.BigEndian.PutUint16(header[0:], ipfixVersion)
+	binary.BigEndian.PutUint16(header[2:], uint16(ipfixMessageHeaderLength+len(body)))
+	binary.BigEndian.PutUint32(header[4:], uint32(now.Unix()))
+	binary.BigEndian.PutUint32(header[8:], e.sequence)
+	binary.BigEndian.PutUint32(header[12:], e.domainId)
+
+	e.sequence += uint32(len(v4Records) + len(v6Records))
+
+	return append(header, body...)
+}
+
+// encodeTemplateSet returns the template set with the templates of IPv4 and IPv6 flow records.
+func encodeTemplateSet() []byte {
+	var templates [][]byte
+	for _, template := range []struct {
+		id uint16
+		v4 bool
+	}{{templateIdV4, true}, {templateIdV6, false}} {
+		fields := templateFields(template.v4)
+		record := binary.BigEndian.AppendUint16(nil, template.id)
+		record = binary.BigEndian.AppendUint16(record, uint16(len(fields)))
+		for _, field := range fields {
+			record = binary.BigEndian.AppendUint16(record, field.id)
+			record = binary.BigEndian.AppendUint16(record, field.length)
+		}
+		templates = append(templates, record)
+	}
+
+	return encodeSet(ipfixTemplateSetId, templates...)
+}
+
+func encodeSet(id uint16, records ...[]byte) []byte {
+	length := ipfixSetHeaderLength
+	for _, record := range records {
+		length += len(record)
+	}
+
+	set := binary.BigEndian.AppendUint16(make([]byte, 0, length), id)
+	set = binary.BigEndian.AppendUint16(set, uint16(length))
+	for _, record := range records {
+		set = append(set, record...)
+	}
+
+	return set
+}
+
+// encodeDataRecord returns the fields of the record in the order of the template of its address family.
+func encodeDataRecord(record domain.FlowRecord) []byte {
+	var b []byte
+	b = appendAddr(b, record.Source.Addr())
+	b = appendAddr(b, record.Destination.Addr())
+	b = binary.BigEndian.AppendUint16(b, record.Source.Port())
+	b = binary.BigEndian.AppendUint16(b, record.Destination.Port())
+	b = append(b, record.Protocol)
+	b = binary.BigEndian.AppendUint64(b, record.Bytes)
+	b = binary.BigEndian.AppendUint64(b, record.Packets)
+	b = binary.BigEndian.AppendUint64(b, uint64(record.StartedAt.UnixMilli()))
+	b = binary.BigEndian.AppendUint64(b, uint64(record.EndedAt.UnixMilli()))
+	b = appendVariableLength(b, []byte(record.InterfaceId))
+	b = appendVariableLength(b, []byte(record.UserId))
+
+	return b
+}
+
+func appendAddr(b []byte, addr netip.Addr) []byte {
+	if addr.Is4() {
+		ip := addr.As4()
+		return append(b, ip[:]...)
+	}
+	ip := addr.As16()
+	return append(b, ip[:]...)
+}
+
+// appendVariableLength appends a variable-length field, see RFC 7011 section 7. Values of up to 254 bytes have a
+// single byte length prefix, longer values have a three byte prefix.
+func appendVariableLength(b []byte, value []byte) []byte {
+	if len(value) > 65535 {
+		value = value[:65535]
+	}
+	if len(value) < 255 {
+		b = append(b, byte(len(value)))
+	} else {
+		b = append(b, 255)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(value)))
+	}
+
+	return append(b, value...)
+}
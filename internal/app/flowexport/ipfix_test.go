@@ -0,0 +1,119 @@
+package flowexport
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func testRecord(source, destination string) domain.FlowRecord {
+	start := time.Date(2024, 10, 14, 10, 0, 0, 0, time.UTC)
+	return domain.FlowRecord{
+		FlowPeer:    domain.FlowPeer{PeerId: "peer", InterfaceId: "wg0", UserId: "alice"},
+		Protocol:    6,
+		Source:      netip.MustParseAddrPort(source),
+		Destination: netip.MustParseAddrPort(destination),
+		Packets:     10,
+		Bytes:       1500,
+		StartedAt:   start,
+		EndedAt:     start.Add(time.Minute),
+	}
+}
+
+// decodeSets returns the sets of an IPFIX message by their set id.
+func decodeSets(t *testing.T, msg []byte) map[uint16][]byte {
+	require.GreaterOrEqual(t, len(msg), ipfixMessageHeaderLength)
+	require.Equal(t, uint16(ipfixVersion), binary.BigEndian.Uint16(msg[0:]))
+	require.Equal(t, len(msg), int(binary.BigEndian.Uint16(msg[2:])))
+
+	sets := make(map[uint16][]byte)
+	for body := msg[ipfixMessageHeaderLength:]; len(body) > 0; {
+		require.GreaterOrEqual(t, len(body), ipfixSetHeaderLength)
+		length := int(binary.BigEndian.Uint16(body[2:]))
+		require.LessOrEqual(t, length, len(body))
+		sets[binary.BigEndian.Uint16(body[0:])] = body[ipfixSetHeaderLength:length]
+		body = body[length:]
+	}
+
+	return sets
+}
+
+func TestIpfixEncoder_Encode(t *testing.T) {
+	encoder := &ipfixEncoder{domainId: 42, maxMessageSize: maxMessageSize}
+	now := time.Date(2024, 10, 14, 10, 1, 0, 0, time.UTC)
+
+	messages := encoder.encode([]domain.FlowRecord{
+		testRecord("10.11.12.2:40000", "192.0.2.10:443"),
+		testRecord("[fd00::2]:40000", "[2001:db8::10]:443"),
+	}, now)
+	require.Len(t, messages, 1)
+
+	msg := messages[0]
+	assert.Equal(t, uint32(now.Unix()), binary.BigEndian.Uint32(msg[4:]))
+	assert.Equal(t, uint32(0), binary.BigEndian.Uint32(msg[8:]), "sequence number of the first message")
+	assert.Equal(t, uint32(42), binary.BigEndian.Uint32(msg[12:]))
+
+	sets := decodeSets(t, msg)
+	require.Contains(t, sets, uint16(ipfixTemplateSetId))
+	templates := sets[ipfixTemplateSetId]
+	assert.Equal(t, templateIdV4, binary.BigEndian.Uint16(templates[0:]))
+	assert.Equal(t, uint16(11), binary.BigEndian.Uint16(templates[2:]), "field count")
+	assert.Equal(t, ieSourceIPv4Address.id, binary.BigEndian.Uint16(templates[4:]))
+
+	require.Contains(t, sets, templateIdV4)
+	v4 := sets[templateIdV4]
+	assert.Equal(t, []byte{10, 11, 12, 2}, v4[0:4])
+	assert.Equal(t, []byte{192, 0, 2, 10}, v4[4:8])
+	assert.Equal(t, uint16(40000), binary.BigEndian.Uint16(v4[8:]))
+	assert.Equal(t, uint16(443), binary.BigEndian.Uint16(v4[10:]))
+	assert.Equal(t, byte(6), v4[12])
+	assert.Equal(t, uint64(1500), binary.BigEndian.Uint64(v4[13:]))
+	assert.Equal(t, uint64(10), binary.BigEndian.Uint64(v4[21:]))
+	assert.Equal(t, uint64(now.Add(-time.Minute).UnixMilli()), binary.BigEndian.Uint64(v4[29:]))
+	assert.Equal(t, uint64(now.UnixMilli()), binary.BigEndian.Uint64(v4[37:]))
+	assert.Equal(t, []byte{3, 'w', 'g', '0', 5, 'a', 'l', 'i', 'c', 'e'}, v4[45:])
+
+	require.Contains(t, sets, templateIdV6)
+	v6 := sets[templateIdV6]
+	assert.Equal(t, netip.MustParseAddr("fd00::2").AsSlice(), v6[0:16])
+	assert.Equal(t, netip.MustParseAddr("2001:db8::10").AsSlice(), v6[16:32])
+}
+
+func TestIpfixEncoder_EncodeSplitsMessages(t *testing.T) {
+	encoder := &ipfixEncoder{domainId: 1, maxMessageSize: maxMessageSize}
+	now := time.Now()
+
+	records := make([]domain.FlowRecord, 100)
+	for i := range records {
+		records[i] = testRecord("10.11.12.2:40000", "192.0.2.10:443")
+	}
+
+	messages := encoder.encode(records, now)
+	require.Greater(t, len(messages), 1)
+
+	exported := 0
+	for _, msg := range messages {
+		assert.LessOrEqual(t, len(msg), maxMessageSize)
+		assert.Equal(t, uint32(exported), binary.BigEndian.Uint32(msg[8:]), "sequence counts previous records")
+		exported += len(decodeSets(t, msg)[templateIdV4]) / len(encodeDataRecord(records[0]))
+	}
+	assert.Equal(t, 100, exported)
+	assert.Equal(t, uint32(100), encoder.sequence)
+
+	assert.Empty(t, encoder.encode(nil, now), "no messages without records")
+}
+
+func TestAppendVariableLength(t *testing.T) {
+	assert.Equal(t, []byte{0}, appendVariableLength(nil, nil))
+
+	long := make([]byte, 300)
+	encoded := appendVariableLength(nil, long)
+	assert.Equal(t, []byte{255, 1, 44}, encoded[:3])
+	assert.Len(t, encoded, 303)
+}
@@ -0,0 +1,215 @@
+package flowexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers for a given interface
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+}
+
+type FlowSource interface {
+	// GetConnectionFlows returns all connections of the connection tracking table.
+	GetConnectionFlows(ctx context.Context) ([]domain.ConnectionFlow, error)
+}
+
+// endregion dependencies
+
+const maxMessageSize = 1400 // keeps the IPFIX messages below the common path MTU
+
+// Manager periodically reads the connection tracking table and exports the traffic of the connections of peers as
+// IPFIX flow records to a collector. Connections are assigned to peers by the tunnel addresses of the peers.
+type Manager struct {
+	cfg *config.Config
+
+	db    DatabaseRepo
+	flows FlowSource
+
+	state *exportState
+}
+
+// exportState is only accessed by the export loop.
+type exportState struct {
+	encoder *ipfixEncoder
+	flows   map[string]trackedFlow // the connections of the previous export, by connection key
+}
+
+type trackedFlow struct {
+	flow      domain.ConnectionFlow
+	firstSeen time.Time
+}
+
+// NewFlowExportManager creates a new flow export manager instance.
+func NewFlowExportManager(cfg *config.Config, db DatabaseRepo, flows FlowSource) (*Manager, error) {
+	if cfg.FlowExport.Enabled {
+		if _, _, err := net.SplitHostPort(cfg.FlowExport.CollectorAddress); err != nil {
+			return nil, fmt.Errorf("invalid flow export collector address: %w", err)
+		}
+		if cfg.FlowExport.ExportInterval <= 0 {
+			return nil, fmt.Errorf("invalid flow export interval %s", cfg.FlowExport.ExportInterval)
+		}
+	}
+
+	m := &Manager{
+		cfg: cfg,
+
+		db:    db,
+		flows: flows,
+
+		state: &exportState{
+			encoder: &ipfixEncoder{
+				domainId:       cfg.FlowExport.ObservationDomainId,
+				maxMessageSize: maxMessageSize,
+			},
+			flows: make(map[string]trackedFlow),
+		},
+	}
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the periodic export of flow records.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.FlowExport.Enabled {
+		return
+	}
+
+	conn, err := net.Dial("udp", m.cfg.FlowExport.CollectorAddress)
+	if err != nil {
+		slog.Error("failed to connect to flow collector", "collector", m.cfg.FlowExport.CollectorAddress,
+			"error", err)
+		return
+	}
+	slog.Info("started flow export", "collector", m.cfg.FlowExport.CollectorAddress,
+		"interval", m.cfg.FlowExport.ExportInterval)
+
+	go m.runExport(ctx, conn)
+}
+
+func (m Manager) runExport(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(m.cfg.FlowExport.ExportInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		if err := m.export(ctx, conn, time.Now()); err != nil {
+			slog.Error("failed to export flow records", "error", err)
+		}
+	}
+}
+
+// export sends the records of the traffic since the previous export to the collector.
+func (m Manager) export(ctx context.Context, w io.Writer, now time.Time) error {
+	records, err := m.collect(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, msg := range m.state.encoder.encode(records, now) {
+		if _, err := w.Write(msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to send %d IPFIX messages: %w", len(errs), errors.Join(errs...))
+	}
+
+	slog.Debug("exported flow records", "records", len(records), "connections", len(m.state.flows))
+
+	return nil
+}
+
+// collect reads the connection tracking table and returns the records of the connections of peers. Connections
+// that were not seen again are forgotten.
+func (m Manager) collect(ctx context.Context, now time.Time) ([]domain.FlowRecord, error) {
+	peers, err := m.peerAddresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	flows, err := m.flows.GetConnectionFlows(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []domain.FlowRecord
+	seen := make(map[string]trackedFlow, len(flows))
+	for _, flow := range flows {
+		peer, ok := peers[flow.Source.Addr()]
+		if !ok {
+			peer, ok = peers[flow.Destination.Addr()]
+		}
+		if !ok {
+			continue // no tunnel connection
+		}
+
+		key := flow.Key()
+		tracked, known := m.state.flows[key]
+		var previous *domain.ConnectionFlow
+		if known {
+			previous = &tracked.flow
+		} else {
+			tracked.firstSeen = now
+		}
+
+		records = append(records, domain.NewFlowRecords(peer, previous, flow, tracked.firstSeen, now)...)
+
+		tracked.flow = flow
+		seen[key] = tracked
+	}
+	m.state.flows = seen
+
+	return records, nil
+}
+
+// peerAddresses returns the peers by their tunnel addresses.
+func (m Manager) peerAddresses(ctx context.Context) (map[netip.Addr]domain.FlowPeer, error) {
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	addresses := make(map[netip.Addr]domain.FlowPeer)
+	for _, iface := range interfaces {
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load peers of interface %s: %w", iface.Identifier, err)
+		}
+
+		for _, peer := range peers {
+			for _, addr := range peer.Interface.Addresses {
+				addresses[addr.Prefix().Addr()] = domain.FlowPeer{
+					PeerId:      peer.Identifier,
+					InterfaceId: iface.Identifier,
+					UserId:      peer.UserIdentifier,
+				}
+			}
+		}
+	}
+
+	return addresses, nil
+}
@@ -0,0 +1,147 @@
+package flowexport
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	interfaces []domain.Interface
+	peers      map[domain.InterfaceIdentifier][]domain.Peer
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	return f.peers[id], nil
+}
+
+type fakeFlowSource struct {
+	flows []domain.ConnectionFlow
+}
+
+func (f *fakeFlowSource) GetConnectionFlows(_ context.Context) ([]domain.ConnectionFlow, error) {
+	return f.flows, nil
+}
+
+func newTestManager(t *testing.T, flows *fakeFlowSource) *Manager {
+	cfg := &config.Config{}
+	cfg.FlowExport = config.FlowExportConfig{
+		Enabled:             true,
+		CollectorAddress:    "127.0.0.1:4739",
+		ExportInterval:      time.Minute,
+		ObservationDomainId: 1,
+	}
+
+	addr, err := domain.CidrFromString("10.11.12.2/32")
+	require.NoError(t, err)
+	db := &fakeDatabase{
+		interfaces: []domain.Interface{{Identifier: "wg0"}},
+		peers: map[domain.InterfaceIdentifier][]domain.Peer{
+			"wg0": {{
+				Identifier:     "peer",
+				UserIdentifier: "alice",
+				Interface:      domain.PeerInterfaceConfig{Addresses: []domain.Cidr{addr}},
+			}},
+		},
+	}
+
+	m, err := NewFlowExportManager(cfg, db, flows)
+	require.NoError(t, err)
+	return m
+}
+
+func TestManager_Collect(t *testing.T) {
+	tunnel := domain.ConnectionFlow{
+		Protocol:     6,
+		Source:       netip.MustParseAddrPort("10.11.12.2:40000"),
+		Destination:  netip.MustParseAddrPort("192.0.2.10:443"),
+		Packets:      10,
+		Bytes:        1000,
+		ReplyPackets: 5,
+		ReplyBytes:   4000,
+	}
+	inbound := domain.ConnectionFlow{
+		Protocol:    6,
+		Source:      netip.MustParseAddrPort("192.0.2.20:50000"),
+		Destination: netip.MustParseAddrPort("10.11.12.2:22"),
+		Packets:     3,
+		Bytes:       300,
+	}
+	other := domain.ConnectionFlow{
+		Protocol:    17,
+		Source:      netip.MustParseAddrPort("192.168.1.5:5353"),
+		Destination: netip.MustParseAddrPort("192.168.1.1:53"),
+		Packets:     1,
+		Bytes:       80,
+	}
+	flows := &fakeFlowSource{flows: []domain.ConnectionFlow{tunnel, inbound, other}}
+	m := newTestManager(t, flows)
+	start := time.Date(2024, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	records, err := m.collect(context.Background(), start)
+	require.NoError(t, err)
+	require.Len(t, records, 3, "both directions of the tunnel connection and the inbound connection")
+	for _, record := range records {
+		assert.Equal(t, domain.FlowPeer{PeerId: "peer", InterfaceId: "wg0", UserId: "alice"}, record.FlowPeer)
+	}
+
+	updated := tunnel
+	updated.Packets, updated.Bytes = 12, 1200
+	flows.flows = []domain.ConnectionFlow{updated, other}
+
+	records, err = m.collect(context.Background(), start.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, uint64(2), records[0].Packets)
+	assert.Equal(t, uint64(200), records[0].Bytes)
+	assert.Equal(t, start, records[0].StartedAt)
+	assert.Len(t, m.state.flows, 1, "closed and foreign connections are not tracked")
+}
+
+func TestManager_Export(t *testing.T) {
+	flows := &fakeFlowSource{flows: []domain.ConnectionFlow{{
+		Protocol:    1,
+		Source:      netip.MustParseAddrPort("10.11.12.2:0"),
+		Destination: netip.MustParseAddrPort("192.0.2.10:0"),
+		Packets:     4,
+		Bytes:       336,
+	}}}
+	m := newTestManager(t, flows)
+
+	var buf bytes.Buffer
+	require.NoError(t, m.export(context.Background(), &buf, time.Now()))
+	sets := decodeSets(t, buf.Bytes())
+	assert.Contains(t, sets, templateIdV4)
+
+	buf.Reset()
+	require.NoError(t, m.export(context.Background(), &buf, time.Now()))
+	assert.Zero(t, buf.Len(), "no message without new traffic")
+}
+
+func TestNewFlowExportManager_Validation(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.FlowExport = config.FlowExportConfig{Enabled: true, CollectorAddress: "collector", ExportInterval: time.Minute}
+	_, err := NewFlowExportManager(cfg, &fakeDatabase{}, &fakeFlowSource{})
+	assert.Error(t, err, "missing port")
+
+	cfg.FlowExport.CollectorAddress = "collector:4739"
+	cfg.FlowExport.ExportInterval = 0
+	_, err = NewFlowExportManager(cfg, &fakeDatabase{}, &fakeFlowSource{})
+	assert.Error(t, err)
+
+	cfg.FlowExport.Enabled = false
+	_, err = NewFlowExportManager(cfg, &fakeDatabase{}, &fakeFlowSource{})
+	assert.NoError(t, err, "the configuration is not validated if the export is disabled")
+}
@@ -118,6 +118,30 @@ func (s GormEncryptedStringSerializer) Value(
 	}
 }
 
+// EncryptString encrypts the given value for storage backends that do not use GORM. Empty values are not encrypted.
+// If no passphrase is configured, the value is returned unchanged.
+func (s GormEncryptedStringSerializer) EncryptString(value string) (string, error) {
+	if value == "" || !s.useEncryption {
+		return value, nil
+	}
+
+	encryptedString, err := EncryptAES256(value, s.keyPhrase)
+	if err != nil {
+		return "", err
+	}
+	return s.prefix + encryptedString, nil
+}
+
+// DecryptString decrypts a value that was encrypted with EncryptString. Values without the encryption prefix
+// are returned unchanged.
+func (s GormEncryptedStringSerializer) DecryptString(value string) (string, error) {
+	if !s.useEncryption || !strings.HasPrefix(value, s.prefix) {
+		return value, nil
+	}
+
+	return DecryptAES256(strings.TrimPrefix(value, s.prefix), s.keyPhrase)
+}
+
 // EncryptAES256 encrypts the given plaintext with the given key using AES256 in CBC mode with PKCS7 padding
 func EncryptAES256(plaintext, key string) (string, error) {
 	if len(plaintext) == 0 {
@@ -0,0 +1,280 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+}
+
+type InterfaceController interface {
+	// GetInterfaces returns all physical WireGuard interfaces.
+	GetInterfaces(_ context.Context) ([]domain.PhysicalInterface, error)
+}
+
+type ServiceNotifier interface {
+	// Notify sends the given newline separated state assignments, for example READY=1, to the service manager.
+	Notify(state string) error
+	// WatchdogTimeout returns the timeout of the watchdog of the service manager, zero if it is disabled.
+	WatchdogTimeout() time.Duration
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+const (
+	checkDatabase  = "database"
+	checkWireGuard = "wireguard"
+)
+
+// Manager periodically checks whether WireGuard Portal is still working. The database and the WireGuard
+// interfaces must respond within the check timeout, and the background loops that publish heartbeats must
+// keep running. The last result is served to container health checks and keeps the systemd watchdog alive.
+type Manager struct {
+	cfg *config.Config
+
+	db       DatabaseRepo
+	wg       InterfaceController
+	notifier ServiceNotifier
+
+	state *healthState
+}
+
+type healthState struct {
+	mux        sync.Mutex
+	heartbeats map[string]time.Time // the last run of each background loop
+	pending    map[string]bool      // checks that did not return yet
+	status     *domain.HealthStatus // nil until the first check finished
+}
+
+// NewHealthManager creates a new health manager.
+func NewHealthManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	wg InterfaceController,
+	notifier ServiceNotifier,
+) (*Manager, error) {
+	if cfg.Health.CheckInterval <= 0 || cfg.Health.CheckTimeout <= 0 || cfg.Health.StallTimeout <= 0 {
+		return nil, errors.New("health check interval, timeout and stall timeout must be positive")
+	}
+
+	m := &Manager{
+		cfg: cfg,
+
+		db:       db,
+		wg:       wg,
+		notifier: notifier,
+
+		state: &healthState{
+			heartbeats: make(map[string]time.Time),
+			pending:    make(map[string]bool),
+		},
+	}
+
+	if err := bus.Subscribe(app.TopicHealthHeartbeat, m.handleHeartbeatEvent); err != nil {
+		return nil, fmt.Errorf("failed to subscribe to heartbeats: %w", err)
+	}
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the health checks and the systemd watchdog notifications.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	go m.runChecks(ctx)
+
+	if timeout := m.notifier.WatchdogTimeout(); timeout > 0 {
+		go m.runWatchdog(ctx, timeout)
+	}
+
+	slog.Debug("started health checks", "interval", m.cfg.Health.CheckInterval,
+		"watchdog", m.notifier.WatchdogTimeout())
+}
+
+// NotifyReady informs the service manager that the startup is complete.
+func (m Manager) NotifyReady() {
+	if err := m.notifier.Notify("READY=1\nSTATUS=WireGuard Portal is running"); err != nil {
+		slog.Warn("failed to send readiness notification", "error", err)
+	}
+}
+
+func (m Manager) handleHeartbeatEvent(loop string) {
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	m.state.heartbeats[loop] = time.Now()
+}
+
+func (m Manager) runChecks(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	ticker := time.NewTicker(m.cfg.Health.CheckInterval)
+	defer ticker.Stop()
+	for {
+		status := m.check(ctx, time.Now())
+		if !status.Healthy {
+			for _, c := range status.Checks {
+				if !c.Healthy {
+					slog.Warn("health check failed", "check", c.Name, "error", c.Error)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if err := m.notifier.Notify("STOPPING=1"); err != nil {
+				slog.Warn("failed to send stopping notification", "error", err)
+			}
+			return // program stopped
+		case <-ticker.C:
+		}
+	}
+}
+
+// check runs all health checks and stores the result.
+func (m Manager) check(ctx context.Context, now time.Time) domain.HealthStatus {
+	checks := []domain.HealthCheck{
+		m.probe(ctx, checkDatabase, func(ctx context.Context) error {
+			_, err := m.db.GetAllInterfaces(ctx)
+			return err
+		}),
+		m.probe(ctx, checkWireGuard, func(ctx context.Context) error {
+			_, err := m.wg.GetInterfaces(ctx)
+			return err
+		}),
+	}
+
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	loops := make([]string, 0, len(m.state.heartbeats))
+	for loop := range m.state.heartbeats {
+		loops = append(loops, loop)
+	}
+	slices.Sort(loops)
+	for _, loop := range loops {
+		check := domain.HealthCheck{Name: loop, Healthy: true}
+		if lastRun := m.state.heartbeats[loop]; now.Sub(lastRun) > m.cfg.Health.StallTimeout {
+			check.Healthy = false
+			check.Error = fmt.Sprintf("no progress since %s", lastRun.Format(time.RFC3339))
+		}
+		checks = append(checks, check)
+	}
+
+	status := domain.HealthStatus{Healthy: true, Checks: checks, CheckedAt: now}
+	for _, c := range checks {
+		status.Healthy = status.Healthy && c.Healthy
+	}
+	m.state.status = &status
+
+	return status
+}
+
+// probe runs a single check with the check timeout. The check function might not return at all if it hangs in
+// a system call, so a check is never started again while a previous run is still pending.
+func (m Manager) probe(ctx context.Context, name string, fn func(ctx context.Context) error) domain.HealthCheck {
+	m.state.mux.Lock()
+	if m.state.pending[name] {
+		m.state.mux.Unlock()
+		return domain.HealthCheck{Name: name, Error: "previous check did not return"}
+	}
+	m.state.pending[name] = true
+	m.state.mux.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Health.CheckTimeout)
+	result := make(chan error, 1)
+	go func() {
+		defer cancel()
+		err := fn(ctx)
+
+		m.state.mux.Lock()
+		delete(m.state.pending, name)
+		m.state.mux.Unlock()
+
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return domain.HealthCheck{Name: name, Error: err.Error()}
+		}
+		return domain.HealthCheck{Name: name, Healthy: true}
+	case <-time.After(m.cfg.Health.CheckTimeout):
+		return domain.HealthCheck{Name: name, Error: "check timed out"}
+	}
+}
+
+// runWatchdog sends keep-alive notifications to the service manager as long as WireGuard Portal is healthy.
+// The notifications stop if a check fails or the checks stop running, so the service manager restarts
+// WireGuard Portal once the watchdog timeout is exceeded.
+func (m Manager) runWatchdog(ctx context.Context, timeout time.Duration) {
+	ticker := time.NewTicker(timeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return // program stopped
+		case <-ticker.C:
+		}
+
+		if !m.isAlive(time.Now()) {
+			slog.Warn("WireGuard Portal is unhealthy, skipping watchdog notification")
+			continue
+		}
+		if err := m.notifier.Notify("WATCHDOG=1"); err != nil {
+			slog.Warn("failed to send watchdog notification", "error", err)
+		}
+	}
+}
+
+// isAlive returns true if the last check run is healthy and recent. The watchdog is also kept alive until the
+// first check finished.
+func (m Manager) isAlive(now time.Time) bool {
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	if m.state.status == nil {
+		return true
+	}
+	return m.state.status.Healthy && m.isRecent(m.state.status, now)
+}
+
+// isRecent returns false if the checks stopped running.
+func (m Manager) isRecent(status *domain.HealthStatus, now time.Time) bool {
+	return now.Sub(status.CheckedAt) <= m.cfg.Health.CheckInterval+2*m.cfg.Health.CheckTimeout
+}
+
+// GetHealth returns the result of the last health check run. No authentication is required, the checks are
+// only run in the background.
+func (m Manager) GetHealth(_ context.Context) (*domain.HealthStatus, error) {
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	if m.state.status == nil {
+		return &domain.HealthStatus{Healthy: false}, nil // still starting
+	}
+	status := *m.state.status
+	status.Checks = slices.Clone(status.Checks)
+	status.Healthy = status.Healthy && m.isRecent(&status, time.Now())
+	return &status, nil
+}
@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	err error
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return nil, f.err
+}
+
+type fakeController struct {
+	block chan struct{} // if set, GetInterfaces blocks until the channel is closed
+}
+
+func (f *fakeController) GetInterfaces(_ context.Context) ([]domain.PhysicalInterface, error) {
+	if f.block != nil {
+		<-f.block
+	}
+	return nil, nil
+}
+
+type fakeNotifier struct {
+	states []string
+}
+
+func (f *fakeNotifier) Notify(state string) error {
+	f.states = append(f.states, state)
+	return nil
+}
+
+func (f *fakeNotifier) WatchdogTimeout() time.Duration { return 0 }
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func newTestManager(t *testing.T) (*Manager, *fakeDatabase, *fakeController, *fakeNotifier) {
+	cfg := &config.Config{}
+	cfg.Health = config.HealthConfig{
+		CheckInterval: 30 * time.Second,
+		CheckTimeout:  50 * time.Millisecond,
+		StallTimeout:  5 * time.Minute,
+	}
+
+	db := &fakeDatabase{}
+	wg := &fakeController{}
+	notifier := &fakeNotifier{}
+	m, err := NewHealthManager(cfg, fakeBus{}, db, wg, notifier)
+	require.NoError(t, err)
+
+	return m, db, wg, notifier
+}
+
+func TestNewHealthManager_InvalidConfig(t *testing.T) {
+	cfg := &config.Config{}
+	_, err := NewHealthManager(cfg, fakeBus{}, &fakeDatabase{}, &fakeController{}, &fakeNotifier{})
+	assert.Error(t, err)
+}
+
+func TestManager_check(t *testing.T) {
+	m, db, _, _ := newTestManager(t)
+	now := time.Now()
+
+	status := m.check(context.Background(), now)
+	assert.True(t, status.Healthy)
+	assert.Equal(t, []domain.HealthCheck{
+		{Name: checkDatabase, Healthy: true},
+		{Name: checkWireGuard, Healthy: true},
+	}, status.Checks)
+	assert.True(t, m.isAlive(now))
+
+	db.err = errors.New("database is locked")
+	status = m.check(context.Background(), now)
+	assert.False(t, status.Healthy)
+	assert.Equal(t, domain.HealthCheck{Name: checkDatabase, Error: "database is locked"}, status.Checks[0])
+	assert.False(t, m.isAlive(now))
+}
+
+func TestManager_check_Timeout(t *testing.T) {
+	m, _, wg, _ := newTestManager(t)
+	wg.block = make(chan struct{})
+
+	status := m.check(context.Background(), time.Now())
+	assert.False(t, status.Healthy)
+	assert.Equal(t, domain.HealthCheck{Name: checkWireGuard, Error: "check timed out"}, status.Checks[1])
+
+	// the hanging check is not started again
+	status = m.check(context.Background(), time.Now())
+	assert.Equal(t, domain.HealthCheck{Name: checkWireGuard, Error: "previous check did not return"},
+		status.Checks[1])
+
+	close(wg.block)
+	assert.Eventually(t, func() bool {
+		return m.check(context.Background(), time.Now()).Healthy
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestManager_check_StalledLoop(t *testing.T) {
+	m, _, _, _ := newTestManager(t)
+	m.handleHeartbeatEvent("peer_data_collection")
+	now := time.Now()
+
+	status := m.check(context.Background(), now)
+	assert.True(t, status.Healthy)
+	assert.Contains(t, status.Checks, domain.HealthCheck{Name: "peer_data_collection", Healthy: true})
+
+	status = m.check(context.Background(), now.Add(10*time.Minute))
+	assert.False(t, status.Healthy)
+	assert.False(t, status.Checks[2].Healthy)
+	assert.Contains(t, status.Checks[2].Error, "no progress since")
+}
+
+func TestManager_GetHealth(t *testing.T) {
+	m, _, _, _ := newTestManager(t)
+
+	health, err := m.GetHealth(context.Background())
+	require.NoError(t, err)
+	assert.False(t, health.Healthy) // no check finished yet
+	assert.True(t, m.isAlive(time.Now()))
+
+	m.check(context.Background(), time.Now())
+	health, err = m.GetHealth(context.Background())
+	require.NoError(t, err)
+	assert.True(t, health.Healthy)
+
+	// the checks stopped running
+	m.check(context.Background(), time.Now().Add(-time.Hour))
+	health, err = m.GetHealth(context.Background())
+	require.NoError(t, err)
+	assert.False(t, health.Healthy)
+	assert.False(t, m.isAlive(time.Now()))
+}
+
+func TestManager_NotifyReady(t *testing.T) {
+	m, _, _, notifier := newTestManager(t)
+
+	m.NotifyReady()
+	require.Len(t, notifier.states, 1)
+	assert.Contains(t, notifier.states[0], "READY=1")
+}
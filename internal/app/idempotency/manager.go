@@ -0,0 +1,153 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// RetentionPeriod is the duration for which the responses of idempotent requests are stored and replayed.
+const RetentionPeriod = 24 * time.Hour
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetIdempotencyRecord returns the stored response for the given idempotency key of the given user.
+	GetIdempotencyRecord(ctx context.Context, userId domain.UserIdentifier, key string) (
+		*domain.IdempotencyRecord,
+		error,
+	)
+	// SaveIdempotencyRecord creates or updates the given idempotency record.
+	SaveIdempotencyRecord(ctx context.Context, record *domain.IdempotencyRecord) error
+	// DeleteIdempotencyRecords deletes all idempotency records that were created before the given time.
+	DeleteIdempotencyRecords(ctx context.Context, before time.Time) error
+}
+
+// endregion dependencies
+
+// Manager stores the responses of requests with an Idempotency-Key header, so that retries of automation scripts
+// receive the original response instead of creating a second peer or interface.
+// Keys are unique per user. Requests that are still running are tracked in memory, so a concurrent retry with the
+// same key is rejected instead of being executed twice.
+type Manager struct {
+	db DatabaseRepo
+
+	mux      *sync.Mutex
+	inFlight map[string]struct{} // the user and key of running requests
+}
+
+// NewIdempotencyManager creates a new idempotency manager instance.
+func NewIdempotencyManager(db DatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		db: db,
+
+		mux:      &sync.Mutex{},
+		inFlight: make(map[string]struct{}),
+	}
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background jobs for the idempotency manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	go m.runCleanup(ctx)
+}
+
+func (m Manager) runCleanup(ctx context.Context) {
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(time.Hour):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		if err := m.db.DeleteIdempotencyRecords(ctx, time.Now().Add(-RetentionPeriod)); err != nil {
+			slog.Error("failed to delete expired idempotency records", "error", err)
+		}
+	}
+}
+
+// Begin starts a request with the given idempotency key for the current user. If the response of an earlier
+// request with the same key is stored, it is returned and the request must not be executed again. Otherwise, the
+// key is reserved until Finish is called.
+// The request hash identifies the request, reusing a key for a different request fails with
+// domain.ErrIdempotencyKeyReused.
+func (m Manager) Begin(ctx context.Context, key, requestHash string) (*domain.IdempotencyRecord, error) {
+	if err := domain.ValidateIdempotencyKey(key); err != nil {
+		return nil, err
+	}
+	userId := domain.GetUserInfo(ctx).Id
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if _, running := m.inFlight[inFlightKey(userId, key)]; running {
+		return nil, fmt.Errorf("a request with idempotency key %s is still in progress: %w", key,
+			domain.ErrDuplicateEntry)
+	}
+
+	record, err := m.db.GetIdempotencyRecord(ctx, userId, key)
+	switch {
+	case err == nil && !record.IsExpired(time.Now(), RetentionPeriod):
+		if record.RequestHash != requestHash {
+			return nil, fmt.Errorf("idempotency key %s: %w", key, domain.ErrIdempotencyKeyReused)
+		}
+		return record, nil
+	case err != nil && !errors.Is(err, domain.ErrNotFound):
+		return nil, fmt.Errorf("failed to load idempotency record: %w", err)
+	}
+
+	m.inFlight[inFlightKey(userId, key)] = struct{}{}
+
+	return nil, nil
+}
+
+// Finish stores the response of a request that was started with Begin and releases the key. Server errors are
+// not stored, so that the request can be retried with the same key.
+func (m Manager) Finish(
+	ctx context.Context,
+	key, requestHash string,
+	statusCode int,
+	contentType string,
+	body []byte,
+) error {
+	userId := domain.GetUserInfo(ctx).Id
+
+	defer func() {
+		m.mux.Lock()
+		delete(m.inFlight, inFlightKey(userId, key))
+		m.mux.Unlock()
+	}()
+
+	if statusCode < 200 || statusCode >= 500 {
+		return nil
+	}
+
+	err := m.db.SaveIdempotencyRecord(ctx, &domain.IdempotencyRecord{
+		UserIdentifier: userId,
+		Key:            key,
+		RequestHash:    requestHash,
+		StatusCode:     statusCode,
+		ContentType:    contentType,
+		Body:           body,
+		CreatedAt:      time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+func inFlightKey(userId domain.UserIdentifier, key string) string {
+	return string(userId) + "\x00" + key
+}
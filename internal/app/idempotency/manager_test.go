@@ -0,0 +1,99 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	records map[string]domain.IdempotencyRecord
+}
+
+func (f *fakeDatabase) GetIdempotencyRecord(_ context.Context, userId domain.UserIdentifier, key string) (
+	*domain.IdempotencyRecord,
+	error,
+) {
+	record, ok := f.records[inFlightKey(userId, key)]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &record, nil
+}
+
+func (f *fakeDatabase) SaveIdempotencyRecord(_ context.Context, record *domain.IdempotencyRecord) error {
+	f.records[inFlightKey(record.UserIdentifier, record.Key)] = *record
+	return nil
+}
+
+func (f *fakeDatabase) DeleteIdempotencyRecords(_ context.Context, before time.Time) error {
+	for key, record := range f.records {
+		if record.CreatedAt.Before(before) {
+			delete(f.records, key)
+		}
+	}
+	return nil
+}
+
+func TestManager_BeginFinish(t *testing.T) {
+	db := &fakeDatabase{records: make(map[string]domain.IdempotencyRecord)}
+	m, _ := NewIdempotencyManager(db)
+	aliceCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	bobCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "bob"})
+
+	record, err := m.Begin(aliceCtx, "key-1", "hash-1")
+	require.NoError(t, err)
+	assert.Nil(t, record, "the first request must be executed")
+
+	_, err = m.Begin(aliceCtx, "key-1", "hash-1")
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry, "concurrent retries are rejected")
+	record, err = m.Begin(bobCtx, "key-1", "hash-1")
+	require.NoError(t, err)
+	assert.Nil(t, record, "keys are unique per user")
+
+	require.NoError(t, m.Finish(aliceCtx, "key-1", "hash-1", 201, "application/json", []byte(`{"id":1}`)))
+
+	record, err = m.Begin(aliceCtx, "key-1", "hash-1")
+	require.NoError(t, err)
+	require.NotNil(t, record, "retries receive the stored response")
+	assert.Equal(t, 201, record.StatusCode)
+	assert.Equal(t, `{"id":1}`, string(record.Body))
+
+	_, err = m.Begin(aliceCtx, "key-1", "hash-2")
+	assert.ErrorIs(t, err, domain.ErrIdempotencyKeyReused)
+
+	_, err = m.Begin(aliceCtx, "", "hash-1")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
+
+func TestManager_Finish_serverError(t *testing.T) {
+	db := &fakeDatabase{records: make(map[string]domain.IdempotencyRecord)}
+	m, _ := NewIdempotencyManager(db)
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+
+	_, err := m.Begin(ctx, "key-1", "hash-1")
+	require.NoError(t, err)
+	require.NoError(t, m.Finish(ctx, "key-1", "hash-1", 500, "application/json", nil))
+	assert.Empty(t, db.records, "server errors are not stored")
+
+	record, err := m.Begin(ctx, "key-1", "hash-1")
+	require.NoError(t, err)
+	assert.Nil(t, record, "the request can be retried after a server error")
+}
+
+func TestManager_Begin_expired(t *testing.T) {
+	db := &fakeDatabase{records: make(map[string]domain.IdempotencyRecord)}
+	m, _ := NewIdempotencyManager(db)
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	require.NoError(t, db.SaveIdempotencyRecord(ctx, &domain.IdempotencyRecord{UserIdentifier: "alice",
+		Key: "key-1", RequestHash: "hash-1", StatusCode: 201, CreatedAt: time.Now().Add(-RetentionPeriod - time.Minute)}))
+
+	record, err := m.Begin(ctx, "key-1", "hash-2")
+	require.NoError(t, err)
+	assert.Nil(t, record, "expired records are not replayed")
+}
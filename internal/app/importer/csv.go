@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// maxImportRows limits the number of rows of a single import. Larger lists must be split.
+const maxImportRows = 10000
+
+var userFields = []domain.ImportField{
+	{Key: "identifier", Title: "Identifier", Required: true},
+	{Key: "email", Title: "Email"},
+	{Key: "firstname", Title: "Firstname"},
+	{Key: "lastname", Title: "Lastname"},
+	{Key: "phone", Title: "Phone"},
+	{Key: "department", Title: "Department"},
+	{Key: "notes", Title: "Notes"},
+	{Key: "password", Title: "Password", Required: true},
+	{Key: "is_admin", Title: "Admin"},
+}
+
+var peerFields = []domain.ImportField{
+	{Key: "display_name", Title: "Display Name"},
+	{Key: "user", Title: "User"},
+	{Key: "addresses", Title: "Addresses"},
+	{Key: "notes", Title: "Notes"},
+	{Key: "expires_at", Title: "Expires At"},
+	{Key: "mail_recipients", Title: "Mail Recipients"},
+}
+
+// parseCsv returns the column names of the first row and all other records of the data.
+// The delimiter is detected from the first row, spreadsheet applications use semicolons in some locales.
+func parseCsv(data string) ([]string, [][]string, []int, error) {
+	data = strings.TrimPrefix(data, "\ufeff") // byte order mark of UTF-8 exports
+
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comma = detectDelimiter(data)
+	r.FieldsPerRecord = -1 // missing trailing values are treated as empty
+	r.TrimLeadingSpace = true
+
+	columns, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, nil, fmt.Errorf("no CSV data: %w", domain.ErrInvalidData)
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid CSV header: %v: %w", err, domain.ErrInvalidData)
+	}
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+
+	var records [][]string
+	var lines []int
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid CSV data: %v: %w", err, domain.ErrInvalidData)
+		}
+		if len(records) == maxImportRows {
+			return nil, nil, nil, fmt.Errorf("too many rows, at most %d rows can be imported at once: %w",
+				maxImportRows, domain.ErrInvalidData)
+		}
+
+		line, _ := r.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+
+	return columns, records, lines, nil
+}
+
+func detectDelimiter(data string) rune {
+	header, _, _ := strings.Cut(data, "\n")
+	if strings.Count(header, ";") > strings.Count(header, ",") {
+		return ';'
+	}
+	return ','
+}
+
+// resolveMapping returns the column index of each mapped field and the effective mapping.
+// Fields without explicit mapping are matched with the column that is named like the field key or title, ignoring
+// case, spaces, dashes and underscores. So the files of the list export can be imported without a mapping.
+func resolveMapping(
+	fields []domain.ImportField,
+	columns []string,
+	mapping map[string]string,
+) (map[string]int, map[string]string, error) {
+	for key := range mapping {
+		if !isField(fields, key) {
+			return nil, nil, fmt.Errorf("unknown import field %q: %w", key, domain.ErrInvalidData)
+		}
+	}
+
+	indexes := make(map[string]int)
+	effective := make(map[string]string)
+	for _, field := range fields {
+		column, explicit := mapping[field.Key]
+		idx := -1
+		for i, name := range columns {
+			if explicit && name == column {
+				idx = i
+				break
+			}
+			if !explicit && (normalizeName(name) == normalizeName(field.Key) ||
+				normalizeName(name) == normalizeName(field.Title)) {
+				idx = i
+				break
+			}
+		}
+		if explicit && column != "" && idx < 0 {
+			return nil, nil, fmt.Errorf("unknown column %q for field %s: %w", column, field.Key, domain.ErrInvalidData)
+		}
+		if idx >= 0 {
+			indexes[field.Key] = idx
+			effective[field.Key] = columns[idx]
+		}
+	}
+
+	return indexes, effective, nil
+}
+
+func isField(fields []domain.ImportField, key string) bool {
+	for _, field := range fields {
+		if field.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeName(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// mapRows converts the records to import rows with the values of all mapped fields.
+func mapRows(records [][]string, lines []int, indexes map[string]int) []domain.ImportRow {
+	rows := make([]domain.ImportRow, len(records))
+	for i, record := range records {
+		values := make(map[string]string, len(indexes))
+		for key, idx := range indexes {
+			if idx < len(record) {
+				values[key] = unescapeCsvFormula(strings.TrimSpace(record[idx]))
+			}
+		}
+		rows[i] = domain.ImportRow{Line: lines[i], Values: values}
+	}
+	return rows
+}
+
+// unescapeCsvFormula removes the quote that the list export adds in front of values that look like formulas.
+func unescapeCsvFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune("=+-@", rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
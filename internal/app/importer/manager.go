@@ -0,0 +1,386 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type UserService interface {
+	// GetUser returns the user with the given identifier.
+	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	// CreateUser creates a new user.
+	CreateUser(ctx context.Context, user *domain.User) (*domain.User, error)
+}
+
+type PeerService interface {
+	// GetInterfaceAndPeers returns the interface with the given id and all peers associated with it.
+	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
+	// PreparePeer returns a new peer with default values for the given interface.
+	PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error)
+	// CreatePeer creates a new peer.
+	CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+}
+
+type MailService interface {
+	// SendPeerEmail sends the configuration of the given peers to the linked users.
+	SendPeerEmail(ctx context.Context, linkOnly bool, peers ...domain.PeerIdentifier) error
+}
+
+// endregion dependencies
+
+// Manager imports users and peers in bulk from CSV data. Every row is validated first, rows with errors are
+// skipped. The users and peers are created by the user and peer services, so all their checks apply as well.
+type Manager struct {
+	cfg *config.Config
+
+	users UserService
+	peers PeerService
+	mails MailService
+}
+
+// NewImportManager creates a new import manager.
+func NewImportManager(cfg *config.Config, users UserService, peers PeerService, mails MailService) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+
+		users: users,
+		peers: peers,
+		mails: mails,
+	}
+
+	return m, nil
+}
+
+// PreviewImport validates the given import without creating any user or peer.
+func (m Manager) PreviewImport(ctx context.Context, req domain.ImportRequest) (*domain.ImportReport, error) {
+	return m.runImport(ctx, req, false)
+}
+
+// Import creates the users or peers of all valid rows of the given import.
+func (m Manager) Import(ctx context.Context, req domain.ImportRequest) (*domain.ImportReport, error) {
+	report, err := m.runImport(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("imported from CSV", "kind", req.Kind, "interface", req.InterfaceId,
+		"created", report.Created, "failed", report.Failed)
+
+	return report, nil
+}
+
+func (m Manager) runImport(ctx context.Context, req domain.ImportRequest, execute bool) (*domain.ImportReport, error) {
+	var fields []domain.ImportField
+	switch req.Kind {
+	case domain.ImportKindUsers:
+		if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+			return nil, err
+		}
+		fields = userFields
+	case domain.ImportKindPeers:
+		iface, _, err := m.peers.GetInterfaceAndPeers(ctx, req.InterfaceId)
+		if err != nil {
+			return nil, err
+		}
+		if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+			return nil, err
+		}
+		fields = peerFields
+	default:
+		return nil, fmt.Errorf("unsupported import kind %q: %w", req.Kind, domain.ErrInvalidData)
+	}
+
+	columns, records, lines, err := parseCsv(req.Data)
+	if err != nil {
+		return nil, err
+	}
+	indexes, mapping, err := resolveMapping(fields, columns, req.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := mapRows(records, lines, indexes)
+	for i := range rows {
+		for _, field := range fields {
+			if field.Required && rows[i].Values[field.Key] == "" {
+				rows[i].Errors = append(rows[i].Errors, fmt.Sprintf("missing value for %s", field.Key))
+			}
+		}
+	}
+
+	switch req.Kind {
+	case domain.ImportKindUsers:
+		m.importUsers(ctx, rows, execute)
+	case domain.ImportKindPeers:
+		if err := m.importPeers(ctx, req, rows, execute); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &domain.ImportReport{
+		Columns: columns,
+		Fields:  fields,
+		Mapping: mapping,
+		Rows:    rows,
+	}
+	for _, row := range rows {
+		if row.Created {
+			report.Created++
+		}
+		if row.HasErrors() {
+			report.Failed++
+		}
+	}
+
+	return report, nil
+}
+
+// region users
+
+func (m Manager) importUsers(ctx context.Context, rows []domain.ImportRow, execute bool) {
+	seen := make(map[domain.UserIdentifier]int)
+	for i := range rows {
+		row := &rows[i]
+		user := m.validateUserRow(ctx, row, seen)
+		if password := row.Values["password"]; password != "" {
+			row.Values["password"] = "********" // the report must not contain the passwords
+		}
+		if row.HasErrors() || !execute {
+			continue
+		}
+
+		created, err := m.users.CreateUser(ctx, user)
+		if err != nil {
+			row.Errors = append(row.Errors, err.Error())
+			continue
+		}
+		row.Identifier = string(created.Identifier)
+		row.Created = true
+	}
+}
+
+func (m Manager) validateUserRow(
+	ctx context.Context,
+	row *domain.ImportRow,
+	seen map[domain.UserIdentifier]int,
+) *domain.User {
+	user := &domain.User{
+		Identifier: domain.UserIdentifier(row.Values["identifier"]),
+		Email:      row.Values["email"],
+		Source:     domain.UserSourceDatabase,
+		Firstname:  row.Values["firstname"],
+		Lastname:   row.Values["lastname"],
+		Phone:      row.Values["phone"],
+		Department: row.Values["department"],
+		Notes:      row.Values["notes"],
+		Password:   domain.PrivateString(row.Values["password"]),
+	}
+
+	if user.Identifier != "" {
+		if line, ok := seen[user.Identifier]; ok {
+			row.Errors = append(row.Errors, fmt.Sprintf("duplicate identifier, already used in line %d", line))
+		} else {
+			seen[user.Identifier] = row.Line
+		}
+
+		_, err := m.users.GetUser(ctx, user.Identifier)
+		switch {
+		case err == nil:
+			row.Errors = append(row.Errors, fmt.Sprintf("user %s already exists", user.Identifier))
+		case !errors.Is(err, domain.ErrNotFound):
+			row.Errors = append(row.Errors, err.Error())
+		}
+	}
+
+	if user.Email != "" {
+		if err := validateMailAddress(user.Email); err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		}
+	}
+
+	if isAdmin := row.Values["is_admin"]; isAdmin != "" {
+		value, err := strconv.ParseBool(isAdmin)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid admin flag %q", isAdmin))
+		}
+		user.IsAdmin = value
+	}
+
+	if err := user.HasWeakPassword(m.cfg.Auth.MinPasswordLength); err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	}
+
+	return user
+}
+
+// endregion users
+
+// region peers
+
+func (m Manager) importPeers(
+	ctx context.Context,
+	req domain.ImportRequest,
+	rows []domain.ImportRow,
+	execute bool,
+) error {
+	_, existingPeers, err := m.peers.GetInterfaceAndPeers(ctx, req.InterfaceId)
+	if err != nil {
+		return fmt.Errorf("failed to load peers of interface %s: %w", req.InterfaceId, err)
+	}
+
+	usedAddresses := make(map[string]string) // address -> owner description
+	for _, peer := range existingPeers {
+		for _, addr := range peer.Interface.Addresses {
+			usedAddresses[addr.Addr] = fmt.Sprintf("peer %s", peer.DisplayName)
+		}
+	}
+
+	users := make(map[domain.UserIdentifier]*domain.User)
+	for i := range rows {
+		row := &rows[i]
+		peer, mailable := m.validatePeerRow(ctx, req, row, usedAddresses, users)
+		if row.HasErrors() || !execute {
+			continue
+		}
+
+		created, err := m.createPeer(ctx, req.InterfaceId, peer)
+		if err != nil {
+			row.Errors = append(row.Errors, err.Error())
+			continue
+		}
+		row.Identifier = string(created.Identifier)
+		row.Created = true
+
+		if req.SendMails && mailable {
+			if err := m.mails.SendPeerEmail(ctx, req.LinkOnlyMails, created.Identifier); err != nil {
+				row.Warnings = append(row.Warnings, fmt.Sprintf("failed to send configuration mail: %v", err))
+			} else {
+				row.MailSent = true
+			}
+		}
+	}
+
+	return nil
+}
+
+// validatePeerRow checks the values of the row and returns the peer fields. The second return value is true if a
+// configuration mail can be sent for the peer.
+func (m Manager) validatePeerRow(
+	ctx context.Context,
+	req domain.ImportRequest,
+	row *domain.ImportRow,
+	usedAddresses map[string]string,
+	users map[domain.UserIdentifier]*domain.User,
+) (*domain.Peer, bool) {
+	peer := &domain.Peer{
+		DisplayName:       row.Values["display_name"],
+		UserIdentifier:    domain.UserIdentifier(row.Values["user"]),
+		Notes:             row.Values["notes"],
+		MailRecipientsStr: row.Values["mail_recipients"],
+	}
+
+	if addresses := row.Values["addresses"]; addresses != "" {
+		cidrs, err := domain.CidrsFromString(addresses)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid addresses %q: %v", addresses, err))
+		}
+		for _, cidr := range cidrs {
+			if owner, used := usedAddresses[cidr.Addr]; used {
+				row.Errors = append(row.Errors, fmt.Sprintf("address %s is already used by %s", cidr.Addr, owner))
+			} else {
+				usedAddresses[cidr.Addr] = fmt.Sprintf("line %d", row.Line)
+			}
+		}
+		peer.Interface.Addresses = cidrs
+	}
+
+	if expiresAt := row.Values["expires_at"]; expiresAt != "" {
+		expiry, err := parseDate(expiresAt)
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid expiry date %q", expiresAt))
+		} else {
+			peer.ExpiresAt = &expiry
+		}
+	}
+
+	for _, recipient := range peer.MailRecipients() {
+		if err := validateMailAddress(recipient); err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		}
+	}
+
+	var user *domain.User
+	if peer.UserIdentifier != "" {
+		cached, ok := users[peer.UserIdentifier]
+		if !ok {
+			cached, _ = m.users.GetUser(ctx, peer.UserIdentifier) // unknown users are only reported as warning
+			users[peer.UserIdentifier] = cached
+		}
+		user = cached
+		if user == nil {
+			row.Warnings = append(row.Warnings, fmt.Sprintf("user %s does not exist", peer.UserIdentifier))
+		}
+	}
+
+	mailable := (user != nil && !user.IsServiceAccount() && user.Email != "") || len(peer.MailRecipients()) > 0
+	if req.SendMails && !mailable {
+		row.Warnings = append(row.Warnings, "no configuration mail can be sent, the peer has no mail recipient")
+	}
+
+	return peer, mailable
+}
+
+// createPeer creates a peer with the default values of the interface and the imported fields. Peers without
+// addresses get the next free addresses of the interface.
+func (m Manager) createPeer(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	imported *domain.Peer,
+) (*domain.Peer, error) {
+	peer, err := m.peers.PreparePeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare peer: %w", err)
+	}
+
+	if imported.DisplayName != "" {
+		peer.DisplayName = imported.DisplayName
+	}
+	peer.UserIdentifier = imported.UserIdentifier // peers without user are not linked to the importing admin
+	peer.Notes = imported.Notes
+	peer.ExpiresAt = imported.ExpiresAt
+	peer.MailRecipientsStr = strings.Join(imported.MailRecipients(), ",")
+	if len(imported.Interface.Addresses) > 0 {
+		peer.Interface.Addresses = imported.Interface.Addresses
+	}
+
+	return m.peers.CreatePeer(ctx, peer)
+}
+
+// endregion peers
+
+func validateMailAddress(address string) error {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address {
+		return fmt.Errorf("invalid email address %q", address)
+	}
+	return nil
+}
+
+// parseDate parses a date (2006-01-02) or a timestamp in RFC3339 format, like it is written by the list export.
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
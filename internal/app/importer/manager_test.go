@@ -0,0 +1,230 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeUserService struct {
+	users map[domain.UserIdentifier]domain.User
+}
+
+func (f *fakeUserService) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (f *fakeUserService) CreateUser(_ context.Context, user *domain.User) (*domain.User, error) {
+	if user.Identifier == "new" {
+		return nil, errors.New("reserved user identifier")
+	}
+	f.users[user.Identifier] = *user
+	return user, nil
+}
+
+type fakePeerService struct {
+	peers   []domain.Peer
+	created int
+}
+
+func (f *fakePeerService) GetInterfaceAndPeers(_ context.Context, id domain.InterfaceIdentifier) (
+	*domain.Interface,
+	[]domain.Peer,
+	error,
+) {
+	return &domain.Interface{Identifier: id}, f.peers, nil
+}
+
+func (f *fakePeerService) PreparePeer(_ context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
+	f.created++
+	peer := &domain.Peer{
+		Identifier:          domain.PeerIdentifier("key-" + string(rune('a'+f.created))),
+		InterfaceIdentifier: id,
+		UserIdentifier:      "admin",
+		DisplayName:         "Generated",
+	}
+	peer.Interface.Addresses = []domain.Cidr{{Addr: "10.0.0.100", NetLength: 32}}
+	return peer, nil
+}
+
+func (f *fakePeerService) CreatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	f.peers = append(f.peers, *peer)
+	return peer, nil
+}
+
+type fakeMailService struct {
+	sent []domain.PeerIdentifier
+}
+
+func (f *fakeMailService) SendPeerEmail(_ context.Context, _ bool, peers ...domain.PeerIdentifier) error {
+	f.sent = append(f.sent, peers...)
+	return nil
+}
+
+func newTestManager() (*Manager, *fakeUserService, *fakePeerService, *fakeMailService) {
+	users := &fakeUserService{users: map[domain.UserIdentifier]domain.User{
+		"alice": {Identifier: "alice", Email: "alice@example.com"},
+		"svc":   {Identifier: "svc", Type: domain.UserTypeService},
+	}}
+	peers := &fakePeerService{peers: []domain.Peer{
+		{Identifier: "existing", DisplayName: "Existing", Interface: domain.PeerInterfaceConfig{
+			Addresses: []domain.Cidr{{Addr: "10.0.0.2", NetLength: 32}},
+		}},
+	}}
+	mails := &fakeMailService{}
+
+	cfg := &config.Config{}
+	cfg.Auth.MinPasswordLength = 8
+
+	return &Manager{cfg: cfg, users: users, peers: peers, mails: mails}, users, peers, mails
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+}
+
+func TestManager_PreviewImport_users(t *testing.T) {
+	m, users, _, _ := newTestManager()
+
+	report, err := m.PreviewImport(adminContext(), domain.ImportRequest{
+		Kind: domain.ImportKindUsers,
+		Data: "Identifier;E-Mail;Password;Admin\n" +
+			"bob;bob@example.com;secret-password;true\n" +
+			"alice;alice@example.com;secret-password;\n" +
+			"bob;invalid;short;maybe\n" +
+			";;secret-password;\n",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Identifier", "E-Mail", "Password", "Admin"}, report.Columns)
+	assert.Equal(t, map[string]string{
+		"identifier": "Identifier", "email": "E-Mail", "password": "Password", "is_admin": "Admin",
+	}, report.Mapping)
+	require.Len(t, report.Rows, 4)
+	assert.Equal(t, 3, report.Failed)
+	assert.Equal(t, 2, report.Rows[0].Line)
+
+	assert.Empty(t, report.Rows[0].Errors)
+	assert.Equal(t, "********", report.Rows[0].Values["password"], "passwords are not part of the report")
+	assert.Equal(t, []string{"user alice already exists"}, report.Rows[1].Errors)
+	assert.Equal(t, []string{
+		"duplicate identifier, already used in line 2",
+		`invalid email address "invalid"`,
+		`invalid admin flag "maybe"`,
+		"password is too short, minimum length is 8",
+	}, report.Rows[2].Errors)
+	assert.Equal(t, []string{"missing value for identifier"}, report.Rows[3].Errors)
+
+	assert.Zero(t, report.Created)
+	assert.Len(t, users.users, 2, "the preview does not create users")
+}
+
+func TestManager_Import_users(t *testing.T) {
+	m, users, _, _ := newTestManager()
+
+	report, err := m.Import(adminContext(), domain.ImportRequest{
+		Kind: domain.ImportKindUsers,
+		Data: "login,mail,pw\nbob,bob@example.com,secret-password\nnew,,secret-password\nalice,,secret-password\n",
+		Mapping: map[string]string{
+			"identifier": "login", "email": "mail", "password": "pw",
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 2, report.Failed)
+	assert.True(t, report.Rows[0].Created)
+	assert.Equal(t, "bob", report.Rows[0].Identifier)
+	assert.Equal(t, []string{"reserved user identifier"}, report.Rows[1].Errors, "creation errors are reported")
+	assert.Equal(t, "bob@example.com", users.users["bob"].Email)
+	assert.Equal(t, domain.UserSourceDatabase, users.users["bob"].Source)
+
+	_, err = m.Import(adminContext(), domain.ImportRequest{
+		Kind:    domain.ImportKindUsers,
+		Data:    "login\nbob\n",
+		Mapping: map[string]string{"identifier": "user_name"},
+	})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
+
+func TestManager_Import_peers(t *testing.T) {
+	m, _, peers, mails := newTestManager()
+
+	report, err := m.Import(adminContext(), domain.ImportRequest{
+		Kind:        domain.ImportKindPeers,
+		InterfaceId: "wg0",
+		Data: "Display Name,User,Addresses,Expires At\n" +
+			"Alice laptop,alice,10.0.0.3/32,2030-01-31\n" +
+			"Conflict,alice,10.0.0.2/32,\n" +
+			"Unknown owner,carol,,\n" +
+			"Service,svc,,tomorrow\n",
+		SendMails: true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 2, report.Failed)
+
+	assert.True(t, report.Rows[0].Created)
+	assert.True(t, report.Rows[0].MailSent)
+	assert.Equal(t, []string{"address 10.0.0.2 is already used by peer Existing"}, report.Rows[1].Errors)
+	assert.Equal(t, []string{
+		"user carol does not exist",
+		"no configuration mail can be sent, the peer has no mail recipient",
+	}, report.Rows[2].Warnings)
+	assert.False(t, report.Rows[2].MailSent)
+	assert.Equal(t, []string{`invalid expiry date "tomorrow"`}, report.Rows[3].Errors)
+
+	assert.Equal(t, []domain.PeerIdentifier{domain.PeerIdentifier(report.Rows[0].Identifier)}, mails.sent)
+
+	require.Len(t, peers.peers, 3)
+	imported := peers.peers[1]
+	assert.Equal(t, "Alice laptop", imported.DisplayName)
+	assert.Equal(t, domain.UserIdentifier("alice"), imported.UserIdentifier)
+	assert.Equal(t, "10.0.0.3", imported.Interface.Addresses[0].Addr)
+	require.NotNil(t, imported.ExpiresAt)
+	assert.Equal(t, 2030, imported.ExpiresAt.Year())
+
+	generated := peers.peers[2]
+	assert.Equal(t, "Unknown owner", generated.DisplayName)
+	assert.Equal(t, "10.0.0.100", generated.Interface.Addresses[0].Addr, "missing addresses are allocated")
+}
+
+func TestManager_PreviewImport_permissions(t *testing.T) {
+	m, _, _, _ := newTestManager()
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+
+	_, err := m.PreviewImport(userCtx, domain.ImportRequest{Kind: domain.ImportKindUsers, Data: "identifier\n"})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	_, err = m.PreviewImport(userCtx, domain.ImportRequest{
+		Kind: domain.ImportKindPeers, InterfaceId: "wg0", Data: "user\n",
+	})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func Test_parseCsv(t *testing.T) {
+	columns, records, lines, err := parseCsv("\ufeffa, b\n1,\"multi\nline\"\n\n3\n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, columns)
+	assert.Equal(t, [][]string{{"1", "multi\nline"}, {"3"}}, records)
+	assert.Equal(t, []int{2, 5}, lines)
+
+	_, _, _, err = parseCsv("")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
+
+func Test_unescapeCsvFormula(t *testing.T) {
+	assert.Equal(t, "=1+1", unescapeCsvFormula("'=1+1"))
+	assert.Equal(t, "'quoted'", unescapeCsvFormula("'quoted'"))
+}
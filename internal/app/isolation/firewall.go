@@ -0,0 +1,54 @@
+package isolation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// isolatedInterface is an interface whose peers must not reach each other.
+type isolatedInterface struct {
+	name    string
+	allowed []domain.Cidr // destinations behind peers that stay reachable
+}
+
+// firewallRuleset returns the nftables script that drops all forwarded packets between the peers of the given
+// interfaces. Packets to the allowed networks and packets of established connections are still forwarded.
+// The script replaces an existing table.
+func firewallRuleset(table string, interfaces []isolatedInterface) string {
+	var sb strings.Builder
+
+	// declaring the table before deleting it ensures that the delete command succeeds if the table does not exist
+	fmt.Fprintf(&sb, "table inet %s\ndelete table inet %s\n", table, table)
+	fmt.Fprintf(&sb, "table inet %s {\n", table)
+	sb.WriteString("\tchain forward {\n\t\ttype filter hook forward priority -10; policy accept;\n")
+	sb.WriteString("\t\tct state established,related accept\n")
+	for _, iface := range interfaces {
+		match := fmt.Sprintf("iifname %q oifname %q", iface.name, iface.name)
+
+		var v4, v6 []string
+		for _, cidr := range iface.allowed {
+			if cidr.IsV4() {
+				v4 = append(v4, cidr.Prefix().Masked().String())
+			} else {
+				v6 = append(v6, cidr.Prefix().Masked().String())
+			}
+		}
+		if len(v4) > 0 {
+			fmt.Fprintf(&sb, "\t\t%s ip daddr { %s } accept\n", match, strings.Join(v4, ", "))
+		}
+		if len(v6) > 0 {
+			fmt.Fprintf(&sb, "\t\t%s ip6 daddr { %s } accept\n", match, strings.Join(v6, ", "))
+		}
+		fmt.Fprintf(&sb, "\t\t%s drop\n", match)
+	}
+	sb.WriteString("\t}\n}\n")
+
+	return sb.String()
+}
+
+// removeRuleset returns the nftables script that removes the table, if it exists.
+func removeRuleset(table string) string {
+	return fmt.Sprintf("table inet %s\ndelete table inet %s\n", table, table)
+}
@@ -0,0 +1,156 @@
+package isolation
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type InterfaceDatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+}
+
+type FirewallRepo interface {
+	// ApplyRuleset applies the given nftables script in a single transaction.
+	ApplyRuleset(ctx context.Context, ruleset string) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager applies the client isolation of the interfaces. For each server interface with client isolation, the
+// firewall drops all traffic between its peers, except for the allowed networks of the interface.
+// The firewall rules are only touched if at least one interface uses client isolation.
+type Manager struct {
+	cfg *config.Config
+
+	bus      EventBus
+	db       InterfaceDatabaseRepo
+	firewall FirewallRepo
+
+	state *isolationState
+}
+
+type isolationState struct {
+	mux     sync.Mutex
+	ruleset string // the currently applied ruleset, empty if no ruleset was applied
+}
+
+// NewIsolationManager creates a new client isolation manager instance.
+func NewIsolationManager(
+	cfg *config.Config,
+	bus EventBus,
+	db InterfaceDatabaseRepo,
+	firewall FirewallRepo,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:       db,
+		firewall: firewall,
+
+		state: &isolationState{},
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicInterfaceCreated, m.handleInterfaceEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceUpdated, m.handleInterfaceEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceEvent)
+}
+
+// StartBackgroundJobs applies the client isolation rules and removes them on shutdown.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	m.refresh(ctx)
+
+	go func() {
+		<-ctx.Done()
+
+		m.state.mux.Lock()
+		defer m.state.mux.Unlock()
+
+		if m.state.ruleset == "" {
+			return
+		}
+		// the context is already canceled, use a fresh context for the cleanup
+		if err := m.firewall.ApplyRuleset(context.Background(),
+			removeRuleset(m.cfg.ClientIsolation.FirewallTable)); err != nil {
+			slog.Error("failed to remove client isolation firewall rules", "error", err)
+		}
+		m.state.ruleset = ""
+	}()
+}
+
+func (m Manager) handleInterfaceEvent(iface domain.Interface) {
+	slog.Debug("handling interface event", "interface", iface.Identifier)
+
+	m.refresh(context.Background())
+}
+
+// refresh updates the firewall rules if the client isolation of the interfaces changed.
+func (m Manager) refresh(ctx context.Context) {
+	if err := m.ApplyState(ctx); err != nil {
+		slog.Error("failed to refresh client isolation", "error", err)
+	}
+}
+
+// ApplyState applies the client isolation firewall rules of all interfaces. The rules are only replaced if they
+// changed since they were applied the last time.
+func (m Manager) ApplyState(ctx context.Context) error {
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces for client isolation: %w", err)
+	}
+
+	var isolated []isolatedInterface
+	for _, iface := range interfaces {
+		if !iface.ClientIsolation || iface.IsDisabled() || iface.Type != domain.InterfaceTypeServer {
+			continue
+		}
+		isolated = append(isolated, isolatedInterface{
+			name:    string(iface.Identifier),
+			allowed: iface.ClientIsolationAllowed(),
+		})
+	}
+
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	ruleset := ""
+	if len(isolated) > 0 {
+		ruleset = firewallRuleset(m.cfg.ClientIsolation.FirewallTable, isolated)
+	}
+	if ruleset == m.state.ruleset {
+		return nil // nothing changed, or client isolation is not used at all
+	}
+
+	apply := ruleset
+	if apply == "" {
+		apply = removeRuleset(m.cfg.ClientIsolation.FirewallTable)
+	}
+	if err := m.firewall.ApplyRuleset(ctx, apply); err != nil {
+		return fmt.Errorf("failed to apply client isolation firewall rules: %w", err)
+	}
+	m.state.ruleset = ruleset
+	slog.Debug("applied client isolation firewall rules", "interfaces", len(isolated))
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package isolation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	interfaces []domain.Interface
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+type fakeFirewall struct {
+	rulesets []string
+}
+
+func (f *fakeFirewall) ApplyRuleset(_ context.Context, ruleset string) error {
+	f.rulesets = append(f.rulesets, ruleset)
+	return nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func newTestManager(t *testing.T) (*Manager, *fakeDatabase, *fakeFirewall) {
+	cfg := &config.Config{}
+	cfg.ClientIsolation = config.ClientIsolationConfig{FirewallTable: "wg_portal_isolation"}
+
+	db := &fakeDatabase{
+		interfaces: []domain.Interface{
+			{Identifier: "wg0", Type: domain.InterfaceTypeServer},
+			{Identifier: "wg1", Type: domain.InterfaceTypeClient, ClientIsolation: true},
+		},
+	}
+	firewall := &fakeFirewall{}
+
+	m, err := NewIsolationManager(cfg, fakeBus{}, db, firewall)
+	require.NoError(t, err)
+
+	return m, db, firewall
+}
+
+func TestFirewallRuleset(t *testing.T) {
+	allowed, err := domain.CidrsFromArray([]string{"192.168.50.1/24", "fd00:50::/64"})
+	require.NoError(t, err)
+
+	ruleset := firewallRuleset("wg_portal_isolation", []isolatedInterface{
+		{name: "wg0", allowed: allowed},
+		{name: "wg1"},
+	})
+
+	assert.Equal(t, "table inet wg_portal_isolation\ndelete table inet wg_portal_isolation\n"+
+		"table inet wg_portal_isolation {\n"+
+		"\tchain forward {\n\t\ttype filter hook forward priority -10; policy accept;\n"+
+		"\t\tct state established,related accept\n"+
+		"\t\tiifname \"wg0\" oifname \"wg0\" ip daddr { 192.168.50.0/24 } accept\n"+
+		"\t\tiifname \"wg0\" oifname \"wg0\" ip6 daddr { fd00:50::/64 } accept\n"+
+		"\t\tiifname \"wg0\" oifname \"wg0\" drop\n"+
+		"\t\tiifname \"wg1\" oifname \"wg1\" drop\n"+
+		"\t}\n}\n", ruleset)
+}
+
+func TestManager_refresh(t *testing.T) {
+	m, db, firewall := newTestManager(t)
+	ctx := context.Background()
+
+	// no server interface uses client isolation, the firewall is not touched
+	m.refresh(ctx)
+	assert.Empty(t, firewall.rulesets)
+
+	db.interfaces[0].ClientIsolation = true
+	m.refresh(ctx)
+	require.Len(t, firewall.rulesets, 1)
+	assert.Contains(t, firewall.rulesets[0], "iifname \"wg0\" oifname \"wg0\" drop")
+	assert.NotContains(t, firewall.rulesets[0], "wg1")
+
+	// unchanged interfaces do not reapply the ruleset
+	m.refresh(ctx)
+	assert.Len(t, firewall.rulesets, 1)
+
+	db.interfaces[0].ClientIsolation = false
+	m.refresh(ctx)
+	require.Len(t, firewall.rulesets, 2)
+	assert.Equal(t, removeRuleset("wg_portal_isolation"), firewall.rulesets[1])
+}
@@ -0,0 +1,167 @@
+package keepalive
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetPeersStats returns the stats for the given peer ids.
+	GetPeersStats(ctx context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error)
+	// UpdatePeerStatus updates the status of the given peer.
+	UpdatePeerStatus(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		updateFunc func(in *domain.PeerStatus) (*domain.PeerStatus, error),
+	) error
+}
+
+type PeerManager interface {
+	// GetInterfaceAndPeers returns the interface with the given id and all peers associated with it.
+	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+}
+
+// endregion dependencies
+
+// Manager analyzes the endpoint churn of peers and recommends keepalive intervals for peers that flap,
+// usually because a NAT device drops their idle UDP mappings.
+type Manager struct {
+	cfg *config.Config
+
+	db    DatabaseRepo
+	peers PeerManager
+}
+
+// NewKeepaliveManager creates a new keepalive recommendation manager.
+func NewKeepaliveManager(cfg *config.Config, db DatabaseRepo, peers PeerManager) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+
+		db:    db,
+		peers: peers,
+	}
+
+	return m, nil
+}
+
+// GetRecommendations returns the keepalive recommendations for all flapping peers of the given interface.
+// The peers with the most endpoint changes are returned first.
+func (m Manager) GetRecommendations(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+) ([]domain.KeepaliveRecommendation, error) {
+	if !m.isEnabled() {
+		return nil, nil
+	}
+
+	_, peers, err := m.peers.GetInterfaceAndPeers(ctx, id) // validates the access rights
+	if err != nil {
+		return nil, err
+	}
+	if len(peers) == 0 {
+		return nil, nil
+	}
+
+	peerIds := make([]domain.PeerIdentifier, len(peers))
+	for i, peer := range peers {
+		peerIds[i] = peer.Identifier
+	}
+	stats, err := m.db.GetPeersStats(ctx, peerIds...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer stats of interface %s: %w", id, err)
+	}
+	statsMap := make(map[domain.PeerIdentifier]domain.PeerStatus, len(stats))
+	for _, s := range stats {
+		statsMap[s.PeerId] = s
+	}
+
+	var recommendations []domain.KeepaliveRecommendation
+	for _, peer := range peers {
+		status, ok := statsMap[peer.Identifier]
+		if !ok || peer.IsDisabled() {
+			continue
+		}
+
+		if recommendation, ok := domain.RecommendKeepalive(peer, status, m.cfg.Statistics.KeepaliveChurnThreshold); ok {
+			recommendations = append(recommendations, recommendation)
+		}
+	}
+
+	sort.Slice(recommendations, func(i, j int) bool {
+		return recommendations[i].EndpointChanges > recommendations[j].EndpointChanges
+	})
+
+	return recommendations, nil
+}
+
+// ApplyRecommendation sets the recommended keepalive interval for the given peer and starts a new observation
+// window for the endpoint changes of the peer.
+func (m Manager) ApplyRecommendation(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	if !m.isEnabled() {
+		return nil, fmt.Errorf("keepalive recommendations are disabled: %w", domain.ErrInvalidData)
+	}
+
+	peer, err := m.peers.GetPeer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	iface, _, err := m.peers.GetInterfaceAndPeers(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return nil, err
+	}
+
+	stats, err := m.db.GetPeersStats(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer stats of %s: %w", id, err)
+	}
+	if len(stats) == 0 {
+		return nil, fmt.Errorf("no keepalive recommendation for peer %s: %w", id, domain.ErrNotFound)
+	}
+
+	recommendation, ok := domain.RecommendKeepalive(*peer, stats[0], m.cfg.Statistics.KeepaliveChurnThreshold)
+	if !ok {
+		return nil, fmt.Errorf("no keepalive recommendation for peer %s: %w", id, domain.ErrNotFound)
+	}
+	if recommendation.RecommendedKeepalive == 0 {
+		return nil, fmt.Errorf("keepalive interval of peer %s is already at the minimum: %w", id,
+			domain.ErrInvalidData)
+	}
+
+	peer.PersistentKeepalive.SetValue(recommendation.RecommendedKeepalive)
+	updatedPeer, err := m.peers.UpdatePeer(ctx, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.db.UpdatePeerStatus(ctx, id, func(in *domain.PeerStatus) (*domain.PeerStatus, error) {
+		in.ResetEndpointChurn(time.Now())
+		return in, nil
+	})
+	if err != nil {
+		slog.Warn("failed to reset endpoint churn", "peer", id, "error", err)
+	}
+
+	slog.Info("applied keepalive recommendation", "peer", id,
+		"oldKeepalive", recommendation.CurrentKeepalive, "keepalive", recommendation.RecommendedKeepalive)
+
+	return updatedPeer, nil
+}
+
+func (m Manager) isEnabled() bool {
+	return m.cfg.Statistics.CollectPeerData && m.cfg.Statistics.KeepaliveChurnThreshold > 0
+}
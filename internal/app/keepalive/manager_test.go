@@ -0,0 +1,106 @@
+package keepalive
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	stats map[domain.PeerIdentifier]domain.PeerStatus
+}
+
+func (f *fakeDatabase) GetPeersStats(_ context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error) {
+	var result []domain.PeerStatus
+	for _, id := range ids {
+		if s, ok := f.stats[id]; ok {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeDatabase) UpdatePeerStatus(
+	_ context.Context,
+	id domain.PeerIdentifier,
+	updateFunc func(in *domain.PeerStatus) (*domain.PeerStatus, error),
+) error {
+	s := f.stats[id]
+	updated, err := updateFunc(&s)
+	if err != nil {
+		return err
+	}
+	f.stats[id] = *updated
+	return nil
+}
+
+type fakePeerManager struct {
+	peers map[domain.PeerIdentifier]domain.Peer
+}
+
+func (f *fakePeerManager) GetInterfaceAndPeers(_ context.Context, id domain.InterfaceIdentifier) (
+	*domain.Interface,
+	[]domain.Peer,
+	error,
+) {
+	var peers []domain.Peer
+	for _, p := range f.peers {
+		if p.InterfaceIdentifier == id {
+			peers = append(peers, p)
+		}
+	}
+	return &domain.Interface{Identifier: id}, peers, nil
+}
+
+func (f *fakePeerManager) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	p, ok := f.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &p, nil
+}
+
+func (f *fakePeerManager) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	f.peers[peer.Identifier] = *peer
+	return peer, nil
+}
+
+func TestManager_ApplyRecommendation(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Statistics.CollectPeerData = true
+	cfg.Statistics.KeepaliveChurnThreshold = 3
+
+	since := time.Now().Add(-time.Hour)
+	db := &fakeDatabase{stats: map[domain.PeerIdentifier]domain.PeerStatus{
+		"flapping": {PeerId: "flapping", EndpointChanges: 5, NatRebinds: 4, ChurnSince: &since},
+		"stable":   {PeerId: "stable", EndpointChanges: 1, ChurnSince: &since},
+	}}
+	peers := &fakePeerManager{peers: map[domain.PeerIdentifier]domain.Peer{
+		"flapping": {Identifier: "flapping", InterfaceIdentifier: "wg0"},
+		"stable":   {Identifier: "stable", InterfaceIdentifier: "wg0"},
+	}}
+	m, err := NewKeepaliveManager(cfg, db, peers)
+	require.NoError(t, err)
+
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	recommendations, err := m.GetRecommendations(ctx, "wg0")
+	require.NoError(t, err)
+	require.Len(t, recommendations, 1)
+	assert.Equal(t, domain.PeerIdentifier("flapping"), recommendations[0].Peer.Identifier)
+	assert.Equal(t, domain.RecommendedNatKeepalive, recommendations[0].RecommendedKeepalive)
+
+	peer, err := m.ApplyRecommendation(ctx, "flapping")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RecommendedNatKeepalive, peer.PersistentKeepalive.GetValue())
+	assert.Zero(t, db.stats["flapping"].EndpointChanges, "a new observation window is started")
+
+	_, err = m.ApplyRecommendation(ctx, "stable")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
@@ -0,0 +1,299 @@
+package keyreveal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetExportApprovals returns all export approval requests, the most recent requests first.
+	GetExportApprovals(ctx context.Context) ([]domain.ExportApproval, error)
+	// SaveExportApproval creates or updates the given export approval request.
+	SaveExportApproval(ctx context.Context, approval *domain.ExportApproval) error
+	// GetKeyExports returns the private key exports of the given user since the given time.
+	GetKeyExports(ctx context.Context, id domain.UserIdentifier, since time.Time) ([]domain.KeyExport, error)
+	// SaveKeyExport creates or updates the given private key export.
+	SaveKeyExport(ctx context.Context, export *domain.KeyExport) error
+}
+
+type EventBus interface {
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
+}
+
+// endregion dependencies
+
+// Manager gates the reveal of private peer keys behind a recent (re-)authentication of the user. Each reveal is
+// recorded as audit event. Only requests of the web interface and the REST API are gated, internal usages like
+// configuration mails or SSH deployments are not.
+// If an export approval threshold is configured, users that reveal the keys of more peers of other users within the
+// configured window need the approval of another administrator. The exports are stored in the database, so they are
+// counted across restarts and instances.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+	db  DatabaseRepo
+
+	mux *sync.Mutex // serializes the check and the recording of exports
+}
+
+// NewKeyRevealManager creates a new private key reveal manager.
+func NewKeyRevealManager(cfg *config.Config, bus EventBus, db DatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+		db:  db,
+
+		mux: &sync.Mutex{},
+	}
+
+	return m, nil
+}
+
+// ValidateReveal checks that the current user is allowed to see the private key of the given peer in the given
+// format and records the reveal. If a step-up is required, the user must have (re-)authenticated recently.
+func (m Manager) ValidateReveal(ctx context.Context, peer *domain.Peer, format string) error {
+	client := domain.GetClientInfo(ctx)
+	if client == nil || peer.Interface.PrivateKey == "" {
+		return nil // internal usage, or there is no private key to reveal
+	}
+
+	currentUser := domain.GetUserInfo(ctx)
+	if err := m.validateStepUp(currentUser, peer); err != nil {
+		return err
+	}
+
+	if err := m.validateExportApproval(ctx, currentUser.Id, peer, time.Now()); err != nil {
+		return err
+	}
+
+	m.publishReveal(ctx, client, currentUser, peer, format)
+
+	return nil
+}
+
+// CheckReveals checks that the current user is allowed to see the private keys of all given peers, without recording
+// any reveal. It is used for bulk exports: the whole set is checked up front, each peer is recorded with
+// RecordReveal once its key was actually written.
+func (m Manager) CheckReveals(ctx context.Context, peers []domain.Peer) error {
+	if domain.GetClientInfo(ctx) == nil {
+		return nil // internal usage
+	}
+
+	currentUser := domain.GetUserInfo(ctx)
+	revealed := make([]*domain.Peer, 0, len(peers))
+	for i := range peers {
+		if peers[i].Interface.PrivateKey == "" {
+			continue // there is no private key to reveal
+		}
+		if err := m.validateStepUp(currentUser, &peers[i]); err != nil {
+			return err
+		}
+		revealed = append(revealed, &peers[i])
+	}
+
+	threshold := m.cfg.ExportApproval.Threshold
+	if threshold <= 0 || len(revealed) == 0 {
+		return nil
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.checkExportApproval(ctx, currentUser.Id, revealed, time.Now())
+}
+
+// RecordReveal records the reveal of the private key of the given peer, that was checked with CheckReveals before.
+// The reveal is published as audit event and counts towards the export approval threshold.
+func (m Manager) RecordReveal(ctx context.Context, peer *domain.Peer, format string) {
+	client := domain.GetClientInfo(ctx)
+	if client == nil || peer.Interface.PrivateKey == "" {
+		return // internal usage, or there is no private key to reveal
+	}
+
+	currentUser := domain.GetUserInfo(ctx)
+	if m.cfg.ExportApproval.Threshold > 0 && !peer.IsMember(currentUser.Id) {
+		m.mux.Lock()
+		err := m.recordExport(ctx, currentUser.Id, peer.Identifier, time.Now())
+		m.mux.Unlock()
+		if err != nil {
+			slog.Error("failed to record key export", "peer", peer.Identifier, "user", currentUser.Id, "error", err)
+		}
+	}
+
+	m.publishReveal(ctx, client, currentUser, peer, format)
+}
+
+// validateStepUp checks that the given user (re-)authenticated recently, if a step-up is required.
+func (m Manager) validateStepUp(currentUser *domain.ContextUserInfo, peer *domain.Peer) error {
+	if m.cfg.KeyReveal.StepUpRequired && !currentUser.AuthenticatedWithin(m.cfg.KeyReveal.StepUpValidity) {
+		return fmt.Errorf("the private key of peer %s is only revealed after a re-authentication: %w",
+			peer.Identifier, domain.ErrStepUpRequired)
+	}
+
+	return nil
+}
+
+func (m Manager) publishReveal(
+	ctx context.Context,
+	client *domain.ClientInfo,
+	currentUser *domain.ContextUserInfo,
+	peer *domain.Peer,
+	format string,
+) {
+	slog.Info("revealed private key", "peer", peer.Identifier, "format", format, "user", currentUser.Id,
+		"ip", client.IpAddress)
+
+	m.bus.Publish(app.TopicAuditKeyRevealed, domain.AuditEventWrapper[audit.KeyRevealEvent]{
+		Ctx: ctx,
+		Event: audit.KeyRevealEvent{
+			Peer:   peer.Identifier,
+			Format: format,
+			Client: *client,
+		},
+	})
+}
+
+// validateExportApproval counts the exports of peers that the given user is not a member of. Once the user exported
+// the configured number of peers within the window, further peers can only be exported with an approved request.
+// If no request is pending, a new request is created and the administrators are notified.
+func (m Manager) validateExportApproval(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	peer *domain.Peer,
+	now time.Time,
+) error {
+	threshold := m.cfg.ExportApproval.Threshold
+	if threshold <= 0 || peer.IsMember(userId) {
+		return nil
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if err := m.checkExportApproval(ctx, userId, []*domain.Peer{peer}, now); err != nil {
+		return err
+	}
+
+	return m.recordExport(ctx, userId, peer.Identifier, now)
+}
+
+// checkExportApproval checks that the given user may export the given peers in addition to the recent exports. The
+// exports are not counted, but if an approval is required and no request is pending, a new request is created and
+// the administrators are notified. The caller must hold the lock.
+func (m Manager) checkExportApproval(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	peers []*domain.Peer,
+	now time.Time,
+) error {
+	threshold := m.cfg.ExportApproval.Threshold
+	exports, err := m.recentExports(ctx, userId, now)
+	if err != nil {
+		return err
+	}
+
+	exportCount := len(exports)
+	added := make(map[domain.PeerIdentifier]struct{}, len(peers))
+	for _, peer := range peers {
+		if peer.IsMember(userId) {
+			continue
+		}
+		if _, ok := exports[peer.Identifier]; ok {
+			continue
+		}
+		if _, ok := added[peer.Identifier]; ok {
+			continue
+		}
+		added[peer.Identifier] = struct{}{}
+		exportCount++
+	}
+	if exportCount <= threshold {
+		return nil
+	}
+
+	approvals, err := m.db.GetExportApprovals(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load export approvals: %w", err)
+	}
+
+	pending := false
+	for _, approval := range approvals {
+		if approval.RequestedBy != userId {
+			continue
+		}
+		if approval.AllowsExports(now) {
+			return nil
+		}
+		if approval.IsPending() {
+			pending = true
+		}
+	}
+
+	if !pending {
+		approval := &domain.ExportApproval{
+			RequestedBy: userId,
+			RequestedAt: now,
+			ExportCount: len(exports),
+			State:       domain.ExportApprovalStatePending,
+		}
+		if err := m.db.SaveExportApproval(ctx, approval); err != nil {
+			return fmt.Errorf("failed to create export approval request: %w", err)
+		}
+
+		slog.Warn("export approval required", "user", userId, "exports", len(exports), "request", approval.Id)
+
+		m.bus.Publish(app.TopicExportApprovalRequested, *approval)
+	}
+
+	return fmt.Errorf("exporting the keys of more than %d peers within %s requires the approval of another "+
+		"administrator: %w", threshold, m.cfg.ExportApproval.Window, domain.ErrApprovalRequired)
+}
+
+// recentExports returns the peers that the given user exported within the configured window.
+func (m Manager) recentExports(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	now time.Time,
+) (map[domain.PeerIdentifier]struct{}, error) {
+	recent, err := m.db.GetKeyExports(ctx, userId, now.Add(-m.cfg.ExportApproval.Window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key exports: %w", err)
+	}
+
+	exports := make(map[domain.PeerIdentifier]struct{}, len(recent))
+	for _, export := range recent {
+		exports[export.PeerIdentifier] = struct{}{}
+	}
+
+	return exports, nil
+}
+
+// recordExport stores the export of the given peer by the given user. The caller must hold the lock.
+func (m Manager) recordExport(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	peerId domain.PeerIdentifier,
+	now time.Time,
+) error {
+	err := m.db.SaveKeyExport(ctx, &domain.KeyExport{
+		UserIdentifier: userId,
+		PeerIdentifier: peerId,
+		ExportedAt:     now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store key export: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package keyreveal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeBus struct {
+	reveals   []audit.KeyRevealEvent
+	approvals []domain.ExportApproval
+}
+
+func (f *fakeBus) Publish(topic string, args ...any) {
+	switch topic {
+	case app.TopicAuditKeyRevealed:
+		f.reveals = append(f.reveals, args[0].(domain.AuditEventWrapper[audit.KeyRevealEvent]).Event)
+	case app.TopicExportApprovalRequested:
+		f.approvals = append(f.approvals, args[0].(domain.ExportApproval))
+	}
+}
+
+type fakeDatabase struct {
+	approvals []domain.ExportApproval
+	exports   map[domain.UserIdentifier]map[domain.PeerIdentifier]time.Time
+}
+
+func (f *fakeDatabase) GetExportApprovals(_ context.Context) ([]domain.ExportApproval, error) {
+	return f.approvals, nil
+}
+
+func (f *fakeDatabase) SaveExportApproval(_ context.Context, approval *domain.ExportApproval) error {
+	if approval.Id == 0 {
+		approval.Id = uint64(len(f.approvals) + 1)
+		f.approvals = append(f.approvals, *approval)
+		return nil
+	}
+	f.approvals[approval.Id-1] = *approval
+	return nil
+}
+
+func (f *fakeDatabase) GetKeyExports(_ context.Context, id domain.UserIdentifier, since time.Time) (
+	[]domain.KeyExport,
+	error,
+) {
+	var exports []domain.KeyExport
+	for peerId, exportedAt := range f.exports[id] {
+		if exportedAt.After(since) {
+			exports = append(exports, domain.KeyExport{UserIdentifier: id, PeerIdentifier: peerId, ExportedAt: exportedAt})
+		}
+	}
+	return exports, nil
+}
+
+func (f *fakeDatabase) SaveKeyExport(_ context.Context, export *domain.KeyExport) error {
+	if f.exports == nil {
+		f.exports = make(map[domain.UserIdentifier]map[domain.PeerIdentifier]time.Time)
+	}
+	if f.exports[export.UserIdentifier] == nil {
+		f.exports[export.UserIdentifier] = make(map[domain.PeerIdentifier]time.Time)
+	}
+	f.exports[export.UserIdentifier][export.PeerIdentifier] = export.ExportedAt
+	return nil
+}
+
+func newTestManager(t *testing.T, stepUpRequired bool) (*Manager, *fakeBus) {
+	cfg := &config.Config{}
+	cfg.KeyReveal = config.KeyRevealConfig{StepUpRequired: stepUpRequired, StepUpValidity: 5 * time.Minute}
+
+	bus := &fakeBus{}
+	m, err := NewKeyRevealManager(cfg, bus, &fakeDatabase{})
+	require.NoError(t, err)
+
+	return m, bus
+}
+
+func requestContext(authenticatedAt time.Time) context.Context {
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{
+		Id:              "alice",
+		AuthenticatedAt: authenticatedAt,
+	})
+	return domain.SetClientInfo(ctx, domain.ClientInfo{IpAddress: "198.51.100.7"})
+}
+
+func testPeer() *domain.Peer {
+	peer := &domain.Peer{Identifier: "peer-1"}
+	peer.Interface.PrivateKey = "private-key"
+	return peer
+}
+
+func TestManager_ValidateReveal_StepUp(t *testing.T) {
+	m, bus := newTestManager(t, true)
+
+	err := m.ValidateReveal(requestContext(time.Now().Add(-time.Hour)), testPeer(), domain.PeerConfigFormatFile)
+	assert.ErrorIs(t, err, domain.ErrStepUpRequired)
+	assert.Empty(t, bus.reveals)
+
+	err = m.ValidateReveal(requestContext(time.Now().Add(-time.Minute)), testPeer(), domain.PeerConfigFormatFile)
+	require.NoError(t, err)
+	require.Len(t, bus.reveals, 1)
+	assert.Equal(t, domain.PeerIdentifier("peer-1"), bus.reveals[0].Peer)
+	assert.Equal(t, domain.PeerConfigFormatFile, bus.reveals[0].Format)
+	assert.Equal(t, "198.51.100.7", bus.reveals[0].Client.IpAddress)
+}
+
+func TestManager_ValidateReveal_AuditOnly(t *testing.T) {
+	m, bus := newTestManager(t, false)
+
+	err := m.ValidateReveal(requestContext(time.Time{}), testPeer(), domain.PeerConfigFormatQrCode)
+	require.NoError(t, err)
+	assert.Len(t, bus.reveals, 1)
+}
+
+func TestManager_ValidateReveal_Internal(t *testing.T) {
+	m, bus := newTestManager(t, true)
+
+	// internal usages, like configuration mails, carry no client information
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	require.NoError(t, m.ValidateReveal(ctx, testPeer(), domain.PeerConfigFormatFile))
+
+	// peers with a user provided public key have no private key
+	peer := testPeer()
+	peer.Interface.PrivateKey = ""
+	require.NoError(t, m.ValidateReveal(requestContext(time.Time{}), peer, domain.PeerConfigFormatFile))
+
+	assert.Empty(t, bus.reveals)
+}
+
+func TestManager_validateExportApproval(t *testing.T) {
+	m, bus := newTestManager(t, false)
+	m.cfg.ExportApproval = config.ExportApprovalConfig{Threshold: 2, Window: time.Hour, Validity: time.Hour}
+	db := m.db.(*fakeDatabase)
+	now := time.Now()
+	ctx := context.Background()
+	foreignPeer := func(id string) *domain.Peer {
+		return &domain.Peer{Identifier: domain.PeerIdentifier(id), UserIdentifier: "carol"}
+	}
+
+	require.NoError(t, m.validateExportApproval(ctx, "alice", foreignPeer("p1"), now))
+	require.NoError(t, m.validateExportApproval(ctx, "alice", foreignPeer("p2"), now))
+	require.NoError(t, m.validateExportApproval(ctx, "alice", foreignPeer("p1"), now), "peers are counted once")
+	require.NoError(t, m.validateExportApproval(ctx, "alice", &domain.Peer{Identifier: "own", UserIdentifier: "alice"},
+		now), "own peers are not counted")
+
+	err := m.validateExportApproval(ctx, "alice", foreignPeer("p3"), now)
+	assert.ErrorIs(t, err, domain.ErrApprovalRequired)
+	err = m.validateExportApproval(ctx, "alice", foreignPeer("p4"), now)
+	assert.ErrorIs(t, err, domain.ErrApprovalRequired)
+	require.Len(t, db.approvals, 1, "only one pending request per user")
+	require.Len(t, bus.approvals, 1)
+	assert.Equal(t, domain.UserIdentifier("alice"), db.approvals[0].RequestedBy)
+	assert.Equal(t, 2, db.approvals[0].ExportCount)
+
+	validUntil := now.Add(time.Hour)
+	db.approvals[0].State = domain.ExportApprovalStateApproved
+	db.approvals[0].ValidUntil = &validUntil
+	require.NoError(t, m.validateExportApproval(ctx, "alice", foreignPeer("p3"), now))
+
+	require.NoError(t, m.validateExportApproval(ctx, "bob", foreignPeer("p5"), now.Add(2*time.Hour)),
+		"exports are counted per user")
+	require.NoError(t, m.validateExportApproval(ctx, "alice", foreignPeer("p6"), now.Add(2*time.Hour)),
+		"exports outside of the window are not counted")
+}
+
+func TestManager_CheckReveals(t *testing.T) {
+	m, bus := newTestManager(t, false)
+	m.cfg.ExportApproval = config.ExportApprovalConfig{Threshold: 2, Window: time.Hour, Validity: time.Hour}
+	db := m.db.(*fakeDatabase)
+	ctx := requestContext(time.Time{})
+	peers := []domain.Peer{{Identifier: "p1"}, {Identifier: "p2"}, {Identifier: "p3"}}
+	for i := range peers {
+		peers[i].Interface.PrivateKey = "private-key"
+	}
+
+	err := m.CheckReveals(ctx, peers)
+	assert.ErrorIs(t, err, domain.ErrApprovalRequired, "the whole set is checked")
+	assert.Empty(t, bus.reveals, "a denied check records no reveal")
+	assert.Empty(t, db.exports["alice"], "a denied check counts no export")
+	require.Len(t, db.approvals, 1)
+	assert.Equal(t, 0, db.approvals[0].ExportCount)
+
+	require.NoError(t, m.CheckReveals(ctx, peers[:2]))
+	assert.Empty(t, bus.reveals, "a check records no reveal")
+	assert.Empty(t, db.exports["alice"], "a check counts no export")
+
+	m.RecordReveal(ctx, &peers[0], domain.PeerConfigFormatFile)
+	require.Len(t, bus.reveals, 1)
+	assert.Equal(t, domain.PeerIdentifier("p1"), bus.reveals[0].Peer)
+	assert.Len(t, db.exports["alice"], 1)
+
+	require.NoError(t, m.CheckReveals(ctx, peers[:2]), "recorded peers are counted once")
+	assert.ErrorIs(t, m.CheckReveals(ctx, peers[1:]), domain.ErrApprovalRequired)
+}
+
+func TestManager_CheckReveals_StepUp(t *testing.T) {
+	m, bus := newTestManager(t, true)
+	peers := []domain.Peer{*testPeer()}
+
+	err := m.CheckReveals(requestContext(time.Now().Add(-time.Hour)), peers)
+	assert.ErrorIs(t, err, domain.ErrStepUpRequired)
+	require.NoError(t, m.CheckReveals(requestContext(time.Now().Add(-time.Minute)), peers))
+	assert.Empty(t, bus.reveals)
+}
+
+func TestManager_validateExportApproval_sharedDatabase(t *testing.T) {
+	m, _ := newTestManager(t, false)
+	m.cfg.ExportApproval = config.ExportApprovalConfig{Threshold: 2, Window: time.Hour, Validity: time.Hour}
+	now := time.Now()
+	ctx := context.Background()
+	foreignPeer := func(id string) *domain.Peer {
+		return &domain.Peer{Identifier: domain.PeerIdentifier(id), UserIdentifier: "carol"}
+	}
+
+	require.NoError(t, m.validateExportApproval(ctx, "alice", foreignPeer("p1"), now))
+	require.NoError(t, m.validateExportApproval(ctx, "alice", foreignPeer("p2"), now))
+
+	// a restarted or second instance uses the same database
+	other, err := NewKeyRevealManager(m.cfg, &fakeBus{}, m.db)
+	require.NoError(t, err)
+	err = other.validateExportApproval(ctx, "alice", foreignPeer("p3"), now.Add(time.Minute))
+	assert.ErrorIs(t, err, domain.ErrApprovalRequired, "exports of other instances are counted")
+	require.NoError(t, other.validateExportApproval(ctx, "alice", foreignPeer("p1"), now.Add(time.Minute)),
+		"peers are counted once")
+}
@@ -0,0 +1,54 @@
+package knock
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// firewallRuleset returns the nftables script that drops all packets to the given listen ports, unless the source
+// address was opened by a knock or the packet belongs to an established connection.
+// The script replaces an existing table, all opened addresses are removed.
+func firewallRuleset(table string, ports []uint16) string {
+	var sb strings.Builder
+
+	// declaring the table before deleting it ensures that the delete command succeeds if the table does not exist
+	fmt.Fprintf(&sb, "table inet %s\ndelete table inet %s\n", table, table)
+	fmt.Fprintf(&sb, "table inet %s {\n", table)
+	sb.WriteString("\tset allowed_v4 {\n\t\ttype ipv4_addr . inet_service\n\t\tflags timeout\n\t}\n")
+	sb.WriteString("\tset allowed_v6 {\n\t\ttype ipv6_addr . inet_service\n\t\tflags timeout\n\t}\n")
+	sb.WriteString("\tchain input {\n\t\ttype filter hook input priority -10; policy accept;\n")
+	sb.WriteString("\t\tct state established,related accept\n")
+	sb.WriteString("\t\tip saddr . udp dport @allowed_v4 accept\n")
+	sb.WriteString("\t\tip6 saddr . udp dport @allowed_v6 accept\n")
+	if len(ports) > 0 {
+		portList := make([]string, len(ports))
+		for i, port := range ports {
+			portList[i] = fmt.Sprintf("%d", port)
+		}
+		fmt.Fprintf(&sb, "\t\tudp dport { %s } drop\n", strings.Join(portList, ", "))
+	}
+	sb.WriteString("\t}\n}\n")
+
+	return sb.String()
+}
+
+// allowRuleset returns the nftables script that opens the given listen port for the source address.
+func allowRuleset(table string, source netip.Addr, port uint16, timeout time.Duration) string {
+	set := "allowed_v4"
+	if source.Is6() {
+		set = "allowed_v6"
+	}
+	key := fmt.Sprintf("%s . %d", source, port)
+	element := fmt.Sprintf("{ %s timeout %ds }", key, int(timeout.Seconds()))
+
+	// adding, deleting and re-adding the element in a single transaction refreshes the timeout of existing elements
+	return fmt.Sprintf("add element inet %[1]s %[2]s %[3]s\ndelete element inet %[1]s %[2]s { %[4]s }\n"+
+		"add element inet %[1]s %[2]s %[3]s\n", table, set, element, key)
+}
+
+// removeRuleset returns the nftables script that removes the table, if it exists.
+func removeRuleset(table string) string {
+	return fmt.Sprintf("table inet %s\ndelete table inet %s\n", table, table)
+}
@@ -0,0 +1,305 @@
+package knock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type InterfaceAndPeerDatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers for a given interface
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+}
+
+type FirewallRepo interface {
+	// ApplyRuleset applies the given nftables script in a single transaction.
+	ApplyRuleset(ctx context.Context, ruleset string) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+const refreshInterval = 1 * time.Minute
+
+// Manager runs the single packet authorization front door. The listen ports of all server interfaces are dropped
+// by the firewall, until a peer sends a valid knock packet. The knock opens the listen port of the interface of the
+// peer for the source address of the packet.
+type Manager struct {
+	cfg *config.Config
+
+	bus      EventBus
+	db       InterfaceAndPeerDatabaseRepo
+	firewall FirewallRepo
+
+	state *knockState
+}
+
+type knockState struct {
+	mux     sync.Mutex
+	targets map[string]knockTarget // by knock id
+	ports   []uint16               // the currently firewalled listen ports, nil if no ruleset was applied
+	nonces  map[string]time.Time   // the nonces of accepted packets, by nonce
+}
+
+// knockTarget is the listen port that is opened by the knock credentials of a peer.
+type knockTarget struct {
+	peer   domain.PeerIdentifier
+	secret string
+	port   uint16
+}
+
+// NewKnockManager creates a new port knocking manager instance.
+func NewKnockManager(
+	cfg *config.Config,
+	bus EventBus,
+	db InterfaceAndPeerDatabaseRepo,
+	firewall FirewallRepo,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:       db,
+		firewall: firewall,
+
+		state: &knockState{
+			targets: make(map[string]knockTarget),
+			nonces:  make(map[string]time.Time),
+		},
+	}
+
+	if !cfg.PortKnocking.Enabled {
+		return m, nil
+	}
+
+	if cfg.PortKnocking.Secret == "" {
+		return nil, errors.New("missing port knocking secret")
+	}
+	if _, _, err := net.SplitHostPort(cfg.PortKnocking.ListeningAddress); err != nil {
+		return nil, fmt.Errorf("invalid port knocking listening address: %w", err)
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerCreated, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicPeerUpdated, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicPeerInterfaceUpdated, m.handlePeerInterfaceUpdatedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceCreated, m.handleInterfaceEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceUpdated, m.handleInterfaceEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceEvent)
+}
+
+// StartBackgroundJobs starts the knock listener and the periodic refresh of the firewall rules.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.PortKnocking.Enabled {
+		return
+	}
+
+	conn, err := net.ListenPacket("udp", m.cfg.PortKnocking.ListeningAddress)
+	if err != nil {
+		slog.Error("failed to start knock listener", "address", m.cfg.PortKnocking.ListeningAddress, "error", err)
+		return
+	}
+	slog.Info("started knock listener", "address", m.cfg.PortKnocking.ListeningAddress)
+
+	go m.serve(conn)
+	go m.runRefresh(ctx, conn)
+}
+
+func (m Manager) handlePeerEvent(peer domain.Peer) {
+	slog.Debug("handling peer event", "peer", peer.Identifier)
+
+	m.refresh(context.Background())
+}
+
+func (m Manager) handlePeerInterfaceUpdatedEvent(id domain.InterfaceIdentifier) {
+	slog.Debug("handling peer interface updated event", "interface", id)
+
+	m.refresh(context.Background())
+}
+
+func (m Manager) handleInterfaceEvent(iface domain.Interface) {
+	slog.Debug("handling interface event", "interface", iface.Identifier)
+
+	m.refresh(context.Background())
+}
+
+// runRefresh periodically refreshes the knock credentials and the firewall rules. On shutdown, the listener is
+// closed and the firewall rules are removed.
+func (m Manager) runRefresh(ctx context.Context, conn net.PacketConn) {
+	m.refresh(ctx)
+
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(refreshInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		m.refresh(ctx)
+	}
+
+	_ = conn.Close()
+
+	// the context is already canceled, use a fresh context for the cleanup
+	ruleset := removeRuleset(m.cfg.PortKnocking.FirewallTable)
+	if err := m.firewall.ApplyRuleset(context.Background(), ruleset); err != nil {
+		slog.Error("failed to remove knock firewall rules", "error", err)
+	}
+}
+
+// refresh rebuilds the knock credentials of all peers and updates the firewall rules if the listen ports changed.
+func (m Manager) refresh(ctx context.Context) {
+	if err := m.ApplyState(ctx); err != nil {
+		slog.Error("failed to refresh port knocking", "error", err)
+	}
+}
+
+// ApplyState rebuilds the knock credentials of all peers and applies the knock firewall rules. The rules are only
+// replaced if the listen ports changed, as replacing them would remove all opened addresses.
+func (m Manager) ApplyState(ctx context.Context) error {
+	if !m.cfg.PortKnocking.Enabled {
+		return nil
+	}
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces for port knocking: %w", err)
+	}
+
+	targets := make(map[string]knockTarget)
+	ports := make([]uint16, 0, len(interfaces))
+	for _, iface := range interfaces {
+		if iface.IsDisabled() || iface.Type != domain.InterfaceTypeServer || iface.ListenPort <= 0 {
+			continue
+		}
+		port := uint16(iface.ListenPort)
+		if !slices.Contains(ports, port) {
+			ports = append(ports, port)
+		}
+
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			slog.Error("failed to load peers for port knocking", "interface", iface.Identifier, "error", err)
+			continue
+		}
+		for _, peer := range peers {
+			if peer.IsDisabled() {
+				continue
+			}
+			credentials := domain.DeriveKnockCredentials(m.cfg.PortKnocking.Secret, peer.Interface.PublicKey)
+			targets[credentials.Id] = knockTarget{peer: peer.Identifier, secret: credentials.Secret, port: port}
+		}
+	}
+	slices.Sort(ports)
+
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	m.state.targets = targets
+
+	if m.state.ports != nil && slices.Equal(m.state.ports, ports) {
+		return nil // replacing the ruleset would remove all opened addresses
+	}
+	if err := m.firewall.ApplyRuleset(ctx, firewallRuleset(m.cfg.PortKnocking.FirewallTable, ports)); err != nil {
+		return fmt.Errorf("failed to apply knock firewall rules: %w", err)
+	}
+	m.state.ports = ports
+	slog.Debug("applied knock firewall rules", "ports", ports)
+
+	return nil
+}
+
+func (m Manager) serve(conn net.PacketConn) {
+	buf := make([]byte, domain.KnockPacketSize+1) // larger packets are rejected by the parser
+	for {
+		n, remote, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		udpAddr, ok := remote.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		source := udpAddr.AddrPort().Addr().Unmap()
+
+		if err := m.handleKnock(context.Background(), buf[:n], source, time.Now()); err != nil {
+			slog.Debug("rejected knock packet", "source", source, "error", err)
+		}
+	}
+}
+
+// handleKnock verifies the given knock packet and opens the listen port of the peer for the source address.
+func (m Manager) handleKnock(ctx context.Context, data []byte, source netip.Addr, now time.Time) error {
+	packet, err := domain.ParseKnockPacket(data)
+	if err != nil {
+		return err
+	}
+
+	maxSkew := m.cfg.PortKnocking.MaxClockSkew
+	if skew := now.Sub(packet.Timestamp); skew > maxSkew || skew < -maxSkew {
+		return fmt.Errorf("knock timestamp %s is outside of the accepted time window", packet.Timestamp)
+	}
+
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	target, ok := m.state.targets[packet.Id]
+	if !ok {
+		return fmt.Errorf("unknown knock id %s", packet.Id)
+	}
+	if err := packet.Verify(target.secret); err != nil {
+		return err
+	}
+
+	// nonces only need to be remembered as long as the timestamp of the packet is accepted
+	for nonce, seen := range m.state.nonces {
+		if now.Sub(seen) > 2*maxSkew {
+			delete(m.state.nonces, nonce)
+		}
+	}
+	if _, replayed := m.state.nonces[packet.Nonce]; replayed {
+		return fmt.Errorf("replayed knock packet of peer %s", target.peer)
+	}
+	m.state.nonces[packet.Nonce] = now
+
+	ruleset := allowRuleset(m.cfg.PortKnocking.FirewallTable, source, target.port, m.cfg.PortKnocking.OpenDuration)
+	if err := m.firewall.ApplyRuleset(ctx, ruleset); err != nil {
+		return fmt.Errorf("failed to open listen port for peer %s: %w", target.peer, err)
+	}
+
+	slog.Info("accepted knock", "peer", target.peer, "source", source, "port", target.port)
+
+	return nil
+}
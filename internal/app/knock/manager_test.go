@@ -0,0 +1,133 @@
+package knock
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	interfaces []domain.Interface
+	peers      map[domain.InterfaceIdentifier][]domain.Peer
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	return f.peers[id], nil
+}
+
+type fakeFirewall struct {
+	rulesets []string
+}
+
+func (f *fakeFirewall) ApplyRuleset(_ context.Context, ruleset string) error {
+	f.rulesets = append(f.rulesets, ruleset)
+	return nil
+}
+
+func newTestManager(t *testing.T) (*Manager, *fakeDatabase, *fakeFirewall) {
+	cfg := &config.Config{}
+	cfg.PortKnocking = config.PortKnockingConfig{
+		Enabled:          true,
+		ListeningAddress: ":62201",
+		Secret:           "knock-secret",
+		OpenDuration:     5 * time.Minute,
+		MaxClockSkew:     30 * time.Second,
+		FirewallTable:    "wg_portal_knock",
+	}
+
+	db := &fakeDatabase{
+		interfaces: []domain.Interface{
+			{Identifier: "wg0", Type: domain.InterfaceTypeServer, ListenPort: 51820},
+			{Identifier: "wg1", Type: domain.InterfaceTypeClient, ListenPort: 51821},
+		},
+		peers: map[domain.InterfaceIdentifier][]domain.Peer{
+			"wg0": {{Identifier: "peer-a", Interface: domain.PeerInterfaceConfig{
+				KeyPair: domain.KeyPair{PublicKey: "key-a"},
+			}}},
+		},
+	}
+	firewall := &fakeFirewall{}
+
+	m, err := NewKnockManager(cfg, fakeBus{}, db, firewall)
+	require.NoError(t, err)
+
+	return m, db, firewall
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func knockPacket(t *testing.T, publicKey string, now time.Time, nonce byte) []byte {
+	data, err := domain.NewKnockPacket(domain.DeriveKnockCredentials("knock-secret", publicKey), now,
+		bytes.Repeat([]byte{nonce}, 16))
+	require.NoError(t, err)
+	return data
+}
+
+func TestNewKnockManager_missingSecret(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.PortKnocking.Enabled = true
+	cfg.PortKnocking.ListeningAddress = ":62201"
+
+	_, err := NewKnockManager(cfg, fakeBus{}, &fakeDatabase{}, &fakeFirewall{})
+	assert.Error(t, err)
+}
+
+func TestManager_refresh(t *testing.T) {
+	m, db, firewall := newTestManager(t)
+	ctx := context.Background()
+
+	m.refresh(ctx)
+	require.Len(t, firewall.rulesets, 1)
+	assert.Contains(t, firewall.rulesets[0], "udp dport { 51820 } drop", "client interfaces are not firewalled")
+
+	m.refresh(ctx)
+	assert.Len(t, firewall.rulesets, 1, "the ruleset is only replaced if the listen ports changed")
+
+	db.interfaces[0].ListenPort = 51830
+	m.refresh(ctx)
+	require.Len(t, firewall.rulesets, 2)
+	assert.Contains(t, firewall.rulesets[1], "udp dport { 51830 } drop")
+}
+
+func TestManager_handleKnock(t *testing.T) {
+	m, db, firewall := newTestManager(t)
+	ctx := context.Background()
+	now := time.Now()
+	source := netip.MustParseAddr("198.51.100.7")
+
+	m.refresh(ctx)
+	firewall.rulesets = nil
+
+	require.NoError(t, m.handleKnock(ctx, knockPacket(t, "key-a", now, 1), source, now))
+	require.Len(t, firewall.rulesets, 1)
+	assert.Contains(t, firewall.rulesets[0],
+		"add element inet wg_portal_knock allowed_v4 { 198.51.100.7 . 51820 timeout 300s }")
+
+	assert.Error(t, m.handleKnock(ctx, knockPacket(t, "key-a", now, 1), source, now), "replayed packet")
+	assert.Error(t, m.handleKnock(ctx, knockPacket(t, "key-b", now, 2), source, now), "unknown peer")
+	assert.Error(t, m.handleKnock(ctx, knockPacket(t, "key-a", now.Add(-time.Minute), 3), source, now),
+		"outdated packet")
+	assert.Error(t, m.handleKnock(ctx, []byte("knock"), source, now))
+	assert.Len(t, firewall.rulesets, 1)
+
+	// the credentials are rotated with the keys of the peer
+	db.peers["wg0"][0].Interface.PublicKey = "key-b"
+	m.refresh(ctx)
+	assert.Error(t, m.handleKnock(ctx, knockPacket(t, "key-a", now, 4), source, now))
+	require.NoError(t, m.handleKnock(ctx, knockPacket(t, "key-b", now, 5), netip.MustParseAddr("2001:db8::1"), now))
+	assert.Contains(t, firewall.rulesets[1], "allowed_v6 { 2001:db8::1 . 51820 timeout 300s }")
+}
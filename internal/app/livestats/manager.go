@@ -0,0 +1,145 @@
+package livestats
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+}
+
+type InterfaceController interface {
+	// GetPeer returns the state of the given peer on the WireGuard interface.
+	GetPeer(
+		_ context.Context,
+		deviceId domain.InterfaceIdentifier,
+		id domain.PeerIdentifier,
+	) (*domain.PhysicalPeer, error)
+}
+
+// endregion dependencies
+
+// Manager samples the traffic counters of single peers in short intervals, for example to debug throughput problems.
+// The samples are streamed to the caller and not stored.
+type Manager struct {
+	cfg *config.Config
+
+	db DatabaseRepo
+	wg InterfaceController
+
+	interval time.Duration
+}
+
+// NewLiveStatsManager creates a new live statistics manager.
+func NewLiveStatsManager(cfg *config.Config, db DatabaseRepo, wg InterfaceController) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+
+		db: db,
+		wg: wg,
+
+		interval: domain.LiveSampleInterval,
+	}
+
+	return m, nil
+}
+
+// SamplePeer starts the live sampling of the given peer. The samples are sent to the returned channel, which is
+// closed once the duration has passed, the context is done or the peer can no longer be read from the WireGuard
+// interface. If no duration is given, the configured maximum duration is used.
+func (m Manager) SamplePeer(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	duration time.Duration,
+) (<-chan domain.PeerLiveSample, error) {
+	maxDuration := m.cfg.Statistics.LiveSamplingMaxDuration
+	if maxDuration <= 0 {
+		return nil, fmt.Errorf("live sampling is disabled: %w", domain.ErrNoPermission)
+	}
+
+	if duration == 0 {
+		duration = maxDuration
+	}
+	if duration < 0 || duration > maxDuration {
+		return nil, fmt.Errorf("sampling duration must be between 1s and %s: %w", maxDuration, domain.ErrInvalidData)
+	}
+
+	peer, err := m.db.GetPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find peer %s: %w", id, err)
+	}
+	iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
+	if iface.IsDisabled() {
+		return nil, fmt.Errorf("interface %s is disabled: %w", iface.Identifier, domain.ErrInvalidData)
+	}
+
+	physicalPeer, err := m.wg.GetPeer(ctx, iface.Identifier, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read peer %s from interface %s: %w", id, iface.Identifier, err)
+	}
+
+	samples := make(chan domain.PeerLiveSample)
+	go m.sample(ctx, iface.Identifier, *physicalPeer, time.Now(), duration, samples)
+
+	slog.Debug("started live sampling", "peer", id, "interface", iface.Identifier, "duration", duration)
+
+	return samples, nil
+}
+
+func (m Manager) sample(
+	ctx context.Context,
+	ifaceId domain.InterfaceIdentifier,
+	previous domain.PhysicalPeer,
+	previousTime time.Time,
+	duration time.Duration,
+	samples chan<- domain.PeerLiveSample,
+) {
+	defer close(samples)
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Debug("stopped live sampling", "peer", previous.Identifier, "reason", ctx.Err())
+			return
+		case now := <-ticker.C:
+			current, err := m.wg.GetPeer(ctx, ifaceId, previous.Identifier)
+			if err != nil {
+				slog.Warn("live sampling failed", "peer", previous.Identifier, "interface", ifaceId, "error", err)
+				return
+			}
+
+			select {
+			case samples <- domain.NewPeerLiveSample(previous, *current, previousTime, now):
+			case <-ctx.Done():
+				return
+			}
+			previous, previousTime = *current, now
+		}
+	}
+}
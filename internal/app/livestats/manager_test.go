@@ -0,0 +1,156 @@
+package livestats
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	peer  domain.Peer
+	iface domain.Interface
+}
+
+func (f *fakeDatabase) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	if id != f.peer.Identifier {
+		return nil, domain.ErrNotFound
+	}
+	return &f.peer, nil
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, _ domain.InterfaceIdentifier) (*domain.Interface, error) {
+	return &f.iface, nil
+}
+
+// fakeController returns a peer whose counters grow by 1000 received and 100 transmitted bytes with each read.
+type fakeController struct {
+	mux   sync.Mutex
+	reads uint64
+	err   error
+}
+
+func (f *fakeController) GetPeer(
+	_ context.Context,
+	_ domain.InterfaceIdentifier,
+	id domain.PeerIdentifier,
+) (*domain.PhysicalPeer, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.reads++
+	return &domain.PhysicalPeer{Identifier: id, BytesUpload: f.reads * 1000, BytesDownload: f.reads * 100}, nil
+}
+
+func newTestManager(t *testing.T, maxDuration time.Duration) (*Manager, *fakeController) {
+	cfg := &config.Config{}
+	cfg.Statistics.LiveSamplingMaxDuration = maxDuration
+
+	db := &fakeDatabase{
+		peer:  domain.Peer{Identifier: "peer-1", InterfaceIdentifier: "wg0"},
+		iface: domain.Interface{Identifier: "wg0", Type: domain.InterfaceTypeServer},
+	}
+	wg := &fakeController{}
+
+	m, err := NewLiveStatsManager(cfg, db, wg)
+	require.NoError(t, err)
+	m.interval = 10 * time.Millisecond
+	return m, wg
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+}
+
+func TestManager_SamplePeer(t *testing.T) {
+	m, _ := newTestManager(t, time.Minute)
+
+	samples, err := m.SamplePeer(adminContext(), "peer-1", 95*time.Millisecond)
+	require.NoError(t, err)
+
+	var received []domain.PeerLiveSample
+	for sample := range samples {
+		received = append(received, sample)
+	}
+
+	require.NotEmpty(t, received, "samples are sent until the duration has passed")
+	assert.LessOrEqual(t, len(received), 9)
+	for _, sample := range received {
+		assert.Equal(t, domain.PeerIdentifier("peer-1"), sample.PeerId)
+		assert.Equal(t, uint64(1000), sample.BytesReceived)
+		assert.Equal(t, uint64(100), sample.BytesTransmitted)
+		assert.Positive(t, sample.ReceiveRate)
+	}
+}
+
+func TestManager_SamplePeer_Cancel(t *testing.T) {
+	m, wg := newTestManager(t, time.Minute)
+
+	ctx, cancel := context.WithCancel(adminContext())
+	samples, err := m.SamplePeer(ctx, "peer-1", 0)
+	require.NoError(t, err)
+
+	<-samples
+	cancel()
+	for range samples {
+		// drain until the sampling stopped
+	}
+
+	wg.mux.Lock()
+	reads := wg.reads
+	wg.mux.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	wg.mux.Lock()
+	defer wg.mux.Unlock()
+	assert.Equal(t, reads, wg.reads, "no reads after the context was canceled")
+}
+
+func TestManager_SamplePeer_Validation(t *testing.T) {
+	m, _ := newTestManager(t, time.Minute)
+
+	_, err := m.SamplePeer(adminContext(), "peer-1", 2*time.Minute)
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, err = m.SamplePeer(adminContext(), "missing", time.Second)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user"})
+	_, err = m.SamplePeer(userCtx, "peer-1", time.Second)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	disabled, _ := newTestManager(t, 0)
+	_, err = disabled.SamplePeer(adminContext(), "peer-1", time.Second)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_SamplePeer_PeerRemoved(t *testing.T) {
+	m, wg := newTestManager(t, time.Minute)
+
+	samples, err := m.SamplePeer(adminContext(), "peer-1", 0)
+	require.NoError(t, err)
+
+	wg.mux.Lock()
+	wg.err = domain.ErrNotFound
+	wg.mux.Unlock()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-samples:
+			if !ok {
+				return // the channel is closed if the peer can no longer be read
+			}
+		case <-timeout:
+			t.Fatal("sampling did not stop")
+		}
+	}
+}
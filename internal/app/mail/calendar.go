@@ -0,0 +1,174 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	calendarAttachmentName = "vpn-access-expiry.ics"
+	calendarTimeLayout     = "20060102T150405Z"
+	calendarLineLimit      = 75 // octets per line, longer lines are folded
+)
+
+// calendarEvent is a single calendar entry that is sent as ICS attachment.
+type calendarEvent struct {
+	Uid         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	Organizer   *mail.Address // if nil, the event is published instead of sent as invitation
+	Attendees   []string
+	AlarmBefore time.Duration // 0 disables the alarm
+}
+
+// newPeerExpiryEvent returns the calendar entry for the expiry of the given peer. The identifier of the entry only
+// depends on the peer, so calendar applications update the existing entry if the expiry date changes.
+func newPeerExpiryEvent(
+	externalUrl, from string,
+	peer *domain.Peer,
+	attendees []string,
+	alarmBefore time.Duration,
+) calendarEvent {
+	host := "wg-portal"
+	if u, err := url.Parse(externalUrl); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	peerHash := sha256.Sum256([]byte(peer.Identifier))
+
+	name := peer.DisplayName
+	if name == "" {
+		name = string(peer.Identifier)
+	}
+
+	event := calendarEvent{
+		Uid:     "peer-expiry-" + hex.EncodeToString(peerHash[:8]) + "@" + host,
+		Summary: "VPN access expires: " + name,
+		Description: fmt.Sprintf("The WireGuard VPN access of %s expires. Contact your administrator "+
+			"to extend the access: %s", name, externalUrl),
+		Start:       peer.ExpiresAt.UTC(),
+		End:         peer.ExpiresAt.UTC().Add(30 * time.Minute),
+		Attendees:   attendees,
+		AlarmBefore: alarmBefore,
+	}
+	if organizer, err := mail.ParseAddress(from); err == nil && len(attendees) > 0 {
+		event.Organizer = organizer
+	}
+
+	return event
+}
+
+// Method returns the iTIP method of the event: REQUEST for invitations, PUBLISH otherwise.
+func (e calendarEvent) Method() string {
+	if e.Organizer != nil {
+		return "REQUEST"
+	}
+	return "PUBLISH"
+}
+
+// ContentType returns the MIME type of the rendered event.
+func (e calendarEvent) ContentType() string {
+	return "text/calendar; charset=utf-8; method=" + e.Method()
+}
+
+// Render returns the event in iCalendar format (RFC 5545).
+func (e calendarEvent) Render(now time.Time) []byte {
+	var buf bytes.Buffer
+	line := func(content string) {
+		buf.WriteString(foldCalendarLine(content))
+		buf.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//WireGuard Portal//Peer Expiry//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:" + e.Method())
+	line("BEGIN:VEVENT")
+	line("UID:" + e.Uid)
+	line("DTSTAMP:" + now.UTC().Format(calendarTimeLayout))
+	line("DTSTART:" + e.Start.UTC().Format(calendarTimeLayout))
+	line("DTEND:" + e.End.UTC().Format(calendarTimeLayout))
+	line("SUMMARY:" + escapeCalendarText(e.Summary))
+	line("DESCRIPTION:" + escapeCalendarText(e.Description))
+	line("TRANSP:TRANSPARENT")
+	if e.Organizer != nil {
+		cn := e.Organizer.Name
+		if cn == "" {
+			cn = e.Organizer.Address
+		}
+		line("ORGANIZER;CN=" + quoteCalendarParam(cn) + ":mailto:" + e.Organizer.Address)
+		for _, attendee := range e.Attendees {
+			line("ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=FALSE:mailto:" + attendee)
+		}
+	}
+	if e.AlarmBefore > 0 {
+		line("BEGIN:VALARM")
+		line("ACTION:DISPLAY")
+		line("DESCRIPTION:" + escapeCalendarText(e.Summary))
+		line("TRIGGER:" + formatCalendarDuration(-e.AlarmBefore))
+		line("END:VALARM")
+	}
+	line("END:VEVENT")
+	line("END:VCALENDAR")
+
+	return buf.Bytes()
+}
+
+// formatCalendarDuration formats the given duration as DURATION value, see RFC 5545, section 3.3.6.
+func formatCalendarDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%sP%dD", sign, d/(24*time.Hour))
+	}
+	return fmt.Sprintf("%sPT%dM", sign, int(d.Minutes()))
+}
+
+// escapeCalendarText escapes a TEXT value as defined in RFC 5545, section 3.3.11.
+func escapeCalendarText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// quoteCalendarParam quotes a parameter value if it contains characters that are not allowed in plain values.
+func quoteCalendarParam(value string) string {
+	value = strings.ReplaceAll(value, `"`, "'")
+	if strings.ContainsAny(value, ":;,") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// foldCalendarLine splits content lines that are longer than 75 octets, see RFC 5545, section 3.1.
+// Lines are only split between UTF-8 characters.
+func foldCalendarLine(content string) string {
+	if len(content) <= calendarLineLimit {
+		return content
+	}
+
+	var sb strings.Builder
+	lineLen := 0
+	for _, r := range content {
+		runeLen := len(string(r))
+		if lineLen+runeLen > calendarLineLimit {
+			sb.WriteString("\r\n ")
+			lineLen = 1 // the leading space of the continuation line
+		}
+		sb.WriteRune(r)
+		lineLen += runeLen
+	}
+
+	return sb.String()
+}
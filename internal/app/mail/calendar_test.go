@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestCalendarEvent_Render(t *testing.T) {
+	expiresAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	peer := &domain.Peer{Identifier: "peer-a", DisplayName: "Contractor, Laptop", ExpiresAt: &expiresAt}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	event := newPeerExpiryEvent("https://vpn.example.com", "WireGuard Portal <noreply@example.com>", peer,
+		[]string{"alice@example.com"}, 24*time.Hour)
+	assert.Equal(t, "REQUEST", event.Method())
+	ics := string(event.Render(now))
+
+	assert.Contains(t, ics, "METHOD:REQUEST\r\n")
+	assert.Contains(t, ics, "DTSTART:20240601T000000Z\r\n")
+	assert.Contains(t, ics, "SUMMARY:VPN access expires: Contractor\\, Laptop\r\n")
+	assert.Contains(t, ics, "ORGANIZER;CN=WireGuard Portal:mailto:noreply@example.com\r\n")
+	assert.Contains(t, ics, "TRIGGER:-P1D\r\n")
+	assert.Contains(t, ics, "@vpn.example.com\r\n")
+	for _, line := range strings.Split(ics, "\r\n") {
+		assert.LessOrEqual(t, len(line), calendarLineLimit, "long lines must be folded")
+	}
+
+	// the identifier only depends on the peer, so updates replace the existing calendar entry
+	otherExpiry := expiresAt.Add(24 * time.Hour)
+	peer.ExpiresAt = &otherExpiry
+	assert.Equal(t, event.Uid, newPeerExpiryEvent("https://vpn.example.com", "", peer, nil, 0).Uid)
+
+	published := newPeerExpiryEvent("https://vpn.example.com", "invalid", peer, []string{"alice@example.com"}, 0)
+	assert.Equal(t, "PUBLISH", published.Method())
+	assert.NotContains(t, string(published.Render(now)), "ATTENDEE")
+	assert.NotContains(t, string(published.Render(now)), "VALARM")
+}
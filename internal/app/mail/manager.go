@@ -1,20 +1,54 @@
 package mail
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/h44z/wg-portal/internal/app"
 	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
+const (
+	peerConfigMailSubject     = "WireGuard VPN Configuration"
+	peerCleanupWarningSubject = "WireGuard VPN: inactive peers"
+	clientUpdateSubject       = "WireGuard VPN: please update your WireGuard app"
+	peerExpiryReminderSubject = "WireGuard VPN: your access expires soon"
+	peerSetupReminderSubject  = "WireGuard VPN: your VPN is not set up yet"
+	peerTransferSubject       = "WireGuard VPN: peer transfer"
+	loginNotificationSubject  = "WireGuard Portal: new sign-in to your account"
+	configDownloadSubject     = "WireGuard VPN: your configuration was downloaded"
+	addressPoolWarningSubject = "WireGuard Portal: address pool almost exhausted"
+	roamingWarningSubject     = "WireGuard VPN: your peer connected from an unusual location"
+	peerCompromisedSubject    = "WireGuard VPN: your peer was marked as compromised"
+	announcementSubject       = "WireGuard Portal: %s"
+	exportApprovalSubject     = "WireGuard Portal: export approval required"
+	exportDecisionSubject     = "WireGuard Portal: your export request was %s"
+)
+
 // region dependencies
 
 type Mailer interface {
 	// Send sends an email with the given subject and body to the given recipients.
 	Send(ctx context.Context, subject, body string, to []string, options *domain.MailOptions) error
+	// SetPassword replaces the SMTP password, for example, if the password was rotated.
+	SetPassword(password string)
+	// SetConfig replaces the SMTP settings, for example, if the config was reloaded.
+	SetConfig(cfg config.MailConfig)
+}
+
+type Messenger interface {
+	// SendMessage sends a plain text message to the given Telegram chat.
+	SendMessage(ctx context.Context, chatId, text string) error
 }
 
 type ConfigFileManager interface {
@@ -29,6 +63,11 @@ type ConfigFileManager interface {
 type UserDatabaseRepo interface {
 	// GetUser returns the user with the given identifier.
 	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	// GetUserNotificationPreferences returns the notification preferences of the given user.
+	GetUserNotificationPreferences(ctx context.Context, id domain.UserIdentifier) (
+		*domain.UserNotificationPreferences,
+		error,
+	)
 }
 
 type WireguardDatabaseRepo interface {
@@ -40,54 +79,270 @@ type WireguardDatabaseRepo interface {
 	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
 }
 
+type OrganizationDatabaseRepo interface {
+	// GetOrganization returns the organization with the given identifier.
+	GetOrganization(ctx context.Context, id domain.OrganizationIdentifier) (*domain.Organization, error)
+}
+
+type ConfigVersionRepo interface {
+	// GetPeerConfigVersions returns the recorded configuration versions of the given peer, ordered by version.
+	GetPeerConfigVersions(ctx context.Context, id domain.PeerIdentifier) ([]domain.PeerConfigVersion, error)
+	// SavePeerConfigVersion creates or updates the given peer configuration version.
+	SavePeerConfigVersion(ctx context.Context, version *domain.PeerConfigVersion) error
+	// DeletePeerConfigVersion deletes the given configuration version of the peer.
+	DeletePeerConfigVersion(ctx context.Context, id domain.PeerIdentifier, version int) error
+}
+
+type MailLogRepo interface {
+	// SaveMailLogEntry saves the given mail log entry.
+	SaveMailLogEntry(ctx context.Context, entry *domain.MailLogEntry) error
+	// FindMailLogEntries returns all mail log entries that match the given filter, newest first.
+	FindMailLogEntries(ctx context.Context, filter domain.MailLogFilter) ([]domain.MailLogEntry, error)
+	// GetMailLogEntryByTrackingToken returns the mail log entry of the tracked mail with the given token.
+	GetMailLogEntryByTrackingToken(ctx context.Context, token string) (*domain.MailLogEntry, error)
+}
+
 type TemplateRenderer interface {
 	// GetConfigMail returns the text and html template for the mail with a link.
-	GetConfigMail(user *domain.User, link string) (io.Reader, io.Reader, error)
+	GetConfigMail(
+		user *domain.User,
+		org *domain.Organization,
+		link, trackingPixel string,
+		changes []domain.PeerConfigChange,
+	) (io.Reader, io.Reader, error)
 	// GetConfigMailWithAttachment returns the text and html template for the mail with an attachment.
-	GetConfigMailWithAttachment(user *domain.User, cfgName, qrName string) (
+	GetConfigMailWithAttachment(
+		user *domain.User,
+		org *domain.Organization,
+		peer *domain.Peer,
+		cfgName, qrName, guideName, trackingPixel string,
+		changes []domain.PeerConfigChange,
+	) (io.Reader, io.Reader, error)
+	// GetSetupGuide returns the html setup guide for the operating system of the peer.
+	GetSetupGuide(user *domain.User, org *domain.Organization, peer *domain.Peer, cfgName string) (io.Reader, error)
+	// GetPeerCleanupWarningMail returns the text and html template for the peer cleanup warning mail.
+	GetPeerCleanupWarningMail(
+		user *domain.User,
+		org *domain.Organization,
+		action string,
+		candidates []domain.PeerCleanupCandidate,
+	) (io.Reader, io.Reader, error)
+	// GetPeerExpiryReminderMail returns the text and html template for the peer expiry reminder mail.
+	GetPeerExpiryReminderMail(user *domain.User, org *domain.Organization, peer *domain.Peer) (
+		io.Reader,
+		io.Reader,
+		error,
+	)
+	// GetPeerSetupReminderMail returns the text and html template for the peer setup reminder mail.
+	GetPeerSetupReminderMail(user *domain.User, org *domain.Organization, peer *domain.Peer, mailedAt time.Time) (
+		io.Reader,
+		io.Reader,
+		error,
+	)
+	// GetClientUpdateMail returns the text and html template for the client update notification mail.
+	GetClientUpdateMail(user *domain.User, org *domain.Organization, clients []domain.OutdatedClient) (
+		io.Reader,
+		io.Reader,
+		error,
+	)
+	// GetAddressPoolWarningMail returns the text and html template for the address pool warning mail.
+	GetAddressPoolWarningMail(
+		user *domain.User,
+		org *domain.Organization,
+		iface *domain.Interface,
+		pools []domain.AddressPoolCapacity,
+	) (io.Reader, io.Reader, error)
+	// GetPeerTransferMail returns the text and html template for the peer transfer notification mail.
+	GetPeerTransferMail(user *domain.User, org *domain.Organization, transfer *domain.PeerTransfer) (
+		io.Reader,
+		io.Reader,
+		error,
+	)
+	// GetLoginNotificationMail returns the text and html template for the new login device notification mail.
+	GetLoginNotificationMail(user *domain.User, org *domain.Organization, device *domain.UserLoginDevice) (
+		io.Reader,
+		io.Reader,
+		error,
+	)
+	// GetConfigDownloadMail returns the text and html template for the peer config download notification mail.
+	GetConfigDownloadMail(user *domain.User, org *domain.Organization, download *domain.PeerConfigDownload) (
+		io.Reader,
+		io.Reader,
+		error,
+	)
+	// GetRoamingWarningMail returns the text and html template for the roaming policy warning mail.
+	GetRoamingWarningMail(
+		user *domain.User,
+		org *domain.Organization,
+		violation *domain.RoamingViolation,
+		disabled bool,
+	) (io.Reader, io.Reader, error)
+	// GetPeerCompromisedMail returns the text and html template for the compromised peer notification mail.
+	GetPeerCompromisedMail(user *domain.User, org *domain.Organization, incident *domain.PeerCompromise) (
+		io.Reader,
+		io.Reader,
+		error,
+	)
+	// GetAnnouncementMail returns the text and html template for the announcement mail.
+	GetAnnouncementMail(user *domain.User, org *domain.Organization, announcement *domain.Announcement) (
+		io.Reader,
+		io.Reader,
+		error,
+	)
+	// GetExportApprovalMail returns the text and html template for the export approval request and decision mail.
+	GetExportApprovalMail(user *domain.User, org *domain.Organization, approval *domain.ExportApproval) (
 		io.Reader,
 		io.Reader,
 		error,
 	)
 }
 
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
 // endregion dependencies
 
 type Manager struct {
 	cfg *config.Config
+	bus EventBus
 
-	tplHandler  TemplateRenderer
+	state       *mailState
 	mailer      Mailer
+	messenger   Messenger
+	throttle    *throttle
 	configFiles ConfigFileManager
 	users       UserDatabaseRepo
 	wg          WireguardDatabaseRepo
+	orgs        OrganizationDatabaseRepo
+	versions    ConfigVersionRepo
+	mailLog     MailLogRepo
 }
 
 // NewMailManager creates a new mail manager.
 func NewMailManager(
 	cfg *config.Config,
+	bus EventBus,
 	mailer Mailer,
+	messenger Messenger,
 	configFiles ConfigFileManager,
 	users UserDatabaseRepo,
 	wg WireguardDatabaseRepo,
+	orgs OrganizationDatabaseRepo,
+	versions ConfigVersionRepo,
+	mailLog MailLogRepo,
 ) (*Manager, error) {
-	tplHandler, err := newTemplateHandler(cfg.Web.ExternalUrl)
+	tplHandler, err := newTemplateHandler(cfg.Web.ExternalUrl, cfg.Mail)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize template handler: %w", err)
 	}
 
 	m := &Manager{
 		cfg:         cfg,
-		tplHandler:  tplHandler,
+		bus:         bus,
+		state:       &mailState{cfg: cfg.Mail, tplHandler: tplHandler},
 		mailer:      mailer,
+		messenger:   messenger,
+		throttle:    newThrottle(cfg.Mail),
 		configFiles: configFiles,
 		users:       users,
 		wg:          wg,
+		orgs:        orgs,
+		versions:    versions,
+		mailLog:     mailLog,
 	}
 
+	m.connectToMessageBus()
+
 	return m, nil
 }
 
+// mailState holds the mail settings that are replaced if the config is reloaded.
+type mailState struct {
+	mux        sync.RWMutex
+	cfg        config.MailConfig
+	tplHandler TemplateRenderer
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicSecretRotated, m.handleSecretRotatedEvent)
+	_ = m.bus.Subscribe(app.TopicConfigReloaded, m.handleConfigReloadedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceRenumbered, m.handleInterfaceRenumberedEvent)
+}
+
+// mailConfig returns the current mail settings.
+func (m Manager) mailConfig() config.MailConfig {
+	m.state.mux.RLock()
+	defer m.state.mux.RUnlock()
+
+	return m.state.cfg
+}
+
+// templates returns the current mail template renderer.
+func (m Manager) templates() TemplateRenderer {
+	m.state.mux.RLock()
+	defer m.state.mux.RUnlock()
+
+	return m.state.tplHandler
+}
+
+// handleConfigReloadedEvent applies the reloaded mail settings. The templates are loaded again, so changed template
+// files are applied as well. If the templates are invalid, the current settings are kept.
+func (m Manager) handleConfigReloadedEvent(cfg *config.Config) {
+	slog.Debug("handling config reloaded event", "section", "mail")
+
+	tplHandler, err := newTemplateHandler(m.cfg.Web.ExternalUrl, cfg.Mail)
+	if err != nil {
+		slog.Error("failed to reload mail templates, keeping the current mail settings", "error", err)
+		return
+	}
+
+	m.state.mux.Lock()
+	m.state.cfg = cfg.Mail
+	m.state.tplHandler = tplHandler
+	m.state.mux.Unlock()
+
+	m.mailer.SetConfig(cfg.Mail)
+
+	slog.Info("reloaded mail settings", "host", cfg.Mail.Host, "from", cfg.Mail.From)
+}
+
+func (m Manager) handleSecretRotatedEvent(rotation domain.SecretRotation) {
+	if rotation.Field != "mail.password" {
+		return
+	}
+
+	slog.Debug("handling secret rotated event", "field", rotation.Field)
+
+	m.mailer.SetPassword(string(rotation.Value))
+}
+
+// handleInterfaceRenumberedEvent mails the updated configuration to the users of all renumbered peers, if requested.
+// Disabled peers and peers without a user are skipped.
+func (m Manager) handleInterfaceRenumberedEvent(renumbering domain.InterfaceRenumbering) {
+	if !renumbering.SendMails {
+		return
+	}
+
+	slog.Debug("handling interface renumbered event", "interface", renumbering.InterfaceId)
+
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	linkOnly := m.mailConfig().LinkOnly
+	for _, peer := range renumbering.Peers {
+		if peer.Disabled || peer.UserIdentifier == "" {
+			continue
+		}
+
+		if err := m.SendPeerEmail(ctx, linkOnly, peer.PeerId); err != nil {
+			slog.Error("failed to send renumbered peer configuration",
+				"interface", renumbering.InterfaceId,
+				"peer", peer.PeerId,
+				"error", err)
+		}
+	}
+}
+
 // SendPeerEmail sends an email to the user linked to the given peers.
 func (m Manager) SendPeerEmail(ctx context.Context, linkOnly bool, peers ...domain.PeerIdentifier) error {
 	for _, peerId := range peers {
@@ -96,10 +351,28 @@ func (m Manager) SendPeerEmail(ctx context.Context, linkOnly bool, peers ...doma
 			return fmt.Errorf("failed to fetch peer %s: %w", peerId, err)
 		}
 
-		if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+		iface, err := m.wg.GetInterface(ctx, peer.InterfaceIdentifier)
+		if err != nil {
+			return fmt.Errorf("failed to fetch interface %s: %w", peer.InterfaceIdentifier, err)
+		}
+
+		mailCtx := ctx
+		if currentUser := domain.GetUserInfo(ctx); currentUser.IsHelpdesk && currentUser.Id != peer.UserIdentifier &&
+			!currentUser.IsInterfaceAdmin(iface) {
+			// helpdesk users resend the configuration to the owner without getting access to the key material
+			mailCtx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+			slog.Info("helpdesk resends peer email", "peer", peerId, "by", currentUser.Id)
+		} else if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier, iface.OrganizationIdentifier,
+			iface.Identifier); err != nil {
 			return err
 		}
 
+		if currentUser := domain.GetUserInfo(ctx); currentUser.Id != peer.UserIdentifier {
+			if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+				return err
+			}
+		}
+
 		if peer.UserIdentifier == "" {
 			slog.Debug("skipping peer email",
 				"peer", peerId,
@@ -116,14 +389,31 @@ func (m Manager) SendPeerEmail(ctx context.Context, linkOnly bool, peers ...doma
 			continue
 		}
 
-		if user.Email == "" {
+		if user.IsServiceAccount() && len(peer.MailRecipients()) == 0 {
+			slog.Debug("skipping peer email",
+				"peer", peerId,
+				"reason", "service accounts do not receive mails")
+			continue
+		}
+
+		if m.getNotificationPreferences(ctx, user.Identifier).Channel(domain.NotificationCategoryConfig) ==
+			domain.NotificationChannelNone {
+			slog.Debug("skipping peer email",
+				"peer", peerId,
+				"reason", "disabled by user")
+			continue
+		}
+
+		if user.Email == "" && len(peer.MailRecipients()) == 0 {
 			slog.Debug("skipping peer email",
 				"peer", peerId,
 				"reason", "user has no mail address")
 			continue
 		}
 
-		err = m.sendPeerEmail(ctx, linkOnly, user, peer)
+		// the peer belongs to the organization of its interface, so the interface decides about the branding
+		err = m.sendPeerEmail(mailCtx, linkOnly, user, m.getOrganization(ctx, iface.OrganizationIdentifier), iface,
+			peer)
 		if err != nil {
 			return fmt.Errorf("failed to send peer email for %s: %w", peerId, err)
 		}
@@ -132,17 +422,43 @@ func (m Manager) SendPeerEmail(ctx context.Context, linkOnly bool, peers ...doma
 	return nil
 }
 
-func (m Manager) sendPeerEmail(ctx context.Context, linkOnly bool, user *domain.User, peer *domain.Peer) error {
+func (m Manager) sendPeerEmail(
+	ctx context.Context,
+	linkOnly bool,
+	user *domain.User,
+	org *domain.Organization,
+	iface *domain.Interface,
+	peer *domain.Peer,
+) error {
 	qrName := "WireGuardQRCode.png"
 	configName := peer.GetConfigFileName()
 
+	var peerConfigQr io.Reader
+	if !linkOnly {
+		var err error
+		peerConfigQr, err = m.configFiles.GetPeerConfigQrCode(ctx, peer.Identifier)
+		switch {
+		case errors.Is(err, domain.ErrConfigTooLarge):
+			slog.Warn("config too large for QR, link sent instead", "peer", peer.Identifier, "error", err)
+			linkOnly = true
+		case err != nil:
+			return fmt.Errorf("failed to fetch peer config QR code for %s: %w", peer.Identifier, err)
+		}
+	}
+
+	versions, changes := m.getConfigChanges(ctx, peer)
+
+	link, trackingPixel, trackingToken, err := m.newMailTracking()
+	if err != nil {
+		return fmt.Errorf("failed to create mail tracking token: %w", err)
+	}
+
 	var (
 		txtMail, htmlMail io.Reader
-		err               error
 		mailOptions       domain.MailOptions
 	)
 	if linkOnly {
-		txtMail, htmlMail, err = m.tplHandler.GetConfigMail(user, "deep link TBD")
+		txtMail, htmlMail, err = m.templates().GetConfigMail(user, org, link, trackingPixel, changes)
 		if err != nil {
 			return fmt.Errorf("failed to get mail body: %w", err)
 		}
@@ -153,19 +469,43 @@ func (m Manager) sendPeerEmail(ctx context.Context, linkOnly bool, user *domain.
 			return fmt.Errorf("failed to fetch peer config for %s: %w", peer.Identifier, err)
 		}
 
-		peerConfigQr, err := m.configFiles.GetPeerConfigQrCode(ctx, peer.Identifier)
-		if err != nil {
-			return fmt.Errorf("failed to fetch peer config QR code for %s: %w", peer.Identifier, err)
+		configContentType := "text/plain"
+		if m.mailConfig().CompressAttachment {
+			peerConfig, err = compressAttachment(peerConfig)
+			if err != nil {
+				return fmt.Errorf("failed to compress peer config for %s: %w", peer.Identifier, err)
+			}
+			configName += ".gz"
+			configContentType = "application/gzip"
+		}
+
+		var guide *domain.MailAttachment
+		if m.mailConfig().SetupGuides && peer.OperatingSystem.SetupGuideTemplate() != "" {
+			guideData, err := m.templates().GetSetupGuide(user, org, peer, configName)
+			if err != nil {
+				return fmt.Errorf("failed to get setup guide for %s: %w", peer.Identifier, err)
+			}
+			guide = &domain.MailAttachment{
+				Name:        peer.OperatingSystem.SetupGuideFileName(),
+				ContentType: "text/html",
+				Data:        guideData,
+				Embedded:    false,
+			}
 		}
 
-		txtMail, htmlMail, err = m.tplHandler.GetConfigMailWithAttachment(user, configName, qrName)
+		guideName := ""
+		if guide != nil {
+			guideName = guide.Name
+		}
+		txtMail, htmlMail, err = m.templates().GetConfigMailWithAttachment(user, org, peer, configName, qrName,
+			guideName, trackingPixel, changes)
 		if err != nil {
 			return fmt.Errorf("failed to get full mail body: %w", err)
 		}
 
 		mailOptions.Attachments = append(mailOptions.Attachments, domain.MailAttachment{
 			Name:        configName,
-			ContentType: "text/plain",
+			ContentType: configContentType,
 			Data:        peerConfig,
 			Embedded:    false,
 		})
@@ -175,13 +515,988 @@ func (m Manager) sendPeerEmail(ctx context.Context, linkOnly bool, user *domain.
 			Data:        peerConfigQr,
 			Embedded:    true,
 		})
+		if guide != nil {
+			mailOptions.Attachments = append(mailOptions.Attachments, *guide)
+		}
 	}
 
 	txtMailStr, _ := io.ReadAll(txtMail)
 	htmlMailStr, _ := io.ReadAll(htmlMail)
 	mailOptions.HtmlBody = string(htmlMailStr)
+	mailOptions.Cc = iface.PeerMailCc()
+	mailOptions.Bcc = iface.PeerMailBcc()
+
+	recipients := peer.MailRecipients()
+	if user.IsServiceAccount() && len(recipients) == 0 {
+		slog.Debug("skipping peer expiry reminder",
+			"peer", peer.Identifier,
+			"reason", "service accounts do not receive mails")
+		return nil
+	}
+	if user.Email != "" {
+		recipients = append([]string{user.Email}, recipients...)
+	}
+
+	if m.mailConfig().CalendarInvites && peer.ExpiresAt != nil {
+		mailOptions.Attachments = append(mailOptions.Attachments, m.newExpiryAttachment(peer, recipients))
+	}
+
+	info := mailInfo{template: "mail_with_attachment", user: user.Identifier, peer: peer.Identifier,
+		trackingToken: trackingToken}
+	if linkOnly {
+		info.template = "mail_with_link"
+	}
+	err = m.send(ctx, info, peerConfigMailSubject, string(txtMailStr), recipients, &mailOptions)
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	m.recordConfigVersion(ctx, peer, versions)
+
+	return nil
+}
+
+// maxPeerConfigVersions is the number of mailed configuration versions that are kept per peer.
+const maxPeerConfigVersions = 10
+
+// getConfigChanges returns the recorded configuration versions of the peer and the settings that changed since the
+// last mailed version. If the configuration was not mailed before, no changes are returned. The version history is
+// optional, the mail is sent even if the history cannot be loaded.
+func (m Manager) getConfigChanges(ctx context.Context, peer *domain.Peer) (
+	[]domain.PeerConfigVersion,
+	[]domain.PeerConfigChange,
+) {
+	versions, err := m.versions.GetPeerConfigVersions(ctx, peer.Identifier)
+	if err != nil {
+		slog.Warn("failed to load peer config versions", "peer", peer.Identifier, "error", err)
+		return nil, nil
+	}
+	if len(versions) == 0 {
+		return nil, nil
+	}
+
+	lastVersion := versions[len(versions)-1]
+	return versions, domain.NewPeerConfigSettings(peer).Changes(lastVersion.Settings)
+}
+
+// recordConfigVersion records the mailed configuration as new version of the peer. The oldest versions are removed
+// once more than maxPeerConfigVersions are recorded.
+func (m Manager) recordConfigVersion(ctx context.Context, peer *domain.Peer, versions []domain.PeerConfigVersion) {
+	version := domain.PeerConfigVersion{
+		PeerId:   peer.Identifier,
+		Version:  1,
+		MailedAt: time.Now(),
+		Settings: domain.NewPeerConfigSettings(peer),
+	}
+	if len(versions) > 0 {
+		version.Version = versions[len(versions)-1].Version + 1
+	}
+
+	if err := m.versions.SavePeerConfigVersion(ctx, &version); err != nil {
+		slog.Warn("failed to record peer config version", "peer", peer.Identifier, "error", err)
+		return
+	}
+
+	versions = append(versions, version)
+	for _, old := range versions[:max(0, len(versions)-maxPeerConfigVersions)] {
+		if err := m.versions.DeletePeerConfigVersion(ctx, old.PeerId, old.Version); err != nil {
+			slog.Warn("failed to delete peer config version", "peer", peer.Identifier, "version", old.Version,
+				"error", err)
+		}
+	}
+}
+
+// SendPeerCleanupWarning sends an email to the given user that lists all peers which will be cleaned up
+// due to inactivity.
+func (m Manager) SendPeerCleanupWarning(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	candidates []domain.PeerCleanupCandidate,
+) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	user, err := m.users.GetUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", userId, err)
+	}
+
+	if user.IsServiceAccount() {
+		slog.Debug("skipping peer cleanup warning email",
+			"user", userId,
+			"reason", "service accounts do not receive mails")
+		return nil
+	}
+
+	prefs := m.getNotificationPreferences(ctx, userId)
+	if skip, reason := skipNotification(prefs, domain.NotificationCategoryExpiry, user.Email != ""); skip {
+		slog.Debug("skipping peer cleanup warning email",
+			"user", userId,
+			"reason", reason)
+		return nil
+	}
+
+	txtMail, htmlMail, err := m.templates().GetPeerCleanupWarningMail(user,
+		m.getOrganization(ctx, user.OrganizationIdentifier), string(m.cfg.PeerCleanup.Action), candidates)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	info := mailInfo{template: "mail_peer_cleanup_warning", user: userId}
+	err = m.notify(ctx, prefs, domain.NotificationCategoryExpiry, info, peerCleanupWarningSubject,
+		string(txtMailStr), []string{user.Email}, &domain.MailOptions{HtmlBody: string(htmlMailStr)})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// SendClientUpdateNotification sends an email to the given user that lists all devices with an outdated
+// WireGuard app, including update instructions for the respective operating system.
+func (m Manager) SendClientUpdateNotification(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	clients []domain.OutdatedClient,
+) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	user, err := m.users.GetUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", userId, err)
+	}
+
+	if user.IsServiceAccount() {
+		slog.Debug("skipping client update email",
+			"user", userId,
+			"reason", "service accounts do not receive mails")
+		return nil
+	}
+
+	if user.Email == "" {
+		slog.Debug("skipping client update email",
+			"user", userId,
+			"reason", "user has no mail address")
+		return nil
+	}
+
+	txtMail, htmlMail, err := m.templates().GetClientUpdateMail(user,
+		m.getOrganization(ctx, user.OrganizationIdentifier), clients)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	info := mailInfo{template: "mail_client_update", user: userId}
+	err = m.send(ctx, info, clientUpdateSubject, string(txtMailStr), []string{user.Email},
+		&domain.MailOptions{HtmlBody: string(htmlMailStr)})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// SendAddressPoolWarning sends an email to the given administrator that lists the address pools of the interface
+// that crossed the utilization warning threshold.
+func (m Manager) SendAddressPoolWarning(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	iface *domain.Interface,
+	pools []domain.AddressPoolCapacity,
+) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	user, err := m.users.GetUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", userId, err)
+	}
+
+	if user.IsServiceAccount() {
+		slog.Debug("skipping address pool warning email",
+			"user", userId,
+			"reason", "service accounts do not receive mails")
+		return nil
+	}
+
+	if user.Email == "" {
+		slog.Debug("skipping address pool warning email",
+			"user", userId,
+			"reason", "user has no mail address")
+		return nil
+	}
+
+	txtMail, htmlMail, err := m.templates().GetAddressPoolWarningMail(user,
+		m.getOrganization(ctx, user.OrganizationIdentifier), iface, pools)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	info := mailInfo{template: "mail_address_pool_warning", user: userId}
+	err = m.send(ctx, info, addressPoolWarningSubject, string(txtMailStr), []string{user.Email},
+		&domain.MailOptions{HtmlBody: string(htmlMailStr)})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// SendPeerExpiryReminder sends an email with a calendar invitation to the owner of the given peer, reminding
+// them of the upcoming expiry of the peer.
+func (m Manager) SendPeerExpiryReminder(ctx context.Context, peer *domain.Peer) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+	if peer.ExpiresAt == nil {
+		return fmt.Errorf("peer %s has no expiry date: %w", peer.Identifier, domain.ErrInvalidData)
+	}
+
+	user, err := m.users.GetUser(ctx, peer.UserIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", peer.UserIdentifier, err)
+	}
+
+	recipients := peer.MailRecipients()
+	if user.Email != "" {
+		recipients = append([]string{user.Email}, recipients...)
+	}
+	prefs := m.getNotificationPreferences(ctx, peer.UserIdentifier)
+	if skip, reason := skipNotification(prefs, domain.NotificationCategoryExpiry, len(recipients) > 0); skip {
+		slog.Debug("skipping peer expiry reminder",
+			"peer", peer.Identifier,
+			"reason", reason)
+		return nil
+	}
+
+	iface, err := m.wg.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to fetch interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+
+	txtMail, htmlMail, err := m.templates().GetPeerExpiryReminderMail(user,
+		m.getOrganization(ctx, iface.OrganizationIdentifier), peer)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	info := mailInfo{template: "mail_peer_expiry_reminder", user: peer.UserIdentifier, peer: peer.Identifier}
+	err = m.notify(ctx, prefs, domain.NotificationCategoryExpiry, info, peerExpiryReminderSubject,
+		string(txtMailStr), recipients, &domain.MailOptions{
+			HtmlBody:    string(htmlMailStr),
+			Attachments: []domain.MailAttachment{m.newExpiryAttachment(peer, recipients)},
+		})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// SendPeerSetupReminder reminds the owner of the given peer to set up the configuration that was mailed at the
+// given time, because the peer never connected. The mail contains setup instructions for the common platforms.
+func (m Manager) SendPeerSetupReminder(ctx context.Context, peer *domain.Peer, mailedAt time.Time) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	user, err := m.users.GetUser(ctx, peer.UserIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", peer.UserIdentifier, err)
+	}
+
+	recipients := peer.MailRecipients()
+	if user.Email != "" {
+		recipients = append([]string{user.Email}, recipients...)
+	}
+	prefs := m.getNotificationPreferences(ctx, peer.UserIdentifier)
+	if skip, reason := skipNotification(prefs, domain.NotificationCategoryConfig, len(recipients) > 0); skip {
+		slog.Debug("skipping peer setup reminder",
+			"peer", peer.Identifier,
+			"reason", reason)
+		return nil
+	}
+
+	iface, err := m.wg.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to fetch interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+
+	txtMail, htmlMail, err := m.templates().GetPeerSetupReminderMail(user,
+		m.getOrganization(ctx, iface.OrganizationIdentifier), peer, mailedAt)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	info := mailInfo{template: "mail_peer_setup_reminder", user: peer.UserIdentifier, peer: peer.Identifier}
+	err = m.notify(ctx, prefs, domain.NotificationCategoryConfig, info, peerSetupReminderSubject,
+		string(txtMailStr), recipients, &domain.MailOptions{HtmlBody: string(htmlMailStr)})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// SendPeerTransferNotification informs the given user about the current state of a peer transfer. The user must
+// either be the previous or the new owner of the peer.
+func (m Manager) SendPeerTransferNotification(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	transfer *domain.PeerTransfer,
+) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+	if !transfer.IsParticipant(userId) {
+		return fmt.Errorf("user %s is not part of transfer %s: %w", userId, transfer.Identifier,
+			domain.ErrInvalidData)
+	}
+
+	user, err := m.users.GetUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", userId, err)
+	}
+
+	if user.IsServiceAccount() {
+		slog.Debug("skipping peer transfer email",
+			"user", userId,
+			"transfer", transfer.Identifier,
+			"reason", "service accounts do not receive mails")
+		return nil
+	}
+
+	if user.Email == "" {
+		slog.Debug("skipping peer transfer email",
+			"user", userId,
+			"transfer", transfer.Identifier,
+			"reason", "user has no mail address")
+		return nil
+	}
+
+	txtMail, htmlMail, err := m.templates().GetPeerTransferMail(user,
+		m.getOrganization(ctx, user.OrganizationIdentifier), transfer)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	info := mailInfo{template: "mail_peer_transfer", user: userId, peer: transfer.PeerId}
+	err = m.send(ctx, info, peerTransferSubject, string(txtMailStr), []string{user.Email},
+		&domain.MailOptions{HtmlBody: string(htmlMailStr)})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// SendLoginNotification informs the given user about a login to their account from a new device.
+func (m Manager) SendLoginNotification(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	device *domain.UserLoginDevice,
+) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	user, err := m.users.GetUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", userId, err)
+	}
+
+	prefs := m.getNotificationPreferences(ctx, userId)
+	if skip, reason := skipNotification(prefs, domain.NotificationCategorySecurity, user.Email != ""); skip {
+		slog.Debug("skipping login notification email",
+			"user", userId,
+			"reason", reason)
+		return nil
+	}
+
+	txtMail, htmlMail, err := m.templates().GetLoginNotificationMail(user,
+		m.getOrganization(ctx, user.OrganizationIdentifier), device)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	info := mailInfo{template: "mail_login_notification", user: userId}
+	err = m.notify(ctx, prefs, domain.NotificationCategorySecurity, info, loginNotificationSubject,
+		string(txtMailStr), []string{user.Email}, &domain.MailOptions{HtmlBody: string(htmlMailStr)})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// SendConfigDownloadNotification informs the owner of a peer about the download of the peer configuration.
+func (m Manager) SendConfigDownloadNotification(ctx context.Context, download *domain.PeerConfigDownload) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	userId := download.Peer.UserIdentifier
+	user, err := m.users.GetUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", userId, err)
+	}
+
+	if user.IsServiceAccount() {
+		slog.Debug("skipping config download email",
+			"peer", download.Peer.Identifier,
+			"reason", "service accounts do not receive mails")
+		return nil
+	}
+
+	prefs := m.getNotificationPreferences(ctx, userId)
+	if skip, reason := skipNotification(prefs, domain.NotificationCategorySecurity, user.Email != ""); skip {
+		slog.Debug("skipping config download email",
+			"peer", download.Peer.Identifier,
+			"reason", reason)
+		return nil
+	}
+
+	iface, err := m.wg.GetInterface(ctx, download.Peer.InterfaceIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to fetch interface %s: %w", download.Peer.InterfaceIdentifier, err)
+	}
+
+	txtMail, htmlMail, err := m.templates().GetConfigDownloadMail(user,
+		m.getOrganization(ctx, iface.OrganizationIdentifier), download)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	info := mailInfo{template: "mail_config_download", user: userId, peer: download.Peer.Identifier}
+	err = m.notify(ctx, prefs, domain.NotificationCategorySecurity, info, configDownloadSubject,
+		string(txtMailStr), []string{user.Email}, &domain.MailOptions{HtmlBody: string(htmlMailStr)})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// SendRoamingWarning informs the owner of a peer about an endpoint that violates the roaming policy. If disabled is
+// true, the mail states that the peer was disabled because of the violation.
+func (m Manager) SendRoamingWarning(ctx context.Context, violation *domain.RoamingViolation, disabled bool) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	userId := violation.UserIdentifier
+	user, err := m.users.GetUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", userId, err)
+	}
+
+	if user.IsServiceAccount() {
+		slog.Debug("skipping roaming warning email",
+			"peer", violation.PeerId,
+			"reason", "service accounts do not receive mails")
+		return nil
+	}
+
+	prefs := m.getNotificationPreferences(ctx, userId)
+	if skip, reason := skipNotification(prefs, domain.NotificationCategorySecurity, user.Email != ""); skip {
+		slog.Debug("skipping roaming warning email",
+			"peer", violation.PeerId,
+			"reason", reason)
+		return nil
+	}
+
+	iface, err := m.wg.GetInterface(ctx, violation.InterfaceId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch interface %s: %w", violation.InterfaceId, err)
+	}
+
+	txtMail, htmlMail, err := m.templates().GetRoamingWarningMail(user,
+		m.getOrganization(ctx, iface.OrganizationIdentifier), violation, disabled)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	info := mailInfo{template: "mail_roaming_warning", user: userId, peer: violation.PeerId}
+	err = m.notify(ctx, prefs, domain.NotificationCategorySecurity, info, roamingWarningSubject,
+		string(txtMailStr), []string{user.Email}, &domain.MailOptions{HtmlBody: string(htmlMailStr)})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// SendPeerCompromiseNotification informs the owner of a peer that the peer was marked as compromised and disabled.
+func (m Manager) SendPeerCompromiseNotification(ctx context.Context, incident *domain.PeerCompromise) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	userId := incident.UserIdentifier
+	user, err := m.users.GetUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", userId, err)
+	}
+
+	if user.IsServiceAccount() {
+		slog.Debug("skipping compromised peer email",
+			"peer", incident.PeerIdentifier,
+			"reason", "service accounts do not receive mails")
+		return nil
+	}
+
+	prefs := m.getNotificationPreferences(ctx, userId)
+	if skip, reason := skipNotification(prefs, domain.NotificationCategorySecurity, user.Email != ""); skip {
+		slog.Debug("skipping compromised peer email",
+			"peer", incident.PeerIdentifier,
+			"reason", reason)
+		return nil
+	}
+
+	iface, err := m.wg.GetInterface(ctx, incident.InterfaceIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to fetch interface %s: %w", incident.InterfaceIdentifier, err)
+	}
+
+	txtMail, htmlMail, err := m.templates().GetPeerCompromisedMail(user,
+		m.getOrganization(ctx, iface.OrganizationIdentifier), incident)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	info := mailInfo{template: "mail_peer_compromised", user: userId, peer: incident.PeerIdentifier}
+	err = m.notify(ctx, prefs, domain.NotificationCategorySecurity, info, peerCompromisedSubject,
+		string(txtMailStr), []string{user.Email}, &domain.MailOptions{HtmlBody: string(htmlMailStr)})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// SendAnnouncement sends the given announcement to the given user. Announcements are sent to all targeted users
+// with a mail address, independent of their notification preferences.
+func (m Manager) SendAnnouncement(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	announcement *domain.Announcement,
+) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	user, err := m.users.GetUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", userId, err)
+	}
+
+	if user.IsServiceAccount() {
+		slog.Debug("skipping announcement email",
+			"user", userId,
+			"reason", "service accounts do not receive mails")
+		return nil
+	}
+
+	if user.Email == "" {
+		slog.Debug("skipping announcement email",
+			"user", userId,
+			"reason", "user has no mail address")
+		return nil
+	}
+
+	txtMail, htmlMail, err := m.templates().GetAnnouncementMail(user,
+		m.getOrganization(ctx, user.OrganizationIdentifier), announcement)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	info := mailInfo{template: "mail_announcement", user: userId}
+	err = m.send(ctx, info, fmt.Sprintf(announcementSubject, announcement.Title), string(txtMailStr),
+		[]string{user.Email}, &domain.MailOptions{HtmlBody: string(htmlMailStr)})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// newExpiryAttachment returns the calendar entry for the expiry of the given peer as mail attachment.
+func (m Manager) newExpiryAttachment(peer *domain.Peer, attendees []string) domain.MailAttachment {
+	alarmBefore := 24 * time.Hour // remind one day before the expiry if reminder mails are disabled
+	if m.mailConfig().ExpiryReminderDays > 0 {
+		alarmBefore = time.Duration(m.mailConfig().ExpiryReminderDays) * 24 * time.Hour
+	}
+	event := newPeerExpiryEvent(m.cfg.Web.ExternalUrl, m.mailConfig().From, peer, attendees, alarmBefore)
+
+	return domain.MailAttachment{
+		Name:        calendarAttachmentName,
+		ContentType: event.ContentType(),
+		Data:        bytes.NewReader(event.Render(time.Now())),
+		Embedded:    false,
+	}
+}
+
+// mailInfo describes a mail for the mail log.
+type mailInfo struct {
+	template      string
+	user          domain.UserIdentifier
+	peer          domain.PeerIdentifier // empty if the mail is not about a single peer
+	trackingToken string                // empty if the mail is not tracked
+}
+
+// send passes the mail to the mailer once the configured rate limits allow it. The result is recorded in the
+// mail log.
+func (m Manager) send(
+	ctx context.Context,
+	info mailInfo,
+	subject, body string,
+	to []string,
+	options *domain.MailOptions,
+) error {
+	recipients := slices.Concat(to, options.Cc, options.Bcc)
+	err := m.throttle.Wait(ctx, recipients...)
+	if err != nil {
+		err = fmt.Errorf("mail rate limit wait aborted: %w", err)
+	} else {
+		err = m.mailer.Send(ctx, subject, body, to, options)
+	}
+
+	m.logMail(ctx, info, subject, recipients, err)
+
+	return err
+}
+
+// logMail records the sent mail in the mail log. The mail log is optional, a failure only results in a warning.
+func (m Manager) logMail(ctx context.Context, info mailInfo, subject string, recipients []string, sendErr error) {
+	entry := domain.MailLogEntry{
+		SentAt:         time.Now(),
+		Template:       info.template,
+		Subject:        subject,
+		Recipients:     strings.Join(recipients, ", "),
+		UserIdentifier: info.user,
+		PeerIdentifier: info.peer,
+		TrackingToken:  info.trackingToken,
+	}
+	if sendErr != nil {
+		entry.Error = sendErr.Error()
+	}
+
+	// the mail was sent (or not) already, so the entry is also stored if the request was cancelled
+	if err := m.mailLog.SaveMailLogEntry(context.WithoutCancel(ctx), &entry); err != nil {
+		slog.Warn("failed to record mail in the mail log", "template", info.template, "error", err)
+	}
+}
+
+// GetMailLog returns the mail log entries that match the given filter, newest first. Administrators of an
+// organization only see the mails of users and peers of their own organization, the unfiltered mail log is only
+// available to global administrators.
+func (m Manager) GetMailLog(ctx context.Context, filter domain.MailLogFilter) ([]domain.MailLogEntry, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case filter.PeerId != "":
+		peer, err := m.wg.GetPeer(ctx, filter.PeerId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch peer %s: %w", filter.PeerId, err)
+		}
+		iface, err := m.wg.GetInterface(ctx, peer.InterfaceIdentifier)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch interface %s: %w", peer.InterfaceIdentifier, err)
+		}
+		if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+			return nil, err
+		}
+	case filter.UserId != "":
+		user, err := m.users.GetUser(ctx, filter.UserId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch user %s: %w", filter.UserId, err)
+		}
+		if err := domain.ValidateOrganizationAccessRights(ctx, user.OrganizationIdentifier); err != nil {
+			return nil, err
+		}
+	default:
+		if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := m.mailLog.FindMailLogEntries(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mail log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// getNotificationPreferences returns the notification preferences of the given user. If the preferences cannot be
+// loaded, the default preferences are used, so notifications are not lost.
+func (m Manager) getNotificationPreferences(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+) domain.UserNotificationPreferences {
+	prefs, err := m.users.GetUserNotificationPreferences(ctx, userId)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			slog.Warn("failed to load notification preferences", "user", userId, "error", err)
+		}
+		return domain.DefaultNotificationPreferences(userId)
+	}
+
+	return *prefs
+}
+
+// skipNotification returns true and the reason if a notification of the given category must not be sent.
+func skipNotification(
+	prefs domain.UserNotificationPreferences,
+	category domain.NotificationCategory,
+	hasMailRecipients bool,
+) (bool, string) {
+	switch prefs.Channel(category) {
+	case domain.NotificationChannelNone:
+		return true, "disabled by user"
+	case domain.NotificationChannelMail:
+		if !hasMailRecipients {
+			return true, "user has no mail address"
+		}
+	}
+
+	return false, ""
+}
+
+// notify sends the notification on the channel the user selected for the category. Telegram messages contain the
+// plain text body only, attachments are only sent by mail. Telegram messages are not part of the mail log.
+func (m Manager) notify(
+	ctx context.Context,
+	prefs domain.UserNotificationPreferences,
+	category domain.NotificationCategory,
+	info mailInfo,
+	subject, body string,
+	to []string,
+	options *domain.MailOptions,
+) error {
+	if prefs.Channel(category) == domain.NotificationChannelTelegram {
+		return m.messenger.SendMessage(ctx, prefs.TelegramChatId, subject+"\n\n"+body)
+	}
+
+	return m.send(ctx, info, subject, body, to, options)
+}
+
+// GetMailPreviews renders the peer configuration mails (link and attachment variant) with sample data.
+// If an organization is given, the custom templates of the organization are used. Organization admins always
+// preview the templates of their own organization.
+func (m Manager) GetMailPreviews(ctx context.Context, orgId domain.OrganizationIdentifier) (
+	[]domain.MailPreview,
+	error,
+) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if currentUser := domain.GetUserInfo(ctx); currentUser.Organization != "" && orgId == "" {
+		orgId = currentUser.Organization
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, orgId); err != nil {
+		return nil, err
+	}
+
+	var org *domain.Organization
+	if orgId != "" {
+		var err error
+		org, err = m.orgs.GetOrganization(ctx, orgId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch organization %s: %w", orgId, err)
+		}
+	}
+
+	sampleUser := &domain.User{
+		Identifier: "jane.doe",
+		Email:      "jane.doe@example.com",
+		Firstname:  "Jane",
+		Lastname:   "Doe",
+	}
+	samplePeer := &domain.Peer{Identifier: "sample", DisplayName: "Sample Peer", UserIdentifier: sampleUser.Identifier}
+	sampleChanges := []domain.PeerConfigChange{
+		{Setting: "Private key", Secret: true},
+		{Setting: "DNS servers", Old: "10.0.0.1", New: "10.0.0.53"},
+	}
+
+	txtMail, htmlMail, err := m.templates().GetConfigMail(sampleUser, org, m.cfg.Web.ExternalUrl, "", sampleChanges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render link mail: %w", err)
+	}
+	linkPreview := newMailPreview("mail_with_link", peerConfigMailSubject, txtMail, htmlMail)
+
+	txtMail, htmlMail, err = m.templates().GetConfigMailWithAttachment(sampleUser, org, samplePeer,
+		samplePeer.GetConfigFileName(), "WireGuardQRCode.png", "", "", sampleChanges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render attachment mail: %w", err)
+	}
+	attachmentPreview := newMailPreview("mail_with_attachment", peerConfigMailSubject, txtMail, htmlMail)
+
+	return []domain.MailPreview{linkPreview, attachmentPreview}, nil
+}
+
+// GetMailTemplates returns the built-in mail templates and the variables that are available in them.
+func (m Manager) GetMailTemplates(ctx context.Context) ([]domain.MailTemplate, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return GetTemplates(), nil
+}
+
+// LintMailTemplate validates a custom template for the built-in template with the given name, before it is placed
+// in the organization templates directory.
+func (m Manager) LintMailTemplate(
+	ctx context.Context,
+	name string,
+	format domain.MailTemplateFormat,
+	content string,
+) ([]domain.MailTemplateIssue, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return LintTemplate(name, format, content)
+}
+
+func newMailPreview(name, subject string, txtMail, htmlMail io.Reader) domain.MailPreview {
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	return domain.MailPreview{
+		Template: name,
+		Subject:  subject,
+		TextBody: string(txtMailStr),
+		HtmlBody: string(htmlMailStr),
+	}
+}
+
+// compressAttachment returns the gzip compressed data of the given reader.
+func compressAttachment(data io.Reader) (io.Reader, error) {
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	if _, err := io.Copy(gz, data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// getOrganization returns the organization with the given identifier, or nil if no organization is set.
+// Errors are only logged, mails are sent with the default branding in that case.
+func (m Manager) getOrganization(ctx context.Context, id domain.OrganizationIdentifier) *domain.Organization {
+	if id == "" {
+		return nil
+	}
+
+	org, err := m.orgs.GetOrganization(ctx, id)
+	if err != nil {
+		slog.Warn("failed to load organization for mail branding", "organization", id, "error", err)
+		return nil
+	}
+
+	return org
+}
+
+// SendExportApprovalRequest asks the given administrator to approve or reject the given export approval request.
+func (m Manager) SendExportApprovalRequest(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	approval *domain.ExportApproval,
+) error {
+	return m.sendExportApprovalMail(ctx, userId, approval, exportApprovalSubject)
+}
+
+// SendExportApprovalDecision informs the requesting user about the decision on the given export approval request.
+func (m Manager) SendExportApprovalDecision(ctx context.Context, approval *domain.ExportApproval) error {
+	return m.sendExportApprovalMail(ctx, approval.RequestedBy, approval,
+		fmt.Sprintf(exportDecisionSubject, approval.State))
+}
+
+func (m Manager) sendExportApprovalMail(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	approval *domain.ExportApproval,
+	subject string,
+) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	user, err := m.users.GetUser(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", userId, err)
+	}
+
+	if user.IsServiceAccount() {
+		slog.Debug("skipping export approval email",
+			"user", userId,
+			"reason", "service accounts do not receive mails")
+		return nil
+	}
+
+	if user.Email == "" {
+		slog.Debug("skipping export approval email",
+			"user", userId,
+			"reason", "user has no mail address")
+		return nil
+	}
+
+	txtMail, htmlMail, err := m.templates().GetExportApprovalMail(user,
+		m.getOrganization(ctx, user.OrganizationIdentifier), approval)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
 
-	err = m.mailer.Send(ctx, "WireGuard VPN Configuration", string(txtMailStr), []string{user.Email}, &mailOptions)
+	info := mailInfo{template: "mail_export_approval", user: userId}
+	err = m.send(ctx, info, subject, string(txtMailStr), []string{user.Email},
+		&domain.MailOptions{HtmlBody: string(htmlMailStr)})
 	if err != nil {
 		return fmt.Errorf("failed to send mail: %w", err)
 	}
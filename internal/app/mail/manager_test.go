@@ -0,0 +1,580 @@
+package mail
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeOrganizations map[domain.OrganizationIdentifier]domain.Organization
+
+func (f fakeOrganizations) GetOrganization(_ context.Context, id domain.OrganizationIdentifier) (
+	*domain.Organization,
+	error,
+) {
+	org, ok := f[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &org, nil
+}
+
+type fakeUsers map[domain.UserIdentifier]domain.User
+
+func (f fakeUsers) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	user, ok := f[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (f fakeUsers) GetUserNotificationPreferences(_ context.Context, _ domain.UserIdentifier) (
+	*domain.UserNotificationPreferences,
+	error,
+) {
+	return nil, domain.ErrNotFound
+}
+
+type fakePreferenceUsers struct {
+	fakeUsers
+	prefs map[domain.UserIdentifier]domain.UserNotificationPreferences
+}
+
+func (f fakePreferenceUsers) GetUserNotificationPreferences(_ context.Context, id domain.UserIdentifier) (
+	*domain.UserNotificationPreferences,
+	error,
+) {
+	prefs, ok := f.prefs[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &prefs, nil
+}
+
+type fakeMessenger struct {
+	chatId string
+	text   string
+}
+
+func (f *fakeMessenger) SendMessage(_ context.Context, chatId, text string) error {
+	f.chatId = chatId
+	f.text = text
+	return nil
+}
+
+type fakeMailer struct {
+	to      []string
+	body    string
+	options *domain.MailOptions
+	err     error
+	sent    int
+}
+
+func (f *fakeMailer) Send(_ context.Context, _, body string, to []string, options *domain.MailOptions) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent++
+	f.to = to
+	f.body = body
+	f.options = options
+	return nil
+}
+
+func (f *fakeMailer) SetPassword(_ string) {}
+
+func (f *fakeMailer) SetConfig(_ config.MailConfig) {}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error {
+	return nil
+}
+
+type fakeConfigVersions struct {
+	versions []domain.PeerConfigVersion
+}
+
+func (f *fakeConfigVersions) GetPeerConfigVersions(_ context.Context, id domain.PeerIdentifier) (
+	[]domain.PeerConfigVersion,
+	error,
+) {
+	var versions []domain.PeerConfigVersion
+	for _, version := range f.versions {
+		if version.PeerId == id {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
+
+func (f *fakeConfigVersions) SavePeerConfigVersion(_ context.Context, version *domain.PeerConfigVersion) error {
+	f.versions = append(f.versions, *version)
+	return nil
+}
+
+func (f *fakeConfigVersions) DeletePeerConfigVersion(_ context.Context, id domain.PeerIdentifier, version int) error {
+	f.versions = slices.DeleteFunc(f.versions, func(v domain.PeerConfigVersion) bool {
+		return v.PeerId == id && v.Version == version
+	})
+	return nil
+}
+
+type fakeMailLog struct {
+	entries []domain.MailLogEntry
+}
+
+func (f *fakeMailLog) SaveMailLogEntry(_ context.Context, entry *domain.MailLogEntry) error {
+	if entry.UniqueId != 0 {
+		f.entries[entry.UniqueId-1] = *entry
+		return nil
+	}
+	entry.UniqueId = uint64(len(f.entries) + 1)
+	f.entries = append(f.entries, *entry)
+	return nil
+}
+
+func (f *fakeMailLog) FindMailLogEntries(_ context.Context, filter domain.MailLogFilter) (
+	[]domain.MailLogEntry,
+	error,
+) {
+	var entries []domain.MailLogEntry
+	for _, entry := range f.entries {
+		if filter.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (f *fakeMailLog) GetMailLogEntryByTrackingToken(_ context.Context, token string) (*domain.MailLogEntry, error) {
+	for _, entry := range f.entries {
+		if entry.TrackingToken == token {
+			return &entry, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+type fakeConfigFiles struct {
+	qrTooLarge bool
+}
+
+func (f fakeConfigFiles) GetInterfaceConfig(_ context.Context, _ domain.InterfaceIdentifier) (io.Reader, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (f fakeConfigFiles) GetPeerConfig(_ context.Context, id domain.PeerIdentifier) (io.Reader, error) {
+	return bytes.NewBufferString("config of " + string(id)), nil
+}
+
+func (f fakeConfigFiles) GetPeerConfigQrCode(_ context.Context, id domain.PeerIdentifier) (io.Reader, error) {
+	if f.qrTooLarge {
+		return nil, fmt.Errorf("config of peer %s is too large: %w", id, domain.ErrConfigTooLarge)
+	}
+	return bytes.NewBufferString("png"), nil
+}
+
+func TestManager_sendPeerEmail(t *testing.T) {
+	cfg := &config.Config{}
+	user := &domain.User{Identifier: "alice", Email: "alice@example.com"}
+	iface := &domain.Interface{Identifier: "wg0"}
+	peer := &domain.Peer{Identifier: "peer-a", DisplayName: "Laptop"}
+
+	mailer := &fakeMailer{}
+	m, err := NewMailManager(cfg, fakeBus{}, mailer, nil, fakeConfigFiles{}, nil, nil, fakeOrganizations{},
+		&fakeConfigVersions{}, &fakeMailLog{})
+	require.NoError(t, err)
+	require.NoError(t, m.sendPeerEmail(context.Background(), false, user, nil, iface, peer))
+	require.Len(t, mailer.options.Attachments, 2)
+	assert.Equal(t, "Laptop.conf", mailer.options.Attachments[0].Name)
+	assert.Equal(t, "text/plain", mailer.options.Attachments[0].ContentType)
+
+	// the config attachment is compressed if enabled, the setting is applied by a config reload
+	cfg.Mail.CompressAttachment = true
+	m.handleConfigReloadedEvent(cfg)
+	require.NoError(t, m.sendPeerEmail(context.Background(), false, user, nil, iface, peer))
+	require.Len(t, mailer.options.Attachments, 2)
+	assert.Equal(t, "Laptop.conf.gz", mailer.options.Attachments[0].Name)
+	assert.Contains(t, mailer.body, "Laptop.conf.gz")
+	gz, err := gzip.NewReader(mailer.options.Attachments[0].Data)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "config of peer-a", string(data))
+
+	// configs that are too large for a QR code are sent as link mail
+	m, err = NewMailManager(cfg, fakeBus{}, mailer, nil, fakeConfigFiles{qrTooLarge: true}, nil, nil,
+		fakeOrganizations{}, &fakeConfigVersions{}, &fakeMailLog{})
+	require.NoError(t, err)
+	require.NoError(t, m.sendPeerEmail(context.Background(), false, user, nil, iface, peer))
+	assert.Empty(t, mailer.options.Attachments)
+}
+
+func TestManager_sendPeerEmail_setupGuide(t *testing.T) {
+	cfg := &config.Config{}
+	user := &domain.User{Identifier: "alice", Email: "alice@example.com"}
+	iface := &domain.Interface{Identifier: "wg0"}
+	peer := &domain.Peer{Identifier: "peer-a", DisplayName: "Laptop", OperatingSystem: domain.PeerOperatingSystemWindows}
+
+	mailer := &fakeMailer{}
+	m, err := NewMailManager(cfg, fakeBus{}, mailer, nil, fakeConfigFiles{}, nil, nil, fakeOrganizations{},
+		&fakeConfigVersions{}, &fakeMailLog{})
+	require.NoError(t, err)
+	require.NoError(t, m.sendPeerEmail(context.Background(), false, user, nil, iface, peer))
+	assert.Len(t, mailer.options.Attachments, 2, "setup guides are disabled by default")
+
+	cfg.Mail.SetupGuides = true
+	m.handleConfigReloadedEvent(cfg)
+	require.NoError(t, m.sendPeerEmail(context.Background(), false, user, nil, iface, peer))
+	require.Len(t, mailer.options.Attachments, 3)
+	guide := mailer.options.Attachments[2]
+	assert.Equal(t, "WireGuard-Setup-windows.html", guide.Name)
+	assert.Equal(t, "text/html", guide.ContentType)
+	assert.False(t, guide.Embedded)
+	assert.Contains(t, mailer.body, "WireGuard-Setup-windows.html")
+
+	// peers without operating system and link mails have no setup guide
+	peer.OperatingSystem = domain.PeerOperatingSystemUnknown
+	require.NoError(t, m.sendPeerEmail(context.Background(), false, user, nil, iface, peer))
+	assert.Len(t, mailer.options.Attachments, 2)
+	peer.OperatingSystem = domain.PeerOperatingSystemWindows
+	require.NoError(t, m.sendPeerEmail(context.Background(), true, user, nil, iface, peer))
+	assert.Empty(t, mailer.options.Attachments)
+}
+
+func TestManager_sendPeerEmail_recipients(t *testing.T) {
+	user := &domain.User{Identifier: "alice", Email: "alice@example.com"}
+	iface := &domain.Interface{
+		Identifier:     "wg0",
+		PeerMailCcStr:  "assets@example.com",
+		PeerMailBccStr: "audit@example.com, archive@example.com",
+	}
+	peer := &domain.Peer{Identifier: "peer-a", MailRecipientsStr: "team@example.com"}
+
+	mailer := &fakeMailer{}
+	m, err := NewMailManager(&config.Config{}, fakeBus{}, mailer, nil, fakeConfigFiles{}, nil, nil, fakeOrganizations{},
+		&fakeConfigVersions{}, &fakeMailLog{})
+	require.NoError(t, err)
+	require.NoError(t, m.sendPeerEmail(context.Background(), true, user, nil, iface, peer))
+	assert.Equal(t, []string{"alice@example.com", "team@example.com"}, mailer.to)
+	assert.Equal(t, []string{"assets@example.com"}, mailer.options.Cc)
+	assert.Equal(t, []string{"audit@example.com", "archive@example.com"}, mailer.options.Bcc)
+
+	// the additional recipients also receive the mail if the user has no mail address
+	require.NoError(t, m.sendPeerEmail(context.Background(), true, &domain.User{Identifier: "bob"}, nil, iface, peer))
+	assert.Equal(t, []string{"team@example.com"}, mailer.to)
+}
+
+func TestManager_sendPeerEmail_changes(t *testing.T) {
+	user := &domain.User{Identifier: "alice", Email: "alice@example.com"}
+	iface := &domain.Interface{Identifier: "wg0"}
+	peer := &domain.Peer{Identifier: "peer-a"}
+	peer.Interface.DnsStr = domain.NewConfigOption("10.0.0.1", true)
+
+	mailer := &fakeMailer{}
+	versions := &fakeConfigVersions{}
+	m, err := NewMailManager(&config.Config{}, fakeBus{}, mailer, nil, fakeConfigFiles{}, nil, nil, fakeOrganizations{},
+		versions, &fakeMailLog{})
+	require.NoError(t, err)
+
+	// the first mail has nothing to compare with
+	require.NoError(t, m.sendPeerEmail(context.Background(), true, user, nil, iface, peer))
+	assert.NotContains(t, mailer.body, "What changed")
+	require.Len(t, versions.versions, 1)
+
+	peer.Interface.DnsStr = domain.NewConfigOption("10.0.0.53", true)
+	peer.Interface.PublicKey = "rotated"
+	require.NoError(t, m.sendPeerEmail(context.Background(), false, user, nil, iface, peer))
+	assert.Contains(t, mailer.body, "What changed")
+	assert.Contains(t, mailer.body, "- DNS servers: 10.0.0.1 -> 10.0.0.53")
+	assert.Contains(t, mailer.body, "- Private key: replaced")
+	assert.Equal(t, 2, versions.versions[1].Version)
+
+	// only the newest versions are kept
+	for range maxPeerConfigVersions {
+		require.NoError(t, m.sendPeerEmail(context.Background(), true, user, nil, iface, peer))
+	}
+	assert.Len(t, versions.versions, maxPeerConfigVersions)
+	assert.Equal(t, maxPeerConfigVersions+2, versions.versions[maxPeerConfigVersions-1].Version)
+	assert.NotContains(t, mailer.body, "What changed")
+}
+
+func TestManager_SendClientUpdateNotification_serviceAccount(t *testing.T) {
+	users := fakeUsers{
+		"alice": {Identifier: "alice", Email: "alice@example.com"},
+		"ci":    {Identifier: "ci", Type: domain.UserTypeService, Email: "ci@example.com"},
+	}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	mailer := &fakeMailer{}
+	m, err := NewMailManager(&config.Config{}, fakeBus{}, mailer, nil, fakeConfigFiles{}, users, nil, fakeOrganizations{},
+		nil, &fakeMailLog{})
+	require.NoError(t, err)
+
+	// service accounts never receive mails, even if an address was stored before
+	require.NoError(t, m.SendClientUpdateNotification(ctx, "ci", nil))
+	assert.Empty(t, mailer.to)
+
+	require.NoError(t, m.SendClientUpdateNotification(ctx, "alice", nil))
+	assert.Equal(t, []string{"alice@example.com"}, mailer.to)
+}
+
+func TestManager_GetMailPreviews(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Web.ExternalUrl = "https://vpn.example.com"
+	orgs := fakeOrganizations{
+		"acme":   {Identifier: "acme", CompanyName: "ACME Corp"},
+		"globex": {Identifier: "globex"},
+	}
+	m, err := NewMailManager(cfg, fakeBus{}, nil, nil, nil, nil, nil, orgs, nil, nil)
+	require.NoError(t, err)
+
+	adminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+	previews, err := m.GetMailPreviews(adminCtx, "")
+	require.NoError(t, err)
+	require.Len(t, previews, 2)
+	assert.Equal(t, "mail_with_link", previews[0].Template)
+	assert.Equal(t, "mail_with_attachment", previews[1].Template)
+	assert.Contains(t, previews[1].TextBody, "Hello Jane Doe")
+	assert.Contains(t, previews[1].TextBody, "Sample_Peer.conf")
+	assert.Contains(t, previews[1].HtmlBody, "<html")
+
+	// organization admins preview the templates of their own organization
+	orgAdminCtx := domain.SetUserInfo(context.Background(),
+		&domain.ContextUserInfo{Id: "acme-admin", IsAdmin: true, Organization: "acme"})
+	previews, err = m.GetMailPreviews(orgAdminCtx, "")
+	require.NoError(t, err)
+	assert.Contains(t, previews[0].TextBody, "for ACME Corp")
+
+	_, err = m.GetMailPreviews(orgAdminCtx, "globex")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	_, err = m.GetMailPreviews(adminCtx, "initech")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	_, err = m.GetMailPreviews(userCtx, "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_SendLoginNotification_preferences(t *testing.T) {
+	users := fakePreferenceUsers{
+		fakeUsers: fakeUsers{
+			"alice": {Identifier: "alice", Email: "alice@example.com"},
+			"bob":   {Identifier: "bob", Email: "bob@example.com"},
+			"carol": {Identifier: "carol"},
+		},
+		prefs: map[domain.UserIdentifier]domain.UserNotificationPreferences{
+			"bob":   {UserIdentifier: "bob", SecurityChannel: domain.NotificationChannelNone},
+			"carol": {UserIdentifier: "carol", SecurityChannel: domain.NotificationChannelTelegram, TelegramChatId: "42"},
+		},
+	}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	device := &domain.UserLoginDevice{IpAddress: "198.51.100.7", Browser: "Firefox"}
+
+	mailer := &fakeMailer{}
+	messenger := &fakeMessenger{}
+	m, err := NewMailManager(&config.Config{}, fakeBus{}, mailer, messenger, fakeConfigFiles{}, users, nil,
+		fakeOrganizations{}, nil, &fakeMailLog{})
+	require.NoError(t, err)
+
+	// users without preferences receive the notification by mail
+	require.NoError(t, m.SendLoginNotification(ctx, "alice", device))
+	assert.Equal(t, []string{"alice@example.com"}, mailer.to)
+
+	mailer.to = nil
+	require.NoError(t, m.SendLoginNotification(ctx, "bob", device))
+	assert.Empty(t, mailer.to, "disabled notifications are not sent")
+
+	// telegram notifications do not require a mail address
+	require.NoError(t, m.SendLoginNotification(ctx, "carol", device))
+	assert.Empty(t, mailer.to)
+	assert.Equal(t, "42", messenger.chatId)
+	assert.Contains(t, messenger.text, loginNotificationSubject)
+	assert.Contains(t, messenger.text, "198.51.100.7")
+}
+
+func TestManager_send_mailLog(t *testing.T) {
+	user := &domain.User{Identifier: "alice", Email: "alice@example.com"}
+	iface := &domain.Interface{Identifier: "wg0", PeerMailBccStr: "audit@example.com"}
+	peer := &domain.Peer{Identifier: "peer-a"}
+
+	mailer := &fakeMailer{}
+	mailLog := &fakeMailLog{}
+	m, err := NewMailManager(&config.Config{}, fakeBus{}, mailer, nil, fakeConfigFiles{}, nil, nil, fakeOrganizations{},
+		&fakeConfigVersions{}, mailLog)
+	require.NoError(t, err)
+
+	require.NoError(t, m.sendPeerEmail(context.Background(), true, user, nil, iface, peer))
+	mailer.err = errors.New("connection refused")
+	require.Error(t, m.sendPeerEmail(context.Background(), false, user, nil, iface, peer))
+
+	require.Len(t, mailLog.entries, 2)
+	assert.Equal(t, "mail_with_link", mailLog.entries[0].Template)
+	assert.Equal(t, peerConfigMailSubject, mailLog.entries[0].Subject)
+	assert.Equal(t, "alice@example.com, audit@example.com", mailLog.entries[0].Recipients)
+	assert.Equal(t, domain.UserIdentifier("alice"), mailLog.entries[0].UserIdentifier)
+	assert.Equal(t, domain.PeerIdentifier("peer-a"), mailLog.entries[0].PeerIdentifier)
+	assert.False(t, mailLog.entries[0].Failed())
+
+	assert.Equal(t, "mail_with_attachment", mailLog.entries[1].Template)
+	assert.True(t, mailLog.entries[1].Failed())
+	assert.Equal(t, "connection refused", mailLog.entries[1].Error)
+}
+
+func TestManager_mailTracking(t *testing.T) {
+	user := &domain.User{Identifier: "alice", Email: "alice@example.com"}
+	iface := &domain.Interface{Identifier: "wg0"}
+	peer := &domain.Peer{Identifier: "peer-a"}
+
+	cfg := &config.Config{Mail: config.MailConfig{Tracking: true}, Web: config.WebConfig{
+		ExternalUrl: "https://vpn.example.com",
+	}}
+	mailer := &fakeMailer{}
+	mailLog := &fakeMailLog{}
+	m, err := NewMailManager(cfg, fakeBus{}, mailer, nil, fakeConfigFiles{}, nil, nil, fakeOrganizations{},
+		&fakeConfigVersions{}, mailLog)
+	require.NoError(t, err)
+
+	require.NoError(t, m.sendPeerEmail(context.Background(), true, user, nil, iface, peer))
+	require.Len(t, mailLog.entries, 1)
+	token := mailLog.entries[0].TrackingToken
+	require.NotEmpty(t, token)
+	assert.Contains(t, mailer.options.HtmlBody, "https://vpn.example.com/api/v0/mail-tracking/"+token+"/open")
+	assert.Contains(t, mailer.options.HtmlBody, "https://vpn.example.com/api/v0/mail-tracking/"+token+"/click")
+
+	require.NoError(t, m.TrackMailOpened(context.Background(), token))
+	opened := mailLog.entries[0].OpenedAt
+	require.NotNil(t, opened)
+	assert.Nil(t, mailLog.entries[0].ClickedAt)
+
+	redirectUrl, err := m.TrackMailClicked(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "https://vpn.example.com", redirectUrl)
+	require.NotNil(t, mailLog.entries[0].ClickedAt)
+	assert.Equal(t, opened, mailLog.entries[0].OpenedAt, "only the first open is recorded")
+
+	redirectUrl, err = m.TrackMailClicked(context.Background(), "unknown")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Equal(t, "https://vpn.example.com", redirectUrl, "unknown tokens are redirected as well")
+
+	// without tracking, the mail contains the plain portal link
+	cfg.Mail.Tracking = false
+	m, err = NewMailManager(cfg, fakeBus{}, mailer, nil, fakeConfigFiles{}, nil, nil, fakeOrganizations{},
+		&fakeConfigVersions{}, mailLog)
+	require.NoError(t, err)
+	require.NoError(t, m.sendPeerEmail(context.Background(), true, user, nil, iface, peer))
+	require.Len(t, mailLog.entries, 2)
+	assert.False(t, mailLog.entries[1].IsTracked())
+	assert.NotContains(t, mailer.options.HtmlBody, "mail-tracking")
+}
+
+func TestManager_GetMailLog(t *testing.T) {
+	users := fakeUsers{
+		"alice": {Identifier: "alice"},
+		"bob":   {Identifier: "bob", OrganizationIdentifier: "acme"},
+	}
+	mailLog := &fakeMailLog{entries: []domain.MailLogEntry{
+		{UserIdentifier: "alice", Subject: "first"},
+		{UserIdentifier: "bob", Subject: "second"},
+	}}
+	m, err := NewMailManager(&config.Config{}, fakeBus{}, nil, nil, nil, users, nil, fakeOrganizations{}, nil,
+		mailLog)
+	require.NoError(t, err)
+
+	adminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+	entries, err := m.GetMailLog(adminCtx, domain.MailLogFilter{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	entries, err = m.GetMailLog(adminCtx, domain.MailLogFilter{UserId: "bob"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "second", entries[0].Subject)
+
+	// organization admins only see the mails of their own organization
+	orgAdminCtx := domain.SetUserInfo(context.Background(),
+		&domain.ContextUserInfo{Id: "acme-admin", IsAdmin: true, Organization: "acme"})
+	_, err = m.GetMailLog(orgAdminCtx, domain.MailLogFilter{UserId: "bob"})
+	require.NoError(t, err)
+	_, err = m.GetMailLog(orgAdminCtx, domain.MailLogFilter{UserId: "alice"})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	_, err = m.GetMailLog(orgAdminCtx, domain.MailLogFilter{})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	_, err = m.GetMailLog(userCtx, domain.MailLogFilter{UserId: "alice"})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+type fakeWireguard struct {
+	iface domain.Interface
+	peers []domain.Peer
+}
+
+func (f fakeWireguard) GetInterfaceAndPeers(_ context.Context, _ domain.InterfaceIdentifier) (
+	*domain.Interface,
+	[]domain.Peer,
+	error,
+) {
+	return &f.iface, f.peers, nil
+}
+
+func (f fakeWireguard) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	for _, peer := range f.peers {
+		if peer.Identifier == id {
+			return &peer, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f fakeWireguard) GetInterface(_ context.Context, _ domain.InterfaceIdentifier) (*domain.Interface, error) {
+	return &f.iface, nil
+}
+
+func TestManager_handleInterfaceRenumberedEvent(t *testing.T) {
+	users := fakeUsers{"alice": {Identifier: "alice", Email: "alice@example.com"}}
+	wg := fakeWireguard{
+		iface: domain.Interface{Identifier: "wg0"},
+		peers: []domain.Peer{
+			{Identifier: "peer-a", InterfaceIdentifier: "wg0", UserIdentifier: "alice"},
+			{Identifier: "peer-b", InterfaceIdentifier: "wg0", UserIdentifier: "alice"},
+			{Identifier: "peer-c", InterfaceIdentifier: "wg0"},
+		},
+	}
+	renumbering := domain.InterfaceRenumbering{
+		InterfaceId: "wg0",
+		Peers: []domain.PeerRenumbering{
+			{PeerId: "peer-a", UserIdentifier: "alice"},
+			{PeerId: "peer-b", UserIdentifier: "alice", Disabled: true},
+			{PeerId: "peer-c"},
+		},
+	}
+
+	mailer := &fakeMailer{}
+	m, err := NewMailManager(&config.Config{}, fakeBus{}, mailer, nil, fakeConfigFiles{}, users, wg,
+		fakeOrganizations{}, &fakeConfigVersions{}, &fakeMailLog{})
+	require.NoError(t, err)
+
+	m.handleInterfaceRenumberedEvent(renumbering)
+	assert.Zero(t, mailer.sent, "no mails are sent unless requested")
+
+	renumbering.SendMails = true
+	m.handleInterfaceRenumberedEvent(renumbering)
+	assert.Equal(t, 1, mailer.sent, "disabled peers and peers without user are skipped")
+	assert.Equal(t, []string{"alice@example.com"}, mailer.to)
+}
@@ -6,8 +6,16 @@ import (
 	"fmt"
 	htmlTemplate "html/template"
 	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"text/template"
+	"time"
 
+	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
@@ -19,82 +27,538 @@ type TemplateHandler struct {
 	portalUrl     string
 	htmlTemplates *htmlTemplate.Template
 	textTemplates *template.Template
+
+	organizationTemplatesPath string
+	variantTemplatesPath      string
+	setupGuidesPath           string
+	variants                  []config.MailTemplateVariant
+
+	cache *templateCache
+}
+
+// templateSetKey identifies the custom templates of an organization and template variant.
+type templateSetKey struct {
+	org     domain.OrganizationIdentifier
+	variant string
+}
+
+// templateSet holds the parsed templates of an organization and template variant, and the custom template files they
+// were parsed from.
+type templateSet struct {
+	html  *htmlTemplate.Template
+	text  *template.Template
+	files []templateFile
+}
+
+// templateFile identifies a version of a custom template file.
+type templateFile struct {
+	path    string
+	size    int64
+	modTime int64
+}
+
+// templateCache holds the parsed templates of all organizations and template variants that were rendered so far.
+type templateCache struct {
+	mux  sync.RWMutex
+	sets map[templateSetKey]*templateSet
 }
 
-func newTemplateHandler(portalUrl string) (*TemplateHandler, error) {
-	htmlTemplateCache, err := htmlTemplate.New("Html").ParseFS(TemplateFiles, "tpl_files/*.gohtml")
+func newTemplateHandler(portalUrl string, cfg config.MailConfig) (*TemplateHandler, error) {
+	htmlTemplateCache, err := parseHtmlTemplates()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse html template files: %w", err)
+		return nil, err
 	}
 
-	txtTemplateCache, err := template.New("Txt").ParseFS(TemplateFiles, "tpl_files/*.gotpl")
+	txtTemplateCache, err := parseTextTemplates()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse text template files: %w", err)
+		return nil, err
+	}
+
+	for _, variant := range cfg.TemplateVariants {
+		if err := validateTemplateVariant(variant); err != nil {
+			return nil, err
+		}
 	}
 
 	handler := &TemplateHandler{
 		portalUrl:     portalUrl,
 		htmlTemplates: htmlTemplateCache,
 		textTemplates: txtTemplateCache,
+
+		organizationTemplatesPath: cfg.OrganizationTemplatesPath,
+		variantTemplatesPath:      cfg.TemplateVariantsPath,
+		setupGuidesPath:           cfg.SetupGuidesPath,
+		variants:                  cfg.TemplateVariants,
+
+		cache: &templateCache{sets: make(map[templateSetKey]*templateSet)},
 	}
+	handler.precompile()
 
 	return handler, nil
 }
 
-// GetConfigMail returns the text and html template for the mail with a link.
-func (c TemplateHandler) GetConfigMail(user *domain.User, link string) (io.Reader, io.Reader, error) {
-	var tplBuff bytes.Buffer
-	var htmlTplBuff bytes.Buffer
+// precompile parses the custom templates of all organizations and template variants, so that the first mails do not
+// have to wait for it. Combinations of an organization and a variant are parsed on first use. Invalid templates are
+// only logged, they fail the mails that use them.
+func (c TemplateHandler) precompile() {
+	var orgs []domain.OrganizationIdentifier
+	if c.organizationTemplatesPath != "" {
+		entries, _ := os.ReadDir(c.organizationTemplatesPath)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				orgs = append(orgs, domain.OrganizationIdentifier(entry.Name()))
+			}
+		}
+	}
 
-	err := c.textTemplates.ExecuteTemplate(&tplBuff, "mail_with_link.gotpl", map[string]any{
-		"User":      user,
-		"Link":      link,
-		"PortalUrl": c.portalUrl,
-	})
+	for _, org := range orgs {
+		if _, _, err := c.templates(&domain.Organization{Identifier: org}, ""); err != nil {
+			slog.Warn("failed to parse organization mail templates", "organization", org, "error", err)
+		}
+	}
+	for _, variant := range c.variants {
+		if _, _, err := c.templates(nil, variant.Name); err != nil {
+			slog.Warn("failed to parse mail template variant", "variant", variant.Name, "error", err)
+		}
+	}
+}
+
+func validateTemplateVariant(variant config.MailTemplateVariant) error {
+	// the variant name is used as directory name
+	if variant.Name == "" || variant.Name == "." || variant.Name == ".." || filepath.Base(variant.Name) != variant.Name {
+		return fmt.Errorf("invalid mail template variant name %q", variant.Name)
+	}
+	for _, role := range variant.Roles {
+		if role != templateRoleAdmin && role != templateRoleUser {
+			return fmt.Errorf("invalid role %q in mail template variant %s", role, variant.Name)
+		}
+	}
+
+	return nil
+}
+
+func parseHtmlTemplates(overrides ...string) (*htmlTemplate.Template, error) {
+	tpl, err := htmlTemplate.New("Html").ParseFS(TemplateFiles, "tpl_files/*.gohtml")
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to execute template mail_with_link.gotpl: %w", err)
+		return nil, fmt.Errorf("failed to parse html template files: %w", err)
 	}
 
-	err = c.htmlTemplates.ExecuteTemplate(&htmlTplBuff, "mail_with_link.gohtml", map[string]any{
-		"User":      user,
-		"Link":      link,
-		"PortalUrl": c.portalUrl,
-	})
+	if len(overrides) > 0 {
+		tpl, err = tpl.ParseFiles(overrides...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse custom html template files: %w", err)
+		}
+	}
+
+	return tpl, nil
+}
+
+func parseTextTemplates(overrides ...string) (*template.Template, error) {
+	tpl, err := template.New("Txt").ParseFS(TemplateFiles, "tpl_files/*.gotpl")
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to execute template mail_with_link.gohtml: %w", err)
+		return nil, fmt.Errorf("failed to parse text template files: %w", err)
 	}
 
-	return &tplBuff, &htmlTplBuff, nil
+	if len(overrides) > 0 {
+		tpl, err = tpl.ParseFiles(overrides...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse custom text template files: %w", err)
+		}
+	}
+
+	return tpl, nil
 }
 
-// GetConfigMailWithAttachment returns the text and html template for the mail with an attachment.
-func (c TemplateHandler) GetConfigMailWithAttachment(user *domain.User, cfgName, qrName string) (
-	io.Reader,
-	io.Reader,
+// templates returns the html and text templates for the given organization and template variant. Custom setup
+// guides replace the built-in guides, custom templates of the organization replace the built-in templates with the
+// same file name and templates of the variant replace both. The parsed templates are cached, they are only parsed
+// again if a template file was added, removed or changed, so changes are applied without a restart.
+func (c TemplateHandler) templates(org *domain.Organization, variant string) (
+	*htmlTemplate.Template,
+	*template.Template,
 	error,
 ) {
+	key := templateSetKey{variant: variant}
+	var dirs []string
+	if org != nil && c.organizationTemplatesPath != "" {
+		key.org = org.Identifier
+		// the organization identifier is validated, it is safe to use it as directory name
+		dirs = append(dirs, filepath.Join(c.organizationTemplatesPath, string(org.Identifier)))
+	}
+	if variant != "" && c.variantTemplatesPath != "" {
+		dirs = append(dirs, filepath.Join(c.variantTemplatesPath, variant))
+	}
+
+	// if multiple files have the same name, the last one is used
+	var htmlFiles, txtFiles []string
+	if c.setupGuidesPath != "" {
+		htmlFiles, _ = filepath.Glob(filepath.Join(c.setupGuidesPath, "setup_guide_*.gohtml"))
+	}
+	for _, dir := range dirs {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.gohtml"))
+		htmlFiles = append(htmlFiles, files...)
+		files, _ = filepath.Glob(filepath.Join(dir, "*.gotpl"))
+		txtFiles = append(txtFiles, files...)
+	}
+	if len(htmlFiles) == 0 && len(txtFiles) == 0 {
+		return c.htmlTemplates, c.textTemplates, nil
+	}
+
+	files := statTemplateFiles(slices.Concat(htmlFiles, txtFiles))
+	c.cache.mux.RLock()
+	set, ok := c.cache.sets[key]
+	c.cache.mux.RUnlock()
+	if ok && slices.Equal(set.files, files) {
+		return set.html, set.text, nil
+	}
+
+	htmlTemplates := c.htmlTemplates
+	if len(htmlFiles) > 0 {
+		tpl, err := parseHtmlTemplates(htmlFiles...)
+		if err != nil {
+			return nil, nil, err
+		}
+		htmlTemplates = tpl
+	}
+
+	txtTemplates := c.textTemplates
+	if len(txtFiles) > 0 {
+		tpl, err := parseTextTemplates(txtFiles...)
+		if err != nil {
+			return nil, nil, err
+		}
+		txtTemplates = tpl
+	}
+
+	c.cache.mux.Lock()
+	c.cache.sets[key] = &templateSet{html: htmlTemplates, text: txtTemplates, files: files}
+	c.cache.mux.Unlock()
+
+	return htmlTemplates, txtTemplates, nil
+}
+
+// statTemplateFiles returns the current version of the given template files. Files that cannot be read are skipped,
+// parsing them fails anyway.
+func statTemplateFiles(paths []string) []templateFile {
+	files := make([]templateFile, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		files = append(files, templateFile{path: path, size: info.Size(), modTime: info.ModTime().UnixNano()})
+	}
+
+	return files
+}
+
+const (
+	templateRoleAdmin = "admin"
+	templateRoleUser  = "user"
+)
+
+// templateVariant returns the name of the first template variant that matches the given user. An empty string is
+// returned if no variant matches.
+func (c TemplateHandler) templateVariant(user *domain.User) string {
+	if user == nil {
+		return ""
+	}
+
+	role := templateRoleUser
+	if user.IsAdmin {
+		role = templateRoleAdmin
+	}
+	_, emailDomain, _ := strings.Cut(user.Email, "@")
+
+	matches := func(values []string, value string) bool {
+		if len(values) == 0 {
+			return true
+		}
+		return slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, value) })
+	}
+
+	for _, variant := range c.variants {
+		if matches(variant.Roles, role) &&
+			matches(variant.Departments, user.Department) &&
+			matches(variant.Organizations, string(user.OrganizationIdentifier)) &&
+			matches(variant.EmailDomains, emailDomain) {
+			return variant.Name
+		}
+	}
+
+	return ""
+}
+
+// render executes the text and html template with the given base name, using the template variant of the user.
+// The user, organization, company name of the organization and portal url are added to the template data.
+func (c TemplateHandler) render(
+	name string,
+	user *domain.User,
+	org *domain.Organization,
+	data map[string]any,
+) (io.Reader, io.Reader, error) {
+	variant := c.templateVariant(user)
+	htmlTemplates, txtTemplates, err := c.templates(org, variant)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.addCommonData(data, user, org)
+
 	var tplBuff bytes.Buffer
 	var htmlTplBuff bytes.Buffer
 
-	err := c.textTemplates.ExecuteTemplate(&tplBuff, "mail_with_attachment.gotpl", map[string]any{
-		"User":           user,
+	err = txtTemplates.ExecuteTemplate(&tplBuff, name+".gotpl", data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute template %s.gotpl: %w", name, err)
+	}
+
+	err = htmlTemplates.ExecuteTemplate(&htmlTplBuff, name+".gohtml", data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute template %s.gohtml: %w", name, err)
+	}
+
+	return &tplBuff, &htmlTplBuff, nil
+}
+
+// addCommonData adds the user, organization, company name of the organization and portal url to the template data.
+func (c TemplateHandler) addCommonData(data map[string]any, user *domain.User, org *domain.Organization) {
+	companyName := ""
+	if org != nil {
+		companyName = org.CompanyName
+	}
+	data["User"] = user
+	data["Organization"] = org
+	data["CompanyName"] = companyName
+	data["PortalUrl"] = c.portalUrl
+}
+
+// GetSetupGuide returns the html setup guide for the operating system of the peer. Setup guides only have an html
+// template, they are attached to the configuration mail as standalone document.
+func (c TemplateHandler) GetSetupGuide(
+	user *domain.User,
+	org *domain.Organization,
+	peer *domain.Peer,
+	cfgName string,
+) (io.Reader, error) {
+	name := peer.OperatingSystem.SetupGuideTemplate()
+	if name == "" {
+		return nil, fmt.Errorf("no setup guide for operating system %q: %w", peer.OperatingSystem,
+			domain.ErrInvalidData)
+	}
+
+	htmlTemplates, _, err := c.templates(org, c.templateVariant(user))
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]any{
+		"Peer":           peer,
 		"ConfigFileName": cfgName,
-		"QrcodePngName":  qrName,
-		"PortalUrl":      c.portalUrl,
-	})
+	}
+	c.addCommonData(data, user, org)
+
+	var htmlTplBuff bytes.Buffer
+	err = htmlTemplates.ExecuteTemplate(&htmlTplBuff, name+".gohtml", data)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to execute template mail_with_attachment.gotpl: %w", err)
+		return nil, fmt.Errorf("failed to execute template %s.gohtml: %w", name, err)
 	}
 
-	err = c.htmlTemplates.ExecuteTemplate(&htmlTplBuff, "mail_with_attachment.gohtml", map[string]any{
-		"User":           user,
+	return &htmlTplBuff, nil
+}
+
+// GetConfigMail returns the text and html template for the mail with a link. The changes summarize what changed
+// since the configuration was mailed last. The tracking pixel is only embedded if it is not empty.
+func (c TemplateHandler) GetConfigMail(
+	user *domain.User,
+	org *domain.Organization,
+	link, trackingPixel string,
+	changes []domain.PeerConfigChange,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_with_link", user, org, map[string]any{
+		"Link":          link,
+		"TrackingPixel": trackingPixel,
+		"Changes":       changes,
+	})
+}
+
+// GetConfigMailWithAttachment returns the text and html template for the mail with an attachment. The device type
+// of the peer selects the setup instructions and the changes summarize what changed since the configuration was
+// mailed last. The setup guide name and the tracking pixel are only mentioned in the mail if they are not empty.
+func (c TemplateHandler) GetConfigMailWithAttachment(
+	user *domain.User,
+	org *domain.Organization,
+	peer *domain.Peer,
+	cfgName, qrName, guideName, trackingPixel string,
+	changes []domain.PeerConfigChange,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_with_attachment", user, org, map[string]any{
+		"Peer":           peer,
 		"ConfigFileName": cfgName,
 		"QrcodePngName":  qrName,
-		"PortalUrl":      c.portalUrl,
+		"SetupGuideName": guideName,
+		"TrackingPixel":  trackingPixel,
+		"Changes":        changes,
 	})
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to execute template mail_with_attachment.gohtml: %w", err)
+}
+
+// GetPeerCleanupWarningMail returns the text and html template for the mail that warns a user about the
+// upcoming cleanup of inactive peers.
+func (c TemplateHandler) GetPeerCleanupWarningMail(
+	user *domain.User,
+	org *domain.Organization,
+	action string,
+	candidates []domain.PeerCleanupCandidate,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_peer_cleanup_warning", user, org, map[string]any{
+		"Action":     action,
+		"Candidates": candidates,
+	})
+}
+
+// GetPeerExpiryReminderMail returns the text and html template for the mail that reminds a user about the
+// upcoming expiry of a peer.
+func (c TemplateHandler) GetPeerExpiryReminderMail(
+	user *domain.User,
+	org *domain.Organization,
+	peer *domain.Peer,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_peer_expiry_reminder", user, org, map[string]any{
+		"Peer": peer,
+	})
+}
+
+// GetPeerSetupReminderMail returns the text and html template for the mail that reminds a user to set up a peer
+// that never connected since its configuration was mailed.
+func (c TemplateHandler) GetPeerSetupReminderMail(
+	user *domain.User,
+	org *domain.Organization,
+	peer *domain.Peer,
+	mailedAt time.Time,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_peer_setup_reminder", user, org, map[string]any{
+		"Peer":     peer,
+		"MailedAt": mailedAt,
+	})
+}
+
+// GetPeerTransferMail returns the text and html template for the mail that informs a user about the state of a
+// peer transfer. The mail text depends on the transfer state and on whether the user receives the peer.
+func (c TemplateHandler) GetPeerTransferMail(
+	user *domain.User,
+	org *domain.Organization,
+	transfer *domain.PeerTransfer,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_peer_transfer", user, org, map[string]any{
+		"Transfer": transfer,
+		"Incoming": user.Identifier == transfer.ToUser,
+	})
+}
+
+// GetClientUpdateMail returns the text and html template for the mail that asks a user to update the WireGuard
+// app on devices with outdated clients.
+func (c TemplateHandler) GetClientUpdateMail(
+	user *domain.User,
+	org *domain.Organization,
+	clients []domain.OutdatedClient,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_client_update", user, org, map[string]any{
+		"Clients": clients,
+	})
+}
+
+// GetAddressPoolWarningMail returns the text and html template for the mail that warns an administrator about
+// address pools of an interface that are almost exhausted.
+func (c TemplateHandler) GetAddressPoolWarningMail(
+	user *domain.User,
+	org *domain.Organization,
+	iface *domain.Interface,
+	pools []domain.AddressPoolCapacity,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_address_pool_warning", user, org, map[string]any{
+		"Interface": iface,
+		"Pools":     pools,
+	})
+}
+
+// GetLoginNotificationMail returns the text and html template for the mail that informs a user about a login from
+// a new device.
+func (c TemplateHandler) GetLoginNotificationMail(
+	user *domain.User,
+	org *domain.Organization,
+	device *domain.UserLoginDevice,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_login_notification", user, org, map[string]any{
+		"Device": device,
+	})
+}
+
+// GetRoamingWarningMail returns the text and html template for the mail that informs a user about an endpoint of one
+// of their peers that violates the roaming policy.
+func (c TemplateHandler) GetRoamingWarningMail(
+	user *domain.User,
+	org *domain.Organization,
+	violation *domain.RoamingViolation,
+	disabled bool,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_roaming_warning", user, org, map[string]any{
+		"Violation": violation,
+		"Disabled":  disabled,
+	})
+}
+
+// GetPeerCompromisedMail returns the text and html template for the mail that informs a user that one of their
+// peers was marked as compromised.
+func (c TemplateHandler) GetPeerCompromisedMail(
+	user *domain.User,
+	org *domain.Organization,
+	incident *domain.PeerCompromise,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_peer_compromised", user, org, map[string]any{
+		"Incident": incident,
+	})
+}
+
+// GetAnnouncementMail returns the text and html template for the mail that delivers an announcement of the
+// administrators.
+func (c TemplateHandler) GetAnnouncementMail(
+	user *domain.User,
+	org *domain.Organization,
+	announcement *domain.Announcement,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_announcement", user, org, map[string]any{
+		"Announcement": announcement,
+	})
+}
+
+// GetExportApprovalMail returns the text and html template for the mail that asks the administrators to approve an
+// export request, or that informs the requesting user about the decision.
+func (c TemplateHandler) GetExportApprovalMail(
+	user *domain.User,
+	org *domain.Organization,
+	approval *domain.ExportApproval,
+) (io.Reader, io.Reader, error) {
+	return c.render("mail_export_approval", user, org, map[string]any{
+		"Approval": approval,
+	})
+}
+
+// GetConfigDownloadMail returns the text and html template for the mail that informs a user about the download of
+// one of their peer configurations.
+func (c TemplateHandler) GetConfigDownloadMail(
+	user *domain.User,
+	org *domain.Organization,
+	download *domain.PeerConfigDownload,
+) (io.Reader, io.Reader, error) {
+	peerName := download.Peer.DisplayName
+	if peerName == "" {
+		peerName = string(download.Peer.Identifier)
 	}
 
-	return &tplBuff, &htmlTplBuff, nil
+	return c.render("mail_config_download", user, org, map[string]any{
+		"Download": download,
+		"PeerName": peerName,
+		"Self":     download.DownloadedBy == user.Identifier,
+		"QrCode":   download.Format == domain.PeerConfigFormatQrCode,
+	})
 }
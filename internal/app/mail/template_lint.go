@@ -0,0 +1,447 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	htmlTemplate "html/template"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// templateVariable describes a variable that is passed to a mail template. The sample value only defines the type.
+type templateVariable struct {
+	name        string
+	sample      any
+	description string
+}
+
+// commonTemplateVariables are added to the data of all mail templates by TemplateHandler.render.
+var commonTemplateVariables = []templateVariable{
+	{"User", (*domain.User)(nil), "The user that receives the mail."},
+	{"Organization", (*domain.Organization)(nil), "The organization of the user, empty if the user has none."},
+	{"CompanyName", "", "The company name of the organization of the user."},
+	{"PortalUrl", "", "The external URL of WireGuard Portal."},
+}
+
+// templateVariables contains the template specific variables of all built-in mail templates. It has to be kept in
+// sync with the data passed to TemplateHandler.render.
+var templateVariables = map[string][]templateVariable{
+	"mail_with_link": {
+		{"Link", "", "The link to download the peer configuration."},
+		{"TrackingPixel", "", "The URL of the tracking pixel, empty if mail tracking is disabled."},
+		{"Changes", []domain.PeerConfigChange(nil), "The settings that changed since the last configuration mail."},
+	},
+	"mail_with_attachment": {
+		{"Peer", (*domain.Peer)(nil), "The peer of the configuration, its device type selects the setup instructions."},
+		{"ConfigFileName", "", "The file name of the attached peer configuration."},
+		{"QrcodePngName", "", "The content id of the embedded QR code image."},
+		{"SetupGuideName", "", "The file name of the attached setup guide, empty if no guide is attached."},
+		{"TrackingPixel", "", "The URL of the tracking pixel, empty if mail tracking is disabled."},
+		{"Changes", []domain.PeerConfigChange(nil), "The settings that changed since the last configuration mail."},
+	},
+	"mail_peer_cleanup_warning": {
+		{"Action", "", "The cleanup action, either disable or delete."},
+		{"Candidates", []domain.PeerCleanupCandidate(nil), "The peers that will be cleaned up."},
+	},
+	"mail_peer_expiry_reminder": {
+		{"Peer", (*domain.Peer)(nil), "The peer that expires soon."},
+	},
+	"mail_peer_setup_reminder": {
+		{"Peer", (*domain.Peer)(nil), "The peer that never connected."},
+		{"MailedAt", time.Time{}, "The time the configuration was mailed first."},
+	},
+	"mail_peer_transfer": {
+		{"Transfer", (*domain.PeerTransfer)(nil), "The peer transfer."},
+		{"Incoming", false, "True if the user receives the peer."},
+	},
+	"mail_client_update": {
+		{"Clients", []domain.OutdatedClient(nil), "The outdated clients of the user."},
+	},
+	"mail_address_pool_warning": {
+		{"Interface", (*domain.Interface)(nil), "The interface with almost exhausted address pools."},
+		{"Pools", []domain.AddressPoolCapacity(nil), "The address pools that crossed the warning threshold."},
+	},
+	"mail_login_notification": {
+		{"Device", (*domain.UserLoginDevice)(nil), "The unknown device the user logged in from."},
+	},
+	"mail_config_download": {
+		{"Download", (*domain.PeerConfigDownload)(nil), "The configuration download."},
+		{"PeerName", "", "The display name of the peer, or its identifier if no display name is set."},
+		{"Self", false, "True if the user downloaded the configuration."},
+		{"QrCode", false, "True if the configuration was shown as QR code."},
+	},
+	"mail_roaming_warning": {
+		{"Violation", (*domain.RoamingViolation)(nil), "The endpoint of the peer that violates the roaming policy."},
+		{"Disabled", false, "True if the peer was disabled because of the violation."},
+	},
+	"mail_peer_compromised": {
+		{"Incident", (*domain.PeerCompromise)(nil), "The incident of the compromised peer."},
+	},
+	"mail_announcement": {
+		{"Announcement", (*domain.Announcement)(nil), "The announcement of the administrators."},
+	},
+	"mail_export_approval": {
+		{"Approval", (*domain.ExportApproval)(nil), "The export approval request, pending or decided."},
+	},
+}
+
+// parseErrorLine extracts the line number from errors of the template parser, for example:
+// template: mail_with_link:3: unexpected "}" in operand
+var parseErrorLine = regexp.MustCompile(`^template: [^:]*:(\d+):(?:\d+:)?\s*(.*)$`)
+
+// GetTemplates returns the built-in mail templates and their variables, sorted by name.
+func GetTemplates() []domain.MailTemplate {
+	templates := make([]domain.MailTemplate, 0, len(templateVariables))
+	for name, specificVariables := range templateVariables {
+		tpl := domain.MailTemplate{Name: name}
+		for _, variable := range append(slices.Clone(commonTemplateVariables), specificVariables...) {
+			t := reflect.TypeOf(variable.sample)
+			tpl.Variables = append(tpl.Variables, domain.MailTemplateVariable{
+				Name:        variable.name,
+				Type:        t.String(),
+				Description: variable.description,
+				Fields:      typeMembers(t),
+			})
+		}
+		templates = append(templates, tpl)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	return templates
+}
+
+// LintTemplate validates the content of a custom template with the given name. It reports syntax errors,
+// variables that are not passed to the template and fields that do not exist on the variables. An empty result
+// means that the template can be rendered safely.
+func LintTemplate(name string, format domain.MailTemplateFormat, content string) ([]domain.MailTemplateIssue, error) {
+	specificVariables, ok := templateVariables[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown mail template %s: %w", name, domain.ErrInvalidData)
+	}
+
+	root := make(map[string]reflect.Type)
+	for _, variable := range append(slices.Clone(commonTemplateVariables), specificVariables...) {
+		root[variable.name] = reflect.TypeOf(variable.sample)
+	}
+
+	tpl, err := template.New(name).Parse(content)
+	if err != nil {
+		return []domain.MailTemplateIssue{newParseIssue(err)}, nil
+	}
+
+	switch format {
+	case domain.MailTemplateFormatText:
+	case domain.MailTemplateFormatHtml:
+		// the contextual escaping of html templates is only checked on the first execution
+		htmlTpl, err := htmlTemplate.New(name).Parse(content)
+		if err != nil {
+			return []domain.MailTemplateIssue{newParseIssue(err)}, nil
+		}
+		var escapeErr *htmlTemplate.Error
+		if err := htmlTpl.Execute(io.Discard, nil); errors.As(err, &escapeErr) {
+			message := escapeErr.Description
+			if escapeErr.ErrorCode == htmlTemplate.ErrEndContext {
+				message = "the template ends inside an unclosed tag, attribute, comment or script"
+			}
+			line := escapeErr.Line
+			if line == 0 && escapeErr.Node != nil {
+				line = lineOf(content, escapeErr.Node)
+			}
+			return []domain.MailTemplateIssue{{Line: line, Message: message}}, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported template format %s: %w", format, domain.ErrInvalidData)
+	}
+
+	if tpl.Tree == nil || tpl.Tree.Root == nil {
+		return nil, nil // empty template
+	}
+
+	l := &templateLinter{content: content}
+	rootType := lintType{variables: root}
+	l.walk(tpl.Tree.Root, lintScope{dot: rootType, root: rootType, variables: map[string]lintType{}})
+
+	return l.issues, nil
+}
+
+func newParseIssue(err error) domain.MailTemplateIssue {
+	matches := parseErrorLine.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return domain.MailTemplateIssue{Message: err.Error()}
+	}
+	line, _ := strconv.Atoi(matches[1])
+
+	return domain.MailTemplateIssue{Line: line, Message: matches[2]}
+}
+
+// lintType is the type of a template value. The zero value is an unknown type that is not checked.
+type lintType struct {
+	t         reflect.Type
+	variables map[string]reflect.Type // set for the template data, which is a map of variables
+}
+
+type lintScope struct {
+	dot       lintType
+	root      lintType
+	variables map[string]lintType
+}
+
+// with returns a copy of the scope with its own variables, as variables declared in a block are not visible
+// outside of it.
+func (s lintScope) with(dot lintType) lintScope {
+	variables := make(map[string]lintType, len(s.variables))
+	for k, v := range s.variables {
+		variables[k] = v
+	}
+
+	return lintScope{dot: dot, root: s.root, variables: variables}
+}
+
+type templateLinter struct {
+	content string
+	issues  []domain.MailTemplateIssue
+}
+
+func (l *templateLinter) report(node parse.Node, message string) {
+	issue := domain.MailTemplateIssue{Line: lineOf(l.content, node), Message: message}
+	if !slices.Contains(l.issues, issue) {
+		l.issues = append(l.issues, issue)
+	}
+}
+
+// lineOf returns the line of the given node in the template content.
+func lineOf(content string, node parse.Node) int {
+	return 1 + strings.Count(content[:min(int(node.Position()), len(content))], "\n")
+}
+
+func (l *templateLinter) walk(node parse.Node, scope lintScope) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			l.walk(child, scope)
+		}
+	case *parse.ActionNode:
+		l.pipe(n.Pipe, scope)
+	case *parse.IfNode:
+		l.pipe(n.Pipe, scope)
+		l.walk(n.List, scope.with(scope.dot))
+		l.walk(n.ElseList, scope.with(scope.dot))
+	case *parse.WithNode:
+		value := l.pipe(n.Pipe, scope)
+		l.walk(n.List, scope.with(value))
+		l.walk(n.ElseList, scope.with(scope.dot))
+	case *parse.RangeNode:
+		inner := scope.with(lintType{})
+		key, elem := rangeTypes(l.pipe(n.Pipe, inner))
+		inner.dot = elem
+		switch len(n.Pipe.Decl) {
+		case 1:
+			inner.variables[n.Pipe.Decl[0].Ident[0]] = elem
+		case 2:
+			inner.variables[n.Pipe.Decl[0].Ident[0]] = key
+			inner.variables[n.Pipe.Decl[1].Ident[0]] = elem
+		}
+		l.walk(n.List, inner)
+		l.walk(n.ElseList, scope.with(scope.dot))
+	case *parse.TemplateNode:
+		if n.Pipe != nil {
+			l.pipe(n.Pipe, scope)
+		}
+	}
+}
+
+// pipe checks all commands of the pipeline and returns the type of its result. Variables declared by the
+// pipeline are added to the scope.
+func (l *templateLinter) pipe(pipe *parse.PipeNode, scope lintScope) lintType {
+	if pipe == nil {
+		return lintType{}
+	}
+
+	var result lintType
+	for _, cmd := range pipe.Cmds {
+		result = lintType{}
+		for i, arg := range cmd.Args {
+			value := l.node(arg, scope)
+			if i == 0 {
+				result = value // other values are arguments of the function or method
+			}
+		}
+		if _, isFunction := cmd.Args[0].(*parse.IdentifierNode); isFunction {
+			result = lintType{}
+		}
+	}
+
+	if len(pipe.Decl) == 1 {
+		if pipe.IsAssign {
+			if _, ok := scope.variables[pipe.Decl[0].Ident[0]]; ok {
+				scope.variables[pipe.Decl[0].Ident[0]] = lintType{} // the type may change on assignment
+			}
+		} else {
+			scope.variables[pipe.Decl[0].Ident[0]] = result
+		}
+	}
+
+	return result
+}
+
+func (l *templateLinter) node(node parse.Node, scope lintScope) lintType {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return scope.dot
+	case *parse.FieldNode:
+		return l.fields(n, scope.dot, n.Ident)
+	case *parse.VariableNode:
+		base := scope.root
+		if n.Ident[0] != "$" {
+			base = scope.variables[n.Ident[0]]
+		}
+		return l.fields(n, base, n.Ident[1:])
+	case *parse.ChainNode:
+		return l.fields(n, l.node(n.Node, scope), n.Field)
+	case *parse.PipeNode:
+		return l.pipe(n, scope.with(scope.dot))
+	}
+
+	return lintType{}
+}
+
+// fields resolves the chain of field names on the given type and reports fields that do not exist.
+func (l *templateLinter) fields(node parse.Node, base lintType, names []string) lintType {
+	current := base
+	for _, name := range names {
+		next, err := current.field(name)
+		if err != nil {
+			l.report(node, err.Error())
+			return lintType{}
+		}
+		current = next
+	}
+
+	return current
+}
+
+// field returns the type of the field or method with the given name.
+func (t lintType) field(name string) (lintType, error) {
+	if t.variables != nil {
+		if variable, ok := t.variables[name]; ok {
+			return lintType{t: variable}, nil
+		}
+		available := make([]string, 0, len(t.variables))
+		for variable := range t.variables {
+			available = append(available, variable)
+		}
+		sort.Strings(available)
+		return lintType{}, fmt.Errorf("variable %q is not available in this template%s, available variables: %s",
+			name, suggestion(name, available), strings.Join(available, ", "))
+	}
+
+	typ := t.t
+	if typ == nil || typ.Kind() == reflect.Interface {
+		return lintType{}, nil // unknown type, for example the result of a function
+	}
+
+	if method, ok := lookupMethod(typ, name); ok {
+		if method.Type.NumOut() == 0 {
+			return lintType{}, nil
+		}
+		return lintType{t: method.Type.Out(0)}, nil
+	}
+
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		if field, ok := typ.FieldByName(name); ok && field.IsExported() {
+			return lintType{t: field.Type}, nil
+		}
+	case reflect.Map:
+		if typ.Key().Kind() == reflect.String {
+			return lintType{t: typ.Elem()}, nil
+		}
+	}
+
+	return lintType{}, fmt.Errorf("%s has no field or method %q%s", typ.String(), name,
+		suggestion(name, typeMembers(typ)))
+}
+
+// lookupMethod returns the exported method with the given name. Methods with pointer receivers are included, as
+// the fields of template values are addressable.
+func lookupMethod(typ reflect.Type, name string) (reflect.Method, bool) {
+	if typ.Kind() != reflect.Pointer && typ.Kind() != reflect.Interface {
+		typ = reflect.PointerTo(typ)
+	}
+
+	return typ.MethodByName(name)
+}
+
+// rangeTypes returns the key and element type for a range over a value of the given type.
+func rangeTypes(t lintType) (lintType, lintType) {
+	typ := t.t
+	if typ == nil {
+		return lintType{}, lintType{}
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		return lintType{t: reflect.TypeOf(0)}, lintType{t: typ.Elem()}
+	case reflect.Map:
+		return lintType{t: typ.Key()}, lintType{t: typ.Elem()}
+	case reflect.Int:
+		return lintType{t: typ}, lintType{t: typ}
+	}
+
+	return lintType{}, lintType{}
+}
+
+// typeMembers returns the exported fields and methods of struct types.
+func typeMembers(typ reflect.Type) []string {
+	for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var members []string
+	for _, field := range reflect.VisibleFields(typ) {
+		if field.IsExported() && !field.Anonymous {
+			members = append(members, field.Name)
+		}
+	}
+	ptr := reflect.PointerTo(typ)
+	for i := range ptr.NumMethod() {
+		if method := ptr.Method(i); method.Type.NumIn() == 1 && method.Type.NumOut() > 0 {
+			members = append(members, method.Name) // only methods without arguments are listed
+		}
+	}
+
+	return members
+}
+
+// suggestion returns a hint for names that only differ in case from one of the available names.
+func suggestion(name string, available []string) string {
+	for _, candidate := range available {
+		if strings.EqualFold(candidate, name) {
+			return fmt.Sprintf(" (did you mean %q?)", candidate)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestLintTemplate_builtInTemplates(t *testing.T) {
+	templates := GetTemplates()
+	require.Len(t, templates, len(templateVariables))
+
+	for _, tpl := range templates {
+		for ext, format := range map[string]domain.MailTemplateFormat{
+			".gotpl":  domain.MailTemplateFormatText,
+			".gohtml": domain.MailTemplateFormatHtml,
+		} {
+			content, err := TemplateFiles.ReadFile("tpl_files/" + tpl.Name + ext)
+			require.NoError(t, err)
+
+			issues, err := LintTemplate(tpl.Name, format, string(content))
+			require.NoError(t, err)
+			assert.Empty(t, issues, "template %s%s", tpl.Name, ext)
+		}
+	}
+}
+
+func TestLintTemplate(t *testing.T) {
+	issues, err := LintTemplate("mail_with_link", domain.MailTemplateFormatText,
+		"Hello {{$.User.Firstname}},\n{{$.Link}}\n{{$.ConfigFileName}}")
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, 3, issues[0].Line)
+	assert.Contains(t, issues[0].Message, `variable "ConfigFileName" is not available`)
+	assert.Contains(t, issues[0].Message, "Link")
+
+	issues, err = LintTemplate("mail_with_link", domain.MailTemplateFormatText, "{{.User.EMail}}")
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, `domain.User has no field or method "EMail" (did you mean "Email"?)`, issues[0].Message)
+
+	// the element type of ranges and variables is checked as well
+	issues, err = LintTemplate("mail_peer_cleanup_warning", domain.MailTemplateFormatText,
+		"{{range $c := $.Candidates}}{{.Peer.DisplayName}} {{$c.LastActivity.Format \"2006\"}} {{.Peer.Nmae}}{{end}}")
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Contains(t, issues[0].Message, `domain.Peer has no field or method "Nmae"`)
+
+	issues, err = LintTemplate("mail_peer_expiry_reminder", domain.MailTemplateFormatText,
+		"{{with $.Peer}}{{.GetConfigFileName}}{{.ExpiresAt.Format \"2006\"}}{{end}}")
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	issues, err = LintTemplate("mail_with_link", domain.MailTemplateFormatText, "line 1\n{{if $.Link}}")
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, 2, issues[0].Line)
+	assert.Contains(t, issues[0].Message, "unexpected EOF")
+
+	issues, err = LintTemplate("mail_with_link", domain.MailTemplateFormatText, "{{lower $.Link}}")
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Contains(t, issues[0].Message, `function "lower" not defined`)
+
+	_, err = LintTemplate("mail_unknown", domain.MailTemplateFormatText, "")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+	_, err = LintTemplate("mail_with_link", "pdf", "")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
+
+func TestLintTemplate_html(t *testing.T) {
+	issues, err := LintTemplate("mail_with_link", domain.MailTemplateFormatHtml,
+		"<p>\n<a href=\"{{$.Link}}\">link</a>\n<script>var x = '{{$.Link}}</script>")
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Contains(t, issues[0].Message, "ends inside an unclosed tag")
+
+	issues, err = LintTemplate("mail_with_link", domain.MailTemplateFormatHtml,
+		"<p>\n{{if $.Link}}<a href=\"{{$.Link}}\">{{else}}<a href=\"{{end}}link</a>")
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, 2, issues[0].Line)
+	assert.Contains(t, issues[0].Message, "branches end in different contexts")
+}
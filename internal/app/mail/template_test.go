@@ -0,0 +1,481 @@
+package mail
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestTemplateHandler_organizationTemplates(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "acme"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "acme", "mail_with_link.gotpl"),
+		[]byte("{{$.CompanyName}}: {{$.Link}}"), 0o644))
+
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{OrganizationTemplatesPath: dir})
+	require.NoError(t, err)
+
+	user := &domain.User{Identifier: "alice"}
+	acme := &domain.Organization{Identifier: "acme", CompanyName: "ACME Corp"}
+
+	txt, html, err := handler.GetConfigMail(user, acme, "https://vpn.example.com/link", "", nil)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Equal(t, "ACME Corp: https://vpn.example.com/link", string(txtStr))
+	assert.Contains(t, string(htmlStr), "for ACME Corp", "missing html templates fall back to the built-in ones")
+
+	// organizations without custom templates use the built-in templates
+	txt, _, err = handler.GetConfigMail(user, &domain.Organization{Identifier: "globex"}, "link", "", nil)
+	require.NoError(t, err)
+	txtStr, _ = io.ReadAll(txt)
+	assert.Contains(t, string(txtStr), "This mail was generated using WireGuard Portal.")
+
+	txt, _, err = handler.GetConfigMail(user, nil, "link", "", nil)
+	require.NoError(t, err)
+	txtStr, _ = io.ReadAll(txt)
+	assert.Contains(t, string(txtStr), "This mail was generated using WireGuard Portal.")
+}
+
+func TestTemplateHandler_templateVariants(t *testing.T) {
+	orgDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(orgDir, "acme"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(orgDir, "acme", "mail_with_link.gotpl"),
+		[]byte("acme: {{$.Link}}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(orgDir, "acme", "mail_with_attachment.gotpl"),
+		[]byte("acme attachment"), 0o644))
+
+	variantDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(variantDir, "contractors"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(variantDir, "contractors", "mail_with_link.gotpl"),
+		[]byte("contractor terms: {{$.Link}}"), 0o644))
+
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{
+		OrganizationTemplatesPath: orgDir,
+		TemplateVariantsPath:      variantDir,
+		TemplateVariants: []config.MailTemplateVariant{
+			{Name: "admins", Roles: []string{"admin"}},
+			{Name: "contractors", Departments: []string{"contractors"}},
+			{Name: "contractors", EmailDomains: []string{"partner.example.com"}},
+		},
+	})
+	require.NoError(t, err)
+
+	acme := &domain.Organization{Identifier: "acme"}
+	render := func(user *domain.User, org *domain.Organization) string {
+		txt, _, err := handler.GetConfigMail(user, org, "link", "", nil)
+		require.NoError(t, err)
+		txtStr, _ := io.ReadAll(txt)
+		return string(txtStr)
+	}
+
+	contractor := &domain.User{Identifier: "bob", Department: "Contractors", OrganizationIdentifier: "acme"}
+	assert.Equal(t, "contractor terms: link", render(contractor, acme), "variants replace organization templates")
+	partner := &domain.User{Identifier: "carol", Email: "carol@partner.example.com"}
+	assert.Equal(t, "contractor terms: link", render(partner, nil))
+	assert.Equal(t, "acme: link", render(&domain.User{Identifier: "alice", OrganizationIdentifier: "acme"}, acme))
+	assert.Contains(t, render(&domain.User{Identifier: "admin", IsAdmin: true}, nil),
+		"This mail was generated using WireGuard Portal.", "variants without templates use the default templates")
+
+	txt, _, err := handler.GetConfigMailWithAttachment(contractor, acme, &domain.Peer{}, "wg.conf", "", "", "", nil)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	assert.Equal(t, "acme attachment", string(txtStr), "missing variant templates fall back to the organization")
+
+	_, err = newTemplateHandler("", config.MailConfig{TemplateVariants: []config.MailTemplateVariant{{Name: "../x"}}})
+	assert.Error(t, err)
+	_, err = newTemplateHandler("", config.MailConfig{
+		TemplateVariants: []config.MailTemplateVariant{{Name: "x", Roles: []string{"owner"}}},
+	})
+	assert.Error(t, err)
+}
+
+func TestTemplateHandler_templateCache(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "acme"), 0o755))
+	tplFile := filepath.Join(dir, "acme", "mail_with_link.gotpl")
+	require.NoError(t, os.WriteFile(tplFile, []byte("v1: {{$.Link}}"), 0o644))
+
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{OrganizationTemplatesPath: dir})
+	require.NoError(t, err)
+	assert.Len(t, handler.cache.sets, 1, "organization templates are parsed at startup")
+
+	acme := &domain.Organization{Identifier: "acme"}
+	htmlTpl, txtTpl, err := handler.templates(acme, "")
+	require.NoError(t, err)
+	htmlCached, txtCached, err := handler.templates(acme, "")
+	require.NoError(t, err)
+	assert.Same(t, htmlTpl, htmlCached, "unchanged templates are not parsed again")
+	assert.Same(t, txtTpl, txtCached)
+
+	require.NoError(t, os.WriteFile(tplFile, []byte("v2: {{$.Link}}"), 0o644))
+	changed := time.Now().Add(time.Minute) // the file system time resolution may be too coarse
+	require.NoError(t, os.Chtimes(tplFile, changed, changed))
+
+	txt, _, err := handler.GetConfigMail(&domain.User{Identifier: "alice"}, acme, "link", "", nil)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	assert.Equal(t, "v2: link", string(txtStr), "changed templates are parsed again")
+
+	require.NoError(t, os.Remove(tplFile))
+	txt, _, err = handler.GetConfigMail(&domain.User{Identifier: "alice"}, acme, "link", "", nil)
+	require.NoError(t, err)
+	txtStr, _ = io.ReadAll(txt)
+	assert.Contains(t, string(txtStr), "This mail was generated using WireGuard Portal.")
+}
+
+func TestTemplateHandler_GetConfigMailWithAttachment_deviceType(t *testing.T) {
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{})
+	require.NoError(t, err)
+
+	user := &domain.User{Identifier: "alice"}
+	tests := map[domain.PeerDeviceType]string{
+		domain.PeerDeviceTypeUnknown: "Scan the attached Qrcode or open the attached configuration file (wg.conf)",
+		domain.PeerDeviceTypePhone:   "Scan the attached Qrcode with the WireGuard app on your phone",
+		domain.PeerDeviceTypeRouter:  "Copy the attached configuration file (wg.conf) to the device",
+	}
+	for deviceType, want := range tests {
+		peer := &domain.Peer{Identifier: "peer-a", DeviceType: deviceType}
+		txt, html, err := handler.GetConfigMailWithAttachment(user, nil, peer, "wg.conf", "qr.png", "", "", nil)
+		require.NoError(t, err)
+		txtStr, _ := io.ReadAll(txt)
+		assert.Contains(t, string(txtStr), want, "device type %q", deviceType)
+		_, err = io.ReadAll(html)
+		require.NoError(t, err)
+	}
+}
+
+func TestTemplateHandler_GetClientUpdateMail(t *testing.T) {
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{})
+	require.NoError(t, err)
+
+	clients := []domain.OutdatedClient{
+		{Peer: domain.Peer{Identifier: "peer-a", DisplayName: "Laptop"}, Os: domain.ClientOsWindows,
+			Version: "0.5.1", MinVersion: "0.5.3"},
+		{Peer: domain.Peer{Identifier: "peer-b"}, Os: domain.ClientOsAndroid, Version: "1.0.1", MinVersion: "1.0.2"},
+	}
+	txt, html, err := handler.GetClientUpdateMail(&domain.User{Identifier: "alice"}, nil, clients)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Contains(t, string(txtStr), "Laptop: version 0.5.1 (windows), at least 0.5.3 is required")
+	assert.Contains(t, string(txtStr), "Check for updates")
+	assert.Contains(t, string(txtStr), "Google Play Store")
+	assert.Contains(t, string(htmlStr), "<strong>peer-b</strong>")
+}
+
+func TestTemplateHandler_GetAddressPoolWarningMail(t *testing.T) {
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{})
+	require.NoError(t, err)
+
+	network, err := domain.CidrFromString("10.0.0.0/24")
+	require.NoError(t, err)
+	exhaustedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	pools := []domain.AddressPoolCapacity{{InterfaceId: "wg0", Network: network, Size: 254, Used: 230,
+		ExhaustedAt: &exhaustedAt}}
+	iface := &domain.Interface{Identifier: "wg0", DisplayName: "Office"}
+	txt, html, err := handler.GetAddressPoolWarningMail(&domain.User{Identifier: "admin"}, nil, iface, pools)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Contains(t, string(txtStr), `"Office" (wg0)`)
+	assert.Contains(t, string(txtStr), "10.0.0.0/24: 230 of 254 addresses used (91%), 24 free")
+	assert.Contains(t, string(txtStr), "Projected exhaustion: 2024-06-01")
+	assert.Contains(t, string(htmlStr), "<strong>10.0.0.0/24</strong>")
+}
+
+func TestTemplateHandler_GetPeerExpiryReminderMail(t *testing.T) {
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{})
+	require.NoError(t, err)
+
+	expiresAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	peer := &domain.Peer{Identifier: "peer-a", DisplayName: "Laptop", ExpiresAt: &expiresAt}
+	txt, html, err := handler.GetPeerExpiryReminderMail(&domain.User{Identifier: "alice"}, nil, peer)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Contains(t, string(txtStr), `Your VPN access "Laptop" expires on 2024-06-01 00:00 UTC.`)
+	assert.Contains(t, string(htmlStr), "<strong>2024-06-01 00:00 UTC</strong>")
+}
+
+func TestTemplateHandler_GetPeerSetupReminderMail(t *testing.T) {
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{})
+	require.NoError(t, err)
+
+	mailedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	peer := &domain.Peer{Identifier: "peer-a", DisplayName: "Laptop"}
+	txt, html, err := handler.GetPeerSetupReminderMail(&domain.User{Identifier: "alice"}, nil, peer, mailedAt)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Contains(t, string(txtStr), `Your VPN configuration "Laptop" was sent to you on 2024-06-01`)
+	assert.Contains(t, string(txtStr), "Windows:")
+	assert.Contains(t, string(htmlStr), "<strong>iOS and Android:</strong>")
+}
+
+func TestTemplateHandler_GetSetupGuide(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "setup_guide_linux.gohtml"),
+		[]byte("<p>custom guide for {{$.ConfigFileName}}</p>"), 0o644))
+
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{SetupGuidesPath: dir})
+	require.NoError(t, err)
+
+	user := &domain.User{Identifier: "alice"}
+	peer := &domain.Peer{Identifier: "peer-a", DisplayName: "Laptop", OperatingSystem: domain.PeerOperatingSystemIOS}
+	html, err := handler.GetSetupGuide(user, nil, peer, "Laptop.conf")
+	require.NoError(t, err)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Contains(t, string(htmlStr), "<h1>WireGuard setup guide for iOS</h1>")
+	assert.Contains(t, string(htmlStr), "<code>Laptop.conf</code>")
+
+	// custom guides replace the built-in guides
+	peer.OperatingSystem = domain.PeerOperatingSystemLinux
+	html, err = handler.GetSetupGuide(user, nil, peer, "Laptop.conf")
+	require.NoError(t, err)
+	htmlStr, _ = io.ReadAll(html)
+	assert.Equal(t, "<p>custom guide for Laptop.conf</p>", string(htmlStr))
+
+	peer.OperatingSystem = domain.PeerOperatingSystemUnknown
+	_, err = handler.GetSetupGuide(user, nil, peer, "Laptop.conf")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
+
+func TestTemplateHandler_GetPeerTransferMail(t *testing.T) {
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{})
+	require.NoError(t, err)
+
+	transfer := &domain.PeerTransfer{
+		PeerId:    "peer-a",
+		PeerName:  "Laptop",
+		FromUser:  "alice",
+		ToUser:    "bob",
+		State:     domain.PeerTransferPending,
+		ExpiresAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	txt, html, err := handler.GetPeerTransferMail(&domain.User{Identifier: "bob"}, nil, transfer)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Contains(t, string(txtStr), `alice wants to transfer the VPN peer "Laptop" to you.`)
+	assert.Contains(t, string(htmlStr), "<strong>2024-06-01 00:00 UTC</strong>")
+
+	transfer.State = domain.PeerTransferCompleted
+	txt, _, err = handler.GetPeerTransferMail(&domain.User{Identifier: "alice"}, nil, transfer)
+	require.NoError(t, err)
+	txtStr, _ = io.ReadAll(txt)
+	assert.Contains(t, string(txtStr), `The VPN peer "Laptop" was transferred to bob.`)
+}
+
+func TestTemplateHandler_GetLoginNotificationMail(t *testing.T) {
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{})
+	require.NoError(t, err)
+
+	device := &domain.UserLoginDevice{
+		IpAddress: "198.51.100.7",
+		Browser:   "Firefox",
+		Platform:  "Linux",
+		FirstSeen: time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC),
+	}
+	txt, html, err := handler.GetLoginNotificationMail(&domain.User{Identifier: "alice"}, nil, device)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Contains(t, string(txtStr), `account "alice" was just used to sign in from a new device.`)
+	assert.Contains(t, string(txtStr), "Browser: Firefox on Linux")
+	assert.Contains(t, string(htmlStr), "<strong>198.51.100.7</strong>")
+}
+
+func TestTemplateHandler_GetConfigDownloadMail(t *testing.T) {
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{})
+	require.NoError(t, err)
+
+	download := &domain.PeerConfigDownload{
+		Peer:         domain.Peer{Identifier: "peer-a", DisplayName: "Laptop", UserIdentifier: "alice"},
+		DownloadedBy: "admin",
+		Format:       domain.PeerConfigFormatQrCode,
+		Client:       domain.ClientInfo{IpAddress: "198.51.100.7", UserAgent: "curl/8.0"},
+		DownloadedAt: time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC),
+	}
+	txt, html, err := handler.GetConfigDownloadMail(&domain.User{Identifier: "alice"}, nil, download)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Contains(t, string(txtStr), `your VPN peer "Laptop" was shown as QR code by admin.`)
+	assert.Contains(t, string(txtStr), "Browser: Unknown browser\n")
+	assert.Contains(t, string(htmlStr), "by <strong>admin</strong>")
+
+	download.DownloadedBy = "alice"
+	download.Format = domain.PeerConfigFormatFile
+	txt, _, err = handler.GetConfigDownloadMail(&domain.User{Identifier: "alice"}, nil, download)
+	require.NoError(t, err)
+	txtStr, _ = io.ReadAll(txt)
+	assert.Contains(t, string(txtStr), `your VPN peer "Laptop" was downloaded using your account.`)
+}
+
+func TestTemplateHandler_GetRoamingWarningMail(t *testing.T) {
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{})
+	require.NoError(t, err)
+
+	violation := &domain.RoamingViolation{
+		PeerId:           "peer-a",
+		PeerName:         "Laptop",
+		Message:          "endpoint moved from AT to DE",
+		Time:             time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC),
+		Endpoint:         "198.51.100.7:51820",
+		PreviousEndpoint: "203.0.113.7:51820",
+		Country:          "DE",
+		PreviousCountry:  "AT",
+	}
+	txt, html, err := handler.GetRoamingWarningMail(&domain.User{Identifier: "alice"}, nil, violation, false)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Contains(t, string(txtStr), `your VPN peer "Laptop" connected from an unusual location: endpoint moved`)
+	assert.Contains(t, string(txtStr), "Previous endpoint: 203.0.113.7:51820 (AT)")
+	assert.Contains(t, string(txtStr), "renew the keys")
+	assert.Contains(t, string(htmlStr), "<strong>198.51.100.7:51820 (DE)</strong>")
+
+	txt, _, err = handler.GetRoamingWarningMail(&domain.User{Identifier: "alice"}, nil, violation, true)
+	require.NoError(t, err)
+	txtStr, _ = io.ReadAll(txt)
+	assert.Contains(t, string(txtStr), "The peer has been disabled.")
+}
+
+func TestTemplateHandler_GetPeerCompromisedMail(t *testing.T) {
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{})
+	require.NoError(t, err)
+
+	incident := &domain.PeerCompromise{
+		PeerIdentifier: "peer-a",
+		PeerName:       "Laptop",
+		Reason:         "device stolen",
+		ReportedAt:     time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC),
+	}
+	txt, html, err := handler.GetPeerCompromisedMail(&domain.User{Identifier: "alice"}, nil, incident)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Contains(t, string(txtStr), `your VPN peer "Laptop" was marked as compromised and has been disabled.`)
+	assert.Contains(t, string(txtStr), "Reason: device stolen")
+	assert.NotContains(t, string(txtStr), "preshared keys")
+	assert.Contains(t, string(htmlStr), "<strong>device stolen</strong>")
+
+	incident.RotatedPeers = []domain.PeerIdentifier{"peer-b", "peer-c"}
+	txt, _, err = handler.GetPeerCompromisedMail(&domain.User{Identifier: "alice"}, nil, incident)
+	require.NoError(t, err)
+	txtStr, _ = io.ReadAll(txt)
+	assert.Contains(t, string(txtStr), "The preshared keys of 2 other peer(s) of yours were renewed")
+}
+
+func TestTemplateHandler_GetAnnouncementMail(t *testing.T) {
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	announcement := &domain.Announcement{
+		Kind:     domain.AnnouncementKindMaintenance,
+		Title:    "Gateway upgrade",
+		Message:  "The VPN is unavailable during the upgrade.",
+		StartsAt: start,
+		EndsAt:   &end,
+	}
+	txt, html, err := handler.GetAnnouncementMail(&domain.User{Identifier: "alice"}, nil, announcement)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Contains(t, string(txtStr), "the administrators announced a maintenance of the VPN:")
+	assert.Contains(t, string(txtStr), "The VPN is unavailable during the upgrade.")
+	assert.Contains(t, string(txtStr), "From 2024-06-01 20:00 UTC until 2024-06-01 22:00 UTC")
+	assert.Contains(t, string(htmlStr), "<strong>Gateway upgrade</strong>")
+
+	announcement.Kind = domain.AnnouncementKindInfo
+	announcement.EndsAt = nil
+	txt, _, err = handler.GetAnnouncementMail(&domain.User{Identifier: "alice"}, nil, announcement)
+	require.NoError(t, err)
+	txtStr, _ = io.ReadAll(txt)
+	assert.Contains(t, string(txtStr), "the administrators published an announcement:")
+	assert.Contains(t, string(txtStr), "Since 2024-06-01 20:00 UTC")
+}
+
+func TestTemplateHandler_GetExportApprovalMail(t *testing.T) {
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{})
+	require.NoError(t, err)
+
+	requested := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+	approval := &domain.ExportApproval{
+		RequestedBy: "alice",
+		RequestedAt: requested,
+		ExportCount: 25,
+		State:       domain.ExportApprovalStatePending,
+	}
+	txt, html, err := handler.GetExportApprovalMail(&domain.User{Identifier: "admin"}, nil, approval)
+	require.NoError(t, err)
+	txtStr, _ := io.ReadAll(txt)
+	htmlStr, _ := io.ReadAll(html)
+	assert.Contains(t, string(txtStr), "the user alice exported the keys of 25 peers of other users")
+	assert.Contains(t, string(txtStr), "2024-06-01 20:00 UTC")
+	assert.Contains(t, string(htmlStr), "<strong>alice</strong>")
+
+	validUntil := requested.Add(time.Hour)
+	approval.State = domain.ExportApprovalStateApproved
+	approval.DecidedBy = "bob"
+	approval.ValidUntil = &validUntil
+	txt, _, err = handler.GetExportApprovalMail(&domain.User{Identifier: "alice"}, nil, approval)
+	require.NoError(t, err)
+	txtStr, _ = io.ReadAll(txt)
+	assert.Contains(t, string(txtStr), "was approved by bob")
+	assert.Contains(t, string(txtStr), "until 2024-06-01 21:00 UTC")
+
+	approval.State = domain.ExportApprovalStateRejected
+	txt, _, err = handler.GetExportApprovalMail(&domain.User{Identifier: "alice"}, nil, approval)
+	require.NoError(t, err)
+	txtStr, _ = io.ReadAll(txt)
+	assert.Contains(t, string(txtStr), "was rejected by bob")
+}
+
+func BenchmarkTemplateHandler_GetConfigMail(b *testing.B) {
+	dir := b.TempDir()
+	require.NoError(b, os.MkdirAll(filepath.Join(dir, "acme"), 0o755))
+	require.NoError(b, os.WriteFile(filepath.Join(dir, "acme", "mail_with_link.gotpl"),
+		[]byte("{{$.CompanyName}}: {{$.Link}}"), 0o644))
+
+	handler, err := newTemplateHandler("https://vpn.example.com", config.MailConfig{OrganizationTemplatesPath: dir})
+	require.NoError(b, err)
+	user := &domain.User{Identifier: "alice"}
+
+	orgs := map[string]*domain.Organization{
+		"built-in":     nil,
+		"organization": {Identifier: "acme", CompanyName: "ACME Corp"},
+	}
+	for name, org := range orgs {
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				if _, _, err := handler.GetConfigMail(user, org, "https://vpn.example.com/link", "", nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkParseTemplates measures the parsing that the template cache saves for each mail.
+func BenchmarkParseTemplates(b *testing.B) {
+	for b.Loop() {
+		if _, err := parseHtmlTemplates(); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := parseTextTemplates(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package mail
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+)
+
+// throttleWindow is the time window of the configured mail rate limits.
+const throttleWindow = time.Minute
+
+// globalThrottleKey is the key of the global rate limit, domain limits are keyed by the domain name.
+const globalThrottleKey = ""
+
+// throttle enforces the configured mail rate limits. Mails that exceed a limit are held back until the limit
+// allows sending again, so bulk sends are spread over time instead of being rejected by the SMTP provider.
+type throttle struct {
+	mux    sync.Mutex
+	limits map[string]int         // messages per window, keyed by domain
+	sent   map[string][]time.Time // send times within the current window, keyed by domain
+
+	now func() time.Time
+}
+
+func newThrottle(cfg config.MailConfig) *throttle {
+	t := &throttle{
+		limits: make(map[string]int),
+		sent:   make(map[string][]time.Time),
+		now:    time.Now,
+	}
+
+	if cfg.RateLimit > 0 {
+		t.limits[globalThrottleKey] = cfg.RateLimit
+	}
+	for domain, limit := range cfg.DomainRateLimits {
+		if limit > 0 {
+			t.limits[strings.ToLower(domain)] = limit
+		}
+	}
+
+	return t
+}
+
+// Wait blocks until a mail to the given recipients can be sent without exceeding a rate limit, or until the
+// context is done.
+func (t *throttle) Wait(ctx context.Context, recipients ...string) error {
+	if len(t.limits) == 0 {
+		return nil
+	}
+
+	keys := t.limitKeys(recipients)
+	for {
+		delay := t.reserve(keys)
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// limitKeys returns the keys of all limits that apply to a mail with the given recipients.
+func (t *throttle) limitKeys(recipients []string) []string {
+	keys := make([]string, 0, len(recipients)+1)
+	if _, ok := t.limits[globalThrottleKey]; ok {
+		keys = append(keys, globalThrottleKey)
+	}
+
+	for _, recipient := range recipients {
+		at := strings.LastIndex(recipient, "@")
+		if at < 0 {
+			continue
+		}
+		domain := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(recipient[at+1:]), ">"))
+		if _, ok := t.limits[domain]; !ok {
+			continue
+		}
+		if !slices.Contains(keys, domain) {
+			keys = append(keys, domain)
+		}
+	}
+
+	return keys
+}
+
+// reserve records a sent mail for all given keys if none of the limits is exceeded. Otherwise, nothing is recorded
+// and the time until the next attempt is returned.
+func (t *throttle) reserve(keys []string) time.Duration {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	now := t.now()
+	var delay time.Duration
+	for _, key := range keys {
+		sent := t.sent[key]
+		for len(sent) > 0 && !sent[0].After(now.Add(-throttleWindow)) {
+			sent = sent[1:]
+		}
+		t.sent[key] = sent
+
+		if len(sent) >= t.limits[key] {
+			delay = max(delay, sent[0].Add(throttleWindow).Sub(now))
+		}
+	}
+	if delay > 0 {
+		return delay
+	}
+
+	for _, key := range keys {
+		t.sent[key] = append(t.sent[key], now)
+	}
+
+	return 0
+}
@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/h44z/wg-portal/internal/config"
+)
+
+func TestThrottle_reserve(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	th := newThrottle(config.MailConfig{
+		RateLimit:        3,
+		DomainRateLimits: map[string]int{"Example.com": 1},
+	})
+	th.now = func() time.Time { return now }
+
+	keys := th.limitKeys([]string{"alice@example.com", "Bob <bob@EXAMPLE.com>", "carol@example.org"})
+	assert.Equal(t, []string{globalThrottleKey, "example.com"}, keys)
+
+	assert.Zero(t, th.reserve(keys))
+	assert.Equal(t, time.Minute, th.reserve(keys), "the domain limit is exceeded")
+
+	other := th.limitKeys([]string{"carol@example.org"})
+	assert.Zero(t, th.reserve(other))
+	assert.Zero(t, th.reserve(other))
+	assert.Equal(t, time.Minute, th.reserve(other), "the global limit is exceeded")
+
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, 30*time.Second, th.reserve(keys))
+
+	now = now.Add(30 * time.Second)
+	assert.Zero(t, th.reserve(keys), "sent mails leave the window")
+}
+
+func TestThrottle_Wait(t *testing.T) {
+	th := newThrottle(config.MailConfig{})
+	assert.NoError(t, th.Wait(context.Background(), "alice@example.com"), "no limits configured")
+
+	th = newThrottle(config.MailConfig{RateLimit: 1})
+	assert.NoError(t, th.Wait(context.Background(), "alice@example.com"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, th.Wait(ctx, "alice@example.com"), context.Canceled)
+}
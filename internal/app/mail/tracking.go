@@ -0,0 +1,93 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// trackingPath is the path of the public tracking endpoints, relative to the external URL.
+const trackingPath = "/api/v0/mail-tracking/"
+
+// newMailTracking returns the portal link, the tracking pixel URL and the tracking token for a new configuration
+// mail. If mail tracking is disabled, the plain portal link is returned and the tracking pixel and token are empty.
+func (m Manager) newMailTracking() (link, pixel, token string, err error) {
+	if !m.mailConfig().Tracking {
+		return m.cfg.Web.ExternalUrl, "", "", nil
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+
+	baseUrl := m.cfg.Web.ExternalUrl + trackingPath + token
+	return baseUrl + "/click", baseUrl + "/open", token, nil
+}
+
+// TrackMailOpened records that the tracking pixel of the mail with the given token was loaded. Only the first time
+// is recorded. The tracking endpoints are public, so unknown tokens are only logged.
+func (m Manager) TrackMailOpened(ctx context.Context, token string) error {
+	return m.trackMail(ctx, token, func(entry *domain.MailLogEntry, now time.Time) bool {
+		if entry.OpenedAt != nil {
+			return false
+		}
+		entry.OpenedAt = &now
+		return true
+	})
+}
+
+// TrackMailClicked records that the tracked link of the mail with the given token was used and returns the portal
+// URL the user should be redirected to. Using the link implies that the mail was opened, even if the tracking pixel
+// was blocked by the mail client.
+func (m Manager) TrackMailClicked(ctx context.Context, token string) (string, error) {
+	err := m.trackMail(ctx, token, func(entry *domain.MailLogEntry, now time.Time) bool {
+		if entry.ClickedAt != nil {
+			return false
+		}
+		entry.ClickedAt = &now
+		if entry.OpenedAt == nil {
+			entry.OpenedAt = &now
+		}
+		return true
+	})
+
+	return m.cfg.Web.ExternalUrl, err
+}
+
+// trackMail loads the mail log entry of the given token and stores it if the update function changed it.
+func (m Manager) trackMail(
+	ctx context.Context,
+	token string,
+	updateFunc func(entry *domain.MailLogEntry, now time.Time) bool,
+) error {
+	if token == "" {
+		return domain.ErrNotFound
+	}
+
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	entry, err := m.mailLog.GetMailLogEntryByTrackingToken(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to load tracked mail: %w", err)
+	}
+
+	if !updateFunc(entry, time.Now()) {
+		return nil // already recorded
+	}
+
+	if err := m.mailLog.SaveMailLogEntry(ctx, entry); err != nil {
+		return fmt.Errorf("failed to update tracked mail %d: %w", entry.UniqueId, err)
+	}
+
+	slog.Debug("tracked mail updated", "mail", entry.UniqueId, "peer", entry.PeerIdentifier,
+		"opened", entry.OpenedAt != nil, "clicked", entry.ClickedAt != nil)
+
+	return nil
+}
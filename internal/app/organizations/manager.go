@@ -0,0 +1,165 @@
+package organizations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetOrganization returns the organization with the given identifier
+	GetOrganization(ctx context.Context, id domain.OrganizationIdentifier) (*domain.Organization, error)
+	// GetAllOrganizations returns all organizations
+	GetAllOrganizations(ctx context.Context) ([]domain.Organization, error)
+	// SaveOrganization creates or updates the organization with the given identifier
+	SaveOrganization(
+		ctx context.Context,
+		id domain.OrganizationIdentifier,
+		updateFunc func(o *domain.Organization) (*domain.Organization, error),
+	) error
+	// DeleteOrganization deletes the organization with the given identifier
+	DeleteOrganization(ctx context.Context, id domain.OrganizationIdentifier) error
+	// IsOrganizationInUse returns true if interfaces or users belong to the given organization
+	IsOrganizationInUse(ctx context.Context, id domain.OrganizationIdentifier) (bool, error)
+}
+
+// endregion dependencies
+
+// Manager manages organizations. Organizations separate the interfaces, users and peers of different tenants.
+// Only global administrators, which do not belong to an organization, can manage organizations.
+type Manager struct {
+	cfg *config.Config
+
+	db DatabaseRepo
+}
+
+// NewOrganizationManager creates a new organization manager instance.
+func NewOrganizationManager(cfg *config.Config, db DatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+
+		db: db,
+	}
+
+	return m, nil
+}
+
+// GetAllOrganizations returns all organizations.
+func (m Manager) GetAllOrganizations(ctx context.Context) ([]domain.Organization, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetAllOrganizations(ctx)
+}
+
+// GetOrganization returns the organization with the given identifier.
+// Members of the organization are allowed to read it, for example to load the branding settings.
+func (m Manager) GetOrganization(ctx context.Context, id domain.OrganizationIdentifier) (
+	*domain.Organization,
+	error,
+) {
+	if id == "" {
+		return nil, domain.ErrNotFound
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetOrganization(ctx, id)
+}
+
+// CreateOrganization creates a new organization.
+func (m Manager) CreateOrganization(ctx context.Context, organization *domain.Organization) (
+	*domain.Organization,
+	error,
+) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := organization.Validate(); err != nil {
+		return nil, errors.Join(fmt.Errorf("invalid organization: %w", err), domain.ErrInvalidData)
+	}
+
+	existingOrganization, err := m.db.GetOrganization(ctx, organization.Identifier)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("unable to load existing organization %s: %w", organization.Identifier, err)
+	}
+	if existingOrganization != nil {
+		return nil, errors.Join(fmt.Errorf("organization %s already exists", organization.Identifier),
+			domain.ErrDuplicateEntry)
+	}
+
+	err = m.db.SaveOrganization(ctx, organization.Identifier,
+		func(o *domain.Organization) (*domain.Organization, error) {
+			organization.BaseModel = o.BaseModel
+			return organization, nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("creation failure: %w", err)
+	}
+
+	return organization, nil
+}
+
+// UpdateOrganization updates the given organization.
+func (m Manager) UpdateOrganization(ctx context.Context, organization *domain.Organization) (
+	*domain.Organization,
+	error,
+) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := organization.Validate(); err != nil {
+		return nil, errors.Join(fmt.Errorf("invalid organization: %w", err), domain.ErrInvalidData)
+	}
+
+	if _, err := m.db.GetOrganization(ctx, organization.Identifier); err != nil {
+		return nil, fmt.Errorf("unable to load existing organization %s: %w", organization.Identifier, err)
+	}
+
+	err := m.db.SaveOrganization(ctx, organization.Identifier,
+		func(o *domain.Organization) (*domain.Organization, error) {
+			organization.BaseModel = o.BaseModel
+			return organization, nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("update failure: %w", err)
+	}
+
+	return organization, nil
+}
+
+// DeleteOrganization deletes the organization with the given identifier.
+// Organizations that still own interfaces or users cannot be deleted.
+func (m Manager) DeleteOrganization(ctx context.Context, id domain.OrganizationIdentifier) error {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	if _, err := m.db.GetOrganization(ctx, id); err != nil {
+		return fmt.Errorf("unable to find organization %s: %w", id, err)
+	}
+
+	inUse, err := m.db.IsOrganizationInUse(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to check usage of organization %s: %w", id, err)
+	}
+	if inUse {
+		return errors.Join(fmt.Errorf("organization %s still owns interfaces or users", id),
+			domain.ErrInvalidData)
+	}
+
+	if err := m.db.DeleteOrganization(ctx, id); err != nil {
+		return fmt.Errorf("deletion failure: %w", err)
+	}
+
+	return nil
+}
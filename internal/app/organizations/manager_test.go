@@ -0,0 +1,135 @@
+package organizations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	organizations map[domain.OrganizationIdentifier]domain.Organization
+	inUse         map[domain.OrganizationIdentifier]bool
+}
+
+func (f *fakeDatabase) GetOrganization(_ context.Context, id domain.OrganizationIdentifier) (
+	*domain.Organization,
+	error,
+) {
+	organization, ok := f.organizations[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &organization, nil
+}
+
+func (f *fakeDatabase) GetAllOrganizations(_ context.Context) ([]domain.Organization, error) {
+	var organizations []domain.Organization
+	for _, organization := range f.organizations {
+		organizations = append(organizations, organization)
+	}
+	return organizations, nil
+}
+
+func (f *fakeDatabase) SaveOrganization(
+	_ context.Context,
+	id domain.OrganizationIdentifier,
+	updateFunc func(o *domain.Organization) (*domain.Organization, error),
+) error {
+	organization, ok := f.organizations[id]
+	if !ok {
+		organization = domain.Organization{Identifier: id}
+	}
+	updated, err := updateFunc(&organization)
+	if err != nil {
+		return err
+	}
+	f.organizations[id] = *updated
+	return nil
+}
+
+func (f *fakeDatabase) DeleteOrganization(_ context.Context, id domain.OrganizationIdentifier) error {
+	delete(f.organizations, id)
+	return nil
+}
+
+func (f *fakeDatabase) IsOrganizationInUse(_ context.Context, id domain.OrganizationIdentifier) (bool, error) {
+	return f.inUse[id], nil
+}
+
+func newTestManager(t *testing.T) (*Manager, *fakeDatabase) {
+	db := &fakeDatabase{
+		organizations: map[domain.OrganizationIdentifier]domain.Organization{},
+		inUse:         map[domain.OrganizationIdentifier]bool{},
+	}
+	m, err := NewOrganizationManager(&config.Config{}, db)
+	require.NoError(t, err)
+
+	return m, db
+}
+
+func TestManager_CreateOrganization(t *testing.T) {
+	m, db := newTestManager(t)
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	orgAdminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{
+		Id:           "admin@acme.example.com",
+		IsAdmin:      true,
+		Organization: "acme",
+	})
+
+	_, err := m.CreateOrganization(orgAdminCtx, &domain.Organization{Identifier: "globex"})
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "organization admins must not create organizations")
+
+	_, err = m.CreateOrganization(adminCtx, &domain.Organization{Identifier: "Acme Corp"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, err = m.CreateOrganization(adminCtx, &domain.Organization{Identifier: "acme", CompanyName: "ACME"})
+	require.NoError(t, err)
+	assert.Equal(t, "ACME", db.organizations["acme"].CompanyName)
+
+	_, err = m.CreateOrganization(adminCtx, &domain.Organization{Identifier: "acme"})
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+
+	organization, err := m.GetOrganization(orgAdminCtx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "ACME", organization.CompanyName)
+
+	_, err = m.GetAllOrganizations(orgAdminCtx)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_GetOrganization(t *testing.T) {
+	m, db := newTestManager(t)
+	db.organizations["globex"] = domain.Organization{Identifier: "globex"}
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{
+		Id:           "user@acme.example.com",
+		Organization: "acme",
+	})
+
+	_, err := m.GetOrganization(userCtx, "globex")
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "other organizations must not be visible")
+
+	_, err = m.GetOrganization(userCtx, "")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestManager_DeleteOrganization(t *testing.T) {
+	m, db := newTestManager(t)
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	db.organizations["acme"] = domain.Organization{Identifier: "acme"}
+	db.inUse["acme"] = true
+
+	err := m.DeleteOrganization(adminCtx, "acme")
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "organizations in use must not be deleted")
+
+	db.inUse["acme"] = false
+	require.NoError(t, m.DeleteOrganization(adminCtx, "acme"))
+	assert.Empty(t, db.organizations)
+
+	err = m.DeleteOrganization(adminCtx, "acme")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
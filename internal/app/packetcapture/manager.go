@@ -0,0 +1,174 @@
+package packetcapture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+}
+
+type Capturer interface {
+	// Capture streams the packets of the given capture in pcap format to the writer until the context is done or the
+	// maximum size of the capture is reached. It returns the number of bytes that were written.
+	Capture(ctx context.Context, capture *domain.PacketCapture, w io.Writer) (int64, error)
+}
+
+type EventBus interface {
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
+}
+
+// endregion dependencies
+
+// Manager runs time-limited packet captures of the traffic of single peers on their WireGuard interface, for example
+// to debug connectivity issues. Only admins can capture packets, each capture is recorded as audit event.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db       DatabaseRepo
+	capturer Capturer
+
+	mux     *sync.Mutex
+	running map[domain.PeerIdentifier]struct{} // peers with a running capture
+}
+
+// NewPacketCaptureManager creates a new packet capture manager.
+func NewPacketCaptureManager(cfg *config.Config, bus EventBus, db DatabaseRepo, capturer Capturer) (*Manager, error) {
+	if cfg.PacketCapture.Enabled && cfg.PacketCapture.MaxDuration <= 0 {
+		return nil, fmt.Errorf("invalid packet capture max duration %s", cfg.PacketCapture.MaxDuration)
+	}
+
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:       db,
+		capturer: capturer,
+
+		mux:     &sync.Mutex{},
+		running: make(map[domain.PeerIdentifier]struct{}),
+	}
+
+	return m, nil
+}
+
+// CapturePeer captures the traffic of the given peer for the given duration and streams it in pcap format to the
+// writer. If no duration is given, the configured maximum duration is used. Only one capture per peer can run at a
+// time. Errors that are returned before the first write are safe to report to the client.
+func (m Manager) CapturePeer(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	duration time.Duration,
+	w io.Writer,
+) error {
+	if !m.cfg.PacketCapture.Enabled {
+		return fmt.Errorf("packet captures are disabled: %w", domain.ErrNoPermission)
+	}
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	if duration == 0 {
+		duration = m.cfg.PacketCapture.MaxDuration
+	}
+	if duration < 0 || duration > m.cfg.PacketCapture.MaxDuration {
+		return fmt.Errorf("capture duration must be between 1s and %s: %w", m.cfg.PacketCapture.MaxDuration,
+			domain.ErrInvalidData)
+	}
+
+	peer, err := m.db.GetPeer(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to find peer %s: %w", id, err)
+	}
+	iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return fmt.Errorf("unable to find interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+		return err
+	}
+	if iface.IsDisabled() {
+		return fmt.Errorf("interface %s is disabled: %w", iface.Identifier, domain.ErrInvalidData)
+	}
+
+	capture, err := domain.NewPacketCapture(peer, duration, m.cfg.PacketCapture.MaxSize,
+		m.cfg.PacketCapture.SnapLength)
+	if err != nil {
+		return err
+	}
+	capture.StartedBy = domain.GetUserInfo(ctx).Id
+	capture.StartedAt = time.Now()
+
+	if !m.acquire(id) {
+		return errors.Join(fmt.Errorf("a capture of peer %s is already running", id), domain.ErrDuplicateEntry)
+	}
+	defer m.release(id)
+
+	slog.Info("starting packet capture", "peer", id, "interface", iface.Identifier, "duration", duration,
+		"user", capture.StartedBy)
+	m.publishAuditEvent(ctx, capture, "start", 0)
+
+	captureCtx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	written, err := m.capturer.Capture(captureCtx, capture, w)
+
+	slog.Info("finished packet capture", "peer", id, "interface", iface.Identifier, "bytes", written,
+		"error", err)
+	m.publishAuditEvent(ctx, capture, "finish", written)
+
+	return err
+}
+
+// acquire registers a running capture for the peer. It returns false if a capture is already running.
+func (m Manager) acquire(id domain.PeerIdentifier) bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if _, ok := m.running[id]; ok {
+		return false
+	}
+	m.running[id] = struct{}{}
+
+	return true
+}
+
+func (m Manager) release(id domain.PeerIdentifier) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.running, id)
+}
+
+func (m Manager) publishAuditEvent(ctx context.Context, capture *domain.PacketCapture, action string, bytes int64) {
+	event := audit.PacketCaptureEvent{
+		Capture: *capture,
+		Action:  action,
+		Bytes:   bytes,
+	}
+	if client := domain.GetClientInfo(ctx); client != nil {
+		event.Client = *client
+	}
+
+	m.bus.Publish(app.TopicAuditPacketCapture, domain.AuditEventWrapper[audit.PacketCaptureEvent]{
+		Ctx:   ctx,
+		Event: event,
+	})
+}
@@ -0,0 +1,146 @@
+package packetcapture
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	peer  domain.Peer
+	iface domain.Interface
+}
+
+func (f *fakeDatabase) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	if id != f.peer.Identifier {
+		return nil, domain.ErrNotFound
+	}
+	return &f.peer, nil
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, _ domain.InterfaceIdentifier) (*domain.Interface, error) {
+	return &f.iface, nil
+}
+
+type fakeCapturer struct {
+	captures []domain.PacketCapture
+	deadline time.Duration
+	block    chan struct{} // if set, the capture runs until the channel is closed
+}
+
+func (f *fakeCapturer) Capture(ctx context.Context, capture *domain.PacketCapture, w io.Writer) (int64, error) {
+	f.captures = append(f.captures, *capture)
+	if deadline, ok := ctx.Deadline(); ok {
+		f.deadline = time.Until(deadline)
+	}
+	if f.block != nil {
+		<-f.block
+	}
+	n, err := w.Write([]byte("pcap"))
+	return int64(n), err
+}
+
+type fakeBus struct {
+	events []audit.PacketCaptureEvent
+}
+
+func (f *fakeBus) Publish(topic string, args ...any) {
+	if topic == app.TopicAuditPacketCapture {
+		f.events = append(f.events, args[0].(domain.AuditEventWrapper[audit.PacketCaptureEvent]).Event)
+	}
+}
+
+func newTestManager(t *testing.T, enabled bool) (*Manager, *fakeCapturer, *fakeBus) {
+	cfg := &config.Config{}
+	cfg.PacketCapture = config.PacketCaptureConfig{Enabled: enabled, MaxDuration: time.Minute, MaxSize: 1024}
+
+	address, err := domain.CidrFromString("10.11.12.2/24")
+	require.NoError(t, err)
+	db := &fakeDatabase{
+		peer: domain.Peer{
+			Identifier:          "peer-1",
+			InterfaceIdentifier: "wg0",
+			Interface:           domain.PeerInterfaceConfig{Type: domain.InterfaceTypeClient, Addresses: []domain.Cidr{address}},
+		},
+		iface: domain.Interface{Identifier: "wg0", Type: domain.InterfaceTypeServer},
+	}
+	capturer := &fakeCapturer{}
+	bus := &fakeBus{}
+
+	m, err := NewPacketCaptureManager(cfg, bus, db, capturer)
+	require.NoError(t, err)
+	return m, capturer, bus
+}
+
+func adminContext() context.Context {
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+	return domain.SetClientInfo(ctx, domain.ClientInfo{IpAddress: "198.51.100.7"})
+}
+
+func TestManager_CapturePeer(t *testing.T) {
+	m, capturer, bus := newTestManager(t, true)
+
+	var out bytes.Buffer
+	require.NoError(t, m.CapturePeer(adminContext(), "peer-1", 0, &out))
+	assert.Equal(t, "pcap", out.String())
+
+	require.Len(t, capturer.captures, 1)
+	assert.Equal(t, "host 10.11.12.2", capturer.captures[0].Filter())
+	assert.Equal(t, int64(1024), capturer.captures[0].MaxSize)
+	assert.Equal(t, domain.UserIdentifier("admin"), capturer.captures[0].StartedBy)
+	assert.InDelta(t, time.Minute, capturer.deadline, float64(time.Second), "the maximum duration is the default")
+
+	require.Len(t, bus.events, 2)
+	assert.Equal(t, "start", bus.events[0].Action)
+	assert.Equal(t, "198.51.100.7", bus.events[0].Client.IpAddress)
+	assert.Equal(t, "finish", bus.events[1].Action)
+	assert.Equal(t, int64(4), bus.events[1].Bytes)
+}
+
+func TestManager_CapturePeer_Validation(t *testing.T) {
+	m, capturer, _ := newTestManager(t, true)
+
+	assert.ErrorIs(t, m.CapturePeer(adminContext(), "peer-1", 2*time.Minute, io.Discard), domain.ErrInvalidData)
+	assert.ErrorIs(t, m.CapturePeer(adminContext(), "missing", time.Second, io.Discard), domain.ErrNotFound)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user"})
+	assert.ErrorIs(t, m.CapturePeer(userCtx, "peer-1", time.Second, io.Discard), domain.ErrNoPermission)
+
+	disabled, _, _ := newTestManager(t, false)
+	assert.ErrorIs(t, disabled.CapturePeer(adminContext(), "peer-1", time.Second, io.Discard), domain.ErrNoPermission)
+
+	assert.Empty(t, capturer.captures)
+}
+
+func TestManager_CapturePeer_Concurrent(t *testing.T) {
+	m, capturer, _ := newTestManager(t, true)
+	capturer.block = make(chan struct{})
+
+	done := make(chan error)
+	go func() {
+		done <- m.CapturePeer(adminContext(), "peer-1", time.Second, io.Discard)
+	}()
+
+	require.Eventually(t, func() bool {
+		m.mux.Lock()
+		defer m.mux.Unlock()
+		return len(m.running) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	err := m.CapturePeer(adminContext(), "peer-1", time.Second, io.Discard)
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+
+	close(capturer.block)
+	require.NoError(t, <-done)
+	assert.Empty(t, m.running)
+}
@@ -0,0 +1,539 @@
+package peertransfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetUser returns the user with the given identifier.
+	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetPeerTransfer returns the peer transfer with the given identifier.
+	GetPeerTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (*domain.PeerTransfer, error)
+	// GetPeerTransfers returns all peer transfers, the most recent transfers first.
+	GetPeerTransfers(ctx context.Context) ([]domain.PeerTransfer, error)
+	// SavePeerTransfer creates or updates the given peer transfer.
+	SavePeerTransfer(ctx context.Context, transfer *domain.PeerTransfer) error
+	// UpdatePeerTransferPeer moves all transfers of a peer to the new peer identifier.
+	UpdatePeerTransferPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error
+}
+
+type PeerManager interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+}
+
+type MailManager interface {
+	// SendPeerTransferNotification informs the given user about the current state of a peer transfer.
+	SendPeerTransferNotification(ctx context.Context, userId domain.UserIdentifier, transfer *domain.PeerTransfer) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager handles requests to transfer the ownership of a peer to another user. A transfer is completed once the
+// target user, and if configured an admin, accepted it. On completion, the keys of the peer are rotated and both
+// users are notified by mail.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db    DatabaseRepo
+	peers PeerManager
+	mail  MailManager
+}
+
+// NewPeerTransferManager creates a new peer transfer manager.
+func NewPeerTransferManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	peers PeerManager,
+	mail MailManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:    db,
+		peers: peers,
+		mail:  mail,
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background jobs for the peer transfer manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.PeerTransfer.Enabled {
+		return
+	}
+
+	go m.runExpiryCheck(ctx)
+
+	slog.Debug("started peer transfer expiry checks")
+}
+
+func (m Manager) connectToMessageBus() {
+	// Transfers of deleted peers are not cancelled on the peer deleted event, as the event is also published if the
+	// identifier of a peer changes. Transfers of missing peers are cancelled on the next acceptance attempt instead.
+	_ = m.bus.Subscribe(app.TopicPeerIdentifierUpdated, m.handlePeerIdentifierUpdatedEvent)
+}
+
+func (m Manager) handlePeerIdentifierUpdatedEvent(oldId, newId domain.PeerIdentifier) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	if err := m.db.UpdatePeerTransferPeer(ctx, oldId, newId); err != nil {
+		slog.Error("failed to migrate peer transfers", "oldIdentifier", oldId, "newIdentifier", newId,
+			"error", err)
+	}
+}
+
+func (m Manager) runExpiryCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		if err := m.expireTransfers(ctx, time.Now()); err != nil {
+			slog.Error("failed to expire peer transfers", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-time.After(m.cfg.Advanced.ExpiryCheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+	}
+}
+
+// expireTransfers resolves all open transfers that were not accepted in time and informs the requesting users.
+func (m Manager) expireTransfers(ctx context.Context, now time.Time) error {
+	transfers, err := m.db.GetPeerTransfers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load peer transfers: %w", err)
+	}
+
+	for _, transfer := range transfers {
+		if !transfer.IsExpired(now) {
+			continue
+		}
+
+		transfer.Resolve(domain.PeerTransferExpired, domain.CtxSystemAdminId, now)
+		if err := m.db.SavePeerTransfer(ctx, &transfer); err != nil {
+			return fmt.Errorf("failed to expire transfer %s: %w", transfer.Identifier, err)
+		}
+		m.notify(ctx, &transfer, transfer.FromUser)
+	}
+
+	return nil
+}
+
+func (m Manager) checkEnabled() error {
+	if !m.cfg.PeerTransfer.Enabled {
+		return fmt.Errorf("peer transfers are disabled: %w", domain.ErrNoPermission)
+	}
+
+	return nil
+}
+
+// GetTransfers returns all transfers the current user is part of. Admins receive all transfers of the interfaces
+// they administrate.
+func (m Manager) GetTransfers(ctx context.Context) ([]domain.PeerTransfer, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	transfers, err := m.db.GetPeerTransfers(domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer transfers: %w", err)
+	}
+
+	sessionUser := domain.GetUserInfo(ctx)
+	visible := make([]domain.PeerTransfer, 0, len(transfers))
+	for _, transfer := range transfers {
+		if transfer.IsParticipant(sessionUser.Id) || m.isInterfaceAdmin(ctx, transfer.InterfaceIdentifier) {
+			visible = append(visible, transfer)
+		}
+	}
+
+	return visible, nil
+}
+
+// RequestTransfer requests the transfer of the given peer to the target user. Only the owner of the peer and
+// admins can request a transfer. If the transfer is requested by an admin, the admin approval is granted
+// implicitly.
+func (m Manager) RequestTransfer(
+	ctx context.Context,
+	peerId domain.PeerIdentifier,
+	toUser domain.UserIdentifier,
+	message string,
+) (*domain.PeerTransfer, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	peer, err := m.peers.GetPeer(ctx, peerId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer %s: %w", peerId, err)
+	}
+
+	iface, err := m.getInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier, iface.OrganizationIdentifier,
+		iface.Identifier); err != nil {
+		return nil, err
+	}
+
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+	if err := m.validateTarget(systemCtx, peer, toUser); err != nil {
+		return nil, err
+	}
+
+	transfers, err := m.db.GetPeerTransfers(systemCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer transfers: %w", err)
+	}
+	for _, transfer := range transfers {
+		if transfer.PeerId == peer.Identifier && transfer.IsOpen() {
+			return nil, fmt.Errorf("peer %s already has an open transfer: %w", peer.Identifier,
+				domain.ErrDuplicateEntry)
+		}
+	}
+
+	sessionUser := domain.GetUserInfo(ctx)
+	now := time.Now()
+	transfer := &domain.PeerTransfer{
+		Identifier:          domain.PeerTransferIdentifier(uuid.New().String()),
+		PeerId:              peer.Identifier,
+		PeerName:            peer.DisplayName,
+		InterfaceIdentifier: peer.InterfaceIdentifier,
+		FromUser:            peer.UserIdentifier,
+		ToUser:              toUser,
+		Message:             message,
+		State:               domain.PeerTransferPending,
+		RequestedBy:         sessionUser.Id,
+		RequestedAt:         now,
+		ExpiresAt:           now.Add(m.cfg.PeerTransfer.RequestTimeout),
+		RequiresApproval:    m.cfg.PeerTransfer.RequireAdminApproval,
+	}
+	if transfer.RequiresApproval && sessionUser.IsInterfaceAdmin(iface) {
+		transfer.ApprovedBy = sessionUser.Id
+		transfer.ApprovedAt = &now
+	}
+
+	if err := m.db.SavePeerTransfer(systemCtx, transfer); err != nil {
+		return nil, fmt.Errorf("failed to store peer transfer: %w", err)
+	}
+
+	slog.Info("requested peer transfer", "transfer", transfer.Identifier, "peer", peer.Identifier,
+		"from", transfer.FromUser, "to", transfer.ToUser)
+	m.notify(systemCtx, transfer, transfer.ToUser)
+
+	return transfer, nil
+}
+
+// validateTarget checks that the given user is allowed to receive the peer.
+func (m Manager) validateTarget(ctx context.Context, peer *domain.Peer, toUser domain.UserIdentifier) error {
+	if toUser == "" || toUser == peer.UserIdentifier {
+		return fmt.Errorf("invalid target user %s: %w", toUser, domain.ErrInvalidData)
+	}
+
+	user, err := m.db.GetUser(ctx, toUser)
+	if err != nil {
+		return fmt.Errorf("failed to load target user %s: %w", toUser, err)
+	}
+	if user.IsDisabled() {
+		return fmt.Errorf("target user %s is disabled: %w", toUser, domain.ErrInvalidData)
+	}
+
+	iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to load interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+	if iface.OrganizationIdentifier != "" && iface.OrganizationIdentifier != user.OrganizationIdentifier {
+		return fmt.Errorf("target user %s is not a member of organization %s: %w", toUser,
+			iface.OrganizationIdentifier, domain.ErrInvalidData)
+	}
+
+	return nil
+}
+
+// AcceptTransfer accepts the given transfer. Only the target user can accept a transfer. The transfer is completed
+// immediately unless an admin approval is still missing.
+func (m Manager) AcceptTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (
+	*domain.PeerTransfer,
+	error,
+) {
+	transfer, err := m.getOpenTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if domain.GetUserInfo(ctx).Id != transfer.ToUser {
+		return nil, domain.ErrNoPermission
+	}
+
+	now := time.Now()
+	transfer.AcceptedAt = &now
+
+	return m.completeOrSave(ctx, transfer)
+}
+
+// ApproveTransfer grants the admin approval for the given transfer. The transfer is completed immediately if the
+// target user already accepted it.
+func (m Manager) ApproveTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (
+	*domain.PeerTransfer,
+	error,
+) {
+	transfer, err := m.getOpenTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.validateInterfaceAdmin(ctx, transfer.InterfaceIdentifier); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	transfer.ApprovedBy = domain.GetUserInfo(ctx).Id
+	transfer.ApprovedAt = &now
+
+	return m.completeOrSave(ctx, transfer)
+}
+
+// RejectTransfer rejects the given transfer. Transfers can be rejected by the target user and by admins.
+func (m Manager) RejectTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (
+	*domain.PeerTransfer,
+	error,
+) {
+	transfer, err := m.getOpenTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionUser := domain.GetUserInfo(ctx)
+	if sessionUser.Id != transfer.ToUser && !m.isInterfaceAdmin(ctx, transfer.InterfaceIdentifier) {
+		return nil, domain.ErrNoPermission
+	}
+
+	return m.resolve(ctx, transfer, domain.PeerTransferRejected, transfer.FromUser)
+}
+
+// CancelTransfer withdraws the given transfer. Transfers can be cancelled by the previous owner, the requesting
+// user and by admins.
+func (m Manager) CancelTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (
+	*domain.PeerTransfer,
+	error,
+) {
+	transfer, err := m.getOpenTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionUser := domain.GetUserInfo(ctx)
+	if sessionUser.Id != transfer.FromUser && sessionUser.Id != transfer.RequestedBy &&
+		!m.isInterfaceAdmin(ctx, transfer.InterfaceIdentifier) {
+		return nil, domain.ErrNoPermission
+	}
+
+	return m.resolve(ctx, transfer, domain.PeerTransferCancelled, transfer.ToUser)
+}
+
+// getOpenTransfer loads the given transfer and ensures that it is still waiting for acceptance. Expired transfers
+// are resolved on access, so they can no longer be accepted before the background job handled them.
+func (m Manager) getOpenTransfer(ctx context.Context, id domain.PeerTransferIdentifier) (
+	*domain.PeerTransfer,
+	error,
+) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+	transfer, err := m.db.GetPeerTransfer(systemCtx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer transfer %s: %w", id, err)
+	}
+
+	sessionUser := domain.GetUserInfo(ctx)
+	if !transfer.IsParticipant(sessionUser.Id) && sessionUser.Id != transfer.RequestedBy &&
+		!m.isInterfaceAdmin(ctx, transfer.InterfaceIdentifier) {
+		return nil, domain.ErrNoPermission
+	}
+
+	if transfer.IsExpired(time.Now()) {
+		if _, err := m.resolve(systemCtx, transfer, domain.PeerTransferExpired, transfer.FromUser); err != nil {
+			return nil, err
+		}
+	}
+	if !transfer.IsOpen() {
+		return nil, fmt.Errorf("peer transfer %s is %s: %w", id, transfer.State, domain.ErrInvalidData)
+	}
+
+	return transfer, nil
+}
+
+// resolve sets the final state of the transfer and informs the given user.
+func (m Manager) resolve(
+	ctx context.Context,
+	transfer *domain.PeerTransfer,
+	state domain.PeerTransferState,
+	notifyUser domain.UserIdentifier,
+) (*domain.PeerTransfer, error) {
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	transfer.Resolve(state, domain.GetUserInfo(ctx).Id, time.Now())
+	if err := m.db.SavePeerTransfer(systemCtx, transfer); err != nil {
+		return nil, fmt.Errorf("failed to store peer transfer: %w", err)
+	}
+
+	slog.Info("resolved peer transfer", "transfer", transfer.Identifier, "peer", transfer.PeerId,
+		"state", transfer.State, "by", transfer.ResolvedBy)
+	m.notify(systemCtx, transfer, notifyUser)
+
+	return transfer, nil
+}
+
+// completeOrSave completes the transfer if all required parties accepted it, otherwise the updated transfer is
+// stored.
+func (m Manager) completeOrSave(ctx context.Context, transfer *domain.PeerTransfer) (*domain.PeerTransfer, error) {
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	if !transfer.IsAccepted() {
+		if err := m.db.SavePeerTransfer(systemCtx, transfer); err != nil {
+			return nil, fmt.Errorf("failed to store peer transfer: %w", err)
+		}
+		return transfer, nil
+	}
+
+	if err := m.complete(ctx, transfer); err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// complete moves the peer to the target user and rotates its keys. The transfer is marked as completed before the
+// peer is updated, so the identifier change of the peer is applied to the completed transfer.
+func (m Manager) complete(ctx context.Context, transfer *domain.PeerTransfer) error {
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	peer, err := m.peers.GetPeer(systemCtx, transfer.PeerId)
+	if errors.Is(err, domain.ErrNotFound) {
+		_, _ = m.resolve(ctx, transfer, domain.PeerTransferCancelled, transfer.FromUser)
+		return fmt.Errorf("peer %s of transfer %s no longer exists: %w", transfer.PeerId, transfer.Identifier,
+			domain.ErrInvalidData)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load peer %s: %w", transfer.PeerId, err)
+	}
+
+	// the target user might have been disabled or moved since the transfer was requested
+	if err := m.validateTarget(systemCtx, peer, transfer.ToUser); err != nil {
+		return err
+	}
+
+	keyPair, err := domain.NewFreshKeypair()
+	if err != nil {
+		return fmt.Errorf("failed to generate keys: %w", err)
+	}
+	peer.Interface.KeyPair = keyPair
+	if peer.PresharedKey != "" {
+		peer.PresharedKey, err = domain.NewPreSharedKey()
+		if err != nil {
+			return fmt.Errorf("failed to generate pre-shared key: %w", err)
+		}
+	}
+	peer.UserIdentifier = transfer.ToUser
+
+	pending := *transfer
+	transfer.Resolve(domain.PeerTransferCompleted, domain.GetUserInfo(ctx).Id, time.Now())
+	if err := m.db.SavePeerTransfer(systemCtx, transfer); err != nil {
+		return fmt.Errorf("failed to store peer transfer: %w", err)
+	}
+
+	if _, err := m.peers.UpdatePeer(systemCtx, peer); err != nil {
+		*transfer = pending
+		if err := m.db.SavePeerTransfer(systemCtx, transfer); err != nil {
+			slog.Error("failed to restore peer transfer", "transfer", transfer.Identifier, "error", err)
+		}
+		return fmt.Errorf("failed to transfer peer %s: %w", peer.Identifier, err)
+	}
+	transfer.PeerId = domain.PeerIdentifier(keyPair.PublicKey)
+
+	slog.Info("completed peer transfer", "transfer", transfer.Identifier, "peer", transfer.PeerId,
+		"from", transfer.FromUser, "to", transfer.ToUser)
+	m.notify(systemCtx, transfer, transfer.FromUser)
+	m.notify(systemCtx, transfer, transfer.ToUser)
+
+	return nil
+}
+
+// getInterface loads the interface with the given identifier regardless of the access rights of the current user.
+func (m Manager) getInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	iface, err := m.db.GetInterface(domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo()), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load interface %s: %w", id, err)
+	}
+
+	return iface, nil
+}
+
+// isInterfaceAdmin returns true if the current user administrates the interface with the given identifier.
+func (m Manager) isInterfaceAdmin(ctx context.Context, id domain.InterfaceIdentifier) bool {
+	iface, err := m.getInterface(ctx, id)
+	if err != nil {
+		return false
+	}
+
+	return domain.GetUserInfo(ctx).IsInterfaceAdmin(iface)
+}
+
+// validateInterfaceAdmin checks if the current user administrates the interface with the given identifier. Admins
+// of an organization can only administrate the interfaces of their organization.
+func (m Manager) validateInterfaceAdmin(ctx context.Context, id domain.InterfaceIdentifier) error {
+	iface, err := m.getInterface(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return domain.ValidateInterfaceAdminAccessRights(ctx, iface)
+}
+
+// notify sends the transfer notification mail to the given user. Failures are only logged, as the transfer itself
+// was processed successfully.
+func (m Manager) notify(ctx context.Context, transfer *domain.PeerTransfer, userId domain.UserIdentifier) {
+	if userId == "" {
+		return // peers without owner have nobody to notify
+	}
+
+	if err := m.mail.SendPeerTransferNotification(ctx, userId, transfer); err != nil {
+		slog.Warn("failed to send peer transfer notification", "transfer", transfer.Identifier, "user", userId,
+			"error", err)
+	}
+}
@@ -0,0 +1,234 @@
+package peertransfer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	users        map[domain.UserIdentifier]domain.User
+	transfers    map[domain.PeerTransferIdentifier]domain.PeerTransfer
+	organization domain.OrganizationIdentifier // the organization of all interfaces
+}
+
+func (f *fakeDatabase) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	return &domain.Interface{Identifier: id, OrganizationIdentifier: f.organization}, nil
+}
+
+func (f *fakeDatabase) GetPeerTransfer(_ context.Context, id domain.PeerTransferIdentifier) (
+	*domain.PeerTransfer,
+	error,
+) {
+	transfer, ok := f.transfers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &transfer, nil
+}
+
+func (f *fakeDatabase) GetPeerTransfers(_ context.Context) ([]domain.PeerTransfer, error) {
+	transfers := make([]domain.PeerTransfer, 0, len(f.transfers))
+	for _, transfer := range f.transfers {
+		transfers = append(transfers, transfer)
+	}
+	return transfers, nil
+}
+
+func (f *fakeDatabase) SavePeerTransfer(_ context.Context, transfer *domain.PeerTransfer) error {
+	f.transfers[transfer.Identifier] = *transfer
+	return nil
+}
+
+func (f *fakeDatabase) UpdatePeerTransferPeer(_ context.Context, oldId, newId domain.PeerIdentifier) error {
+	for id, transfer := range f.transfers {
+		if transfer.PeerId == oldId {
+			transfer.PeerId = newId
+			f.transfers[id] = transfer
+		}
+	}
+	return nil
+}
+
+type fakePeerManager struct {
+	peers map[domain.PeerIdentifier]domain.Peer
+}
+
+func (f *fakePeerManager) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &peer, nil
+}
+
+func (f *fakePeerManager) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	delete(f.peers, peer.Identifier)
+	peer.Identifier = domain.PeerIdentifier(peer.Interface.PublicKey)
+	f.peers[peer.Identifier] = *peer
+	return peer, nil
+}
+
+type fakeMailManager struct {
+	sent []domain.UserIdentifier
+}
+
+func (f *fakeMailManager) SendPeerTransferNotification(
+	_ context.Context,
+	userId domain.UserIdentifier,
+	_ *domain.PeerTransfer,
+) error {
+	f.sent = append(f.sent, userId)
+	return nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func newTestManager(t *testing.T, requireApproval bool) (*Manager, *fakePeerManager, *fakeMailManager) {
+	cfg := &config.Config{}
+	cfg.PeerTransfer.Enabled = true
+	cfg.PeerTransfer.RequireAdminApproval = requireApproval
+	cfg.PeerTransfer.RequestTimeout = 24 * time.Hour
+
+	db := &fakeDatabase{
+		users: map[domain.UserIdentifier]domain.User{
+			"alice": {Identifier: "alice"},
+			"bob":   {Identifier: "bob"},
+		},
+		transfers: map[domain.PeerTransferIdentifier]domain.PeerTransfer{},
+	}
+	peers := &fakePeerManager{peers: map[domain.PeerIdentifier]domain.Peer{
+		"old-key": {
+			Identifier:          "old-key",
+			InterfaceIdentifier: "wg0",
+			UserIdentifier:      "alice",
+			PresharedKey:        "old-psk",
+			Interface:           domain.PeerInterfaceConfig{KeyPair: domain.KeyPair{PublicKey: "old-key"}},
+		},
+	}}
+	mail := &fakeMailManager{}
+
+	m, err := NewPeerTransferManager(cfg, fakeBus{}, db, peers, mail)
+	require.NoError(t, err)
+
+	return m, peers, mail
+}
+
+func userContext(id domain.UserIdentifier, admin bool) context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: id, IsAdmin: admin})
+}
+
+func TestManager_AcceptTransfer(t *testing.T) {
+	m, peers, mail := newTestManager(t, false)
+
+	_, err := m.RequestTransfer(userContext("bob", false), "old-key", "bob", "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "only the owner can request a transfer")
+
+	transfer, err := m.RequestTransfer(userContext("alice", false), "old-key", "bob", "new laptop")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserIdentifier{"bob"}, mail.sent)
+
+	_, err = m.RequestTransfer(userContext("alice", false), "old-key", "bob", "")
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+
+	_, err = m.AcceptTransfer(userContext("alice", false), transfer.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "only the target user can accept")
+
+	transfer, err = m.AcceptTransfer(userContext("bob", false), transfer.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerTransferCompleted, transfer.State)
+	assert.ElementsMatch(t, []domain.UserIdentifier{"bob", "alice", "bob"}, mail.sent)
+
+	require.Len(t, peers.peers, 1)
+	peer, err := peers.GetPeer(context.Background(), transfer.PeerId)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserIdentifier("bob"), peer.UserIdentifier)
+	assert.NotEqual(t, domain.PeerIdentifier("old-key"), peer.Identifier, "the keys are rotated")
+	assert.NotEqual(t, domain.PreSharedKey("old-psk"), peer.PresharedKey)
+}
+
+func TestManager_ApproveTransfer(t *testing.T) {
+	m, peers, _ := newTestManager(t, true)
+
+	transfer, err := m.RequestTransfer(userContext("alice", false), "old-key", "bob", "")
+	require.NoError(t, err)
+
+	transfer, err = m.AcceptTransfer(userContext("bob", false), transfer.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerTransferPending, transfer.State, "the admin approval is missing")
+	assert.Contains(t, peers.peers, domain.PeerIdentifier("old-key"))
+
+	_, err = m.ApproveTransfer(userContext("bob", false), transfer.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	transfer, err = m.ApproveTransfer(userContext("admin", true), transfer.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerTransferCompleted, transfer.State)
+	assert.NotContains(t, peers.peers, domain.PeerIdentifier("old-key"))
+}
+
+func TestManager_ApproveTransfer_otherOrganization(t *testing.T) {
+	m, peers, _ := newTestManager(t, true)
+	db := m.db.(*fakeDatabase)
+	db.organization = "org-a"
+	for id, user := range db.users {
+		user.OrganizationIdentifier = "org-a"
+		db.users[id] = user
+	}
+
+	orgAdminCtx := func(org domain.OrganizationIdentifier) context.Context {
+		return domain.SetUserInfo(context.Background(),
+			&domain.ContextUserInfo{Id: "org-admin", IsAdmin: true, Organization: org})
+	}
+
+	_, err := m.RequestTransfer(orgAdminCtx("org-b"), "old-key", "bob", "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "admins of other organizations cannot request transfers")
+
+	transfer, err := m.RequestTransfer(userContext("alice", false), "old-key", "bob", "")
+	require.NoError(t, err)
+	transfer, err = m.AcceptTransfer(userContext("bob", false), transfer.Identifier)
+	require.NoError(t, err)
+
+	_, err = m.ApproveTransfer(orgAdminCtx("org-b"), transfer.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "admins of other organizations cannot approve transfers")
+	_, err = m.RejectTransfer(orgAdminCtx("org-b"), transfer.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	transfers, err := m.GetTransfers(orgAdminCtx("org-b"))
+	require.NoError(t, err)
+	assert.Empty(t, transfers)
+
+	transfer, err = m.ApproveTransfer(orgAdminCtx("org-a"), transfer.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerTransferCompleted, transfer.State)
+	assert.NotContains(t, peers.peers, domain.PeerIdentifier("old-key"))
+}
+
+func TestManager_expireTransfers(t *testing.T) {
+	m, _, mail := newTestManager(t, false)
+
+	transfer, err := m.RequestTransfer(userContext("alice", false), "old-key", "bob", "")
+	require.NoError(t, err)
+
+	require.NoError(t, m.expireTransfers(userContext("admin", true), time.Now().Add(48*time.Hour)))
+	assert.Equal(t, []domain.UserIdentifier{"bob", "alice"}, mail.sent)
+
+	_, err = m.AcceptTransfer(userContext("bob", false), transfer.Identifier)
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
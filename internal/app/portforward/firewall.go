@@ -0,0 +1,57 @@
+package portforward
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// forwardRule is a port forward with the resolved tunnel address of the peer.
+type forwardRule struct {
+	iface        domain.InterfaceIdentifier
+	protocol     domain.PortForwardProtocol
+	externalPort int
+	target       netip.Addr
+	targetPort   int
+}
+
+// firewallRuleset returns the nftables script that forwards the external ports to the peers. Forwarded connections
+// are masqueraded, so the peers answer through the tunnel even if they do not route all traffic through it.
+// The script replaces an existing table.
+func firewallRuleset(table string, rules []forwardRule) string {
+	var sb strings.Builder
+
+	// declaring the table before deleting it ensures that the delete command succeeds if the table does not exist
+	fmt.Fprintf(&sb, "table inet %s\ndelete table inet %s\n", table, table)
+	fmt.Fprintf(&sb, "table inet %s {\n", table)
+	sb.WriteString("\tchain prerouting {\n\t\ttype nat hook prerouting priority dstnat; policy accept;\n")
+	var interfaces []domain.InterfaceIdentifier
+	for _, rule := range rules {
+		if rule.target.Is4() {
+			fmt.Fprintf(&sb, "\t\tmeta nfproto ipv4 %s dport %d dnat ip to %s:%d\n", rule.protocol,
+				rule.externalPort, rule.target, rule.targetPort)
+		} else {
+			fmt.Fprintf(&sb, "\t\tmeta nfproto ipv6 %s dport %d dnat ip6 to [%s]:%d\n", rule.protocol,
+				rule.externalPort, rule.target, rule.targetPort)
+		}
+		if !slices.Contains(interfaces, rule.iface) {
+			interfaces = append(interfaces, rule.iface)
+		}
+	}
+	sb.WriteString("\t}\n")
+	sb.WriteString("\tchain postrouting {\n\t\ttype nat hook postrouting priority srcnat; policy accept;\n")
+	for _, iface := range interfaces {
+		fmt.Fprintf(&sb, "\t\toifname %q ct status dnat masquerade\n", iface)
+	}
+	sb.WriteString("\t}\n}\n")
+
+	return sb.String()
+}
+
+// removeRuleset returns the nftables script that removes the table, if it exists.
+func removeRuleset(table string) string {
+	return fmt.Sprintf("table inet %s\ndelete table inet %s\n", table, table)
+}
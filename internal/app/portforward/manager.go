@@ -0,0 +1,541 @@
+package portforward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetPortForward returns the port forward with the given identifier.
+	GetPortForward(ctx context.Context, id domain.PortForwardIdentifier) (*domain.PortForward, error)
+	// GetPortForwards returns all port forwards.
+	GetPortForwards(ctx context.Context) ([]domain.PortForward, error)
+	// SavePortForward creates or updates the given port forward.
+	SavePortForward(ctx context.Context, forward *domain.PortForward) error
+	// DeletePortForward deletes the port forward with the given identifier.
+	DeletePortForward(ctx context.Context, id domain.PortForwardIdentifier) error
+	// UpdatePortForwardPeer moves all port forwards of a peer to the new peer identifier.
+	UpdatePortForwardPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+}
+
+type FirewallRepo interface {
+	// ApplyRuleset applies the given nftables script in a single transaction.
+	ApplyRuleset(ctx context.Context, ruleset string) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager manages the inbound port forwards to the tunnel addresses of peers. The forwards are applied as nftables
+// NAT rules. Forwards of disabled or missing peers are kept, but not applied.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db       DatabaseRepo
+	firewall FirewallRepo
+
+	mux   *sync.Mutex // serializes the conflict checks and the creation of port forwards
+	state *forwardState
+}
+
+type forwardState struct {
+	mux     sync.Mutex
+	ruleset string // the currently applied ruleset, empty if no ruleset was applied
+}
+
+// NewPortForwardManager creates a new port forward manager instance.
+func NewPortForwardManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	firewall FirewallRepo,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:       db,
+		firewall: firewall,
+
+		mux:   &sync.Mutex{},
+		state: &forwardState{},
+	}
+
+	if !cfg.PortForwarding.Enabled {
+		return m, nil
+	}
+
+	if cfg.PortForwarding.PortRangeStart < 1 || cfg.PortForwarding.PortRangeEnd > 65535 ||
+		cfg.PortForwarding.PortRangeStart > cfg.PortForwarding.PortRangeEnd {
+		return nil, fmt.Errorf("invalid port forwarding port range %d-%d", cfg.PortForwarding.PortRangeStart,
+			cfg.PortForwarding.PortRangeEnd)
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	// Forwards of deleted peers are not removed on the peer deleted event, as the event is also published if the
+	// identifier of a peer changes. Forwards of missing peers are not applied and can be deleted by admins.
+	_ = m.bus.Subscribe(app.TopicPeerIdentifierUpdated, m.handlePeerIdentifierUpdatedEvent)
+	_ = m.bus.Subscribe(app.TopicPeerCreated, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicPeerUpdated, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceUpdated, m.handleInterfaceEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceEvent)
+}
+
+// StartBackgroundJobs applies the port forwards and removes them on shutdown.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.PortForwarding.Enabled {
+		return
+	}
+
+	m.refresh(ctx)
+
+	go func() {
+		<-ctx.Done()
+
+		m.state.mux.Lock()
+		defer m.state.mux.Unlock()
+
+		if m.state.ruleset == "" {
+			return
+		}
+		// the context is already canceled, use a fresh context for the cleanup
+		if err := m.firewall.ApplyRuleset(context.Background(),
+			removeRuleset(m.cfg.PortForwarding.FirewallTable)); err != nil {
+			slog.Error("failed to remove port forwarding firewall rules", "error", err)
+		}
+		m.state.ruleset = ""
+	}()
+}
+
+func (m Manager) handlePeerIdentifierUpdatedEvent(oldId, newId domain.PeerIdentifier) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	if err := m.db.UpdatePortForwardPeer(ctx, oldId, newId); err != nil {
+		slog.Error("failed to migrate port forwards", "oldIdentifier", oldId, "newIdentifier", newId,
+			"error", err)
+	}
+
+	m.refresh(ctx)
+}
+
+func (m Manager) handlePeerEvent(peer domain.Peer) {
+	slog.Debug("handling peer event", "peer", peer.Identifier)
+
+	m.refresh(domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo()))
+}
+
+func (m Manager) handleInterfaceEvent(iface domain.Interface) {
+	slog.Debug("handling interface event", "interface", iface.Identifier)
+
+	m.refresh(domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo()))
+}
+
+// GetAllPortForwards returns all port forwards. Only admins can list all port forwards, admins of an organization
+// only receive the port forwards to the interfaces of their organization.
+func (m Manager) GetAllPortForwards(ctx context.Context) ([]domain.PortForward, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	forwards, err := m.db.GetPortForwards(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load port forwards: %w", err)
+	}
+	currentUser := domain.GetUserInfo(ctx)
+	if currentUser.IsGlobalAdmin() {
+		return forwards, nil
+	}
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load interfaces: %w", err)
+	}
+	organizations := make(map[domain.InterfaceIdentifier]domain.OrganizationIdentifier, len(interfaces))
+	for _, iface := range interfaces {
+		organizations[iface.Identifier] = iface.OrganizationIdentifier
+	}
+
+	var result []domain.PortForward
+	for _, forward := range forwards {
+		org, ok := organizations[forward.InterfaceIdentifier]
+		if ok && currentUser.CanAccessOrganization(org) {
+			result = append(result, forward)
+		}
+	}
+
+	return result, nil
+}
+
+// GetPeerPortForwards returns the port forwards of the given peer.
+func (m Manager) GetPeerPortForwards(ctx context.Context, id domain.PeerIdentifier) ([]domain.PortForward, error) {
+	peer, err := m.db.GetPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find peer %s: %w", id, err)
+	}
+	iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+	if err := domain.ValidatePeerAccessRights(ctx, peer, iface); err != nil {
+		return nil, err
+	}
+	if err := validatePeerOrganization(ctx, peer, iface); err != nil {
+		return nil, err
+	}
+
+	forwards, err := m.db.GetPortForwards(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load port forwards: %w", err)
+	}
+
+	var result []domain.PortForward
+	for _, forward := range forwards {
+		if forward.PeerId == id {
+			result = append(result, forward)
+		}
+	}
+
+	return result, nil
+}
+
+// CreatePortForward creates a new port forward to the peer of the given port forward.
+func (m Manager) CreatePortForward(ctx context.Context, forward *domain.PortForward) (*domain.PortForward, error) {
+	if !m.cfg.PortForwarding.Enabled {
+		return nil, fmt.Errorf("port forwarding is disabled: %w", domain.ErrNoPermission)
+	}
+
+	peer, err := m.db.GetPeer(ctx, forward.PeerId)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find peer %s: %w", forward.PeerId, err)
+	}
+	isManager, err := m.validateManageAccess(ctx, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := forward.Validate(); err != nil {
+		return nil, errors.Join(fmt.Errorf("invalid port forward: %w", err), domain.ErrInvalidData)
+	}
+	if forward.ExternalPort < m.cfg.PortForwarding.PortRangeStart ||
+		forward.ExternalPort > m.cfg.PortForwarding.PortRangeEnd {
+		return nil, errors.Join(fmt.Errorf("external port %d is outside of the allowed range %d-%d",
+			forward.ExternalPort, m.cfg.PortForwarding.PortRangeStart, m.cfg.PortForwarding.PortRangeEnd),
+			domain.ErrInvalidData)
+	}
+	if !peer.PortForwardTarget().IsValid() {
+		return nil, errors.Join(fmt.Errorf("peer %s has no tunnel address", peer.Identifier),
+			domain.ErrInvalidData)
+	}
+
+	forward.Identifier = domain.PortForwardIdentifier(uuid.New().String())
+	forward.InterfaceIdentifier = peer.InterfaceIdentifier
+	forward.CreatedBy = domain.GetUserInfo(ctx).Id
+	forward.CreatedAt = time.Now()
+
+	if err := m.checkAndSave(ctx, forward, peer, !isManager); err != nil {
+		return nil, err
+	}
+	slog.Info("created port forward",
+		"peer", forward.PeerId,
+		"protocol", forward.Protocol,
+		"externalPort", forward.ExternalPort,
+		"targetPort", forward.TargetPort)
+
+	m.refresh(ctx)
+
+	return forward, nil
+}
+
+// DeletePortForward deletes the port forward with the given identifier.
+func (m Manager) DeletePortForward(ctx context.Context, id domain.PortForwardIdentifier) error {
+	forward, err := m.db.GetPortForward(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to find port forward %s: %w", id, err)
+	}
+
+	peer, err := m.db.GetPeer(ctx, forward.PeerId)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		// forwards of deleted peers are cleaned up by admins
+		if err := m.validateInterfaceAdmin(ctx, forward.InterfaceIdentifier); err != nil {
+			return err
+		}
+	case err != nil:
+		return fmt.Errorf("unable to find peer %s: %w", forward.PeerId, err)
+	default:
+		if _, err := m.validateManageAccess(ctx, peer); err != nil {
+			return err
+		}
+	}
+
+	if err := m.db.DeletePortForward(ctx, id); err != nil {
+		return fmt.Errorf("deletion failure: %w", err)
+	}
+
+	m.refresh(ctx)
+
+	return nil
+}
+
+// validateManageAccess checks if the current user may manage the port forwards of the peer. It returns true if the
+// user is an admin of the peer, who is not limited by the self-service settings.
+func (m Manager) validateManageAccess(ctx context.Context, peer *domain.Peer) (bool, error) {
+	iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return false, fmt.Errorf("unable to find interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+	if err := validatePeerOrganization(ctx, peer, iface); err != nil {
+		return false, err
+	}
+
+	sessionUser := domain.GetUserInfo(ctx)
+
+	if sessionUser.IsAdmin || sessionUser.IsInterfaceAdmin(iface) {
+		if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier, iface.OrganizationIdentifier,
+			iface.Identifier); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if m.cfg.PortForwarding.SelfService && sessionUser.Id == peer.UserIdentifier {
+		return false, nil
+	}
+
+	return false, domain.ErrNoPermission
+}
+
+// validateInterfaceAdmin checks if the current user administrates the interface with the given identifier. If the
+// interface no longer exists, only global admins are allowed.
+func (m Manager) validateInterfaceAdmin(ctx context.Context, id domain.InterfaceIdentifier) error {
+	iface, err := m.db.GetInterface(ctx, id)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return domain.ValidateGlobalAdminAccessRights(ctx)
+	case err != nil:
+		return fmt.Errorf("unable to find interface %s: %w", id, err)
+	}
+
+	return domain.ValidateInterfaceAdminAccessRights(ctx, iface)
+}
+
+// validatePeerOrganization checks if the current user is allowed to access the given peer of the given interface.
+// Users can always access their own peers, all other access is restricted to the organization of the interface.
+func validatePeerOrganization(ctx context.Context, peer *domain.Peer, iface *domain.Interface) error {
+	if domain.GetUserInfo(ctx).Id == peer.UserIdentifier {
+		return nil
+	}
+
+	return domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier)
+}
+
+// checkAndSave checks the given port forward for conflicts and, if requested, the limit of the owner of the peer,
+// before it is stored. The checks and the creation are serialized, so that concurrent requests cannot claim the
+// same external port or exceed the limit.
+func (m Manager) checkAndSave(
+	ctx context.Context,
+	forward *domain.PortForward,
+	peer *domain.Peer,
+	checkLimit bool,
+) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	forwards, err := m.db.GetPortForwards(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load port forwards: %w", err)
+	}
+	if err := m.checkConflicts(ctx, forward, forwards); err != nil {
+		return err
+	}
+	if checkLimit {
+		if err := m.checkUserLimit(ctx, peer.UserIdentifier, forwards); err != nil {
+			return err
+		}
+	}
+
+	if err := m.db.SavePortForward(ctx, forward); err != nil {
+		return fmt.Errorf("creation failure: %w", err)
+	}
+
+	return nil
+}
+
+// checkConflicts ensures that the external port is neither used by another port forward nor by WireGuard Portal.
+func (m Manager) checkConflicts(ctx context.Context, forward *domain.PortForward, forwards []domain.PortForward) error {
+	for _, existing := range forwards {
+		if forward.ConflictsWith(existing) {
+			return errors.Join(fmt.Errorf("%s port %d is already forwarded to peer %s", forward.Protocol,
+				forward.ExternalPort, existing.PeerId), domain.ErrDuplicateEntry)
+		}
+	}
+
+	if forward.Protocol == domain.PortForwardUdp {
+		interfaces, err := m.db.GetAllInterfaces(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load interfaces: %w", err)
+		}
+		for _, iface := range interfaces {
+			if iface.ListenPort == forward.ExternalPort {
+				return errors.Join(fmt.Errorf("udp port %d is the listen port of interface %s",
+					forward.ExternalPort, iface.Identifier), domain.ErrDuplicateEntry)
+			}
+		}
+		if m.cfg.PortKnocking.Enabled && listeningPort(m.cfg.PortKnocking.ListeningAddress) == forward.ExternalPort {
+			return errors.Join(fmt.Errorf("udp port %d is the port knocking port", forward.ExternalPort),
+				domain.ErrDuplicateEntry)
+		}
+	}
+
+	if forward.Protocol == domain.PortForwardTcp && listeningPort(m.cfg.Web.ListeningAddress) == forward.ExternalPort {
+		return errors.Join(fmt.Errorf("tcp port %d is the port of the web interface", forward.ExternalPort),
+			domain.ErrDuplicateEntry)
+	}
+
+	return nil
+}
+
+// checkUserLimit ensures that the user does not exceed the maximum number of port forwards to the peers of the user.
+func (m Manager) checkUserLimit(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	forwards []domain.PortForward,
+) error {
+	if m.cfg.PortForwarding.MaxPerUser <= 0 {
+		return nil
+	}
+
+	count := 0
+	for _, forward := range forwards {
+		peer, err := m.db.GetPeer(ctx, forward.PeerId)
+		if err != nil {
+			continue // forwards of missing peers are not counted
+		}
+		if peer.UserIdentifier == userId {
+			count++
+		}
+	}
+
+	if count >= m.cfg.PortForwarding.MaxPerUser {
+		return errors.Join(fmt.Errorf("user %s already has %d port forwards", userId, count),
+			domain.ErrInvalidData)
+	}
+
+	return nil
+}
+
+// refresh rebuilds the firewall rules from the port forwards of all enabled peers, if they changed.
+func (m Manager) refresh(ctx context.Context) {
+	if err := m.ApplyState(ctx); err != nil {
+		slog.Error("failed to refresh port forwards", "error", err)
+	}
+}
+
+// ApplyState applies the firewall rules of the port forwards of all enabled peers. The rules are only replaced if
+// they changed since they were applied the last time.
+func (m Manager) ApplyState(ctx context.Context) error {
+	if !m.cfg.PortForwarding.Enabled {
+		return nil
+	}
+
+	forwards, err := m.db.GetPortForwards(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load port forwards: %w", err)
+	}
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces for port forwarding: %w", err)
+	}
+	disabledInterfaces := make(map[domain.InterfaceIdentifier]bool, len(interfaces))
+	for _, iface := range interfaces {
+		disabledInterfaces[iface.Identifier] = iface.IsDisabled()
+	}
+
+	var rules []forwardRule
+	for _, forward := range forwards {
+		peer, err := m.db.GetPeer(ctx, forward.PeerId)
+		if err != nil {
+			continue // the peer was deleted, or is currently re-identified
+		}
+		disabled, ok := disabledInterfaces[peer.InterfaceIdentifier]
+		if !ok || disabled || peer.IsDisabled() {
+			continue
+		}
+		target := peer.PortForwardTarget()
+		if !target.IsValid() {
+			continue
+		}
+		rules = append(rules, forwardRule{
+			iface:        peer.InterfaceIdentifier,
+			protocol:     forward.Protocol,
+			externalPort: forward.ExternalPort,
+			target:       target,
+			targetPort:   forward.TargetPort,
+		})
+	}
+
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	ruleset := ""
+	if len(rules) > 0 {
+		ruleset = firewallRuleset(m.cfg.PortForwarding.FirewallTable, rules)
+	}
+	if ruleset == m.state.ruleset {
+		return nil // nothing changed
+	}
+
+	apply := ruleset
+	if apply == "" {
+		apply = removeRuleset(m.cfg.PortForwarding.FirewallTable)
+	}
+	if err := m.firewall.ApplyRuleset(ctx, apply); err != nil {
+		return fmt.Errorf("failed to apply port forwarding firewall rules: %w", err)
+	}
+	m.state.ruleset = ruleset
+	slog.Debug("applied port forwarding firewall rules", "forwards", len(rules))
+
+	return nil
+}
+
+// listeningPort returns the port of a listening address like ":8888", or 0 if the address has no valid port.
+func listeningPort(address string) int {
+	_, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return port
+}
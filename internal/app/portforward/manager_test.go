@@ -0,0 +1,339 @@
+package portforward
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	mux        sync.Mutex    // guards the forwards
+	delay      time.Duration // delays the loading of the forwards, so that concurrent requests interleave
+	forwards   map[domain.PortForwardIdentifier]domain.PortForward
+	interfaces []domain.Interface
+	peers      map[domain.PeerIdentifier]domain.Peer
+}
+
+func (f *fakeDatabase) GetPortForward(_ context.Context, id domain.PortForwardIdentifier) (
+	*domain.PortForward,
+	error,
+) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	forward, ok := f.forwards[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &forward, nil
+}
+
+func (f *fakeDatabase) GetPortForwards(_ context.Context) ([]domain.PortForward, error) {
+	f.mux.Lock()
+	var forwards []domain.PortForward
+	for _, forward := range f.forwards {
+		forwards = append(forwards, forward)
+	}
+	f.mux.Unlock()
+
+	time.Sleep(f.delay)
+	return forwards, nil
+}
+
+func (f *fakeDatabase) SavePortForward(_ context.Context, forward *domain.PortForward) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.forwards[forward.Identifier] = *forward
+	return nil
+}
+
+func (f *fakeDatabase) DeletePortForward(_ context.Context, id domain.PortForwardIdentifier) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	delete(f.forwards, id)
+	return nil
+}
+
+func (f *fakeDatabase) UpdatePortForwardPeer(_ context.Context, oldId, newId domain.PeerIdentifier) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	for id, forward := range f.forwards {
+		if forward.PeerId == oldId {
+			forward.PeerId = newId
+			f.forwards[id] = forward
+		}
+	}
+	return nil
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	for _, iface := range f.interfaces {
+		if iface.Identifier == id {
+			return &iface, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeDatabase) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &peer, nil
+}
+
+type fakeFirewall struct {
+	rulesets []string
+}
+
+func (f *fakeFirewall) ApplyRuleset(_ context.Context, ruleset string) error {
+	f.rulesets = append(f.rulesets, ruleset)
+	return nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func testPeer(t *testing.T, id domain.PeerIdentifier, user domain.UserIdentifier, address string) domain.Peer {
+	addresses, err := domain.CidrsFromArray([]string{address})
+	require.NoError(t, err)
+
+	return domain.Peer{
+		Identifier:          id,
+		UserIdentifier:      user,
+		InterfaceIdentifier: "wg0",
+		Interface:           domain.PeerInterfaceConfig{Addresses: addresses},
+	}
+}
+
+func newTestManager(t *testing.T) (*Manager, *fakeDatabase, *fakeFirewall) {
+	cfg := &config.Config{}
+	cfg.Web.ListeningAddress = ":8888"
+	cfg.PortForwarding = config.PortForwardingConfig{
+		Enabled:        true,
+		SelfService:    true,
+		MaxPerUser:     1,
+		PortRangeStart: 1024,
+		PortRangeEnd:   65535,
+		FirewallTable:  "wg_portal_forward",
+	}
+
+	db := &fakeDatabase{
+		forwards: map[domain.PortForwardIdentifier]domain.PortForward{},
+		interfaces: []domain.Interface{
+			{Identifier: "wg0", Type: domain.InterfaceTypeServer, ListenPort: 51820},
+		},
+		peers: map[domain.PeerIdentifier]domain.Peer{
+			"peer-a": testPeer(t, "peer-a", "alice", "10.11.12.2/32"),
+			"peer-b": testPeer(t, "peer-b", "bob", "fd00::3/128"),
+		},
+	}
+	firewall := &fakeFirewall{}
+
+	m, err := NewPortForwardManager(cfg, fakeBus{}, db, firewall)
+	require.NoError(t, err)
+
+	return m, db, firewall
+}
+
+func TestFirewallRuleset(t *testing.T) {
+	peerA := testPeer(t, "peer-a", "alice", "10.11.12.2/32")
+	peerB := testPeer(t, "peer-b", "bob", "fd00::3/128")
+
+	ruleset := firewallRuleset("wg_portal_forward", []forwardRule{
+		{iface: "wg0", protocol: domain.PortForwardTcp, externalPort: 8080, target: peerA.PortForwardTarget(),
+			targetPort: 80},
+		{iface: "wg0", protocol: domain.PortForwardUdp, externalPort: 3478, target: peerB.PortForwardTarget(),
+			targetPort: 3478},
+	})
+
+	assert.Equal(t, "table inet wg_portal_forward\ndelete table inet wg_portal_forward\n"+
+		"table inet wg_portal_forward {\n"+
+		"\tchain prerouting {\n\t\ttype nat hook prerouting priority dstnat; policy accept;\n"+
+		"\t\tmeta nfproto ipv4 tcp dport 8080 dnat ip to 10.11.12.2:80\n"+
+		"\t\tmeta nfproto ipv6 udp dport 3478 dnat ip6 to [fd00::3]:3478\n"+
+		"\t}\n"+
+		"\tchain postrouting {\n\t\ttype nat hook postrouting priority srcnat; policy accept;\n"+
+		"\t\toifname \"wg0\" ct status dnat masquerade\n"+
+		"\t}\n}\n", ruleset)
+}
+
+func TestManager_CreatePortForward(t *testing.T) {
+	m, db, firewall := newTestManager(t)
+	aliceCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	bobCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "bob"})
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	forward, err := m.CreatePortForward(aliceCtx, &domain.PortForward{
+		PeerId: "peer-a", Protocol: domain.PortForwardTcp, ExternalPort: 8080, TargetPort: 80,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.InterfaceIdentifier("wg0"), forward.InterfaceIdentifier)
+	assert.Equal(t, domain.UserIdentifier("alice"), forward.CreatedBy)
+	assert.Len(t, db.forwards, 1)
+	require.Len(t, firewall.rulesets, 1)
+	assert.Contains(t, firewall.rulesets[0], "tcp dport 8080 dnat ip to 10.11.12.2:80")
+
+	// users only manage the forwards of their own peers
+	_, err = m.CreatePortForward(bobCtx, &domain.PortForward{
+		PeerId: "peer-a", Protocol: domain.PortForwardTcp, ExternalPort: 8081, TargetPort: 80,
+	})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	// the user limit is reached, admins are not limited
+	_, err = m.CreatePortForward(aliceCtx, &domain.PortForward{
+		PeerId: "peer-a", Protocol: domain.PortForwardTcp, ExternalPort: 8081, TargetPort: 81,
+	})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+	_, err = m.CreatePortForward(adminCtx, &domain.PortForward{
+		PeerId: "peer-a", Protocol: domain.PortForwardTcp, ExternalPort: 8081, TargetPort: 81,
+	})
+	assert.NoError(t, err)
+
+	// conflicts with other forwards and the ports of the portal
+	_, err = m.CreatePortForward(bobCtx, &domain.PortForward{
+		PeerId: "peer-b", Protocol: domain.PortForwardTcp, ExternalPort: 8080, TargetPort: 80,
+	})
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+	_, err = m.CreatePortForward(bobCtx, &domain.PortForward{
+		PeerId: "peer-b", Protocol: domain.PortForwardUdp, ExternalPort: 51820, TargetPort: 51820,
+	})
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+	_, err = m.CreatePortForward(bobCtx, &domain.PortForward{
+		PeerId: "peer-b", Protocol: domain.PortForwardTcp, ExternalPort: 8888, TargetPort: 80,
+	})
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+
+	// the same port with another protocol is fine, ports below the range are not
+	_, err = m.CreatePortForward(bobCtx, &domain.PortForward{
+		PeerId: "peer-b", Protocol: domain.PortForwardTcp, ExternalPort: 443, TargetPort: 443,
+	})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+	_, err = m.CreatePortForward(bobCtx, &domain.PortForward{
+		PeerId: "peer-b", Protocol: domain.PortForwardUdp, ExternalPort: 8080, TargetPort: 80,
+	})
+	assert.NoError(t, err)
+}
+
+func TestManager_CreatePortForward_concurrent(t *testing.T) {
+	m, db, _ := newTestManager(t)
+	db.delay = 10 * time.Millisecond
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = m.CreatePortForward(adminCtx, &domain.PortForward{
+				PeerId: "peer-a", Protocol: domain.PortForwardTcp, ExternalPort: 8080, TargetPort: 80,
+			})
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		if err == nil {
+			created++
+		} else {
+			assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+		}
+	}
+	assert.Equal(t, 1, created, "the external port is only forwarded once")
+	assert.Len(t, db.forwards, 1)
+}
+
+func TestManager_PortForwards_organization(t *testing.T) {
+	m, db, _ := newTestManager(t)
+	db.interfaces[0].OrganizationIdentifier = "org-a"
+	db.interfaces = append(db.interfaces,
+		domain.Interface{Identifier: "wg1", Type: domain.InterfaceTypeServer, OrganizationIdentifier: "org-b"})
+	peer := testPeer(t, "peer-c", "carol", "10.11.13.2/32")
+	peer.InterfaceIdentifier = "wg1"
+	db.peers[peer.Identifier] = peer
+
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	orgAdminCtx := domain.SetUserInfo(context.Background(),
+		&domain.ContextUserInfo{Id: "admin-a", IsAdmin: true, Organization: "org-a"})
+
+	forwardA, err := m.CreatePortForward(orgAdminCtx, &domain.PortForward{
+		PeerId: "peer-a", Protocol: domain.PortForwardTcp, ExternalPort: 8080, TargetPort: 80,
+	})
+	require.NoError(t, err)
+	forwardC, err := m.CreatePortForward(adminCtx, &domain.PortForward{
+		PeerId: "peer-c", Protocol: domain.PortForwardTcp, ExternalPort: 8081, TargetPort: 80,
+	})
+	require.NoError(t, err)
+
+	// admins of other organizations neither see nor manage the forwards
+	forwards, err := m.GetAllPortForwards(orgAdminCtx)
+	require.NoError(t, err)
+	require.Len(t, forwards, 1)
+	assert.Equal(t, forwardA.Identifier, forwards[0].Identifier)
+
+	_, err = m.GetPeerPortForwards(orgAdminCtx, "peer-c")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	_, err = m.CreatePortForward(orgAdminCtx, &domain.PortForward{
+		PeerId: "peer-c", Protocol: domain.PortForwardTcp, ExternalPort: 8082, TargetPort: 80,
+	})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	assert.ErrorIs(t, m.DeletePortForward(orgAdminCtx, forwardC.Identifier), domain.ErrNoPermission)
+
+	// forwards of deleted peers are only cleaned up by admins of the interface
+	delete(db.peers, "peer-c")
+	assert.ErrorIs(t, m.DeletePortForward(orgAdminCtx, forwardC.Identifier), domain.ErrNoPermission)
+	assert.NoError(t, m.DeletePortForward(adminCtx, forwardC.Identifier))
+
+	forwards, err = m.GetAllPortForwards(adminCtx)
+	require.NoError(t, err)
+	assert.Len(t, forwards, 1)
+}
+
+func TestManager_refresh(t *testing.T) {
+	m, db, firewall := newTestManager(t)
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	// without port forwards, the firewall is not touched
+	m.refresh(ctx)
+	assert.Empty(t, firewall.rulesets)
+
+	db.forwards["fwd-1"] = domain.PortForward{
+		Identifier: "fwd-1", PeerId: "peer-a", Protocol: domain.PortForwardTcp, ExternalPort: 8080, TargetPort: 80,
+	}
+	m.refresh(ctx)
+	require.Len(t, firewall.rulesets, 1)
+
+	// forwards of re-identified peers are migrated, the rules stay the same
+	peer := db.peers["peer-a"]
+	peer.Identifier = "peer-a2"
+	db.peers["peer-a2"] = peer
+	delete(db.peers, "peer-a")
+	m.handlePeerIdentifierUpdatedEvent("peer-a", "peer-a2")
+	assert.Equal(t, domain.PeerIdentifier("peer-a2"), db.forwards["fwd-1"].PeerId)
+	assert.Len(t, firewall.rulesets, 1)
+
+	// forwards of disabled peers are not applied
+	peer.Disabled = &peer.CreatedAt
+	db.peers["peer-a2"] = peer
+	m.refresh(ctx)
+	require.Len(t, firewall.rulesets, 2)
+	assert.Equal(t, removeRuleset("wg_portal_forward"), firewall.rulesets[1])
+}
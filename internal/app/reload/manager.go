@@ -0,0 +1,195 @@
+package reload
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+// ConfigLoader loads the configuration from the config file.
+type ConfigLoader func() (*config.Config, error)
+
+type SecretResolver interface {
+	// ResolveReloadedSecrets replaces the secret references of a reloaded config with the values of the secrets.
+	ResolveReloadedSecrets(ctx context.Context, cfg *config.Config) error
+}
+
+type EventBus interface {
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
+}
+
+// endregion dependencies
+
+// reloadableSections are the config sections that are applied without a restart.
+var reloadableSections = map[string]struct{}{
+	"mail": {},
+	"auth": {},
+}
+
+// reloadableAdvancedSettings are the settings of the advanced section that are applied without a restart.
+var reloadableAdvancedSettings = []string{"schedule_check_interval", "schedule_timezone"}
+
+// Manager reloads the configuration on SIGHUP or on request of an administrator. The reloaded config is published
+// to the message bus, the subscribers apply their settings without restarting the process. The WireGuard
+// interfaces are not touched by a reload.
+type Manager struct {
+	bus     EventBus
+	secrets SecretResolver
+	loader  ConfigLoader
+
+	state *reloadState
+}
+
+type reloadState struct {
+	mux     sync.Mutex    // serializes reloads
+	current config.Config // the config that was loaded last, with resolved secrets
+}
+
+// NewReloadManager creates a new config reload manager.
+func NewReloadManager(cfg *config.Config, bus EventBus, secrets SecretResolver, loader ConfigLoader) (
+	*Manager,
+	error,
+) {
+	m := &Manager{
+		bus:     bus,
+		secrets: secrets,
+		loader:  loader,
+
+		state: &reloadState{current: *cfg},
+	}
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts listening for SIGHUP signals, each signal reloads the config.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(signals)
+
+		ctx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				slog.Info("received SIGHUP, reloading config")
+				if _, err := m.Reload(ctx); err != nil {
+					slog.Error("failed to reload config", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Reload loads the config file again and publishes the reloaded config. If the config file is invalid, the current
+// config is kept. Only global administrators are allowed to reload the config.
+func (m Manager) Reload(ctx context.Context) (*domain.ConfigReload, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	cfg, err := m.loader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w: %w", err, domain.ErrInvalidData)
+	}
+	if err := m.secrets.ResolveReloadedSecrets(ctx, cfg); err != nil {
+		return nil, fmt.Errorf("failed to resolve secrets of config: %w", err)
+	}
+
+	result := &domain.ConfigReload{ReloadedAt: time.Now()}
+	result.Applied, result.RestartRequired = changedSections(&m.state.current, cfg)
+
+	m.state.current = *cfg
+	m.bus.Publish(app.TopicConfigReloaded, cfg)
+
+	slog.Info("reloaded config", "applied", result.Applied, "restartRequired", result.RestartRequired)
+	if len(result.RestartRequired) > 0 {
+		slog.Warn("changed config sections are applied on the next restart", "sections", result.RestartRequired)
+	}
+
+	return result, nil
+}
+
+// changedSections compares the config sections by their yaml names. Changed sections are either applied by the
+// reload or require a restart.
+func changedSections(old, new *config.Config) (applied, restartRequired []string) {
+	oldValue := reflect.ValueOf(old).Elem()
+	newValue := reflect.ValueOf(new).Elem()
+	for i := range oldValue.NumField() {
+		section := yamlName(oldValue.Type().Field(i))
+		if section == "" {
+			continue
+		}
+		if section == "advanced" {
+			a, r := changedSettings(section, oldValue.Field(i), newValue.Field(i), reloadableAdvancedSettings)
+			applied = append(applied, a...)
+			restartRequired = append(restartRequired, r...)
+			continue
+		}
+		if reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			continue
+		}
+
+		if _, ok := reloadableSections[section]; ok {
+			applied = append(applied, section)
+		} else {
+			restartRequired = append(restartRequired, section)
+		}
+	}
+
+	return applied, restartRequired
+}
+
+// changedSettings compares the settings of a config section. The section requires a restart if any setting that
+// is not reloadable was changed.
+func changedSettings(section string, old, new reflect.Value, reloadable []string) (applied, restartRequired []string) {
+	restart := false
+	for i := range old.NumField() {
+		setting := yamlName(old.Type().Field(i))
+		if reflect.DeepEqual(old.Field(i).Interface(), new.Field(i).Interface()) {
+			continue
+		}
+
+		if slices.Contains(reloadable, setting) {
+			applied = append(applied, section+"."+setting)
+		} else {
+			restart = true
+		}
+	}
+	if restart {
+		restartRequired = append(restartRequired, section)
+	}
+
+	return applied, restartRequired
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+
+	return name
+}
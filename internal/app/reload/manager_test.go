@@ -0,0 +1,107 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeSecrets struct{}
+
+func (fakeSecrets) ResolveReloadedSecrets(_ context.Context, cfg *config.Config) error {
+	if cfg.Mail.Password == "env://SMTP_PASSWORD" {
+		cfg.Mail.Password = "resolved"
+	}
+	return nil
+}
+
+type fakeBus struct {
+	reloaded []*config.Config
+}
+
+func (f *fakeBus) Publish(topic string, args ...any) {
+	if topic == app.TopicConfigReloaded {
+		f.reloaded = append(f.reloaded, args[0].(*config.Config))
+	}
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+}
+
+func TestManager_Reload(t *testing.T) {
+	current := &config.Config{}
+	current.Mail.Host = "smtp.example.com"
+	current.Advanced.ScheduleTimezone = "UTC"
+
+	next := *current
+	next.Mail.Host = "smtp2.example.com"
+	next.Mail.Password = "env://SMTP_PASSWORD"
+	next.Advanced.ScheduleTimezone = "Europe/Vienna"
+	next.Database.DSN = "data/other.db"
+
+	bus := &fakeBus{}
+	m, err := NewReloadManager(current, bus, fakeSecrets{}, func() (*config.Config, error) {
+		cfg := next
+		return &cfg, nil
+	})
+	require.NoError(t, err)
+
+	result, err := m.Reload(adminContext())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"advanced.schedule_timezone", "mail"}, result.Applied)
+	assert.Equal(t, []string{"database"}, result.RestartRequired)
+	assert.WithinDuration(t, time.Now(), result.ReloadedAt, time.Minute)
+
+	require.Len(t, bus.reloaded, 1)
+	assert.Equal(t, "resolved", bus.reloaded[0].Mail.Password)
+	assert.Equal(t, "smtp2.example.com", bus.reloaded[0].Mail.Host)
+
+	// reloading the same config again does not report any changes
+	result, err = m.Reload(adminContext())
+	require.NoError(t, err)
+	assert.Empty(t, result.Applied)
+	assert.Empty(t, result.RestartRequired)
+}
+
+func TestManager_Reload_InvalidConfig(t *testing.T) {
+	bus := &fakeBus{}
+	m, err := NewReloadManager(&config.Config{}, bus, fakeSecrets{}, func() (*config.Config, error) {
+		return nil, errors.New("yaml: line 3: mapping values are not allowed in this context")
+	})
+	require.NoError(t, err)
+
+	_, err = m.Reload(adminContext())
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+	assert.Empty(t, bus.reloaded)
+}
+
+func TestManager_Reload_NoPermission(t *testing.T) {
+	m, err := NewReloadManager(&config.Config{}, &fakeBus{}, fakeSecrets{}, func() (*config.Config, error) {
+		return &config.Config{}, nil
+	})
+	require.NoError(t, err)
+
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	_, err = m.Reload(ctx)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func Test_changedSections_AdvancedRestart(t *testing.T) {
+	old := &config.Config{}
+	new := &config.Config{}
+	new.Advanced.ScheduleCheckInterval = time.Minute
+	new.Advanced.StartListenPort = 51830
+
+	applied, restartRequired := changedSections(old, new)
+	assert.Equal(t, []string{"advanced.schedule_check_interval"}, applied)
+	assert.Equal(t, []string{"advanced"}, restartRequired)
+}
@@ -0,0 +1,248 @@
+package reservations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetInterfacePeers returns all peers for the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	// GetAddressReservation returns the address reservation with the given identifier.
+	GetAddressReservation(
+		ctx context.Context,
+		id domain.AddressReservationIdentifier,
+	) (*domain.AddressReservation, error)
+	// GetAddressReservations returns all address reservations of the given interface.
+	GetAddressReservations(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.AddressReservation, error)
+	// SaveAddressReservation creates or updates the given address reservation.
+	SaveAddressReservation(ctx context.Context, reservation *domain.AddressReservation) error
+	// DeleteAddressReservation deletes the address reservation with the given identifier.
+	DeleteAddressReservation(ctx context.Context, id domain.AddressReservationIdentifier) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager manages the address reservations of interfaces. Reserved addresses are held back for future peers or
+// statically assigned to a user, excluded addresses are never assigned to peers. The reservations are enforced by the
+// WireGuard manager when addresses are allocated and when peers are saved.
+type Manager struct {
+	bus EventBus
+
+	db DatabaseRepo
+}
+
+// NewReservationManager creates a new address reservation manager.
+func NewReservationManager(bus EventBus, db DatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		bus: bus,
+
+		db: db,
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceDeletedEvent)
+}
+
+func (m Manager) handleInterfaceDeletedEvent(iface domain.Interface) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	reservations, err := m.db.GetAddressReservations(ctx, iface.Identifier)
+	if err != nil {
+		slog.Error("failed to load address reservations of deleted interface",
+			"interface", iface.Identifier, "error", err)
+		return
+	}
+
+	for _, reservation := range reservations {
+		if err := m.db.DeleteAddressReservation(ctx, reservation.Identifier); err != nil {
+			slog.Error("failed to delete address reservation of deleted interface",
+				"interface", iface.Identifier, "reservation", reservation.Identifier, "error", err)
+		}
+	}
+}
+
+// GetReservations returns all address reservations of the given interface.
+func (m Manager) GetReservations(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+) ([]domain.AddressReservation, error) {
+	if _, err := m.validateInterfaceAccess(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetAddressReservations(ctx, id)
+}
+
+// CreateReservation creates a new address reservation.
+func (m Manager) CreateReservation(
+	ctx context.Context,
+	reservation *domain.AddressReservation,
+) (*domain.AddressReservation, error) {
+	iface, err := m.validateInterfaceAccess(ctx, reservation.InterfaceIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation.Identifier = domain.AddressReservationIdentifier(uuid.New().String())
+	if err := m.validateReservation(ctx, iface, reservation); err != nil {
+		return nil, err
+	}
+
+	reservation.CreatedBy = domain.GetUserInfo(ctx).Id
+	reservation.CreatedAt = time.Now()
+
+	if err := m.db.SaveAddressReservation(ctx, reservation); err != nil {
+		return nil, fmt.Errorf("creation failure: %w", err)
+	}
+	slog.Info("created address reservation",
+		"interface", reservation.InterfaceIdentifier,
+		"address", reservation.Address,
+		"kind", reservation.Kind,
+		"user", reservation.UserIdentifier)
+
+	return reservation, nil
+}
+
+// UpdateReservation updates the given address reservation. The interface of a reservation cannot be changed.
+func (m Manager) UpdateReservation(
+	ctx context.Context,
+	reservation *domain.AddressReservation,
+) (*domain.AddressReservation, error) {
+	existing, err := m.db.GetAddressReservation(ctx, reservation.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find address reservation %s: %w", reservation.Identifier, err)
+	}
+
+	iface, err := m.validateInterfaceAccess(ctx, existing.InterfaceIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation.InterfaceIdentifier = existing.InterfaceIdentifier
+	reservation.CreatedBy = existing.CreatedBy
+	reservation.CreatedAt = existing.CreatedAt
+	if err := m.validateReservation(ctx, iface, reservation); err != nil {
+		return nil, err
+	}
+
+	if err := m.db.SaveAddressReservation(ctx, reservation); err != nil {
+		return nil, fmt.Errorf("update failure: %w", err)
+	}
+
+	return reservation, nil
+}
+
+// DeleteReservation deletes the address reservation with the given identifier. The address is released and can be
+// assigned to new peers again.
+func (m Manager) DeleteReservation(ctx context.Context, id domain.AddressReservationIdentifier) error {
+	reservation, err := m.db.GetAddressReservation(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to find address reservation %s: %w", id, err)
+	}
+
+	if _, err := m.validateInterfaceAccess(ctx, reservation.InterfaceIdentifier); err != nil {
+		return err
+	}
+
+	if err := m.db.DeleteAddressReservation(ctx, id); err != nil {
+		return fmt.Errorf("deletion failure: %w", err)
+	}
+
+	return nil
+}
+
+// validateReservation checks that the reserved address is part of the peer networks of the interface, that it is not
+// reserved twice and that it is not used by a peer that would violate the reservation.
+func (m Manager) validateReservation(
+	ctx context.Context,
+	iface *domain.Interface,
+	reservation *domain.AddressReservation,
+) error {
+	if err := reservation.Validate(); err != nil {
+		return errors.Join(fmt.Errorf("invalid address reservation: %w", err), domain.ErrInvalidData)
+	}
+	addr := reservation.Addr()
+
+	if iface.PeerDefNetworkStr != "" {
+		networks, err := domain.CidrsFromString(iface.PeerDefNetworkStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse peer networks of interface %s: %w", iface.Identifier, err)
+		}
+
+		inNetwork := false
+		for _, network := range networks {
+			if network.Prefix().Contains(addr) {
+				inNetwork = true
+				break
+			}
+		}
+		if !inNetwork {
+			return fmt.Errorf("address %s is not part of the peer networks %s: %w", addr,
+				iface.PeerDefNetworkStr, domain.ErrInvalidData)
+		}
+	}
+
+	reservations, err := m.db.GetAddressReservations(ctx, iface.Identifier)
+	if err != nil {
+		return fmt.Errorf("failed to load address reservations: %w", err)
+	}
+	for _, other := range reservations {
+		if other.Identifier != reservation.Identifier && other.Addr() == addr {
+			return fmt.Errorf("address %s is already reserved: %w", addr, domain.ErrDuplicateEntry)
+		}
+	}
+
+	peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+	if err != nil {
+		return fmt.Errorf("failed to load peers of interface %s: %w", iface.Identifier, err)
+	}
+	for _, peer := range peers {
+		if len(domain.ReservationViolations(&peer, []domain.AddressReservation{*reservation}, nil)) != 0 {
+			return fmt.Errorf("address %s is used by peer %s: %w", addr, peer.DisplayName, domain.ErrInvalidData)
+		}
+	}
+
+	return nil
+}
+
+func (m Manager) validateInterfaceAccess(ctx context.Context, id domain.InterfaceIdentifier) (
+	*domain.Interface,
+	error,
+) {
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load interface %s: %w", id, err)
+	}
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return nil, err
+	}
+
+	if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
+
+	return iface, nil
+}
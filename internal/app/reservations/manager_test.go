@@ -0,0 +1,144 @@
+package reservations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	peers        []domain.Peer
+	reservations map[domain.AddressReservationIdentifier]domain.AddressReservation
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	if id != "wg0" {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.Interface{Identifier: id, PeerDefNetworkStr: "10.0.0.1/24"}, nil
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	return f.peers, nil
+}
+
+func (f *fakeDatabase) GetAddressReservation(_ context.Context, id domain.AddressReservationIdentifier) (
+	*domain.AddressReservation,
+	error,
+) {
+	reservation, ok := f.reservations[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &reservation, nil
+}
+
+func (f *fakeDatabase) GetAddressReservations(_ context.Context, id domain.InterfaceIdentifier) (
+	[]domain.AddressReservation,
+	error,
+) {
+	var reservations []domain.AddressReservation
+	for _, reservation := range f.reservations {
+		if reservation.InterfaceIdentifier == id {
+			reservations = append(reservations, reservation)
+		}
+	}
+	return reservations, nil
+}
+
+func (f *fakeDatabase) SaveAddressReservation(_ context.Context, reservation *domain.AddressReservation) error {
+	f.reservations[reservation.Identifier] = *reservation
+	return nil
+}
+
+func (f *fakeDatabase) DeleteAddressReservation(_ context.Context, id domain.AddressReservationIdentifier) error {
+	delete(f.reservations, id)
+	return nil
+}
+
+type fakeBus struct {
+	handlers map[string]interface{}
+}
+
+func (f *fakeBus) Subscribe(topic string, fn interface{}) error {
+	f.handlers[topic] = fn
+	return nil
+}
+
+func newTestManager(t *testing.T) (*Manager, *fakeDatabase, *fakeBus) {
+	address, err := domain.CidrFromString("10.0.0.2/32")
+	require.NoError(t, err)
+
+	db := &fakeDatabase{
+		peers: []domain.Peer{{Identifier: "peer1", DisplayName: "Alice Laptop", InterfaceIdentifier: "wg0",
+			UserIdentifier: "alice", Interface: domain.PeerInterfaceConfig{Addresses: []domain.Cidr{address}}}},
+		reservations: map[domain.AddressReservationIdentifier]domain.AddressReservation{},
+	}
+	bus := &fakeBus{handlers: map[string]interface{}{}}
+
+	m, err := NewReservationManager(bus, db)
+	require.NoError(t, err)
+
+	return m, db, bus
+}
+
+func TestManager_CreateReservation(t *testing.T) {
+	m, db, _ := newTestManager(t)
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+
+	_, err := m.CreateReservation(userCtx, &domain.AddressReservation{InterfaceIdentifier: "wg0",
+		Address: "10.0.0.10", Kind: domain.AddressReservationExcluded})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	reservation, err := m.CreateReservation(adminCtx, &domain.AddressReservation{InterfaceIdentifier: "wg0",
+		Address: "10.0.0.10", Kind: domain.AddressReservationExcluded, Description: "printer"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, reservation.Identifier)
+	assert.Len(t, db.reservations, 1)
+
+	_, err = m.CreateReservation(adminCtx, &domain.AddressReservation{InterfaceIdentifier: "wg0",
+		Address: "10.0.0.10", Kind: domain.AddressReservationReserved})
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+
+	_, err = m.CreateReservation(adminCtx, &domain.AddressReservation{InterfaceIdentifier: "wg0",
+		Address: "10.1.0.10", Kind: domain.AddressReservationReserved})
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "the address is outside of the peer network")
+
+	_, err = m.CreateReservation(adminCtx, &domain.AddressReservation{InterfaceIdentifier: "wg0",
+		Address: "10.0.0.2", Kind: domain.AddressReservationReserved, UserIdentifier: "bob"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "the address is used by a peer of another user")
+
+	_, err = m.CreateReservation(adminCtx, &domain.AddressReservation{InterfaceIdentifier: "wg0",
+		Address: "10.0.0.2", Kind: domain.AddressReservationReserved, UserIdentifier: "alice"})
+	assert.NoError(t, err, "the address is used by a peer of the assigned user")
+}
+
+func TestManager_UpdateReservation(t *testing.T) {
+	m, db, bus := newTestManager(t)
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	reservation, err := m.CreateReservation(adminCtx, &domain.AddressReservation{InterfaceIdentifier: "wg0",
+		Address: "10.0.0.10", Kind: domain.AddressReservationReserved})
+	require.NoError(t, err)
+
+	updated, err := m.UpdateReservation(adminCtx, &domain.AddressReservation{Identifier: reservation.Identifier,
+		InterfaceIdentifier: "wg1", Address: "10.0.0.11", Kind: domain.AddressReservationReserved,
+		UserIdentifier: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.InterfaceIdentifier("wg0"), updated.InterfaceIdentifier, "the interface is kept")
+	assert.Equal(t, reservation.CreatedAt, updated.CreatedAt)
+	assert.Equal(t, "10.0.0.11", db.reservations[reservation.Identifier].Address)
+
+	_, err = m.UpdateReservation(adminCtx, &domain.AddressReservation{Identifier: "missing",
+		Address: "10.0.0.12", Kind: domain.AddressReservationReserved})
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	bus.handlers[app.TopicInterfaceDeleted].(func(domain.Interface))(domain.Interface{Identifier: "wg0"})
+	assert.Empty(t, db.reservations, "reservations of deleted interfaces are removed")
+}
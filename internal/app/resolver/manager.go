@@ -0,0 +1,374 @@
+package resolver
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type InterfaceAndPeerDatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers for a given interface
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+const (
+	listenerCheckInterval = 30 * time.Second
+	upstreamTimeout       = 5 * time.Second
+	maxMessageSize        = 65535
+	resolvConfPath        = "/etc/resolv.conf"
+)
+
+// Manager runs the built-in DNS resolver of the VPN network.
+// Names in the VPN zone are answered with the tunnel addresses of peers and interfaces, all other queries are
+// forwarded to the upstream name servers.
+type Manager struct {
+	cfg *config.Config
+
+	bus EventBus
+	db  InterfaceAndPeerDatabaseRepo
+
+	upstreams []string
+	state     *resolverState
+}
+
+type resolverState struct {
+	mux       sync.RWMutex
+	zone      *zoneData
+	listeners map[netip.AddrPort]net.PacketConn
+}
+
+// NewDnsResolverManager creates a new DNS resolver manager instance.
+func NewDnsResolverManager(cfg *config.Config, bus EventBus, db InterfaceAndPeerDatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db: db,
+
+		state: &resolverState{
+			zone:      newZoneData(cfg.DnsResolver.Zone, uint32(cfg.DnsResolver.TTL/time.Second)),
+			listeners: make(map[netip.AddrPort]net.PacketConn),
+		},
+	}
+
+	if !cfg.DnsResolver.Enabled {
+		return m, nil
+	}
+
+	if cfg.DnsResolver.Zone == "" {
+		return nil, errors.New("missing DNS resolver zone")
+	}
+
+	upstreams := cfg.DnsResolver.Upstreams
+	if len(upstreams) == 0 {
+		var err error
+		upstreams, err = systemNameServers(resolvConfPath)
+		if err != nil {
+			slog.Warn("failed to read system name servers, queries outside the VPN zone will fail",
+				"error", err)
+		}
+	}
+	for _, upstream := range upstreams {
+		if _, _, err := net.SplitHostPort(upstream); err != nil {
+			upstream = net.JoinHostPort(upstream, "53")
+		}
+		m.upstreams = append(m.upstreams, upstream)
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerCreated, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicPeerUpdated, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicPeerInterfaceUpdated, m.handlePeerInterfaceUpdatedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceCreated, m.handleInterfaceEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceUpdated, m.handleInterfaceEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceEvent)
+}
+
+// StartBackgroundJobs starts background jobs for the DNS resolver.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.DnsResolver.Enabled {
+		return
+	}
+
+	go m.runListenerCheck(ctx)
+}
+
+func (m Manager) handlePeerEvent(peer domain.Peer) {
+	slog.Debug("handling peer event", "peer", peer.Identifier)
+
+	m.refresh(context.Background())
+}
+
+func (m Manager) handlePeerInterfaceUpdatedEvent(id domain.InterfaceIdentifier) {
+	slog.Debug("handling peer interface updated event", "interface", id)
+
+	m.refresh(context.Background())
+}
+
+func (m Manager) handleInterfaceEvent(iface domain.Interface) {
+	slog.Debug("handling interface event", "interface", iface.Identifier)
+
+	m.refresh(context.Background())
+}
+
+// runListenerCheck periodically refreshes the zone data and the listeners. Listeners can only be started once the
+// interface addresses are assigned, for example after the WireGuard interface was restored on startup.
+func (m Manager) runListenerCheck(ctx context.Context) {
+	m.refresh(ctx)
+
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(listenerCheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		m.refresh(ctx)
+	}
+
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+	for addr, conn := range m.state.listeners {
+		_ = conn.Close()
+		delete(m.state.listeners, addr)
+	}
+}
+
+// refresh rebuilds the zone data and starts or stops listeners for the interface addresses.
+func (m Manager) refresh(ctx context.Context) {
+	if err := m.ApplyState(ctx); err != nil {
+		slog.Debug("failed to refresh DNS resolver", "error", err) // addresses might not be assigned yet
+	}
+}
+
+// ApplyState rebuilds the zone data and starts or stops listeners for the interface addresses. An error is
+// returned if the interfaces can not be loaded or a listener could not be started.
+func (m Manager) ApplyState(ctx context.Context) error {
+	if !m.cfg.DnsResolver.Enabled {
+		return nil
+	}
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces for DNS resolver: %w", err)
+	}
+	sort.Slice(interfaces, func(i, j int) bool {
+		return interfaces[i].Identifier < interfaces[j].Identifier
+	})
+
+	zone := newZoneData(m.cfg.DnsResolver.Zone, uint32(m.cfg.DnsResolver.TTL/time.Second))
+	listenAddresses := make(map[netip.AddrPort]struct{})
+	for _, iface := range interfaces {
+		if iface.IsDisabled() || iface.Type == domain.InterfaceTypeClient {
+			continue
+		}
+
+		for _, cidr := range iface.Addresses {
+			if addr := cidr.Prefix().Addr(); addr.IsValid() {
+				listenAddresses[netip.AddrPortFrom(addr, uint16(m.cfg.DnsResolver.Port))] = struct{}{}
+			}
+		}
+
+		if !zone.addHost(domain.DnsLabel(string(iface.Identifier)), iface.Addresses) {
+			slog.Warn("DNS name of interface is already in use", "interface", iface.Identifier)
+		}
+
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			slog.Error("failed to load peers for DNS resolver", "interface", iface.Identifier, "error", err)
+			continue
+		}
+		sort.Slice(peers, func(i, j int) bool {
+			return peers[i].Identifier < peers[j].Identifier
+		})
+
+		for _, peer := range peers {
+			if peer.IsDisabled() || peer.Interface.Type == domain.InterfaceTypeServer {
+				continue
+			}
+			if !zone.addHost(peer.DnsHostname(), peer.Interface.Addresses) {
+				slog.Debug("DNS name of peer is already in use, skipping",
+					"peer", peer.Identifier, "name", peer.DnsHostname())
+			}
+		}
+	}
+
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	m.state.zone = zone
+
+	for addr, conn := range m.state.listeners {
+		if _, ok := listenAddresses[addr]; ok {
+			continue
+		}
+		_ = conn.Close()
+		delete(m.state.listeners, addr)
+		slog.Debug("stopped DNS resolver listener", "address", addr)
+	}
+
+	var listenErrs []error
+	for addr := range listenAddresses {
+		if _, ok := m.state.listeners[addr]; ok {
+			continue
+		}
+		conn, err := net.ListenPacket("udp", addr.String())
+		if err != nil {
+			listenErrs = append(listenErrs, fmt.Errorf("unable to start listener %s: %w", addr, err))
+			continue // the address might not be assigned yet
+		}
+		m.state.listeners[addr] = conn
+		slog.Info("started DNS resolver listener", "address", addr)
+
+		go m.serve(conn)
+	}
+
+	return errors.Join(listenErrs...)
+}
+
+func (m Manager) serve(conn net.PacketConn) {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, remote, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		query := make([]byte, n)
+		copy(query, buf[:n])
+
+		go func() {
+			response, err := m.handleQuery(query)
+			if err != nil {
+				slog.Debug("failed to handle DNS query", "remote", remote, "error", err)
+				return
+			}
+			_, _ = conn.WriteTo(response, remote)
+		}()
+	}
+}
+
+// handleQuery returns the response for the given DNS query message.
+func (m Manager) handleQuery(query []byte) ([]byte, error) {
+	header, question, err := parseQuery(query)
+	if err != nil {
+		return nil, err // malformed queries are dropped
+	}
+
+	m.state.mux.RLock()
+	zone := m.state.zone
+	m.state.mux.RUnlock()
+
+	if zone.isLocal(strings.ToLower(question.Name.String())) {
+		return zone.answer(header, question)
+	}
+
+	response, err := m.forward(query)
+	if err != nil {
+		slog.Debug("failed to forward DNS query", "name", question.Name.String(), "error", err)
+		return errorResponse(header, question, dnsmessage.RCodeServerFailure)
+	}
+
+	return response, nil
+}
+
+// forward sends the query to the upstream name servers and returns the first response.
+func (m Manager) forward(query []byte) ([]byte, error) {
+	if len(m.upstreams) == 0 {
+		return nil, errors.New("no upstream name servers")
+	}
+
+	var lastErr error
+	for _, upstream := range m.upstreams {
+		response, err := exchange(upstream, query)
+		if err == nil {
+			return response, nil
+		}
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+func exchange(server string, query []byte) ([]byte, error) {
+	conn, err := net.DialTimeout("udp", server, upstreamTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(upstreamTimeout))
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("no response from %s: %w", server, err)
+		}
+		if n >= 2 && buf[0] == query[0] && buf[1] == query[1] { // matching message id
+			return buf[:n], nil
+		}
+	}
+}
+
+// systemNameServers returns the name servers from the given resolv.conf file.
+func systemNameServers(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, fields[1])
+		}
+	}
+
+	return servers, scanner.Err()
+}
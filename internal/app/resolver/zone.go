@@ -0,0 +1,199 @@
+package resolver
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// zoneData contains all names of the VPN zone. It is immutable after it has been built.
+type zoneData struct {
+	zone  string // the fully qualified zone name, including the trailing dot
+	ttl   uint32
+	hosts map[string][]netip.Addr // fully qualified host name -> addresses
+	ptrs  map[string]string       // fully qualified reverse name -> fully qualified host name
+}
+
+func newZoneData(zone string, ttl uint32) *zoneData {
+	return &zoneData{
+		zone:  domain.DnsFqdn("", zone),
+		ttl:   ttl,
+		hosts: make(map[string][]netip.Addr),
+		ptrs:  make(map[string]string),
+	}
+}
+
+// addHost adds the given host to the zone. It returns false if the name is already in use.
+func (z *zoneData) addHost(label string, addresses []domain.Cidr) bool {
+	if label == "" {
+		return false
+	}
+
+	fqdn := domain.DnsFqdn(label, z.zone)
+	if _, exists := z.hosts[fqdn]; exists {
+		return false
+	}
+
+	addrs := make([]netip.Addr, 0, len(addresses))
+	for _, cidr := range addresses {
+		addr := cidr.Prefix().Addr()
+		if !addr.IsValid() {
+			continue
+		}
+		addrs = append(addrs, addr)
+
+		ptrName := domain.ReverseDnsName(addr)
+		if _, exists := z.ptrs[ptrName]; !exists {
+			z.ptrs[ptrName] = fqdn
+		}
+	}
+	z.hosts[fqdn] = addrs
+
+	return true
+}
+
+// isLocal returns true if the query must be answered from the zone data instead of being forwarded.
+func (z *zoneData) isLocal(name string) bool {
+	if _, ok := z.ptrs[name]; ok {
+		return true
+	}
+
+	return domain.DnsNameInZone(name, z.zone)
+}
+
+// answer builds the response for the given question. It must only be called for local names.
+func (z *zoneData) answer(header dnsmessage.Header, question dnsmessage.Question) ([]byte, error) {
+	name := strings.ToLower(question.Name.String())
+
+	responseHeader := dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		OpCode:             header.OpCode,
+		Authoritative:      true,
+		RecursionDesired:   header.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              dnsmessage.RCodeSuccess,
+	}
+
+	addrs, isHost := z.hosts[name]
+	ptr, isPtr := z.ptrs[name]
+	if !isHost && !isPtr && name != z.zone {
+		responseHeader.RCode = dnsmessage.RCodeNameError
+	}
+
+	b := dnsmessage.NewBuilder(nil, responseHeader)
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(question); err != nil {
+		return nil, err
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	answered := false
+	rrHeader := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: z.ttl}
+	for _, addr := range addrs {
+		switch {
+		case addr.Is4() && (question.Type == dnsmessage.TypeA || question.Type == dnsmessage.TypeALL):
+			if err := b.AResource(rrHeader, dnsmessage.AResource{A: addr.As4()}); err != nil {
+				return nil, err
+			}
+			answered = true
+		case addr.Is6() && (question.Type == dnsmessage.TypeAAAA || question.Type == dnsmessage.TypeALL):
+			if err := b.AAAAResource(rrHeader, dnsmessage.AAAAResource{AAAA: addr.As16()}); err != nil {
+				return nil, err
+			}
+			answered = true
+		}
+	}
+	if isPtr && (question.Type == dnsmessage.TypePTR || question.Type == dnsmessage.TypeALL) {
+		if err := b.PTRResource(rrHeader, dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(ptr)}); err != nil {
+			return nil, err
+		}
+		answered = true
+	}
+
+	if !answered {
+		// negative answers contain the SOA record of the zone, see RFC 2308
+		if err := z.addSoa(&b); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.Finish()
+}
+
+func (z *zoneData) addSoa(b *dnsmessage.Builder) error {
+	zoneName, err := dnsmessage.NewName(z.zone)
+	if err != nil {
+		return err
+	}
+	ns, err := dnsmessage.NewName("ns." + z.zone)
+	if err != nil {
+		return err
+	}
+	mbox, err := dnsmessage.NewName("hostmaster." + z.zone)
+	if err != nil {
+		return err
+	}
+
+	if err := b.StartAuthorities(); err != nil {
+		return err
+	}
+
+	return b.SOAResource(dnsmessage.ResourceHeader{Name: zoneName, Class: dnsmessage.ClassINET, TTL: z.ttl},
+		dnsmessage.SOAResource{
+			NS:      ns,
+			MBox:    mbox,
+			Serial:  1,
+			Refresh: 3600,
+			Retry:   600,
+			Expire:  86400,
+			MinTTL:  z.ttl,
+		})
+}
+
+// errorResponse builds an empty response with the given response code.
+func errorResponse(header dnsmessage.Header, question dnsmessage.Question, rcode dnsmessage.RCode) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		OpCode:             header.OpCode,
+		RecursionDesired:   header.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              rcode,
+	})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(question); err != nil {
+		return nil, err
+	}
+
+	return b.Finish()
+}
+
+// parseQuery returns the header and the first question of the given query message.
+func parseQuery(query []byte) (dnsmessage.Header, dnsmessage.Question, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(query)
+	if err != nil {
+		return dnsmessage.Header{}, dnsmessage.Question{}, err
+	}
+	if header.Response {
+		return header, dnsmessage.Question{}, errors.New("message is not a query")
+	}
+
+	question, err := p.Question()
+	if err != nil {
+		return header, dnsmessage.Question{}, err
+	}
+
+	return header, question, nil
+}
@@ -0,0 +1,128 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func testZone(t *testing.T) *zoneData {
+	zone := newZoneData("wg.internal", 60)
+
+	v4, err := domain.CidrFromString("10.11.12.2/32")
+	require.NoError(t, err)
+	v6, err := domain.CidrFromString("fdfd:d3ad::2/128")
+	require.NoError(t, err)
+
+	assert.True(t, zone.addHost("laptop", []domain.Cidr{v4, v6}))
+	assert.False(t, zone.addHost("laptop", []domain.Cidr{v4}))
+	assert.False(t, zone.addHost("", []domain.Cidr{v4}))
+
+	return zone
+}
+
+func buildQuery(t *testing.T, name string, qType dnsmessage.Type) (dnsmessage.Header, dnsmessage.Question) {
+	header, question, err := parseQuery(mustQuery(t, name, qType))
+	require.NoError(t, err)
+	return header, question
+}
+
+func mustQuery(t *testing.T, name string, qType dnsmessage.Type) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 4711, RecursionDesired: true})
+	require.NoError(t, b.StartQuestions())
+	require.NoError(t, b.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  qType,
+		Class: dnsmessage.ClassINET,
+	}))
+	msg, err := b.Finish()
+	require.NoError(t, err)
+	return msg
+}
+
+func parseResponse(t *testing.T, response []byte) dnsmessage.Message {
+	var msg dnsmessage.Message
+	require.NoError(t, msg.Unpack(response))
+	return msg
+}
+
+func Test_zoneData_isLocal(t *testing.T) {
+	zone := testZone(t)
+
+	assert.True(t, zone.isLocal("laptop.wg.internal."))
+	assert.True(t, zone.isLocal("unknown.wg.internal."))
+	assert.True(t, zone.isLocal("2.12.11.10.in-addr.arpa."))
+	assert.False(t, zone.isLocal("3.12.11.10.in-addr.arpa."))
+	assert.False(t, zone.isLocal("example.com."))
+}
+
+func Test_zoneData_answer(t *testing.T) {
+	zone := testZone(t)
+
+	t.Run("A record", func(t *testing.T) {
+		response, err := zone.answer(buildQuery(t, "Laptop.wg.internal.", dnsmessage.TypeA))
+		require.NoError(t, err)
+
+		msg := parseResponse(t, response)
+		assert.Equal(t, uint16(4711), msg.Header.ID)
+		assert.True(t, msg.Header.Authoritative)
+		assert.Equal(t, dnsmessage.RCodeSuccess, msg.Header.RCode)
+		if assert.Len(t, msg.Answers, 1) {
+			assert.Equal(t, [4]byte{10, 11, 12, 2}, msg.Answers[0].Body.(*dnsmessage.AResource).A)
+			assert.Equal(t, uint32(60), msg.Answers[0].Header.TTL)
+		}
+	})
+
+	t.Run("AAAA record", func(t *testing.T) {
+		response, err := zone.answer(buildQuery(t, "laptop.wg.internal.", dnsmessage.TypeAAAA))
+		require.NoError(t, err)
+
+		msg := parseResponse(t, response)
+		assert.Len(t, msg.Answers, 1)
+	})
+
+	t.Run("PTR record", func(t *testing.T) {
+		response, err := zone.answer(buildQuery(t, "2.12.11.10.in-addr.arpa.", dnsmessage.TypePTR))
+		require.NoError(t, err)
+
+		msg := parseResponse(t, response)
+		if assert.Len(t, msg.Answers, 1) {
+			assert.Equal(t, "laptop.wg.internal.", msg.Answers[0].Body.(*dnsmessage.PTRResource).PTR.String())
+		}
+	})
+
+	t.Run("no data", func(t *testing.T) {
+		response, err := zone.answer(buildQuery(t, "laptop.wg.internal.", dnsmessage.TypeMX))
+		require.NoError(t, err)
+
+		msg := parseResponse(t, response)
+		assert.Equal(t, dnsmessage.RCodeSuccess, msg.Header.RCode)
+		assert.Empty(t, msg.Answers)
+		assert.Len(t, msg.Authorities, 1)
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		response, err := zone.answer(buildQuery(t, "desktop.wg.internal.", dnsmessage.TypeA))
+		require.NoError(t, err)
+
+		msg := parseResponse(t, response)
+		assert.Equal(t, dnsmessage.RCodeNameError, msg.Header.RCode)
+		assert.Empty(t, msg.Answers)
+	})
+}
+
+func Test_systemNameServers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	content := "# generated\nnameserver 127.0.0.53\nsearch example.com\nnameserver 1.1.1.1\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	servers, err := systemNameServers(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"127.0.0.53", "1.1.1.1"}, servers)
+}
@@ -0,0 +1,228 @@
+package restore
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+}
+
+type InterfaceRestorer interface {
+	// RestoreInterfaceState applies the stored state of the filtered interfaces and their peers.
+	RestoreInterfaceState(ctx context.Context, updateDbOnError bool, filter ...domain.InterfaceIdentifier) error
+}
+
+type StateApplier interface {
+	// ApplyState applies the stored state of a feature, like the routes or the firewall rules, to the system.
+	ApplyState(ctx context.Context) error
+}
+
+// endregion dependencies
+
+// Manager restores the stored state on startup. In contrast to the best-effort restoration, the state is applied
+// in dependency order: first the interfaces and their peers, then the routes, the firewall rules and the DNS
+// records. The result of each step is kept in a restore report.
+type Manager struct {
+	cfg *config.Config
+
+	db DatabaseRepo
+	wg InterfaceRestorer
+
+	routes     StateApplier
+	isolation  StateApplier
+	knock      StateApplier
+	forwards   StateApplier
+	dnsRecords StateApplier
+	resolver   StateApplier
+
+	state *restoreState
+}
+
+type restoreState struct {
+	mux    sync.Mutex
+	report *domain.RestoreReport // nil until the restoration finished
+}
+
+type step struct {
+	name    string
+	enabled bool
+	apply   func(ctx context.Context) error
+}
+
+// NewRestoreManager creates a new restore manager.
+func NewRestoreManager(
+	cfg *config.Config,
+	db DatabaseRepo,
+	wg InterfaceRestorer,
+	routes StateApplier,
+	isolation StateApplier,
+	knock StateApplier,
+	forwards StateApplier,
+	dnsRecords StateApplier,
+	resolver StateApplier,
+) (*Manager, error) {
+	if cfg.Restore.StepTimeout <= 0 {
+		return nil, errors.New("restore step timeout must be positive")
+	}
+
+	m := &Manager{
+		cfg: cfg,
+
+		db: db,
+		wg: wg,
+
+		routes:     routes,
+		isolation:  isolation,
+		knock:      knock,
+		forwards:   forwards,
+		dnsRecords: dnsRecords,
+		resolver:   resolver,
+
+		state: &restoreState{},
+	}
+
+	return m, nil
+}
+
+// Run applies all restoration steps and stores the restore report. If abort_on_error is enabled, the remaining
+// steps are skipped after the first failure and an error is returned.
+func (m Manager) Run(ctx context.Context) error {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	report := domain.RestoreReport{StartedAt: time.Now()}
+	defer func() {
+		report.FinishedAt = time.Now()
+
+		m.state.mux.Lock()
+		m.state.report = &report
+		m.state.mux.Unlock()
+	}()
+
+	steps, err := m.steps(ctx)
+	if err != nil {
+		report.Steps = append(report.Steps, domain.RestoreStepResult{
+			Name:   "interfaces",
+			Status: domain.RestoreStepFailed,
+			Error:  err.Error(),
+		})
+		return fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	var failed error
+	for _, s := range steps {
+		if !s.enabled || (failed != nil && m.cfg.Restore.AbortOnError) {
+			report.Steps = append(report.Steps, domain.RestoreStepResult{
+				Name:   s.name,
+				Status: domain.RestoreStepSkipped,
+			})
+			continue
+		}
+
+		result := m.apply(ctx, s)
+		report.Steps = append(report.Steps, result)
+		if result.Status == domain.RestoreStepFailed {
+			slog.Error("restore step failed", "step", s.name, "error", result.Error)
+			failed = errors.Join(failed, fmt.Errorf("%s: %s", s.name, result.Error))
+		} else {
+			slog.Debug("restore step succeeded", "step", s.name, "duration", result.Duration)
+		}
+	}
+
+	if failed != nil {
+		if m.cfg.Restore.AbortOnError {
+			return failed
+		}
+		slog.Warn("state restored with errors, check the restore report")
+		return nil
+	}
+
+	slog.Info("state restored", "steps", len(report.Steps), "duration", time.Since(report.StartedAt))
+	return nil
+}
+
+// steps returns the restoration steps in the order they must be applied.
+func (m Manager) steps(ctx context.Context) ([]step, error) {
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(interfaces, func(a, b domain.Interface) int {
+		return cmp.Compare(a.Identifier, b.Identifier)
+	})
+
+	steps := make([]step, 0, len(interfaces)+6)
+	for _, iface := range interfaces {
+		id := iface.Identifier
+		steps = append(steps, step{
+			name:    "interface:" + string(id),
+			enabled: true,
+			apply: func(ctx context.Context) error {
+				return m.wg.RestoreInterfaceState(ctx, true, id)
+			},
+		})
+	}
+
+	steps = append(steps,
+		step{name: "routes", enabled: true, apply: m.routes.ApplyState},
+		step{name: "firewall:client_isolation", enabled: true, apply: m.isolation.ApplyState},
+		step{name: "firewall:port_knocking", enabled: m.cfg.PortKnocking.Enabled, apply: m.knock.ApplyState},
+		step{name: "firewall:port_forwarding", enabled: m.cfg.PortForwarding.Enabled, apply: m.forwards.ApplyState},
+		step{name: "dns:records", enabled: m.cfg.DnsRecords.Enabled, apply: m.dnsRecords.ApplyState},
+		step{name: "dns:resolver", enabled: m.cfg.DnsResolver.Enabled, apply: m.resolver.ApplyState},
+	)
+
+	return steps, nil
+}
+
+// apply runs a single step with the step timeout.
+func (m Manager) apply(ctx context.Context, s step) domain.RestoreStepResult {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Restore.StepTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := s.apply(ctx)
+	result := domain.RestoreStepResult{
+		Name:     s.name,
+		Status:   domain.RestoreStepSucceeded,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		result.Status = domain.RestoreStepFailed
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// GetReport returns the report of the startup restoration. The report is empty if the restoration did not run.
+func (m Manager) GetReport(ctx context.Context) (*domain.RestoreReport, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	if m.state.report == nil {
+		return &domain.RestoreReport{}, nil
+	}
+	report := *m.state.report
+	report.Steps = slices.Clone(report.Steps)
+	return &report, nil
+}
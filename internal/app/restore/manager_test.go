@@ -0,0 +1,146 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// recorder records the order in which the restoration steps are applied.
+type recorder struct {
+	applied []string
+	errs    map[string]error
+}
+
+func (r *recorder) apply(name string) error {
+	r.applied = append(r.applied, name)
+	return r.errs[name]
+}
+
+type fakeDatabase struct {
+	interfaces []domain.Interface
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+type fakeRestorer struct {
+	rec *recorder
+}
+
+func (f *fakeRestorer) RestoreInterfaceState(
+	_ context.Context,
+	_ bool,
+	filter ...domain.InterfaceIdentifier,
+) error {
+	return f.rec.apply("interface:" + string(filter[0]))
+}
+
+type fakeApplier struct {
+	rec  *recorder
+	name string
+}
+
+func (f *fakeApplier) ApplyState(_ context.Context) error {
+	return f.rec.apply(f.name)
+}
+
+func newTestManager(t *testing.T, cfg *config.Config) (*Manager, *recorder) {
+	cfg.Restore.Enabled = true
+	cfg.Restore.StepTimeout = time.Second
+
+	rec := &recorder{errs: make(map[string]error)}
+	db := &fakeDatabase{interfaces: []domain.Interface{{Identifier: "wg1"}, {Identifier: "wg0"}}}
+	m, err := NewRestoreManager(cfg, db, &fakeRestorer{rec: rec},
+		&fakeApplier{rec: rec, name: "routes"},
+		&fakeApplier{rec: rec, name: "firewall:client_isolation"},
+		&fakeApplier{rec: rec, name: "firewall:port_knocking"},
+		&fakeApplier{rec: rec, name: "firewall:port_forwarding"},
+		&fakeApplier{rec: rec, name: "dns:records"},
+		&fakeApplier{rec: rec, name: "dns:resolver"},
+	)
+	require.NoError(t, err)
+
+	return m, rec
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+}
+
+func stepStatus(report *domain.RestoreReport) map[string]domain.RestoreStepStatus {
+	status := make(map[string]domain.RestoreStepStatus)
+	for _, step := range report.Steps {
+		status[step.Name] = step.Status
+	}
+	return status
+}
+
+func TestManager_Run(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DnsRecords.Enabled = true
+	cfg.PortKnocking.Enabled = true
+	m, rec := newTestManager(t, cfg)
+
+	require.NoError(t, m.Run(adminContext()))
+	assert.Equal(t, []string{"interface:wg0", "interface:wg1", "routes", "firewall:client_isolation",
+		"firewall:port_knocking", "dns:records"}, rec.applied)
+
+	report, err := m.GetReport(adminContext())
+	require.NoError(t, err)
+	assert.False(t, report.Failed())
+	assert.Len(t, report.Steps, 8)
+	assert.Equal(t, domain.RestoreStepSkipped, stepStatus(report)["firewall:port_forwarding"])
+	assert.Equal(t, domain.RestoreStepSkipped, stepStatus(report)["dns:resolver"])
+	assert.Equal(t, domain.RestoreStepSucceeded, stepStatus(report)["routes"])
+}
+
+func TestManager_Run_ContinueOnError(t *testing.T) {
+	m, rec := newTestManager(t, &config.Config{})
+	rec.errs["interface:wg0"] = errors.New("device busy")
+
+	require.NoError(t, m.Run(adminContext()))
+	assert.Contains(t, rec.applied, "routes")
+
+	report, err := m.GetReport(adminContext())
+	require.NoError(t, err)
+	assert.True(t, report.Failed())
+	assert.Equal(t, domain.RestoreStepResult{Name: "interface:wg0", Status: domain.RestoreStepFailed,
+		Error: "device busy", Duration: report.Steps[0].Duration}, report.Steps[0])
+	assert.Equal(t, domain.RestoreStepSucceeded, stepStatus(report)["interface:wg1"])
+}
+
+func TestManager_Run_AbortOnError(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Restore.AbortOnError = true
+	m, rec := newTestManager(t, cfg)
+	rec.errs["interface:wg1"] = errors.New("device busy")
+
+	err := m.Run(adminContext())
+	assert.ErrorContains(t, err, "interface:wg1: device busy")
+	assert.Equal(t, []string{"interface:wg0", "interface:wg1"}, rec.applied)
+
+	report, err := m.GetReport(adminContext())
+	require.NoError(t, err)
+	assert.Equal(t, domain.RestoreStepSkipped, stepStatus(report)["routes"])
+	assert.Equal(t, domain.RestoreStepSkipped, stepStatus(report)["firewall:client_isolation"])
+}
+
+func TestManager_GetReport(t *testing.T) {
+	m, _ := newTestManager(t, &config.Config{})
+
+	_, err := m.GetReport(context.Background())
+	assert.Error(t, err) // admin rights required
+
+	report, err := m.GetReport(adminContext())
+	require.NoError(t, err)
+	assert.Empty(t, report.Steps) // not run yet
+}
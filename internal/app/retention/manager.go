@@ -0,0 +1,220 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetUser returns the user with the given identifier.
+	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	// DeleteUser deletes the user with the given identifier.
+	DeleteUser(ctx context.Context, id domain.UserIdentifier) error
+	// GetUserPeers returns all peers linked to the given user.
+	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
+	// UpdatePeerStatus updates the status of the given peer.
+	UpdatePeerStatus(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		updateFunc func(in *domain.PeerStatus) (*domain.PeerStatus, error),
+	) error
+	// AnonymizePeerConnectionEvents removes the endpoint addresses from all connection events of the given peer.
+	AnonymizePeerConnectionEvents(ctx context.Context, id domain.PeerIdentifier) error
+	// DeleteAuditEntries deletes all audit entries that were created before the given time.
+	DeleteAuditEntries(ctx context.Context, before time.Time) error
+	// AnonymizeAuditEntries replaces the given personal data values in all audit entries.
+	AnonymizeAuditEntries(ctx context.Context, values []string) error
+	// DeleteMailLogEntries deletes all mail log entries that were created before the given time.
+	DeleteMailLogEntries(ctx context.Context, before time.Time) error
+	// DeleteUserMailLogEntries deletes all mail log entries of mails that were sent to the given user.
+	DeleteUserMailLogEntries(ctx context.Context, id domain.UserIdentifier) error
+}
+
+type PeerManager interface {
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+	// DeletePeer deletes the peer with the given identifier.
+	DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
+}
+
+type EventBus interface {
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
+}
+
+// endregion dependencies
+
+// Manager removes expired personal data and erases the personal data of users on request.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db    DatabaseRepo
+	peers PeerManager
+}
+
+// NewRetentionManager creates a new data retention manager.
+func NewRetentionManager(cfg *config.Config, bus EventBus, db DatabaseRepo, peers PeerManager) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:    db,
+		peers: peers,
+	}
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background jobs for the retention manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if m.cfg.DataRetention.AuditRetention <= 0 && m.cfg.DataRetention.MailLogRetention <= 0 {
+		return
+	}
+
+	go m.runCleanup(ctx)
+
+	slog.Debug("started data retention cleanup",
+		"auditRetention", m.cfg.DataRetention.AuditRetention,
+		"mailLogRetention", m.cfg.DataRetention.MailLogRetention)
+}
+
+func (m Manager) runCleanup(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	ticker := time.NewTicker(m.cfg.DataRetention.CleanupInterval)
+	defer ticker.Stop()
+	for {
+		m.removeExpiredData(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return // program stopped
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m Manager) removeExpiredData(ctx context.Context, now time.Time) {
+	if m.cfg.DataRetention.AuditRetention > 0 {
+		cutoff := now.Add(-m.cfg.DataRetention.AuditRetention)
+		if err := m.db.DeleteAuditEntries(ctx, cutoff); err != nil {
+			slog.Warn("failed to remove expired audit entries", "error", err)
+		}
+	}
+
+	if m.cfg.DataRetention.MailLogRetention > 0 {
+		cutoff := now.Add(-m.cfg.DataRetention.MailLogRetention)
+		if err := m.db.DeleteMailLogEntries(ctx, cutoff); err != nil {
+			slog.Warn("failed to remove expired mail log entries", "error", err)
+		}
+	}
+}
+
+// ForgetUser erases the personal data of the given user. The user is deleted, the peers of the user are deleted or
+// disabled depending on the core configuration. Endpoint addresses are removed from the peer status and the
+// connection history, and all audit entries are anonymized. Traffic statistics are kept, they do not contain
+// personal data. The mail log entries of the user are deleted, as they contain the mail addresses of the user.
+// The user might have been deleted already, in that case only the remaining data is erased.
+func (m Manager) ForgetUser(ctx context.Context, id domain.UserIdentifier) (*domain.UserErasure, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		return nil, fmt.Errorf("missing user identifier: %w", domain.ErrInvalidData)
+	}
+	if domain.GetUserInfo(ctx).Id == id {
+		return nil, fmt.Errorf("cannot erase own user: %w", domain.ErrInvalidData)
+	}
+
+	user, err := m.db.GetUser(ctx, id)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("unable to find user %s: %w", id, err)
+	}
+
+	peers, err := m.db.GetUserPeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find peers of user %s: %w", id, err)
+	}
+
+	erasure := &domain.UserErasure{UserId: id, ErasedAt: time.Now()}
+	for _, peer := range peers {
+		deleted, err := m.erasePeer(ctx, peer, erasure.ErasedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to erase peer %s: %w", peer.Identifier, err)
+		}
+		if deleted {
+			erasure.DeletedPeers = append(erasure.DeletedPeers, peer.Identifier)
+		} else {
+			erasure.AnonymizedPeers = append(erasure.AnonymizedPeers, peer.Identifier)
+		}
+	}
+
+	if err := m.db.AnonymizeAuditEntries(ctx, domain.PersonalDataValues(id, user)); err != nil {
+		return nil, fmt.Errorf("failed to anonymize audit entries: %w", err)
+	}
+	if err := m.db.DeleteUserMailLogEntries(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to delete mail log entries: %w", err)
+	}
+
+	if user != nil {
+		if err := m.db.DeleteUser(ctx, id); err != nil {
+			return nil, fmt.Errorf("failed to delete user %s: %w", id, err)
+		}
+
+		// only the identifier is published, the peers were already unlinked
+		m.bus.Publish(app.TopicUserDeleted, domain.User{Identifier: id})
+	}
+
+	slog.Info("erased personal data of user", "user", id,
+		"deletedPeers", len(erasure.DeletedPeers), "anonymizedPeers", len(erasure.AnonymizedPeers))
+
+	return erasure, nil
+}
+
+// erasePeer removes the endpoint addresses of the peer and deletes the peer, or disables and unlinks it from its
+// owner. It returns true if the peer was deleted.
+func (m Manager) erasePeer(ctx context.Context, peer domain.Peer, now time.Time) (bool, error) {
+	err := m.db.UpdatePeerStatus(ctx, peer.Identifier, func(in *domain.PeerStatus) (*domain.PeerStatus, error) {
+		in.Endpoint = ""
+		return in, nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to anonymize status: %w", err)
+	}
+	if err := m.db.AnonymizePeerConnectionEvents(ctx, peer.Identifier); err != nil {
+		return false, fmt.Errorf("failed to anonymize connection history: %w", err)
+	}
+
+	if m.cfg.Core.DeletePeerAfterUserDeleted {
+		if err := m.peers.DeletePeer(ctx, peer.Identifier); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	peer.UserIdentifier = ""
+	peer.DisplayName = ""
+	peer.Notes = ""
+	peer.MailRecipientsStr = ""
+	if !peer.IsDisabled() {
+		peer.Disabled = &now
+		peer.DisabledReason = domain.DisabledReasonUserDeleted
+	}
+	if _, err := m.peers.UpdatePeer(ctx, &peer); err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
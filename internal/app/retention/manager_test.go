@@ -0,0 +1,239 @@
+package retention
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	users           map[domain.UserIdentifier]domain.User
+	peers           map[domain.PeerIdentifier]domain.Peer
+	status          map[domain.PeerIdentifier]domain.PeerStatus
+	audit           []domain.AuditEntry
+	mailLog         []domain.MailLogEntry
+	anonymizedPeers []domain.PeerIdentifier
+}
+
+func (f *fakeDatabase) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (f *fakeDatabase) DeleteUser(_ context.Context, id domain.UserIdentifier) error {
+	delete(f.users, id)
+	return nil
+}
+
+func (f *fakeDatabase) GetUserPeers(_ context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
+	var peers []domain.Peer
+	for _, peer := range f.peers {
+		if peer.UserIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+func (f *fakeDatabase) UpdatePeerStatus(
+	_ context.Context,
+	id domain.PeerIdentifier,
+	updateFunc func(in *domain.PeerStatus) (*domain.PeerStatus, error),
+) error {
+	status := f.status[id]
+	updated, err := updateFunc(&status)
+	if err != nil {
+		return err
+	}
+	f.status[id] = *updated
+	return nil
+}
+
+func (f *fakeDatabase) AnonymizePeerConnectionEvents(_ context.Context, id domain.PeerIdentifier) error {
+	f.anonymizedPeers = append(f.anonymizedPeers, id)
+	return nil
+}
+
+func (f *fakeDatabase) DeleteAuditEntries(_ context.Context, before time.Time) error {
+	var kept []domain.AuditEntry
+	for _, entry := range f.audit {
+		if !entry.CreatedAt.Before(before) {
+			kept = append(kept, entry)
+		}
+	}
+	f.audit = kept
+	return nil
+}
+
+func (f *fakeDatabase) AnonymizeAuditEntries(_ context.Context, values []string) error {
+	for i := range f.audit {
+		f.audit[i].ContextUser = domain.AnonymizeText(f.audit[i].ContextUser, values)
+		f.audit[i].Message = domain.AnonymizeText(f.audit[i].Message, values)
+	}
+	return nil
+}
+
+func (f *fakeDatabase) DeleteMailLogEntries(_ context.Context, before time.Time) error {
+	f.mailLog = slices.DeleteFunc(f.mailLog, func(entry domain.MailLogEntry) bool {
+		return entry.SentAt.Before(before)
+	})
+	return nil
+}
+
+func (f *fakeDatabase) DeleteUserMailLogEntries(_ context.Context, id domain.UserIdentifier) error {
+	f.mailLog = slices.DeleteFunc(f.mailLog, func(entry domain.MailLogEntry) bool {
+		return entry.UserIdentifier == id
+	})
+	return nil
+}
+
+type fakePeerManager struct {
+	db *fakeDatabase
+}
+
+func (f fakePeerManager) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	f.db.peers[peer.Identifier] = *peer
+	return peer, nil
+}
+
+func (f fakePeerManager) DeletePeer(_ context.Context, id domain.PeerIdentifier) error {
+	delete(f.db.peers, id)
+	return nil
+}
+
+type fakeBus struct {
+	topics []string
+	users  []domain.User
+}
+
+func (f *fakeBus) Publish(topic string, args ...any) {
+	f.topics = append(f.topics, topic)
+	f.users = append(f.users, args[0].(domain.User))
+}
+
+func newTestManager() (*Manager, *fakeDatabase, *fakeBus) {
+	db := &fakeDatabase{
+		users: map[domain.UserIdentifier]domain.User{
+			"alice": {Identifier: "alice", Email: "alice@example.com", Firstname: "Alice", Lastname: "Liddell"},
+		},
+		peers: map[domain.PeerIdentifier]domain.Peer{
+			"peer-a": {Identifier: "peer-a", UserIdentifier: "alice", DisplayName: "Alice's laptop"},
+			"peer-b": {Identifier: "peer-b", UserIdentifier: "bob"},
+		},
+		status: map[domain.PeerIdentifier]domain.PeerStatus{
+			"peer-a": {PeerId: "peer-a", Endpoint: "192.0.2.1:51820", BytesReceived: 1000},
+		},
+		audit: []domain.AuditEntry{
+			{ContextUser: "alice", Message: "alice logged in"},
+			{ContextUser: "admin", Message: "admin started impersonating alice"},
+			{ContextUser: "bob", Message: "bob logged in"},
+			{ContextUser: "", Message: "alice@example.com failed to login: invalid password"},
+		},
+		mailLog: []domain.MailLogEntry{
+			{UserIdentifier: "alice", Recipients: "alice@example.com"},
+			{UserIdentifier: "bob", Recipients: "bob@example.com"},
+		},
+	}
+	bus := &fakeBus{}
+
+	m := &Manager{cfg: &config.Config{}, bus: bus, db: db, peers: fakePeerManager{db: db}}
+	return m, db, bus
+}
+
+func TestManager_ForgetUser(t *testing.T) {
+	m, db, bus := newTestManager()
+	adminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+
+	_, err := m.ForgetUser(domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "bob"}), "alice")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	_, err = m.ForgetUser(adminCtx, "admin")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	erasure, err := m.ForgetUser(adminCtx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.PeerIdentifier{"peer-a"}, erasure.AnonymizedPeers)
+	assert.Empty(t, erasure.DeletedPeers)
+
+	assert.NotContains(t, db.users, domain.UserIdentifier("alice"))
+	assert.Equal(t, []string{app.TopicUserDeleted}, bus.topics)
+	assert.Empty(t, bus.users[0].Email, "no personal data is published")
+
+	peer := db.peers["peer-a"]
+	assert.Empty(t, peer.UserIdentifier)
+	assert.Empty(t, peer.DisplayName)
+	assert.True(t, peer.IsDisabled())
+	assert.Equal(t, "bob", string(db.peers["peer-b"].UserIdentifier))
+
+	assert.Empty(t, db.status["peer-a"].Endpoint)
+	assert.Equal(t, uint64(1000), db.status["peer-a"].BytesReceived, "statistics are kept")
+	assert.Equal(t, []domain.PeerIdentifier{"peer-a"}, db.anonymizedPeers)
+
+	assert.Equal(t, []domain.AuditEntry{
+		{ContextUser: "[erased]", Message: "[erased] logged in"},
+		{ContextUser: "admin", Message: "admin started impersonating [erased]"},
+		{ContextUser: "bob", Message: "bob logged in"},
+		{ContextUser: "", Message: "[erased] failed to login: invalid password"},
+	}, db.audit)
+
+	require.Len(t, db.mailLog, 1)
+	assert.Equal(t, domain.UserIdentifier("bob"), db.mailLog[0].UserIdentifier)
+}
+
+func TestManager_ForgetUser_deletePeers(t *testing.T) {
+	m, db, _ := newTestManager()
+	m.cfg.Core.DeletePeerAfterUserDeleted = true
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	erasure, err := m.ForgetUser(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.PeerIdentifier{"peer-a"}, erasure.DeletedPeers)
+	assert.NotContains(t, db.peers, domain.PeerIdentifier("peer-a"))
+}
+
+func TestManager_ForgetUser_alreadyDeleted(t *testing.T) {
+	m, db, bus := newTestManager()
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	delete(db.users, "alice")
+
+	_, err := m.ForgetUser(ctx, "alice")
+	require.NoError(t, err)
+	assert.Empty(t, bus.topics)
+	assert.Equal(t, "[erased] logged in", db.audit[0].Message)
+	assert.Equal(t, "[erased]@example.com failed to login: invalid password", db.audit[3].Message,
+		"the email address is unknown without the user")
+}
+
+func TestManager_removeExpiredData(t *testing.T) {
+	m, db, _ := newTestManager()
+	m.cfg.DataRetention.AuditRetention = 24 * time.Hour
+
+	now := time.Now()
+	db.audit = []domain.AuditEntry{
+		{Message: "old", CreatedAt: now.Add(-48 * time.Hour)},
+		{Message: "new", CreatedAt: now.Add(-time.Hour)},
+	}
+	db.mailLog = []domain.MailLogEntry{
+		{Subject: "old", SentAt: now.Add(-48 * time.Hour)},
+	}
+	m.removeExpiredData(context.Background(), now)
+
+	require.Len(t, db.audit, 1)
+	assert.Equal(t, "new", db.audit[0].Message)
+	assert.Len(t, db.mailLog, 1, "the mail log is kept without retention")
+
+	m.cfg.DataRetention.MailLogRetention = 24 * time.Hour
+	m.removeExpiredData(context.Background(), now)
+	assert.Empty(t, db.mailLog)
+}
@@ -0,0 +1,18 @@
+package roaming
+
+import (
+	"os"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// loadCountryDatabase reads the CSV country database from the given file.
+func loadCountryDatabase(path string) (*domain.CountryDatabase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return domain.ParseCountryDatabase(file)
+}
@@ -0,0 +1,257 @@
+package roaming
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	// changeWindow is the sliding time window in which the endpoint changes of a peer are counted.
+	changeWindow = 1 * time.Hour
+	// warningCooldown suppresses repeated actions for the same peer, for example if a shared configuration keeps
+	// switching between two endpoints.
+	warningCooldown = 1 * time.Hour
+)
+
+// region dependencies
+
+type PeerManager interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+}
+
+type MailService interface {
+	// SendRoamingWarning informs the owner of a peer about an endpoint that violates the roaming policy.
+	SendRoamingWarning(ctx context.Context, violation *domain.RoamingViolation, disabled bool) error
+}
+
+type EventBus interface {
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager enforces the roaming policy. It checks all endpoint changes of the peers and warns the owner of, or
+// disables, peers whose endpoint changes too often, moves to another country or leaves the endpoint pin of the
+// peer. Such peers are likely used on several devices at once, for example because their configuration is shared.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	peers PeerManager
+	mails MailService
+
+	countries *domain.CountryDatabase // nil if no country database is configured
+
+	mux     *sync.Mutex
+	changes map[domain.PeerIdentifier][]time.Time // recent endpoint changes per peer
+	warned  map[domain.PeerIdentifier]time.Time   // last policy action per peer
+}
+
+// NewRoamingManager creates a new roaming policy manager.
+func NewRoamingManager(cfg *config.Config, bus EventBus, peers PeerManager, mails MailService) (*Manager, error) {
+	switch cfg.Roaming.Action {
+	case config.RoamingActionWarn, config.RoamingActionDisable:
+	default:
+		return nil, fmt.Errorf("unsupported roaming policy action %s", cfg.Roaming.Action)
+	}
+
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		peers: peers,
+		mails: mails,
+
+		mux:     &sync.Mutex{},
+		changes: make(map[domain.PeerIdentifier][]time.Time),
+		warned:  make(map[domain.PeerIdentifier]time.Time),
+	}
+
+	if !cfg.Roaming.Enabled {
+		return m, nil
+	}
+
+	if cfg.Roaming.CountryDatabase != "" {
+		countries, err := loadCountryDatabase(cfg.Roaming.CountryDatabase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load country database: %w", err)
+		}
+		m.countries = countries
+		slog.Debug("loaded country database", "ranges", countries.Size())
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerStateChanged, m.handlePeerStateChangedEvent)
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerDeletedEvent)
+}
+
+func (m Manager) handlePeerStateChangedEvent(change domain.PeerStateChange) {
+	if change.Kind != domain.PeerStateConnected && change.Kind != domain.PeerStateEndpointChanged {
+		return // disconnects do not change the endpoint
+	}
+
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	peer, err := m.peers.GetPeer(ctx, change.PeerId)
+	if err != nil {
+		slog.Error("failed to load peer for roaming policy check", "peer", change.PeerId, "error", err)
+		return
+	}
+
+	violation := m.checkEndpoint(*peer, change)
+	if violation == nil {
+		return
+	}
+
+	m.enforce(ctx, peer, violation)
+}
+
+func (m Manager) handlePeerDeletedEvent(peer domain.Peer) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.changes, peer.Identifier)
+	delete(m.warned, peer.Identifier)
+}
+
+// checkEndpoint records the endpoint change of the peer and returns the violation of the roaming policy, or nil if
+// the endpoint is allowed.
+func (m Manager) checkEndpoint(peer domain.Peer, change domain.PeerStateChange) *domain.RoamingViolation {
+	if peer.IsDisabled() || peer.IsExcludedFromRoamingPolicy(m.cfg.Roaming.ExclusionTag) {
+		return nil
+	}
+
+	addr, ok := domain.EndpointAddr(change.Endpoint)
+	if !ok {
+		return nil
+	}
+
+	violation := &domain.RoamingViolation{
+		PeerId:           peer.Identifier,
+		PeerName:         peer.DisplayName,
+		InterfaceId:      peer.InterfaceIdentifier,
+		UserIdentifier:   peer.UserIdentifier,
+		Time:             change.Time,
+		Endpoint:         change.Endpoint,
+		PreviousEndpoint: change.PreviousEndpoint,
+		Country:          m.countries.Lookup(addr),
+	}
+	if violation.PeerName == "" {
+		violation.PeerName = string(peer.Identifier)
+	}
+	if previousAddr, ok := domain.EndpointAddr(change.PreviousEndpoint); ok {
+		violation.PreviousCountry = m.countries.Lookup(previousAddr)
+	}
+
+	if change.Kind == domain.PeerStateEndpointChanged {
+		m.mux.Lock()
+		m.changes[peer.Identifier] = domain.TrackEndpointChange(m.changes[peer.Identifier], change.Time, changeWindow)
+		violation.Changes = len(m.changes[peer.Identifier])
+		m.mux.Unlock()
+	}
+
+	pin, err := domain.ParseEndpointPin(peer.EndpointPinStr)
+	if err != nil {
+		slog.Warn("ignoring invalid endpoint pin", "peer", peer.Identifier, "error", err)
+	}
+	if pin.HasCountries() && m.countries == nil {
+		slog.Warn("endpoint pin contains countries, but no country database is configured", "peer", peer.Identifier)
+	}
+
+	switch {
+	case !pin.Allows(addr, violation.Country):
+		violation.Kind = domain.RoamingViolationPin
+		violation.Message = fmt.Sprintf("endpoint %s is outside of the pinned networks and countries", addr)
+	case violation.Country != "" && violation.PreviousCountry != "" &&
+		violation.Country != violation.PreviousCountry:
+		violation.Kind = domain.RoamingViolationCountry
+		violation.Message = fmt.Sprintf("endpoint moved from %s to %s", violation.PreviousCountry,
+			violation.Country)
+	case m.cfg.Roaming.MaxEndpointChanges > 0 && violation.Changes > m.cfg.Roaming.MaxEndpointChanges:
+		violation.Kind = domain.RoamingViolationChurn
+		violation.Message = fmt.Sprintf("endpoint changed %d times within the last hour", violation.Changes)
+	default:
+		return nil
+	}
+
+	return violation
+}
+
+// enforce takes the configured action for the violation, unless an action was taken for the peer recently.
+func (m Manager) enforce(ctx context.Context, peer *domain.Peer, violation *domain.RoamingViolation) {
+	if !m.shouldEnforce(violation) {
+		return
+	}
+
+	action := m.cfg.Roaming.Action
+	slog.Warn("peer violates roaming policy",
+		"peer", peer.Identifier,
+		"kind", violation.Kind,
+		"endpoint", violation.Endpoint,
+		"action", action)
+
+	disabled := false
+	if action == config.RoamingActionDisable {
+		now := time.Now()
+		peer.Disabled = &now
+		peer.DisabledReason = domain.DisabledReasonRoaming
+		if _, err := m.peers.UpdatePeer(ctx, peer); err != nil {
+			slog.Error("failed to disable peer that violates roaming policy", "peer", peer.Identifier, "error", err)
+		} else {
+			disabled = true
+		}
+	}
+
+	m.bus.Publish(app.TopicAuditRoamingViolation, domain.AuditEventWrapper[audit.RoamingEvent]{
+		Ctx: ctx,
+		Event: audit.RoamingEvent{
+			Violation: *violation,
+			Action:    string(action),
+		},
+	})
+
+	if peer.UserIdentifier == "" {
+		return // nobody to notify
+	}
+	if err := m.mails.SendRoamingWarning(ctx, violation, disabled); err != nil {
+		slog.Error("failed to send roaming warning", "peer", peer.Identifier, "error", err)
+	}
+}
+
+// shouldEnforce returns false if an action was taken for the same peer within the warning cooldown.
+func (m Manager) shouldEnforce(violation *domain.RoamingViolation) bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for id, warnedAt := range m.warned {
+		if violation.Time.Sub(warnedAt) > warningCooldown {
+			delete(m.warned, id)
+		}
+	}
+
+	if _, ok := m.warned[violation.PeerId]; ok {
+		return false
+	}
+	m.warned[violation.PeerId] = violation.Time
+
+	return true
+}
@@ -0,0 +1,184 @@
+package roaming
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakePeerManager struct {
+	peers map[domain.PeerIdentifier]domain.Peer
+}
+
+func (f *fakePeerManager) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &peer, nil
+}
+
+func (f *fakePeerManager) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	f.peers[peer.Identifier] = *peer
+	return peer, nil
+}
+
+type fakeMailService struct {
+	warnings []domain.RoamingViolation
+	disabled []bool
+}
+
+func (f *fakeMailService) SendRoamingWarning(
+	_ context.Context,
+	violation *domain.RoamingViolation,
+	disabled bool,
+) error {
+	f.warnings = append(f.warnings, *violation)
+	f.disabled = append(f.disabled, disabled)
+	return nil
+}
+
+type fakeBus struct {
+	published []string
+}
+
+func (f *fakeBus) Publish(topic string, _ ...any) {
+	f.published = append(f.published, topic)
+}
+
+func (f *fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+const testCountryDatabase = `start_ip,end_ip,country
+# documentation ranges
+203.0.113.0,203.0.113.255,at
+198.51.100.0,198.51.100.255,DE
+`
+
+func newTestManager(t *testing.T, cfg config.RoamingConfig) (*Manager, *fakePeerManager, *fakeMailService) {
+	countries, err := domain.ParseCountryDatabase(strings.NewReader(testCountryDatabase))
+	require.NoError(t, err)
+
+	peers := &fakePeerManager{peers: map[domain.PeerIdentifier]domain.Peer{
+		"peer1": {Identifier: "peer1", DisplayName: "Laptop", InterfaceIdentifier: "wg0", UserIdentifier: "alice"},
+	}}
+	mails := &fakeMailService{}
+
+	m := &Manager{
+		cfg:       &config.Config{Roaming: cfg},
+		bus:       &fakeBus{},
+		peers:     peers,
+		mails:     mails,
+		countries: countries,
+		mux:       &sync.Mutex{},
+		changes:   make(map[domain.PeerIdentifier][]time.Time),
+		warned:    make(map[domain.PeerIdentifier]time.Time),
+	}
+
+	return m, peers, mails
+}
+
+func endpointChange(endpoint, previous string, at time.Time) domain.PeerStateChange {
+	return domain.PeerStateChange{
+		PeerId:           "peer1",
+		InterfaceId:      "wg0",
+		Kind:             domain.PeerStateEndpointChanged,
+		Time:             at,
+		Endpoint:         endpoint,
+		PreviousEndpoint: previous,
+	}
+}
+
+func TestManager_checkEndpoint_churn(t *testing.T) {
+	m, peers, _ := newTestManager(t, config.RoamingConfig{MaxEndpointChanges: 2})
+	peer := peers.peers["peer1"]
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, m.checkEndpoint(peer, endpointChange("203.0.113.1:1000", "203.0.113.2:1000", now)))
+	assert.Nil(t, m.checkEndpoint(peer, endpointChange("203.0.113.2:1000", "203.0.113.1:1000",
+		now.Add(10*time.Minute))))
+
+	violation := m.checkEndpoint(peer, endpointChange("203.0.113.1:1000", "203.0.113.2:1000",
+		now.Add(20*time.Minute)))
+	require.NotNil(t, violation)
+	assert.Equal(t, domain.RoamingViolationChurn, violation.Kind)
+	assert.Equal(t, 3, violation.Changes)
+	assert.Equal(t, "AT", violation.Country)
+
+	// the first changes left the window
+	assert.Nil(t, m.checkEndpoint(peer, endpointChange("203.0.113.2:1000", "203.0.113.1:1000",
+		now.Add(75*time.Minute))))
+
+	peer.Notes = "shared test device #roaming"
+	m.cfg.Roaming.ExclusionTag = "#roaming"
+	assert.Nil(t, m.checkEndpoint(peer, endpointChange("203.0.113.1:1000", "203.0.113.2:1000",
+		now.Add(76*time.Minute))), "excluded peer")
+}
+
+func TestManager_checkEndpoint_country(t *testing.T) {
+	m, peers, _ := newTestManager(t, config.RoamingConfig{})
+	peer := peers.peers["peer1"]
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	violation := m.checkEndpoint(peer, endpointChange("198.51.100.7:1000", "203.0.113.7:1000", now))
+	require.NotNil(t, violation)
+	assert.Equal(t, domain.RoamingViolationCountry, violation.Kind)
+	assert.Equal(t, "endpoint moved from AT to DE", violation.Message)
+
+	assert.Nil(t, m.checkEndpoint(peer, endpointChange("192.0.2.7:1000", "203.0.113.7:1000", now)),
+		"unknown countries are ignored")
+}
+
+func TestManager_checkEndpoint_pin(t *testing.T) {
+	m, peers, _ := newTestManager(t, config.RoamingConfig{})
+	peer := peers.peers["peer1"]
+	peer.EndpointPinStr = "192.0.2.0/24, AT"
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	connected := domain.PeerStateChange{PeerId: "peer1", Kind: domain.PeerStateConnected, Time: now,
+		Endpoint: "198.51.100.7:1000"}
+	violation := m.checkEndpoint(peer, connected)
+	require.NotNil(t, violation)
+	assert.Equal(t, domain.RoamingViolationPin, violation.Kind)
+	assert.Equal(t, 0, violation.Changes, "connects are no endpoint changes")
+
+	connected.Endpoint = "192.0.2.7:1000"
+	assert.Nil(t, m.checkEndpoint(peer, connected))
+	connected.Endpoint = "203.0.113.7:1000"
+	assert.Nil(t, m.checkEndpoint(peer, connected))
+}
+
+func TestManager_enforce(t *testing.T) {
+	m, peers, mails := newTestManager(t, config.RoamingConfig{Action: config.RoamingActionWarn})
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	violation := m.checkEndpoint(peers.peers["peer1"], endpointChange("198.51.100.7:1000", "203.0.113.7:1000", now))
+	require.NotNil(t, violation)
+
+	peer := peers.peers["peer1"]
+	m.enforce(ctx, &peer, violation)
+	assert.Nil(t, peers.peers["peer1"].Disabled)
+	require.Len(t, mails.warnings, 1)
+	assert.Equal(t, "Laptop", mails.warnings[0].PeerName)
+	assert.False(t, mails.disabled[0])
+
+	m.enforce(ctx, &peer, violation)
+	assert.Len(t, mails.warnings, 1, "repeated violations within the cooldown are ignored")
+
+	m.cfg.Roaming.Action = config.RoamingActionDisable
+	violation.Time = now.Add(2 * time.Hour)
+	m.enforce(ctx, &peer, violation)
+	assert.NotNil(t, peers.peers["peer1"].Disabled)
+	assert.Equal(t, domain.DisabledReasonRoaming, peers.peers["peer1"].DisabledReason)
+	require.Len(t, mails.warnings, 2)
+	assert.True(t, mails.disabled[1])
+	assert.Len(t, m.bus.(*fakeBus).published, 2, "each action is recorded in the audit log")
+}
@@ -1,9 +1,12 @@
+//go:build linux
+
 package route
 
 import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 
 	"github.com/vishvananda/netlink"
 	"golang.org/x/sys/unix"
@@ -49,6 +52,8 @@ type Manager struct {
 	wg  lowlevel.WireGuardClient
 	nl  lowlevel.NetlinkClient
 	db  InterfaceAndPeerDatabaseRepo
+
+	mux *sync.Mutex // serializes the route synchronization of events and the startup restoration
 }
 
 // NewRouteManager creates a new route manager instance.
@@ -67,6 +72,8 @@ func NewRouteManager(cfg *config.Config, bus EventBus, db InterfaceAndPeerDataba
 		db: db,
 		wg: wg,
 		nl: nl,
+
+		mux: &sync.Mutex{},
 	}
 
 	m.connectToMessageBus()
@@ -115,7 +122,15 @@ func (m Manager) handleRouteRemoveEvent(info domain.RoutingTableInfo) {
 	slog.Debug("routes removed", "table", info.String())
 }
 
+// ApplyState synchronizes the routes of all interfaces.
+func (m Manager) ApplyState(ctx context.Context) error {
+	return m.syncRoutes(ctx)
+}
+
 func (m Manager) syncRoutes(ctx context.Context) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
 	interfaces, err := m.db.GetAllInterfaces(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to find all interfaces: %w", err)
@@ -0,0 +1,161 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"sync"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+	"github.com/h44z/wg-portal/internal/lowlevel"
+)
+
+// region dependencies
+
+type InterfaceAndPeerDatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers for a given interface
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager sets the routes to the allowed IPs of the peers on FreeBSD. Only the routes in the default routing table
+// are managed, default routes are not supported as FreeBSD has no policy routing like Linux.
+type Manager struct {
+	cfg *config.Config
+
+	bus EventBus
+	nw  lowlevel.BsdNetworkClient
+	db  InterfaceAndPeerDatabaseRepo
+
+	mux *sync.Mutex // serializes the route synchronization of events and the startup restoration
+}
+
+// NewRouteManager creates a new route manager instance.
+func NewRouteManager(cfg *config.Config, bus EventBus, db InterfaceAndPeerDatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db: db,
+		nw: lowlevel.BsdNetworkManager{},
+
+		mux: &sync.Mutex{},
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicRouteUpdate, m.handleRouteUpdateEvent)
+}
+
+// StartBackgroundJobs starts background jobs for the route manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(_ context.Context) {
+	// this is a no-op for now
+}
+
+func (m Manager) handleRouteUpdateEvent(srcDescription string) {
+	slog.Debug("handling route update event", "source", srcDescription)
+
+	err := m.syncRoutes(context.Background())
+	if err != nil {
+		slog.Error("failed to synchronize routes",
+			"source", srcDescription,
+			"error", err)
+	}
+
+	slog.Debug("routes synchronized", "source", srcDescription)
+}
+
+// ApplyState synchronizes the routes of all interfaces.
+func (m Manager) ApplyState(ctx context.Context) error {
+	return m.syncRoutes(ctx)
+}
+
+func (m Manager) syncRoutes(ctx context.Context) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find all interfaces: %w", err)
+	}
+
+	for _, iface := range interfaces {
+		if iface.IsDisabled() {
+			continue // disabled interface does not need route entries
+		}
+		if !iface.ManageRoutingTable() {
+			continue
+		}
+
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			return fmt.Errorf("failed to find peers for %s: %w", iface.Identifier, err)
+		}
+
+		var destinations []netip.Prefix
+		for _, allowedIP := range iface.GetAllowedIPs(peers) {
+			if allowedIP.Prefix().Bits() == 0 {
+				slog.Warn("default routes are not managed on FreeBSD", "interface", iface.Identifier)
+				continue
+			}
+			destinations = append(destinations, allowedIP.Prefix().Masked())
+		}
+
+		if err := m.syncInterfaceRoutes(string(iface.Identifier), destinations); err != nil {
+			return fmt.Errorf("failed to set routes for %s: %w", iface.Identifier, err)
+		}
+	}
+
+	return nil
+}
+
+// syncInterfaceRoutes adds the missing routes and removes the deprecated routes of the interface. Routes that were
+// not added manually, like the routes of the interface addresses, are not changed.
+func (m Manager) syncInterfaceRoutes(name string, destinations []netip.Prefix) error {
+	routes, err := m.nw.RouteList(name)
+	if err != nil {
+		return fmt.Errorf("failed to fetch routes: %w", err)
+	}
+
+	var existing []netip.Prefix
+	for _, route := range routes {
+		if !route.Static {
+			continue
+		}
+		if slices.Contains(destinations, route.Destination) {
+			existing = append(existing, route.Destination)
+			continue
+		}
+		if err := m.nw.RouteDel(name, route.Destination); err != nil {
+			return fmt.Errorf("failed to remove deprecated route %s: %w", route.Destination, err)
+		}
+	}
+
+	for _, destination := range destinations {
+		if slices.Contains(existing, destination) {
+			continue
+		}
+		if err := m.nw.RouteAdd(name, destination); err != nil {
+			return fmt.Errorf("failed to add route %s: %w", destination, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,375 @@
+package routereview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/h44z/wg-portal/internal"
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetRouteReview returns the route review with the given identifier.
+	GetRouteReview(ctx context.Context, id domain.RouteReviewIdentifier) (*domain.RouteReview, error)
+	// GetRouteReviews returns all route reviews, the most recent reviews first.
+	GetRouteReviews(ctx context.Context) ([]domain.RouteReview, error)
+	// SaveRouteReview creates or updates the given route review.
+	SaveRouteReview(ctx context.Context, review *domain.RouteReview) error
+	// UpdateRouteReviewPeer moves all route reviews of a peer to the new peer identifier.
+	UpdateRouteReviewPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error
+}
+
+type PeerManager interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager handles the review of routes that users request for their own peers. The WireGuard manager only applies
+// routes that are covered by the route allowlist of the interface. All other route changes are published as route
+// review, which must be approved by an administrator of the interface before the routes are applied to the peer.
+// Only the most recent review of a peer stays open, older open reviews are superseded.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db    DatabaseRepo
+	peers PeerManager
+}
+
+// NewRouteReviewManager creates a new route review manager.
+func NewRouteReviewManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	peers PeerManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:    db,
+		peers: peers,
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background job that expires open reviews.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	go m.runExpiryCheck(ctx)
+
+	slog.Debug("started route review expiry checks")
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerRouteReviewRequested, m.handleRouteReviewRequestedEvent)
+	_ = m.bus.Subscribe(app.TopicPeerIdentifierUpdated, m.handlePeerIdentifierUpdatedEvent)
+}
+
+func (m Manager) handleRouteReviewRequestedEvent(review domain.RouteReview) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	if err := m.createReview(ctx, &review); err != nil {
+		slog.Error("failed to store route review", "peer", review.PeerId, "routes", review.RoutesStr,
+			"error", err)
+	}
+}
+
+func (m Manager) handlePeerIdentifierUpdatedEvent(oldId, newId domain.PeerIdentifier) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	if err := m.db.UpdateRouteReviewPeer(ctx, oldId, newId); err != nil {
+		slog.Error("failed to migrate route reviews", "oldIdentifier", oldId, "newIdentifier", newId,
+			"error", err)
+	}
+}
+
+// createReview stores the given review and supersedes all other open reviews of the peer.
+func (m Manager) createReview(ctx context.Context, review *domain.RouteReview) error {
+	reviews, err := m.db.GetRouteReviews(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load route reviews: %w", err)
+	}
+
+	for _, other := range reviews {
+		if other.PeerId != review.PeerId || !other.IsOpen() {
+			continue
+		}
+		other.Resolve(domain.RouteReviewSuperseded, review.RequestedBy, review.RequestedAt)
+		if err := m.db.SaveRouteReview(ctx, &other); err != nil {
+			return fmt.Errorf("failed to supersede route review %s: %w", other.Identifier, err)
+		}
+	}
+
+	review.Identifier = domain.RouteReviewIdentifier(uuid.New().String())
+	review.ExpiresAt = review.RequestedAt.Add(m.cfg.RouteReview.RequestTimeout)
+	if err := m.db.SaveRouteReview(ctx, review); err != nil {
+		return err
+	}
+
+	slog.Info("requested route review", "review", review.Identifier, "peer", review.PeerId,
+		"routes", review.UnreviewedStr, "by", review.RequestedBy)
+
+	return nil
+}
+
+func (m Manager) runExpiryCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		if err := m.expireReviews(ctx, time.Now()); err != nil {
+			slog.Error("failed to expire route reviews", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-time.After(m.cfg.Advanced.ExpiryCheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+	}
+}
+
+// expireReviews resolves all open reviews that were not approved in time.
+func (m Manager) expireReviews(ctx context.Context, now time.Time) error {
+	reviews, err := m.db.GetRouteReviews(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load route reviews: %w", err)
+	}
+
+	for _, review := range reviews {
+		if !review.IsExpired(now) {
+			continue
+		}
+		review.Resolve(domain.RouteReviewExpired, domain.CtxSystemAdminId, now)
+		if err := m.db.SaveRouteReview(ctx, &review); err != nil {
+			return fmt.Errorf("failed to expire route review %s: %w", review.Identifier, err)
+		}
+	}
+
+	return nil
+}
+
+func (m Manager) checkEnabled() error {
+	if !m.cfg.RouteReview.Enabled {
+		return fmt.Errorf("route reviews are disabled: %w", domain.ErrNoPermission)
+	}
+
+	return nil
+}
+
+// GetRouteReviews returns all reviews of the current user. Admins receive all reviews of the interfaces they
+// administrate.
+func (m Manager) GetRouteReviews(ctx context.Context) ([]domain.RouteReview, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	reviews, err := m.db.GetRouteReviews(domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load route reviews: %w", err)
+	}
+
+	sessionUser := domain.GetUserInfo(ctx)
+	visible := make([]domain.RouteReview, 0, len(reviews))
+	for _, review := range reviews {
+		if isRequester(sessionUser, &review) || m.isInterfaceAdmin(ctx, review.InterfaceIdentifier) {
+			visible = append(visible, review)
+		}
+	}
+
+	return visible, nil
+}
+
+// ApproveReview approves the given review and applies the requested routes to the peer. Only admins of the
+// interface can approve reviews. If the routes of the peer changed since the review was requested, the review is
+// superseded instead, so that the requested routes do not overwrite newer changes.
+func (m Manager) ApproveReview(ctx context.Context, id domain.RouteReviewIdentifier) (*domain.RouteReview, error) {
+	review, err := m.getReview(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.validateInterfaceAdmin(ctx, review.InterfaceIdentifier); err != nil {
+		return nil, err
+	}
+	if !review.IsOpen() {
+		return nil, fmt.Errorf("route review %s is %s: %w", id, review.State, domain.ErrInvalidData)
+	}
+
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+	peer, err := m.peers.GetPeer(systemCtx, review.PeerId)
+	if errors.Is(err, domain.ErrNotFound) {
+		_, _ = m.resolve(systemCtx, review, domain.RouteReviewCancelled)
+		return nil, fmt.Errorf("peer %s of route review %s no longer exists: %w", review.PeerId, id,
+			domain.ErrInvalidData)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer %s: %w", review.PeerId, err)
+	}
+
+	if normalizeRoutes(peer.ExtraAllowedIPsStr) != normalizeRoutes(review.PreviousRoutesStr) {
+		_, _ = m.resolve(systemCtx, review, domain.RouteReviewSuperseded)
+		return nil, fmt.Errorf("routes of peer %s changed since the review was requested: %w", peer.Identifier,
+			domain.ErrInvalidData)
+	}
+
+	peer.ExtraAllowedIPsStr = review.RoutesStr
+	if _, err := m.peers.UpdatePeer(systemCtx, peer); err != nil {
+		return nil, fmt.Errorf("failed to apply routes to peer %s: %w", peer.Identifier, err)
+	}
+
+	return m.resolve(ctx, review, domain.RouteReviewApproved)
+}
+
+// RejectReview rejects the given review, the routes of the peer are not modified. Only admins of the interface can
+// reject reviews.
+func (m Manager) RejectReview(ctx context.Context, id domain.RouteReviewIdentifier) (*domain.RouteReview, error) {
+	review, err := m.getReview(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.validateInterfaceAdmin(ctx, review.InterfaceIdentifier); err != nil {
+		return nil, err
+	}
+	if !review.IsOpen() {
+		return nil, fmt.Errorf("route review %s is %s: %w", id, review.State, domain.ErrInvalidData)
+	}
+
+	return m.resolve(ctx, review, domain.RouteReviewRejected)
+}
+
+// CancelReview withdraws the given open review. Reviews can be cancelled by the owner of the peer, the requesting
+// user and by admins.
+func (m Manager) CancelReview(ctx context.Context, id domain.RouteReviewIdentifier) (*domain.RouteReview, error) {
+	review, err := m.getReview(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !review.IsOpen() {
+		return nil, fmt.Errorf("route review %s is %s: %w", id, review.State, domain.ErrInvalidData)
+	}
+
+	return m.resolve(ctx, review, domain.RouteReviewCancelled)
+}
+
+// getReview loads the given review and ensures that the current user is allowed to access it. Expired reviews are
+// resolved on access, so they can no longer be approved before the background job handled them.
+func (m Manager) getReview(ctx context.Context, id domain.RouteReviewIdentifier) (*domain.RouteReview, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+	review, err := m.db.GetRouteReview(systemCtx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load route review %s: %w", id, err)
+	}
+
+	sessionUser := domain.GetUserInfo(ctx)
+	if !isRequester(sessionUser, review) && !m.isInterfaceAdmin(ctx, review.InterfaceIdentifier) {
+		return nil, domain.ErrNoPermission
+	}
+
+	if review.IsExpired(time.Now()) {
+		if _, err := m.resolve(systemCtx, review, domain.RouteReviewExpired); err != nil {
+			return nil, err
+		}
+	}
+
+	return review, nil
+}
+
+// resolve sets the final state of the review.
+func (m Manager) resolve(
+	ctx context.Context,
+	review *domain.RouteReview,
+	state domain.RouteReviewState,
+) (*domain.RouteReview, error) {
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	review.Resolve(state, domain.GetUserInfo(ctx).Id, time.Now())
+	if err := m.db.SaveRouteReview(systemCtx, review); err != nil {
+		return nil, fmt.Errorf("failed to store route review: %w", err)
+	}
+
+	slog.Info("resolved route review", "review", review.Identifier, "peer", review.PeerId,
+		"state", review.State, "by", review.ResolvedBy)
+
+	return review, nil
+}
+
+// getInterface loads the interface with the given identifier regardless of the access rights of the current user.
+func (m Manager) getInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	iface, err := m.db.GetInterface(domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo()), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load interface %s: %w", id, err)
+	}
+
+	return iface, nil
+}
+
+// isInterfaceAdmin returns true if the current user administrates the interface with the given identifier.
+func (m Manager) isInterfaceAdmin(ctx context.Context, id domain.InterfaceIdentifier) bool {
+	iface, err := m.getInterface(ctx, id)
+	if err != nil {
+		return false
+	}
+
+	return domain.GetUserInfo(ctx).IsInterfaceAdmin(iface)
+}
+
+// validateInterfaceAdmin checks if the current user administrates the interface with the given identifier. Admins
+// of an organization can only administrate the interfaces of their organization.
+func (m Manager) validateInterfaceAdmin(ctx context.Context, id domain.InterfaceIdentifier) error {
+	iface, err := m.getInterface(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return domain.ValidateInterfaceAdminAccessRights(ctx, iface)
+}
+
+// isRequester returns true if the given user requested the routes or owns the peer of the review.
+func isRequester(user *domain.ContextUserInfo, review *domain.RouteReview) bool {
+	return user.Id == review.RequestedBy || user.Id == review.UserIdentifier
+}
+
+// normalizeRoutes returns the given comma separated routes in their canonical form.
+func normalizeRoutes(routesStr string) string {
+	routes, err := domain.CidrsFromArray(internal.SliceString(routesStr))
+	if err != nil {
+		return routesStr
+	}
+	for i := range routes {
+		routes[i] = routes[i].NetworkAddr()
+	}
+
+	return domain.CidrsToString(routes)
+}
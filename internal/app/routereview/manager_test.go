@@ -0,0 +1,224 @@
+package routereview
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	reviews map[domain.RouteReviewIdentifier]domain.RouteReview
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	return &domain.Interface{Identifier: id, OrganizationIdentifier: "org-a"}, nil
+}
+
+func (f *fakeDatabase) GetRouteReview(_ context.Context, id domain.RouteReviewIdentifier) (
+	*domain.RouteReview,
+	error,
+) {
+	review, ok := f.reviews[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &review, nil
+}
+
+func (f *fakeDatabase) GetRouteReviews(_ context.Context) ([]domain.RouteReview, error) {
+	reviews := make([]domain.RouteReview, 0, len(f.reviews))
+	for _, review := range f.reviews {
+		reviews = append(reviews, review)
+	}
+	return reviews, nil
+}
+
+func (f *fakeDatabase) SaveRouteReview(_ context.Context, review *domain.RouteReview) error {
+	f.reviews[review.Identifier] = *review
+	return nil
+}
+
+func (f *fakeDatabase) UpdateRouteReviewPeer(_ context.Context, oldId, newId domain.PeerIdentifier) error {
+	for id, review := range f.reviews {
+		if review.PeerId == oldId {
+			review.PeerId = newId
+			f.reviews[id] = review
+		}
+	}
+	return nil
+}
+
+type fakePeerManager struct {
+	peers map[domain.PeerIdentifier]domain.Peer
+}
+
+func (f *fakePeerManager) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &peer, nil
+}
+
+func (f *fakePeerManager) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	f.peers[peer.Identifier] = *peer
+	return peer, nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func newTestManager(t *testing.T) (*Manager, *fakePeerManager) {
+	cfg := &config.Config{}
+	cfg.RouteReview.Enabled = true
+	cfg.RouteReview.RequestTimeout = 24 * time.Hour
+
+	db := &fakeDatabase{reviews: map[domain.RouteReviewIdentifier]domain.RouteReview{}}
+	peers := &fakePeerManager{peers: map[domain.PeerIdentifier]domain.Peer{
+		"peer1": {
+			Identifier:          "peer1",
+			InterfaceIdentifier: "wg0",
+			UserIdentifier:      "alice",
+			ExtraAllowedIPsStr:  "10.1.0.0/24",
+		},
+	}}
+
+	m, err := NewRouteReviewManager(cfg, fakeBus{}, db, peers)
+	require.NoError(t, err)
+
+	return m, peers
+}
+
+func userContext(id domain.UserIdentifier, admin bool) context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: id, IsAdmin: admin})
+}
+
+// requestReview creates a review like the WireGuard manager does for the given routes.
+func requestReview(t *testing.T, m *Manager, routes string) domain.RouteReview {
+	review := domain.RouteReview{
+		PeerId:              "peer1",
+		InterfaceIdentifier: "wg0",
+		UserIdentifier:      "alice",
+		RoutesStr:           routes,
+		PreviousRoutesStr:   "10.1.0.0/24",
+		State:               domain.RouteReviewPending,
+		RequestedBy:         "alice",
+		RequestedAt:         time.Now(),
+	}
+	require.NoError(t, m.createReview(userContext("system", true), &review))
+	return review
+}
+
+func TestManager_ApproveReview(t *testing.T) {
+	m, peers := newTestManager(t)
+
+	superseded := requestReview(t, m, "10.1.0.0/24,0.0.0.0/0")
+	review := requestReview(t, m, "10.1.0.0/24,172.16.0.0/12")
+	assert.Equal(t, time.Hour*24, review.ExpiresAt.Sub(review.RequestedAt))
+
+	_, err := m.ApproveReview(userContext("admin", true), superseded.Identifier)
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "only the most recent review of a peer stays open")
+
+	_, err = m.ApproveReview(userContext("alice", false), review.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "users cannot approve their own routes")
+
+	_, err = m.ApproveReview(userContext("bob", false), review.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	approved, err := m.ApproveReview(userContext("admin", true), review.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RouteReviewApproved, approved.State)
+	assert.Equal(t, "10.1.0.0/24,172.16.0.0/12", peers.peers["peer1"].ExtraAllowedIPsStr)
+
+	_, err = m.RejectReview(userContext("admin", true), review.Identifier)
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "resolved reviews cannot be rejected")
+}
+
+func TestManager_ApproveReview_otherOrganization(t *testing.T) {
+	m, peers := newTestManager(t)
+
+	review := requestReview(t, m, "10.1.0.0/24,172.16.0.0/12")
+
+	orgAdminCtx := func(org domain.OrganizationIdentifier) context.Context {
+		return domain.SetUserInfo(context.Background(),
+			&domain.ContextUserInfo{Id: "org-admin", IsAdmin: true, Organization: org})
+	}
+
+	_, err := m.ApproveReview(orgAdminCtx("org-b"), review.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "admins of other organizations cannot approve reviews")
+	_, err = m.RejectReview(orgAdminCtx("org-b"), review.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	reviews, err := m.GetRouteReviews(orgAdminCtx("org-b"))
+	require.NoError(t, err)
+	assert.Empty(t, reviews)
+
+	approved, err := m.ApproveReview(orgAdminCtx("org-a"), review.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RouteReviewApproved, approved.State)
+	assert.Equal(t, "10.1.0.0/24,172.16.0.0/12", peers.peers["peer1"].ExtraAllowedIPsStr)
+}
+
+func TestManager_ApproveReview_routesChanged(t *testing.T) {
+	m, peers := newTestManager(t)
+
+	review := requestReview(t, m, "10.1.0.0/24,172.16.0.0/12")
+
+	// an admin changed the routes of the peer while the review was pending
+	peer := peers.peers["peer1"]
+	peer.ExtraAllowedIPsStr = "10.2.0.0/24"
+	peers.peers["peer1"] = peer
+
+	_, err := m.ApproveReview(userContext("admin", true), review.Identifier)
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+	assert.Equal(t, "10.2.0.0/24", peers.peers["peer1"].ExtraAllowedIPsStr)
+
+	reviews, err := m.GetRouteReviews(userContext("alice", false))
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	assert.Equal(t, domain.RouteReviewSuperseded, reviews[0].State)
+}
+
+func TestManager_CancelAndRejectReview(t *testing.T) {
+	m, peers := newTestManager(t)
+
+	review := requestReview(t, m, "0.0.0.0/0")
+	_, err := m.RejectReview(userContext("alice", false), review.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	cancelled, err := m.CancelReview(userContext("alice", false), review.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RouteReviewCancelled, cancelled.State)
+
+	review = requestReview(t, m, "0.0.0.0/0")
+	rejected, err := m.RejectReview(userContext("admin", true), review.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RouteReviewRejected, rejected.State)
+	assert.Equal(t, "10.1.0.0/24", peers.peers["peer1"].ExtraAllowedIPsStr)
+
+	reviews, err := m.GetRouteReviews(userContext("bob", false))
+	require.NoError(t, err)
+	assert.Empty(t, reviews)
+}
+
+func TestManager_expireReviews(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	review := requestReview(t, m, "0.0.0.0/0")
+	require.NoError(t, m.expireReviews(userContext("admin", true), time.Now().Add(48*time.Hour)))
+
+	_, err := m.ApproveReview(userContext("admin", true), review.Identifier)
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "expired reviews cannot be approved")
+
+	reviews, err := m.GetRouteReviews(userContext("alice", false))
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	assert.Equal(t, domain.RouteReviewExpired, reviews[0].State)
+}
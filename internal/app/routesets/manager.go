@@ -0,0 +1,239 @@
+package routesets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetRouteSet returns the route set with the given identifier
+	GetRouteSet(ctx context.Context, id domain.RouteSetIdentifier) (*domain.RouteSet, error)
+	// GetAllRouteSets returns all route sets
+	GetAllRouteSets(ctx context.Context) ([]domain.RouteSet, error)
+	// SaveRouteSet creates or updates the route set with the given identifier
+	SaveRouteSet(
+		ctx context.Context,
+		id domain.RouteSetIdentifier,
+		updateFunc func(rs *domain.RouteSet) (*domain.RouteSet, error),
+	) error
+	// DeleteRouteSet deletes the route set with the given identifier
+	DeleteRouteSet(ctx context.Context, id domain.RouteSetIdentifier) error
+	// GetAllInterfaces returns all interfaces
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers for a given interface
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+}
+
+type MailManager interface {
+	// SendPeerEmail sends the configuration of the given peers to the linked users.
+	SendPeerEmail(ctx context.Context, linkOnly bool, peers ...domain.PeerIdentifier) error
+}
+
+// endregion dependencies
+
+// Manager manages named route sets. The networks of route sets are published to peers by adding them to the
+// AllowedIPs of the generated peer configuration.
+type Manager struct {
+	cfg *config.Config
+
+	db   DatabaseRepo
+	mail MailManager
+}
+
+// NewRouteSetManager creates a new route set manager instance.
+func NewRouteSetManager(cfg *config.Config, db DatabaseRepo, mail MailManager) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+
+		db:   db,
+		mail: mail,
+	}
+
+	return m, nil
+}
+
+// GetAllRouteSets returns all route sets.
+func (m Manager) GetAllRouteSets(ctx context.Context) ([]domain.RouteSet, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetAllRouteSets(ctx)
+}
+
+// GetRouteSet returns the route set with the given identifier.
+func (m Manager) GetRouteSet(ctx context.Context, id domain.RouteSetIdentifier) (*domain.RouteSet, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetRouteSet(ctx, id)
+}
+
+// CreateRouteSet creates a new route set.
+func (m Manager) CreateRouteSet(ctx context.Context, routeSet *domain.RouteSet) (*domain.RouteSet, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := routeSet.Validate(); err != nil {
+		return nil, errors.Join(fmt.Errorf("invalid route set: %w", err), domain.ErrInvalidData)
+	}
+
+	existingRouteSet, err := m.db.GetRouteSet(ctx, routeSet.Identifier)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("unable to load existing route set %s: %w", routeSet.Identifier, err)
+	}
+	if existingRouteSet != nil {
+		return nil, errors.Join(fmt.Errorf("route set %s already exists", routeSet.Identifier),
+			domain.ErrDuplicateEntry)
+	}
+
+	err = m.db.SaveRouteSet(ctx, routeSet.Identifier, func(rs *domain.RouteSet) (*domain.RouteSet, error) {
+		routeSet.BaseModel = rs.BaseModel
+		return routeSet, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creation failure: %w", err)
+	}
+
+	// route sets might already be referenced by peers or interface defaults
+	m.notifyAffectedPeers(ctx, routeSet.Identifier)
+
+	return routeSet, nil
+}
+
+// UpdateRouteSet updates the given route set.
+// If the networks of the route set changed, the new configuration is sent to the users of all affected peers.
+func (m Manager) UpdateRouteSet(ctx context.Context, routeSet *domain.RouteSet) (*domain.RouteSet, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := routeSet.Validate(); err != nil {
+		return nil, errors.Join(fmt.Errorf("invalid route set: %w", err), domain.ErrInvalidData)
+	}
+
+	existingRouteSet, err := m.db.GetRouteSet(ctx, routeSet.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load existing route set %s: %w", routeSet.Identifier, err)
+	}
+
+	err = m.db.SaveRouteSet(ctx, routeSet.Identifier, func(rs *domain.RouteSet) (*domain.RouteSet, error) {
+		routeSet.BaseModel = rs.BaseModel
+		return routeSet, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update failure: %w", err)
+	}
+
+	if !sameNetworks(existingRouteSet, routeSet) {
+		m.notifyAffectedPeers(ctx, routeSet.Identifier)
+	}
+
+	return routeSet, nil
+}
+
+// DeleteRouteSet deletes the route set with the given identifier.
+// References to the deleted route set are ignored when generating peer configurations.
+func (m Manager) DeleteRouteSet(ctx context.Context, id domain.RouteSetIdentifier) error {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	if _, err := m.db.GetRouteSet(ctx, id); err != nil {
+		return fmt.Errorf("unable to find route set %s: %w", id, err)
+	}
+
+	if err := m.db.DeleteRouteSet(ctx, id); err != nil {
+		return fmt.Errorf("deletion failure: %w", err)
+	}
+
+	m.notifyAffectedPeers(ctx, id)
+
+	return nil
+}
+
+// GetRouteSetPeers returns all peers that use the route set with the given identifier.
+func (m Manager) GetRouteSetPeers(ctx context.Context, id domain.RouteSetIdentifier) ([]domain.Peer, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load interfaces: %w", err)
+	}
+
+	var peers []domain.Peer
+	for _, iface := range interfaces {
+		if iface.Type == domain.InterfaceTypeClient {
+			continue // peers of client interfaces are remote endpoints, their configuration is not generated
+		}
+
+		interfacePeers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load peers for %s: %w", iface.Identifier, err)
+		}
+
+		for _, peer := range interfacePeers {
+			if peer.HasRouteSet(id) {
+				peers = append(peers, peer)
+			}
+		}
+	}
+
+	return peers, nil
+}
+
+// notifyAffectedPeers sends the updated configuration to the users of all peers that use the given route set.
+// Mails are sent in the background, errors are only logged.
+func (m Manager) notifyAffectedPeers(ctx context.Context, id domain.RouteSetIdentifier) {
+	peers, err := m.GetRouteSetPeers(ctx, id)
+	if err != nil {
+		slog.Error("failed to find peers of route set", "routeSet", id, "error", err)
+		return
+	}
+	if len(peers) == 0 {
+		return
+	}
+
+	slog.Debug("notifying users about changed route set", "routeSet", id, "peers", len(peers))
+
+	// the request context is canceled once the response is sent
+	mailCtx := domain.SetUserInfo(context.Background(), domain.GetUserInfo(ctx))
+	go func() {
+		for _, peer := range peers {
+			if peer.IsDisabled() || peer.UserIdentifier == "" {
+				continue
+			}
+
+			if err := m.mail.SendPeerEmail(mailCtx, m.cfg.Mail.LinkOnly, peer.Identifier); err != nil {
+				slog.Error("failed to send updated peer configuration",
+					"routeSet", id, "peer", peer.Identifier, "error", err)
+			}
+		}
+	}()
+}
+
+func sameNetworks(a, b *domain.RouteSet) bool {
+	networksA := a.Networks()
+	networksB := b.Networks()
+	if len(networksA) != len(networksB) {
+		return false
+	}
+	for i := range networksA {
+		if networksA[i].String() != networksB[i].String() {
+			return false
+		}
+	}
+
+	return true
+}
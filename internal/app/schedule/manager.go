@@ -0,0 +1,228 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers for the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+}
+
+type PeerService interface {
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager enforces the access schedules of peers. Peers are disabled outside their access windows and enabled
+// again once a window starts. Disabled peers are removed from the WireGuard interface, so their traffic is blocked
+// and their routes and firewall rules are removed. Only peers that were disabled by the schedule are re-enabled.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db    DatabaseRepo
+	peers PeerService
+
+	state *scheduleState
+}
+
+// scheduleState holds the schedule settings that are replaced if the config is reloaded.
+type scheduleState struct {
+	mux      sync.RWMutex
+	location *time.Location // default time zone for peers without time zone
+	interval time.Duration
+}
+
+// NewScheduleManager creates a new peer access schedule manager.
+func NewScheduleManager(cfg *config.Config, bus EventBus, db DatabaseRepo, peers PeerService) (*Manager, error) {
+	location, err := domain.LoadScheduleLocation(cfg.Advanced.ScheduleTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schedule time zone: %w", err)
+	}
+
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:    db,
+		peers: peers,
+
+		state: &scheduleState{
+			location: location,
+			interval: cfg.Advanced.ScheduleCheckInterval,
+		},
+	}
+
+	if cfg.Advanced.ScheduleCheckInterval > 0 {
+		m.connectToMessageBus()
+	}
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background jobs for the schedule manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if m.cfg.Advanced.ScheduleCheckInterval <= 0 {
+		return
+	}
+
+	go m.runScheduleCheck(ctx)
+
+	slog.Debug("started peer access schedule checks",
+		"interval", m.cfg.Advanced.ScheduleCheckInterval, "timezone", m.state.location)
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerCreated, m.handlePeerChangedEvent)
+	_ = m.bus.Subscribe(app.TopicPeerUpdated, m.handlePeerChangedEvent)
+	_ = m.bus.Subscribe(app.TopicConfigReloaded, m.handleConfigReloadedEvent)
+}
+
+// settings returns the current default time zone and check interval.
+func (m Manager) settings() (*time.Location, time.Duration) {
+	m.state.mux.RLock()
+	defer m.state.mux.RUnlock()
+
+	return m.state.location, m.state.interval
+}
+
+// handleConfigReloadedEvent applies the reloaded time zone and check interval. Enabling or disabling the schedule
+// checks requires a restart.
+func (m Manager) handleConfigReloadedEvent(cfg *config.Config) {
+	slog.Debug("handling config reloaded event", "section", "schedule")
+
+	location, err := domain.LoadScheduleLocation(cfg.Advanced.ScheduleTimezone)
+	if err != nil {
+		slog.Error("failed to reload schedule time zone, keeping the current settings", "error", err)
+		return
+	}
+	interval := cfg.Advanced.ScheduleCheckInterval
+	if interval <= 0 {
+		slog.Warn("disabling the peer access schedule checks requires a restart")
+		_, interval = m.settings()
+	}
+
+	m.state.mux.Lock()
+	m.state.location = location
+	m.state.interval = interval
+	m.state.mux.Unlock()
+
+	slog.Info("reloaded peer access schedule settings", "interval", interval, "timezone", location)
+}
+
+// handlePeerChangedEvent applies the schedule right away, so a changed schedule does not wait for the next check.
+func (m Manager) handlePeerChangedEvent(peer domain.Peer) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	m.applySchedule(ctx, time.Now(), peer)
+}
+
+func (m Manager) runScheduleCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		m.checkSchedules(ctx, time.Now())
+
+		_, interval := m.settings()
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-time.After(interval):
+			// select blocks until one of the cases evaluate to true
+		}
+	}
+}
+
+func (m Manager) checkSchedules(ctx context.Context, now time.Time) {
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		slog.Error("failed to fetch all interfaces for schedule check", "error", err)
+		return
+	}
+
+	for _, iface := range interfaces {
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			slog.Error("failed to fetch all peers from interface for schedule check",
+				"interface", iface.Identifier,
+				"error", err)
+			continue
+		}
+
+		for _, peer := range peers {
+			m.applySchedule(ctx, now, peer)
+		}
+	}
+}
+
+func (m Manager) applySchedule(ctx context.Context, now time.Time, peer domain.Peer) {
+	disable, enable, err := m.scheduleTransition(now, &peer)
+	if err != nil {
+		slog.Warn("invalid peer access schedule", "peer", peer.Identifier, "error", err)
+		return
+	}
+
+	switch {
+	case disable:
+		slog.Info("peer is outside of its access schedule, disabling", "peer", peer.Identifier)
+		peer.Disabled = &now
+		peer.DisabledReason = domain.DisabledReasonSchedule
+	case enable:
+		slog.Info("peer is within its access schedule, enabling", "peer", peer.Identifier)
+		peer.Disabled = nil
+		peer.DisabledReason = ""
+	default:
+		return
+	}
+
+	if _, err := m.peers.UpdatePeer(ctx, &peer); err != nil {
+		slog.Error("failed to update scheduled peer", "peer", peer.Identifier, "error", err)
+	}
+}
+
+// scheduleTransition returns whether the peer has to be disabled or enabled at the given time.
+// Peers that were disabled for other reasons are never enabled.
+func (m Manager) scheduleTransition(now time.Time, peer *domain.Peer) (disable, enable bool, err error) {
+	disabledBySchedule := peer.IsDisabled() && peer.DisabledReason == domain.DisabledReasonSchedule
+
+	if !peer.HasAccessSchedule() {
+		return false, disabledBySchedule && !peer.IsExpired(), nil // the schedule has been removed
+	}
+
+	location, _ := m.settings()
+	allowed, err := peer.IsWithinAccessSchedule(now, location)
+	if err != nil {
+		return false, false, err
+	}
+
+	switch {
+	case !allowed && !peer.IsDisabled():
+		return true, false, nil
+	case allowed && disabledBySchedule && !peer.IsExpired():
+		return false, true, nil
+	default:
+		return false, false, nil
+	}
+}
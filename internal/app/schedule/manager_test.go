@@ -0,0 +1,79 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	peers []domain.Peer
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return []domain.Interface{{Identifier: "wg0"}}, nil
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	return f.peers, nil
+}
+
+type fakePeerService struct {
+	updated map[domain.PeerIdentifier]domain.Peer
+}
+
+func (f *fakePeerService) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	f.updated[peer.Identifier] = *peer
+	return peer, nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func TestManager_checkSchedules(t *testing.T) {
+	now := time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC) // saturday
+	past := now.Add(-time.Hour)
+
+	weekdays := "Mon-Fri 08:00-18:00"
+	db := &fakeDatabase{peers: []domain.Peer{
+		{Identifier: "outside", AccessScheduleStr: weekdays},
+		{Identifier: "inside", AccessScheduleStr: "Sat 10:00-14:00"},
+		{Identifier: "reopened", AccessScheduleStr: "Sat 10:00-14:00",
+			Disabled: &past, DisabledReason: domain.DisabledReasonSchedule},
+		{Identifier: "admin-disabled", AccessScheduleStr: "Sat 10:00-14:00",
+			Disabled: &past, DisabledReason: domain.DisabledReasonAdmin},
+		{Identifier: "unscheduled", Disabled: &past, DisabledReason: domain.DisabledReasonSchedule},
+		{Identifier: "invalid", AccessScheduleStr: "weekends"},
+		{Identifier: "other-zone", AccessScheduleStr: "Sat 06:00-08:00", AccessTimezone: "America/New_York"},
+	}}
+	peers := &fakePeerService{updated: make(map[domain.PeerIdentifier]domain.Peer)}
+
+	cfg := &config.Config{}
+	cfg.Advanced.ScheduleTimezone = "UTC"
+	m, err := NewScheduleManager(cfg, fakeBus{}, db, peers)
+	require.NoError(t, err)
+
+	m.checkSchedules(context.Background(), now)
+
+	assert.Len(t, peers.updated, 4)
+	assert.NotNil(t, peers.updated["outside"].Disabled)
+	assert.Equal(t, domain.DisabledReasonSchedule, peers.updated["outside"].DisabledReason)
+	assert.Nil(t, peers.updated["reopened"].Disabled)
+	assert.Nil(t, peers.updated["unscheduled"].Disabled, "peers without schedule are enabled again")
+	assert.NotNil(t, peers.updated["other-zone"].Disabled, "08:00 in New York is outside of the window")
+}
+
+func TestNewScheduleManager_invalidTimezone(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Advanced.ScheduleTimezone = "Nowhere/City"
+
+	_, err := NewScheduleManager(cfg, fakeBus{}, &fakeDatabase{}, &fakePeerService{})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
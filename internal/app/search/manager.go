@@ -0,0 +1,290 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	// MinQueryLength is the minimum length of a search query. Shorter queries return no results, so type-ahead
+	// requests for the first typed characters stay cheap.
+	MinQueryLength = 2
+	// DefaultLimit is the default number of results per result type.
+	DefaultLimit = 5
+	// MaxLimit is the maximum number of results per result type.
+	MaxLimit = 25
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllUsers returns all users.
+	GetAllUsers(ctx context.Context) ([]domain.User, error)
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers of the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	// GetUserPeers returns all peers linked to the given user.
+	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
+	// GetAllAuditEntries returns all audit entries, the newest entries first.
+	GetAllAuditEntries(ctx context.Context) ([]domain.AuditEntry, error)
+}
+
+// endregion dependencies
+
+// Manager implements the global search of the portal. It searches users, peers, interfaces and audit entries and
+// only returns the entities the current user is allowed to view:
+//   - users are only found by admins and helpdesk users, restricted to their organization
+//   - peers of the administrated interfaces are found by interface admins, helpdesk users find the peers of all
+//     users, all other users only find their own peers
+//   - interfaces are only found by their interface admins
+//   - audit entries are only found by global admins
+type Manager struct {
+	db DatabaseRepo
+}
+
+// NewSearchManager creates a new search manager instance.
+func NewSearchManager(db DatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		db: db,
+	}
+
+	return m, nil
+}
+
+// Search returns the entities that match the given query, at most limit results per result type. Users are found
+// by identifier, name and email, peers and interfaces by identifier, name, public key and ip address and audit
+// entries by user and message.
+func (m Manager) Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if len(query) < MinQueryLength {
+		return []domain.SearchResult{}, nil
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	currentUser := domain.GetUserInfo(ctx)
+	results := make([]domain.SearchResult, 0)
+
+	users, err := m.searchUsers(ctx, currentUser, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, users...)
+
+	peers, interfaces, err := m.searchPeersAndInterfaces(ctx, currentUser, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, peers...)
+	results = append(results, interfaces...)
+
+	entries, err := m.searchAuditEntries(ctx, currentUser, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, entries...)
+
+	return results, nil
+}
+
+func (m Manager) searchUsers(
+	ctx context.Context,
+	currentUser *domain.ContextUserInfo,
+	query string,
+	limit int,
+) ([]domain.SearchResult, error) {
+	if !currentUser.HasHelpdeskRights() {
+		return nil, nil
+	}
+
+	users, err := m.db.GetAllUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+
+	var results []domain.SearchResult
+	for _, user := range users {
+		if len(results) >= limit {
+			break
+		}
+		if !currentUser.CanAccessOrganization(user.OrganizationIdentifier) {
+			continue
+		}
+
+		detail, ok := match(query, string(user.Identifier), user.Email, user.WebAuthnDisplayName())
+		if !ok {
+			continue
+		}
+		results = append(results, domain.SearchResult{
+			Type:       domain.SearchResultTypeUser,
+			Identifier: string(user.Identifier),
+			Title:      user.WebAuthnDisplayName(),
+			Detail:     detail,
+		})
+	}
+
+	return results, nil
+}
+
+func (m Manager) searchPeersAndInterfaces(
+	ctx context.Context,
+	currentUser *domain.ContextUserInfo,
+	query string,
+	limit int,
+) (peerResults, interfaceResults []domain.SearchResult, err error) {
+	if !currentUser.HasAdminInterfaces() && !currentUser.HasHelpdeskRights() {
+		peers, err := m.db.GetUserPeers(ctx, currentUser.Id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load peers of user %s: %w", currentUser.Id, err)
+		}
+		return matchPeers(query, limit, peers, nil), nil, nil
+	}
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	for _, iface := range interfaces {
+		if !currentUser.CanAccessOrganization(iface.OrganizationIdentifier) {
+			continue
+		}
+		isInterfaceAdmin := currentUser.IsInterfaceAdmin(&iface)
+
+		if isInterfaceAdmin && len(interfaceResults) < limit {
+			detail, ok := match(query, append([]string{string(iface.Identifier), iface.DisplayName, iface.PublicKey},
+				addrs(iface.Addresses)...)...)
+			if ok {
+				interfaceResults = append(interfaceResults, domain.SearchResult{
+					Type:       domain.SearchResultTypeInterface,
+					Identifier: string(iface.Identifier),
+					Title:      displayName(iface.DisplayName, string(iface.Identifier)),
+					Detail:     detail,
+				})
+			}
+		}
+
+		if len(peerResults) >= limit {
+			continue
+		}
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load peers of interface %s: %w", iface.Identifier, err)
+		}
+		peerResults = append(peerResults, matchPeers(query, limit-len(peerResults), peers, func(peer domain.Peer) bool {
+			switch {
+			case isInterfaceAdmin:
+				return true
+			case currentUser.HasHelpdeskRights():
+				return peer.UserIdentifier != "" // helpdesk users can view the peers of all users
+			default:
+				return peer.UserIdentifier == currentUser.Id
+			}
+		})...)
+	}
+
+	return peerResults, interfaceResults, nil
+}
+
+// matchPeers returns at most limit results for the peers that match the query. If visibleFunc is set, only peers
+// for which it returns true are matched.
+func matchPeers(
+	query string,
+	limit int,
+	peers []domain.Peer,
+	visibleFunc func(peer domain.Peer) bool,
+) []domain.SearchResult {
+	var results []domain.SearchResult
+	for _, peer := range peers {
+		if len(results) >= limit {
+			break
+		}
+		if visibleFunc != nil && !visibleFunc(peer) {
+			continue
+		}
+
+		detail, ok := match(query, append([]string{peer.DisplayName, string(peer.Identifier), peer.Interface.PublicKey},
+			addrs(peer.Interface.Addresses)...)...)
+		if !ok {
+			continue
+		}
+		results = append(results, domain.SearchResult{
+			Type:                domain.SearchResultTypePeer,
+			Identifier:          string(peer.Identifier),
+			Title:               displayName(peer.DisplayName, string(peer.Identifier)),
+			Detail:              detail,
+			InterfaceIdentifier: peer.InterfaceIdentifier,
+			UserIdentifier:      peer.UserIdentifier,
+		})
+	}
+
+	return results
+}
+
+func (m Manager) searchAuditEntries(
+	ctx context.Context,
+	currentUser *domain.ContextUserInfo,
+	query string,
+	limit int,
+) ([]domain.SearchResult, error) {
+	if !currentUser.IsGlobalAdmin() {
+		return nil, nil
+	}
+
+	entries, err := m.db.GetAllAuditEntries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load audit entries: %w", err)
+	}
+
+	var results []domain.SearchResult
+	for _, entry := range entries {
+		if len(results) >= limit {
+			break
+		}
+
+		if _, ok := match(query, entry.ContextUser, entry.Message); !ok {
+			continue
+		}
+		results = append(results, domain.SearchResult{
+			Type:       domain.SearchResultTypeAuditEntry,
+			Identifier: strconv.FormatUint(entry.UniqueId, 10),
+			Title:      entry.Origin,
+			Detail:     entry.Message,
+		})
+	}
+
+	return results, nil
+}
+
+// match returns the first of the given values that contains the lower case query, ignoring the case.
+func match(query string, values ...string) (string, bool) {
+	for _, value := range values {
+		if value != "" && strings.Contains(strings.ToLower(value), query) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// addrs returns the plain ip addresses of the given networks.
+func addrs(cidrs []domain.Cidr) []string {
+	result := make([]string, len(cidrs))
+	for i, cidr := range cidrs {
+		result[i] = cidr.Addr
+	}
+	return result
+}
+
+func displayName(name, identifier string) string {
+	if name != "" {
+		return name
+	}
+	return identifier
+}
@@ -0,0 +1,144 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	users      []domain.User
+	interfaces []domain.Interface
+	peers      []domain.Peer
+	entries    []domain.AuditEntry
+}
+
+func (f *fakeDatabase) GetAllUsers(_ context.Context) ([]domain.User, error) {
+	return f.users, nil
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	var peers []domain.Peer
+	for _, peer := range f.peers {
+		if peer.InterfaceIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+func (f *fakeDatabase) GetUserPeers(_ context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
+	var peers []domain.Peer
+	for _, peer := range f.peers {
+		if peer.UserIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+func (f *fakeDatabase) GetAllAuditEntries(_ context.Context) ([]domain.AuditEntry, error) {
+	return f.entries, nil
+}
+
+func newTestManager() *Manager {
+	db := &fakeDatabase{
+		users: []domain.User{
+			{Identifier: "alice", Firstname: "Alice", Lastname: "Lab", Email: "alice@example.com"},
+			{Identifier: "bob", Email: "bob@example.com", OrganizationIdentifier: "acme"},
+		},
+		interfaces: []domain.Interface{
+			{Identifier: "wg0", DisplayName: "Lab", Addresses: []domain.Cidr{{Cidr: "10.0.0.1/24", Addr: "10.0.0.1"}}},
+			{Identifier: "wg1", OrganizationIdentifier: "acme"},
+		},
+		peers: []domain.Peer{
+			{Identifier: "key-a", DisplayName: "alice laptop", UserIdentifier: "alice", InterfaceIdentifier: "wg0",
+				Interface: domain.PeerInterfaceConfig{Addresses: []domain.Cidr{{Cidr: "10.0.0.2/32", Addr: "10.0.0.2"}}}},
+			{Identifier: "key-b", DisplayName: "bob phone", UserIdentifier: "bob", InterfaceIdentifier: "wg1"},
+			{Identifier: "key-c", DisplayName: "lab router", InterfaceIdentifier: "wg0"},
+		},
+		entries: []domain.AuditEntry{{UniqueId: 7, ContextUser: "alice", Origin: "auth", Message: "login of alice"}},
+	}
+	m, _ := NewSearchManager(db)
+	return m
+}
+
+func resultIds(results []domain.SearchResult, resultType domain.SearchResultType) []string {
+	var ids []string
+	for _, result := range results {
+		if result.Type == resultType {
+			ids = append(ids, result.Identifier)
+		}
+	}
+	return ids
+}
+
+func TestManager_Search(t *testing.T) {
+	m := newTestManager()
+	adminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+
+	results, err := m.Search(adminCtx, "ALICE", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, resultIds(results, domain.SearchResultTypeUser))
+	assert.Equal(t, []string{"key-a"}, resultIds(results, domain.SearchResultTypePeer))
+	assert.Equal(t, []string{"7"}, resultIds(results, domain.SearchResultTypeAuditEntry))
+
+	results, err = m.Search(adminCtx, "10.0.0", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key-a"}, resultIds(results, domain.SearchResultTypePeer))
+	assert.Equal(t, []string{"wg0"}, resultIds(results, domain.SearchResultTypeInterface))
+	assert.Equal(t, domain.InterfaceIdentifier("wg0"), results[0].InterfaceIdentifier)
+
+	results, err = m.Search(adminCtx, "key", 1)
+	require.NoError(t, err)
+	assert.Len(t, resultIds(results, domain.SearchResultTypePeer), 1, "results are limited per type")
+
+	results, err = m.Search(adminCtx, "a", 0)
+	require.NoError(t, err)
+	assert.Empty(t, results, "queries must have a minimum length")
+}
+
+func TestManager_Search_permissions(t *testing.T) {
+	m := newTestManager()
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	results, err := m.Search(userCtx, "key", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key-a"}, resultIds(results, domain.SearchResultTypePeer), "users only find own peers")
+	results, err = m.Search(userCtx, "bob", 0)
+	require.NoError(t, err)
+	assert.Empty(t, results, "users cannot search other users")
+
+	helpdeskCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "carol", IsHelpdesk: true})
+	results, err = m.Search(helpdeskCtx, "key", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key-a", "key-b"}, resultIds(results, domain.SearchResultTypePeer),
+		"helpdesk users find user peers")
+	results, err = m.Search(helpdeskCtx, "wg", 0)
+	require.NoError(t, err)
+	assert.Empty(t, resultIds(results, domain.SearchResultTypeInterface))
+
+	ifaceAdminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "dave",
+		AdminInterfaces: []domain.InterfaceIdentifier{"wg1"}})
+	results, err = m.Search(ifaceAdminCtx, "key", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key-b"}, resultIds(results, domain.SearchResultTypePeer))
+	assert.Empty(t, resultIds(results, domain.SearchResultTypeUser))
+
+	orgAdminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "erin", IsAdmin: true,
+		Organization: "acme"})
+	results, err = m.Search(orgAdminCtx, "example.com", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, resultIds(results, domain.SearchResultTypeUser))
+	results, err = m.Search(orgAdminCtx, "alice", 0)
+	require.NoError(t, err)
+	assert.Empty(t, results, "organization admins neither find other organizations nor audit entries")
+}
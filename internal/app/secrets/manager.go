@@ -0,0 +1,187 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type SecretRepo interface {
+	// GetSecret returns the current value of the referenced secret.
+	GetSecret(ctx context.Context, ref domain.SecretReference) (string, error)
+}
+
+type EventBus interface {
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
+}
+
+// endregion dependencies
+
+// Manager replaces config values that reference a secret with the value of the secret. Referenced secrets are read
+// again periodically, rotated secrets are published to the message bus.
+type Manager struct {
+	cfg *config.Config
+
+	bus     EventBus
+	secrets SecretRepo
+
+	state *secretState
+}
+
+type secretState struct {
+	mux    sync.Mutex
+	fields []secretField
+}
+
+// secretField is a config value that references a secret.
+type secretField struct {
+	config.SecretField
+	ref   domain.SecretReference
+	value string // the last known value of the secret
+}
+
+// NewSecretManager creates a new secret manager. The secret references of all config values are validated, but not
+// resolved yet.
+func NewSecretManager(cfg *config.Config, bus EventBus, secrets SecretRepo) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+
+		bus:     bus,
+		secrets: secrets,
+
+		state: &secretState{},
+	}
+
+	fields, err := secretFields(cfg)
+	if err != nil {
+		return nil, err
+	}
+	m.state.fields = fields
+
+	return m, nil
+}
+
+// secretFields returns all config values that reference a secret.
+func secretFields(cfg *config.Config) ([]secretField, error) {
+	var fields []secretField
+	for _, field := range cfg.SecretFields() {
+		ref, ok, err := domain.ParseSecretReference(*field.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret reference for %s: %w", field.Name, err)
+		}
+		if !ok {
+			continue
+		}
+		fields = append(fields, secretField{SecretField: field, ref: ref})
+	}
+
+	return fields, nil
+}
+
+// ResolveSecrets replaces all config values that reference a secret with the value of the secret. It must be called
+// before the config values are used.
+func (m Manager) ResolveSecrets(ctx context.Context) error {
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	return m.resolve(ctx, m.state.fields)
+}
+
+// ResolveReloadedSecrets replaces the secret references of a reloaded config with the values of the secrets. The
+// references of the reloaded config are refreshed from now on.
+func (m Manager) ResolveReloadedSecrets(ctx context.Context, cfg *config.Config) error {
+	fields, err := secretFields(cfg)
+	if err != nil {
+		return err
+	}
+
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	if err := m.resolve(ctx, fields); err != nil {
+		return err
+	}
+	m.state.fields = fields
+
+	return nil
+}
+
+func (m Manager) resolve(ctx context.Context, fields []secretField) error {
+	for i, field := range fields {
+		value, err := m.secrets.GetSecret(ctx, field.ref)
+		if err != nil {
+			return fmt.Errorf("failed to resolve secret %s for %s: %w", field.ref, field.Name, err)
+		}
+		*field.Value = value
+		fields[i].value = value
+
+		slog.Debug("resolved secret config value", "field", field.Name, "provider", field.ref.Provider)
+	}
+
+	return nil
+}
+
+// StartBackgroundJobs starts the periodic refresh of the referenced secrets.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if m.cfg.Secrets.RefreshInterval <= 0 || len(m.state.fields) == 0 {
+		return
+	}
+
+	go m.runRefresh(ctx)
+}
+
+func (m Manager) runRefresh(ctx context.Context) {
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(m.cfg.Secrets.RefreshInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		m.refresh(ctx)
+	}
+}
+
+// refresh reads all referenced secrets again. Rotated secrets of reloadable config values are published, all other
+// rotated secrets are applied on the next restart. The config itself is not modified, as it is read concurrently.
+func (m Manager) refresh(ctx context.Context) {
+	m.state.mux.Lock()
+	defer m.state.mux.Unlock()
+
+	for i, field := range m.state.fields {
+		value, err := m.secrets.GetSecret(ctx, field.ref)
+		if err != nil {
+			slog.Error("failed to refresh secret", "field", field.Name, "provider", field.ref.Provider,
+				"error", err)
+			continue
+		}
+		if value == field.value {
+			continue
+		}
+		m.state.fields[i].value = value
+
+		if !field.Reloadable {
+			slog.Warn("secret was rotated, restart to apply the new value", "field", field.Name)
+			continue
+		}
+
+		slog.Info("secret was rotated", "field", field.Name)
+		m.bus.Publish(app.TopicSecretRotated, domain.SecretRotation{
+			Field: field.Name,
+			Value: domain.PrivateString(value),
+		})
+	}
+}
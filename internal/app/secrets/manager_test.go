@@ -0,0 +1,107 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeSecrets map[string]string // by reference
+
+func (f fakeSecrets) GetSecret(_ context.Context, ref domain.SecretReference) (string, error) {
+	value, ok := f[ref.String()]
+	if !ok {
+		return "", errors.New("secret not found")
+	}
+	return value, nil
+}
+
+type fakeBus struct {
+	rotations []domain.SecretRotation
+}
+
+func (f *fakeBus) Publish(topic string, args ...any) {
+	if topic == app.TopicSecretRotated {
+		f.rotations = append(f.rotations, args[0].(domain.SecretRotation))
+	}
+}
+
+func newTestConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Mail.Password = "vault://secret/data/wg-portal#smtp"
+	cfg.Database.DSN = "file:///run/secrets/dsn"
+	cfg.Database.EncryptionPassphrase = "plain-passphrase"
+	cfg.Auth.OpenIDConnect = []config.OpenIDConnectProvider{
+		{ProviderName: "Keycloak", ClientSecret: "env://OIDC_SECRET"},
+	}
+	return cfg
+}
+
+func TestManager_ResolveSecrets(t *testing.T) {
+	cfg := newTestConfig()
+	secrets := fakeSecrets{
+		"vault://secret/data/wg-portal#smtp": "smtp-password",
+		"file:///run/secrets/dsn":            "postgres://wg",
+		"env://OIDC_SECRET":                  "oidc-secret",
+	}
+
+	m, err := NewSecretManager(cfg, &fakeBus{}, secrets)
+	require.NoError(t, err)
+	require.NoError(t, m.ResolveSecrets(context.Background()))
+
+	assert.Equal(t, "smtp-password", cfg.Mail.Password)
+	assert.Equal(t, "postgres://wg", cfg.Database.DSN)
+	assert.Equal(t, "plain-passphrase", cfg.Database.EncryptionPassphrase)
+	assert.Equal(t, "oidc-secret", cfg.Auth.OpenIDConnect[0].ClientSecret)
+
+	delete(secrets, "env://OIDC_SECRET")
+	cfg = newTestConfig()
+	m, err = NewSecretManager(cfg, &fakeBus{}, secrets)
+	require.NoError(t, err)
+	assert.ErrorContains(t, m.ResolveSecrets(context.Background()), "auth.oidc.keycloak.client_secret")
+}
+
+func TestNewSecretManager_InvalidReference(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Mail.Password = "env://"
+
+	_, err := NewSecretManager(cfg, &fakeBus{}, fakeSecrets{})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
+
+func TestManager_refresh(t *testing.T) {
+	cfg := newTestConfig()
+	secrets := fakeSecrets{
+		"vault://secret/data/wg-portal#smtp": "smtp-password",
+		"file:///run/secrets/dsn":            "postgres://wg",
+		"env://OIDC_SECRET":                  "oidc-secret",
+	}
+	bus := &fakeBus{}
+
+	m, err := NewSecretManager(cfg, bus, secrets)
+	require.NoError(t, err)
+	require.NoError(t, m.ResolveSecrets(context.Background()))
+
+	m.refresh(context.Background())
+	assert.Empty(t, bus.rotations)
+
+	secrets["vault://secret/data/wg-portal#smtp"] = "rotated-password"
+	secrets["file:///run/secrets/dsn"] = "postgres://rotated"
+	m.refresh(context.Background())
+
+	// the DSN is not reloadable, it is applied on the next restart
+	require.Len(t, bus.rotations, 1)
+	assert.Equal(t, "mail.password", bus.rotations[0].Field)
+	assert.Equal(t, domain.PrivateString("rotated-password"), bus.rotations[0].Value)
+	assert.Equal(t, "smtp-password", cfg.Mail.Password, "the config must not be modified concurrently")
+
+	m.refresh(context.Background())
+	assert.Len(t, bus.rotations, 1)
+}
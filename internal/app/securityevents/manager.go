@@ -0,0 +1,429 @@
+package securityevents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// cleanupInterval is the interval in which expired security events are removed.
+const cleanupInterval = 1 * time.Hour
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetUser returns the user with the given identifier.
+	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	// GetSecurityEvents returns all security events, the most recent events first.
+	GetSecurityEvents(ctx context.Context) ([]domain.SecurityEvent, error)
+	// SaveSecurityEvent creates or updates the given security event.
+	SaveSecurityEvent(ctx context.Context, event *domain.SecurityEvent) error
+	// DeleteSecurityEvents deletes all security events that were created before the given time.
+	DeleteSecurityEvents(ctx context.Context, before time.Time) error
+}
+
+type EventBus interface {
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// rule is a parsed anomaly rule.
+type rule struct {
+	config.SecurityRuleConfig
+
+	severity  domain.SecurityEventSeverity
+	countries map[string]struct{}   // upper case country codes, only set for handshake_from_country rules
+	schedule  domain.AccessSchedule // only set for schedule based rules
+}
+
+// Manager evaluates the configured anomaly rules and records a security event for each match. Security events are
+// listed in a dedicated feed and published on the event bus, so that they are forwarded by the webhook.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db DatabaseRepo
+
+	rules     []rule
+	countries *domain.CountryDatabase // nil if no country database is configured
+	location  *time.Location          // the time zone of the rule schedules
+}
+
+// NewSecurityEventManager creates a new security event manager.
+func NewSecurityEventManager(cfg *config.Config, bus EventBus, db DatabaseRepo) (*Manager, error) {
+	rules, err := parseRules(cfg.SecurityEvents.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	timezone := cfg.SecurityEvents.Timezone
+	if timezone == "" {
+		timezone = cfg.Advanced.ScheduleTimezone
+	}
+	location, err := domain.LoadScheduleLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load security event time zone: %w", err)
+	}
+
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db: db,
+
+		rules:    rules,
+		location: location,
+	}
+
+	if !cfg.SecurityEvents.Enabled {
+		return m, nil
+	}
+
+	countryDatabase := cfg.SecurityEvents.CountryDatabase
+	if countryDatabase == "" {
+		countryDatabase = cfg.Roaming.CountryDatabase
+	}
+	if countryDatabase != "" {
+		countries, err := loadCountryDatabase(countryDatabase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load country database: %w", err)
+		}
+		m.countries = countries
+	}
+	for _, r := range rules {
+		if r.Condition == config.SecurityRuleHandshakeFromCountry && m.countries == nil {
+			return nil, fmt.Errorf("security rule %s requires a country database", r.Name)
+		}
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+// parseRules validates the rule configuration and parses the countries and schedules of the rules.
+func parseRules(rulesCfg []config.SecurityRuleConfig) ([]rule, error) {
+	rules := make([]rule, 0, len(rulesCfg))
+	seenRules := make(map[string]struct{}, len(rulesCfg))
+	for _, ruleCfg := range rulesCfg {
+		if ruleCfg.Name == "" {
+			return nil, errors.New("security rule without name")
+		}
+		if _, exists := seenRules[ruleCfg.Name]; exists {
+			return nil, fmt.Errorf("duplicate security rule %s", ruleCfg.Name)
+		}
+		seenRules[ruleCfg.Name] = struct{}{}
+
+		r := rule{SecurityRuleConfig: ruleCfg}
+
+		severity, err := domain.ParseSecurityEventSeverity(ruleCfg.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("security rule %s: %w", ruleCfg.Name, err)
+		}
+		r.severity = severity
+
+		switch ruleCfg.Condition {
+		case config.SecurityRuleHandshakeFromCountry:
+			if len(ruleCfg.Countries) == 0 {
+				return nil, fmt.Errorf("security rule %s has no countries", ruleCfg.Name)
+			}
+			r.countries = make(map[string]struct{}, len(ruleCfg.Countries))
+			for _, country := range ruleCfg.Countries {
+				r.countries[strings.ToUpper(strings.TrimSpace(country))] = struct{}{}
+			}
+		case config.SecurityRulePeerEnabledOutsideSchedule, config.SecurityRuleAdminCreatedWithinSchedule:
+			schedule, err := domain.ParseAccessSchedule(ruleCfg.Schedule)
+			if err != nil {
+				return nil, fmt.Errorf("security rule %s has an invalid schedule: %w", ruleCfg.Name, err)
+			}
+			if len(schedule) == 0 {
+				return nil, fmt.Errorf("security rule %s has no schedule", ruleCfg.Name)
+			}
+			r.schedule = schedule
+		default:
+			return nil, fmt.Errorf("security rule %s has unsupported condition %s", ruleCfg.Name, ruleCfg.Condition)
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// loadCountryDatabase reads the CSV country database from the given file.
+func loadCountryDatabase(path string) (*domain.CountryDatabase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return domain.ParseCountryDatabase(file)
+}
+
+// StartBackgroundJobs starts the background jobs for the security event manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.SecurityEvents.Enabled || m.cfg.SecurityEvents.Retention <= 0 {
+		return
+	}
+
+	go m.runCleanup(ctx)
+
+	slog.Debug("started security event cleanup", "retention", m.cfg.SecurityEvents.Retention)
+}
+
+func (m Manager) runCleanup(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		cutoff := time.Now().Add(-m.cfg.SecurityEvents.Retention)
+		if err := m.db.DeleteSecurityEvents(ctx, cutoff); err != nil {
+			slog.Warn("failed to remove expired security events", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return // program stopped
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerStateChanged, m.handlePeerStateChangedEvent)
+	_ = m.bus.Subscribe(app.TopicPeerEnabled, m.handlePeerEnabledEvent)
+	_ = m.bus.Subscribe(app.TopicUserCreated, m.handleUserCreatedEvent)
+}
+
+func (m Manager) handlePeerStateChangedEvent(change domain.PeerStateChange) {
+	if change.Kind != domain.PeerStateConnected && change.Kind != domain.PeerStateEndpointChanged {
+		return // disconnects have no new endpoint
+	}
+
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	events := m.checkHandshake(change)
+	if len(events) == 0 {
+		return
+	}
+
+	peer, err := m.db.GetPeer(ctx, change.PeerId)
+	if err != nil {
+		slog.Warn("failed to load peer for security event", "peer", change.PeerId, "error", err)
+	}
+	for _, event := range events {
+		if peer != nil {
+			event.UserIdentifier = peer.UserIdentifier
+		}
+		m.record(ctx, &event)
+	}
+}
+
+func (m Manager) handlePeerEnabledEvent(peer domain.Peer, actor domain.UserIdentifier) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	for _, event := range m.checkPeerEnabled(peer, actor, time.Now()) {
+		m.record(ctx, &event)
+	}
+}
+
+func (m Manager) handleUserCreatedEvent(user domain.User) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	for _, event := range m.checkUserCreated(user, time.Now()) {
+		m.record(ctx, &event)
+	}
+}
+
+// checkHandshake returns a security event for each handshake rule that matches the country of the new endpoint.
+func (m Manager) checkHandshake(change domain.PeerStateChange) []domain.SecurityEvent {
+	addr, ok := domain.EndpointAddr(change.Endpoint)
+	if !ok {
+		return nil
+	}
+	country := m.countries.Lookup(addr)
+	if country == "" {
+		return nil
+	}
+
+	var events []domain.SecurityEvent
+	for _, r := range m.rules {
+		if r.Condition != config.SecurityRuleHandshakeFromCountry {
+			continue
+		}
+		if _, blocked := r.countries[country]; !blocked {
+			continue
+		}
+
+		event := r.newEvent(change.Time, fmt.Sprintf("handshake of peer %s from %s (%s)", change.PeerId, addr,
+			country))
+		event.PeerId = change.PeerId
+		event.InterfaceId = change.InterfaceId
+		event.Endpoint = change.Endpoint
+		event.Country = country
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// checkPeerEnabled returns a security event for each rule whose schedule does not contain the time at which the peer
+// was enabled.
+func (m Manager) checkPeerEnabled(peer domain.Peer, actor domain.UserIdentifier, now time.Time) []domain.SecurityEvent {
+	localNow := now.In(m.location)
+
+	var events []domain.SecurityEvent
+	for _, r := range m.rules {
+		if r.Condition != config.SecurityRulePeerEnabledOutsideSchedule || r.schedule.Allows(localNow) {
+			continue
+		}
+
+		event := r.newEvent(now, fmt.Sprintf("peer %s was enabled by %s at %s", peer.Identifier, actor,
+			localNow.Format("Mon 15:04")))
+		event.PeerId = peer.Identifier
+		event.InterfaceId = peer.InterfaceIdentifier
+		event.UserIdentifier = peer.UserIdentifier
+		event.Actor = actor
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// checkUserCreated returns a security event for each rule whose schedule contains the time at which the
+// administrator account was created.
+func (m Manager) checkUserCreated(user domain.User, now time.Time) []domain.SecurityEvent {
+	if !user.IsAdmin {
+		return nil
+	}
+
+	localNow := now.In(m.location)
+
+	var events []domain.SecurityEvent
+	for _, r := range m.rules {
+		if r.Condition != config.SecurityRuleAdminCreatedWithinSchedule || !r.schedule.Allows(localNow) {
+			continue
+		}
+
+		event := r.newEvent(now, fmt.Sprintf("administrator %s was created at %s", user.Identifier,
+			localNow.Format("Mon 15:04")))
+		event.UserIdentifier = user.Identifier
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func (r rule) newEvent(now time.Time, message string) domain.SecurityEvent {
+	if r.Description != "" {
+		message = r.Description + ": " + message
+	}
+
+	return domain.SecurityEvent{
+		CreatedAt: now,
+		RuleName:  r.Name,
+		Condition: string(r.Condition),
+		Severity:  r.severity,
+		Message:   message,
+	}
+}
+
+// record stores the security event and publishes it on the event bus.
+func (m Manager) record(ctx context.Context, event *domain.SecurityEvent) {
+	if err := m.db.SaveSecurityEvent(ctx, event); err != nil {
+		slog.Error("failed to store security event", "rule", event.RuleName, "error", err)
+		return
+	}
+
+	slog.Warn("security event", "rule", event.RuleName, "severity", event.Severity, "message", event.Message)
+	m.bus.Publish(app.TopicSecurityEvent, *event)
+}
+
+// GetEvents returns all security events, the most recent events first. Admins of an organization only receive the
+// events about the interfaces and users of their organization.
+func (m Manager) GetEvents(ctx context.Context) ([]domain.SecurityEvent, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	events, err := m.db.GetSecurityEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load security events: %w", err)
+	}
+
+	currentUser := domain.GetUserInfo(ctx)
+	if currentUser.IsGlobalAdmin() {
+		return events, nil
+	}
+
+	return m.filterOrganizationEvents(ctx, currentUser, events)
+}
+
+// filterOrganizationEvents returns the events that belong to an organization the given user can access. Events are
+// owned by the organization of their interface or, if they are not about a peer, by the organization of their user.
+// Events of deleted interfaces and users, and events without an owner are only visible to global admins.
+func (m Manager) filterOrganizationEvents(
+	ctx context.Context,
+	currentUser *domain.ContextUserInfo,
+	events []domain.SecurityEvent,
+) ([]domain.SecurityEvent, error) {
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load interfaces: %w", err)
+	}
+	interfaceOrganizations := make(map[domain.InterfaceIdentifier]domain.OrganizationIdentifier, len(interfaces))
+	for _, iface := range interfaces {
+		interfaceOrganizations[iface.Identifier] = iface.OrganizationIdentifier
+	}
+
+	userOrganizations := make(map[domain.UserIdentifier]*domain.OrganizationIdentifier)
+	userOrganization := func(id domain.UserIdentifier) (domain.OrganizationIdentifier, bool) {
+		org, ok := userOrganizations[id]
+		if !ok {
+			if user, err := m.db.GetUser(ctx, id); err == nil {
+				org = &user.OrganizationIdentifier
+			}
+			userOrganizations[id] = org // also remember missing users
+		}
+		if org == nil {
+			return "", false
+		}
+		return *org, true
+	}
+
+	visible := make([]domain.SecurityEvent, 0, len(events))
+	for _, event := range events {
+		var org domain.OrganizationIdentifier
+		var ok bool
+		switch {
+		case event.InterfaceId != "":
+			org, ok = interfaceOrganizations[event.InterfaceId]
+		case event.UserIdentifier != "":
+			org, ok = userOrganization(event.UserIdentifier)
+		}
+		if ok && currentUser.CanAccessOrganization(org) {
+			visible = append(visible, event)
+		}
+	}
+
+	return visible, nil
+}
@@ -0,0 +1,219 @@
+package securityevents
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	peers      map[domain.PeerIdentifier]domain.Peer
+	interfaces []domain.Interface
+	users      map[domain.UserIdentifier]domain.User
+	events     []domain.SecurityEvent
+}
+
+func (f *fakeDatabase) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &peer, nil
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+func (f *fakeDatabase) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (f *fakeDatabase) GetSecurityEvents(_ context.Context) ([]domain.SecurityEvent, error) {
+	return f.events, nil
+}
+
+func (f *fakeDatabase) SaveSecurityEvent(_ context.Context, event *domain.SecurityEvent) error {
+	event.Id = uint64(len(f.events) + 1)
+	f.events = append(f.events, *event)
+	return nil
+}
+
+func (f *fakeDatabase) DeleteSecurityEvents(_ context.Context, _ time.Time) error {
+	return nil
+}
+
+type fakeBus struct {
+	published []any
+}
+
+func (f *fakeBus) Publish(_ string, args ...any) {
+	f.published = append(f.published, args...)
+}
+
+func (f *fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+var testRules = []config.SecurityRuleConfig{
+	{Name: "blocked-countries", Condition: config.SecurityRuleHandshakeFromCountry, Countries: []string{"at"},
+		Severity: "high"},
+	{Name: "business-hours", Condition: config.SecurityRulePeerEnabledOutsideSchedule,
+		Schedule: "Mon-Fri 08:00-18:00"},
+	{Name: "night-admins", Condition: config.SecurityRuleAdminCreatedWithinSchedule,
+		Schedule: "Mon-Sun 22:00-06:00", Description: "Admin created at night"},
+}
+
+func newTestManager(t *testing.T) (*Manager, *fakeDatabase, *fakeBus) {
+	countries, err := domain.ParseCountryDatabase(strings.NewReader(
+		"203.0.113.0,203.0.113.255,AT\n198.51.100.0,198.51.100.255,DE\n"))
+	require.NoError(t, err)
+
+	rules, err := parseRules(testRules)
+	require.NoError(t, err)
+
+	db := &fakeDatabase{peers: map[domain.PeerIdentifier]domain.Peer{
+		"peer1": {Identifier: "peer1", InterfaceIdentifier: "wg0", UserIdentifier: "alice"},
+	}}
+	bus := &fakeBus{}
+
+	m := &Manager{
+		cfg:       &config.Config{SecurityEvents: config.SecurityEventsConfig{Enabled: true, Rules: testRules}},
+		bus:       bus,
+		db:        db,
+		rules:     rules,
+		countries: countries,
+		location:  time.UTC,
+	}
+
+	return m, db, bus
+}
+
+func TestManager_handlePeerStateChangedEvent(t *testing.T) {
+	m, db, bus := newTestManager(t)
+
+	m.handlePeerStateChangedEvent(domain.PeerStateChange{PeerId: "peer1", InterfaceId: "wg0",
+		Kind: domain.PeerStateConnected, Endpoint: "198.51.100.7:51820"})
+	assert.Empty(t, db.events, "country is not blocked")
+
+	m.handlePeerStateChangedEvent(domain.PeerStateChange{PeerId: "peer1", InterfaceId: "wg0",
+		Kind: domain.PeerStateDisconnected, Endpoint: "203.0.113.7:51820"})
+	assert.Empty(t, db.events, "disconnects are ignored")
+
+	m.handlePeerStateChangedEvent(domain.PeerStateChange{PeerId: "peer1", InterfaceId: "wg0",
+		Kind: domain.PeerStateEndpointChanged, Endpoint: "203.0.113.7:51820"})
+	require.Len(t, db.events, 1)
+	event := db.events[0]
+	assert.Equal(t, "blocked-countries", event.RuleName)
+	assert.Equal(t, domain.SecurityEventSeverityHigh, event.Severity)
+	assert.Equal(t, "AT", event.Country)
+	assert.Equal(t, domain.UserIdentifier("alice"), event.UserIdentifier)
+
+	require.Len(t, bus.published, 1, "the event is forwarded to the webhook")
+	assert.Equal(t, event, bus.published[0])
+}
+
+func TestManager_checkPeerEnabled(t *testing.T) {
+	m, _, _ := newTestManager(t)
+	peer := domain.Peer{Identifier: "peer1", InterfaceIdentifier: "wg0", UserIdentifier: "alice"}
+
+	wednesdayNoon := time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+	assert.Empty(t, m.checkPeerEnabled(peer, "admin", wednesdayNoon))
+
+	saturday := time.Date(2024, 5, 18, 12, 0, 0, 0, time.UTC)
+	events := m.checkPeerEnabled(peer, "admin", saturday)
+	require.Len(t, events, 1)
+	assert.Equal(t, "business-hours", events[0].RuleName)
+	assert.Equal(t, domain.SecurityEventSeverityMedium, events[0].Severity, "default severity")
+	assert.Equal(t, domain.UserIdentifier("admin"), events[0].Actor)
+	assert.Equal(t, domain.PeerIdentifier("peer1"), events[0].PeerId)
+}
+
+func TestManager_checkUserCreated(t *testing.T) {
+	m, _, _ := newTestManager(t)
+
+	night := time.Date(2024, 5, 15, 2, 30, 0, 0, time.UTC)
+	assert.Empty(t, m.checkUserCreated(domain.User{Identifier: "bob"}, night), "only administrators are checked")
+
+	events := m.checkUserCreated(domain.User{Identifier: "eve", IsAdmin: true}, night)
+	require.Len(t, events, 1)
+	assert.Equal(t, "night-admins", events[0].RuleName)
+	assert.True(t, strings.HasPrefix(events[0].Message, "Admin created at night: "))
+
+	noon := time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+	assert.Empty(t, m.checkUserCreated(domain.User{Identifier: "eve", IsAdmin: true}, noon))
+}
+
+func TestManager_GetEvents_organization(t *testing.T) {
+	m, db, _ := newTestManager(t)
+	db.interfaces = []domain.Interface{
+		{Identifier: "wg0", OrganizationIdentifier: "org-a"},
+		{Identifier: "wg1", OrganizationIdentifier: "org-b"},
+	}
+	db.users = map[domain.UserIdentifier]domain.User{
+		"eve":     {Identifier: "eve", OrganizationIdentifier: "org-a"},
+		"mallory": {Identifier: "mallory", OrganizationIdentifier: "org-b"},
+	}
+	db.events = []domain.SecurityEvent{
+		{Id: 1, InterfaceId: "wg0", PeerId: "peer1", UserIdentifier: "mallory"},
+		{Id: 2, InterfaceId: "wg1", PeerId: "peer2", UserIdentifier: "eve"},
+		{Id: 3, UserIdentifier: "eve"},
+		{Id: 4, UserIdentifier: "mallory"},
+		{Id: 5, UserIdentifier: "deleted"},
+		{Id: 6, InterfaceId: "deleted", PeerId: "peer3"},
+	}
+
+	eventIds := func(events []domain.SecurityEvent) []uint64 {
+		ids := make([]uint64, len(events))
+		for i, event := range events {
+			ids[i] = event.Id
+		}
+		return ids
+	}
+
+	orgAdminCtx := domain.SetUserInfo(context.Background(),
+		&domain.ContextUserInfo{Id: "admin", IsAdmin: true, Organization: "org-a"})
+	events, err := m.GetEvents(orgAdminCtx)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 3}, eventIds(events), "events of peers belong to the organization of the interface")
+
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	events, err = m.GetEvents(adminCtx)
+	require.NoError(t, err)
+	assert.Len(t, events, 6)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "eve"})
+	_, err = m.GetEvents(userCtx)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestParseRules(t *testing.T) {
+	_, err := parseRules([]config.SecurityRuleConfig{{Name: "a", Condition: "unknown"}})
+	assert.Error(t, err)
+
+	_, err = parseRules([]config.SecurityRuleConfig{{Name: "a", Condition: config.SecurityRuleHandshakeFromCountry}})
+	assert.Error(t, err, "missing countries")
+
+	_, err = parseRules([]config.SecurityRuleConfig{
+		{Name: "a", Condition: config.SecurityRulePeerEnabledOutsideSchedule, Schedule: "Mon-Fri 8-18"},
+	})
+	assert.Error(t, err, "invalid schedule")
+
+	_, err = parseRules([]config.SecurityRuleConfig{
+		{Name: "a", Condition: config.SecurityRuleAdminCreatedWithinSchedule, Schedule: "Mon 00:00-06:00",
+			Severity: "critical"},
+	})
+	assert.Error(t, err, "unknown severity")
+
+	_, err = parseRules(append(testRules, testRules[0]))
+	assert.Error(t, err, "duplicate rule")
+}
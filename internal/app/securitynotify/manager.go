@@ -0,0 +1,194 @@
+package securitynotify
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	// loginDeviceRetention is the time after which unused login devices are forgotten.
+	loginDeviceRetention = 180 * 24 * time.Hour
+	// downloadNotificationCooldown suppresses repeated mails for the same peer and downloading user, for example
+	// if the configuration and the QR code are loaded one after another.
+	downloadNotificationCooldown = 15 * time.Minute
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetUserLoginDevices returns all known login devices of the given user.
+	GetUserLoginDevices(ctx context.Context, id domain.UserIdentifier) ([]domain.UserLoginDevice, error)
+	// SaveUserLoginDevice creates or updates the given login device.
+	SaveUserLoginDevice(ctx context.Context, device *domain.UserLoginDevice) error
+	// DeleteUserLoginDevice deletes the login device with the given identifier.
+	DeleteUserLoginDevice(ctx context.Context, id string) error
+	// DeleteUserLoginDevices deletes all login devices of the given user.
+	DeleteUserLoginDevices(ctx context.Context, id domain.UserIdentifier) error
+}
+
+type MailService interface {
+	// SendLoginNotification informs the given user about a login to their account from a new device.
+	SendLoginNotification(ctx context.Context, userId domain.UserIdentifier, device *domain.UserLoginDevice) error
+	// SendConfigDownloadNotification informs the owner of a peer about the download of the peer configuration.
+	SendConfigDownloadNotification(ctx context.Context, download *domain.PeerConfigDownload) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager sends security notification mails to users. Users are notified about logins from unknown combinations
+// of IP address and browser, and about downloads of their peer configurations.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db    DatabaseRepo
+	mails MailService
+
+	mux       *sync.Mutex
+	downloads map[string]time.Time // last download notification per peer and downloading user
+}
+
+// NewSecurityNotificationManager creates a new security notification manager.
+func NewSecurityNotificationManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	mails MailService,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:    db,
+		mails: mails,
+
+		mux:       &sync.Mutex{},
+		downloads: make(map[string]time.Time),
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	if m.cfg.Mail.LoginNotifications {
+		_ = m.bus.Subscribe(app.TopicAuditLoginSuccess, m.handleLoginEvent)
+	}
+	if m.cfg.Mail.ConfigDownloadNotifications {
+		_ = m.bus.Subscribe(app.TopicPeerConfigDownloaded, m.handleConfigDownloadedEvent)
+	}
+	_ = m.bus.Subscribe(app.TopicUserDeleted, m.handleUserDeletedEvent)
+}
+
+func (m Manager) handleLoginEvent(e domain.AuditEventWrapper[audit.AuthEvent]) {
+	client := domain.GetClientInfo(e.Ctx)
+	if client == nil {
+		return // the login did not originate from the web interface
+	}
+
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	m.checkLoginDevice(ctx, domain.UserIdentifier(e.Event.Username), *client, time.Now())
+}
+
+// checkLoginDevice records the device of the login and notifies the user if the device is unknown. The first login
+// of a user only records the device, as there is nothing to compare it with.
+func (m Manager) checkLoginDevice(
+	ctx context.Context,
+	userId domain.UserIdentifier,
+	client domain.ClientInfo,
+	now time.Time,
+) {
+	devices, err := m.db.GetUserLoginDevices(ctx, userId)
+	if err != nil {
+		slog.Error("failed to load login devices", "user", userId, "error", err)
+		return
+	}
+
+	device := domain.NewUserLoginDevice(userId, client, now)
+	isKnown := false
+	hasOtherDevices := false
+	for _, known := range devices {
+		switch {
+		case known.Identifier == device.Identifier:
+			device.FirstSeen = known.FirstSeen
+			isKnown = true
+		case now.Sub(known.LastSeen) > loginDeviceRetention:
+			if err := m.db.DeleteUserLoginDevice(ctx, known.Identifier); err != nil {
+				slog.Warn("failed to delete stale login device", "user", userId, "error", err)
+			}
+		default:
+			hasOtherDevices = true
+		}
+	}
+
+	if err := m.db.SaveUserLoginDevice(ctx, &device); err != nil {
+		slog.Error("failed to store login device", "user", userId, "error", err)
+		return
+	}
+
+	if isKnown || !hasOtherDevices {
+		return
+	}
+
+	slog.Info("login from new device", "user", userId, "ip", device.IpAddress, "browser", device.Browser)
+	if err := m.mails.SendLoginNotification(ctx, userId, &device); err != nil {
+		slog.Error("failed to send login notification", "user", userId, "error", err)
+	}
+}
+
+func (m Manager) handleConfigDownloadedEvent(download domain.PeerConfigDownload) {
+	if download.Peer.UserIdentifier == "" {
+		return // nobody to notify
+	}
+	if !m.shouldNotifyDownload(download) {
+		return
+	}
+
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	if err := m.mails.SendConfigDownloadNotification(ctx, &download); err != nil {
+		slog.Error("failed to send config download notification",
+			"peer", download.Peer.Identifier,
+			"error", err)
+	}
+}
+
+// shouldNotifyDownload returns false if a notification for the same peer and downloading user was sent recently.
+func (m Manager) shouldNotifyDownload(download domain.PeerConfigDownload) bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for key, notifiedAt := range m.downloads {
+		if download.DownloadedAt.Sub(notifiedAt) > downloadNotificationCooldown {
+			delete(m.downloads, key)
+		}
+	}
+
+	key := string(download.Peer.Identifier) + "/" + string(download.DownloadedBy)
+	if _, ok := m.downloads[key]; ok {
+		return false
+	}
+	m.downloads[key] = download.DownloadedAt
+
+	return true
+}
+
+func (m Manager) handleUserDeletedEvent(user domain.User) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	if err := m.db.DeleteUserLoginDevices(ctx, user.Identifier); err != nil {
+		slog.Error("failed to delete login devices of deleted user", "user", user.Identifier, "error", err)
+	}
+}
@@ -0,0 +1,171 @@
+package securitynotify
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app/audit"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	devices map[string]domain.UserLoginDevice
+}
+
+func (f *fakeDatabase) GetUserLoginDevices(_ context.Context, id domain.UserIdentifier) (
+	[]domain.UserLoginDevice,
+	error,
+) {
+	var devices []domain.UserLoginDevice
+	for _, device := range f.devices {
+		if device.UserIdentifier == id {
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+func (f *fakeDatabase) SaveUserLoginDevice(_ context.Context, device *domain.UserLoginDevice) error {
+	f.devices[device.Identifier] = *device
+	return nil
+}
+
+func (f *fakeDatabase) DeleteUserLoginDevice(_ context.Context, id string) error {
+	delete(f.devices, id)
+	return nil
+}
+
+func (f *fakeDatabase) DeleteUserLoginDevices(_ context.Context, id domain.UserIdentifier) error {
+	for key, device := range f.devices {
+		if device.UserIdentifier == id {
+			delete(f.devices, key)
+		}
+	}
+	return nil
+}
+
+type fakeMailService struct {
+	logins    []domain.UserLoginDevice
+	downloads []domain.PeerConfigDownload
+}
+
+func (f *fakeMailService) SendLoginNotification(
+	_ context.Context,
+	_ domain.UserIdentifier,
+	device *domain.UserLoginDevice,
+) error {
+	f.logins = append(f.logins, *device)
+	return nil
+}
+
+func (f *fakeMailService) SendConfigDownloadNotification(_ context.Context, download *domain.PeerConfigDownload) error {
+	f.downloads = append(f.downloads, *download)
+	return nil
+}
+
+func newTestManager() (*Manager, *fakeDatabase, *fakeMailService) {
+	db := &fakeDatabase{devices: map[string]domain.UserLoginDevice{}}
+	mails := &fakeMailService{}
+
+	m := &Manager{
+		cfg:       &config.Config{},
+		db:        db,
+		mails:     mails,
+		mux:       &sync.Mutex{},
+		downloads: map[string]time.Time{},
+	}
+
+	return m, db, mails
+}
+
+func loginEvent(user, ip, userAgent string) domain.AuditEventWrapper[audit.AuthEvent] {
+	ctx := domain.SetClientInfo(context.Background(), domain.ClientInfo{IpAddress: ip, UserAgent: userAgent})
+
+	return domain.AuditEventWrapper[audit.AuthEvent]{
+		Ctx:   ctx,
+		Event: audit.AuthEvent{Username: user},
+	}
+}
+
+func TestManager_handleLoginEvent(t *testing.T) {
+	m, db, mails := newTestManager()
+	firefox := "Mozilla/5.0 (X11; Linux x86_64) Firefox/126.0"
+	chrome := "Mozilla/5.0 (X11; Linux x86_64) Chrome/125.0.0.0 Safari/537.36"
+
+	m.handleLoginEvent(loginEvent("alice", "198.51.100.7", firefox))
+	assert.Empty(t, mails.logins, "the first login only records the device")
+	assert.Len(t, db.devices, 1)
+
+	m.handleLoginEvent(loginEvent("alice", "198.51.100.7", firefox))
+	assert.Empty(t, mails.logins, "known devices are not reported")
+
+	m.handleLoginEvent(loginEvent("alice", "198.51.100.7", chrome))
+	require.Len(t, mails.logins, 1)
+	assert.Equal(t, "Chrome", mails.logins[0].Browser)
+
+	m.handleLoginEvent(loginEvent("alice", "203.0.113.1", chrome))
+	require.Len(t, mails.logins, 2)
+	assert.Equal(t, "203.0.113.1", mails.logins[1].IpAddress)
+	assert.Len(t, db.devices, 3)
+
+	// logins without client information, for example from the REST API, are ignored
+	m.handleLoginEvent(domain.AuditEventWrapper[audit.AuthEvent]{
+		Ctx: context.Background(), Event: audit.AuthEvent{Username: "alice"},
+	})
+	assert.Len(t, db.devices, 3)
+
+	m.handleUserDeletedEvent(domain.User{Identifier: "alice"})
+	assert.Empty(t, db.devices)
+}
+
+func TestManager_checkLoginDevice_staleDevices(t *testing.T) {
+	m, db, mails := newTestManager()
+	ctx := context.Background()
+	now := time.Now()
+	client := domain.ClientInfo{IpAddress: "198.51.100.7"}
+
+	stale := domain.NewUserLoginDevice("alice", domain.ClientInfo{IpAddress: "203.0.113.1"},
+		now.Add(-loginDeviceRetention-time.Hour))
+	db.devices[stale.Identifier] = stale
+
+	m.checkLoginDevice(ctx, "alice", client, now)
+	assert.Empty(t, mails.logins, "forgotten devices do not count as known devices")
+	assert.NotContains(t, db.devices, stale.Identifier)
+
+	m.checkLoginDevice(ctx, "alice", client, now.Add(time.Hour))
+	device := db.devices[domain.LoginDeviceIdentifier("alice", client)]
+	assert.Equal(t, now, device.FirstSeen)
+	assert.Equal(t, now.Add(time.Hour), device.LastSeen)
+}
+
+func TestManager_handleConfigDownloadedEvent(t *testing.T) {
+	m, _, mails := newTestManager()
+	now := time.Now()
+	download := domain.PeerConfigDownload{
+		Peer:         domain.Peer{Identifier: "peer-a", UserIdentifier: "alice"},
+		DownloadedBy: "admin",
+		Format:       domain.PeerConfigFormatFile,
+		DownloadedAt: now,
+	}
+
+	m.handleConfigDownloadedEvent(download)
+	require.Len(t, mails.downloads, 1)
+
+	download.Format = domain.PeerConfigFormatQrCode
+	download.DownloadedAt = now.Add(time.Minute)
+	m.handleConfigDownloadedEvent(download)
+	assert.Len(t, mails.downloads, 1, "repeated downloads are only reported once")
+
+	download.DownloadedAt = now.Add(downloadNotificationCooldown + time.Minute)
+	m.handleConfigDownloadedEvent(download)
+	assert.Len(t, mails.downloads, 2)
+
+	m.handleConfigDownloadedEvent(domain.PeerConfigDownload{Peer: domain.Peer{Identifier: "peer-b"}})
+	assert.Len(t, mails.downloads, 2, "peers without owner are ignored")
+}
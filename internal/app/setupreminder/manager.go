@@ -0,0 +1,201 @@
+package setupreminder
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// checkInterval is the interval in which peers that never connected are checked.
+const checkInterval = 1 * time.Hour
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers for the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	// GetPeersStats returns the status of the given peers.
+	GetPeersStats(ctx context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error)
+	// GetPeerConfigVersions returns the mailed configuration versions of the given peer, ordered by version.
+	GetPeerConfigVersions(ctx context.Context, id domain.PeerIdentifier) ([]domain.PeerConfigVersion, error)
+	// GetPeerSetupReminders returns all stored peer setup reminders.
+	GetPeerSetupReminders(ctx context.Context) ([]domain.PeerSetupReminder, error)
+	// SavePeerSetupReminder creates or updates the given peer setup reminder.
+	SavePeerSetupReminder(ctx context.Context, reminder *domain.PeerSetupReminder) error
+	// DeletePeerSetupReminder deletes the peer setup reminder for the given peer id.
+	DeletePeerSetupReminder(ctx context.Context, id domain.PeerIdentifier) error
+}
+
+type MailManager interface {
+	// SendPeerSetupReminder sends an email with setup instructions to the owner of the given peer.
+	SendPeerSetupReminder(ctx context.Context, peer *domain.Peer, mailedAt time.Time) error
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager periodically checks for peers whose configuration was mailed but which never connected, and reminds their
+// owners by mail. Each owner is reminded once per peer.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db   DatabaseRepo
+	mail MailManager
+}
+
+// NewSetupReminderManager creates a new peer setup reminder manager.
+func NewSetupReminderManager(cfg *config.Config, bus EventBus, db DatabaseRepo, mail MailManager) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:   db,
+		mail: mail,
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background jobs for the setup reminder manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if m.cfg.Mail.SetupReminderDays <= 0 {
+		return
+	}
+	if !m.cfg.Statistics.CollectPeerData {
+		slog.Warn("peer setup reminders require peer data collection, reminders are disabled")
+		return
+	}
+
+	go m.runReminderCheck(ctx)
+
+	slog.Debug("started peer setup reminder checks", "days", m.cfg.Mail.SetupReminderDays)
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerDeletedEvent)
+}
+
+func (m Manager) handlePeerDeletedEvent(peer domain.Peer) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	err := m.db.DeletePeerSetupReminder(ctx, peer.Identifier)
+	if err != nil {
+		slog.Error("failed to delete setup reminder of deleted peer", "peer", peer.Identifier, "error", err)
+	}
+}
+
+func (m Manager) runReminderCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		if err := m.sendReminders(ctx, time.Now()); err != nil {
+			slog.Error("failed to send peer setup reminders", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return // program stopped
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendReminders reminds the owners of all peers that never connected within the reminder period after their
+// configuration was mailed first. Peers that were already reminded are skipped.
+func (m Manager) sendReminders(ctx context.Context, now time.Time) error {
+	reminders, err := m.db.GetPeerSetupReminders(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load setup reminders: %w", err)
+	}
+	reminded := make(map[domain.PeerIdentifier]struct{}, len(reminders))
+	for _, reminder := range reminders {
+		reminded[reminder.PeerId] = struct{}{}
+	}
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	reminderPeriod := time.Duration(m.cfg.Mail.SetupReminderDays) * 24 * time.Hour
+	for _, iface := range interfaces {
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			return fmt.Errorf("failed to load peers of interface %s: %w", iface.Identifier, err)
+		}
+
+		for _, peer := range peers {
+			if peer.UserIdentifier == "" || peer.IsDisabled() {
+				continue
+			}
+			if _, ok := reminded[peer.Identifier]; ok {
+				continue // already reminded
+			}
+
+			mailedAt, ok := m.neverConnectedSince(ctx, peer.Identifier)
+			if !ok || now.Sub(mailedAt) < reminderPeriod {
+				continue
+			}
+
+			if err := m.mail.SendPeerSetupReminder(ctx, &peer, mailedAt); err != nil {
+				slog.Warn("failed to send peer setup reminder", "peer", peer.Identifier, "error", err)
+				continue
+			}
+
+			err := m.db.SavePeerSetupReminder(ctx, &domain.PeerSetupReminder{
+				PeerId:     peer.Identifier,
+				MailedAt:   mailedAt,
+				RemindedAt: now,
+			})
+			if err != nil {
+				slog.Warn("failed to store peer setup reminder", "peer", peer.Identifier, "error", err)
+			}
+			slog.Info("sent peer setup reminder", "peer", peer.Identifier, "mailedAt", mailedAt)
+		}
+	}
+
+	return nil
+}
+
+// neverConnectedSince returns the time at which the configuration of the peer was mailed first. If the
+// configuration was never mailed, or the peer connected at least once, false is returned.
+func (m Manager) neverConnectedSince(ctx context.Context, id domain.PeerIdentifier) (time.Time, bool) {
+	versions, err := m.db.GetPeerConfigVersions(ctx, id)
+	if err != nil {
+		slog.Warn("failed to load peer config versions", "peer", id, "error", err)
+		return time.Time{}, false
+	}
+	if len(versions) == 0 {
+		return time.Time{}, false // the configuration was never mailed
+	}
+
+	stats, err := m.db.GetPeersStats(ctx, id)
+	if err != nil {
+		slog.Warn("failed to load peer status", "peer", id, "error", err)
+		return time.Time{}, false
+	}
+	for _, status := range stats {
+		if status.LastHandshake != nil {
+			return time.Time{}, false
+		}
+	}
+
+	return versions[0].MailedAt, true
+}
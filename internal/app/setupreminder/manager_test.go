@@ -0,0 +1,128 @@
+package setupreminder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	peers     []domain.Peer
+	stats     map[domain.PeerIdentifier]domain.PeerStatus
+	versions  map[domain.PeerIdentifier][]domain.PeerConfigVersion
+	reminders map[domain.PeerIdentifier]domain.PeerSetupReminder
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return []domain.Interface{{Identifier: "wg0"}}, nil
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	return f.peers, nil
+}
+
+func (f *fakeDatabase) GetPeersStats(_ context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error) {
+	var stats []domain.PeerStatus
+	for _, id := range ids {
+		if status, ok := f.stats[id]; ok {
+			stats = append(stats, status)
+		}
+	}
+	return stats, nil
+}
+
+func (f *fakeDatabase) GetPeerConfigVersions(_ context.Context, id domain.PeerIdentifier) (
+	[]domain.PeerConfigVersion,
+	error,
+) {
+	return f.versions[id], nil
+}
+
+func (f *fakeDatabase) GetPeerSetupReminders(_ context.Context) ([]domain.PeerSetupReminder, error) {
+	reminders := make([]domain.PeerSetupReminder, 0, len(f.reminders))
+	for _, reminder := range f.reminders {
+		reminders = append(reminders, reminder)
+	}
+	return reminders, nil
+}
+
+func (f *fakeDatabase) SavePeerSetupReminder(_ context.Context, reminder *domain.PeerSetupReminder) error {
+	f.reminders[reminder.PeerId] = *reminder
+	return nil
+}
+
+func (f *fakeDatabase) DeletePeerSetupReminder(_ context.Context, id domain.PeerIdentifier) error {
+	delete(f.reminders, id)
+	return nil
+}
+
+type fakeMailManager struct {
+	sent     []domain.PeerIdentifier
+	mailedAt []time.Time
+}
+
+func (f *fakeMailManager) SendPeerSetupReminder(_ context.Context, peer *domain.Peer, mailedAt time.Time) error {
+	f.sent = append(f.sent, peer.Identifier)
+	f.mailedAt = append(f.mailedAt, mailedAt)
+	return nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func TestManager_sendReminders(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	longAgo := now.Add(-10 * 24 * time.Hour)
+	recently := now.Add(-24 * time.Hour)
+	handshake := now.Add(-2 * 24 * time.Hour)
+
+	cfg := &config.Config{}
+	cfg.Mail.SetupReminderDays = 7
+
+	db := &fakeDatabase{
+		peers: []domain.Peer{
+			{Identifier: "unused", UserIdentifier: "alice"},
+			{Identifier: "connected", UserIdentifier: "alice"},
+			{Identifier: "recent", UserIdentifier: "alice"},
+			{Identifier: "never-mailed", UserIdentifier: "alice"},
+			{Identifier: "no-owner"},
+			{Identifier: "disabled", UserIdentifier: "alice", Disabled: &longAgo},
+		},
+		stats: map[domain.PeerIdentifier]domain.PeerStatus{
+			"unused":    {PeerId: "unused"},
+			"connected": {PeerId: "connected", LastHandshake: &handshake},
+		},
+		versions: map[domain.PeerIdentifier][]domain.PeerConfigVersion{
+			"unused":    {{PeerId: "unused", Version: 1, MailedAt: longAgo}, {PeerId: "unused", Version: 2, MailedAt: now}},
+			"connected": {{PeerId: "connected", Version: 1, MailedAt: longAgo}},
+			"recent":    {{PeerId: "recent", Version: 1, MailedAt: recently}},
+			"no-owner":  {{PeerId: "no-owner", Version: 1, MailedAt: longAgo}},
+			"disabled":  {{PeerId: "disabled", Version: 1, MailedAt: longAgo}},
+		},
+		reminders: map[domain.PeerIdentifier]domain.PeerSetupReminder{},
+	}
+	mail := &fakeMailManager{}
+
+	m, err := NewSetupReminderManager(cfg, fakeBus{}, db, mail)
+	require.NoError(t, err)
+
+	require.NoError(t, m.sendReminders(context.Background(), now))
+	assert.Equal(t, []domain.PeerIdentifier{"unused"}, mail.sent)
+	assert.Equal(t, []time.Time{longAgo}, mail.mailedAt, "the period starts with the first mailed configuration")
+	require.Contains(t, db.reminders, domain.PeerIdentifier("unused"))
+	assert.Equal(t, now, db.reminders["unused"].RemindedAt)
+
+	// each peer is reminded once
+	require.NoError(t, m.sendReminders(context.Background(), now.Add(24*time.Hour)))
+	assert.Len(t, mail.sent, 1)
+
+	m.handlePeerDeletedEvent(domain.Peer{Identifier: "unused"})
+	assert.Empty(t, db.reminders)
+}
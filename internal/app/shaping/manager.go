@@ -0,0 +1,511 @@
+//go:build linux
+
+package shaping
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+	"github.com/h44z/wg-portal/internal/lowlevel"
+)
+
+// region dependencies
+
+type InterfaceAndPeerDatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterface returns the interface with the given identifier
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetInterfacePeers returns all peers for a given interface
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+const (
+	rootQdiscMajor    uint16 = 1      // major number of the root HTB qdisc
+	ingressQdiscMajor uint16 = 0xffff // major number of the ingress qdisc
+	peerClassOffset   uint16 = 0x10   // first minor number that is used for peer classes
+
+	filterPrioV4 uint16 = 1 // tc filters for one priority must share the same protocol
+	filterPrioV6 uint16 = 2
+
+	shapingCheckInterval = 30 * time.Second
+)
+
+// Manager enforces the bandwidth limits of peers using Linux traffic control.
+// Traffic sent to a peer is shaped by an HTB class with a fq_codel leaf qdisc, traffic sent by a peer is policed
+// on the ingress side of the WireGuard interface.
+type Manager struct {
+	cfg *config.Config
+
+	bus EventBus
+	nl  lowlevel.NetlinkClient
+	db  InterfaceAndPeerDatabaseRepo
+}
+
+// NewShapingManager creates a new traffic shaping manager instance.
+func NewShapingManager(cfg *config.Config, bus EventBus, db InterfaceAndPeerDatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db: db,
+		nl: &lowlevel.NetlinkManager{},
+	}
+
+	if cfg.Advanced.BandwidthShaping {
+		m.connectToMessageBus()
+	}
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerInterfaceUpdated, m.handlePeerInterfaceUpdatedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceCreated, m.handleInterfaceUpdatedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceUpdated, m.handleInterfaceUpdatedEvent)
+}
+
+// StartBackgroundJobs starts background jobs for the traffic shaping manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.Advanced.BandwidthShaping {
+		return
+	}
+
+	go m.runShapingCheck(ctx)
+}
+
+func (m Manager) handlePeerInterfaceUpdatedEvent(id domain.InterfaceIdentifier) {
+	slog.Debug("handling peer interface updated event", "interface", id)
+
+	if err := m.syncInterface(context.Background(), id); err != nil {
+		slog.Error("failed to synchronize traffic shaping rules", "interface", id, "error", err)
+	}
+}
+
+func (m Manager) handleInterfaceUpdatedEvent(iface domain.Interface) {
+	slog.Debug("handling interface updated event", "interface", iface.Identifier)
+
+	if err := m.syncInterface(context.Background(), iface.Identifier); err != nil {
+		slog.Error("failed to synchronize traffic shaping rules", "interface", iface.Identifier, "error", err)
+	}
+}
+
+// runShapingCheck periodically ensures that the traffic shaping rules exist, for example after the WireGuard
+// interface was restored on startup or recreated.
+func (m Manager) runShapingCheck(ctx context.Context) {
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(shapingCheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		interfaces, err := m.db.GetAllInterfaces(ctx)
+		if err != nil {
+			slog.Error("failed to fetch interfaces for traffic shaping check", "error", err)
+			continue
+		}
+
+		for _, iface := range interfaces {
+			if iface.IsDisabled() {
+				continue
+			}
+
+			link, err := m.nl.LinkByName(string(iface.Identifier))
+			if err != nil {
+				continue // physical interface not available (yet)
+			}
+
+			peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+			if err != nil {
+				slog.Error("failed to fetch peers for traffic shaping check",
+					"interface", iface.Identifier, "error", err)
+				continue
+			}
+
+			if len(limitedPeers(peers)) == 0 || m.hasRootQdisc(link) {
+				continue
+			}
+
+			slog.Debug("traffic shaping rules missing, recreating", "interface", iface.Identifier)
+			if err := m.applyShaping(&iface, link, peers); err != nil {
+				slog.Error("failed to restore traffic shaping rules", "interface", iface.Identifier, "error", err)
+			}
+		}
+	}
+}
+
+// syncInterface rebuilds the traffic shaping rules of the given interface.
+func (m Manager) syncInterface(ctx context.Context, id domain.InterfaceIdentifier) error {
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to find interface %s: %w", id, err)
+	}
+
+	link, err := m.nl.LinkByName(string(id))
+	if err != nil {
+		slog.Debug("skipping traffic shaping, physical interface not found", "interface", id)
+		return nil
+	}
+
+	if iface.IsDisabled() {
+		return m.clearShaping(link)
+	}
+
+	peers, err := m.db.GetInterfacePeers(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to find peers for %s: %w", id, err)
+	}
+
+	return m.applyShaping(iface, link, peers)
+}
+
+func (m Manager) applyShaping(iface *domain.Interface, link netlink.Link, peers []domain.Peer) error {
+	if err := m.clearShaping(link); err != nil {
+		return err
+	}
+
+	limited := limitedPeers(peers)
+	if len(limited) == 0 {
+		return nil
+	}
+
+	linkIndex := link.Attrs().Index
+	rootHandle := netlink.MakeHandle(rootQdiscMajor, 0)
+	ingressHandle := netlink.MakeHandle(ingressQdiscMajor, 0)
+
+	err := m.nl.QdiscReplace(netlink.NewHtb(netlink.QdiscAttrs{
+		LinkIndex: linkIndex,
+		Handle:    rootHandle,
+		Parent:    netlink.HANDLE_ROOT,
+	})) // unclassified traffic is not shaped as the default class is 0
+	if err != nil {
+		return fmt.Errorf("failed to create root qdisc: %w", err)
+	}
+
+	err = m.nl.QdiscReplace(&netlink.Ingress{QdiscAttrs: netlink.QdiscAttrs{
+		LinkIndex: linkIndex,
+		Handle:    ingressHandle,
+		Parent:    netlink.HANDLE_INGRESS,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to create ingress qdisc: %w", err)
+	}
+
+	for i, peer := range limited {
+		classId := netlink.MakeHandle(rootQdiscMajor, peerClassOffset+uint16(i))
+		allowedIPs := iface.GetAllowedIPs([]domain.Peer{peer})
+
+		if limit := peer.DownloadLimit.GetValue(); limit > 0 {
+			if err := m.addDownloadShaper(linkIndex, classId, limit, allowedIPs); err != nil {
+				return fmt.Errorf("failed to set download limit for peer %s: %w", peer.Identifier, err)
+			}
+		}
+
+		if limit := peer.UploadLimit.GetValue(); limit > 0 {
+			if err := m.addUploadPolicer(linkIndex, classId, limit, allowedIPs); err != nil {
+				return fmt.Errorf("failed to set upload limit for peer %s: %w", peer.Identifier, err)
+			}
+		}
+	}
+
+	slog.Debug("traffic shaping rules applied", "interface", iface.Identifier, "peers", len(limited))
+
+	return nil
+}
+
+func (m Manager) addDownloadShaper(linkIndex int, classId uint32, limit int, allowedIPs []domain.Cidr) error {
+	rate := uint64(limit) * 1000 // bit/s
+	err := m.nl.ClassReplace(netlink.NewHtbClass(netlink.ClassAttrs{
+		LinkIndex: linkIndex,
+		Parent:    netlink.MakeHandle(rootQdiscMajor, 0),
+		Handle:    classId,
+	}, netlink.HtbClassAttrs{
+		Rate: rate,
+		Ceil: rate,
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to create class: %w", err)
+	}
+
+	_, minor := netlink.MajorMinor(classId)
+	err = m.nl.QdiscReplace(netlink.NewFqCodel(netlink.QdiscAttrs{
+		LinkIndex: linkIndex,
+		Parent:    classId,
+		Handle:    netlink.MakeHandle(minor, 0),
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to create leaf qdisc: %w", err)
+	}
+
+	for _, allowedIP := range allowedIPs {
+		sel, protocol, prio := addressSelector(allowedIP, false)
+		err := m.nl.FilterAdd(&netlink.U32{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: linkIndex,
+				Parent:    netlink.MakeHandle(rootQdiscMajor, 0),
+				Priority:  prio,
+				Protocol:  protocol,
+			},
+			ClassId: classId,
+			Sel:     sel,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create filter for %s: %w", allowedIP.String(), err)
+		}
+	}
+
+	return nil
+}
+
+func (m Manager) addUploadPolicer(linkIndex int, classId uint32, limit int, allowedIPs []domain.Cidr) error {
+	rate := uint32(limit) * 1000 / 8 // byte/s
+
+	for _, allowedIP := range allowedIPs {
+		police := netlink.NewPoliceAction()
+		// all filters of the peer share the same policer, so the index must be unique on the host
+		police.Index = linkIndex<<16 | int(classId&0xffff)
+		police.Rate = rate
+		police.Burst = max(rate/10, 16*1024) // allow bursts of 100ms
+		police.ExceedAction = netlink.TC_POLICE_SHOT
+
+		sel, protocol, prio := addressSelector(allowedIP, true)
+		err := m.nl.FilterAdd(&netlink.U32{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: linkIndex,
+				Parent:    netlink.MakeHandle(ingressQdiscMajor, 0),
+				Priority:  prio,
+				Protocol:  protocol,
+			},
+			ClassId: classId, // only used to map the filter to the peer
+			Sel:     sel,
+			Actions: []netlink.Action{police},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create filter for %s: %w", allowedIP.String(), err)
+		}
+	}
+
+	return nil
+}
+
+// clearShaping removes the root and ingress qdisc, all classes and filters are removed with them.
+func (m Manager) clearShaping(link netlink.Link) error {
+	qdiscs, err := m.nl.QdiscList(link)
+	if err != nil {
+		return fmt.Errorf("failed to list qdiscs: %w", err)
+	}
+
+	for _, qdisc := range qdiscs {
+		attrs := qdisc.Attrs()
+		isRoot := attrs.Parent == netlink.HANDLE_ROOT && qdisc.Type() == "htb"
+		isIngress := attrs.Parent == netlink.HANDLE_INGRESS && qdisc.Type() == "ingress"
+		if !isRoot && !isIngress {
+			continue
+		}
+
+		if err := m.nl.QdiscDel(qdisc); err != nil && !errors.Is(err, unix.ENOENT) {
+			return fmt.Errorf("failed to remove %s qdisc: %w", qdisc.Type(), err)
+		}
+	}
+
+	return nil
+}
+
+func (m Manager) hasRootQdisc(link netlink.Link) bool {
+	qdiscs, err := m.nl.QdiscList(link)
+	if err != nil {
+		return false
+	}
+
+	for _, qdisc := range qdiscs {
+		attrs := qdisc.Attrs()
+		if attrs.Parent == netlink.HANDLE_ROOT && attrs.Handle == netlink.MakeHandle(rootQdiscMajor, 0) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetPeerShapingStatus returns the traffic counters of all peers of the given interface that have a bandwidth limit.
+func (m Manager) GetPeerShapingStatus(ctx context.Context, id domain.InterfaceIdentifier) (
+	[]domain.PeerShapingStatus,
+	error,
+) {
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find interface %s: %w", id, err)
+	}
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
+
+	peers, err := m.db.GetInterfacePeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find peers for %s: %w", id, err)
+	}
+
+	limited := limitedPeers(peers)
+	status := make([]domain.PeerShapingStatus, len(limited))
+	classIndex := make(map[uint32]int, len(limited))
+	for i, peer := range limited {
+		classIndex[netlink.MakeHandle(rootQdiscMajor, peerClassOffset+uint16(i))] = i
+		status[i] = domain.PeerShapingStatus{
+			PeerId:        peer.Identifier,
+			UploadLimit:   peer.UploadLimit.GetValue(),
+			DownloadLimit: peer.DownloadLimit.GetValue(),
+		}
+	}
+
+	if !m.cfg.Advanced.BandwidthShaping || len(limited) == 0 {
+		return status, nil
+	}
+
+	link, err := m.nl.LinkByName(string(id))
+	if err != nil {
+		return status, nil // no counters available
+	}
+
+	classes, err := m.nl.ClassList(link, netlink.MakeHandle(rootQdiscMajor, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list classes: %w", err)
+	}
+	for _, class := range classes {
+		i, ok := classIndex[class.Attrs().Handle]
+		if !ok || class.Attrs().Statistics == nil {
+			continue
+		}
+		stats := class.Attrs().Statistics
+		if stats.Basic != nil {
+			status[i].DownloadBytes = stats.Basic.Bytes
+			status[i].DownloadPackets = stats.Basic.Packets
+		}
+		if stats.Queue != nil {
+			status[i].DownloadDropped = stats.Queue.Drops
+			status[i].DownloadOverlimits = stats.Queue.Overlimits
+		}
+	}
+
+	filters, err := m.nl.FilterList(link, netlink.MakeHandle(ingressQdiscMajor, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingress filters: %w", err)
+	}
+	seen := make(map[int]struct{}, len(limited))
+	for _, filter := range filters {
+		u32, ok := filter.(*netlink.U32)
+		if !ok || len(u32.Actions) == 0 {
+			continue
+		}
+		i, ok := classIndex[u32.ClassId]
+		if !ok {
+			continue
+		}
+		if _, counted := seen[i]; counted {
+			continue // the policer is shared by all filters of the peer
+		}
+		stats := u32.Actions[0].Attrs().Statistics
+		if stats == nil {
+			continue
+		}
+		seen[i] = struct{}{}
+		if stats.Basic != nil {
+			status[i].UploadBytes = stats.Basic.Bytes
+			status[i].UploadPackets = stats.Basic.Packets
+		}
+		if stats.Queue != nil {
+			status[i].UploadDropped = stats.Queue.Drops
+		}
+	}
+
+	return status, nil
+}
+
+// limitedPeers returns all enabled peers with a bandwidth limit, sorted by their identifier.
+// The position of a peer in the result determines the tc class of the peer.
+func limitedPeers(peers []domain.Peer) []domain.Peer {
+	limited := make([]domain.Peer, 0, len(peers))
+	for _, peer := range peers {
+		if peer.IsDisabled() || !peer.HasBandwidthLimit() {
+			continue
+		}
+		limited = append(limited, peer)
+	}
+
+	sort.Slice(limited, func(i, j int) bool {
+		return limited[i].Identifier < limited[j].Identifier
+	})
+
+	return limited
+}
+
+// addressSelector returns the u32 selector that matches the source or destination address of the given network.
+// WireGuard interfaces do not have a link layer header, so the offsets are relative to the IP header.
+func addressSelector(cidr domain.Cidr, matchSource bool) (*netlink.TcU32Sel, uint16, uint16) {
+	prefix := cidr.Prefix().Masked()
+	addr := prefix.Addr().AsSlice()
+
+	var offset int32
+	var protocol, prio uint16
+	if prefix.Addr().Is4() {
+		protocol, prio = unix.ETH_P_IP, filterPrioV4
+		offset = 16
+		if matchSource {
+			offset = 12
+		}
+	} else {
+		protocol, prio = unix.ETH_P_IPV6, filterPrioV6
+		offset = 24
+		if matchSource {
+			offset = 8
+		}
+	}
+
+	var keys []netlink.TcU32Key
+	bits := prefix.Bits()
+	for word := 0; word < len(addr)/4; word++ {
+		wordBits := min(max(bits-word*32, 0), 32)
+		if wordBits == 0 {
+			break
+		}
+		mask := ^uint32(0) << (32 - wordBits)
+		keys = append(keys, netlink.TcU32Key{
+			Mask: mask,
+			Val:  binary.BigEndian.Uint32(addr[word*4:]) & mask,
+			Off:  offset + int32(word*4),
+		})
+	}
+	if len(keys) == 0 {
+		keys = append(keys, netlink.TcU32Key{Off: offset}) // match all addresses
+	}
+
+	return &netlink.TcU32Sel{
+		Flags: netlink.TC_U32_TERMINAL,
+		Keys:  keys,
+	}, protocol, prio
+}
@@ -0,0 +1,67 @@
+package shaping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type InterfaceAndPeerDatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterface returns the interface with the given identifier
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetInterfacePeers returns all peers for a given interface
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager does not enforce bandwidth limits on FreeBSD, traffic shaping is only available on Linux.
+type Manager struct {
+	cfg *config.Config
+
+	db InterfaceAndPeerDatabaseRepo
+}
+
+// NewShapingManager creates a new traffic shaping manager instance.
+// An error is returned if bandwidth shaping is enabled.
+func NewShapingManager(cfg *config.Config, _ EventBus, db InterfaceAndPeerDatabaseRepo) (*Manager, error) {
+	if cfg.Advanced.BandwidthShaping {
+		return nil, errors.New("bandwidth shaping is only supported on Linux")
+	}
+
+	return &Manager{cfg: cfg, db: db}, nil
+}
+
+// StartBackgroundJobs is a no-op on FreeBSD.
+func (m Manager) StartBackgroundJobs(_ context.Context) {}
+
+// GetPeerShapingStatus returns no peers on FreeBSD, as bandwidth limits are not enforced.
+func (m Manager) GetPeerShapingStatus(ctx context.Context, id domain.InterfaceIdentifier) (
+	[]domain.PeerShapingStatus,
+	error,
+) {
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find interface %s: %w", id, err)
+	}
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,63 @@
+//go:build linux
+
+package shaping
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func Test_addressSelector(t *testing.T) {
+	t.Run("ipv4 host destination", func(t *testing.T) {
+		cidr, _ := domain.CidrFromString("10.11.12.2/32")
+		sel, protocol, prio := addressSelector(cidr, false)
+		assert.Equal(t, uint16(unix.ETH_P_IP), protocol)
+		assert.Equal(t, filterPrioV4, prio)
+		assert.Equal(t, []netlink.TcU32Key{{Mask: 0xffffffff, Val: 0x0a0b0c02, Off: 16}}, sel.Keys)
+	})
+
+	t.Run("ipv4 network source", func(t *testing.T) {
+		cidr, _ := domain.CidrFromString("192.168.5.77/24")
+		sel, _, _ := addressSelector(cidr, true)
+		assert.Equal(t, []netlink.TcU32Key{{Mask: 0xffffff00, Val: 0xc0a80500, Off: 12}}, sel.Keys)
+	})
+
+	t.Run("ipv6 network destination", func(t *testing.T) {
+		cidr, _ := domain.CidrFromString("fdfd:d3ad:c0de:1234::1/48")
+		sel, protocol, prio := addressSelector(cidr, false)
+		assert.Equal(t, uint16(unix.ETH_P_IPV6), protocol)
+		assert.Equal(t, filterPrioV6, prio)
+		assert.Equal(t, []netlink.TcU32Key{
+			{Mask: 0xffffffff, Val: 0xfdfdd3ad, Off: 24},
+			{Mask: 0xffff0000, Val: 0xc0de0000, Off: 28},
+		}, sel.Keys)
+	})
+
+	t.Run("default route", func(t *testing.T) {
+		cidr, _ := domain.CidrFromString("0.0.0.0/0")
+		sel, _, _ := addressSelector(cidr, true)
+		assert.Equal(t, []netlink.TcU32Key{{Off: 12}}, sel.Keys)
+	})
+}
+
+func Test_limitedPeers(t *testing.T) {
+	now := time.Now()
+	peers := []domain.Peer{
+		{Identifier: "c", DownloadLimit: domain.NewConfigOption(1000, true)},
+		{Identifier: "b"},
+		{Identifier: "a", UploadLimit: domain.NewConfigOption(500, false)},
+		{Identifier: "d", UploadLimit: domain.NewConfigOption(500, false), Disabled: &now},
+	}
+
+	limited := limitedPeers(peers)
+	if assert.Len(t, limited, 2) {
+		assert.Equal(t, domain.PeerIdentifier("a"), limited[0].Identifier)
+		assert.Equal(t, domain.PeerIdentifier("c"), limited[1].Identifier)
+	}
+}
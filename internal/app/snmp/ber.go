@@ -0,0 +1,304 @@
+package snmp
+
+import (
+	"errors"
+)
+
+// BER tags of the ASN.1 and SNMP types that are used by the agent, see RFC 3416.
+const (
+	tagInteger     byte = 0x02
+	tagOctetString byte = 0x04
+	tagNull        byte = 0x05
+	tagOid         byte = 0x06
+	tagSequence    byte = 0x30
+
+	tagGauge32   byte = 0x42
+	tagCounter64 byte = 0x46
+
+	tagNoSuchObject   byte = 0x80
+	tagNoSuchInstance byte = 0x81
+	tagEndOfMibView   byte = 0x82
+
+	tagGetRequest     byte = 0xa0
+	tagGetNextRequest byte = 0xa1
+	tagResponse       byte = 0xa2
+	tagSetRequest     byte = 0xa3
+	tagGetBulkRequest byte = 0xa5
+)
+
+// SNMP error status codes, see RFC 3416.
+const (
+	errorStatusNoError     = 0
+	errorStatusTooBig      = 1
+	errorStatusNotWritable = 17
+)
+
+// versionV2c is the version number of SNMPv2c messages.
+const versionV2c = 1
+
+var errMalformed = errors.New("malformed message")
+
+// value is the value of a variable binding. The tag defines how the value is encoded.
+type value struct {
+	tag    byte
+	number uint64 // the value of integer, counter and gauge types, integers are stored in two's complement
+	bytes  []byte // the value of octet strings
+}
+
+func integerValue(v int64) value {
+	return value{tag: tagInteger, number: uint64(v)}
+}
+
+func stringValue(v string) value {
+	return value{tag: tagOctetString, bytes: []byte(v)}
+}
+
+func gauge32Value(v uint32) value {
+	return value{tag: tagGauge32, number: uint64(v)}
+}
+
+func counter64Value(v uint64) value {
+	return value{tag: tagCounter64, number: v}
+}
+
+// truthValue returns the TruthValue of SNMPv2-TC, true(1) or false(2).
+func truthValue(v bool) value {
+	if v {
+		return integerValue(1)
+	}
+	return integerValue(2)
+}
+
+// varBind is a variable binding of a PDU, the object identifier and its value.
+type varBind struct {
+	oid   oid
+	value value
+}
+
+// message is a SNMPv2c message.
+type message struct {
+	version   int64
+	community string
+	pduType   byte
+
+	requestId   int64
+	errorStatus int64 // the non-repeaters of GetBulk requests
+	errorIndex  int64 // the max-repetitions of GetBulk requests
+	varBinds    []varBind
+}
+
+// decodeMessage parses a SNMP message. The values of the variable bindings are ignored, as requests of a read-only
+// agent only contain NULL values.
+func decodeMessage(data []byte) (*message, error) {
+	body, rest, err := decodeTLV(data, tagSequence)
+	if err != nil || len(rest) != 0 {
+		return nil, errMalformed
+	}
+
+	msg := &message{}
+	if msg.version, body, err = decodeInteger(body); err != nil {
+		return nil, err
+	}
+	community, body, err := decodeTLV(body, tagOctetString)
+	if err != nil {
+		return nil, err
+	}
+	msg.community = string(community)
+
+	if len(body) == 0 {
+		return nil, errMalformed
+	}
+	msg.pduType = body[0]
+	pdu, _, err := decodeTLV(body, msg.pduType)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.requestId, pdu, err = decodeInteger(pdu); err != nil {
+		return nil, err
+	}
+	if msg.errorStatus, pdu, err = decodeInteger(pdu); err != nil {
+		return nil, err
+	}
+	if msg.errorIndex, pdu, err = decodeInteger(pdu); err != nil {
+		return nil, err
+	}
+
+	list, _, err := decodeTLV(pdu, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	for len(list) > 0 {
+		var bind []byte
+		if bind, list, err = decodeTLV(list, tagSequence); err != nil {
+			return nil, err
+		}
+		rawOid, _, err := decodeTLV(bind, tagOid)
+		if err != nil {
+			return nil, err
+		}
+		name, err := decodeOid(rawOid)
+		if err != nil {
+			return nil, err
+		}
+		msg.varBinds = append(msg.varBinds, varBind{oid: name, value: value{tag: tagNull}})
+	}
+
+	return msg, nil
+}
+
+// encode returns the BER encoding of the message.
+func (m *message) encode() []byte {
+	var binds []byte
+	for _, bind := range m.varBinds {
+		binds = append(binds, encodeTLV(tagSequence, append(encodeOid(bind.oid), encodeValue(bind.value)...))...)
+	}
+
+	var pdu []byte
+	pdu = append(pdu, encodeValue(integerValue(m.requestId))...)
+	pdu = append(pdu, encodeValue(integerValue(m.errorStatus))...)
+	pdu = append(pdu, encodeValue(integerValue(m.errorIndex))...)
+	pdu = append(pdu, encodeTLV(tagSequence, binds)...)
+
+	var body []byte
+	body = append(body, encodeValue(integerValue(m.version))...)
+	body = append(body, encodeValue(stringValue(m.community))...)
+	body = append(body, encodeTLV(m.pduType, pdu)...)
+
+	return encodeTLV(tagSequence, body)
+}
+
+// decodeTLV returns the content of the element with the given tag and the remaining data.
+func decodeTLV(data []byte, tag byte) (content, rest []byte, err error) {
+	if len(data) < 2 || data[0] != tag {
+		return nil, nil, errMalformed
+	}
+
+	length := int(data[1])
+	offset := 2
+	if length&0x80 != 0 {
+		numBytes := length & 0x7f
+		if numBytes == 0 || numBytes > 4 || len(data) < 2+numBytes {
+			return nil, nil, errMalformed
+		}
+		length = 0
+		for _, b := range data[2 : 2+numBytes] {
+			length = length<<8 | int(b)
+		}
+		offset += numBytes
+	}
+	if length < 0 || len(data)-offset < length {
+		return nil, nil, errMalformed
+	}
+
+	return data[offset : offset+length], data[offset+length:], nil
+}
+
+func decodeInteger(data []byte) (int64, []byte, error) {
+	content, rest, err := decodeTLV(data, tagInteger)
+	if err != nil || len(content) == 0 || len(content) > 8 {
+		return 0, nil, errMalformed
+	}
+
+	v := int64(int8(content[0])) // sign extension
+	for _, b := range content[1:] {
+		v = v<<8 | int64(b)
+	}
+
+	return v, rest, nil
+}
+
+func decodeOid(content []byte) (oid, error) {
+	if len(content) == 0 {
+		return nil, errMalformed
+	}
+
+	result := oid{uint32(content[0]) / 40, uint32(content[0]) % 40}
+	var arc uint64
+	for i, b := range content[1:] {
+		arc = arc<<7 | uint64(b&0x7f)
+		if arc > 0xffffffff {
+			return nil, errMalformed
+		}
+		if b&0x80 == 0 {
+			result = append(result, uint32(arc))
+			arc = 0
+		} else if i == len(content)-2 {
+			return nil, errMalformed // the last arc is not terminated
+		}
+	}
+
+	return result, nil
+}
+
+func encodeTLV(tag byte, content []byte) []byte {
+	return append(append([]byte{tag}, encodeLength(len(content))...), content...)
+}
+
+func encodeLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+
+	var b []byte
+	for l := length; l > 0; l >>= 8 {
+		b = append([]byte{byte(l)}, b...)
+	}
+
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func encodeValue(v value) []byte {
+	switch v.tag {
+	case tagInteger:
+		return encodeTLV(v.tag, encodeSigned(int64(v.number)))
+	case tagGauge32, tagCounter64:
+		return encodeTLV(v.tag, encodeUnsigned(v.number))
+	case tagOctetString:
+		return encodeTLV(v.tag, v.bytes)
+	default: // NULL and exceptions have no content
+		return encodeTLV(v.tag, nil)
+	}
+}
+
+// encodeSigned returns the shortest two's complement encoding of the integer.
+func encodeSigned(v int64) []byte {
+	b := []byte{byte(v)}
+	for (v > 0x7f || v < -0x80) && len(b) < 8 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+
+	return b
+}
+
+// encodeUnsigned returns the encoding of the unsigned integer, with a leading zero byte if the highest bit is set.
+func encodeUnsigned(v uint64) []byte {
+	b := []byte{byte(v)}
+	for v > 0xff {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+
+	return b
+}
+
+func encodeOid(o oid) []byte {
+	if len(o) < 2 {
+		return encodeTLV(tagOid, []byte{0})
+	}
+
+	content := []byte{byte(o[0]*40 + o[1])}
+	for _, arc := range o[2:] {
+		chunk := []byte{byte(arc & 0x7f)}
+		for arc >>= 7; arc > 0; arc >>= 7 {
+			chunk = append([]byte{byte(arc&0x7f) | 0x80}, chunk...)
+		}
+		content = append(content, chunk...)
+	}
+
+	return encodeTLV(tagOid, content)
+}
@@ -0,0 +1,78 @@
+package snmp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getRequest is a GetRequest for sysDescr.0 with the community public, as sent by snmpget -v2c.
+const getRequest = "302902010104067075626c6963a01c02041234567802010002010030" + "0e300c06082b060102010101000500"
+
+func TestDecodeMessage(t *testing.T) {
+	data, err := hex.DecodeString(getRequest)
+	require.NoError(t, err)
+
+	msg, err := decodeMessage(data)
+	require.NoError(t, err)
+	assert.Equal(t, int64(versionV2c), msg.version)
+	assert.Equal(t, "public", msg.community)
+	assert.Equal(t, tagGetRequest, msg.pduType)
+	assert.Equal(t, int64(0x12345678), msg.requestId)
+	require.Len(t, msg.varBinds, 1)
+	assert.Equal(t, "1.3.6.1.2.1.1.1.0", msg.varBinds[0].oid.String())
+
+	assert.Equal(t, getRequest, hex.EncodeToString(msg.encode()), "encoding a decoded message returns the same bytes")
+}
+
+func TestDecodeMessage_Malformed(t *testing.T) {
+	data, err := hex.DecodeString(getRequest)
+	require.NoError(t, err)
+
+	for _, invalid := range [][]byte{nil, {0x30}, data[:len(data)-1], append(data, 0x00), {0x30, 0x84, 0xff}} {
+		_, err := decodeMessage(invalid)
+		assert.ErrorIs(t, err, errMalformed)
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	tests := []struct {
+		value value
+		want  string
+	}{
+		{integerValue(0), "020100"},
+		{integerValue(127), "02017f"},
+		{integerValue(128), "02020080"},
+		{integerValue(-129), "0202ff7f"},
+		{truthValue(false), "020102"},
+		{gauge32Value(4294967295), "420500ffffffff"},
+		{counter64Value(255), "460200ff"},
+		{stringValue("wg0"), "0403776730"},
+		{value{tag: tagEndOfMibView}, "8200"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, hex.EncodeToString(encodeValue(tt.value)), tt.value)
+	}
+}
+
+func TestEncodeOid(t *testing.T) {
+	o, err := parseOid("1.3.6.1.4.1.8072.9999.9999.1")
+	require.NoError(t, err)
+
+	encoded := encodeOid(o)
+	assert.Equal(t, "060c2b06010401bf08ce0fce0f01", hex.EncodeToString(encoded))
+
+	content, _, err := decodeTLV(encoded, tagOid)
+	require.NoError(t, err)
+	decoded, err := decodeOid(content)
+	require.NoError(t, err)
+	assert.Equal(t, o, decoded)
+}
+
+func TestEncodeLength(t *testing.T) {
+	assert.Equal(t, []byte{0x7f}, encodeLength(127))
+	assert.Equal(t, []byte{0x81, 0x80}, encodeLength(128))
+	assert.Equal(t, []byte{0x82, 0x05, 0xc0}, encodeLength(1472))
+}
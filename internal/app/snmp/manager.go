@@ -0,0 +1,314 @@
+package snmp
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers for a given interface
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	// GetInterfaceStats returns the statistics of the given interface.
+	GetInterfaceStats(ctx context.Context, id domain.InterfaceIdentifier) (*domain.InterfaceStatus, error)
+	// GetPeersStats returns the statistics of the given peers.
+	GetPeersStats(ctx context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error)
+}
+
+// endregion dependencies
+
+const (
+	defaultRefreshInterval = 1 * time.Minute
+	maxMessageSize         = 1472 // the largest response that fits into a single ethernet frame
+	maxRepetitions         = 64   // the upper limit of the max-repetitions of GetBulk requests
+)
+
+// Manager runs a minimal, read-only SNMPv2c agent. The agent answers Get, GetNext and GetBulk requests for the
+// interface and peer counters from a snapshot, which is refreshed in the data collection interval of the statistics.
+type Manager struct {
+	cfg *config.Config
+
+	db DatabaseRepo
+
+	baseOid        oid
+	allowedSources []netip.Prefix
+
+	state *agentState
+}
+
+type agentState struct {
+	mux sync.RWMutex
+	mib *mib
+}
+
+// NewSnmpManager creates a new SNMP agent instance.
+func NewSnmpManager(cfg *config.Config, db DatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+
+		db: db,
+
+		state: &agentState{},
+	}
+
+	if !cfg.Snmp.Enabled {
+		return m, nil
+	}
+
+	if cfg.Snmp.Community == "" {
+		return nil, errors.New("missing snmp community")
+	}
+	if _, _, err := net.SplitHostPort(cfg.Snmp.ListeningAddress); err != nil {
+		return nil, fmt.Errorf("invalid snmp listening address: %w", err)
+	}
+	baseOid, err := parseOid(cfg.Snmp.BaseOid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snmp base oid: %w", err)
+	}
+	m.baseOid = baseOid
+	for _, source := range cfg.Snmp.AllowedSources {
+		prefix, err := netip.ParsePrefix(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snmp allowed source %s: %w", source, err)
+		}
+		m.allowedSources = append(m.allowedSources, prefix.Masked())
+	}
+	m.state.mib = newMib(baseOid, nil, time.Now())
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the SNMP agent and the periodic refresh of its snapshot.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.Snmp.Enabled {
+		return
+	}
+
+	conn, err := net.ListenPacket("udp", m.cfg.Snmp.ListeningAddress)
+	if err != nil {
+		slog.Error("failed to start snmp agent", "address", m.cfg.Snmp.ListeningAddress, "error", err)
+		return
+	}
+	slog.Info("started snmp agent", "address", m.cfg.Snmp.ListeningAddress, "baseOid", m.baseOid)
+
+	go m.serve(conn)
+	go m.runRefresh(ctx, conn)
+}
+
+// runRefresh periodically rebuilds the snapshot of the agent. On shutdown, the listener is closed.
+func (m Manager) runRefresh(ctx context.Context, conn net.PacketConn) {
+	interval := m.cfg.Statistics.DataCollectionInterval
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+
+	m.refresh(ctx)
+
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(interval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		m.refresh(ctx)
+	}
+
+	_ = conn.Close()
+}
+
+// refresh loads all interfaces, peers and their statistics and replaces the snapshot of the agent.
+func (m Manager) refresh(ctx context.Context) {
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		slog.Error("failed to load interfaces for snmp agent", "error", err)
+		return
+	}
+
+	rows := make([]mibInterface, 0, len(interfaces))
+	for _, iface := range interfaces {
+		row := mibInterface{iface: iface}
+
+		if status, err := m.db.GetInterfaceStats(ctx, iface.Identifier); err == nil {
+			row.status = status
+		}
+
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			slog.Error("failed to load peers for snmp agent", "interface", iface.Identifier, "error", err)
+			continue
+		}
+		ids := make([]domain.PeerIdentifier, len(peers))
+		for i, peer := range peers {
+			ids[i] = peer.Identifier
+		}
+		stats, err := m.db.GetPeersStats(ctx, ids...)
+		if err != nil {
+			slog.Warn("failed to load peer statistics for snmp agent", "interface", iface.Identifier, "error", err)
+		}
+		for _, peer := range peers {
+			mibRow := mibPeer{peer: peer}
+			if i := slices.IndexFunc(stats, func(s domain.PeerStatus) bool { return s.PeerId == peer.Identifier }); i >= 0 {
+				mibRow.status = &stats[i]
+			}
+			row.peers = append(row.peers, mibRow)
+		}
+
+		rows = append(rows, row)
+	}
+
+	snapshot := newMib(m.baseOid, rows, time.Now())
+
+	m.state.mux.Lock()
+	m.state.mib = snapshot
+	m.state.mux.Unlock()
+
+	slog.Debug("refreshed snmp agent", "interfaces", len(rows), "variables", len(snapshot.variables))
+}
+
+func (m Manager) serve(conn net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, remote, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		udpAddr, ok := remote.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		source := udpAddr.AddrPort().Addr().Unmap()
+		if !m.isAllowedSource(source) {
+			slog.Debug("rejected snmp request", "source", source, "error", "source not allowed")
+			continue
+		}
+
+		response, err := m.handleRequest(buf[:n])
+		if err != nil {
+			slog.Debug("rejected snmp request", "source", source, "error", err)
+			continue
+		}
+		if _, err := conn.WriteTo(response, remote); err != nil {
+			slog.Debug("failed to send snmp response", "source", source, "error", err)
+		}
+	}
+}
+
+func (m Manager) isAllowedSource(source netip.Addr) bool {
+	if len(m.allowedSources) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(m.allowedSources, func(prefix netip.Prefix) bool {
+		return prefix.Contains(source)
+	})
+}
+
+// handleRequest answers the given SNMP request. Requests with another version or a wrong community return an
+// error, they are not answered.
+func (m Manager) handleRequest(data []byte) ([]byte, error) {
+	request, err := decodeMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	if request.version != versionV2c {
+		return nil, fmt.Errorf("unsupported snmp version %d", request.version)
+	}
+	if subtle.ConstantTimeCompare([]byte(request.community), []byte(m.cfg.Snmp.Community)) != 1 {
+		return nil, errors.New("invalid community")
+	}
+
+	m.state.mux.RLock()
+	snapshot := m.state.mib
+	m.state.mux.RUnlock()
+
+	response := &message{
+		version:   request.version,
+		community: request.community,
+		pduType:   tagResponse,
+		requestId: request.requestId,
+	}
+
+	switch request.pduType {
+	case tagGetRequest:
+		for _, bind := range request.varBinds {
+			response.varBinds = append(response.varBinds, varBind{oid: bind.oid, value: snapshot.get(bind.oid)})
+		}
+	case tagGetNextRequest:
+		for _, bind := range request.varBinds {
+			next, _ := snapshot.next(bind.oid)
+			response.varBinds = append(response.varBinds, next)
+		}
+	case tagGetBulkRequest:
+		response.varBinds = getBulk(snapshot, request.varBinds, request.errorStatus, request.errorIndex)
+		for len(response.encode()) > maxMessageSize && len(response.varBinds) > 1 {
+			response.varBinds = response.varBinds[:len(response.varBinds)-1] // GetBulk responses may be truncated
+		}
+	case tagSetRequest:
+		response.errorStatus = errorStatusNotWritable
+		response.errorIndex = 1
+		response.varBinds = request.varBinds
+	default:
+		return nil, fmt.Errorf("unsupported pdu type %#x", request.pduType)
+	}
+
+	encoded := response.encode()
+	if len(encoded) > maxMessageSize {
+		response.errorStatus = errorStatusTooBig
+		response.errorIndex = 0
+		response.varBinds = nil
+		encoded = response.encode()
+	}
+
+	return encoded, nil
+}
+
+// getBulk returns the variables of a GetBulk request, see RFC 3416 section 4.2.3. The first non-repeaters variables
+// are answered once, the other variables are answered up to max-repetitions times.
+func getBulk(snapshot *mib, binds []varBind, nonRepeaters, repetitions int64) []varBind {
+	nonRepeaters = min(max(nonRepeaters, 0), int64(len(binds)))
+	repetitions = min(max(repetitions, 0), maxRepetitions)
+
+	var result []varBind
+	for _, bind := range binds[:nonRepeaters] {
+		next, _ := snapshot.next(bind.oid)
+		result = append(result, next)
+	}
+
+	repeaters := slices.Clone(binds[nonRepeaters:])
+	for r := int64(0); r < repetitions && len(repeaters) > 0; r++ {
+		done := true
+		for i, bind := range repeaters {
+			next, ok := snapshot.next(bind.oid)
+			result = append(result, next)
+			repeaters[i] = next
+			done = done && !ok
+		}
+		if done {
+			break // all repeaters reached the end of the MIB view
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,226 @@
+package snmp
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	interfaces []domain.Interface
+	peers      map[domain.InterfaceIdentifier][]domain.Peer
+	stats      []domain.PeerStatus
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+func (f *fakeDatabase) GetInterfacePeers(_ context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	return f.peers[id], nil
+}
+
+func (f *fakeDatabase) GetInterfaceStats(
+	_ context.Context,
+	id domain.InterfaceIdentifier,
+) (*domain.InterfaceStatus, error) {
+	return &domain.InterfaceStatus{InterfaceId: id, BytesReceived: 1000, BytesTransmitted: 2000}, nil
+}
+
+func (f *fakeDatabase) GetPeersStats(_ context.Context, _ ...domain.PeerIdentifier) ([]domain.PeerStatus, error) {
+	return f.stats, nil
+}
+
+func newTestManager(t *testing.T) *Manager {
+	cfg := &config.Config{}
+	cfg.Snmp = config.SnmpConfig{
+		Enabled:          true,
+		ListeningAddress: "127.0.0.1:1161",
+		Community:        "secret",
+		BaseOid:          "1.3.6.1.4.1.8072.9999.9999.1",
+	}
+
+	handshake := time.Now().Add(-time.Minute)
+	db := &fakeDatabase{
+		interfaces: []domain.Interface{{Identifier: "wg0", Type: domain.InterfaceTypeServer, ListenPort: 51820}},
+		peers: map[domain.InterfaceIdentifier][]domain.Peer{
+			"wg0": {
+				{Identifier: "peer-a", DisplayName: "Laptop", InterfaceIdentifier: "wg0"},
+				{Identifier: "peer-b", DisplayName: "Phone", InterfaceIdentifier: "wg0"},
+			},
+		},
+		stats: []domain.PeerStatus{
+			{PeerId: "peer-a", BytesReceived: 10, BytesTransmitted: 20, LastHandshake: &handshake},
+		},
+	}
+
+	m, err := NewSnmpManager(cfg, db)
+	require.NoError(t, err)
+	m.refresh(context.Background())
+	return m
+}
+
+func request(t *testing.T, m *Manager, pduType byte, nonRepeaters, repetitions int64, oids ...string) *message {
+	req := &message{
+		version:     versionV2c,
+		community:   "secret",
+		pduType:     pduType,
+		requestId:   42,
+		errorStatus: nonRepeaters,
+		errorIndex:  repetitions,
+	}
+	for _, str := range oids {
+		o, err := parseOid(str)
+		require.NoError(t, err)
+		req.varBinds = append(req.varBinds, varBind{oid: o, value: value{tag: tagNull}})
+	}
+
+	data, err := m.handleRequest(req.encode())
+	require.NoError(t, err)
+	response, err := decodeMessage(data)
+	require.NoError(t, err)
+	assert.Equal(t, tagResponse, response.pduType)
+	assert.Equal(t, int64(42), response.requestId)
+	return response
+}
+
+// responseValues returns the values of the response, the values are not part of decoded messages.
+func responseValues(m *Manager, response *message) []value {
+	values := make([]value, len(response.varBinds))
+	for i, bind := range response.varBinds {
+		values[i] = m.state.mib.get(bind.oid)
+	}
+	return values
+}
+
+func TestManager_HandleRequest_Get(t *testing.T) {
+	m := newTestManager(t)
+
+	base := "1.3.6.1.4.1.8072.9999.9999.1"
+	response := request(t, m, tagGetRequest, 0, 0, base+".1.1.0", base+".1.2.0", base+".1.3.0")
+	values := responseValues(m, response)
+	assert.Equal(t, gauge32Value(1), values[0], "interface count")
+	assert.Equal(t, gauge32Value(2), values[1], "peer count")
+	assert.Equal(t, gauge32Value(1), values[2], "connected peer count")
+
+	ifIndex := tableIndices([]string{"wg0"}, func(s string) string { return s })[0]
+	assert.Equal(t, stringValue("wg0"), m.state.mib.get(m.baseOid.child(mibInterfaceTable, 1, interfaceName, ifIndex)))
+	assert.Equal(t, counter64Value(1000),
+		m.state.mib.get(m.baseOid.child(mibInterfaceTable, 1, interfaceBytesReceived, ifIndex)))
+
+	assert.Equal(t, value{tag: tagNoSuchInstance}, m.state.mib.get(m.baseOid.child(mibScalars, 9, 0)))
+	assert.Equal(t, value{tag: tagNoSuchObject}, m.state.mib.get(oid{1, 3, 6, 1, 2, 1, 1, 1, 0}))
+}
+
+func TestManager_HandleRequest_Walk(t *testing.T) {
+	m := newTestManager(t)
+
+	// walk the complete MIB with GetNext requests
+	current := "1.3.6.1.4.1.8072.9999.9999.1"
+	var walked []string
+	for i := 0; i < 100; i++ {
+		response := request(t, m, tagGetNextRequest, 0, 0, current)
+		require.Len(t, response.varBinds, 1)
+		next := response.varBinds[0].oid.String()
+		if next == current {
+			break // end of the MIB view
+		}
+		walked = append(walked, next)
+		current = next
+	}
+
+	assert.Len(t, walked, len(m.state.mib.variables))
+	assert.Equal(t, "1.3.6.1.4.1.8072.9999.9999.1.1.1.0", walked[0])
+}
+
+func TestManager_HandleRequest_GetBulk(t *testing.T) {
+	m := newTestManager(t)
+
+	base := "1.3.6.1.4.1.8072.9999.9999.1"
+	response := request(t, m, tagGetBulkRequest, 1, 5, base+".1.1.0", base+".3.1.3")
+	require.Len(t, response.varBinds, 6)
+	assert.Equal(t, base+".1.2.0", response.varBinds[0].oid.String(), "the non-repeater is answered once")
+	names := responseValues(m, response)[1:3]
+	assert.ElementsMatch(t, []value{stringValue("Laptop"), stringValue("Phone")}, names,
+		"the repeater continues with the peer names")
+
+	response = request(t, m, tagGetBulkRequest, 0, 50, base)
+	assert.Len(t, response.varBinds, len(m.state.mib.variables)+1, "the repetitions stop at the end of the MIB view")
+}
+
+func TestManager_HandleRequest_GetBulkLimits(t *testing.T) {
+	m := newTestManager(t)
+
+	peers := make([]mibPeer, 100)
+	for i := range peers {
+		peers[i] = mibPeer{peer: domain.Peer{Identifier: domain.PeerIdentifier(fmt.Sprintf("peer-%d", i))}}
+	}
+	m.state.mib = newMib(m.baseOid, []mibInterface{{iface: domain.Interface{Identifier: "wg0"}, peers: peers}},
+		time.Now())
+
+	binds := []varBind{{oid: m.baseOid}}
+	assert.Len(t, getBulk(m.state.mib, binds, 0, 1000), maxRepetitions, "the repetitions are limited")
+
+	response := request(t, m, tagGetBulkRequest, 0, 50, m.baseOid.String(), m.baseOid.String())
+	assert.Less(t, len(response.varBinds), 100, "the response is truncated")
+	assert.LessOrEqual(t, len(response.encode()), maxMessageSize)
+}
+
+func TestManager_HandleRequest_Set(t *testing.T) {
+	m := newTestManager(t)
+
+	response := request(t, m, tagSetRequest, 0, 0, "1.3.6.1.4.1.8072.9999.9999.1.1.1.0")
+	assert.Equal(t, int64(errorStatusNotWritable), response.errorStatus)
+}
+
+func TestManager_HandleRequest_Rejected(t *testing.T) {
+	m := newTestManager(t)
+
+	req := &message{version: versionV2c, community: "wrong", pduType: tagGetRequest}
+	_, err := m.handleRequest(req.encode())
+	assert.Error(t, err)
+
+	req = &message{version: 0, community: "secret", pduType: tagGetRequest}
+	_, err = m.handleRequest(req.encode())
+	assert.Error(t, err, "SNMPv1 is not supported")
+}
+
+func TestNewSnmpManager_Validation(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Snmp = config.SnmpConfig{Enabled: true, ListeningAddress: ":161", BaseOid: "1.3.6.1.4.1"}
+	_, err := NewSnmpManager(cfg, &fakeDatabase{})
+	assert.Error(t, err, "the community is required")
+
+	cfg.Snmp.Community = "secret"
+	cfg.Snmp.BaseOid = "1.3.x"
+	_, err = NewSnmpManager(cfg, &fakeDatabase{})
+	assert.Error(t, err)
+
+	cfg.Snmp.BaseOid = "1.3.6.1.4.1"
+	cfg.Snmp.AllowedSources = []string{"192.168.1.0/24"}
+	m, err := NewSnmpManager(cfg, &fakeDatabase{})
+	require.NoError(t, err)
+	assert.True(t, m.isAllowedSource(netip.MustParseAddr("192.168.1.7")))
+	assert.False(t, m.isAllowedSource(netip.MustParseAddr("10.0.0.1")))
+}
+
+func TestTableIndices(t *testing.T) {
+	indices := tableIndices([]string{"b", "a", "c"}, func(s string) string { return s })
+	again := tableIndices([]string{"a", "c", "b", "d"}, func(s string) string { return s })
+
+	assert.Equal(t, indices[0], again[2], "the index does not depend on the other rows")
+	assert.Equal(t, indices[1], again[0])
+	assert.Equal(t, indices[2], again[1])
+	for _, index := range again {
+		assert.NotZero(t, index)
+		assert.LessOrEqual(t, index, uint32(0x7fffffff))
+	}
+}
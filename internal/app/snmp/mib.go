@@ -0,0 +1,242 @@
+package snmp
+
+import (
+	"fmt"
+	"hash/crc32"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// oid is an ASN.1 object identifier.
+type oid []uint32
+
+// parseOid parses the dotted notation of an object identifier, for example 1.3.6.1.4.1.
+func parseOid(str string) (oid, error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(str), "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("object identifier %q is too short", str)
+	}
+
+	result := make(oid, len(parts))
+	for i, part := range parts {
+		arc, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid object identifier %q: %w", str, err)
+		}
+		result[i] = uint32(arc)
+	}
+	if result[0] > 2 || (result[0] < 2 && result[1] > 39) {
+		return nil, fmt.Errorf("invalid object identifier %q", str)
+	}
+
+	return result, nil
+}
+
+func (o oid) String() string {
+	parts := make([]string, len(o))
+	for i, arc := range o {
+		parts[i] = strconv.FormatUint(uint64(arc), 10)
+	}
+	return strings.Join(parts, ".")
+}
+
+// child returns the object identifier with the given arcs appended.
+func (o oid) child(arcs ...uint32) oid {
+	return append(slices.Clone(o), arcs...)
+}
+
+// The layout of the WireGuard Portal MIB below the base object identifier, see docs/documentation/monitoring/snmp.md.
+const (
+	mibScalars        = 1
+	mibInterfaceTable = 2
+	mibPeerTable      = 3
+	mibTableEntry     = 1
+
+	scalarInterfaceCount     = 1
+	scalarPeerCount          = 2
+	scalarConnectedPeerCount = 3
+
+	// column 1 is the not-accessible interface index
+	interfaceName               = 2
+	interfaceMode               = 3
+	interfaceEnabled            = 4
+	interfaceListenPort         = 5
+	interfacePublicKey          = 6
+	interfaceBytesReceived      = 7
+	interfaceBytesTransmitted   = 8
+	interfacePeerCount          = 9
+	interfaceConnectedPeerCount = 10
+
+	// column 1 is the not-accessible peer index
+	peerIdentifier       = 2
+	peerName             = 3
+	peerAddresses        = 4
+	peerUser             = 5
+	peerEnabled          = 6
+	peerConnected        = 7
+	peerEndpoint         = 8
+	peerLastHandshake    = 9
+	peerBytesReceived    = 10
+	peerBytesTransmitted = 11
+)
+
+// mibInterface contains the data of an interface that is exposed by the agent.
+type mibInterface struct {
+	iface  domain.Interface
+	status *domain.InterfaceStatus // nil if no statistics are available
+	peers  []mibPeer
+}
+
+// mibPeer contains the data of a peer that is exposed by the agent.
+type mibPeer struct {
+	peer   domain.Peer
+	status *domain.PeerStatus // nil if no statistics are available
+}
+
+// mib is an immutable snapshot of all variables of the agent, sorted by their object identifier.
+type mib struct {
+	base      oid
+	variables []varBind
+}
+
+// newMib builds the variables of the given interfaces and peers below the base object identifier.
+// Interfaces and peers are indexed by a checksum of their identifier, so that the rows keep their index if other
+// interfaces or peers are added or removed.
+func newMib(base oid, interfaces []mibInterface, now time.Time) *mib {
+	m := &mib{base: base}
+
+	ifaceIndices := tableIndices(interfaces, func(i mibInterface) string { return string(i.iface.Identifier) })
+	peerCount, connectedCount := 0, 0
+	for i, iface := range interfaces {
+		ifIndex := ifaceIndices[i]
+		peerIndices := tableIndices(iface.peers, func(p mibPeer) string { return string(p.peer.Identifier) })
+
+		ifaceConnected := 0
+		for j, peer := range iface.peers {
+			connected := peer.status != nil && peer.status.IsConnectedAt(now)
+			if connected {
+				ifaceConnected++
+			}
+			m.addPeer(base, ifIndex, peerIndices[j], peer, connected)
+		}
+		m.addInterface(base, ifIndex, iface, len(iface.peers), ifaceConnected)
+
+		peerCount += len(iface.peers)
+		connectedCount += ifaceConnected
+	}
+
+	scalars := base.child(mibScalars)
+	m.add(scalars.child(scalarInterfaceCount, 0), gauge32Value(uint32(len(interfaces))))
+	m.add(scalars.child(scalarPeerCount, 0), gauge32Value(uint32(peerCount)))
+	m.add(scalars.child(scalarConnectedPeerCount, 0), gauge32Value(uint32(connectedCount)))
+
+	sort.Slice(m.variables, func(i, j int) bool {
+		return slices.Compare(m.variables[i].oid, m.variables[j].oid) < 0
+	})
+
+	return m
+}
+
+func (m *mib) add(o oid, v value) {
+	m.variables = append(m.variables, varBind{oid: o, value: v})
+}
+
+func (m *mib) addInterface(base oid, ifIndex uint32, i mibInterface, peers, connected int) {
+	var received, transmitted uint64
+	if i.status != nil {
+		received, transmitted = i.status.BytesReceived, i.status.BytesTransmitted
+	}
+
+	column := func(col uint32) oid { return base.child(mibInterfaceTable, mibTableEntry, col, ifIndex) }
+	m.add(column(interfaceName), stringValue(string(i.iface.Identifier)))
+	m.add(column(interfaceMode), stringValue(string(i.iface.Type)))
+	m.add(column(interfaceEnabled), truthValue(!i.iface.IsDisabled()))
+	m.add(column(interfaceListenPort), integerValue(int64(i.iface.ListenPort)))
+	m.add(column(interfacePublicKey), stringValue(i.iface.PublicKey))
+	m.add(column(interfaceBytesReceived), counter64Value(received))
+	m.add(column(interfaceBytesTransmitted), counter64Value(transmitted))
+	m.add(column(interfacePeerCount), gauge32Value(uint32(peers)))
+	m.add(column(interfaceConnectedPeerCount), gauge32Value(uint32(connected)))
+}
+
+func (m *mib) addPeer(base oid, ifIndex, peerIndex uint32, p mibPeer, connected bool) {
+	var received, transmitted uint64
+	var endpoint string
+	var lastHandshake uint32
+	if p.status != nil {
+		received, transmitted = p.status.BytesReceived, p.status.BytesTransmitted
+		endpoint = p.status.Endpoint
+		if p.status.LastHandshake != nil && p.status.LastHandshake.Unix() > 0 {
+			lastHandshake = uint32(p.status.LastHandshake.Unix())
+		}
+	}
+
+	column := func(col uint32) oid { return base.child(mibPeerTable, mibTableEntry, col, ifIndex, peerIndex) }
+	m.add(column(peerIdentifier), stringValue(string(p.peer.Identifier)))
+	m.add(column(peerName), stringValue(p.peer.DisplayName))
+	m.add(column(peerAddresses), stringValue(p.peer.Interface.AddressStr()))
+	m.add(column(peerUser), stringValue(string(p.peer.UserIdentifier)))
+	m.add(column(peerEnabled), truthValue(!p.peer.IsDisabled()))
+	m.add(column(peerConnected), truthValue(connected))
+	m.add(column(peerEndpoint), stringValue(endpoint))
+	m.add(column(peerLastHandshake), gauge32Value(lastHandshake))
+	m.add(column(peerBytesReceived), counter64Value(received))
+	m.add(column(peerBytesTransmitted), counter64Value(transmitted))
+}
+
+// get returns the value of the variable with the given object identifier.
+func (m *mib) get(o oid) value {
+	i, found := slices.BinarySearchFunc(m.variables, o, func(v varBind, o oid) int {
+		return slices.Compare(v.oid, o)
+	})
+	if !found && len(o) > len(m.base) && slices.Equal(o[:len(m.base)], m.base) {
+		return value{tag: tagNoSuchInstance}
+	}
+	if !found {
+		return value{tag: tagNoSuchObject}
+	}
+	return m.variables[i].value
+}
+
+// next returns the first variable whose object identifier follows the given object identifier.
+// The second return value is false if there is no such variable.
+func (m *mib) next(o oid) (varBind, bool) {
+	i := sort.Search(len(m.variables), func(i int) bool {
+		return slices.Compare(m.variables[i].oid, o) > 0
+	})
+	if i == len(m.variables) {
+		return varBind{oid: o, value: value{tag: tagEndOfMibView}}, false
+	}
+	return m.variables[i], true
+}
+
+// tableIndices returns the table index of each row. The index is derived from a checksum of the row key, collisions
+// are resolved by using the next free index in the order of the keys.
+func tableIndices[T any](rows []T, key func(T) string) []uint32 {
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return key(rows[order[a]]) < key(rows[order[b]]) })
+
+	indices := make([]uint32, len(rows))
+	used := make(map[uint32]struct{}, len(rows))
+	for _, row := range order {
+		index := crc32.ChecksumIEEE([]byte(key(rows[row]))) & 0x7fffffff // Integer32 (1..2147483647)
+		for {
+			if _, ok := used[index]; !ok && index != 0 {
+				break
+			}
+			index = (index + 1) & 0x7fffffff
+		}
+		used[index] = struct{}{}
+		indices[row] = index
+	}
+
+	return indices
+}
@@ -0,0 +1,431 @@
+package sshdeploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// defaultConfigDirectory is the configuration directory of wg-quick.
+const defaultConfigDirectory = "/etc/wireguard"
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetSshDeployment returns the SSH deployment of the given peer.
+	GetSshDeployment(ctx context.Context, peerId domain.PeerIdentifier) (*domain.SshDeployment, error)
+	// GetAllSshDeployments returns all SSH deployments.
+	GetAllSshDeployments(ctx context.Context) ([]domain.SshDeployment, error)
+	// SaveSshDeployment creates or updates the given SSH deployment.
+	SaveSshDeployment(ctx context.Context, deployment *domain.SshDeployment) error
+	// DeleteSshDeployment deletes the SSH deployment of the given peer.
+	DeleteSshDeployment(ctx context.Context, peerId domain.PeerIdentifier) error
+	// UpdateSshDeploymentPeer moves the SSH deployment of a peer to the new peer identifier.
+	UpdateSshDeploymentPeer(ctx context.Context, oldId, newId domain.PeerIdentifier) error
+}
+
+type PeerManager interface {
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+}
+
+type ConfigFileManager interface {
+	// GetPeerConfig returns the peer configuration for the given peer identifier.
+	GetPeerConfig(ctx context.Context, id domain.PeerIdentifier) (io.Reader, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// remoteRunner executes commands on the configured SSH hosts.
+type remoteRunner interface {
+	// Run executes the given command on the host. The input is passed to the standard input of the command.
+	Run(ctx context.Context, hostName, command string, input []byte) ([]byte, error)
+	// CloseIdle closes all connections that were not used within the idle timeout.
+	CloseIdle()
+	// Close closes all connections.
+	Close()
+}
+
+// endregion dependencies
+
+// Manager pushes the configuration of peers to remote hosts via SSH. It is meant for hosts that cannot run the
+// config pull agent. The configuration is written to the wg-quick configuration directory of the host and applied
+// with wg syncconf, so existing sessions are not interrupted.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db          DatabaseRepo
+	peers       PeerManager
+	configFiles ConfigFileManager
+	runner      remoteRunner
+
+	mux *sync.Mutex // serializes the deployments
+}
+
+// NewSshDeploymentManager creates a new SSH deployment manager. The configured hosts are validated on startup.
+func NewSshDeploymentManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	peers PeerManager,
+	configFiles ConfigFileManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:          db,
+		peers:       peers,
+		configFiles: configFiles,
+
+		mux: &sync.Mutex{},
+	}
+
+	if cfg.SshDeployment.Enabled {
+		pool, err := newConnectionPool(cfg.SshDeployment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up SSH deployment: %w", err)
+		}
+		m.runner = pool
+
+		m.connectToMessageBus()
+	}
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the periodic synchronization of all deployments.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.SshDeployment.Enabled {
+		return
+	}
+
+	go m.runPeriodicSync(ctx)
+
+	slog.Debug("started SSH deployment synchronization")
+}
+
+func (m Manager) connectToMessageBus() {
+	// Deployments of deleted peers are not removed on the peer deleted event, as the event is also published if the
+	// identifier of a peer changes. Stale deployments are removed on the next synchronization instead.
+	_ = m.bus.Subscribe(app.TopicPeerUpdated, m.handlePeerUpdatedEvent)
+	_ = m.bus.Subscribe(app.TopicPeerIdentifierUpdated, m.handlePeerIdentifierUpdatedEvent)
+}
+
+func (m Manager) handlePeerUpdatedEvent(peer domain.Peer) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	deployment, err := m.db.GetSshDeployment(ctx, peer.Identifier)
+	if errors.Is(err, domain.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		slog.Error("failed to load SSH deployment", "peer", peer.Identifier, "error", err)
+		return
+	}
+
+	m.deploy(ctx, deployment, false)
+}
+
+func (m Manager) handlePeerIdentifierUpdatedEvent(oldId, newId domain.PeerIdentifier) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	if err := m.db.UpdateSshDeploymentPeer(ctx, oldId, newId); err != nil {
+		slog.Error("failed to migrate SSH deployment", "oldIdentifier", oldId, "newIdentifier", newId,
+			"error", err)
+	}
+}
+
+func (m Manager) runPeriodicSync(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+	defer m.runner.Close()
+
+	running := true
+	for running {
+		m.syncAll(ctx)
+		m.runner.CloseIdle()
+
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-time.After(m.cfg.SshDeployment.SyncInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+	}
+}
+
+// syncAll deploys all peers whose configuration changed since the last successful deployment.
+func (m Manager) syncAll(ctx context.Context) {
+	deployments, err := m.db.GetAllSshDeployments(ctx)
+	if err != nil {
+		slog.Error("failed to load SSH deployments", "error", err)
+		return
+	}
+
+	for i := range deployments {
+		if ctx.Err() != nil {
+			return
+		}
+		m.deploy(ctx, &deployments[i], false)
+	}
+}
+
+func (m Manager) checkEnabled() error {
+	if !m.cfg.SshDeployment.Enabled {
+		return fmt.Errorf("SSH deployment is disabled: %w", domain.ErrNoPermission)
+	}
+
+	return nil
+}
+
+// GetHosts returns the names of all configured SSH hosts.
+func (m Manager) GetHosts(ctx context.Context) ([]string, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	hosts := make([]string, 0, len(m.cfg.SshDeployment.Hosts))
+	for _, host := range m.cfg.SshDeployment.Hosts {
+		hosts = append(hosts, host.Name)
+	}
+
+	return hosts, nil
+}
+
+// GetDeployment returns the SSH deployment of the given peer.
+func (m Manager) GetDeployment(ctx context.Context, peerId domain.PeerIdentifier) (*domain.SshDeployment, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetSshDeployment(ctx, peerId)
+}
+
+// SaveDeployment assigns the given peer to a remote host and deploys the configuration immediately.
+// The returned deployment contains the result of the deployment attempt.
+func (m Manager) SaveDeployment(ctx context.Context, deployment *domain.SshDeployment) (
+	*domain.SshDeployment,
+	error,
+) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := deployment.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidData, err)
+	}
+	if _, ok := m.cfg.SshDeployment.GetHost(deployment.Host); !ok {
+		return nil, fmt.Errorf("%w: unknown host %s", domain.ErrInvalidData, deployment.Host)
+	}
+
+	peer, err := m.peers.GetPeer(ctx, deployment.PeerId)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := m.db.GetSshDeployment(ctx, peer.Identifier)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		existing = &domain.SshDeployment{
+			PeerId:    peer.Identifier,
+			CreatedAt: time.Now(),
+			CreatedBy: string(domain.GetUserInfo(ctx).Id),
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to load SSH deployment: %w", err)
+	}
+
+	if existing.Host != deployment.Host || existing.RemoteInterface != deployment.RemoteInterface {
+		existing.LastConfigHash = "" // the new target has not received the configuration yet
+	}
+	existing.Host = deployment.Host
+	existing.RemoteInterface = deployment.RemoteInterface
+	if err := m.db.SaveSshDeployment(ctx, existing); err != nil {
+		return nil, fmt.Errorf("failed to save SSH deployment: %w", err)
+	}
+
+	m.deploy(ctx, existing, true)
+
+	return existing, nil
+}
+
+// DeleteDeployment removes the SSH deployment of the given peer. The configuration on the remote host is not
+// touched.
+func (m Manager) DeleteDeployment(ctx context.Context, peerId domain.PeerIdentifier) error {
+	if err := m.checkEnabled(); err != nil {
+		return err
+	}
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	return m.db.DeleteSshDeployment(ctx, peerId)
+}
+
+// Deploy pushes the current configuration of the given peer to its remote host, even if it did not change.
+func (m Manager) Deploy(ctx context.Context, peerId domain.PeerIdentifier) (*domain.SshDeployment, error) {
+	if err := m.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	deployment, err := m.db.GetSshDeployment(ctx, peerId)
+	if err != nil {
+		return nil, err
+	}
+
+	m.deploy(ctx, deployment, true)
+
+	return deployment, nil
+}
+
+// deploy pushes the configuration of the peer to the remote host if it changed since the last successful
+// deployment, or if force is set. The result is recorded in the deployment.
+func (m Manager) deploy(ctx context.Context, deployment *domain.SshDeployment, force bool) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	cfgData, err := m.loadPeerConfig(ctx, deployment)
+	if errors.Is(err, domain.ErrNotFound) {
+		slog.Info("removing SSH deployment of deleted peer", "peer", deployment.PeerId)
+		if err := m.db.DeleteSshDeployment(ctx, deployment.PeerId); err != nil {
+			slog.Warn("failed to delete stale SSH deployment", "peer", deployment.PeerId, "error", err)
+		}
+		return
+	}
+
+	configHash := ""
+	if err == nil {
+		configHash = domain.HashDeploymentConfig(cfgData)
+		if !force && configHash == deployment.LastConfigHash {
+			return // the remote host is up to date
+		}
+
+		err = m.apply(ctx, deployment, cfgData)
+	}
+
+	if err != nil {
+		slog.Warn("SSH deployment failed", "peer", deployment.PeerId, "host", deployment.Host, "error", err)
+		deployment.LastError = err.Error()
+	} else {
+		slog.Info("deployed peer configuration via SSH", "peer", deployment.PeerId, "host", deployment.Host,
+			"interface", deployment.RemoteInterface)
+		now := time.Now()
+		deployment.LastDeployedAt = &now
+		deployment.LastConfigHash = configHash
+		deployment.LastError = ""
+	}
+
+	if err := m.db.SaveSshDeployment(ctx, deployment); err != nil {
+		slog.Warn("failed to update SSH deployment", "peer", deployment.PeerId, "error", err)
+	}
+}
+
+func (m Manager) loadPeerConfig(ctx context.Context, deployment *domain.SshDeployment) ([]byte, error) {
+	// the deployment is performed on behalf of the administrator that configured it
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	peer, err := m.peers.GetPeer(ctx, deployment.PeerId)
+	if err != nil {
+		return nil, err
+	}
+	if peer.IsDisabled() {
+		return nil, errors.New("peer is disabled")
+	}
+	if peer.Interface.PrivateKey == "" {
+		return nil, errors.New("the private key of the peer is not known to the server")
+	}
+
+	cfgReader, err := m.configFiles.GetPeerConfig(ctx, peer.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer config: %w", err)
+	}
+	cfgData, err := io.ReadAll(cfgReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read peer config: %w", err)
+	}
+
+	return cfgData, nil
+}
+
+func (m Manager) apply(ctx context.Context, deployment *domain.SshDeployment, cfgData []byte) error {
+	host, ok := m.cfg.SshDeployment.GetHost(deployment.Host)
+	if !ok {
+		return fmt.Errorf("unknown host %s", deployment.Host)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.SshDeployment.ConnectTimeout+time.Minute)
+	defer cancel()
+
+	command := applyCommand(host, deployment.RemoteInterface)
+	if _, err := m.runner.Run(ctx, host.Name, command, cfgData); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// applyCommand builds the remote shell command that replaces the wg-quick configuration file with the data from
+// the standard input. A running interface is updated with wg syncconf, otherwise the interface is started.
+func applyCommand(host config.SshDeploymentHost, iface string) string {
+	dir := host.ConfigDirectory
+	if dir == "" {
+		dir = defaultConfigDirectory
+	}
+	cfgFile := shellQuote(path.Join(dir, iface+".conf"))
+	tmpFile := shellQuote(path.Join(dir, "."+iface+".conf.wgp"))
+	quotedIface := shellQuote(iface)
+
+	script := strings.Join([]string{
+		"set -e",
+		"umask 077",
+		"mkdir -p " + shellQuote(dir),
+		"cat > " + tmpFile,
+		"mv -f " + tmpFile + " " + cfgFile,
+		"if wg show " + quotedIface + " > /dev/null 2>&1; then",
+		"wg-quick strip " + cfgFile + " > " + tmpFile,
+		"wg syncconf " + quotedIface + " " + tmpFile,
+		"rm -f " + tmpFile,
+		"else",
+		"wg-quick up " + cfgFile,
+		"fi",
+	}, "\n")
+
+	command := "sh -c " + shellQuote(script)
+	if host.UseSudo {
+		command = "sudo -n " + command
+	}
+
+	return command
+}
+
+// shellQuote quotes the given value for usage as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
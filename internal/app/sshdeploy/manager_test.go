@@ -0,0 +1,202 @@
+package sshdeploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDeploymentRepo struct {
+	deployments map[domain.PeerIdentifier]domain.SshDeployment
+}
+
+func (f *fakeDeploymentRepo) GetSshDeployment(_ context.Context, peerId domain.PeerIdentifier) (
+	*domain.SshDeployment,
+	error,
+) {
+	deployment, ok := f.deployments[peerId]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &deployment, nil
+}
+
+func (f *fakeDeploymentRepo) GetAllSshDeployments(_ context.Context) ([]domain.SshDeployment, error) {
+	var deployments []domain.SshDeployment
+	for _, deployment := range f.deployments {
+		deployments = append(deployments, deployment)
+	}
+	return deployments, nil
+}
+
+func (f *fakeDeploymentRepo) SaveSshDeployment(_ context.Context, deployment *domain.SshDeployment) error {
+	f.deployments[deployment.PeerId] = *deployment
+	return nil
+}
+
+func (f *fakeDeploymentRepo) DeleteSshDeployment(_ context.Context, peerId domain.PeerIdentifier) error {
+	delete(f.deployments, peerId)
+	return nil
+}
+
+func (f *fakeDeploymentRepo) UpdateSshDeploymentPeer(_ context.Context, oldId, newId domain.PeerIdentifier) error {
+	if deployment, ok := f.deployments[oldId]; ok {
+		deployment.PeerId = newId
+		f.deployments[newId] = deployment
+		delete(f.deployments, oldId)
+	}
+	return nil
+}
+
+type fakePeers map[domain.PeerIdentifier]domain.Peer
+
+func (f fakePeers) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &peer, nil
+}
+
+type fakeConfigFiles map[domain.PeerIdentifier]string
+
+func (f fakeConfigFiles) GetPeerConfig(_ context.Context, id domain.PeerIdentifier) (io.Reader, error) {
+	return bytes.NewReader([]byte(f[id])), nil
+}
+
+type fakeRunner struct {
+	mux      sync.Mutex
+	err      error
+	commands []string
+	inputs   []string
+}
+
+func (f *fakeRunner) Run(_ context.Context, _, command string, input []byte) ([]byte, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.commands = append(f.commands, command)
+	f.inputs = append(f.inputs, string(input))
+	return nil, f.err
+}
+
+func (f *fakeRunner) CloseIdle() {}
+
+func (f *fakeRunner) Close() {}
+
+func newTestManager(peers fakePeers, configs fakeConfigFiles) (*Manager, *fakeDeploymentRepo, *fakeRunner) {
+	cfg := &config.Config{}
+	cfg.SshDeployment.Enabled = true
+	cfg.SshDeployment.Hosts = []config.SshDeploymentHost{{Name: "router"}}
+
+	db := &fakeDeploymentRepo{deployments: make(map[domain.PeerIdentifier]domain.SshDeployment)}
+	runner := &fakeRunner{}
+
+	return &Manager{
+		cfg:         cfg,
+		db:          db,
+		peers:       peers,
+		configFiles: configs,
+		runner:      runner,
+		mux:         &sync.Mutex{},
+	}, db, runner
+}
+
+func testPeer(id domain.PeerIdentifier) domain.Peer {
+	peer := domain.Peer{Identifier: id}
+	peer.Interface.PrivateKey = "private-key"
+	return peer
+}
+
+func TestManager_SaveDeployment(t *testing.T) {
+	peers := fakePeers{"peer-a": testPeer("peer-a")}
+	m, db, runner := newTestManager(peers, fakeConfigFiles{"peer-a": "[Interface]"})
+	adminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+
+	_, err := m.SaveDeployment(adminCtx, &domain.SshDeployment{PeerId: "peer-a", Host: "other", RemoteInterface: "wg0"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, err = m.SaveDeployment(adminCtx, &domain.SshDeployment{PeerId: "peer-a", Host: "router", RemoteInterface: "w g"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, err = m.SaveDeployment(userCtx, &domain.SshDeployment{PeerId: "peer-a", Host: "router", RemoteInterface: "wg0"})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	deployment, err := m.SaveDeployment(adminCtx,
+		&domain.SshDeployment{PeerId: "peer-a", Host: "router", RemoteInterface: "wg0"})
+	require.NoError(t, err)
+	assert.Equal(t, "admin", deployment.CreatedBy)
+	assert.NotNil(t, deployment.LastDeployedAt)
+	assert.Empty(t, deployment.LastError)
+	assert.Equal(t, domain.HashDeploymentConfig([]byte("[Interface]")), db.deployments["peer-a"].LastConfigHash)
+	assert.Equal(t, []string{"[Interface]"}, runner.inputs)
+}
+
+func TestManager_deploy(t *testing.T) {
+	peers := fakePeers{"peer-a": testPeer("peer-a")}
+	configs := fakeConfigFiles{"peer-a": "v1"}
+	m, db, runner := newTestManager(peers, configs)
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	db.deployments["peer-a"] = domain.SshDeployment{PeerId: "peer-a", Host: "router", RemoteInterface: "wg0"}
+	m.syncAll(ctx)
+	m.syncAll(ctx) // unchanged configurations are not deployed again
+	assert.Len(t, runner.commands, 1)
+
+	configs["peer-a"] = "v2"
+	runner.err = errors.New("connection refused")
+	m.syncAll(ctx)
+	assert.Len(t, runner.commands, 2)
+	assert.Equal(t, "connection refused", db.deployments["peer-a"].LastError)
+	assert.Equal(t, domain.HashDeploymentConfig([]byte("v1")), db.deployments["peer-a"].LastConfigHash)
+
+	runner.err = nil
+	m.syncAll(ctx) // failed deployments are retried
+	assert.Len(t, runner.commands, 3)
+	assert.Empty(t, db.deployments["peer-a"].LastError)
+
+	delete(peers, "peer-a")
+	m.syncAll(ctx)
+	assert.Empty(t, db.deployments, "deployments of deleted peers are removed")
+}
+
+func TestManager_deploy_missingPrivateKey(t *testing.T) {
+	peer := testPeer("peer-a")
+	peer.Interface.PrivateKey = ""
+	m, db, runner := newTestManager(fakePeers{"peer-a": peer}, fakeConfigFiles{"peer-a": "v1"})
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	db.deployments["peer-a"] = domain.SshDeployment{PeerId: "peer-a", Host: "router", RemoteInterface: "wg0"}
+	m.syncAll(ctx)
+
+	assert.Empty(t, runner.commands)
+	assert.Contains(t, db.deployments["peer-a"].LastError, "private key")
+}
+
+func TestApplyCommand(t *testing.T) {
+	cmd := applyCommand(config.SshDeploymentHost{}, "wg0")
+	assert.Contains(t, cmd, `cat > '\''/etc/wireguard/.wg0.conf.wgp'\''`)
+	assert.Contains(t, cmd, `wg syncconf '\''wg0'\''`)
+	assert.Contains(t, cmd, `wg-quick up '\''/etc/wireguard/wg0.conf'\''`)
+	assert.NotContains(t, cmd, "sudo")
+
+	cmd = applyCommand(config.SshDeploymentHost{ConfigDirectory: "/opt/wireguard", UseSudo: true}, "wg0")
+	assert.True(t, strings.HasPrefix(cmd, "sudo -n sh -c "))
+	assert.Contains(t, cmd, `wg-quick up '\''/opt/wireguard/wg0.conf'\''`)
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'wg0'`, shellQuote("wg0"))
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+}
@@ -0,0 +1,232 @@
+package sshdeploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/h44z/wg-portal/internal/config"
+)
+
+const defaultSshPort = "22"
+
+// sshHost contains the parsed connection settings of a configured host.
+type sshHost struct {
+	address      string
+	clientConfig *ssh.ClientConfig
+}
+
+type pooledConnection struct {
+	client   *ssh.Client
+	lastUsed time.Time
+}
+
+// connectionPool keeps one SSH connection per host open, so consecutive deployments do not pay for the SSH
+// handshake. Connections that are unused for longer than the idle timeout are closed.
+// Host keys are pinned, connections to hosts with a different host key are rejected.
+type connectionPool struct {
+	connectTimeout time.Duration
+	idleTimeout    time.Duration
+	hosts          map[string]sshHost
+
+	mux         sync.Mutex
+	connections map[string]*pooledConnection
+}
+
+func newConnectionPool(cfg config.SshDeploymentConfig) (*connectionPool, error) {
+	p := &connectionPool{
+		connectTimeout: cfg.ConnectTimeout,
+		idleTimeout:    cfg.IdleTimeout,
+		hosts:          make(map[string]sshHost, len(cfg.Hosts)),
+		connections:    make(map[string]*pooledConnection),
+	}
+
+	for _, hostCfg := range cfg.Hosts {
+		if hostCfg.Name == "" {
+			return nil, errors.New("missing SSH host name")
+		}
+		if _, exists := p.hosts[hostCfg.Name]; exists {
+			return nil, fmt.Errorf("duplicate SSH host %s", hostCfg.Name)
+		}
+
+		host, err := parseSshHost(hostCfg, cfg.ConnectTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSH host %s: %w", hostCfg.Name, err)
+		}
+		p.hosts[hostCfg.Name] = host
+	}
+
+	return p, nil
+}
+
+func parseSshHost(cfg config.SshDeploymentHost, timeout time.Duration) (sshHost, error) {
+	if cfg.Address == "" {
+		return sshHost{}, errors.New("missing address")
+	}
+	if cfg.User == "" {
+		return sshHost{}, errors.New("missing user")
+	}
+	if cfg.HostKey == "" {
+		return sshHost{}, errors.New("missing pinned host key")
+	}
+
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(cfg.HostKey))
+	if err != nil {
+		return sshHost{}, fmt.Errorf("failed to parse host key: %w", err)
+	}
+
+	keyData, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return sshHost{}, fmt.Errorf("failed to read private key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(keyData)
+	if err != nil {
+		return sshHost{}, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	address := cfg.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, defaultSshPort)
+	}
+
+	return sshHost{
+		address: address,
+		clientConfig: &ssh.ClientConfig{
+			User:              cfg.User,
+			Auth:              []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback:   ssh.FixedHostKey(hostKey),
+			HostKeyAlgorithms: []string{hostKey.Type()},
+			Timeout:           timeout,
+		},
+	}, nil
+}
+
+// Run executes the given command on the host. The input is passed to the standard input of the command.
+// A broken pooled connection is replaced once, errors of the command itself are returned as they are.
+func (p *connectionPool) Run(ctx context.Context, hostName, command string, input []byte) ([]byte, error) {
+	host, ok := p.hosts[hostName]
+	if !ok {
+		return nil, fmt.Errorf("unknown SSH host %s", hostName)
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		conn, err := p.connection(ctx, hostName, host)
+		if err != nil {
+			return nil, err
+		}
+
+		session, err := conn.client.NewSession()
+		if err != nil {
+			p.drop(hostName)
+			if attempt == 0 {
+				slog.Debug("reconnecting broken SSH connection", "host", hostName, "error", err)
+				continue
+			}
+			return nil, fmt.Errorf("failed to open SSH session on %s: %w", hostName, err)
+		}
+
+		output, err := runSession(ctx, session, command, input)
+		conn.lastUsed = time.Now()
+
+		return output, err
+	}
+}
+
+func (p *connectionPool) connection(ctx context.Context, hostName string, host sshHost) (*pooledConnection, error) {
+	if conn, ok := p.connections[hostName]; ok {
+		return conn, nil
+	}
+
+	dialer := net.Dialer{Timeout: p.connectTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", host.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", host.address, err)
+	}
+
+	sshConn, channels, requests, err := ssh.NewClientConn(netConn, host.address, host.clientConfig)
+	if err != nil {
+		_ = netConn.Close()
+		return nil, fmt.Errorf("SSH handshake with %s failed: %w", host.address, err)
+	}
+
+	conn := &pooledConnection{
+		client:   ssh.NewClient(sshConn, channels, requests),
+		lastUsed: time.Now(),
+	}
+	p.connections[hostName] = conn
+
+	slog.Debug("opened SSH connection", "host", hostName, "address", host.address)
+
+	return conn, nil
+}
+
+func (p *connectionPool) drop(hostName string) {
+	if conn, ok := p.connections[hostName]; ok {
+		_ = conn.client.Close()
+		delete(p.connections, hostName)
+	}
+}
+
+// CloseIdle closes all connections that were not used within the idle timeout.
+func (p *connectionPool) CloseIdle() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	for hostName, conn := range p.connections {
+		if time.Since(conn.lastUsed) > p.idleTimeout {
+			slog.Debug("closing idle SSH connection", "host", hostName)
+			p.drop(hostName)
+		}
+	}
+}
+
+// Close closes all pooled connections.
+func (p *connectionPool) Close() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	for hostName := range p.connections {
+		p.drop(hostName)
+	}
+}
+
+// runSession runs the command and returns its standard output. Both output streams are copied by separate goroutines
+// of the SSH session, so each of them gets its own buffer. They are only read after the command has finished.
+func runSession(ctx context.Context, session *ssh.Session, command string, input []byte) ([]byte, error) {
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = bytes.NewReader(input)
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(command)
+	}()
+
+	select {
+	case <-ctx.Done():
+		_ = session.Close()
+		return nil, ctx.Err()
+	case err := <-done:
+		if err != nil {
+			output := strings.TrimSpace(strings.Join([]string{
+				strings.TrimSpace(stdout.String()), strings.TrimSpace(stderr.String())}, "\n"))
+			return nil, fmt.Errorf("remote command failed: %w: %s", err, output)
+		}
+		return stdout.Bytes(), nil
+	}
+}
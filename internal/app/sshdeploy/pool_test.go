@@ -0,0 +1,236 @@
+package sshdeploy
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/h44z/wg-portal/internal/config"
+)
+
+// startTestSshServer starts an SSH server that answers every command with the data it received on stdin. The command
+// name is written to stderr at the same time, the command "false" exits with status 1.
+// It returns the listen address, the public host key and a counter of accepted connections.
+func startTestSshServer(t *testing.T, clientKey ssh.PublicKey) (string, ssh.PublicKey, *atomic.Int32) {
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	require.NoError(t, err)
+
+	serverCfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, assert.AnError
+			}
+			return nil, nil
+		},
+	}
+	serverCfg.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	connections := &atomic.Int32{}
+	go func() {
+		for {
+			netConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSshConn(netConn, serverCfg, connections)
+		}
+	}()
+
+	return listener.Addr().String(), hostSigner.PublicKey(), connections
+}
+
+func serveTestSshConn(netConn net.Conn, serverCfg *ssh.ServerConfig, connections *atomic.Int32) {
+	_, channels, requests, err := ssh.NewServerConn(netConn, serverCfg)
+	if err != nil {
+		return
+	}
+	connections.Add(1)
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range channelRequests {
+				_ = req.Reply(req.Type == "exec", nil)
+				if req.Type != "exec" {
+					continue
+				}
+				var exec struct{ Command string }
+				_ = ssh.Unmarshal(req.Payload, &exec)
+
+				data, _ := io.ReadAll(channel)
+				var wg sync.WaitGroup
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					_, _ = channel.Write(data)
+				}()
+				go func() {
+					defer wg.Done()
+					_, _ = channel.Stderr().Write([]byte(exec.Command))
+				}()
+				wg.Wait()
+
+				status := make([]byte, 4)
+				if exec.Command == "false" {
+					binary.BigEndian.PutUint32(status, 1)
+				}
+				_, _ = channel.SendRequest("exit-status", false, status)
+				_ = channel.Close()
+			}
+		}()
+	}
+}
+
+func writeTestClientKey(t *testing.T) (string, ssh.PublicKey) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600))
+
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	return keyFile, signer.PublicKey()
+}
+
+func TestConnectionPool_Run(t *testing.T) {
+	keyFile, clientKey := writeTestClientKey(t)
+	address, hostKey, connections := startTestSshServer(t, clientKey)
+
+	pool, err := newConnectionPool(config.SshDeploymentConfig{
+		ConnectTimeout: 5 * time.Second,
+		IdleTimeout:    time.Minute,
+		Hosts: []config.SshDeploymentHost{{
+			Name:           "router",
+			Address:        address,
+			User:           "root",
+			PrivateKeyFile: keyFile,
+			HostKey:        string(ssh.MarshalAuthorizedKey(hostKey)),
+		}},
+	})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	output, err := pool.Run(context.Background(), "router", "cat", []byte("config-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "config-1", string(output))
+
+	output, err = pool.Run(context.Background(), "router", "cat", []byte("config-2"))
+	require.NoError(t, err)
+	assert.Equal(t, "config-2", string(output))
+	assert.Equal(t, int32(1), connections.Load(), "the connection is reused")
+
+	pool.idleTimeout = 0
+	pool.CloseIdle()
+	_, err = pool.Run(context.Background(), "router", "cat", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), connections.Load())
+
+	_, err = pool.Run(context.Background(), "unknown", "cat", nil)
+	assert.Error(t, err)
+}
+
+func TestConnectionPool_Run_outputStreams(t *testing.T) {
+	keyFile, clientKey := writeTestClientKey(t)
+	address, hostKey, _ := startTestSshServer(t, clientKey)
+
+	pool, err := newConnectionPool(config.SshDeploymentConfig{
+		ConnectTimeout: 5 * time.Second,
+		IdleTimeout:    time.Minute,
+		Hosts: []config.SshDeploymentHost{{
+			Name:           "router",
+			Address:        address,
+			User:           "root",
+			PrivateKeyFile: keyFile,
+			HostKey:        string(ssh.MarshalAuthorizedKey(hostKey)),
+		}},
+	})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	input := []byte(strings.Repeat("[Interface]\n", 4096))
+	for i := 0; i < 10; i++ {
+		output, err := pool.Run(context.Background(), "router", "cat", input)
+		require.NoError(t, err)
+		assert.Equal(t, input, output, "stderr is not part of the output")
+	}
+
+	_, err = pool.Run(context.Background(), "router", "false", []byte("config"))
+	assert.ErrorContains(t, err, "remote command failed")
+	assert.ErrorContains(t, err, "config\nfalse", "both output streams are part of the error")
+}
+
+func TestConnectionPool_Run_hostKeyMismatch(t *testing.T) {
+	keyFile, clientKey := writeTestClientKey(t)
+	address, _, connections := startTestSshServer(t, clientKey)
+	_, otherHostKey := writeTestClientKey(t)
+
+	pool, err := newConnectionPool(config.SshDeploymentConfig{
+		ConnectTimeout: 5 * time.Second,
+		Hosts: []config.SshDeploymentHost{{
+			Name:           "router",
+			Address:        address,
+			User:           "root",
+			PrivateKeyFile: keyFile,
+			HostKey:        string(ssh.MarshalAuthorizedKey(otherHostKey)),
+		}},
+	})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Run(context.Background(), "router", "cat", []byte("config"))
+	assert.ErrorContains(t, err, "handshake")
+	assert.Equal(t, int32(0), connections.Load())
+}
+
+func TestNewConnectionPool_invalidHosts(t *testing.T) {
+	keyFile, clientKey := writeTestClientKey(t)
+	hostKey := string(ssh.MarshalAuthorizedKey(clientKey))
+
+	valid := config.SshDeploymentHost{Name: "router", Address: "router", User: "root", PrivateKeyFile: keyFile,
+		HostKey: hostKey}
+	pool, err := newConnectionPool(config.SshDeploymentConfig{Hosts: []config.SshDeploymentHost{valid}})
+	require.NoError(t, err)
+	assert.Equal(t, "router:22", pool.hosts["router"].address)
+
+	_, err = newConnectionPool(config.SshDeploymentConfig{Hosts: []config.SshDeploymentHost{valid, valid}})
+	assert.ErrorContains(t, err, "duplicate")
+
+	missingHostKey := valid
+	missingHostKey.HostKey = ""
+	_, err = newConnectionPool(config.SshDeploymentConfig{Hosts: []config.SshDeploymentHost{missingHostKey}})
+	assert.ErrorContains(t, err, "host key")
+
+	missingKeyFile := valid
+	missingKeyFile.PrivateKeyFile = filepath.Join(t.TempDir(), "missing")
+	_, err = newConnectionPool(config.SshDeploymentConfig{Hosts: []config.SshDeploymentHost{missingKeyFile}})
+	assert.ErrorContains(t, err, "private key")
+}
@@ -0,0 +1,225 @@
+package statuspage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// resolveTimeout is the maximum duration of the DNS lookup of a single endpoint.
+const resolveTimeout = 5 * time.Second
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfaceStats returns the statistics of the given interface.
+	GetInterfaceStats(ctx context.Context, id domain.InterfaceIdentifier) (*domain.InterfaceStatus, error)
+}
+
+type InterfaceController interface {
+	// GetInterface returns the physical WireGuard interface with the given identifier.
+	GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.PhysicalInterface, error)
+}
+
+type Resolver interface {
+	// LookupHost resolves the given host name.
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// endregion dependencies
+
+// Manager periodically checks the VPN endpoints of the server interfaces for the public status page.
+// The status is cached, so requests to the unauthenticated status API never trigger checks.
+type Manager struct {
+	cfg *config.Config
+
+	db       DatabaseRepo
+	wg       InterfaceController
+	resolver Resolver
+
+	status *atomic.Pointer[domain.ServiceStatus] // nil until the first check finished
+}
+
+// NewStatusPageManager creates a new status page manager.
+func NewStatusPageManager(cfg *config.Config, db DatabaseRepo, wg InterfaceController) (*Manager, error) {
+	for _, notice := range cfg.StatusPage.Notices {
+		if notice.Title == "" {
+			return nil, errors.New("maintenance notice without title")
+		}
+		if !notice.Start.IsZero() && !notice.End.IsZero() && notice.End.Before(notice.Start) {
+			return nil, fmt.Errorf("maintenance notice %s ends before it starts", notice.Title)
+		}
+	}
+
+	m := &Manager{
+		cfg: cfg,
+
+		db:       db,
+		wg:       wg,
+		resolver: net.DefaultResolver,
+
+		status: &atomic.Pointer[domain.ServiceStatus]{},
+	}
+
+	return m, nil
+}
+
+// StartBackgroundJobs starts the background jobs for the status page manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.StatusPage.Enabled {
+		return
+	}
+
+	go m.runChecks(ctx)
+
+	slog.Debug("started status page checks", "interval", m.cfg.StatusPage.CheckInterval)
+}
+
+func (m Manager) runChecks(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	ticker := time.NewTicker(m.cfg.StatusPage.CheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := m.refreshStatus(ctx, time.Now()); err != nil {
+			slog.Warn("failed to refresh status page", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return // program stopped
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetStatus returns the last checked service status. No authentication is required, the status contains no
+// peer or user data.
+func (m Manager) GetStatus(_ context.Context) (*domain.ServiceStatus, error) {
+	if !m.cfg.StatusPage.Enabled {
+		return nil, fmt.Errorf("status page is disabled: %w", domain.ErrNotFound)
+	}
+
+	status := domain.ServiceStatus{}
+	if checked := m.status.Load(); checked != nil {
+		status = *checked
+	}
+	status.Notices = m.getNotices(time.Now()) // notices do not depend on the checks
+	return &status, nil
+}
+
+func (m Manager) refreshStatus(ctx context.Context, now time.Time) error {
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	status := &domain.ServiceStatus{CheckedAt: now}
+	for _, iface := range interfaces {
+		if !m.isVisible(iface) {
+			continue
+		}
+		status.Endpoints = append(status.Endpoints, m.checkEndpoint(ctx, iface))
+	}
+
+	m.status.Store(status)
+
+	return nil
+}
+
+func (m Manager) isVisible(iface domain.Interface) bool {
+	if iface.Type != domain.InterfaceTypeServer || iface.IsDisabled() {
+		return false
+	}
+	if len(m.cfg.StatusPage.Interfaces) == 0 {
+		return true
+	}
+
+	return slices.Contains(m.cfg.StatusPage.Interfaces, string(iface.Identifier))
+}
+
+func (m Manager) checkEndpoint(ctx context.Context, iface domain.Interface) domain.EndpointStatus {
+	status := domain.EndpointStatus{
+		InterfaceId: iface.Identifier,
+		DisplayName: iface.DisplayName,
+		Endpoint:    iface.PeerDefEndpoint,
+	}
+
+	physicalInterface, err := m.wg.GetInterface(ctx, iface.Identifier)
+	if err != nil {
+		slog.Debug("status page interface check failed", "interface", iface.Identifier, "error", err)
+	} else {
+		status.DeviceUp = physicalInterface.DeviceUp && physicalInterface.ListenPort != 0
+	}
+
+	status.Resolvable = m.isResolvable(ctx, iface.PeerDefEndpoint)
+
+	if stats, err := m.db.GetInterfaceStats(ctx, iface.Identifier); err == nil && stats != nil && status.DeviceUp {
+		status.UpSince = stats.UpSince
+	}
+
+	return status
+}
+
+func (m Manager) isResolvable(ctx context.Context, endpoint string) bool {
+	if endpoint == "" {
+		return false
+	}
+
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host = endpoint
+	}
+	if net.ParseIP(host) != nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	addrs, err := m.resolver.LookupHost(ctx, host)
+	if err != nil {
+		slog.Debug("status page endpoint lookup failed", "endpoint", endpoint, "error", err)
+		return false
+	}
+
+	return len(addrs) > 0
+}
+
+// getNotices returns all maintenance notices that did not end before the given time.
+func (m Manager) getNotices(now time.Time) []domain.MaintenanceNotice {
+	var notices []domain.MaintenanceNotice
+	for _, cfgNotice := range m.cfg.StatusPage.Notices {
+		notice := domain.MaintenanceNotice{
+			Title:   cfgNotice.Title,
+			Message: cfgNotice.Message,
+		}
+		if !cfgNotice.Start.IsZero() {
+			notice.Start = &cfgNotice.Start
+		}
+		if !cfgNotice.End.IsZero() {
+			notice.End = &cfgNotice.End
+		}
+		for _, id := range cfgNotice.Interfaces {
+			notice.Interfaces = append(notice.Interfaces, domain.InterfaceIdentifier(id))
+		}
+
+		if notice.IsOver(now) {
+			continue
+		}
+		notices = append(notices, notice)
+	}
+
+	return notices
+}
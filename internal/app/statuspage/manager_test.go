@@ -0,0 +1,116 @@
+package statuspage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	interfaces []domain.Interface
+	stats      map[domain.InterfaceIdentifier]domain.InterfaceStatus
+}
+
+func (f *fakeDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+func (f *fakeDatabase) GetInterfaceStats(_ context.Context, id domain.InterfaceIdentifier) (
+	*domain.InterfaceStatus,
+	error,
+) {
+	stats, ok := f.stats[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &stats, nil
+}
+
+type fakeController struct {
+	up map[domain.InterfaceIdentifier]bool
+}
+
+func (f *fakeController) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (
+	*domain.PhysicalInterface,
+	error,
+) {
+	up, ok := f.up[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.PhysicalInterface{Identifier: id, DeviceUp: up, ListenPort: 51820}, nil
+}
+
+type fakeResolver struct{}
+
+func (fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if host == "vpn.example.com" {
+		return []string{"192.0.2.1"}, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestManager_GetStatus(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	upSince := now.Add(-time.Hour)
+	disabled := now
+
+	cfg := &config.Config{}
+	cfg.StatusPage.Enabled = true
+	cfg.StatusPage.Notices = []config.MaintenanceNoticeConfig{
+		{Title: "past", End: now.Add(-time.Minute)},
+		{Title: "ongoing", Start: now.Add(-time.Minute), End: now.Add(time.Hour)},
+		{Title: "upcoming", Start: now.Add(time.Hour), Interfaces: []string{"wg1"}},
+	}
+
+	db := &fakeDatabase{
+		interfaces: []domain.Interface{
+			{Identifier: "wg0", Type: domain.InterfaceTypeServer, PeerDefEndpoint: "vpn.example.com:51820"},
+			{Identifier: "wg1", Type: domain.InterfaceTypeServer, PeerDefEndpoint: "unknown.example.com:51820"},
+			{Identifier: "wg2", Type: domain.InterfaceTypeClient, PeerDefEndpoint: "vpn.example.com:51821"},
+			{Identifier: "wg3", Type: domain.InterfaceTypeServer, Disabled: &disabled},
+		},
+		stats: map[domain.InterfaceIdentifier]domain.InterfaceStatus{"wg0": {UpSince: &upSince}},
+	}
+	wg := &fakeController{up: map[domain.InterfaceIdentifier]bool{"wg0": true, "wg1": true}}
+
+	m, err := NewStatusPageManager(cfg, db, wg)
+	require.NoError(t, err)
+	m.resolver = fakeResolver{}
+
+	require.NoError(t, m.refreshStatus(context.Background(), now))
+	status, err := m.GetStatus(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, status.Endpoints, 2, "only enabled server interfaces are shown")
+	assert.True(t, status.Endpoints[0].IsReachable())
+	assert.Equal(t, &upSince, status.Endpoints[0].UpSince)
+	assert.False(t, status.Endpoints[1].IsReachable(), "unresolvable endpoints are not reachable")
+
+	notices := m.getNotices(now)
+	require.Len(t, notices, 2)
+	assert.True(t, notices[0].IsActive(now))
+	assert.False(t, notices[1].IsActive(now))
+	assert.False(t, notices[1].Affects("wg0"))
+
+	cfg.StatusPage.Enabled = false
+	_, err = m.GetStatus(context.Background())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestNewStatusPageManager_invalidNotice(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.StatusPage.Notices = []config.MaintenanceNoticeConfig{
+		{Title: "broken", Start: time.Now(), End: time.Now().Add(-time.Hour)},
+	}
+
+	_, err := NewStatusPageManager(cfg, &fakeDatabase{}, &fakeController{})
+	assert.Error(t, err)
+}
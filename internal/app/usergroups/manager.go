@@ -0,0 +1,214 @@
+package usergroups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetUserGroup returns the user group with the given identifier
+	GetUserGroup(ctx context.Context, id domain.UserGroupIdentifier) (*domain.UserGroup, error)
+	// GetAllUserGroups returns all user groups
+	GetAllUserGroups(ctx context.Context) ([]domain.UserGroup, error)
+	// SaveUserGroup creates or updates the user group with the given identifier
+	SaveUserGroup(
+		ctx context.Context,
+		id domain.UserGroupIdentifier,
+		updateFunc func(g *domain.UserGroup) (*domain.UserGroup, error),
+	) error
+	// DeleteUserGroup deletes the user group with the given identifier
+	DeleteUserGroup(ctx context.Context, id domain.UserGroupIdentifier) error
+	// GetUser returns the user with the given identifier
+	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	// GetInterface returns the interface with the given identifier
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetRouteSet returns the route set with the given identifier
+	GetRouteSet(ctx context.Context, id domain.RouteSetIdentifier) (*domain.RouteSet, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager manages the user groups of the portal. User groups are independent of LDAP groups, they grant interface
+// administration rights, attach route sets to new peers and are used as notification targets.
+// Only global administrators can manage user groups.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db DatabaseRepo
+}
+
+// NewUserGroupManager creates a new user group manager instance.
+func NewUserGroupManager(cfg *config.Config, bus EventBus, db DatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db: db,
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicUserDeleted, m.handleUserDeletedEvent)
+}
+
+// handleUserDeletedEvent removes the deleted user from all user groups.
+func (m Manager) handleUserDeletedEvent(user domain.User) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	groups, err := m.db.GetAllUserGroups(ctx)
+	if err != nil {
+		slog.Error("failed to load user groups for deleted user", "user", user.Identifier, "error", err)
+		return
+	}
+
+	for _, group := range groups {
+		if !group.HasMember(user.Identifier) {
+			continue
+		}
+
+		err := m.db.SaveUserGroup(ctx, group.Identifier, func(g *domain.UserGroup) (*domain.UserGroup, error) {
+			g.RemoveMember(user.Identifier)
+			return g, nil
+		})
+		if err != nil {
+			slog.Error("failed to remove deleted user from user group",
+				"user", user.Identifier,
+				"group", group.Identifier,
+				"error", err)
+		}
+	}
+}
+
+// GetAllUserGroups returns all user groups.
+func (m Manager) GetAllUserGroups(ctx context.Context) ([]domain.UserGroup, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetAllUserGroups(ctx)
+}
+
+// GetUserGroup returns the user group with the given identifier.
+func (m Manager) GetUserGroup(ctx context.Context, id domain.UserGroupIdentifier) (*domain.UserGroup, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetUserGroup(ctx, id)
+}
+
+// CreateUserGroup creates a new user group.
+func (m Manager) CreateUserGroup(ctx context.Context, group *domain.UserGroup) (*domain.UserGroup, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := m.validateUserGroup(ctx, group); err != nil {
+		return nil, err
+	}
+
+	existingGroup, err := m.db.GetUserGroup(ctx, group.Identifier)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("unable to load existing user group %s: %w", group.Identifier, err)
+	}
+	if existingGroup != nil {
+		return nil, errors.Join(fmt.Errorf("user group %s already exists", group.Identifier),
+			domain.ErrDuplicateEntry)
+	}
+
+	err = m.db.SaveUserGroup(ctx, group.Identifier, func(g *domain.UserGroup) (*domain.UserGroup, error) {
+		group.BaseModel = g.BaseModel
+		return group, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creation failure: %w", err)
+	}
+
+	return group, nil
+}
+
+// UpdateUserGroup updates the given user group. Changed interface administration rights apply to the members
+// with their next login.
+func (m Manager) UpdateUserGroup(ctx context.Context, group *domain.UserGroup) (*domain.UserGroup, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := m.validateUserGroup(ctx, group); err != nil {
+		return nil, err
+	}
+
+	if _, err := m.db.GetUserGroup(ctx, group.Identifier); err != nil {
+		return nil, fmt.Errorf("unable to load existing user group %s: %w", group.Identifier, err)
+	}
+
+	err := m.db.SaveUserGroup(ctx, group.Identifier, func(g *domain.UserGroup) (*domain.UserGroup, error) {
+		group.BaseModel = g.BaseModel
+		return group, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update failure: %w", err)
+	}
+
+	return group, nil
+}
+
+// DeleteUserGroup deletes the user group with the given identifier.
+func (m Manager) DeleteUserGroup(ctx context.Context, id domain.UserGroupIdentifier) error {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	if _, err := m.db.GetUserGroup(ctx, id); err != nil {
+		return fmt.Errorf("unable to find user group %s: %w", id, err)
+	}
+
+	if err := m.db.DeleteUserGroup(ctx, id); err != nil {
+		return fmt.Errorf("deletion failure: %w", err)
+	}
+
+	return nil
+}
+
+// validateUserGroup checks the user group and ensures that all members, interfaces and route sets exist.
+func (m Manager) validateUserGroup(ctx context.Context, group *domain.UserGroup) error {
+	if err := group.Validate(); err != nil {
+		return errors.Join(fmt.Errorf("invalid user group: %w", err), domain.ErrInvalidData)
+	}
+
+	for _, id := range group.Members() {
+		if _, err := m.db.GetUser(ctx, id); err != nil {
+			return errors.Join(fmt.Errorf("invalid member %s: %w", id, err), domain.ErrInvalidData)
+		}
+	}
+	for _, id := range group.AdminInterfaces() {
+		if _, err := m.db.GetInterface(ctx, id); err != nil {
+			return errors.Join(fmt.Errorf("invalid interface %s: %w", id, err), domain.ErrInvalidData)
+		}
+	}
+	for _, id := range group.PeerRouteSets() {
+		if _, err := m.db.GetRouteSet(ctx, id); err != nil {
+			return errors.Join(fmt.Errorf("invalid route set %s: %w", id, err), domain.ErrInvalidData)
+		}
+	}
+
+	return nil
+}
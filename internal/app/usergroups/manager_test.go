@@ -0,0 +1,178 @@
+package usergroups
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeDatabase struct {
+	groups     map[domain.UserGroupIdentifier]domain.UserGroup
+	users      map[domain.UserIdentifier]domain.User
+	interfaces map[domain.InterfaceIdentifier]domain.Interface
+	routeSets  map[domain.RouteSetIdentifier]domain.RouteSet
+}
+
+func (f *fakeDatabase) GetUserGroup(_ context.Context, id domain.UserGroupIdentifier) (*domain.UserGroup, error) {
+	group, ok := f.groups[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &group, nil
+}
+
+func (f *fakeDatabase) GetAllUserGroups(_ context.Context) ([]domain.UserGroup, error) {
+	var groups []domain.UserGroup
+	for _, group := range f.groups {
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (f *fakeDatabase) SaveUserGroup(
+	_ context.Context,
+	id domain.UserGroupIdentifier,
+	updateFunc func(g *domain.UserGroup) (*domain.UserGroup, error),
+) error {
+	group, ok := f.groups[id]
+	if !ok {
+		group = domain.UserGroup{Identifier: id}
+	}
+	updated, err := updateFunc(&group)
+	if err != nil {
+		return err
+	}
+	f.groups[id] = *updated
+	return nil
+}
+
+func (f *fakeDatabase) DeleteUserGroup(_ context.Context, id domain.UserGroupIdentifier) error {
+	delete(f.groups, id)
+	return nil
+}
+
+func (f *fakeDatabase) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (f *fakeDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	iface, ok := f.interfaces[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &iface, nil
+}
+
+func (f *fakeDatabase) GetRouteSet(_ context.Context, id domain.RouteSetIdentifier) (*domain.RouteSet, error) {
+	routeSet, ok := f.routeSets[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &routeSet, nil
+}
+
+type fakeBus struct{}
+
+func (fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func newTestManager(t *testing.T) (*Manager, *fakeDatabase) {
+	db := &fakeDatabase{
+		groups: map[domain.UserGroupIdentifier]domain.UserGroup{},
+		users: map[domain.UserIdentifier]domain.User{
+			"alice": {Identifier: "alice"},
+			"bob":   {Identifier: "bob"},
+		},
+		interfaces: map[domain.InterfaceIdentifier]domain.Interface{
+			"wg0": {Identifier: "wg0"},
+		},
+		routeSets: map[domain.RouteSetIdentifier]domain.RouteSet{
+			"lab-nets": {Identifier: "lab-nets"},
+		},
+	}
+	m, err := NewUserGroupManager(&config.Config{}, fakeBus{}, db)
+	require.NoError(t, err)
+
+	return m, db
+}
+
+func TestManager_CreateUserGroup(t *testing.T) {
+	m, db := newTestManager(t)
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+
+	group := &domain.UserGroup{
+		Identifier:         "lab-staff",
+		DisplayName:        "Lab Staff",
+		MembersStr:         "alice,bob",
+		AdminInterfacesStr: "wg0",
+		PeerRouteSetsStr:   "lab-nets",
+	}
+
+	_, err := m.CreateUserGroup(userCtx, group)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	_, err = m.CreateUserGroup(adminCtx, group)
+	require.NoError(t, err)
+	assert.Contains(t, db.groups, domain.UserGroupIdentifier("lab-staff"))
+
+	_, err = m.CreateUserGroup(adminCtx, group)
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+
+	_, err = m.CreateUserGroup(adminCtx, &domain.UserGroup{Identifier: "ghosts", MembersStr: "carol"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, err = m.CreateUserGroup(adminCtx, &domain.UserGroup{Identifier: "remote", AdminInterfacesStr: "wg9"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, err = m.CreateUserGroup(adminCtx, &domain.UserGroup{Identifier: "Invalid Name"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
+
+func TestManager_UpdateUserGroup(t *testing.T) {
+	m, db := newTestManager(t)
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	_, err := m.UpdateUserGroup(adminCtx, &domain.UserGroup{Identifier: "lab-staff"})
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	db.groups["lab-staff"] = domain.UserGroup{Identifier: "lab-staff", MembersStr: "alice"}
+
+	_, err = m.UpdateUserGroup(adminCtx, &domain.UserGroup{Identifier: "lab-staff", MembersStr: "alice,bob"})
+	require.NoError(t, err)
+	group := db.groups["lab-staff"]
+	assert.Equal(t, []domain.UserIdentifier{"alice", "bob"}, group.Members())
+}
+
+func TestManager_DeleteUserGroup(t *testing.T) {
+	m, db := newTestManager(t)
+	adminCtx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	db.groups["lab-staff"] = domain.UserGroup{Identifier: "lab-staff"}
+
+	require.NoError(t, m.DeleteUserGroup(adminCtx, "lab-staff"))
+	assert.NotContains(t, db.groups, domain.UserGroupIdentifier("lab-staff"))
+
+	assert.ErrorIs(t, m.DeleteUserGroup(adminCtx, "lab-staff"), domain.ErrNotFound)
+}
+
+func TestManager_handleUserDeletedEvent(t *testing.T) {
+	m, db := newTestManager(t)
+
+	db.groups["lab-staff"] = domain.UserGroup{Identifier: "lab-staff", MembersStr: "alice,bob"}
+	db.groups["ops"] = domain.UserGroup{Identifier: "ops", MembersStr: "bob"}
+
+	m.handleUserDeletedEvent(domain.User{Identifier: "alice"})
+
+	labStaff, ops := db.groups["lab-staff"], db.groups["ops"]
+	assert.Equal(t, []domain.UserIdentifier{"bob"}, labStaff.Members())
+	assert.Equal(t, []domain.UserIdentifier{"bob"}, ops.Members())
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"math"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 
 	"github.com/h44z/wg-portal/internal"
 	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
 	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
 )
@@ -35,6 +37,27 @@ type UserDatabaseRepo interface {
 	SaveUser(ctx context.Context, id domain.UserIdentifier, updateFunc func(u *domain.User) (*domain.User, error)) error
 	// DeleteUser deletes the user with the given identifier.
 	DeleteUser(ctx context.Context, id domain.UserIdentifier) error
+	// GetUserNotificationPreferences returns the notification preferences of the given user.
+	GetUserNotificationPreferences(ctx context.Context, id domain.UserIdentifier) (
+		*domain.UserNotificationPreferences,
+		error,
+	)
+	// SaveUserNotificationPreferences creates or updates the notification preferences of a user.
+	SaveUserNotificationPreferences(ctx context.Context, prefs *domain.UserNotificationPreferences) error
+	// DeleteUserNotificationPreferences deletes the notification preferences of the given user.
+	DeleteUserNotificationPreferences(ctx context.Context, id domain.UserIdentifier) error
+	// GetSavedView returns the saved view with the given id.
+	GetSavedView(ctx context.Context, id uint64) (*domain.SavedView, error)
+	// GetSavedViews returns the saved views of all users for the given list.
+	GetSavedViews(ctx context.Context, list domain.SavedViewList) ([]domain.SavedView, error)
+	// SaveSavedView creates or updates the given saved view.
+	SaveSavedView(ctx context.Context, view *domain.SavedView) error
+	// DeleteSavedView deletes the saved view with the given id.
+	DeleteSavedView(ctx context.Context, id uint64) error
+	// DeleteUserSavedViews deletes all saved views of the given user.
+	DeleteUserSavedViews(ctx context.Context, id domain.UserIdentifier) error
+	// GetAllUserGroups returns all user groups.
+	GetAllUserGroups(ctx context.Context) ([]domain.UserGroup, error)
 }
 
 type PeerDatabaseRepo interface {
@@ -97,21 +120,34 @@ func (m Manager) StartBackgroundJobs(ctx context.Context) {
 
 // GetUser returns the user with the given identifier.
 func (m Manager) GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error) {
-	if err := domain.ValidateUserAccessRights(ctx, id); err != nil {
-		return nil, err
-	}
-
 	user, err := m.users.GetUser(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load user %s: %w", id, err)
 	}
+	if err := domain.ValidateUserReadAccessRights(ctx, id, user.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
 	peers, _ := m.peers.GetUserPeers(ctx, id) // ignore error, list will be empty in error case
 
 	user.LinkedPeerCount = len(peers)
+	m.loadGroups(ctx, user)
 
 	return user, nil
 }
 
+// loadGroups sets the user groups of the given users. If the groups cannot be loaded, the users have no groups.
+func (m Manager) loadGroups(ctx context.Context, users ...*domain.User) {
+	groups, err := m.users.GetAllUserGroups(ctx)
+	if err != nil {
+		slog.Warn("failed to load user groups", "error", err)
+		return
+	}
+
+	for _, user := range users {
+		user.Groups = domain.GroupsOfUser(groups, user.Identifier)
+	}
+}
+
 // GetUserByEmail returns the user with the given email address.
 func (m Manager) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
 
@@ -120,13 +156,14 @@ func (m Manager) GetUserByEmail(ctx context.Context, email string) (*domain.User
 		return nil, fmt.Errorf("unable to load user for email %s: %w", email, err)
 	}
 
-	if err := domain.ValidateUserAccessRights(ctx, user.Identifier); err != nil {
+	if err := domain.ValidateUserAccessRights(ctx, user.Identifier, user.OrganizationIdentifier); err != nil {
 		return nil, err
 	}
 
 	peers, _ := m.peers.GetUserPeers(ctx, user.Identifier) // ignore error, list will be empty in error case
 
 	user.LinkedPeerCount = len(peers)
+	m.loadGroups(ctx, user)
 
 	return user, nil
 }
@@ -139,28 +176,38 @@ func (m Manager) GetUserByWebAuthnCredential(ctx context.Context, credentialIdBa
 		return nil, fmt.Errorf("unable to load user for webauthn credential %s: %w", credentialIdBase64, err)
 	}
 
-	if err := domain.ValidateUserAccessRights(ctx, user.Identifier); err != nil {
+	if err := domain.ValidateUserAccessRights(ctx, user.Identifier, user.OrganizationIdentifier); err != nil {
 		return nil, err
 	}
 
 	peers, _ := m.peers.GetUserPeers(ctx, user.Identifier) // ignore error, list will be empty in error case
 
 	user.LinkedPeerCount = len(peers)
+	m.loadGroups(ctx, user)
 
 	return user, nil
 }
 
 // GetAllUsers returns all users.
 func (m Manager) GetAllUsers(ctx context.Context) ([]domain.User, error) {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+	if err := domain.ValidateHelpdeskAccessRights(ctx); err != nil {
 		return nil, err
 	}
 
-	users, err := m.users.GetAllUsers(ctx)
+	allUsers, err := m.users.GetAllUsers(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load users: %w", err)
 	}
 
+	// organization admins only see the users of their organization
+	currentUser := domain.GetUserInfo(ctx)
+	users := make([]domain.User, 0, len(allUsers))
+	for _, user := range allUsers {
+		if currentUser.CanAccessOrganization(user.OrganizationIdentifier) {
+			users = append(users, user)
+		}
+	}
+
 	ch := make(chan *domain.User)
 	wg := sync.WaitGroup{}
 	workers := int(math.Min(float64(len(users)), 10))
@@ -180,19 +227,25 @@ func (m Manager) GetAllUsers(ctx context.Context) ([]domain.User, error) {
 	close(ch)
 	wg.Wait()
 
+	userRefs := make([]*domain.User, len(users))
+	for i := range users {
+		userRefs[i] = &users[i]
+	}
+	m.loadGroups(ctx, userRefs...)
+
 	return users, nil
 }
 
 // UpdateUser updates the user with the given identifier.
 func (m Manager) UpdateUser(ctx context.Context, user *domain.User) (*domain.User, error) {
-	if err := domain.ValidateUserAccessRights(ctx, user.Identifier); err != nil {
-		return nil, err
-	}
-
 	existingUser, err := m.users.GetUser(ctx, user.Identifier)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load existing user %s: %w", user.Identifier, err)
 	}
+	err = domain.ValidateUserAccessRights(ctx, existingUser.Identifier, existingUser.OrganizationIdentifier)
+	if err != nil {
+		return nil, err
+	}
 
 	if err := m.validateModifications(ctx, existingUser, user); err != nil {
 		return nil, fmt.Errorf("update not allowed: %w", err)
@@ -215,6 +268,7 @@ func (m Manager) UpdateUser(ctx context.Context, user *domain.User) (*domain.Use
 		return nil, fmt.Errorf("update failure: %w", err)
 	}
 
+	m.loadGroups(ctx, user)
 	m.bus.Publish(app.TopicUserUpdated, *user)
 
 	switch {
@@ -237,6 +291,10 @@ func (m Manager) CreateUser(ctx context.Context, user *domain.User) (*domain.Use
 		return nil, err
 	}
 
+	if currentUser := domain.GetUserInfo(ctx); !currentUser.IsGlobalAdmin() {
+		user.OrganizationIdentifier = currentUser.Organization // organization admins create users in their own org
+	}
+
 	existingUser, err := m.users.GetUser(ctx, user.Identifier)
 	if err != nil && !errors.Is(err, domain.ErrNotFound) {
 		return nil, fmt.Errorf("unable to load existing user %s: %w", user.Identifier, err)
@@ -277,6 +335,11 @@ func (m Manager) DeleteUser(ctx context.Context, id domain.UserIdentifier) error
 	if err != nil && !errors.Is(err, domain.ErrNotFound) {
 		return fmt.Errorf("unable to find user %s: %w", id, err)
 	}
+	if existingUser != nil {
+		if err := validateOrganizationAccess(ctx, existingUser); err != nil {
+			return err
+		}
+	}
 
 	if err := m.validateDeletion(ctx, existingUser); err != nil {
 		return fmt.Errorf("deletion not allowed: %w", err)
@@ -287,11 +350,199 @@ func (m Manager) DeleteUser(ctx context.Context, id domain.UserIdentifier) error
 		return fmt.Errorf("deletion failure: %w", err)
 	}
 
+	if err := m.users.DeleteUserNotificationPreferences(ctx, id); err != nil {
+		slog.Warn("failed to delete notification preferences of deleted user", "user", id, "error", err)
+	}
+	if err := m.users.DeleteUserSavedViews(ctx, id); err != nil {
+		slog.Warn("failed to delete saved views of deleted user", "user", id, "error", err)
+	}
+
 	m.bus.Publish(app.TopicUserDeleted, *existingUser)
 
 	return nil
 }
 
+// GetNotificationPreferences returns the notification preferences of the given user. Users that did not store
+// any preferences receive the default preferences.
+func (m Manager) GetNotificationPreferences(ctx context.Context, id domain.UserIdentifier) (
+	*domain.UserNotificationPreferences,
+	error,
+) {
+	if _, err := m.getPreferenceUser(ctx, id); err != nil {
+		return nil, err
+	}
+
+	prefs, err := m.users.GetUserNotificationPreferences(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		defaults := domain.DefaultNotificationPreferences(id)
+		return &defaults, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to load notification preferences of user %s: %w", id, err)
+	}
+
+	return prefs, nil
+}
+
+// UpdateNotificationPreferences stores the notification preferences of a user.
+func (m Manager) UpdateNotificationPreferences(ctx context.Context, prefs *domain.UserNotificationPreferences) (
+	*domain.UserNotificationPreferences,
+	error,
+) {
+	if _, err := m.getPreferenceUser(ctx, prefs.UserIdentifier); err != nil {
+		return nil, err
+	}
+
+	if err := prefs.Validate(); err != nil {
+		return nil, err
+	}
+	if prefs.UsesChannel(domain.NotificationChannelTelegram) && m.cfg.Alerting.Telegram.BotToken == "" {
+		return nil, fmt.Errorf("telegram notifications are not configured: %w", domain.ErrInvalidData)
+	}
+
+	prefs.UpdatedAt = time.Now()
+	if err := m.users.SaveUserNotificationPreferences(ctx, prefs); err != nil {
+		return nil, fmt.Errorf("unable to store notification preferences of user %s: %w", prefs.UserIdentifier, err)
+	}
+
+	return prefs, nil
+}
+
+// getPreferenceUser loads the user whose preferences are accessed and checks the access rights.
+func (m Manager) getPreferenceUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	user, err := m.users.GetUser(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load user %s: %w", id, err)
+	}
+	if err := domain.ValidateUserAccessRights(ctx, id, user.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetSavedViews returns the saved views of the given list that the user can use: the own views and, for
+// administrators, the views shared by other administrators.
+func (m Manager) GetSavedViews(ctx context.Context, id domain.UserIdentifier, list domain.SavedViewList) (
+	[]domain.SavedView,
+	error,
+) {
+	user, err := m.getPreferenceUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !list.IsValid() {
+		return nil, errors.Join(fmt.Errorf("invalid list %q", list), domain.ErrInvalidData)
+	}
+
+	views, err := m.users.GetSavedViews(ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load saved views: %w", err)
+	}
+
+	visible := make([]domain.SavedView, 0, len(views))
+	for _, view := range views {
+		if view.IsVisibleTo(id, user.IsAdmin) {
+			visible = append(visible, view)
+		}
+	}
+
+	return visible, nil
+}
+
+// CreateSavedView stores a new saved view for the owner of the view.
+func (m Manager) CreateSavedView(ctx context.Context, view *domain.SavedView) (*domain.SavedView, error) {
+	user, err := m.getPreferenceUser(ctx, view.UserIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSavedView(user, view); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	view.Id = 0
+	view.CreatedAt = now
+	view.UpdatedAt = now
+
+	if err := m.users.SaveSavedView(ctx, view); err != nil {
+		return nil, fmt.Errorf("unable to store saved view: %w", err)
+	}
+
+	return view, nil
+}
+
+// UpdateSavedView updates a saved view. Views can only be changed by their owner.
+func (m Manager) UpdateSavedView(ctx context.Context, view *domain.SavedView) (*domain.SavedView, error) {
+	user, err := m.getPreferenceUser(ctx, view.UserIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := m.getOwnSavedView(ctx, view.UserIdentifier, view.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSavedView(user, view); err != nil {
+		return nil, err
+	}
+
+	view.CreatedAt = existing.CreatedAt
+	view.UpdatedAt = time.Now()
+
+	if err := m.users.SaveSavedView(ctx, view); err != nil {
+		return nil, fmt.Errorf("unable to store saved view %d: %w", view.Id, err)
+	}
+
+	return view, nil
+}
+
+// DeleteSavedView deletes a saved view of the given user. Shared views can only be deleted by their owner.
+func (m Manager) DeleteSavedView(ctx context.Context, userId domain.UserIdentifier, id uint64) error {
+	if _, err := m.getPreferenceUser(ctx, userId); err != nil {
+		return err
+	}
+	if _, err := m.getOwnSavedView(ctx, userId, id); err != nil {
+		return err
+	}
+
+	if err := m.users.DeleteSavedView(ctx, id); err != nil {
+		return fmt.Errorf("unable to delete saved view %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// getOwnSavedView loads the saved view with the given id and ensures that it belongs to the given user.
+func (m Manager) getOwnSavedView(ctx context.Context, userId domain.UserIdentifier, id uint64) (
+	*domain.SavedView,
+	error,
+) {
+	view, err := m.users.GetSavedView(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load saved view %d: %w", id, err)
+	}
+	if view.UserIdentifier != userId {
+		return nil, fmt.Errorf("saved view %d belongs to another user: %w", id, domain.ErrNoPermission)
+	}
+
+	return view, nil
+}
+
+// validateSavedView checks the given view of the user. Only administrators can share views.
+func validateSavedView(user *domain.User, view *domain.SavedView) error {
+	view.Name = strings.TrimSpace(view.Name)
+	view.Filter = strings.TrimSpace(view.Filter)
+
+	if err := view.Validate(); err != nil {
+		return errors.Join(fmt.Errorf("invalid saved view: %w", err), domain.ErrInvalidData)
+	}
+	if view.Shared && !user.IsAdmin {
+		return fmt.Errorf("only administrators can share views: %w", domain.ErrNoPermission)
+	}
+
+	return nil
+}
+
 // ActivateApi activates the API access for the user with the given identifier.
 func (m Manager) ActivateApi(ctx context.Context, id domain.UserIdentifier) (*domain.User, error) {
 	user, err := m.users.GetUser(ctx, id)
@@ -349,6 +600,48 @@ func (m Manager) DeactivateApi(ctx context.Context, id domain.UserIdentifier) (*
 	return user, nil
 }
 
+// ResetWebAuthnCredentials removes all passkeys of the user with the given identifier, for example after the user lost
+// the security key. Helpdesk users cannot reset the passkeys of administrators.
+func (m Manager) ResetWebAuthnCredentials(ctx context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	if err := domain.ValidateHelpdeskAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := m.users.GetUser(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find user %s: %w", id, err)
+	}
+	if err := validateOrganizationAccess(ctx, user); err != nil {
+		return nil, err
+	}
+	if user.IsAdmin && !domain.GetUserInfo(ctx).IsAdmin {
+		return nil, fmt.Errorf("cannot reset passkeys of administrators: %w", domain.ErrNoPermission)
+	}
+
+	removed := len(user.WebAuthnCredentialList)
+	user.WebAuthnCredentialList = nil
+
+	err = m.users.SaveUser(ctx, user.Identifier, func(u *domain.User) (*domain.User, error) {
+		user.CopyCalculatedAttributes(u)
+		return user, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update failure: %w", err)
+	}
+
+	m.bus.Publish(app.TopicUserUpdated, *user)
+	m.bus.Publish(app.TopicAuditCredentialsReset, domain.AuditEventWrapper[audit.CredentialsResetEvent]{
+		Ctx:    ctx,
+		Source: "passkey reset",
+		Event: audit.CredentialsResetEvent{
+			User:        user.Identifier,
+			Credentials: removed,
+		},
+	})
+
+	return user, nil
+}
+
 func (m Manager) validateModifications(ctx context.Context, old, new *domain.User) error {
 	currentUser := domain.GetUserInfo(ctx)
 
@@ -368,6 +661,15 @@ func (m Manager) validateModifications(ctx context.Context, old, new *domain.Use
 		return errors.Join(fmt.Errorf("password too weak: %w", err), domain.ErrInvalidData)
 	}
 
+	if !currentUser.IsAdmin && (old.IsAdmin != new.IsAdmin || old.IsHelpdesk != new.IsHelpdesk ||
+		old.AdminInterfacesStr != new.AdminInterfacesStr) {
+		return fmt.Errorf("cannot change admin rights: %w", domain.ErrNoPermission)
+	}
+
+	if !currentUser.IsGlobalAdmin() && old.OrganizationIdentifier != new.OrganizationIdentifier {
+		return fmt.Errorf("cannot change organization: %w", domain.ErrNoPermission)
+	}
+
 	if currentUser.Id == old.Identifier && old.IsAdmin && !new.IsAdmin {
 		return fmt.Errorf("cannot remove own admin rights: %w", domain.ErrInvalidData)
 	}
@@ -384,6 +686,14 @@ func (m Manager) validateModifications(ctx context.Context, old, new *domain.Use
 		return fmt.Errorf("cannot change user source: %w", domain.ErrInvalidData)
 	}
 
+	if old.IsServiceAccount() != new.IsServiceAccount() {
+		return fmt.Errorf("cannot change user type: %w", domain.ErrInvalidData)
+	}
+
+	if new.IsServiceAccount() && new.Email != "" {
+		return fmt.Errorf("service accounts cannot have an email address: %w", domain.ErrInvalidData)
+	}
+
 	return nil
 }
 
@@ -414,6 +724,14 @@ func (m Manager) validateCreation(ctx context.Context, new *domain.User) error {
 		return fmt.Errorf("reserved user identifier: %w", domain.ErrInvalidData)
 	}
 
+	if new.Type != "" && new.Type != domain.UserTypeHuman && new.Type != domain.UserTypeService {
+		return fmt.Errorf("invalid user type %s: %w", new.Type, domain.ErrInvalidData)
+	}
+
+	if new.IsServiceAccount() {
+		return m.validateServiceAccountCreation(new)
+	}
+
 	// Admins are allowed to create users for arbitrary sources.
 	if new.Source != domain.UserSourceDatabase && !currentUser.IsAdmin {
 		return fmt.Errorf("invalid user source: %s, only %s is allowed: %w",
@@ -432,6 +750,25 @@ func (m Manager) validateCreation(ctx context.Context, new *domain.User) error {
 	return nil
 }
 
+// validateServiceAccountCreation checks the fields of a new service account. Service accounts are always stored in
+// the database and have neither a password nor an email address.
+func (m Manager) validateServiceAccountCreation(new *domain.User) error {
+	if new.Source != domain.UserSourceDatabase {
+		return fmt.Errorf("invalid service account source: %s, only %s is allowed: %w",
+			new.Source, domain.UserSourceDatabase, domain.ErrInvalidData)
+	}
+
+	if string(new.Password) != "" {
+		return fmt.Errorf("service accounts cannot have a password: %w", domain.ErrInvalidData)
+	}
+
+	if new.Email != "" {
+		return fmt.Errorf("service accounts cannot have an email address: %w", domain.ErrInvalidData)
+	}
+
+	return nil
+}
+
 func (m Manager) validateDeletion(ctx context.Context, del *domain.User) error {
 	currentUser := domain.GetUserInfo(ctx)
 
@@ -450,9 +787,24 @@ func (m Manager) validateDeletion(ctx context.Context, del *domain.User) error {
 	return nil
 }
 
+// validateOrganizationAccess checks if the current user is allowed to access the given user. Users can always
+// access their own data, access through admin rights is restricted to the organization of the admin.
+func validateOrganizationAccess(ctx context.Context, user *domain.User) error {
+	if domain.GetUserInfo(ctx).Id == user.Identifier {
+		return nil
+	}
+
+	return domain.ValidateOrganizationAccessRights(ctx, user.OrganizationIdentifier)
+}
+
 func (m Manager) validateApiChange(ctx context.Context, user *domain.User) error {
 	currentUser := domain.GetUserInfo(ctx)
 
+	if user.IsServiceAccount() && currentUser.IsAdmin {
+		// service accounts cannot log in, so their API access is managed by admins
+		return validateOrganizationAccess(ctx, user)
+	}
+
 	if currentUser.Id != user.Identifier {
 		return fmt.Errorf("cannot change API access of user: %w", domain.ErrNoPermission)
 	}
@@ -569,6 +921,13 @@ func (m Manager) updateLdapUsers(
 				return fmt.Errorf("create error for user id %s: %w", user.Identifier, err)
 			}
 		} else {
+			if existingUser.IsServiceAccount() {
+				cancel()
+				slog.Warn("ldap user collides with service account, skipping", "user", user.Identifier,
+					"provider", provider.ProviderName)
+				continue
+			}
+
 			// update existing user
 			if provider.AutoReEnable && existingUser.DisabledReason == domain.DisabledReasonLdapMissing {
 				user.Disabled = nil
@@ -625,6 +984,9 @@ func (m Manager) disableMissingLdapUsers(
 		if user.Source != domain.UserSourceLdap {
 			continue // ignore non ldap users
 		}
+		if user.IsServiceAccount() {
+			continue // service accounts are never managed by LDAP
+		}
 		if user.ProviderName != providerName {
 			continue // user was synchronized through different provider
 		}
@@ -0,0 +1,271 @@
+package users
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestManager_validateCreation_serviceAccount(t *testing.T) {
+	m := Manager{cfg: &config.Config{}}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	// service accounts need no password
+	user := &domain.User{Identifier: "ci", Source: domain.UserSourceDatabase, Type: domain.UserTypeService}
+	assert.NoError(t, m.validateCreation(ctx, user))
+
+	user.Email = "ci@example.com"
+	assert.ErrorIs(t, m.validateCreation(ctx, user), domain.ErrInvalidData)
+
+	user.Email = ""
+	user.Password = "secret-password"
+	assert.ErrorIs(t, m.validateCreation(ctx, user), domain.ErrInvalidData)
+
+	user.Password = ""
+	user.Source = domain.UserSourceLdap
+	assert.ErrorIs(t, m.validateCreation(ctx, user), domain.ErrInvalidData)
+
+	user.Source = domain.UserSourceDatabase
+	user.Type = "robot"
+	assert.ErrorIs(t, m.validateCreation(ctx, user), domain.ErrInvalidData)
+}
+
+func TestManager_validateModifications_serviceAccount(t *testing.T) {
+	m := Manager{cfg: &config.Config{}}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	old := &domain.User{Identifier: "ci", Source: domain.UserSourceDatabase, Type: domain.UserTypeService}
+
+	updated := *old
+	updated.Notes = "deployment pipeline"
+	assert.NoError(t, m.validateModifications(ctx, old, &updated))
+
+	updated.Type = domain.UserTypeHuman
+	assert.ErrorIs(t, m.validateModifications(ctx, old, &updated), domain.ErrInvalidData)
+
+	updated.Type = domain.UserTypeService
+	updated.Email = "ci@example.com"
+	assert.ErrorIs(t, m.validateModifications(ctx, old, &updated), domain.ErrInvalidData)
+
+	updated.Email = ""
+	updated.Password = "secret-password"
+	assert.ErrorIs(t, m.validateModifications(ctx, old, &updated), domain.ErrInvalidData)
+}
+
+func TestManager_validateApiChange_serviceAccount(t *testing.T) {
+	m := Manager{cfg: &config.Config{}}
+	adminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+
+	service := &domain.User{Identifier: "ci", Source: domain.UserSourceDatabase, Type: domain.UserTypeService}
+	assert.NoError(t, m.validateApiChange(adminCtx, service))
+	assert.ErrorIs(t, m.validateApiChange(userCtx, service), domain.ErrNoPermission)
+
+	human := &domain.User{Identifier: "bob", Source: domain.UserSourceDatabase}
+	assert.ErrorIs(t, m.validateApiChange(adminCtx, human), domain.ErrNoPermission)
+}
+
+type fakeNotificationRepo struct {
+	UserDatabaseRepo
+	users map[domain.UserIdentifier]domain.User
+	prefs map[domain.UserIdentifier]domain.UserNotificationPreferences
+}
+
+func (f *fakeNotificationRepo) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (f *fakeNotificationRepo) GetUserNotificationPreferences(_ context.Context, id domain.UserIdentifier) (
+	*domain.UserNotificationPreferences,
+	error,
+) {
+	prefs, ok := f.prefs[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &prefs, nil
+}
+
+func (f *fakeNotificationRepo) SaveUserNotificationPreferences(
+	_ context.Context,
+	prefs *domain.UserNotificationPreferences,
+) error {
+	f.prefs[prefs.UserIdentifier] = *prefs
+	return nil
+}
+
+func TestManager_NotificationPreferences(t *testing.T) {
+	repo := &fakeNotificationRepo{
+		users: map[domain.UserIdentifier]domain.User{"alice": {Identifier: "alice"}, "bob": {Identifier: "bob"}},
+		prefs: map[domain.UserIdentifier]domain.UserNotificationPreferences{},
+	}
+	m := Manager{cfg: &config.Config{}, users: repo}
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+
+	prefs, err := m.GetNotificationPreferences(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, domain.DefaultNotificationPreferences("alice"), *prefs)
+
+	_, err = m.GetNotificationPreferences(ctx, "bob")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	telegram := &domain.UserNotificationPreferences{
+		UserIdentifier:  "alice",
+		SecurityChannel: domain.NotificationChannelTelegram,
+		TelegramChatId:  "42",
+	}
+	_, err = m.UpdateNotificationPreferences(ctx, telegram)
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "telegram requires a configured bot")
+
+	m.cfg.Alerting.Telegram.BotToken = "token"
+	_, err = m.UpdateNotificationPreferences(ctx, telegram)
+	require.NoError(t, err)
+	assert.Equal(t, domain.NotificationChannelTelegram,
+		repo.prefs["alice"].Channel(domain.NotificationCategorySecurity))
+	assert.False(t, repo.prefs["alice"].UpdatedAt.IsZero())
+}
+
+type fakeCredentialRepo struct {
+	fakeNotificationRepo
+}
+
+func (f *fakeCredentialRepo) SaveUser(
+	_ context.Context,
+	id domain.UserIdentifier,
+	updateFunc func(u *domain.User) (*domain.User, error),
+) error {
+	user := f.users[id]
+	updated, err := updateFunc(&user)
+	if err != nil {
+		return err
+	}
+	f.users[id] = *updated
+	return nil
+}
+
+type fakeBus struct {
+	topics []string
+}
+
+func (f *fakeBus) Publish(topic string, _ ...any) {
+	f.topics = append(f.topics, topic)
+}
+
+func TestManager_ResetWebAuthnCredentials(t *testing.T) {
+	credentials := []domain.UserWebauthnCredential{{CredentialIdentifier: "key"}}
+	repo := &fakeCredentialRepo{fakeNotificationRepo{users: map[domain.UserIdentifier]domain.User{
+		"alice": {Identifier: "alice", WebAuthnCredentialList: credentials},
+		"admin": {Identifier: "admin", IsAdmin: true, WebAuthnCredentialList: credentials},
+	}}}
+	bus := &fakeBus{}
+	m := Manager{cfg: &config.Config{}, bus: bus, users: repo}
+	helpdeskCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "bob", IsHelpdesk: true})
+
+	_, err := m.ResetWebAuthnCredentials(helpdeskCtx, "alice")
+	require.NoError(t, err)
+	assert.Empty(t, repo.users["alice"].WebAuthnCredentialList)
+	assert.Contains(t, bus.topics, app.TopicAuditCredentialsReset)
+
+	_, err = m.ResetWebAuthnCredentials(helpdeskCtx, "admin")
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "helpdesk users cannot reset administrators")
+	assert.Len(t, repo.users["admin"].WebAuthnCredentialList, 1)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "carol"})
+	_, err = m.ResetWebAuthnCredentials(userCtx, "alice")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+type fakeSavedViewRepo struct {
+	fakeNotificationRepo
+	views map[uint64]domain.SavedView
+}
+
+func (f *fakeSavedViewRepo) GetSavedView(_ context.Context, id uint64) (*domain.SavedView, error) {
+	view, ok := f.views[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &view, nil
+}
+
+func (f *fakeSavedViewRepo) GetSavedViews(_ context.Context, list domain.SavedViewList) ([]domain.SavedView, error) {
+	var views []domain.SavedView
+	for _, view := range f.views {
+		if view.List == list {
+			views = append(views, view)
+		}
+	}
+	return views, nil
+}
+
+func (f *fakeSavedViewRepo) SaveSavedView(_ context.Context, view *domain.SavedView) error {
+	if view.Id == 0 {
+		view.Id = uint64(len(f.views) + 1)
+	}
+	f.views[view.Id] = *view
+	return nil
+}
+
+func (f *fakeSavedViewRepo) DeleteSavedView(_ context.Context, id uint64) error {
+	delete(f.views, id)
+	return nil
+}
+
+func TestManager_SavedViews(t *testing.T) {
+	repo := &fakeSavedViewRepo{
+		fakeNotificationRepo: fakeNotificationRepo{users: map[domain.UserIdentifier]domain.User{
+			"alice": {Identifier: "alice", IsAdmin: true},
+			"bob":   {Identifier: "bob", IsAdmin: true},
+			"carol": {Identifier: "carol"},
+		}},
+		views: map[uint64]domain.SavedView{},
+	}
+	m := Manager{cfg: &config.Config{}, users: repo}
+	aliceCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice", IsAdmin: true})
+	bobCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "bob", IsAdmin: true})
+	carolCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "carol"})
+
+	shared, err := m.CreateSavedView(aliceCtx, &domain.SavedView{UserIdentifier: "alice",
+		List: domain.SavedViewListPeers, Name: " Offline ", Shared: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Offline", shared.Name)
+	_, err = m.CreateSavedView(aliceCtx, &domain.SavedView{UserIdentifier: "alice",
+		List: domain.SavedViewListPeers, Name: "Private"})
+	require.NoError(t, err)
+	_, err = m.CreateSavedView(carolCtx, &domain.SavedView{UserIdentifier: "carol",
+		List: domain.SavedViewListPeers, Name: "Mine"})
+	require.NoError(t, err)
+
+	_, err = m.CreateSavedView(carolCtx, &domain.SavedView{UserIdentifier: "carol",
+		List: domain.SavedViewListPeers, Name: "Shared", Shared: true})
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "only administrators can share views")
+	_, err = m.CreateSavedView(carolCtx, &domain.SavedView{UserIdentifier: "carol", List: "interfaces", Name: "x"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	views, err := m.GetSavedViews(bobCtx, "bob", domain.SavedViewListPeers)
+	require.NoError(t, err)
+	require.Len(t, views, 1, "administrators see shared views of other administrators")
+	assert.Equal(t, shared.Id, views[0].Id)
+	views, err = m.GetSavedViews(carolCtx, "carol", domain.SavedViewListPeers)
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	assert.Equal(t, "Mine", views[0].Name)
+
+	shared.UserIdentifier = "bob"
+	_, err = m.UpdateSavedView(bobCtx, shared)
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "shared views can only be changed by their owner")
+	assert.ErrorIs(t, m.DeleteSavedView(bobCtx, "bob", shared.Id), domain.ErrNoPermission)
+
+	require.NoError(t, m.DeleteSavedView(aliceCtx, "alice", shared.Id))
+	assert.Len(t, repo.views, 2)
+}
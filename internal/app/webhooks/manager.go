@@ -1,17 +1,32 @@
 package webhooks
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/h44z/wg-portal/internal/app"
 	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
+// EventIdHeader contains the id of the delivered event. Events are delivered at least once, consumers can use the
+// id to detect duplicate deliveries.
+const EventIdHeader = "X-Webhook-Event-Id"
+
+// deliveryBatchSize is the number of events that are loaded at once by the delivery workers.
+const deliveryBatchSize = 100
+
+// cleanupInterval is the interval in which expired events are removed from the event log.
+const cleanupInterval = 1 * time.Hour
+
 // region dependencies
 
 type EventBus interface {
@@ -21,23 +36,70 @@ type EventBus interface {
 	Subscribe(topic string, fn interface{}) error
 }
 
+type DatabaseRepo interface {
+	// SaveWebhookEvent stores the given webhook event. New events receive the next event id.
+	SaveWebhookEvent(ctx context.Context, event *domain.WebhookEvent) error
+	// GetWebhookEvent returns the webhook event with the given id.
+	GetWebhookEvent(ctx context.Context, id uint64) (*domain.WebhookEvent, error)
+	// GetWebhookEvents returns the webhook events with an id greater than the given id, ordered by id.
+	GetWebhookEvents(ctx context.Context, afterId uint64, limit int) ([]domain.WebhookEvent, error)
+	// GetLastWebhookEventId returns the id of the most recent webhook event, 0 if no event was stored yet.
+	GetLastWebhookEventId(ctx context.Context) (uint64, error)
+	// DeleteWebhookEvents deletes all webhook events that were created before the given time.
+	DeleteWebhookEvents(ctx context.Context, before time.Time) error
+	// GetWebhookDeliveries returns the delivery records of the given webhook event.
+	GetWebhookDeliveries(ctx context.Context, eventId uint64) ([]domain.WebhookDelivery, error)
+	// SaveWebhookDelivery creates or updates the given webhook delivery record.
+	SaveWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	// GetWebhookCursors returns the cursors of all webhook endpoints.
+	GetWebhookCursors(ctx context.Context) ([]domain.WebhookCursor, error)
+	// SaveWebhookCursor creates or updates the given webhook cursor.
+	SaveWebhookCursor(ctx context.Context, cursor *domain.WebhookCursor) error
+}
+
 // endregion dependencies
 
+// Manager stores all entity events in the webhook event log and delivers them to the configured endpoints.
+// Each endpoint has its own worker and cursor, events are delivered in order and at least once. An event that
+// cannot be delivered is retried until the maximum number of attempts is reached.
+// The cursors are only updated by a single instance, the manager must not run on multiple instances that share
+// a database.
 type Manager struct {
 	cfg *config.Config
 	bus EventBus
+	db  DatabaseRepo
 
-	client *http.Client
+	endpoints map[string]config.WebhookEndpointConfig
+	clients   map[string]*http.Client
+	triggers  map[string]chan struct{} // wakes up the delivery worker of an endpoint
+
+	cursorMux *sync.Mutex // serializes cursor updates of the workers and the replay requests
 }
 
 // NewManager creates a new webhook manager instance.
-func NewManager(cfg *config.Config, bus EventBus) (*Manager, error) {
+func NewManager(cfg *config.Config, bus EventBus, db DatabaseRepo) (*Manager, error) {
 	m := &Manager{
 		cfg: cfg,
 		bus: bus,
-		client: &http.Client{
-			Timeout: cfg.Webhook.Timeout,
-		},
+		db:  db,
+
+		endpoints: make(map[string]config.WebhookEndpointConfig),
+		clients:   make(map[string]*http.Client),
+		triggers:  make(map[string]chan struct{}),
+
+		cursorMux: &sync.Mutex{},
+	}
+
+	for _, endpoint := range cfg.Webhook.AllEndpoints() {
+		if endpoint.Name == "" {
+			return nil, fmt.Errorf("webhook endpoint %s has no name", endpoint.Url)
+		}
+		if _, exists := m.endpoints[endpoint.Name]; exists {
+			return nil, fmt.Errorf("duplicate webhook endpoint name %s", endpoint.Name)
+		}
+		m.endpoints[endpoint.Name] = endpoint
+		m.clients[endpoint.Name] = &http.Client{Timeout: endpoint.Timeout}
+		m.triggers[endpoint.Name] = make(chan struct{}, 1)
 	}
 
 	m.connectToMessageBus()
@@ -45,14 +107,78 @@ func NewManager(cfg *config.Config, bus EventBus) (*Manager, error) {
 	return m, nil
 }
 
-// StartBackgroundJobs starts background jobs for the webhook manager.
+// StartBackgroundJobs starts the delivery workers and the event log cleanup of the webhook manager.
 // This method is non-blocking and returns immediately.
-func (m Manager) StartBackgroundJobs(_ context.Context) {
-	// this is a no-op for now
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if len(m.endpoints) == 0 {
+		return
+	}
+
+	if err := m.initializeCursors(ctx); err != nil {
+		slog.Error("[WEBHOOK] failed to initialize endpoint cursors", "error", err)
+		return
+	}
+
+	for name := range m.endpoints {
+		go m.runDelivery(ctx, name)
+	}
+
+	if m.cfg.Webhook.EventRetention > 0 {
+		go m.runCleanup(ctx)
+	}
+
+	slog.Debug("[WEBHOOK] started delivery workers", "endpoints", len(m.endpoints))
+}
+
+// initializeCursors creates the cursors of new endpoints. New endpoints only receive events that are emitted after
+// the endpoint was configured, older events can be replayed by an administrator.
+func (m Manager) initializeCursors(ctx context.Context) error {
+	cursors, err := m.db.GetWebhookCursors(ctx)
+	if err != nil {
+		return err
+	}
+	lastId, err := m.db.GetLastWebhookEventId(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]struct{}, len(cursors))
+	for _, cursor := range cursors {
+		existing[cursor.Endpoint] = struct{}{}
+	}
+
+	for name := range m.endpoints {
+		if _, ok := existing[name]; ok {
+			continue
+		}
+		cursor := &domain.WebhookCursor{Endpoint: name, EventId: lastId, UpdatedAt: time.Now()}
+		if err := m.db.SaveWebhookCursor(ctx, cursor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m Manager) runCleanup(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		cutoff := time.Now().Add(-m.cfg.Webhook.EventRetention)
+		if err := m.db.DeleteWebhookEvents(ctx, cutoff); err != nil {
+			slog.Warn("[WEBHOOK] failed to remove expired events", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return // program stopped
+		case <-ticker.C:
+		}
+	}
 }
 
 func (m Manager) connectToMessageBus() {
-	if m.cfg.Webhook.Url == "" {
+	if len(m.endpoints) == 0 {
 		slog.Info("[WEBHOOK] no webhook configured, skipping event-bus subscription")
 		return
 	}
@@ -68,20 +194,194 @@ func (m Manager) connectToMessageBus() {
 	_ = m.bus.Subscribe(app.TopicInterfaceCreated, m.handleInterfaceCreateEvent)
 	_ = m.bus.Subscribe(app.TopicInterfaceUpdated, m.handleInterfaceUpdateEvent)
 	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceDeleteEvent)
+
+	_ = m.bus.Subscribe(app.TopicSecurityEvent, m.handleSecurityEvent)
+}
+
+func (m Manager) runDelivery(ctx context.Context, endpoint string) {
+	for {
+		m.deliverPendingEvents(ctx, endpoint)
+
+		select {
+		case <-ctx.Done():
+			return // program stopped
+		case <-m.triggers[endpoint]:
+		case <-time.After(m.cfg.Webhook.RetryInterval):
+		}
+	}
+}
+
+// deliverPendingEvents delivers all events after the cursor of the given endpoint, in order. It stops at the first
+// event that could not be delivered yet, so that the order of the events is kept.
+func (m Manager) deliverPendingEvents(ctx context.Context, endpoint string) {
+	for ctx.Err() == nil {
+		cursor, err := m.getCursor(ctx, endpoint)
+		if err != nil {
+			slog.Error("[WEBHOOK] failed to load endpoint cursor", "endpoint", endpoint, "error", err)
+			return
+		}
+
+		events, err := m.db.GetWebhookEvents(ctx, cursor.EventId, deliveryBatchSize)
+		if err != nil {
+			slog.Error("[WEBHOOK] failed to load pending events", "endpoint", endpoint, "error", err)
+			return
+		}
+		if len(events) == 0 {
+			return // all events were processed
+		}
+
+		for _, event := range events {
+			finished, err := m.deliverEvent(ctx, endpoint, *cursor, event)
+			if err != nil {
+				slog.Error("[WEBHOOK] failed to update delivery record", "endpoint", endpoint, "event", event.Id,
+					"error", err)
+				return
+			}
+			if !finished {
+				return // retry later
+			}
+
+			advanced, err := m.advanceCursor(ctx, endpoint, cursor.EventId, event.Id)
+			if err != nil {
+				slog.Error("[WEBHOOK] failed to update endpoint cursor", "endpoint", endpoint, "error", err)
+				return
+			}
+			if !advanced {
+				break // the cursor was moved by an administrator, start again at the new position
+			}
+			cursor.EventId = event.Id
+		}
+	}
 }
 
-func (m Manager) sendWebhook(ctx context.Context, data io.Reader) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.Webhook.Url, data)
+// deliverEvent performs a delivery attempt of the given event and records it. It returns true if no further
+// attempts are made, either because the event was delivered or because all attempts failed.
+func (m Manager) deliverEvent(
+	ctx context.Context,
+	endpoint string,
+	cursor domain.WebhookCursor,
+	event domain.WebhookEvent,
+) (bool, error) {
+	delivery, err := m.getDelivery(ctx, endpoint, event.Id)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	switch {
+	case delivery.IsFinished() && !cursor.IsReplay(event.Id):
+		return true, nil // already processed, the cursor update was interrupted
+	case delivery.IsFinished():
+		delivery.Status = domain.WebhookDeliveryStatusPending
+		delivery.Attempts = 0
+		delivery.Replays++
+		delivery.LastAttemptAt = nil
+	case delivery.LastAttemptAt != nil && now.Before(delivery.LastAttemptAt.Add(m.cfg.Webhook.RetryInterval)):
+		return false, nil // wait for the retry interval
+	}
+
+	err = m.sendWebhook(ctx, m.endpoints[endpoint], event)
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	switch {
+	case err == nil:
+		delivery.Status = domain.WebhookDeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		slog.Info("[WEBHOOK] executed webhook", "endpoint", endpoint, "event", event.Id, "action", event.Event,
+			"entity", event.Entity, "identifier", event.Identifier)
+	case m.cfg.Webhook.MaxAttempts > 0 && delivery.Attempts >= m.cfg.Webhook.MaxAttempts:
+		delivery.Status = domain.WebhookDeliveryStatusFailed
+		delivery.LastError = err.Error()
+		slog.Error("[WEBHOOK] giving up webhook delivery", "endpoint", endpoint, "event", event.Id,
+			"attempts", delivery.Attempts, "error", err)
+	default:
+		delivery.LastError = err.Error()
+		slog.Warn("[WEBHOOK] failed to execute webhook", "endpoint", endpoint, "event", event.Id,
+			"attempts", delivery.Attempts, "error", err)
+	}
+
+	if err := m.db.SaveWebhookDelivery(ctx, delivery); err != nil {
+		return false, err
+	}
+
+	return delivery.IsFinished(), nil
+}
+
+func (m Manager) getDelivery(ctx context.Context, endpoint string, eventId uint64) (*domain.WebhookDelivery, error) {
+	deliveries, err := m.db.GetWebhookDeliveries(ctx, eventId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, delivery := range deliveries {
+		if delivery.Endpoint == endpoint {
+			return &delivery, nil
+		}
+	}
+
+	return &domain.WebhookDelivery{
+		EventId:  eventId,
+		Endpoint: endpoint,
+		Status:   domain.WebhookDeliveryStatusPending,
+	}, nil
+}
+
+func (m Manager) getCursor(ctx context.Context, endpoint string) (*domain.WebhookCursor, error) {
+	cursors, err := m.db.GetWebhookCursors(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cursor := range cursors {
+		if cursor.Endpoint == endpoint {
+			return &cursor, nil
+		}
+	}
+
+	return &domain.WebhookCursor{Endpoint: endpoint}, nil
+}
+
+// advanceCursor moves the cursor of the given endpoint to the given event. The cursor is only moved if it still
+// points to the expected event, so that a concurrent replay request is not overwritten.
+func (m Manager) advanceCursor(ctx context.Context, endpoint string, expected, eventId uint64) (bool, error) {
+	m.cursorMux.Lock()
+	defer m.cursorMux.Unlock()
+
+	cursor, err := m.getCursor(ctx, endpoint)
+	if err != nil {
+		return false, err
+	}
+	if cursor.EventId != expected {
+		return false, nil
+	}
+
+	cursor.EventId = eventId
+	cursor.UpdatedAt = time.Now()
+	if err := m.db.SaveWebhookCursor(ctx, cursor); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (m Manager) sendWebhook(
+	ctx context.Context,
+	endpoint config.WebhookEndpointConfig,
+	event domain.WebhookEvent,
+) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader(event.Payload))
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	if m.cfg.Webhook.Authentication != "" {
-		req.Header.Set("Authorization", m.cfg.Webhook.Authentication)
+	req.Header.Set(EventIdHeader, strconv.FormatUint(event.Id, 10))
+	if endpoint.Authentication != "" {
+		req.Header.Set("Authorization", endpoint.Authentication)
 	}
 
-	resp, err := m.client.Do(req)
+	resp, err := m.clients[endpoint.Name].Do(req)
 	if err != nil {
 		return err
 	}
@@ -135,6 +435,11 @@ func (m Manager) handleInterfaceDeleteEvent(iface domain.Interface) {
 	m.handleGenericEvent(WebhookEventDelete, iface)
 }
 
+func (m Manager) handleSecurityEvent(event domain.SecurityEvent) {
+	m.handleGenericEvent(WebhookEventCreate, event)
+}
+
+// handleGenericEvent stores the event in the event log and wakes up the delivery workers.
 func (m Manager) handleGenericEvent(action WebhookEvent, payload any) {
 	eventData, err := m.createWebhookData(action, payload)
 	if err != nil {
@@ -143,22 +448,32 @@ func (m Manager) handleGenericEvent(action WebhookEvent, payload any) {
 		return
 	}
 
-	eventJson, err := eventData.Serialize()
+	eventJson, err := json.Marshal(eventData)
 	if err != nil {
 		slog.Error("[WEBHOOK] failed to serialize event data", "error", err, "action", action,
 			"payload", fmt.Sprintf("%T", payload), "identifier", eventData.Identifier)
 		return
 	}
 
-	err = m.sendWebhook(context.Background(), eventJson)
-	if err != nil {
-		slog.Error("[WEBHOOK] failed to execute webhook", "error", err, "action", action,
+	event := &domain.WebhookEvent{
+		CreatedAt:  time.Now(),
+		Event:      eventData.Event,
+		Entity:     eventData.Entity,
+		Identifier: eventData.Identifier,
+		Payload:    eventJson,
+	}
+	if err := m.db.SaveWebhookEvent(context.Background(), event); err != nil {
+		slog.Error("[WEBHOOK] failed to store event", "error", err, "action", action,
 			"payload", fmt.Sprintf("%T", payload), "identifier", eventData.Identifier)
 		return
 	}
 
-	slog.Info("[WEBHOOK] executed webhook", "action", action, "payload", fmt.Sprintf("%T", payload),
-		"identifier", eventData.Identifier)
+	for _, trigger := range m.triggers {
+		select {
+		case trigger <- struct{}{}:
+		default: // the worker is already triggered
+		}
+	}
 }
 
 func (m Manager) createWebhookData(action WebhookEvent, payload any) (*WebhookData, error) {
@@ -177,9 +492,124 @@ func (m Manager) createWebhookData(action WebhookEvent, payload any) (*WebhookDa
 	case domain.Interface:
 		d.Entity = WebhookEntityInterface
 		d.Identifier = string(v.Identifier)
+	case domain.SecurityEvent:
+		d.Entity = WebhookEntitySecurity
+		d.Identifier = strconv.FormatUint(v.Id, 10)
 	default:
 		return nil, fmt.Errorf("unsupported payload type: %T", v)
 	}
 
 	return d, nil
 }
+
+// GetEndpoints returns the delivery state of all configured webhook endpoints.
+func (m Manager) GetEndpoints(ctx context.Context) ([]domain.WebhookEndpointState, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	lastId, err := m.db.GetLastWebhookEventId(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load last event id: %w", err)
+	}
+
+	states := make([]domain.WebhookEndpointState, 0, len(m.endpoints))
+	for _, endpoint := range m.cfg.Webhook.AllEndpoints() {
+		cursor, err := m.getCursor(ctx, endpoint.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cursor of endpoint %s: %w", endpoint.Name, err)
+		}
+		states = append(states, domain.WebhookEndpointState{
+			Name:        endpoint.Name,
+			Url:         endpoint.Url,
+			Cursor:      *cursor,
+			LastEventId: lastId,
+		})
+	}
+
+	return states, nil
+}
+
+// GetEvents returns the events of the event log with an id greater than the given id, ordered by id, together
+// with their delivery records. At most limit events are returned.
+func (m Manager) GetEvents(ctx context.Context, afterId uint64, limit int) ([]domain.WebhookEventLogEntry, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	events, err := m.db.GetWebhookEvents(ctx, afterId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events: %w", err)
+	}
+
+	entries := make([]domain.WebhookEventLogEntry, len(events))
+	for i, event := range events {
+		deliveries, err := m.db.GetWebhookDeliveries(ctx, event.Id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load deliveries of event %d: %w", event.Id, err)
+		}
+		entries[i] = domain.WebhookEventLogEntry{Event: event, Deliveries: deliveries}
+	}
+
+	return entries, nil
+}
+
+// GetEvent returns the event with the given id, together with its delivery records.
+func (m Manager) GetEvent(ctx context.Context, id uint64) (*domain.WebhookEventLogEntry, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	event, err := m.db.GetWebhookEvent(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load event %d: %w", id, err)
+	}
+	deliveries, err := m.db.GetWebhookDeliveries(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deliveries of event %d: %w", id, err)
+	}
+
+	return &domain.WebhookEventLogEntry{Event: *event, Deliveries: deliveries}, nil
+}
+
+// SetCursor moves the cursor of the given endpoint. All events after the given event are delivered to the
+// endpoint, including events that were delivered before. Moving the cursor forward skips events.
+func (m Manager) SetCursor(ctx context.Context, endpoint string, eventId uint64) (*domain.WebhookCursor, error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if _, ok := m.endpoints[endpoint]; !ok {
+		return nil, fmt.Errorf("webhook endpoint %s: %w", endpoint, domain.ErrNotFound)
+	}
+	lastId, err := m.db.GetLastWebhookEventId(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load last event id: %w", err)
+	}
+	if eventId > lastId {
+		return nil, fmt.Errorf("event %d does not exist yet: %w", eventId, domain.ErrInvalidData)
+	}
+
+	m.cursorMux.Lock()
+	cursor, err := m.getCursor(ctx, endpoint)
+	if err == nil {
+		cursor.ReplayUntil = max(cursor.ReplayUntil, cursor.EventId)
+		cursor.EventId = eventId
+		cursor.UpdatedAt = time.Now()
+		err = m.db.SaveWebhookCursor(ctx, cursor)
+	}
+	m.cursorMux.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update cursor of endpoint %s: %w", endpoint, err)
+	}
+
+	slog.Info("[WEBHOOK] moved endpoint cursor", "endpoint", endpoint, "event", eventId,
+		"user", domain.GetUserInfo(ctx).Id)
+
+	select {
+	case m.triggers[endpoint] <- struct{}{}:
+	default: // the worker is already triggered
+	}
+
+	return cursor, nil
+}
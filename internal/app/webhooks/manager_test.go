@@ -0,0 +1,204 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeBus struct{}
+
+func (f fakeBus) Publish(_ string, _ ...any) {}
+
+func (f fakeBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+type fakeDatabase struct {
+	events     []domain.WebhookEvent
+	deliveries map[uint64][]domain.WebhookDelivery
+	cursors    map[string]domain.WebhookCursor
+}
+
+func newFakeDatabase() *fakeDatabase {
+	return &fakeDatabase{
+		deliveries: make(map[uint64][]domain.WebhookDelivery),
+		cursors:    make(map[string]domain.WebhookCursor),
+	}
+}
+
+func (f *fakeDatabase) SaveWebhookEvent(_ context.Context, event *domain.WebhookEvent) error {
+	event.Id = uint64(len(f.events) + 1)
+	f.events = append(f.events, *event)
+	return nil
+}
+
+func (f *fakeDatabase) GetWebhookEvent(_ context.Context, id uint64) (*domain.WebhookEvent, error) {
+	for _, event := range f.events {
+		if event.Id == id {
+			return &event, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f *fakeDatabase) GetWebhookEvents(_ context.Context, afterId uint64, limit int) ([]domain.WebhookEvent, error) {
+	var events []domain.WebhookEvent
+	for _, event := range f.events {
+		if event.Id > afterId && (limit <= 0 || len(events) < limit) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (f *fakeDatabase) GetLastWebhookEventId(_ context.Context) (uint64, error) {
+	return uint64(len(f.events)), nil
+}
+
+func (f *fakeDatabase) DeleteWebhookEvents(_ context.Context, _ time.Time) error {
+	return nil
+}
+
+func (f *fakeDatabase) GetWebhookDeliveries(_ context.Context, eventId uint64) ([]domain.WebhookDelivery, error) {
+	return slices.Clone(f.deliveries[eventId]), nil
+}
+
+func (f *fakeDatabase) SaveWebhookDelivery(_ context.Context, delivery *domain.WebhookDelivery) error {
+	deliveries := slices.DeleteFunc(f.deliveries[delivery.EventId], func(d domain.WebhookDelivery) bool {
+		return d.Endpoint == delivery.Endpoint
+	})
+	f.deliveries[delivery.EventId] = append(deliveries, *delivery)
+	return nil
+}
+
+func (f *fakeDatabase) GetWebhookCursors(_ context.Context) ([]domain.WebhookCursor, error) {
+	var cursors []domain.WebhookCursor
+	for _, cursor := range f.cursors {
+		cursors = append(cursors, cursor)
+	}
+	return cursors, nil
+}
+
+func (f *fakeDatabase) SaveWebhookCursor(_ context.Context, cursor *domain.WebhookCursor) error {
+	f.cursors[cursor.Endpoint] = *cursor
+	return nil
+}
+
+// newTestManager returns a manager with a single endpoint. The endpoint responds with the given status codes, one
+// per request, and returns the received event ids.
+func newTestManager(t *testing.T, maxAttempts int, statusCodes ...int) (*Manager, *fakeDatabase, *[]string) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusOK
+		if len(statusCodes) > 0 {
+			status, statusCodes = statusCodes[0], statusCodes[1:]
+		}
+		if status == http.StatusOK {
+			received = append(received, r.Header.Get(EventIdHeader))
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	cfg.Webhook.Url = server.URL
+	cfg.Webhook.Timeout = time.Second
+	cfg.Webhook.MaxAttempts = maxAttempts
+	cfg.Webhook.RetryInterval = 0
+
+	db := newFakeDatabase()
+	m, err := NewManager(cfg, fakeBus{}, db)
+	require.NoError(t, err)
+	require.NoError(t, m.initializeCursors(context.Background()))
+
+	return m, db, &received
+}
+
+func TestManager_deliverPendingEvents(t *testing.T) {
+	m, db, received := newTestManager(t, 3, http.StatusOK, http.StatusServiceUnavailable)
+	ctx := context.Background()
+
+	m.handlePeerCreateEvent(domain.Peer{Identifier: "peer-1"})
+	m.handlePeerDeleteEvent(domain.Peer{Identifier: "peer-1"})
+	require.Len(t, db.events, 2)
+	assert.Equal(t, "peer", db.events[0].Entity)
+	assert.Contains(t, string(db.events[1].Payload), `"event":"delete"`)
+
+	m.deliverPendingEvents(ctx, config.DefaultWebhookEndpoint)
+	assert.Equal(t, []string{"1"}, *received)
+	assert.Equal(t, uint64(1), db.cursors[config.DefaultWebhookEndpoint].EventId,
+		"the cursor stops at the first failed event")
+	assert.Equal(t, domain.WebhookDeliveryStatusPending, db.deliveries[2][0].Status)
+	assert.Equal(t, "webhook request failed with status: 503 Service Unavailable", db.deliveries[2][0].LastError)
+
+	m.deliverPendingEvents(ctx, config.DefaultWebhookEndpoint)
+	assert.Equal(t, []string{"1", "2"}, *received, "failed events are retried")
+	assert.Equal(t, uint64(2), db.cursors[config.DefaultWebhookEndpoint].EventId)
+	assert.Equal(t, domain.WebhookDeliveryStatusDelivered, db.deliveries[2][0].Status)
+	assert.Equal(t, 2, db.deliveries[2][0].Attempts)
+}
+
+func TestManager_deliverPendingEvents_maxAttempts(t *testing.T) {
+	m, db, received := newTestManager(t, 2, http.StatusInternalServerError, http.StatusInternalServerError)
+	ctx := context.Background()
+
+	m.handleUserCreateEvent(domain.User{Identifier: "alice"})
+	m.handleUserUpdateEvent(domain.User{Identifier: "alice"})
+
+	m.deliverPendingEvents(ctx, config.DefaultWebhookEndpoint)
+	m.deliverPendingEvents(ctx, config.DefaultWebhookEndpoint)
+	assert.Equal(t, domain.WebhookDeliveryStatusFailed, db.deliveries[1][0].Status)
+	assert.Equal(t, []string{"2"}, *received, "the failed event is skipped")
+	assert.Equal(t, uint64(2), db.cursors[config.DefaultWebhookEndpoint].EventId)
+}
+
+func TestManager_SetCursor(t *testing.T) {
+	m, db, received := newTestManager(t, 3)
+	ctx := context.Background()
+	adminCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	m.handleInterfaceCreateEvent(domain.Interface{Identifier: "wg0"})
+	m.handleInterfaceUpdateEvent(domain.Interface{Identifier: "wg0"})
+	m.deliverPendingEvents(ctx, config.DefaultWebhookEndpoint)
+	require.Equal(t, []string{"1", "2"}, *received)
+
+	_, err := m.SetCursor(ctx, config.DefaultWebhookEndpoint, 0)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	_, err = m.SetCursor(adminCtx, "unknown", 0)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = m.SetCursor(adminCtx, config.DefaultWebhookEndpoint, 3)
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	cursor, err := m.SetCursor(adminCtx, config.DefaultWebhookEndpoint, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), cursor.ReplayUntil)
+
+	m.deliverPendingEvents(ctx, config.DefaultWebhookEndpoint)
+	assert.Equal(t, []string{"1", "2", "2"}, *received, "events after the cursor are replayed")
+	assert.Equal(t, 1, db.deliveries[2][0].Replays)
+	assert.Equal(t, 0, db.deliveries[1][0].Replays)
+
+	states, err := m.GetEndpoints(adminCtx)
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	assert.Equal(t, uint64(0), states[0].PendingEvents())
+}
+
+func TestNewManager_duplicateEndpoints(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Webhook.Url = "http://localhost/default"
+	cfg.Webhook.Endpoints = []config.WebhookEndpointConfig{
+		{Name: config.DefaultWebhookEndpoint, WebhookConfig: config.WebhookConfig{Url: "http://localhost/other"}},
+	}
+
+	_, err := NewManager(cfg, fakeBus{}, newFakeDatabase())
+	assert.Error(t, err)
+}
@@ -28,19 +28,46 @@ type StatisticsDatabaseRepo interface {
 		updateFunc func(in *domain.InterfaceStatus) (*domain.InterfaceStatus, error),
 	) error
 	DeletePeerStatus(ctx context.Context, id domain.PeerIdentifier) error
+	SavePeerStatsSamples(ctx context.Context, samples []domain.PeerStatsSample) error
+	GetPeerStatsSamples(
+		ctx context.Context,
+		resolution domain.PeerStatsResolution,
+		from, to time.Time,
+		ids ...domain.PeerIdentifier,
+	) ([]domain.PeerStatsSample, error)
+	DeletePeerStatsSamples(ctx context.Context, resolution domain.PeerStatsResolution, before time.Time) error
+	SavePeerConnectionEvents(ctx context.Context, events []domain.PeerConnectionEvent) error
+	DeletePeerConnectionEvents(ctx context.Context, before time.Time) error
 }
 
 type StatisticsInterfaceController interface {
 	GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.PhysicalInterface, error)
 	GetPeers(_ context.Context, deviceId domain.InterfaceIdentifier) ([]domain.PhysicalPeer, error)
+	SubscribeLinkUpdates(ctx context.Context) (<-chan domain.InterfaceIdentifier, error)
 }
 
 type StatisticsMetricsServer interface {
 	UpdateInterfaceMetrics(status domain.InterfaceStatus)
 	UpdatePeerMetrics(peer *domain.Peer, status domain.PeerStatus)
+	UpdatePeerTrafficMetrics(peer *domain.Peer, traffic domain.PeerTraffic)
+}
+
+type StatisticsExporter interface {
+	ExportPeerStats(ctx context.Context, points []domain.PeerStatsPoint) error
+}
+
+type StatisticsAccounting interface {
+	// GetPeerTraffic returns the traffic of the given peers of the interface, broken down by destination subnet.
+	GetPeerTraffic(
+		ctx context.Context,
+		id domain.InterfaceIdentifier,
+		peers []domain.PhysicalPeer,
+	) (map[domain.PeerIdentifier]domain.PeerTraffic, error)
 }
 
 type StatisticsEventBus interface {
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
 	// Subscribe subscribes to a topic
 	Subscribe(topic string, fn interface{}) error
 }
@@ -55,6 +82,8 @@ type StatisticsCollector struct {
 	db StatisticsDatabaseRepo
 	wg StatisticsInterfaceController
 	ms StatisticsMetricsServer
+	ex StatisticsExporter
+	ac StatisticsAccounting
 }
 
 // NewStatisticsCollector creates a new statistics collector.
@@ -64,6 +93,8 @@ func NewStatisticsCollector(
 	db StatisticsDatabaseRepo,
 	wg StatisticsInterfaceController,
 	ms StatisticsMetricsServer,
+	ex StatisticsExporter,
+	ac StatisticsAccounting,
 ) (*StatisticsCollector, error) {
 	c := &StatisticsCollector{
 		cfg: cfg,
@@ -72,6 +103,8 @@ func NewStatisticsCollector(
 		db: db,
 		wg: wg,
 		ms: ms,
+		ex: ex,
+		ac: ac,
 	}
 
 	c.connectToMessageBus()
@@ -85,6 +118,9 @@ func (c *StatisticsCollector) StartBackgroundJobs(ctx context.Context) {
 	c.startPingWorkers(ctx)
 	c.startInterfaceDataFetcher(ctx)
 	c.startPeerDataFetcher(ctx)
+	c.startStateWatcher(ctx)
+	c.startSampleCompaction(ctx)
+	c.startConnectionHistoryCleanup(ctx)
 }
 
 func (c *StatisticsCollector) startInterfaceDataFetcher(ctx context.Context) {
@@ -106,6 +142,8 @@ func (c *StatisticsCollector) collectInterfaceData(ctx context.Context) {
 		case <-ctx.Done():
 			return // program stopped
 		case <-ticker.C:
+			c.bus.Publish(app.TopicHealthHeartbeat, "interface_data_collection")
+
 			interfaces, err := c.db.GetAllInterfaces(ctx)
 			if err != nil {
 				slog.Warn("failed to fetch all interfaces for data collection", "error", err)
@@ -117,11 +155,15 @@ func (c *StatisticsCollector) collectInterfaceData(ctx context.Context) {
 				if err != nil {
 					slog.Warn("failed to load physical interface for data collection", "interface", in.Identifier,
 						"error", err)
+					c.markInterfaceDown(ctx, in.Identifier)
 					continue
 				}
 				err = c.db.UpdateInterfaceStatus(ctx, in.Identifier,
 					func(i *domain.InterfaceStatus) (*domain.InterfaceStatus, error) {
 						i.UpdatedAt = time.Now()
+						if i.UpSince == nil {
+							i.UpSince = &i.UpdatedAt
+						}
 						i.BytesReceived = physicalInterface.BytesDownload
 						i.BytesTransmitted = physicalInterface.BytesUpload
 
@@ -139,6 +181,18 @@ func (c *StatisticsCollector) collectInterfaceData(ctx context.Context) {
 	}
 }
 
+// markInterfaceDown resets the uptime of an interface that is no longer available.
+func (c *StatisticsCollector) markInterfaceDown(ctx context.Context, id domain.InterfaceIdentifier) {
+	err := c.db.UpdateInterfaceStatus(ctx, id, func(i *domain.InterfaceStatus) (*domain.InterfaceStatus, error) {
+		i.UpdatedAt = time.Now()
+		i.UpSince = nil
+		return i, nil
+	})
+	if err != nil {
+		slog.Warn("failed to update interface status", "interface", id, "error", err)
+	}
+}
+
 func (c *StatisticsCollector) startPeerDataFetcher(ctx context.Context) {
 	if !c.cfg.Statistics.CollectPeerData {
 		return
@@ -158,6 +212,8 @@ func (c *StatisticsCollector) collectPeerData(ctx context.Context) {
 		case <-ctx.Done():
 			return // program stopped
 		case <-ticker.C:
+			c.bus.Publish(app.TopicHealthHeartbeat, "peer_data_collection")
+
 			interfaces, err := c.db.GetAllInterfaces(ctx)
 			if err != nil {
 				slog.Warn("failed to fetch all interfaces for peer data collection", "error", err)
@@ -170,7 +226,19 @@ func (c *StatisticsCollector) collectPeerData(ctx context.Context) {
 					slog.Warn("failed to fetch peers for data collection", "interface", in.Identifier, "error", err)
 					continue
 				}
+				if c.cfg.Statistics.AccountingBackend == config.AccountingBackendEbpf {
+					if err := c.applyAccountedTraffic(ctx, in.Identifier, peers); err != nil {
+						slog.Warn("failed to fetch accounted traffic for data collection", "interface", in.Identifier,
+							"error", err)
+						continue
+					}
+				}
+				samples := make([]domain.PeerStatsSample, 0, len(peers))
+				statuses := make([]domain.PeerStatus, 0, len(peers))
 				for _, peer := range peers {
+					var sample domain.PeerStatsSample
+					var status domain.PeerStatus
+					var changes []domain.PeerStateChange
 					err = c.db.UpdatePeerStatus(ctx, peer.Identifier,
 						func(p *domain.PeerStatus) (*domain.PeerStatus, error) {
 							var lastHandshake *time.Time
@@ -178,31 +246,86 @@ func (c *StatisticsCollector) collectPeerData(ctx context.Context) {
 								lastHandshake = &peer.LastHandshake
 							}
 
+							sample = newPeerStatsSample(*p, peer, lastHandshake)
+
 							// calculate if session was restarted
-							p.UpdatedAt = time.Now()
 							p.LastSessionStart = getSessionStartTime(*p, peer.BytesUpload, peer.BytesDownload,
 								lastHandshake)
 							p.BytesReceived = peer.BytesUpload      // store bytes that where uploaded from the peer and received by the server
 							p.BytesTransmitted = peer.BytesDownload // store bytes that where received from the peer and sent by the server
-							p.Endpoint = peer.Endpoint
-							p.LastHandshake = lastHandshake
+							changes = c.applyPeerDeviceState(p, in.Identifier, peer, time.Now())
 
 							// Update prometheus metrics
 							go c.updatePeerMetrics(ctx, *p)
 
+							status = *p
 							return p, nil
 						})
 					if err != nil {
 						slog.Warn("failed to update peer status", "peer", peer.Identifier, "error", err)
-					} else {
-						slog.Debug("updated peer status", "peer", peer.Identifier)
+						continue
+					}
+					slog.Debug("updated peer status", "peer", peer.Identifier)
+					c.recordPeerStateChanges(ctx, changes)
+					statuses = append(statuses, status)
+
+					if sample.BytesReceived > 0 || sample.BytesTransmitted > 0 {
+						samples = append(samples, sample) // only store samples of active peers
 					}
 				}
+
+				if c.cfg.Statistics.SampleRetention > 0 {
+					if err := c.db.SavePeerStatsSamples(ctx, samples); err != nil {
+						slog.Warn("failed to store peer stats samples", "interface", in.Identifier, "error", err)
+					}
+				}
+
+				if c.cfg.StatisticsExport.Enabled {
+					c.exportPeerStats(ctx, in.Identifier, statuses)
+				}
 			}
 		}
 	}
 }
 
+// exportPeerStats writes the given peer statuses of an interface to the configured time-series database.
+func (c *StatisticsCollector) exportPeerStats(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	statuses []domain.PeerStatus,
+) {
+	peers, err := c.db.GetInterfacePeers(ctx, id)
+	if err != nil {
+		slog.Warn("failed to fetch peers for statistics export", "interface", id, "error", err)
+		return
+	}
+	peerMap := make(map[domain.PeerIdentifier]domain.Peer, len(peers))
+	for _, peer := range peers {
+		peerMap[peer.Identifier] = peer
+	}
+
+	points := make([]domain.PeerStatsPoint, 0, len(statuses))
+	for _, status := range statuses {
+		peer := peerMap[status.PeerId] // unknown peers are exported without name and user
+		points = append(points, domain.PeerStatsPoint{
+			Time:             status.UpdatedAt,
+			PeerId:           status.PeerId,
+			PeerName:         peer.DisplayName,
+			InterfaceId:      id,
+			UserId:           peer.UserIdentifier,
+			BytesReceived:    status.BytesReceived,
+			BytesTransmitted: status.BytesTransmitted,
+			LastHandshake:    status.LastHandshake,
+			Endpoint:         status.Endpoint,
+			IsConnected:      status.IsConnected(),
+		})
+	}
+
+	if err := c.ex.ExportPeerStats(ctx, points); err != nil {
+		slog.Warn("failed to export peer statistics", "interface", id, "error", err)
+	}
+}
+
 func getSessionStartTime(
 	oldStats domain.PeerStatus,
 	newReceived, newTransmitted uint64,
@@ -298,8 +421,8 @@ func (c *StatisticsCollector) enqueuePingChecks(ctx context.Context) {
 func (c *StatisticsCollector) pingWorker(ctx context.Context) {
 	defer c.pingWaitGroup.Done()
 	for peer := range c.pingJobs {
-		peerPingable := c.isPeerPingable(ctx, peer)
-		slog.Debug("peer ping check completed", "peer", peer.Identifier, "pingable", peerPingable)
+		peerPingable, rtt := c.isPeerPingable(ctx, peer)
+		slog.Debug("peer ping check completed", "peer", peer.Identifier, "pingable", peerPingable, "rtt", rtt)
 
 		now := time.Now()
 		err := c.db.UpdatePeerStatus(ctx, peer.Identifier,
@@ -307,9 +430,11 @@ func (c *StatisticsCollector) pingWorker(ctx context.Context) {
 				if peerPingable {
 					p.IsPingable = true
 					p.LastPing = &now
+					p.LastRtt = rtt
 				} else {
 					p.IsPingable = false
 					p.LastPing = nil
+					p.LastRtt = 0
 				}
 
 				// Update prometheus metrics
@@ -325,20 +450,21 @@ func (c *StatisticsCollector) pingWorker(ctx context.Context) {
 	}
 }
 
-func (c *StatisticsCollector) isPeerPingable(ctx context.Context, peer domain.Peer) bool {
+// isPeerPingable returns true and the measured round-trip time if the peer responded to the ping check.
+func (c *StatisticsCollector) isPeerPingable(ctx context.Context, peer domain.Peer) (bool, time.Duration) {
 	if !c.cfg.Statistics.UsePingChecks {
-		return false
+		return false, 0
 	}
 
 	checkAddr := peer.CheckAliveAddress()
 	if checkAddr == "" {
-		return false
+		return false, 0
 	}
 
 	pinger, err := probing.NewPinger(checkAddr)
 	if err != nil {
 		slog.Debug("failed to instantiate pinger", "peer", peer.Identifier, "address", checkAddr, "error", err)
-		return false
+		return false, 0
 	}
 
 	checkCount := 1
@@ -348,10 +474,13 @@ func (c *StatisticsCollector) isPeerPingable(ctx context.Context, peer domain.Pe
 	err = pinger.RunWithContext(ctx) // Blocks until finished.
 	if err != nil {
 		slog.Debug("pinger for peer exited unexpectedly", "peer", peer.Identifier, "address", checkAddr, "error", err)
-		return false
+		return false, 0
 	}
 	stats := pinger.Statistics()
-	return stats.PacketsRecv == checkCount
+	if stats.PacketsRecv != checkCount {
+		return false, 0
+	}
+	return true, stats.AvgRtt
 }
 
 func (c *StatisticsCollector) updateInterfaceMetrics(status domain.InterfaceStatus) {
@@ -368,6 +497,42 @@ func (c *StatisticsCollector) updatePeerMetrics(ctx context.Context, status doma
 	c.ms.UpdatePeerMetrics(peer, status)
 }
 
+// applyAccountedTraffic replaces the traffic counters of the WireGuard peers with the counters of the accounting
+// backend and updates the per-subnet metrics of the peers.
+func (c *StatisticsCollector) applyAccountedTraffic(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	peers []domain.PhysicalPeer,
+) error {
+	traffic, err := c.ac.GetPeerTraffic(ctx, id, peers)
+	if err != nil {
+		return err
+	}
+
+	for i := range peers {
+		peerTraffic := traffic[peers[i].Identifier]
+		peers[i].BytesUpload = peerTraffic.BytesReceived() // bytes that where uploaded from the peer
+		peers[i].BytesDownload = peerTraffic.BytesTransmitted()
+
+		go c.updatePeerTrafficMetrics(ctx, peers[i].Identifier, peerTraffic)
+	}
+
+	return nil
+}
+
+func (c *StatisticsCollector) updatePeerTrafficMetrics(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	traffic domain.PeerTraffic,
+) {
+	peer, err := c.db.GetPeer(ctx, id)
+	if err != nil {
+		slog.Warn("failed to fetch peer data for traffic metrics", "peer", id, "error", err)
+		return
+	}
+	c.ms.UpdatePeerTrafficMetrics(peer, traffic)
+}
+
 func (c *StatisticsCollector) connectToMessageBus() {
 	_ = c.bus.Subscribe(app.TopicPeerIdentifierUpdated, c.handlePeerIdentifierChangeEvent)
 }
@@ -0,0 +1,150 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// sampleCompactionInterval is the interval in which samples are rolled up and expired samples are removed.
+const sampleCompactionInterval = 15 * time.Minute
+
+// newPeerStatsSample returns a raw sample with the traffic since the last data collection.
+func newPeerStatsSample(
+	oldStats domain.PeerStatus,
+	peer domain.PhysicalPeer,
+	lastHandshake *time.Time,
+) domain.PeerStatsSample {
+	return domain.PeerStatsSample{
+		PeerId:           peer.Identifier,
+		Resolution:       domain.PeerStatsResolutionRaw,
+		Timestamp:        time.Now().UTC(),
+		BytesReceived:    counterDelta(oldStats.BytesReceived, peer.BytesUpload),
+		BytesTransmitted: counterDelta(oldStats.BytesTransmitted, peer.BytesDownload),
+		LastHandshake:    lastHandshake,
+	}
+}
+
+// counterDelta returns the difference between two values of a traffic counter.
+// If the counter was reset, for example, by an interface restart, the new value is returned.
+func counterDelta(oldValue, newValue uint64) uint64 {
+	if newValue < oldValue {
+		return newValue
+	}
+	return newValue - oldValue
+}
+
+func (c *StatisticsCollector) startSampleCompaction(ctx context.Context) {
+	if !c.cfg.Statistics.CollectPeerData || c.cfg.Statistics.SampleRetention == 0 {
+		return
+	}
+
+	go c.runSampleCompaction(ctx)
+
+	slog.Debug("started peer stats sample compaction")
+}
+
+func (c *StatisticsCollector) runSampleCompaction(ctx context.Context) {
+	ticker := time.NewTicker(sampleCompactionInterval)
+	defer ticker.Stop()
+	for {
+		if err := c.compactSamples(ctx, time.Now()); err != nil {
+			slog.Warn("failed to compact peer stats samples", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return // program stopped
+		case <-ticker.C:
+		}
+	}
+}
+
+// compactSamples rolls up raw samples into hourly samples and hourly samples into daily samples.
+// Afterward, all samples that exceed their retention time are removed.
+func (c *StatisticsCollector) compactSamples(ctx context.Context, now time.Time) error {
+	stats := c.cfg.Statistics
+
+	rawCutoff := now.Add(-stats.SampleRetention)
+	if stats.HourlySampleRetention > 0 {
+		if err := c.rollupSamples(ctx, now, domain.PeerStatsResolutionRaw, domain.PeerStatsResolutionHourly,
+			stats.SampleRetention); err != nil {
+			return err
+		}
+		rawCutoff = minTime(rawCutoff, domain.PeerStatsResolutionHourly.PeriodStart(now)) // keep pending samples
+
+		hourlyCutoff := now.Add(-stats.HourlySampleRetention)
+		if stats.DailySampleRetention > 0 {
+			if err := c.rollupSamples(ctx, now, domain.PeerStatsResolutionHourly, domain.PeerStatsResolutionDaily,
+				stats.HourlySampleRetention); err != nil {
+				return err
+			}
+			hourlyCutoff = minTime(hourlyCutoff, domain.PeerStatsResolutionDaily.PeriodStart(now))
+
+			err := c.db.DeletePeerStatsSamples(ctx, domain.PeerStatsResolutionDaily,
+				now.Add(-stats.DailySampleRetention))
+			if err != nil {
+				return fmt.Errorf("failed to remove expired daily samples: %w", err)
+			}
+		}
+
+		if err := c.db.DeletePeerStatsSamples(ctx, domain.PeerStatsResolutionHourly, hourlyCutoff); err != nil {
+			return fmt.Errorf("failed to remove expired hourly samples: %w", err)
+		}
+	}
+
+	if err := c.db.DeletePeerStatsSamples(ctx, domain.PeerStatsResolutionRaw, rawCutoff); err != nil {
+		return fmt.Errorf("failed to remove expired raw samples: %w", err)
+	}
+
+	return nil
+}
+
+// rollupSamples aggregates the samples of all completed periods that were not rolled up yet.
+// Only periods within the retention time of the source samples are considered.
+func (c *StatisticsCollector) rollupSamples(
+	ctx context.Context,
+	now time.Time,
+	source, target domain.PeerStatsResolution,
+	sourceRetention time.Duration,
+) error {
+	end := target.PeriodStart(now) // the current period is not complete yet
+	start := target.PeriodStart(minTime(now.Add(-sourceRetention), end.Add(-target.Period())))
+
+	existing, err := c.db.GetPeerStatsSamples(ctx, target, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to load %s samples: %w", target, err)
+	}
+	for _, sample := range existing {
+		if next := sample.Timestamp.Add(target.Period()); next.After(start) {
+			start = next // already rolled up
+		}
+	}
+	if !start.Before(end) {
+		return nil
+	}
+
+	samples, err := c.db.GetPeerStatsSamples(ctx, source, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to load %s samples: %w", source, err)
+	}
+
+	rollups := domain.RollupPeerStatsSamples(samples, target)
+	if err := c.db.SavePeerStatsSamples(ctx, rollups); err != nil {
+		return fmt.Errorf("failed to store %s samples: %w", target, err)
+	}
+	slog.Debug("rolled up peer stats samples", "resolution", target, "from", start, "to", end,
+		"samples", len(samples), "rollups", len(rollups))
+
+	return nil
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,135 @@
+package wireguard
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type fakeSampleDatabase struct {
+	StatisticsDatabaseRepo
+
+	samples []domain.PeerStatsSample
+}
+
+func (f *fakeSampleDatabase) SavePeerStatsSamples(_ context.Context, samples []domain.PeerStatsSample) error {
+	for _, sample := range samples {
+		f.samples = slices.DeleteFunc(f.samples, func(s domain.PeerStatsSample) bool {
+			return s.PeerId == sample.PeerId && s.Resolution == sample.Resolution && s.Timestamp.Equal(sample.Timestamp)
+		})
+		f.samples = append(f.samples, sample)
+	}
+	return nil
+}
+
+func (f *fakeSampleDatabase) GetPeerStatsSamples(
+	_ context.Context,
+	resolution domain.PeerStatsResolution,
+	from, to time.Time,
+	_ ...domain.PeerIdentifier,
+) ([]domain.PeerStatsSample, error) {
+	var result []domain.PeerStatsSample
+	for _, s := range f.samples {
+		if s.Resolution == resolution && !s.Timestamp.Before(from) && s.Timestamp.Before(to) {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeSampleDatabase) DeletePeerStatsSamples(
+	_ context.Context,
+	resolution domain.PeerStatsResolution,
+	before time.Time,
+) error {
+	f.samples = slices.DeleteFunc(f.samples, func(s domain.PeerStatsSample) bool {
+		return s.Resolution == resolution && s.Timestamp.Before(before)
+	})
+	return nil
+}
+
+func (f *fakeSampleDatabase) count(resolution domain.PeerStatsResolution) (count int, bytes uint64) {
+	for _, s := range f.samples {
+		if s.Resolution == resolution {
+			count++
+			bytes += s.BytesReceived
+		}
+	}
+	return count, bytes
+}
+
+func TestStatisticsCollector_compactSamples(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Statistics.SampleRetention = 2 * time.Hour
+	cfg.Statistics.HourlySampleRetention = 48 * time.Hour
+	cfg.Statistics.DailySampleRetention = 10 * 24 * time.Hour
+
+	db := &fakeSampleDatabase{}
+	c := &StatisticsCollector{cfg: cfg, db: db}
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	// one raw sample every 10 minutes for three days, compacted every 15 minutes
+	for i := 0; i < 3*24*6; i++ {
+		now = start.Add(time.Duration(i) * 10 * time.Minute)
+		db.samples = append(db.samples, domain.PeerStatsSample{
+			PeerId:        "peer-a",
+			Resolution:    domain.PeerStatsResolutionRaw,
+			Timestamp:     now,
+			BytesReceived: 1,
+		})
+		if i%3 == 0 {
+			if err := c.compactSamples(context.Background(), now.Add(time.Minute)); err != nil {
+				t.Fatalf("compaction failed: %v", err)
+			}
+		}
+	}
+
+	rawCount, _ := db.count(domain.PeerStatsResolutionRaw)
+	if rawCount > 3*6 {
+		t.Errorf("expected expired raw samples to be removed, got %d samples", rawCount)
+	}
+	hourlyCount, _ := db.count(domain.PeerStatsResolutionHourly)
+	if hourlyCount > 48 {
+		t.Errorf("expected expired hourly samples to be removed, got %d samples", hourlyCount)
+	}
+	dailyCount, dailyBytes := db.count(domain.PeerStatsResolutionDaily)
+	if dailyCount != 2 {
+		t.Errorf("expected two completed days, got %d daily samples", dailyCount)
+	}
+	if dailyBytes != 2*24*6 {
+		t.Errorf("expected all traffic of the completed days in the daily samples, got %d", dailyBytes)
+	}
+
+	// the current day is covered by the hourly and the pending raw samples
+	lastHour := domain.PeerStatsResolutionHourly.PeriodStart(now)
+	var pendingRaw uint64
+	for _, s := range db.samples {
+		if s.Resolution == domain.PeerStatsResolutionRaw && !s.Timestamp.Before(lastHour) {
+			pendingRaw += s.BytesReceived
+		}
+	}
+	var currentDayHourly uint64
+	for _, s := range db.samples {
+		if s.Resolution == domain.PeerStatsResolutionHourly && !s.Timestamp.Before(start.Add(48*time.Hour)) {
+			currentDayHourly += s.BytesReceived
+		}
+	}
+	if currentDayHourly+pendingRaw != 24*6 {
+		t.Errorf("expected the traffic of the current day to be preserved, got %d hourly and %d raw bytes",
+			currentDayHourly, pendingRaw)
+	}
+}
+
+func Test_counterDelta(t *testing.T) {
+	if got := counterDelta(100, 150); got != 50 {
+		t.Errorf("expected 50, got %d", got)
+	}
+	if got := counterDelta(100, 30); got != 30 {
+		t.Errorf("expected the new value after a counter reset, got %d", got)
+	}
+}
@@ -0,0 +1,279 @@
+package wireguard
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// handshakeTimeout is the age after which a handshake no longer counts as an active connection,
+// see domain.PeerStatus.IsConnected.
+const handshakeTimeout = 2 * time.Minute
+
+// watchedPeerState is the last known device state of a peer.
+type watchedPeerState struct {
+	lastHandshake time.Time
+	endpoint      string
+	connected     bool // whether the handshake was recent at the time the state was read
+}
+
+func newWatchedPeerState(peer domain.PhysicalPeer, now time.Time) watchedPeerState {
+	return watchedPeerState{
+		lastHandshake: peer.LastHandshake,
+		endpoint:      peer.Endpoint,
+		connected:     !peer.LastHandshake.IsZero() && now.Sub(peer.LastHandshake) <= handshakeTimeout,
+	}
+}
+
+func (s watchedPeerState) equal(other watchedPeerState) bool {
+	return s.lastHandshake.Equal(other.lastHandshake) && s.endpoint == other.endpoint && s.connected == other.connected
+}
+
+// startStateWatcher starts the peer state watcher. Link changes are received from netlink where available.
+// WireGuard itself does not publish events for handshakes or endpoint changes, so the watcher also reads the device
+// state in short intervals. Only changed peers are written to the database and published on the event bus.
+func (c *StatisticsCollector) startStateWatcher(ctx context.Context) {
+	if !c.cfg.Statistics.CollectPeerData {
+		return
+	}
+
+	var linkUpdates <-chan domain.InterfaceIdentifier
+	if c.cfg.Statistics.UseNetlinkEvents {
+		updates, err := c.wg.SubscribeLinkUpdates(ctx)
+		if err != nil {
+			slog.Warn("netlink events are not available, interface changes are detected by polling", "error", err)
+		} else {
+			linkUpdates = updates
+		}
+	}
+
+	if linkUpdates == nil && c.cfg.Statistics.StateWatchInterval <= 0 {
+		return
+	}
+
+	go c.watchPeerStates(ctx, linkUpdates)
+
+	slog.Debug("started peer state watcher", "netlink", linkUpdates != nil,
+		"interval", c.cfg.Statistics.StateWatchInterval)
+}
+
+func (c *StatisticsCollector) watchPeerStates(ctx context.Context, linkUpdates <-chan domain.InterfaceIdentifier) {
+	var tick <-chan time.Time
+	if c.cfg.Statistics.StateWatchInterval > 0 {
+		ticker := time.NewTicker(c.cfg.Statistics.StateWatchInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	states := make(map[domain.InterfaceIdentifier]map[domain.PeerIdentifier]watchedPeerState)
+	for {
+		select {
+		case <-ctx.Done():
+			return // program stopped
+		case id, ok := <-linkUpdates:
+			if !ok {
+				slog.Warn("netlink subscription closed, interface changes are detected by polling")
+				linkUpdates = nil
+				continue
+			}
+			c.handleLinkUpdate(ctx, id, states)
+		case <-tick:
+			interfaces, err := c.db.GetAllInterfaces(ctx)
+			if err != nil {
+				slog.Warn("failed to fetch all interfaces for peer state watch", "error", err)
+				continue
+			}
+			for _, in := range interfaces {
+				states[in.Identifier] = c.checkPeerStates(ctx, in.Identifier, states[in.Identifier], time.Now())
+			}
+		}
+	}
+}
+
+func (c *StatisticsCollector) handleLinkUpdate(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	states map[domain.InterfaceIdentifier]map[domain.PeerIdentifier]watchedPeerState,
+) {
+	interfaces, err := c.db.GetAllInterfaces(ctx)
+	if err != nil {
+		slog.Warn("failed to fetch all interfaces for link update", "interface", id, "error", err)
+		return
+	}
+	if !slices.ContainsFunc(interfaces, func(in domain.Interface) bool { return in.Identifier == id }) {
+		return // the interface is not managed by the portal
+	}
+
+	slog.Debug("handling link update", "interface", id)
+
+	if _, err := c.wg.GetInterface(ctx, id); err != nil {
+		if c.cfg.Statistics.CollectInterfaceData {
+			c.markInterfaceDown(ctx, id)
+		}
+		delete(states, id)
+		return
+	}
+
+	states[id] = c.checkPeerStates(ctx, id, states[id], time.Now())
+}
+
+// checkPeerStates compares the device state of all peers of the interface with the given previous state.
+// Changed peers are updated in the database, state transitions are published on the event bus.
+// The returned map contains the new state of all peers.
+func (c *StatisticsCollector) checkPeerStates(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	previous map[domain.PeerIdentifier]watchedPeerState,
+	now time.Time,
+) map[domain.PeerIdentifier]watchedPeerState {
+	peers, err := c.wg.GetPeers(ctx, id)
+	if err != nil {
+		slog.Debug("failed to fetch peers for state watch", "interface", id, "error", err)
+		return previous
+	}
+
+	current := make(map[domain.PeerIdentifier]watchedPeerState, len(peers))
+	for _, peer := range peers {
+		state := newWatchedPeerState(peer, now)
+		current[peer.Identifier] = state
+
+		if old, known := previous[peer.Identifier]; known && old.equal(state) {
+			continue // unchanged
+		}
+
+		c.updatePeerState(ctx, id, peer, now)
+	}
+
+	return current
+}
+
+// updatePeerState stores the handshake and endpoint of the peer and publishes the resulting state transitions.
+func (c *StatisticsCollector) updatePeerState(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	peer domain.PhysicalPeer,
+	now time.Time,
+) {
+	var changes []domain.PeerStateChange
+	err := c.db.UpdatePeerStatus(ctx, peer.Identifier, func(p *domain.PeerStatus) (*domain.PeerStatus, error) {
+		changes = c.applyPeerDeviceState(p, id, peer, now)
+		return p, nil
+	})
+	if err != nil {
+		slog.Warn("failed to update peer state", "peer", peer.Identifier, "error", err)
+		return
+	}
+
+	c.recordPeerStateChanges(ctx, changes)
+}
+
+// applyPeerDeviceState updates the handshake and endpoint of the status with the device state of the peer.
+// It returns the state transitions since the previous update of the status.
+func (c *StatisticsCollector) applyPeerDeviceState(
+	p *domain.PeerStatus,
+	id domain.InterfaceIdentifier,
+	peer domain.PhysicalPeer,
+	now time.Time,
+) []domain.PeerStateChange {
+	var lastHandshake *time.Time
+	if !peer.LastHandshake.IsZero() {
+		lastHandshake = &peer.LastHandshake
+	}
+
+	wasConnected := p.IsConnectedAt(p.UpdatedAt)
+	previousEndpoint := p.Endpoint
+
+	p.UpdatedAt = now
+	p.TrackEndpoint(peer.Endpoint, now, c.cfg.Statistics.KeepaliveChurnWindow)
+	p.Endpoint = peer.Endpoint
+	p.LastHandshake = lastHandshake
+
+	return peerStateChanges(id, *p, wasConnected, previousEndpoint, now)
+}
+
+// recordPeerStateChanges stores the given state changes in the connection history and publishes them on the event bus.
+func (c *StatisticsCollector) recordPeerStateChanges(ctx context.Context, changes []domain.PeerStateChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	if c.cfg.Statistics.ConnectionHistoryRetention > 0 {
+		events := make([]domain.PeerConnectionEvent, len(changes))
+		for i, change := range changes {
+			events[i] = domain.NewPeerConnectionEvent(change)
+		}
+		if err := c.db.SavePeerConnectionEvents(ctx, events); err != nil {
+			slog.Warn("failed to store peer connection events", "peer", changes[0].PeerId, "error", err)
+		}
+	}
+
+	for _, change := range changes {
+		slog.Debug("peer state changed", "peer", change.PeerId, "kind", change.Kind, "endpoint", change.Endpoint)
+		c.bus.Publish(app.TopicPeerStateChanged, change)
+	}
+}
+
+// peerStateChanges returns the state transitions between the previous values and the updated status.
+func peerStateChanges(
+	id domain.InterfaceIdentifier,
+	status domain.PeerStatus,
+	wasConnected bool,
+	previousEndpoint string,
+	now time.Time,
+) []domain.PeerStateChange {
+	newChange := func(kind domain.PeerStateChangeKind) domain.PeerStateChange {
+		return domain.PeerStateChange{
+			PeerId:        status.PeerId,
+			InterfaceId:   id,
+			Kind:          kind,
+			Time:          now,
+			Endpoint:      status.Endpoint,
+			LastHandshake: status.LastHandshake,
+		}
+	}
+
+	var changes []domain.PeerStateChange
+	isConnected := status.IsConnectedAt(now)
+	if isConnected && !wasConnected {
+		changes = append(changes, newChange(domain.PeerStateConnected))
+	}
+	if previousEndpoint != "" && status.Endpoint != "" && previousEndpoint != status.Endpoint {
+		change := newChange(domain.PeerStateEndpointChanged)
+		change.PreviousEndpoint = previousEndpoint
+		changes = append(changes, change)
+	}
+	if !isConnected && wasConnected {
+		changes = append(changes, newChange(domain.PeerStateDisconnected))
+	}
+
+	return changes
+}
+
+func (c *StatisticsCollector) startConnectionHistoryCleanup(ctx context.Context) {
+	if !c.cfg.Statistics.CollectPeerData || c.cfg.Statistics.ConnectionHistoryRetention == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(sampleCompactionInterval)
+		defer ticker.Stop()
+		for {
+			cutoff := time.Now().Add(-c.cfg.Statistics.ConnectionHistoryRetention)
+			if err := c.db.DeletePeerConnectionEvents(ctx, cutoff); err != nil {
+				slog.Warn("failed to remove expired peer connection events", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return // program stopped
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	slog.Debug("started peer connection history cleanup")
+}
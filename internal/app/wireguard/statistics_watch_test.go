@@ -0,0 +1,160 @@
+package wireguard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// stateDatabase implements the peer status updates and connection events required by the state watcher, all other
+// methods are not implemented.
+type stateDatabase struct {
+	StatisticsDatabaseRepo
+
+	status  map[domain.PeerIdentifier]domain.PeerStatus
+	updates int
+	events  []domain.PeerConnectionEvent
+}
+
+func (f *stateDatabase) SavePeerConnectionEvents(_ context.Context, events []domain.PeerConnectionEvent) error {
+	f.events = append(f.events, events...)
+	return nil
+}
+
+func (f *stateDatabase) UpdatePeerStatus(
+	_ context.Context,
+	id domain.PeerIdentifier,
+	updateFunc func(in *domain.PeerStatus) (*domain.PeerStatus, error),
+) error {
+	status, ok := f.status[id]
+	if !ok {
+		status = domain.PeerStatus{PeerId: id}
+	}
+	updated, err := updateFunc(&status)
+	if err != nil {
+		return err
+	}
+	f.status[id] = *updated
+	f.updates++
+	return nil
+}
+
+// stateController returns the configured peers for every interface.
+type stateController struct {
+	StatisticsInterfaceController
+
+	peers []domain.PhysicalPeer
+}
+
+func (f *stateController) GetPeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.PhysicalPeer, error) {
+	return f.peers, nil
+}
+
+type stateBus struct {
+	StatisticsEventBus
+
+	changes []domain.PeerStateChange
+}
+
+func (f *stateBus) Publish(_ string, args ...any) {
+	f.changes = append(f.changes, args[0].(domain.PeerStateChange))
+}
+
+func (f *stateBus) kinds() []domain.PeerStateChangeKind {
+	kinds := make([]domain.PeerStateChangeKind, 0, len(f.changes))
+	for _, change := range f.changes {
+		kinds = append(kinds, change.Kind)
+	}
+	f.changes = nil
+	return kinds
+}
+
+func TestStatisticsCollector_checkPeerStates(t *testing.T) {
+	db := &stateDatabase{status: make(map[domain.PeerIdentifier]domain.PeerStatus)}
+	wg := &stateController{}
+	bus := &stateBus{}
+	c := &StatisticsCollector{cfg: &config.Config{}, db: db, wg: wg, bus: bus}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	wg.peers = []domain.PhysicalPeer{{Identifier: "peer-a"}}
+	states := c.checkPeerStates(context.Background(), "wg0", nil, now)
+	assert.Empty(t, bus.kinds(), "peers without handshake are not connected")
+	assert.Equal(t, 1, db.updates)
+
+	now = now.Add(5 * time.Second)
+	states = c.checkPeerStates(context.Background(), "wg0", states, now)
+	assert.Equal(t, 1, db.updates, "unchanged peers are not written")
+
+	wg.peers[0].LastHandshake = now
+	wg.peers[0].Endpoint = "192.0.2.1:51820"
+	now = now.Add(5 * time.Second)
+	states = c.checkPeerStates(context.Background(), "wg0", states, now)
+	assert.Equal(t, []domain.PeerStateChangeKind{domain.PeerStateConnected}, bus.kinds())
+	assert.Equal(t, "192.0.2.1:51820", db.status["peer-a"].Endpoint)
+
+	wg.peers[0].Endpoint = "198.51.100.7:4500"
+	now = now.Add(5 * time.Second)
+	states = c.checkPeerStates(context.Background(), "wg0", states, now)
+	require.Len(t, bus.changes, 1)
+	assert.Equal(t, domain.PeerStateEndpointChanged, bus.changes[0].Kind)
+	assert.Equal(t, "192.0.2.1:51820", bus.changes[0].PreviousEndpoint)
+	assert.Equal(t, domain.InterfaceIdentifier("wg0"), bus.changes[0].InterfaceId)
+	bus.changes = nil
+
+	updates := db.updates
+	now = now.Add(5 * time.Second)
+	states = c.checkPeerStates(context.Background(), "wg0", states, now)
+	assert.Equal(t, updates, db.updates)
+
+	now = now.Add(3 * time.Minute) // the handshake expired
+	c.checkPeerStates(context.Background(), "wg0", states, now)
+	assert.Equal(t, []domain.PeerStateChangeKind{domain.PeerStateDisconnected}, bus.kinds())
+}
+
+func TestStatisticsCollector_recordPeerStateChanges(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Statistics.ConnectionHistoryRetention = 24 * time.Hour
+	db := &stateDatabase{status: make(map[domain.PeerIdentifier]domain.PeerStatus)}
+	bus := &stateBus{}
+	c := &StatisticsCollector{cfg: cfg, db: db, bus: bus}
+
+	now := time.Now()
+	c.recordPeerStateChanges(context.Background(), []domain.PeerStateChange{
+		{PeerId: "peer-a", InterfaceId: "wg0", Kind: domain.PeerStateConnected, Time: now, Endpoint: "192.0.2.1:51820"},
+	})
+	require.Len(t, db.events, 1)
+	assert.Equal(t, domain.PeerIdentifier("peer-a"), db.events[0].PeerId)
+	assert.Equal(t, "192.0.2.1:51820", db.events[0].Endpoint)
+	assert.Equal(t, time.UTC, db.events[0].Timestamp.Location())
+	assert.Len(t, bus.changes, 1)
+
+	cfg.Statistics.ConnectionHistoryRetention = 0 // the history is disabled, events are published only
+	c.recordPeerStateChanges(context.Background(), []domain.PeerStateChange{
+		{PeerId: "peer-a", InterfaceId: "wg0", Kind: domain.PeerStateDisconnected, Time: now},
+	})
+	assert.Len(t, db.events, 1)
+	assert.Len(t, bus.changes, 2)
+}
+
+func Test_peerStateChanges(t *testing.T) {
+	now := time.Now()
+	handshake := now.Add(-time.Minute)
+	connected := domain.PeerStatus{PeerId: "peer-a", Endpoint: "192.0.2.1:51820", LastHandshake: &handshake}
+
+	assert.Empty(t, peerStateChanges("wg0", connected, true, "192.0.2.1:51820", now))
+
+	changes := peerStateChanges("wg0", connected, false, "", now)
+	require.Len(t, changes, 1)
+	assert.Equal(t, domain.PeerStateConnected, changes[0].Kind)
+	assert.Equal(t, &handshake, changes[0].LastHandshake)
+
+	changes = peerStateChanges("wg0", domain.PeerStatus{PeerId: "peer-a"}, true, "192.0.2.1:51820", now)
+	require.Len(t, changes, 1)
+	assert.Equal(t, domain.PeerStateDisconnected, changes[0].Kind)
+}
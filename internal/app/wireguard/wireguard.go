@@ -2,7 +2,9 @@ package wireguard
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net/netip"
 	"sync"
 	"time"
 
@@ -27,6 +29,7 @@ type InterfaceAndPeerDatabaseRepo interface {
 	DeleteInterface(ctx context.Context, id domain.InterfaceIdentifier) error
 	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
 	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
+	GetSharedUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
 	SavePeer(
 		ctx context.Context,
 		id domain.PeerIdentifier,
@@ -34,7 +37,11 @@ type InterfaceAndPeerDatabaseRepo interface {
 	) error
 	DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
 	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	GetPeerCount(ctx context.Context) (int, error)
 	GetUsedIpsPerSubnet(ctx context.Context, subnets []domain.Cidr) (map[domain.Cidr][]domain.Cidr, error)
+	GetAddressReservations(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.AddressReservation, error)
+	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	GetAllUserGroups(ctx context.Context) ([]domain.UserGroup, error)
 }
 
 type InterfaceController interface {
@@ -54,6 +61,15 @@ type InterfaceController interface {
 		updateFunc func(pp *domain.PhysicalPeer) (*domain.PhysicalPeer, error),
 	) error
 	DeletePeer(_ context.Context, deviceId domain.InterfaceIdentifier, id domain.PeerIdentifier) error
+	// ApplyPeers creates or updates the given peers and removes the peers with the given ids in a single device
+	// configuration call.
+	ApplyPeers(
+		_ context.Context,
+		deviceId domain.InterfaceIdentifier,
+		peers []domain.PhysicalPeer,
+		removed []domain.PeerIdentifier,
+	) error
+	ProbePathMtu(_ context.Context, destination netip.Addr) (*domain.PathMtu, error)
 }
 
 type WgQuickController interface {
@@ -62,6 +78,17 @@ type WgQuickController interface {
 	UnsetDNS(id domain.InterfaceIdentifier) error
 }
 
+type AcceptableUseValidator interface {
+	// ValidateAcceptance checks that the current user accepted the acceptable use policy.
+	ValidateAcceptance(ctx context.Context) error
+}
+
+type KeyRevealValidator interface {
+	// ValidateReveal checks that the current user is allowed to see the private key of the peer and records the
+	// reveal.
+	ValidateReveal(ctx context.Context, peer *domain.Peer, format string) error
+}
+
 type EventBus interface {
 	// Publish sends a message to the message bus.
 	Publish(topic string, args ...any)
@@ -72,11 +99,15 @@ type EventBus interface {
 // endregion dependencies
 
 type Manager struct {
-	cfg   *config.Config
-	bus   EventBus
-	db    InterfaceAndPeerDatabaseRepo
-	wg    InterfaceController
-	quick WgQuickController
+	cfg    *config.Config
+	bus    EventBus
+	db     InterfaceAndPeerDatabaseRepo
+	wg     InterfaceController
+	quick  WgQuickController
+	aup    AcceptableUseValidator
+	reveal KeyRevealValidator
+
+	portPool domain.PortPool // empty if listen ports are assigned sequentially
 
 	userLockMap *sync.Map
 }
@@ -87,13 +118,23 @@ func NewWireGuardManager(
 	wg InterfaceController,
 	quick WgQuickController,
 	db InterfaceAndPeerDatabaseRepo,
+	aup AcceptableUseValidator,
+	reveal KeyRevealValidator,
 ) (*Manager, error) {
+	portPool, err := domain.ParsePortPool(cfg.Advanced.ListenPortPool)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen port pool: %w", err)
+	}
+
 	m := &Manager{
 		cfg:         cfg,
 		bus:         bus,
 		wg:          wg,
 		db:          db,
 		quick:       quick,
+		aup:         aup,
+		reveal:      reveal,
+		portPool:    portPool,
 		userLockMap: &sync.Map{},
 	}
 
@@ -172,6 +213,8 @@ func (m Manager) handleUserLoginEvent(userId domain.UserIdentifier) {
 
 func (m Manager) handleUserDisabledEvent(user domain.User) {
 	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	m.offboardSharedPeers(ctx, user.Identifier)
+
 	userPeers, err := m.db.GetUserPeers(ctx, user.Identifier)
 	if err != nil {
 		slog.Error("failed to retrieve peers for disabled user",
@@ -245,6 +288,8 @@ func (m Manager) handleUserEnabledEvent(user domain.User) {
 
 func (m Manager) handleUserDeletedEvent(user domain.User) {
 	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	m.offboardSharedPeers(ctx, user.Identifier)
+
 	userPeers, err := m.db.GetUserPeers(ctx, user.Identifier)
 	if err != nil {
 		slog.Error("failed to retrieve peers for deleted user",
@@ -290,6 +335,74 @@ func (m Manager) handleUserDeletedEvent(user domain.User) {
 	}
 }
 
+// offboardSharedPeers removes the user from all shared peers and rotates the keys of these peers, as the user still
+// knows their configuration. Owned peers are handed over to the next active member. If no other member is active,
+// the peer is left to the regular handling, so that the peer expires with its last active member.
+func (m Manager) offboardSharedPeers(ctx context.Context, userId domain.UserIdentifier) {
+	ownedPeers, err := m.db.GetUserPeers(ctx, userId)
+	if err != nil {
+		slog.Error("failed to retrieve owned peers for offboarding", "user", userId, "error", err)
+		return
+	}
+	sharedPeers, err := m.db.GetSharedUserPeers(ctx, userId)
+	if err != nil {
+		slog.Error("failed to retrieve shared peers for offboarding", "user", userId, "error", err)
+		return
+	}
+
+	for _, peer := range append(ownedPeers, sharedPeers...) {
+		if !peer.IsSharedDevice() {
+			continue
+		}
+
+		successor := m.nextActiveMember(ctx, peer, userId)
+		if peer.UserIdentifier == userId && successor == "" {
+			continue // no other active member, the peer is disabled or deleted together with its owner
+		}
+
+		peer.RemoveMember(userId, successor)
+		if err := peer.RotateKeys(); err != nil {
+			slog.Error("failed to rotate keys of shared peer", "peer", peer.Identifier, "error", err)
+			continue
+		}
+
+		updatedPeer, err := m.UpdatePeer(ctx, &peer)
+		if err != nil {
+			slog.Error("failed to offboard user from shared peer",
+				"peer", peer.Identifier,
+				"user", userId,
+				"error", err)
+			continue
+		}
+
+		slog.Info("removed user from shared peer and rotated keys",
+			"peer", peer.Identifier,
+			"new", updatedPeer.Identifier,
+			"user", userId,
+			"owner", updatedPeer.UserIdentifier)
+	}
+}
+
+// nextActiveMember returns the first member of the peer, except the given user, that exists and is not disabled.
+func (m Manager) nextActiveMember(
+	ctx context.Context,
+	peer domain.Peer,
+	except domain.UserIdentifier,
+) domain.UserIdentifier {
+	for _, id := range peer.Members() {
+		if id == except {
+			continue
+		}
+		user, err := m.db.GetUser(ctx, id)
+		if err != nil || user.IsDisabled() {
+			continue
+		}
+		return id
+	}
+
+	return ""
+}
+
 func (m Manager) runExpiredPeersCheck(ctx context.Context) {
 	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
 
@@ -0,0 +1,108 @@
+package wireguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// maxParallelInterfaceApplies limits the number of interfaces that are configured at the same time.
+const maxParallelInterfaceApplies = 8
+
+// peerBatch holds all peer changes of a single interface, they are applied with one device configuration call.
+type peerBatch struct {
+	iface   domain.InterfaceIdentifier
+	peers   []domain.PhysicalPeer
+	removed []domain.PeerIdentifier
+}
+
+// newPeerBatches groups the given peers by their interface. Disabled and expired peers are removed from the device,
+// all other peers are created or updated.
+func newPeerBatches(peers []*domain.Peer) []*peerBatch {
+	var batches []*peerBatch
+	byInterface := make(map[domain.InterfaceIdentifier]*peerBatch)
+
+	for _, peer := range peers {
+		batch, ok := byInterface[peer.InterfaceIdentifier]
+		if !ok {
+			batch = &peerBatch{iface: peer.InterfaceIdentifier}
+			byInterface[peer.InterfaceIdentifier] = batch
+			batches = append(batches, batch)
+		}
+
+		if peer.IsDisabled() || peer.IsExpired() {
+			batch.removed = append(batch.removed, peer.Identifier)
+			continue
+		}
+
+		var pp domain.PhysicalPeer
+		domain.MergeToPhysicalPeer(&pp, peer)
+		batch.peers = append(batch.peers, pp)
+	}
+
+	return batches
+}
+
+// applyPeerBatches applies the given batches, the interfaces are configured in parallel. All batches are applied,
+// even if one of them fails. The returned error contains the errors of all failed batches.
+func (m Manager) applyPeerBatches(ctx context.Context, batches []*peerBatch) error {
+	var mux sync.Mutex
+	var errs []error
+
+	ch := make(chan *peerBatch)
+	wg := sync.WaitGroup{}
+	workers := int(math.Min(float64(len(batches)), maxParallelInterfaceApplies))
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for batch := range ch {
+				err := m.wg.ApplyPeers(ctx, batch.iface, batch.peers, batch.removed)
+				if err != nil {
+					mux.Lock()
+					errs = append(errs, fmt.Errorf("failed to apply peers of interface %s: %w", batch.iface, err))
+					mux.Unlock()
+				}
+			}
+		}()
+	}
+	for _, batch := range batches {
+		ch <- batch
+	}
+	close(ch)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// restorePhysicalPeers restores the snapshot state of the given peers on their devices.
+func (m Manager) restorePhysicalPeers(
+	ctx context.Context,
+	snapshot map[domain.PeerIdentifier]peerSnapshot,
+	peers []*domain.Peer,
+) error {
+	var batches []*peerBatch
+	byInterface := make(map[domain.InterfaceIdentifier]*peerBatch)
+
+	for _, peer := range peers {
+		batch, ok := byInterface[peer.InterfaceIdentifier]
+		if !ok {
+			batch = &peerBatch{iface: peer.InterfaceIdentifier}
+			byInterface[peer.InterfaceIdentifier] = batch
+			batches = append(batches, batch)
+		}
+
+		state := snapshot[peer.Identifier]
+		if state.physical == nil {
+			batch.removed = append(batch.removed, peer.Identifier)
+		} else {
+			batch.peers = append(batch.peers, *state.physical)
+		}
+	}
+
+	return m.applyPeerBatches(ctx, batches)
+}
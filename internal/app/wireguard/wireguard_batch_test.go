@@ -0,0 +1,118 @@
+package wireguard
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// batchController keeps the peers of multiple devices in memory and counts the device configuration calls.
+type batchController struct {
+	InterfaceController
+
+	mux   sync.Mutex
+	peers map[domain.InterfaceIdentifier]map[domain.PeerIdentifier]domain.PhysicalPeer
+	calls map[domain.InterfaceIdentifier]int
+}
+
+func (f *batchController) GetPeers(_ context.Context, id domain.InterfaceIdentifier) ([]domain.PhysicalPeer, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	peers := make([]domain.PhysicalPeer, 0, len(f.peers[id]))
+	for _, peer := range f.peers[id] {
+		peers = append(peers, peer)
+	}
+	return peers, nil
+}
+
+func (f *batchController) ApplyPeers(
+	_ context.Context,
+	id domain.InterfaceIdentifier,
+	peers []domain.PhysicalPeer,
+	removed []domain.PeerIdentifier,
+) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.calls[id]++
+	if f.peers[id] == nil {
+		f.peers[id] = make(map[domain.PeerIdentifier]domain.PhysicalPeer)
+	}
+	for _, peerId := range removed {
+		delete(f.peers[id], peerId)
+	}
+	for _, peer := range peers {
+		f.peers[id][peer.Identifier] = peer
+	}
+	return nil
+}
+
+type batchBus struct {
+	EventBus
+}
+
+func (f batchBus) Publish(_ string, _ ...any) {}
+
+func TestManager_savePeers_batch(t *testing.T) {
+	db := rollbackDatabase{peers: map[domain.PeerIdentifier]domain.Peer{}}
+	wg := &batchController{
+		peers: map[domain.InterfaceIdentifier]map[domain.PeerIdentifier]domain.PhysicalPeer{
+			"wg1": {"disabled": {Identifier: "disabled"}},
+		},
+		calls: map[domain.InterfaceIdentifier]int{},
+	}
+	m := Manager{db: db, wg: wg, bus: batchBus{}}
+	now := time.Now()
+
+	var peers []*domain.Peer
+	for _, id := range []domain.PeerIdentifier{"a", "b", "c"} {
+		peers = append(peers, &domain.Peer{Identifier: id, InterfaceIdentifier: "wg0",
+			Endpoint: domain.NewConfigOption("vpn.example.com:51820", false)})
+	}
+	peers = append(peers, &domain.Peer{Identifier: "d", InterfaceIdentifier: "wg1"})
+	peers = append(peers, &domain.Peer{Identifier: "disabled", InterfaceIdentifier: "wg1", Disabled: &now})
+
+	require.NoError(t, m.savePeers(context.Background(), peers...))
+
+	assert.Equal(t, map[domain.InterfaceIdentifier]int{"wg0": 1, "wg1": 1}, wg.calls,
+		"one configuration call per interface")
+	assert.Len(t, wg.peers["wg0"], 3)
+	assert.Equal(t, "vpn.example.com:51820", wg.peers["wg0"]["b"].Endpoint)
+	assert.Len(t, wg.peers["wg1"], 1)
+	assert.Contains(t, wg.peers["wg1"], domain.PeerIdentifier("d"))
+	assert.Len(t, db.peers, 5)
+}
+
+func TestManager_restorePhysicalPeers(t *testing.T) {
+	wg := &batchController{
+		peers: map[domain.InterfaceIdentifier]map[domain.PeerIdentifier]domain.PhysicalPeer{
+			"wg0": {
+				"existing": {Identifier: "existing", Endpoint: "new.example.com:51820"},
+				"fresh":    {Identifier: "fresh"},
+			},
+		},
+		calls: map[domain.InterfaceIdentifier]int{},
+	}
+	m := Manager{wg: wg}
+
+	snapshot := map[domain.PeerIdentifier]peerSnapshot{
+		"existing": {physical: &domain.PhysicalPeer{Identifier: "existing", Endpoint: "old.example.com:51820"}},
+		"fresh":    {},
+	}
+	err := m.restorePhysicalPeers(context.Background(), snapshot, []*domain.Peer{
+		{Identifier: "existing", InterfaceIdentifier: "wg0"},
+		{Identifier: "fresh", InterfaceIdentifier: "wg0"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, wg.calls["wg0"])
+	assert.Len(t, wg.peers["wg0"], 1)
+	assert.Equal(t, "old.example.com:51820", wg.peers["wg0"]["existing"].Endpoint)
+}
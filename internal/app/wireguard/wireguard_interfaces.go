@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"slices"
 	"time"
 
@@ -14,10 +15,13 @@ import (
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
+// wgQuickConfigDirectory is the default directory of the wg-quick configuration files.
+const wgQuickConfigDirectory = "/etc/wireguard"
+
 // GetImportableInterfaces returns all physical interfaces that are available on the system.
 // This function also returns interfaces that are already available in the database.
 func (m Manager) GetImportableInterfaces(ctx context.Context) ([]domain.PhysicalInterface, error) {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
 		return nil, err
 	}
 
@@ -35,29 +39,56 @@ func (m Manager) GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceId
 	[]domain.Peer,
 	error,
 ) {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+	iface, peers, err := m.db.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
 		return nil, nil, err
 	}
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return nil, nil, err
+	}
+	if !domain.GetUserInfo(ctx).IsAdmin {
+		iface.PrivateKey = "" // the interface key is only visible for global admins
+	}
 
-	return m.db.GetInterfaceAndPeers(ctx, id)
+	return iface, peers, nil
 }
 
 // GetAllInterfaces returns all interfaces that are available in the database.
+// Interface admins only receive the interfaces they administrate, organization admins only receive the interfaces
+// of their organization.
 func (m Manager) GetAllInterfaces(ctx context.Context) ([]domain.Interface, error) {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+	currentUser := domain.GetUserInfo(ctx)
+	if !currentUser.HasAdminInterfaces() {
+		return nil, domain.ValidateAdminAccessRights(ctx)
+	}
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
 		return nil, err
 	}
+	if currentUser.IsGlobalAdmin() {
+		return interfaces, nil
+	}
+
+	adminInterfaces := make([]domain.Interface, 0, len(interfaces))
+	for _, iface := range interfaces {
+		if !currentUser.IsInterfaceAdmin(&iface) ||
+			!currentUser.CanAccessOrganization(iface.OrganizationIdentifier) {
+			continue
+		}
+		if !currentUser.IsAdmin {
+			iface.PrivateKey = "" // the interface key is only visible for global admins
+		}
+		adminInterfaces = append(adminInterfaces, iface)
+	}
 
-	return m.db.GetAllInterfaces(ctx)
+	return adminInterfaces, nil
 }
 
 // GetAllInterfacesAndPeers returns all interfaces and their peers.
+// Interface admins only receive the interfaces they administrate.
 func (m Manager) GetAllInterfacesAndPeers(ctx context.Context) ([]domain.Interface, [][]domain.Peer, error) {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
-		return nil, nil, err
-	}
-
-	interfaces, err := m.db.GetAllInterfaces(ctx)
+	interfaces, err := m.GetAllInterfaces(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to load all interfaces: %w", err)
 	}
@@ -76,12 +107,15 @@ func (m Manager) GetAllInterfacesAndPeers(ctx context.Context) ([]domain.Interfa
 
 // GetUserInterfaces returns all interfaces that are available for users to create new peers.
 // If self-provisioning is disabled, this function will return an empty list.
+// Users only receive the interfaces of their own organization.
 // At the moment, there are no interfaces specific to single users, thus the user id is not used.
 func (m Manager) GetUserInterfaces(ctx context.Context, _ domain.UserIdentifier) ([]domain.Interface, error) {
 	if !m.cfg.Core.SelfProvisioningAllowed {
 		return nil, nil // self-provisioning is disabled - no interfaces for users
 	}
 
+	currentUser := domain.GetUserInfo(ctx)
+
 	interfaces, err := m.db.GetAllInterfaces(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load all interfaces: %w", err)
@@ -96,6 +130,9 @@ func (m Manager) GetUserInterfaces(ctx context.Context, _ domain.UserIdentifier)
 		if iface.Type != domain.InterfaceTypeServer {
 			continue // skip client interfaces
 		}
+		if iface.OrganizationIdentifier != currentUser.Organization && !currentUser.IsGlobalAdmin() {
+			continue // skip interfaces of other organizations
+		}
 
 		userInterfaces = append(userInterfaces, iface.PublicInfo())
 	}
@@ -105,7 +142,7 @@ func (m Manager) GetUserInterfaces(ctx context.Context, _ domain.UserIdentifier)
 
 // ImportNewInterfaces imports all new physical interfaces that are available on the system.
 func (m Manager) ImportNewInterfaces(ctx context.Context, filter ...domain.InterfaceIdentifier) (int, error) {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
 		return 0, err
 	}
 
@@ -193,8 +230,8 @@ func (m Manager) RestoreInterfaceState(
 	ctx context.Context,
 	updateDbOnError bool,
 	filter ...domain.InterfaceIdentifier,
-) error {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+) (err error) {
+	if err := domain.ValidateGlobalAdminAccessRights(ctx); err != nil {
 		return err
 	}
 
@@ -203,6 +240,15 @@ func (m Manager) RestoreInterfaceState(
 		return err
 	}
 
+	// the peers of all interfaces are restored in parallel once the interfaces are set up. If an interface fails, the
+	// peers of the interfaces that were already set up are restored nevertheless.
+	var batches []*peerBatch
+	defer func() {
+		if applyErr := m.applyPeerBatches(ctx, batches); applyErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to restore peers: %w", applyErr))
+		}
+	}()
+
 	for _, iface := range interfaces {
 		if len(filter) != 0 && !slices.Contains(filter, iface.Identifier) {
 			continue // ignore filtered interface
@@ -257,50 +303,30 @@ func (m Manager) RestoreInterfaceState(
 			}
 		}
 
-		// restore peers
+		// restore peers, all peer changes of the interface are applied with a single configuration call
+		batch := &peerBatch{iface: iface.Identifier}
+		wgPortalPeers := make(map[domain.PeerIdentifier]struct{}, len(peers))
 		for _, peer := range peers {
-			switch {
-			case iface.IsDisabled(): // if interface is disabled, delete all peers
-				if err := m.wg.DeletePeer(ctx, iface.Identifier, peer.Identifier); err != nil {
-					return fmt.Errorf("failed to remove peer %s for disabled interface %s: %w",
-						peer.Identifier, iface.Identifier, err)
-				}
-			case peer.IsDisabled(): // if peer is disabled, delete it
-				if err := m.wg.DeletePeer(ctx, iface.Identifier, peer.Identifier); err != nil {
-					return fmt.Errorf("failed to remove disbaled peer %s from interface %s: %w",
-						peer.Identifier, iface.Identifier, err)
-				}
-			default: // update peer
-				err := m.wg.SavePeer(ctx, iface.Identifier, peer.Identifier,
-					func(pp *domain.PhysicalPeer) (*domain.PhysicalPeer, error) {
-						domain.MergeToPhysicalPeer(pp, &peer)
-						return pp, nil
-					})
-				if err != nil {
-					return fmt.Errorf("failed to create/update physical peer %s for interface %s: %w",
-						peer.Identifier, iface.Identifier, err)
-				}
+			wgPortalPeers[peer.Identifier] = struct{}{}
+			if iface.IsDisabled() || peer.IsDisabled() { // remove disabled peers and all peers of disabled interfaces
+				batch.removed = append(batch.removed, peer.Identifier)
+				continue
 			}
+
+			var pp domain.PhysicalPeer
+			domain.MergeToPhysicalPeer(&pp, &peer)
+			batch.peers = append(batch.peers, pp)
 		}
 
 		// remove non-wgportal peers
 		physicalPeers, _ := m.wg.GetPeers(ctx, iface.Identifier)
 		for _, physicalPeer := range physicalPeers {
-			isWgPortalPeer := false
-			for _, peer := range peers {
-				if peer.Identifier == domain.PeerIdentifier(physicalPeer.PublicKey) {
-					isWgPortalPeer = true
-					break
-				}
-			}
-			if !isWgPortalPeer {
-				err := m.wg.DeletePeer(ctx, iface.Identifier, domain.PeerIdentifier(physicalPeer.PublicKey))
-				if err != nil {
-					return fmt.Errorf("failed to remove non-wgportal peer %s from interface %s: %w",
-						physicalPeer.PublicKey, iface.Identifier, err)
-				}
+			if _, ok := wgPortalPeers[physicalPeer.Identifier]; !ok {
+				batch.removed = append(batch.removed, physicalPeer.Identifier)
 			}
 		}
+
+		batches = append(batches, batch)
 	}
 
 	return nil
@@ -369,11 +395,13 @@ func (m Manager) PrepareInterface(ctx context.Context) (*domain.Interface, error
 		DriverType:                 "",
 		Disabled:                   nil,
 		DisabledReason:             "",
+		OrganizationIdentifier:     currentUser.Organization,
 		PeerDefNetworkStr:          domain.CidrsToString(networks),
 		PeerDefDnsStr:              "",
 		PeerDefDnsSearchStr:        "",
 		PeerDefEndpoint:            "",
 		PeerDefAllowedIPsStr:       domain.CidrsToString(networks),
+		PeerDefRouteSetsStr:        "",
 		PeerDefMtu:                 1420,
 		PeerDefPersistentKeepalive: 16,
 		PeerDefFirewallMark:        0,
@@ -401,6 +429,10 @@ func (m Manager) CreateInterface(ctx context.Context, in *domain.Interface) (*do
 		return nil, fmt.Errorf("interface %s already exists: %w", in.Identifier, domain.ErrDuplicateEntry)
 	}
 
+	if currentUser := domain.GetUserInfo(ctx); !currentUser.IsGlobalAdmin() {
+		in.OrganizationIdentifier = currentUser.Organization // organization admins create interfaces in their own org
+	}
+
 	if err := m.validateInterfaceCreation(ctx, existingInterface, in); err != nil {
 		return nil, fmt.Errorf("creation not allowed: %w", err)
 	}
@@ -430,6 +462,8 @@ func (m Manager) UpdateInterface(ctx context.Context, in *domain.Interface) (*do
 		return nil, nil, fmt.Errorf("update not allowed: %w", err)
 	}
 
+	propagateListenPortToDefaults(existingInterface, in)
+
 	in, err = m.saveInterface(ctx, in)
 	if err != nil {
 		return nil, nil, fmt.Errorf("update failure: %w", err)
@@ -437,7 +471,12 @@ func (m Manager) UpdateInterface(ctx context.Context, in *domain.Interface) (*do
 
 	m.bus.Publish(app.TopicInterfaceUpdated, *in)
 
-	return in, existingPeers, nil
+	peers, err := m.propagateListenPortToPeers(ctx, existingInterface, in, existingPeers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listen port propagation failure: %w", err)
+	}
+
+	return in, peers, nil
 }
 
 // DeleteInterface deletes the given interface.
@@ -730,36 +769,6 @@ func (m Manager) getFreshInterfaceIpConfig(ctx context.Context) (ipV4, ipV6 doma
 	return
 }
 
-func (m Manager) getFreshListenPort(ctx context.Context) (port int, err error) {
-	existingInterfaces, err := m.db.GetAllInterfaces(ctx)
-	if err != nil {
-		return -1, err
-	}
-
-	port = m.cfg.Advanced.StartListenPort
-
-	for {
-		conflict := false
-		for _, in := range existingInterfaces {
-			if in.ListenPort == port {
-				conflict = true
-				break
-			}
-		}
-		if !conflict {
-			break
-		}
-
-		port++
-	}
-
-	if port > 65535 { // maximum allowed port number (16 bit uint)
-		return -1, fmt.Errorf("port space exhausted")
-	}
-
-	return
-}
-
 func (m Manager) importInterface(ctx context.Context, in *domain.PhysicalInterface, peers []domain.PhysicalPeer) error {
 	now := time.Now()
 	iface := domain.ConvertPhysicalInterface(in)
@@ -786,9 +795,14 @@ func (m Manager) importInterface(ctx context.Context, in *domain.PhysicalInterfa
 		return fmt.Errorf("database save failed: %w", err)
 	}
 
-	// import peers
+	// import peers, the metadata of peers that were exported by WireGuard Portal is restored from the annotations
+	annotations := m.loadPeerAnnotations(iface)
 	for _, peer := range peers {
-		err = m.importPeer(ctx, iface, &peer)
+		var annotation *domain.PeerAnnotation
+		if a, ok := annotations[peer.PublicKey]; ok {
+			annotation = &a
+		}
+		err = m.importPeer(ctx, iface, &peer, annotation)
 		if err != nil {
 			return fmt.Errorf("import of peer %s failed: %w", peer.Identifier, err)
 		}
@@ -797,7 +811,12 @@ func (m Manager) importInterface(ctx context.Context, in *domain.PhysicalInterfa
 	return nil
 }
 
-func (m Manager) importPeer(ctx context.Context, in *domain.Interface, p *domain.PhysicalPeer) error {
+func (m Manager) importPeer(
+	ctx context.Context,
+	in *domain.Interface,
+	p *domain.PhysicalPeer,
+	annotation *domain.PeerAnnotation,
+) error {
 	now := time.Now()
 	peer := domain.ConvertPhysicalPeer(p)
 	peer.BaseModel = domain.BaseModel{
@@ -810,6 +829,7 @@ func (m Manager) importPeer(ctx context.Context, in *domain.Interface, p *domain
 	peer.InterfaceIdentifier = in.Identifier
 	peer.EndpointPublicKey = domain.NewConfigOption(in.PublicKey, true)
 	peer.AllowedIPsStr = domain.NewConfigOption(in.PeerDefAllowedIPsStr, true)
+	peer.RouteSetsStr = domain.NewConfigOption(in.PeerDefRouteSetsStr, true)
 	peer.Interface.Addresses = p.AllowedIPs // use allowed IP's as the peer IP's TODO: Should this also match server interface address' prefix length?
 	peer.Interface.DnsStr = domain.NewConfigOption(in.PeerDefDnsStr, true)
 	peer.Interface.DnsSearchStr = domain.NewConfigOption(in.PeerDefDnsSearchStr, true)
@@ -833,6 +853,10 @@ func (m Manager) importPeer(ctx context.Context, in *domain.Interface, p *domain
 		peer.DisplayName = "Autodetected Client (" + peer.Interface.PublicKey[0:8] + ")"
 	}
 
+	if annotation != nil {
+		m.applyPeerAnnotation(ctx, peer, annotation)
+	}
+
 	err := m.db.SavePeer(ctx, peer.Identifier, func(_ *domain.Peer) (*domain.Peer, error) {
 		return peer, nil
 	})
@@ -843,6 +867,60 @@ func (m Manager) importPeer(ctx context.Context, in *domain.Interface, p *domain
 	return nil
 }
 
+// loadPeerAnnotations reads the peer annotations from an existing wg-quick configuration file of the interface.
+// The configuration storage path is searched first, followed by the default wg-quick configuration directory.
+func (m Manager) loadPeerAnnotations(iface *domain.Interface) map[string]domain.PeerAnnotation {
+	var directories []string
+	if m.cfg.Advanced.ConfigStoragePath != "" {
+		directories = append(directories, m.cfg.Advanced.ConfigStoragePath)
+	}
+	directories = append(directories, wgQuickConfigDirectory)
+
+	for _, directory := range directories {
+		path := filepath.Join(directory, iface.GetConfigFileName())
+		file, err := os.Open(path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				slog.Warn("failed to open interface config for peer annotations", "path", path, "error", err)
+			}
+			continue
+		}
+
+		annotations, err := domain.ParsePeerAnnotations(file)
+		_ = file.Close()
+		if err != nil {
+			slog.Warn("failed to parse peer annotations", "path", path, "error", err)
+			continue
+		}
+
+		slog.Debug("loaded peer annotations", "interface", iface.Identifier, "path", path,
+			"peers", len(annotations))
+		return annotations
+	}
+
+	return nil
+}
+
+// applyPeerAnnotation restores the metadata of an imported peer. Owners that do not exist are ignored.
+func (m Manager) applyPeerAnnotation(ctx context.Context, peer *domain.Peer, annotation *domain.PeerAnnotation) {
+	if annotation.DisplayName != "" {
+		peer.DisplayName = annotation.DisplayName
+	}
+	if annotation.ExpiresAt != nil {
+		peer.ExpiresAt = annotation.ExpiresAt
+	}
+	if annotation.Owner == "" {
+		return
+	}
+
+	if _, err := m.db.GetUser(ctx, annotation.Owner); err != nil {
+		slog.Warn("ignoring unknown owner of imported peer", "peer", peer.Identifier, "user", annotation.Owner,
+			"error", err)
+		return
+	}
+	peer.UserIdentifier = annotation.Owner
+}
+
 func (m Manager) deleteInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) error {
 	allPeers, err := m.db.GetInterfacePeers(ctx, id)
 	if err != nil {
@@ -863,13 +941,25 @@ func (m Manager) deleteInterfacePeers(ctx context.Context, id domain.InterfaceId
 	return nil
 }
 
-func (m Manager) validateInterfaceModifications(ctx context.Context, _, _ *domain.Interface) error {
+func (m Manager) validateInterfaceModifications(ctx context.Context, old, new *domain.Interface) error {
 	currentUser := domain.GetUserInfo(ctx)
 
 	if !currentUser.IsAdmin {
 		return fmt.Errorf("insufficient permissions")
 	}
 
+	if err := domain.ValidateOrganizationAccessRights(ctx, old.OrganizationIdentifier); err != nil {
+		return err
+	}
+
+	if !currentUser.IsGlobalAdmin() && old.OrganizationIdentifier != new.OrganizationIdentifier {
+		return fmt.Errorf("cannot change organization: %w", domain.ErrNoPermission)
+	}
+
+	if err := m.validateListenPort(ctx, old, new); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -891,17 +981,70 @@ func (m Manager) validateInterfaceCreation(ctx context.Context, _, new *domain.I
 		}
 	}
 
+	if err := m.validateListenPort(ctx, nil, new); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (m Manager) validateInterfaceDeletion(ctx context.Context, _ *domain.Interface) error {
+func (m Manager) validateInterfaceDeletion(ctx context.Context, del *domain.Interface) error {
 	currentUser := domain.GetUserInfo(ctx)
 
 	if !currentUser.IsAdmin {
 		return fmt.Errorf("insufficient permissions")
 	}
 
+	if err := domain.ValidateOrganizationAccessRights(ctx, del.OrganizationIdentifier); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// validateInterfaceOrganization checks if the current user is allowed to access the interface with the given
+// identifier. Users without organization are not restricted.
+func (m Manager) validateInterfaceOrganization(ctx context.Context, id domain.InterfaceIdentifier) error {
+	if domain.GetUserInfo(ctx).Organization == "" {
+		return nil
+	}
+
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to find interface %s: %w", id, err)
+	}
+
+	return domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier)
+}
+
+// validateInterfaceAdmin checks if the current user is allowed to administrate the interface with the given
+// identifier, see domain.ValidateInterfaceAdminAccessRights.
+func (m Manager) validateInterfaceAdmin(ctx context.Context, id domain.InterfaceIdentifier) error {
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to find interface %s: %w", id, err)
+	}
+
+	return domain.ValidateInterfaceAdminAccessRights(ctx, iface)
+}
+
+// isInterfaceAdmin returns true if the current user administrates the interface with the given identifier.
+// The interface is only loaded if the answer depends on its organization.
+func (m Manager) isInterfaceAdmin(ctx context.Context, id domain.InterfaceIdentifier) bool {
+	currentUser := domain.GetUserInfo(ctx)
+	if currentUser.IsGlobalAdmin() || slices.Contains(currentUser.AdminInterfaces, id) {
+		return true
+	}
+	if !currentUser.IsAdmin {
+		return false
+	}
+
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return false
+	}
+
+	return currentUser.IsInterfaceAdmin(iface)
+}
+
 // endregion helper-functions
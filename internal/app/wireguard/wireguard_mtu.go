@@ -0,0 +1,112 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// SuggestInterfaceMtu probes the paths to all known peer endpoints of the given interface and returns the path
+// with the smallest MTU. If no peer endpoint is known, the path of the default route is returned.
+func (m Manager) SuggestInterfaceMtu(ctx context.Context, id domain.InterfaceIdentifier) (*domain.PathMtu, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.validateInterfaceOrganization(ctx, id); err != nil {
+		return nil, err
+	}
+
+	iface, peers, err := m.db.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", id, err)
+	}
+
+	var smallest *domain.PathMtu
+	for _, peer := range peers {
+		destination := m.getPeerEndpointAddress(ctx, iface, &peer)
+		if !destination.IsValid() {
+			continue
+		}
+
+		pathMtu, err := m.wg.ProbePathMtu(ctx, destination)
+		if err != nil {
+			slog.Debug("failed to probe path mtu", "peer", peer.Identifier, "destination", destination,
+				"error", err)
+			continue
+		}
+		if smallest == nil || pathMtu.Mtu < smallest.Mtu {
+			smallest = pathMtu
+		}
+	}
+	if smallest != nil {
+		return smallest, nil
+	}
+
+	pathMtu, err := m.wg.ProbePathMtu(ctx, netip.Addr{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe default route mtu: %w", err)
+	}
+
+	return pathMtu, nil
+}
+
+// SuggestPeerMtu probes the path to the endpoint of the given peer. If the endpoint of the peer is unknown,
+// the path of the default route is returned.
+func (m Manager) SuggestPeerMtu(ctx context.Context, id domain.PeerIdentifier) (*domain.PathMtu, error) {
+	peer, err := m.db.GetPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find peer %s: %w", id, err)
+	}
+
+	iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
+		return nil, err
+	}
+
+	pathMtu, err := m.wg.ProbePathMtu(ctx, m.getPeerEndpointAddress(ctx, iface, peer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe path mtu of peer %s: %w", id, err)
+	}
+
+	return pathMtu, nil
+}
+
+// getPeerEndpointAddress returns the address that is used to reach the given peer.
+// The endpoint of the last handshake is preferred. For client interfaces, the configured endpoint is used as
+// fallback. If no endpoint is known, an invalid address is returned.
+func (m Manager) getPeerEndpointAddress(ctx context.Context, iface *domain.Interface, peer *domain.Peer) netip.Addr {
+	endpoint := ""
+	if status, err := m.db.GetPeersStats(ctx, peer.Identifier); err == nil && len(status) > 0 {
+		endpoint = status[0].Endpoint
+	}
+	if endpoint == "" && iface.Type == domain.InterfaceTypeClient {
+		endpoint = peer.Endpoint.GetValue()
+	}
+	if endpoint == "" {
+		return netip.Addr{}
+	}
+
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host = endpoint // endpoint without port
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap()
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		slog.Debug("failed to resolve peer endpoint", "peer", peer.Identifier, "endpoint", endpoint, "error", err)
+		return netip.Addr{}
+	}
+
+	return addrs[0].Unmap()
+}
@@ -5,20 +5,32 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/netip"
 	"slices"
+	"strings"
 	"time"
 
+	"github.com/h44z/wg-portal/internal"
 	"github.com/h44z/wg-portal/internal/app"
 	"github.com/h44z/wg-portal/internal/app/audit"
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
-// CreateDefaultPeer creates a default peer for the given user on all server interfaces.
+// CreateDefaultPeer creates a default peer for the given user on all server interfaces of the user's organization.
 func (m Manager) CreateDefaultPeer(ctx context.Context, userId domain.UserIdentifier) error {
 	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
 		return err
 	}
 
+	var organization domain.OrganizationIdentifier
+	user, err := m.db.GetUser(ctx, userId)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("failed to load user %s: %w", userId, err)
+	}
+	if user != nil {
+		organization = user.OrganizationIdentifier
+	}
+
 	existingInterfaces, err := m.db.GetAllInterfaces(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to fetch all interfaces: %w", err)
@@ -34,6 +46,9 @@ func (m Manager) CreateDefaultPeer(ctx context.Context, userId domain.UserIdenti
 		if iface.Type != domain.InterfaceTypeServer {
 			continue // only create default peers for server interfaces
 		}
+		if iface.OrganizationIdentifier != organization {
+			continue // only create default peers for interfaces of the user's organization
+		}
 
 		peerAlreadyCreated := slices.ContainsFunc(userPeers, func(peer domain.Peer) bool {
 			return peer.InterfaceIdentifier == iface.Identifier
@@ -48,6 +63,9 @@ func (m Manager) CreateDefaultPeer(ctx context.Context, userId domain.UserIdenti
 		}
 
 		peer.UserIdentifier = userId
+		if peer.Interface.Addresses, err = m.getFreshPeerIpConfig(ctx, &iface, userId); err != nil {
+			return fmt.Errorf("failed to assign addresses of default peer for interface %s: %w", iface.Identifier, err)
+		}
 		peer.Notes = fmt.Sprintf("Default peer created for user %s", userId)
 		peer.AutomaticallyCreated = true
 		peer.GenerateDisplayName("Default")
@@ -72,33 +90,59 @@ func (m Manager) CreateDefaultPeer(ctx context.Context, userId domain.UserIdenti
 
 // GetUserPeers returns all peers for the given user.
 func (m Manager) GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
-	if err := domain.ValidateUserAccessRights(ctx, id); err != nil {
+	if err := m.validateUserReadAccess(ctx, id); err != nil {
+		return nil, err
+	}
+
+	peers, err := m.getOwnedAndSharedPeers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	peers, err = m.filterOrganizationPeers(ctx, id, peers)
+	if err != nil {
 		return nil, err
 	}
+	hideHelpdeskKeys(ctx, id, peers)
 
-	return m.db.GetUserPeers(ctx, id)
+	return peers, nil
 }
 
 // PreparePeer prepares a new peer for the given interface with fresh keys and ip addresses.
 func (m Manager) PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", id, err)
+	}
+
 	if !m.cfg.Core.SelfProvisioningAllowed {
-		if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
 			return nil, err
 		}
 	}
 
 	currentUser := domain.GetUserInfo(ctx)
 
-	iface, err := m.db.GetInterface(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("unable to find interface %s: %w", id, err)
+	if currentUser.IsInterfaceAdmin(iface) {
+		if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+			return nil, err
+		}
+	} else if iface.OrganizationIdentifier != currentUser.Organization {
+		return nil, fmt.Errorf("self provisioning is only allowed for interfaces of the own organization: %w",
+			domain.ErrNoPermission)
 	}
 
-	if m.cfg.Core.SelfProvisioningAllowed && !currentUser.IsAdmin && iface.Type != domain.InterfaceTypeServer {
+	if m.cfg.Core.SelfProvisioningAllowed && !currentUser.IsInterfaceAdmin(iface) &&
+		iface.Type != domain.InterfaceTypeServer {
 		return nil, fmt.Errorf("self provisioning is only allowed for server interfaces: %w", domain.ErrNoPermission)
 	}
 
-	ips, err := m.getFreshPeerIpConfig(ctx, iface)
+	// admins prepare peers for other users, addresses that are assigned to the owner are applied on creation
+	var addressOwner domain.UserIdentifier
+	if !currentUser.IsInterfaceAdmin(iface) {
+		addressOwner = currentUser.Id
+	}
+	ips, err := m.getFreshPeerIpConfig(ctx, iface, addressOwner)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get fresh ip addresses: %w", err)
 	}
@@ -129,9 +173,12 @@ func (m Manager) PreparePeer(ctx context.Context, id domain.InterfaceIdentifier)
 		Endpoint:            domain.NewConfigOption(iface.PeerDefEndpoint, true),
 		EndpointPublicKey:   domain.NewConfigOption(iface.PublicKey, true),
 		AllowedIPsStr:       domain.NewConfigOption(iface.PeerDefAllowedIPsStr, true),
+		RouteSetsStr:        domain.NewConfigOption(iface.PeerDefRouteSetsStr, true),
 		ExtraAllowedIPsStr:  "",
 		PresharedKey:        pk,
 		PersistentKeepalive: domain.NewConfigOption(iface.PeerDefPersistentKeepalive, true),
+		UploadLimit:         domain.NewConfigOption(iface.PeerDefUploadLimit, true),
+		DownloadLimit:       domain.NewConfigOption(iface.PeerDefDownloadLimit, true),
 		Identifier:          peerId,
 		UserIdentifier:      currentUser.Id,
 		InterfaceIdentifier: iface.Identifier,
@@ -167,21 +214,48 @@ func (m Manager) GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain
 		return nil, fmt.Errorf("unable to find peer %s: %w", id, err)
 	}
 
-	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+	if err := m.validatePeerAccess(ctx, peer); err != nil {
 		return nil, err
 	}
 
 	return peer, nil
 }
 
+// GetPeerPrivateKey returns the private key of the given peer. The reveal is recorded, and if configured, requires
+// a recent re-authentication of the user.
+func (m Manager) GetPeerPrivateKey(ctx context.Context, id domain.PeerIdentifier) (string, error) {
+	peer, err := m.db.GetPeer(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("unable to find peer %s: %w", id, err)
+	}
+
+	if err := m.validatePeerAccess(ctx, peer); err != nil {
+		return "", err
+	}
+	if peer.Interface.PrivateKey == "" {
+		return "", fmt.Errorf("the private key of peer %s is unknown: %w", id, domain.ErrNotFound)
+	}
+	if err := m.reveal.ValidateReveal(ctx, peer, domain.PeerPrivateKeyFormat); err != nil {
+		return "", err
+	}
+
+	return peer.Interface.PrivateKey, nil
+}
+
 // CreatePeer creates a new peer.
 func (m Manager) CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+
 	if !m.cfg.Core.SelfProvisioningAllowed {
-		if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
 			return nil, err
 		}
 	} else {
-		if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+		if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier, iface.OrganizationIdentifier,
+			iface.Identifier); err != nil {
 			return nil, err
 		}
 	}
@@ -197,7 +271,15 @@ func (m Manager) CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Pee
 	}
 
 	// if a peer is self provisioned, ensure that only allowed fields are set from the request
-	if !sessionUser.IsAdmin {
+	if sessionUser.IsInterfaceAdmin(iface) {
+		if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := m.aup.ValidateAcceptance(ctx); err != nil {
+			return nil, fmt.Errorf("creation not allowed: %w", err)
+		}
+
 		preparedPeer, err := m.PreparePeer(ctx, peer.InterfaceIdentifier)
 		if err != nil {
 			return nil, fmt.Errorf("failed to prepare peer for interface %s: %w", peer.InterfaceIdentifier, err)
@@ -208,10 +290,27 @@ func (m Manager) CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Pee
 		peer = preparedPeer
 	}
 
+	if err := m.applyUserGroupProfile(ctx, peer); err != nil {
+		return nil, err
+	}
+	m.applyDeviceTypePolicy(peer)
+
 	if err := m.validatePeerCreation(ctx, existingPeer, peer); err != nil {
 		return nil, fmt.Errorf("creation not allowed: %w", err)
 	}
 
+	if err := m.validatePeerQuota(ctx, peer); err != nil {
+		return nil, fmt.Errorf("creation not allowed: %w", err)
+	}
+
+	if err := m.validateAllowedIPConflicts(ctx, nil, peer); err != nil {
+		return nil, fmt.Errorf("creation not allowed: %w", err)
+	}
+
+	if err := m.validateAddressReservations(ctx, nil, peer); err != nil {
+		return nil, fmt.Errorf("creation not allowed: %w", err)
+	}
+
 	err = m.savePeers(ctx, peer)
 	if err != nil {
 		return nil, fmt.Errorf("creation failure: %w", err)
@@ -229,7 +328,11 @@ func (m Manager) CreateMultiplePeers(
 	interfaceId domain.InterfaceIdentifier,
 	r *domain.PeerCreationRequest,
 ) ([]domain.Peer, error) {
-	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+	iface, err := m.db.GetInterface(ctx, interfaceId)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", interfaceId, err)
+	}
+	if err := domain.ValidateInterfaceAdminAccessRights(ctx, iface); err != nil {
 		return nil, err
 	}
 
@@ -242,10 +345,17 @@ func (m Manager) CreateMultiplePeers(
 		}
 
 		freshPeer.UserIdentifier = domain.UserIdentifier(id) // use id as user identifier. peers are allowed to have invalid user identifiers
+		if freshPeer.Interface.Addresses, err = m.getFreshPeerIpConfig(ctx, iface, freshPeer.UserIdentifier); err != nil {
+			return nil, fmt.Errorf("failed to assign addresses for user %s: %w", id, err)
+		}
 		if r.Suffix != "" {
 			freshPeer.DisplayName += " " + r.Suffix
 		}
 
+		if err := m.applyUserGroupProfile(ctx, freshPeer); err != nil {
+			return nil, err
+		}
+
 		if err := m.validatePeerCreation(ctx, nil, freshPeer); err != nil {
 			return nil, fmt.Errorf("creation not allowed: %w", err)
 		}
@@ -253,7 +363,11 @@ func (m Manager) CreateMultiplePeers(
 		newPeers = append(newPeers, freshPeer)
 	}
 
-	err := m.savePeers(ctx, newPeers...)
+	if err := m.validatePeerQuota(ctx, newPeers...); err != nil {
+		return nil, fmt.Errorf("creation not allowed: %w", err)
+	}
+
+	err = m.savePeers(ctx, newPeers...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new peers: %w", err)
 	}
@@ -268,6 +382,79 @@ func (m Manager) CreateMultiplePeers(
 	return createdPeers, nil
 }
 
+// applyUserGroupProfile attaches the route sets of the user groups of the peer owner to the new peer.
+func (m Manager) applyUserGroupProfile(ctx context.Context, peer *domain.Peer) error {
+	if peer.UserIdentifier == "" {
+		return nil
+	}
+
+	groups, err := m.db.GetAllUserGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load user groups: %w", err)
+	}
+	peer.ApplyUserGroups(domain.GroupsOfUser(groups, peer.UserIdentifier))
+
+	return nil
+}
+
+// applyDeviceTypePolicy removes the expiry date of peers whose device type never expires. Ephemeral peers always
+// keep their expiry date.
+func (m Manager) applyDeviceTypePolicy(peer *domain.Peer) {
+	if peer.ExpiresAt == nil || peer.Ephemeral || peer.DeviceType == domain.PeerDeviceTypeUnknown {
+		return
+	}
+	if slices.Contains(m.cfg.DeviceTypes.NeverExpire, string(peer.DeviceType)) {
+		peer.ExpiresAt = nil
+	}
+}
+
+// applyUserRoutes applies the routes that a user requested for their own peer. Routes that are neither routed to the
+// peer already nor covered by the route allowlist of the interface are not applied. Instead, the current routes are
+// kept and a route review is returned, which must be approved by an interface administrator.
+func (m Manager) applyUserRoutes(ctx context.Context, peer *domain.Peer, routesStr string) (*domain.RouteReview, error) {
+	requested, err := domain.CidrsFromArray(internal.SliceString(routesStr))
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("invalid route: %w", err), domain.ErrInvalidData)
+	}
+	for i := range requested {
+		requested[i] = requested[i].NetworkAddr()
+	}
+	routesStr = domain.CidrsToString(requested)
+
+	current, _ := domain.CidrsFromArray(internal.SliceString(peer.ExtraAllowedIPsStr))
+	if routesStr == domain.CidrsToString(current) {
+		return nil, nil // routes are unchanged
+	}
+
+	iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+
+	unreviewed := domain.UnreviewedRoutes(requested, current, iface.PeerRouteAllowlist())
+	if len(unreviewed) == 0 {
+		peer.ExtraAllowedIPsStr = routesStr
+		return nil, nil
+	}
+
+	userId := domain.GetUserInfo(ctx).Id
+	slog.Info("routes of peer require review", "peer", peer.Identifier, "user", userId,
+		"routes", domain.CidrsToString(unreviewed))
+
+	return &domain.RouteReview{
+		PeerId:              peer.Identifier,
+		PeerName:            peer.DisplayName,
+		InterfaceIdentifier: peer.InterfaceIdentifier,
+		UserIdentifier:      peer.UserIdentifier,
+		RoutesStr:           routesStr,
+		PreviousRoutesStr:   peer.ExtraAllowedIPsStr,
+		UnreviewedStr:       domain.CidrsToString(unreviewed),
+		State:               domain.RouteReviewPending,
+		RequestedBy:         userId,
+		RequestedAt:         time.Now(),
+	}, nil
+}
+
 // UpdatePeer updates the given peer.
 func (m Manager) UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error) {
 	existingPeer, err := m.db.GetPeer(ctx, peer.Identifier)
@@ -275,26 +462,61 @@ func (m Manager) UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Pee
 		return nil, fmt.Errorf("unable to load existing peer %s: %w", peer.Identifier, err)
 	}
 
-	if err := domain.ValidateUserAccessRights(ctx, existingPeer.UserIdentifier); err != nil {
+	if err := m.validatePeerOwnerAccess(ctx, existingPeer); err != nil {
 		return nil, err
 	}
+	if peer.InterfaceIdentifier != existingPeer.InterfaceIdentifier {
+		// moving a peer requires admin rights for the target interface
+		if err := m.validateInterfaceAdmin(ctx, peer.InterfaceIdentifier); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := m.validatePeerModifications(ctx, existingPeer, peer); err != nil {
 		return nil, fmt.Errorf("update not allowed: %w", err)
 	}
 
-	sessionUser := domain.GetUserInfo(ctx)
-
 	// if a peer is self provisioned, ensure that only allowed fields are set from the request
-	if !sessionUser.IsAdmin {
+	var review *domain.RouteReview
+	if !m.isInterfaceAdmin(ctx, existingPeer.InterfaceIdentifier) {
 		originalPeer, err := m.db.GetPeer(ctx, peer.Identifier)
 		if err != nil {
 			return nil, fmt.Errorf("unable to load existing peer %s: %w", peer.Identifier, err)
 		}
 		originalPeer.OverwriteUserEditableFields(peer, m.cfg)
+		if m.cfg.RouteReview.Enabled {
+			review, err = m.applyUserRoutes(ctx, originalPeer, peer.ExtraAllowedIPsStr)
+			if err != nil {
+				return nil, err
+			}
+		}
 
 		peer = originalPeer
 	}
+	m.applyDeviceTypePolicy(peer)
+
+	// hidden private keys are not sent back by the client, the stored key is kept unless the key pair was changed
+	if m.cfg.KeyReveal.StepUpRequired && peer.Interface.PrivateKey == "" &&
+		peer.Interface.PublicKey == existingPeer.Interface.PublicKey {
+		peer.Interface.PrivateKey = existingPeer.Interface.PrivateKey
+	}
+	// the time of the last key rotation is only set by RegeneratePeerKeys
+	if peer.KeysRotatedAt == nil {
+		peer.KeysRotatedAt = existingPeer.KeysRotatedAt
+	}
+	// ephemeral peers keep the expiry date they were issued with
+	if existingPeer.Ephemeral {
+		peer.Ephemeral = true
+		peer.ExpiresAt = existingPeer.ExpiresAt
+	}
+
+	if err := m.validateAllowedIPConflicts(ctx, existingPeer, peer); err != nil {
+		return nil, fmt.Errorf("update not allowed: %w", err)
+	}
+
+	if err := m.validateAddressReservations(ctx, existingPeer, peer); err != nil {
+		return nil, fmt.Errorf("update not allowed: %w", err)
+	}
 
 	// handle peer identifier change (new public key)
 	if existingPeer.Identifier != domain.PeerIdentifier(peer.Interface.PublicKey) {
@@ -333,10 +555,65 @@ func (m Manager) UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Pee
 	}
 
 	m.bus.Publish(app.TopicPeerUpdated, *peer)
+	if existingPeer.IsDisabled() && !peer.IsDisabled() {
+		m.bus.Publish(app.TopicPeerEnabled, *peer, domain.GetUserInfo(ctx).Id)
+	}
+	if review != nil {
+		review.PeerId = peer.Identifier // the identifier changes with a new public key
+		m.bus.Publish(app.TopicPeerRouteReviewRequested, *review)
+	}
 
 	return peer, nil
 }
 
+// RegeneratePeerKeys generates a new key pair for the given peer, and a new pre-shared key if the peer uses one.
+// The peer is re-identified by its new public key. Users can regenerate the keys of their own enabled peers if the
+// self-service key rotation is enabled, but only once per configured cooldown.
+func (m Manager) RegeneratePeerKeys(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, err := m.db.GetPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load peer %s: %w", id, err)
+	}
+
+	if err := m.validatePeerOwnerAccess(ctx, peer); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessionUser := domain.GetUserInfo(ctx)
+	if !m.isInterfaceAdmin(ctx, peer.InterfaceIdentifier) {
+		if !m.cfg.KeyRotation.SelfService {
+			return nil, fmt.Errorf("self-service key rotation is disabled: %w", domain.ErrNoPermission)
+		}
+		if peer.IsDisabled() {
+			return nil, errors.Join(fmt.Errorf("peer %s is disabled", id), domain.ErrInvalidData)
+		}
+		if next := peer.NextKeyRotation(m.cfg.KeyRotation.Cooldown); now.Before(next) {
+			return nil, fmt.Errorf("keys of peer %s can be regenerated again after %s: %w",
+				id, next.Format(time.RFC3339), domain.ErrQuotaExceeded)
+		}
+	}
+
+	if err := peer.RotateKeys(); err != nil {
+		return nil, fmt.Errorf("failed to generate new keys for peer %s: %w", id, err)
+	}
+	peer.KeysRotatedAt = &now
+
+	// users may regenerate their keys even if they are not allowed to edit them
+	systemCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+	updatedPeer, err := m.UpdatePeer(systemCtx, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("peer keys regenerated",
+		"peer", id,
+		"newPeer", updatedPeer.Identifier,
+		"user", sessionUser.Id)
+
+	return updatedPeer, nil
+}
+
 // DeletePeer deletes the peer with the given identifier.
 func (m Manager) DeletePeer(ctx context.Context, id domain.PeerIdentifier) error {
 	peer, err := m.db.GetPeer(ctx, id)
@@ -344,7 +621,7 @@ func (m Manager) DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
 		return fmt.Errorf("unable to find peer %s: %w", id, err)
 	}
 
-	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+	if err := m.validatePeerOwnerAccess(ctx, peer); err != nil {
 		return err
 	}
 
@@ -373,14 +650,18 @@ func (m Manager) DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
 
 // GetPeerStats returns the status of the peer with the given identifier.
 func (m Manager) GetPeerStats(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.PeerStatus, error) {
-	_, peers, err := m.db.GetInterfaceAndPeers(ctx, id)
+	iface, peers, err := m.db.GetInterfaceAndPeers(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch peers for interface %s: %w", id, err)
 	}
+	if err := domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier); err != nil {
+		return nil, err
+	}
 
 	peerIds := make([]domain.PeerIdentifier, len(peers))
 	for i, peer := range peers {
-		if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+		if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier, iface.OrganizationIdentifier,
+			peer.InterfaceIdentifier); err != nil {
 			return nil, err
 		}
 
@@ -392,14 +673,18 @@ func (m Manager) GetPeerStats(ctx context.Context, id domain.InterfaceIdentifier
 
 // GetUserPeerStats returns the status of all peers for the given user.
 func (m Manager) GetUserPeerStats(ctx context.Context, id domain.UserIdentifier) ([]domain.PeerStatus, error) {
-	if err := domain.ValidateUserAccessRights(ctx, id); err != nil {
+	if err := m.validateUserReadAccess(ctx, id); err != nil {
 		return nil, err
 	}
 
-	peers, err := m.db.GetUserPeers(ctx, id)
+	peers, err := m.getOwnedAndSharedPeers(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch peers for user %s: %w", id, err)
 	}
+	peers, err = m.filterOrganizationPeers(ctx, id, peers)
+	if err != nil {
+		return nil, err
+	}
 
 	peerIds := make([]domain.PeerIdentifier, len(peers))
 	for i, peer := range peers {
@@ -412,8 +697,85 @@ func (m Manager) GetUserPeerStats(ctx context.Context, id domain.UserIdentifier)
 // region helper-functions
 
 func (m Manager) savePeers(ctx context.Context, peers ...*domain.Peer) error {
+	snapshot, err := m.snapshotPeers(ctx, peers)
+	if err != nil {
+		return err
+	}
+
 	interfaces := make(map[domain.InterfaceIdentifier]struct{})
+	for _, peer := range peers {
+		interfaces[peer.InterfaceIdentifier] = struct{}{}
+	}
+
+	// the whole set is applied with a single configuration call per interface. If a batch fails, the peers are applied
+	// one by one to find the failing peer and to roll back the applied changes.
+	if err := m.applyPeerBatches(ctx, newPeerBatches(peers)); err != nil {
+		slog.Warn("failed to apply peer batch, applying peers one by one", "peers", len(peers), "error", err)
+		if err := m.restorePhysicalPeers(ctx, snapshot, peers); err != nil {
+			slog.Error("failed to restore peers after failed batch", "error", err)
+		}
+		if err := m.savePeersOneByOne(ctx, snapshot, peers); err != nil {
+			return err
+		}
+	} else if err := m.storePeers(ctx, snapshot, peers); err != nil {
+		return err
+	}
+
+	// publish events only after the whole set has been applied, a rolled back set did not change anything
+
+	for _, peer := range peers {
+		m.bus.Publish(app.TopicAuditPeerChanged, domain.AuditEventWrapper[audit.PeerEvent]{
+			Ctx: ctx,
+			Event: audit.PeerEvent{
+				Action: "save",
+				Peer:   *peer,
+			},
+		})
+	}
+
+	// Update routes after peers have changed
+	if len(interfaces) != 0 {
+		m.bus.Publish(app.TopicRouteUpdate, "peers updated")
+	}
 
+	for iface := range interfaces {
+		m.bus.Publish(app.TopicPeerInterfaceUpdated, iface)
+	}
+
+	return nil
+}
+
+// storePeers stores the given peers, which were already applied to their devices, in the database.
+func (m Manager) storePeers(
+	ctx context.Context,
+	snapshot map[domain.PeerIdentifier]peerSnapshot,
+	peers []*domain.Peer,
+) error {
+	for i := range peers {
+		peer := peers[i]
+		err := m.db.SavePeer(ctx, peer.Identifier, func(p *domain.Peer) (*domain.Peer, error) {
+			peer.CopyCalculatedAttributes(p)
+			return peer, nil
+		})
+		if err != nil {
+			// the remaining peers are not stored, so only their device state has to be restored
+			if err := m.restorePhysicalPeers(ctx, snapshot, peers[i+1:]); err != nil {
+				slog.Error("failed to restore unsaved peers", "error", err)
+			}
+			return m.rollbackPeers(ctx, snapshot, peers[:i], peer, err)
+		}
+	}
+
+	return nil
+}
+
+// savePeersOneByOne applies and stores the given peers one after another. If a peer fails, all peers of the set are
+// rolled back.
+func (m Manager) savePeersOneByOne(
+	ctx context.Context,
+	snapshot map[domain.PeerIdentifier]peerSnapshot,
+	peers []*domain.Peer,
+) error {
 	for i := range peers {
 		peer := peers[i]
 		var err error
@@ -444,35 +806,21 @@ func (m Manager) savePeers(ctx context.Context, peers ...*domain.Peer) error {
 			})
 		}
 		if err != nil {
-			return fmt.Errorf("save failure for peer %s: %w", peer.Identifier, err)
+			return m.rollbackPeers(ctx, snapshot, peers[:i], peer, err)
 		}
-
-		// publish event
-
-		m.bus.Publish(app.TopicAuditPeerChanged, domain.AuditEventWrapper[audit.PeerEvent]{
-			Ctx: ctx,
-			Event: audit.PeerEvent{
-				Action: "save",
-				Peer:   *peer,
-			},
-		})
-
-		interfaces[peer.InterfaceIdentifier] = struct{}{}
-	}
-
-	// Update routes after peers have changed
-	if len(interfaces) != 0 {
-		m.bus.Publish(app.TopicRouteUpdate, "peers updated")
-	}
-
-	for iface := range interfaces {
-		m.bus.Publish(app.TopicPeerInterfaceUpdated, iface)
 	}
 
 	return nil
 }
 
-func (m Manager) getFreshPeerIpConfig(ctx context.Context, iface *domain.Interface) (ips []domain.Cidr, err error) {
+// getFreshPeerIpConfig returns one unused address of each peer network of the interface. Reserved and excluded
+// addresses are skipped. If an unused address of the network is statically assigned to the given user, it is returned
+// instead.
+func (m Manager) getFreshPeerIpConfig(
+	ctx context.Context,
+	iface *domain.Interface,
+	userId domain.UserIdentifier,
+) (ips []domain.Cidr, err error) {
 	if iface.PeerDefNetworkStr == "" {
 		return []domain.Cidr{}, nil // cannot suggest new ip addresses if there is no subnet
 	}
@@ -489,7 +837,18 @@ func (m Manager) getFreshPeerIpConfig(ctx context.Context, iface *domain.Interfa
 		return
 	}
 
+	reservations, err := m.db.GetAddressReservations(ctx, iface.Identifier)
+	if err != nil {
+		err = fmt.Errorf("failed to get address reservations: %w", err)
+		return
+	}
+
 	for _, network := range networks {
+		if ip, ok := assignedAddress(network, existingIps[network], reservations, userId); ok {
+			ips = append(ips, ip)
+			continue
+		}
+
 		ip := network.NextAddr()
 
 		for {
@@ -500,6 +859,12 @@ func (m Manager) getFreshPeerIpConfig(ctx context.Context, iface *domain.Interfa
 					break
 				}
 			}
+			for _, reservation := range reservations {
+				if reservation.Addr() == ip.Prefix().Addr() {
+					ipConflict = true
+					break
+				}
+			}
 
 			if !ipConflict {
 				break
@@ -518,27 +883,110 @@ func (m Manager) getFreshPeerIpConfig(ctx context.Context, iface *domain.Interfa
 	return
 }
 
-func (m Manager) validatePeerModifications(ctx context.Context, _, _ *domain.Peer) error {
-	currentUser := domain.GetUserInfo(ctx)
+// assignedAddress returns the first unused address of the network that is statically assigned to the given user.
+func assignedAddress(
+	network domain.Cidr,
+	usedIps []domain.Cidr,
+	reservations []domain.AddressReservation,
+	userId domain.UserIdentifier,
+) (domain.Cidr, bool) {
+	for _, reservation := range reservations {
+		addr := reservation.Addr()
+		if !reservation.IsAssignedTo(userId) || !network.Prefix().Contains(addr) {
+			continue
+		}
+		if slices.ContainsFunc(usedIps, func(usedIp domain.Cidr) bool { return usedIp.Prefix().Addr() == addr }) {
+			continue // the address is already in use, for example by an earlier peer of the user
+		}
 
-	if !currentUser.IsAdmin && !m.cfg.Core.SelfProvisioningAllowed {
+		return domain.CidrFromPrefix(netip.PrefixFrom(addr, addr.BitLen())), true
+	}
+
+	return domain.Cidr{}, false
+}
+
+func (m Manager) validatePeerModifications(ctx context.Context, old, new *domain.Peer) error {
+	isInterfaceAdmin := m.isInterfaceAdmin(ctx, old.InterfaceIdentifier)
+
+	if !isInterfaceAdmin && !m.cfg.Core.SelfProvisioningAllowed {
 		return domain.ErrNoPermission
 	}
 
+	if !isInterfaceAdmin && old.IsDisabled() && !new.IsDisabled() &&
+		old.DisabledReason == domain.DisabledReasonEmergency {
+		return fmt.Errorf("peer is disabled by an emergency lockdown: %w", domain.ErrNoPermission)
+	}
+
+	if !isInterfaceAdmin && old.IsDisabled() && !new.IsDisabled() &&
+		old.DisabledReason == domain.DisabledReasonRoaming {
+		return fmt.Errorf("peer is disabled by the roaming policy: %w", domain.ErrNoPermission)
+	}
+
+	if !isInterfaceAdmin && old.IsDisabled() && !new.IsDisabled() &&
+		old.DisabledReason == domain.DisabledReasonCompromised {
+		return fmt.Errorf("peer is marked as compromised: %w", domain.ErrNoPermission)
+	}
+
+	if !new.Interface.KillSwitch.IsValid() {
+		return fmt.Errorf("invalid kill-switch mode %s: %w", new.Interface.KillSwitch, domain.ErrInvalidData)
+	}
+
+	if !new.OperatingSystem.IsValid() {
+		return fmt.Errorf("invalid operating system %s: %w", new.OperatingSystem, domain.ErrInvalidData)
+	}
+
+	if !new.DeviceType.IsValid() {
+		return fmt.Errorf("invalid device type %s: %w", new.DeviceType, domain.ErrInvalidData)
+	}
+
+	if err := new.ValidateAccessSchedule(); err != nil {
+		return err
+	}
+
+	if err := new.ValidateEndpointPin(); err != nil {
+		return err
+	}
+
+	if err := m.validateSharedUsers(ctx, new); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (m Manager) validatePeerCreation(ctx context.Context, _, new *domain.Peer) error {
-	currentUser := domain.GetUserInfo(ctx)
-
 	if new.Identifier == "" {
 		return fmt.Errorf("invalid peer identifier: %w", domain.ErrInvalidData)
 	}
 
-	if !currentUser.IsAdmin && !m.cfg.Core.SelfProvisioningAllowed {
+	if !m.isInterfaceAdmin(ctx, new.InterfaceIdentifier) && !m.cfg.Core.SelfProvisioningAllowed {
 		return domain.ErrNoPermission
 	}
 
+	if !new.Interface.KillSwitch.IsValid() {
+		return fmt.Errorf("invalid kill-switch mode %s: %w", new.Interface.KillSwitch, domain.ErrInvalidData)
+	}
+
+	if !new.OperatingSystem.IsValid() {
+		return fmt.Errorf("invalid operating system %s: %w", new.OperatingSystem, domain.ErrInvalidData)
+	}
+
+	if !new.DeviceType.IsValid() {
+		return fmt.Errorf("invalid device type %s: %w", new.DeviceType, domain.ErrInvalidData)
+	}
+
+	if err := new.ValidateAccessSchedule(); err != nil {
+		return err
+	}
+
+	if err := new.ValidateEndpointPin(); err != nil {
+		return err
+	}
+
+	if err := m.validateSharedUsers(ctx, new); err != nil {
+		return err
+	}
+
 	_, err := m.db.GetInterface(ctx, new.InterfaceIdentifier)
 	if err != nil {
 		return fmt.Errorf("invalid interface: %w", domain.ErrInvalidData)
@@ -547,14 +995,270 @@ func (m Manager) validatePeerCreation(ctx context.Context, _, new *domain.Peer)
 	return nil
 }
 
-func (m Manager) validatePeerDeletion(ctx context.Context, _ *domain.Peer) error {
-	currentUser := domain.GetUserInfo(ctx)
+// validatePeerQuota checks that the creation of the given peers does not exceed the configured peer limits.
+func (m Manager) validatePeerQuota(ctx context.Context, newPeers ...*domain.Peer) error {
+	quota := m.cfg.PeerQuota
+	if quota.MaxPeersPerUser <= 0 && quota.MaxPeersPerInterface <= 0 && quota.MaxPeersTotal <= 0 {
+		return nil // no limits configured
+	}
+
+	var limitedPeers []*domain.Peer
+	for _, peer := range newPeers {
+		if quota.AdminOverride && m.isInterfaceAdmin(ctx, peer.InterfaceIdentifier) {
+			continue // administrators are allowed to exceed the limits
+		}
+		limitedPeers = append(limitedPeers, peer)
+	}
+	if len(limitedPeers) == 0 {
+		return nil
+	}
+
+	if quota.MaxPeersTotal > 0 {
+		count, err := m.db.GetPeerCount(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to count peers: %w", err)
+		}
+		if count+len(limitedPeers) > quota.MaxPeersTotal {
+			return fmt.Errorf("the maximum number of %d peers is reached: %w",
+				quota.MaxPeersTotal, domain.ErrQuotaExceeded)
+		}
+	}
 
-	if !currentUser.IsAdmin && !m.cfg.Core.SelfProvisioningAllowed {
+	if quota.MaxPeersPerInterface > 0 {
+		newInterfacePeers := make(map[domain.InterfaceIdentifier]int)
+		for _, peer := range limitedPeers {
+			newInterfacePeers[peer.InterfaceIdentifier]++
+		}
+		for id, count := range newInterfacePeers {
+			existingPeers, err := m.db.GetInterfacePeers(ctx, id)
+			if err != nil {
+				return fmt.Errorf("failed to load peers of interface %s: %w", id, err)
+			}
+			if len(existingPeers)+count > quota.MaxPeersPerInterface {
+				return fmt.Errorf("interface %s reached the maximum number of %d peers: %w",
+					id, quota.MaxPeersPerInterface, domain.ErrQuotaExceeded)
+			}
+		}
+	}
+
+	if quota.MaxPeersPerUser > 0 {
+		newUserPeers := make(map[domain.UserIdentifier]int)
+		for _, peer := range limitedPeers {
+			if peer.UserIdentifier == "" {
+				continue // peers without a user are only limited by the interface and total limits
+			}
+			newUserPeers[peer.UserIdentifier]++
+		}
+		for id, count := range newUserPeers {
+			existingPeers, err := m.db.GetUserPeers(ctx, id)
+			if err != nil {
+				return fmt.Errorf("failed to load peers of user %s: %w", id, err)
+			}
+			if len(existingPeers)+count > quota.MaxPeersPerUser {
+				return fmt.Errorf("user %s reached the maximum number of %d peers: %w",
+					id, quota.MaxPeersPerUser, domain.ErrQuotaExceeded)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateAllowedIPConflicts checks that the allowed IPs of the new or updated peer do not overlap with the allowed
+// IPs of other peers of the interface. Conflicts that the peer already had before the update are tolerated, so peers
+// with existing conflicts can still be edited.
+func (m Manager) validateAllowedIPConflicts(ctx context.Context, old, new *domain.Peer) error {
+	if !m.cfg.AllowedIPConflicts.RejectOnSave {
+		return nil
+	}
+
+	others, err := m.db.GetInterfacePeers(ctx, new.InterfaceIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to load peers of interface %s: %w", new.InterfaceIdentifier, err)
+	}
+	if old != nil { // the stored peer is replaced, even if the identifier changes
+		others = slices.DeleteFunc(others, func(p domain.Peer) bool { return p.Identifier == old.Identifier })
+	}
+
+	conflicts := domain.PeerAllowedIPConflicts(new, others)
+	if old != nil && len(conflicts) > 0 {
+		existing := domain.PeerAllowedIPConflicts(old, others)
+		conflicts = slices.DeleteFunc(conflicts, func(c domain.AllowedIPConflict) bool {
+			return slices.ContainsFunc(existing, c.SameOverlap)
+		})
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, len(conflicts))
+	for i, conflict := range conflicts {
+		descriptions[i] = conflict.String()
+	}
+
+	return fmt.Errorf("allowed IPs overlap with other peers: %s: %w", strings.Join(descriptions, "; "),
+		domain.ErrInvalidData)
+}
+
+// validateAddressReservations checks that the new or updated peer does not use excluded addresses or addresses that
+// are assigned to other users. Addresses that the peer already used before the update are tolerated.
+func (m Manager) validateAddressReservations(ctx context.Context, old, new *domain.Peer) error {
+	reservations, err := m.db.GetAddressReservations(ctx, new.InterfaceIdentifier)
+	if err != nil {
+		return fmt.Errorf("failed to load address reservations of interface %s: %w", new.InterfaceIdentifier, err)
+	}
+
+	var existing []domain.Cidr
+	if old != nil {
+		existing = old.Interface.Addresses
+	}
+
+	violations := domain.ReservationViolations(new, reservations, existing)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("peer addresses are reserved: %s: %w", strings.Join(violations, "; "), domain.ErrInvalidData)
+}
+
+func (m Manager) validatePeerDeletion(ctx context.Context, del *domain.Peer) error {
+	if !m.isInterfaceAdmin(ctx, del.InterfaceIdentifier) && !m.cfg.Core.SelfProvisioningAllowed {
 		return domain.ErrNoPermission
 	}
 
 	return nil
 }
 
+// validatePeerAccess checks if the current user may use the given peer, see domain.ValidatePeerAccessRights.
+func (m Manager) validatePeerAccess(ctx context.Context, peer *domain.Peer) error {
+	iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return fmt.Errorf("unable to find interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+
+	if err := domain.ValidatePeerAccessRights(ctx, peer, iface); err != nil {
+		return err
+	}
+
+	return validatePeerOrganization(ctx, peer, iface)
+}
+
+// validatePeerOwnerAccess checks if the current user may manage the given peer. Unlike validatePeerAccess, users the
+// peer is only shared with are not allowed.
+func (m Manager) validatePeerOwnerAccess(ctx context.Context, peer *domain.Peer) error {
+	iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return fmt.Errorf("unable to find interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+
+	err = domain.ValidateUserAccessRights(ctx, peer.UserIdentifier, iface.OrganizationIdentifier, iface.Identifier)
+	if err != nil {
+		return err
+	}
+
+	return validatePeerOrganization(ctx, peer, iface)
+}
+
+// validatePeerOrganization checks if the current user is allowed to access the given peer of the given interface.
+// Users can always access their own peers, all other access is restricted to the organization of the interface.
+func validatePeerOrganization(ctx context.Context, peer *domain.Peer, iface *domain.Interface) error {
+	if domain.GetUserInfo(ctx).Id == peer.UserIdentifier {
+		return nil
+	}
+
+	return domain.ValidateOrganizationAccessRights(ctx, iface.OrganizationIdentifier)
+}
+
+// validateUserReadAccess checks if the current user may view the peers of the given user, see
+// domain.ValidateUserReadAccessRights.
+func (m Manager) validateUserReadAccess(ctx context.Context, id domain.UserIdentifier) error {
+	var org domain.OrganizationIdentifier
+	if currentUser := domain.GetUserInfo(ctx); currentUser.Organization != "" && currentUser.Id != id {
+		user, err := m.db.GetUser(ctx, id)
+		if err != nil {
+			return fmt.Errorf("unable to find user %s: %w", id, err)
+		}
+		org = user.OrganizationIdentifier
+	}
+
+	return domain.ValidateUserReadAccessRights(ctx, id, org)
+}
+
+// filterOrganizationPeers removes all peers of interfaces that belong to other organizations.
+// The own peers of the current user are never removed.
+// validateSharedUsers ensures that a shared peer has an owner and that all shared users exist.
+func (m Manager) validateSharedUsers(ctx context.Context, peer *domain.Peer) error {
+	sharedUsers := peer.SharedUsers()
+	if len(sharedUsers) == 0 {
+		return nil
+	}
+	if peer.UserIdentifier == "" {
+		return fmt.Errorf("a shared peer requires an owner: %w", domain.ErrInvalidData)
+	}
+
+	for _, id := range sharedUsers {
+		if _, err := m.db.GetUser(ctx, id); err != nil {
+			return fmt.Errorf("invalid shared user %s: %w", id, domain.ErrInvalidData)
+		}
+	}
+
+	return nil
+}
+
+// getOwnedAndSharedPeers returns the peers owned by the given user, followed by the peers shared with the user.
+func (m Manager) getOwnedAndSharedPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
+	peers, err := m.db.GetUserPeers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sharedPeers, err := m.db.GetSharedUserPeers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(peers, sharedPeers...), nil
+}
+
+func (m Manager) filterOrganizationPeers(ctx context.Context, userId domain.UserIdentifier, peers []domain.Peer) (
+	[]domain.Peer,
+	error,
+) {
+	currentUser := domain.GetUserInfo(ctx)
+	if currentUser.Organization == "" || currentUser.Id == userId {
+		return peers, nil
+	}
+
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load interfaces: %w", err)
+	}
+	accessible := make(map[domain.InterfaceIdentifier]bool, len(interfaces))
+	for _, iface := range interfaces {
+		accessible[iface.Identifier] = currentUser.CanAccessOrganization(iface.OrganizationIdentifier)
+	}
+
+	filtered := make([]domain.Peer, 0, len(peers))
+	for _, peer := range peers {
+		if accessible[peer.InterfaceIdentifier] {
+			filtered = append(filtered, peer)
+		}
+	}
+
+	return filtered, nil
+}
+
+// hideHelpdeskKeys removes the private and preshared keys from the peers of the given user if the current user only
+// views them with helpdesk rights.
+func hideHelpdeskKeys(ctx context.Context, userId domain.UserIdentifier, peers []domain.Peer) {
+	currentUser := domain.GetUserInfo(ctx)
+	if currentUser.IsAdmin || currentUser.Id == userId {
+		return
+	}
+
+	for i := range peers {
+		peers[i].Interface.PrivateKey = ""
+		peers[i].PresharedKey = ""
+	}
+}
+
 // endregion helper-functions
@@ -0,0 +1,378 @@
+package wireguard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// quotaDatabase implements the peer lookups required for the quota checks, all other methods are not implemented.
+type quotaDatabase struct {
+	InterfaceAndPeerDatabaseRepo
+
+	peers []domain.Peer
+}
+
+func (f quotaDatabase) GetPeerCount(_ context.Context) (int, error) {
+	return len(f.peers), nil
+}
+
+func (f quotaDatabase) GetInterfacePeers(_ context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	var peers []domain.Peer
+	for _, peer := range f.peers {
+		if peer.InterfaceIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+func (f quotaDatabase) GetUserPeers(_ context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
+	var peers []domain.Peer
+	for _, peer := range f.peers {
+		if peer.UserIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+func TestManager_validatePeerQuota(t *testing.T) {
+	db := quotaDatabase{peers: []domain.Peer{
+		{Identifier: "a1", InterfaceIdentifier: "wg0", UserIdentifier: "alice"},
+		{Identifier: "b1", InterfaceIdentifier: "wg0", UserIdentifier: "bob"},
+		{Identifier: "b2", InterfaceIdentifier: "wg1", UserIdentifier: "bob"},
+	}}
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	adminCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+
+	tests := []struct {
+		name    string
+		quota   config.PeerQuotaConfig
+		ctx     context.Context
+		peers   []*domain.Peer
+		wantErr bool
+	}{
+		{
+			name:  "no limits",
+			ctx:   userCtx,
+			peers: []*domain.Peer{{InterfaceIdentifier: "wg0", UserIdentifier: "alice"}},
+		},
+		{
+			name:  "user limit not reached",
+			quota: config.PeerQuotaConfig{MaxPeersPerUser: 2},
+			ctx:   userCtx,
+			peers: []*domain.Peer{{InterfaceIdentifier: "wg1", UserIdentifier: "alice"}},
+		},
+		{
+			name:    "user limit reached",
+			quota:   config.PeerQuotaConfig{MaxPeersPerUser: 2},
+			ctx:     userCtx,
+			peers:   []*domain.Peer{{InterfaceIdentifier: "wg1", UserIdentifier: "bob"}},
+			wantErr: true,
+		},
+		{
+			name:  "user limit reached in batch",
+			quota: config.PeerQuotaConfig{MaxPeersPerUser: 2},
+			ctx:   userCtx,
+			peers: []*domain.Peer{
+				{InterfaceIdentifier: "wg1", UserIdentifier: "alice"},
+				{InterfaceIdentifier: "wg1", UserIdentifier: "alice"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "interface limit reached",
+			quota:   config.PeerQuotaConfig{MaxPeersPerInterface: 2},
+			ctx:     userCtx,
+			peers:   []*domain.Peer{{InterfaceIdentifier: "wg0", UserIdentifier: "alice"}},
+			wantErr: true,
+		},
+		{
+			name:    "total limit reached",
+			quota:   config.PeerQuotaConfig{MaxPeersTotal: 3},
+			ctx:     userCtx,
+			peers:   []*domain.Peer{{InterfaceIdentifier: "wg2", UserIdentifier: "carol"}},
+			wantErr: true,
+		},
+		{
+			name:  "admin override",
+			quota: config.PeerQuotaConfig{MaxPeersTotal: 3, AdminOverride: true},
+			ctx:   adminCtx,
+			peers: []*domain.Peer{{InterfaceIdentifier: "wg2", UserIdentifier: "carol"}},
+		},
+		{
+			name:    "admin without override",
+			quota:   config.PeerQuotaConfig{MaxPeersTotal: 3},
+			ctx:     adminCtx,
+			peers:   []*domain.Peer{{InterfaceIdentifier: "wg2", UserIdentifier: "carol"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{PeerQuota: tt.quota}
+			m := Manager{cfg: cfg, db: db}
+
+			err := m.validatePeerQuota(tt.ctx, tt.peers...)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, domain.ErrQuotaExceeded)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestManager_applyDeviceTypePolicy(t *testing.T) {
+	m := Manager{cfg: &config.Config{DeviceTypes: config.DeviceTypesConfig{NeverExpire: []string{"server"}}}}
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	server := &domain.Peer{DeviceType: domain.PeerDeviceTypeServer, ExpiresAt: &expiresAt}
+	m.applyDeviceTypePolicy(server)
+	assert.Nil(t, server.ExpiresAt)
+
+	laptop := &domain.Peer{DeviceType: domain.PeerDeviceTypeLaptop, ExpiresAt: &expiresAt}
+	m.applyDeviceTypePolicy(laptop)
+	assert.Equal(t, &expiresAt, laptop.ExpiresAt)
+
+	unknown := &domain.Peer{ExpiresAt: &expiresAt}
+	m.applyDeviceTypePolicy(unknown)
+	assert.Equal(t, &expiresAt, unknown.ExpiresAt)
+
+	ephemeral := &domain.Peer{DeviceType: domain.PeerDeviceTypeServer, ExpiresAt: &expiresAt, Ephemeral: true}
+	m.applyDeviceTypePolicy(ephemeral)
+	assert.Equal(t, &expiresAt, ephemeral.ExpiresAt, "ephemeral peers always expire")
+}
+
+// keyRotationDatabase implements the peer and interface lookup required for the key rotation checks, all other
+// methods are not implemented.
+type keyRotationDatabase struct {
+	InterfaceAndPeerDatabaseRepo
+
+	peers map[domain.PeerIdentifier]domain.Peer
+}
+
+func (f keyRotationDatabase) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &peer, nil
+}
+
+func (f keyRotationDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (
+	*domain.Interface,
+	error,
+) {
+	return &domain.Interface{Identifier: id, OrganizationIdentifier: "org-a"}, nil
+}
+
+func TestManager_RegeneratePeerKeys_denied(t *testing.T) {
+	now := time.Now()
+	recently := now.Add(-time.Hour)
+	db := keyRotationDatabase{peers: map[domain.PeerIdentifier]domain.Peer{
+		"active":   {Identifier: "active", UserIdentifier: "alice", InterfaceIdentifier: "wg0"},
+		"disabled": {Identifier: "disabled", UserIdentifier: "alice", InterfaceIdentifier: "wg0", Disabled: &now},
+		"rotated":  {Identifier: "rotated", UserIdentifier: "alice", InterfaceIdentifier: "wg0", KeysRotatedAt: &recently},
+		"foreign":  {Identifier: "foreign", UserIdentifier: "bob", InterfaceIdentifier: "wg0"},
+	}}
+	cfg := &config.Config{}
+	cfg.KeyRotation = config.KeyRotationConfig{SelfService: true, Cooldown: 24 * time.Hour}
+	m := Manager{cfg: cfg, db: db}
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+
+	_, err := m.RegeneratePeerKeys(userCtx, "disabled")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, err = m.RegeneratePeerKeys(userCtx, "rotated")
+	assert.ErrorIs(t, err, domain.ErrQuotaExceeded)
+
+	_, err = m.RegeneratePeerKeys(userCtx, "foreign")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	_, err = m.RegeneratePeerKeys(userCtx, "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	orgAdminCtx := domain.SetUserInfo(context.Background(),
+		&domain.ContextUserInfo{Id: "admin", IsAdmin: true, Organization: "org-b"})
+	_, err = m.RegeneratePeerKeys(orgAdminCtx, "active")
+	assert.ErrorIs(t, err, domain.ErrNoPermission, "admins of other organizations cannot rotate the keys")
+
+	cfg.KeyRotation.SelfService = false
+	_, err = m.RegeneratePeerKeys(userCtx, "active")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+// helpdeskDatabase implements the peer lookups of a user, all other methods are not implemented.
+type helpdeskDatabase struct {
+	quotaDatabase
+}
+
+func (f helpdeskDatabase) GetSharedUserPeers(_ context.Context, _ domain.UserIdentifier) ([]domain.Peer, error) {
+	return nil, nil
+}
+
+func TestManager_GetUserPeers_helpdesk(t *testing.T) {
+	peer := domain.Peer{Identifier: "peer", UserIdentifier: "alice", InterfaceIdentifier: "wg0",
+		PresharedKey: "psk"}
+	peer.Interface.PrivateKey = "private"
+	m := Manager{cfg: &config.Config{}, db: helpdeskDatabase{quotaDatabase{peers: []domain.Peer{peer}}}}
+
+	helpdeskCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "bob", IsHelpdesk: true})
+	peers, err := m.GetUserPeers(helpdeskCtx, "alice")
+	assert.NoError(t, err)
+	assert.Len(t, peers, 1)
+	assert.Empty(t, peers[0].Interface.PrivateKey, "helpdesk users never see private keys")
+	assert.Empty(t, peers[0].PresharedKey)
+
+	ownerCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	peers, err = m.GetUserPeers(ownerCtx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "private", peers[0].Interface.PrivateKey)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "bob"})
+	_, err = m.GetUserPeers(userCtx, "alice")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+// routeReviewDatabase implements the interface lookup required for the route review, all other methods are not
+// implemented.
+type routeReviewDatabase struct {
+	InterfaceAndPeerDatabaseRepo
+}
+
+func (f routeReviewDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	return &domain.Interface{Identifier: id, PeerRouteAllowlistStr: "192.168.0.0/16"}, nil
+}
+
+func TestManager_applyUserRoutes(t *testing.T) {
+	m := Manager{cfg: &config.Config{}, db: routeReviewDatabase{}}
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "alice"})
+	peer := &domain.Peer{Identifier: "peer", UserIdentifier: "alice", InterfaceIdentifier: "wg0",
+		ExtraAllowedIPsStr: "10.1.0.0/24"}
+
+	review, err := m.applyUserRoutes(userCtx, peer, "10.1.0.0/24, 192.168.7.1/24")
+	assert.NoError(t, err)
+	assert.Nil(t, review, "allowlisted and existing routes are applied without review")
+	assert.Equal(t, "10.1.0.0/24,192.168.7.0/24", peer.ExtraAllowedIPsStr)
+
+	review, err = m.applyUserRoutes(userCtx, peer, "192.168.7.0/24, 0.0.0.0/0")
+	assert.NoError(t, err)
+	if assert.NotNil(t, review) {
+		assert.Equal(t, domain.RouteReviewPending, review.State)
+		assert.Equal(t, "192.168.7.0/24,0.0.0.0/0", review.RoutesStr)
+		assert.Equal(t, "0.0.0.0/0", review.UnreviewedStr)
+		assert.Equal(t, domain.UserIdentifier("alice"), review.RequestedBy)
+	}
+	assert.Equal(t, "10.1.0.0/24,192.168.7.0/24", peer.ExtraAllowedIPsStr, "routes are kept until approved")
+
+	_, err = m.applyUserRoutes(userCtx, peer, "10.1.0.0/33")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	review, err = m.applyUserRoutes(userCtx, peer, "")
+	assert.NoError(t, err)
+	assert.Nil(t, review, "removing routes needs no review")
+	assert.Empty(t, peer.ExtraAllowedIPsStr)
+}
+
+func TestManager_validateAllowedIPConflicts(t *testing.T) {
+	peer := func(id domain.PeerIdentifier, address, extra string) domain.Peer {
+		cidr, _ := domain.CidrFromString(address)
+		return domain.Peer{Identifier: id, DisplayName: string(id), InterfaceIdentifier: "wg0",
+			Interface: domain.PeerInterfaceConfig{Addresses: []domain.Cidr{cidr}}, ExtraAllowedIPsStr: extra}
+	}
+	alice := peer("alice", "10.0.0.2/32", "")
+	gateway := peer("gateway", "10.0.0.3/32", "192.168.1.0/24")
+	legacy := peer("legacy", "10.0.0.4/32", "192.168.1.128/25") // conflicts with the gateway already
+
+	cfg := &config.Config{}
+	cfg.AllowedIPConflicts.RejectOnSave = true
+	m := Manager{cfg: cfg, db: quotaDatabase{peers: []domain.Peer{alice, gateway, legacy}}}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	newPeer := peer("new", "10.0.0.5/32", "")
+	assert.NoError(t, m.validateAllowedIPConflicts(ctx, nil, &newPeer))
+
+	newPeer = peer("new", "10.0.0.2/32", "")
+	err := m.validateAllowedIPConflicts(ctx, nil, &newPeer)
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+	assert.ErrorContains(t, err, "10.0.0.2/32 of peer new overlaps with 10.0.0.2/32 of peer alice")
+
+	updated := alice
+	updated.ExtraAllowedIPsStr = "192.168.0.0/16"
+	assert.ErrorIs(t, m.validateAllowedIPConflicts(ctx, &alice, &updated), domain.ErrInvalidData)
+
+	updated = legacy
+	updated.DisplayName = "renamed"
+	assert.NoError(t, m.validateAllowedIPConflicts(ctx, &legacy, &updated), "existing conflicts are tolerated")
+
+	rekeyed := legacy
+	rekeyed.Identifier = "legacy-new-key"
+	assert.NoError(t, m.validateAllowedIPConflicts(ctx, &legacy, &rekeyed), "the replaced peer does not conflict")
+
+	cfg.AllowedIPConflicts.RejectOnSave = false
+	newPeer = peer("new", "10.0.0.2/32", "")
+	assert.NoError(t, m.validateAllowedIPConflicts(ctx, nil, &newPeer))
+}
+
+type reservationDatabase struct {
+	InterfaceAndPeerDatabaseRepo
+
+	used         []domain.Cidr
+	reservations []domain.AddressReservation
+}
+
+func (f reservationDatabase) GetUsedIpsPerSubnet(_ context.Context, subnets []domain.Cidr) (
+	map[domain.Cidr][]domain.Cidr,
+	error,
+) {
+	result := make(map[domain.Cidr][]domain.Cidr)
+	for _, subnet := range subnets {
+		for _, ip := range f.used {
+			if subnet.Contains(ip) {
+				result[subnet] = append(result[subnet], ip)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (f reservationDatabase) GetAddressReservations(_ context.Context, _ domain.InterfaceIdentifier) (
+	[]domain.AddressReservation,
+	error,
+) {
+	return f.reservations, nil
+}
+
+func TestManager_getFreshPeerIpConfig_reservations(t *testing.T) {
+	used, _ := domain.CidrsFromArray([]string{"10.0.0.1/24", "10.0.0.2/32"})
+	db := reservationDatabase{used: used, reservations: []domain.AddressReservation{
+		{InterfaceIdentifier: "wg0", Address: "10.0.0.3", Kind: domain.AddressReservationExcluded},
+		{InterfaceIdentifier: "wg0", Address: "10.0.0.4", Kind: domain.AddressReservationReserved},
+		{InterfaceIdentifier: "wg0", Address: "10.0.0.50", Kind: domain.AddressReservationReserved,
+			UserIdentifier: "alice"},
+		{InterfaceIdentifier: "wg0", Address: "10.0.0.2", Kind: domain.AddressReservationReserved,
+			UserIdentifier: "bob"},
+	}}
+	m := Manager{cfg: &config.Config{}, db: db}
+	iface := &domain.Interface{Identifier: "wg0", PeerDefNetworkStr: "10.0.0.1/24"}
+
+	ips, err := m.getFreshPeerIpConfig(context.Background(), iface, "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.5/32", domain.CidrsToString(ips), "reserved and excluded addresses are skipped")
+
+	ips, err = m.getFreshPeerIpConfig(context.Background(), iface, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.50/32", domain.CidrsToString(ips), "the assigned address is used")
+
+	ips, err = m.getFreshPeerIpConfig(context.Background(), iface, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.5/32", domain.CidrsToString(ips), "assigned addresses that are in use are skipped")
+}
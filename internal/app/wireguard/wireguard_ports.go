@@ -0,0 +1,163 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// isListenPortAvailable reports whether the given UDP port can be bound on this node. It is a variable, so that
+// tests do not depend on the ports that are used on the test machine.
+var isListenPortAvailable = func(port int) bool {
+	conn, err := net.ListenPacket("udp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+
+	return true
+}
+
+// getFreshListenPort returns the first port that is neither used by another interface nor bound by another service.
+// If a listen port pool is configured, only ports of the pool are assigned.
+func (m Manager) getFreshListenPort(ctx context.Context) (port int, err error) {
+	existingInterfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return -1, err
+	}
+
+	usedPorts := make(map[int]struct{}, len(existingInterfaces))
+	for _, in := range existingInterfaces {
+		usedPorts[in.ListenPort] = struct{}{}
+	}
+	isFree := func(port int) bool {
+		if _, used := usedPorts[port]; used {
+			return false
+		}
+		if !isListenPortAvailable(port) {
+			slog.Debug("skipping listen port that is bound by another service", "port", port)
+			return false
+		}
+		return true
+	}
+
+	if len(m.portPool) != 0 {
+		for port := range m.portPool.All() {
+			if isFree(port) {
+				return port, nil
+			}
+		}
+		return -1, fmt.Errorf("listen port pool exhausted")
+	}
+
+	for port = m.cfg.Advanced.StartListenPort; port <= 65535; port++ { // maximum allowed port number (16 bit uint)
+		if isFree(port) {
+			return port, nil
+		}
+	}
+
+	return -1, fmt.Errorf("port space exhausted")
+}
+
+// validateListenPort ensures that the listen port of the interface does not conflict with other interfaces or with
+// other services of this node. Unchanged listen ports are not probed, as they are bound by the interface itself.
+func (m Manager) validateListenPort(ctx context.Context, old, new *domain.Interface) error {
+	if new.ListenPort == 0 {
+		return nil // random port, chosen by the kernel
+	}
+	if new.ListenPort < 0 || new.ListenPort > 65535 {
+		return fmt.Errorf("invalid listen port %d: %w", new.ListenPort, domain.ErrInvalidData)
+	}
+
+	existingInterfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load existing interfaces: %w", err)
+	}
+	for _, in := range existingInterfaces {
+		if in.Identifier != new.Identifier && in.ListenPort == new.ListenPort {
+			return fmt.Errorf("listen port %d is already used by interface %s: %w",
+				new.ListenPort, in.Identifier, domain.ErrInvalidData)
+		}
+	}
+
+	if old != nil && old.ListenPort == new.ListenPort {
+		return nil
+	}
+	if !isListenPortAvailable(new.ListenPort) {
+		return fmt.Errorf("listen port %d is already in use by another service: %w",
+			new.ListenPort, domain.ErrInvalidData)
+	}
+
+	return nil
+}
+
+// listenPortChangeEndpoint returns the old and the new endpoint address of the interface, if the listen port was
+// changed and the default peer endpoint uses the listen port. Otherwise, ok is false.
+func listenPortChangeEndpoint(old, new *domain.Interface) (oldHost, newEndpoint string, ok bool) {
+	if old.ListenPort == new.ListenPort || old.ListenPort == 0 || new.ListenPort == 0 {
+		return "", "", false
+	}
+
+	oldHost, oldPort := domain.SplitEndpoint(old.PeerDefEndpoint)
+	if oldHost == "" || oldPort != strconv.Itoa(old.ListenPort) {
+		// the default endpoint does not use the listen port, for example because of port forwarding
+		return "", "", false
+	}
+
+	return oldHost, net.JoinHostPort(oldHost, strconv.Itoa(new.ListenPort)), true
+}
+
+// propagateListenPortToDefaults updates the default peer endpoint of the interface if it still points to the old
+// listen port.
+func propagateListenPortToDefaults(old, new *domain.Interface) {
+	oldHost, newEndpoint, ok := listenPortChangeEndpoint(old, new)
+	if !ok {
+		return
+	}
+
+	if host, port := domain.SplitEndpoint(new.PeerDefEndpoint); host == oldHost &&
+		(port == "" || port == strconv.Itoa(old.ListenPort)) {
+		new.PeerDefEndpoint = newEndpoint
+	}
+}
+
+// propagateListenPortToPeers updates the endpoints of all peers that still point to the old listen port of the
+// interface. The resulting peer list is returned.
+func (m Manager) propagateListenPortToPeers(
+	ctx context.Context,
+	old, new *domain.Interface,
+	peers []domain.Peer,
+) ([]domain.Peer, error) {
+	oldHost, newEndpoint, ok := listenPortChangeEndpoint(old, new)
+	if !ok {
+		return peers, nil
+	}
+
+	updatedPeers := make([]domain.Peer, len(peers))
+	updated := 0
+	for i, peer := range peers {
+		updatedPeers[i] = peer
+
+		host, port := domain.SplitEndpoint(peer.Endpoint.GetValue())
+		if host != oldHost || port != strconv.Itoa(old.ListenPort) {
+			continue
+		}
+
+		peer.Endpoint.SetValue(newEndpoint)
+		updatedPeer, err := m.UpdatePeer(ctx, &peer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update endpoint of peer %s: %w", peer.Identifier, err)
+		}
+		updatedPeers[i] = *updatedPeer
+		updated++
+	}
+
+	slog.Info("propagated listen port change to peers", "interface", new.Identifier, "port", new.ListenPort,
+		"peers", updated)
+
+	return updatedPeers, nil
+}
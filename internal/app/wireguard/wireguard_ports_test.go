@@ -0,0 +1,104 @@
+package wireguard
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// portDatabase implements the interface lookups required for the listen port checks, all other methods are not
+// implemented.
+type portDatabase struct {
+	InterfaceAndPeerDatabaseRepo
+
+	interfaces []domain.Interface
+}
+
+func (f portDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+// withBoundPorts replaces the listen port probe for the duration of the test.
+func withBoundPorts(t *testing.T, bound ...int) {
+	original := isListenPortAvailable
+	t.Cleanup(func() { isListenPortAvailable = original })
+
+	isListenPortAvailable = func(port int) bool {
+		for _, p := range bound {
+			if p == port {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func TestManager_getFreshListenPort(t *testing.T) {
+	withBoundPorts(t, 51821)
+
+	cfg := &config.Config{}
+	cfg.Advanced.StartListenPort = 51820
+	db := portDatabase{interfaces: []domain.Interface{{Identifier: "wg0", ListenPort: 51820}}}
+
+	m := Manager{cfg: cfg, db: db}
+	port, err := m.getFreshListenPort(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 51822, port)
+
+	m.portPool, err = domain.ParsePortPool("51820-51821,51900")
+	require.NoError(t, err)
+	port, err = m.getFreshListenPort(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 51900, port)
+
+	m.portPool, err = domain.ParsePortPool("51820-51821")
+	require.NoError(t, err)
+	_, err = m.getFreshListenPort(context.Background())
+	assert.Error(t, err)
+}
+
+func TestManager_validateListenPort(t *testing.T) {
+	withBoundPorts(t, 51821, 51822)
+
+	db := portDatabase{interfaces: []domain.Interface{
+		{Identifier: "wg0", ListenPort: 51820},
+		{Identifier: "wg1", ListenPort: 51822},
+	}}
+	m := Manager{cfg: &config.Config{}, db: db}
+	ctx := context.Background()
+
+	assert.NoError(t, m.validateListenPort(ctx, nil, &domain.Interface{Identifier: "wg2", ListenPort: 51823}))
+	assert.NoError(t, m.validateListenPort(ctx, nil, &domain.Interface{Identifier: "wg2"}))
+	assert.ErrorIs(t, m.validateListenPort(ctx, nil, &domain.Interface{Identifier: "wg2", ListenPort: 51820}),
+		domain.ErrInvalidData, "used by another interface")
+	assert.ErrorIs(t, m.validateListenPort(ctx, nil, &domain.Interface{Identifier: "wg2", ListenPort: 51821}),
+		domain.ErrInvalidData, "bound by another service")
+
+	// the unchanged port is bound by the interface itself
+	old := &domain.Interface{Identifier: "wg1", ListenPort: 51822}
+	assert.NoError(t, m.validateListenPort(ctx, old, &domain.Interface{Identifier: "wg1", ListenPort: 51822}))
+}
+
+func Test_propagateListenPortToDefaults(t *testing.T) {
+	old := &domain.Interface{ListenPort: 51820, PeerDefEndpoint: "vpn.example.com:51820"}
+
+	changed := &domain.Interface{ListenPort: 51830, PeerDefEndpoint: "vpn.example.com:51820"}
+	propagateListenPortToDefaults(old, changed)
+	assert.Equal(t, "vpn.example.com:51830", changed.PeerDefEndpoint)
+
+	// an explicitly changed endpoint is kept
+	edited := &domain.Interface{ListenPort: 51830, PeerDefEndpoint: "other.example.com:51820"}
+	propagateListenPortToDefaults(old, edited)
+	assert.Equal(t, "other.example.com:51820", edited.PeerDefEndpoint)
+
+	// a forwarded endpoint port does not follow the listen port
+	forwarded := &domain.Interface{ListenPort: 51820, PeerDefEndpoint: "vpn.example.com:443"}
+	forwardedChanged := &domain.Interface{ListenPort: 51830, PeerDefEndpoint: "vpn.example.com:443"}
+	propagateListenPortToDefaults(forwarded, forwardedChanged)
+	assert.Equal(t, "vpn.example.com:443", forwardedChanged.PeerDefEndpoint)
+}
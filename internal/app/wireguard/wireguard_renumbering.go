@@ -0,0 +1,282 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// PrepareRenumbering calculates the renumbering of the given peer network of an interface to a larger network
+// without applying it. If no target network is given, a larger network that does not overlap with the networks of
+// other interfaces is suggested.
+func (m Manager) PrepareRenumbering(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	from, to domain.Cidr,
+) (*domain.InterfaceRenumbering, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.validateInterfaceOrganization(ctx, id); err != nil {
+		return nil, err
+	}
+
+	iface, peers, err := m.db.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", id, err)
+	}
+
+	plan, _, _, err := m.planRenumbering(ctx, iface, peers, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// RenumberInterface moves the given peer network of an interface, including the addresses of the interface and all
+// its peers, to a larger network. The changes are applied all at once: if one of the peers cannot be saved, all
+// changes are reverted. If sendMails is true, the updated configurations are mailed to the users of the peers.
+func (m Manager) RenumberInterface(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	from, to domain.Cidr,
+	sendMails bool,
+) (*domain.InterfaceRenumbering, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.validateInterfaceOrganization(ctx, id); err != nil {
+		return nil, err
+	}
+
+	iface, peers, err := m.db.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", id, err)
+	}
+
+	plan, renumberedIface, renumberedPeers, err := m.planRenumbering(ctx, iface, peers, from, to)
+	if err != nil {
+		return nil, err
+	}
+	plan.SendMails = sendMails
+
+	if _, err := m.saveInterface(ctx, renumberedIface); err != nil {
+		return nil, fmt.Errorf("failed to renumber interface: %w", err)
+	}
+
+	for i := range renumberedPeers {
+		if err := m.savePeers(ctx, &renumberedPeers[i]); err != nil {
+			m.revertRenumbering(ctx, iface, peers, renumberedPeers[:i+1])
+			return nil, fmt.Errorf("failed to renumber peer %s, changes were reverted: %w",
+				renumberedPeers[i].Identifier, err)
+		}
+	}
+
+	m.bus.Publish(app.TopicInterfaceUpdated, *renumberedIface)
+	for _, peer := range renumberedPeers {
+		m.bus.Publish(app.TopicPeerUpdated, peer)
+	}
+	m.bus.Publish(app.TopicInterfaceRenumbered, *plan)
+
+	slog.Info("renumbered interface", "interface", id, "from", plan.From.String(), "to", plan.To.String(),
+		"peers", len(plan.Peers))
+
+	return plan, nil
+}
+
+// planRenumbering calculates the renumbering plan and returns it together with the renumbered interface and the
+// changed peers.
+func (m Manager) planRenumbering(
+	ctx context.Context,
+	iface *domain.Interface,
+	peers []domain.Peer,
+	from, to domain.Cidr,
+) (*domain.InterfaceRenumbering, *domain.Interface, []domain.Peer, error) {
+	networks, err := domain.CidrsFromString(iface.PeerDefNetworkStr)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse peer networks of interface %s: %w", iface.Identifier, err)
+	}
+	networkIdx := slices.IndexFunc(networks, func(network domain.Cidr) bool {
+		return from.Addr != "" && network.Prefix().Masked() == from.Prefix().Masked()
+	})
+	if networkIdx < 0 {
+		return nil, nil, nil, fmt.Errorf("%s is not a peer network of interface %s: %w", from.String(),
+			iface.Identifier, domain.ErrInvalidData)
+	}
+	from = networks[networkIdx]
+
+	occupied, err := m.getOtherInterfaceNetworks(ctx, iface.Identifier)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if to.Addr == "" {
+		usedIps, err := m.db.GetUsedIpsPerSubnet(ctx, []domain.Cidr{from})
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to load used addresses of interface %s: %w", iface.Identifier,
+				err)
+		}
+		var ok bool
+		to, ok = domain.SuggestRenumberingNetwork(from, uint64(len(usedIps[from])), occupied)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("no larger network available for %s: %w", from.String(),
+				domain.ErrInvalidData)
+		}
+	}
+	to = domain.CidrFromPrefix(to.Prefix().Masked())
+
+	if to.IsV4() != from.IsV4() {
+		return nil, nil, nil, fmt.Errorf("%s and %s must be of the same address family: %w", from.String(),
+			to.String(), domain.ErrInvalidData)
+	}
+	if to.NetLength >= from.NetLength {
+		return nil, nil, nil, fmt.Errorf("%s must be larger than %s: %w", to.String(), from.String(),
+			domain.ErrInvalidData)
+	}
+	if overlapping := slices.IndexFunc(occupied, func(c domain.Cidr) bool {
+		return to.Prefix().Overlaps(c.Prefix().Masked())
+	}); overlapping >= 0 {
+		return nil, nil, nil, fmt.Errorf("%s overlaps with %s of another interface: %w", to.String(),
+			occupied[overlapping].String(), domain.ErrInvalidData)
+	}
+
+	plan := &domain.InterfaceRenumbering{
+		InterfaceId: iface.Identifier,
+		From:        from,
+		To:          to,
+	}
+
+	renumberedIface := *iface
+	renumberedIface.Addresses, plan.InterfaceAddresses = renumberAddresses(iface.Addresses, from, to)
+	networks[networkIdx] = to
+	renumberedIface.PeerDefNetworkStr = domain.CidrsToString(networks)
+	renumberedIface.PeerDefAllowedIPsStr = domain.RenumberAddressList(iface.PeerDefAllowedIPsStr, from, to)
+	renumberedIface.PeerDefDnsStr = domain.RenumberAddressList(iface.PeerDefDnsStr, from, to)
+	renumberedIface.DnsStr = domain.RenumberAddressList(iface.DnsStr, from, to)
+
+	var renumberedPeers []domain.Peer
+	for _, peer := range peers {
+		renumbered := peer
+		var changes []domain.AddressChange
+		renumbered.Interface.Addresses, changes = renumberAddresses(peer.Interface.Addresses, from, to)
+		renumbered.AllowedIPsStr.SetValue(domain.RenumberAddressList(peer.AllowedIPsStr.GetValue(), from, to))
+		renumbered.Interface.DnsStr.SetValue(domain.RenumberAddressList(peer.Interface.DnsStr.GetValue(), from,
+			to))
+		renumbered.Interface.CheckAliveAddress = domain.RenumberAddressList(peer.Interface.CheckAliveAddress,
+			from, to)
+
+		if len(changes) == 0 &&
+			renumbered.AllowedIPsStr == peer.AllowedIPsStr &&
+			renumbered.Interface.DnsStr == peer.Interface.DnsStr &&
+			renumbered.Interface.CheckAliveAddress == peer.Interface.CheckAliveAddress {
+			continue // the peer is not affected
+		}
+
+		renumberedPeers = append(renumberedPeers, renumbered)
+		plan.Peers = append(plan.Peers, domain.PeerRenumbering{
+			PeerId:         peer.Identifier,
+			DisplayName:    peer.DisplayName,
+			UserIdentifier: peer.UserIdentifier,
+			Disabled:       peer.IsDisabled(),
+			Addresses:      changes,
+		})
+	}
+
+	if err := m.validateRenumberedAddresses(ctx, plan); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return plan, &renumberedIface, renumberedPeers, nil
+}
+
+// getOtherInterfaceNetworks returns the addresses and peer networks of all interfaces except the given one.
+func (m Manager) getOtherInterfaceNetworks(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Cidr, error) {
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	var networks []domain.Cidr
+	for _, iface := range interfaces {
+		if iface.Identifier == id {
+			continue
+		}
+
+		networks = append(networks, iface.Addresses...)
+		peerNetworks, err := domain.CidrsFromString(iface.PeerDefNetworkStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse peer networks of interface %s: %w", iface.Identifier, err)
+		}
+		networks = append(networks, peerNetworks...)
+	}
+
+	return networks, nil
+}
+
+// validateRenumberedAddresses ensures that the new addresses are not used by any interface or peer that keeps its
+// address.
+func (m Manager) validateRenumberedAddresses(ctx context.Context, plan *domain.InterfaceRenumbering) error {
+	usedIps, err := m.db.GetUsedIpsPerSubnet(ctx, []domain.Cidr{plan.To})
+	if err != nil {
+		return fmt.Errorf("failed to load used addresses of %s: %w", plan.To.String(), err)
+	}
+
+	changes := slices.Clone(plan.InterfaceAddresses)
+	for _, peer := range plan.Peers {
+		changes = append(changes, peer.Addresses...)
+	}
+
+	remaining := make(map[string]struct{}, len(usedIps[plan.To]))
+	for _, ip := range usedIps[plan.To] {
+		remaining[ip.Addr] = struct{}{}
+	}
+	for _, change := range changes {
+		delete(remaining, change.Old.Addr)
+	}
+	for _, change := range changes {
+		if _, ok := remaining[change.New.Addr]; ok {
+			return fmt.Errorf("address %s is already in use: %w", change.New.Addr, domain.ErrInvalidData)
+		}
+	}
+
+	return nil
+}
+
+// revertRenumbering restores the original interface and the already renumbered peers.
+func (m Manager) revertRenumbering(
+	ctx context.Context,
+	iface *domain.Interface,
+	originalPeers []domain.Peer,
+	savedPeers []domain.Peer,
+) {
+	for _, saved := range savedPeers {
+		idx := slices.IndexFunc(originalPeers, func(p domain.Peer) bool { return p.Identifier == saved.Identifier })
+		if err := m.savePeers(ctx, &originalPeers[idx]); err != nil {
+			slog.Error("failed to revert renumbered peer", "peer", saved.Identifier, "error", err)
+		}
+	}
+
+	if _, err := m.saveInterface(ctx, iface); err != nil {
+		slog.Error("failed to revert renumbered interface", "interface", iface.Identifier, "error", err)
+	}
+}
+
+// renumberAddresses renumbers all addresses of the old network and returns the new address list and the changes.
+func renumberAddresses(addresses []domain.Cidr, from, to domain.Cidr) ([]domain.Cidr, []domain.AddressChange) {
+	renumbered := make([]domain.Cidr, len(addresses))
+	var changes []domain.AddressChange
+	for i, addr := range addresses {
+		newAddr, ok := domain.RenumberAddress(addr, from, to)
+		renumbered[i] = newAddr
+		if ok {
+			changes = append(changes, domain.AddressChange{Old: addr, New: newAddr})
+		}
+	}
+
+	return renumbered, changes
+}
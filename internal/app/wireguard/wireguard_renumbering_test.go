@@ -0,0 +1,186 @@
+package wireguard
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// renumberingDatabase implements the lookups required to plan a renumbering, all other methods are not
+// implemented.
+type renumberingDatabase struct {
+	InterfaceAndPeerDatabaseRepo
+
+	interfaces []domain.Interface
+	peers      []domain.Peer
+}
+
+func (f renumberingDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return f.interfaces, nil
+}
+
+func (f renumberingDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (
+	*domain.Interface,
+	error,
+) {
+	for _, iface := range f.interfaces {
+		if iface.Identifier == id {
+			return &iface, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (f renumberingDatabase) GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (
+	*domain.Interface,
+	[]domain.Peer,
+	error,
+) {
+	iface, err := f.GetInterface(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var peers []domain.Peer
+	for _, peer := range f.peers {
+		if peer.InterfaceIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+	return iface, peers, nil
+}
+
+func (f renumberingDatabase) GetUsedIpsPerSubnet(_ context.Context, subnets []domain.Cidr) (
+	map[domain.Cidr][]domain.Cidr,
+	error,
+) {
+	var used []domain.Cidr
+	for _, iface := range f.interfaces {
+		used = append(used, iface.Addresses...)
+	}
+	for _, peer := range f.peers {
+		used = append(used, peer.Interface.Addresses...)
+	}
+
+	result := make(map[domain.Cidr][]domain.Cidr)
+	for _, ip := range used {
+		for _, subnet := range subnets {
+			if subnet.Contains(ip) {
+				result[subnet] = append(result[subnet], ip)
+			}
+		}
+	}
+	return result, nil
+}
+
+func mustCidr(t *testing.T, str string) domain.Cidr {
+	cidr, err := domain.CidrFromString(str)
+	require.NoError(t, err)
+	return cidr
+}
+
+func newRenumberingDatabase(t *testing.T) renumberingDatabase {
+	return renumberingDatabase{
+		interfaces: []domain.Interface{
+			{
+				Identifier:           "wg0",
+				Addresses:            []domain.Cidr{mustCidr(t, "10.0.1.1/24")},
+				PeerDefNetworkStr:    "10.0.1.0/24",
+				PeerDefAllowedIPsStr: "10.0.1.0/24",
+				PeerDefDnsStr:        "10.0.1.1",
+			},
+			{
+				Identifier:        "wg1",
+				Addresses:         []domain.Cidr{mustCidr(t, "10.0.0.1/24")},
+				PeerDefNetworkStr: "10.0.0.0/24",
+			},
+		},
+		peers: []domain.Peer{
+			{
+				Identifier:          "peer1",
+				InterfaceIdentifier: "wg0",
+				UserIdentifier:      "user1",
+				AllowedIPsStr:       domain.NewConfigOption("10.0.1.0/24, 192.168.0.0/16", true),
+				Interface: domain.PeerInterfaceConfig{
+					Addresses: []domain.Cidr{mustCidr(t, "10.0.1.2/32")},
+					DnsStr:    domain.NewConfigOption("10.0.1.1", true),
+				},
+			},
+			{
+				Identifier:          "peer2",
+				InterfaceIdentifier: "wg0",
+				AllowedIPsStr:       domain.NewConfigOption("0.0.0.0/0", true),
+				Interface: domain.PeerInterfaceConfig{
+					Addresses: []domain.Cidr{mustCidr(t, "192.168.5.2/32")},
+				},
+			},
+		},
+	}
+}
+
+func TestManager_PrepareRenumbering(t *testing.T) {
+	m := Manager{cfg: &config.Config{}, db: newRenumberingDatabase(t)}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	plan, err := m.PrepareRenumbering(ctx, "wg0", mustCidr(t, "10.0.1.0/24"), mustCidr(t, "172.16.0.0/22"))
+	require.NoError(t, err)
+	assert.Equal(t, "172.16.0.0/22", plan.To.String())
+	require.Len(t, plan.InterfaceAddresses, 1)
+	assert.Equal(t, "172.16.0.1/22", plan.InterfaceAddresses[0].New.String())
+	require.Len(t, plan.Peers, 1, "peers outside the old network are not affected")
+	assert.Equal(t, domain.PeerIdentifier("peer1"), plan.Peers[0].PeerId)
+	assert.Equal(t, "172.16.0.2/32", plan.Peers[0].Addresses[0].New.String())
+
+	iface, peers, err := m.db.GetInterfaceAndPeers(ctx, "wg0")
+	require.NoError(t, err)
+	_, renumberedIface, renumberedPeers, err := m.planRenumbering(ctx, iface, peers, plan.From, plan.To)
+	require.NoError(t, err)
+	assert.Equal(t, "172.16.0.0/22", renumberedIface.PeerDefNetworkStr)
+	assert.Equal(t, "172.16.0.0/22", renumberedIface.PeerDefAllowedIPsStr)
+	assert.Equal(t, "172.16.0.1", renumberedIface.PeerDefDnsStr)
+	assert.Equal(t, "172.16.0.0/22,192.168.0.0/16", renumberedPeers[0].AllowedIPsStr.GetValue())
+	assert.Equal(t, "172.16.0.1", renumberedPeers[0].Interface.DnsStr.GetValue())
+	assert.Equal(t, "10.0.1.2/32", peers[0].Interface.Addresses[0].String(), "the original peer is unchanged")
+}
+
+func TestManager_PrepareRenumbering_suggestion(t *testing.T) {
+	m := Manager{cfg: &config.Config{}, db: newRenumberingDatabase(t)}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	// 10.0.0.0/23 contains the network of wg1, so the next free network is suggested
+	plan, err := m.PrepareRenumbering(ctx, "wg0", mustCidr(t, "10.0.1.0/24"), domain.Cidr{})
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.2.0/23", plan.To.String())
+}
+
+func TestManager_PrepareRenumbering_invalid(t *testing.T) {
+	db := newRenumberingDatabase(t)
+	db.peers = append(db.peers, domain.Peer{
+		Identifier:          "peer3",
+		InterfaceIdentifier: "wg1",
+		Interface:           domain.PeerInterfaceConfig{Addresses: []domain.Cidr{mustCidr(t, "172.16.8.2/32")}},
+	})
+	m := Manager{cfg: &config.Config{}, db: db}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	from := mustCidr(t, "10.0.1.0/24")
+
+	_, err := m.PrepareRenumbering(ctx, "wg0", mustCidr(t, "10.0.3.0/24"), domain.Cidr{})
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "not a peer network")
+	_, err = m.PrepareRenumbering(ctx, "wg0", from, mustCidr(t, "10.0.1.0/25"))
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "smaller network")
+	_, err = m.PrepareRenumbering(ctx, "wg0", from, mustCidr(t, "fd00::/64"))
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "other address family")
+	_, err = m.PrepareRenumbering(ctx, "wg0", from, mustCidr(t, "10.0.0.0/22"))
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "overlaps with wg1")
+	_, err = m.PrepareRenumbering(ctx, "wg0", from, mustCidr(t, "172.16.8.0/22"))
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "the new address of peer1 is used by peer3")
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user1"})
+	_, err = m.PrepareRenumbering(userCtx, "wg0", from, domain.Cidr{})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
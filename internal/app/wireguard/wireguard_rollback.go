@@ -0,0 +1,143 @@
+package wireguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// peerSnapshot holds the state of a peer before a set of peer changes is applied.
+type peerSnapshot struct {
+	stored   *domain.Peer         // nil if the peer did not exist in the database
+	physical *domain.PhysicalPeer // nil if the peer did not exist on the WireGuard device
+}
+
+// snapshotPeers records the database and device state of the given peers, so that a failed apply can be rolled back.
+func (m Manager) snapshotPeers(ctx context.Context, peers []*domain.Peer) (map[domain.PeerIdentifier]peerSnapshot,
+	error,
+) {
+	devicePeers := make(map[domain.InterfaceIdentifier]map[domain.PeerIdentifier]domain.PhysicalPeer)
+	snapshot := make(map[domain.PeerIdentifier]peerSnapshot, len(peers))
+
+	for _, peer := range peers {
+		physicalPeers, ok := devicePeers[peer.InterfaceIdentifier]
+		if !ok {
+			current, err := m.wg.GetPeers(ctx, peer.InterfaceIdentifier)
+			if err != nil {
+				return nil, fmt.Errorf("failed to snapshot peers of interface %s: %w", peer.InterfaceIdentifier, err)
+			}
+			physicalPeers = make(map[domain.PeerIdentifier]domain.PhysicalPeer, len(current))
+			for _, physicalPeer := range current {
+				physicalPeers[physicalPeer.Identifier] = physicalPeer
+			}
+			devicePeers[peer.InterfaceIdentifier] = physicalPeers
+		}
+
+		var state peerSnapshot
+		stored, err := m.db.GetPeer(ctx, peer.Identifier)
+		switch {
+		case err == nil:
+			state.stored = stored
+		case !errors.Is(err, domain.ErrNotFound):
+			return nil, fmt.Errorf("failed to snapshot peer %s: %w", peer.Identifier, err)
+		}
+		if physicalPeer, ok := physicalPeers[peer.Identifier]; ok {
+			state.physical = &physicalPeer
+		}
+		snapshot[peer.Identifier] = state
+	}
+
+	return snapshot, nil
+}
+
+// rollbackPeers restores the snapshot state of the applied peers and of the failed peer. The failed peer is only
+// restored on the device, its database transaction has already been aborted. The returned error lists the failed
+// peer and the outcome of the rollback.
+func (m Manager) rollbackPeers(
+	ctx context.Context,
+	snapshot map[domain.PeerIdentifier]peerSnapshot,
+	applied []*domain.Peer,
+	failed *domain.Peer,
+	cause error,
+) *domain.PeerApplyError {
+	applyErr := &domain.PeerApplyError{
+		Peer:             failed.Identifier,
+		Interface:        failed.InterfaceIdentifier,
+		Err:              cause,
+		RollbackFailures: make(map[domain.PeerIdentifier]string),
+	}
+
+	slog.Warn("failed to apply peer changes, rolling back",
+		"peer", failed.Identifier,
+		"interface", failed.InterfaceIdentifier,
+		"applied", len(applied),
+		"error", cause)
+
+	if err := m.restorePhysicalPeer(ctx, failed.InterfaceIdentifier, failed.Identifier,
+		snapshot[failed.Identifier]); err != nil {
+		applyErr.RollbackFailures[failed.Identifier] = err.Error()
+	}
+
+	// restore in reverse order, so that the last change is undone first
+	for i := len(applied) - 1; i >= 0; i-- {
+		peer := applied[i]
+		state := snapshot[peer.Identifier]
+
+		err := m.restorePhysicalPeer(ctx, peer.InterfaceIdentifier, peer.Identifier, state)
+		if err == nil {
+			err = m.restoreStoredPeer(ctx, peer.Identifier, state)
+		}
+		if err != nil {
+			slog.Error("failed to roll back peer", "peer", peer.Identifier, "error", err)
+			applyErr.RollbackFailures[peer.Identifier] = err.Error()
+			continue
+		}
+
+		applyErr.RolledBack = append(applyErr.RolledBack, peer.Identifier)
+	}
+
+	return applyErr
+}
+
+func (m Manager) restorePhysicalPeer(
+	ctx context.Context,
+	deviceId domain.InterfaceIdentifier,
+	id domain.PeerIdentifier,
+	state peerSnapshot,
+) error {
+	if state.physical == nil {
+		if err := m.wg.DeletePeer(ctx, deviceId, id); err != nil {
+			return fmt.Errorf("failed to remove wireguard peer: %w", err)
+		}
+		return nil
+	}
+
+	err := m.wg.SavePeer(ctx, deviceId, id, func(pp *domain.PhysicalPeer) (*domain.PhysicalPeer, error) {
+		restored := *state.physical
+		return &restored, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore wireguard peer: %w", err)
+	}
+	return nil
+}
+
+func (m Manager) restoreStoredPeer(ctx context.Context, id domain.PeerIdentifier, state peerSnapshot) error {
+	if state.stored == nil {
+		if err := m.db.DeletePeer(ctx, id); err != nil {
+			return fmt.Errorf("failed to remove stored peer: %w", err)
+		}
+		return nil
+	}
+
+	err := m.db.SavePeer(ctx, id, func(_ *domain.Peer) (*domain.Peer, error) {
+		return state.stored, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore stored peer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package wireguard
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// rollbackDatabase implements the peer storage required to apply peers, all other methods are not implemented.
+type rollbackDatabase struct {
+	InterfaceAndPeerDatabaseRepo
+
+	peers map[domain.PeerIdentifier]domain.Peer
+}
+
+func (f rollbackDatabase) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	peer, ok := f.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &peer, nil
+}
+
+func (f rollbackDatabase) SavePeer(
+	_ context.Context,
+	id domain.PeerIdentifier,
+	updateFunc func(in *domain.Peer) (*domain.Peer, error),
+) error {
+	peer, ok := f.peers[id]
+	if !ok {
+		peer = domain.Peer{Identifier: id}
+	}
+	updated, err := updateFunc(&peer)
+	if err != nil {
+		return err
+	}
+	f.peers[id] = *updated
+	return nil
+}
+
+func (f rollbackDatabase) DeletePeer(_ context.Context, id domain.PeerIdentifier) error {
+	delete(f.peers, id)
+	return nil
+}
+
+// rollbackController keeps the peers of a single device in memory and fails to save the peers listed in failOn.
+type rollbackController struct {
+	InterfaceController
+
+	peers  map[domain.PeerIdentifier]domain.PhysicalPeer
+	failOn map[domain.PeerIdentifier]bool
+}
+
+func (f rollbackController) GetPeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.PhysicalPeer, error) {
+	peers := make([]domain.PhysicalPeer, 0, len(f.peers))
+	for _, peer := range f.peers {
+		peers = append(peers, peer)
+	}
+	return peers, nil
+}
+
+func (f rollbackController) SavePeer(
+	_ context.Context,
+	_ domain.InterfaceIdentifier,
+	id domain.PeerIdentifier,
+	updateFunc func(pp *domain.PhysicalPeer) (*domain.PhysicalPeer, error),
+) error {
+	peer, ok := f.peers[id]
+	if !ok {
+		peer = domain.PhysicalPeer{Identifier: id}
+		f.peers[id] = peer // the peer is created before it is configured, like the kernel implementation does
+	}
+	if f.failOn[id] {
+		return errors.New("address already in use")
+	}
+	updated, err := updateFunc(&peer)
+	if err != nil {
+		return err
+	}
+	f.peers[id] = *updated
+	return nil
+}
+
+func (f rollbackController) DeletePeer(_ context.Context, _ domain.InterfaceIdentifier, id domain.PeerIdentifier) error {
+	delete(f.peers, id)
+	return nil
+}
+
+// ApplyPeers applies the peers in order and stops at the first failing peer, like the kernel implementation does.
+func (f rollbackController) ApplyPeers(
+	_ context.Context,
+	_ domain.InterfaceIdentifier,
+	peers []domain.PhysicalPeer,
+	removed []domain.PeerIdentifier,
+) error {
+	for _, id := range removed {
+		delete(f.peers, id)
+	}
+	for _, peer := range peers {
+		if f.failOn[peer.Identifier] {
+			return errors.New("address already in use")
+		}
+		f.peers[peer.Identifier] = peer
+	}
+	return nil
+}
+
+func TestManager_savePeers_rollback(t *testing.T) {
+	db := rollbackDatabase{peers: map[domain.PeerIdentifier]domain.Peer{
+		"existing": {
+			Identifier:          "existing",
+			InterfaceIdentifier: "wg0",
+			Endpoint:            domain.NewConfigOption("old.example.com:51820", false),
+		},
+	}}
+	wg := rollbackController{
+		peers: map[domain.PeerIdentifier]domain.PhysicalPeer{
+			"existing": {Identifier: "existing", Endpoint: "old.example.com:51820"},
+		},
+		failOn: map[domain.PeerIdentifier]bool{"broken": true},
+	}
+	m := Manager{db: db, wg: wg}
+
+	err := m.savePeers(context.Background(),
+		&domain.Peer{
+			Identifier:          "existing",
+			InterfaceIdentifier: "wg0",
+			Endpoint:            domain.NewConfigOption("new.example.com:51820", false),
+		},
+		&domain.Peer{Identifier: "fresh", InterfaceIdentifier: "wg0"},
+		&domain.Peer{Identifier: "broken", InterfaceIdentifier: "wg0"},
+		&domain.Peer{Identifier: "skipped", InterfaceIdentifier: "wg0"},
+	)
+
+	var applyErr *domain.PeerApplyError
+	require.ErrorAs(t, err, &applyErr)
+	assert.Equal(t, domain.PeerIdentifier("broken"), applyErr.Peer)
+	assert.Equal(t, domain.InterfaceIdentifier("wg0"), applyErr.Interface)
+	assert.ErrorContains(t, applyErr.Err, "address already in use")
+	assert.Equal(t, []domain.PeerIdentifier{"fresh", "existing"}, applyErr.RolledBack)
+	assert.True(t, applyErr.RollbackComplete())
+
+	assert.Len(t, wg.peers, 1)
+	assert.Equal(t, "old.example.com:51820", wg.peers["existing"].Endpoint)
+	assert.Len(t, db.peers, 1)
+	assert.Equal(t, "old.example.com:51820", db.peers["existing"].Endpoint.Value)
+}
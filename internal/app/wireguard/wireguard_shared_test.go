@@ -0,0 +1,44 @@
+package wireguard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// memberDatabase implements the user lookup required to find active members, all other methods are not
+// implemented.
+type memberDatabase struct {
+	InterfaceAndPeerDatabaseRepo
+
+	users map[domain.UserIdentifier]domain.User
+}
+
+func (f memberDatabase) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &user, nil
+}
+
+func TestManager_nextActiveMember(t *testing.T) {
+	now := time.Now()
+	m := Manager{db: memberDatabase{users: map[domain.UserIdentifier]domain.User{
+		"owner":    {Identifier: "owner"},
+		"disabled": {Identifier: "disabled", Disabled: &now},
+		"active":   {Identifier: "active"},
+	}}}
+	ctx := context.Background()
+	peer := domain.Peer{UserIdentifier: "owner", SharedUsersStr: "deleted,disabled,active"}
+
+	assert.Equal(t, domain.UserIdentifier("active"), m.nextActiveMember(ctx, peer, "owner"))
+	assert.Equal(t, domain.UserIdentifier("owner"), m.nextActiveMember(ctx, peer, "active"))
+
+	peer.SharedUsersStr = "deleted,disabled"
+	assert.Empty(t, m.nextActiveMember(ctx, peer, "owner"))
+}
@@ -0,0 +1,13 @@
+package config
+
+// AcceptableUseConfig contains the configuration of the acceptable use policy (AUP) that users have to accept before
+// they can create peers or download peer configurations.
+type AcceptableUseConfig struct {
+	// Enabled enables the acceptable use policy.
+	Enabled bool `yaml:"enabled"`
+	// Title is the title of the policy that is shown to the users.
+	Title string `yaml:"title"`
+	// Text is the policy text, shown as plain text. Changing the text requires all users to accept the policy
+	// again.
+	Text string `yaml:"text"`
+}
@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+// AccessRequestConfig contains the configuration for just-in-time access requests. Users request temporary access
+// to a route set for one of their peers, an administrator of the interface approves the request.
+type AccessRequestConfig struct {
+	// Enabled allows users to request temporary access to route sets.
+	Enabled bool `yaml:"enabled"`
+	// DefaultDuration is the duration of the access window if the request does not specify one.
+	DefaultDuration time.Duration `yaml:"default_duration"`
+	// MaxDuration is the maximum duration of an access window that can be requested.
+	MaxDuration time.Duration `yaml:"max_duration"`
+	// RequestTimeout is the duration after which a request that was not approved expires.
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+}
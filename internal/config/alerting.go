@@ -0,0 +1,75 @@
+package config
+
+import "time"
+
+// AlertCondition is the condition that is checked by an alert rule.
+type AlertCondition string
+
+const (
+	// AlertConditionNoHandshake fires if a peer had no handshake within the threshold duration.
+	AlertConditionNoHandshake AlertCondition = "no_handshake"
+	// AlertConditionHighLatency fires if the ping round-trip time of a peer exceeds the threshold duration,
+	// or if the peer is not reachable by ping. Requires ping checks to be enabled.
+	AlertConditionHighLatency AlertCondition = "high_latency"
+)
+
+// AlertChannel is a notification channel for alerts.
+type AlertChannel string
+
+const (
+	AlertChannelMail     AlertChannel = "mail"
+	AlertChannelWebhook  AlertChannel = "webhook"
+	AlertChannelTelegram AlertChannel = "telegram"
+)
+
+// AlertingConfig contains the configuration for peer SLA alerting.
+type AlertingConfig struct {
+	// Enabled enables the periodic evaluation of alert rules.
+	Enabled bool `yaml:"enabled"`
+	// CheckInterval is the interval in which alert rules are evaluated.
+	CheckInterval time.Duration `yaml:"check_interval"`
+	// Rules is the list of alert rules.
+	Rules []AlertRuleConfig `yaml:"rules"`
+	// MailRecipients is the list of mail addresses that receive alert notifications of the mail channel.
+	MailRecipients []string `yaml:"mail_recipients"`
+	// MailGroups is the list of user groups whose members receive alert notifications of the mail channel.
+	MailGroups []string `yaml:"mail_groups"`
+	// Webhook contains the webhook that receives alert notifications of the webhook channel.
+	Webhook WebhookConfig `yaml:"webhook"`
+	// Telegram contains the Telegram bot settings for the telegram channel.
+	Telegram TelegramConfig `yaml:"telegram"`
+}
+
+// AlertRuleConfig contains the configuration of a single alert rule.
+type AlertRuleConfig struct {
+	// Name is the unique name of the rule.
+	Name string `yaml:"name"`
+	// Description is an optional description that is included in notifications.
+	Description string `yaml:"description"`
+	// Condition is the condition that is checked for each peer. Supported: no_handshake, high_latency
+	Condition AlertCondition `yaml:"condition"`
+	// Threshold is the maximum handshake age for no_handshake rules, or the maximum ping round-trip time for
+	// high_latency rules.
+	Threshold time.Duration `yaml:"threshold"`
+	// Tag limits the rule to peers that contain the tag in their notes. If empty, all peers are checked.
+	Tag string `yaml:"tag"`
+	// Interfaces limits the rule to peers of the given interfaces. If empty, peers of all interfaces are checked.
+	Interfaces []string `yaml:"interfaces"`
+	// Channels is the list of notification channels. Supported: mail, webhook, telegram
+	Channels []AlertChannel `yaml:"channels"`
+	// RepeatInterval is the interval in which notifications are repeated for unacknowledged alerts.
+	// If zero, only one notification is sent when the alert fires.
+	RepeatInterval time.Duration `yaml:"repeat_interval"`
+}
+
+// TelegramConfig contains the configuration for Telegram notifications.
+type TelegramConfig struct {
+	// BotToken is the token of the Telegram bot that sends the messages.
+	BotToken string `yaml:"bot_token"`
+	// ChatId is the identifier of the chat or channel that receives the messages.
+	ChatId string `yaml:"chat_id"`
+	// ApiUrl is the base URL of the Telegram Bot API.
+	ApiUrl string `yaml:"api_url"`
+	// Timeout is the timeout for requests to the Telegram Bot API.
+	Timeout time.Duration `yaml:"timeout"`
+}
@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// AllowedIPConflictConfig contains the configuration for the detection of overlapping allowed IPs of the peers of
+// an interface.
+type AllowedIPConflictConfig struct {
+	// RejectOnSave rejects the creation or update of a peer if its allowed IPs overlap with the allowed IPs of another
+	// peer of the interface. Conflicts that already existed before an update are tolerated.
+	RejectOnSave bool `yaml:"reject_on_save"`
+	// CheckInterval is the interval in which the allowed IPs of all interfaces are checked for conflicts.
+	// "0" disables the background check, the conflict report of an interface can still be refreshed manually.
+	CheckInterval time.Duration `yaml:"check_interval"`
+}
@@ -18,9 +18,16 @@ type Auth struct {
 	Ldap []LdapProvider `yaml:"ldap"`
 	// Webauthn contains the configuration for the WebAuthn authenticator.
 	WebAuthn WebauthnConfig `yaml:"webauthn"`
+	// DeviceAuthorization contains the configuration for the OAuth2 device authorization grant.
+	DeviceAuthorization DeviceAuthorizationConfig `yaml:"device_authorization"`
+	// ProxyAuth contains the configuration for the authentication by a trusted reverse proxy.
+	ProxyAuth ProxyAuthConfig `yaml:"proxy"`
 	// MinPasswordLength is the minimum password length for user accounts. This also applies to the admin user.
 	// It is encouraged to set this value to at least 16 characters.
 	MinPasswordLength int `yaml:"min_password_length"`
+	// ImpersonationTimeout is the maximum duration an administrator can impersonate another user.
+	// After this duration, the impersonation session ends automatically. Set to 0 to disable impersonation.
+	ImpersonationTimeout time.Duration `yaml:"impersonation_timeout"`
 }
 
 // BaseFields contains the basic fields that are used to map user information from the authentication providers.
@@ -256,3 +263,45 @@ type WebauthnConfig struct {
 	// Enabled specifies whether WebAuthn is enabled.
 	Enabled bool `yaml:"enabled"`
 }
+
+// DeviceAuthorizationConfig contains the configuration for the OAuth2 device authorization grant (RFC 8628).
+// Headless devices request a user code, the user confirms the code in the web UI, and the device
+// receives its peer configuration.
+type DeviceAuthorizationConfig struct {
+	// Enabled specifies whether the device authorization grant is enabled.
+	Enabled bool `yaml:"enabled"`
+	// CodeLifetime is the duration after which an unconfirmed device code expires.
+	CodeLifetime time.Duration `yaml:"code_lifetime"`
+	// PollInterval is the minimum interval in which devices may poll for the authorization result.
+	PollInterval time.Duration `yaml:"poll_interval"`
+}
+
+// ProxyAuthConfig contains the configuration for the header based authentication by a trusted reverse proxy.
+// Authentication proxies like Authelia, authentik or oauth2-proxy log in the user and pass the identity of the user
+// to wg-portal in request headers.
+type ProxyAuthConfig struct {
+	// Enabled specifies whether users are logged in based on the headers of a trusted reverse proxy.
+	Enabled bool `yaml:"enabled"`
+	// ProviderName is stored as provider name of users that logged in through the proxy.
+	ProviderName string `yaml:"provider_name"`
+	// TrustedProxies is a list of IP addresses or CIDR ranges of the reverse proxies that are allowed to set the
+	// authentication headers. The headers of requests from all other sources are ignored.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+
+	// UserHeader is the name of the header that contains the user identifier.
+	UserHeader string `yaml:"user_header"`
+	// EmailHeader is the name of the header that contains the user's email address.
+	EmailHeader string `yaml:"email_header"`
+	// NameHeader is the name of the header that contains the user's display name.
+	NameHeader string `yaml:"name_header"`
+	// GroupsHeader is the name of the header that contains the user's groups.
+	GroupsHeader string `yaml:"groups_header"`
+	// GroupSeparator separates the groups in the groups header.
+	GroupSeparator string `yaml:"group_separator"`
+	// AdminGroupRegex grants admin rights to users that are member of a group matching the regular expression.
+	// If it is empty, no admin rights are granted.
+	AdminGroupRegex string `yaml:"admin_group_regex"`
+
+	// If RegistrationEnabled is set to true, wg-portal will create new users that do not exist in the database.
+	RegistrationEnabled bool `yaml:"registration_enabled"`
+}
@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+// CapacityConfig contains the configuration for the capacity planning of the interface address pools.
+type CapacityConfig struct {
+	// WarningThreshold is the utilization of an address pool in percent at which the administrators of the
+	// interface are warned by mail. "0" disables the warnings, the utilization is still available.
+	WarningThreshold int `yaml:"warning_threshold"`
+	// GrowthWindow is the time window of recently created peers that is used to project the exhaustion date of an
+	// address pool.
+	GrowthWindow time.Duration `yaml:"growth_window"`
+	// CheckInterval is the interval in which the utilization of all address pools is checked.
+	CheckInterval time.Duration `yaml:"check_interval"`
+}
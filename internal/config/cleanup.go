@@ -0,0 +1,39 @@
+package config
+
+import "time"
+
+// PeerCleanupAction is the action that is taken for inactive peers.
+type PeerCleanupAction string
+
+const (
+	PeerCleanupActionDisable PeerCleanupAction = "disable"
+	PeerCleanupActionDelete  PeerCleanupAction = "delete"
+)
+
+// PeerCleanupConfig contains the configuration for the usage-based cleanup of inactive peers.
+type PeerCleanupConfig struct {
+	// Enabled enables the periodic cleanup of peers without a recent handshake.
+	Enabled bool `yaml:"enabled"`
+	// DryRun only logs the cleanup report. No warning mails are sent and no peers are modified.
+	DryRun bool `yaml:"dry_run"`
+	// InactiveDays is the number of days without a handshake after which the cleanup action is taken.
+	InactiveDays int `yaml:"inactive_days"`
+	// WarningDays is the number of days the peer owner is warned by mail before the cleanup action is taken.
+	WarningDays int `yaml:"warning_days"`
+	// Action is the cleanup action that is taken for inactive peers. Supported: disable, delete
+	Action PeerCleanupAction `yaml:"action"`
+	// ExclusionTag excludes all peers from the cleanup that contain the tag in their notes.
+	ExclusionTag string `yaml:"exclusion_tag"`
+	// CheckInterval is the interval in which inactive peers are checked.
+	CheckInterval time.Duration `yaml:"check_interval"`
+}
+
+// InactivityPeriod returns the duration without a handshake after which the cleanup action is taken.
+func (c PeerCleanupConfig) InactivityPeriod() time.Duration {
+	return time.Duration(c.InactiveDays) * 24 * time.Hour
+}
+
+// WarningPeriod returns the duration between the warning mail and the cleanup action.
+func (c PeerCleanupConfig) WarningPeriod() time.Duration {
+	return time.Duration(c.WarningDays) * 24 * time.Hour
+}
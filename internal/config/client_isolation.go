@@ -0,0 +1,8 @@
+package config
+
+// ClientIsolationConfig contains the configuration of the client isolation firewall rules. Client isolation itself
+// is enabled per interface.
+type ClientIsolationConfig struct {
+	// FirewallTable is the name of the nftables table (family inet) that contains the client isolation rules.
+	FirewallTable string `yaml:"firewall_table"`
+}
@@ -0,0 +1,10 @@
+package config
+
+// CompromiseConfig contains the configuration of the response to peers that are marked as compromised.
+type CompromiseConfig struct {
+	// RotatePresharedKeys rotates the preshared keys of all other enabled peers of the owner on the interface of the
+	// compromised peer, as their configurations may have been stored on the same device.
+	RotatePresharedKeys bool `yaml:"rotate_preshared_keys"`
+	// SecurityContacts is the list of mail addresses that are informed about every compromised peer.
+	SecurityContacts []string `yaml:"security_contacts"`
+}
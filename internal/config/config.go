@@ -33,6 +33,7 @@ type Config struct {
 		LogPretty           bool          `yaml:"log_pretty"`
 		LogJson             bool          `yaml:"log_json"`
 		StartListenPort     int           `yaml:"start_listen_port"`
+		ListenPortPool      string        `yaml:"listen_port_pool"` // for example "51820-51829,51900", overrides start_listen_port
 		StartCidrV4         string        `yaml:"start_cidr_v4"`
 		StartCidrV6         string        `yaml:"start_cidr_v6"`
 		UseIpV6             bool          `yaml:"use_ip_v6"`
@@ -40,7 +41,15 @@ type Config struct {
 		ExpiryCheckInterval time.Duration `yaml:"expiry_check_interval"`
 		RulePrioOffset      int           `yaml:"rule_prio_offset"`
 		RouteTableOffset    int           `yaml:"route_table_offset"`
-		ApiAdminOnly        bool          `yaml:"api_admin_only"` // if true, only admin users can access the API
+		ApiAdminOnly        bool          `yaml:"api_admin_only"`    // if true, only admin users can access the API
+		BandwidthShaping    bool          `yaml:"bandwidth_shaping"` // if true, peer bandwidth limits are enforced using tc
+		RcConfPath          string        `yaml:"rc_conf_path"`      // FreeBSD only, keep empty to disable rc.conf persistence
+
+		// Linux only, wireguard-go or boringtun, used if the kernel module is missing, keep empty to disable
+		UserspaceImplementation string `yaml:"userspace_implementation"`
+
+		ScheduleCheckInterval time.Duration `yaml:"schedule_check_interval"` // "0" disables peer access schedules
+		ScheduleTimezone      string        `yaml:"schedule_timezone"`       // default time zone of access schedules
 	} `yaml:"advanced"`
 
 	Statistics struct {
@@ -53,8 +62,29 @@ type Config struct {
 		CollectPeerData        bool          `yaml:"collect_peer_data"`
 		CollectAuditData       bool          `yaml:"collect_audit_data"`
 		ListeningAddress       string        `yaml:"listening_address"`
+
+		SampleRetention       time.Duration `yaml:"sample_retention"`        // raw traffic samples, "0" disables samples
+		HourlySampleRetention time.Duration `yaml:"hourly_sample_retention"` // "0" disables hourly and daily rollups
+		DailySampleRetention  time.Duration `yaml:"daily_sample_retention"`  // "0" disables daily rollups
+
+		KeepaliveChurnThreshold int           `yaml:"keepalive_churn_threshold"` // "0" disables recommendations
+		KeepaliveChurnWindow    time.Duration `yaml:"keepalive_churn_window"`
+
+		UseNetlinkEvents   bool          `yaml:"use_netlink_events"`   // if true, link events trigger state updates
+		StateWatchInterval time.Duration `yaml:"state_watch_interval"` // "0" disables the peer state watcher
+
+		ConnectionHistoryRetention time.Duration `yaml:"connection_history_retention"` // "0" disables the history
+
+		LiveSamplingMaxDuration time.Duration `yaml:"live_sampling_max_duration"` // "0" disables live sampling
+
+		AccountingBackend AccountingBackendType `yaml:"accounting_backend"` // source of the peer traffic counters
+		AccountingSubnets []string              `yaml:"accounting_subnets"` // destination subnets of the eBPF backend
 	} `yaml:"statistics"`
 
+	StatisticsExport StatisticsExportConfig `yaml:"statistics_export"`
+
+	EventStream EventStreamConfig `yaml:"event_stream"`
+
 	Mail MailConfig `yaml:"mail"`
 
 	Auth Auth `yaml:"auth"`
@@ -63,7 +93,77 @@ type Config struct {
 
 	Web WebConfig `yaml:"web"`
 
-	Webhook WebhookConfig `yaml:"webhook"`
+	Webhook WebhookEventConfig `yaml:"webhook"`
+
+	PeerCleanup PeerCleanupConfig `yaml:"peer_cleanup"`
+
+	DnsRecords DnsRecordConfig `yaml:"dns_records"`
+
+	DnsResolver DnsResolverConfig `yaml:"dns_resolver"`
+
+	Alerting AlertingConfig `yaml:"alerting"`
+
+	ConfigPull ConfigPullConfig `yaml:"config_pull"`
+
+	PeerQuota PeerQuotaConfig `yaml:"peer_quota"`
+
+	StatusPage StatusPageConfig `yaml:"status_page"`
+
+	PeerTransfer PeerTransferConfig `yaml:"peer_transfer"`
+
+	ConfigSigning ConfigSigningConfig `yaml:"config_signing"`
+
+	SshDeployment SshDeploymentConfig `yaml:"ssh_deployment"`
+
+	DataRetention DataRetentionConfig `yaml:"data_retention"`
+
+	PortKnocking PortKnockingConfig `yaml:"port_knocking"`
+
+	ClientIsolation ClientIsolationConfig `yaml:"client_isolation"`
+
+	PortForwarding PortForwardingConfig `yaml:"port_forwarding"`
+
+	PacketCapture PacketCaptureConfig `yaml:"packet_capture"`
+
+	Snmp SnmpConfig `yaml:"snmp"`
+
+	FlowExport FlowExportConfig `yaml:"flow_export"`
+
+	Failover FailoverConfig `yaml:"failover"`
+
+	AcceptableUse AcceptableUseConfig `yaml:"acceptable_use"`
+
+	Secrets SecretsConfig `yaml:"secrets"`
+
+	KeyReveal KeyRevealConfig `yaml:"key_reveal"`
+
+	KeyRotation KeyRotationConfig `yaml:"key_rotation"`
+
+	ExportApproval ExportApprovalConfig `yaml:"export_approval"`
+
+	Capacity CapacityConfig `yaml:"capacity"`
+
+	Roaming RoamingConfig `yaml:"roaming"`
+
+	Compromise CompromiseConfig `yaml:"compromise"`
+
+	DeviceTypes DeviceTypesConfig `yaml:"device_types"`
+
+	SecurityEvents SecurityEventsConfig `yaml:"security_events"`
+
+	Health HealthConfig `yaml:"health"`
+
+	Restore RestoreConfig `yaml:"restore"`
+
+	GraphQL GraphQLConfig `yaml:"graphql"`
+
+	EphemeralPeers EphemeralPeersConfig `yaml:"ephemeral_peers"`
+
+	AccessRequests AccessRequestConfig `yaml:"access_requests"`
+
+	RouteReview RouteReviewConfig `yaml:"route_review"`
+
+	AllowedIPConflicts AllowedIPConflictConfig `yaml:"allowed_ip_conflicts"`
 }
 
 // LogStartupValues logs the startup values of the configuration in debug level
@@ -79,20 +179,284 @@ func (c *Config) LogStartupValues() {
 		"importExisting", c.Core.ImportExisting,
 		"restoreState", c.Core.RestoreState,
 		"useIpV6", c.Advanced.UseIpV6,
+		"bandwidthShaping", c.Advanced.BandwidthShaping,
 		"collectInterfaceData", c.Statistics.CollectInterfaceData,
 		"collectPeerData", c.Statistics.CollectPeerData,
 		"collectAuditData", c.Statistics.CollectAuditData,
+		"accountingBackend", c.Statistics.AccountingBackend,
 	)
 
 	slog.Debug("Config Settings",
 		"configStoragePath", c.Advanced.ConfigStoragePath,
+		"rcConfPath", c.Advanced.RcConfPath,
+		"userspaceImplementation", c.Advanced.UserspaceImplementation,
+		"listenPortPool", c.Advanced.ListenPortPool,
+		"scheduleCheckInterval", c.Advanced.ScheduleCheckInterval,
+		"scheduleTimezone", c.Advanced.ScheduleTimezone,
 		"externalUrl", c.Web.ExternalUrl,
 	)
 
+	slog.Debug("Config Webhook",
+		"endpoints", len(c.Webhook.AllEndpoints()),
+		"maxAttempts", c.Webhook.MaxAttempts,
+		"retryInterval", c.Webhook.RetryInterval,
+		"eventRetention", c.Webhook.EventRetention,
+	)
+
+	slog.Debug("Config Peer Cleanup",
+		"enabled", c.PeerCleanup.Enabled,
+		"dryRun", c.PeerCleanup.DryRun,
+		"inactiveDays", c.PeerCleanup.InactiveDays,
+		"action", c.PeerCleanup.Action,
+	)
+
+	slog.Debug("Config Statistics Export",
+		"enabled", c.StatisticsExport.Enabled,
+		"exporter", c.StatisticsExport.Exporter,
+	)
+
+	slog.Debug("Config Event Stream",
+		"enabled", c.EventStream.Enabled,
+		"publisher", c.EventStream.Publisher,
+		"events", len(c.EventStream.Events),
+		"queueSize", c.EventStream.QueueSize,
+	)
+
+	slog.Debug("Config DNS Records",
+		"enabled", c.DnsRecords.Enabled,
+		"provider", c.DnsRecords.Provider,
+		"zone", c.DnsRecords.Zone,
+		"reverseZones", len(c.DnsRecords.ReverseZones),
+	)
+
+	slog.Debug("Config DNS Resolver",
+		"enabled", c.DnsResolver.Enabled,
+		"zone", c.DnsResolver.Zone,
+		"port", c.DnsResolver.Port,
+		"upstreams", len(c.DnsResolver.Upstreams),
+		"advertiseDns", c.DnsResolver.AdvertiseDns,
+	)
+
+	slog.Debug("Config Alerting",
+		"enabled", c.Alerting.Enabled,
+		"rules", len(c.Alerting.Rules),
+		"mailRecipients", len(c.Alerting.MailRecipients),
+		"mailGroups", len(c.Alerting.MailGroups),
+		"webhook", c.Alerting.Webhook.Url != "",
+		"telegram", c.Alerting.Telegram.BotToken != "",
+	)
+
+	slog.Debug("Config Pull",
+		"enabled", c.ConfigPull.Enabled,
+		"pollInterval", c.ConfigPull.PollInterval,
+	)
+
+	slog.Debug("Config Peer Quota",
+		"maxPeersPerUser", c.PeerQuota.MaxPeersPerUser,
+		"maxPeersPerInterface", c.PeerQuota.MaxPeersPerInterface,
+		"maxPeersTotal", c.PeerQuota.MaxPeersTotal,
+		"adminOverride", c.PeerQuota.AdminOverride,
+	)
+
+	slog.Debug("Config Status Page",
+		"enabled", c.StatusPage.Enabled,
+		"interfaces", len(c.StatusPage.Interfaces),
+		"checkInterval", c.StatusPage.CheckInterval,
+		"notices", len(c.StatusPage.Notices),
+	)
+
+	slog.Debug("Config Peer Transfer",
+		"enabled", c.PeerTransfer.Enabled,
+		"requireAdminApproval", c.PeerTransfer.RequireAdminApproval,
+		"requestTimeout", c.PeerTransfer.RequestTimeout,
+	)
+
+	slog.Debug("Config Signing",
+		"enabled", c.ConfigSigning.Enabled,
+		"keyFile", c.ConfigSigning.KeyFile,
+	)
+
+	slog.Debug("Config SSH Deployment",
+		"enabled", c.SshDeployment.Enabled,
+		"syncInterval", c.SshDeployment.SyncInterval,
+		"connectTimeout", c.SshDeployment.ConnectTimeout,
+		"idleTimeout", c.SshDeployment.IdleTimeout,
+		"hosts", len(c.SshDeployment.Hosts),
+	)
+
+	slog.Debug("Config Data Retention",
+		"auditRetention", c.DataRetention.AuditRetention,
+		"mailLogRetention", c.DataRetention.MailLogRetention,
+		"sessionLifetime", c.DataRetention.SessionLifetime,
+		"cleanupInterval", c.DataRetention.CleanupInterval,
+	)
+
+	slog.Debug("Config Port Knocking",
+		"enabled", c.PortKnocking.Enabled,
+		"listeningAddress", c.PortKnocking.ListeningAddress,
+		"openDuration", c.PortKnocking.OpenDuration,
+		"maxClockSkew", c.PortKnocking.MaxClockSkew,
+		"firewallTable", c.PortKnocking.FirewallTable,
+	)
+
+	slog.Debug("Config Client Isolation",
+		"firewallTable", c.ClientIsolation.FirewallTable,
+	)
+
+	slog.Debug("Config Port Forwarding",
+		"enabled", c.PortForwarding.Enabled,
+		"selfService", c.PortForwarding.SelfService,
+		"maxPerUser", c.PortForwarding.MaxPerUser,
+		"portRangeStart", c.PortForwarding.PortRangeStart,
+		"portRangeEnd", c.PortForwarding.PortRangeEnd,
+		"firewallTable", c.PortForwarding.FirewallTable,
+	)
+
+	slog.Debug("Config Packet Capture",
+		"enabled", c.PacketCapture.Enabled,
+		"maxDuration", c.PacketCapture.MaxDuration,
+		"maxSize", c.PacketCapture.MaxSize,
+		"snapLength", c.PacketCapture.SnapLength,
+		"tcpdumpPath", c.PacketCapture.TcpdumpPath,
+	)
+
+	slog.Debug("Config SNMP",
+		"enabled", c.Snmp.Enabled,
+		"listeningAddress", c.Snmp.ListeningAddress,
+		"baseOid", c.Snmp.BaseOid,
+		"allowedSources", c.Snmp.AllowedSources,
+	)
+
+	slog.Debug("Config Flow Export",
+		"enabled", c.FlowExport.Enabled,
+		"collectorAddress", c.FlowExport.CollectorAddress,
+		"exportInterval", c.FlowExport.ExportInterval,
+		"observationDomainId", c.FlowExport.ObservationDomainId,
+	)
+
+	slog.Debug("Config Failover",
+		"enabled", c.Failover.Enabled,
+		"nodeName", c.Failover.NodeName,
+		"role", c.Failover.Role,
+		"peerHealthUrl", c.Failover.PeerHealthUrl,
+		"interfaces", c.Failover.Interfaces,
+		"checkInterval", c.Failover.CheckInterval,
+		"failureThreshold", c.Failover.FailureThreshold,
+		"syncInterval", c.Failover.SyncInterval,
+		"dnsName", c.Failover.DnsName,
+		"hook", c.Failover.Hook != "",
+	)
+
+	slog.Debug("Config Acceptable Use",
+		"enabled", c.AcceptableUse.Enabled,
+		"title", c.AcceptableUse.Title,
+	)
+
+	slog.Debug("Config Secrets",
+		"refreshInterval", c.Secrets.RefreshInterval,
+		"vaultAddress", c.Secrets.Vault.Address,
+		"awsRegion", c.Secrets.Aws.Region,
+		"gcpEndpoint", c.Secrets.Gcp.Endpoint,
+	)
+
+	slog.Debug("Config Key Reveal",
+		"stepUpRequired", c.KeyReveal.StepUpRequired,
+		"stepUpValidity", c.KeyReveal.StepUpValidity,
+	)
+
+	slog.Debug("Config Key Rotation",
+		"selfService", c.KeyRotation.SelfService,
+		"cooldown", c.KeyRotation.Cooldown,
+		"mailConfig", c.KeyRotation.MailConfig,
+	)
+
+	slog.Debug("Config Export Approval",
+		"threshold", c.ExportApproval.Threshold,
+		"window", c.ExportApproval.Window,
+		"validity", c.ExportApproval.Validity,
+	)
+
+	slog.Debug("Config Capacity",
+		"warningThreshold", c.Capacity.WarningThreshold,
+		"growthWindow", c.Capacity.GrowthWindow,
+		"checkInterval", c.Capacity.CheckInterval,
+	)
+
+	slog.Debug("Config Roaming",
+		"enabled", c.Roaming.Enabled,
+		"maxEndpointChanges", c.Roaming.MaxEndpointChanges,
+		"countryDatabase", c.Roaming.CountryDatabase,
+		"action", c.Roaming.Action,
+	)
+
+	slog.Debug("Config Compromise",
+		"rotatePresharedKeys", c.Compromise.RotatePresharedKeys,
+		"securityContacts", len(c.Compromise.SecurityContacts),
+	)
+
+	slog.Debug("Config Device Types",
+		"neverExpire", c.DeviceTypes.NeverExpire,
+	)
+
+	slog.Debug("Config Security Events",
+		"enabled", c.SecurityEvents.Enabled,
+		"rules", len(c.SecurityEvents.Rules),
+		"countryDatabase", c.SecurityEvents.CountryDatabase,
+		"timezone", c.SecurityEvents.Timezone,
+		"retention", c.SecurityEvents.Retention,
+	)
+
+	slog.Debug("Config Health",
+		"checkInterval", c.Health.CheckInterval,
+		"checkTimeout", c.Health.CheckTimeout,
+		"stallTimeout", c.Health.StallTimeout,
+	)
+
+	slog.Debug("Config Restore",
+		"enabled", c.Restore.Enabled,
+		"abortOnError", c.Restore.AbortOnError,
+		"stepTimeout", c.Restore.StepTimeout,
+	)
+
+	slog.Debug("Config GraphQL",
+		"enabled", c.GraphQL.Enabled,
+		"maxDepth", c.GraphQL.MaxDepth,
+		"persistedQueries", len(c.GraphQL.PersistedQueries),
+		"persistedQueriesOnly", c.GraphQL.PersistedQueriesOnly,
+	)
+
+	slog.Debug("Config Ephemeral Peers",
+		"enabled", c.EphemeralPeers.Enabled,
+		"defaultLifetime", c.EphemeralPeers.DefaultLifetime,
+		"maxLifetime", c.EphemeralPeers.MaxLifetime,
+		"cleanupInterval", c.EphemeralPeers.CleanupInterval,
+	)
+
+	slog.Debug("Config Access Requests",
+		"enabled", c.AccessRequests.Enabled,
+		"defaultDuration", c.AccessRequests.DefaultDuration,
+		"maxDuration", c.AccessRequests.MaxDuration,
+		"requestTimeout", c.AccessRequests.RequestTimeout,
+	)
+
+	slog.Debug("Config Route Review",
+		"enabled", c.RouteReview.Enabled,
+		"requestTimeout", c.RouteReview.RequestTimeout,
+	)
+
+	slog.Debug("Config Allowed IP Conflicts",
+		"rejectOnSave", c.AllowedIPConflicts.RejectOnSave,
+		"checkInterval", c.AllowedIPConflicts.CheckInterval,
+	)
+
 	slog.Debug("Config Authentication",
 		"oidcProviders", len(c.Auth.OpenIDConnect),
 		"oauthProviders", len(c.Auth.OAuth),
 		"ldapProviders", len(c.Auth.Ldap),
+		"impersonationTimeout", c.Auth.ImpersonationTimeout,
+		"deviceAuthorization", c.Auth.DeviceAuthorization.Enabled,
+		"proxyAuth", c.Auth.ProxyAuth.Enabled,
+		"trustedProxies", len(c.Auth.ProxyAuth.TrustedProxies),
 	)
 }
 
@@ -113,8 +477,11 @@ func defaultConfig() *Config {
 	cfg.Core.DeletePeerAfterUserDeleted = false
 
 	cfg.Database = DatabaseConfig{
-		Type: "sqlite",
-		DSN:  "data/sqlite.db",
+		Type:                  "sqlite",
+		DSN:                   "data/sqlite.db",
+		MaxOpenConnections:    10,
+		MaxIdleConnections:    2,
+		ConnectionMaxLifetime: 5 * time.Minute,
 	}
 
 	cfg.Web = WebConfig{
@@ -130,6 +497,7 @@ func defaultConfig() *Config {
 
 	cfg.Advanced.LogLevel = "info"
 	cfg.Advanced.StartListenPort = 51820
+	cfg.Advanced.ListenPortPool = "" // use start_listen_port
 	cfg.Advanced.StartCidrV4 = "10.11.12.0/24"
 	cfg.Advanced.StartCidrV6 = "fdfd:d3ad:c0de:1234::0/64"
 	cfg.Advanced.UseIpV6 = true
@@ -137,6 +505,11 @@ func defaultConfig() *Config {
 	cfg.Advanced.RulePrioOffset = 20000
 	cfg.Advanced.RouteTableOffset = 20000
 	cfg.Advanced.ApiAdminOnly = true
+	cfg.Advanced.BandwidthShaping = false
+	cfg.Advanced.RcConfPath = ""              // interfaces are not stored in rc.conf
+	cfg.Advanced.UserspaceImplementation = "" // only the kernel module is used
+	cfg.Advanced.ScheduleCheckInterval = 1 * time.Minute
+	cfg.Advanced.ScheduleTimezone = "" // server local time
 
 	cfg.Statistics.UsePingChecks = true
 	cfg.Statistics.PingCheckWorkers = 10
@@ -147,6 +520,45 @@ func defaultConfig() *Config {
 	cfg.Statistics.CollectPeerData = true
 	cfg.Statistics.CollectAuditData = true
 	cfg.Statistics.ListeningAddress = ":8787"
+	cfg.Statistics.SampleRetention = 24 * time.Hour
+	cfg.Statistics.HourlySampleRetention = 30 * 24 * time.Hour
+	cfg.Statistics.DailySampleRetention = 365 * 24 * time.Hour
+	cfg.Statistics.KeepaliveChurnThreshold = 3
+	cfg.Statistics.KeepaliveChurnWindow = 24 * time.Hour
+	cfg.Statistics.UseNetlinkEvents = true
+	cfg.Statistics.StateWatchInterval = 5 * time.Second
+	cfg.Statistics.ConnectionHistoryRetention = 90 * 24 * time.Hour
+	cfg.Statistics.LiveSamplingMaxDuration = 5 * time.Minute
+	cfg.Statistics.AccountingBackend = AccountingBackendWireGuard
+	cfg.Statistics.AccountingSubnets = nil
+
+	cfg.StatisticsExport = StatisticsExportConfig{
+		Enabled:  false,
+		Exporter: StatisticsExporterInfluxDb,
+		InfluxDb: InfluxDbConfig{
+			Measurement: "wireguard_peer",
+			Timeout:     10 * time.Second,
+		},
+		TimescaleDb: TimescaleDbConfig{
+			Table: "wg_portal_peer_stats",
+		},
+	}
+
+	cfg.EventStream = EventStreamConfig{
+		Enabled:   false,
+		Publisher: EventStreamPublisherNats,
+		QueueSize: 1000,
+		Nats: NatsConfig{
+			Url:           "nats://localhost:4222",
+			SubjectPrefix: "wg-portal",
+			Timeout:       10 * time.Second,
+		},
+		Kafka: KafkaConfig{
+			Topic:    "wg-portal-events",
+			ClientId: "wg-portal",
+			Timeout:  10 * time.Second,
+		},
+	}
 
 	cfg.Mail = MailConfig{
 		Host:           "127.0.0.1",
@@ -158,14 +570,277 @@ func defaultConfig() *Config {
 		AuthType:       MailAuthPlain,
 		From:           "Wireguard Portal <noreply@wireguard.local>",
 		LinkOnly:       false,
+
+		CompressAttachment:        false,
+		OrganizationTemplatesPath: "",
+		TemplateVariantsPath:      "",
+		TemplateVariants:          []MailTemplateVariant{},
+
+		LoginNotifications:          false,
+		ConfigDownloadNotifications: false,
+		Tracking:                    false,
+
+		RateLimit:        0,
+		DomainRateLimits: map[string]int{},
 	}
 
 	cfg.Webhook.Url = "" // no webhook by default
 	cfg.Webhook.Authentication = ""
 	cfg.Webhook.Timeout = 10 * time.Second
+	cfg.Webhook.MaxAttempts = 10
+	cfg.Webhook.RetryInterval = 1 * time.Minute
+	cfg.Webhook.EventRetention = 30 * 24 * time.Hour
+
+	cfg.PeerCleanup = PeerCleanupConfig{
+		Enabled:       false,
+		DryRun:        false,
+		InactiveDays:  90,
+		WarningDays:   7,
+		Action:        PeerCleanupActionDisable,
+		ExclusionTag:  "#keep",
+		CheckInterval: 1 * time.Hour,
+	}
+
+	cfg.DnsRecords = DnsRecordConfig{
+		Enabled:  false,
+		Provider: DnsProviderRfc2136,
+		TTL:      5 * time.Minute,
+		Rfc2136: Rfc2136Config{
+			TsigAlgorithm: "hmac-sha256",
+			Timeout:       10 * time.Second,
+		},
+		PowerDns: PowerDnsConfig{
+			ServerId: "localhost",
+			Timeout:  10 * time.Second,
+		},
+		Route53: Route53Config{
+			Timeout: 10 * time.Second,
+		},
+	}
+
+	cfg.DnsResolver = DnsResolverConfig{
+		Enabled:      false,
+		Zone:         "wg.internal",
+		Port:         53,
+		TTL:          60 * time.Second,
+		AdvertiseDns: true,
+	}
+
+	cfg.Alerting = AlertingConfig{
+		Enabled:       false,
+		CheckInterval: 1 * time.Minute,
+		Webhook: WebhookConfig{
+			Timeout: 10 * time.Second,
+		},
+		Telegram: TelegramConfig{
+			ApiUrl:  "https://api.telegram.org",
+			Timeout: 10 * time.Second,
+		},
+	}
+
+	cfg.ConfigPull = ConfigPullConfig{
+		Enabled:      false,
+		PollInterval: 1 * time.Hour,
+	}
+
+	cfg.StatusPage = StatusPageConfig{
+		Enabled:       false,
+		CheckInterval: 1 * time.Minute,
+	}
+
+	cfg.PeerTransfer = PeerTransferConfig{
+		Enabled:              false,
+		RequireAdminApproval: false,
+		RequestTimeout:       7 * 24 * time.Hour,
+	}
+
+	cfg.ConfigSigning = ConfigSigningConfig{
+		Enabled: false,
+		KeyFile: "data/config-signing.pem",
+	}
+
+	cfg.SshDeployment = SshDeploymentConfig{
+		Enabled:        false,
+		SyncInterval:   5 * time.Minute,
+		ConnectTimeout: 10 * time.Second,
+		IdleTimeout:    5 * time.Minute,
+	}
+
+	cfg.DataRetention = DataRetentionConfig{
+		AuditRetention:   0, // keep forever
+		MailLogRetention: 90 * 24 * time.Hour,
+		SessionLifetime:  24 * time.Hour,
+		CleanupInterval:  1 * time.Hour,
+	}
+
+	cfg.PortKnocking = PortKnockingConfig{
+		Enabled:          false,
+		ListeningAddress: ":62201",
+		OpenDuration:     5 * time.Minute,
+		MaxClockSkew:     30 * time.Second,
+		FirewallTable:    "wg_portal_knock",
+	}
+
+	cfg.ClientIsolation = ClientIsolationConfig{
+		FirewallTable: "wg_portal_isolation",
+	}
+
+	cfg.PortForwarding = PortForwardingConfig{
+		Enabled:        false,
+		SelfService:    false,
+		MaxPerUser:     5,
+		PortRangeStart: 1024,
+		PortRangeEnd:   65535,
+		FirewallTable:  "wg_portal_forward",
+	}
+
+	cfg.PacketCapture = PacketCaptureConfig{
+		Enabled:     false,
+		MaxDuration: 60 * time.Second,
+		MaxSize:     50 * 1024 * 1024,
+		SnapLength:  0,
+		TcpdumpPath: "tcpdump",
+	}
+
+	cfg.Snmp = SnmpConfig{
+		Enabled:          false,
+		ListeningAddress: ":161",
+		BaseOid:          "1.3.6.1.4.1.8072.9999.9999.1",
+	}
+
+	cfg.FlowExport = FlowExportConfig{
+		Enabled:             false,
+		ExportInterval:      1 * time.Minute,
+		ObservationDomainId: 1,
+	}
+
+	cfg.Failover = FailoverConfig{
+		Enabled:          false,
+		Role:             FailoverRolePrimary,
+		CheckInterval:    5 * time.Second,
+		CheckTimeout:     2 * time.Second,
+		FailureThreshold: 3,
+		SyncInterval:     30 * time.Second,
+		DnsTTL:           30 * time.Second,
+	}
+
+	cfg.AcceptableUse = AcceptableUseConfig{
+		Enabled: false,
+		Title:   "Acceptable Use Policy",
+		Text:    "",
+	}
+
+	cfg.Secrets = SecretsConfig{
+		RefreshInterval: 5 * time.Minute,
+	}
+
+	cfg.KeyReveal = KeyRevealConfig{
+		StepUpRequired: false,
+		StepUpValidity: 5 * time.Minute,
+	}
+
+	cfg.KeyRotation = KeyRotationConfig{
+		SelfService: false,
+		Cooldown:    24 * time.Hour,
+		MailConfig:  true,
+	}
+
+	cfg.ExportApproval = ExportApprovalConfig{
+		Threshold: 0,
+		Window:    1 * time.Hour,
+		Validity:  1 * time.Hour,
+	}
+
+	cfg.Capacity = CapacityConfig{
+		WarningThreshold: 80,
+		GrowthWindow:     30 * 24 * time.Hour,
+		CheckInterval:    1 * time.Hour,
+	}
+
+	cfg.Roaming = RoamingConfig{
+		Enabled:            false,
+		MaxEndpointChanges: 10,
+		Action:             RoamingActionWarn,
+		ExclusionTag:       "#roaming",
+	}
+
+	cfg.Compromise = CompromiseConfig{
+		RotatePresharedKeys: false,
+	}
+
+	cfg.DeviceTypes = DeviceTypesConfig{
+		NeverExpire: []string{"server"},
+	}
+
+	cfg.SecurityEvents = SecurityEventsConfig{
+		Enabled:   false,
+		Retention: 90 * 24 * time.Hour,
+	}
+
+	cfg.Health = HealthConfig{
+		CheckInterval: 30 * time.Second,
+		CheckTimeout:  10 * time.Second,
+		StallTimeout:  5 * time.Minute,
+	}
+
+	cfg.Restore = RestoreConfig{
+		Enabled:      false,
+		AbortOnError: false,
+		StepTimeout:  30 * time.Second,
+	}
+
+	cfg.GraphQL = GraphQLConfig{
+		Enabled:              false,
+		MaxDepth:             5,
+		PersistedQueriesOnly: false,
+	}
+
+	cfg.EphemeralPeers = EphemeralPeersConfig{
+		Enabled:         false,
+		DefaultLifetime: 1 * time.Hour,
+		MaxLifetime:     24 * time.Hour,
+		CleanupInterval: 1 * time.Minute,
+	}
+
+	cfg.AccessRequests = AccessRequestConfig{
+		Enabled:         false,
+		DefaultDuration: 1 * time.Hour,
+		MaxDuration:     8 * time.Hour,
+		RequestTimeout:  24 * time.Hour,
+	}
+
+	cfg.RouteReview = RouteReviewConfig{
+		Enabled:        false,
+		RequestTimeout: 7 * 24 * time.Hour,
+	}
+
+	cfg.AllowedIPConflicts = AllowedIPConflictConfig{
+		RejectOnSave:  true,
+		CheckInterval: 15 * time.Minute,
+	}
+
+	cfg.PeerQuota = PeerQuotaConfig{
+		MaxPeersPerUser:      0, // unlimited
+		MaxPeersPerInterface: 0, // unlimited
+		MaxPeersTotal:        0, // unlimited
+		AdminOverride:        true,
+	}
 
 	cfg.Auth.WebAuthn.Enabled = true
 	cfg.Auth.MinPasswordLength = 16
+	cfg.Auth.ImpersonationTimeout = 30 * time.Minute
+	cfg.Auth.DeviceAuthorization.CodeLifetime = 10 * time.Minute
+	cfg.Auth.DeviceAuthorization.PollInterval = 5 * time.Second
+	cfg.Auth.ProxyAuth = ProxyAuthConfig{
+		Enabled:             false,
+		ProviderName:        "proxy",
+		UserHeader:          "Remote-User",
+		EmailHeader:         "Remote-Email",
+		NameHeader:          "Remote-Name",
+		GroupsHeader:        "Remote-Groups",
+		GroupSeparator:      ",",
+		RegistrationEnabled: true,
+	}
 
 	return cfg
 }
@@ -0,0 +1,12 @@
+package config
+
+import "time"
+
+// ConfigPullConfig contains the configuration for the config pull API. Clients periodically fetch the latest
+// configuration of their peer with a long-lived, per-peer pull token.
+type ConfigPullConfig struct {
+	// Enabled enables the config pull API and the creation of pull tokens.
+	Enabled bool `yaml:"enabled"`
+	// PollInterval is the interval in which clients are advised to fetch their configuration.
+	PollInterval time.Duration `yaml:"poll_interval"`
+}
@@ -0,0 +1,10 @@
+package config
+
+// ConfigSigningConfig contains the configuration for the signing of generated configuration files. Signed files
+// contain an Ed25519 signature comment, so downstream automation can verify that they were not tampered with.
+type ConfigSigningConfig struct {
+	// Enabled enables the signing of all generated peer and interface configuration files.
+	Enabled bool `yaml:"enabled"`
+	// KeyFile is the path to the PEM encoded Ed25519 private key. If the file does not exist, a new key is generated.
+	KeyFile string `yaml:"key_file"`
+}
@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+// DataRetentionConfig contains the retention settings for personal data.
+// The retention of statistics is configured in the statistics section.
+type DataRetentionConfig struct {
+	// AuditRetention is the duration after which audit entries are removed. "0" keeps audit entries forever.
+	AuditRetention time.Duration `yaml:"audit_retention"`
+	// MailLogRetention is the duration after which mail log entries are removed. "0" keeps the mail log forever.
+	MailLogRetention time.Duration `yaml:"mail_log_retention"`
+	// SessionLifetime is the maximum lifetime of a web session. Sessions are only kept in memory.
+	SessionLifetime time.Duration `yaml:"session_lifetime"`
+	// CleanupInterval is the interval in which expired data is removed.
+	CleanupInterval time.Duration `yaml:"cleanup_interval"`
+}
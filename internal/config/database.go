@@ -3,7 +3,7 @@ package config
 import "time"
 
 // SupportedDatabase is a type for the supported database types.
-// Supported: mysql, mssql, postgres, sqlite
+// Supported: mysql, mssql, postgres, sqlite, etcd, consul
 type SupportedDatabase string
 
 const (
@@ -11,20 +11,38 @@ const (
 	DatabaseMsSQL    SupportedDatabase = "mssql"
 	DatabasePostgres SupportedDatabase = "postgres"
 	DatabaseSQLite   SupportedDatabase = "sqlite"
+	DatabaseEtcd     SupportedDatabase = "etcd"
+	DatabaseConsul   SupportedDatabase = "consul"
 )
 
+// IsKeyValueStore returns true if the database type is a key-value store instead of a SQL database.
+func (d SupportedDatabase) IsKeyValueStore() bool {
+	return d == DatabaseEtcd || d == DatabaseConsul
+}
+
 // DatabaseConfig contains the configuration for the database connection.
 type DatabaseConfig struct {
 	// Debug enables logging of all database statements
 	Debug bool `yaml:"debug"`
 	// SlowQueryThreshold enables logging of slow queries which take longer than the specified duration
 	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"` // "0" means no logging of slow queries
-	// Type is the database type. Supported: mysql, mssql, postgres, sqlite
+	// Type is the database type. Supported: mysql, mssql, postgres, sqlite, etcd, consul
 	Type SupportedDatabase `yaml:"type"`
 	// DSN is the database connection string.
 	// For SQLite, it is the path to the database file.
+	// For etcd and Consul, it is the URL of the HTTP API, the path of the URL is used as key prefix.
 	// For other databases, it is the connection string, see: https://gorm.io/docs/connecting_to_the_database.html
 	DSN string `yaml:"dsn"`
+	// MaxOpenConnections limits the number of open connections to the database, 0 means unlimited.
+	// The connection pool settings are ignored for SQLite and the key-value stores.
+	MaxOpenConnections int `yaml:"max_open_connections"`
+	// MaxIdleConnections limits the number of idle connections that are kept open, 0 means that no idle connections
+	// are kept.
+	MaxIdleConnections int `yaml:"max_idle_connections"`
+	// ConnectionMaxLifetime is the duration after which connections are closed and replaced, 0 means no limit.
+	ConnectionMaxLifetime time.Duration `yaml:"connection_max_lifetime"`
+	// ConnectionMaxIdleTime is the duration after which idle connections are closed, 0 means no limit.
+	ConnectionMaxIdleTime time.Duration `yaml:"connection_max_idle_time"`
 	// EncryptionPassphrase is the passphrase used to encrypt sensitive data (WireGuard keys) in the database.
 	// If no passphrase is provided, no encryption will be used.
 	EncryptionPassphrase string `yaml:"encryption_passphrase"`
@@ -0,0 +1,8 @@
+package config
+
+// DeviceTypesConfig contains the policies that apply to peers depending on their device type.
+type DeviceTypesConfig struct {
+	// NeverExpire lists the device types whose peers never expire, for example "server". The expiry date of such
+	// peers is removed when they are saved.
+	NeverExpire []string `yaml:"never_expire"`
+}
@@ -0,0 +1,69 @@
+package config
+
+import "time"
+
+// DnsProviderType is the type of the DNS provider that is used to manage peer records.
+type DnsProviderType string
+
+const (
+	DnsProviderRfc2136  DnsProviderType = "rfc2136"
+	DnsProviderPowerDns DnsProviderType = "powerdns"
+	DnsProviderRoute53  DnsProviderType = "route53"
+)
+
+// DnsRecordConfig contains the configuration for the automatic management of DNS records for peers.
+type DnsRecordConfig struct {
+	// Enabled enables the automatic creation and removal of DNS records for peer tunnel addresses.
+	Enabled bool `yaml:"enabled"`
+	// Provider is the DNS provider that hosts the zones. Supported: rfc2136, powerdns, route53
+	Provider DnsProviderType `yaml:"provider"`
+	// Zone is the forward zone in which A and AAAA records are created, for example: vpn.example.com
+	Zone string `yaml:"zone"`
+	// ReverseZones is a list of reverse zones in which PTR records are created, for example: 12.11.10.in-addr.arpa
+	// PTR records are only created for addresses that belong to one of the configured zones.
+	ReverseZones []string `yaml:"reverse_zones"`
+	// TTL is the time to live of the created records.
+	TTL time.Duration `yaml:"ttl"`
+
+	Rfc2136  Rfc2136Config  `yaml:"rfc2136"`
+	PowerDns PowerDnsConfig `yaml:"powerdns"`
+	Route53  Route53Config  `yaml:"route53"`
+}
+
+// Rfc2136Config contains the settings for dynamic DNS updates as specified in RFC 2136.
+type Rfc2136Config struct {
+	// Server is the address of the primary name server, for example: ns1.example.com:53
+	Server string `yaml:"server"`
+	// TsigKeyName is the name of the TSIG key. Updates are not signed if the key name is empty.
+	TsigKeyName string `yaml:"tsig_key_name"`
+	// TsigSecret is the base64 encoded TSIG secret.
+	TsigSecret string `yaml:"tsig_secret"`
+	// TsigAlgorithm is the TSIG algorithm. Supported: hmac-sha1, hmac-sha256, hmac-sha512
+	TsigAlgorithm string `yaml:"tsig_algorithm"`
+	// Timeout is the timeout for a single update request.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// PowerDnsConfig contains the settings for the PowerDNS HTTP API.
+type PowerDnsConfig struct {
+	// Url is the base URL of the PowerDNS API, for example: http://localhost:8081
+	Url string `yaml:"url"`
+	// ApiKey is the API key that is sent in the X-API-Key header.
+	ApiKey string `yaml:"api_key"`
+	// ServerId is the id of the PowerDNS server, usually localhost.
+	ServerId string `yaml:"server_id"`
+	// Timeout is the timeout for a single API request.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Route53Config contains the settings for the AWS Route 53 API.
+type Route53Config struct {
+	// AccessKeyId is the AWS access key id.
+	AccessKeyId string `yaml:"access_key_id"`
+	// SecretAccessKey is the AWS secret access key.
+	SecretAccessKey string `yaml:"secret_access_key"`
+	// HostedZones maps the forward and reverse zone names to their Route 53 hosted zone ids.
+	HostedZones map[string]string `yaml:"hosted_zones"`
+	// Timeout is the timeout for a single API request.
+	Timeout time.Duration `yaml:"timeout"`
+}
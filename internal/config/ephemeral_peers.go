@@ -0,0 +1,18 @@
+package config
+
+import "time"
+
+// EphemeralPeersConfig contains the configuration for short-lived peers that are issued through the API, for
+// example for break-glass access. Ephemeral peers are deleted once they expire.
+type EphemeralPeersConfig struct {
+	// Enabled allows administrators to issue ephemeral peers. Existing ephemeral peers are always cleaned up, even
+	// if issuing new ones is disabled.
+	Enabled bool `yaml:"enabled"`
+	// DefaultLifetime is the lifetime of an ephemeral peer if the request does not specify one.
+	DefaultLifetime time.Duration `yaml:"default_lifetime"`
+	// MaxLifetime is the maximum lifetime that can be requested.
+	MaxLifetime time.Duration `yaml:"max_lifetime"`
+	// CleanupInterval is the maximum interval between two checks for expired ephemeral peers. Peers are also removed
+	// exactly at their expiry time while WireGuard Portal is running.
+	CleanupInterval time.Duration `yaml:"cleanup_interval"`
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"slices"
+	"time"
+)
+
+// EventStreamPublisherType is the type of the message broker that events are published to.
+type EventStreamPublisherType string
+
+const (
+	EventStreamPublisherNats  EventStreamPublisherType = "nats"
+	EventStreamPublisherKafka EventStreamPublisherType = "kafka"
+)
+
+// EventStreamConfig contains the configuration for publishing the internal events to a message broker.
+type EventStreamConfig struct {
+	// Enabled enables the event stream publisher.
+	Enabled bool `yaml:"enabled"`
+	// Publisher is the message broker that the events are published to. Supported: nats, kafka
+	Publisher EventStreamPublisherType `yaml:"publisher"`
+	// Events limits the published events, for example: peer.created. If empty, all events are published.
+	Events []string `yaml:"events"`
+	// QueueSize is the number of events that are buffered while the broker is slow or unavailable.
+	// Events are dropped if the queue is full.
+	QueueSize int `yaml:"queue_size"`
+
+	Nats  NatsConfig  `yaml:"nats"`
+	Kafka KafkaConfig `yaml:"kafka"`
+}
+
+// IsEventEnabled returns true if the event with the given name should be published.
+func (c EventStreamConfig) IsEventEnabled(name string) bool {
+	return len(c.Events) == 0 || slices.Contains(c.Events, name)
+}
+
+// NatsConfig contains the settings for the NATS publisher.
+type NatsConfig struct {
+	// Url is the address of the NATS server, for example: nats://localhost:4222
+	Url string `yaml:"url"`
+	// SubjectPrefix is prepended to the event name, for example: wg-portal results in the subject wg-portal.peer.created
+	SubjectPrefix string `yaml:"subject_prefix"`
+	// Username and Password are used for user/password authentication.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Token is used for token authentication.
+	Token string `yaml:"token"`
+	// Tls enables TLS for the connection.
+	Tls bool `yaml:"tls"`
+	// Timeout is the timeout for connecting and publishing a single event.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// KafkaConfig contains the settings for the Kafka publisher.
+type KafkaConfig struct {
+	// Brokers are the bootstrap brokers, for example: localhost:9092
+	Brokers []string `yaml:"brokers"`
+	// Topic is the topic that all events are published to. The event name is sent in the record header "event".
+	Topic string `yaml:"topic"`
+	// ClientId identifies WireGuard Portal in the broker logs.
+	ClientId string `yaml:"client_id"`
+	// Username and Password enable SASL/PLAIN authentication.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Tls enables TLS for the broker connections.
+	Tls bool `yaml:"tls"`
+	// Timeout is the timeout for connecting and publishing a single event.
+	Timeout time.Duration `yaml:"timeout"`
+}
@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// ExportApprovalConfig contains the configuration for the four-eyes approval of bulk exports of key material.
+type ExportApprovalConfig struct {
+	// Threshold is the number of peer configurations or private keys of other users that a user can export within
+	// the window without the approval of another administrator. 0 disables the approval policy.
+	Threshold int `yaml:"threshold"`
+	// Window is the time window in which the exports of a user are counted.
+	Window time.Duration `yaml:"window"`
+	// Validity is the duration in which an approved request allows further exports.
+	Validity time.Duration `yaml:"validity"`
+}
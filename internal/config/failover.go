@@ -0,0 +1,49 @@
+package config
+
+import "time"
+
+// FailoverRole is the role of a gateway in an active/standby pair.
+type FailoverRole string
+
+const (
+	FailoverRolePrimary FailoverRole = "primary"
+	FailoverRoleStandby FailoverRole = "standby"
+)
+
+// FailoverConfig contains the configuration of the active/standby failover between two WireGuard Portal gateways
+// that share the same database and interface definitions.
+type FailoverConfig struct {
+	// Enabled enables the failover controller.
+	Enabled bool `yaml:"enabled"`
+	// NodeName identifies this gateway in logs, hooks and health responses.
+	NodeName string `yaml:"node_name"`
+	// Role is the role of this gateway. Supported: primary, standby
+	Role FailoverRole `yaml:"role"`
+	// PeerHealthUrl is the failover health endpoint of the other gateway,
+	// for example: https://gw2.example.com/api/v0/failover/health
+	PeerHealthUrl string `yaml:"peer_health_url"`
+	// Interfaces are the interfaces that are served by the gateway pair. If empty, all interfaces are served.
+	Interfaces []string `yaml:"interfaces"`
+	// CheckInterval is the interval of the health checks.
+	CheckInterval time.Duration `yaml:"check_interval"`
+	// CheckTimeout is the timeout of a single health check.
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+	// FailureThreshold is the number of consecutive failed health checks of the primary before the standby takes
+	// over. The standby hands back after the same number of consecutive successful checks.
+	FailureThreshold int `yaml:"failure_threshold"`
+	// SyncInterval is the interval in which the standby applies the stored interface and peer state to its local
+	// WireGuard interfaces. "0" disables the replication.
+	SyncInterval time.Duration `yaml:"sync_interval"`
+	// DnsName is the DNS name of the advertised endpoint, for example: vpn.example.com
+	// The record is updated with the dns_records provider when a gateway becomes active. Keep empty to disable.
+	DnsName string `yaml:"dns_name"`
+	// DnsZone is the zone that contains the DNS name, for example: example.com
+	DnsZone string `yaml:"dns_zone"`
+	// DnsTTL is the time to live of the advertised endpoint record. Use a short TTL for a fast failover.
+	DnsTTL time.Duration `yaml:"dns_ttl"`
+	// AdvertisedAddresses are the public IPv4 and IPv6 addresses of this gateway that are published as DNS records.
+	AdvertisedAddresses []string `yaml:"advertised_addresses"`
+	// Hook is a shell command that is executed on each state change, for example to move a VRRP address.
+	// The environment variables WG_PORTAL_FAILOVER_STATE (active or standby) and WG_PORTAL_FAILOVER_NODE are set.
+	Hook string `yaml:"hook"`
+}
@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+// FlowExportConfig contains the configuration of the IPFIX export of the connection flows of peers.
+type FlowExportConfig struct {
+	// Enabled enables the export of flow records to the collector.
+	Enabled bool `yaml:"enabled"`
+	// CollectorAddress is the UDP address of the IPFIX collector, for example collector.example.com:4739
+	CollectorAddress string `yaml:"collector_address"`
+	// ExportInterval is the interval in which the connection tracking table is read and flow records are exported.
+	ExportInterval time.Duration `yaml:"export_interval"`
+	// ObservationDomainId identifies this WireGuard Portal instance at the collector.
+	ObservationDomainId uint32 `yaml:"observation_domain_id"`
+}
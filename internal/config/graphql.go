@@ -0,0 +1,14 @@
+package config
+
+// GraphQLConfig contains the configuration of the GraphQL endpoint of the REST API.
+type GraphQLConfig struct {
+	// Enabled enables the GraphQL endpoint at /api/v1/graphql/query.
+	Enabled bool `yaml:"enabled"`
+	// MaxDepth is the maximum nesting depth of the fields of a query. 0 disables the limit.
+	MaxDepth int `yaml:"max_depth"`
+	// PersistedQueries maps query identifiers to query documents. Clients can send the identifier or the hex
+	// encoded SHA-256 hash of the document instead of the full query.
+	PersistedQueries map[string]string `yaml:"persisted_queries"`
+	// PersistedQueriesOnly rejects all queries that are not persisted.
+	PersistedQueriesOnly bool `yaml:"persisted_queries_only"`
+}
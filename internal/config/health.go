@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+// HealthConfig contains the configuration of the health checks. The health status is served for container
+// health checks and is used to send keep-alive notifications to the systemd watchdog.
+type HealthConfig struct {
+	// CheckInterval is the interval of the health checks.
+	CheckInterval time.Duration `yaml:"check_interval"`
+	// CheckTimeout is the timeout of a single health check. Checks that do not finish in time are unhealthy.
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+	// StallTimeout is the maximum time between two runs of the data collection loops. If a loop did not run
+	// for a longer time, it is considered to be stuck.
+	StallTimeout time.Duration `yaml:"stall_timeout"`
+}
@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+// KeyRevealConfig contains the configuration for revealing the private keys of peers in the web interface and the
+// REST API.
+type KeyRevealConfig struct {
+	// StepUpRequired requires a recent (re-)authentication before a private key, or a peer configuration that
+	// contains a private key, is shown or downloaded. Private keys are no longer part of the peer details.
+	StepUpRequired bool `yaml:"step_up_required"`
+	// StepUpValidity is the duration after a (re-)authentication in which private keys can be revealed.
+	StepUpValidity time.Duration `yaml:"step_up_validity"`
+}
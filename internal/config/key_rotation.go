@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// KeyRotationConfig contains the configuration for the self-service key rotation of peers.
+type KeyRotationConfig struct {
+	// SelfService allows users to regenerate the keys of their own peers in the web interface. Administrators can
+	// always regenerate peer keys.
+	SelfService bool `yaml:"self_service"`
+	// Cooldown is the minimum duration between two self-service key rotations of the same peer.
+	Cooldown time.Duration `yaml:"cooldown"`
+	// MailConfig sends the updated peer configuration to the owner of the peer after the keys were regenerated.
+	MailConfig bool `yaml:"mail_config"`
+}
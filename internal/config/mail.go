@@ -41,4 +41,60 @@ type MailConfig struct {
 	From string `yaml:"from"`
 	// LinkOnly specifies whether emails should only contain a link to WireGuard Portal or attach the full configuration
 	LinkOnly bool `yaml:"link_only"`
+	// CompressAttachment specifies whether the configuration file attachment should be gzip compressed
+	CompressAttachment bool `yaml:"compress_attachment"`
+	// OrganizationTemplatesPath is an optional directory with organization specific mail templates. Templates are
+	// loaded from a subdirectory named like the organization identifier and replace the built-in templates.
+	OrganizationTemplatesPath string `yaml:"organization_templates_path"`
+	// TemplateVariantsPath is an optional directory with mail template variants. Templates of a variant are loaded
+	// from a subdirectory named like the variant and replace the built-in and organization templates.
+	TemplateVariantsPath string `yaml:"template_variants_path"`
+	// TemplateVariants are the rules that select a template variant for the recipient of a mail. The first matching
+	// variant is used, recipients without a matching variant get the default templates.
+	TemplateVariants []MailTemplateVariant `yaml:"template_variants"`
+	// CalendarInvites specifies whether a calendar entry (ICS) for the expiry date is attached to the configuration
+	// mail of peers with an expiry date
+	CalendarInvites bool `yaml:"calendar_invites"`
+	// ExpiryReminderDays is the number of days before the expiry of a peer at which the owner receives a reminder
+	// mail with a calendar invitation, 0 disables the reminders
+	ExpiryReminderDays int `yaml:"expiry_reminder_days"`
+	// SetupReminderDays is the number of days after the first configuration mail at which the owner of a peer that
+	// never connected receives a reminder with setup instructions, 0 disables the reminders
+	SetupReminderDays int `yaml:"setup_reminder_days"`
+	// LoginNotifications specifies whether users receive a mail when their account logs in from a new IP address
+	// or browser
+	LoginNotifications bool `yaml:"login_notifications"`
+	// ConfigDownloadNotifications specifies whether users receive a mail when one of their peer configurations is
+	// downloaded or shown as QR code in the web interface
+	ConfigDownloadNotifications bool `yaml:"config_download_notifications"`
+	// SetupGuides specifies whether configuration mails with attachments contain a setup guide for the operating
+	// system of the peer
+	SetupGuides bool `yaml:"setup_guides"`
+	// SetupGuidesPath is an optional directory with custom setup guides (setup_guide_<os>.gohtml), which replace the
+	// built-in guides
+	SetupGuidesPath string `yaml:"setup_guides_path"`
+	// Tracking specifies whether configuration mails contain a tracking pixel and a tracked portal link, so that
+	// administrators can see whether a configuration mail was opened and whether its link was used
+	Tracking bool `yaml:"tracking"`
+
+	// RateLimit is the maximum number of mails that are sent per minute, 0 means unlimited
+	RateLimit int `yaml:"rate_limit"`
+	// DomainRateLimits limits the number of mails per minute to recipients of the given domains, keyed by the
+	// domain name, for example "example.com"
+	DomainRateLimits map[string]int `yaml:"domain_rate_limits"`
+}
+
+// MailTemplateVariant selects a template variant for the recipients that match all conditions of the variant.
+// Empty conditions match all recipients.
+type MailTemplateVariant struct {
+	// Name is the name of the variant and of its template directory, for example "contractors"
+	Name string `yaml:"name"`
+	// Roles of the recipient. Supported: admin, user
+	Roles []string `yaml:"roles"`
+	// Departments of the recipient, compared case-insensitively
+	Departments []string `yaml:"departments"`
+	// Organizations are the organization identifiers of the recipient
+	Organizations []string `yaml:"organizations"`
+	// EmailDomains are the domains of the recipient email address, for example "partner.example.com"
+	EmailDomains []string `yaml:"email_domains"`
 }
@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+// PacketCaptureConfig contains the configuration of the on-demand packet captures for peers.
+type PacketCaptureConfig struct {
+	// Enabled allows admins to capture the traffic of peers on the WireGuard interface.
+	Enabled bool `yaml:"enabled"`
+	// MaxDuration is the longest duration of a capture, it is also used if no duration is requested.
+	MaxDuration time.Duration `yaml:"max_duration"`
+	// MaxSize is the maximum size of a capture file in bytes. The capture stops once the size is reached.
+	MaxSize int64 `yaml:"max_size"`
+	// SnapLength is the number of bytes that are captured per packet. 0 captures the full packets.
+	SnapLength int `yaml:"snap_length"`
+	// TcpdumpPath is the path of the tcpdump binary that performs the captures.
+	TcpdumpPath string `yaml:"tcpdump_path"`
+}
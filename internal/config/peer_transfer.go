@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+// PeerTransferConfig contains the configuration for the transfer of peers between users.
+type PeerTransferConfig struct {
+	// Enabled allows users to request the transfer of their peers to another user.
+	Enabled bool `yaml:"enabled"`
+	// RequireAdminApproval specifies whether an admin has to approve a transfer in addition to the target user.
+	RequireAdminApproval bool `yaml:"require_admin_approval"`
+	// RequestTimeout is the duration after which a transfer that was not accepted expires.
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+}
@@ -0,0 +1,18 @@
+package config
+
+// PortForwardingConfig contains the configuration of the inbound port forwards to peers.
+type PortForwardingConfig struct {
+	// Enabled enables the port forwards and the firewall rules for them.
+	Enabled bool `yaml:"enabled"`
+	// SelfService allows users to manage the port forwards of their own peers. Otherwise, only admins can.
+	SelfService bool `yaml:"self_service"`
+	// MaxPerUser is the maximum number of port forwards to the peers of a user that users can create themselves.
+	// Admins are not limited. 0 means unlimited.
+	MaxPerUser int `yaml:"max_per_user"`
+	// PortRangeStart is the lowest external port that can be forwarded.
+	PortRangeStart int `yaml:"port_range_start"`
+	// PortRangeEnd is the highest external port that can be forwarded.
+	PortRangeEnd int `yaml:"port_range_end"`
+	// FirewallTable is the name of the nftables table (family inet) that contains the port forwarding rules.
+	FirewallTable string `yaml:"firewall_table"`
+}
@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+// PortKnockingConfig contains the configuration of the single packet authorization (SPA) front door. If enabled,
+// the listen ports of all server interfaces are firewalled until a peer sends a valid knock packet.
+type PortKnockingConfig struct {
+	// Enabled enables the knock listener and the firewall rules for the WireGuard listen ports.
+	Enabled bool `yaml:"enabled"`
+	// ListeningAddress is the UDP address that receives the knock packets, for example :62201
+	ListeningAddress string `yaml:"listening_address"`
+	// Secret is used to derive the knock credentials of the peers. Changing the secret invalidates all credentials.
+	Secret string `yaml:"secret"`
+	// OpenDuration is the duration for which the listen port is opened for the source address of a valid knock.
+	// Established connections are not affected when the duration ends.
+	OpenDuration time.Duration `yaml:"open_duration"`
+	// MaxClockSkew is the maximum accepted difference between the timestamp of a knock packet and the server time.
+	MaxClockSkew time.Duration `yaml:"max_clock_skew"`
+	// FirewallTable is the name of the nftables table (family inet) that contains the firewall rules.
+	FirewallTable string `yaml:"firewall_table"`
+}
@@ -0,0 +1,13 @@
+package config
+
+// PeerQuotaConfig contains the limits for the number of peers. A limit of zero disables the respective check.
+type PeerQuotaConfig struct {
+	// MaxPeersPerUser is the maximum number of peers a single user can own.
+	MaxPeersPerUser int `yaml:"max_peers_per_user"`
+	// MaxPeersPerInterface is the maximum number of peers of a single interface.
+	MaxPeersPerInterface int `yaml:"max_peers_per_interface"`
+	// MaxPeersTotal is the maximum number of peers across all interfaces.
+	MaxPeersTotal int `yaml:"max_peers_total"`
+	// AdminOverride allows administrators to exceed the limits when creating peers.
+	AdminOverride bool `yaml:"admin_override"`
+}
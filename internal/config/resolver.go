@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+// DnsResolverConfig contains the configuration for the built-in DNS resolver of the VPN network.
+type DnsResolverConfig struct {
+	// Enabled enables the built-in DNS resolver. It listens on the addresses of all enabled server interfaces.
+	Enabled bool `yaml:"enabled"`
+	// Zone is the DNS zone for which the resolver answers with peer and interface addresses, for example: wg.internal
+	Zone string `yaml:"zone"`
+	// Port is the UDP port the resolver listens on. WireGuard clients only support port 53.
+	Port int `yaml:"port"`
+	// TTL is the time to live of the answers for names in the VPN zone.
+	TTL time.Duration `yaml:"ttl"`
+	// Upstreams is a list of DNS servers that queries outside the VPN zone are forwarded to, for example: 1.1.1.1:53
+	// If empty, the name servers of the host (/etc/resolv.conf) are used.
+	Upstreams []string `yaml:"upstreams"`
+	// AdvertiseDns sets the resolver as DNS server and the zone as search domain in generated peer configurations.
+	// Peers with a non-overridable DNS setting are not changed.
+	AdvertiseDns bool `yaml:"advertise_dns"`
+}
@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// RestoreConfig contains the configuration of the ordered startup restoration. If enabled, the stored state is
+// applied step by step, and the result of each step is kept in a restore report.
+type RestoreConfig struct {
+	// Enabled replaces the best-effort restoration of core.restore_state with the ordered restoration phase.
+	Enabled bool `yaml:"enabled"`
+	// AbortOnError stops the startup if a step failed. Otherwise, the remaining steps are applied anyway.
+	AbortOnError bool `yaml:"abort_on_error"`
+	// StepTimeout is the timeout of a single restoration step.
+	StepTimeout time.Duration `yaml:"step_timeout"`
+}
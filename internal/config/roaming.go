@@ -0,0 +1,27 @@
+package config
+
+// RoamingAction is the action that is taken for peers that violate the roaming policy.
+type RoamingAction string
+
+const (
+	RoamingActionWarn    RoamingAction = "warn"
+	RoamingActionDisable RoamingAction = "disable"
+)
+
+// RoamingConfig contains the configuration of the roaming policy that detects shared peer credentials by the
+// source endpoints of the peers.
+type RoamingConfig struct {
+	// Enabled enables the roaming policy checks for all endpoint changes of the peers.
+	Enabled bool `yaml:"enabled"`
+	// MaxEndpointChanges is the number of endpoint changes within one hour after which a peer violates the policy.
+	// "0" disables the check.
+	MaxEndpointChanges int `yaml:"max_endpoint_changes"`
+	// CountryDatabase is the path to a CSV file that maps IP ranges to country codes, one "start_ip,end_ip,country"
+	// range per line. If set, peers whose endpoint moves to another country violate the policy, and endpoint pins
+	// of peers may contain country codes.
+	CountryDatabase string `yaml:"country_database"`
+	// Action is the action that is taken for peers that violate the policy. Supported: warn, disable
+	Action RoamingAction `yaml:"action"`
+	// ExclusionTag excludes all peers from the roaming policy that contain the tag in their notes.
+	ExclusionTag string `yaml:"exclusion_tag"`
+}
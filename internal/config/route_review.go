@@ -0,0 +1,12 @@
+package config
+
+import "time"
+
+// RouteReviewConfig contains the configuration for the review of routes that users add to their own peers.
+type RouteReviewConfig struct {
+	// Enabled allows users to change the networks that are routed to their own peers. Networks that are not covered
+	// by the route allowlist of the interface are only applied once an interface administrator approved them.
+	Enabled bool `yaml:"enabled"`
+	// RequestTimeout is the duration after which a review that was not approved expires.
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+}
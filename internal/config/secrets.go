@@ -0,0 +1,102 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// SecretsConfig contains the configuration of the secret providers. Secret config values (the SMTP password,
+// the OAuth and OIDC client secrets, the database DSN and the database encryption passphrase) can reference a
+// secret instead of containing the plain value, for example: password: "vault://secret/data/wg-portal#smtp".
+type SecretsConfig struct {
+	// RefreshInterval is the interval in which referenced secrets are read again to detect rotated secrets.
+	// A value of 0 disables the refresh.
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	// Vault contains the configuration of the HashiCorp Vault secret provider.
+	Vault VaultSecretsConfig `yaml:"vault"`
+	// Aws contains the configuration of the AWS Secrets Manager secret provider.
+	Aws AwsSecretsConfig `yaml:"aws"`
+	// Gcp contains the configuration of the Google Cloud Secret Manager secret provider.
+	Gcp GcpSecretsConfig `yaml:"gcp"`
+}
+
+// VaultSecretsConfig contains the configuration of the HashiCorp Vault secret provider.
+type VaultSecretsConfig struct {
+	// Address is the URL of the Vault server.
+	Address string `yaml:"address"`
+	// Token is the Vault token. If empty, the token is read from TokenFile.
+	Token string `yaml:"token"`
+	// TokenFile is the path of a file that contains the Vault token, for example, written by the Vault agent.
+	TokenFile string `yaml:"token_file"`
+	// Namespace is the optional Vault enterprise namespace.
+	Namespace string `yaml:"namespace"`
+}
+
+// AwsSecretsConfig contains the configuration of the AWS Secrets Manager secret provider. Missing credentials are
+// read from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+type AwsSecretsConfig struct {
+	// Region is the AWS region of the secrets. If empty, the AWS_REGION environment variable is used.
+	Region string `yaml:"region"`
+	// AccessKeyId is the AWS access key id.
+	AccessKeyId string `yaml:"access_key_id"`
+	// SecretAccessKey is the AWS secret access key.
+	SecretAccessKey string `yaml:"secret_access_key"`
+	// SessionToken is the optional AWS session token of temporary credentials.
+	SessionToken string `yaml:"session_token"`
+	// Endpoint overrides the Secrets Manager endpoint, for example, for VPC endpoints.
+	Endpoint string `yaml:"endpoint"`
+}
+
+// GcpSecretsConfig contains the configuration of the Google Cloud Secret Manager secret provider.
+type GcpSecretsConfig struct {
+	// AccessTokenFile is the path of a file that contains an OAuth access token. If empty, the token of the
+	// default service account is requested from the metadata server.
+	AccessTokenFile string `yaml:"access_token_file"`
+	// Endpoint overrides the Secret Manager endpoint.
+	Endpoint string `yaml:"endpoint"`
+}
+
+// SecretField is a config value that can reference a secret.
+type SecretField struct {
+	// Name is the name of the config value, for example mail.password.
+	Name string
+	// Value points to the config value.
+	Value *string
+	// Reloadable is true if a rotated secret is applied without restarting WireGuard Portal.
+	Reloadable bool
+}
+
+// SecretFields returns all config values that can reference a secret.
+func (c *Config) SecretFields() []SecretField {
+	fields := []SecretField{
+		{Name: "mail.password", Value: &c.Mail.Password, Reloadable: true},
+		{Name: "database.dsn", Value: &c.Database.DSN},
+		{Name: "database.encryption_passphrase", Value: &c.Database.EncryptionPassphrase},
+	}
+	for i := range c.Auth.OpenIDConnect {
+		fields = append(fields, SecretField{
+			Name:       OidcClientSecretField(c.Auth.OpenIDConnect[i].ProviderName),
+			Value:      &c.Auth.OpenIDConnect[i].ClientSecret,
+			Reloadable: true,
+		})
+	}
+	for i := range c.Auth.OAuth {
+		fields = append(fields, SecretField{
+			Name:       OauthClientSecretField(c.Auth.OAuth[i].ProviderName),
+			Value:      &c.Auth.OAuth[i].ClientSecret,
+			Reloadable: true,
+		})
+	}
+
+	return fields
+}
+
+// OidcClientSecretField returns the secret field name of the client secret of the given OIDC provider.
+func OidcClientSecretField(providerName string) string {
+	return "auth.oidc." + strings.ToLower(providerName) + ".client_secret"
+}
+
+// OauthClientSecretField returns the secret field name of the client secret of the given OAuth provider.
+func OauthClientSecretField(providerName string) string {
+	return "auth.oauth." + strings.ToLower(providerName) + ".client_secret"
+}
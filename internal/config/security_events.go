@@ -0,0 +1,52 @@
+package config
+
+import "time"
+
+// SecurityRuleCondition is the condition that is checked by a security event rule.
+type SecurityRuleCondition string
+
+const (
+	// SecurityRuleHandshakeFromCountry matches peers that connect from an endpoint in one of the rule countries.
+	// Requires a country database.
+	SecurityRuleHandshakeFromCountry SecurityRuleCondition = "handshake_from_country"
+	// SecurityRulePeerEnabledOutsideSchedule matches peers that are enabled outside of the rule schedule,
+	// for example outside of business hours.
+	SecurityRulePeerEnabledOutsideSchedule SecurityRuleCondition = "peer_enabled_outside_schedule"
+	// SecurityRuleAdminCreatedWithinSchedule matches administrator accounts that are created within the rule
+	// schedule, for example at night.
+	SecurityRuleAdminCreatedWithinSchedule SecurityRuleCondition = "admin_created_within_schedule"
+)
+
+// SecurityEventsConfig contains the configuration of the anomaly rules that generate security events.
+type SecurityEventsConfig struct {
+	// Enabled enables the evaluation of the security event rules.
+	Enabled bool `yaml:"enabled"`
+	// Rules is the list of anomaly rules.
+	Rules []SecurityRuleConfig `yaml:"rules"`
+	// CountryDatabase is the path to a CSV file that maps IP ranges to country codes, in the same format as the
+	// roaming country database. If empty, the country database of the roaming policy is used.
+	CountryDatabase string `yaml:"country_database"`
+	// Timezone is the IANA time zone of the rule schedules. If empty, the schedule time zone is used.
+	Timezone string `yaml:"timezone"`
+	// Retention is the duration after which security events are removed. "0" keeps security events forever.
+	Retention time.Duration `yaml:"retention"`
+}
+
+// SecurityRuleConfig contains the configuration of a single anomaly rule.
+type SecurityRuleConfig struct {
+	// Name is the unique name of the rule.
+	Name string `yaml:"name"`
+	// Description is an optional description that is included in the security events.
+	Description string `yaml:"description"`
+	// Condition is the anomaly that is detected by the rule.
+	// Supported: handshake_from_country, peer_enabled_outside_schedule, admin_created_within_schedule
+	Condition SecurityRuleCondition `yaml:"condition"`
+	// Severity is the severity of the generated events. Supported: low, medium, high
+	Severity string `yaml:"severity"`
+	// Countries is the list of blocked two-letter country codes for handshake_from_country rules.
+	Countries []string `yaml:"countries"`
+	// Schedule is the weekly schedule of the rule, for example "Mon-Fri 08:00-18:00". For
+	// peer_enabled_outside_schedule rules it contains the business hours, for admin_created_within_schedule rules
+	// the suspicious hours.
+	Schedule string `yaml:"schedule"`
+}
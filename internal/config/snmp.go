@@ -0,0 +1,16 @@
+package config
+
+// SnmpConfig contains the configuration of the read-only SNMP agent that exposes interface and peer counters to
+// SNMP-based monitoring systems.
+type SnmpConfig struct {
+	// Enabled enables the SNMP agent.
+	Enabled bool `yaml:"enabled"`
+	// ListeningAddress is the UDP address of the SNMP agent, for example :161
+	ListeningAddress string `yaml:"listening_address"`
+	// Community is the SNMPv2c community that is required to read the counters.
+	Community string `yaml:"community"`
+	// BaseOid is the object identifier below which the WireGuard Portal tables are exposed.
+	BaseOid string `yaml:"base_oid"`
+	// AllowedSources restricts the source networks of SNMP requests. If empty, requests from all sources are answered.
+	AllowedSources []string `yaml:"allowed_sources"`
+}
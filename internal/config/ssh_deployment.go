@@ -0,0 +1,47 @@
+package config
+
+import "time"
+
+// SshDeploymentConfig contains the configuration for the SSH deployment driver. It pushes peer configurations to
+// remote hosts that cannot run the config pull agent and applies them with wg syncconf.
+type SshDeploymentConfig struct {
+	// Enabled enables the SSH deployment driver.
+	Enabled bool `yaml:"enabled"`
+	// SyncInterval is the interval in which all deployments are checked for outdated configurations.
+	SyncInterval time.Duration `yaml:"sync_interval"`
+	// ConnectTimeout is the timeout for establishing an SSH connection.
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// IdleTimeout is the duration after which unused pooled SSH connections are closed.
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	// Hosts are the remote hosts that peers can be deployed to.
+	Hosts []SshDeploymentHost `yaml:"hosts"`
+}
+
+// SshDeploymentHost describes a remote host that is reachable via SSH.
+type SshDeploymentHost struct {
+	// Name is the unique name of the host that is referenced by the peer deployments.
+	Name string `yaml:"name"`
+	// Address is the address of the SSH server in the form host:port. The port defaults to 22.
+	Address string `yaml:"address"`
+	// User is the SSH user name.
+	User string `yaml:"user"`
+	// PrivateKeyFile is the path to the unencrypted private key that is used for authentication.
+	PrivateKeyFile string `yaml:"private_key_file"`
+	// HostKey is the pinned public host key of the server in authorized_keys format.
+	HostKey string `yaml:"host_key"`
+	// ConfigDirectory is the remote directory for the wg-quick configuration files.
+	ConfigDirectory string `yaml:"config_directory"`
+	// UseSudo runs the remote commands with sudo, for users that are not root.
+	UseSudo bool `yaml:"use_sudo"`
+}
+
+// GetHost returns the host with the given name.
+func (c SshDeploymentConfig) GetHost(name string) (SshDeploymentHost, bool) {
+	for _, host := range c.Hosts {
+		if host.Name == name {
+			return host, true
+		}
+	}
+
+	return SshDeploymentHost{}, false
+}
@@ -0,0 +1,11 @@
+package config
+
+type AccountingBackendType string
+
+const (
+	// AccountingBackendWireGuard uses the traffic counters of the WireGuard peers.
+	AccountingBackendWireGuard AccountingBackendType = "wireguard"
+	// AccountingBackendEbpf counts the traffic with eBPF programs on the WireGuard interfaces, broken down by the
+	// configured accounting subnets.
+	AccountingBackendEbpf AccountingBackendType = "ebpf"
+)
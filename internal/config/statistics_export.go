@@ -0,0 +1,46 @@
+package config
+
+import "time"
+
+// StatisticsExporterType is the type of the time-series database that peer statistics are exported to.
+type StatisticsExporterType string
+
+const (
+	StatisticsExporterInfluxDb    StatisticsExporterType = "influxdb"
+	StatisticsExporterTimescaleDb StatisticsExporterType = "timescaledb"
+)
+
+// StatisticsExportConfig contains the configuration for the export of peer statistics to a time-series database.
+type StatisticsExportConfig struct {
+	// Enabled enables the export of the collected peer statistics.
+	Enabled bool `yaml:"enabled"`
+	// Exporter is the time-series database that the statistics are written to. Supported: influxdb, timescaledb
+	Exporter StatisticsExporterType `yaml:"exporter"`
+
+	InfluxDb    InfluxDbConfig    `yaml:"influxdb"`
+	TimescaleDb TimescaleDbConfig `yaml:"timescaledb"`
+}
+
+// InfluxDbConfig contains the settings for the InfluxDB v2 write API.
+type InfluxDbConfig struct {
+	// Url is the base URL of the InfluxDB server, for example: http://localhost:8086
+	Url string `yaml:"url"`
+	// Token is the API token. For InfluxDB 1.8, use username:password.
+	Token string `yaml:"token"`
+	// Organization is the organization that owns the bucket. It is ignored by InfluxDB 1.8.
+	Organization string `yaml:"organization"`
+	// Bucket is the bucket that the points are written to. For InfluxDB 1.8, use database/retention-policy.
+	Bucket string `yaml:"bucket"`
+	// Measurement is the name of the measurement.
+	Measurement string `yaml:"measurement"`
+	// Timeout is the timeout for a single write request.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// TimescaleDbConfig contains the settings for the TimescaleDB exporter.
+type TimescaleDbConfig struct {
+	// DSN is the Postgres connection string, for example: host=localhost user=wg dbname=metrics sslmode=disable
+	DSN string `yaml:"dsn"`
+	// Table is the name of the hypertable. It is created if it does not exist.
+	Table string `yaml:"table"`
+}
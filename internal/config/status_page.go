@@ -0,0 +1,31 @@
+package config
+
+import "time"
+
+// StatusPageConfig contains the configuration for the public status page. The status page shows the state of
+// the VPN endpoints without authentication, so users can check for outages before contacting the support.
+type StatusPageConfig struct {
+	// Enabled enables the public status page and the unauthenticated status API.
+	Enabled bool `yaml:"enabled"`
+	// Interfaces limits the status page to the given interface identifiers. If empty, all enabled server
+	// interfaces are shown.
+	Interfaces []string `yaml:"interfaces"`
+	// CheckInterval is the interval in which the endpoint status is refreshed.
+	CheckInterval time.Duration `yaml:"check_interval"`
+	// Notices is the list of maintenance notices. Notices are shown until their end time is reached.
+	Notices []MaintenanceNoticeConfig `yaml:"notices"`
+}
+
+// MaintenanceNoticeConfig is a maintenance notice that is shown on the status page.
+type MaintenanceNoticeConfig struct {
+	// Title is the short summary of the notice.
+	Title string `yaml:"title"`
+	// Message is the detailed description of the maintenance.
+	Message string `yaml:"message"`
+	// Start is the start time of the maintenance window. If empty, the notice is shown immediately.
+	Start time.Time `yaml:"start"`
+	// End is the end time of the maintenance window. If empty, the notice is shown until it is removed.
+	End time.Time `yaml:"end"`
+	// Interfaces limits the notice to the given interface identifiers. If empty, the notice affects all interfaces.
+	Interfaces []string `yaml:"interfaces"`
+}
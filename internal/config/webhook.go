@@ -12,3 +12,51 @@ type WebhookConfig struct {
 	// Timeout is the timeout for the webhook request.
 	Timeout time.Duration `yaml:"timeout"`
 }
+
+// WebhookEventConfig contains the configuration of the webhooks that receive the entity events of WireGuard Portal.
+// All events are stored in the webhook event log and delivered at least once to each endpoint.
+type WebhookEventConfig struct {
+	// WebhookConfig is the default endpoint. It is called "default" in the event log.
+	WebhookConfig `yaml:",inline"`
+
+	// Endpoints are additional named endpoints that receive all events.
+	Endpoints []WebhookEndpointConfig `yaml:"endpoints"`
+
+	// MaxAttempts is the number of delivery attempts for an event before it is marked as failed and skipped.
+	MaxAttempts int `yaml:"max_attempts"`
+	// RetryInterval is the time between two delivery attempts of an event that could not be delivered.
+	RetryInterval time.Duration `yaml:"retry_interval"`
+	// EventRetention is the duration after which events are removed from the event log. "0" keeps events forever.
+	EventRetention time.Duration `yaml:"event_retention"`
+}
+
+// WebhookEndpointConfig contains the configuration of a named webhook endpoint.
+type WebhookEndpointConfig struct {
+	// Name identifies the endpoint in the event log, it must be unique.
+	Name string `yaml:"name"`
+
+	WebhookConfig `yaml:",inline"`
+}
+
+// DefaultWebhookEndpoint is the name of the endpoint that is configured by the url of the webhook section.
+const DefaultWebhookEndpoint = "default"
+
+// AllEndpoints returns all configured webhook endpoints, including the default endpoint if its url is set.
+// Endpoints without a timeout use the timeout of the default endpoint.
+func (c WebhookEventConfig) AllEndpoints() []WebhookEndpointConfig {
+	endpoints := make([]WebhookEndpointConfig, 0, len(c.Endpoints)+1)
+	if c.Url != "" {
+		endpoints = append(endpoints, WebhookEndpointConfig{Name: DefaultWebhookEndpoint, WebhookConfig: c.WebhookConfig})
+	}
+	for _, endpoint := range c.Endpoints {
+		if endpoint.Url == "" {
+			continue
+		}
+		if endpoint.Timeout <= 0 {
+			endpoint.Timeout = c.Timeout
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// AcceptableUsePolicy is the operator defined policy that users have to accept before they receive a peer.
+type AcceptableUsePolicy struct {
+	Version string // derived from the policy text, changes of the text require a new acceptance
+	Title   string
+	Text    string
+}
+
+// NewAcceptableUsePolicy creates the policy with the given title and text. The version is the truncated SHA-256
+// hash of the text, leading and trailing whitespace is ignored.
+func NewAcceptableUsePolicy(title, text string) AcceptableUsePolicy {
+	text = strings.TrimSpace(text)
+	hash := sha256.Sum256([]byte(text))
+
+	return AcceptableUsePolicy{
+		Version: hex.EncodeToString(hash[:8]),
+		Title:   title,
+		Text:    text,
+	}
+}
+
+// AcceptableUseAcceptance records that a user accepted a version of the acceptable use policy.
+type AcceptableUseAcceptance struct {
+	UserIdentifier UserIdentifier `gorm:"primaryKey;column:user_identifier"`
+	Version        string         `gorm:"primaryKey;column:version"`
+	AcceptedAt     time.Time      `gorm:"column:accepted_at"`
+}
+
+// AcceptableUseStatus is the acceptable use policy together with the latest acceptance of a user.
+type AcceptableUseStatus struct {
+	Policy AcceptableUsePolicy
+
+	LastAcceptance *AcceptableUseAcceptance // nil if the user never accepted any version of the policy
+}
+
+// IsAccepted returns true if the user accepted the current version of the policy.
+func (s AcceptableUseStatus) IsAccepted() bool {
+	return s.LastAcceptance != nil && s.LastAcceptance.Version == s.Policy.Version
+}
@@ -0,0 +1,84 @@
+package domain
+
+import "time"
+
+type AccessRequestIdentifier string
+
+type AccessRequestState string
+
+const (
+	AccessRequestPending   AccessRequestState = "pending"   // waiting for the approval of an admin
+	AccessRequestActive    AccessRequestState = "active"    // the route set is attached to the peer
+	AccessRequestEnded     AccessRequestState = "ended"     // the access window ended or was closed early
+	AccessRequestRejected  AccessRequestState = "rejected"  // an admin rejected the request
+	AccessRequestCancelled AccessRequestState = "cancelled" // the requesting user withdrew the request
+	AccessRequestExpired   AccessRequestState = "expired"   // the request was not approved in time
+)
+
+// AccessRequest is a request for temporary access to a route set. Once an admin approved the request, the route set
+// is attached to the peer until the access window ends.
+type AccessRequest struct {
+	Identifier AccessRequestIdentifier `gorm:"primaryKey;column:identifier"`
+	PeerId     PeerIdentifier          `gorm:"index;column:peer_identifier"`
+	PeerName   string                  `gorm:"column:peer_name"` // the display name of the peer at request time
+
+	InterfaceIdentifier InterfaceIdentifier `gorm:"index;column:interface_identifier"`
+	UserIdentifier      UserIdentifier      `gorm:"index;column:user_identifier"` // the owner of the peer
+
+	RouteSetId RouteSetIdentifier `gorm:"column:route_set_identifier"`
+	Reason     string             `gorm:"column:reason"`
+	Duration   time.Duration      `gorm:"column:duration"` // the length of the access window
+
+	State       AccessRequestState `gorm:"column:state"`
+	RequestedBy UserIdentifier     `gorm:"column:requested_by"`
+	RequestedAt time.Time          `gorm:"column:requested_at"`
+	ExpiresAt   time.Time          `gorm:"column:expires_at"` // the time an open request expires
+
+	ApprovedBy UserIdentifier `gorm:"column:approved_by"`
+	ApprovedAt *time.Time     `gorm:"column:approved_at"`
+	EndsAt     *time.Time     `gorm:"column:ends_at"` // the end of the access window
+	// Granted is true if the route set was attached by this request. Route sets that were already attached to the
+	// peer are not removed when the access window ends.
+	Granted bool `gorm:"column:granted"`
+
+	ResolvedBy UserIdentifier `gorm:"column:resolved_by"` // the user that ended, rejected or cancelled
+	ResolvedAt *time.Time     `gorm:"column:resolved_at"`
+}
+
+// IsOpen returns true if the request is still waiting for approval.
+func (r *AccessRequest) IsOpen() bool {
+	return r.State == AccessRequestPending
+}
+
+// IsActive returns true if the access window of the request is open.
+func (r *AccessRequest) IsActive() bool {
+	return r.State == AccessRequestActive
+}
+
+// IsExpired returns true if the open request was not approved in time.
+func (r *AccessRequest) IsExpired(now time.Time) bool {
+	return r.IsOpen() && !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// IsOver returns true if the access window of the active request ended.
+func (r *AccessRequest) IsOver(now time.Time) bool {
+	return r.IsActive() && r.EndsAt != nil && !now.Before(*r.EndsAt)
+}
+
+// Activate opens the access window of the approved request.
+func (r *AccessRequest) Activate(userId UserIdentifier, now time.Time, granted bool) {
+	endsAt := now.Add(r.Duration)
+
+	r.State = AccessRequestActive
+	r.ApprovedBy = userId
+	r.ApprovedAt = &now
+	r.EndsAt = &endsAt
+	r.Granted = granted
+}
+
+// Resolve sets the final state of the request.
+func (r *AccessRequest) Resolve(state AccessRequestState, userId UserIdentifier, now time.Time) {
+	r.State = state
+	r.ResolvedBy = userId
+	r.ResolvedAt = &now
+}
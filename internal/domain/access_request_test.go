@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessRequest_Lifecycle(t *testing.T) {
+	now := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	request := AccessRequest{
+		State:     AccessRequestPending,
+		Duration:  2 * time.Hour,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	assert.True(t, request.IsOpen())
+	assert.False(t, request.IsExpired(now))
+	assert.True(t, request.IsExpired(now.Add(time.Hour)))
+	assert.False(t, request.IsOver(now.Add(3*time.Hour)), "pending requests have no access window")
+
+	request.Activate("admin", now, true)
+	assert.True(t, request.IsActive())
+	assert.False(t, request.IsExpired(now.Add(time.Hour)), "approved requests no longer expire")
+	assert.Equal(t, now.Add(2*time.Hour), *request.EndsAt)
+	assert.False(t, request.IsOver(now.Add(time.Hour)))
+	assert.True(t, request.IsOver(now.Add(2*time.Hour)))
+
+	request.Resolve(AccessRequestEnded, CtxSystemAdminId, now.Add(2*time.Hour))
+	assert.False(t, request.IsActive())
+	assert.False(t, request.IsOver(now.Add(3*time.Hour)))
+}
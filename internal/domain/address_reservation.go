@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+type AddressReservationIdentifier string
+
+type AddressReservationKind string
+
+const (
+	// AddressReservationReserved marks an address that is held back for a future peer. If the reservation belongs to
+	// a user, the address is statically assigned to the next peer that is provisioned for the user.
+	AddressReservationReserved AddressReservationKind = "reserved"
+	// AddressReservationExcluded marks an address that is never assigned to a peer, for example the address of an
+	// infrastructure host within the peer network.
+	AddressReservationExcluded AddressReservationKind = "excluded"
+)
+
+// AddressReservation reserves a single address of the peer network of an interface. Reserved and excluded addresses
+// are skipped when new peer addresses are allocated.
+type AddressReservation struct {
+	Identifier          AddressReservationIdentifier `gorm:"primaryKey;column:identifier"`
+	InterfaceIdentifier InterfaceIdentifier          `gorm:"index;column:interface_identifier"`
+
+	Address        string                 `gorm:"column:address"` // a single IP address, for example: 10.11.12.50
+	Kind           AddressReservationKind `gorm:"column:kind"`
+	UserIdentifier UserIdentifier         `gorm:"column:user_identifier"` // optional, the user the address is assigned to
+	Description    string                 `gorm:"column:description"`
+
+	CreatedBy UserIdentifier `gorm:"column:created_by"`
+	CreatedAt time.Time      `gorm:"column:created_at"`
+}
+
+// Validate performs checks to ensure that the reservation is valid. The address is normalized.
+func (r *AddressReservation) Validate() error {
+	addr, err := netip.ParseAddr(strings.TrimSpace(r.Address))
+	if err != nil {
+		return fmt.Errorf("invalid address %q, a single IP address is required", r.Address)
+	}
+	r.Address = addr.Unmap().String()
+
+	switch r.Kind {
+	case AddressReservationReserved:
+	case AddressReservationExcluded:
+		if r.UserIdentifier != "" {
+			return errors.New("excluded addresses cannot be assigned to a user")
+		}
+	default:
+		return fmt.Errorf("invalid kind %s, only reserved and excluded are supported", r.Kind)
+	}
+
+	return nil
+}
+
+// Addr returns the reserved address. The result is invalid if the address cannot be parsed.
+func (r *AddressReservation) Addr() netip.Addr {
+	addr, err := netip.ParseAddr(r.Address)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+// IsAssignedTo returns true if the address is statically assigned to the given user.
+func (r *AddressReservation) IsAssignedTo(user UserIdentifier) bool {
+	return r.Kind == AddressReservationReserved && r.UserIdentifier != "" && r.UserIdentifier == user
+}
+
+// Permits returns true if a peer of the given user may use the reserved address. Excluded addresses are never
+// permitted, addresses that are assigned to a user are only permitted for peers of that user.
+func (r *AddressReservation) Permits(user UserIdentifier) bool {
+	if r.Kind == AddressReservationExcluded {
+		return false
+	}
+	return r.UserIdentifier == "" || r.UserIdentifier == user
+}
+
+// ReservationViolations returns a description for each address of the peer that is reserved and not permitted for
+// the owner of the peer. Addresses that are contained in the ignored list are skipped, so peers that already used an
+// address before it was reserved can still be edited.
+func ReservationViolations(peer *Peer, reservations []AddressReservation, ignored []Cidr) []string {
+	var violations []string
+	for _, address := range peer.Interface.Addresses {
+		addr := address.Prefix().Addr().Unmap()
+		if containsAddr(ignored, addr) {
+			continue
+		}
+
+		for _, reservation := range reservations {
+			if reservation.InterfaceIdentifier != peer.InterfaceIdentifier || reservation.Addr() != addr {
+				continue
+			}
+			if reservation.Permits(peer.UserIdentifier) {
+				continue
+			}
+
+			if reservation.Kind == AddressReservationExcluded {
+				violations = append(violations, fmt.Sprintf("address %s is excluded", addr))
+			} else {
+				violations = append(violations, fmt.Sprintf("address %s is reserved for user %s", addr,
+					reservation.UserIdentifier))
+			}
+		}
+	}
+
+	return violations
+}
+
+func containsAddr(cidrs []Cidr, addr netip.Addr) bool {
+	for _, cidr := range cidrs {
+		if cidr.Prefix().Addr().Unmap() == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressReservation_Validate(t *testing.T) {
+	reservation := AddressReservation{Address: " 10.0.0.5 ", Kind: AddressReservationReserved, UserIdentifier: "alice"}
+	require.NoError(t, reservation.Validate())
+	assert.Equal(t, "10.0.0.5", reservation.Address, "the address is normalized")
+
+	reservation = AddressReservation{Address: "10.0.0.0/24", Kind: AddressReservationReserved}
+	assert.Error(t, reservation.Validate(), "networks cannot be reserved")
+
+	reservation = AddressReservation{Address: "10.0.0.5", Kind: "blocked"}
+	assert.Error(t, reservation.Validate())
+
+	reservation = AddressReservation{Address: "10.0.0.5", Kind: AddressReservationExcluded, UserIdentifier: "alice"}
+	assert.Error(t, reservation.Validate())
+}
+
+func TestAddressReservation_Permits(t *testing.T) {
+	excluded := AddressReservation{Kind: AddressReservationExcluded}
+	assert.False(t, excluded.Permits(""))
+	assert.False(t, excluded.IsAssignedTo(""))
+
+	reserved := AddressReservation{Kind: AddressReservationReserved}
+	assert.True(t, reserved.Permits("alice"), "unassigned reservations can be used explicitly")
+	assert.False(t, reserved.IsAssignedTo("alice"))
+
+	assigned := AddressReservation{Kind: AddressReservationReserved, UserIdentifier: "alice"}
+	assert.True(t, assigned.Permits("alice"))
+	assert.False(t, assigned.Permits("bob"))
+	assert.True(t, assigned.IsAssignedTo("alice"))
+}
+
+func TestReservationViolations(t *testing.T) {
+	reservations := []AddressReservation{
+		{InterfaceIdentifier: "wg0", Address: "10.0.0.5", Kind: AddressReservationExcluded},
+		{InterfaceIdentifier: "wg0", Address: "10.0.0.6", Kind: AddressReservationReserved, UserIdentifier: "alice"},
+		{InterfaceIdentifier: "wg1", Address: "10.0.0.7", Kind: AddressReservationExcluded},
+	}
+	peer := func(user UserIdentifier, addresses ...string) *Peer {
+		cidrs, err := CidrsFromArray(addresses)
+		require.NoError(t, err)
+		return &Peer{InterfaceIdentifier: "wg0", UserIdentifier: user, Interface: PeerInterfaceConfig{Addresses: cidrs}}
+	}
+
+	assert.Empty(t, ReservationViolations(peer("bob", "10.0.0.7/32"), reservations, nil),
+		"reservations of other interfaces are ignored")
+	assert.Empty(t, ReservationViolations(peer("alice", "10.0.0.6/32"), reservations, nil))
+	assert.Equal(t, []string{"address 10.0.0.5 is excluded", "address 10.0.0.6 is reserved for user alice"},
+		ReservationViolations(peer("bob", "10.0.0.5/32", "10.0.0.6/32"), reservations, nil))
+
+	existing := peer("bob", "10.0.0.5/32").Interface.Addresses
+	assert.Equal(t, []string{"address 10.0.0.6 is reserved for user alice"},
+		ReservationViolations(peer("bob", "10.0.0.5/32", "10.0.0.6/32"), reservations, existing),
+		"addresses that were used before are tolerated")
+}
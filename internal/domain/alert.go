@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Alert is a firing alert of an alert rule for a single peer.
+// The alert is removed as soon as the rule condition is no longer met.
+type Alert struct {
+	RuleName string         `gorm:"primaryKey;column:rule_name"`
+	PeerId   PeerIdentifier `gorm:"primaryKey;column:peer_identifier"`
+
+	InterfaceId InterfaceIdentifier `gorm:"column:interface_identifier"`
+	PeerName    string              `gorm:"column:peer_name"` // the display name of the peer at the time the alert fired
+	Message     string              `gorm:"column:message"`   // a short description of the rule violation
+
+	FiredAt        time.Time      `gorm:"column:fired_at"`
+	LastNotifiedAt *time.Time     `gorm:"column:last_notified_at"` // nil if no notification was sent yet, for example, because the alert is silenced
+	AcknowledgedAt *time.Time     `gorm:"column:acknowledged_at"`
+	AcknowledgedBy UserIdentifier `gorm:"column:acknowledged_by"`
+}
+
+// IsAcknowledged returns true if the alert was acknowledged by an administrator.
+// Acknowledged alerts do not send repeated notifications.
+func (a Alert) IsAcknowledged() bool {
+	return a.AcknowledgedAt != nil
+}
+
+// AlertSilence suppresses notifications for matching alerts within the given time window.
+type AlertSilence struct {
+	Id uint64 `gorm:"primaryKey;autoIncrement;column:id"`
+
+	RuleName string         `gorm:"column:rule_name"`       // the rule that is silenced, empty for all rules
+	PeerId   PeerIdentifier `gorm:"column:peer_identifier"` // the peer that is silenced, empty for all peers
+
+	StartsAt  time.Time      `gorm:"column:starts_at"`
+	EndsAt    time.Time      `gorm:"column:ends_at"`
+	Comment   string         `gorm:"column:comment"`
+	CreatedBy UserIdentifier `gorm:"column:created_by"`
+	CreatedAt time.Time      `gorm:"column:created_at"`
+}
+
+// IsActive returns true if the silence window contains the given time.
+func (s AlertSilence) IsActive(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// IsExpired returns true if the silence window ended before the given time.
+func (s AlertSilence) IsExpired(now time.Time) bool {
+	return !now.Before(s.EndsAt)
+}
+
+// Matches returns true if the silence is active and applies to the given rule and peer.
+func (s AlertSilence) Matches(ruleName string, peerId PeerIdentifier, now time.Time) bool {
+	if !s.IsActive(now) {
+		return false
+	}
+	if s.RuleName != "" && s.RuleName != ruleName {
+		return false
+	}
+	if s.PeerId != "" && s.PeerId != peerId {
+		return false
+	}
+	return true
+}
+
+// HasTag returns true if the notes of the peer contain the given tag. The comparison is case-insensitive.
+func (p *Peer) HasTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+
+	return strings.Contains(strings.ToLower(p.Notes), strings.ToLower(tag))
+}
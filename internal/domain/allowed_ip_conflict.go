@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// AllowedIPConflict describes two peers of the same interface whose allowed IPs on the interface overlap. WireGuard
+// routes every address to exactly one peer, so the traffic for the overlapping network silently reaches only one of
+// the peers.
+type AllowedIPConflict struct {
+	InterfaceIdentifier InterfaceIdentifier
+	PeerId              PeerIdentifier
+	PeerName            string
+	Network             Cidr // the allowed IP of the peer
+	OtherPeerId         PeerIdentifier
+	OtherPeerName       string
+	OtherNetwork        Cidr // the overlapping allowed IP of the other peer
+}
+
+// String returns a human-readable description of the conflict.
+func (c AllowedIPConflict) String() string {
+	return fmt.Sprintf("%s of peer %s overlaps with %s of peer %s", c.Network.String(), peerLabel(c.PeerId, c.PeerName),
+		c.OtherNetwork.String(), peerLabel(c.OtherPeerId, c.OtherPeerName))
+}
+
+// SameOverlap returns true if both conflicts describe the same overlap with the same other peer, regardless of the
+// identifier of the peer itself.
+func (c AllowedIPConflict) SameOverlap(other AllowedIPConflict) bool {
+	return c.OtherPeerId == other.OtherPeerId &&
+		c.Network.String() == other.Network.String() &&
+		c.OtherNetwork.String() == other.OtherNetwork.String()
+}
+
+// AllowedIPConflictReport is the result of a consistency check of the allowed IPs of all peers of an interface.
+type AllowedIPConflictReport struct {
+	InterfaceIdentifier InterfaceIdentifier
+	CheckedAt           time.Time
+	Conflicts           []AllowedIPConflict
+}
+
+// FindAllowedIPConflicts returns all overlapping allowed IPs of the given peers, each pair of peers is only reported
+// once. Disabled peers are not applied to the interface, so they never conflict.
+func FindAllowedIPConflicts(peers []Peer) []AllowedIPConflict {
+	var conflicts []AllowedIPConflict
+	for i := range peers {
+		conflicts = append(conflicts, PeerAllowedIPConflicts(&peers[i], peers[i+1:])...)
+	}
+
+	return conflicts
+}
+
+// PeerAllowedIPConflicts returns the allowed IPs of the peer that overlap with the allowed IPs of the other peers
+// of the same interface. The peer itself and disabled peers are skipped.
+func PeerAllowedIPConflicts(peer *Peer, others []Peer) []AllowedIPConflict {
+	if peer.IsDisabled() {
+		return nil
+	}
+
+	networks := peerInterfaceAllowedIPs(peer)
+
+	var conflicts []AllowedIPConflict
+	for _, other := range others {
+		if other.Identifier == peer.Identifier || other.IsDisabled() ||
+			other.InterfaceIdentifier != peer.InterfaceIdentifier {
+			continue
+		}
+		for _, otherNetwork := range peerInterfaceAllowedIPs(&other) {
+			for _, network := range networks {
+				if !network.Prefix().Masked().Overlaps(otherNetwork.Prefix().Masked()) {
+					continue
+				}
+				conflicts = append(conflicts, AllowedIPConflict{
+					InterfaceIdentifier: peer.InterfaceIdentifier,
+					PeerId:              peer.Identifier,
+					PeerName:            peer.DisplayName,
+					Network:             network,
+					OtherPeerId:         other.Identifier,
+					OtherPeerName:       other.DisplayName,
+					OtherNetwork:        otherNetwork,
+				})
+			}
+		}
+	}
+
+	return conflicts
+}
+
+func peerLabel(id PeerIdentifier, name string) string {
+	if name == "" {
+		return string(id)
+	}
+	return name
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindAllowedIPConflicts(t *testing.T) {
+	alice := diagnosisTestPeer("alice", "10.0.0.2/24")
+	bob := diagnosisTestPeer("bob", "10.0.0.3/24")
+	gateway := diagnosisTestPeer("gateway", "10.0.0.4/24")
+	gateway.ExtraAllowedIPsStr = "10.0.0.0/30, 192.168.1.0/24"
+	site := diagnosisTestPeer("site", "10.0.0.5/24")
+	site.ExtraAllowedIPsStr = "192.168.0.0/16"
+
+	assert.Empty(t, FindAllowedIPConflicts([]Peer{alice, bob}))
+
+	conflicts := FindAllowedIPConflicts([]Peer{alice, bob, gateway, site})
+	require.Len(t, conflicts, 3)
+	assert.Equal(t, PeerIdentifier("alice"), conflicts[0].PeerId)
+	assert.Equal(t, PeerIdentifier("gateway"), conflicts[0].OtherPeerId)
+	assert.Equal(t, "10.0.0.0/30", conflicts[0].OtherNetwork.String())
+	assert.Equal(t, PeerIdentifier("bob"), conflicts[1].PeerId)
+	assert.Equal(t, "192.168.1.0/24 of peer gateway overlaps with 192.168.0.0/16 of peer site",
+		conflicts[2].String())
+
+	now := time.Now()
+	gateway.Disabled = &now
+	assert.Empty(t, FindAllowedIPConflicts([]Peer{alice, bob, gateway, site}), "disabled peers never conflict")
+}
+
+func TestPeerAllowedIPConflicts(t *testing.T) {
+	peer := diagnosisTestPeer("peer", "10.0.0.2/24")
+	duplicate := diagnosisTestPeer("duplicate", "10.0.0.2/24")
+	other := diagnosisTestPeer("other", "10.0.0.2/24")
+	other.InterfaceIdentifier = "wg1"
+
+	conflicts := PeerAllowedIPConflicts(&peer, []Peer{peer, duplicate, other})
+	require.Len(t, conflicts, 1, "the peer itself and peers of other interfaces are skipped")
+	assert.Equal(t, PeerIdentifier("duplicate"), conflicts[0].OtherPeerId)
+
+	renamed := diagnosisTestPeer("renamed", "10.0.0.2/24")
+	assert.True(t, PeerAllowedIPConflicts(&renamed, []Peer{duplicate})[0].SameOverlap(conflicts[0]))
+}
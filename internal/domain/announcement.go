@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal"
+)
+
+// AnnouncementKind describes the purpose of an announcement. The kind is used to style the banner in the portal.
+type AnnouncementKind string
+
+const (
+	AnnouncementKindInfo        AnnouncementKind = "info"
+	AnnouncementKindMaintenance AnnouncementKind = "maintenance"
+	AnnouncementKindPolicy      AnnouncementKind = "policy"
+)
+
+// IsValid returns true if the announcement kind is known.
+func (k AnnouncementKind) IsValid() bool {
+	switch k {
+	case AnnouncementKindInfo, AnnouncementKindMaintenance, AnnouncementKindPolicy:
+		return true
+	default:
+		return false
+	}
+}
+
+// Announcement is a message of the administrators, for example, a maintenance window or a policy change.
+// Active announcements are shown as banner in the portal to all targeted users until they mark them as read.
+type Announcement struct {
+	Id uint64 `gorm:"primaryKey;autoIncrement;column:id"`
+
+	Kind    AnnouncementKind `gorm:"column:kind"`
+	Title   string           `gorm:"column:title"`
+	Message string           `gorm:"column:message"`
+
+	StartsAt time.Time  `gorm:"column:starts_at"` // the announcement is shown from this time on
+	EndsAt   *time.Time `gorm:"column:ends_at"`   // the announcement is hidden after this time, nil to show it until it is deleted
+
+	TargetGroupsStr string     `gorm:"column:target_groups_str"` // the targeted user groups, comma separated, empty for all users
+	SendMail        bool       `gorm:"column:send_mail"`         // send the announcement by mail to the targeted users once it starts
+	MailedAt        *time.Time `gorm:"column:mailed_at"`         // nil if the announcement was not mailed yet
+
+	CreatedBy UserIdentifier `gorm:"column:created_by"`
+	CreatedAt time.Time      `gorm:"column:created_at"`
+	UpdatedAt time.Time      `gorm:"column:updated_at"`
+}
+
+// Validate performs checks to ensure that the announcement is valid.
+func (a *Announcement) Validate() error {
+	if !a.Kind.IsValid() {
+		return fmt.Errorf("invalid announcement kind %q", a.Kind)
+	}
+	if strings.TrimSpace(a.Title) == "" {
+		return errors.New("announcement title must not be empty")
+	}
+	if a.StartsAt.IsZero() {
+		return errors.New("announcement start time must be set")
+	}
+	if a.EndsAt != nil && !a.EndsAt.After(a.StartsAt) {
+		return errors.New("announcement must end after it starts")
+	}
+
+	for _, id := range a.TargetGroups() {
+		if !userGroupIdentifierPattern.MatchString(string(id)) {
+			return fmt.Errorf("invalid user group identifier %s", id)
+		}
+	}
+
+	return nil
+}
+
+// IsActive returns true if the announcement is shown at the given time.
+func (a *Announcement) IsActive(now time.Time) bool {
+	if now.Before(a.StartsAt) {
+		return false
+	}
+	return a.EndsAt == nil || now.Before(*a.EndsAt)
+}
+
+// TargetGroups returns the identifiers of the targeted user groups. An empty list targets all users.
+func (a *Announcement) TargetGroups() []UserGroupIdentifier {
+	var groups []UserGroupIdentifier
+	for _, id := range internal.SliceString(a.TargetGroupsStr) {
+		if !slices.Contains(groups, UserGroupIdentifier(id)) {
+			groups = append(groups, UserGroupIdentifier(id))
+		}
+	}
+	return groups
+}
+
+// IsTargeted returns true if the announcement targets a member of the given groups.
+func (a *Announcement) IsTargeted(groups []UserGroup) bool {
+	targets := a.TargetGroups()
+	if len(targets) == 0 {
+		return true
+	}
+
+	for _, group := range groups {
+		if slices.Contains(targets, group.Identifier) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AnnouncementRead records that a user marked an announcement as read.
+type AnnouncementRead struct {
+	AnnouncementId uint64         `gorm:"primaryKey;column:announcement_id"`
+	UserIdentifier UserIdentifier `gorm:"primaryKey;column:user_identifier"`
+	ReadAt         time.Time      `gorm:"column:read_at"`
+}
+
+// AnnouncementStatus is an announcement together with its read state. For administrators, the number of users that
+// read the announcement is included as well.
+type AnnouncementStatus struct {
+	Announcement
+
+	Read      bool // true if the current user marked the announcement as read
+	ReadCount int  // the number of users that marked the announcement as read
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnouncement_Validate(t *testing.T) {
+	start := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+
+	assert.NoError(t, (&Announcement{Kind: AnnouncementKindMaintenance, Title: "Maintenance", StartsAt: start,
+		EndsAt: &end, TargetGroupsStr: "lab-staff"}).Validate())
+	assert.Error(t, (&Announcement{Kind: "urgent", Title: "Maintenance", StartsAt: start}).Validate())
+	assert.Error(t, (&Announcement{Kind: AnnouncementKindInfo, Title: " ", StartsAt: start}).Validate())
+	assert.Error(t, (&Announcement{Kind: AnnouncementKindInfo, Title: "News"}).Validate(), "missing start")
+	assert.Error(t, (&Announcement{Kind: AnnouncementKindInfo, Title: "News", StartsAt: end, EndsAt: &start}).Validate())
+	assert.Error(t, (&Announcement{Kind: AnnouncementKindInfo, Title: "News", StartsAt: start,
+		TargetGroupsStr: "Lab Staff"}).Validate())
+}
+
+func TestAnnouncement_IsActive(t *testing.T) {
+	start := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	announcement := Announcement{StartsAt: start, EndsAt: &end}
+
+	assert.False(t, announcement.IsActive(start.Add(-time.Minute)))
+	assert.True(t, announcement.IsActive(start))
+	assert.True(t, announcement.IsActive(end.Add(-time.Minute)))
+	assert.False(t, announcement.IsActive(end))
+
+	announcement.EndsAt = nil
+	assert.True(t, announcement.IsActive(end.Add(24*time.Hour)), "announcements without end stay active")
+}
+
+func TestAnnouncement_IsTargeted(t *testing.T) {
+	groups := []UserGroup{{Identifier: "lab-staff"}, {Identifier: "ops"}}
+
+	assert.True(t, (&Announcement{}).IsTargeted(nil), "announcements without groups target all users")
+	assert.True(t, (&Announcement{TargetGroupsStr: "ops, finance"}).IsTargeted(groups))
+	assert.False(t, (&Announcement{TargetGroupsStr: "finance"}).IsTargeted(groups))
+	assert.False(t, (&Announcement{TargetGroupsStr: "finance"}).IsTargeted(nil))
+}
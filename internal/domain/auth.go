@@ -18,3 +18,30 @@ type AuthenticatorUserInfo struct {
 	Department string
 	IsAdmin    bool
 }
+
+// OidcSession identifies the single sign-on session at an OpenID Connect provider that a portal session was
+// started from. The provider can end the portal session with a back-channel logout.
+type OidcSession struct {
+	Provider  string
+	Subject   string
+	SessionId string // optional, not all providers issue session identifiers
+}
+
+// IsEndedBy returns true if the given back-channel logout ends the session. A logout with session identifier ends
+// only that session, a logout with subject only ends all sessions of the subject.
+func (s OidcSession) IsEndedBy(logout OidcSession) bool {
+	if s.Provider == "" || s.Provider != logout.Provider {
+		return false
+	}
+	if logout.SessionId == "" && logout.Subject == "" {
+		return false
+	}
+	if logout.SessionId != "" && logout.SessionId != s.SessionId {
+		return false
+	}
+	if logout.Subject != "" && logout.Subject != s.Subject {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOidcSession_IsEndedBy(t *testing.T) {
+	session := OidcSession{Provider: "keycloak", Subject: "sub-1", SessionId: "sid-1"}
+
+	tests := []struct {
+		name   string
+		logout OidcSession
+		want   bool
+	}{
+		{"SessionId", OidcSession{Provider: "keycloak", SessionId: "sid-1"}, true},
+		{"SubjectAndSessionId", OidcSession{Provider: "keycloak", Subject: "sub-1", SessionId: "sid-1"}, true},
+		{"Subject", OidcSession{Provider: "keycloak", Subject: "sub-1"}, true},
+		{"OtherSessionId", OidcSession{Provider: "keycloak", Subject: "sub-1", SessionId: "sid-2"}, false},
+		{"OtherSubject", OidcSession{Provider: "keycloak", Subject: "sub-2"}, false},
+		{"OtherProvider", OidcSession{Provider: "google", SessionId: "sid-1"}, false},
+		{"Empty", OidcSession{Provider: "keycloak"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, session.IsEndedBy(tt.logout))
+		})
+	}
+
+	assert.False(t, OidcSession{}.IsEndedBy(OidcSession{Subject: "sub-1"}), "local sessions are never ended")
+}
@@ -59,6 +59,12 @@ const (
 	DisabledReasonLdapMissing      = "missing in ldap"
 	DisabledReasonMigrationDummy   = "migration dummy user"
 	DisabledReasonInterfaceMissing = "missing WireGuard interface"
+	DisabledReasonInactive         = "inactive"
+	DisabledReasonSchedule         = "outside access schedule"
+	DisabledReasonEmergency        = "emergency lockdown"
+	DisabledReasonArchived         = "archived duplicate"
+	DisabledReasonRoaming          = "roaming policy violation"
+	DisabledReasonCompromised      = "key compromised"
 
 	LockedReasonAdmin = "locked by admin"
 	LockedReasonApi   = "locked by admin"
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// maxExhaustionProjection limits the projected exhaustion date, pools that last longer are not exhausted in practice.
+const maxExhaustionProjection = 100 * 365 * 24 * time.Hour
+
+// AddressPoolCapacity is the utilization of a peer network (address pool) of an interface.
+type AddressPoolCapacity struct {
+	InterfaceId InterfaceIdentifier
+	Network     Cidr
+
+	Size uint64 // the number of usable addresses, large IPv6 networks are capped at math.MaxUint64
+	Used uint64 // the number of addresses that are assigned to the interface or to peers
+
+	GrowthPerDay float64    // the number of addresses that were assigned per day within the growth window
+	ExhaustedAt  *time.Time // the projected exhaustion date, nil if the pool does not grow
+}
+
+// NewAddressPoolCapacity calculates the capacity of the given network. The used addresses might contain
+// addresses outside the network, they are ignored. The growth is based on the number of addresses that were
+// allocated within the given window.
+func NewAddressPoolCapacity(
+	iface InterfaceIdentifier,
+	network Cidr,
+	used []Cidr,
+	allocated int,
+	window time.Duration,
+	now time.Time,
+) AddressPoolCapacity {
+	c := AddressPoolCapacity{
+		InterfaceId: iface,
+		Network:     network.NetworkAddr(),
+		Size:        addressPoolSize(network),
+	}
+
+	usedAddrs := make(map[string]struct{}, len(used))
+	for _, addr := range used {
+		if network.Contains(addr) {
+			usedAddrs[addr.Addr] = struct{}{}
+		}
+	}
+	c.Used = min(uint64(len(usedAddrs)), c.Size)
+
+	if window > 0 && allocated > 0 {
+		c.GrowthPerDay = float64(allocated) / (window.Hours() / 24)
+	}
+
+	switch {
+	case c.Free() == 0:
+		c.ExhaustedAt = &now
+	case c.GrowthPerDay > 0:
+		remaining := float64(c.Free()) / c.GrowthPerDay * float64(24*time.Hour)
+		if remaining < float64(maxExhaustionProjection) {
+			exhaustedAt := now.Add(time.Duration(remaining))
+			c.ExhaustedAt = &exhaustedAt
+		}
+	}
+
+	return c
+}
+
+// addressPoolSize returns the number of addresses of the network that can be assigned. The network address and,
+// for IPv4, the broadcast address cannot be assigned.
+func addressPoolSize(network Cidr) uint64 {
+	prefix := network.Prefix()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits >= 64 {
+		return math.MaxUint64
+	}
+
+	size := uint64(1) << hostBits
+	switch {
+	case prefix.Addr().Is4() && hostBits >= 2:
+		return size - 2
+	case !prefix.Addr().Is4() && hostBits >= 1:
+		return size - 1
+	default:
+		return size // point-to-point networks
+	}
+}
+
+// Free returns the number of addresses that are still available.
+func (c AddressPoolCapacity) Free() uint64 {
+	return c.Size - c.Used
+}
+
+// Utilization returns the used share of the address pool in percent.
+func (c AddressPoolCapacity) Utilization() float64 {
+	if c.Size == 0 {
+		return 100
+	}
+
+	return float64(c.Used) / float64(c.Size) * 100
+}
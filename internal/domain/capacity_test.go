@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustCidrs(t *testing.T, strs ...string) []Cidr {
+	cidrs, err := CidrsFromArray(strs)
+	require.NoError(t, err)
+	return cidrs
+}
+
+func TestNewAddressPoolCapacity(t *testing.T) {
+	now := time.Now()
+	network := mustCidrs(t, "10.0.0.1/24")[0]
+	used := mustCidrs(t, "10.0.0.1/24", "10.0.0.2/32", "10.0.0.3/32", "10.0.0.3/32", "10.0.1.2/32")
+
+	c := NewAddressPoolCapacity("wg0", network, used, 10, 10*24*time.Hour, now)
+	assert.Equal(t, "10.0.0.0/24", c.Network.String())
+	assert.Equal(t, uint64(254), c.Size)
+	assert.Equal(t, uint64(3), c.Used, "duplicates and addresses of other networks are ignored")
+	assert.Equal(t, uint64(251), c.Free())
+	assert.InDelta(t, 1.18, c.Utilization(), 0.01)
+	assert.InDelta(t, 1.0, c.GrowthPerDay, 0.001)
+	require.NotNil(t, c.ExhaustedAt)
+	assert.WithinDuration(t, now.Add(251*24*time.Hour), *c.ExhaustedAt, time.Second)
+
+	// without growth, the pool is never exhausted
+	c = NewAddressPoolCapacity("wg0", network, used, 0, 10*24*time.Hour, now)
+	assert.Nil(t, c.ExhaustedAt)
+}
+
+func TestNewAddressPoolCapacity_exhausted(t *testing.T) {
+	now := time.Now()
+	network := mustCidrs(t, "10.0.0.0/30")[0]
+
+	c := NewAddressPoolCapacity("wg0", network, mustCidrs(t, "10.0.0.1/32", "10.0.0.2/32"), 0, time.Hour, now)
+	assert.Equal(t, uint64(2), c.Size)
+	assert.Equal(t, float64(100), c.Utilization())
+	require.NotNil(t, c.ExhaustedAt)
+	assert.Equal(t, now, *c.ExhaustedAt)
+}
+
+func TestNewAddressPoolCapacity_ipv6(t *testing.T) {
+	network := mustCidrs(t, "fd00::/64")[0]
+
+	c := NewAddressPoolCapacity("wg0", network, mustCidrs(t, "fd00::1/128"), 1000, 24*time.Hour, time.Now())
+	assert.Equal(t, uint64(math.MaxUint64), c.Size)
+	assert.Nil(t, c.ExhaustedAt, "large networks are not exhausted within the projection limit")
+
+	c = NewAddressPoolCapacity("wg0", mustCidrs(t, "fd00::/120")[0], nil, 0, time.Hour, time.Now())
+	assert.Equal(t, uint64(255), c.Size)
+}
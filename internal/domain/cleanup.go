@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+type PeerCleanupStage string
+
+const (
+	PeerCleanupStageWarn    PeerCleanupStage = "warn"    // the owner will be warned in the current run
+	PeerCleanupStagePending PeerCleanupStage = "pending" // the owner was warned, waiting for the warning period
+	PeerCleanupStageAction  PeerCleanupStage = "action"  // the cleanup action will be taken in the current run
+)
+
+// PeerCleanupNotice stores the time at which the owner of an inactive peer was warned about the upcoming cleanup.
+type PeerCleanupNotice struct {
+	PeerId   PeerIdentifier `gorm:"primaryKey;column:identifier"`
+	WarnedAt time.Time      `gorm:"column:warned_at"`
+}
+
+// PeerCleanupCandidate describes an inactive peer that is affected by the cleanup policy.
+type PeerCleanupCandidate struct {
+	Peer         Peer
+	LastActivity time.Time        // the last handshake or modification of the peer
+	WarnedAt     *time.Time       // the time the owner was warned, nil if no warning was sent yet
+	ActionAt     time.Time        // the earliest time at which the cleanup action is taken
+	Stage        PeerCleanupStage // the stage of the cleanup process
+}
+
+// PeerLastActivity returns the time of the last handshake or, if it is more recent, the last modification of the peer.
+func PeerLastActivity(peer Peer, status *PeerStatus) time.Time {
+	lastActivity := peer.UpdatedAt
+	if lastActivity.Before(peer.CreatedAt) {
+		lastActivity = peer.CreatedAt
+	}
+	if status != nil && status.LastHandshake != nil && status.LastHandshake.After(lastActivity) {
+		lastActivity = *status.LastHandshake
+	}
+
+	return lastActivity
+}
+
+// IsExcludedFromCleanup returns true if the notes of the peer contain the given exclusion tag.
+func (p *Peer) IsExcludedFromCleanup(exclusionTag string) bool {
+	return p.HasTag(exclusionTag)
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ClientOsWindows = "windows"
+	ClientOsMacOs   = "macos"
+	ClientOsLinux   = "linux"
+	ClientOsIos     = "ios"
+	ClientOsAndroid = "android"
+)
+
+// ConfigPullClient describes the client that pulls its configuration.
+type ConfigPullClient struct {
+	Ip      string
+	Os      string // the normalized operating system, see NormalizeClientOs
+	Version string // the app version, empty if the client did not report it
+}
+
+// ParseConfigPullClient returns the client information of a config pull request. Explicitly reported values
+// take precedence over the user agent, which is expected in the form "<app>/<version> (<os>; ...)".
+func ParseConfigPullClient(ip, userAgent, os, version string) ConfigPullClient {
+	client := ConfigPullClient{
+		Ip:      ip,
+		Os:      NormalizeClientOs(os),
+		Version: strings.TrimSpace(version),
+	}
+
+	product, comment, _ := strings.Cut(userAgent, " ")
+	if client.Version == "" {
+		if _, uaVersion, ok := strings.Cut(product, "/"); ok {
+			client.Version = uaVersion
+		}
+	}
+	if client.Os == "" {
+		comment = strings.Trim(strings.TrimSpace(comment), "()")
+		uaOs, _, _ := strings.Cut(comment, ";")
+		client.Os = NormalizeClientOs(uaOs)
+	}
+
+	return client
+}
+
+// NormalizeClientOs maps common operating system names to one of the ClientOs constants.
+// Unknown names are returned in lower case.
+func NormalizeClientOs(os string) string {
+	os = strings.ToLower(strings.TrimSpace(os))
+	switch {
+	case strings.HasPrefix(os, "win"):
+		return ClientOsWindows
+	case strings.HasPrefix(os, "mac"), os == "darwin", os == "osx":
+		return ClientOsMacOs
+	case strings.HasPrefix(os, "iphone"), strings.HasPrefix(os, "ipad"), os == "ios":
+		return ClientOsIos
+	case strings.HasPrefix(os, "android"):
+		return ClientOsAndroid
+	case strings.HasPrefix(os, "linux"):
+		return ClientOsLinux
+	default:
+		return os
+	}
+}
+
+// CompareClientVersions compares two dot separated version strings, for example "0.5.3" and "0.5.10".
+// A leading "v" and suffixes like "-beta" are ignored. The result is -1 if a < b, 0 if a == b, and 1 if a > b.
+func CompareClientVersions(a, b string) int {
+	partsA := clientVersionParts(a)
+	partsB := clientVersionParts(b)
+	for i := 0; i < max(len(partsA), len(partsB)); i++ {
+		var valA, valB int
+		if i < len(partsA) {
+			valA = partsA[i]
+		}
+		if i < len(partsB) {
+			valB = partsB[i]
+		}
+		switch {
+		case valA < valB:
+			return -1
+		case valA > valB:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func clientVersionParts(version string) []int {
+	version = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(version)), "v")
+	version, _, _ = strings.Cut(version, "-")
+	version, _, _ = strings.Cut(version, "+")
+
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		value, _ := strconv.Atoi(part)
+		parts = append(parts, value)
+	}
+
+	return parts
+}
+
+// ClientUpdateCampaign notifies the owners of peers whose clients reported an outdated app version.
+type ClientUpdateCampaign struct {
+	MinVersions map[string]string // the minimum app version per operating system, for example "windows": "0.5.3"
+	DryRun      bool              // if true, no mails are sent
+}
+
+// OutdatedClient is a peer whose client reported an app version below the minimum version.
+type OutdatedClient struct {
+	Peer         Peer
+	Os           string
+	Version      string
+	MinVersion   string
+	LastPulledAt time.Time
+}
+
+// ClientUpdateCampaignReport is the result of a client update campaign.
+type ClientUpdateCampaignReport struct {
+	Clients       []OutdatedClient
+	NotifiedUsers []UserIdentifier // the users that were notified, empty for dry runs
+}
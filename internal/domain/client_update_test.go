@@ -0,0 +1,34 @@
+package domain
+
+import "testing"
+
+func TestCompareClientVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"0.5.3", "0.5.3", 0},
+		{"0.5.3", "0.5.10", -1},
+		{"v1.0.20220627", "1.0.20210914", 1},
+		{"1.2", "1.2.0", 0},
+		{"1.3.0-beta", "1.3.0", 0},
+		{"", "0.1", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareClientVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareClientVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseConfigPullClient(t *testing.T) {
+	client := ParseConfigPullClient("192.0.2.1", "wg-agent/1.4.2 (Windows NT 10.0; x64)", "", "")
+	if client.Os != ClientOsWindows || client.Version != "1.4.2" {
+		t.Errorf("unexpected client from user agent: %+v", client)
+	}
+
+	client = ParseConfigPullClient("192.0.2.1", "curl/8.5.0", "Darwin", "0.5.3")
+	if client.Os != ClientOsMacOs || client.Version != "0.5.3" {
+		t.Errorf("explicit values must take precedence: %+v", client)
+	}
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// PeerCompromise is a security incident for a peer whose keys are no longer trusted, for example because the device
+// of the peer was lost or stolen. The peer is disabled immediately.
+type PeerCompromise struct {
+	PeerIdentifier      PeerIdentifier
+	PeerName            string
+	InterfaceIdentifier InterfaceIdentifier
+	UserIdentifier      UserIdentifier // the owner of the peer, empty if the peer has no owner
+	Reason              string
+
+	ReportedBy UserIdentifier
+	ReportedAt time.Time
+
+	// RotatedPeers lists the other peers of the owner on the same interface that got a new preshared key.
+	RotatedPeers []PeerIdentifier
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"time"
+)
+
+// ConfigPullToken is a long-lived secret that allows a client to fetch the latest configuration of a peer.
+// Only the SHA-256 hash of the token is stored.
+type ConfigPullToken struct {
+	TokenHash      string         `gorm:"primaryKey;column:token_hash"`
+	PeerId         PeerIdentifier `gorm:"uniqueIndex;column:peer_identifier"` // each peer has at most one token
+	UserIdentifier UserIdentifier `gorm:"column:user_identifier"`             // the owner of the peer at creation time
+
+	CreatedAt      time.Time  `gorm:"column:created_at"`
+	CreatedBy      string     `gorm:"column:created_by"`
+	LastPulledAt   *time.Time `gorm:"column:last_pulled_at"`
+	LastPulledFrom string     `gorm:"column:last_pulled_from"` // the client IP address of the last pull
+	ClientOs       string     `gorm:"column:client_os"`        // the operating system reported by the client
+	ClientVersion  string     `gorm:"column:client_version"`   // the app version reported by the client
+}
+
+// ConfigPullProvisioning contains everything a device needs to fetch its configuration via the config pull API.
+// It is encoded as URI in provisioning QR codes, which are much smaller than QR codes of the full configuration.
+type ConfigPullProvisioning struct {
+	PullUrl    string
+	Token      string // the plain text pull token
+	SigningKey string // the base64 encoded Ed25519 key that verifies the config signatures, empty if signing is disabled
+}
+
+// Uri returns the provisioning URI, for example: wgportal://config-pull?url=https%3A%2F%2F...&token=wgp_...
+// Devices that pin the inlined signing key can verify the pulled configurations without fetching the key first.
+func (p ConfigPullProvisioning) Uri() string {
+	query := url.Values{}
+	query.Set("url", p.PullUrl)
+	query.Set("token", p.Token)
+	if p.SigningKey != "" {
+		query.Set("key", p.SigningKey)
+	}
+
+	return (&url.URL{Scheme: "wgportal", Host: "config-pull", RawQuery: query.Encode()}).String()
+}
+
+// HashConfigPullToken returns the hex encoded SHA-256 hash of the given token.
+func HashConfigPullToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigPullProvisioning_Uri(t *testing.T) {
+	provisioning := ConfigPullProvisioning{
+		PullUrl: "https://vpn.example.com/api/v1/provisioning/config-pull",
+		Token:   "wgp_abc",
+	}
+
+	uri, err := url.Parse(provisioning.Uri())
+	require.NoError(t, err)
+	assert.Equal(t, "wgportal", uri.Scheme)
+	assert.Equal(t, "config-pull", uri.Host)
+	assert.Equal(t, provisioning.PullUrl, uri.Query().Get("url"))
+	assert.Equal(t, "wgp_abc", uri.Query().Get("token"))
+	assert.False(t, uri.Query().Has("key"), "the key is only set if config signing is enabled")
+
+	provisioning.SigningKey = "a+b/c="
+	uri, err = url.Parse(provisioning.Uri())
+	require.NoError(t, err)
+	assert.Equal(t, "a+b/c=", uri.Query().Get("key"))
+}
@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// ConfigReload is the result of a config reload.
+type ConfigReload struct {
+	ReloadedAt      time.Time
+	Applied         []string // the changed config sections that were applied
+	RestartRequired []string // the changed config sections that are applied on the next restart
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+)
+
+// ConfigSignaturePrefix starts the comment line that holds the signature of a signed configuration file.
+// The signature is always the last line of the file and covers all bytes preceding it.
+const ConfigSignaturePrefix = "# -WGP- Signature: "
+
+// SignConfig appends an Ed25519 signature comment to the given configuration file.
+func SignConfig(key ed25519.PrivateKey, data []byte) []byte {
+	signed := make([]byte, 0, len(data)+len(ConfigSignaturePrefix)+90)
+	signed = append(signed, data...)
+	if len(signed) > 0 && signed[len(signed)-1] != '\n' {
+		signed = append(signed, '\n')
+	}
+
+	signature := ed25519.Sign(key, signed)
+	signed = append(signed, ConfigSignaturePrefix...)
+	signed = append(signed, base64.StdEncoding.EncodeToString(signature)...)
+	signed = append(signed, '\n')
+
+	return signed
+}
+
+// SplitConfigSignature separates a signed configuration file into the signed content and the raw signature.
+func SplitConfigSignature(data []byte) (content, signature []byte, err error) {
+	trimmed := bytes.TrimSuffix(data, []byte("\n"))
+	idx := bytes.LastIndexByte(trimmed, '\n') + 1
+	lastLine, ok := bytes.CutPrefix(trimmed[idx:], []byte(ConfigSignaturePrefix))
+	if !ok {
+		return nil, nil, errors.New("configuration is not signed")
+	}
+
+	signature, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(lastLine)))
+	if err != nil {
+		return nil, nil, errors.New("invalid signature encoding")
+	}
+
+	return data[:idx], signature, nil
+}
+
+// VerifyConfig checks the embedded signature of the given configuration file.
+func VerifyConfig(key ed25519.PublicKey, data []byte) error {
+	content, signature, err := SplitConfigSignature(data)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(key, content, signature) {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
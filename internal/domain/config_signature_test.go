@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignConfig(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	config := []byte("[Interface]\nPrivateKey = abc\n\n[Peer]\nPublicKey = def")
+	signed := SignConfig(private, config)
+	assert.Contains(t, string(signed), "\n"+ConfigSignaturePrefix)
+	require.NoError(t, VerifyConfig(public, signed))
+
+	content, _, err := SplitConfigSignature(signed)
+	require.NoError(t, err)
+	assert.Equal(t, string(config)+"\n", string(content))
+
+	// modified configurations are rejected
+	tampered := []byte("[Interface]\nPrivateKey = xyz" + string(signed[len("[Interface]\nPrivateKey = abc"):]))
+	assert.Error(t, VerifyConfig(public, tampered))
+
+	otherPublic, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	assert.Error(t, VerifyConfig(otherPublic, signed))
+
+	assert.Error(t, VerifyConfig(public, config), "unsigned configurations are rejected")
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 )
 
 const CtxUserInfo = "userInfo"
+const CtxClientInfo = "clientInfo"
 
 const (
 	CtxSystemAdminId    = "_WG_SYS_ADMIN_"
@@ -19,16 +22,78 @@ const (
 type ContextUserInfo struct {
 	Id      UserIdentifier
 	IsAdmin bool
+
+	// IsHelpdesk is set for helpdesk users. They can view the peers of other users, resend configuration mails and
+	// reset the passkeys of users, but they can neither create or delete peers nor view private keys.
+	IsHelpdesk bool
+
+	// AdminInterfaces contains the interfaces the user administrates without global admin rights.
+	AdminInterfaces []InterfaceIdentifier
+
+	// Organization restricts the admin rights of the user to the resources of the given organization.
+	// Users without organization are not restricted.
+	Organization OrganizationIdentifier
+
+	// ImpersonatedBy is set if an administrator currently acts on behalf of the user.
+	ImpersonatedBy UserIdentifier
+
+	// AuthenticatedAt is the time of the last (re-)authentication of the user, zero if unknown.
+	AuthenticatedAt time.Time
 }
 
 func (u *ContextUserInfo) String() string {
 	return fmt.Sprintf("%s|%t", u.Id, u.IsAdmin)
 }
 
+// IsInterfaceAdmin returns true if the user is an admin of the organization that owns the given interface (global
+// admins included) or an administrator of the interface.
+func (u *ContextUserInfo) IsInterfaceAdmin(iface *Interface) bool {
+	return u.IsOrganizationAdmin(iface.OrganizationIdentifier) || slices.Contains(u.AdminInterfaces, iface.Identifier)
+}
+
+// IsOrganizationAdmin returns true if the user is a global admin or an admin of the given organization.
+func (u *ContextUserInfo) IsOrganizationAdmin(id OrganizationIdentifier) bool {
+	return u.IsAdmin && u.CanAccessOrganization(id)
+}
+
+// HasAdminInterfaces returns true if the user is a global admin or administrates at least one interface.
+func (u *ContextUserInfo) HasAdminInterfaces() bool {
+	return u.IsAdmin || len(u.AdminInterfaces) > 0
+}
+
+// HasHelpdeskRights returns true if the user is an admin or a helpdesk user.
+func (u *ContextUserInfo) HasHelpdeskRights() bool {
+	return u.IsAdmin || u.IsHelpdesk
+}
+
+// IsGlobalAdmin returns true if the user is an admin that is not restricted to an organization.
+func (u *ContextUserInfo) IsGlobalAdmin() bool {
+	return u.IsAdmin && u.Organization == ""
+}
+
+// CanAccessOrganization returns true if the user is allowed to access resources of the given organization.
+func (u *ContextUserInfo) CanAccessOrganization(id OrganizationIdentifier) bool {
+	return u.Organization == "" || u.Organization == id
+}
+
+// AuthenticatedWithin returns true if the user (re-)authenticated within the given duration.
+func (u *ContextUserInfo) AuthenticatedWithin(d time.Duration) bool {
+	return !u.AuthenticatedAt.IsZero() && time.Since(u.AuthenticatedAt) <= d
+}
+
 func (u *ContextUserInfo) UserId() string {
 	return string(u.Id)
 }
 
+// AuditUserId returns the user id that should be recorded in audit entries.
+// If the user is impersonated, the identifier of the impersonating administrator is included.
+func (u *ContextUserInfo) AuditUserId() string {
+	if u.ImpersonatedBy != "" {
+		return fmt.Sprintf("%s (impersonated by %s)", u.Id, u.ImpersonatedBy)
+	}
+	return string(u.Id)
+}
+
 // DefaultContextUserInfo returns a default context user info.
 func DefaultContextUserInfo() *ContextUserInfo {
 	return &ContextUserInfo{
@@ -73,22 +138,93 @@ func GetUserInfo(ctx context.Context) *ContextUserInfo {
 	return DefaultContextUserInfo()
 }
 
-// ValidateUserAccessRights checks if the current user has access rights to the requested user.
-// If the user is an admin, access is granted.
-func ValidateUserAccessRights(ctx context.Context, requiredUser UserIdentifier) error {
+// SetClientInfo sets the information about the requesting client in the context.
+func SetClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, CtxClientInfo, info)
+}
+
+// GetClientInfo returns the information about the requesting client from the context, or nil if the context does
+// not originate from a request of the web interface.
+func GetClientInfo(ctx context.Context) *ClientInfo {
+	if info, ok := ctx.Value(CtxClientInfo).(ClientInfo); ok {
+		return &info
+	}
+
+	return nil
+}
+
+// ValidateUserAccessRights checks if the current user has access rights to the requested user. The organization is
+// the organization that owns the requested data, for example the organization of the user or of the interface of a
+// peer. Admins of this organization are granted access. If interfaces are given, access is also granted to
+// administrators of one of these interfaces, for example to the administrators of the interface of a peer.
+func ValidateUserAccessRights(
+	ctx context.Context,
+	requiredUser UserIdentifier,
+	org OrganizationIdentifier,
+	interfaces ...InterfaceIdentifier,
+) error {
 	sessionUser := GetUserInfo(ctx)
 
-	if sessionUser.IsAdmin {
-		return nil // Admins can do everything
+	if sessionUser.IsOrganizationAdmin(org) {
+		return nil // Admins can do everything within their organization
 	}
 
 	if sessionUser.Id == requiredUser {
 		return nil // User can access own data
 	}
 
+	for _, iface := range interfaces {
+		if slices.Contains(sessionUser.AdminInterfaces, iface) {
+			return nil // Interface admins can access all data of their interfaces
+		}
+	}
+
 	slog.Warn("insufficient permissions",
 		"user", sessionUser.Id,
 		"requiredUser", requiredUser,
+		"organization", sessionUser.Organization,
+		"requiredOrganization", org,
+		"stack", GetStackTrace())
+	return ErrNoPermission
+}
+
+// ValidateUserReadAccessRights checks if the current user may view the requested user and its peers. Besides the
+// users that pass ValidateUserAccessRights, helpdesk users of the given organization are allowed. Private keys must
+// never be revealed to helpdesk users.
+func ValidateUserReadAccessRights(ctx context.Context, requiredUser UserIdentifier, org OrganizationIdentifier) error {
+	sessionUser := GetUserInfo(ctx)
+	if sessionUser.IsHelpdesk && sessionUser.CanAccessOrganization(org) {
+		return nil
+	}
+
+	return ValidateUserAccessRights(ctx, requiredUser, org)
+}
+
+// ValidatePeerAccessRights checks if the current session user may use the given peer of the given interface. Besides
+// the users that pass ValidateUserAccessRights for the owner of the peer, all users the peer is shared with are
+// allowed.
+func ValidatePeerAccessRights(ctx context.Context, peer *Peer, iface *Interface) error {
+	if peer.IsMember(GetUserInfo(ctx).Id) {
+		return nil // the user owns the peer or the peer is shared with the user
+	}
+
+	return ValidateUserAccessRights(ctx, peer.UserIdentifier, iface.OrganizationIdentifier, iface.Identifier)
+}
+
+// ValidateInterfaceAdminAccessRights checks if the current user has admin access rights for the given interface.
+// Admins that are restricted to an organization only administrate the interfaces of their organization.
+func ValidateInterfaceAdminAccessRights(ctx context.Context, iface *Interface) error {
+	sessionUser := GetUserInfo(ctx)
+
+	if sessionUser.IsInterfaceAdmin(iface) {
+		return nil
+	}
+
+	slog.Warn("insufficient interface admin permissions",
+		"user", sessionUser.Id,
+		"interface", iface.Identifier,
+		"organization", sessionUser.Organization,
+		"requiredOrganization", iface.OrganizationIdentifier,
 		"stack", GetStackTrace())
 	return ErrNoPermission
 }
@@ -106,3 +242,51 @@ func ValidateAdminAccessRights(ctx context.Context) error {
 		"stack", GetStackTrace())
 	return ErrNoPermission
 }
+
+// ValidateHelpdeskAccessRights checks if the current user has helpdesk access rights. Admins have all helpdesk
+// rights.
+func ValidateHelpdeskAccessRights(ctx context.Context) error {
+	sessionUser := GetUserInfo(ctx)
+
+	if sessionUser.HasHelpdeskRights() {
+		return nil
+	}
+
+	slog.Warn("insufficient helpdesk permissions",
+		"user", sessionUser.Id,
+		"stack", GetStackTrace())
+	return ErrNoPermission
+}
+
+// ValidateGlobalAdminAccessRights checks if the current user has admin access rights that are not restricted to an
+// organization.
+func ValidateGlobalAdminAccessRights(ctx context.Context) error {
+	sessionUser := GetUserInfo(ctx)
+
+	if sessionUser.IsGlobalAdmin() {
+		return nil
+	}
+
+	slog.Warn("insufficient global admin permissions",
+		"user", sessionUser.Id,
+		"organization", sessionUser.Organization,
+		"stack", GetStackTrace())
+	return ErrNoPermission
+}
+
+// ValidateOrganizationAccessRights checks if the current user has access rights to resources of the given
+// organization.
+func ValidateOrganizationAccessRights(ctx context.Context, id OrganizationIdentifier) error {
+	sessionUser := GetUserInfo(ctx)
+
+	if sessionUser.CanAccessOrganization(id) {
+		return nil
+	}
+
+	slog.Warn("insufficient organization permissions",
+		"user", sessionUser.Id,
+		"organization", sessionUser.Organization,
+		"requiredOrganization", id,
+		"stack", GetStackTrace())
+	return ErrNoPermission
+}
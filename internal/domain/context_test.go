@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextUserInfo_AuditUserId(t *testing.T) {
+	info := &ContextUserInfo{Id: "user@example.com"}
+	assert.Equal(t, "user@example.com", info.AuditUserId())
+
+	info.ImpersonatedBy = "admin@example.com"
+	assert.Equal(t, "user@example.com (impersonated by admin@example.com)", info.AuditUserId())
+	assert.Equal(t, "user@example.com", info.UserId())
+}
+
+func TestContextUserInfo_AuthenticatedWithin(t *testing.T) {
+	info := &ContextUserInfo{Id: "user@example.com"}
+	assert.False(t, info.AuthenticatedWithin(time.Hour))
+
+	info.AuthenticatedAt = time.Now().Add(-10 * time.Minute)
+	assert.True(t, info.AuthenticatedWithin(time.Hour))
+	assert.False(t, info.AuthenticatedWithin(5*time.Minute))
+}
+
+func TestValidateUserAccessRights_interfaceAdmin(t *testing.T) {
+	ctx := SetUserInfo(context.Background(), &ContextUserInfo{
+		Id:              "admin@example.com",
+		AdminInterfaces: []InterfaceIdentifier{"wg0"},
+	})
+
+	assert.NoError(t, ValidateUserAccessRights(ctx, "admin@example.com", ""))
+	assert.ErrorIs(t, ValidateUserAccessRights(ctx, "user@example.com", ""), ErrNoPermission)
+	assert.NoError(t, ValidateUserAccessRights(ctx, "user@example.com", "", "wg0"))
+	assert.ErrorIs(t, ValidateUserAccessRights(ctx, "user@example.com", "", "wg1"), ErrNoPermission)
+
+	assert.NoError(t, ValidateInterfaceAdminAccessRights(ctx, &Interface{Identifier: "wg0"}))
+	assert.ErrorIs(t, ValidateInterfaceAdminAccessRights(ctx, &Interface{Identifier: "wg1"}), ErrNoPermission)
+	assert.ErrorIs(t, ValidateAdminAccessRights(ctx), ErrNoPermission)
+
+	adminCtx := SetUserInfo(context.Background(), SystemAdminContextUserInfo())
+	assert.NoError(t, ValidateInterfaceAdminAccessRights(adminCtx, &Interface{Identifier: "wg1"}))
+}
+
+func TestValidateInterfaceAdminAccessRights_organization(t *testing.T) {
+	ctx := SetUserInfo(context.Background(), &ContextUserInfo{
+		Id:           "admin@org-a.example.com",
+		IsAdmin:      true,
+		Organization: "org-a",
+	})
+	ifaceA := &Interface{Identifier: "wg0", OrganizationIdentifier: "org-a"}
+	ifaceB := &Interface{Identifier: "wg1", OrganizationIdentifier: "org-b"}
+	globalIface := &Interface{Identifier: "wg2"}
+
+	assert.True(t, GetUserInfo(ctx).IsInterfaceAdmin(ifaceA))
+	assert.False(t, GetUserInfo(ctx).IsInterfaceAdmin(ifaceB))
+	assert.False(t, GetUserInfo(ctx).IsInterfaceAdmin(globalIface))
+
+	assert.NoError(t, ValidateInterfaceAdminAccessRights(ctx, ifaceA))
+	assert.ErrorIs(t, ValidateInterfaceAdminAccessRights(ctx, ifaceB), ErrNoPermission)
+	assert.ErrorIs(t, ValidateInterfaceAdminAccessRights(ctx, globalIface), ErrNoPermission)
+
+	assert.NoError(t, ValidateUserAccessRights(ctx, "user@org-a.example.com", "org-a"))
+	assert.ErrorIs(t, ValidateUserAccessRights(ctx, "user@org-b.example.com", "org-b"), ErrNoPermission)
+	assert.ErrorIs(t, ValidateUserAccessRights(ctx, "user@org-b.example.com", "org-b", "wg1"), ErrNoPermission)
+
+	peer := &Peer{UserIdentifier: "user@org-b.example.com", InterfaceIdentifier: "wg1"}
+	assert.ErrorIs(t, ValidatePeerAccessRights(ctx, peer, ifaceB), ErrNoPermission)
+
+	adminCtx := SetUserInfo(context.Background(), SystemAdminContextUserInfo())
+	assert.NoError(t, ValidateInterfaceAdminAccessRights(adminCtx, ifaceB))
+	assert.NoError(t, ValidateUserAccessRights(adminCtx, "user@org-b.example.com", "org-b"))
+}
+
+func TestValidatePeerAccessRights(t *testing.T) {
+	peer := &Peer{UserIdentifier: "owner", InterfaceIdentifier: "wg0", SharedUsersStr: "member"}
+	iface := &Interface{Identifier: "wg0"}
+
+	memberCtx := SetUserInfo(context.Background(), &ContextUserInfo{Id: "member"})
+	assert.NoError(t, ValidatePeerAccessRights(memberCtx, peer, iface))
+
+	otherCtx := SetUserInfo(context.Background(), &ContextUserInfo{Id: "other"})
+	assert.ErrorIs(t, ValidatePeerAccessRights(otherCtx, peer, iface), ErrNoPermission)
+
+	adminCtx := SetUserInfo(context.Background(), &ContextUserInfo{
+		Id:              "admin",
+		AdminInterfaces: []InterfaceIdentifier{"wg0"},
+	})
+	assert.NoError(t, ValidatePeerAccessRights(adminCtx, peer, iface))
+}
+
+func TestValidateHelpdeskAccessRights(t *testing.T) {
+	ctx := SetUserInfo(context.Background(), &ContextUserInfo{Id: "helpdesk", IsHelpdesk: true})
+
+	assert.NoError(t, ValidateHelpdeskAccessRights(ctx))
+	assert.NoError(t, ValidateUserReadAccessRights(ctx, "user@example.com", ""))
+	assert.ErrorIs(t, ValidateUserAccessRights(ctx, "user@example.com", ""), ErrNoPermission)
+	assert.ErrorIs(t, ValidateAdminAccessRights(ctx), ErrNoPermission)
+
+	userCtx := SetUserInfo(context.Background(), &ContextUserInfo{Id: "other"})
+	assert.ErrorIs(t, ValidateHelpdeskAccessRights(userCtx), ErrNoPermission)
+	assert.ErrorIs(t, ValidateUserReadAccessRights(userCtx, "user@example.com", ""), ErrNoPermission)
+	assert.NoError(t, ValidateUserReadAccessRights(userCtx, "other", ""))
+
+	adminCtx := SetUserInfo(context.Background(), SystemAdminContextUserInfo())
+	assert.NoError(t, ValidateHelpdeskAccessRights(adminCtx))
+}
+
+func TestValidateOrganizationAccessRights(t *testing.T) {
+	ctx := SetUserInfo(context.Background(), &ContextUserInfo{
+		Id:           "admin@acme.example.com",
+		IsAdmin:      true,
+		Organization: "acme",
+	})
+
+	assert.NoError(t, ValidateAdminAccessRights(ctx))
+	assert.ErrorIs(t, ValidateGlobalAdminAccessRights(ctx), ErrNoPermission)
+	assert.NoError(t, ValidateOrganizationAccessRights(ctx, "acme"))
+	assert.ErrorIs(t, ValidateOrganizationAccessRights(ctx, "globex"), ErrNoPermission)
+	assert.ErrorIs(t, ValidateOrganizationAccessRights(ctx, ""), ErrNoPermission)
+
+	adminCtx := SetUserInfo(context.Background(), SystemAdminContextUserInfo())
+	assert.NoError(t, ValidateGlobalAdminAccessRights(adminCtx))
+	assert.NoError(t, ValidateOrganizationAccessRights(adminCtx, "globex"))
+}
+
+func TestGetClientInfo(t *testing.T) {
+	assert.Nil(t, GetClientInfo(context.Background()))
+
+	ctx := SetClientInfo(context.Background(), ClientInfo{IpAddress: "198.51.100.7"})
+	info := GetClientInfo(ctx)
+	if assert.NotNil(t, info) {
+		assert.Equal(t, "198.51.100.7", info.IpAddress)
+	}
+}
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+type DeviceAuthorizationState string
+
+const (
+	DeviceAuthorizationPending  DeviceAuthorizationState = "pending"
+	DeviceAuthorizationApproved DeviceAuthorizationState = "approved"
+	DeviceAuthorizationDenied   DeviceAuthorizationState = "denied"
+)
+
+// Errors returned while a device polls for the authorization result.
+// The error messages correspond to the error codes defined in RFC 8628, section 3.5.
+var (
+	ErrDeviceAuthorizationPending = errors.New("authorization_pending")
+	ErrDeviceSlowDown             = errors.New("slow_down")
+	ErrDeviceAccessDenied         = errors.New("access_denied")
+	ErrDeviceExpiredToken         = errors.New("expired_token")
+)
+
+// userCodeAlphabet contains the characters of user codes. Vowels and ambiguous characters are omitted,
+// as recommended in RFC 8628, section 6.1.
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// UserCodeLength is the number of characters of a user code, excluding the separator.
+const UserCodeLength = 8
+
+// DeviceAuthorization is a pending or completed OAuth2 device authorization request (RFC 8628).
+type DeviceAuthorization struct {
+	DeviceCode string // the secret code the device uses to poll for the result
+	UserCode   string // the short code the user confirms in the browser, normalized without separator
+
+	ClientName          string              // the name the device sent with the request, used as peer name
+	InterfaceIdentifier InterfaceIdentifier // the interface requested by the device, may be empty
+	PublicKey           string              // the public key generated by the device, may be empty
+
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	Interval   time.Duration // the minimum poll interval of the device
+	LastPollAt time.Time
+
+	State          DeviceAuthorizationState
+	UserIdentifier UserIdentifier // the user that approved or denied the request
+	PeerIdentifier PeerIdentifier // the peer whose configuration is handed to the device
+}
+
+// IsExpired returns true if the device authorization request expired.
+func (d *DeviceAuthorization) IsExpired(now time.Time) bool {
+	return !now.Before(d.ExpiresAt)
+}
+
+// FormattedUserCode returns the user code with a separator in the middle, e.g. "BCDF-GHJK".
+func (d *DeviceAuthorization) FormattedUserCode() string {
+	return FormatUserCode(d.UserCode)
+}
+
+// FormatUserCode adds a separator in the middle of the given normalized user code.
+func FormatUserCode(code string) string {
+	if len(code) != UserCodeLength {
+		return code
+	}
+
+	return code[:UserCodeLength/2] + "-" + code[UserCodeLength/2:]
+}
+
+// NormalizeUserCode converts user input to the normalized user code representation.
+// Letters are upper-cased, separators and whitespace are removed.
+func NormalizeUserCode(input string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(input) {
+		if strings.ContainsRune(userCodeAlphabet, r) {
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
+
+// NewUserCode creates a user code from the given random bytes. Bytes that would bias the character
+// distribution are skipped, so the input should contain more than UserCodeLength bytes.
+// The returned code is shorter than UserCodeLength if not enough usable bytes were given.
+func NewUserCode(random []byte) string {
+	limit := 256 - 256%len(userCodeAlphabet)
+
+	var sb strings.Builder
+	for _, b := range random {
+		if sb.Len() == UserCodeLength {
+			break
+		}
+		if int(b) >= limit {
+			continue
+		}
+		sb.WriteByte(userCodeAlphabet[int(b)%len(userCodeAlphabet)])
+	}
+
+	return sb.String()
+}
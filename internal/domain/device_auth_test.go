@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeUserCode(t *testing.T) {
+	assert.Equal(t, "BCDFGHJK", NormalizeUserCode("bcdf-ghjk"))
+	assert.Equal(t, "BCDFGHJK", NormalizeUserCode(" BCDF GHJK "))
+	assert.Equal(t, "", NormalizeUserCode("AEIOU-01"))
+}
+
+func TestNewUserCode(t *testing.T) {
+	code := NewUserCode([]byte{0, 1, 2, 3, 19, 20, 21, 255, 42})
+	assert.Equal(t, "BCDFZBCD", code)
+	assert.Equal(t, code, NormalizeUserCode(FormatUserCode(code)))
+	assert.Equal(t, "BCDF-ZBCD", FormatUserCode(code))
+}
+
+func TestDeviceAuthorization_IsExpired(t *testing.T) {
+	now := time.Now()
+	d := DeviceAuthorization{ExpiresAt: now.Add(time.Minute)}
+	assert.False(t, d.IsExpired(now))
+	assert.True(t, d.IsExpired(now.Add(time.Minute)))
+}
@@ -0,0 +1,25 @@
+package domain
+
+// PeerDeviceType is the kind of device that uses a peer. It is used to filter the peer list, for the device type
+// policies and in the configuration mail.
+type PeerDeviceType string
+
+const (
+	PeerDeviceTypeUnknown PeerDeviceType = ""
+	PeerDeviceTypeLaptop  PeerDeviceType = "laptop"
+	PeerDeviceTypePhone   PeerDeviceType = "phone"
+	PeerDeviceTypeServer  PeerDeviceType = "server"
+	PeerDeviceTypeRouter  PeerDeviceType = "router"
+	PeerDeviceTypeIoT     PeerDeviceType = "iot"
+)
+
+// IsValid returns true if the device type is known.
+func (t PeerDeviceType) IsValid() bool {
+	switch t {
+	case PeerDeviceTypeUnknown, PeerDeviceTypeLaptop, PeerDeviceTypePhone, PeerDeviceTypeServer, PeerDeviceTypeRouter,
+		PeerDeviceTypeIoT:
+		return true
+	default:
+		return false
+	}
+}
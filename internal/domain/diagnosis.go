@@ -0,0 +1,356 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HandshakeTimeout is the time after which WireGuard drops the session of a peer if no new handshake happened
+	// (REJECT_AFTER_TIME of the WireGuard protocol).
+	HandshakeTimeout = 3 * time.Minute
+	// DefaultTunnelMtu is the MTU that WireGuard clients use if the configuration does not set an MTU.
+	DefaultTunnelMtu = 1420
+	// maxClockSkew is the tolerated difference between the clock of the server and the reported handshake times.
+	maxClockSkew = 1 * time.Minute
+)
+
+// DiagnosisCheck identifies a check of the connection diagnosis of a peer.
+type DiagnosisCheck string
+
+const (
+	DiagnosisCheckState      DiagnosisCheck = "state"       // the peer and its interface are enabled
+	DiagnosisCheckKeys       DiagnosisCheck = "keys"        // the keys of the configuration match the interface
+	DiagnosisCheckAllowedIPs DiagnosisCheck = "allowed-ips" // the allowed IPs of the peer are unique
+	DiagnosisCheckHandshake  DiagnosisCheck = "handshake"   // the peer completed a recent handshake
+	DiagnosisCheckEndpoint   DiagnosisCheck = "endpoint"    // the endpoint of the configuration is reachable
+	DiagnosisCheckMtu        DiagnosisCheck = "mtu"         // the tunnel MTU fits the path to the peer
+	DiagnosisCheckClock      DiagnosisCheck = "clock"       // the clocks do not break the handshake replay protection
+)
+
+type DiagnosisStatus string
+
+const (
+	DiagnosisStatusOk      DiagnosisStatus = "ok"
+	DiagnosisStatusWarning DiagnosisStatus = "warning" // a possible cause of connection problems
+	DiagnosisStatusProblem DiagnosisStatus = "problem" // a cause that breaks the connection
+	DiagnosisStatusSkipped DiagnosisStatus = "skipped" // the check could not be performed
+)
+
+// DiagnosisFinding is the result of a single check of the connection diagnosis.
+type DiagnosisFinding struct {
+	Check      DiagnosisCheck
+	Status     DiagnosisStatus
+	Likelihood int    // 0 to 100, how likely the finding is the cause of connection problems
+	Message    string // a short description of the result
+	Hint       string // how to solve the problem, empty for passed checks
+}
+
+// IsCause returns true if the finding is a likely cause of connection problems.
+func (f DiagnosisFinding) IsCause() bool {
+	return f.Status == DiagnosisStatusProblem || f.Status == DiagnosisStatusWarning
+}
+
+// PeerDiagnosis is the result of the connection diagnosis of a peer, it helps to answer "the VPN does not work"
+// tickets.
+type PeerDiagnosis struct {
+	PeerId      PeerIdentifier
+	InterfaceId InterfaceIdentifier
+	CheckedAt   time.Time
+	Findings    []DiagnosisFinding // the most likely cause first, see Rank
+}
+
+// Add appends the given findings to the diagnosis.
+func (d *PeerDiagnosis) Add(findings ...DiagnosisFinding) {
+	d.Findings = append(d.Findings, findings...)
+}
+
+// Rank sorts the findings by their likelihood, problems are ranked before warnings of the same likelihood.
+// Passed and skipped checks are moved to the end.
+func (d *PeerDiagnosis) Rank() {
+	sort.SliceStable(d.Findings, func(i, j int) bool {
+		a, b := d.Findings[i], d.Findings[j]
+		if a.IsCause() != b.IsCause() {
+			return a.IsCause()
+		}
+		if a.Likelihood != b.Likelihood {
+			return a.Likelihood > b.Likelihood
+		}
+		return a.Status == DiagnosisStatusProblem && b.Status != DiagnosisStatusProblem
+	})
+}
+
+// LikelyCauses returns the findings that likely cause connection problems, the most likely cause first.
+func (d *PeerDiagnosis) LikelyCauses() []DiagnosisFinding {
+	var causes []DiagnosisFinding
+	for _, finding := range d.Findings {
+		if finding.IsCause() {
+			causes = append(causes, finding)
+		}
+	}
+	return causes
+}
+
+// DiagnosePeerState checks that the peer and its interface are enabled.
+func DiagnosePeerState(peer *Peer, iface *Interface) DiagnosisFinding {
+	finding := DiagnosisFinding{Check: DiagnosisCheckState, Status: DiagnosisStatusProblem, Likelihood: 100}
+
+	switch {
+	case iface.IsDisabled():
+		finding.Message = fmt.Sprintf("interface %s is disabled", iface.Identifier)
+		finding.Hint = "Enable the interface."
+	case peer.IsDisabled():
+		finding.Message = "the peer is disabled"
+		if peer.DisabledReason != "" {
+			finding.Message += ": " + peer.DisabledReason
+		}
+		finding.Hint = "Enable the peer if the reason for the deactivation no longer applies."
+	case peer.IsExpired():
+		finding.Message = fmt.Sprintf("the peer expired at %s", peer.ExpiresAt.Format(time.RFC3339))
+		finding.Hint = "Extend the expiry date of the peer."
+	default:
+		return DiagnosisFinding{Check: DiagnosisCheckState, Status: DiagnosisStatusOk,
+			Message: "the peer and the interface are enabled"}
+	}
+
+	return finding
+}
+
+// DiagnosePeerKeys checks that the keys of the peer configuration are consistent and match the interface.
+// The physical peer is the state of the peer on the WireGuard interface, it is nil if the peer is missing there.
+// If the state of the WireGuard interface is unknown, physicalKnown is false.
+func DiagnosePeerKeys(peer *Peer, iface *Interface, physical *PhysicalPeer, physicalKnown bool) DiagnosisFinding {
+	finding := DiagnosisFinding{Check: DiagnosisCheckKeys, Status: DiagnosisStatusProblem}
+
+	switch {
+	case physicalKnown && physical == nil && !peer.IsDisabled() && !iface.IsDisabled():
+		finding.Likelihood = 95
+		finding.Message = fmt.Sprintf("the peer is not configured on WireGuard interface %s", iface.Identifier)
+		finding.Hint = "Save the peer again to apply it to the WireGuard interface."
+	case peer.Interface.PrivateKey != "" &&
+		PublicKeyFromPrivateKey(peer.Interface.PrivateKey) != peer.Interface.PublicKey:
+		finding.Likelihood = 90
+		finding.Message = "the private key of the peer does not match its public key"
+		finding.Hint = "Generate a new key pair for the peer and send the new configuration to the user."
+	case iface.Type == InterfaceTypeServer && peer.EndpointPublicKey.GetValue() != "" &&
+		peer.EndpointPublicKey.GetValue() != iface.PublicKey:
+		finding.Likelihood = 90
+		finding.Message = "the configuration of the peer contains an outdated public key of the server"
+		finding.Hint = "Update the endpoint public key of the peer and send the new configuration to the user."
+	case physical != nil && physical.PresharedKey != "" && physical.PresharedKey != peer.PresharedKey:
+		finding.Likelihood = 85
+		finding.Message = "the pre-shared key on the WireGuard interface differs from the stored pre-shared key"
+		finding.Hint = "Save the peer again to apply the stored pre-shared key to the WireGuard interface."
+	default:
+		finding.Status = DiagnosisStatusOk
+		finding.Message = "the keys of the peer match the interface"
+	}
+
+	return finding
+}
+
+// DiagnosePeerAllowedIPs checks that the allowed IPs of the peer on the interface do not overlap with the allowed IPs
+// of the other enabled peers of the interface. WireGuard routes overlapping networks to only one of the peers.
+func DiagnosePeerAllowedIPs(peer *Peer, others []Peer) DiagnosisFinding {
+	allowedIPs := peerInterfaceAllowedIPs(peer)
+	if len(allowedIPs) == 0 {
+		return DiagnosisFinding{
+			Check:      DiagnosisCheckAllowedIPs,
+			Status:     DiagnosisStatusProblem,
+			Likelihood: 80,
+			Message:    "the peer has no allowed IPs, WireGuard drops all of its traffic",
+			Hint:       "Assign an IP address to the peer.",
+		}
+	}
+
+	var overlaps []string
+	for _, other := range others {
+		if other.Identifier == peer.Identifier || other.IsDisabled() {
+			continue
+		}
+		for _, otherIP := range peerInterfaceAllowedIPs(&other) {
+			if overlapsAny(otherIP, allowedIPs) {
+				name := other.DisplayName
+				if name == "" {
+					name = string(other.Identifier)
+				}
+				overlaps = append(overlaps, fmt.Sprintf("%s (%s)", name, otherIP))
+				break
+			}
+		}
+	}
+	if len(overlaps) > 0 {
+		return DiagnosisFinding{
+			Check:      DiagnosisCheckAllowedIPs,
+			Status:     DiagnosisStatusProblem,
+			Likelihood: 80,
+			Message:    "the allowed IPs overlap with the peers " + strings.Join(overlaps, ", "),
+			Hint:       "Remove the overlapping addresses or extra allowed IPs, only one peer receives the traffic.",
+		}
+	}
+
+	return DiagnosisFinding{
+		Check:   DiagnosisCheckAllowedIPs,
+		Status:  DiagnosisStatusOk,
+		Message: "the allowed IPs are unique on the interface: " + CidrsToString(allowedIPs),
+	}
+}
+
+// peerInterfaceAllowedIPs returns the allowed IPs of the peer on the WireGuard interface.
+func peerInterfaceAllowedIPs(peer *Peer) []Cidr {
+	var pp PhysicalPeer
+	MergeToPhysicalPeer(&pp, peer)
+	return pp.AllowedIPs
+}
+
+// DiagnosePeerHandshake checks the last handshake of the peer. If ping checks are enabled, it also checks that a
+// connected peer answers the pings through the tunnel.
+func DiagnosePeerHandshake(status *PeerStatus, now time.Time, pingChecks bool) DiagnosisFinding {
+	finding := DiagnosisFinding{Check: DiagnosisCheckHandshake}
+
+	switch {
+	case status == nil || status.LastHandshake == nil || status.LastHandshake.IsZero():
+		finding.Status = DiagnosisStatusProblem
+		finding.Likelihood = 70
+		finding.Message = "the peer never completed a handshake"
+		finding.Hint = "Check that the device imported the current configuration and that UDP traffic to the " +
+			"endpoint is not blocked."
+	case now.Sub(*status.LastHandshake) > HandshakeTimeout:
+		finding.Status = DiagnosisStatusWarning
+		finding.Likelihood = 40
+		finding.Message = fmt.Sprintf("the last handshake was %s ago",
+			now.Sub(*status.LastHandshake).Truncate(time.Second))
+		finding.Hint = "Idle devices without persistent keepalive do not handshake. If the device is active, " +
+			"check its internet connection and that UDP traffic to the endpoint is not blocked."
+	case pingChecks && !status.IsPingable:
+		finding.Status = DiagnosisStatusWarning
+		finding.Likelihood = 55
+		finding.Message = "the handshake is recent, but the peer does not answer pings through the tunnel"
+		finding.Hint = "Check the allowed IPs of the device configuration and the firewall of the device."
+	default:
+		finding.Status = DiagnosisStatusOk
+		finding.Message = fmt.Sprintf("the last handshake was %s ago",
+			max(now.Sub(*status.LastHandshake), 0).Truncate(time.Second))
+	}
+
+	return finding
+}
+
+// DiagnoseEndpoint checks the endpoint that the peer configuration connects to. The addresses are the resolved
+// addresses of the endpoint host. For server interfaces, the listen port of the interface is passed, otherwise 0.
+func DiagnoseEndpoint(endpoint string, addrs []netip.Addr, resolveErr error, listenPort int) DiagnosisFinding {
+	finding := DiagnosisFinding{Check: DiagnosisCheckEndpoint, Status: DiagnosisStatusProblem, Likelihood: 75}
+
+	if endpoint == "" {
+		finding.Message = "the configuration of the peer contains no endpoint"
+		finding.Hint = "Set the endpoint of the peer, or the default endpoint of the interface."
+		return finding
+	}
+	host, portStr, err := net.SplitHostPort(endpoint)
+	port, portErr := strconv.Atoi(portStr)
+	if err != nil || portErr != nil {
+		finding.Message = fmt.Sprintf("the endpoint %s is invalid, expected host:port", endpoint)
+		finding.Hint = "Correct the endpoint of the peer."
+		return finding
+	}
+	if resolveErr != nil || len(addrs) == 0 {
+		finding.Message = fmt.Sprintf("the endpoint host %s cannot be resolved", host)
+		finding.Hint = "Check the DNS record of the endpoint host."
+		return finding
+	}
+
+	finding.Status = DiagnosisStatusWarning
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if listenPort > 0 && (addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast()) {
+			finding.Likelihood = 50
+			finding.Message = fmt.Sprintf("the endpoint %s resolves to the private address %s", endpoint, addr)
+			finding.Hint = "Devices outside of the local network cannot reach a private address, use the public " +
+				"address or DNS name of the server."
+			return finding
+		}
+	}
+	if listenPort > 0 && port != listenPort {
+		finding.Likelihood = 45
+		finding.Message = fmt.Sprintf("the endpoint port %d differs from the listen port %d", port, listenPort)
+		finding.Hint = "This is only correct if the port is forwarded to the listen port of the interface."
+		return finding
+	}
+
+	return DiagnosisFinding{
+		Check:   DiagnosisCheckEndpoint,
+		Status:  DiagnosisStatusOk,
+		Message: fmt.Sprintf("the endpoint %s resolves to %s", endpoint, addrs[0]),
+	}
+}
+
+// DiagnosePeerMtu checks that the tunnel MTUs of the peer and the interface fit the path to the peer. If the MTU is
+// too large, the handshake works but large packets are lost, so for example websites do not load.
+func DiagnosePeerMtu(peer *Peer, iface *Interface, pathMtu *PathMtu) DiagnosisFinding {
+	if pathMtu == nil {
+		return DiagnosisFinding{
+			Check:   DiagnosisCheckMtu,
+			Status:  DiagnosisStatusSkipped,
+			Message: "the path MTU to the peer is unknown",
+		}
+	}
+
+	maxMtu := pathMtu.TunnelMtu()
+	peerMtu := peer.Interface.Mtu.GetValue()
+	if peerMtu <= 0 {
+		peerMtu = DefaultTunnelMtu
+	}
+
+	finding := DiagnosisFinding{Check: DiagnosisCheckMtu, Status: DiagnosisStatusWarning, Likelihood: 45}
+	switch {
+	case peerMtu > maxMtu:
+		finding.Message = fmt.Sprintf("the tunnel MTU %d of the peer exceeds the MTU %d of the path via %s",
+			peerMtu, maxMtu, pathMtu.LinkName)
+		finding.Hint = fmt.Sprintf("Set the MTU of the peer to %d.", maxMtu)
+	case iface.Mtu > maxMtu:
+		finding.Message = fmt.Sprintf("the MTU %d of interface %s exceeds the MTU %d of the path via %s",
+			iface.Mtu, iface.Identifier, maxMtu, pathMtu.LinkName)
+		finding.Hint = fmt.Sprintf("Set the MTU of the interface to %d.", maxMtu)
+	default:
+		finding.Status = DiagnosisStatusOk
+		finding.Likelihood = 0
+		finding.Message = fmt.Sprintf("the tunnel MTU %d fits the MTU %d of the path", peerMtu, maxMtu)
+	}
+
+	return finding
+}
+
+// DiagnosePeerClock checks the handshake times for signs of clock skew. WireGuard rejects handshake initiations
+// with a timestamp older than the last accepted one, so a clock that was set back breaks new handshakes.
+func DiagnosePeerClock(status *PeerStatus, now time.Time) DiagnosisFinding {
+	finding := DiagnosisFinding{Check: DiagnosisCheckClock, Status: DiagnosisStatusWarning}
+
+	switch {
+	case status == nil || status.LastHandshake == nil || status.LastHandshake.IsZero():
+		return DiagnosisFinding{
+			Check:   DiagnosisCheckClock,
+			Status:  DiagnosisStatusSkipped,
+			Message: "the peer never completed a handshake",
+		}
+	case status.LastHandshake.Sub(now) > maxClockSkew:
+		finding.Likelihood = 50
+		finding.Message = fmt.Sprintf("the last handshake at %s lies in the future, the server clock is wrong or "+
+			"was changed", status.LastHandshake.Format(time.RFC3339))
+		finding.Hint = "Synchronize the server clock, for example with NTP, and restart the interface afterwards."
+	case now.Sub(*status.LastHandshake) > HandshakeTimeout:
+		finding.Likelihood = 25
+		finding.Message = "the peer connected before, but no recent handshake happened"
+		finding.Hint = "If the clock of the device was set back since the last handshake, WireGuard drops its " +
+			"handshakes as replays. Correct the device clock or restart the interface."
+	default:
+		finding.Status = DiagnosisStatusOk
+		finding.Message = "the handshake times show no clock skew"
+	}
+
+	return finding
+}
@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func diagnosisTestPeer(id PeerIdentifier, addresses ...string) Peer {
+	peer := Peer{
+		Identifier:          id,
+		DisplayName:         string(id),
+		InterfaceIdentifier: "wg0",
+		Interface:           PeerInterfaceConfig{Type: InterfaceTypeClient},
+	}
+	for _, address := range addresses {
+		cidr, _ := CidrFromString(address)
+		peer.Interface.Addresses = append(peer.Interface.Addresses, cidr)
+	}
+	return peer
+}
+
+func TestPeerDiagnosis_Rank(t *testing.T) {
+	d := PeerDiagnosis{}
+	d.Add(
+		DiagnosisFinding{Check: DiagnosisCheckState, Status: DiagnosisStatusOk},
+		DiagnosisFinding{Check: DiagnosisCheckClock, Status: DiagnosisStatusWarning, Likelihood: 25},
+		DiagnosisFinding{Check: DiagnosisCheckMtu, Status: DiagnosisStatusSkipped},
+		DiagnosisFinding{Check: DiagnosisCheckHandshake, Status: DiagnosisStatusProblem, Likelihood: 70},
+		DiagnosisFinding{Check: DiagnosisCheckEndpoint, Status: DiagnosisStatusWarning, Likelihood: 70},
+	)
+	d.Rank()
+
+	var order []DiagnosisCheck
+	for _, f := range d.Findings {
+		order = append(order, f.Check)
+	}
+	assert.Equal(t, []DiagnosisCheck{DiagnosisCheckHandshake, DiagnosisCheckEndpoint, DiagnosisCheckClock,
+		DiagnosisCheckState, DiagnosisCheckMtu}, order)
+	assert.Len(t, d.LikelyCauses(), 3)
+}
+
+func TestDiagnosePeerKeys(t *testing.T) {
+	keyPair, _ := NewFreshKeypair()
+	serverKeyPair, _ := NewFreshKeypair()
+	iface := &Interface{Identifier: "wg0", Type: InterfaceTypeServer, KeyPair: serverKeyPair}
+	peer := diagnosisTestPeer("peer")
+	peer.Interface.KeyPair = keyPair
+	peer.EndpointPublicKey = NewConfigOption(serverKeyPair.PublicKey, true)
+
+	assert.Equal(t, DiagnosisStatusOk, DiagnosePeerKeys(&peer, iface, &PhysicalPeer{}, true).Status)
+	assert.Equal(t, DiagnosisStatusOk, DiagnosePeerKeys(&peer, iface, nil, false).Status,
+		"unknown interface state is not a problem")
+
+	finding := DiagnosePeerKeys(&peer, iface, nil, true)
+	assert.Equal(t, DiagnosisStatusProblem, finding.Status)
+	assert.Contains(t, finding.Message, "not configured")
+
+	outdated := peer
+	outdated.EndpointPublicKey = NewConfigOption(keyPair.PublicKey, true)
+	assert.Contains(t, DiagnosePeerKeys(&outdated, iface, &PhysicalPeer{}, true).Message, "outdated public key")
+
+	broken := peer
+	broken.Interface.PublicKey = serverKeyPair.PublicKey
+	assert.Contains(t, DiagnosePeerKeys(&broken, iface, &PhysicalPeer{}, true).Message, "does not match")
+
+	psk := DiagnosePeerKeys(&peer, iface, &PhysicalPeer{PresharedKey: "other"}, true)
+	assert.Contains(t, psk.Message, "pre-shared key")
+}
+
+func TestDiagnosePeerAllowedIPs(t *testing.T) {
+	peer := diagnosisTestPeer("peer", "10.0.0.2/24")
+	other := diagnosisTestPeer("other", "10.0.0.3/24")
+	gateway := diagnosisTestPeer("gateway", "10.0.0.4/24")
+	gateway.ExtraAllowedIPsStr = "10.0.0.0/28"
+
+	assert.Equal(t, DiagnosisStatusOk, DiagnosePeerAllowedIPs(&peer, []Peer{peer, other}).Status)
+
+	finding := DiagnosePeerAllowedIPs(&peer, []Peer{peer, other, gateway})
+	assert.Equal(t, DiagnosisStatusProblem, finding.Status)
+	assert.Contains(t, finding.Message, "gateway (10.0.0.0/28)")
+
+	now := time.Now()
+	gateway.Disabled = &now
+	assert.Equal(t, DiagnosisStatusOk, DiagnosePeerAllowedIPs(&peer, []Peer{peer, gateway}).Status,
+		"disabled peers do not receive traffic")
+
+	empty := diagnosisTestPeer("empty")
+	assert.Equal(t, DiagnosisStatusProblem, DiagnosePeerAllowedIPs(&empty, nil).Status)
+}
+
+func TestDiagnosePeerHandshake(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-time.Minute)
+	stale := now.Add(-time.Hour)
+
+	assert.Equal(t, DiagnosisStatusProblem, DiagnosePeerHandshake(nil, now, false).Status)
+	assert.Equal(t, DiagnosisStatusWarning, DiagnosePeerHandshake(&PeerStatus{LastHandshake: &stale}, now, false).Status)
+	assert.Equal(t, DiagnosisStatusOk, DiagnosePeerHandshake(&PeerStatus{LastHandshake: &recent}, now, false).Status)
+
+	finding := DiagnosePeerHandshake(&PeerStatus{LastHandshake: &recent}, now, true)
+	assert.Equal(t, DiagnosisStatusWarning, finding.Status)
+	assert.Contains(t, finding.Message, "pings")
+}
+
+func TestDiagnoseEndpoint(t *testing.T) {
+	public := []netip.Addr{netip.MustParseAddr("203.0.113.1")}
+
+	assert.Equal(t, DiagnosisStatusOk, DiagnoseEndpoint("vpn.example.com:51820", public, nil, 51820).Status)
+	assert.Equal(t, DiagnosisStatusProblem, DiagnoseEndpoint("", nil, nil, 51820).Status)
+	assert.Equal(t, DiagnosisStatusProblem, DiagnoseEndpoint("vpn.example.com", nil, nil, 51820).Status)
+	assert.Equal(t, DiagnosisStatusProblem,
+		DiagnoseEndpoint("vpn.example.com:51820", nil, errors.New("no such host"), 51820).Status)
+
+	private := DiagnoseEndpoint("10.1.1.1:51820", []netip.Addr{netip.MustParseAddr("10.1.1.1")}, nil, 51820)
+	assert.Equal(t, DiagnosisStatusWarning, private.Status)
+	assert.Contains(t, private.Message, "private address")
+
+	port := DiagnoseEndpoint("vpn.example.com:443", public, nil, 51820)
+	assert.Equal(t, DiagnosisStatusWarning, port.Status)
+	assert.Contains(t, port.Message, "listen port")
+
+	assert.Equal(t, DiagnosisStatusOk, DiagnoseEndpoint("10.1.1.1:443", public, nil, 0).Status,
+		"private addresses and other ports are fine for client interfaces")
+}
+
+func TestDiagnosePeerMtu(t *testing.T) {
+	iface := &Interface{Identifier: "wg0", Mtu: 1420}
+	peer := diagnosisTestPeer("peer")
+
+	assert.Equal(t, DiagnosisStatusSkipped, DiagnosePeerMtu(&peer, iface, nil).Status)
+	assert.Equal(t, DiagnosisStatusOk, DiagnosePeerMtu(&peer, iface, &PathMtu{Mtu: 1500}).Status)
+
+	pppoe := DiagnosePeerMtu(&peer, iface, &PathMtu{Mtu: 1492, LinkName: "ppp0"})
+	assert.Equal(t, DiagnosisStatusWarning, pppoe.Status)
+	assert.Contains(t, pppoe.Hint, "1412")
+
+	peer.Interface.Mtu = NewConfigOption(1400, true)
+	finding := DiagnosePeerMtu(&peer, iface, &PathMtu{Mtu: 1492, LinkName: "ppp0"})
+	assert.Contains(t, finding.Message, "interface wg0")
+}
+
+func TestDiagnosePeerClock(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	stale := now.Add(-time.Hour)
+	recent := now.Add(-time.Minute)
+
+	assert.Equal(t, DiagnosisStatusSkipped, DiagnosePeerClock(&PeerStatus{}, now).Status)
+	assert.Equal(t, 50, DiagnosePeerClock(&PeerStatus{LastHandshake: &future}, now).Likelihood)
+	assert.Equal(t, 25, DiagnosePeerClock(&PeerStatus{LastHandshake: &stale}, now).Likelihood)
+	assert.Equal(t, DiagnosisStatusOk, DiagnosePeerClock(&PeerStatus{LastHandshake: &recent}, now).Status)
+}
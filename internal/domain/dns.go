@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+type DnsRecordType string
+
+const (
+	DnsRecordTypeA    DnsRecordType = "A"
+	DnsRecordTypeAAAA DnsRecordType = "AAAA"
+	DnsRecordTypePTR  DnsRecordType = "PTR"
+)
+
+// DnsRecordSet is a set of DNS records with the same name and type that is managed by WireGuard Portal.
+type DnsRecordSet struct {
+	Name      string         `gorm:"primaryKey;column:name"` // the fully qualified name, including the trailing dot
+	Type      DnsRecordType  `gorm:"primaryKey;column:type"`
+	Zone      string         `gorm:"column:zone"`                  // the zone that contains the record set
+	PeerId    PeerIdentifier `gorm:"index;column:peer_identifier"` // the peer that owns the record set
+	TTL       uint32         `gorm:"column:ttl"`
+	ValuesStr string         `gorm:"column:values_str"` // the record values, comma separated
+}
+
+// Values returns the record values of the record set.
+func (r DnsRecordSet) Values() []string {
+	if r.ValuesStr == "" {
+		return nil
+	}
+	return strings.Split(r.ValuesStr, ",")
+}
+
+// Equals returns true if both record sets contain the same records.
+func (r DnsRecordSet) Equals(other DnsRecordSet) bool {
+	return r.Name == other.Name && r.Type == other.Type && r.Zone == other.Zone && r.TTL == other.TTL &&
+		r.ValuesStr == other.ValuesStr
+}
+
+func (r DnsRecordSet) String() string {
+	return fmt.Sprintf("%s %s %s", r.Name, r.Type, r.ValuesStr)
+}
+
+// DnsLabel converts the given name to a valid DNS label.
+// Invalid characters are replaced by a hyphen, an empty string is returned if no valid characters remain.
+func DnsLabel(name string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			sb.WriteRune(c)
+		default:
+			sb.WriteRune('-')
+		}
+	}
+
+	label := sb.String()
+	for strings.Contains(label, "--") {
+		label = strings.ReplaceAll(label, "--", "-")
+	}
+	if len(label) > 63 {
+		label = label[:63]
+	}
+
+	return strings.Trim(label, "-")
+}
+
+// DnsFqdn returns the fully qualified domain name, including the trailing dot, for the given name and zone.
+func DnsFqdn(name, zone string) string {
+	zone = strings.Trim(strings.ToLower(zone), ".")
+	if name == "" {
+		return zone + "."
+	}
+	if zone == "" {
+		return name + "."
+	}
+	return name + "." + zone + "."
+}
+
+// ReverseDnsName returns the fully qualified name of the PTR record for the given address.
+func ReverseDnsName(addr netip.Addr) string {
+	addr = addr.Unmap()
+	raw := addr.AsSlice()
+
+	var sb strings.Builder
+	if addr.Is4() {
+		for i := len(raw) - 1; i >= 0; i-- {
+			sb.WriteString(fmt.Sprintf("%d.", raw[i]))
+		}
+		sb.WriteString("in-addr.arpa.")
+	} else {
+		const hexDigits = "0123456789abcdef"
+		for i := len(raw) - 1; i >= 0; i-- {
+			sb.WriteByte(hexDigits[raw[i]&0x0f])
+			sb.WriteByte('.')
+			sb.WriteByte(hexDigits[raw[i]>>4])
+			sb.WriteByte('.')
+		}
+		sb.WriteString("ip6.arpa.")
+	}
+
+	return sb.String()
+}
+
+// DnsNameInZone returns true if the fully qualified name belongs to the given zone.
+func DnsNameInZone(fqdn, zone string) bool {
+	fqdn = strings.Trim(strings.ToLower(fqdn), ".")
+	zone = strings.Trim(strings.ToLower(zone), ".")
+	if zone == "" {
+		return true
+	}
+
+	return fqdn == zone || strings.HasSuffix(fqdn, "."+zone)
+}
+
+// DnsHostname returns the DNS label that is used for the records of the peer.
+// The label is derived from the display name of the peer, which usually equals the device hostname.
+func (p *Peer) DnsHostname() string {
+	if label := DnsLabel(p.DisplayName); label != "" {
+		return label
+	}
+
+	return "peer-" + DnsLabel(string(p.Identifier[:min(len(p.Identifier), 8)]))
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDnsLabel(t *testing.T) {
+	assert.Equal(t, "my-laptop", DnsLabel("My Laptop"))
+	assert.Equal(t, "peer-1", DnsLabel("  peer_#1 "))
+	assert.Equal(t, "a-b", DnsLabel("a--__--b"))
+	assert.Equal(t, "", DnsLabel("äöü"))
+}
+
+func TestDnsFqdn(t *testing.T) {
+	assert.Equal(t, "host.vpn.example.com.", DnsFqdn("host", "vpn.example.com"))
+	assert.Equal(t, "host.vpn.example.com.", DnsFqdn("host", "VPN.example.com."))
+	assert.Equal(t, "vpn.example.com.", DnsFqdn("", "vpn.example.com"))
+}
+
+func TestReverseDnsName(t *testing.T) {
+	assert.Equal(t, "2.12.11.10.in-addr.arpa.", ReverseDnsName(netip.MustParseAddr("10.11.12.2")))
+	assert.Equal(t,
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.4.3.2.1.e.d.0.c.d.a.3.d.d.f.d.f.ip6.arpa.",
+		ReverseDnsName(netip.MustParseAddr("fdfd:d3ad:c0de:1234::1")))
+}
+
+func TestDnsNameInZone(t *testing.T) {
+	assert.True(t, DnsNameInZone("2.12.11.10.in-addr.arpa.", "12.11.10.in-addr.arpa"))
+	assert.True(t, DnsNameInZone("host.vpn.example.com.", "vpn.example.com."))
+	assert.False(t, DnsNameInZone("2.112.11.10.in-addr.arpa.", "12.11.10.in-addr.arpa"))
+}
+
+func TestPeer_DnsHostname(t *testing.T) {
+	peer := Peer{DisplayName: "Bob's Phone", Identifier: "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="}
+	assert.Equal(t, "bob-s-phone", peer.DnsHostname())
+
+	peer.DisplayName = ""
+	assert.Equal(t, "peer-xtiba5rb", peer.DnsHostname())
+}
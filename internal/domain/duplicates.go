@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal"
+)
+
+// DuplicatePeerGroup is a group of peers of the same user and interface that likely belong to the same device.
+// The peers are ordered by their last activity, the most recently used peer comes first and is suggested to be kept.
+type DuplicatePeerGroup struct {
+	InterfaceId    InterfaceIdentifier
+	UserIdentifier UserIdentifier
+	Device         string // the device name derived from the display names of the peers
+	Os             string // the operating system reported by the config pull clients, empty if unknown
+	Peers          []DuplicatePeer
+}
+
+// DuplicatePeer is a member of a DuplicatePeerGroup.
+type DuplicatePeer struct {
+	Peer         Peer
+	Os           string    // the operating system reported by the config pull client, empty if unknown
+	LastActivity time.Time // the last handshake or modification of the peer
+}
+
+// SuggestedPeer returns the peer that is suggested to be kept if the group is merged.
+func (g DuplicatePeerGroup) SuggestedPeer() PeerIdentifier {
+	return g.Peers[0].Peer.Identifier
+}
+
+// PeerDeviceName returns the device name of the peer, which is derived from the display name. Numeric suffixes that
+// are commonly added to distinguish peers, like in "Laptop 2" or "laptop-3", are removed. An empty string is
+// returned for generated display names.
+func PeerDeviceName(peer *Peer) string {
+	if peer.DisplayName == "" ||
+		strings.HasSuffix(peer.DisplayName, "Peer "+internal.TruncateString(string(peer.Identifier), 8)) {
+		return ""
+	}
+
+	label := DnsLabel(peer.DisplayName)
+	if idx := strings.LastIndex(label, "-"); idx > 0 && strings.Trim(label[idx+1:], "0123456789") == "" {
+		label = label[:idx]
+	}
+
+	return label
+}
+
+// FindDuplicatePeers groups the given peers by user, interface and device name. Peers that report different
+// operating systems are never grouped, peers without a reported operating system are added to the group of the
+// device if only one operating system was reported for it. Peers without user or device name, and archived peers,
+// are ignored. Only groups with at least two peers are returned.
+func FindDuplicatePeers(
+	peers []Peer,
+	stats map[PeerIdentifier]PeerStatus,
+	clientOs map[PeerIdentifier]string,
+) []DuplicatePeerGroup {
+	type deviceKey struct {
+		iface  InterfaceIdentifier
+		user   UserIdentifier
+		device string
+	}
+
+	devices := make(map[deviceKey][]DuplicatePeer)
+	var keys []deviceKey
+	for _, peer := range peers {
+		device := PeerDeviceName(&peer)
+		if peer.UserIdentifier == "" || device == "" || peer.DisabledReason == DisabledReasonArchived {
+			continue
+		}
+
+		var status *PeerStatus
+		if s, ok := stats[peer.Identifier]; ok {
+			status = &s
+		}
+
+		key := deviceKey{iface: peer.InterfaceIdentifier, user: peer.UserIdentifier, device: device}
+		if _, ok := devices[key]; !ok {
+			keys = append(keys, key)
+		}
+		devices[key] = append(devices[key], DuplicatePeer{
+			Peer:         peer,
+			Os:           clientOs[peer.Identifier],
+			LastActivity: PeerLastActivity(peer, status),
+		})
+	}
+
+	var groups []DuplicatePeerGroup
+	for _, key := range keys {
+		byOs := make(map[string][]DuplicatePeer)
+		var unknown []DuplicatePeer
+		for _, peer := range devices[key] {
+			if peer.Os == "" {
+				unknown = append(unknown, peer)
+			} else {
+				byOs[peer.Os] = append(byOs[peer.Os], peer)
+			}
+		}
+		if len(byOs) <= 1 {
+			// all peers either report the same operating system or none
+			var reportedOs string
+			for peerOs := range byOs {
+				reportedOs = peerOs
+			}
+			byOs[reportedOs] = append(byOs[reportedOs], unknown...)
+		} else if len(unknown) != 0 {
+			byOs[""] = unknown
+		}
+
+		for peerOs, members := range byOs {
+			if len(members) < 2 {
+				continue
+			}
+			sort.SliceStable(members, func(i, j int) bool {
+				return members[i].LastActivity.After(members[j].LastActivity)
+			})
+			groups = append(groups, DuplicatePeerGroup{
+				InterfaceId:    key.iface,
+				UserIdentifier: key.user,
+				Device:         key.device,
+				Os:             peerOs,
+				Peers:          members,
+			})
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].UserIdentifier != groups[j].UserIdentifier {
+			return groups[i].UserIdentifier < groups[j].UserIdentifier
+		}
+		if groups[i].Device != groups[j].Device {
+			return groups[i].Device < groups[j].Device
+		}
+		return groups[i].Os < groups[j].Os
+	})
+
+	return groups
+}
+
+// ArchiveAsDuplicate disables the peer and notes the peer it was merged into.
+func (p *Peer) ArchiveAsDuplicate(keep *Peer, now time.Time) {
+	p.Disabled = &now
+	p.DisabledReason = DisabledReasonArchived
+
+	note := fmt.Sprintf("Archived as duplicate of %s (%s) on %s.", keep.DisplayName, keep.Identifier,
+		now.Format(time.DateOnly))
+	if p.Notes != "" {
+		note = p.Notes + "\n" + note
+	}
+	p.Notes = note
+}
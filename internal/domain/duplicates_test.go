@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerDeviceName(t *testing.T) {
+	assert.Equal(t, "alice-laptop", PeerDeviceName(&Peer{DisplayName: "Alice Laptop"}))
+	assert.Equal(t, "alice-laptop", PeerDeviceName(&Peer{DisplayName: "Alice Laptop (2)"}))
+	assert.Equal(t, "laptop", PeerDeviceName(&Peer{DisplayName: "laptop-13"}))
+	assert.Equal(t, "", PeerDeviceName(&Peer{Identifier: "abcdefghijkl", DisplayName: "Peer abcdefgh"}))
+	assert.Equal(t, "", PeerDeviceName(&Peer{Identifier: "abcdefghijkl", DisplayName: "wg0 Peer abcdefgh"}))
+	assert.Equal(t, "", PeerDeviceName(&Peer{}))
+}
+
+func TestFindDuplicatePeers(t *testing.T) {
+	now := time.Now()
+	peer := func(id, user, name string, updated time.Duration) Peer {
+		return Peer{
+			Identifier:          PeerIdentifier(id),
+			InterfaceIdentifier: "wg0",
+			UserIdentifier:      UserIdentifier(user),
+			DisplayName:         name,
+			BaseModel:           BaseModel{UpdatedAt: now.Add(-updated)},
+		}
+	}
+	archived := peer("archived", "alice", "Laptop", 0)
+	archived.DisabledReason = DisabledReasonArchived
+
+	peers := []Peer{
+		peer("a1", "alice", "Laptop", 48*time.Hour),
+		peer("a2", "alice", "Laptop 2", 24*time.Hour),
+		peer("a3", "alice", "laptop-3", 72*time.Hour),
+		peer("a4", "alice", "Phone", time.Hour),
+		peer("b1", "bob", "Laptop", time.Hour),
+		peer("n1", "", "Laptop", time.Hour),
+		archived,
+	}
+	handshake := now.Add(-time.Minute)
+	stats := map[PeerIdentifier]PeerStatus{"a3": {PeerId: "a3", LastHandshake: &handshake}}
+
+	groups := FindDuplicatePeers(peers, stats, nil)
+	require.Len(t, groups, 1)
+	assert.Equal(t, UserIdentifier("alice"), groups[0].UserIdentifier)
+	assert.Equal(t, "laptop", groups[0].Device)
+	require.Len(t, groups[0].Peers, 3)
+	assert.Equal(t, PeerIdentifier("a3"), groups[0].SuggestedPeer(), "the most recently used peer is kept")
+	assert.Equal(t, PeerIdentifier("a2"), groups[0].Peers[1].Peer.Identifier)
+
+	// peers that report different operating systems are different devices
+	groups = FindDuplicatePeers(peers, stats, map[PeerIdentifier]string{"a1": ClientOsWindows, "a2": ClientOsLinux})
+	assert.Empty(t, groups)
+
+	groups = FindDuplicatePeers(peers, stats, map[PeerIdentifier]string{"a1": ClientOsWindows, "a2": ClientOsWindows})
+	require.Len(t, groups, 1)
+	assert.Equal(t, ClientOsWindows, groups[0].Os)
+	assert.Len(t, groups[0].Peers, 3, "peers without reported operating system join the group")
+}
+
+func TestPeer_ArchiveAsDuplicate(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	keep := &Peer{Identifier: "keep", DisplayName: "Laptop"}
+	p := &Peer{Identifier: "old", Notes: "#vip"}
+
+	p.ArchiveAsDuplicate(keep, now)
+	assert.True(t, p.IsDisabled())
+	assert.Equal(t, DisabledReasonArchived, p.DisabledReason)
+	assert.Equal(t, "#vip\nArchived as duplicate of Laptop (keep) on 2024-05-01.", p.Notes)
+}
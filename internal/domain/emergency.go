@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+type EmergencyLockdownIdentifier string
+
+type EmergencyTarget string
+
+const (
+	EmergencyTargetUser      EmergencyTarget = "user"      // all peers of the user are disabled
+	EmergencyTargetInterface EmergencyTarget = "interface" // the whole interface is disabled
+)
+
+// EmergencyLockdown is an emergency action that immediately disabled all peers of a user or an entire interface.
+// The snapshot contains the state before the lockdown, so the lockdown can be reverted.
+type EmergencyLockdown struct {
+	Identifier EmergencyLockdownIdentifier `gorm:"primaryKey;column:identifier"`
+	Target     EmergencyTarget             `gorm:"column:target"`
+	TargetId   string                      `gorm:"index;column:target_id"` // the user or interface identifier
+	Reason     string                      `gorm:"column:reason"`
+
+	CreatedBy  UserIdentifier `gorm:"column:created_by"`
+	CreatedAt  time.Time      `gorm:"column:created_at"`
+	RestoredBy UserIdentifier `gorm:"column:restored_by"`
+	RestoredAt *time.Time     `gorm:"column:restored_at"`
+
+	Snapshot EmergencySnapshot `gorm:"column:snapshot;serializer:json"`
+}
+
+// EmergencySnapshot contains the objects that were disabled by the lockdown. Objects that were already disabled
+// are not part of the snapshot, so they stay disabled after a restore.
+type EmergencySnapshot struct {
+	Peers     []PeerIdentifier    `json:"peers,omitempty"`
+	Interface InterfaceIdentifier `json:"interface,omitempty"`
+}
+
+// IsActive returns true if the lockdown has not been restored yet.
+func (l *EmergencyLockdown) IsActive() bool {
+	return l.RestoredAt == nil
+}
@@ -10,6 +10,11 @@ var ErrNotUnique = errors.New("record not unique")
 var ErrNoPermission = errors.New("no permission")
 var ErrDuplicateEntry = errors.New("duplicate entry")
 var ErrInvalidData = errors.New("invalid data")
+var ErrQuotaExceeded = errors.New("quota exceeded")
+var ErrConfigTooLarge = errors.New("config too large for QR code")
+var ErrStepUpRequired = errors.New("re-authentication required")
+var ErrApprovalRequired = errors.New("approval required")
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
 
 // GetStackTrace returns a stack trace of the current goroutine. The stack trace has at most 1024 bytes.
 func GetStackTrace() string {
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// StreamEvent is an event that is published to the external event stream, for example a NATS subject or a Kafka
+// topic. The payload contains no secrets like private or pre-shared keys.
+type StreamEvent struct {
+	Name       string    `json:"event"`  // the event name, for example: peer.created
+	Time       time.Time `json:"time"`   // the time the event occurred
+	Entity     string    `json:"entity"` // the entity type, for example: peer
+	Identifier string    `json:"identifier"`
+	Payload    any       `json:"payload"`
+}
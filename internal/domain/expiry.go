@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// PeerExpiryReminder stores the time at which the owner of a peer was reminded about the upcoming expiry.
+// If the expiry date of the peer changes, a new reminder is sent.
+type PeerExpiryReminder struct {
+	PeerId     PeerIdentifier `gorm:"primaryKey;column:identifier"`
+	ExpiresAt  time.Time      `gorm:"column:expires_at"` // the expiry date the owner was reminded about
+	RemindedAt time.Time      `gorm:"column:reminded_at"`
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatCsv  ExportFormat = "csv"
+	ExportFormatXlsx ExportFormat = "xlsx"
+)
+
+// ParseExportFormat parses the given export format, the format defaults to CSV if it is empty.
+func ParseExportFormat(format string) (ExportFormat, error) {
+	switch ExportFormat(strings.ToLower(format)) {
+	case "", ExportFormatCsv:
+		return ExportFormatCsv, nil
+	case ExportFormatXlsx:
+		return ExportFormatXlsx, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q: %w", format, ErrInvalidData)
+	}
+}
+
+// ExportRequest describes a list export. The filter and the columns correspond to the list in the frontend.
+type ExportRequest struct {
+	Format  ExportFormat
+	Filter  string   // only rows matching the filter are exported, the filter is matched like the frontend search
+	Columns []string // the column keys in export order, all columns are exported if empty
+
+	DeviceType PeerDeviceType // only peers of the device type are exported, all peers are exported if empty
+}
+
+// Export is a prepared list export. The data is loaded and the access rights are checked when the export is
+// prepared, the rows are rendered only when the export is written.
+type Export struct {
+	Filename    string
+	ContentType string
+
+	// Write renders the export to the given writer. Rows are written one by one, the whole file is never held in
+	// memory.
+	Write func(w io.Writer) error
+}
@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// ExportApprovalState is the state of an export approval request.
+type ExportApprovalState string
+
+const (
+	ExportApprovalStatePending  ExportApprovalState = "pending"
+	ExportApprovalStateApproved ExportApprovalState = "approved"
+	ExportApprovalStateRejected ExportApprovalState = "rejected"
+)
+
+// ExportApproval is a request of a user to export more peer configurations or private keys than the configured
+// threshold allows. The request must be approved by another administrator (four-eyes principle).
+type ExportApproval struct {
+	Id uint64 `gorm:"primaryKey;autoIncrement;column:id"`
+
+	RequestedBy UserIdentifier `gorm:"index;column:requested_by"`
+	RequestedAt time.Time      `gorm:"column:requested_at"`
+	ExportCount int            `gorm:"column:export_count"` // the number of exports within the window when the request was created
+
+	State      ExportApprovalState `gorm:"column:state"`
+	DecidedBy  UserIdentifier      `gorm:"column:decided_by"`
+	DecidedAt  *time.Time          `gorm:"column:decided_at"`
+	ValidUntil *time.Time          `gorm:"column:valid_until"` // approved requests allow further exports until this time
+}
+
+// IsPending returns true if the request was neither approved nor rejected yet.
+func (a *ExportApproval) IsPending() bool {
+	return a.State == ExportApprovalStatePending
+}
+
+// AllowsExports returns true if the request was approved and the approval is still valid at the given time.
+func (a *ExportApproval) AllowsExports(now time.Time) bool {
+	return a.State == ExportApprovalStateApproved && a.ValidUntil != nil && now.Before(*a.ValidUntil)
+}
+
+// KeyExport is the most recent export of the private key of a peer by a user that is not a member of the peer. The
+// exports within the configured window count towards the export approval threshold.
+type KeyExport struct {
+	UserIdentifier UserIdentifier `gorm:"primaryKey;column:user_identifier"`
+	PeerIdentifier PeerIdentifier `gorm:"primaryKey;column:peer_identifier"`
+	ExportedAt     time.Time      `gorm:"index;column:exported_at"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportApproval_AllowsExports(t *testing.T) {
+	now := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	validUntil := now.Add(time.Hour)
+
+	pending := ExportApproval{State: ExportApprovalStatePending}
+	assert.True(t, pending.IsPending())
+	assert.False(t, pending.AllowsExports(now))
+
+	approved := ExportApproval{State: ExportApprovalStateApproved, ValidUntil: &validUntil}
+	assert.False(t, approved.IsPending())
+	assert.True(t, approved.AllowsExports(now))
+	assert.False(t, approved.AllowsExports(validUntil), "expired approvals do not allow exports")
+
+	rejected := ExportApproval{State: ExportApprovalStateRejected, ValidUntil: &validUntil}
+	assert.False(t, rejected.AllowsExports(now))
+}
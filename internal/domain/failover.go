@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// FailoverState is the state of a gateway in an active/standby pair.
+type FailoverState string
+
+const (
+	FailoverStateActive  FailoverState = "active"  // the gateway serves the peers, the endpoint points to it
+	FailoverStateStandby FailoverState = "standby" // the gateway replicates the peers and monitors the primary
+)
+
+// FailoverHealth is the health report that a gateway serves to the other gateway of the pair.
+type FailoverHealth struct {
+	Node    string
+	Role    string
+	State   FailoverState
+	Healthy bool // true if all served interfaces are available
+}
+
+// FailoverStatus is the current state of the local failover controller.
+type FailoverStatus struct {
+	FailoverHealth
+
+	PeerState            FailoverState // the state reported by the other gateway, empty if it is unreachable
+	ConsecutiveFailures  int           // failed health checks of the other gateway in a row
+	ConsecutiveSuccesses int           // successful health checks of the other gateway in a row
+	LastCheck            time.Time
+	LastTransition       time.Time
+	LastSync             time.Time
+	LastError            string
+}
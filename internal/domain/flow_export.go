@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// ConnectionFlow is an entry of the connection tracking table of the kernel. The counters are the totals since the
+// start of the connection, they are only maintained if connection tracking accounting is enabled in the kernel.
+type ConnectionFlow struct {
+	Protocol    uint8          // the IP protocol number, for example 6 for TCP
+	Source      netip.AddrPort // the source of the original direction
+	Destination netip.AddrPort // the destination of the original direction
+
+	Packets      uint64 // packets of the original direction
+	Bytes        uint64 // bytes of the original direction
+	ReplyPackets uint64 // packets of the reply direction
+	ReplyBytes   uint64 // bytes of the reply direction
+
+	StartedAt time.Time // zero if the kernel does not record connection timestamps
+}
+
+// Key identifies the connection across multiple reads of the connection tracking table.
+func (f ConnectionFlow) Key() string {
+	return fmt.Sprintf("%d/%s/%s/%d", f.Protocol, f.Source, f.Destination, f.StartedAt.UnixNano())
+}
+
+// FlowPeer identifies the peer that a connection flow belongs to.
+type FlowPeer struct {
+	PeerId      PeerIdentifier
+	InterfaceId InterfaceIdentifier
+	UserId      UserIdentifier
+}
+
+// FlowRecord is the traffic of one direction of a connection of a peer since the previous record of the connection.
+type FlowRecord struct {
+	FlowPeer
+
+	Protocol    uint8
+	Source      netip.AddrPort
+	Destination netip.AddrPort
+
+	Packets uint64 // packets since the previous record
+	Bytes   uint64 // bytes since the previous record
+
+	StartedAt time.Time // the start of the connection
+	EndedAt   time.Time // the time of the last read of the connection tracking table
+}
+
+// NewFlowRecords returns the records of the traffic of the connection since its previous read. The previous flow is
+// nil if the connection was not seen before. The reply direction is reported with the swapped addresses of the
+// original direction. If the counters of the connection decreased, the connection was replaced, and all of its
+// traffic is reported. Directions without new traffic produce no record.
+func NewFlowRecords(
+	peer FlowPeer,
+	previous *ConnectionFlow,
+	current ConnectionFlow,
+	firstSeen, now time.Time,
+) []FlowRecord {
+	startedAt := current.StartedAt
+	if startedAt.IsZero() {
+		startedAt = firstSeen
+	}
+
+	var records []FlowRecord
+	appendRecord := func(source, destination netip.AddrPort, packets, bytes, previousPackets, previousBytes uint64) {
+		if previous != nil && packets >= previousPackets && bytes >= previousBytes {
+			packets -= previousPackets
+			bytes -= previousBytes
+		}
+		if packets == 0 && bytes == 0 {
+			return
+		}
+		records = append(records, FlowRecord{
+			FlowPeer:    peer,
+			Protocol:    current.Protocol,
+			Source:      source,
+			Destination: destination,
+			Packets:     packets,
+			Bytes:       bytes,
+			StartedAt:   startedAt,
+			EndedAt:     now,
+		})
+	}
+
+	var previousFlow ConnectionFlow
+	if previous != nil {
+		previousFlow = *previous
+	}
+	appendRecord(current.Source, current.Destination, current.Packets, current.Bytes,
+		previousFlow.Packets, previousFlow.Bytes)
+	appendRecord(current.Destination, current.Source, current.ReplyPackets, current.ReplyBytes,
+		previousFlow.ReplyPackets, previousFlow.ReplyBytes)
+
+	return records
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFlowRecords(t *testing.T) {
+	start := time.Date(2024, 10, 14, 10, 0, 0, 0, time.UTC)
+	peer := FlowPeer{PeerId: "peer", InterfaceId: "wg0", UserId: "user"}
+	flow := ConnectionFlow{
+		Protocol:     6,
+		Source:       netip.MustParseAddrPort("10.11.12.2:40000"),
+		Destination:  netip.MustParseAddrPort("192.0.2.10:443"),
+		Packets:      10,
+		Bytes:        1000,
+		ReplyPackets: 20,
+		ReplyBytes:   8000,
+	}
+
+	records := NewFlowRecords(peer, nil, flow, start, start.Add(time.Minute))
+	require.Len(t, records, 2)
+	assert.Equal(t, peer, records[0].FlowPeer)
+	assert.Equal(t, flow.Source, records[0].Source)
+	assert.Equal(t, flow.Destination, records[0].Destination)
+	assert.Equal(t, uint64(10), records[0].Packets)
+	assert.Equal(t, uint64(1000), records[0].Bytes)
+	assert.Equal(t, start, records[0].StartedAt, "first seen is used without kernel timestamps")
+	assert.Equal(t, start.Add(time.Minute), records[0].EndedAt)
+	assert.Equal(t, flow.Destination, records[1].Source, "reply direction swaps the addresses")
+	assert.Equal(t, flow.Source, records[1].Destination)
+	assert.Equal(t, uint64(8000), records[1].Bytes)
+
+	current := flow
+	current.Packets, current.Bytes = 15, 1500
+	current.StartedAt = start.Add(-time.Second)
+	records = NewFlowRecords(peer, &flow, current, start, start.Add(2*time.Minute))
+	require.Len(t, records, 1, "the reply direction has no new traffic")
+	assert.Equal(t, uint64(5), records[0].Packets)
+	assert.Equal(t, uint64(500), records[0].Bytes)
+	assert.Equal(t, start.Add(-time.Second), records[0].StartedAt)
+}
+
+func TestNewFlowRecords_CounterReset(t *testing.T) {
+	start := time.Date(2024, 10, 14, 10, 0, 0, 0, time.UTC)
+	previous := ConnectionFlow{Packets: 10, Bytes: 1000}
+	current := ConnectionFlow{Packets: 2, Bytes: 100}
+
+	records := NewFlowRecords(FlowPeer{}, &previous, current, start, start)
+	require.Len(t, records, 1)
+	assert.Equal(t, uint64(2), records[0].Packets)
+	assert.Equal(t, uint64(100), records[0].Bytes)
+}
+
+func TestConnectionFlow_Key(t *testing.T) {
+	flow := ConnectionFlow{
+		Protocol:    17,
+		Source:      netip.MustParseAddrPort("10.11.12.2:5353"),
+		Destination: netip.MustParseAddrPort("[2001:db8::1]:53"),
+	}
+	other := flow
+	other.StartedAt = time.Unix(1, 0)
+
+	assert.Equal(t, flow.Key(), flow.Key())
+	assert.NotEqual(t, flow.Key(), other.Key(), "a new connection with the same addresses has another key")
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// HealthCheck is the result of a single health check of WireGuard Portal.
+type HealthCheck struct {
+	Name    string
+	Healthy bool
+	Error   string // the reason of the failure, empty if the check is healthy
+}
+
+// HealthStatus is the result of the last health check run.
+type HealthStatus struct {
+	Healthy   bool // true if all checks are healthy
+	Checks    []HealthCheck
+	CheckedAt time.Time
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header that contains the idempotency key of a request.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyKeyMaxLength is the maximum length of an idempotency key.
+const IdempotencyKeyMaxLength = 255
+
+// IdempotencyRecord is the stored response of a request with an Idempotency-Key header. Retries of the request
+// with the same key receive the stored response instead of executing the request again.
+type IdempotencyRecord struct {
+	UserIdentifier UserIdentifier `gorm:"primaryKey;column:user_identifier"` // keys are unique per user
+	Key            string         `gorm:"primaryKey;column:idempotency_key"`
+
+	RequestHash string `gorm:"column:request_hash"` // the SHA-256 hash of the method, path and body of the request
+
+	StatusCode  int    `gorm:"column:status_code"`
+	ContentType string `gorm:"column:content_type"`
+	Body        []byte `gorm:"column:body"`
+
+	CreatedAt time.Time `gorm:"index;column:created_at"`
+}
+
+// IsExpired returns true if the record is older than the given retention period.
+func (r *IdempotencyRecord) IsExpired(now time.Time, retention time.Duration) bool {
+	return now.Sub(r.CreatedAt) > retention
+}
+
+// ValidateIdempotencyKey checks that the given idempotency key is not empty and not too long.
+func ValidateIdempotencyKey(key string) error {
+	if key == "" {
+		return errors.Join(errors.New("idempotency key must not be empty"), ErrInvalidData)
+	}
+	if len(key) > IdempotencyKeyMaxLength {
+		return errors.Join(fmt.Errorf("idempotency key must not be longer than %d characters",
+			IdempotencyKeyMaxLength), ErrInvalidData)
+	}
+
+	return nil
+}
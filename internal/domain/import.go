@@ -0,0 +1,56 @@
+package domain
+
+type ImportKind string
+
+const (
+	ImportKindUsers ImportKind = "users"
+	ImportKindPeers ImportKind = "peers"
+)
+
+// ImportRequest describes a bulk import from CSV data. The first row of the data contains the column names.
+type ImportRequest struct {
+	Kind        ImportKind
+	InterfaceId InterfaceIdentifier // the interface of the imported peers, only used for peer imports
+
+	Data    string            // the CSV data, comma or semicolon separated
+	Mapping map[string]string // maps the field keys to column names, unmapped fields are matched by column name
+
+	SendMails     bool // send the configuration mail for each created peer
+	LinkOnlyMails bool // only send a download link instead of the configuration
+}
+
+// ImportField is a field that can be mapped to a column of the CSV data.
+type ImportField struct {
+	Key      string
+	Title    string
+	Required bool
+}
+
+// ImportReport is the result of the validation or the execution of an import.
+type ImportReport struct {
+	Columns []string          // the column names of the CSV data
+	Fields  []ImportField     // all fields of the import kind
+	Mapping map[string]string // the effective mapping of field keys to column names
+	Rows    []ImportRow
+
+	Created int // the number of created users or peers
+	Failed  int // the number of rows with errors
+}
+
+// ImportRow is a single row of the CSV data.
+type ImportRow struct {
+	Line   int               // the line number in the CSV data, the header is line 1
+	Values map[string]string // the mapped values by field key
+
+	Identifier string // the identifier of the created user or peer
+	Created    bool
+	MailSent   bool
+
+	Errors   []string // the row is not imported if it contains errors
+	Warnings []string
+}
+
+// HasErrors returns true if the row cannot be imported.
+func (r ImportRow) HasErrors() bool {
+	return len(r.Errors) > 0
+}
@@ -19,6 +19,10 @@ const (
 	InterfaceTypeAny    InterfaceType = "any"
 )
 
+// EndpointSrvPrefix marks backup endpoints that are resolved using DNS SRV records, for example
+// "srv:_wireguard._udp.example.com".
+const EndpointSrvPrefix = "srv:"
+
 var allowedFileNameRegex = regexp.MustCompile("[^a-zA-Z0-9-_]+")
 
 type InterfaceIdentifier string
@@ -55,6 +59,8 @@ type Interface struct {
 	Disabled       *time.Time    `gorm:"index"` // flag that specifies if the interface is enabled (up) or not (down)
 	DisabledReason string        // the reason why the interface has been disabled
 
+	OrganizationIdentifier OrganizationIdentifier `gorm:"index;column:organization_identifier"` // the organization that owns the interface and its peers
+
 	// Default settings for the peer, used for new peers, those settings will be published to ConfigOption options of
 	// the peer config
 
@@ -62,16 +68,34 @@ type Interface struct {
 	PeerDefDnsStr              string // the default dns server for the peer
 	PeerDefDnsSearchStr        string // the default dns search options for the peer
 	PeerDefEndpoint            string // the default endpoint for the peer
+	PeerDefBackupEndpointsStr  string // backup endpoints for the peer, comma separated, "srv:" entries use DNS SRV records
 	PeerDefAllowedIPsStr       string // the default allowed IP string for the peer
+	PeerDefRouteSetsStr        string // the default route set identifiers for the peer, comma seperated
 	PeerDefMtu                 int    // the default device MTU
 	PeerDefPersistentKeepalive int    // the default persistent keep-alive Value
 	PeerDefFirewallMark        uint32 // default firewall mark
 	PeerDefRoutingTable        string // the default routing table
+	PeerDefUploadLimit         int    // the default upload limit in kbit/s, 0 means unlimited
+	PeerDefDownloadLimit       int    // the default download limit in kbit/s, 0 means unlimited
 
 	PeerDefPreUp    string // default action that is executed before the device is up
 	PeerDefPostUp   string // default action that is executed after the device is up
 	PeerDefPreDown  string // default action that is executed before the device is down
 	PeerDefPostDown string // default action that is executed after the device is down
+
+	// Mail settings for the peers of the interface
+
+	PeerMailCcStr  string // recipients that receive a copy of all peer mails, comma separated
+	PeerMailBccStr string // recipients that receive a blind copy of all peer mails, comma separated
+
+	// Client isolation settings, applied as firewall rules on the server
+
+	ClientIsolation           bool   // drop the traffic between the peers of the interface
+	ClientIsolationAllowedStr string // networks behind peers that stay reachable for all peers, comma separated
+
+	// Route review settings for self-managed peers
+
+	PeerRouteAllowlistStr string // networks that users may route to their own peers without review, comma separated
 }
 
 // PublicInfo returns a copy of the interface with only the public information.
@@ -102,9 +126,78 @@ func (i *Interface) Validate() error {
 		i.PeerDefEndpoint = net.JoinHostPort(host, port)
 	}
 
+	// validate backup endpoints, add port if needed
+	backupEndpoints := i.PeerDefBackupEndpoints()
+	for idx, endpoint := range backupEndpoints {
+		if srvName, isSrv := strings.CutPrefix(endpoint, EndpointSrvPrefix); isSrv {
+			if srvName == "" {
+				return fmt.Errorf("invalid backup endpoint %s: missing SRV name", endpoint)
+			}
+			continue
+		}
+
+		host, port := SplitEndpoint(endpoint)
+		if host == "" {
+			return fmt.Errorf("invalid backup endpoint %s", endpoint)
+		}
+		if port == "" {
+			port = strconv.Itoa(i.ListenPort)
+		}
+		backupEndpoints[idx] = net.JoinHostPort(host, port)
+	}
+	i.PeerDefBackupEndpointsStr = internal.SliceToString(backupEndpoints)
+
+	if _, err := CidrsFromArray(internal.SliceString(i.ClientIsolationAllowedStr)); err != nil {
+		return fmt.Errorf("invalid client isolation network: %w", err)
+	}
+
+	if _, err := CidrsFromArray(internal.SliceString(i.PeerRouteAllowlistStr)); err != nil {
+		return fmt.Errorf("invalid route allowlist network: %w", err)
+	}
+
 	return nil
 }
 
+// PeerDefBackupEndpoints returns the backup endpoints that are offered to the peers in addition to the default
+// endpoint. Entries with the "srv:" prefix are DNS SRV names that are resolved when a configuration is rendered.
+func (i *Interface) PeerDefBackupEndpoints() []string {
+	return internal.SliceString(i.PeerDefBackupEndpointsStr)
+}
+
+// ClientIsolationAllowed returns the networks that stay reachable through the interface if client isolation is
+// enabled, for example the site network behind a gateway peer. Invalid networks are skipped.
+func (i *Interface) ClientIsolationAllowed() []Cidr {
+	var cidrs []Cidr
+	for _, str := range internal.SliceString(i.ClientIsolationAllowedStr) {
+		if cidr, err := CidrFromString(str); err == nil {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	return cidrs
+}
+
+// PeerRouteAllowlist returns the networks that users may route to their own peers without the review of an
+// administrator. Invalid networks are skipped.
+func (i *Interface) PeerRouteAllowlist() []Cidr {
+	var cidrs []Cidr
+	for _, str := range internal.SliceString(i.PeerRouteAllowlistStr) {
+		if cidr, err := CidrFromString(str); err == nil {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	return cidrs
+}
+
+// PeerMailCc returns the recipients that receive a copy of all peer mails.
+func (i *Interface) PeerMailCc() []string {
+	return internal.SliceString(i.PeerMailCcStr)
+}
+
+// PeerMailBcc returns the recipients that receive a blind copy of all peer mails.
+func (i *Interface) PeerMailBcc() []string {
+	return internal.SliceString(i.PeerMailBccStr)
+}
+
 func (i *Interface) IsDisabled() bool {
 	if i == nil {
 		return true
@@ -231,10 +324,13 @@ func ConvertPhysicalInterface(pi *PhysicalInterface) *Interface {
 		PeerDefDnsSearchStr:        "",
 		PeerDefEndpoint:            "",
 		PeerDefAllowedIPsStr:       "",
+		PeerDefRouteSetsStr:        "",
 		PeerDefMtu:                 pi.Mtu,
 		PeerDefPersistentKeepalive: 0,
 		PeerDefFirewallMark:        0,
 		PeerDefRoutingTable:        "",
+		PeerDefUploadLimit:         0,
+		PeerDefDownloadLimit:       0,
 		PeerDefPreUp:               "",
 		PeerDefPostUp:              "",
 		PeerDefPreDown:             "",
@@ -81,3 +81,34 @@ func TestInterface_GetRoutingTableReturnsCorrectValue(t *testing.T) {
 	iface.RoutingTable = "200"
 	assert.Equal(t, 200, iface.GetRoutingTable())
 }
+
+func TestInterface_ValidateAddsPortToBackupEndpoints(t *testing.T) {
+	iface := &Interface{
+		ListenPort:                51820,
+		PeerDefBackupEndpointsStr: "vpn2.example.com, 192.0.2.1:443, srv:_wireguard._udp.example.com",
+	}
+	assert.NoError(t, iface.Validate())
+	assert.Equal(t, []string{"vpn2.example.com:51820", "192.0.2.1:443", "srv:_wireguard._udp.example.com"},
+		iface.PeerDefBackupEndpoints())
+
+	iface.PeerDefBackupEndpointsStr = "srv:"
+	assert.Error(t, iface.Validate())
+}
+
+func TestInterface_ValidateChecksClientIsolationNetworks(t *testing.T) {
+	iface := &Interface{ClientIsolationAllowedStr: "192.168.50.0/24, fd00:50::/64"}
+	assert.NoError(t, iface.Validate())
+	assert.Len(t, iface.ClientIsolationAllowed(), 2)
+
+	iface.ClientIsolationAllowedStr = "192.168.50.0/24, site-b"
+	assert.Error(t, iface.Validate())
+}
+
+func TestInterface_ValidateChecksPeerRouteAllowlist(t *testing.T) {
+	iface := &Interface{PeerRouteAllowlistStr: "192.168.0.0/16, fd00::/16"}
+	assert.NoError(t, iface.Validate())
+	assert.Len(t, iface.PeerRouteAllowlist(), 2)
+
+	iface.PeerRouteAllowlistStr = "192.168.0.0/16, lan"
+	assert.Error(t, iface.Validate())
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"net"
+	"time"
+)
+
+const (
+	// RecommendedNatKeepalive is the keepalive interval in seconds that keeps the UDP mappings of almost all
+	// NAT devices alive.
+	RecommendedNatKeepalive = 25
+	// MinNatKeepalive is the smallest keepalive interval in seconds that is recommended.
+	MinNatKeepalive = 10
+)
+
+// TrackEndpoint updates the endpoint churn counters with the endpoint that is currently reported by WireGuard.
+// The counters are reset once the observation window has passed. The stored endpoint is not modified.
+func (s *PeerStatus) TrackEndpoint(endpoint string, now time.Time, window time.Duration) {
+	if s.ChurnSince == nil || now.Sub(*s.ChurnSince) > window {
+		s.ChurnSince = &now
+		s.EndpointChanges = 0
+		s.NatRebinds = 0
+	}
+
+	if s.Endpoint == "" || endpoint == "" || s.Endpoint == endpoint {
+		return
+	}
+
+	s.EndpointChanges++
+	oldHost, _, oldErr := net.SplitHostPort(s.Endpoint)
+	newHost, _, newErr := net.SplitHostPort(endpoint)
+	if oldErr == nil && newErr == nil && oldHost == newHost {
+		s.NatRebinds++ // the NAT device assigned a new port, most likely because the old mapping expired
+	}
+}
+
+// ResetEndpointChurn starts a new endpoint churn observation window.
+func (s *PeerStatus) ResetEndpointChurn(now time.Time) {
+	s.ChurnSince = &now
+	s.EndpointChanges = 0
+	s.NatRebinds = 0
+}
+
+// KeepaliveRecommendation describes a peer whose endpoint changes frequently, for example, because of NAT timeouts.
+type KeepaliveRecommendation struct {
+	Peer                 Peer
+	EndpointChanges      int       // endpoint changes within the observation window
+	NatRebinds           int       // endpoint changes that only changed the port
+	ObservedSince        time.Time // the start of the observation window
+	CurrentKeepalive     int       // the current keepalive interval in seconds, 0 if disabled
+	RecommendedKeepalive int       // the recommended keepalive interval, 0 if a shorter interval would not help
+}
+
+// RecommendKeepalive returns a keepalive recommendation for the given peer. The second return value is false if the
+// endpoint of the peer changed less than threshold times within the observation window.
+func RecommendKeepalive(peer Peer, status PeerStatus, threshold int) (KeepaliveRecommendation, bool) {
+	if threshold <= 0 || status.ChurnSince == nil || status.EndpointChanges < threshold {
+		return KeepaliveRecommendation{}, false
+	}
+
+	recommendation := KeepaliveRecommendation{
+		Peer:             peer,
+		EndpointChanges:  status.EndpointChanges,
+		NatRebinds:       status.NatRebinds,
+		ObservedSince:    *status.ChurnSince,
+		CurrentKeepalive: peer.PersistentKeepalive.GetValue(),
+	}
+
+	switch current := recommendation.CurrentKeepalive; {
+	case current <= 0 || current > RecommendedNatKeepalive:
+		recommendation.RecommendedKeepalive = RecommendedNatKeepalive
+	case current > MinNatKeepalive:
+		// the NAT device drops mappings faster than usual
+		recommendation.RecommendedKeepalive = max(current/2, MinNatKeepalive)
+	default:
+		// the peer is roaming between networks, a shorter keepalive interval would not help
+		recommendation.RecommendedKeepalive = 0
+	}
+
+	return recommendation, true
+}
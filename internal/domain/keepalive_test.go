@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeerStatus_TrackEndpoint(t *testing.T) {
+	now := time.Now()
+	s := PeerStatus{Endpoint: "203.0.113.1:51820"}
+
+	s.TrackEndpoint("203.0.113.1:51820", now, time.Hour)
+	s.Endpoint = "203.0.113.1:51820"
+	s.TrackEndpoint("203.0.113.1:40000", now.Add(time.Minute), time.Hour)
+	s.Endpoint = "203.0.113.1:40000"
+	s.TrackEndpoint("198.51.100.7:40000", now.Add(2*time.Minute), time.Hour)
+	s.Endpoint = "198.51.100.7:40000"
+
+	if s.EndpointChanges != 2 || s.NatRebinds != 1 {
+		t.Errorf("expected 2 changes and 1 rebind, got %d changes and %d rebinds", s.EndpointChanges, s.NatRebinds)
+	}
+
+	s.TrackEndpoint("198.51.100.7:40001", now.Add(2*time.Hour), time.Hour)
+	if s.EndpointChanges != 1 || !s.ChurnSince.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("expected the counters to be reset after the observation window, got %d changes", s.EndpointChanges)
+	}
+}
+
+func TestRecommendKeepalive(t *testing.T) {
+	since := time.Now()
+	status := PeerStatus{EndpointChanges: 3, NatRebinds: 3, ChurnSince: &since}
+
+	tests := []struct {
+		name      string
+		keepalive int
+		want      int
+	}{
+		{name: "disabled", keepalive: 0, want: RecommendedNatKeepalive},
+		{name: "too long", keepalive: 60, want: RecommendedNatKeepalive},
+		{name: "default", keepalive: 25, want: 12},
+		{name: "short", keepalive: 16, want: MinNatKeepalive},
+		{name: "minimum", keepalive: 10, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peer := Peer{PersistentKeepalive: NewConfigOption(tt.keepalive, true)}
+			got, ok := RecommendKeepalive(peer, status, 3)
+			if !ok {
+				t.Fatal("expected a recommendation")
+			}
+			if got.RecommendedKeepalive != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got.RecommendedKeepalive)
+			}
+		})
+	}
+
+	if _, ok := RecommendKeepalive(Peer{}, status, 4); ok {
+		t.Error("expected no recommendation below the threshold")
+	}
+}
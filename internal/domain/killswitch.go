@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"net/netip"
+
+	"github.com/h44z/wg-portal/internal"
+)
+
+// KillSwitchMode specifies which firewall rules are added to a peer configuration to block all traffic
+// that does not pass through the tunnel.
+type KillSwitchMode string
+
+const (
+	KillSwitchNone     KillSwitchMode = ""         // no kill-switch rules are rendered
+	KillSwitchIptables KillSwitchMode = "iptables" // iptables/ip6tables rules for wg-quick on Linux
+	KillSwitchNftables KillSwitchMode = "nftables" // nftables rules for wg-quick on Linux
+	KillSwitchWindows  KillSwitchMode = "windows"  // Windows Firewall rules for the WireGuard Windows client
+)
+
+// IsValid returns true if the kill-switch mode is known.
+func (k KillSwitchMode) IsValid() bool {
+	switch k {
+	case KillSwitchNone, KillSwitchIptables, KillSwitchNftables, KillSwitchWindows:
+		return true
+	default:
+		return false
+	}
+}
+
+// KillSwitchRules contains the additional interface hooks that implement the kill-switch.
+// The hooks are rendered in addition to the normal PostUp and PreDown hooks of the peer.
+type KillSwitchRules struct {
+	PostUp  []string
+	PreDown []string
+}
+
+// IsFullTunnel returns true if the peer routes all IPv4 or IPv6 traffic through the tunnel.
+func (p *Peer) IsFullTunnel() bool {
+	for _, network := range internal.SliceString(p.AllowedIPsStr.GetValue()) {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			continue
+		}
+		if prefix.Bits() == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// KillSwitchRules returns the kill-switch hooks for the peer configuration.
+// Kill-switch rules are only returned for full-tunnel peers, otherwise nil is returned.
+func (p *Peer) KillSwitchRules() *KillSwitchRules {
+	if p.Interface.KillSwitch == KillSwitchNone || !p.IsFullTunnel() {
+		return nil
+	}
+
+	switch p.Interface.KillSwitch {
+	case KillSwitchIptables:
+		// reject everything that is not sent through the tunnel, is not marked by wg-quick (encrypted packets) and
+		// is not destined to a local address
+		rule := "OUTPUT ! -o %i -m mark ! --mark $(wg show %i fwmark) -m addrtype ! --dst-type LOCAL -j REJECT"
+		return &KillSwitchRules{
+			PostUp:  []string{"iptables -I " + rule + " && ip6tables -I " + rule},
+			PreDown: []string{"iptables -D " + rule + " && ip6tables -D " + rule},
+		}
+	case KillSwitchNftables:
+		table := "inet wg_portal_killswitch"
+		return &KillSwitchRules{
+			PostUp: []string{
+				"nft add table " + table,
+				"nft add chain " + table + " output '{ type filter hook output priority 0; policy accept; }'",
+				"nft add rule " + table + " output oifname \"%i\" accept",
+				"nft add rule " + table + " output meta mark $(wg show %i fwmark) accept",
+				"nft add rule " + table + " output fib daddr type local accept",
+				"nft add rule " + table + " output reject",
+			},
+			PreDown: []string{"nft delete table " + table},
+		}
+	case KillSwitchWindows:
+		// script execution must be enabled in the WireGuard Windows client (DangerousScriptExecution)
+		rule := "wg-portal-killswitch-%WIREGUARD_TUNNEL_NAME%"
+		return &KillSwitchRules{
+			PostUp: []string{
+				"powershell -NoProfile -Command \"" +
+					"New-NetFirewallRule -DisplayName '" + rule + "' -Direction Outbound -Action Allow " +
+					"-InterfaceAlias '%WIREGUARD_TUNNEL_NAME%'; " +
+					"New-NetFirewallRule -DisplayName '" + rule + "' -Direction Outbound -Action Allow " +
+					"-Program (Join-Path $env:ProgramFiles 'WireGuard\\wireguard.exe'); " +
+					"Set-NetFirewallProfile -All -DefaultOutboundAction Block\"",
+			},
+			PreDown: []string{
+				"powershell -NoProfile -Command \"" +
+					"Set-NetFirewallProfile -All -DefaultOutboundAction Allow; " +
+					"Remove-NetFirewallRule -DisplayName '" + rule + "'\"",
+			},
+		}
+	default:
+		return nil
+	}
+}
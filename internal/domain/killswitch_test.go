@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKillSwitchMode_IsValid(t *testing.T) {
+	assert.True(t, KillSwitchNone.IsValid())
+	assert.True(t, KillSwitchNftables.IsValid())
+	assert.False(t, KillSwitchMode("pf").IsValid())
+}
+
+func TestPeer_KillSwitchRules(t *testing.T) {
+	peer := Peer{
+		AllowedIPsStr: NewConfigOption("10.0.0.0/8", true),
+		Interface:     PeerInterfaceConfig{KillSwitch: KillSwitchIptables},
+	}
+	assert.False(t, peer.IsFullTunnel())
+	assert.Nil(t, peer.KillSwitchRules(), "split-tunnel peers must not get kill-switch rules")
+
+	peer.AllowedIPsStr = NewConfigOption("10.0.0.0/8, ::/0", true)
+	assert.True(t, peer.IsFullTunnel())
+	rules := peer.KillSwitchRules()
+	if assert.NotNil(t, rules) {
+		assert.Len(t, rules.PostUp, 1)
+		assert.Len(t, rules.PreDown, 1)
+		assert.Contains(t, rules.PostUp[0], "ip6tables -I OUTPUT")
+	}
+
+	peer.Interface.KillSwitch = KillSwitchNftables
+	rules = peer.KillSwitchRules()
+	if assert.NotNil(t, rules) {
+		assert.Equal(t, []string{"nft delete table inet wg_portal_killswitch"}, rules.PreDown)
+	}
+
+	peer.Interface.KillSwitch = KillSwitchNone
+	assert.Nil(t, peer.KillSwitchRules())
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	KnockPacketVersion byte = 1
+	// KnockPacketSize is the size of a knock packet: version (1), id (8), unix timestamp (8), nonce (16) and the
+	// HMAC-SHA256 of all preceding bytes (32).
+	KnockPacketSize = 1 + knockIdSize + 8 + knockNonceSize + sha256.Size
+
+	knockIdSize    = 8
+	knockNonceSize = 16
+)
+
+// KnockCredentials are the credentials that a peer uses to open the WireGuard listen port with a knock packet.
+// The credentials are derived from the public key of the peer, so they are rotated together with the keys.
+type KnockCredentials struct {
+	Id      string // hex encoded identifier, tells the server which secret verifies the packet
+	Secret  string // hex encoded HMAC-SHA256 key
+	Address string // the address that receives the knock packets, for example vpn.example.com:62201
+}
+
+// DeriveKnockCredentials returns the knock credentials of the peer with the given public key.
+func DeriveKnockCredentials(masterSecret, publicKey string) KnockCredentials {
+	derive := func(purpose string) []byte {
+		mac := hmac.New(sha256.New, []byte(masterSecret))
+		mac.Write([]byte(purpose + ":" + publicKey))
+		return mac.Sum(nil)
+	}
+
+	return KnockCredentials{
+		Id:     hex.EncodeToString(derive("knock-id")[:knockIdSize]),
+		Secret: hex.EncodeToString(derive("knock-secret")),
+	}
+}
+
+// KnockPacket is a single packet authorization message.
+type KnockPacket struct {
+	Id        string // hex encoded identifier of the knock credentials
+	Timestamp time.Time
+	Nonce     string // hex encoded random value, a nonce is only accepted once
+
+	data []byte
+}
+
+// NewKnockPacket creates the raw knock packet for the given credentials. The nonce must consist of 16 random bytes.
+func NewKnockPacket(credentials KnockCredentials, now time.Time, nonce []byte) ([]byte, error) {
+	id, err := hex.DecodeString(credentials.Id)
+	if err != nil || len(id) != knockIdSize {
+		return nil, fmt.Errorf("invalid knock id: %w", ErrInvalidData)
+	}
+	secret, err := hex.DecodeString(credentials.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid knock secret: %w", ErrInvalidData)
+	}
+	if len(nonce) != knockNonceSize {
+		return nil, fmt.Errorf("knock nonce must have %d bytes: %w", knockNonceSize, ErrInvalidData)
+	}
+
+	data := make([]byte, 0, KnockPacketSize)
+	data = append(data, KnockPacketVersion)
+	data = append(data, id...)
+	data = binary.BigEndian.AppendUint64(data, uint64(now.Unix()))
+	data = append(data, nonce...)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+
+	return mac.Sum(data), nil
+}
+
+// ParseKnockPacket parses a raw knock packet. The signature is not verified, see KnockPacket.Verify.
+func ParseKnockPacket(data []byte) (*KnockPacket, error) {
+	if len(data) != KnockPacketSize {
+		return nil, fmt.Errorf("knock packet has %d bytes instead of %d", len(data), KnockPacketSize)
+	}
+	if data[0] != KnockPacketVersion {
+		return nil, fmt.Errorf("unsupported knock packet version %d", data[0])
+	}
+
+	idEnd := 1 + knockIdSize
+	timestampEnd := idEnd + 8
+	nonceEnd := timestampEnd + knockNonceSize
+
+	return &KnockPacket{
+		Id:        hex.EncodeToString(data[1:idEnd]),
+		Timestamp: time.Unix(int64(binary.BigEndian.Uint64(data[idEnd:timestampEnd])), 0),
+		Nonce:     hex.EncodeToString(data[timestampEnd:nonceEnd]),
+		data:      data,
+	}, nil
+}
+
+// Verify checks the signature of the packet with the given hex encoded secret.
+func (p KnockPacket) Verify(secret string) error {
+	key, err := hex.DecodeString(secret)
+	if err != nil {
+		return errors.New("invalid knock secret")
+	}
+
+	macStart := len(p.data) - sha256.Size
+	mac := hmac.New(sha256.New, key)
+	mac.Write(p.data[:macStart])
+	if !hmac.Equal(mac.Sum(nil), p.data[macStart:]) {
+		return errors.New("invalid knock signature")
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveKnockCredentials(t *testing.T) {
+	credentials := DeriveKnockCredentials("secret", "public-key-a")
+	assert.Len(t, credentials.Id, 16)
+	assert.Len(t, credentials.Secret, 64)
+	assert.Equal(t, credentials, DeriveKnockCredentials("secret", "public-key-a"))
+
+	assert.NotEqual(t, credentials.Secret, DeriveKnockCredentials("secret", "public-key-b").Secret,
+		"new keys result in new credentials")
+	assert.NotEqual(t, credentials.Secret, DeriveKnockCredentials("other", "public-key-a").Secret)
+}
+
+func TestKnockPacket(t *testing.T) {
+	credentials := DeriveKnockCredentials("secret", "public-key-a")
+	now := time.Unix(1700000000, 0)
+	nonce := bytes.Repeat([]byte{0xab}, knockNonceSize)
+
+	data, err := NewKnockPacket(credentials, now, nonce)
+	require.NoError(t, err)
+	assert.Len(t, data, KnockPacketSize)
+
+	packet, err := ParseKnockPacket(data)
+	require.NoError(t, err)
+	assert.Equal(t, credentials.Id, packet.Id)
+	assert.True(t, now.Equal(packet.Timestamp))
+	assert.Equal(t, "abababababababababababababababab", packet.Nonce)
+	assert.NoError(t, packet.Verify(credentials.Secret))
+	assert.Error(t, packet.Verify(DeriveKnockCredentials("secret", "public-key-b").Secret))
+
+	data[10] ^= 0x01 // modify the timestamp
+	packet, err = ParseKnockPacket(data)
+	require.NoError(t, err)
+	assert.Error(t, packet.Verify(credentials.Secret))
+
+	_, err = ParseKnockPacket(data[:KnockPacketSize-1])
+	assert.Error(t, err)
+
+	_, err = NewKnockPacket(credentials, now, nonce[:8])
+	assert.ErrorIs(t, err, ErrInvalidData)
+}
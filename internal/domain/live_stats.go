@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+// LiveSampleInterval is the interval in which the traffic counters of a peer are read during live sampling.
+const LiveSampleInterval = time.Second
+
+// PeerLiveSample is a short-interval sample of the throughput of a peer. Live samples are only streamed to the
+// client, they are not stored.
+type PeerLiveSample struct {
+	PeerId    PeerIdentifier
+	Timestamp time.Time
+
+	BytesReceived    uint64  // bytes received from the peer since the previous sample
+	BytesTransmitted uint64  // bytes sent to the peer since the previous sample
+	ReceiveRate      float64 // bytes per second
+	TransmitRate     float64 // bytes per second
+
+	LastHandshake time.Time
+}
+
+// NewPeerLiveSample returns the throughput of the peer between the previous and the current state of its counters.
+// If a counter was reset, for example, by an interface restart, the new value counts as traffic since the previous
+// sample.
+func NewPeerLiveSample(previous, current PhysicalPeer, previousTime, now time.Time) PeerLiveSample {
+	sample := PeerLiveSample{
+		PeerId:           current.Identifier,
+		Timestamp:        now,
+		BytesReceived:    liveCounterDelta(previous.BytesUpload, current.BytesUpload),
+		BytesTransmitted: liveCounterDelta(previous.BytesDownload, current.BytesDownload),
+		LastHandshake:    current.LastHandshake,
+	}
+
+	if elapsed := now.Sub(previousTime).Seconds(); elapsed > 0 {
+		sample.ReceiveRate = float64(sample.BytesReceived) / elapsed
+		sample.TransmitRate = float64(sample.BytesTransmitted) / elapsed
+	}
+
+	return sample
+}
+
+func liveCounterDelta(oldValue, newValue uint64) uint64 {
+	if newValue < oldValue {
+		return newValue
+	}
+	return newValue - oldValue
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPeerLiveSample(t *testing.T) {
+	start := time.Date(2024, 10, 14, 10, 0, 0, 0, time.UTC)
+	handshake := start.Add(-time.Minute)
+
+	previous := PhysicalPeer{Identifier: "peer", BytesUpload: 1000, BytesDownload: 5000}
+	current := PhysicalPeer{Identifier: "peer", BytesUpload: 3000, BytesDownload: 5500, LastHandshake: handshake}
+
+	sample := NewPeerLiveSample(previous, current, start, start.Add(2*time.Second))
+	assert.Equal(t, PeerIdentifier("peer"), sample.PeerId)
+	assert.Equal(t, uint64(2000), sample.BytesReceived)
+	assert.Equal(t, uint64(500), sample.BytesTransmitted)
+	assert.InDelta(t, 1000, sample.ReceiveRate, 0.001)
+	assert.InDelta(t, 250, sample.TransmitRate, 0.001)
+	assert.Equal(t, handshake, sample.LastHandshake)
+}
+
+func TestNewPeerLiveSample_CounterReset(t *testing.T) {
+	start := time.Date(2024, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	previous := PhysicalPeer{Identifier: "peer", BytesUpload: 1000, BytesDownload: 5000}
+	current := PhysicalPeer{Identifier: "peer", BytesUpload: 100, BytesDownload: 200}
+
+	sample := NewPeerLiveSample(previous, current, start, start.Add(time.Second))
+	assert.Equal(t, uint64(100), sample.BytesReceived)
+	assert.Equal(t, uint64(200), sample.BytesTransmitted)
+
+	sample = NewPeerLiveSample(previous, current, start, start)
+	assert.Zero(t, sample.ReceiveRate, "no rate without elapsed time")
+}
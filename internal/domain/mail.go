@@ -1,6 +1,10 @@
 package domain
 
-import "io"
+import (
+	"io"
+	"strings"
+	"time"
+)
 
 type MailOptions struct {
 	ReplyTo     string // defaults to the sender
@@ -16,3 +20,94 @@ type MailAttachment struct {
 	Data        io.Reader
 	Embedded    bool
 }
+
+// MailPreview contains a mail template that was rendered with sample data.
+type MailPreview struct {
+	Template string // the template name, for example: mail_with_link
+	Subject  string
+	TextBody string
+	HtmlBody string
+}
+
+// MailTemplateFormat is the format of a mail template file.
+type MailTemplateFormat string
+
+const (
+	MailTemplateFormatText MailTemplateFormat = "text" // plain text templates, file extension .gotpl
+	MailTemplateFormatHtml MailTemplateFormat = "html" // html templates, file extension .gohtml
+)
+
+// MailTemplate describes a built-in mail template and the variables that are passed to it.
+type MailTemplate struct {
+	Name      string // the template name, for example: mail_with_link
+	Variables []MailTemplateVariable
+}
+
+// MailTemplateVariable describes a variable that is available in a mail template.
+type MailTemplateVariable struct {
+	Name        string // the variable name, accessed as {{$.Name}} in the template
+	Type        string // the Go type of the variable
+	Description string
+	Fields      []string // the fields and methods of struct variables, for example: Firstname
+}
+
+// MailTemplateIssue is a problem that was found while validating a mail template.
+type MailTemplateIssue struct {
+	Line    int // the line of the template, 0 if unknown
+	Message string
+}
+
+// MailLogEntry records a mail that was sent, or failed to be sent, by WireGuard Portal.
+type MailLogEntry struct {
+	UniqueId uint64    `gorm:"primaryKey;autoIncrement:true;column:id"`
+	SentAt   time.Time `gorm:"column:sent_at;index:idx_ml_sent"`
+
+	Template   string `gorm:"column:template"` // the template name, for example: mail_with_link
+	Subject    string `gorm:"column:subject"`
+	Recipients string `gorm:"column:recipients"` // all recipients, including CC and BCC recipients, comma separated
+
+	UserIdentifier UserIdentifier `gorm:"column:user_identifier;index:idx_ml_user"` // the user the mail was sent to
+	PeerIdentifier PeerIdentifier `gorm:"column:peer_identifier;index:idx_ml_peer"` // empty if not about a single peer
+
+	Error string `gorm:"column:error"` // empty if the mail was sent successfully
+
+	// TrackingToken identifies the mail in the tracking pixel and the tracked link, empty if the mail is not tracked
+	TrackingToken string     `gorm:"column:tracking_token;index:idx_ml_tracking"`
+	OpenedAt      *time.Time `gorm:"column:opened_at"`  // the first time the tracking pixel was loaded
+	ClickedAt     *time.Time `gorm:"column:clicked_at"` // the first time the tracked link was used
+}
+
+// IsTracked returns true if the mail contains a tracking pixel or a tracked link.
+func (e MailLogEntry) IsTracked() bool {
+	return e.TrackingToken != ""
+}
+
+// Failed returns true if the mail could not be sent.
+func (e MailLogEntry) Failed() bool {
+	return e.Error != ""
+}
+
+// MailLogFilter restricts the returned mail log entries. Empty fields match all entries.
+type MailLogFilter struct {
+	UserId UserIdentifier
+	PeerId PeerIdentifier
+	Search string // matched against the recipients, the subject and the template name
+}
+
+// Matches returns true if the given entry matches the filter.
+func (f MailLogFilter) Matches(entry MailLogEntry) bool {
+	if f.UserId != "" && entry.UserIdentifier != f.UserId {
+		return false
+	}
+	if f.PeerId != "" && entry.PeerIdentifier != f.PeerId {
+		return false
+	}
+	if f.Search == "" {
+		return true
+	}
+
+	search := strings.ToLower(f.Search)
+	return strings.Contains(strings.ToLower(entry.Recipients), search) ||
+		strings.Contains(strings.ToLower(entry.Subject), search) ||
+		strings.Contains(strings.ToLower(entry.Template), search)
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "net/netip"
+
+const (
+	// WireGuardOverhead is the per-packet overhead of WireGuard with an IPv6 outer header:
+	// IPv6 header (40) + UDP header (8) + WireGuard data header (16) + authentication tag (16).
+	// The IPv6 overhead is used for all endpoints, so the tunnel keeps working if a peer roams to IPv6.
+	WireGuardOverhead = 80
+	// MinTunnelMtu is the minimum MTU that is required to transport IPv6 packets through the tunnel.
+	MinTunnelMtu = 1280
+)
+
+// PathMtu is the result of a path MTU probe.
+type PathMtu struct {
+	Destination netip.Addr // the probed address, invalid if the path of the default route was probed
+	LinkName    string     // the name of the underlying interface that is used to reach the destination
+	LinkMtu     int        // the MTU of the underlying interface
+	Mtu         int        // the MTU of the path, equals the link MTU if no smaller path MTU is known
+}
+
+// TunnelMtu returns the largest tunnel MTU that avoids fragmentation of the encrypted packets on the path.
+func (p PathMtu) TunnelMtu() int {
+	return max(p.Mtu-WireGuardOverhead, MinTunnelMtu)
+}
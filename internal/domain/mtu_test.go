@@ -0,0 +1,15 @@
+package domain
+
+import "testing"
+
+func TestPathMtu_TunnelMtu(t *testing.T) {
+	if got := (PathMtu{Mtu: 1500}).TunnelMtu(); got != 1420 {
+		t.Errorf("expected 1420, got %d", got)
+	}
+	if got := (PathMtu{Mtu: 1492}).TunnelMtu(); got != 1412 {
+		t.Errorf("expected 1412 for PPPoE links, got %d", got)
+	}
+	if got := (PathMtu{Mtu: 1300}).TunnelMtu(); got != MinTunnelMtu {
+		t.Errorf("expected the minimum tunnel MTU, got %d", got)
+	}
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// NotificationCategory groups the notifications that users can configure in their notification preferences.
+type NotificationCategory string
+
+const (
+	NotificationCategoryConfig   NotificationCategory = "config"   // peer configuration mails
+	NotificationCategoryExpiry   NotificationCategory = "expiry"   // peer expiry reminders and cleanup warnings
+	NotificationCategorySecurity NotificationCategory = "security" // login and config download notifications
+)
+
+// NotificationChannel is the channel on which a user receives the notifications of a category.
+type NotificationChannel string
+
+const (
+	NotificationChannelMail     NotificationChannel = "mail"
+	NotificationChannelTelegram NotificationChannel = "telegram"
+	NotificationChannelNone     NotificationChannel = "none" // the user does not receive notifications
+)
+
+// UserNotificationPreferences contains the notification channels a user selected per notification category.
+// Users without stored preferences receive all notifications by mail.
+type UserNotificationPreferences struct {
+	UserIdentifier UserIdentifier `gorm:"primaryKey;column:user_identifier"`
+
+	ConfigChannel   NotificationChannel `gorm:"column:config_channel"`
+	ExpiryChannel   NotificationChannel `gorm:"column:expiry_channel"`
+	SecurityChannel NotificationChannel `gorm:"column:security_channel"`
+
+	// TelegramChatId is the chat that receives the notifications of the telegram channel.
+	TelegramChatId string `gorm:"column:telegram_chat_id"`
+
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+// DefaultNotificationPreferences returns the preferences of users that did not store any preferences.
+func DefaultNotificationPreferences(userId UserIdentifier) UserNotificationPreferences {
+	return UserNotificationPreferences{
+		UserIdentifier:  userId,
+		ConfigChannel:   NotificationChannelMail,
+		ExpiryChannel:   NotificationChannelMail,
+		SecurityChannel: NotificationChannelMail,
+	}
+}
+
+// Channel returns the channel for notifications of the given category. Unset channels default to mail.
+func (p UserNotificationPreferences) Channel(category NotificationCategory) NotificationChannel {
+	var channel NotificationChannel
+	switch category {
+	case NotificationCategoryConfig:
+		channel = p.ConfigChannel
+	case NotificationCategoryExpiry:
+		channel = p.ExpiryChannel
+	case NotificationCategorySecurity:
+		channel = p.SecurityChannel
+	}
+
+	if channel == "" {
+		return NotificationChannelMail
+	}
+	return channel
+}
+
+// UsesChannel returns true if the notifications of at least one category are sent on the given channel.
+func (p UserNotificationPreferences) UsesChannel(channel NotificationChannel) bool {
+	for _, category := range []NotificationCategory{
+		NotificationCategoryConfig, NotificationCategoryExpiry, NotificationCategorySecurity,
+	} {
+		if p.Channel(category) == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the selected channels. Peer configurations contain attachments, so they can only be sent by mail.
+func (p UserNotificationPreferences) Validate() error {
+	channels := map[NotificationCategory]NotificationChannel{
+		NotificationCategoryConfig:   p.ConfigChannel,
+		NotificationCategoryExpiry:   p.ExpiryChannel,
+		NotificationCategorySecurity: p.SecurityChannel,
+	}
+	for category, channel := range channels {
+		switch channel {
+		case "", NotificationChannelMail, NotificationChannelNone:
+		case NotificationChannelTelegram:
+			if category == NotificationCategoryConfig {
+				return fmt.Errorf("%s notifications can only be sent by mail: %w", category, ErrInvalidData)
+			}
+		default:
+			return fmt.Errorf("unsupported %s notification channel %q: %w", category, channel, ErrInvalidData)
+		}
+	}
+
+	if p.UsesChannel(NotificationChannelTelegram) && p.TelegramChatId == "" {
+		return fmt.Errorf("a telegram chat id is required for telegram notifications: %w", ErrInvalidData)
+	}
+
+	return nil
+}
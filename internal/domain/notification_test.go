@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserNotificationPreferences_Channel(t *testing.T) {
+	prefs := UserNotificationPreferences{
+		ExpiryChannel:   NotificationChannelTelegram,
+		SecurityChannel: NotificationChannelNone,
+	}
+
+	assert.Equal(t, NotificationChannelMail, prefs.Channel(NotificationCategoryConfig), "unset channels default to mail")
+	assert.Equal(t, NotificationChannelTelegram, prefs.Channel(NotificationCategoryExpiry))
+	assert.Equal(t, NotificationChannelNone, prefs.Channel(NotificationCategorySecurity))
+	assert.True(t, prefs.UsesChannel(NotificationChannelTelegram))
+	assert.False(t, DefaultNotificationPreferences("alice").UsesChannel(NotificationChannelTelegram))
+}
+
+func TestUserNotificationPreferences_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefs   UserNotificationPreferences
+		wantErr bool
+	}{
+		{"Default", DefaultNotificationPreferences("alice"), false},
+		{"Empty", UserNotificationPreferences{}, false},
+		{"Disabled", UserNotificationPreferences{ConfigChannel: NotificationChannelNone}, false},
+		{"Telegram", UserNotificationPreferences{ExpiryChannel: NotificationChannelTelegram, TelegramChatId: "42"},
+			false},
+		{"TelegramWithoutChat", UserNotificationPreferences{SecurityChannel: NotificationChannelTelegram}, true},
+		{"TelegramConfig", UserNotificationPreferences{ConfigChannel: NotificationChannelTelegram,
+			TelegramChatId: "42"}, true},
+		{"Unsupported", UserNotificationPreferences{ExpiryChannel: "sms"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.prefs.Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidData)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
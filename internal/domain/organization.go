@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+)
+
+type OrganizationIdentifier string
+
+var organizationIdentifierPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Organization is a tenant that owns interfaces and users. The peers of an interface belong to the organization
+// of the interface. Administrators of an organization can only see and modify the resources of their organization.
+type Organization struct {
+	BaseModel
+
+	Identifier  OrganizationIdentifier `gorm:"primaryKey;column:identifier"` // organization unique identifier, for example: acme
+	DisplayName string                 // a nice display name for the organization
+
+	// Branding, empty values fall back to the global web settings
+	SiteTitle   string // the title that is shown in the web frontend
+	CompanyName string // the company name that is shown in the web frontend and in mails
+}
+
+// Validate performs checks to ensure that the organization is valid.
+func (o *Organization) Validate() error {
+	if !organizationIdentifierPattern.MatchString(string(o.Identifier)) {
+		return errors.New("invalid identifier, only lower case letters, digits, '-' and '_' are allowed")
+	}
+
+	return nil
+}
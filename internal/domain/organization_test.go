@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrganization_Validate(t *testing.T) {
+	valid := Organization{Identifier: "acme-corp"}
+	assert.NoError(t, valid.Validate())
+
+	invalidId := Organization{Identifier: "Acme Corp"}
+	assert.Error(t, invalidId.Validate())
+
+	emptyId := Organization{}
+	assert.Error(t, emptyId.Validate())
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal"
+)
+
+// PacketCaptureContentType is the content type of the pcap files that are produced by packet captures.
+const PacketCaptureContentType = "application/vnd.tcpdump.pcap"
+
+// PacketCapture describes a time-limited capture of the traffic of a peer on its WireGuard interface.
+type PacketCapture struct {
+	PeerId      PeerIdentifier
+	InterfaceId InterfaceIdentifier
+	Networks    []Cidr        // the allowed IPs of the peer on the interface, only their traffic is captured
+	Duration    time.Duration // the capture stops after this duration
+	MaxSize     int64         // the capture stops once the capture file reached this size in bytes
+	SnapLength  int           // the number of bytes that are captured per packet, 0 captures the full packets
+	StartedBy   UserIdentifier
+	StartedAt   time.Time
+}
+
+// NewPacketCapture returns a packet capture of the traffic of the given peer.
+func NewPacketCapture(peer *Peer, duration time.Duration, maxSize int64, snapLength int) (*PacketCapture, error) {
+	networks := peerInterfaceAllowedIPs(peer)
+	if len(networks) == 0 {
+		return nil, errors.Join(fmt.Errorf("peer %s has no allowed IPs to capture", peer.Identifier), ErrInvalidData)
+	}
+
+	return &PacketCapture{
+		PeerId:      peer.Identifier,
+		InterfaceId: peer.InterfaceIdentifier,
+		Networks:    networks,
+		Duration:    duration,
+		MaxSize:     maxSize,
+		SnapLength:  snapLength,
+	}, nil
+}
+
+// Filter returns the pcap filter expression that matches the traffic of the peer, for example
+// "host 10.11.12.2 or net 192.168.1.0/24".
+func (c PacketCapture) Filter() string {
+	expressions := make([]string, len(c.Networks))
+	for i, network := range c.Networks {
+		prefix := network.Prefix().Masked()
+		if prefix.IsSingleIP() {
+			expressions[i] = "host " + prefix.Addr().String()
+		} else {
+			expressions[i] = "net " + prefix.String()
+		}
+	}
+
+	return strings.Join(expressions, " or ")
+}
+
+// PacketCaptureFileName returns the name of the capture file for the given peer, for example
+// "capture_xTIBA5rb_20241014-101500.pcap".
+func PacketCaptureFileName(id PeerIdentifier, startedAt time.Time) string {
+	peerPart := allowedFileNameRegex.ReplaceAllString(internal.TruncateString(string(id), 8), "")
+	return fmt.Sprintf("capture_%s_%s.pcap", peerPart, startedAt.Format("20060102-150405"))
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPacketCapture(t *testing.T) {
+	peer := &Peer{
+		Identifier:          "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=",
+		InterfaceIdentifier: "wg0",
+		ExtraAllowedIPsStr:  "192.168.1.0/24",
+		Interface:           PeerInterfaceConfig{Type: InterfaceTypeClient},
+	}
+	for _, address := range []string{"10.11.12.2/24", "fd00::2/64"} {
+		cidr, err := CidrFromString(address)
+		require.NoError(t, err)
+		peer.Interface.Addresses = append(peer.Interface.Addresses, cidr)
+	}
+
+	capture, err := NewPacketCapture(peer, time.Minute, 1024, 96)
+	require.NoError(t, err)
+	assert.Equal(t, InterfaceIdentifier("wg0"), capture.InterfaceId)
+	assert.Equal(t, "host 10.11.12.2 or host fd00::2 or net 192.168.1.0/24", capture.Filter())
+
+	_, err = NewPacketCapture(&Peer{Identifier: "empty"}, time.Minute, 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidData)
+}
+
+func TestPacketCaptureFileName(t *testing.T) {
+	startedAt := time.Date(2024, 10, 14, 10, 15, 0, 0, time.UTC)
+	assert.Equal(t, "capture_xTIBA5r_20241014-101500.pcap",
+		PacketCaptureFileName("xTIBA5r/oUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=", startedAt))
+}
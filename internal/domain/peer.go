@@ -35,10 +35,16 @@ type Peer struct {
 	Endpoint            ConfigOption[string] `gorm:"embedded;embeddedPrefix:endpoint_"`        // the endpoint address
 	EndpointPublicKey   ConfigOption[string] `gorm:"embedded;embeddedPrefix:endpoint_pubkey_"` // the endpoint public key
 	AllowedIPsStr       ConfigOption[string] `gorm:"embedded;embeddedPrefix:allowed_ips_str_"` // all allowed ip subnets, comma seperated
+	RouteSetsStr        ConfigOption[string] `gorm:"embedded;embeddedPrefix:route_sets_str_"`  // route set identifiers that extend the allowed ips, comma seperated
 	ExtraAllowedIPsStr  string               // all allowed ip subnets on the server side, comma seperated
 	PresharedKey        PreSharedKey         `gorm:"serializer:encstr"`                              // the pre-shared Key of the peer
 	PersistentKeepalive ConfigOption[int]    `gorm:"embedded;embeddedPrefix:persistent_keep_alive_"` // the persistent keep-alive interval
 
+	// Bandwidth limits in kbit/s, enforced on the server interface, 0 means unlimited
+
+	UploadLimit   ConfigOption[int] `gorm:"embedded;embeddedPrefix:upload_limit_"`   // traffic sent by the peer
+	DownloadLimit ConfigOption[int] `gorm:"embedded;embeddedPrefix:download_limit_"` // traffic sent to the peer
+
 	// WG Portal specific
 
 	DisplayName          string              // a nice display name/ description for the peer
@@ -48,9 +54,24 @@ type Peer struct {
 	Disabled             *time.Time          `gorm:"column:disabled"`                   // if this field is set, the peer is disabled
 	DisabledReason       string              // the reason why the peer has been disabled
 	ExpiresAt            *time.Time          `gorm:"column:expires_at"`         // expiry dates for peers
+	Ephemeral            bool                `gorm:"column:ephemeral"`          // ephemeral peers are deleted once they expire
 	Notes                string              `form:"notes" binding:"omitempty"` // a note field for peers
 	AutomaticallyCreated bool                `gorm:"column:auto_created"`       // specifies if the peer was automatically created
 
+	MailRecipientsStr string // additional recipients of peer mails, for example a team mailbox, comma separated
+
+	SharedUsersStr string // additional users that are authorized to use the peer, for example for a lab machine, comma separated
+
+	AccessScheduleStr string // weekly time windows in which the peer is enabled, for example "Mon-Fri 08:00-18:00"
+	AccessTimezone    string // IANA time zone of the access schedule, empty means the configured default
+
+	EndpointPinStr string // networks or country codes the source endpoint of the peer is pinned to, comma separated
+
+	OperatingSystem PeerOperatingSystem // the operating system of the device, selects the setup guide in config mails
+	DeviceType      PeerDeviceType      // the kind of device, for example laptop or server
+
+	KeysRotatedAt *time.Time `gorm:"column:keys_rotated_at"` // the last time the keys were regenerated, nil if never
+
 	// Interface settings for the peer, used to generate the [interface] section in the peer config file
 	Interface PeerInterfaceConfig `gorm:"embedded"`
 }
@@ -69,6 +90,25 @@ func (p *Peer) IsExpired() bool {
 	return false
 }
 
+// HasBandwidthLimit returns true if an upload or download limit is set for the peer.
+func (p *Peer) HasBandwidthLimit() bool {
+	return p.UploadLimit.GetValue() > 0 || p.DownloadLimit.GetValue() > 0
+}
+
+// MailRecipients returns the additional recipients of peer mails.
+func (p *Peer) MailRecipients() []string {
+	return internal.SliceString(p.MailRecipientsStr)
+}
+
+// NextKeyRotation returns the time from which the keys of the peer can be regenerated again. A zero time is returned
+// if the keys were never regenerated.
+func (p *Peer) NextKeyRotation(cooldown time.Duration) time.Time {
+	if p.KeysRotatedAt == nil {
+		return time.Time{}
+	}
+	return p.KeysRotatedAt.Add(cooldown)
+}
+
 func (p *Peer) CheckAliveAddress() string {
 	if p.Interface.CheckAliveAddress != "" {
 		return p.Interface.CheckAliveAddress
@@ -110,7 +150,10 @@ func (p *Peer) ApplyInterfaceDefaults(in *Interface) {
 	p.Endpoint.TrySetValue(in.PeerDefEndpoint)
 	p.EndpointPublicKey.TrySetValue(in.PublicKey)
 	p.AllowedIPsStr.TrySetValue(in.PeerDefAllowedIPsStr)
+	p.RouteSetsStr.TrySetValue(in.PeerDefRouteSetsStr)
 	p.PersistentKeepalive.TrySetValue(in.PeerDefPersistentKeepalive)
+	p.UploadLimit.TrySetValue(in.PeerDefUploadLimit)
+	p.DownloadLimit.TrySetValue(in.PeerDefDownloadLimit)
 	p.Interface.DnsStr.TrySetValue(in.PeerDefDnsStr)
 	p.Interface.DnsSearchStr.TrySetValue(in.PeerDefDnsSearchStr)
 	p.Interface.Mtu.TrySetValue(in.PeerDefMtu)
@@ -161,6 +204,8 @@ type PeerInterfaceConfig struct {
 	PostUp   ConfigOption[string] `gorm:"embedded;embeddedPrefix:iface_post_up_"`   // action that is executed after the device is up
 	PreDown  ConfigOption[string] `gorm:"embedded;embeddedPrefix:iface_pre_down_"`  // action that is executed before the device is down
 	PostDown ConfigOption[string] `gorm:"embedded;embeddedPrefix:iface_post_down_"` // action that is executed after the device is down
+
+	KillSwitch KillSwitchMode `gorm:"column:iface_kill_switch"` // optional kill-switch rules, only rendered for full-tunnel peers
 }
 
 func (p *PeerInterfaceConfig) AddressStr() string {
@@ -230,6 +275,7 @@ func ConvertPhysicalPeer(pp *PhysicalPeer) *Peer {
 		Endpoint:            NewConfigOption(pp.Endpoint, true),
 		EndpointPublicKey:   NewConfigOption("", true),
 		AllowedIPsStr:       NewConfigOption("", true),
+		RouteSetsStr:        NewConfigOption("", true),
 		ExtraAllowedIPsStr:  "",
 		PresharedKey:        pp.PresharedKey,
 		PersistentKeepalive: NewConfigOption(pp.PersistentKeepalive, true),
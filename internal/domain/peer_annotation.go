@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	peerAnnotationPrefix  = "# -WGP- "
+	peerFriendlyNameKey   = "# friendly_name" // used by the prometheus_wireguard_exporter
+	peerAnnotationPeer    = "Peer"
+	peerAnnotationName    = "Display name"
+	peerAnnotationOwner   = "Owner"
+	peerAnnotationExpires = "Expires"
+)
+
+// PeerAnnotation contains the portal metadata of a peer that is stored as structured comments in the [Peer]
+// sections of exported wg-quick configuration files. The annotations are restored when an existing device
+// is imported, so the metadata survives a round-trip through the wg-quick tooling.
+type PeerAnnotation struct {
+	PublicKey   string
+	PeerId      PeerIdentifier
+	DisplayName string
+	Owner       UserIdentifier
+	ExpiresAt   *time.Time
+}
+
+// FormatAnnotationTime formats the given time for usage in a peer annotation. Nil values result in an empty string.
+func FormatAnnotationTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ParsePeerAnnotations reads the annotations of all [Peer] sections of the given wg-quick configuration file.
+// The result is indexed by the public key of the peers, sections without public key are ignored.
+func ParsePeerAnnotations(r io.Reader) (map[string]PeerAnnotation, error) {
+	annotations := make(map[string]PeerAnnotation)
+
+	var current *PeerAnnotation
+	finishSection := func() {
+		if current != nil && current.PublicKey != "" {
+			annotations[current.PublicKey] = *current
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "["):
+			finishSection()
+			if strings.EqualFold(line, "[Peer]") {
+				current = &PeerAnnotation{}
+			}
+		case current == nil:
+			continue // not within a peer section
+		case strings.HasPrefix(line, peerAnnotationPrefix):
+			key, value, ok := strings.Cut(strings.TrimPrefix(line, peerAnnotationPrefix), ":")
+			if ok {
+				current.apply(strings.TrimSpace(key), strings.TrimSpace(value))
+			}
+		case strings.HasPrefix(line, peerFriendlyNameKey):
+			_, value, ok := strings.Cut(line, "=")
+			if ok && current.DisplayName == "" {
+				current.DisplayName = strings.TrimSpace(value)
+			}
+		case !strings.HasPrefix(line, "#"):
+			key, value, ok := strings.Cut(line, "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "PublicKey") {
+				current.PublicKey = strings.TrimSpace(value)
+			}
+		}
+	}
+	finishSection()
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return annotations, nil
+}
+
+func (a *PeerAnnotation) apply(key, value string) {
+	if value == "" {
+		return
+	}
+
+	switch key {
+	case peerAnnotationPeer:
+		a.PeerId = PeerIdentifier(value)
+	case peerAnnotationName:
+		a.DisplayName = value
+	case peerAnnotationOwner:
+		a.Owner = UserIdentifier(value)
+	case peerAnnotationExpires:
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			a.ExpiresAt = &t
+		}
+	}
+}
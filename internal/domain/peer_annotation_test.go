@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeerAnnotations(t *testing.T) {
+	expiry := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	config := `# -WGP- WIREGUARD PORTAL CONFIGURATION FILE
+
+[Interface]
+# -WGP- Interface: wg0
+# -WGP- PublicKey = interface-key
+PrivateKey = private-key
+
+[Peer]
+# friendly_name = Laptop
+# -WGP- Peer: peer-a
+# -WGP- Display name: Laptop
+# -WGP- Owner: alice
+# -WGP- Expires: ` + FormatAnnotationTime(&expiry) + `
+PublicKey = key-a
+AllowedIPs = 10.0.0.2/32
+
+[Peer]
+# friendly_name = Router
+PublicKey = key-b
+
+[Peer]
+# -WGP- Owner: bob
+AllowedIPs = 10.0.0.4/32
+`
+
+	annotations, err := ParsePeerAnnotations(strings.NewReader(config))
+	require.NoError(t, err)
+	require.Len(t, annotations, 2) // the section without public key is ignored
+
+	a := annotations["key-a"]
+	assert.Equal(t, PeerIdentifier("peer-a"), a.PeerId)
+	assert.Equal(t, "Laptop", a.DisplayName)
+	assert.Equal(t, UserIdentifier("alice"), a.Owner)
+	require.NotNil(t, a.ExpiresAt)
+	assert.True(t, expiry.Equal(*a.ExpiresAt))
+
+	// plain wg-quick files only provide the friendly name
+	b := annotations["key-b"]
+	assert.Equal(t, "Router", b.DisplayName)
+	assert.Empty(t, b.Owner)
+	assert.Nil(t, b.ExpiresAt)
+}
+
+func TestFormatAnnotationTime(t *testing.T) {
+	assert.Empty(t, FormatAnnotationTime(nil))
+
+	ts := time.Date(2025, 3, 1, 13, 0, 0, 0, time.FixedZone("CET", 3600))
+	assert.Equal(t, "2025-03-01T12:00:00Z", FormatAnnotationTime(&ts))
+}
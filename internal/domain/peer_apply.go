@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PeerApplyError is returned if a set of peer changes could not be applied to the WireGuard device. All peers of the
+// set that were already applied have been rolled back to their state before the apply.
+type PeerApplyError struct {
+	Peer      PeerIdentifier // the peer that failed
+	Interface InterfaceIdentifier
+	Err       error // the reason of the failure
+
+	RolledBack       []PeerIdentifier          // the peers that were restored to their previous state
+	RollbackFailures map[PeerIdentifier]string // the peers that could not be restored, with the reason
+}
+
+func (e *PeerApplyError) Error() string {
+	msg := fmt.Sprintf("failed to apply peer %s on interface %s: %v", e.Peer, e.Interface, e.Err)
+	if len(e.RollbackFailures) == 0 {
+		return msg
+	}
+
+	failures := make([]string, 0, len(e.RollbackFailures))
+	for id, reason := range e.RollbackFailures {
+		failures = append(failures, fmt.Sprintf("%s: %s", id, reason))
+	}
+	sort.Strings(failures)
+	return msg + "; rollback incomplete: " + strings.Join(failures, ", ")
+}
+
+func (e *PeerApplyError) Unwrap() error {
+	return e.Err
+}
+
+// RollbackComplete returns true if all applied changes were restored.
+func (e *PeerApplyError) RollbackComplete() bool {
+	return len(e.RollbackFailures) == 0
+}
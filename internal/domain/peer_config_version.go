@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// PeerConfigVersion is a version of a peer configuration. A version is recorded each time the configuration is
+// mailed to the user, the next configuration mail summarizes what changed since the last mailed version.
+type PeerConfigVersion struct {
+	PeerId   PeerIdentifier     `gorm:"primaryKey;column:identifier"`
+	Version  int                `gorm:"primaryKey;column:version;autoIncrement:false"`
+	MailedAt time.Time          `gorm:"column:mailed_at"`
+	Settings PeerConfigSettings `gorm:"serializer:json;column:settings"`
+}
+
+// PeerConfigSettings contains the settings of a peer configuration that matter to the user. Keys are only stored as
+// fingerprints, the version history must not contain any secrets.
+type PeerConfigSettings struct {
+	PublicKey           string // the public key of the peer, it changes if the key pair was rotated
+	PresharedKeyHash    string
+	Addresses           string
+	Dns                 string
+	DnsSearch           string
+	Mtu                 int
+	Endpoint            string
+	EndpointPublicKey   string
+	AllowedIPs          string
+	RouteSets           string
+	PersistentKeepalive int
+}
+
+// NewPeerConfigSettings returns the current configuration settings of the given peer.
+func NewPeerConfigSettings(peer *Peer) PeerConfigSettings {
+	presharedKeyHash := ""
+	if peer.PresharedKey != "" {
+		hash := sha256.Sum256([]byte(peer.PresharedKey))
+		presharedKeyHash = hex.EncodeToString(hash[:])
+	}
+
+	return PeerConfigSettings{
+		PublicKey:           peer.Interface.PublicKey,
+		PresharedKeyHash:    presharedKeyHash,
+		Addresses:           peer.Interface.AddressStr(),
+		Dns:                 peer.Interface.DnsStr.GetValue(),
+		DnsSearch:           peer.Interface.DnsSearchStr.GetValue(),
+		Mtu:                 peer.Interface.Mtu.GetValue(),
+		Endpoint:            peer.Endpoint.GetValue(),
+		EndpointPublicKey:   peer.EndpointPublicKey.GetValue(),
+		AllowedIPs:          peer.AllowedIPsStr.GetValue(),
+		RouteSets:           peer.RouteSetsStr.GetValue(),
+		PersistentKeepalive: peer.PersistentKeepalive.GetValue(),
+	}
+}
+
+// PeerConfigChange is a setting that changed between two versions of a peer configuration.
+type PeerConfigChange struct {
+	Setting string // the human-readable name of the setting
+	Old     string
+	New     string
+	Secret  bool // if true, the old and new values are not shown, for example, for rotated keys
+}
+
+// Changes returns the settings that changed since the given previous version, in the order of the configuration
+// file.
+func (s PeerConfigSettings) Changes(previous PeerConfigSettings) []PeerConfigChange {
+	var changes []PeerConfigChange
+	add := func(setting, old, new string) {
+		if old != new {
+			changes = append(changes, PeerConfigChange{Setting: setting, Old: old, New: new})
+		}
+	}
+	addSecret := func(setting, old, new string) {
+		if old != new {
+			changes = append(changes, PeerConfigChange{Setting: setting, Secret: true})
+		}
+	}
+	addInt := func(setting string, old, new int) {
+		if old != new {
+			changes = append(changes, PeerConfigChange{Setting: setting, Old: intSetting(old), New: intSetting(new)})
+		}
+	}
+
+	addSecret("Private key", previous.PublicKey, s.PublicKey)
+	add("Addresses", previous.Addresses, s.Addresses)
+	add("DNS servers", previous.Dns, s.Dns)
+	add("DNS search domains", previous.DnsSearch, s.DnsSearch)
+	addInt("MTU", previous.Mtu, s.Mtu)
+	add("Server public key", previous.EndpointPublicKey, s.EndpointPublicKey)
+	addSecret("Pre-shared key", previous.PresharedKeyHash, s.PresharedKeyHash)
+	add("Endpoint", previous.Endpoint, s.Endpoint)
+	add("Allowed IPs", previous.AllowedIPs, s.AllowedIPs)
+	add("Route sets", previous.RouteSets, s.RouteSets)
+	addInt("Persistent keepalive", previous.PersistentKeepalive, s.PersistentKeepalive)
+
+	return changes
+}
+
+// intSetting formats a numeric setting, 0 means that the setting is not set.
+func intSetting(v int) string {
+	if v == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(v)
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerConfigSettings_Changes(t *testing.T) {
+	peer := &Peer{PresharedKey: "psk"}
+	peer.Interface.PublicKey = "public-key"
+	peer.Interface.DnsStr = NewConfigOption("10.0.0.1", true)
+	peer.Endpoint = NewConfigOption("vpn.example.com:51820", true)
+	previous := NewPeerConfigSettings(peer)
+
+	assert.Empty(t, NewPeerConfigSettings(peer).Changes(previous))
+
+	peer.Interface.PublicKey = "rotated-public-key"
+	peer.Interface.DnsStr = NewConfigOption("10.0.0.2", true)
+	peer.Interface.Mtu = NewConfigOption(1380, true)
+
+	changes := NewPeerConfigSettings(peer).Changes(previous)
+	assert.Equal(t, []PeerConfigChange{
+		{Setting: "Private key", Secret: true},
+		{Setting: "DNS servers", Old: "10.0.0.1", New: "10.0.0.2"},
+		{Setting: "MTU", Old: "", New: "1380"},
+	}, changes)
+}
+
+func TestNewPeerConfigSettings_NoSecrets(t *testing.T) {
+	peer := &Peer{PresharedKey: "psk"}
+	peer.Interface.PrivateKey = "private-key"
+
+	settings := NewPeerConfigSettings(peer)
+	assert.NotContains(t, settings.PresharedKeyHash, "psk")
+	assert.NotEmpty(t, settings.PresharedKeyHash)
+	assert.Empty(t, NewPeerConfigSettings(&Peer{}).PresharedKeyHash)
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"slices"
+
+	"github.com/h44z/wg-portal/internal"
+)
+
+// SharedUsers returns the additional users that are authorized to use the peer. The owner of the peer is not part
+// of the list.
+func (p *Peer) SharedUsers() []UserIdentifier {
+	var users []UserIdentifier
+	for _, id := range internal.SliceString(p.SharedUsersStr) {
+		if UserIdentifier(id) != p.UserIdentifier && !slices.Contains(users, UserIdentifier(id)) {
+			users = append(users, UserIdentifier(id))
+		}
+	}
+
+	return users
+}
+
+// SetSharedUsers sets the additional users that are authorized to use the peer.
+func (p *Peer) SetSharedUsers(users []UserIdentifier) {
+	ids := make([]string, len(users))
+	for i, id := range users {
+		ids[i] = string(id)
+	}
+	p.SharedUsersStr = internal.SliceToString(ids)
+}
+
+// IsSharedDevice returns true if the peer is used by several users, for example a lab machine.
+func (p *Peer) IsSharedDevice() bool {
+	return len(p.SharedUsers()) > 0
+}
+
+// Members returns the owner of the peer, followed by all shared users.
+func (p *Peer) Members() []UserIdentifier {
+	var members []UserIdentifier
+	if p.UserIdentifier != "" {
+		members = append(members, p.UserIdentifier)
+	}
+
+	return append(members, p.SharedUsers()...)
+}
+
+// IsMember returns true if the given user owns the peer or the peer is shared with the user.
+func (p *Peer) IsMember(id UserIdentifier) bool {
+	return id != "" && slices.Contains(p.Members(), id)
+}
+
+// RemoveMember removes the given user from the members of the peer. If the user owns the peer, the ownership is
+// handed over to the given successor, which is no longer listed as shared user afterwards.
+func (p *Peer) RemoveMember(id, successor UserIdentifier) {
+	shared := slices.DeleteFunc(p.SharedUsers(), func(u UserIdentifier) bool { return u == id })
+	if p.UserIdentifier == id {
+		p.UserIdentifier = successor
+		shared = slices.DeleteFunc(shared, func(u UserIdentifier) bool { return u == successor })
+	}
+	p.SetSharedUsers(shared)
+}
+
+// RotateKeys generates a new key pair for the peer. If the peer uses a pre-shared key, a new pre-shared key is
+// generated as well. The identifier of the peer is not changed.
+func (p *Peer) RotateKeys() error {
+	keyPair, err := NewFreshKeypair()
+	if err != nil {
+		return err
+	}
+	p.Interface.KeyPair = keyPair
+
+	if p.PresharedKey != "" {
+		p.PresharedKey, err = NewPreSharedKey()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeer_SharedUsers(t *testing.T) {
+	p := &Peer{UserIdentifier: "owner", SharedUsersStr: "alice, owner,bob,alice"}
+
+	assert.Equal(t, []UserIdentifier{"alice", "bob"}, p.SharedUsers())
+	assert.Equal(t, []UserIdentifier{"owner", "alice", "bob"}, p.Members())
+	assert.True(t, p.IsSharedDevice())
+	assert.True(t, p.IsMember("owner"))
+	assert.True(t, p.IsMember("bob"))
+	assert.False(t, p.IsMember("eve"))
+	assert.False(t, p.IsMember(""))
+
+	assert.False(t, (&Peer{UserIdentifier: "owner"}).IsSharedDevice())
+}
+
+func TestPeer_RemoveMember(t *testing.T) {
+	p := &Peer{UserIdentifier: "owner", SharedUsersStr: "alice,bob"}
+
+	p.RemoveMember("alice", "")
+	assert.Equal(t, UserIdentifier("owner"), p.UserIdentifier)
+	assert.Equal(t, []UserIdentifier{"bob"}, p.SharedUsers())
+
+	p.RemoveMember("owner", "bob")
+	assert.Equal(t, UserIdentifier("bob"), p.UserIdentifier)
+	assert.Empty(t, p.SharedUsers())
+	assert.False(t, p.IsSharedDevice())
+}
+
+func TestPeer_RotateKeys(t *testing.T) {
+	keyPair, err := NewFreshKeypair()
+	require.NoError(t, err)
+	p := &Peer{Identifier: PeerIdentifier(keyPair.PublicKey), PresharedKey: "psk"}
+	p.Interface.KeyPair = keyPair
+
+	require.NoError(t, p.RotateKeys())
+	assert.NotEqual(t, keyPair.PublicKey, p.Interface.PublicKey)
+	assert.NotEqual(t, keyPair.PrivateKey, p.Interface.PrivateKey)
+	assert.NotEqual(t, PreSharedKey("psk"), p.PresharedKey)
+	assert.Equal(t, PeerIdentifier(keyPair.PublicKey), p.Identifier)
+
+	p.PresharedKey = ""
+	require.NoError(t, p.RotateKeys())
+	assert.Empty(t, p.PresharedKey)
+}
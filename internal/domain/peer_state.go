@@ -0,0 +1,48 @@
+package domain
+
+import "time"
+
+type PeerStateChangeKind string
+
+const (
+	PeerStateConnected       PeerStateChangeKind = "connected"
+	PeerStateDisconnected    PeerStateChangeKind = "disconnected"
+	PeerStateEndpointChanged PeerStateChangeKind = "endpoint-changed"
+)
+
+// PeerStateChange is published on the event bus if the connection state or the endpoint of a peer changed.
+type PeerStateChange struct {
+	PeerId      PeerIdentifier
+	InterfaceId InterfaceIdentifier
+	Kind        PeerStateChangeKind
+	Time        time.Time
+
+	Endpoint         string // the current endpoint of the peer
+	PreviousEndpoint string // the endpoint before the change, only set for endpoint changes
+	LastHandshake    *time.Time
+}
+
+// PeerConnectionEvent is a state change of a peer that is stored in the connection history of the peer.
+type PeerConnectionEvent struct {
+	PeerId    PeerIdentifier      `gorm:"primaryKey;column:identifier"`
+	Timestamp time.Time           `gorm:"primaryKey;column:timestamp;index:idx_pce_timestamp"`
+	Kind      PeerStateChangeKind `gorm:"primaryKey;column:kind"`
+
+	InterfaceId      InterfaceIdentifier `gorm:"column:interface_identifier"`
+	Endpoint         string              `gorm:"column:endpoint"`
+	PreviousEndpoint string              `gorm:"column:previous_endpoint"`
+	LastHandshake    *time.Time          `gorm:"column:last_handshake"`
+}
+
+// NewPeerConnectionEvent returns the connection history event for the given state change.
+func NewPeerConnectionEvent(change PeerStateChange) PeerConnectionEvent {
+	return PeerConnectionEvent{
+		PeerId:           change.PeerId,
+		Timestamp:        change.Time.UTC(),
+		Kind:             change.Kind,
+		InterfaceId:      change.InterfaceId,
+		Endpoint:         change.Endpoint,
+		PreviousEndpoint: change.PreviousEndpoint,
+		LastHandshake:    change.LastHandshake,
+	}
+}
@@ -164,3 +164,12 @@ func TestPeer_GetAllowedIPs(t *testing.T) {
 	assert.Equal(t, "192.168.1.0/24", ips2[0].String())
 	assert.Equal(t, "fe80::/64", ips2[1].String())
 }
+
+func TestPeer_NextKeyRotation(t *testing.T) {
+	peer := &Peer{}
+	assert.True(t, peer.NextKeyRotation(time.Hour).IsZero())
+
+	rotated := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	peer.KeysRotatedAt = &rotated
+	assert.Equal(t, rotated.Add(time.Hour), peer.NextKeyRotation(time.Hour))
+}
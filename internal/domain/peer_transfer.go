@@ -0,0 +1,73 @@
+package domain
+
+import "time"
+
+type PeerTransferIdentifier string
+
+type PeerTransferState string
+
+const (
+	PeerTransferPending   PeerTransferState = "pending"   // waiting for the target user and, if required, an admin
+	PeerTransferCompleted PeerTransferState = "completed" // the peer was moved to the target user
+	PeerTransferRejected  PeerTransferState = "rejected"  // the target user or an admin rejected the transfer
+	PeerTransferCancelled PeerTransferState = "cancelled" // the requesting user withdrew the transfer
+	PeerTransferExpired   PeerTransferState = "expired"   // the transfer was not accepted in time
+)
+
+// PeerTransfer is a request to move the ownership of a peer to another user. The transfer is completed once the
+// target user, and if required an admin, accepted it. On completion, the keys of the peer are rotated, so the
+// previous owner can no longer use the peer configuration.
+type PeerTransfer struct {
+	Identifier PeerTransferIdentifier `gorm:"primaryKey;column:identifier"`
+	PeerId     PeerIdentifier         `gorm:"index;column:peer_identifier"`
+	PeerName   string                 `gorm:"column:peer_name"` // the display name of the peer at request time
+
+	InterfaceIdentifier InterfaceIdentifier `gorm:"index;column:interface_identifier"`
+
+	FromUser UserIdentifier `gorm:"index;column:from_user"` // the owner of the peer at request time
+	ToUser   UserIdentifier `gorm:"index;column:to_user"`
+	Message  string         `gorm:"column:message"` // an optional message of the requesting user
+
+	State       PeerTransferState `gorm:"column:state"`
+	RequestedBy UserIdentifier    `gorm:"column:requested_by"`
+	RequestedAt time.Time         `gorm:"column:requested_at"`
+	ExpiresAt   time.Time         `gorm:"column:expires_at"`
+
+	AcceptedAt       *time.Time     `gorm:"column:accepted_at"` // the time the target user accepted the transfer
+	RequiresApproval bool           `gorm:"column:requires_approval"`
+	ApprovedBy       UserIdentifier `gorm:"column:approved_by"` // the admin that approved the transfer
+	ApprovedAt       *time.Time     `gorm:"column:approved_at"`
+	ResolvedBy       UserIdentifier `gorm:"column:resolved_by"` // the user that completed, rejected or cancelled
+	ResolvedAt       *time.Time     `gorm:"column:resolved_at"`
+}
+
+// IsOpen returns true if the transfer is still waiting for acceptance.
+func (t *PeerTransfer) IsOpen() bool {
+	return t.State == PeerTransferPending
+}
+
+// IsExpired returns true if the open transfer was not accepted in time.
+func (t *PeerTransfer) IsExpired(now time.Time) bool {
+	return t.IsOpen() && !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+// IsAccepted returns true if the target user and, if required, an admin accepted the transfer.
+func (t *PeerTransfer) IsAccepted() bool {
+	if t.AcceptedAt == nil {
+		return false
+	}
+
+	return !t.RequiresApproval || t.ApprovedAt != nil
+}
+
+// IsParticipant returns true if the given user requested or receives the transfer.
+func (t *PeerTransfer) IsParticipant(userId UserIdentifier) bool {
+	return userId == t.FromUser || userId == t.ToUser
+}
+
+// Resolve sets the final state of the transfer.
+func (t *PeerTransfer) Resolve(state PeerTransferState, userId UserIdentifier, now time.Time) {
+	t.State = state
+	t.ResolvedBy = userId
+	t.ResolvedAt = &now
+}
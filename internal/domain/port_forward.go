@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+type PortForwardIdentifier string
+
+type PortForwardProtocol string
+
+const (
+	PortForwardTcp PortForwardProtocol = "tcp"
+	PortForwardUdp PortForwardProtocol = "udp"
+)
+
+// PortForward forwards an inbound port of the server to a port on the tunnel address of a peer, for example to
+// expose a web server that runs on the device of the peer.
+type PortForward struct {
+	Identifier PortForwardIdentifier `gorm:"primaryKey;column:identifier"`
+	PeerId     PeerIdentifier        `gorm:"index;column:peer_identifier"`
+
+	InterfaceIdentifier InterfaceIdentifier `gorm:"index;column:interface_identifier"`
+
+	Protocol     PortForwardProtocol `gorm:"column:protocol"`
+	ExternalPort int                 `gorm:"column:external_port"` // the port on the server
+	TargetPort   int                 `gorm:"column:target_port"`   // the port on the tunnel address of the peer
+	Description  string              `gorm:"column:description"`
+
+	CreatedBy UserIdentifier `gorm:"column:created_by"`
+	CreatedAt time.Time      `gorm:"column:created_at"`
+}
+
+// Validate performs checks to ensure that the port forward is valid.
+func (f *PortForward) Validate() error {
+	if f.Protocol != PortForwardTcp && f.Protocol != PortForwardUdp {
+		return fmt.Errorf("invalid protocol %s, only tcp and udp are supported", f.Protocol)
+	}
+	if f.ExternalPort < 1 || f.ExternalPort > 65535 {
+		return fmt.Errorf("invalid external port %d", f.ExternalPort)
+	}
+	if f.TargetPort < 1 || f.TargetPort > 65535 {
+		return fmt.Errorf("invalid target port %d", f.TargetPort)
+	}
+
+	return nil
+}
+
+// ConflictsWith returns true if both port forwards use the same external port and protocol.
+func (f *PortForward) ConflictsWith(other PortForward) bool {
+	return f.Identifier != other.Identifier && f.Protocol == other.Protocol && f.ExternalPort == other.ExternalPort
+}
+
+// PortForwardTarget returns the tunnel address of the peer that port forwards are sent to. IPv4 addresses are
+// preferred, the result is invalid if the peer has no address.
+func (p *Peer) PortForwardTarget() netip.Addr {
+	var target netip.Addr
+	for _, cidr := range p.Interface.Addresses {
+		addr := cidr.Prefix().Addr().Unmap()
+		if addr.Is4() {
+			return addr
+		}
+		if !target.IsValid() {
+			target = addr
+		}
+	}
+
+	return target
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortForward_Validate(t *testing.T) {
+	assert.NoError(t, (&PortForward{Protocol: PortForwardTcp, ExternalPort: 8080, TargetPort: 80}).Validate())
+	assert.Error(t, (&PortForward{Protocol: "icmp", ExternalPort: 8080, TargetPort: 80}).Validate())
+	assert.Error(t, (&PortForward{Protocol: PortForwardUdp, ExternalPort: 0, TargetPort: 80}).Validate())
+	assert.Error(t, (&PortForward{Protocol: PortForwardUdp, ExternalPort: 8080, TargetPort: 70000}).Validate())
+}
+
+func TestPortForward_ConflictsWith(t *testing.T) {
+	forward := PortForward{Identifier: "a", Protocol: PortForwardTcp, ExternalPort: 8080}
+
+	assert.True(t, forward.ConflictsWith(PortForward{Identifier: "b", Protocol: PortForwardTcp, ExternalPort: 8080}))
+	assert.False(t, forward.ConflictsWith(PortForward{Identifier: "b", Protocol: PortForwardUdp, ExternalPort: 8080}))
+	assert.False(t, forward.ConflictsWith(PortForward{Identifier: "b", Protocol: PortForwardTcp, ExternalPort: 8081}))
+	assert.False(t, forward.ConflictsWith(forward))
+}
+
+func TestPeer_PortForwardTarget(t *testing.T) {
+	addresses, err := CidrsFromArray([]string{"fd00::2/128", "10.11.12.2/32"})
+	require.NoError(t, err)
+
+	peer := Peer{Interface: PeerInterfaceConfig{Addresses: addresses}}
+	assert.Equal(t, netip.MustParseAddr("10.11.12.2"), peer.PortForwardTarget())
+
+	peer.Interface.Addresses = addresses[:1]
+	assert.Equal(t, netip.MustParseAddr("fd00::2"), peer.PortForwardTarget())
+
+	peer.Interface.Addresses = nil
+	assert.False(t, peer.PortForwardTarget().IsValid())
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+)
+
+// PortRange is an inclusive range of UDP ports.
+type PortRange struct {
+	First int
+	Last  int
+}
+
+// PortPool is an ordered list of port ranges that are used to assign listen ports to new interfaces.
+type PortPool []PortRange
+
+// ParsePortPool parses a comma separated list of ports and port ranges, for example "51820-51829,51900".
+// An empty specification results in an empty pool.
+func ParsePortPool(spec string) (PortPool, error) {
+	var pool PortPool
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		firstStr, lastStr, isRange := strings.Cut(part, "-")
+		first, err := parsePoolPort(firstStr)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parsePoolPort(lastStr); err != nil {
+				return nil, err
+			}
+		}
+		if last < first {
+			return nil, fmt.Errorf("invalid port range %s: %w", part, ErrInvalidData)
+		}
+
+		pool = append(pool, PortRange{First: first, Last: last})
+	}
+
+	return pool, nil
+}
+
+func parsePoolPort(value string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q: %w", value, ErrInvalidData)
+	}
+
+	return port, nil
+}
+
+// Contains returns true if the given port is part of the pool.
+func (p PortPool) Contains(port int) bool {
+	for _, r := range p {
+		if port >= r.First && port <= r.Last {
+			return true
+		}
+	}
+
+	return false
+}
+
+// All returns all ports of the pool, in the order of the configured ranges.
+func (p PortPool) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for _, r := range p {
+			for port := r.First; port <= r.Last; port++ {
+				if !yield(port) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePortPool(t *testing.T) {
+	pool, err := ParsePortPool("51900, 51820-51822")
+	require.NoError(t, err)
+	assert.Equal(t, PortPool{{First: 51900, Last: 51900}, {First: 51820, Last: 51822}}, pool)
+	assert.Equal(t, []int{51900, 51820, 51821, 51822}, slices.Collect(pool.All()))
+	assert.True(t, pool.Contains(51821))
+	assert.False(t, pool.Contains(51823))
+
+	pool, err = ParsePortPool("")
+	require.NoError(t, err)
+	assert.Empty(t, pool)
+
+	for _, spec := range []string{"abc", "0", "65536", "51830-51820", "51820-"} {
+		_, err = ParsePortPool(spec)
+		assert.ErrorIs(t, err, ErrInvalidData, spec)
+	}
+}
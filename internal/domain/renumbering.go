@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// maxRenumberingCandidates limits the number of networks that are checked for conflicts when a larger network
+// is suggested.
+const maxRenumberingCandidates = 64
+
+// InterfaceRenumbering describes the migration of a peer network (address pool) of an interface to a larger network.
+// All addresses of the old network are moved to the same position in the new network. If the new network contains
+// the old network, the addresses are kept and only the prefix length of the interface address changes.
+type InterfaceRenumbering struct {
+	InterfaceId InterfaceIdentifier
+	From        Cidr // the current peer network
+	To          Cidr // the new, larger peer network
+
+	InterfaceAddresses []AddressChange // the changed addresses of the interface
+	Peers              []PeerRenumbering
+
+	SendMails bool // if true, the updated configurations are mailed to the users of the renumbered peers
+}
+
+// AddressChange is an address that is replaced by a renumbering.
+type AddressChange struct {
+	Old Cidr
+	New Cidr
+}
+
+// PeerRenumbering contains the address changes of a single peer.
+type PeerRenumbering struct {
+	PeerId         PeerIdentifier
+	DisplayName    string
+	UserIdentifier UserIdentifier
+	Disabled       bool
+	Addresses      []AddressChange
+}
+
+// PeerIds returns the identifiers of all renumbered peers.
+func (r *InterfaceRenumbering) PeerIds() []PeerIdentifier {
+	ids := make([]PeerIdentifier, len(r.Peers))
+	for i, peer := range r.Peers {
+		ids[i] = peer.PeerId
+	}
+
+	return ids
+}
+
+// RenumberAddress moves the given address from the old network to the same position in the new network. The
+// second return value is false if the address is not part of the old network. Addresses that have the prefix length
+// of the old network, like interface addresses, get the prefix length of the new network.
+func RenumberAddress(addr, from, to Cidr) (Cidr, bool) {
+	fromPrefix := from.Prefix().Masked()
+	toPrefix := to.Prefix().Masked()
+	addrPrefix := addr.Prefix()
+	if !fromPrefix.Contains(addrPrefix.Addr()) {
+		return addr, false
+	}
+
+	bits := addrPrefix.Bits()
+	if bits == fromPrefix.Bits() {
+		bits = toPrefix.Bits()
+	}
+
+	if toPrefix.Contains(addrPrefix.Addr()) {
+		return CidrFromPrefix(netip.PrefixFrom(addrPrefix.Addr(), bits)), true
+	}
+
+	// the host part of the address in the old network is kept, the network part is replaced
+	a := addrPrefix.Addr().AsSlice()
+	n := toPrefix.Addr().AsSlice()
+	for i := range a {
+		hostBits := max(0, min(8, (i+1)*8-fromPrefix.Bits()))
+		a[i] = n[i] | (a[i] & byte(1<<hostBits-1))
+	}
+	newAddr, _ := netip.AddrFromSlice(a)
+
+	return CidrFromPrefix(netip.PrefixFrom(newAddr, bits)), true
+}
+
+// RenumberAddressList renumbers all entries of a comma separated list of addresses or networks. Entries that equal
+// the old network are replaced by the new network, entries that are not part of the old network are kept.
+func RenumberAddressList(list string, from, to Cidr) string {
+	if strings.TrimSpace(list) == "" {
+		return list
+	}
+
+	entries := strings.Split(list, ",")
+	for i, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		entries[i] = entry
+
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			renumbered, ok := RenumberAddress(CidrFromPrefix(netip.PrefixFrom(addr, addr.BitLen())), from, to)
+			if ok {
+				entries[i] = renumbered.Prefix().Addr().String()
+			}
+			continue
+		}
+
+		cidr, err := CidrFromString(entry)
+		if err != nil {
+			continue // not an address, for example, a DNS name
+		}
+		if cidr.Prefix().Masked() == from.Prefix().Masked() {
+			entries[i] = to.NetworkAddr().String()
+			continue
+		}
+		if renumbered, ok := RenumberAddress(cidr, from, to); ok {
+			entries[i] = renumbered.String()
+		}
+	}
+
+	return strings.Join(entries, ",")
+}
+
+// SuggestRenumberingNetwork suggests a larger network for the given peer network, so that the used addresses fill
+// at most half of the new network. The next larger network that contains the current network is preferred. If it
+// overlaps with one of the given networks, the following networks of the same size are checked. The second return
+// value is false if no suitable network was found.
+func SuggestRenumberingNetwork(network Cidr, used uint64, occupied []Cidr) (Cidr, bool) {
+	prefix := network.Prefix().Masked()
+
+	bits := prefix.Bits() - 1
+	for bits > 0 {
+		candidate := CidrFromPrefix(netip.PrefixFrom(prefix.Addr(), bits).Masked())
+		if used <= addressPoolSize(candidate)/2 {
+			break
+		}
+		bits--
+	}
+	if bits <= 0 {
+		return Cidr{}, false
+	}
+
+	candidate := CidrFromPrefix(netip.PrefixFrom(prefix.Addr(), bits).Masked())
+	for range maxRenumberingCandidates {
+		if !overlapsAny(candidate, occupied) {
+			return candidate, true
+		}
+
+		next := candidate.BroadcastAddr().Prefix().Addr().Next()
+		if !next.IsValid() {
+			break // end of the address space
+		}
+		candidate = CidrFromPrefix(netip.PrefixFrom(next, bits))
+	}
+
+	return Cidr{}, false
+}
+
+func overlapsAny(network Cidr, others []Cidr) bool {
+	for _, other := range others {
+		if network.Prefix().Overlaps(other.Prefix().Masked()) {
+			return true
+		}
+	}
+
+	return false
+}
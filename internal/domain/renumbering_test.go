@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenumberAddress(t *testing.T) {
+	from := mustCidrs(t, "10.0.1.0/24")[0]
+
+	// the new network contains the old network, addresses are kept
+	to := mustCidrs(t, "10.0.0.0/23")[0]
+	addr, ok := RenumberAddress(mustCidrs(t, "10.0.1.7/32")[0], from, to)
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.1.7/32", addr.String())
+	addr, _ = RenumberAddress(mustCidrs(t, "10.0.1.1/24")[0], from, to)
+	assert.Equal(t, "10.0.1.1/23", addr.String(), "interface addresses get the new prefix length")
+
+	// the new network is a different range, the host part is kept
+	to = mustCidrs(t, "172.16.4.0/22")[0]
+	addr, ok = RenumberAddress(mustCidrs(t, "10.0.1.7/32")[0], from, to)
+	assert.True(t, ok)
+	assert.Equal(t, "172.16.4.7/32", addr.String())
+
+	_, ok = RenumberAddress(mustCidrs(t, "10.0.2.7/32")[0], from, to)
+	assert.False(t, ok)
+
+	addr, ok = RenumberAddress(mustCidrs(t, "fd00:1::5/128")[0], mustCidrs(t, "fd00:1::/120")[0],
+		mustCidrs(t, "fd00:2::/112")[0])
+	assert.True(t, ok)
+	assert.Equal(t, "fd00:2::5/128", addr.String())
+}
+
+func TestRenumberAddressList(t *testing.T) {
+	from := mustCidrs(t, "10.0.1.0/24")[0]
+	to := mustCidrs(t, "172.16.4.0/22")[0]
+
+	assert.Equal(t, "172.16.4.0/22,192.168.0.0/16", RenumberAddressList("10.0.1.0/24, 192.168.0.0/16", from, to))
+	assert.Equal(t, "172.16.4.1,dns.example.com", RenumberAddressList("10.0.1.1,dns.example.com", from, to))
+	assert.Equal(t, "", RenumberAddressList("", from, to))
+}
+
+func TestSuggestRenumberingNetwork(t *testing.T) {
+	network := mustCidrs(t, "10.0.0.0/24")[0]
+
+	suggested, ok := SuggestRenumberingNetwork(network, 200, nil)
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.0/23", suggested.String())
+
+	suggested, ok = SuggestRenumberingNetwork(network, 600, nil)
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.0/21", suggested.String())
+
+	// the enlarged network overlaps with another network, the next free network of the same size is suggested
+	suggested, ok = SuggestRenumberingNetwork(network, 200, mustCidrs(t, "10.0.1.0/24", "10.0.2.0/24"))
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.4.0/23", suggested.String())
+
+	_, ok = SuggestRenumberingNetwork(mustCidrs(t, "255.255.255.0/24")[0], 200, mustCidrs(t, "255.255.254.0/24"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+type RestoreStepStatus string
+
+const (
+	RestoreStepSucceeded RestoreStepStatus = "succeeded"
+	RestoreStepFailed    RestoreStepStatus = "failed"
+	RestoreStepSkipped   RestoreStepStatus = "skipped" // the feature is disabled, or a previous step failed
+)
+
+// RestoreStepResult is the result of a single step of the startup restoration.
+type RestoreStepResult struct {
+	Name     string // for example interface:wg0, routes or dns:records
+	Status   RestoreStepStatus
+	Error    string // the reason of the failure, empty if the step did not fail
+	Duration time.Duration
+}
+
+// RestoreReport is the result of the startup restoration. The steps are listed in the order they were applied.
+type RestoreReport struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Steps      []RestoreStepResult
+}
+
+// Failed returns true if at least one step failed.
+func (r RestoreReport) Failed() bool {
+	for _, step := range r.Steps {
+		if step.Status == RestoreStepFailed {
+			return true
+		}
+	}
+	return false
+}
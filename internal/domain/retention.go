@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErasedPlaceholder replaces the personal data of erased users in records that are kept, for example audit entries.
+const ErasedPlaceholder = "[erased]"
+
+// minErasureValueLength is the minimum length of a personal data value that is replaced in free-text records.
+// Shorter values would also match unrelated parts of the text.
+const minErasureValueLength = 3
+
+// UserErasure is the result of the erasure of the personal data of a user.
+type UserErasure struct {
+	UserId   UserIdentifier
+	ErasedAt time.Time
+
+	DeletedPeers    []PeerIdentifier // peers that were deleted
+	AnonymizedPeers []PeerIdentifier // peers that were disabled and unlinked from the user, their statistics are kept
+}
+
+// PersonalDataValues returns the values that identify the user in free-text records like audit messages.
+// Values that are too short to be replaced safely are omitted.
+// The user might be nil if the user was already deleted, only the identifier is known in that case.
+func PersonalDataValues(id UserIdentifier, user *User) []string {
+	values := []string{string(id)}
+	if user != nil {
+		values = append(values, user.Email)
+		if user.Firstname != "" && user.Lastname != "" {
+			values = append(values, user.Firstname+" "+user.Lastname)
+		}
+	}
+
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) < minErasureValueLength {
+			continue
+		}
+		result = append(result, value)
+	}
+
+	// longer values first, so that values which contain other values are replaced completely
+	slices.SortStableFunc(result, func(a, b string) int { return len(b) - len(a) })
+
+	return result
+}
+
+// AnonymizeText replaces all given personal data values in the text with the ErasedPlaceholder.
+func AnonymizeText(text string, values []string) string {
+	for _, value := range values {
+		text = strings.ReplaceAll(text, value, ErasedPlaceholder)
+	}
+	return text
+}
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPersonalDataValues(t *testing.T) {
+	user := &User{Identifier: "al", Email: "al@example.com", Firstname: "Al", Lastname: "Bundy"}
+
+	assert.Equal(t, []string{"al@example.com", "Al Bundy"}, PersonalDataValues("al", user))
+	assert.Equal(t, []string{"alice"}, PersonalDataValues("alice", nil))
+}
+
+func TestAnonymizeText(t *testing.T) {
+	values := PersonalDataValues("alice", &User{Email: "alice@example.com"})
+
+	assert.Equal(t, "[erased] failed to login", AnonymizeText("alice@example.com failed to login", values))
+	assert.Equal(t, "[erased] logged in", AnonymizeText("alice logged in", values))
+	assert.Equal(t, "bob logged in", AnonymizeText("bob logged in", values))
+}
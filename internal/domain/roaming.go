@@ -0,0 +1,235 @@
+package domain
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal"
+)
+
+type RoamingViolationKind string
+
+const (
+	RoamingViolationChurn   RoamingViolationKind = "endpoint-churn"  // too many endpoint changes within an hour
+	RoamingViolationCountry RoamingViolationKind = "country-changed" // the endpoint moved to another country
+	RoamingViolationPin     RoamingViolationKind = "outside-pin"     // the endpoint is not allowed by the endpoint pin
+)
+
+// RoamingViolation describes an endpoint of a peer that violates the roaming policy.
+type RoamingViolation struct {
+	PeerId         PeerIdentifier
+	PeerName       string // the display name of the peer, or its identifier if no display name is set
+	InterfaceId    InterfaceIdentifier
+	UserIdentifier UserIdentifier
+	Kind           RoamingViolationKind
+	Time           time.Time
+	Message        string // a short description of the violation
+
+	Endpoint         string
+	PreviousEndpoint string // empty if the peer connected with the endpoint
+	Country          string // the country of the endpoint, empty if unknown
+	PreviousCountry  string // the country of the previous endpoint, empty if unknown
+	Changes          int    // the number of endpoint changes within the last hour
+}
+
+// EndpointPin restricts the source endpoints of a peer to a set of networks or countries.
+// An empty pin allows all endpoints.
+type EndpointPin struct {
+	Networks  []netip.Prefix
+	Countries []string // upper case ISO 3166-1 alpha-2 country codes
+}
+
+// ParseEndpointPin parses a comma separated list of networks, addresses and two-letter country codes,
+// for example "203.0.113.0/24, 2001:db8::1, AT".
+func ParseEndpointPin(str string) (EndpointPin, error) {
+	var pin EndpointPin
+	for _, entry := range internal.SliceString(str) {
+		if len(entry) == 2 && isLetters(entry) {
+			pin.Countries = append(pin.Countries, strings.ToUpper(entry))
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			pin.Networks = append(pin.Networks, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			pin.Networks = append(pin.Networks, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		return EndpointPin{}, fmt.Errorf("invalid endpoint pin %q, expected a network or country code: %w",
+			entry, ErrInvalidData)
+	}
+
+	return pin, nil
+}
+
+func isLetters(str string) bool {
+	for _, r := range str {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEmpty returns true if the pin does not restrict the endpoints.
+func (p EndpointPin) IsEmpty() bool {
+	return len(p.Networks) == 0 && len(p.Countries) == 0
+}
+
+// HasCountries returns true if the pin contains country codes, which require a country database.
+func (p EndpointPin) HasCountries() bool {
+	return len(p.Countries) > 0
+}
+
+// Allows returns true if the endpoint address or its country, which may be empty if unknown, matches the pin.
+func (p EndpointPin) Allows(addr netip.Addr, country string) bool {
+	if p.IsEmpty() {
+		return true
+	}
+	for _, network := range p.Networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	for _, c := range p.Countries {
+		if c == country {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateEndpointPin checks the endpoint pin of the peer.
+func (p *Peer) ValidateEndpointPin() error {
+	_, err := ParseEndpointPin(p.EndpointPinStr)
+	return err
+}
+
+// IsExcludedFromRoamingPolicy returns true if the notes of the peer contain the exclusion tag.
+func (p *Peer) IsExcludedFromRoamingPolicy(exclusionTag string) bool {
+	return p.HasTag(exclusionTag)
+}
+
+// EndpointAddr returns the IP address of a WireGuard endpoint like "203.0.113.7:51820".
+func EndpointAddr(endpoint string) (netip.Addr, bool) {
+	addrPort, err := netip.ParseAddrPort(endpoint)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addrPort.Addr().Unmap(), true
+}
+
+// TrackEndpointChange appends the endpoint change at the given time to the recent endpoint changes of a peer and
+// drops all changes that are older than the window.
+func TrackEndpointChange(changes []time.Time, now time.Time, window time.Duration) []time.Time {
+	recent := changes[:0]
+	for _, change := range changes {
+		if now.Sub(change) < window {
+			recent = append(recent, change)
+		}
+	}
+
+	return append(recent, now)
+}
+
+// CountryRange maps a range of IP addresses to a country.
+type CountryRange struct {
+	Start   netip.Addr
+	End     netip.Addr
+	Country string // upper case ISO 3166-1 alpha-2 country code
+}
+
+// CountryDatabase maps IP addresses to countries. The ranges must not overlap.
+type CountryDatabase struct {
+	ranges []CountryRange // sorted by start address
+}
+
+// NewCountryDatabase returns a country database for the given ranges.
+func NewCountryDatabase(ranges []CountryRange) *CountryDatabase {
+	sorted := make([]CountryRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Less(sorted[j].Start) })
+
+	return &CountryDatabase{ranges: sorted}
+}
+
+// ParseCountryDatabase parses a country database with one "start_ip,end_ip,country" range per line, as used by the
+// freely available IP to country lite databases. Additional columns, comments and a header line are ignored.
+func ParseCountryDatabase(r io.Reader) (*CountryDatabase, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var ranges []CountryRange
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: expected start address, end address and country", line)
+		}
+
+		start, startErr := netip.ParseAddr(record[0])
+		end, endErr := netip.ParseAddr(record[1])
+		if startErr != nil || endErr != nil {
+			if line == 1 {
+				continue // header line
+			}
+			return nil, fmt.Errorf("line %d: invalid address range %s - %s", line, record[0], record[1])
+		}
+		start, end = start.Unmap(), end.Unmap()
+		if start.BitLen() != end.BitLen() || end.Less(start) {
+			return nil, fmt.Errorf("line %d: invalid address range %s - %s", line, record[0], record[1])
+		}
+
+		ranges = append(ranges, CountryRange{
+			Start:   start,
+			End:     end,
+			Country: strings.ToUpper(strings.TrimSpace(record[2])),
+		})
+	}
+
+	return NewCountryDatabase(ranges), nil
+}
+
+// Size returns the number of ranges in the database.
+func (db *CountryDatabase) Size() int {
+	if db == nil {
+		return 0
+	}
+	return len(db.ranges)
+}
+
+// Lookup returns the country of the given address, or an empty string if the address is not part of any range.
+func (db *CountryDatabase) Lookup(addr netip.Addr) string {
+	if db == nil || !addr.IsValid() {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	// find the last range that starts before or at the address
+	idx := sort.Search(len(db.ranges), func(i int) bool { return addr.Less(db.ranges[i].Start) }) - 1
+	if idx < 0 {
+		return ""
+	}
+	r := db.ranges[idx]
+	if r.Start.BitLen() != addr.BitLen() || r.End.Less(addr) {
+		return ""
+	}
+
+	return r.Country
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEndpointPin(t *testing.T) {
+	pin, err := ParseEndpointPin("203.0.113.7/24, 2001:db8::1, at")
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24"), netip.MustParsePrefix("2001:db8::1/128")},
+		pin.Networks)
+	assert.Equal(t, []string{"AT"}, pin.Countries)
+
+	pin, err = ParseEndpointPin(" ")
+	require.NoError(t, err)
+	assert.True(t, pin.IsEmpty())
+
+	_, err = ParseEndpointPin("203.0.113.0/24, Austria")
+	assert.ErrorIs(t, err, ErrInvalidData)
+}
+
+func TestEndpointPin_Allows(t *testing.T) {
+	pin, err := ParseEndpointPin("203.0.113.0/24, AT")
+	require.NoError(t, err)
+
+	assert.True(t, pin.Allows(netip.MustParseAddr("203.0.113.7"), ""))
+	assert.True(t, pin.Allows(netip.MustParseAddr("198.51.100.7"), "AT"))
+	assert.False(t, pin.Allows(netip.MustParseAddr("198.51.100.7"), "DE"))
+	assert.False(t, pin.Allows(netip.MustParseAddr("198.51.100.7"), ""))
+	assert.True(t, EndpointPin{}.Allows(netip.MustParseAddr("198.51.100.7"), ""))
+}
+
+func TestEndpointAddr(t *testing.T) {
+	addr, ok := EndpointAddr("203.0.113.7:51820")
+	assert.True(t, ok)
+	assert.Equal(t, "203.0.113.7", addr.String())
+
+	addr, ok = EndpointAddr("[::ffff:203.0.113.7]:51820")
+	assert.True(t, ok)
+	assert.Equal(t, "203.0.113.7", addr.String(), "mapped addresses are unmapped")
+
+	_, ok = EndpointAddr("(none)")
+	assert.False(t, ok)
+}
+
+func TestTrackEndpointChange(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	changes := TrackEndpointChange(nil, now.Add(-90*time.Minute), time.Hour)
+	changes = TrackEndpointChange(changes, now.Add(-30*time.Minute), time.Hour)
+	assert.Len(t, changes, 1, "the first change is outside of the window")
+
+	changes = TrackEndpointChange(changes, now, time.Hour)
+	assert.Equal(t, []time.Time{now.Add(-30 * time.Minute), now}, changes)
+}
+
+func TestCountryDatabase_Lookup(t *testing.T) {
+	db := NewCountryDatabase([]CountryRange{
+		{Start: netip.MustParseAddr("2001:db8::"), End: netip.MustParseAddr("2001:db8::ffff"), Country: "DE"},
+		{Start: netip.MustParseAddr("203.0.113.0"), End: netip.MustParseAddr("203.0.113.255"), Country: "AT"},
+		{Start: netip.MustParseAddr("198.51.100.0"), End: netip.MustParseAddr("198.51.100.127"), Country: "US"},
+	})
+
+	assert.Equal(t, 3, db.Size())
+	assert.Equal(t, "AT", db.Lookup(netip.MustParseAddr("203.0.113.7")))
+	assert.Equal(t, "US", db.Lookup(netip.MustParseAddr("198.51.100.0")))
+	assert.Equal(t, "", db.Lookup(netip.MustParseAddr("198.51.100.128")), "gap between ranges")
+	assert.Equal(t, "", db.Lookup(netip.MustParseAddr("10.0.0.1")), "before the first range")
+	assert.Equal(t, "DE", db.Lookup(netip.MustParseAddr("2001:db8::1")))
+	assert.Equal(t, "", db.Lookup(netip.MustParseAddr("2001:db9::1")))
+
+	var empty *CountryDatabase
+	assert.Equal(t, "", empty.Lookup(netip.MustParseAddr("203.0.113.7")))
+}
+
+func TestParseCountryDatabase(t *testing.T) {
+	db, err := ParseCountryDatabase(strings.NewReader(`start_ip,end_ip,country
+# documentation ranges
+203.0.113.0,203.0.113.255,at
+198.51.100.0,198.51.100.255,DE
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 2, db.Size())
+
+	_, err = ParseCountryDatabase(strings.NewReader("203.0.113.0,203.0.113.255,AT\n203.0.113.255,10.0.0.1,DE\n"))
+	assert.Error(t, err, "end before start")
+	_, err = ParseCountryDatabase(strings.NewReader("203.0.113.0,203.0.113.255,AT\nfoo,bar,DE\n"))
+	assert.Error(t, err, "invalid line after the header")
+}
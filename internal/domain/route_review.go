@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"net/netip"
+	"slices"
+	"time"
+)
+
+type RouteReviewIdentifier string
+
+type RouteReviewState string
+
+const (
+	RouteReviewPending    RouteReviewState = "pending"    // waiting for the approval of an admin
+	RouteReviewApproved   RouteReviewState = "approved"   // the routes were applied to the peer
+	RouteReviewRejected   RouteReviewState = "rejected"   // an admin rejected the routes
+	RouteReviewCancelled  RouteReviewState = "cancelled"  // the requesting user withdrew the routes
+	RouteReviewSuperseded RouteReviewState = "superseded" // the user submitted other routes for the same peer
+	RouteReviewExpired    RouteReviewState = "expired"    // the review was not approved in time
+)
+
+// RouteReview holds routes that a user requested for their own peer, but that are not covered by the route allowlist
+// of the interface. The routes are only applied to the peer once an admin approved them.
+type RouteReview struct {
+	Identifier RouteReviewIdentifier `gorm:"primaryKey;column:identifier"`
+	PeerId     PeerIdentifier        `gorm:"index;column:peer_identifier"`
+	PeerName   string                `gorm:"column:peer_name"` // the display name of the peer at request time
+
+	InterfaceIdentifier InterfaceIdentifier `gorm:"index;column:interface_identifier"`
+	UserIdentifier      UserIdentifier      `gorm:"index;column:user_identifier"` // the owner of the peer
+
+	RoutesStr         string `gorm:"column:routes"`          // the requested routes of the peer, comma separated
+	PreviousRoutesStr string `gorm:"column:previous_routes"` // the routes of the peer at request time, comma separated
+	UnreviewedStr     string `gorm:"column:unreviewed"`      // the requested routes outside the allowlist
+
+	State       RouteReviewState `gorm:"column:state"`
+	RequestedBy UserIdentifier   `gorm:"column:requested_by"`
+	RequestedAt time.Time        `gorm:"column:requested_at"`
+	ExpiresAt   time.Time        `gorm:"column:expires_at"` // the time an open review expires
+
+	ResolvedBy UserIdentifier `gorm:"column:resolved_by"` // the user that approved, rejected or cancelled
+	ResolvedAt *time.Time     `gorm:"column:resolved_at"`
+}
+
+// IsOpen returns true if the review is still waiting for approval.
+func (r *RouteReview) IsOpen() bool {
+	return r.State == RouteReviewPending
+}
+
+// IsExpired returns true if the open review was not approved in time.
+func (r *RouteReview) IsExpired(now time.Time) bool {
+	return r.IsOpen() && !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// Resolve sets the final state of the review.
+func (r *RouteReview) Resolve(state RouteReviewState, userId UserIdentifier, now time.Time) {
+	r.State = state
+	r.ResolvedBy = userId
+	r.ResolvedAt = &now
+}
+
+// UnreviewedRoutes returns the requested routes that need the review of an admin. Routes that are already routed to
+// the peer, or that lie completely within one of the allowlisted networks, do not need a review.
+func UnreviewedRoutes(requested, current, allowlist []Cidr) []Cidr {
+	var unreviewed []Cidr
+	for _, route := range requested {
+		prefix := route.Prefix().Masked()
+		if slices.ContainsFunc(current, func(c Cidr) bool { return c.Prefix().Masked() == prefix }) {
+			continue
+		}
+		if slices.ContainsFunc(allowlist, func(a Cidr) bool { return prefixCovers(a.Prefix(), prefix) }) {
+			continue
+		}
+		unreviewed = append(unreviewed, route)
+	}
+
+	return unreviewed
+}
+
+// prefixCovers returns true if the network is completely contained in the outer network.
+func prefixCovers(outer, network netip.Prefix) bool {
+	return outer.Bits() <= network.Bits() && outer.Masked().Contains(network.Addr())
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteReview_Lifecycle(t *testing.T) {
+	now := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	review := RouteReview{State: RouteReviewPending, ExpiresAt: now.Add(time.Hour)}
+	assert.True(t, review.IsOpen())
+	assert.False(t, review.IsExpired(now))
+	assert.True(t, review.IsExpired(now.Add(time.Hour)))
+
+	review.Resolve(RouteReviewApproved, "admin", now)
+	assert.False(t, review.IsOpen())
+	assert.False(t, review.IsExpired(now.Add(time.Hour)), "resolved reviews no longer expire")
+	assert.Equal(t, UserIdentifier("admin"), review.ResolvedBy)
+}
+
+func TestUnreviewedRoutes(t *testing.T) {
+	cidrs := func(strs ...string) []Cidr {
+		result, err := CidrsFromArray(strs)
+		require.NoError(t, err)
+		return result
+	}
+
+	requested := cidrs("192.168.10.0/24", "192.168.0.0/16", "10.20.0.0/24", "10.30.0.1/32", "fd00:10::/64")
+	current := cidrs("10.20.0.0/24")
+	allowlist := cidrs("192.168.10.0/23", "fd00::/16")
+
+	unreviewed := UnreviewedRoutes(requested, current, allowlist)
+	assert.Equal(t, []string{"192.168.0.0/16", "10.30.0.1/32"}, CidrsToStringSlice(unreviewed))
+
+	assert.Empty(t, UnreviewedRoutes(nil, current, allowlist))
+	assert.Len(t, UnreviewedRoutes(requested, nil, nil), len(requested), "without allowlist all new routes are reviewed")
+}
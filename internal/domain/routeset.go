@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/h44z/wg-portal/internal"
+)
+
+type RouteSetIdentifier string
+
+var routeSetIdentifierPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// RouteSet is a named list of networks that can be attached to peers or interface profiles.
+// The networks of all attached route sets are added to the AllowedIPs of the generated peer configuration.
+type RouteSet struct {
+	BaseModel
+
+	Identifier  RouteSetIdentifier `gorm:"primaryKey;column:identifier"` // route set unique identifier, for example: corp-subnets
+	DisplayName string             // a nice display name for the route set
+	Description string             // an optional description
+	NetworksStr string             `gorm:"column:networks_str"` // the networks of the route set, comma separated
+}
+
+// Validate performs checks to ensure that the route set is valid.
+func (r *RouteSet) Validate() error {
+	if !routeSetIdentifierPattern.MatchString(string(r.Identifier)) {
+		return errors.New("invalid identifier, only lower case letters, digits, '-' and '_' are allowed")
+	}
+
+	if strings.TrimSpace(r.NetworksStr) == "" {
+		return errors.New("at least one network is required")
+	}
+
+	for _, network := range internal.SliceString(r.NetworksStr) {
+		if _, err := CidrFromString(network); err != nil {
+			return fmt.Errorf("invalid network %s: %w", network, err)
+		}
+	}
+
+	return nil
+}
+
+// Networks returns the networks of the route set.
+func (r *RouteSet) Networks() []Cidr {
+	var networks []Cidr
+	for _, network := range internal.SliceString(r.NetworksStr) {
+		if cidr, err := CidrFromString(network); err == nil {
+			networks = append(networks, cidr)
+		}
+	}
+	return networks
+}
+
+// RouteSetIds returns the identifiers of all route sets that are attached to the peer.
+func (p *Peer) RouteSetIds() []RouteSetIdentifier {
+	var ids []RouteSetIdentifier
+	for _, id := range internal.SliceString(p.RouteSetsStr.GetValue()) {
+		ids = append(ids, RouteSetIdentifier(id))
+	}
+	return ids
+}
+
+// HasRouteSet returns true if the route set with the given identifier is attached to the peer.
+func (p *Peer) HasRouteSet(id RouteSetIdentifier) bool {
+	for _, setId := range p.RouteSetIds() {
+		if setId == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AddRouteSet attaches the route set with the given identifier to the peer. It returns false if the route set was
+// already attached.
+func (p *Peer) AddRouteSet(id RouteSetIdentifier) bool {
+	if p.HasRouteSet(id) {
+		return false
+	}
+
+	ids := internal.SliceString(p.RouteSetsStr.GetValue())
+	p.RouteSetsStr.SetValue(internal.SliceToString(append(ids, string(id))))
+	return true
+}
+
+// RemoveRouteSet detaches the route set with the given identifier from the peer. It returns false if the route set
+// was not attached.
+func (p *Peer) RemoveRouteSet(id RouteSetIdentifier) bool {
+	if !p.HasRouteSet(id) {
+		return false
+	}
+
+	var ids []string
+	for _, setId := range p.RouteSetIds() {
+		if setId != id {
+			ids = append(ids, string(setId))
+		}
+	}
+	p.RouteSetsStr.SetValue(internal.SliceToString(ids))
+	return true
+}
+
+// ExpandAllowedIPs returns the AllowedIPs of the peer, extended by the networks of the given route sets.
+// Duplicate networks are only included once, the order of the peer's own AllowedIPs is preserved.
+func (p *Peer) ExpandAllowedIPs(routeSets []RouteSet) string {
+	var result []string
+	seen := make(map[string]struct{})
+	add := func(network string) {
+		if _, ok := seen[network]; ok {
+			return
+		}
+		seen[network] = struct{}{}
+		result = append(result, network)
+	}
+
+	for _, network := range internal.SliceString(p.AllowedIPsStr.GetValue()) {
+		add(network)
+	}
+	for _, routeSet := range routeSets {
+		if !p.HasRouteSet(routeSet.Identifier) {
+			continue
+		}
+		for _, network := range routeSet.Networks() {
+			add(network.String())
+		}
+	}
+
+	return strings.Join(result, ",")
+}
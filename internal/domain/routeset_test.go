@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteSet_Validate(t *testing.T) {
+	valid := RouteSet{Identifier: "corp-subnets", NetworksStr: "10.10.0.0/16, fd00:10::/64"}
+	assert.NoError(t, valid.Validate())
+
+	invalidId := RouteSet{Identifier: "Corp Subnets", NetworksStr: "10.10.0.0/16"}
+	assert.Error(t, invalidId.Validate())
+
+	noNetworks := RouteSet{Identifier: "empty", NetworksStr: " "}
+	assert.Error(t, noNetworks.Validate())
+
+	invalidNetwork := RouteSet{Identifier: "printers", NetworksStr: "10.20.0.0/16,printer"}
+	assert.Error(t, invalidNetwork.Validate())
+}
+
+func TestPeer_ExpandAllowedIPs(t *testing.T) {
+	routeSets := []RouteSet{
+		{Identifier: "corp-subnets", NetworksStr: "10.10.0.0/16,10.20.0.0/16"},
+		{Identifier: "office-printers", NetworksStr: "192.168.5.0/24,10.10.0.0/16"},
+		{Identifier: "unused", NetworksStr: "172.16.0.0/12"},
+	}
+
+	peer := Peer{
+		AllowedIPsStr: NewConfigOption("10.11.12.0/24,10.20.0.0/16", true),
+		RouteSetsStr:  NewConfigOption("corp-subnets, office-printers,deleted", true),
+	}
+
+	assert.True(t, peer.HasRouteSet("office-printers"))
+	assert.False(t, peer.HasRouteSet("unused"))
+	assert.Equal(t, "10.11.12.0/24,10.20.0.0/16,10.10.0.0/16,192.168.5.0/24", peer.ExpandAllowedIPs(routeSets))
+
+	peer.RouteSetsStr = NewConfigOption("", true)
+	assert.Equal(t, "10.11.12.0/24,10.20.0.0/16", peer.ExpandAllowedIPs(routeSets))
+}
+
+func TestPeer_AddRemoveRouteSet(t *testing.T) {
+	peer := Peer{RouteSetsStr: NewConfigOption("corp-subnets", true)}
+
+	assert.False(t, peer.AddRouteSet("corp-subnets"))
+	assert.True(t, peer.AddRouteSet("office-printers"))
+	assert.Equal(t, "corp-subnets,office-printers", peer.RouteSetsStr.GetValue())
+
+	assert.False(t, peer.RemoveRouteSet("unused"))
+	assert.True(t, peer.RemoveRouteSet("corp-subnets"))
+	assert.Equal(t, "office-printers", peer.RouteSetsStr.GetValue())
+	assert.True(t, peer.RemoveRouteSet("office-printers"))
+	assert.Empty(t, peer.RouteSetIds())
+}
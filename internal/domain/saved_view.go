@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal"
+)
+
+// savedViewKeyPattern matches the column, sort and filter keys of a saved view.
+var savedViewKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,63}$`)
+
+// SavedViewList is the list in the portal a saved view belongs to.
+type SavedViewList string
+
+const (
+	SavedViewListPeers SavedViewList = "peers"
+	SavedViewListUsers SavedViewList = "users"
+)
+
+// IsValid returns true if the list is known.
+func (l SavedViewList) IsValid() bool {
+	switch l {
+	case SavedViewListPeers, SavedViewListUsers:
+		return true
+	default:
+		return false
+	}
+}
+
+// SavedView stores the filters, the visible columns and the sort order of a list in the portal, so users can switch
+// between their favourite views. Administrators can share their views with all other administrators.
+type SavedView struct {
+	Id uint64 `gorm:"primaryKey;autoIncrement;column:id"`
+
+	UserIdentifier UserIdentifier `gorm:"index;column:user_identifier"` // the owner of the view
+	List           SavedViewList  `gorm:"column:list_name"`
+	Name           string         `gorm:"column:name"`
+
+	Filter        string            `gorm:"column:filter"`                         // the search text
+	FilterOptions map[string]string `gorm:"serializer:json;column:filter_options"` // list specific filters, for example, the device type of peers
+	ColumnsStr    string            `gorm:"column:columns_str"`                    // the visible columns, comma separated, empty for all columns
+
+	SortKey        string `gorm:"column:sort_key"` // empty for the default order of the list
+	SortDescending bool   `gorm:"column:sort_descending"`
+
+	Shared bool `gorm:"column:shared"` // shared views are visible to all administrators
+
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+// Validate performs checks to ensure that the saved view is valid.
+func (v *SavedView) Validate() error {
+	if !v.List.IsValid() {
+		return fmt.Errorf("invalid list %q", v.List)
+	}
+	name := strings.TrimSpace(v.Name)
+	if name == "" {
+		return errors.New("view name must not be empty")
+	}
+	if len(name) > 64 {
+		return errors.New("view name must not be longer than 64 characters")
+	}
+
+	if v.SortKey != "" && !savedViewKeyPattern.MatchString(v.SortKey) {
+		return fmt.Errorf("invalid sort key %q", v.SortKey)
+	}
+	for _, column := range v.Columns() {
+		if !savedViewKeyPattern.MatchString(column) {
+			return fmt.Errorf("invalid column %q", column)
+		}
+	}
+	for key := range v.FilterOptions {
+		if !savedViewKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid filter option %q", key)
+		}
+	}
+
+	return nil
+}
+
+// Columns returns the visible columns of the view. An empty list shows all columns.
+func (v *SavedView) Columns() []string {
+	return internal.SliceString(v.ColumnsStr)
+}
+
+// IsVisibleTo returns true if the given user can use the view. Users see their own views, administrators also see
+// the shared views of other administrators.
+func (v *SavedView) IsVisibleTo(userId UserIdentifier, isAdmin bool) bool {
+	if v.UserIdentifier == userId {
+		return true
+	}
+	return v.Shared && isAdmin
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavedView_Validate(t *testing.T) {
+	assert.NoError(t, (&SavedView{List: SavedViewListPeers, Name: "Offline laptops", Filter: "lab",
+		FilterOptions: map[string]string{"deviceType": "laptop"}, ColumnsStr: "user,status",
+		SortKey: "DisplayName"}).Validate())
+	assert.NoError(t, (&SavedView{List: SavedViewListUsers, Name: "All"}).Validate())
+	assert.Error(t, (&SavedView{List: "interfaces", Name: "All"}).Validate())
+	assert.Error(t, (&SavedView{List: SavedViewListUsers, Name: " "}).Validate())
+	assert.Error(t, (&SavedView{List: SavedViewListUsers, Name: strings.Repeat("a", 65)}).Validate())
+	assert.Error(t, (&SavedView{List: SavedViewListUsers, Name: "All", SortKey: "name desc"}).Validate())
+	assert.Error(t, (&SavedView{List: SavedViewListUsers, Name: "All", ColumnsStr: "email,<b>"}).Validate())
+	assert.Error(t, (&SavedView{List: SavedViewListUsers, Name: "All",
+		FilterOptions: map[string]string{"": "x"}}).Validate())
+}
+
+func TestSavedView_IsVisibleTo(t *testing.T) {
+	view := SavedView{UserIdentifier: "alice"}
+	assert.True(t, view.IsVisibleTo("alice", false))
+	assert.False(t, view.IsVisibleTo("bob", true), "private views are only visible to the owner")
+
+	view.Shared = true
+	assert.True(t, view.IsVisibleTo("bob", true))
+	assert.False(t, view.IsVisibleTo("bob", false), "shared views are only visible to administrators")
+}
@@ -0,0 +1,186 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// AccessWindow is a weekly recurring time window in which a peer is enabled.
+// If End is before Start, the window spans midnight and ends on the following day.
+type AccessWindow struct {
+	Days  [7]bool       // indexed by time.Weekday
+	Start time.Duration // offset from midnight
+	End   time.Duration // offset from midnight, exclusive
+}
+
+// AccessSchedule contains all access windows of a peer. An empty schedule allows access at any time.
+type AccessSchedule []AccessWindow
+
+// ParseAccessSchedule parses a schedule like "Mon-Fri 08:00-18:00; Sat,Sun 10:00-12:00".
+// Windows are separated by semicolons or new lines, day names use their three-letter english abbreviation.
+func ParseAccessSchedule(str string) (AccessSchedule, error) {
+	var schedule AccessSchedule
+	for _, part := range strings.FieldsFunc(str, func(r rune) bool { return r == ';' || r == '\n' }) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		window, err := parseAccessWindow(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid access window %q: %w", part, err)
+		}
+		schedule = append(schedule, window)
+	}
+
+	return schedule, nil
+}
+
+func parseAccessWindow(str string) (AccessWindow, error) {
+	var window AccessWindow
+
+	fields := strings.Fields(str)
+	if len(fields) != 2 {
+		return window, fmt.Errorf("expected days and time range")
+	}
+
+	for _, dayRange := range strings.Split(fields[0], ",") {
+		from, to, isRange := strings.Cut(dayRange, "-")
+		first, ok := weekdayNames[strings.ToLower(from)]
+		if !ok {
+			return window, fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdayNames[strings.ToLower(to)]; !ok {
+				return window, fmt.Errorf("unknown day %q", to)
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 { // ranges like Sat-Mon wrap around the week
+			window.Days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+
+	start, end, ok := strings.Cut(fields[1], "-")
+	if !ok {
+		return window, fmt.Errorf("expected time range like 08:00-18:00")
+	}
+	var err error
+	if window.Start, err = parseTimeOfDay(start); err != nil {
+		return window, err
+	}
+	if window.End, err = parseTimeOfDay(end); err != nil {
+		return window, err
+	}
+	if window.Start == window.End {
+		return window, fmt.Errorf("empty time range")
+	}
+
+	return window, nil
+}
+
+// parseTimeOfDay parses a time in HH:MM format, 24:00 is allowed as end of day.
+func parseTimeOfDay(str string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(str, ":")
+	h, errH := strconv.Atoi(hours)
+	m, errM := strconv.Atoi(minutes)
+	if !ok || errH != nil || errM != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q", str)
+	}
+
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// Allows returns true if the given time lies within one of the access windows.
+// The time must already be converted to the time zone of the schedule.
+func (s AccessSchedule) Allows(t time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	day := t.Weekday()
+	previousDay := (day + 6) % 7
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+
+	for _, window := range s {
+		if window.Start < window.End {
+			if window.Days[day] && offset >= window.Start && offset < window.End {
+				return true
+			}
+			continue
+		}
+
+		// the window spans midnight
+		if (window.Days[day] && offset >= window.Start) || (window.Days[previousDay] && offset < window.End) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoadScheduleLocation returns the time zone with the given IANA name. An empty name returns the local time zone
+// of the server.
+func LoadScheduleLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", name, ErrInvalidData)
+	}
+
+	return loc, nil
+}
+
+// HasAccessSchedule returns true if the peer is only enabled within certain time windows.
+func (p *Peer) HasAccessSchedule() bool {
+	return strings.TrimSpace(p.AccessScheduleStr) != ""
+}
+
+// ValidateAccessSchedule checks the access schedule and the time zone of the peer.
+func (p *Peer) ValidateAccessSchedule() error {
+	if _, err := ParseAccessSchedule(p.AccessScheduleStr); err != nil {
+		return fmt.Errorf("%w: %w", err, ErrInvalidData)
+	}
+	if _, err := LoadScheduleLocation(p.AccessTimezone); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IsWithinAccessSchedule returns true if the access schedule of the peer allows access at the given time.
+// The default location is used if no time zone is set for the peer.
+func (p *Peer) IsWithinAccessSchedule(now time.Time, defaultLocation *time.Location) (bool, error) {
+	schedule, err := ParseAccessSchedule(p.AccessScheduleStr)
+	if err != nil {
+		return false, err
+	}
+
+	loc := defaultLocation
+	if p.AccessTimezone != "" {
+		if loc, err = LoadScheduleLocation(p.AccessTimezone); err != nil {
+			return false, err
+		}
+	}
+
+	return schedule.Allows(now.In(loc)), nil
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccessSchedule(t *testing.T) {
+	schedule, err := ParseAccessSchedule("Mon-Fri 08:00-18:00; sat,Sun 22:00-02:00\n")
+	require.NoError(t, err)
+	require.Len(t, schedule, 2)
+	assert.Equal(t, [7]bool{false, true, true, true, true, true, false}, schedule[0].Days)
+	assert.Equal(t, 8*time.Hour, schedule[0].Start)
+	assert.Equal(t, 18*time.Hour, schedule[0].End)
+	assert.Equal(t, [7]bool{true, false, false, false, false, false, true}, schedule[1].Days)
+
+	schedule, err = ParseAccessSchedule("Sat-Mon 00:00-24:00")
+	require.NoError(t, err)
+	assert.Equal(t, [7]bool{true, true, false, false, false, false, true}, schedule[0].Days)
+
+	empty, err := ParseAccessSchedule(" ; ")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	invalid := []string{"Mon", "Mon 08:00", "Xyz 08:00-10:00", "Mon 08:00-08:00", "Mon 8-18", "Mon 24:30-25:00"}
+	for _, schedule := range invalid {
+		_, err := ParseAccessSchedule(schedule)
+		assert.Error(t, err, schedule)
+	}
+}
+
+func TestAccessSchedule_Allows(t *testing.T) {
+	schedule, err := ParseAccessSchedule("Mon-Fri 08:00-18:00; Fri 22:00-02:00")
+	require.NoError(t, err)
+
+	friday := time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)
+	assert.False(t, schedule.Allows(friday.Add(7*time.Hour+59*time.Minute)))
+	assert.True(t, schedule.Allows(friday.Add(8*time.Hour)))
+	assert.False(t, schedule.Allows(friday.Add(18*time.Hour)), "the end of a window is exclusive")
+	assert.True(t, schedule.Allows(friday.Add(23*time.Hour)))
+	assert.True(t, schedule.Allows(friday.Add(25*time.Hour)), "the window continues on saturday")
+	assert.False(t, schedule.Allows(friday.Add(26*time.Hour)))
+	assert.False(t, schedule.Allows(friday.Add(2*time.Hour)), "thursday has no night window")
+
+	assert.True(t, AccessSchedule(nil).Allows(friday))
+}
+
+func TestPeer_IsWithinAccessSchedule(t *testing.T) {
+	peer := Peer{AccessScheduleStr: "Mon-Fri 08:00-18:00", AccessTimezone: "America/New_York"}
+	require.NoError(t, peer.ValidateAccessSchedule())
+
+	now := time.Date(2024, 5, 3, 13, 0, 0, 0, time.UTC) // 09:00 in New York
+	allowed, err := peer.IsWithinAccessSchedule(now, time.UTC)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	peer.AccessTimezone = ""
+	allowed, err = peer.IsWithinAccessSchedule(now.Add(-6*time.Hour), time.UTC)
+	require.NoError(t, err)
+	assert.False(t, allowed, "the default location is used without time zone")
+
+	peer.AccessTimezone = "Mars/Olympus"
+	assert.ErrorIs(t, peer.ValidateAccessSchedule(), ErrInvalidData)
+	peer.AccessTimezone = ""
+	peer.AccessScheduleStr = "weekdays"
+	assert.ErrorIs(t, peer.ValidateAccessSchedule(), ErrInvalidData)
+}
@@ -0,0 +1,22 @@
+package domain
+
+// SearchResultType is the kind of entity a search result refers to.
+type SearchResultType string
+
+const (
+	SearchResultTypeUser       SearchResultType = "user"
+	SearchResultTypePeer       SearchResultType = "peer"
+	SearchResultTypeInterface  SearchResultType = "interface"
+	SearchResultTypeAuditEntry SearchResultType = "audit"
+)
+
+// SearchResult is a single hit of the global search.
+type SearchResult struct {
+	Type       SearchResultType
+	Identifier string // the identifier of the entity, the unique id for audit entries
+	Title      string // a human-readable name of the entity
+	Detail     string // the value that matched the search query, for example, the ip address of a peer
+
+	InterfaceIdentifier InterfaceIdentifier // the interface of the peer, empty for other result types
+	UserIdentifier      UserIdentifier      // the owner of the peer, empty for other result types
+}
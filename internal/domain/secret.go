@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SecretProvider is the source of a secret config value.
+type SecretProvider string
+
+const (
+	SecretProviderEnv   SecretProvider = "env"   // env://NAME
+	SecretProviderFile  SecretProvider = "file"  // file:///run/secrets/name
+	SecretProviderVault SecretProvider = "vault" // vault://secret/data/wg-portal#key
+	SecretProviderAws   SecretProvider = "awssm" // awssm://secret-id#key
+	SecretProviderGcp   SecretProvider = "gcpsm" // gcpsm://projects/p/secrets/s/versions/latest#key
+)
+
+// SecretReference points to a secret value of a secret provider. Config values that start with the scheme of a
+// secret provider are replaced by the referenced secret at startup.
+type SecretReference struct {
+	Provider SecretProvider
+	Path     string // the environment variable, file path, or the name of the secret
+	Key      string // optional, the key of the value if the secret is a JSON object
+}
+
+// ParseSecretReference parses the given config value. If the value does not start with the scheme of a secret
+// provider, ok is false and the value is used as is.
+func ParseSecretReference(value string) (ref SecretReference, ok bool, err error) {
+	scheme, rest, found := strings.Cut(value, "://")
+	if !found {
+		return SecretReference{}, false, nil
+	}
+
+	provider := SecretProvider(scheme)
+	switch provider {
+	case SecretProviderEnv, SecretProviderFile, SecretProviderVault, SecretProviderAws, SecretProviderGcp:
+	default:
+		return SecretReference{}, false, nil
+	}
+
+	path, key, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return SecretReference{}, true, fmt.Errorf("missing path in secret reference %s: %w", value, ErrInvalidData)
+	}
+	if key != "" && (provider == SecretProviderEnv || provider == SecretProviderFile) {
+		return SecretReference{}, true, fmt.Errorf("secret reference %s does not support keys: %w", value,
+			ErrInvalidData)
+	}
+
+	return SecretReference{Provider: provider, Path: path, Key: key}, true, nil
+}
+
+// String returns the reference in the config value format.
+func (r SecretReference) String() string {
+	if r.Key == "" {
+		return string(r.Provider) + "://" + r.Path
+	}
+
+	return string(r.Provider) + "://" + r.Path + "#" + r.Key
+}
+
+// SecretRotation is published if a referenced secret changed after startup.
+type SecretRotation struct {
+	Field string // the name of the config value, for example mail.password
+	Value PrivateString
+}
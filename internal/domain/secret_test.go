@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSecretReference(t *testing.T) {
+	ref, ok, err := ParseSecretReference("vault://secret/data/wg-portal#smtp")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, SecretReference{Provider: SecretProviderVault, Path: "secret/data/wg-portal", Key: "smtp"}, ref)
+	assert.Equal(t, "vault://secret/data/wg-portal#smtp", ref.String())
+
+	ref, ok, err = ParseSecretReference("file:///run/secrets/dsn")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, SecretReference{Provider: SecretProviderFile, Path: "/run/secrets/dsn"}, ref)
+
+	for _, value := range []string{"plain-secret", "https://example.com", "data/sqlite.db", ""} {
+		_, ok, err = ParseSecretReference(value)
+		require.NoError(t, err)
+		assert.False(t, ok, value)
+	}
+
+	for _, value := range []string{"env://", "awssm://#key", "env://NAME#key"} {
+		_, ok, err = ParseSecretReference(value)
+		assert.True(t, ok, value)
+		assert.ErrorIs(t, err, ErrInvalidData, value)
+	}
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+type SecurityEventSeverity string
+
+const (
+	SecurityEventSeverityLow    SecurityEventSeverity = "low"
+	SecurityEventSeverityMedium SecurityEventSeverity = "medium"
+	SecurityEventSeverityHigh   SecurityEventSeverity = "high"
+)
+
+// ParseSecurityEventSeverity returns the severity with the given name. An empty name returns the medium severity.
+func ParseSecurityEventSeverity(str string) (SecurityEventSeverity, error) {
+	switch SecurityEventSeverity(str) {
+	case "":
+		return SecurityEventSeverityMedium, nil
+	case SecurityEventSeverityLow, SecurityEventSeverityMedium, SecurityEventSeverityHigh:
+		return SecurityEventSeverity(str), nil
+	default:
+		return "", fmt.Errorf("unknown severity %q: %w", str, ErrInvalidData)
+	}
+}
+
+// SecurityEvent is an anomaly that was detected by a security event rule, for example a handshake from a blocked
+// country.
+type SecurityEvent struct {
+	Id        uint64    `gorm:"primaryKey;autoIncrement:true;column:id"`
+	CreatedAt time.Time `gorm:"column:created_at;index:idx_se_created"`
+
+	RuleName  string                `gorm:"column:rule_name"`
+	Condition string                `gorm:"column:condition"` // the condition of the rule, for example handshake_from_country
+	Severity  SecurityEventSeverity `gorm:"column:severity"`
+	Message   string                `gorm:"column:message"` // a short description of the anomaly
+
+	PeerId         PeerIdentifier      `gorm:"column:peer_identifier"`      // empty if the event is not about a peer
+	InterfaceId    InterfaceIdentifier `gorm:"column:interface_identifier"` // empty if the event is not about a peer
+	UserIdentifier UserIdentifier      `gorm:"column:user_identifier"`      // the owner of the peer or the created user
+	Actor          UserIdentifier      `gorm:"column:actor"`                // the user that caused the event, if known
+
+	Endpoint string `gorm:"column:endpoint"` // the endpoint of the peer, only set for handshake events
+	Country  string `gorm:"column:country"`  // the country of the endpoint, only set for handshake events
+}
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const (
+	PeerConfigFormatFile   = "file"
+	PeerConfigFormatQrCode = "qr-code"
+	PeerConfigFormatUci    = "uci"
+)
+
+// PeerPrivateKeyFormat describes a reveal of the bare private key of a peer, without the peer configuration.
+const PeerPrivateKeyFormat = "private-key"
+
+// ClientInfo describes the client that sent a request to the web interface.
+type ClientInfo struct {
+	IpAddress string
+	UserAgent string
+}
+
+// Browser returns the browser family parsed from the user agent, for example "Firefox".
+func (c ClientInfo) Browser() string {
+	ua := c.UserAgent
+	switch {
+	case strings.Contains(ua, "Edg/") || strings.Contains(ua, "EdgA/") || strings.Contains(ua, "EdgiOS/"):
+		return "Edge"
+	case strings.Contains(ua, "OPR/") || strings.Contains(ua, "Opera"):
+		return "Opera"
+	case strings.Contains(ua, "Firefox/") || strings.Contains(ua, "FxiOS/"):
+		return "Firefox"
+	case strings.Contains(ua, "Chrome/") || strings.Contains(ua, "CriOS/"):
+		return "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		return "Safari"
+	default:
+		return "Unknown browser"
+	}
+}
+
+// Platform returns the operating system parsed from the user agent, or an empty string if it is unknown.
+func (c ClientInfo) Platform() string {
+	ua := c.UserAgent
+	switch {
+	case strings.Contains(ua, "Android"): // Android user agents contain "Linux" as well
+		return "Android"
+	case strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad"):
+		return "iOS"
+	case strings.Contains(ua, "Windows"):
+		return "Windows"
+	case strings.Contains(ua, "Macintosh") || strings.Contains(ua, "Mac OS X"):
+		return "macOS"
+	case strings.Contains(ua, "CrOS"):
+		return "ChromeOS"
+	case strings.Contains(ua, "Linux"):
+		return "Linux"
+	default:
+		return ""
+	}
+}
+
+// UserLoginDevice is a combination of IP address and browser that a user has logged in from before.
+// A login from an unknown combination triggers a security notification.
+type UserLoginDevice struct {
+	Identifier     string         `gorm:"primaryKey;column:identifier"` // hash of the user, IP address and browser
+	UserIdentifier UserIdentifier `gorm:"index;column:user_identifier"`
+	IpAddress      string         `gorm:"column:ip_address"`
+	Browser        string         `gorm:"column:browser"`
+	Platform       string         `gorm:"column:platform"`
+	FirstSeen      time.Time      `gorm:"column:first_seen"`
+	LastSeen       time.Time      `gorm:"column:last_seen"`
+}
+
+// NewUserLoginDevice creates a new login device for the given user and client.
+func NewUserLoginDevice(userId UserIdentifier, client ClientInfo, now time.Time) UserLoginDevice {
+	return UserLoginDevice{
+		Identifier:     LoginDeviceIdentifier(userId, client),
+		UserIdentifier: userId,
+		IpAddress:      client.IpAddress,
+		Browser:        client.Browser(),
+		Platform:       client.Platform(),
+		FirstSeen:      now,
+		LastSeen:       now,
+	}
+}
+
+// LoginDeviceIdentifier returns the identifier of the login device of the given user and client. Browser updates
+// do not change the identifier, as only the browser family and platform are considered.
+func LoginDeviceIdentifier(userId UserIdentifier, client ClientInfo) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		string(userId), client.IpAddress, client.Browser(), client.Platform(),
+	}, "\x00")))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// PeerConfigDownload describes a download of a peer configuration through the web interface.
+type PeerConfigDownload struct {
+	Peer         Peer
+	DownloadedBy UserIdentifier
+	Format       string // PeerConfigFormatFile, PeerConfigFormatQrCode or PeerConfigFormatUci
+	Client       ClientInfo
+	DownloadedAt time.Time
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientInfo_Browser(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		browser   string
+		platform  string
+	}{
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:126.0) Gecko/20100101 Firefox/126.0", "Firefox", "Linux"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) " +
+			"Chrome/125.0.0.0 Safari/537.36 Edg/125.0.0.0", "Edge", "Windows"},
+		{"Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Mobile " +
+			"Safari/537.36", "Chrome", "Android"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) " +
+			"Version/17.5 Mobile/15E148 Safari/604.1", "Safari", "iOS"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/537.36 (KHTML, like Gecko) " +
+			"Chrome/125.0.0.0 Safari/537.36 OPR/110.0.0.0", "Opera", "macOS"},
+		{"curl/8.0", "Unknown browser", ""},
+	}
+	for _, tt := range tests {
+		client := ClientInfo{UserAgent: tt.userAgent}
+		assert.Equal(t, tt.browser, client.Browser(), tt.userAgent)
+		assert.Equal(t, tt.platform, client.Platform(), tt.userAgent)
+	}
+}
+
+func TestLoginDeviceIdentifier(t *testing.T) {
+	firefox125 := ClientInfo{IpAddress: "198.51.100.7", UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/125.0"}
+	firefox126 := ClientInfo{IpAddress: "198.51.100.7", UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/126.0"}
+	otherIp := ClientInfo{IpAddress: "203.0.113.1", UserAgent: firefox126.UserAgent}
+
+	assert.Equal(t, LoginDeviceIdentifier("alice", firefox125), LoginDeviceIdentifier("alice", firefox126),
+		"browser updates do not create a new device")
+	assert.NotEqual(t, LoginDeviceIdentifier("alice", firefox126), LoginDeviceIdentifier("alice", otherIp))
+	assert.NotEqual(t, LoginDeviceIdentifier("alice", firefox126), LoginDeviceIdentifier("bob", firefox126))
+
+	now := time.Now()
+	device := NewUserLoginDevice("alice", firefox126, now)
+	assert.Equal(t, "Firefox", device.Browser)
+	assert.Equal(t, "Linux", device.Platform)
+	assert.Equal(t, now, device.LastSeen)
+}
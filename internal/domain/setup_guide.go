@@ -0,0 +1,39 @@
+package domain
+
+// PeerOperatingSystem is the operating system of the device that uses a peer. It selects the setup guide that is
+// attached to configuration mails.
+type PeerOperatingSystem string
+
+const (
+	PeerOperatingSystemUnknown PeerOperatingSystem = "" // no setup guide is attached
+	PeerOperatingSystemWindows PeerOperatingSystem = "windows"
+	PeerOperatingSystemMacOS   PeerOperatingSystem = "macos"
+	PeerOperatingSystemIOS     PeerOperatingSystem = "ios"
+	PeerOperatingSystemAndroid PeerOperatingSystem = "android"
+	PeerOperatingSystemLinux   PeerOperatingSystem = "linux"
+)
+
+// IsValid returns true if the operating system is known.
+func (o PeerOperatingSystem) IsValid() bool {
+	switch o {
+	case PeerOperatingSystemUnknown, PeerOperatingSystemWindows, PeerOperatingSystemMacOS, PeerOperatingSystemIOS,
+		PeerOperatingSystemAndroid, PeerOperatingSystemLinux:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetupGuideTemplate returns the base name of the setup guide template for the operating system, or an empty string
+// if the operating system is unknown.
+func (o PeerOperatingSystem) SetupGuideTemplate() string {
+	if o == PeerOperatingSystemUnknown || !o.IsValid() {
+		return ""
+	}
+	return "setup_guide_" + string(o)
+}
+
+// SetupGuideFileName returns the file name of the setup guide attachment for the operating system.
+func (o PeerOperatingSystem) SetupGuideFileName() string {
+	return "WireGuard-Setup-" + string(o) + ".html"
+}
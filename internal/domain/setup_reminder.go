@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// PeerSetupReminder stores the time at which the owner of a peer was reminded to set up the mailed configuration,
+// because the peer never connected. Each peer is reminded once.
+type PeerSetupReminder struct {
+	PeerId     PeerIdentifier `gorm:"primaryKey;column:identifier"`
+	MailedAt   time.Time      `gorm:"column:mailed_at"` // the time the configuration was mailed first
+	RemindedAt time.Time      `gorm:"column:reminded_at"`
+}
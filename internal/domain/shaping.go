@@ -0,0 +1,19 @@
+package domain
+
+// PeerShapingStatus contains the traffic counters of the bandwidth limits that are enforced for a peer.
+// The counters are reset whenever the traffic shaping rules of the interface are rebuilt.
+type PeerShapingStatus struct {
+	PeerId PeerIdentifier
+
+	UploadLimit   int // the enforced upload limit in kbit/s, 0 means unlimited
+	DownloadLimit int // the enforced download limit in kbit/s, 0 means unlimited
+
+	UploadBytes   uint64 // number of bytes received from the peer
+	UploadPackets uint32 // number of packets received from the peer
+	UploadDropped uint32 // number of packets dropped because the upload limit was exceeded
+
+	DownloadBytes      uint64 // number of bytes sent to the peer through the shaper
+	DownloadPackets    uint32 // number of packets sent to the peer through the shaper
+	DownloadDropped    uint32 // number of packets dropped by the shaper
+	DownloadOverlimits uint32 // number of packets that were delayed because the download limit was exceeded
+}
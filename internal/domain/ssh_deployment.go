@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"time"
+)
+
+// remoteInterfacePattern matches the interface names that are accepted by wg-quick.
+var remoteInterfacePattern = regexp.MustCompile(`^[a-zA-Z0-9_=+.-]{1,15}$`)
+
+// SshDeployment assigns a peer to a remote host that cannot run the config pull agent. The configuration of the
+// peer is pushed to the host via SSH and applied with wg syncconf whenever it changes.
+type SshDeployment struct {
+	PeerId          PeerIdentifier `gorm:"primaryKey;column:peer_identifier"`
+	Host            string         `gorm:"column:host"`             // the name of the configured SSH host
+	RemoteInterface string         `gorm:"column:remote_interface"` // the wg-quick interface name on the host
+
+	CreatedAt time.Time `gorm:"column:created_at"`
+	CreatedBy string    `gorm:"column:created_by"`
+
+	LastDeployedAt *time.Time `gorm:"column:last_deployed_at"`
+	LastConfigHash string     `gorm:"column:last_config_hash"` // the hash of the last successfully applied config
+	LastError      string     `gorm:"column:last_error"`       // the error of the last deployment attempt
+}
+
+// Validate performs checks to ensure that the deployment is valid.
+func (d *SshDeployment) Validate() error {
+	if d.Host == "" {
+		return errors.New("missing host")
+	}
+	if !remoteInterfacePattern.MatchString(d.RemoteInterface) {
+		return errors.New("invalid remote interface, at most 15 letters, digits and '_=+.-' are allowed")
+	}
+
+	return nil
+}
+
+// HashDeploymentConfig returns the hex encoded SHA-256 hash of the given configuration file.
+func HashDeploymentConfig(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
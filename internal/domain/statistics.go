@@ -6,8 +6,9 @@ type PeerStatus struct {
 	PeerId    PeerIdentifier `gorm:"primaryKey;column:identifier"`
 	UpdatedAt time.Time      `gorm:"column:updated_at"`
 
-	IsPingable bool       `gorm:"column:pingable"`
-	LastPing   *time.Time `gorm:"column:last_ping"`
+	IsPingable bool          `gorm:"column:pingable"`
+	LastPing   *time.Time    `gorm:"column:last_ping"`
+	LastRtt    time.Duration `gorm:"column:last_rtt"` // the round-trip time of the last successful ping
 
 	BytesReceived    uint64 `gorm:"column:received"`
 	BytesTransmitted uint64 `gorm:"column:transmitted"`
@@ -15,10 +16,19 @@ type PeerStatus struct {
 	LastHandshake    *time.Time `gorm:"column:last_handshake"`
 	Endpoint         string     `gorm:"column:endpoint"`
 	LastSessionStart *time.Time `gorm:"column:last_session_start"`
+
+	EndpointChanges int        `gorm:"column:endpoint_changes"` // endpoint changes since ChurnSince
+	NatRebinds      int        `gorm:"column:nat_rebinds"`      // endpoint changes that only changed the port
+	ChurnSince      *time.Time `gorm:"column:churn_since"`      // the start of the endpoint churn observation window
 }
 
 func (s PeerStatus) IsConnected() bool {
-	oldestHandshakeTime := time.Now().Add(-2 * time.Minute) // if a handshake is older than 2 minutes, the peer is no longer connected
+	return s.IsConnectedAt(time.Now())
+}
+
+// IsConnectedAt reports whether the peer was connected at the given time, based on the stored status.
+func (s PeerStatus) IsConnectedAt(t time.Time) bool {
+	oldestHandshakeTime := t.Add(-2 * time.Minute) // if a handshake is older than 2 minutes, the peer is no longer connected
 
 	handshakeValid := false
 	if s.LastHandshake != nil {
@@ -34,4 +44,99 @@ type InterfaceStatus struct {
 
 	BytesReceived    uint64 `gorm:"column:received"`
 	BytesTransmitted uint64 `gorm:"column:transmitted"`
+
+	UpSince *time.Time `gorm:"column:up_since"` // the first data collection since the interface is up, nil if down
+}
+
+// PeerStatsResolution is the time resolution of stored peer statistics samples.
+type PeerStatsResolution string
+
+const (
+	PeerStatsResolutionRaw    PeerStatsResolution = "raw"    // one sample per data collection interval
+	PeerStatsResolutionHourly PeerStatsResolution = "hourly" // rollup of the raw samples of one hour
+	PeerStatsResolutionDaily  PeerStatsResolution = "daily"  // rollup of the hourly samples of one day
+)
+
+// Period returns the length of the aggregation period. Raw samples are not aggregated, so the period is zero.
+func (r PeerStatsResolution) Period() time.Duration {
+	switch r {
+	case PeerStatsResolutionHourly:
+		return time.Hour
+	case PeerStatsResolutionDaily:
+		return 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// PeriodStart returns the start of the aggregation period that contains the given time.
+func (r PeerStatsResolution) PeriodStart(t time.Time) time.Time {
+	if r.Period() == 0 {
+		return t
+	}
+	return t.UTC().Truncate(r.Period()) // periods are aligned to UTC
+}
+
+// PeerStatsSample contains the traffic of a peer within a time period.
+type PeerStatsSample struct {
+	PeerId     PeerIdentifier      `gorm:"primaryKey;column:identifier"`
+	Resolution PeerStatsResolution `gorm:"primaryKey;column:resolution;index:idx_pss_resolution_timestamp"`
+	Timestamp  time.Time           `gorm:"primaryKey;column:timestamp;index:idx_pss_resolution_timestamp"` // the start of the period
+
+	BytesReceived    uint64     `gorm:"column:received"`       // bytes received within the period
+	BytesTransmitted uint64     `gorm:"column:transmitted"`    // bytes transmitted within the period
+	LastHandshake    *time.Time `gorm:"column:last_handshake"` // the latest handshake within the period
+}
+
+// RollupPeerStatsSamples aggregates the given samples into samples of the given resolution.
+// The traffic is summed up per peer and period, the latest handshake is kept.
+func RollupPeerStatsSamples(samples []PeerStatsSample, resolution PeerStatsResolution) []PeerStatsSample {
+	type rollupKey struct {
+		peerId PeerIdentifier
+		start  int64
+	}
+
+	rollups := make([]PeerStatsSample, 0)
+	index := make(map[rollupKey]int)
+	for _, sample := range samples {
+		start := resolution.PeriodStart(sample.Timestamp)
+		key := rollupKey{peerId: sample.PeerId, start: start.Unix()}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(rollups)
+			index[key] = i
+			rollups = append(rollups, PeerStatsSample{
+				PeerId:     sample.PeerId,
+				Resolution: resolution,
+				Timestamp:  start,
+			})
+		}
+
+		rollup := &rollups[i]
+		rollup.BytesReceived += sample.BytesReceived
+		rollup.BytesTransmitted += sample.BytesTransmitted
+		if sample.LastHandshake != nil &&
+			(rollup.LastHandshake == nil || sample.LastHandshake.After(*rollup.LastHandshake)) {
+			rollup.LastHandshake = sample.LastHandshake
+		}
+	}
+
+	return rollups
+}
+
+// PeerStatsPoint is a measurement of the statistics of a peer that is exported to a time-series database.
+type PeerStatsPoint struct {
+	Time time.Time
+
+	PeerId      PeerIdentifier
+	PeerName    string // the display name of the peer
+	InterfaceId InterfaceIdentifier
+	UserId      UserIdentifier
+
+	BytesReceived    uint64 // total bytes received by the server from the peer in the current session
+	BytesTransmitted uint64 // total bytes sent by the server to the peer in the current session
+	LastHandshake    *time.Time
+	Endpoint         string
+	IsConnected      bool
 }
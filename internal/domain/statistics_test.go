@@ -72,3 +72,40 @@ func TestPeerStatus_IsConnected(t *testing.T) {
 		})
 	}
 }
+
+func TestRollupPeerStatsSamples(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	early := base.Add(5 * time.Minute)
+	late := base.Add(50 * time.Minute)
+
+	samples := []PeerStatsSample{
+		{PeerId: "peer-a", Timestamp: base.Add(1 * time.Minute), BytesReceived: 10, BytesTransmitted: 1, LastHandshake: &late},
+		{PeerId: "peer-a", Timestamp: base.Add(2 * time.Minute), BytesReceived: 20, BytesTransmitted: 2, LastHandshake: &early},
+		{PeerId: "peer-b", Timestamp: base.Add(3 * time.Minute), BytesReceived: 5},
+		{PeerId: "peer-a", Timestamp: base.Add(61 * time.Minute), BytesReceived: 7},
+	}
+
+	rollups := RollupPeerStatsSamples(samples, PeerStatsResolutionHourly)
+	if len(rollups) != 3 {
+		t.Fatalf("expected 3 rollups, got %d", len(rollups))
+	}
+
+	first := rollups[0]
+	if first.PeerId != "peer-a" || !first.Timestamp.Equal(base) || first.Resolution != PeerStatsResolutionHourly {
+		t.Errorf("unexpected first rollup: %+v", first)
+	}
+	if first.BytesReceived != 30 || first.BytesTransmitted != 3 {
+		t.Errorf("expected summed traffic, got %d/%d", first.BytesReceived, first.BytesTransmitted)
+	}
+	if first.LastHandshake == nil || !first.LastHandshake.Equal(late) {
+		t.Errorf("expected latest handshake %v, got %v", late, first.LastHandshake)
+	}
+	if rollups[2].BytesReceived != 7 || !rollups[2].Timestamp.Equal(base.Add(time.Hour)) {
+		t.Errorf("unexpected rollup of the next hour: %+v", rollups[2])
+	}
+
+	daily := RollupPeerStatsSamples(rollups, PeerStatsResolutionDaily)
+	if len(daily) != 2 || daily[0].BytesReceived != 37 {
+		t.Errorf("unexpected daily rollups: %+v", daily)
+	}
+}
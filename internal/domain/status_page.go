@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// EndpointStatus is the public status of the VPN endpoint of an interface. It contains no peer data.
+type EndpointStatus struct {
+	InterfaceId InterfaceIdentifier
+	DisplayName string
+	Endpoint    string     // the public endpoint of the interface, for example: vpn.example.com:51820
+	DeviceUp    bool       // true if the WireGuard device is up and listening
+	Resolvable  bool       // true if the endpoint host resolves to at least one address
+	UpSince     *time.Time // nil if unknown, requires the collection of interface data
+}
+
+// IsReachable returns true if clients should be able to connect to the endpoint.
+func (s EndpointStatus) IsReachable() bool {
+	return s.DeviceUp && s.Resolvable
+}
+
+// MaintenanceNotice is a planned or ongoing maintenance that is shown on the status page.
+type MaintenanceNotice struct {
+	Title      string
+	Message    string
+	Start      *time.Time            // nil if the maintenance already started
+	End        *time.Time            // nil if the end is not known yet
+	Interfaces []InterfaceIdentifier // empty if all interfaces are affected
+}
+
+// IsActive returns true if the maintenance window includes the given time.
+func (n MaintenanceNotice) IsActive(now time.Time) bool {
+	return (n.Start == nil || !now.Before(*n.Start)) && !n.IsOver(now)
+}
+
+// IsOver returns true if the maintenance window ended before the given time.
+func (n MaintenanceNotice) IsOver(now time.Time) bool {
+	return n.End != nil && !now.Before(*n.End)
+}
+
+// Affects returns true if the maintenance affects the given interface.
+func (n MaintenanceNotice) Affects(id InterfaceIdentifier) bool {
+	return len(n.Interfaces) == 0 || slices.Contains(n.Interfaces, id)
+}
+
+// ServiceStatus is the content of the public status page.
+type ServiceStatus struct {
+	Endpoints []EndpointStatus
+	Notices   []MaintenanceNotice // current and upcoming maintenance notices
+	CheckedAt time.Time
+}
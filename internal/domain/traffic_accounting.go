@@ -0,0 +1,37 @@
+package domain
+
+// AccountingSubnetOther is the subnet of the traffic to destinations outside of all accounting subnets.
+const AccountingSubnetOther = "other"
+
+// PeerSubnetTraffic is the traffic between a peer and the destinations of one accounting subnet.
+type PeerSubnetTraffic struct {
+	Subnet string // the accounting subnet in CIDR notation, or AccountingSubnetOther
+
+	BytesReceived      uint64 // bytes received from the peer
+	BytesTransmitted   uint64 // bytes sent to the peer
+	PacketsReceived    uint64 // packets received from the peer
+	PacketsTransmitted uint64 // packets sent to the peer
+}
+
+// PeerTraffic is the traffic of a peer as counted by the accounting backend, broken down by destination subnet.
+type PeerTraffic struct {
+	Subnets []PeerSubnetTraffic
+}
+
+// BytesReceived returns the total number of bytes that were received from the peer.
+func (t PeerTraffic) BytesReceived() uint64 {
+	var total uint64
+	for _, subnet := range t.Subnets {
+		total += subnet.BytesReceived
+	}
+	return total
+}
+
+// BytesTransmitted returns the total number of bytes that were sent to the peer.
+func (t PeerTraffic) BytesTransmitted() uint64 {
+	var total uint64
+	for _, subnet := range t.Subnets {
+		total += subnet.BytesTransmitted
+	}
+	return total
+}
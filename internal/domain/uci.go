@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"net"
+	"strings"
+)
+
+// uciMaxNameLength is the maximum length of a network interface name on Linux.
+const uciMaxNameLength = 15
+
+// UciConfigStyle specifies the output of the OpenWrt configuration renderer.
+type UciConfigStyle string
+
+const (
+	UciConfigStyleCommands UciConfigStyle = "commands" // uci commands that can be pasted into a shell
+	UciConfigStyleFile     UciConfigStyle = "file"     // a snippet for the /etc/config/network file
+)
+
+// IsValid returns true if the uci config style is known.
+func (s UciConfigStyle) IsValid() bool {
+	switch s {
+	case UciConfigStyleCommands, UciConfigStyleFile:
+		return true
+	default:
+		return false
+	}
+}
+
+// UciSectionName converts the given identifier to a name that can be used as uci section and as OpenWrt interface
+// name. Uci only allows alphanumeric characters and underscores in section names.
+func UciSectionName(id string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, id)
+
+	if name == "" {
+		name = "wg"
+	}
+	if len(name) > uciMaxNameLength {
+		name = name[:uciMaxNameLength]
+	}
+
+	return name
+}
+
+// UciQuote returns the given value as single-quoted uci value.
+func UciQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// SplitEndpoint splits an endpoint address into host and port, as OpenWrt configures them separately.
+// If the endpoint contains no port, the port is empty.
+func SplitEndpoint(endpoint string) (host, port string) {
+	endpoint = strings.TrimSpace(endpoint)
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return strings.Trim(endpoint, "[]"), ""
+	}
+
+	return host, port
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUciSectionName(t *testing.T) {
+	assert.Equal(t, "wg0", UciSectionName("wg0"))
+	assert.Equal(t, "wg_office_1", UciSectionName("wg-office.1"))
+	assert.Equal(t, "wg", UciSectionName(""))
+	assert.Len(t, UciSectionName("a-very-long-interface-name"), uciMaxNameLength)
+}
+
+func TestSplitEndpoint(t *testing.T) {
+	host, port := SplitEndpoint("vpn.example.com:51820")
+	assert.Equal(t, "vpn.example.com", host)
+	assert.Equal(t, "51820", port)
+
+	host, port = SplitEndpoint("[2001:db8::1]:51820")
+	assert.Equal(t, "2001:db8::1", host)
+	assert.Equal(t, "51820", port)
+
+	host, port = SplitEndpoint("vpn.example.com")
+	assert.Equal(t, "vpn.example.com", host)
+	assert.Empty(t, port)
+}
@@ -13,18 +13,29 @@ import (
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/h44z/wg-portal/internal"
 )
 
 const (
 	UserSourceLdap     UserSource = "ldap"  // LDAP / ActiveDirectory
 	UserSourceDatabase UserSource = "db"    // sqlite / mysql database
 	UserSourceOauth    UserSource = "oauth" // oauth / open id connect
+	UserSourceProxy    UserSource = "proxy" // headers of a trusted authentication proxy
+)
+
+const (
+	UserTypeHuman   UserType = "human"   // a person that logs in to the portal
+	UserTypeService UserType = "service" // a non-human account for machine peers, only accessible through the API
 )
 
 type UserIdentifier string
 
 type UserSource string
 
+// UserType distinguishes human users from service accounts. An empty type is treated as a human user.
+type UserType string
+
 // User is the user model that gets linked to peer entries, by default an empty user model with only the email address is created
 type User struct {
 	BaseModel
@@ -35,6 +46,12 @@ type User struct {
 	Source       UserSource
 	ProviderName string
 	IsAdmin      bool
+	IsHelpdesk   bool     `gorm:"column:is_helpdesk"` // helpdesk users support other users without full admin rights
+	Type         UserType `gorm:"column:user_type"`   // the type of the user, empty for human users
+
+	AdminInterfacesStr string // the interfaces the user administrates without global admin rights, comma separated
+
+	OrganizationIdentifier OrganizationIdentifier `gorm:"index;column:organization_identifier"` // the organization of the user, empty for users without organization
 
 	// optional fields
 	Firstname  string `form:"firstname" binding:"omitempty"`
@@ -58,7 +75,8 @@ type User struct {
 	ApiToken        string `form:"api_token" binding:"omitempty"`
 	ApiTokenCreated *time.Time
 
-	LinkedPeerCount int `gorm:"-"`
+	LinkedPeerCount int         `gorm:"-"`
+	Groups          []UserGroup `gorm:"-"` // the user groups of the user, calculated value
 }
 
 // IsDisabled returns true if the user is disabled. In such a case,
@@ -72,6 +90,41 @@ func (u *User) IsLocked() bool {
 	return u.Locked != nil
 }
 
+// AdminInterfaces returns the identifiers of the interfaces the user administrates without global admin rights.
+// This includes the interfaces that are administrated by the user groups of the user.
+func (u *User) AdminInterfaces() []InterfaceIdentifier {
+	ids := internal.SliceString(u.AdminInterfacesStr)
+	interfaces := make([]InterfaceIdentifier, len(ids))
+	for i, id := range ids {
+		interfaces[i] = InterfaceIdentifier(id)
+	}
+	for _, group := range u.Groups {
+		for _, id := range group.AdminInterfaces() {
+			if !slices.Contains(interfaces, id) {
+				interfaces = append(interfaces, id)
+			}
+		}
+	}
+
+	return interfaces
+}
+
+// GroupIdentifiers returns the identifiers of the user groups of the user.
+func (u *User) GroupIdentifiers() []UserGroupIdentifier {
+	ids := make([]UserGroupIdentifier, len(u.Groups))
+	for i, group := range u.Groups {
+		ids[i] = group.Identifier
+	}
+
+	return ids
+}
+
+// IsServiceAccount returns true if the user is a service account. Service accounts cannot log in, have no email
+// address and are excluded from all mail workflows. They can only access the portal through API tokens.
+func (u *User) IsServiceAccount() bool {
+	return u.Type == UserTypeService
+}
+
 func (u *User) IsApiEnabled() bool {
 	if u.ApiToken != "" {
 		return true
@@ -81,6 +134,10 @@ func (u *User) IsApiEnabled() bool {
 }
 
 func (u *User) CanChangePassword() error {
+	if u.IsServiceAccount() {
+		return errors.New("service accounts have no password")
+	}
+
 	if u.Source == UserSourceDatabase {
 		return nil
 	}
@@ -131,6 +188,10 @@ func (u *User) DeleteAllowed() error {
 }
 
 func (u *User) CheckPassword(password string) error {
+	if u.IsServiceAccount() {
+		return errors.New("login not allowed for service accounts")
+	}
+
 	if u.Source != UserSourceDatabase {
 		return errors.New("invalid user source")
 	}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/h44z/wg-portal/internal"
+)
+
+type UserGroupIdentifier string
+
+var userGroupIdentifierPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// UserGroup is a group of users that is managed in the portal, independent of LDAP groups.
+// Members of a group administrate the interfaces of the group, get the route sets of the group attached to their
+// new peers and receive the notifications that target the group.
+type UserGroup struct {
+	BaseModel
+
+	Identifier  UserGroupIdentifier `gorm:"primaryKey;column:identifier"` // group unique identifier, for example: lab-staff
+	DisplayName string              // a nice display name for the group
+	Description string              // an optional description
+
+	MembersStr         string `gorm:"column:members_str"` // the user identifiers of the members, comma separated
+	AdminInterfacesStr string // the interfaces the members administrate without global admin rights, comma separated
+	PeerRouteSetsStr   string // the route sets that are attached to new peers of the members, comma separated
+}
+
+// Validate performs checks to ensure that the user group is valid.
+func (g *UserGroup) Validate() error {
+	if !userGroupIdentifierPattern.MatchString(string(g.Identifier)) {
+		return errors.New("invalid identifier, only lower case letters, digits, '-' and '_' are allowed")
+	}
+
+	for _, id := range g.PeerRouteSets() {
+		if !routeSetIdentifierPattern.MatchString(string(id)) {
+			return fmt.Errorf("invalid route set identifier %s", id)
+		}
+	}
+
+	return nil
+}
+
+// Members returns the identifiers of all members of the group.
+func (g *UserGroup) Members() []UserIdentifier {
+	var members []UserIdentifier
+	for _, id := range internal.SliceString(g.MembersStr) {
+		if !slices.Contains(members, UserIdentifier(id)) {
+			members = append(members, UserIdentifier(id))
+		}
+	}
+	return members
+}
+
+// HasMember returns true if the given user is a member of the group.
+func (g *UserGroup) HasMember(id UserIdentifier) bool {
+	return slices.Contains(g.Members(), id)
+}
+
+// RemoveMember removes the given user from the group. It returns false if the user was not a member.
+func (g *UserGroup) RemoveMember(id UserIdentifier) bool {
+	members := g.Members()
+	remaining := slices.DeleteFunc(slices.Clone(members), func(m UserIdentifier) bool { return m == id })
+	if len(remaining) == len(members) {
+		return false
+	}
+
+	ids := make([]string, len(remaining))
+	for i, member := range remaining {
+		ids[i] = string(member)
+	}
+	g.MembersStr = internal.SliceToString(ids)
+
+	return true
+}
+
+// AdminInterfaces returns the identifiers of the interfaces the members of the group administrate.
+func (g *UserGroup) AdminInterfaces() []InterfaceIdentifier {
+	var interfaces []InterfaceIdentifier
+	for _, id := range internal.SliceString(g.AdminInterfacesStr) {
+		interfaces = append(interfaces, InterfaceIdentifier(id))
+	}
+	return interfaces
+}
+
+// PeerRouteSets returns the identifiers of the route sets that are attached to new peers of the members.
+func (g *UserGroup) PeerRouteSets() []RouteSetIdentifier {
+	var ids []RouteSetIdentifier
+	for _, id := range internal.SliceString(g.PeerRouteSetsStr) {
+		ids = append(ids, RouteSetIdentifier(id))
+	}
+	return ids
+}
+
+// GroupsOfUser returns the groups the given user is a member of.
+func GroupsOfUser(groups []UserGroup, id UserIdentifier) []UserGroup {
+	var result []UserGroup
+	for _, group := range groups {
+		if group.HasMember(id) {
+			result = append(result, group)
+		}
+	}
+	return result
+}
+
+// ApplyUserGroups attaches the route sets of the given groups to the peer. Route sets that are already attached
+// are kept, so the groups only extend the peer profile.
+func (p *Peer) ApplyUserGroups(groups []UserGroup) {
+	routeSets := internal.SliceString(p.RouteSetsStr.GetValue())
+	changed := false
+	for _, group := range groups {
+		for _, id := range group.PeerRouteSets() {
+			if !slices.Contains(routeSets, string(id)) {
+				routeSets = append(routeSets, string(id))
+				changed = true
+			}
+		}
+	}
+
+	if changed {
+		p.RouteSetsStr.SetValue(internal.SliceToString(routeSets))
+	}
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserGroup_Validate(t *testing.T) {
+	assert.NoError(t, (&UserGroup{Identifier: "lab-staff", PeerRouteSetsStr: "lab-nets"}).Validate())
+	assert.Error(t, (&UserGroup{Identifier: ""}).Validate())
+	assert.Error(t, (&UserGroup{Identifier: "Lab Staff"}).Validate())
+	assert.Error(t, (&UserGroup{Identifier: "lab-staff", PeerRouteSetsStr: "Lab Nets"}).Validate())
+}
+
+func TestUserGroup_Members(t *testing.T) {
+	group := UserGroup{MembersStr: "alice, bob,alice"}
+
+	assert.Equal(t, []UserIdentifier{"alice", "bob"}, group.Members())
+	assert.True(t, group.HasMember("bob"))
+	assert.False(t, group.HasMember("carol"))
+
+	assert.False(t, group.RemoveMember("carol"))
+	assert.True(t, group.RemoveMember("alice"))
+	assert.Equal(t, []UserIdentifier{"bob"}, group.Members())
+}
+
+func TestGroupsOfUser(t *testing.T) {
+	groups := []UserGroup{
+		{Identifier: "lab-staff", MembersStr: "alice,bob"},
+		{Identifier: "ops", MembersStr: "bob"},
+	}
+
+	assert.Len(t, GroupsOfUser(groups, "alice"), 1)
+	assert.Len(t, GroupsOfUser(groups, "bob"), 2)
+	assert.Empty(t, GroupsOfUser(groups, "carol"))
+}
+
+func TestPeer_ApplyUserGroups(t *testing.T) {
+	peer := Peer{}
+	peer.RouteSetsStr.SetValue("corp")
+
+	peer.ApplyUserGroups([]UserGroup{
+		{Identifier: "lab-staff", PeerRouteSetsStr: "lab-nets,corp"},
+		{Identifier: "ops", PeerRouteSetsStr: "ops-nets"},
+	})
+
+	assert.Equal(t, "corp,lab-nets,ops-nets", peer.RouteSetsStr.GetValue())
+}
+
+func TestUser_AdminInterfaces_Groups(t *testing.T) {
+	user := User{
+		AdminInterfacesStr: "wg0",
+		Groups: []UserGroup{
+			{Identifier: "lab-staff", AdminInterfacesStr: "wg0,wg1"},
+		},
+	}
+
+	assert.Equal(t, []InterfaceIdentifier{"wg0", "wg1"}, user.AdminInterfaces())
+}
@@ -105,6 +105,26 @@ func TestUser_CheckPassword(t *testing.T) {
 	assert.Error(t, user.CheckPassword(password))
 }
 
+func TestUser_IsServiceAccount(t *testing.T) {
+	user := &User{}
+	assert.False(t, user.IsServiceAccount())
+
+	user.Type = UserTypeHuman
+	assert.False(t, user.IsServiceAccount())
+
+	user.Type = UserTypeService
+	assert.True(t, user.IsServiceAccount())
+}
+
+func TestUser_CheckPassword_ServiceAccount(t *testing.T) {
+	password := "password"
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+	user := &User{Source: UserSourceDatabase, Type: UserTypeService, Password: PrivateString(hashedPassword)}
+	assert.Error(t, user.CheckPassword(password))
+	assert.Error(t, user.CanChangePassword())
+}
+
 func TestUser_CheckApiToken(t *testing.T) {
 	user := &User{}
 	assert.Error(t, user.CheckApiToken("token"))
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"time"
+)
+
+// WebhookEvent is an event that was emitted to the webhook endpoints. All events are stored in the webhook event
+// log, so that they can be delivered again after an endpoint was unavailable.
+type WebhookEvent struct {
+	Id        uint64    `gorm:"primaryKey;autoIncrement:true;column:id"`
+	CreatedAt time.Time `gorm:"column:created_at;index:idx_we_created"`
+
+	Event      string `gorm:"column:event"`      // the event type, for example: create
+	Entity     string `gorm:"column:entity"`     // the entity type, for example: peer
+	Identifier string `gorm:"column:identifier"` // the identifier of the entity
+
+	Payload []byte `gorm:"column:payload"` // the JSON request body that is sent to the endpoints
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"   // the event is waiting for the next attempt
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered" // the endpoint accepted the event
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"    // all attempts failed, the event was skipped
+)
+
+// WebhookDelivery records the delivery attempts of a webhook event to a single endpoint.
+type WebhookDelivery struct {
+	EventId  uint64 `gorm:"primaryKey;autoIncrement:false;column:event_id"`
+	Endpoint string `gorm:"primaryKey;column:endpoint"`
+
+	Status        WebhookDeliveryStatus `gorm:"column:status"`
+	Attempts      int                   `gorm:"column:attempts"` // the attempts since the event was last (re)played
+	Replays       int                   `gorm:"column:replays"`  // how often the event was replayed by an admin
+	LastAttemptAt *time.Time            `gorm:"column:last_attempt_at"`
+	LastError     string                `gorm:"column:last_error"` // empty if the last attempt succeeded
+	DeliveredAt   *time.Time            `gorm:"column:delivered_at"`
+}
+
+// IsFinished returns true if no further attempts are made for the delivery.
+func (d WebhookDelivery) IsFinished() bool {
+	return d.Status == WebhookDeliveryStatusDelivered || d.Status == WebhookDeliveryStatusFailed
+}
+
+// WebhookCursor is the position of a webhook endpoint in the event log. All events up to and including the
+// cursor event were delivered to the endpoint, or failed after the maximum number of attempts.
+// Moving the cursor back replays all later events to the endpoint.
+type WebhookCursor struct {
+	Endpoint  string    `gorm:"primaryKey;column:endpoint"`
+	EventId   uint64    `gorm:"column:event_id"` // the last processed event, 0 if no event was processed yet
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+
+	// ReplayUntil is the last event that was processed before the cursor was moved back. Events up to this id are
+	// replays and are delivered again, even if they were delivered before.
+	ReplayUntil uint64 `gorm:"column:replay_until"`
+}
+
+// IsReplay returns true if the given event was processed before and is replayed because the cursor was moved back.
+func (c WebhookCursor) IsReplay(eventId uint64) bool {
+	return eventId <= c.ReplayUntil
+}
+
+// WebhookEndpointState is the delivery state of a configured webhook endpoint.
+type WebhookEndpointState struct {
+	Name        string
+	Url         string
+	Cursor      WebhookCursor
+	LastEventId uint64 // the most recent event in the event log
+}
+
+// PendingEvents returns the number of events that were not processed by the endpoint yet.
+func (s WebhookEndpointState) PendingEvents() uint64 {
+	if s.LastEventId <= s.Cursor.EventId {
+		return 0
+	}
+	return s.LastEventId - s.Cursor.EventId
+}
+
+// WebhookEventLogEntry is a webhook event together with its delivery records.
+type WebhookEventLogEntry struct {
+	Event      WebhookEvent
+	Deliveries []WebhookDelivery
+}
@@ -0,0 +1,213 @@
+//go:build linux
+
+package lowlevel
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// BpfInstruction is a single eBPF instruction.
+type BpfInstruction struct {
+	OpCode uint8
+	Dst    uint8 // destination register
+	Src    uint8 // source register
+	Off    int16
+	Imm    int32
+}
+
+func (i BpfInstruction) encode(b []byte) {
+	b[0] = i.OpCode
+	b[1] = i.Src<<4 | i.Dst&0x0f
+	binary.NativeEndian.PutUint16(b[2:], uint16(i.Off))
+	binary.NativeEndian.PutUint32(b[4:], uint32(i.Imm))
+}
+
+// BpfMap is an eBPF map that is owned by this process.
+type BpfMap struct {
+	fd        int
+	keySize   uint32
+	valueSize uint32
+}
+
+// NewBpfMap creates a new eBPF map, see the BPF_MAP_CREATE command of the bpf syscall.
+func NewBpfMap(name string, mapType, keySize, valueSize, maxEntries, flags uint32) (*BpfMap, error) {
+	attr := struct {
+		mapType    uint32
+		keySize    uint32
+		valueSize  uint32
+		maxEntries uint32
+		mapFlags   uint32
+		innerMapFd uint32
+		numaNode   uint32
+		mapName    [unix.BPF_OBJ_NAME_LEN]byte
+	}{
+		mapType:    mapType,
+		keySize:    keySize,
+		valueSize:  valueSize,
+		maxEntries: maxEntries,
+		mapFlags:   flags,
+	}
+	copy(attr.mapName[:unix.BPF_OBJ_NAME_LEN-1], name)
+
+	fd, err := bpf(unix.BPF_MAP_CREATE, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bpf map %s: %w", name, err)
+	}
+
+	return &BpfMap{fd: fd, keySize: keySize, valueSize: valueSize}, nil
+}
+
+// Fd returns the file descriptor of the map.
+func (m *BpfMap) Fd() int {
+	return m.fd
+}
+
+// Lookup reads the value of the given key. It returns unix.ENOENT if the key does not exist.
+func (m *BpfMap) Lookup(key []byte) ([]byte, error) {
+	value := make([]byte, m.valueSize)
+	err := m.elemCommand(unix.BPF_MAP_LOOKUP_ELEM, key, value, 0)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Update creates or updates the value of the given key.
+func (m *BpfMap) Update(key, value []byte) error {
+	return m.elemCommand(unix.BPF_MAP_UPDATE_ELEM, key, value, unix.BPF_ANY)
+}
+
+// Delete removes the given key. It returns unix.ENOENT if the key does not exist.
+func (m *BpfMap) Delete(key []byte) error {
+	return m.elemCommand(unix.BPF_MAP_DELETE_ELEM, key, nil, 0)
+}
+
+// Keys returns all keys of the map.
+func (m *BpfMap) Keys() ([][]byte, error) {
+	var keys [][]byte
+	var key []byte // the first call with a nil key returns the first key
+	for {
+		next := make([]byte, m.keySize)
+		err := m.elemCommand(unix.BPF_MAP_GET_NEXT_KEY, key, next, 0)
+		if errors.Is(err, unix.ENOENT) {
+			return keys, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, next)
+		key = next
+	}
+}
+
+// Close releases the map. Programs that use the map keep it alive.
+func (m *BpfMap) Close() error {
+	return unix.Close(m.fd)
+}
+
+func (m *BpfMap) elemCommand(cmd int, key, value []byte, flags uint64) error {
+	if key != nil && len(key) != int(m.keySize) {
+		return fmt.Errorf("invalid key size %d, expected %d", len(key), m.keySize)
+	}
+	if value != nil && len(value) != int(m.valueSize) && cmd != unix.BPF_MAP_GET_NEXT_KEY {
+		return fmt.Errorf("invalid value size %d, expected %d", len(value), m.valueSize)
+	}
+
+	attr := struct {
+		mapFd uint32
+		_     uint32
+		key   uint64
+		value uint64
+		flags uint64
+	}{
+		mapFd: uint32(m.fd),
+		key:   bytesPointer(key),
+		value: bytesPointer(value),
+		flags: flags,
+	}
+
+	_, err := bpf(cmd, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(key)
+	runtime.KeepAlive(value)
+
+	return err
+}
+
+// BpfProgram is a loaded eBPF program that is owned by this process.
+type BpfProgram struct {
+	fd int
+}
+
+// LoadBpfProgram loads the given instructions into the kernel, see the BPF_PROG_LOAD command of the bpf syscall.
+// If the verifier rejects the program, the error contains the verifier log.
+func LoadBpfProgram(name string, progType uint32, instructions []BpfInstruction) (*BpfProgram, error) {
+	insns := make([]byte, 8*len(instructions))
+	for i, instruction := range instructions {
+		instruction.encode(insns[8*i:])
+	}
+	license := []byte("GPL\x00")
+	log := make([]byte, 64*1024)
+
+	attr := struct {
+		progType    uint32
+		insnCnt     uint32
+		insns       uint64
+		license     uint64
+		logLevel    uint32
+		logSize     uint32
+		logBuf      uint64
+		kernVersion uint32
+		progFlags   uint32
+		progName    [unix.BPF_OBJ_NAME_LEN]byte
+	}{
+		progType: progType,
+		insnCnt:  uint32(len(instructions)),
+		insns:    bytesPointer(insns),
+		license:  bytesPointer(license),
+	}
+	copy(attr.progName[:unix.BPF_OBJ_NAME_LEN-1], name)
+
+	fd, err := bpf(unix.BPF_PROG_LOAD, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	if err != nil {
+		// load the program again with the verifier log enabled to provide details
+		attr.logLevel, attr.logSize, attr.logBuf = 1, uint32(len(log)), bytesPointer(log)
+		_, _ = bpf(unix.BPF_PROG_LOAD, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+		runtime.KeepAlive(log)
+		return nil, fmt.Errorf("failed to load bpf program %s: %w: %s", name, err, unix.ByteSliceToString(log))
+	}
+	runtime.KeepAlive(insns)
+	runtime.KeepAlive(license)
+
+	return &BpfProgram{fd: fd}, nil
+}
+
+// Fd returns the file descriptor of the program.
+func (p *BpfProgram) Fd() int {
+	return p.fd
+}
+
+// Close releases the program. Attached programs stay loaded until they are detached.
+func (p *BpfProgram) Close() error {
+	return unix.Close(p.fd)
+}
+
+func bpf(cmd int, attr unsafe.Pointer, size uintptr) (int, error) {
+	r, _, errno := unix.Syscall(unix.SYS_BPF, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(r), nil
+}
+
+func bytesPointer(b []byte) uint64 {
+	if len(b) == 0 {
+		return 0
+	}
+	return uint64(uintptr(unsafe.Pointer(&b[0])))
+}
@@ -0,0 +1,393 @@
+package lowlevel
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// BsdInterface is the state of a FreeBSD network interface, as reported by ifconfig(8) and netstat(1).
+type BsdInterface struct {
+	Name             string
+	Up               bool
+	Mtu              int
+	Addresses        []netip.Prefix // link-local addresses are omitted
+	BytesReceived    uint64
+	BytesTransmitted uint64
+}
+
+// BsdRoute is an entry of the FreeBSD routing table.
+type BsdRoute struct {
+	Destination netip.Prefix
+	Interface   string
+	Mtu         int
+	Static      bool // true for routes that were added manually
+}
+
+// A BsdNetworkClient is a type which can control the network interfaces and routes of FreeBSD.
+type BsdNetworkClient interface {
+	InterfaceCreate(cloner, name string) error
+	InterfaceDestroy(name string) error
+	InterfaceGet(name string) (*BsdInterface, error)
+	InterfaceSetMtu(name string, mtu int) error
+	InterfaceSetUp(name string) error
+	InterfaceSetDown(name string) error
+	AddrAdd(name string, addr netip.Prefix) error
+	AddrDel(name string, addr netip.Prefix) error
+	RouteAdd(name string, destination netip.Prefix) error
+	RouteDel(name string, destination netip.Prefix) error
+	RouteList(name string) ([]BsdRoute, error)
+	RouteGet(destination netip.Addr) (*BsdRoute, error)
+}
+
+// A RcConfClient is a type which can change the variables of a rc.conf(5) file.
+type RcConfClient interface {
+	// Get returns the value of the variable, or an empty string if the variable is not set.
+	Get(name string) (string, error)
+	Set(name, value string) error
+	Unset(name string) error
+}
+
+// BsdNetworkManager implements the BsdNetworkClient using the ifconfig(8), route(8) and netstat(1) commands of the
+// FreeBSD base system.
+type BsdNetworkManager struct {
+}
+
+func (n BsdNetworkManager) InterfaceCreate(cloner, name string) error {
+	_, err := runCommand("ifconfig", cloner, "create", "name", name)
+	return err
+}
+
+func (n BsdNetworkManager) InterfaceDestroy(name string) error {
+	_, err := runCommand("ifconfig", name, "destroy")
+	return interfaceError(name, err)
+}
+
+// InterfaceGet returns the state of the interface. If the interface does not exist, os.ErrNotExist is returned.
+func (n BsdNetworkManager) InterfaceGet(name string) (*BsdInterface, error) {
+	output, err := runCommand("ifconfig", "-f", "inet:cidr,inet6:cidr", name)
+	if err != nil {
+		return nil, interfaceError(name, err)
+	}
+	iface, err := ParseIfconfig(output)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err = runCommand("netstat", "-i", "-b", "-n", "-I", name)
+	if err != nil {
+		return nil, interfaceError(name, err)
+	}
+	iface.BytesReceived, iface.BytesTransmitted, err = ParseNetstatInterfaceBytes(output)
+	if err != nil {
+		return nil, err
+	}
+
+	return iface, nil
+}
+
+func (n BsdNetworkManager) InterfaceSetMtu(name string, mtu int) error {
+	_, err := runCommand("ifconfig", name, "mtu", strconv.Itoa(mtu))
+	return interfaceError(name, err)
+}
+
+func (n BsdNetworkManager) InterfaceSetUp(name string) error {
+	_, err := runCommand("ifconfig", name, "up")
+	return interfaceError(name, err)
+}
+
+func (n BsdNetworkManager) InterfaceSetDown(name string) error {
+	_, err := runCommand("ifconfig", name, "down")
+	return interfaceError(name, err)
+}
+
+func (n BsdNetworkManager) AddrAdd(name string, addr netip.Prefix) error {
+	_, err := runCommand("ifconfig", name, addressFamily(addr.Addr()), addr.String(), "alias")
+	return interfaceError(name, err)
+}
+
+func (n BsdNetworkManager) AddrDel(name string, addr netip.Prefix) error {
+	_, err := runCommand("ifconfig", name, addressFamily(addr.Addr()), addr.Addr().String(), "-alias")
+	return interfaceError(name, err)
+}
+
+func (n BsdNetworkManager) RouteAdd(name string, destination netip.Prefix) error {
+	_, err := runCommand("route", "-q", "-n", "add", "-"+addressFamily(destination.Addr()), "-net",
+		destination.String(), "-interface", name)
+	return err
+}
+
+func (n BsdNetworkManager) RouteDel(name string, destination netip.Prefix) error {
+	_, err := runCommand("route", "-q", "-n", "delete", "-"+addressFamily(destination.Addr()), "-net",
+		destination.String(), "-interface", name)
+	return err
+}
+
+// RouteList returns the IPv4 and IPv6 routes that use the interface.
+func (n BsdNetworkManager) RouteList(name string) ([]BsdRoute, error) {
+	var routes []BsdRoute
+	for _, family := range []string{"inet", "inet6"} {
+		output, err := runCommand("netstat", "-r", "-n", "-W", "-f", family)
+		if err != nil {
+			return nil, err
+		}
+		for _, route := range ParseNetstatRoutes(output) {
+			if route.Interface == name {
+				routes = append(routes, route)
+			}
+		}
+	}
+
+	return routes, nil
+}
+
+// RouteGet returns the route that is used for the destination.
+func (n BsdNetworkManager) RouteGet(destination netip.Addr) (*BsdRoute, error) {
+	output, err := runCommand("route", "-n", "get", "-"+addressFamily(destination), destination.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseRouteGet(output)
+}
+
+// RcConfManager implements the RcConfClient using the sysrc(8) command of the FreeBSD base system.
+type RcConfManager struct {
+	Path string // the rc.conf file, for example /etc/rc.conf
+}
+
+func (r RcConfManager) Get(name string) (string, error) {
+	output, err := runCommand("sysrc", "-f", r.Path, "-i", "-n", name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+func (r RcConfManager) Set(name, value string) error {
+	_, err := runCommand("sysrc", "-f", r.Path, name+"="+value)
+	return err
+}
+
+func (r RcConfManager) Unset(name string) error {
+	_, err := runCommand("sysrc", "-f", r.Path, "-i", "-x", name)
+	return err
+}
+
+// ParseIfconfig parses the output of ifconfig(8) for a single interface, printed with the format
+// "inet:cidr,inet6:cidr". Link-local addresses are skipped.
+func ParseIfconfig(output string) (*BsdInterface, error) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	if !scanner.Scan() {
+		return nil, errors.New("empty ifconfig output")
+	}
+
+	// wg0: flags=10080c1<UP,RUNNING,NOARP,MULTICAST,LOWER_UP> metric 0 mtu 1420
+	header := scanner.Text()
+	name, attributes, ok := strings.Cut(header, ": ")
+	if !ok {
+		return nil, fmt.Errorf("invalid ifconfig header %q", header)
+	}
+	iface := &BsdInterface{Name: name}
+	fields := strings.Fields(attributes)
+	for i, field := range fields {
+		switch {
+		case strings.HasPrefix(field, "flags="):
+			if _, flags, ok := strings.Cut(field, "<"); ok {
+				iface.Up = slices.Contains(strings.Split(strings.TrimSuffix(flags, ">"), ","), "UP")
+			}
+		case field == "mtu" && i+1 < len(fields):
+			mtu, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid mtu %q: %w", fields[i+1], err)
+			}
+			iface.Mtu = mtu
+		}
+	}
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "inet" && fields[0] != "inet6" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(fields[1])
+		if err != nil {
+			continue // scoped link-local addresses like fe80::1%wg0/64
+		}
+		if prefix.Addr().IsLinkLocalUnicast() {
+			continue
+		}
+		iface.Addresses = append(iface.Addresses, prefix)
+	}
+
+	return iface, nil
+}
+
+// ParseNetstatInterfaceBytes parses the output of "netstat -i -b -n -I <name>" and returns the received and
+// transmitted bytes of the interface.
+func ParseNetstatInterfaceBytes(output string) (received, transmitted uint64, err error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return 0, 0, errors.New("empty netstat output")
+	}
+
+	// the address column of the link row may be empty, so the counters are located from the end of the row
+	header := strings.Fields(lines[0])
+	column := func(fields []string, name string) (uint64, error) {
+		index := slices.Index(header, name)
+		if index < 0 {
+			return 0, fmt.Errorf("missing netstat column %s", name)
+		}
+		position := len(fields) - (len(header) - index)
+		if position < 0 {
+			return 0, fmt.Errorf("missing value of netstat column %s", name)
+		}
+		return strconv.ParseUint(fields[position], 10, 64)
+	}
+
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasPrefix(fields[2], "<Link#") {
+			continue
+		}
+		if received, err = column(fields, "Ibytes"); err != nil {
+			return 0, 0, err
+		}
+		if transmitted, err = column(fields, "Obytes"); err != nil {
+			return 0, 0, err
+		}
+		return received, transmitted, nil
+	}
+
+	return 0, 0, errors.New("missing link statistics in netstat output")
+}
+
+// ParseNetstatRoutes parses the output of "netstat -r -n -W" for a single address family. Entries with scoped or
+// invalid destinations are skipped.
+func ParseNetstatRoutes(output string) []BsdRoute {
+	var routes []BsdRoute
+	var header []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "Destination" {
+			header = fields
+			continue
+		}
+		if header == nil || len(fields) < len(header)-1 { // the expire column may be empty
+			continue
+		}
+
+		value := func(name string) string {
+			index := slices.Index(header, name)
+			if index < 0 || index >= len(fields) {
+				return ""
+			}
+			return fields[index]
+		}
+
+		destination, ok := parseRouteDestination(value("Destination"), value("Gateway"))
+		if !ok {
+			continue
+		}
+		mtu, _ := strconv.Atoi(value("Mtu"))
+		routes = append(routes, BsdRoute{
+			Destination: destination,
+			Interface:   value("Netif"),
+			Mtu:         mtu,
+			Static:      strings.Contains(value("Flags"), "S"),
+		})
+	}
+
+	return routes
+}
+
+func parseRouteDestination(destination, gateway string) (netip.Prefix, bool) {
+	if destination == "default" {
+		if strings.Contains(gateway, ":") {
+			return netip.MustParsePrefix("::/0"), true
+		}
+		return netip.MustParsePrefix("0.0.0.0/0"), true
+	}
+	if strings.Contains(destination, "/") {
+		prefix, err := netip.ParsePrefix(destination)
+		return prefix, err == nil
+	}
+	addr, err := netip.ParseAddr(destination) // host route
+	if err != nil || addr.Zone() != "" {
+		return netip.Prefix{}, false
+	}
+
+	return netip.PrefixFrom(addr, addr.BitLen()), true
+}
+
+// ParseRouteGet parses the output of "route -n get <destination>".
+func ParseRouteGet(output string) (*BsdRoute, error) {
+	route := &BsdRoute{}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for i, line := range lines {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok {
+			switch key {
+			case "destination":
+				route.Destination, _ = parseRouteDestination(strings.TrimSpace(value), "")
+			case "interface":
+				route.Interface = strings.TrimSpace(value)
+			case "flags":
+				route.Static = strings.Contains(value, "STATIC")
+			}
+		}
+
+		// the metrics are printed as a table with a header line and a value line
+		header := strings.Fields(line)
+		mtuIndex := slices.Index(header, "mtu")
+		if mtuIndex >= 0 && i+1 < len(lines) {
+			values := strings.Fields(lines[i+1])
+			if mtuIndex < len(values) {
+				route.Mtu, _ = strconv.Atoi(values[mtuIndex])
+			}
+		}
+	}
+	if route.Interface == "" {
+		return nil, errors.New("missing interface in route output")
+	}
+
+	return route, nil
+}
+
+func addressFamily(addr netip.Addr) string {
+	if addr.Is4() {
+		return "inet"
+	}
+	return "inet6"
+}
+
+// interfaceError converts the error of a command for a missing interface to os.ErrNotExist.
+func interfaceError(name string, err error) error {
+	if err != nil && strings.Contains(err.Error(), "does not exist") {
+		return fmt.Errorf("interface %s: %w", name, os.ErrNotExist)
+	}
+	return err
+}
+
+func runCommand(name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err,
+			strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
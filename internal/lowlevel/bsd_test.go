@@ -0,0 +1,97 @@
+package lowlevel
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIfconfig(t *testing.T) {
+	output := `wg0: flags=10080c1<UP,RUNNING,NOARP,MULTICAST,LOWER_UP> metric 0 mtu 1420
+	options=80000<LINKSTATE>
+	inet 10.11.12.1/24
+	inet 10.11.13.1/24
+	inet6 fe80::1%wg0/64 scopeid 0x3
+	inet6 fdfd:d3ad:c0de:1234::1/64
+	groups: wg
+	nd6 options=109<PERFORMNUD,IFDISABLED,NO_DAD>
+`
+	iface, err := ParseIfconfig(output)
+	require.NoError(t, err)
+
+	assert.Equal(t, "wg0", iface.Name)
+	assert.True(t, iface.Up)
+	assert.Equal(t, 1420, iface.Mtu)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.11.12.1/24"),
+		netip.MustParsePrefix("10.11.13.1/24"),
+		netip.MustParsePrefix("fdfd:d3ad:c0de:1234::1/64"),
+	}, iface.Addresses)
+
+	iface, err = ParseIfconfig("wg1: flags=10080c0<RUNNING,NOARP,MULTICAST,LOWER_UP> metric 0 mtu 1380\n")
+	require.NoError(t, err)
+	assert.False(t, iface.Up)
+	assert.Empty(t, iface.Addresses)
+}
+
+func TestParseNetstatInterfaceBytes(t *testing.T) {
+	output := `Name    Mtu Network                  Address              Ipkts Ierrs Idrop     Ibytes    Opkts Oerrs     Obytes  Coll
+wg0    1420 <Link#3>                                        12     0     0       1536       10     0       2048     0
+wg0       - 10.11.12.0/24            10.11.12.1               0     -     -          0        0     -          0     -
+`
+	received, transmitted, err := ParseNetstatInterfaceBytes(output)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1536), received)
+	assert.Equal(t, uint64(2048), transmitted)
+
+	_, _, err = ParseNetstatInterfaceBytes("Name Mtu Network Address Ibytes Obytes\n")
+	assert.Error(t, err)
+}
+
+func TestParseNetstatRoutes(t *testing.T) {
+	output := `Routing tables
+
+Internet:
+Destination        Gateway            Flags     Nhop#    Mtu      Netif Expire
+default            192.168.1.1        UGS           4   1500        em0
+10.11.12.0/24      link#3             U             3   1420        wg0
+10.11.12.1         link#2             UHS           1  16384        lo0
+10.11.12.2         link#3             UHS           5   1420        wg0
+192.168.100.0/24   link#3             US            6   1420        wg0
+fe80::%lo0/64      link#2             U             2  16384        lo0
+`
+	routes := ParseNetstatRoutes(output)
+	assert.Equal(t, []BsdRoute{
+		{Destination: netip.MustParsePrefix("0.0.0.0/0"), Interface: "em0", Mtu: 1500, Static: true},
+		{Destination: netip.MustParsePrefix("10.11.12.0/24"), Interface: "wg0", Mtu: 1420},
+		{Destination: netip.MustParsePrefix("10.11.12.1/32"), Interface: "lo0", Mtu: 16384, Static: true},
+		{Destination: netip.MustParsePrefix("10.11.12.2/32"), Interface: "wg0", Mtu: 1420, Static: true},
+		{Destination: netip.MustParsePrefix("192.168.100.0/24"), Interface: "wg0", Mtu: 1420, Static: true},
+	}, routes)
+}
+
+func TestParseRouteGet(t *testing.T) {
+	output := `   route to: 198.51.100.7
+destination: default
+       mask: default
+    gateway: 192.168.1.1
+        fib: 0
+  interface: em0
+      flags: <UP,GATEWAY,DONE,STATIC>
+ recvpipe  sendpipe  ssthresh  rtt,msec    mtu        weight    expire
+       0         0         0         0      1492         1         0
+`
+	route, err := ParseRouteGet(output)
+	require.NoError(t, err)
+	assert.Equal(t, &BsdRoute{
+		Destination: netip.MustParsePrefix("0.0.0.0/0"),
+		Interface:   "em0",
+		Mtu:         1492,
+		Static:      true,
+	}, route)
+
+	_, err = ParseRouteGet("route: route has not been found\n")
+	assert.Error(t, err)
+}
@@ -1,6 +1,10 @@
+//go:build linux
+
 package lowlevel
 
 import (
+	"net"
+
 	"github.com/vishvananda/netlink"
 )
 
@@ -9,6 +13,7 @@ type NetlinkClient interface {
 	LinkAdd(link netlink.Link) error
 	LinkDel(link netlink.Link) error
 	LinkByName(name string) (netlink.Link, error)
+	LinkByIndex(index int) (netlink.Link, error)
 	LinkSetUp(link netlink.Link) error
 	LinkSetDown(link netlink.Link) error
 	LinkSetMTU(link netlink.Link, mtu int) error
@@ -19,11 +24,21 @@ type NetlinkClient interface {
 	RouteAdd(route *netlink.Route) error
 	RouteDel(route *netlink.Route) error
 	RouteReplace(route *netlink.Route) error
+	RouteGet(destination net.IP) ([]netlink.Route, error)
 	RouteList(link netlink.Link, family int) ([]netlink.Route, error)
 	RouteListFiltered(family int, filter *netlink.Route, filterMask uint64) ([]netlink.Route, error)
 	RuleAdd(rule *netlink.Rule) error
 	RuleDel(rule *netlink.Rule) error
 	RuleList(family int) ([]netlink.Rule, error)
+	QdiscReplace(qdisc netlink.Qdisc) error
+	QdiscDel(qdisc netlink.Qdisc) error
+	QdiscList(link netlink.Link) ([]netlink.Qdisc, error)
+	ClassReplace(class netlink.Class) error
+	ClassList(link netlink.Link, parent uint32) ([]netlink.Class, error)
+	FilterAdd(filter netlink.Filter) error
+	FilterReplace(filter netlink.Filter) error
+	FilterList(link netlink.Link, parent uint32) ([]netlink.Filter, error)
+	LinkSubscribe(ch chan<- netlink.LinkUpdate, done <-chan struct{}) error
 }
 
 type NetlinkManager struct {
@@ -37,6 +52,10 @@ func (n NetlinkManager) LinkByName(name string) (netlink.Link, error) {
 	return netlink.LinkByName(name)
 }
 
+func (n NetlinkManager) LinkByIndex(index int) (netlink.Link, error) {
+	return netlink.LinkByIndex(index)
+}
+
 func (n NetlinkManager) LinkSetUp(link netlink.Link) error { return netlink.LinkSetUp(link) }
 
 func (n NetlinkManager) LinkSetDown(link netlink.Link) error { return netlink.LinkSetDown(link) }
@@ -87,6 +106,10 @@ func (n NetlinkManager) RouteReplace(route *netlink.Route) error {
 	return netlink.RouteReplace(route)
 }
 
+func (n NetlinkManager) RouteGet(destination net.IP) ([]netlink.Route, error) {
+	return netlink.RouteGet(destination)
+}
+
 func (n NetlinkManager) RouteList(link netlink.Link, family int) ([]netlink.Route, error) {
 	return netlink.RouteList(link, family)
 }
@@ -106,3 +129,39 @@ func (n NetlinkManager) RuleDel(rule *netlink.Rule) error {
 func (n NetlinkManager) RuleList(family int) ([]netlink.Rule, error) {
 	return netlink.RuleList(family)
 }
+
+func (n NetlinkManager) QdiscReplace(qdisc netlink.Qdisc) error {
+	return netlink.QdiscReplace(qdisc)
+}
+
+func (n NetlinkManager) QdiscDel(qdisc netlink.Qdisc) error {
+	return netlink.QdiscDel(qdisc)
+}
+
+func (n NetlinkManager) QdiscList(link netlink.Link) ([]netlink.Qdisc, error) {
+	return netlink.QdiscList(link)
+}
+
+func (n NetlinkManager) ClassReplace(class netlink.Class) error {
+	return netlink.ClassReplace(class)
+}
+
+func (n NetlinkManager) ClassList(link netlink.Link, parent uint32) ([]netlink.Class, error) {
+	return netlink.ClassList(link, parent)
+}
+
+func (n NetlinkManager) FilterAdd(filter netlink.Filter) error {
+	return netlink.FilterAdd(filter)
+}
+
+func (n NetlinkManager) FilterReplace(filter netlink.Filter) error {
+	return netlink.FilterReplace(filter)
+}
+
+func (n NetlinkManager) FilterList(link netlink.Link, parent uint32) ([]netlink.Filter, error) {
+	return netlink.FilterList(link, parent)
+}
+
+func (n NetlinkManager) LinkSubscribe(ch chan<- netlink.LinkUpdate, done <-chan struct{}) error {
+	return netlink.LinkSubscribe(ch, done)
+}